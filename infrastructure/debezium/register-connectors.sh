@@ -0,0 +1,24 @@
+#!/bin/sh
+set -e
+
+# Registers the Debezium connectors in this directory with Kafka Connect.
+# Each file is a connector's config, named after the connector; PUT creates
+# it or updates its config, so the script can be rerun.
+
+CONNECT_URL=${CONNECT_URL:-http://localhost:8083}
+DIR=$(dirname "$0")
+
+echo "Waiting for Kafka Connect at ${CONNECT_URL}..."
+until curl -sf "${CONNECT_URL}/connectors" > /dev/null; do
+    sleep 2
+done
+
+for file in "${DIR}"/*.json; do
+    name=$(basename "${file}" .json)
+    echo "Registering connector ${name}"
+    curl -sf -X PUT -H "Content-Type: application/json" \
+        --data @"${file}" \
+        "${CONNECT_URL}/connectors/${name}/config" > /dev/null
+done
+
+echo "Connectors registered"
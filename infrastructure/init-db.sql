@@ -5,6 +5,19 @@ CREATE DATABASE inventory_db;
 CREATE DATABASE orders_db;
 CREATE DATABASE payments_db;
 CREATE DATABASE users_db;
+CREATE DATABASE notifications_db;
+CREATE DATABASE reviews_db;
+CREATE DATABASE recommendations_db;
+CREATE DATABASE pricing_db;
+CREATE DATABASE coupons_db;
+CREATE DATABASE wishlists_db;
+CREATE DATABASE returns_db;
+CREATE DATABASE tax_db;
+CREATE DATABASE analytics_db;
+CREATE DATABASE availability_db;
+CREATE DATABASE media_db;
+CREATE DATABASE giftcards_db;
+CREATE DATABASE subscriptions_db;
 
 -- Connect to inventory_db and create schema
 \c inventory_db;
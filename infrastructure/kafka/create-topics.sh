@@ -43,6 +43,7 @@ declare -A TOPICS=(
     ["notification-events"]="Event stream for sending notifications (email, sms, push)"
     ["analytics-events"]="Event stream for analytics and business intelligence"
     ["audit-events"]="Event stream for audit logging and compliance"
+    ["warehouse-changes"]="Masked row changes of the users, inventory and orders databases for the data warehouse"
 )
 
 for topic in "${!TOPICS[@]}"; do
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	sharedconfig "github.com/ecommerce-platform/shared/go/config"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/httpmetrics"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce-platform/shared/go/mtls"
+	"github.com/ecommerce/admin-api/internal/api"
+	"github.com/ecommerce/admin-api/internal/config"
+	"github.com/ecommerce/admin-api/internal/downstream"
+	"github.com/ecommerce/admin-api/internal/middleware"
+	"github.com/ecommerce/admin-api/internal/views"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.uber.org/zap"
+)
+
+func main() {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Initialize logger
+	log, err := logging.New(logging.Config{
+		ServiceName: "admin-api",
+		Environment: cfg.Environment,
+		Level:       os.Getenv("LOG_LEVEL"),
+	})
+	if err != nil {
+		fmt.Printf("Failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer log.Sync()
+
+	log.Info("Starting Admin API",
+		zap.String("environment", cfg.Environment),
+		zap.Int("port", cfg.Port),
+	)
+	log.Info("Configuration loaded", zap.Any("config", sharedconfig.Redacted(cfg)))
+
+	// Initialize mTLS
+	serviceTLS, err := mtls.New(cfg.TLS, log)
+	if err != nil {
+		log.Fatal("Failed to initialize mTLS", zap.Error(err))
+	}
+	defer serviceTLS.Close()
+
+	// Initialize OpenTelemetry
+	cleanup, err := initTelemetry(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize telemetry", zap.Error(err))
+	}
+	defer cleanup()
+
+	// Services the views are composed from
+	timeout := time.Duration(cfg.DownstreamTimeout) * time.Second
+	transport := serviceTLS.Transport()
+	composer := views.NewComposer(views.Clients{
+		Orders:        downstream.NewClient("order-service", cfg.OrderServiceURL, timeout, transport),
+		Payments:      downstream.NewClient("payment-service", cfg.PaymentServiceURL, timeout, transport),
+		Users:         downstream.NewClient("user-service", cfg.UserServiceURL, timeout, transport),
+		Notifications: downstream.NewClient("notification-service", cfg.NotificationServiceURL, timeout, transport),
+		Inventory:     downstream.NewClient("inventory-service", cfg.InventoryServiceURL, timeout, transport),
+		Returns:       downstream.NewClient("returns-service", cfg.ReturnsServiceURL, timeout, transport),
+	}, log)
+
+	// Initialize handler
+	handler := api.NewHandler(composer, log)
+
+	// Initialize auth
+	verifier, err := sharedauth.NewVerifier(sharedauth.Config{
+		Secret:  cfg.JWTSecret,
+		JWKSURL: cfg.JWKSURL,
+		Issuer:  sharedauth.Issuer,
+	})
+	if err != nil {
+		log.Fatal("Failed to create token verifier", zap.Error(err))
+	}
+	authMiddleware := sharedauth.NewMiddleware(verifier, log)
+
+	// Setup Gin
+	if cfg.Environment == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(middleware.CorrelationID())
+	router.Use(otelgin.Middleware("admin-api"))
+	router.Use(httpmetrics.Middleware("admin-api"))
+	router.Use(apperrors.Middleware(log))
+
+	// Health check
+	router.GET("/health", handler.HealthCheck)
+
+	// Metrics for Prometheus
+	router.GET("/metrics", gin.WrapH(httpmetrics.Handler()))
+
+	// API routes; each view needs its own permission, and its sections are
+	// fetched with the caller's token, so the services owning them still
+	// authorize the staff member. Only the default tenant is served.
+	v1 := router.Group("/api/v1", sharedauth.RequireDefaultTenant())
+	{
+		backoffice := v1.Group("/backoffice", authMiddleware.Authenticate())
+		{
+			backoffice.GET("/orders/:orderId", authMiddleware.RequirePermission(api.PermissionOrders), handler.OrderDetail)
+			backoffice.GET("/customers/:userId", authMiddleware.RequirePermission(api.PermissionCustomers), handler.Customer)
+			backoffice.GET("/inventory", authMiddleware.RequirePermission(api.PermissionInventory), handler.Inventory)
+		}
+	}
+
+	// Create HTTP server
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Port),
+		Handler:      router,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// Start server in goroutine
+	go func() {
+		log.Info("Server starting", zap.Int("port", cfg.Port))
+		if err := serviceTLS.ListenAndServe(srv); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Server failed to start", zap.Error(err))
+		}
+	}()
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Info("Shutting down server...")
+
+	// Graceful shutdown
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Fatal("Server forced to shutdown", zap.Error(err))
+	}
+
+	log.Info("Server shutdown complete")
+}
+
+func initTelemetry(cfg *config.Config) (func(), error) {
+	ctx := context.Background()
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String("admin-api"),
+			semconv.ServiceVersionKey.String("1.0.0"),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	traceExporter, err := otlptracegrpc.New(ctx,
+		otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint),
+		otlptracegrpc.WithInsecure(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	bsp := sdktrace.NewBatchSpanProcessor(traceExporter)
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithResource(res),
+		sdktrace.WithSpanProcessor(bsp),
+	)
+
+	otel.SetTracerProvider(tracerProvider)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			fmt.Printf("Failed to shutdown tracer provider: %v\n", err)
+		}
+	}, nil
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/pagination"
+	"github.com/ecommerce/admin-api/internal/downstream"
+	"github.com/ecommerce/admin-api/internal/metrics"
+	"github.com/ecommerce/admin-api/internal/views"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Permissions of the views; admins have them all
+const (
+	PermissionOrders    = "backoffice:orders"
+	PermissionCustomers = "backoffice:customers"
+	PermissionInventory = "backoffice:inventory"
+)
+
+// Handler serves the backoffice views
+type Handler struct {
+	composer *views.Composer
+	logger   *zap.Logger
+}
+
+// NewHandler creates a new handler
+func NewHandler(composer *views.Composer, logger *zap.Logger) *Handler {
+	return &Handler{
+		composer: composer,
+		logger:   logger,
+	}
+}
+
+// OrderDetail returns an order with its payment, shipment and notifications
+// GET /api/v1/backoffice/orders/:orderId
+func (h *Handler) OrderDetail(c *gin.Context) {
+	detail, err := h.composer.OrderDetail(c.Request.Context(), sharedauth.Token(c), c.Param("orderId"))
+	if err != nil {
+		abort(c, "order", "Order", err)
+		return
+	}
+
+	recordView("order", detail.Unavailable)
+	c.JSON(http.StatusOK, detail)
+}
+
+// Customer returns a customer's profile, recent orders, returns and
+// notifications, and support tickets
+// GET /api/v1/backoffice/customers/:userId
+func (h *Handler) Customer(c *gin.Context) {
+	customer, err := h.composer.Customer(c.Request.Context(), sharedauth.Token(c), c.Param("userId"))
+	if err != nil {
+		abort(c, "customer", "Customer", err)
+		return
+	}
+
+	recordView("customer", customer.Unavailable)
+	c.JSON(http.StatusOK, customer)
+}
+
+// Inventory returns a page of inventory items, newest first, with the
+// items low on stock
+// GET /api/v1/backoffice/inventory?limit=&cursor=
+func (h *Handler) Inventory(c *gin.Context) {
+	params := pagination.FromQuery(c.Request.URL.Query())
+	inventory, err := h.composer.Inventory(c.Request.Context(), sharedauth.Token(c), params.Limit, params.Cursor)
+	if err != nil {
+		abort(c, "inventory", "Inventory", err)
+		return
+	}
+
+	recordView("inventory", inventory.Unavailable)
+	c.JSON(http.StatusOK, inventory)
+}
+
+// HealthCheck reports the service as up; the services views are composed
+// from are checked by their own health checks
+func (h *Handler) HealthCheck(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "healthy",
+		"service": "admin-api",
+		"version": "1.0.0",
+	})
+}
+
+// recordView counts a view served, partial when sections were left out
+func recordView(view string, unavailable []views.Unavailable) {
+	result := "complete"
+	if len(unavailable) > 0 {
+		result = "partial"
+	}
+	metrics.ViewsTotal.WithLabelValues(view, result).Inc()
+}
+
+// abort answers a view whose main resource couldn't be fetched: as not
+// found or forbidden when its service said so, else as unavailable
+func abort(c *gin.Context, view, resource string, err error) {
+	var statusErr *downstream.StatusError
+	switch {
+	case errors.As(err, &statusErr) && statusErr.NotFound():
+		metrics.ViewsTotal.WithLabelValues(view, "not_found").Inc()
+		apperrors.Abort(c, apperrors.NewNotFound(resource))
+	case errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden):
+		metrics.ViewsTotal.WithLabelValues(view, "failed").Inc()
+		apperrors.Abort(c, apperrors.NewForbidden(statusErr.Service+" denied access"))
+	default:
+		metrics.ViewsTotal.WithLabelValues(view, "failed").Inc()
+		apperrors.Abort(c, apperrors.ErrServiceUnavailable.WithCause(err))
+	}
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"errors"
+	"os"
+
+	sharedconfig "github.com/ecommerce-platform/shared/go/config"
+	"github.com/ecommerce-platform/shared/go/mtls"
+	"github.com/ecommerce-platform/shared/go/secrets"
+)
+
+// defaultJWTSecret only suits local development; it matches JWT_SECRET's
+// default tag
+const defaultJWTSecret = "your-secret-key-change-in-production"
+
+// Config holds application configuration
+type Config struct {
+	// Server
+	Port        int    `env:"PORT" flag:"port" default:"8095"`
+	Environment string `env:"ENVIRONMENT" default:"development"`
+
+	// Services the views are composed from
+	OrderServiceURL        string `env:"ORDER_SERVICE_URL" default:"http://order-service:3001"`
+	PaymentServiceURL      string `env:"PAYMENT_SERVICE_URL" default:"http://payment-service:8001"`
+	UserServiceURL         string `env:"USER_SERVICE_URL" default:"http://user-service:8084"`
+	NotificationServiceURL string `env:"NOTIFICATION_SERVICE_URL" default:"http://notification-service:8085"`
+	InventoryServiceURL    string `env:"INVENTORY_SERVICE_URL" default:"http://inventory-service:8081"`
+	ReturnsServiceURL      string `env:"RETURNS_SERVICE_URL" default:"http://returns-service:8092"`
+	// DownstreamTimeout is how long, in seconds, a view waits for each
+	// service before leaving its section out
+	DownstreamTimeout int `env:"DOWNSTREAM_TIMEOUT_SECONDS" default:"5"`
+
+	// Auth: tokens from user-service are verified with its JWT secret, or
+	// with the keys at JWKSURL if it signs them asymmetrically
+	JWTSecret string `env:"JWT_SECRET" default:"your-secret-key-change-in-production" secret:"true"`
+	JWKSURL   string `env:"JWKS_URL"`
+
+	// OpenTelemetry
+	OTLPEndpoint string `env:"OTLP_ENDPOINT" default:"otel-collector:4317"`
+
+	// Mutual TLS for the service's servers and its clients of other
+	// services
+	TLS mtls.Config
+}
+
+// Load loads configuration from flags and environment variables
+func Load() (*Config, error) {
+	resolver, err := secrets.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := sharedconfig.LoadWith(&cfg, sharedconfig.Options{Args: os.Args[1:], Secrets: resolver}); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate rejects settings the service can't run with
+func (c *Config) Validate() error {
+	if err := c.TLS.Validate(); err != nil {
+		return err
+	}
+	if c.DownstreamTimeout < 1 {
+		return errors.New("DOWNSTREAM_TIMEOUT_SECONDS must be a positive integer")
+	}
+	if c.Environment == "production" && c.JWKSURL == "" && c.JWTSecret == defaultJWTSecret {
+		return errors.New("JWT_SECRET or JWKS_URL must be set in production")
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+// Package downstream calls the services the backoffice views are composed
+// from, on behalf of the signed-in staff member
+package downstream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/admin-api/internal/metrics"
+)
+
+// StatusError is a service answering with an error status; Message is the
+// error it gave, if any
+type StatusError struct {
+	Service    string
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s returned status %d", e.Service, e.StatusCode)
+	}
+	return fmt.Sprintf("%s returned status %d: %s", e.Service, e.StatusCode, e.Message)
+}
+
+// NotFound reports whether the service didn't find what was asked for
+func (e *StatusError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// Client calls one service
+type Client struct {
+	service    string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a client of the service at baseURL, named service in
+// errors and metrics, calling it through transport
+func NewClient(service, baseURL string, timeout time.Duration, transport http.RoundTripper) *Client {
+	return &Client{
+		service:    service,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: timeout, Transport: transport},
+	}
+}
+
+// Service is the name of the service the client calls
+func (c *Client) Service() string {
+	return c.service
+}
+
+// Get fetches path with the caller's token, so the service authorizes the
+// staff member rather than admin-api, and decodes the response into out.
+// Error statuses return a *StatusError.
+func (c *Client) Get(ctx context.Context, path, token string, out interface{}) error {
+	start := time.Now()
+	err := c.get(ctx, path, token, out)
+	metrics.DownstreamRequestSeconds.WithLabelValues(c.service).Observe(time.Since(start).Seconds())
+	metrics.DownstreamRequestsTotal.WithLabelValues(c.service, result(err)).Inc()
+	return err
+}
+
+func (c *Client) get(ctx context.Context, path, token string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if correlationID := logging.CorrelationID(ctx); correlationID != "" {
+		req.Header.Set(logging.CorrelationIDHeader, correlationID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", c.service, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{Service: c.service, StatusCode: resp.StatusCode, Message: errorMessage(resp)}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", c.service, err)
+	}
+	return nil
+}
+
+// errorMessage reads a service's error response: {"error": message} from
+// the Go and Node services, {"detail": message} from the Python ones
+func errorMessage(resp *http.Response) string {
+	var body struct {
+		Error  interface{} `json:"error"`
+		Detail interface{} `json:"detail"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	if message, ok := body.Error.(string); ok {
+		return message
+	}
+	message, _ := body.Detail.(string)
+	return message
+}
+
+// result labels a call's outcome for metrics
+func result(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	if statusErr, ok := err.(*StatusError); ok {
+		switch statusErr.StatusCode {
+		case http.StatusNotFound:
+			return "not_found"
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return "denied"
+		}
+	}
+	return "failed"
+}
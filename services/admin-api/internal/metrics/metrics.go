@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// DownstreamRequestsTotal counts calls to other services by service and
+	// result: ok, not_found, denied (401 or 403) or failed
+	DownstreamRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "admin_api_downstream_requests_total",
+		Help: "Calls to the services views are composed from, per service and result",
+	}, []string{"service", "result"})
+
+	// DownstreamRequestSeconds times calls to other services
+	DownstreamRequestSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "admin_api_downstream_request_seconds",
+		Help:    "Time to call the services views are composed from",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service"})
+
+	// ViewsTotal counts views by view and result: complete, partial (some
+	// sections left out), not_found or failed
+	ViewsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "admin_api_views_total",
+		Help: "Views served, per view and result",
+	}, []string{"view", "result"})
+)
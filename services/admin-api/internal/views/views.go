@@ -0,0 +1,353 @@
+// Package views composes the backoffice's views from the services that own
+// their data. Sections are fetched concurrently; a view still answers when
+// a section's service fails, listing the section as unavailable.
+package views
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/admin-api/internal/downstream"
+	"go.uber.org/zap"
+)
+
+// How much of a customer's history the customer view shows
+const (
+	customerOrdersLimit        = 10
+	customerReturnsLimit       = 10
+	customerNotificationsLimit = 20
+	customerManualSendsLimit   = 100
+	orderNotificationsLimit    = 50
+)
+
+// Clients are the services views are composed from
+type Clients struct {
+	Orders        *downstream.Client
+	Payments      *downstream.Client
+	Users         *downstream.Client
+	Notifications *downstream.Client
+	Inventory     *downstream.Client
+	Returns       *downstream.Client
+}
+
+// Unavailable is a section left out of a view because its service failed
+type Unavailable struct {
+	Section string `json:"section"`
+	Service string `json:"service"`
+	Error   string `json:"error"`
+}
+
+// Shipment is an order's shipment, as order-service records it
+type Shipment struct {
+	Status         string     `json:"status"`
+	Carrier        string     `json:"carrier,omitempty"`
+	TrackingNumber string     `json:"tracking_number,omitempty"`
+	ShippedAt      *time.Time `json:"shipped_at,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// OrderDetail is an order with its payment, shipment and the notifications
+// sent about it. Payment and Shipment are null until the order is paid and
+// shipped.
+type OrderDetail struct {
+	Order         json.RawMessage   `json:"order"`
+	Payment       json.RawMessage   `json:"payment"`
+	Shipment      *Shipment         `json:"shipment"`
+	Notifications []json.RawMessage `json:"notifications"`
+	Unavailable   []Unavailable     `json:"unavailable"`
+}
+
+// Ticket is a support ticket, as far as the platform knows it: the
+// notifications support sent a customer under its ID
+type Ticket struct {
+	TicketID      string       `json:"ticket_id"`
+	LastContactAt time.Time    `json:"last_contact_at"`
+	ManualSends   []ManualSend `json:"manual_sends"`
+}
+
+// ManualSend is a notification support sent, from notification-service's
+// audit log
+type ManualSend struct {
+	ID               string    `json:"id"`
+	Template         string    `json:"template"`
+	OrderID          string    `json:"order_id,omitempty"`
+	RequestedByEmail string    `json:"requested_by_email,omitempty"`
+	Reason           string    `json:"reason"`
+	TicketID         string    `json:"ticket_id,omitempty"`
+	Status           string    `json:"status"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// Customer is a customer's profile with their recent orders, returns,
+// notifications and support tickets
+type Customer struct {
+	Profile       json.RawMessage   `json:"profile"`
+	Orders        []json.RawMessage `json:"orders"`
+	Returns       []json.RawMessage `json:"returns"`
+	ReturnsTotal  int64             `json:"returns_total"`
+	Notifications []json.RawMessage `json:"notifications"`
+	Tickets       []Ticket          `json:"tickets"`
+	Unavailable   []Unavailable     `json:"unavailable"`
+}
+
+// Inventory is a page of inventory items with every item low on stock
+type Inventory struct {
+	Items       []json.RawMessage `json:"items"`
+	NextCursor  string            `json:"next_cursor,omitempty"`
+	TotalCount  int64             `json:"total_count"`
+	LowStock    []json.RawMessage `json:"low_stock"`
+	Unavailable []Unavailable     `json:"unavailable"`
+}
+
+// Composer builds views
+type Composer struct {
+	clients Clients
+	logger  *zap.Logger
+}
+
+// NewComposer creates a composer calling clients
+func NewComposer(clients Clients, logger *zap.Logger) *Composer {
+	return &Composer{
+		clients: clients,
+		logger:  logger,
+	}
+}
+
+// section is one call a view is composed from
+type section struct {
+	name   string
+	client *downstream.Client
+	path   string
+	out    interface{}
+	err    error
+}
+
+// fetch calls every section concurrently with the caller's token
+func fetch(ctx context.Context, token string, sections ...*section) {
+	var wg sync.WaitGroup
+	for _, s := range sections {
+		wg.Add(1)
+		go func(s *section) {
+			defer wg.Done()
+			s.err = s.client.Get(ctx, s.path, token, s.out)
+		}(s)
+	}
+	wg.Wait()
+}
+
+// unavailable lists the sections that failed, logging why. A section its
+// service didn't find is empty rather than unavailable.
+func (c *Composer) unavailable(ctx context.Context, sections ...*section) []Unavailable {
+	missing := []Unavailable{}
+	for _, s := range sections {
+		if s.err == nil || isNotFound(s.err) {
+			continue
+		}
+		logging.WithContext(ctx, c.logger).Warn("View section unavailable",
+			zap.String("section", s.name),
+			zap.String("service", s.client.Service()),
+			zap.Error(s.err),
+		)
+		missing = append(missing, Unavailable{Section: s.name, Service: s.client.Service(), Error: s.err.Error()})
+	}
+	return missing
+}
+
+// OrderDetail composes an order's detail. Failing to get the order itself
+// returns its error, e.g. a *downstream.StatusError of 404.
+func (c *Composer) OrderDetail(ctx context.Context, token, orderID string) (*OrderDetail, error) {
+	var (
+		order struct {
+			Order json.RawMessage `json:"order"`
+		}
+		payment       json.RawMessage
+		notifications struct {
+			Notifications []json.RawMessage `json:"notifications"`
+		}
+	)
+	orderSection := &section{name: "order", client: c.clients.Orders, path: "/api/v1/orders/" + url.PathEscape(orderID), out: &order}
+	paymentSection := &section{name: "payment", client: c.clients.Payments, path: "/api/v1/payments/order/" + url.PathEscape(orderID), out: &payment}
+	notificationsSection := &section{name: "notifications", client: c.clients.Notifications, path: "/api/v1/notifications/history?" + url.Values{
+		"order_id": {orderID},
+		"limit":    {strconv.Itoa(orderNotificationsLimit)},
+	}.Encode(), out: &notifications}
+
+	fetch(ctx, token, orderSection, paymentSection, notificationsSection)
+	if orderSection.err != nil {
+		return nil, orderSection.err
+	}
+
+	detail := &OrderDetail{
+		Order:         order.Order,
+		Payment:       payment,
+		Notifications: orEmpty(notifications.Notifications),
+		Unavailable:   c.unavailable(ctx, paymentSection, notificationsSection),
+	}
+	if len(detail.Payment) == 0 {
+		detail.Payment = json.RawMessage("null")
+	}
+
+	shipment, err := shipmentOf(order.Order)
+	if err != nil {
+		return nil, err
+	}
+	detail.Shipment = shipment
+	return detail, nil
+}
+
+// shipmentOf reads the shipment of an order, nil until it ships
+func shipmentOf(order json.RawMessage) (*Shipment, error) {
+	var fields struct {
+		Status         string     `json:"status"`
+		Carrier        string     `json:"carrier"`
+		TrackingNumber string     `json:"trackingNumber"`
+		ShippedAt      *time.Time `json:"shippedAt"`
+		DeliveredAt    *time.Time `json:"deliveredAt"`
+	}
+	if err := json.Unmarshal(order, &fields); err != nil {
+		return nil, err
+	}
+	if fields.ShippedAt == nil && fields.DeliveredAt == nil {
+		return nil, nil
+	}
+	return &Shipment{
+		Status:         fields.Status,
+		Carrier:        fields.Carrier,
+		TrackingNumber: fields.TrackingNumber,
+		ShippedAt:      fields.ShippedAt,
+		DeliveredAt:    fields.DeliveredAt,
+	}, nil
+}
+
+// Customer composes a customer's view. Failing to get their profile
+// returns its error, e.g. a *downstream.StatusError of 404.
+func (c *Composer) Customer(ctx context.Context, token, userID string) (*Customer, error) {
+	var (
+		profile json.RawMessage
+		orders  struct {
+			Orders []json.RawMessage `json:"orders"`
+		}
+		returns struct {
+			Items      []json.RawMessage `json:"items"`
+			TotalCount int64             `json:"total_count"`
+		}
+		notifications struct {
+			Notifications []json.RawMessage `json:"notifications"`
+		}
+		manualSends struct {
+			ManualSends []ManualSend `json:"manual_sends"`
+		}
+	)
+	profileSection := &section{name: "profile", client: c.clients.Users, path: "/api/v1/admin/users/" + url.PathEscape(userID), out: &profile}
+	ordersSection := &section{name: "orders", client: c.clients.Orders, path: "/api/v1/orders?" + url.Values{
+		"userId": {userID},
+		"limit":  {strconv.Itoa(customerOrdersLimit)},
+	}.Encode(), out: &orders}
+	returnsSection := &section{name: "returns", client: c.clients.Returns, path: "/api/v1/returns/manage?" + url.Values{
+		"user_id": {userID},
+		"limit":   {strconv.Itoa(customerReturnsLimit)},
+	}.Encode(), out: &returns}
+	notificationsSection := &section{name: "notifications", client: c.clients.Notifications, path: "/api/v1/notifications/history?" + url.Values{
+		"user_id": {userID},
+		"limit":   {strconv.Itoa(customerNotificationsLimit)},
+	}.Encode(), out: &notifications}
+	ticketsSection := &section{name: "tickets", client: c.clients.Notifications, path: "/api/v1/notifications/manual-sends?" + url.Values{
+		"user_id": {userID},
+		"limit":   {strconv.Itoa(customerManualSendsLimit)},
+	}.Encode(), out: &manualSends}
+
+	fetch(ctx, token, profileSection, ordersSection, returnsSection, notificationsSection, ticketsSection)
+	if profileSection.err != nil {
+		return nil, profileSection.err
+	}
+
+	return &Customer{
+		Profile:       profile,
+		Orders:        orEmpty(orders.Orders),
+		Returns:       orEmpty(returns.Items),
+		ReturnsTotal:  returns.TotalCount,
+		Notifications: orEmpty(notifications.Notifications),
+		Tickets:       tickets(manualSends.ManualSends),
+		Unavailable:   c.unavailable(ctx, ordersSection, returnsSection, notificationsSection, ticketsSection),
+	}, nil
+}
+
+// tickets groups manual sends by their ticket, the most recently contacted
+// first; sends without a ticket are left out
+func tickets(sends []ManualSend) []Ticket {
+	byID := map[string]*Ticket{}
+	for _, send := range sends {
+		if send.TicketID == "" {
+			continue
+		}
+		ticket, ok := byID[send.TicketID]
+		if !ok {
+			ticket = &Ticket{TicketID: send.TicketID}
+			byID[send.TicketID] = ticket
+		}
+		ticket.ManualSends = append(ticket.ManualSends, send)
+		if send.CreatedAt.After(ticket.LastContactAt) {
+			ticket.LastContactAt = send.CreatedAt
+		}
+	}
+
+	result := make([]Ticket, 0, len(byID))
+	for _, ticket := range byID {
+		result = append(result, *ticket)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].LastContactAt.After(result[j].LastContactAt) })
+	return result
+}
+
+// Inventory composes a page of inventory items, limit and cursor paging
+// as inventory-service does, with the items low on stock. Failing to get
+// the page returns its error.
+func (c *Composer) Inventory(ctx context.Context, token string, limit int, cursor string) (*Inventory, error) {
+	var (
+		page struct {
+			Items      []json.RawMessage `json:"items"`
+			NextCursor string            `json:"next_cursor"`
+			TotalCount int64             `json:"total_count"`
+		}
+		lowStock []json.RawMessage
+	)
+	query := url.Values{"limit": {strconv.Itoa(limit)}, "include_total": {"true"}}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	itemsSection := &section{name: "items", client: c.clients.Inventory, path: "/api/v1/inventory?" + query.Encode(), out: &page}
+	lowStockSection := &section{name: "low_stock", client: c.clients.Inventory, path: "/api/v1/inventory/low-stock", out: &lowStock}
+
+	fetch(ctx, token, itemsSection, lowStockSection)
+	if itemsSection.err != nil {
+		return nil, itemsSection.err
+	}
+
+	return &Inventory{
+		Items:       orEmpty(page.Items),
+		NextCursor:  page.NextCursor,
+		TotalCount:  page.TotalCount,
+		LowStock:    orEmpty(lowStock),
+		Unavailable: c.unavailable(ctx, lowStockSection),
+	}, nil
+}
+
+func isNotFound(err error) bool {
+	var statusErr *downstream.StatusError
+	return errors.As(err, &statusErr) && statusErr.NotFound()
+}
+
+// orEmpty keeps lists serialized as [] rather than null
+func orEmpty(items []json.RawMessage) []json.RawMessage {
+	if items == nil {
+		return []json.RawMessage{}
+	}
+	return items
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	sharedconfig "github.com/ecommerce-platform/shared/go/config"
+	shareddb "github.com/ecommerce-platform/shared/go/db"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/httpmetrics"
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce-platform/shared/go/mtls"
+	"github.com/ecommerce/analytics-service/internal/api"
+	"github.com/ecommerce/analytics-service/internal/collector"
+	"github.com/ecommerce/analytics-service/internal/config"
+	"github.com/ecommerce/analytics-service/internal/ingest"
+	"github.com/ecommerce/analytics-service/internal/middleware"
+	"github.com/ecommerce/analytics-service/internal/repository"
+	"github.com/ecommerce/analytics-service/internal/sink"
+	"github.com/ecommerce/analytics-service/migrations"
+	"github.com/gin-gonic/gin"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.uber.org/zap"
+)
+
+func main() {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Initialize logger
+	log, err := logging.New(logging.Config{
+		ServiceName: "analytics-service",
+		Environment: cfg.Environment,
+		Level:       os.Getenv("LOG_LEVEL"),
+	})
+	if err != nil {
+		fmt.Printf("Failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer log.Sync()
+
+	log.Info("Starting Analytics Service",
+		zap.String("environment", cfg.Environment),
+		zap.Int("port", cfg.Port),
+	)
+	log.Info("Configuration loaded", zap.Any("config", sharedconfig.Redacted(cfg)))
+
+	// Initialize mTLS
+	serviceTLS, err := mtls.New(cfg.TLS, log)
+	if err != nil {
+		log.Fatal("Failed to initialize mTLS", zap.Error(err))
+	}
+	defer serviceTLS.Close()
+
+	// Initialize OpenTelemetry
+	cleanup, err := initTelemetry(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize telemetry", zap.Error(err))
+	}
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Initialize PostgreSQL
+	db, err := shareddb.Open(ctx, shareddb.Config{
+		DSN:          cfg.DatabaseURL,
+		Name:         "analytics",
+		MaxOpenConns: cfg.DBMaxOpenConns,
+		MaxIdleConns: cfg.DBMaxIdleConns,
+	}, log)
+	if err != nil {
+		log.Fatal("Failed to connect to database", zap.Error(err))
+	}
+	defer db.Close()
+
+	if err := shareddb.Migrate(ctx, db, migrations.FS, log); err != nil {
+		log.Fatal("Failed to migrate database", zap.Error(err))
+	}
+
+	// Initialize repositories
+	analyticsRepo := repository.NewPostgresRepository(db)
+
+	// Sinks the collected events are written to
+	var sinks []collector.Sink
+	if cfg.HasSink(config.SinkPostgres) {
+		sinks = append(sinks, sink.NewPostgres(db))
+	}
+	if cfg.HasSink(config.SinkS3) {
+		awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(cfg.S3Region))
+		if err != nil {
+			log.Fatal("Failed to load AWS config", zap.Error(err))
+		}
+		s3Client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+			if cfg.S3Endpoint != "" {
+				o.BaseEndpoint = aws.String(cfg.S3Endpoint)
+				o.UsePathStyle = true
+			}
+		})
+		sinks = append(sinks, sink.NewS3(s3Client, cfg.S3Bucket, cfg.S3Prefix))
+	}
+
+	batcher := collector.NewBatcher(collector.BatcherConfig{
+		Sinks:         sinks,
+		BufferSize:    cfg.BufferSize,
+		BatchSize:     cfg.BatchSize,
+		FlushInterval: time.Duration(cfg.FlushInterval) * time.Second,
+	}, log)
+
+	// Collect storefront, cart and order events
+	brokers := strings.Split(cfg.KafkaBrokers, ",")
+	topics := []string{cfg.ViewEventsTopic, cfg.StorefrontEventsTopic}
+	for _, topic := range []string{cfg.CartEventsTopic, cfg.OrderEventsTopic} {
+		if topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+
+	dlqProducer := sharedkafka.NewProducer(sharedkafka.ProducerConfig{Brokers: brokers}, log)
+	defer dlqProducer.Close()
+
+	eventConsumer := sharedkafka.NewConsumer(sharedkafka.ConsumerConfig{
+		Brokers:     brokers,
+		GroupID:     cfg.KafkaGroupID,
+		Topics:      topics,
+		Concurrency: cfg.ConsumerConcurrency,
+		QueueSize:   cfg.BatchSize,
+		MaxAttempts: 3,
+		DeadLetters: sharedkafka.NewTopicDeadLetters(dlqProducer),
+		Tracer:      otel.Tracer("analytics-service"),
+	}, collector.New(batcher, log).Handle, log)
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	consumerDone := make(chan struct{})
+	go func() {
+		defer close(consumerDone)
+		if err := eventConsumer.Run(backgroundCtx); err != nil {
+			log.Error("Event consumer stopped", zap.Error(err))
+		}
+	}()
+
+	// Storefront events are published asynchronously, so ingestion never
+	// waits for the brokers
+	ingestProducer := sharedkafka.NewProducer(sharedkafka.ProducerConfig{
+		Brokers: brokers,
+		Async:   true,
+		Completion: func(messages []kafkago.Message, err error) {
+			if err != nil {
+				log.Error("Failed to publish storefront events", zap.Int("events", len(messages)), zap.Error(err))
+			}
+		},
+	}, log)
+	publisher := ingest.NewPublisher(ingestProducer, cfg.ViewEventsTopic, cfg.StorefrontEventsTopic)
+
+	// Initialize handler
+	handler := api.NewHandler(publisher, analyticsRepo, log)
+
+	// Initialize auth
+	verifier, err := sharedauth.NewVerifier(sharedauth.Config{
+		Secret:  cfg.JWTSecret,
+		JWKSURL: cfg.JWKSURL,
+		Issuer:  sharedauth.Issuer,
+	})
+	if err != nil {
+		log.Fatal("Failed to create token verifier", zap.Error(err))
+	}
+	authMiddleware := sharedauth.NewMiddleware(verifier, log)
+
+	// Setup Gin
+	if cfg.Environment == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(middleware.CorrelationID())
+	router.Use(otelgin.Middleware("analytics-service"))
+	router.Use(httpmetrics.Middleware("analytics-service"))
+	router.Use(apperrors.Middleware(log))
+
+	// Health check
+	router.GET("/health", handler.HealthCheck)
+
+	// Metrics for Prometheus
+	router.GET("/metrics", gin.WrapH(httpmetrics.Handler()))
+
+	// API routes, for the default tenant only
+	v1 := router.Group("/api/v1", sharedauth.RequireDefaultTenant())
+	{
+		analytics := v1.Group("/analytics")
+		{
+			// Signed-in shoppers' events are attributed to them; guests'
+			// to their session
+			analytics.POST("/events", optionalAuth(authMiddleware), handler.Ingest)
+		}
+
+		read := analytics.Group("", authMiddleware.Authenticate(), authMiddleware.RequirePermission(api.PermissionRead))
+		{
+			read.GET("/products/:productId/daily", handler.ProductDailyStats)
+			read.GET("/searches/top", handler.TopSearches)
+		}
+	}
+
+	// Create HTTP server
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Port),
+		Handler:      router,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// Start server in goroutine
+	go func() {
+		log.Info("Server starting", zap.Int("port", cfg.Port))
+		if err := serviceTLS.ListenAndServe(srv); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Server failed to start", zap.Error(err))
+		}
+	}()
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Info("Shutting down server...")
+
+	// Graceful shutdown
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Fatal("Server forced to shutdown", zap.Error(err))
+	}
+
+	// Deliver the storefront events still queued
+	if err := ingestProducer.Close(); err != nil {
+		log.Error("Failed to publish queued storefront events", zap.Error(err))
+	}
+
+	// Events being collected are left uncommitted and redelivered on
+	// restart; those already buffered are written before exiting
+	stopBackground()
+	select {
+	case <-consumerDone:
+	case <-shutdownCtx.Done():
+		log.Warn("Event consumer didn't stop in time")
+	}
+	if err := batcher.Close(shutdownCtx); err != nil {
+		log.Error("Failed to write buffered events", zap.Error(err))
+	}
+
+	log.Info("Server shutdown complete")
+}
+
+// optionalAuth authenticates requests that carry a token and lets those
+// without one through anonymously
+func optionalAuth(m *sharedauth.Middleware) gin.HandlerFunc {
+	authenticate := m.Authenticate()
+	return func(c *gin.Context) {
+		if sharedauth.Token(c) == "" {
+			c.Next()
+			return
+		}
+		authenticate(c)
+	}
+}
+
+func initTelemetry(cfg *config.Config) (func(), error) {
+	ctx := context.Background()
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String("analytics-service"),
+			semconv.ServiceVersionKey.String("1.0.0"),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	traceExporter, err := otlptracegrpc.New(ctx,
+		otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint),
+		otlptracegrpc.WithInsecure(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	bsp := sdktrace.NewBatchSpanProcessor(traceExporter)
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithResource(res),
+		sdktrace.WithSpanProcessor(bsp),
+	)
+
+	otel.SetTracerProvider(tracerProvider)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			fmt.Printf("Failed to shutdown tracer provider: %v\n", err)
+		}
+	}, nil
+}
@@ -0,0 +1,251 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/analytics-service/internal/domain"
+	"github.com/ecommerce/analytics-service/internal/ingest"
+	"github.com/ecommerce/analytics-service/internal/metrics"
+	"github.com/ecommerce/analytics-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// PermissionRead allows reading product stats and searches; admins have it
+const PermissionRead = "analytics:read"
+
+// Ranges of the read endpoints, in days
+const (
+	defaultStatsDays    = 30
+	defaultSearchesDays = 7
+	maxRangeDays        = 366
+)
+
+// maxIngestBytes bounds an ingestion request's body
+const maxIngestBytes = 1 << 20
+
+// Handler serves the analytics API
+type Handler struct {
+	publisher *ingest.Publisher
+	repo      repository.AnalyticsRepository
+	logger    *zap.Logger
+}
+
+// NewHandler creates a new handler
+func NewHandler(publisher *ingest.Publisher, repo repository.AnalyticsRepository, logger *zap.Logger) *Handler {
+	return &Handler{
+		publisher: publisher,
+		repo:      repo,
+		logger:    logger,
+	}
+}
+
+// EventRequest is a storefront event
+type EventRequest struct {
+	EventType string `json:"event_type" binding:"required,max=50"`
+	// OccurredAt defaults to when the event is received
+	OccurredAt *time.Time `json:"occurred_at"`
+	// SessionID identifies the shopper without a token
+	SessionID string `json:"session_id" binding:"max=255"`
+	ProductID string `json:"product_id" binding:"max=255"`
+	Query     string `json:"query"`
+	Results   int    `json:"results"`
+}
+
+// IngestRequest is a batch of storefront events
+type IngestRequest struct {
+	Events []EventRequest `json:"events" binding:"required,min=1,max=100,dive"`
+}
+
+// RejectedEvent is an event of a batch that wasn't taken
+type RejectedEvent struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// IngestResponse reports what became of a batch
+type IngestResponse struct {
+	Accepted int             `json:"accepted"`
+	Rejected []RejectedEvent `json:"rejected"`
+}
+
+// Ingest takes a batch of storefront events. The signed-in shopper's
+// events are theirs; anonymous ones need a session ID. Valid events are
+// published even when others in the batch are rejected.
+// POST /api/v1/analytics/events
+func (h *Handler) Ingest(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxIngestBytes)
+
+	var req IngestRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	now := time.Now().UTC()
+	userID := c.GetString(sharedauth.ContextUserID)
+	response := IngestResponse{Rejected: []RejectedEvent{}}
+	events := make([]domain.Event, 0, len(req.Events))
+	for i, e := range req.Events {
+		event := domain.Event{
+			Type:      e.EventType,
+			UserID:    userID,
+			SessionID: e.SessionID,
+			ProductID: e.ProductID,
+			Query:     e.Query,
+			Results:   e.Results,
+		}
+		if e.OccurredAt != nil {
+			event.OccurredAt = *e.OccurredAt
+		}
+
+		if err := ingest.Validate(&event, now); err != nil {
+			reason := "invalid"
+			if errors.Is(err, domain.ErrUnsupportedEvent) {
+				reason = "unsupported"
+			}
+			metrics.RejectedEventsTotal.WithLabelValues(reason).Inc()
+			response.Rejected = append(response.Rejected, RejectedEvent{Index: i, Error: err.Error()})
+			continue
+		}
+		events = append(events, event)
+	}
+
+	if len(events) == 0 {
+		var invalid apperrors.ValidationErrors
+		for _, rejected := range response.Rejected {
+			invalid.Add(fmt.Sprintf("events[%d]", rejected.Index), "invalid", rejected.Error)
+		}
+		apperrors.Abort(c, invalid.Err())
+		return
+	}
+
+	if err := h.publisher.Publish(c.Request.Context(), events); err != nil {
+		logging.WithContext(c.Request.Context(), h.logger).Error("Failed to publish events", zap.Int("events", len(events)), zap.Error(err))
+		apperrors.Abort(c, apperrors.FromKafka(err))
+		return
+	}
+
+	response.Accepted = len(events)
+	c.JSON(http.StatusAccepted, response)
+}
+
+// ProductDailyStats returns a product's views, cart adds and purchases per
+// day, from and to being UTC days, by default the last 30
+// GET /api/v1/analytics/products/:productId/daily?from=&to=
+func (h *Handler) ProductDailyStats(c *gin.Context) {
+	from, to, ok := dayRange(c, defaultStatsDays)
+	if !ok {
+		return
+	}
+
+	productID := c.Param("productId")
+	stats, err := h.repo.ProductDailyStats(c.Request.Context(), productID, from, to)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Daily stats"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"product_id": productID,
+		"from":       from.Format(domain.DayLayout),
+		"to":         to.Format(domain.DayLayout),
+		"days":       stats,
+	})
+}
+
+// TopSearches returns the queries searched most often between the UTC days
+// from and to, by default the last 7, with how many found nothing
+// GET /api/v1/analytics/searches/top?from=&to=&limit=
+func (h *Handler) TopSearches(c *gin.Context) {
+	from, to, ok := dayRange(c, defaultSearchesDays)
+	if !ok {
+		return
+	}
+
+	limit := 20
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 100 {
+			apperrors.Abort(c, apperrors.NewValidation(apperrors.FieldError{Field: "limit", Rule: "range", Message: "limit must be between 1 and 100"}))
+			return
+		}
+		limit = n
+	}
+
+	searches, err := h.repo.TopSearches(c.Request.Context(), from, to.AddDate(0, 0, 1), limit)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Searches"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":     from.Format(domain.DayLayout),
+		"to":       to.Format(domain.DayLayout),
+		"searches": searches,
+	})
+}
+
+// HealthCheck reports whether the database is reachable
+func (h *Handler) HealthCheck(c *gin.Context) {
+	if err := h.repo.Ping(c.Request.Context()); err != nil {
+		h.logger.Warn("Health check failed", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unhealthy",
+			"service": "analytics-service",
+			"version": "1.0.0",
+			"error":   "database unavailable",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "healthy",
+		"service": "analytics-service",
+		"version": "1.0.0",
+	})
+}
+
+// dayRange parses the from and to query parameters, UTC days ending with
+// to, by default today, and spanning days days unless from is given. It
+// aborts the request and returns false when they are invalid.
+func dayRange(c *gin.Context, days int) (time.Time, time.Time, bool) {
+	var invalid apperrors.ValidationErrors
+
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse(domain.DayLayout, raw)
+		if err != nil {
+			invalid.Add("to", "format", "to must be a date, YYYY-MM-DD")
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, 1-days)
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse(domain.DayLayout, raw)
+		if err != nil {
+			invalid.Add("from", "format", "from must be a date, YYYY-MM-DD")
+		}
+		from = parsed
+	}
+	if err := invalid.Err(); err != nil {
+		apperrors.Abort(c, err)
+		return time.Time{}, time.Time{}, false
+	}
+
+	if from.After(to) || to.Sub(from) >= maxRangeDays*24*time.Hour {
+		apperrors.Abort(c, apperrors.NewValidation(apperrors.FieldError{
+			Field:   "from",
+			Rule:    "range",
+			Message: fmt.Sprintf("from must be on or before to, and at most %d days before it", maxRangeDays-1),
+		}))
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
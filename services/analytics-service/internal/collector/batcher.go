@@ -0,0 +1,215 @@
+// Package collector turns the behavioral events read from Kafka into
+// warehouse rows and writes them to the sinks in batches
+package collector
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ecommerce/analytics-service/internal/domain"
+	"github.com/ecommerce/analytics-service/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// ErrClosed is returned by Add once the batcher is closing
+var ErrClosed = errors.New("collector: batcher closed")
+
+// Sink stores batches of events, e.g. in Postgres or as Parquet files in S3
+type Sink interface {
+	// Name identifies the sink in logs and metrics
+	Name() string
+	// Write stores a batch of events. Events are redelivered after a crash,
+	// so sinks should drop those they already have by ID where they can.
+	Write(ctx context.Context, events []domain.Event) error
+}
+
+// BatcherConfig configures a Batcher. Zero values get defaults.
+type BatcherConfig struct {
+	Sinks []Sink
+	// BufferSize is how many events may wait for their batch; Add blocks
+	// while it is full. Default 10000.
+	BufferSize int
+	// BatchSize is the most events written at once; default 1000
+	BatchSize int
+	// FlushInterval is the longest an event waits for its batch to fill;
+	// default 5s
+	FlushInterval time.Duration
+	// WriteTimeout bounds each attempt at writing a batch to a sink;
+	// default 30s
+	WriteTimeout time.Duration
+	// WriteAttempts per batch and sink before the batch is dropped from
+	// it, with RetryBackoff doubling between them; default 3 and 1s
+	WriteAttempts int
+	RetryBackoff  time.Duration
+}
+
+// Batcher buffers events and writes them to every sink in batches, each
+// filled up to BatchSize or for at most FlushInterval, so warehouse files
+// and inserts stay large however the events trickle in
+type Batcher struct {
+	cfg    BatcherConfig
+	logger *zap.Logger
+
+	mu     sync.RWMutex
+	closed bool
+	events chan domain.Event
+	done   chan struct{}
+}
+
+// NewBatcher creates a batcher and starts writing its batches. Close it on
+// shutdown to write the events still buffered.
+func NewBatcher(cfg BatcherConfig, logger *zap.Logger) *Batcher {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 10000
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1000
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 30 * time.Second
+	}
+	if cfg.WriteAttempts <= 0 {
+		cfg.WriteAttempts = 3
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = time.Second
+	}
+
+	b := &Batcher{
+		cfg:    cfg,
+		logger: logger,
+		events: make(chan domain.Event, cfg.BufferSize),
+		done:   make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Add buffers events for the next batch. It blocks while the buffer is
+// full, which slows the consumer down to what the sinks can take, until
+// ctx is done.
+func (b *Batcher) Add(ctx context.Context, events ...domain.Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return ErrClosed
+	}
+
+	for _, event := range events {
+		select {
+		case b.events <- event:
+			metrics.BufferedEvents.Inc()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Close stops accepting events and waits until the buffered ones are
+// written, or ctx is done
+func (b *Batcher) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrClosed
+	}
+	b.closed = true
+	close(b.events)
+	b.mu.Unlock()
+
+	select {
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Batcher) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]domain.Event, 0, b.cfg.BatchSize)
+	flush := func(reason string) {
+		if len(batch) == 0 {
+			return
+		}
+		metrics.BatchesTotal.WithLabelValues(reason).Inc()
+		metrics.BatchSize.Observe(float64(len(batch)))
+		b.write(batch)
+		metrics.BufferedEvents.Sub(float64(len(batch)))
+		// Sinks may hold on to a batch, so the next one gets its own array
+		batch = make([]domain.Event, 0, b.cfg.BatchSize)
+	}
+
+	for {
+		select {
+		case event, ok := <-b.events:
+			if !ok {
+				flush("shutdown")
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= b.cfg.BatchSize {
+				flush("size")
+			}
+		case <-ticker.C:
+			flush("interval")
+		}
+	}
+}
+
+// write writes a batch to every sink, retrying each separately; a sink's
+// failure doesn't stop the others
+func (b *Batcher) write(batch []domain.Event) {
+	for _, sink := range b.cfg.Sinks {
+		start := time.Now()
+		err := b.writeSink(sink, batch)
+		metrics.SinkWriteDuration.WithLabelValues(sink.Name()).Observe(time.Since(start).Seconds())
+
+		if err != nil {
+			metrics.SinkEventsTotal.WithLabelValues(sink.Name(), "dropped").Add(float64(len(batch)))
+			b.logger.Error("Failed to write events, dropping batch",
+				zap.String("sink", sink.Name()),
+				zap.Int("events", len(batch)),
+				zap.Int("attempts", b.cfg.WriteAttempts),
+				zap.Error(err),
+			)
+			continue
+		}
+		metrics.SinkEventsTotal.WithLabelValues(sink.Name(), "written").Add(float64(len(batch)))
+	}
+}
+
+// writeSink writes a batch to one sink, retrying with backoff
+func (b *Batcher) writeSink(sink Sink, batch []domain.Event) error {
+	backoff := b.cfg.RetryBackoff
+	var err error
+	for attempt := 1; attempt <= b.cfg.WriteAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), b.cfg.WriteTimeout)
+		err = sink.Write(ctx, batch)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempt < b.cfg.WriteAttempts {
+			b.logger.Warn("Failed to write events, retrying",
+				zap.String("sink", sink.Name()),
+				zap.Int("attempt", attempt),
+				zap.Duration("backoff", backoff),
+				zap.Error(err),
+			)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return err
+}
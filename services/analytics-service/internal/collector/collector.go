@@ -0,0 +1,161 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	sharedevents "github.com/ecommerce-platform/shared/go/events"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/analytics-service/internal/domain"
+	"github.com/ecommerce/analytics-service/internal/metrics"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// cartItemAdded is the data of cart-service's cart.item_added events
+type cartItemAdded struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+// Collector turns consumed events into warehouse events for the batcher
+type Collector struct {
+	batcher *Batcher
+	logger  *zap.Logger
+}
+
+// New creates a collector adding to batcher
+func New(batcher *Batcher, logger *zap.Logger) *Collector {
+	return &Collector{
+		batcher: batcher,
+		logger:  logger,
+	}
+}
+
+// Handle collects a storefront, cart or order event. It is a
+// sharedkafka.Handler; failures are retried and then dead-lettered. Other
+// events are ignored.
+func (c *Collector) Handle(ctx context.Context, msg kafkago.Message) error {
+	env, err := sharedevents.Decode(msg.Value)
+	if err != nil {
+		return err
+	}
+
+	events, err := convert(env, msg)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		metrics.CollectedEventsTotal.WithLabelValues(env.EventType, "ignored").Inc()
+		return nil
+	}
+
+	if err := c.batcher.Add(ctx, events...); err != nil {
+		return err
+	}
+	metrics.CollectedEventsTotal.WithLabelValues(env.EventType, "collected").Add(float64(len(events)))
+	logging.WithContext(ctx, c.logger).Debug("Events collected",
+		zap.String("event_type", env.EventType),
+		zap.Int("events", len(events)),
+	)
+	return nil
+}
+
+// convert converts a consumed event to warehouse events: one per view,
+// search or cart add, and one purchase per order line. IDs derive from the
+// message's position in its topic, so a redelivered message gets the same
+// ones. Events of other types, or missing their product or query, give
+// none.
+func convert(env *sharedevents.Envelope, msg kafkago.Message) ([]domain.Event, error) {
+	base := domain.Event{
+		ID:         fmt.Sprintf("%s-%d-%d", msg.Topic, msg.Partition, msg.Offset),
+		Type:       env.EventType,
+		OccurredAt: env.Timestamp.UTC(),
+		ReceivedAt: time.Now().UTC(),
+		UserID:     env.UserID,
+	}
+	if base.OccurredAt.IsZero() {
+		base.OccurredAt = msg.Time.UTC()
+	}
+
+	switch env.EventType {
+	case domain.EventProductViewed:
+		var viewed sharedevents.ProductViewed
+		if err := env.DecodeData(&viewed); err != nil {
+			return nil, err
+		}
+		base.Source = domain.SourceStorefront
+		base.ProductID = viewed.ProductID
+		if base.ProductID == "" {
+			base.ProductID = env.ProductID
+		}
+		if viewed.UserID != "" {
+			base.UserID = viewed.UserID
+		}
+		base.SessionID = viewed.SessionID
+		if base.ProductID == "" {
+			return nil, nil
+		}
+		return []domain.Event{base}, nil
+
+	case domain.EventSearchPerformed:
+		var searched sharedevents.SearchPerformed
+		if err := env.DecodeData(&searched); err != nil {
+			return nil, err
+		}
+		base.Source = domain.SourceStorefront
+		base.Query = searched.Query
+		base.Results = searched.Results
+		if searched.UserID != "" {
+			base.UserID = searched.UserID
+		}
+		base.SessionID = searched.SessionID
+		if base.Query == "" {
+			return nil, nil
+		}
+		return []domain.Event{base}, nil
+
+	case domain.EventCartItemAdded:
+		var added cartItemAdded
+		if err := json.Unmarshal(env.Data, &added); err != nil {
+			return nil, fmt.Errorf("failed to decode %s data: %w", env.EventType, err)
+		}
+		base.Source = domain.SourceCart
+		base.ProductID = added.ProductID
+		base.Quantity = added.Quantity
+		if base.ProductID == "" {
+			return nil, nil
+		}
+		return []domain.Event{base}, nil
+
+	case "order.created":
+		var order sharedevents.OrderCreated
+		if err := env.DecodeData(&order); err != nil {
+			return nil, err
+		}
+		if order.UserID != "" {
+			base.UserID = order.UserID
+		}
+		base.Type = domain.EventProductPurchased
+		base.Source = domain.SourceOrder
+		base.OrderID = env.OrderID
+
+		events := make([]domain.Event, 0, len(order.Items))
+		for i, item := range order.Items {
+			if item.ProductID == "" {
+				continue
+			}
+			event := base
+			event.ID = fmt.Sprintf("%s-%d", base.ID, i)
+			event.ProductID = item.ProductID
+			event.Quantity = item.Quantity
+			events = append(events, event)
+		}
+		return events, nil
+
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Event types collected. Views and searches come from the storefront
+// through the ingestion endpoint; cart adds and purchases from cart-service
+// and order-service events.
+const (
+	EventProductViewed    = "product.viewed"
+	EventSearchPerformed  = "search.performed"
+	EventCartItemAdded    = "cart.item_added"
+	EventProductPurchased = "product.purchased"
+)
+
+// Sources of events
+const (
+	// SourceStorefront is the ingestion endpoint
+	SourceStorefront = "storefront"
+	// SourceCart is cart-service's events
+	SourceCart = "cart-service"
+	// SourceOrder is order-service's events
+	SourceOrder = "order-service"
+)
+
+// Limits on ingested events
+const (
+	// MaxBatchEvents is the most events one ingestion request may carry
+	MaxBatchEvents = 100
+	// MaxQueryLength is the longest search query kept, in bytes
+	MaxQueryLength = 256
+	// MaxEventAge is how far in the past an event may have occurred, so
+	// replayed or buffered client events are accepted but not ancient ones
+	MaxEventAge = 7 * 24 * time.Hour
+	// MaxClockSkew is how far in the future a client's clock may be
+	MaxClockSkew = 5 * time.Minute
+)
+
+var (
+	// ErrUnsupportedEvent is returned for event types the endpoint doesn't
+	// take, e.g. cart adds, which cart-service already reports
+	ErrUnsupportedEvent = errors.New("unsupported event type")
+	// ErrInvalidEvent is returned for events missing what their type needs
+	ErrInvalidEvent = errors.New("invalid event")
+)
+
+// Event is a shopper's action, one row of the warehouse. Anonymous shoppers
+// are identified by their session.
+type Event struct {
+	// ID is unique per event and stable across redeliveries, so sinks can
+	// drop duplicates
+	ID         string    `json:"id"`
+	Type       string    `json:"event_type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	ReceivedAt time.Time `json:"received_at"`
+	Source     string    `json:"source"`
+	UserID     string    `json:"user_id,omitempty"`
+	SessionID  string    `json:"session_id,omitempty"`
+	ProductID  string    `json:"product_id,omitempty"`
+	// Query and Results are a search's
+	Query   string `json:"query,omitempty"`
+	Results int    `json:"results,omitempty"`
+	// Quantity is the units added to the cart or bought
+	Quantity int    `json:"quantity,omitempty"`
+	OrderID  string `json:"order_id,omitempty"`
+}
+
+// Day is the UTC day an event occurred on, which warehouse files and daily
+// stats are partitioned by
+func (e *Event) Day() string {
+	return e.OccurredAt.UTC().Format(DayLayout)
+}
+
+// DayLayout formats days
+const DayLayout = "2006-01-02"
+
+// ProductDailyStats are a product's events on a day
+type ProductDailyStats struct {
+	ProductID string `json:"product_id"`
+	Day       string `json:"day"`
+	Views     int64  `json:"views"`
+	CartAdds  int64  `json:"cart_adds"`
+	Purchases int64  `json:"purchases"`
+	// UnitsSold is the units of the day's purchases
+	UnitsSold int64 `json:"units_sold"`
+}
+
+// SearchCount is how often a query was searched for
+type SearchCount struct {
+	Query    string `json:"query"`
+	Searches int64  `json:"searches"`
+	// NoResults counts the searches that found nothing
+	NoResults int64 `json:"no_results"`
+}
@@ -0,0 +1,138 @@
+// Package ingest validates the storefront's behavioral events and publishes
+// them to Kafka, as shared events other services can consume too. The
+// collector reads them back from there, so a burst of traffic is absorbed
+// by Kafka rather than by the service's memory.
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	sharedevents "github.com/ecommerce-platform/shared/go/events"
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	"github.com/ecommerce/analytics-service/internal/domain"
+	"github.com/ecommerce/analytics-service/internal/metrics"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Publisher publishes storefront events: views to the view topic, which
+// recommendation-service also reads, and searches to the storefront topic
+type Publisher struct {
+	producer        *sharedkafka.Producer
+	viewTopic       string
+	storefrontTopic string
+}
+
+// NewPublisher creates a publisher writing with producer, which must not
+// have a fixed topic
+func NewPublisher(producer *sharedkafka.Producer, viewTopic, storefrontTopic string) *Publisher {
+	return &Publisher{
+		producer:        producer,
+		viewTopic:       viewTopic,
+		storefrontTopic: storefrontTopic,
+	}
+}
+
+// Validate checks a storefront event and fills in what the client may
+// leave out: when it occurred defaults to now. Only views and searches are
+// taken; cart adds and purchases come from cart-service and order-service,
+// which see every one of them.
+func Validate(event *domain.Event, now time.Time) error {
+	switch event.Type {
+	case domain.EventProductViewed:
+		event.ProductID = strings.TrimSpace(event.ProductID)
+		if event.ProductID == "" {
+			return fmt.Errorf("%w: %s needs a product_id", domain.ErrInvalidEvent, event.Type)
+		}
+	case domain.EventSearchPerformed:
+		event.Query = strings.Join(strings.Fields(event.Query), " ")
+		if event.Query == "" {
+			return fmt.Errorf("%w: %s needs a query", domain.ErrInvalidEvent, event.Type)
+		}
+		if len(event.Query) > domain.MaxQueryLength {
+			return fmt.Errorf("%w: query is longer than %d bytes", domain.ErrInvalidEvent, domain.MaxQueryLength)
+		}
+		if event.Results < 0 {
+			return fmt.Errorf("%w: results can't be negative", domain.ErrInvalidEvent)
+		}
+	default:
+		return fmt.Errorf("%w: %q", domain.ErrUnsupportedEvent, event.Type)
+	}
+
+	if event.UserID == "" && event.SessionID == "" {
+		return fmt.Errorf("%w: a session_id is needed without a token", domain.ErrInvalidEvent)
+	}
+
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = now
+	}
+	if event.OccurredAt.After(now.Add(domain.MaxClockSkew)) {
+		return fmt.Errorf("%w: occurred_at is in the future", domain.ErrInvalidEvent)
+	}
+	if event.OccurredAt.Before(now.Add(-domain.MaxEventAge)) {
+		return fmt.Errorf("%w: occurred_at is more than %s ago", domain.ErrInvalidEvent, domain.MaxEventAge)
+	}
+	event.OccurredAt = event.OccurredAt.UTC()
+	event.Source = domain.SourceStorefront
+	return nil
+}
+
+// Publish queues validated events for Kafka and returns without waiting
+// for the brokers; delivery failures are logged and counted in the shared
+// kafka_producer_messages_total
+func (p *Publisher) Publish(ctx context.Context, events []domain.Event) error {
+	messages := make([]kafkago.Message, 0, len(events))
+	for i := range events {
+		message, err := p.message(&events[i])
+		if err != nil {
+			return err
+		}
+		messages = append(messages, message)
+	}
+
+	if err := p.producer.Publish(ctx, messages...); err != nil {
+		return err
+	}
+	for _, event := range events {
+		metrics.IngestedEventsTotal.WithLabelValues(event.Type).Inc()
+	}
+	return nil
+}
+
+// message encodes an event as its shared event. Views are keyed by product
+// and searches by shopper, as the topics' other consumers expect.
+func (p *Publisher) message(event *domain.Event) (kafkago.Message, error) {
+	env := sharedevents.Envelope{Timestamp: event.OccurredAt, UserID: event.UserID}
+
+	switch event.Type {
+	case domain.EventProductViewed:
+		env.ProductID = event.ProductID
+		value, err := sharedevents.Marshal(env, &sharedevents.ProductViewed{
+			ProductID: event.ProductID,
+			UserID:    event.UserID,
+			SessionID: event.SessionID,
+		})
+		if err != nil {
+			return kafkago.Message{}, err
+		}
+		return kafkago.Message{Topic: p.viewTopic, Key: []byte(event.ProductID), Value: value}, nil
+
+	default:
+		value, err := sharedevents.Marshal(env, &sharedevents.SearchPerformed{
+			Query:     event.Query,
+			Results:   event.Results,
+			UserID:    event.UserID,
+			SessionID: event.SessionID,
+		})
+		if err != nil {
+			return kafkago.Message{}, err
+		}
+		key := event.UserID
+		if key == "" {
+			key = event.SessionID
+		}
+		return kafkago.Message{Topic: p.storefrontTopic, Key: []byte(key), Value: value}, nil
+	}
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// IngestedEventsTotal counts storefront events accepted by the
+	// ingestion endpoint and published to Kafka, by type
+	IngestedEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "analytics_ingested_events_total",
+		Help: "Storefront events accepted for publishing, per type",
+	}, []string{"event_type"})
+
+	// RejectedEventsTotal counts ingestion requests' events that were
+	// rejected by reason: invalid or unsupported
+	RejectedEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "analytics_rejected_events_total",
+		Help: "Storefront events rejected by the ingestion endpoint, per reason",
+	}, []string{"reason"})
+
+	// CollectedEventsTotal counts consumed events by type and result:
+	// collected (warehouse events buffered; an order counts one per line)
+	// or ignored
+	CollectedEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "analytics_collected_events_total",
+		Help: "Consumed events, per type and result",
+	}, []string{"event_type", "result"})
+
+	// BufferedEvents is the number of events waiting for their batch
+	BufferedEvents = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "analytics_buffered_events",
+		Help: "Events waiting to be written",
+	})
+
+	// BatchesTotal counts batches written by what flushed them: size,
+	// interval or shutdown
+	BatchesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "analytics_batches_total",
+		Help: "Batches written, per flush reason",
+	}, []string{"reason"})
+
+	// BatchSize measures the events per batch
+	BatchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "analytics_batch_size",
+		Help:    "Events per batch",
+		Buckets: prometheus.ExponentialBuckets(1, 4, 8),
+	})
+
+	// SinkEventsTotal counts events per sink by result: written, or dropped
+	// after every attempt failed
+	SinkEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "analytics_sink_events_total",
+		Help: "Events written to sinks, per sink and result",
+	}, []string{"sink", "result"})
+
+	// SinkWriteDuration measures writing a batch to a sink, retries
+	// included
+	SinkWriteDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "analytics_sink_write_seconds",
+		Help:    "Time to write a batch to a sink, including retries",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"sink"})
+)
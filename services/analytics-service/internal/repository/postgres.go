@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	shareddb "github.com/ecommerce-platform/shared/go/db"
+	"github.com/ecommerce/analytics-service/internal/domain"
+)
+
+type postgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *sql.DB) AnalyticsRepository {
+	return &postgresRepository{db: db}
+}
+
+// Ping checks that the database is reachable
+func (r *postgresRepository) Ping(ctx context.Context) error {
+	return shareddb.Check(ctx, r.db)
+}
+
+func (r *postgresRepository) ProductDailyStats(ctx context.Context, productID string, from, to time.Time) ([]domain.ProductDailyStats, error) {
+	query := `
+		SELECT TO_CHAR(day, 'YYYY-MM-DD'), views, cart_adds, purchases, units_sold
+		FROM product_daily_stats
+		WHERE product_id = $1 AND day BETWEEN $2::DATE AND $3::DATE
+		ORDER BY day
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, productID, from.Format(domain.DayLayout), to.Format(domain.DayLayout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []domain.ProductDailyStats{}
+	for rows.Next() {
+		day := domain.ProductDailyStats{ProductID: productID}
+		if err := rows.Scan(&day.Day, &day.Views, &day.CartAdds, &day.Purchases, &day.UnitsSold); err != nil {
+			return nil, fmt.Errorf("failed to scan daily stats: %w", err)
+		}
+		stats = append(stats, day)
+	}
+	return stats, rows.Err()
+}
+
+func (r *postgresRepository) TopSearches(ctx context.Context, from, to time.Time, limit int) ([]domain.SearchCount, error) {
+	query := `
+		SELECT LOWER(query), COUNT(*), COUNT(*) FILTER (WHERE results = 0)
+		FROM behavioral_events
+		WHERE event_type = $1 AND occurred_at >= $2 AND occurred_at < $3
+		GROUP BY LOWER(query)
+		ORDER BY COUNT(*) DESC, LOWER(query)
+		LIMIT $4
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, domain.EventSearchPerformed, from.UTC(), to.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query searches: %w", err)
+	}
+	defer rows.Close()
+
+	searches := []domain.SearchCount{}
+	for rows.Next() {
+		var search domain.SearchCount
+		if err := rows.Scan(&search.Query, &search.Searches, &search.NoResults); err != nil {
+			return nil, fmt.Errorf("failed to scan searches: %w", err)
+		}
+		searches = append(searches, search)
+	}
+	return searches, rows.Err()
+}
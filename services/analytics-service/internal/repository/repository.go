@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ecommerce/analytics-service/internal/domain"
+)
+
+// AnalyticsRepository reads the behavioral events the Postgres sink wrote
+type AnalyticsRepository interface {
+	// ProductDailyStats returns a product's stats for the UTC days from
+	// from to to, inclusive, oldest first; days without events are left out
+	ProductDailyStats(ctx context.Context, productID string, from, to time.Time) ([]domain.ProductDailyStats, error)
+	// TopSearches returns the queries searched most often between from and
+	// to, case-insensitively, most searched first
+	TopSearches(ctx context.Context, from, to time.Time, limit int) ([]domain.SearchCount, error)
+
+	// Ping checks that the database is reachable
+	Ping(ctx context.Context) error
+}
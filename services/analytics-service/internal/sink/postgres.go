@@ -0,0 +1,107 @@
+// Package sink holds the collector's sinks: Postgres, and Parquet files in
+// S3 for a warehouse to load
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ecommerce/analytics-service/internal/domain"
+	"github.com/lib/pq"
+)
+
+// timestampLayout formats UTC times for Postgres TIMESTAMP columns
+const timestampLayout = "2006-01-02 15:04:05.999999"
+
+// Postgres writes events to behavioral_events and adds them to
+// product_daily_stats
+type Postgres struct {
+	db *sql.DB
+}
+
+// NewPostgres creates a Postgres sink
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+// Name identifies the sink
+func (p *Postgres) Name() string {
+	return "postgres"
+}
+
+// Write inserts a batch in one statement. Events already stored are
+// skipped, and only the new ones are counted in the daily stats, so a
+// redelivered batch changes nothing.
+func (p *Postgres) Write(ctx context.Context, events []domain.Event) error {
+	n := len(events)
+	var (
+		ids         = make([]string, n)
+		types       = make([]string, n)
+		occurredAts = make([]string, n)
+		receivedAts = make([]string, n)
+		sources     = make([]string, n)
+		userIDs     = make([]string, n)
+		sessionIDs  = make([]string, n)
+		productIDs  = make([]string, n)
+		queries     = make([]string, n)
+		results     = make([]int64, n)
+		quantities  = make([]int64, n)
+		orderIDs    = make([]string, n)
+	)
+	for i, event := range events {
+		ids[i] = event.ID
+		types[i] = event.Type
+		occurredAts[i] = event.OccurredAt.UTC().Format(timestampLayout)
+		receivedAts[i] = event.ReceivedAt.UTC().Format(timestampLayout)
+		sources[i] = event.Source
+		userIDs[i] = event.UserID
+		sessionIDs[i] = event.SessionID
+		productIDs[i] = event.ProductID
+		queries[i] = event.Query
+		results[i] = int64(event.Results)
+		quantities[i] = int64(event.Quantity)
+		orderIDs[i] = event.OrderID
+	}
+
+	query := `
+		WITH inserted AS (
+			INSERT INTO behavioral_events (
+				id, event_type, occurred_at, received_at, source, user_id,
+				session_id, product_id, query, results, quantity, order_id
+			)
+			SELECT * FROM UNNEST(
+				$1::VARCHAR[], $2::VARCHAR[], $3::TIMESTAMP[], $4::TIMESTAMP[],
+				$5::VARCHAR[], $6::VARCHAR[], $7::VARCHAR[], $8::VARCHAR[],
+				$9::VARCHAR[], $10::INTEGER[], $11::INTEGER[], $12::VARCHAR[]
+			)
+			ON CONFLICT (id) DO NOTHING
+			RETURNING event_type, occurred_at, product_id, quantity
+		)
+		INSERT INTO product_daily_stats (product_id, day, views, cart_adds, purchases, units_sold)
+		SELECT product_id, occurred_at::DATE,
+			COUNT(*) FILTER (WHERE event_type = $13),
+			COUNT(*) FILTER (WHERE event_type = $14),
+			COUNT(*) FILTER (WHERE event_type = $15),
+			COALESCE(SUM(quantity) FILTER (WHERE event_type = $15), 0)
+		FROM inserted
+		WHERE product_id <> '' AND event_type IN ($13, $14, $15)
+		GROUP BY product_id, occurred_at::DATE
+		ON CONFLICT (product_id, day) DO UPDATE SET
+			views = product_daily_stats.views + EXCLUDED.views,
+			cart_adds = product_daily_stats.cart_adds + EXCLUDED.cart_adds,
+			purchases = product_daily_stats.purchases + EXCLUDED.purchases,
+			units_sold = product_daily_stats.units_sold + EXCLUDED.units_sold
+	`
+
+	_, err := p.db.ExecContext(ctx, query,
+		pq.Array(ids), pq.Array(types), pq.Array(occurredAts), pq.Array(receivedAts),
+		pq.Array(sources), pq.Array(userIDs), pq.Array(sessionIDs), pq.Array(productIDs),
+		pq.Array(queries), pq.Array(results), pq.Array(quantities), pq.Array(orderIDs),
+		domain.EventProductViewed, domain.EventCartItemAdded, domain.EventProductPurchased,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert events: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ecommerce/analytics-service/internal/domain"
+	"github.com/google/uuid"
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetEvent is a row of the Parquet files, behavioral_events' columns
+type parquetEvent struct {
+	ID         string    `parquet:"id"`
+	EventType  string    `parquet:"event_type,dict"`
+	OccurredAt time.Time `parquet:"occurred_at,timestamp(millisecond)"`
+	ReceivedAt time.Time `parquet:"received_at,timestamp(millisecond)"`
+	Source     string    `parquet:"source,dict"`
+	UserID     string    `parquet:"user_id"`
+	SessionID  string    `parquet:"session_id"`
+	ProductID  string    `parquet:"product_id,dict"`
+	Query      string    `parquet:"query"`
+	Results    int32     `parquet:"results"`
+	Quantity   int32     `parquet:"quantity"`
+	OrderID    string    `parquet:"order_id"`
+}
+
+// S3 writes each batch as Snappy-compressed Parquet files, one per UTC day
+// of the events in it, under <prefix>/event_date=<day>/, the Hive-style
+// partitioning warehouses such as Athena, BigQuery and Snowflake load by
+type S3 struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// NewS3 creates an S3 sink writing to bucket under prefix
+func NewS3(client *s3.Client, bucket, prefix string) *S3 {
+	return &S3{
+		client: client,
+		bucket: bucket,
+		prefix: prefix,
+	}
+}
+
+// Name identifies the sink
+func (s *S3) Name() string {
+	return "s3"
+}
+
+// Write uploads a batch. Files are never overwritten, so a redelivered
+// batch is written again; warehouses drop duplicates by id.
+func (s *S3) Write(ctx context.Context, events []domain.Event) error {
+	days := map[string][]parquetEvent{}
+	for _, event := range events {
+		day := event.Day()
+		days[day] = append(days[day], parquetEvent{
+			ID:         event.ID,
+			EventType:  event.Type,
+			OccurredAt: event.OccurredAt.UTC(),
+			ReceivedAt: event.ReceivedAt.UTC(),
+			Source:     event.Source,
+			UserID:     event.UserID,
+			SessionID:  event.SessionID,
+			ProductID:  event.ProductID,
+			Query:      event.Query,
+			Results:    int32(event.Results),
+			Quantity:   int32(event.Quantity),
+			OrderID:    event.OrderID,
+		})
+	}
+
+	for day, rows := range days {
+		sort.Slice(rows, func(i, j int) bool { return rows[i].OccurredAt.Before(rows[j].OccurredAt) })
+		if err := s.upload(ctx, day, rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upload writes one day's rows as a file
+func (s *S3) upload(ctx context.Context, day string, rows []parquetEvent) error {
+	var buf bytes.Buffer
+	writer := parquet.NewGenericWriter[parquetEvent](&buf, parquet.Compression(&parquet.Snappy))
+	if _, err := writer.Write(rows); err != nil {
+		return fmt.Errorf("failed to encode events: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to encode events: %w", err)
+	}
+
+	// Names sort by when they were written
+	name := fmt.Sprintf("%s-%s.parquet", time.Now().UTC().Format("20060102T150405Z"), uuid.New().String())
+	key := path.Join(s.prefix, "event_date="+day, name)
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(buf.Bytes()),
+		ContentType: aws.String("application/vnd.apache.parquet"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	return nil
+}
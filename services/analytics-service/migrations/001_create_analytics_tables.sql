@@ -0,0 +1,37 @@
+-- Behavioral events, one row per view, search, cart add or purchased order
+-- line. Rows are only appended, in roughly occurred_at order, which the
+-- BRIN index suits.
+CREATE TABLE IF NOT EXISTS behavioral_events (
+    -- <topic>-<partition>-<offset>, plus -<line> for purchases, so a
+    -- redelivered event is dropped
+    id VARCHAR(300) PRIMARY KEY,
+    event_type VARCHAR(50) NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    received_at TIMESTAMP NOT NULL,
+    source VARCHAR(50) NOT NULL,
+    user_id VARCHAR(255) NOT NULL DEFAULT '',
+    session_id VARCHAR(255) NOT NULL DEFAULT '',
+    product_id VARCHAR(255) NOT NULL DEFAULT '',
+    query VARCHAR(256) NOT NULL DEFAULT '',
+    results INTEGER NOT NULL DEFAULT 0,
+    quantity INTEGER NOT NULL DEFAULT 0,
+    order_id VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_behavioral_events_occurred_at ON behavioral_events USING BRIN (occurred_at);
+CREATE INDEX IF NOT EXISTS idx_behavioral_events_type_occurred_at ON behavioral_events(event_type, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_behavioral_events_product ON behavioral_events(product_id, occurred_at) WHERE product_id <> '';
+
+-- Each product's events per UTC day, kept up to date as events are
+-- written, for demand forecasting
+CREATE TABLE IF NOT EXISTS product_daily_stats (
+    product_id VARCHAR(255) NOT NULL,
+    day DATE NOT NULL,
+    views BIGINT NOT NULL DEFAULT 0,
+    cart_adds BIGINT NOT NULL DEFAULT 0,
+    purchases BIGINT NOT NULL DEFAULT 0,
+    units_sold BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (product_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_daily_stats_day ON product_daily_stats(day);
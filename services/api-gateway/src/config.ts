@@ -9,4 +9,27 @@ export const config = {
   otlpEndpoint: process.env.OTEL_EXPORTER_OTLP_ENDPOINT || 'http://otel-collector:4317',
   redisUrl: process.env.REDIS_URL || 'redis://:dev_password@redis:6379/0',
   corsOrigins: (process.env.CORS_ORIGIN || 'http://localhost:3000,http://localhost:3001').split(','),
+  // Storefront hosts and the tenant each serves, e.g.
+  // "shop.acme.com=acme,shop.globex.com=globex"
+  tenants: parseTenants(process.env.TENANTS || ''),
+  // Tenant of hosts not in TENANTS; empty rejects them
+  defaultTenant: process.env.DEFAULT_TENANT ?? 'default',
+  // Client certificate presented to https service targets, and the CA their
+  // certificates are verified against
+  mtls: {
+    certFile: process.env.MTLS_CERT_FILE || '',
+    keyFile: process.env.MTLS_KEY_FILE || '',
+    caFile: process.env.MTLS_CA_FILE || '',
+  },
 };
+
+function parseTenants(value: string): Map<string, string> {
+  const tenants = new Map<string, string>();
+  for (const entry of value.split(',')) {
+    const [host, tenant] = entry.split('=').map((part) => part.trim());
+    if (host && tenant) {
+      tenants.set(host.toLowerCase(), tenant);
+    }
+  }
+  return tenants;
+}
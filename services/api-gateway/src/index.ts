@@ -10,6 +10,7 @@ import { correlationIdMiddleware } from '../../../shared/nodejs/middleware/corre
 import { initTelemetry } from '../../../shared/nodejs/otel';
 import { healthRouter } from './routes/health';
 import { setupProxies } from './routes/proxies';
+import { tenantMiddleware } from './tenant';
 
 // Initialize OpenTelemetry
 initTelemetry({
@@ -48,6 +49,9 @@ app.use(express.urlencoded({ extended: true, limit: '10mb' }));
 // Health check routes
 app.use('/health', healthRouter);
 
+// Tenant of the storefront host, forwarded to services
+app.use(tenantMiddleware);
+
 // Setup service proxies
 setupProxies(app);
 
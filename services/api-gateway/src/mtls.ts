@@ -0,0 +1,67 @@
+// Mutual TLS towards services: https targets are called presenting the
+// gateway's certificate, which services in strict mode require
+import fs from 'fs';
+import https from 'https';
+import net from 'net';
+import tls from 'tls';
+import { config } from './config';
+import { logger } from './logger';
+
+// Lets a rotation finish writing every file before they are read
+const RELOAD_DELAY_MS = 500;
+
+function readFiles() {
+  return {
+    cert: fs.readFileSync(config.mtls.certFile),
+    key: fs.readFileSync(config.mtls.keyFile),
+    ca: fs.readFileSync(config.mtls.caFile),
+  };
+}
+
+// SPIFFE SVIDs have no DNS names, so the host name is only checked for
+// certificates that have some; the chain is always verified against the CA
+function checkServerIdentity(host: string, cert: tls.PeerCertificate): Error | undefined {
+  if (cert.subjectaltname?.includes('DNS:') || net.isIP(host)) {
+    return tls.checkServerIdentity(host, cert);
+  }
+  return undefined;
+}
+
+function createAgent(): https.Agent | undefined {
+  const { certFile, keyFile, caFile } = config.mtls;
+  if (!certFile && !keyFile && !caFile) {
+    return undefined;
+  }
+  if (!certFile || !keyFile || !caFile) {
+    throw new Error('MTLS_CERT_FILE, MTLS_KEY_FILE and MTLS_CA_FILE must be set together');
+  }
+
+  const agent = new https.Agent({ ...readFiles(), keepAlive: true, checkServerIdentity });
+
+  // Rotated certificates are used for new connections; kept-alive ones keep
+  // the certificate they were made with until they close
+  let timer: NodeJS.Timeout | undefined;
+  const reload = () => {
+    clearTimeout(timer);
+    timer = setTimeout(() => {
+      try {
+        Object.assign(agent.options, readFiles());
+        logger.info('Reloaded mTLS certificates');
+      } catch (error) {
+        logger.error('Failed to reload mTLS certificates, keeping the previous ones', {
+          error: error instanceof Error ? error.message : error,
+        });
+      }
+    }, RELOAD_DELAY_MS);
+  };
+  // Polling, as rotations replace the files, or the symlinks to them
+  for (const file of [certFile, keyFile, caFile]) {
+    fs.watchFile(file, { interval: 5000 }, reload);
+  }
+
+  logger.info('mTLS enabled for https service targets');
+  return agent;
+}
+
+/** Agent for https service targets, or undefined when mTLS isn't configured */
+export const mtlsAgent = createAgent();
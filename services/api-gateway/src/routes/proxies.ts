@@ -2,6 +2,8 @@
 import { Express } from 'express';
 import { createProxyMiddleware, Options } from 'http-proxy-middleware';
 import logger from '../logger';
+import { mtlsAgent } from '../mtls';
+import { requireDefaultTenant } from '../tenant';
 
 // Service URLs from environment or defaults
 const CATALOG_SERVICE_URL = process.env.CATALOG_SERVICE_URL || 'http://localhost:8000';
@@ -11,41 +13,74 @@ const ORDER_SERVICE_URL = process.env.ORDER_SERVICE_URL || 'http://localhost:300
 const PAYMENT_SERVICE_URL = process.env.PAYMENT_SERVICE_URL || 'http://localhost:8003';
 const USER_SERVICE_URL = process.env.USER_SERVICE_URL || 'http://localhost:8084';
 const NOTIFICATION_SERVICE_URL = process.env.NOTIFICATION_SERVICE_URL || 'http://localhost:8085';
+const SEARCH_SERVICE_URL = process.env.SEARCH_SERVICE_URL || 'http://localhost:8086';
+const REVIEW_SERVICE_URL = process.env.REVIEW_SERVICE_URL || 'http://localhost:8087';
+const RECOMMENDATION_SERVICE_URL = process.env.RECOMMENDATION_SERVICE_URL || 'http://localhost:8088';
+const PRICING_SERVICE_URL = process.env.PRICING_SERVICE_URL || 'http://localhost:8089';
+const COUPON_SERVICE_URL = process.env.COUPON_SERVICE_URL || 'http://localhost:8090';
+const WISHLIST_SERVICE_URL = process.env.WISHLIST_SERVICE_URL || 'http://localhost:8091';
+const RETURNS_SERVICE_URL = process.env.RETURNS_SERVICE_URL || 'http://localhost:8092';
+const TAX_SERVICE_URL = process.env.TAX_SERVICE_URL || 'http://localhost:8093';
+const ANALYTICS_SERVICE_URL = process.env.ANALYTICS_SERVICE_URL || 'http://localhost:8094';
+const ADMIN_API_URL = process.env.ADMIN_API_URL || 'http://localhost:8095';
+const AVAILABILITY_SERVICE_URL = process.env.AVAILABILITY_SERVICE_URL || 'http://localhost:8096';
+const MEDIA_SERVICE_URL = process.env.MEDIA_SERVICE_URL || 'http://localhost:8098';
+const GIFTCARD_SERVICE_URL = process.env.GIFTCARD_SERVICE_URL || 'http://localhost:8099';
+const SUBSCRIPTION_SERVICE_URL = process.env.SUBSCRIPTION_SERVICE_URL || 'http://localhost:8100';
 
 interface ProxyConfig {
   path: string;
   target: string;
   pathRewrite?: { [key: string]: string };
+  // The service doesn't isolate tenants' data, so only the default tenant
+  // is proxied to it
+  defaultTenantOnly?: boolean;
 }
 
 const proxyConfigs: ProxyConfig[] = [
   {
     path: '/api/v1/products',
     target: CATALOG_SERVICE_URL,
+    defaultTenantOnly: true,
   },
   {
     path: '/api/v1/categories',
     target: CATALOG_SERVICE_URL,
+    defaultTenantOnly: true,
+  },
+  // Storefront search; registered before the catalog's /api/v1/search,
+  // which would otherwise match it
+  {
+    path: '/api/v1/search/products',
+    target: SEARCH_SERVICE_URL,
   },
   {
     path: '/api/v1/search',
     target: CATALOG_SERVICE_URL,
+    defaultTenantOnly: true,
   },
   {
     path: '/api/v1/inventory',
     target: INVENTORY_SERVICE_URL,
   },
+  {
+    path: '/api/v1/purchase-orders',
+    target: INVENTORY_SERVICE_URL,
+  },
   {
     path: '/api/v1/cart',
     target: CART_SERVICE_URL,
+    defaultTenantOnly: true,
   },
   {
     path: '/api/v1/orders',
     target: ORDER_SERVICE_URL,
+    defaultTenantOnly: true,
   },
   {
     path: '/api/v1/payments',
     target: PAYMENT_SERVICE_URL,
+    defaultTenantOnly: true,
   },
   {
     path: '/api/v1/auth',
@@ -59,14 +94,67 @@ const proxyConfigs: ProxyConfig[] = [
     path: '/api/v1/notifications',
     target: NOTIFICATION_SERVICE_URL,
   },
+  {
+    path: '/api/v1/reviews',
+    target: REVIEW_SERVICE_URL,
+  },
+  {
+    path: '/api/v1/recommendations',
+    target: RECOMMENDATION_SERVICE_URL,
+  },
+  {
+    path: '/api/v1/pricing',
+    target: PRICING_SERVICE_URL,
+  },
+  {
+    path: '/api/v1/coupons',
+    target: COUPON_SERVICE_URL,
+  },
+  {
+    path: '/api/v1/wishlists',
+    target: WISHLIST_SERVICE_URL,
+  },
+  {
+    path: '/api/v1/returns',
+    target: RETURNS_SERVICE_URL,
+  },
+  {
+    path: '/api/v1/tax',
+    target: TAX_SERVICE_URL,
+  },
+  {
+    path: '/api/v1/analytics',
+    target: ANALYTICS_SERVICE_URL,
+  },
+  {
+    path: '/api/v1/backoffice',
+    target: ADMIN_API_URL,
+  },
+  {
+    path: '/api/v1/availability',
+    target: AVAILABILITY_SERVICE_URL,
+  },
+  {
+    path: '/api/v1/media',
+    target: MEDIA_SERVICE_URL,
+  },
+  {
+    path: '/api/v1/gift-cards',
+    target: GIFTCARD_SERVICE_URL,
+  },
+  {
+    path: '/api/v1/subscriptions',
+    target: SUBSCRIPTION_SERVICE_URL,
+  },
 ];
 
 export function setupProxies(app: Express) {
-  proxyConfigs.forEach(({ path, target, pathRewrite }) => {
+  proxyConfigs.forEach(({ path, target, pathRewrite, defaultTenantOnly }) => {
     const proxyOptions: Options = {
       target,
       changeOrigin: true,
       pathRewrite: pathRewrite || undefined,
+      agent: target.startsWith('https:') ? mtlsAgent : undefined,
       on: {
         proxyReq: (proxyReq, req, res) => {
           logger.info('Proxying request', {
@@ -98,7 +186,11 @@ export function setupProxies(app: Express) {
       },
     };
 
-    app.use(path, createProxyMiddleware(proxyOptions));
+    if (defaultTenantOnly) {
+      app.use(path, requireDefaultTenant, createProxyMiddleware(proxyOptions));
+    } else {
+      app.use(path, createProxyMiddleware(proxyOptions));
+    }
 
     logger.info('Proxy route configured', {
       path,
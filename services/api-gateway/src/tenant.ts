@@ -0,0 +1,66 @@
+// Tenant resolution: each storefront host belongs to a tenant, which
+// backend services isolate data by
+import { Request, Response, NextFunction } from 'express';
+import { config } from './config';
+import { logger } from './logger';
+
+export const TENANT_HEADER = 'X-Tenant-ID';
+
+// Tenant of single-shop deployments, the only one services that don't
+// isolate tenants' data serve
+export const DEFAULT_TENANT = 'default';
+
+const TENANT_ID_PATTERN = /^[a-z0-9][a-z0-9-]{0,62}$/;
+
+declare global {
+  namespace Express {
+    interface Request {
+      tenantId?: string;
+    }
+  }
+}
+
+for (const [host, tenant] of config.tenants) {
+  if (!TENANT_ID_PATTERN.test(tenant)) {
+    throw new Error(`Invalid tenant ID "${tenant}" for host ${host}`);
+  }
+}
+
+/**
+ * Resolves the request's tenant from its host and forwards it to services
+ * in X-Tenant-ID, replacing any value the client sent so clients can't
+ * pick another storefront's tenant
+ */
+export function tenantMiddleware(req: Request, res: Response, next: NextFunction): void {
+  const tenant = config.tenants.get(req.hostname.toLowerCase()) || config.defaultTenant;
+  if (!tenant) {
+    logger.warn('Request for unknown storefront host', { host: req.hostname, correlationId: req.correlationId });
+    res.status(404).json({
+      error: 'Not Found',
+      message: `Unknown storefront ${req.hostname}`,
+      correlationId: req.correlationId,
+    });
+    return;
+  }
+
+  req.tenantId = tenant;
+  req.headers[TENANT_HEADER.toLowerCase()] = tenant;
+  next();
+}
+
+/**
+ * Rejects requests for any tenant but the default one, for routes to
+ * services that don't isolate tenants' data, so other storefronts can't
+ * read or change the default tenant's
+ */
+export function requireDefaultTenant(req: Request, res: Response, next: NextFunction): void {
+  if (req.tenantId !== DEFAULT_TENANT) {
+    res.status(403).json({
+      error: 'Forbidden',
+      message: 'Not available for this storefront',
+      correlationId: req.correlationId,
+    });
+    return;
+  }
+  next();
+}
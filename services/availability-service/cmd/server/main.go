@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	sharedconfig "github.com/ecommerce-platform/shared/go/config"
+	shareddb "github.com/ecommerce-platform/shared/go/db"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/httpmetrics"
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce-platform/shared/go/mtls"
+	"github.com/ecommerce/availability-service/internal/api"
+	"github.com/ecommerce/availability-service/internal/config"
+	"github.com/ecommerce/availability-service/internal/middleware"
+	"github.com/ecommerce/availability-service/internal/projector"
+	"github.com/ecommerce/availability-service/internal/repository"
+	"github.com/ecommerce/availability-service/internal/seed"
+	"github.com/ecommerce/availability-service/migrations"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.uber.org/zap"
+)
+
+func main() {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Initialize logger
+	log, err := logging.New(logging.Config{
+		ServiceName: "availability-service",
+		Environment: cfg.Environment,
+		Level:       os.Getenv("LOG_LEVEL"),
+	})
+	if err != nil {
+		fmt.Printf("Failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer log.Sync()
+
+	log.Info("Starting Availability Service",
+		zap.String("environment", cfg.Environment),
+		zap.Int("port", cfg.Port),
+	)
+	log.Info("Configuration loaded", zap.Any("config", sharedconfig.Redacted(cfg)))
+
+	// Initialize mTLS
+	serviceTLS, err := mtls.New(cfg.TLS, log)
+	if err != nil {
+		log.Fatal("Failed to initialize mTLS", zap.Error(err))
+	}
+	defer serviceTLS.Close()
+
+	// Initialize OpenTelemetry
+	cleanup, err := initTelemetry(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize telemetry", zap.Error(err))
+	}
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Initialize PostgreSQL
+	db, err := shareddb.Open(ctx, shareddb.Config{
+		DSN:          cfg.DatabaseURL,
+		Name:         "availability",
+		MaxOpenConns: cfg.DBMaxOpenConns,
+		MaxIdleConns: cfg.DBMaxIdleConns,
+	}, log)
+	if err != nil {
+		log.Fatal("Failed to connect to database", zap.Error(err))
+	}
+	defer db.Close()
+
+	if err := shareddb.Migrate(ctx, db, migrations.FS, log); err != nil {
+		log.Fatal("Failed to migrate database", zap.Error(err))
+	}
+
+	// Initialize Redis
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddr,
+		Password: cfg.RedisPassword,
+		DB:       cfg.RedisDB,
+	})
+	defer redisClient.Close()
+
+	if err := redisClient.Ping(ctx).Err(); err != nil {
+		log.Fatal("Failed to connect to Redis", zap.Error(err))
+	}
+	log.Info("Redis connected")
+
+	// Initialize repositories
+	availabilityRepo := repository.NewPostgresRepository(db)
+	cacheRepo := repository.NewRedisRepository(redisClient)
+	cacheTTL := time.Duration(cfg.CacheTTL) * time.Second
+
+	// Project inventory-service's stock events. Events are keyed by
+	// product, so each product's changes apply in order.
+	availabilityProjector := projector.New(availabilityRepo, cacheRepo, cacheTTL, log)
+
+	brokers := strings.Split(cfg.KafkaBrokers, ",")
+	dlqProducer := sharedkafka.NewProducer(sharedkafka.ProducerConfig{Brokers: brokers}, log)
+	defer dlqProducer.Close()
+
+	inventoryConsumer := sharedkafka.NewConsumer(sharedkafka.ConsumerConfig{
+		Brokers:     brokers,
+		GroupID:     cfg.KafkaGroupID,
+		Topics:      []string{cfg.InventoryEventsTopic},
+		Concurrency: 4,
+		MaxAttempts: 3,
+		DeadLetters: sharedkafka.NewTopicDeadLetters(dlqProducer),
+		Tracer:      otel.Tracer("availability-service"),
+	}, availabilityProjector.Handle, log)
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	consumerDone := make(chan struct{})
+	go func() {
+		defer close(consumerDone)
+		if err := inventoryConsumer.Run(backgroundCtx); err != nil {
+			log.Error("Inventory event consumer stopped", zap.Error(err))
+		}
+	}()
+
+	// A tenant's new projection starts from inventory-service's stock
+	// records
+	if cfg.InventoryServiceURL != "" {
+		seeder := seed.New(cfg.InventoryServiceURL, serviceTLS.Transport(), availabilityProjector)
+		go func() {
+			for _, tenant := range cfg.SeedTenants {
+				seedIfEmpty(sharedauth.WithTenant(backgroundCtx, tenant), availabilityRepo, seeder, log)
+			}
+		}()
+	}
+
+	// Initialize handler
+	handler := api.NewHandler(availabilityRepo, cacheRepo, cacheTTL, log)
+
+	// Setup Gin
+	if cfg.Environment == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(middleware.CorrelationID())
+	router.Use(otelgin.Middleware("availability-service"))
+	router.Use(httpmetrics.Middleware("availability-service"))
+	router.Use(apperrors.Middleware(log))
+
+	// Health check
+	router.GET("/health", handler.HealthCheck)
+
+	// Metrics for Prometheus
+	router.GET("/metrics", gin.WrapH(httpmetrics.Handler()))
+
+	// API routes; availability is public, like the storefront showing it,
+	// and scoped to the tenant the gateway resolved
+	v1 := router.Group("/api/v1")
+	v1.Use(sharedauth.ResolveTenant())
+	{
+		v1.GET("/availability", handler.ListAvailability)
+		v1.GET("/availability/:productId", handler.GetAvailability)
+	}
+
+	// Create HTTP server
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Port),
+		Handler:      router,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// Start server in goroutine
+	go func() {
+		log.Info("Server starting", zap.Int("port", cfg.Port))
+		if err := serviceTLS.ListenAndServe(srv); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Server failed to start", zap.Error(err))
+		}
+	}()
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Info("Shutting down server...")
+
+	// Graceful shutdown
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Fatal("Server forced to shutdown", zap.Error(err))
+	}
+
+	stopBackground()
+	select {
+	case <-consumerDone:
+	case <-shutdownCtx.Done():
+		log.Warn("Inventory event consumer didn't stop in time")
+	}
+
+	log.Info("Server shutdown complete")
+}
+
+// seedIfEmpty seeds the projection of ctx's tenant when it has no products
+// yet. A failed seed is logged; restarting the service retries it while
+// the projection is still empty.
+func seedIfEmpty(ctx context.Context, repo repository.AvailabilityRepository, seeder *seed.Seeder, log *zap.Logger) {
+	log = log.With(zap.String("tenant_id", sharedauth.TenantFromContext(ctx)))
+	count, err := repo.Count(ctx)
+	if err != nil {
+		log.Error("Failed to count projected products", zap.Error(err))
+		return
+	}
+	if count > 0 {
+		return
+	}
+
+	log.Info("Projection is empty, seeding it from inventory-service")
+	seeded, err := seeder.Run(ctx)
+	if err != nil {
+		log.Error("Failed to seed projection", zap.Int("seeded", seeded), zap.Error(err))
+		return
+	}
+	log.Info("Projection seeded", zap.Int("products", seeded))
+}
+
+func initTelemetry(cfg *config.Config) (func(), error) {
+	ctx := context.Background()
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String("availability-service"),
+			semconv.ServiceVersionKey.String("1.0.0"),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	traceExporter, err := otlptracegrpc.New(ctx,
+		otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint),
+		otlptracegrpc.WithInsecure(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	bsp := sdktrace.NewBatchSpanProcessor(traceExporter)
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithResource(res),
+		sdktrace.WithSpanProcessor(bsp),
+	)
+
+	otel.SetTracerProvider(tracerProvider)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			fmt.Printf("Failed to shutdown tracer provider: %v\n", err)
+		}
+	}, nil
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/availability-service/internal/domain"
+	"github.com/ecommerce/availability-service/internal/metrics"
+	"github.com/ecommerce/availability-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// maxProducts is the most products one batch lookup may ask for
+const maxProducts = 100
+
+// Handler serves availability lookups
+type Handler struct {
+	repo     repository.AvailabilityRepository
+	cache    repository.CacheRepository
+	cacheTTL time.Duration
+	logger   *zap.Logger
+}
+
+// NewHandler creates a new handler
+func NewHandler(repo repository.AvailabilityRepository, cache repository.CacheRepository, cacheTTL time.Duration, logger *zap.Logger) *Handler {
+	return &Handler{
+		repo:     repo,
+		cache:    cache,
+		cacheTTL: cacheTTL,
+		logger:   logger,
+	}
+}
+
+// GetAvailability returns a product's availability
+// GET /api/v1/availability/:productId
+func (h *Handler) GetAvailability(c *gin.Context) {
+	productID := c.Param("productId")
+
+	availabilities, err := h.lookup(c.Request.Context(), []string{productID})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get availability"))
+		return
+	}
+
+	availability, ok := availabilities[productID]
+	if !ok {
+		apperrors.Abort(c, apperrors.NewNotFound("Availability"))
+		return
+	}
+	c.JSON(http.StatusOK, availability)
+}
+
+// ListAvailability returns the availability of up to 100 products, e.g.
+// for a listing page; products without stock records are left out
+// GET /api/v1/availability?product_ids=a,b
+func (h *Handler) ListAvailability(c *gin.Context) {
+	productIDs := parseProductIDs(c.Query("product_ids"))
+	if len(productIDs) == 0 {
+		apperrors.Abort(c, apperrors.NewBadRequest("product_ids is required"))
+		return
+	}
+	if len(productIDs) > maxProducts {
+		apperrors.Abort(c, apperrors.NewBadRequest("At most 100 product_ids can be looked up at once"))
+		return
+	}
+
+	availabilities, err := h.lookup(c.Request.Context(), productIDs)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get availability"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": availabilities})
+}
+
+// lookup reads products' availability from the cache, falling back to the
+// projection for misses and caching what it finds there. An unreachable
+// cache only slows lookups down.
+func (h *Handler) lookup(ctx context.Context, productIDs []string) (map[string]*domain.Availability, error) {
+	availabilities, err := h.cache.GetMany(ctx, productIDs)
+	if err != nil {
+		logging.WithContext(ctx, h.logger).Warn("Failed to read cached availability", zap.Error(err))
+		availabilities = map[string]*domain.Availability{}
+	}
+	metrics.LookupsTotal.WithLabelValues("cache").Add(float64(len(availabilities)))
+
+	var missed []string
+	for _, productID := range productIDs {
+		if _, ok := availabilities[productID]; !ok {
+			missed = append(missed, productID)
+		}
+	}
+	if len(missed) == 0 {
+		return availabilities, nil
+	}
+
+	stored, err := h.repo.GetMany(ctx, missed)
+	if err != nil {
+		return nil, err
+	}
+	metrics.LookupsTotal.WithLabelValues("database").Add(float64(len(stored)))
+	metrics.LookupsTotal.WithLabelValues("missing").Add(float64(len(missed) - len(stored)))
+
+	refill := make([]*domain.Availability, 0, len(stored))
+	for productID, availability := range stored {
+		availabilities[productID] = availability
+		refill = append(refill, availability)
+	}
+	if len(refill) > 0 {
+		if err := h.cache.Set(ctx, refill, h.cacheTTL); err != nil {
+			logging.WithContext(ctx, h.logger).Warn("Failed to cache availability", zap.Error(err))
+		}
+	}
+	return availabilities, nil
+}
+
+// HealthCheck reports whether the database and Redis are reachable
+func (h *Handler) HealthCheck(c *gin.Context) {
+	ctx := c.Request.Context()
+	if err := h.repo.Ping(ctx); err != nil {
+		h.logger.Warn("Health check failed", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unhealthy",
+			"service": "availability-service",
+			"version": "1.0.0",
+			"error":   "database unavailable",
+		})
+		return
+	}
+
+	cache := "healthy"
+	if err := h.cache.Ping(ctx); err != nil {
+		h.logger.Warn("Redis unreachable", zap.Error(err))
+		cache = "unavailable"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "healthy",
+		"service": "availability-service",
+		"version": "1.0.0",
+		"cache":   cache,
+	})
+}
+
+// parseProductIDs splits a comma-separated list, dropping blanks and
+// repeats
+func parseProductIDs(list string) []string {
+	seen := map[string]bool{}
+	var productIDs []string
+	for _, productID := range strings.Split(list, ",") {
+		productID = strings.TrimSpace(productID)
+		if productID == "" || seen[productID] {
+			continue
+		}
+		seen[productID] = true
+		productIDs = append(productIDs, productID)
+	}
+	return productIDs
+}
@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// Availability is the read model of a product's stock: what inventory-service
+// last reported of it, for answering "is it in stock?" without asking
+// inventory-service. Products are a tenant's: two tenants can have the
+// same product IDs.
+type Availability struct {
+	TenantID  string `json:"-"`
+	ProductID string `json:"product_id"`
+	// ItemID is the product's stock record in inventory-service
+	ItemID            string `json:"item_id,omitempty"`
+	Quantity          int    `json:"quantity"`
+	ReservedQuantity  int    `json:"reserved_quantity"`
+	AvailableQuantity int    `json:"available_quantity"`
+	InStock           bool   `json:"in_stock"`
+	// AsOf is when the change it reflects happened in inventory-service;
+	// older changes arriving late don't overwrite it
+	AsOf      time.Time `json:"as_of"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewAvailability is a tenant's product's availability as of a stock
+// change. Events carry two of the three quantities at least; the third
+// follows from quantity = reserved + available.
+func NewAvailability(tenantID, productID, itemID string, quantity, reserved, available int, asOf time.Time) *Availability {
+	return &Availability{
+		TenantID:          tenantID,
+		ProductID:         productID,
+		ItemID:            itemID,
+		Quantity:          quantity,
+		ReservedQuantity:  reserved,
+		AvailableQuantity: available,
+		InStock:           available > 0,
+		AsOf:              asOf.UTC(),
+	}
+}
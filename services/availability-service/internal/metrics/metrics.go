@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// EventsTotal counts inventory events by type and result: applied,
+	// stale (a later change was already applied), skipped (not a stock
+	// change) or failed
+	EventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "availability_events_total",
+		Help: "Inventory events projected, per event type and result",
+	}, []string{"event_type", "result"})
+
+	// ProjectionLag is how long after a stock change in inventory-service
+	// it was applied to the projection
+	ProjectionLag = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "availability_projection_lag_seconds",
+		Help:    "Delay between a stock change and its projection",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	})
+
+	// LookupsTotal counts products looked up by where they were found:
+	// cache, database or missing
+	LookupsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "availability_lookups_total",
+		Help: "Products looked up, per source",
+	}, []string{"source"})
+)
@@ -0,0 +1,165 @@
+// Package projector builds the availability read model from
+// inventory-service's stock events
+package projector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	sharedevents "github.com/ecommerce-platform/shared/go/events"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/availability-service/internal/domain"
+	"github.com/ecommerce/availability-service/internal/metrics"
+	"github.com/ecommerce/availability-service/internal/repository"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// errSkipped marks events that don't change stock
+var errSkipped = errors.New("event doesn't change stock")
+
+// ErrStale is returned for changes older than the projected one
+var ErrStale = errors.New("a later change is already projected")
+
+// Projector applies stock events to the projection, and writes the result
+// through to the cache
+type Projector struct {
+	repo     repository.AvailabilityRepository
+	cache    repository.CacheRepository
+	cacheTTL time.Duration
+	logger   *zap.Logger
+}
+
+// New creates a projector writing to repo and cache
+func New(repo repository.AvailabilityRepository, cache repository.CacheRepository, cacheTTL time.Duration, logger *zap.Logger) *Projector {
+	return &Projector{repo: repo, cache: cache, cacheTTL: cacheTTL, logger: logger}
+}
+
+// Handle applies an inventory event. It is a sharedkafka.Handler; failures
+// are retried and then dead-lettered.
+func (p *Projector) Handle(ctx context.Context, msg kafkago.Message) error {
+	env, err := sharedevents.Decode(msg.Value)
+	if err != nil {
+		metrics.EventsTotal.WithLabelValues("unknown", "failed").Inc()
+		return err
+	}
+
+	availability, err := availabilityOf(env)
+	if errors.Is(err, errSkipped) {
+		metrics.EventsTotal.WithLabelValues(env.EventType, "skipped").Inc()
+		return nil
+	}
+	if err == nil {
+		err = p.Apply(ctx, availability)
+	}
+	switch {
+	case errors.Is(err, ErrStale):
+		metrics.EventsTotal.WithLabelValues(env.EventType, "stale").Inc()
+		return nil
+	case err != nil:
+		metrics.EventsTotal.WithLabelValues(env.EventType, "failed").Inc()
+		logging.WithContext(ctx, p.logger).Warn("Failed to project inventory event",
+			zap.String("event_type", env.EventType),
+			zap.String("tenant_id", tenantOf(env)),
+			zap.String("product_id", env.ProductID),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	metrics.EventsTotal.WithLabelValues(env.EventType, "applied").Inc()
+	metrics.ProjectionLag.Observe(time.Since(env.Timestamp).Seconds())
+	return nil
+}
+
+// Apply stores a product's availability and caches it, or returns
+// ErrStale if a later change is already stored. A failure to cache is
+// returned, so the event is retried rather than leaving the cache behind.
+func (p *Projector) Apply(ctx context.Context, availability *domain.Availability) error {
+	applied, err := p.repo.Apply(ctx, availability)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return ErrStale
+	}
+	return p.cache.Set(ctx, []*domain.Availability{availability}, p.cacheTTL)
+}
+
+// availabilityOf reads a product's availability from a stock event; other
+// events are errSkipped
+func availabilityOf(env *sharedevents.Envelope) (*domain.Availability, error) {
+	var availability *domain.Availability
+	switch env.EventType {
+	case "inventory.created":
+		var e sharedevents.InventoryCreated
+		if err := env.DecodeData(&e); err != nil {
+			return nil, err
+		}
+		availability = domain.NewAvailability(tenantOf(env), productIDOf(env, e.ProductID), e.ID,
+			e.Quantity, e.Quantity-e.AvailableQuantity, e.AvailableQuantity, env.Timestamp)
+	case "inventory.updated":
+		var e sharedevents.InventoryUpdated
+		if err := env.DecodeData(&e); err != nil {
+			return nil, err
+		}
+		availability = domain.NewAvailability(tenantOf(env), productIDOf(env, e.ProductID), e.ID,
+			e.Quantity, e.ReservedQuantity, e.AvailableQuantity, env.Timestamp)
+	case "inventory.reserved":
+		var e sharedevents.InventoryReserved
+		if err := env.DecodeData(&e); err != nil {
+			return nil, err
+		}
+		availability = domain.NewAvailability(tenantOf(env), productIDOf(env, e.ProductID), "",
+			e.ReservedQuantity+e.AvailableQuantity, e.ReservedQuantity, e.AvailableQuantity, env.Timestamp)
+	case "inventory.reservation_released":
+		var e sharedevents.ReservationReleased
+		if err := env.DecodeData(&e); err != nil {
+			return nil, err
+		}
+		availability = domain.NewAvailability(tenantOf(env), productIDOf(env, e.ProductID), "",
+			e.ReservedQuantity+e.AvailableQuantity, e.ReservedQuantity, e.AvailableQuantity, env.Timestamp)
+	case "inventory.reservation_expired":
+		var e sharedevents.ReservationExpired
+		if err := env.DecodeData(&e); err != nil {
+			return nil, err
+		}
+		availability = domain.NewAvailability(tenantOf(env), productIDOf(env, e.ProductID), "",
+			e.ReservedQuantity+e.AvailableQuantity, e.ReservedQuantity, e.AvailableQuantity, env.Timestamp)
+	case "inventory.adjusted":
+		var e sharedevents.InventoryAdjusted
+		if err := env.DecodeData(&e); err != nil {
+			return nil, err
+		}
+		availability = domain.NewAvailability(tenantOf(env), productIDOf(env, e.ProductID), "",
+			e.NewQuantity, e.NewQuantity-e.AvailableQuantity, e.AvailableQuantity, env.Timestamp)
+	default:
+		return nil, errSkipped
+	}
+
+	if availability.ProductID == "" {
+		return nil, fmt.Errorf("%s event has no product_id", env.EventType)
+	}
+	return availability, nil
+}
+
+// tenantOf is the tenant an event belongs to; events without one belong to
+// the default tenant
+func tenantOf(env *sharedevents.Envelope) string {
+	if env.TenantID != "" {
+		return env.TenantID
+	}
+	return sharedauth.DefaultTenant
+}
+
+// productIDOf prefers the envelope's product ID, which every inventory
+// event sets, over the data's
+func productIDOf(env *sharedevents.Envelope, dataID string) string {
+	if env.ProductID != "" {
+		return env.ProductID
+	}
+	return dataID
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	shareddb "github.com/ecommerce-platform/shared/go/db"
+	"github.com/ecommerce/availability-service/internal/domain"
+	"github.com/lib/pq"
+)
+
+const availabilityColumns = `
+	tenant_id, product_id, item_id, quantity, reserved_quantity, available_quantity, in_stock, as_of, updated_at
+`
+
+type postgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *sql.DB) AvailabilityRepository {
+	return &postgresRepository{db: db}
+}
+
+// Ping checks that the database is reachable
+func (r *postgresRepository) Ping(ctx context.Context) error {
+	return shareddb.Check(ctx, r.db)
+}
+
+// Apply upserts a product's row in its tenant, unless the stored one
+// reflects a later change. Redelivered and replayed events are older or as old, so they
+// change nothing.
+func (r *postgresRepository) Apply(ctx context.Context, availability *domain.Availability) (bool, error) {
+	availability.UpdatedAt = time.Now().UTC()
+
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO product_availability (`+availabilityColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (tenant_id, product_id) DO UPDATE SET
+			item_id = COALESCE(NULLIF(EXCLUDED.item_id, ''), product_availability.item_id),
+			quantity = EXCLUDED.quantity,
+			reserved_quantity = EXCLUDED.reserved_quantity,
+			available_quantity = EXCLUDED.available_quantity,
+			in_stock = EXCLUDED.in_stock,
+			as_of = EXCLUDED.as_of,
+			updated_at = EXCLUDED.updated_at
+		WHERE product_availability.as_of <= EXCLUDED.as_of
+		RETURNING item_id
+	`,
+		availability.TenantID, availability.ProductID, availability.ItemID, availability.Quantity, availability.ReservedQuantity,
+		availability.AvailableQuantity, availability.InStock, availability.AsOf, availability.UpdatedAt,
+	).Scan(&availability.ItemID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetMany returns the availability of those of productIDs the projection
+// has in ctx's tenant
+func (r *postgresRepository) GetMany(ctx context.Context, productIDs []string) (map[string]*domain.Availability, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+availabilityColumns+` FROM product_availability WHERE tenant_id = $1 AND product_id = ANY($2)
+	`, sharedauth.TenantFromContext(ctx), pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	availabilities := make(map[string]*domain.Availability, len(productIDs))
+	for rows.Next() {
+		availability, err := scanAvailability(rows)
+		if err != nil {
+			return nil, err
+		}
+		availabilities[availability.ProductID] = availability
+	}
+
+	return availabilities, rows.Err()
+}
+
+// Count returns how many products the projection has in ctx's tenant
+func (r *postgresRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM product_availability WHERE tenant_id = $1
+	`, sharedauth.TenantFromContext(ctx)).Scan(&count)
+	return count, err
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAvailability(row scanner) (*domain.Availability, error) {
+	var availability domain.Availability
+	err := row.Scan(
+		&availability.TenantID, &availability.ProductID, &availability.ItemID, &availability.Quantity, &availability.ReservedQuantity,
+		&availability.AvailableQuantity, &availability.InStock, &availability.AsOf, &availability.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &availability, nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	"github.com/ecommerce/availability-service/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// setIfNewer caches a product's availability unless the cached one
+// reflects a later change, so a read refilling the cache from the
+// database can't overwrite what the projector just wrote
+var setIfNewer = redis.NewScript(`
+local cached = redis.call('HGET', KEYS[1], 'as_of')
+if cached and tonumber(cached) > tonumber(ARGV[2]) then
+	redis.call('EXPIRE', KEYS[1], ARGV[3])
+	return 0
+end
+redis.call('HSET', KEYS[1], 'data', ARGV[1], 'as_of', ARGV[2])
+redis.call('EXPIRE', KEYS[1], ARGV[3])
+return 1
+`)
+
+// redisRepository caches availability under keys prefixed with its tenant,
+// so tenants with the same product IDs don't share entries
+type redisRepository struct {
+	client *redis.Client
+}
+
+// NewRedisRepository creates a new Redis cache repository
+func NewRedisRepository(client *redis.Client) CacheRepository {
+	return &redisRepository{client: client}
+}
+
+func (r *redisRepository) cacheKey(tenantID, productID string) string {
+	return fmt.Sprintf("availability:%s:%s", tenantID, productID)
+}
+
+// GetMany reads the products' cached availability in ctx's tenant in one
+// round trip
+func (r *redisRepository) GetMany(ctx context.Context, productIDs []string) (map[string]*domain.Availability, error) {
+	tenantID := sharedauth.TenantFromContext(ctx)
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(productIDs))
+	for i, productID := range productIDs {
+		cmds[i] = pipe.HGet(ctx, r.cacheKey(tenantID, productID), "data")
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	availabilities := make(map[string]*domain.Availability, len(productIDs))
+	for i, cmd := range cmds {
+		data, err := cmd.Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		availability := domain.Availability{TenantID: tenantID}
+		if err := json.Unmarshal(data, &availability); err != nil {
+			return nil, err
+		}
+		availabilities[productIDs[i]] = &availability
+	}
+	return availabilities, nil
+}
+
+// Set caches availabilities in one round trip, each in its tenant unless a
+// later one is cached
+func (r *redisRepository) Set(ctx context.Context, availabilities []*domain.Availability, ttl time.Duration) error {
+	pipe := r.client.Pipeline()
+	for _, availability := range availabilities {
+		data, err := json.Marshal(availability)
+		if err != nil {
+			return err
+		}
+		// The script is sent whole: EVALSHA can't fall back to it inside a
+		// pipeline. Microseconds stay exact in Lua's doubles.
+		setIfNewer.Eval(ctx, pipe, []string{r.cacheKey(availability.TenantID, availability.ProductID)},
+			data, availability.AsOf.UnixMicro(), int(ttl.Seconds()))
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Ping checks that Redis is reachable
+func (r *redisRepository) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ecommerce/availability-service/internal/domain"
+)
+
+// AvailabilityRepository stores the availability projection
+type AvailabilityRepository interface {
+	// Apply stores a product's availability in its tenant unless a later
+	// change is already stored, reporting whether it did. Its item ID is kept when
+	// the change doesn't name one.
+	Apply(ctx context.Context, availability *domain.Availability) (bool, error)
+	// GetMany returns the availability of those of productIDs the
+	// projection has in ctx's tenant, by product ID
+	GetMany(ctx context.Context, productIDs []string) (map[string]*domain.Availability, error)
+	// Count returns how many products the projection has in ctx's tenant
+	Count(ctx context.Context) (int64, error)
+
+	// Ping checks that the database is reachable
+	Ping(ctx context.Context) error
+}
+
+// CacheRepository keeps products' availability in Redis, in front of the
+// projection
+type CacheRepository interface {
+	// GetMany returns the cached availability of productIDs in ctx's
+	// tenant, by product ID
+	GetMany(ctx context.Context, productIDs []string) (map[string]*domain.Availability, error)
+	// Set caches availabilities for ttl
+	Set(ctx context.Context, availabilities []*domain.Availability, ttl time.Duration) error
+	// Ping checks that Redis is reachable
+	Ping(ctx context.Context) error
+}
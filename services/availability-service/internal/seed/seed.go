@@ -0,0 +1,112 @@
+// Package seed fills an empty projection with the stock records
+// inventory-service already has, which the inventory topic may no longer
+// hold the events of
+package seed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	"github.com/ecommerce/availability-service/internal/domain"
+	"github.com/ecommerce/availability-service/internal/projector"
+)
+
+// pageSize is the most items inventory-service lists per page
+const pageSize = 100
+
+// item is what the projection needs of an inventory-service stock record
+type item struct {
+	ID                string    `json:"id"`
+	ProductID         string    `json:"product_id"`
+	Quantity          int       `json:"quantity"`
+	ReservedQuantity  int       `json:"reserved_quantity"`
+	AvailableQuantity int       `json:"available_quantity"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// Seeder pages through inventory-service's stock records
+type Seeder struct {
+	baseURL    string
+	httpClient *http.Client
+	projector  *projector.Projector
+}
+
+// New creates a seeder of the inventory-service at baseURL, called through
+// transport, applying stock records through projector
+func New(baseURL string, transport http.RoundTripper, projector *projector.Projector) *Seeder {
+	return &Seeder{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second, Transport: transport},
+		projector:  projector,
+	}
+}
+
+// Run applies every stock record of ctx's tenant, as of its last update.
+// Events consumed meanwhile aren't overwritten: the projection keeps the
+// latest change of each product, whichever arrives first.
+func (s *Seeder) Run(ctx context.Context) (int, error) {
+	tenant := sharedauth.TenantFromContext(ctx)
+	seeded := 0
+	cursor := ""
+	for {
+		items, next, err := s.page(ctx, tenant, cursor)
+		if err != nil {
+			return seeded, err
+		}
+
+		for _, item := range items {
+			availability := domain.NewAvailability(tenant, item.ProductID, item.ID,
+				item.Quantity, item.ReservedQuantity, item.AvailableQuantity, item.UpdatedAt)
+			err := s.projector.Apply(ctx, availability)
+			if err != nil && !errors.Is(err, projector.ErrStale) {
+				return seeded, fmt.Errorf("failed to seed product %s: %w", item.ProductID, err)
+			}
+			seeded++
+		}
+
+		if next == "" {
+			return seeded, nil
+		}
+		cursor = next
+	}
+}
+
+// page fetches tenant's stock records after cursor, and the next page's
+// cursor
+func (s *Seeder) page(ctx context.Context, tenant, cursor string) ([]item, string, error) {
+	query := url.Values{"limit": {fmt.Sprint(pageSize)}}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/api/v1/inventory?"+query.Encode(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set(sharedauth.TenantHeader, tenant)
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to call inventory-service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("inventory-service returned status %d listing stock", resp.StatusCode)
+	}
+
+	var page struct {
+		Items      []item `json:"items"`
+		NextCursor string `json:"next_cursor"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, "", fmt.Errorf("failed to decode stock page: %w", err)
+	}
+	return page.Items, page.NextCursor, nil
+}
@@ -0,0 +1,16 @@
+-- The availability projection: each product's stock as of the latest
+-- inventory-service change applied, one row per product
+CREATE TABLE IF NOT EXISTS product_availability (
+    product_id VARCHAR(255) PRIMARY KEY,
+    -- The product's stock record in inventory-service; '' until an event
+    -- naming it arrives
+    item_id VARCHAR(36) NOT NULL DEFAULT '',
+    quantity INTEGER NOT NULL,
+    reserved_quantity INTEGER NOT NULL,
+    available_quantity INTEGER NOT NULL,
+    in_stock BOOLEAN NOT NULL,
+    -- When the change happened in inventory-service; changes older than
+    -- it are ignored
+    as_of TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
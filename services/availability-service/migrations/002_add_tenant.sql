@@ -0,0 +1,7 @@
+-- Availability belongs to a tenant, one storefront of a multi-tenant
+-- deployment, whose product IDs can be another's; rows projected before
+-- tenants belong to the default one
+ALTER TABLE product_availability ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+ALTER TABLE product_availability DROP CONSTRAINT IF EXISTS product_availability_pkey;
+ALTER TABLE product_availability ADD CONSTRAINT product_availability_pkey PRIMARY KEY (tenant_id, product_id);
@@ -18,6 +18,12 @@ export const config = {
     topic: process.env.KAFKA_TOPIC || 'cart-events',
   },
 
+  services: {
+    // pricingUrl is pricing-service, which quotes carts; empty disables
+    // quotes
+    pricingUrl: process.env.PRICING_SERVICE_URL || '',
+  },
+
   otel: {
     endpoint: process.env.OTLP_ENDPOINT || 'otel-collector:4317',
   },
@@ -39,3 +39,29 @@ export interface CartSummary {
   subtotal: number;
   itemCount: number;
 }
+
+// An amount in minor units, e.g. cents, as pricing-service returns it
+export interface Money {
+  minor_units: number;
+  currency: string;
+}
+
+// A cart priced by pricing-service: current prices less promotions
+export interface PriceQuote {
+  currency: string;
+  lines: Array<{
+    product_id: string;
+    quantity: number;
+    unit_price: Money;
+    subtotal: Money;
+    discount: Money;
+    total: Money;
+    promotion_id?: string;
+  }>;
+  subtotal: Money;
+  discount_total: Money;
+  total: Money;
+  promotions: Array<{ id: string; name: string; code?: string; type: string; discount: Money }>;
+  unused_codes?: string[];
+  quoted_at: string;
+}
@@ -4,6 +4,17 @@ import { AddToCartRequest, UpdateCartItemRequest } from '../models/cart';
 import { logger } from '../middleware/logger';
 import { authenticateJWT } from '../middleware/auth';
 import { validateBody, addToCartSchema, updateCartItemSchema } from '../middleware/validation';
+import { config } from '../config';
+
+// bearerToken returns the token the request was authenticated with, from
+// the Authorization header or the auth_token cookie
+function bearerToken(req: Request): string | undefined {
+  const [scheme, token] = (req.headers.authorization || '').split(' ');
+  if (scheme === 'Bearer' && token) {
+    return token;
+  }
+  return req.cookies?.auth_token;
+}
 
 export function createCartRoutes(cartService: CartService): Router {
   const router = Router();
@@ -28,6 +39,37 @@ export function createCartRoutes(cartService: CartService): Router {
     }
   });
 
+  // Price the cart with current prices and promotions
+  router.get('/quote', async (req: Request, res: Response) => {
+    if (!config.services.pricingUrl) {
+      return res.status(503).json({ error: 'Pricing is not configured' });
+    }
+
+    try {
+      const userId = req.user!.user_id;
+      const promoCodes = String(req.query.promo_codes || '')
+        .split(',')
+        .map(code => code.trim())
+        .filter(code => code.length > 0)
+        .slice(0, 5);
+
+      const quoted = await cartService.quoteCart(userId, promoCodes, bearerToken(req));
+      if (!quoted) {
+        return res.status(404).json({ error: 'Cart not found' });
+      }
+
+      res.json(quoted);
+    } catch (error: any) {
+      logger.error('Failed to quote cart', { error: error.message });
+
+      if (error.message?.includes('cannot be priced')) {
+        return res.status(422).json({ error: error.message });
+      }
+
+      res.status(502).json({ error: 'Failed to quote cart' });
+    }
+  });
+
   // Add item to cart
   router.post('/items', validateBody(addToCartSchema), async (req: Request, res: Response) => {
     try {
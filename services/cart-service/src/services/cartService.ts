@@ -1,6 +1,6 @@
 import { createClient, RedisClientType } from 'redis';
 import { config } from '../config';
-import { Cart, CartItem, AddToCartRequest } from '../models/cart';
+import { Cart, CartItem, AddToCartRequest, PriceQuote } from '../models/cart';
 import { logger } from '../middleware/logger';
 import { EventPublisher } from './eventPublisher';
 
@@ -192,6 +192,42 @@ export class CartService {
     return cart;
   }
 
+  // Prices the cart with pricing-service, at current prices and with the
+  // promotions the customer is eligible for. authToken is passed on so the
+  // customer's segment promotions apply.
+  async quoteCart(userId: string, promoCodes: string[], authToken?: string): Promise<{ cart: Cart; quote: PriceQuote } | null> {
+    const cart = await this.getCart(userId);
+    if (!cart || cart.items.length === 0) {
+      return null;
+    }
+
+    const headers: Record<string, string> = { 'Content-Type': 'application/json' };
+    if (authToken) {
+      headers.Authorization = `Bearer ${authToken}`;
+    }
+
+    const response = await fetch(`${config.services.pricingUrl}/api/v1/pricing/quotes`, {
+      method: 'POST',
+      headers,
+      body: JSON.stringify({
+        items: cart.items.map(item => ({ product_id: item.productId, quantity: item.quantity })),
+        promo_codes: promoCodes,
+      }),
+      signal: AbortSignal.timeout(5000),
+    });
+
+    if (response.status === 422) {
+      const body: any = await response.json().catch(() => ({}));
+      throw new Error(`Cart cannot be priced: ${body.error || 'some products have no price'}`);
+    }
+    if (!response.ok) {
+      throw new Error(`Pricing service returned ${response.status}`);
+    }
+
+    const quote = (await response.json()) as PriceQuote;
+    return { cart, quote };
+  }
+
   async clearCart(userId: string): Promise<void> {
     const cart = await this.getCart(userId);
 
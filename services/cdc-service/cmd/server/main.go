@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	sharedconfig "github.com/ecommerce-platform/shared/go/config"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/httpmetrics"
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce-platform/shared/go/mtls"
+	"github.com/ecommerce/cdc-service/internal/config"
+	"github.com/ecommerce/cdc-service/internal/masking"
+	"github.com/ecommerce/cdc-service/internal/pipeline"
+	"github.com/ecommerce/cdc-service/internal/warehouse"
+	"github.com/gin-gonic/gin"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.uber.org/zap"
+)
+
+func main() {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Initialize logger
+	log, err := logging.New(logging.Config{
+		ServiceName: "cdc-service",
+		Environment: cfg.Environment,
+		Level:       os.Getenv("LOG_LEVEL"),
+	})
+	if err != nil {
+		fmt.Printf("Failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer log.Sync()
+
+	log.Info("Starting CDC Service",
+		zap.String("environment", cfg.Environment),
+		zap.Int("port", cfg.Port),
+	)
+	log.Info("Configuration loaded", zap.Any("config", sharedconfig.Redacted(cfg)))
+
+	// Initialize mTLS
+	serviceTLS, err := mtls.New(cfg.TLS, log)
+	if err != nil {
+		log.Fatal("Failed to initialize mTLS", zap.Error(err))
+	}
+	defer serviceTLS.Close()
+
+	// Initialize OpenTelemetry
+	cleanup, err := initTelemetry(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize telemetry", zap.Error(err))
+	}
+	defer cleanup()
+
+	// Masking rules name the exported tables
+	rules, err := masking.Load(cfg.RulesFile)
+	if err != nil {
+		log.Fatal("Failed to load masking rules", zap.Error(err))
+	}
+	tables := rules.TableNames()
+	topics := make([]string, len(tables))
+	for i, table := range tables {
+		topics[i] = cfg.TopicPrefix + "." + table
+	}
+	log.Info("Exporting tables", zap.Strings("tables", tables))
+
+	// Changes are read from Debezium's topics, masked and published to the
+	// warehouse topic
+	brokers := strings.Split(cfg.KafkaBrokers, ",")
+	warehouseProducer := sharedkafka.NewProducer(sharedkafka.ProducerConfig{
+		Brokers:      brokers,
+		Topic:        cfg.WarehouseTopic,
+		RequiredAcks: kafkago.RequireAll,
+	}, log)
+	defer warehouseProducer.Close()
+
+	dlqProducer := sharedkafka.NewProducer(sharedkafka.ProducerConfig{Brokers: brokers}, log)
+	defer dlqProducer.Close()
+
+	changePipeline := pipeline.New(
+		masking.NewMasker(rules, cfg.HashKey),
+		warehouse.NewTopicSink(warehouseProducer),
+		log,
+	)
+
+	changeConsumer := sharedkafka.NewConsumer(sharedkafka.ConsumerConfig{
+		Brokers:     brokers,
+		GroupID:     cfg.KafkaGroupID,
+		Topics:      topics,
+		Concurrency: cfg.ConsumerConcurrency,
+		MaxAttempts: 3,
+		DeadLetters: sharedkafka.NewTopicDeadLetters(dlqProducer),
+		Tracer:      otel.Tracer("cdc-service"),
+	}, changePipeline.Handle, log)
+
+	consumerCtx, stopConsumer := context.WithCancel(context.Background())
+	consumerDone := make(chan struct{})
+	go func() {
+		defer close(consumerDone)
+		if err := changeConsumer.Run(consumerCtx); err != nil {
+			log.Error("Change consumer stopped", zap.Error(err))
+		}
+	}()
+
+	// Setup Gin
+	if cfg.Environment == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(httpmetrics.Middleware("cdc-service"))
+	router.Use(apperrors.Middleware(log))
+
+	// Health check
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "healthy",
+			"service": "cdc-service",
+			"version": "1.0.0",
+			"tables":  tables,
+		})
+	})
+
+	// Metrics for Prometheus
+	router.GET("/metrics", gin.WrapH(httpmetrics.Handler()))
+
+	// Create HTTP server
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Port),
+		Handler:      router,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// Start server in goroutine
+	go func() {
+		log.Info("Server starting", zap.Int("port", cfg.Port))
+		if err := serviceTLS.ListenAndServe(srv); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Server failed to start", zap.Error(err))
+		}
+	}()
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Info("Shutting down server...")
+
+	// Graceful shutdown
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Fatal("Server forced to shutdown", zap.Error(err))
+	}
+
+	stopConsumer()
+	select {
+	case <-consumerDone:
+	case <-shutdownCtx.Done():
+		log.Warn("Change consumer didn't stop in time")
+	}
+
+	log.Info("Server shutdown complete")
+}
+
+func initTelemetry(cfg *config.Config) (func(), error) {
+	ctx := context.Background()
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String("cdc-service"),
+			semconv.ServiceVersionKey.String("1.0.0"),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	traceExporter, err := otlptracegrpc.New(ctx,
+		otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint),
+		otlptracegrpc.WithInsecure(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	bsp := sdktrace.NewBatchSpanProcessor(traceExporter)
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithResource(res),
+		sdktrace.WithSpanProcessor(bsp),
+	)
+
+	otel.SetTracerProvider(tracerProvider)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			fmt.Printf("Failed to shutdown tracer provider: %v\n", err)
+		}
+	}, nil
+}
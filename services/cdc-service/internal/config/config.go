@@ -0,0 +1,79 @@
+package config
+
+import (
+	"errors"
+	"os"
+
+	sharedconfig "github.com/ecommerce-platform/shared/go/config"
+	"github.com/ecommerce-platform/shared/go/mtls"
+	"github.com/ecommerce-platform/shared/go/secrets"
+)
+
+// defaultHashKey only suits local development; it matches CDC_HASH_KEY's
+// default tag
+const defaultHashKey = "cdc-hash-key-change-in-production"
+
+// Config holds application configuration
+type Config struct {
+	// Server
+	Port        int    `env:"PORT" flag:"port" default:"8097"`
+	Environment string `env:"ENVIRONMENT" default:"development"`
+
+	// Kafka
+	KafkaBrokers string `env:"KAFKA_BROKERS" default:"kafka:9092"`
+	KafkaGroupID string `env:"KAFKA_GROUP_ID" default:"cdc-service"`
+	// TopicPrefix is the Debezium connectors' topic.prefix up to the
+	// database name: the users database's changes are read from
+	// cdc.users_db.public.users
+	TopicPrefix string `env:"CDC_TOPIC_PREFIX" default:"cdc"`
+	// WarehouseTopic receives the masked changes
+	WarehouseTopic string `env:"WAREHOUSE_TOPIC" default:"warehouse-changes"`
+	// ConsumerConcurrency is the number of workers masking changes
+	ConsumerConcurrency int `env:"CONSUMER_CONCURRENCY" default:"4"`
+
+	// Masking
+	// RulesFile lists the exported tables and how their columns are
+	// masked; empty uses the built-in rules
+	RulesFile string `env:"CDC_RULES_FILE"`
+	// HashKey keys the hashes of hashed columns, so they can't be
+	// reversed by hashing guesses without it
+	HashKey string `env:"CDC_HASH_KEY" default:"cdc-hash-key-change-in-production" secret:"true"`
+
+	// OpenTelemetry
+	OTLPEndpoint string `env:"OTLP_ENDPOINT" default:"otel-collector:4317"`
+
+	// Mutual TLS for the service's servers and its clients of other
+	// services
+	TLS mtls.Config
+}
+
+// Load loads configuration from flags and environment variables
+func Load() (*Config, error) {
+	resolver, err := secrets.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := sharedconfig.LoadWith(&cfg, sharedconfig.Options{Args: os.Args[1:], Secrets: resolver}); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate rejects settings the service can't run with
+func (c *Config) Validate() error {
+	if err := c.TLS.Validate(); err != nil {
+		return err
+	}
+	if c.WarehouseTopic == "" {
+		return errors.New("WAREHOUSE_TOPIC must be set")
+	}
+	if c.HashKey == "" {
+		return errors.New("CDC_HASH_KEY must be set")
+	}
+	if c.Environment == "production" && c.HashKey == defaultHashKey {
+		return errors.New("CDC_HASH_KEY must be changed in production")
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Package debezium decodes the change events Debezium's connectors publish,
+// with or without the JSON converter's schemas
+package debezium
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Operations of change events
+const (
+	OpCreate   = "c"
+	OpUpdate   = "u"
+	OpDelete   = "d"
+	OpRead     = "r" // a row read by the initial snapshot
+	OpTruncate = "t"
+)
+
+// ErrTombstone is returned for the empty messages following deletes, which
+// only exist for log compaction
+var ErrTombstone = errors.New("tombstone")
+
+// Row is a row's columns by name. Numbers are json.Number, so large
+// integers keep their precision.
+type Row map[string]interface{}
+
+// Source is where in the database a change happened
+type Source struct {
+	Connector string `json:"connector"`
+	DB        string `json:"db"`
+	Schema    string `json:"schema"`
+	Table     string `json:"table"`
+	// TsMs is when the change was committed, in Unix milliseconds
+	TsMs int64 `json:"ts_ms"`
+	LSN  int64 `json:"lsn"`
+}
+
+// Change is a change event. After is the row of creates, updates and
+// reads. Before is the row deleted, or updated, but unless the table has
+// REPLICA IDENTITY FULL it holds only the key of deletes and is empty for
+// updates.
+type Change struct {
+	Key    Row    `json:"-"`
+	Before Row    `json:"before"`
+	After  Row    `json:"after"`
+	Source Source `json:"source"`
+	Op     string `json:"op"`
+	// TsMs is when the connector processed the change, in Unix
+	// milliseconds
+	TsMs int64 `json:"ts_ms"`
+}
+
+// Table is the changed table as database.schema.table
+func (c *Change) Table() string {
+	return c.Source.DB + "." + c.Source.Schema + "." + c.Source.Table
+}
+
+// CommittedAt is when the change was committed in the database
+func (c *Change) CommittedAt() time.Time {
+	return time.UnixMilli(c.Source.TsMs).UTC()
+}
+
+// Decode reads a change event from a message's key and value
+func Decode(key, value []byte) (*Change, error) {
+	if len(value) == 0 {
+		return nil, ErrTombstone
+	}
+
+	var change Change
+	if err := decodePayload(value, &change); err != nil {
+		return nil, fmt.Errorf("invalid change event: %w", err)
+	}
+	if change.Op == "" || change.Source.Table == "" {
+		return nil, errors.New("invalid change event: missing op or source table")
+	}
+
+	if len(key) > 0 {
+		if err := decodePayload(key, &change.Key); err != nil {
+			return nil, fmt.Errorf("invalid change event key: %w", err)
+		}
+	}
+	return &change, nil
+}
+
+// decodePayload decodes data into v, unwrapping the {"schema", "payload"}
+// envelope the JSON converter adds when schemas are enabled
+func decodePayload(data []byte, v interface{}) error {
+	var wrapped struct {
+		Schema  json.RawMessage `json:"schema"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err == nil && wrapped.Schema != nil && wrapped.Payload != nil {
+		data = wrapped.Payload
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
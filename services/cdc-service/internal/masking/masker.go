@@ -0,0 +1,134 @@
+package masking
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// postalPrefixLength is how much of a postal code an address keeps, e.g.
+// the US ZIP code's sectional center
+const postalPrefixLength = 3
+
+// addressFields are the address fields kept, by the names orders use
+var addressFields = []string{"country", "state", "region"}
+
+// postalCodeFields are the names postal codes go by in addresses
+var postalCodeFields = []string{"postalCode", "postal_code", "zipCode", "zip"}
+
+// Masker masks rows by the rules of their table
+type Masker struct {
+	rules   *Rules
+	hashKey []byte
+}
+
+// NewMasker creates a masker of rules, keying hashes with hashKey
+func NewMasker(rules *Rules, hashKey string) *Masker {
+	return &Masker{rules: rules, hashKey: []byte(hashKey)}
+}
+
+// Table returns the rules of table, and false if it isn't exported
+func (m *Masker) Table(table string) (TableRules, bool) {
+	rules, ok := m.rules.Tables[table]
+	return rules, ok
+}
+
+// Mask returns a copy of row with its columns masked by rules. Null values
+// stay null.
+func (m *Masker) Mask(rules TableRules, row map[string]interface{}) (map[string]interface{}, error) {
+	if row == nil {
+		return nil, nil
+	}
+
+	masked := make(map[string]interface{}, len(row))
+	for column, value := range row {
+		policy, ok := rules.Columns[column]
+		if !ok {
+			masked[column] = value
+			continue
+		}
+		if policy == PolicyDrop {
+			continue
+		}
+		if value == nil {
+			masked[column] = nil
+			continue
+		}
+
+		var err error
+		switch policy {
+		case PolicyHash:
+			masked[column] = m.hash(fmt.Sprint(value))
+		case PolicyEmail:
+			masked[column] = m.email(fmt.Sprint(value))
+		case PolicyRedact:
+			masked[column] = nil
+		case PolicyAddress:
+			masked[column], err = address(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to mask %s: %w", column, err)
+		}
+	}
+	return masked, nil
+}
+
+// hash is the hex HMAC-SHA256 of value
+func (m *Masker) hash(value string) string {
+	mac := hmac.New(sha256.New, m.hashKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// email hashes an address, case-insensitively like user-service compares
+// emails, and appends its domain
+func (m *Masker) email(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	at := strings.LastIndex(value, "@")
+	if at < 0 {
+		return m.hash(value)
+	}
+	return m.hash(value) + value[at:]
+}
+
+// address keeps the coarse location of an address: its country, state and
+// postal code prefix. Debezium sends JSONB columns as JSON strings, which
+// are masked into JSON strings.
+func address(value interface{}) (interface{}, error) {
+	fields, ok := value.(map[string]interface{})
+	raw, isString := value.(string)
+	if isString {
+		if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+			return nil, fmt.Errorf("address isn't a JSON object: %w", err)
+		}
+	} else if !ok {
+		return nil, fmt.Errorf("address isn't a JSON object")
+	}
+
+	kept := map[string]interface{}{}
+	for _, field := range addressFields {
+		if v, ok := fields[field]; ok {
+			kept[field] = v
+		}
+	}
+	for _, field := range postalCodeFields {
+		if v, ok := fields[field].(string); ok {
+			if len(v) > postalPrefixLength {
+				v = v[:postalPrefixLength]
+			}
+			kept[field] = v
+		}
+	}
+
+	if !isString {
+		return kept, nil
+	}
+	data, err := json.Marshal(kept)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,93 @@
+// Package masking strips personal data from changed rows before they leave
+// for the warehouse
+package masking
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Policy is how a column's values are masked
+type Policy string
+
+// Policies
+const (
+	// PolicyHash replaces values with a keyed hash, so they can still be
+	// counted and joined on but not read
+	PolicyHash Policy = "hash"
+	// PolicyEmail hashes an email address like PolicyHash, keeping its
+	// domain
+	PolicyEmail Policy = "email"
+	// PolicyRedact replaces values with null
+	PolicyRedact Policy = "redact"
+	// PolicyDrop leaves the column out
+	PolicyDrop Policy = "drop"
+	// PolicyAddress keeps a JSON address's country, state and the first
+	// three characters of its postal code
+	PolicyAddress Policy = "address"
+)
+
+//go:embed rules.json
+var defaultRules []byte
+
+// Rules are the tables exported to the warehouse, by
+// database.schema.table; changes to other tables are skipped
+type Rules struct {
+	Tables map[string]TableRules `json:"tables"`
+}
+
+// TableRules are the masked columns of a table; the others are exported
+// as they are
+type TableRules struct {
+	Columns map[string]Policy `json:"columns"`
+}
+
+// Load reads the rules in path, or the built-in ones if path is empty
+func Load(path string) (*Rules, error) {
+	data := defaultRules
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read masking rules: %w", err)
+		}
+	}
+
+	var rules Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid masking rules: %w", err)
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	return &rules, nil
+}
+
+// Validate rejects rules without tables or with unknown policies
+func (r *Rules) Validate() error {
+	if len(r.Tables) == 0 {
+		return fmt.Errorf("invalid masking rules: no tables")
+	}
+	for table, tableRules := range r.Tables {
+		for column, policy := range tableRules.Columns {
+			switch policy {
+			case PolicyHash, PolicyEmail, PolicyRedact, PolicyDrop, PolicyAddress:
+			default:
+				return fmt.Errorf("invalid masking rules: unknown policy %q for %s.%s", policy, table, column)
+			}
+		}
+	}
+	return nil
+}
+
+// TableNames returns the exported tables, sorted
+func (r *Rules) TableNames() []string {
+	names := make([]string, 0, len(r.Tables))
+	for name := range r.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,23 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// ChangesTotal counts change events by table, operation and result:
+	// exported, skipped (a table without rules, or a tombstone) or failed
+	ChangesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cdc_changes_total",
+		Help: "Change events read, per table, operation and result",
+	}, []string{"table", "op", "result"})
+
+	// ReplicationLag is how long after a change was committed in its
+	// database it was exported
+	ReplicationLag = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cdc_replication_lag_seconds",
+		Help:    "Delay between a change's commit and its export, per database",
+		Buckets: []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 900},
+	}, []string{"database"})
+)
@@ -0,0 +1,113 @@
+// Package pipeline turns Debezium change events into masked warehouse
+// records
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/cdc-service/internal/debezium"
+	"github.com/ecommerce/cdc-service/internal/masking"
+	"github.com/ecommerce/cdc-service/internal/metrics"
+	"github.com/ecommerce/cdc-service/internal/warehouse"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// ops maps Debezium's operations to records'
+var ops = map[string]string{
+	debezium.OpCreate:   warehouse.OpCreate,
+	debezium.OpUpdate:   warehouse.OpUpdate,
+	debezium.OpDelete:   warehouse.OpDelete,
+	debezium.OpRead:     warehouse.OpSnapshot,
+	debezium.OpTruncate: warehouse.OpTruncate,
+}
+
+// Pipeline masks change events and writes them to the warehouse sink
+type Pipeline struct {
+	masker *masking.Masker
+	sink   warehouse.Sink
+	logger *zap.Logger
+}
+
+// New creates a pipeline
+func New(masker *masking.Masker, sink warehouse.Sink, logger *zap.Logger) *Pipeline {
+	return &Pipeline{masker: masker, sink: sink, logger: logger}
+}
+
+// Handle exports a change event. It is a sharedkafka.Handler; failures
+// are retried and then dead-lettered.
+func (p *Pipeline) Handle(ctx context.Context, msg kafkago.Message) error {
+	change, err := debezium.Decode(msg.Key, msg.Value)
+	if errors.Is(err, debezium.ErrTombstone) {
+		metrics.ChangesTotal.WithLabelValues(msg.Topic, "tombstone", "skipped").Inc()
+		return nil
+	}
+	if err != nil {
+		metrics.ChangesTotal.WithLabelValues(msg.Topic, "unknown", "failed").Inc()
+		return err
+	}
+
+	table := change.Table()
+	rules, ok := p.masker.Table(table)
+	if !ok {
+		metrics.ChangesTotal.WithLabelValues(table, change.Op, "skipped").Inc()
+		return nil
+	}
+
+	record, err := p.record(change, rules)
+	if err == nil {
+		err = p.sink.Write(ctx, *record)
+	}
+	if err != nil {
+		metrics.ChangesTotal.WithLabelValues(table, change.Op, "failed").Inc()
+		logging.WithContext(ctx, p.logger).Warn("Failed to export change",
+			zap.String("table", table),
+			zap.String("op", change.Op),
+			zap.Int64("lsn", change.Source.LSN),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	metrics.ChangesTotal.WithLabelValues(table, change.Op, "exported").Inc()
+	if change.Op != debezium.OpRead {
+		metrics.ReplicationLag.WithLabelValues(change.Source.DB).Observe(time.Since(change.CommittedAt()).Seconds())
+	}
+	return nil
+}
+
+// record masks a change into a record. Keys are masked too, in case a
+// table's primary key is personal data.
+func (p *Pipeline) record(change *debezium.Change, rules masking.TableRules) (*warehouse.Record, error) {
+	op, ok := ops[change.Op]
+	if !ok {
+		return nil, fmt.Errorf("unknown operation %q", change.Op)
+	}
+
+	key, err := p.masker.Mask(rules, change.Key)
+	if err != nil {
+		return nil, err
+	}
+	var row map[string]interface{}
+	if op != warehouse.OpDelete {
+		if row, err = p.masker.Mask(rules, change.After); err != nil {
+			return nil, err
+		}
+	}
+
+	return &warehouse.Record{
+		Database:    change.Source.DB,
+		Schema:      change.Source.Schema,
+		Table:       change.Source.Table,
+		Op:          op,
+		Key:         key,
+		Row:         row,
+		LSN:         change.Source.LSN,
+		CommittedAt: change.CommittedAt(),
+		CapturedAt:  time.UnixMilli(change.TsMs).UTC(),
+	}, nil
+}
@@ -0,0 +1,86 @@
+// Package warehouse delivers masked changes to the data warehouse
+package warehouse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Operations of records
+const (
+	OpCreate   = "create"
+	OpUpdate   = "update"
+	OpDelete   = "delete"
+	OpSnapshot = "snapshot" // a row read by the connector's initial snapshot
+	OpTruncate = "truncate"
+)
+
+// Record is a masked change of a table row
+type Record struct {
+	Database string `json:"database"`
+	Schema   string `json:"schema"`
+	Table    string `json:"table"`
+	Op       string `json:"op"`
+	// Key is the row's primary key; empty for truncates
+	Key map[string]interface{} `json:"key,omitempty"`
+	// Row is the row after the change; empty for deletes and truncates
+	Row map[string]interface{} `json:"row,omitempty"`
+	// LSN is the change's position in the database's write-ahead log
+	LSN         int64     `json:"lsn,omitempty"`
+	CommittedAt time.Time `json:"committed_at"`
+	CapturedAt  time.Time `json:"captured_at"`
+}
+
+// Sink receives records
+type Sink interface {
+	Write(ctx context.Context, records ...Record) error
+}
+
+// TopicSink publishes records to a Kafka topic as JSON, keyed by table and
+// primary key so each row's changes stay in order
+type TopicSink struct {
+	producer *sharedkafka.Producer
+}
+
+// NewTopicSink creates a sink publishing with producer, which must have
+// the warehouse topic as its Topic
+func NewTopicSink(producer *sharedkafka.Producer) *TopicSink {
+	return &TopicSink{producer: producer}
+}
+
+// Write publishes records
+func (s *TopicSink) Write(ctx context.Context, records ...Record) error {
+	messages := make([]kafkago.Message, 0, len(records))
+	for _, record := range records {
+		value, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to encode record: %w", err)
+		}
+		key, err := messageKey(record)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, kafkago.Message{Key: key, Value: value})
+	}
+	return s.producer.Publish(ctx, messages...)
+}
+
+// messageKey is database.schema.table, followed by the primary key as JSON
+func messageKey(record Record) ([]byte, error) {
+	key := record.Database + "." + record.Schema + "." + record.Table
+	if len(record.Key) == 0 {
+		return []byte(key), nil
+	}
+
+	// encoding/json sorts map keys, so a row's key is always the same
+	primaryKey, err := json.Marshal(record.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode record key: %w", err)
+	}
+	return append([]byte(key+":"), primaryKey...), nil
+}
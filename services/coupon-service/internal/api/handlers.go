@@ -0,0 +1,597 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/audit"
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce-platform/shared/go/pagination"
+	"github.com/ecommerce/coupon-service/internal/codes"
+	"github.com/ecommerce/coupon-service/internal/domain"
+	"github.com/ecommerce/coupon-service/internal/metrics"
+	"github.com/ecommerce/coupon-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// PermissionManage allows managing campaigns and codes, reading their
+// reports and releasing any order's redemptions; admins have it
+const PermissionManage = "coupons:manage"
+
+// CodeCouponRejected is the error code of redemptions refused by the
+// code's campaign or limits; the reason field says why
+const CodeCouponRejected = "COUPON_REJECTED"
+
+func init() {
+	apperrors.Register(CodeCouponRejected, http.StatusUnprocessableEntity, "The coupon can't be redeemed on the order; reason says why")
+}
+
+// Handler serves the coupon API
+type Handler struct {
+	repo    repository.CouponRepository
+	auditor *audit.Auditor
+	logger  *zap.Logger
+}
+
+// NewHandler creates a new handler
+func NewHandler(repo repository.CouponRepository, auditor *audit.Auditor, logger *zap.Logger) *Handler {
+	return &Handler{
+		repo:    repo,
+		auditor: auditor,
+		logger:  logger,
+	}
+}
+
+// CreateCampaignRequest is a campaign's discount and limits; which amount
+// is required depends on the discount type
+type CreateCampaignRequest struct {
+	UpdateCampaignRequest
+	DiscountType domain.DiscountType `json:"discount_type" binding:"required,oneof=percentage fixed"`
+	PercentOff   int                 `json:"percent_off" binding:"min=0,max=100"`
+	AmountOff    *money.Money        `json:"amount_off"`
+	// Currency is the currency of the orders the codes apply to
+	Currency string `json:"currency" binding:"required,len=3"`
+}
+
+// UpdateCampaignRequest replaces a campaign's name, limits and dates; its
+// discount can't change once codes are out
+type UpdateCampaignRequest struct {
+	Name                      string       `json:"name" binding:"required,max=200"`
+	MinOrderAmount            *money.Money `json:"min_order_amount"`
+	MaxRedemptionsPerCode     *int         `json:"max_redemptions_per_code" binding:"omitempty,min=1"`
+	MaxRedemptionsPerCustomer *int         `json:"max_redemptions_per_customer" binding:"omitempty,min=1"`
+	StartsAt                  *time.Time   `json:"starts_at"`
+	ExpiresAt                 *time.Time   `json:"expires_at"`
+	// Active defaults to true
+	Active *bool `json:"active"`
+}
+
+// GenerateCodesRequest creates a batch of random codes
+type GenerateCodesRequest struct {
+	Count  int    `json:"count" binding:"required,min=1,max=10000"`
+	Prefix string `json:"prefix" binding:"omitempty,max=20,alphanum"`
+}
+
+// CreateCodeRequest creates a single code of the caller's choosing, e.g. a
+// code to advertise
+type CreateCodeRequest struct {
+	Code string `json:"code" binding:"required,min=3,max=50,alphanum"`
+}
+
+// ValidateRequest checks a code against an order before checkout
+type ValidateRequest struct {
+	Code        string       `json:"code" binding:"required,max=50"`
+	OrderAmount *money.Money `json:"order_amount" binding:"required"`
+}
+
+// RedeemRequest uses a code on an order
+type RedeemRequest struct {
+	Code        string       `json:"code" binding:"required,max=50"`
+	OrderID     string       `json:"order_id" binding:"required,max=255"`
+	OrderAmount *money.Money `json:"order_amount" binding:"required"`
+}
+
+// Validation is whether a code can discount an order, and by how much
+type Validation struct {
+	Code  string `json:"code"`
+	Valid bool   `json:"valid"`
+	// Reason and Message say why an invalid code can't be redeemed
+	Reason     string       `json:"reason,omitempty"`
+	Message    string       `json:"message,omitempty"`
+	CampaignID string       `json:"campaign_id,omitempty"`
+	Discount   *money.Money `json:"discount,omitempty"`
+}
+
+// CreateCampaign creates a campaign
+// POST /api/v1/coupons/campaigns
+func (h *Handler) CreateCampaign(c *gin.Context) {
+	var req CreateCampaignRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	campaign := &domain.Campaign{
+		DiscountType: req.DiscountType,
+		StartsAt:     time.Now().UTC(),
+		Active:       true,
+		CreatedBy:    c.GetString(sharedauth.ContextUserID),
+		// Sets the campaign's currency until the request's minimum does
+		MinOrderAmount: money.New(0, req.Currency),
+	}
+
+	var invalid apperrors.ValidationErrors
+	switch req.DiscountType {
+	case domain.DiscountPercentage:
+		if req.PercentOff < 1 {
+			invalid.Add("percent_off", "required", "percent_off must be between 1 and 100")
+		}
+		campaign.PercentOff = req.PercentOff
+	case domain.DiscountFixed:
+		switch {
+		case req.AmountOff == nil || req.AmountOff.MinorUnits <= 0:
+			invalid.Add("amount_off", "required", "amount_off must be positive")
+		case req.AmountOff.Currency != req.Currency:
+			invalid.Add("amount_off.currency", "eqfield", "amount_off must be in the campaign's currency")
+		default:
+			campaign.AmountOff = req.AmountOff
+		}
+	}
+	applyCampaign(campaign, &req.UpdateCampaignRequest, &invalid)
+	if err := invalid.Err(); err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	if err := h.repo.CreateCampaign(c.Request.Context(), campaign); err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Campaign"))
+		return
+	}
+
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   "campaign.created",
+		Resource: audit.Resource{Type: "campaign", ID: campaign.ID},
+		After:    campaign,
+	})
+
+	c.JSON(http.StatusCreated, campaign)
+}
+
+// ListCampaigns lists campaigns, newest first, optionally only active ones
+// GET /api/v1/coupons/campaigns?active=true
+func (h *Handler) ListCampaigns(c *gin.Context) {
+	campaigns, err := h.repo.ListCampaigns(c.Request.Context(), c.Query("active") == "true")
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Campaign"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"campaigns": campaigns})
+}
+
+// GetCampaign returns a campaign
+// GET /api/v1/coupons/campaigns/:id
+func (h *Handler) GetCampaign(c *gin.Context) {
+	campaign, ok := h.campaign(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+// UpdateCampaign replaces a campaign's name, limits and dates
+// PUT /api/v1/coupons/campaigns/:id
+func (h *Handler) UpdateCampaign(c *gin.Context) {
+	var req UpdateCampaignRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	before, ok := h.campaign(c)
+	if !ok {
+		return
+	}
+
+	after := *before
+	after.MinOrderAmount = money.New(0, before.Currency())
+	after.ExpiresAt, after.MaxRedemptionsPerCode, after.MaxRedemptionsPerCustomer = nil, nil, nil
+	after.Active = true
+
+	var invalid apperrors.ValidationErrors
+	applyCampaign(&after, &req, &invalid)
+	if err := invalid.Err(); err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+	h.update(c, before, &after, "campaign.updated")
+}
+
+// DeactivateCampaign ends a campaign: its codes no longer redeem, and it
+// stays listed, inactive
+// DELETE /api/v1/coupons/campaigns/:id
+func (h *Handler) DeactivateCampaign(c *gin.Context) {
+	before, ok := h.campaign(c)
+	if !ok {
+		return
+	}
+
+	after := *before
+	after.Active = false
+	h.update(c, before, &after, "campaign.deactivated")
+}
+
+// update stores a campaign's new settings and audits them
+func (h *Handler) update(c *gin.Context, before, after *domain.Campaign, action string) {
+	err := h.repo.UpdateCampaign(c.Request.Context(), after)
+	if errors.Is(err, domain.ErrCampaignNotFound) {
+		apperrors.Abort(c, apperrors.NewNotFound("Campaign"))
+		return
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Campaign"))
+		return
+	}
+
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   action,
+		Resource: audit.Resource{Type: "campaign", ID: after.ID},
+		Before:   before,
+		After:    after,
+	})
+
+	c.JSON(http.StatusOK, after)
+}
+
+// GenerateCodes creates a batch of random codes for a campaign
+// POST /api/v1/coupons/campaigns/:id/batches
+func (h *Handler) GenerateCodes(c *gin.Context) {
+	var req GenerateCodesRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	campaign, ok := h.campaign(c)
+	if !ok {
+		return
+	}
+
+	batch := &domain.Batch{
+		CampaignID: campaign.ID,
+		Prefix:     codes.Normalize(req.Prefix),
+		Size:       req.Count,
+		CreatedBy:  c.GetString(sharedauth.ContextUserID),
+	}
+	generated, err := h.repo.CreateBatch(c.Request.Context(), batch, func(n int) ([]string, error) {
+		return codes.Generate(batch.Prefix, n)
+	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to generate codes"))
+		return
+	}
+
+	metrics.CodesGeneratedTotal.Add(float64(len(generated)))
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   "codes.generated",
+		Resource: audit.Resource{Type: "campaign", ID: campaign.ID},
+		After:    batch,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{"batch": batch, "codes": generated})
+}
+
+// CreateCode creates a single code for a campaign
+// POST /api/v1/coupons/campaigns/:id/codes
+func (h *Handler) CreateCode(c *gin.Context) {
+	var req CreateCodeRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	campaign, ok := h.campaign(c)
+	if !ok {
+		return
+	}
+
+	coupon := &domain.Coupon{Code: codes.Normalize(req.Code), CampaignID: campaign.ID}
+	if err := h.repo.CreateCoupon(c.Request.Context(), coupon); errors.Is(err, domain.ErrDuplicateCode) {
+		apperrors.Abort(c, apperrors.NewConflict("The code already exists"))
+		return
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Coupon"))
+		return
+	}
+
+	metrics.CodesGeneratedTotal.Inc()
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   "code.created",
+		Resource: audit.Resource{Type: "campaign", ID: campaign.ID},
+		After:    coupon,
+	})
+
+	c.JSON(http.StatusCreated, coupon)
+}
+
+// ListCodes lists a page of a campaign's codes, sorted by code, optionally
+// only a batch's
+// GET /api/v1/coupons/campaigns/:id/codes?batch_id=&limit=&cursor=
+func (h *Handler) ListCodes(c *gin.Context) {
+	params := pagination.FromQuery(c.Request.URL.Query())
+
+	var after *repository.CouponPosition
+	if params.Cursor != "" {
+		after = &repository.CouponPosition{}
+		if err := pagination.DecodeCursor(params.Cursor, after); err != nil {
+			apperrors.Abort(c, apperrors.NewBadRequest("Invalid cursor"))
+			return
+		}
+	}
+
+	campaign, ok := h.campaign(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	filter := repository.CouponFilter{CampaignID: campaign.ID, BatchID: c.Query("batch_id")}
+	coupons, err := h.repo.ListCoupons(ctx, filter, params.Limit+1, after)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Coupon"))
+		return
+	}
+
+	total, err := h.repo.CountCoupons(ctx, filter)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Coupon"))
+		return
+	}
+
+	page, err := pagination.NewPage(coupons, params.Limit, total, func(coupon *domain.Coupon) interface{} {
+		return repository.CouponPosition{Code: coupon.Code}
+	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list codes"))
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetReport summarizes a campaign's codes and redemptions
+// GET /api/v1/coupons/campaigns/:id/report
+func (h *Handler) GetReport(c *gin.Context) {
+	campaign, ok := h.campaign(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.repo.Report(c.Request.Context(), campaign)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Report"))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// Validate checks whether a code would discount the caller's order, and by
+// how much, without redeeming it. Codes that can't be redeemed aren't an
+// error: the response says why.
+// POST /api/v1/coupons/validate
+func (h *Handler) Validate(c *gin.Context) {
+	var req ValidateRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	if err := validateAmount("order_amount", req.OrderAmount); err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	validation := &Validation{Code: codes.Normalize(req.Code)}
+	discount, campaignID, err := h.check(c, validation.Code, *req.OrderAmount)
+	validation.CampaignID = campaignID
+
+	reason := domain.RejectionReason(err)
+	switch {
+	case err == nil:
+		validation.Valid = true
+		validation.Discount = &discount
+		metrics.ValidationsTotal.WithLabelValues("valid").Inc()
+	case reason != "":
+		validation.Reason = reason
+		validation.Message = err.Error()
+		metrics.ValidationsTotal.WithLabelValues(reason).Inc()
+	default:
+		metrics.ValidationsTotal.WithLabelValues("failed").Inc()
+		apperrors.Abort(c, apperrors.FromSQL(err, "Coupon"))
+		return
+	}
+
+	c.JSON(http.StatusOK, validation)
+}
+
+// check returns the discount of a code on the caller's order, the code's
+// campaign, or why it can't be redeemed
+func (h *Handler) check(c *gin.Context, code string, orderAmount money.Money) (money.Money, string, error) {
+	ctx := c.Request.Context()
+	coupon, err := h.repo.GetCoupon(ctx, code)
+	if err != nil {
+		return money.Money{}, "", err
+	}
+	campaign, err := h.repo.GetCampaign(ctx, coupon.CampaignID)
+	if err != nil {
+		return money.Money{}, coupon.CampaignID, err
+	}
+	count, err := h.repo.CustomerRedemptions(ctx, campaign.ID, c.GetString(sharedauth.ContextUserID))
+	if err != nil {
+		return money.Money{}, campaign.ID, err
+	}
+	if err := domain.CheckRedemption(campaign, coupon, count, orderAmount, time.Now()); err != nil {
+		return money.Money{}, campaign.ID, err
+	}
+	return campaign.Discount(orderAmount), campaign.ID, nil
+}
+
+// Redeem uses a code on the caller's order, atomically with its limits.
+// Redeeming the same code on the same order again returns the existing
+// redemption, so checkouts can retry safely.
+// POST /api/v1/coupons/redemptions
+func (h *Handler) Redeem(c *gin.Context) {
+	var req RedeemRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	if err := validateAmount("order_amount", req.OrderAmount); err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	redemption, created, err := h.repo.Redeem(c.Request.Context(), repository.RedeemRequest{
+		Code:        codes.Normalize(req.Code),
+		UserID:      c.GetString(sharedauth.ContextUserID),
+		OrderID:     req.OrderID,
+		OrderAmount: *req.OrderAmount,
+		At:          time.Now(),
+	})
+	if reason := domain.RejectionReason(err); reason != "" {
+		metrics.RedemptionsTotal.WithLabelValues(reason).Inc()
+		apperrors.Abort(c, apperrors.NewCode(CodeCouponRejected, err.Error()).
+			WithFields(map[string]interface{}{"reason": reason}))
+		return
+	} else if err != nil {
+		metrics.RedemptionsTotal.WithLabelValues("failed").Inc()
+		apperrors.Abort(c, apperrors.FromSQL(err, "Redemption"))
+		return
+	}
+
+	if !created {
+		metrics.RedemptionsTotal.WithLabelValues("replayed").Inc()
+		c.JSON(http.StatusOK, redemption)
+		return
+	}
+
+	metrics.RedemptionsTotal.WithLabelValues("redeemed").Inc()
+	c.JSON(http.StatusCreated, redemption)
+}
+
+// ReleaseRedemption undoes an order's redemption, e.g. when checkout fails
+// after redeeming, so the code's use counts no more. The customer who
+// redeemed it, or a holder of coupons:manage, can release it.
+// DELETE /api/v1/coupons/redemptions/:orderId
+func (h *Handler) ReleaseRedemption(c *gin.Context) {
+	ctx := c.Request.Context()
+	orderID := c.Param("orderId")
+
+	redemptions, err := h.repo.OrderRedemptions(ctx, orderID)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Redemption"))
+		return
+	}
+	if len(redemptions) == 0 {
+		apperrors.Abort(c, apperrors.NewNotFound("Redemption"))
+		return
+	}
+
+	claims, _ := sharedauth.ClaimsFrom(c)
+	manager := claims != nil && claims.HasPermission(PermissionManage)
+	if !manager && redemptions[0].UserID != c.GetString(sharedauth.ContextUserID) {
+		apperrors.Abort(c, apperrors.NewForbidden("Only the customer who redeemed the coupon can release it"))
+		return
+	}
+
+	released, err := h.repo.Release(ctx, orderID, time.Now())
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Redemption"))
+		return
+	}
+
+	metrics.RedemptionsReleasedTotal.WithLabelValues("api").Add(float64(len(released)))
+	if manager && len(released) > 0 {
+		h.auditor.LogGin(c, audit.Entry{
+			Action:   "redemption.released",
+			Resource: audit.Resource{Type: "order", ID: orderID},
+			Before:   redemptions,
+			After:    released,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"order_id": orderID, "released": released})
+}
+
+// HealthCheck reports whether the database is reachable
+func (h *Handler) HealthCheck(c *gin.Context) {
+	if err := h.repo.Ping(c.Request.Context()); err != nil {
+		h.logger.Warn("Health check failed", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unhealthy",
+			"service": "coupon-service",
+			"version": "1.0.0",
+			"error":   "database unavailable",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "healthy",
+		"service": "coupon-service",
+		"version": "1.0.0",
+	})
+}
+
+// campaign loads the campaign of the :id path parameter, aborting with
+// 404 if there is none
+func (h *Handler) campaign(c *gin.Context) (*domain.Campaign, bool) {
+	campaign, err := h.repo.GetCampaign(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, domain.ErrCampaignNotFound) {
+		apperrors.Abort(c, apperrors.NewNotFound("Campaign"))
+		return nil, false
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Campaign"))
+		return nil, false
+	}
+	return campaign, true
+}
+
+// applyCampaign validates req and applies it to campaign, whose currency
+// is already set
+func applyCampaign(campaign *domain.Campaign, req *UpdateCampaignRequest, invalid *apperrors.ValidationErrors) {
+	campaign.Name = req.Name
+	if req.MinOrderAmount != nil {
+		switch {
+		case req.MinOrderAmount.IsNegative():
+			invalid.Add("min_order_amount.minor_units", "min", "min_order_amount must not be negative")
+		case req.MinOrderAmount.Currency != campaign.Currency():
+			invalid.Add("min_order_amount.currency", "eqfield", "min_order_amount must be in the campaign's currency")
+		default:
+			campaign.MinOrderAmount = *req.MinOrderAmount
+		}
+	}
+	campaign.MaxRedemptionsPerCode = req.MaxRedemptionsPerCode
+	campaign.MaxRedemptionsPerCustomer = req.MaxRedemptionsPerCustomer
+	if req.StartsAt != nil {
+		campaign.StartsAt = req.StartsAt.UTC()
+	}
+	if req.ExpiresAt != nil {
+		expiresAt := req.ExpiresAt.UTC()
+		if !expiresAt.After(campaign.StartsAt) {
+			invalid.Add("expires_at", "gtfield", "expires_at must be after starts_at")
+		}
+		campaign.ExpiresAt = &expiresAt
+	}
+	if req.Active != nil {
+		campaign.Active = *req.Active
+	}
+}
+
+// validateAmount rejects negative amounts and currencies that aren't ISO
+// 4217 codes
+func validateAmount(field string, amount *money.Money) *apperrors.AppError {
+	var invalid apperrors.ValidationErrors
+	if amount.IsNegative() {
+		invalid.Add(field+".minor_units", "min", field+" must not be negative")
+	}
+	if len(amount.Currency) != 3 {
+		invalid.Add(field+".currency", "len", "currency must be an ISO 4217 code, e.g. USD")
+	}
+	return invalid.Err()
+}
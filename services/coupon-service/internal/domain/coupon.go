@@ -0,0 +1,234 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/money"
+)
+
+// DiscountType is how a campaign's codes discount an order
+type DiscountType string
+
+const (
+	// DiscountPercentage takes PercentOff percent off the order
+	DiscountPercentage DiscountType = "percentage"
+	// DiscountFixed takes AmountOff off the order, down to zero
+	DiscountFixed DiscountType = "fixed"
+)
+
+// Campaign is a discount and the limits on redeeming its codes
+type Campaign struct {
+	ID           string       `json:"id"`
+	Name         string       `json:"name"`
+	DiscountType DiscountType `json:"discount_type"`
+	PercentOff   int          `json:"percent_off,omitempty"`
+	AmountOff    *money.Money `json:"amount_off,omitempty"`
+	// MinOrderAmount is the smallest order the codes apply to, and sets
+	// the campaign's currency
+	MinOrderAmount money.Money `json:"min_order_amount"`
+	// MaxRedemptionsPerCode limits how many orders each code discounts;
+	// nil is unlimited, 1 makes single-use codes
+	MaxRedemptionsPerCode *int `json:"max_redemptions_per_code,omitempty"`
+	// MaxRedemptionsPerCustomer limits how many orders of a customer the
+	// campaign's codes discount, across codes; nil is unlimited
+	MaxRedemptionsPerCustomer *int       `json:"max_redemptions_per_customer,omitempty"`
+	StartsAt                  time.Time  `json:"starts_at"`
+	ExpiresAt                 *time.Time `json:"expires_at,omitempty"`
+	Active                    bool       `json:"active"`
+	CreatedBy                 string     `json:"created_by,omitempty"`
+	CreatedAt                 time.Time  `json:"created_at"`
+	UpdatedAt                 time.Time  `json:"updated_at"`
+}
+
+// Currency is the currency of the orders the campaign discounts
+func (c *Campaign) Currency() string {
+	return c.MinOrderAmount.Currency
+}
+
+// Status is the campaign's state at t: active, scheduled, expired or
+// inactive
+func (c *Campaign) Status(t time.Time) string {
+	switch {
+	case !c.Active:
+		return "inactive"
+	case t.Before(c.StartsAt):
+		return "scheduled"
+	case c.ExpiresAt != nil && !t.Before(*c.ExpiresAt):
+		return "expired"
+	default:
+		return "active"
+	}
+}
+
+// Check returns why the campaign's codes can't discount orderAmount at t,
+// or nil
+func (c *Campaign) Check(orderAmount money.Money, t time.Time) error {
+	switch c.Status(t) {
+	case "inactive":
+		return ErrInactive
+	case "scheduled":
+		return ErrNotStarted
+	case "expired":
+		return ErrExpired
+	}
+	if orderAmount.Currency != c.Currency() {
+		return ErrCurrencyMismatch
+	}
+	if orderAmount.MinorUnits < c.MinOrderAmount.MinorUnits {
+		return ErrMinimumOrder
+	}
+	return nil
+}
+
+// Discount is what the campaign's codes take off orderAmount, at most the
+// whole order. Percentages are rounded half up to the minor unit.
+func (c *Campaign) Discount(orderAmount money.Money) money.Money {
+	var discount int64
+	switch c.DiscountType {
+	case DiscountPercentage:
+		discount = (orderAmount.MinorUnits*int64(c.PercentOff) + 50) / 100
+	case DiscountFixed:
+		if c.AmountOff != nil {
+			discount = c.AmountOff.MinorUnits
+		}
+	}
+	if discount > orderAmount.MinorUnits {
+		discount = orderAmount.MinorUnits
+	}
+	if discount < 0 {
+		discount = 0
+	}
+	return money.New(discount, orderAmount.Currency)
+}
+
+// CheckRedemption returns why a customer who redeemed the campaign
+// customerRedemptions times can't redeem coupon on an order of orderAmount
+// at t, or nil
+func CheckRedemption(campaign *Campaign, coupon *Coupon, customerRedemptions int, orderAmount money.Money, t time.Time) error {
+	if err := campaign.Check(orderAmount, t); err != nil {
+		return err
+	}
+	if limit := campaign.MaxRedemptionsPerCode; limit != nil && coupon.RedemptionCount >= *limit {
+		return ErrCodeLimit
+	}
+	if limit := campaign.MaxRedemptionsPerCustomer; limit != nil && customerRedemptions >= *limit {
+		return ErrCustomerLimit
+	}
+	return nil
+}
+
+// Batch is a set of codes generated together
+type Batch struct {
+	ID         string    `json:"id"`
+	CampaignID string    `json:"campaign_id"`
+	Prefix     string    `json:"prefix,omitempty"`
+	Size       int       `json:"size"`
+	CreatedBy  string    `json:"created_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Coupon is a redeemable code
+type Coupon struct {
+	Code       string `json:"code"`
+	CampaignID string `json:"campaign_id"`
+	BatchID    string `json:"batch_id,omitempty"`
+	// RedemptionCount counts the redemptions not released
+	RedemptionCount int       `json:"redemption_count"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// RedemptionStatus is whether a redemption still counts
+type RedemptionStatus string
+
+const (
+	// RedemptionRedeemed discounts an order and counts towards limits
+	RedemptionRedeemed RedemptionStatus = "redeemed"
+	// RedemptionReleased was undone, e.g. because the order was cancelled
+	RedemptionReleased RedemptionStatus = "released"
+)
+
+// Redemption is a code used on an order
+type Redemption struct {
+	ID          string           `json:"id"`
+	Code        string           `json:"code"`
+	CampaignID  string           `json:"campaign_id"`
+	UserID      string           `json:"user_id"`
+	OrderID     string           `json:"order_id"`
+	OrderAmount money.Money      `json:"order_amount"`
+	Discount    money.Money      `json:"discount"`
+	Status      RedemptionStatus `json:"status"`
+	RedeemedAt  time.Time        `json:"redeemed_at"`
+	ReleasedAt  *time.Time       `json:"released_at,omitempty"`
+}
+
+// Report summarizes a campaign's codes and redemptions
+type Report struct {
+	CampaignID    string `json:"campaign_id"`
+	Status        string `json:"status"`
+	CodesIssued   int64  `json:"codes_issued"`
+	CodesRedeemed int64  `json:"codes_redeemed"`
+	// Redemptions counts redemptions not released, Released those undone
+	Redemptions   int64       `json:"redemptions"`
+	Released      int64       `json:"released"`
+	Customers     int64       `json:"customers"`
+	DiscountTotal money.Money `json:"discount_total"`
+	OrderTotal    money.Money `json:"order_total"`
+	Daily         []DailyStat `json:"daily"`
+}
+
+// DailyStat is a day's redemptions of a campaign
+type DailyStat struct {
+	Date        string      `json:"date"`
+	Redemptions int64       `json:"redemptions"`
+	Discount    money.Money `json:"discount"`
+}
+
+// Common errors
+var (
+	ErrCampaignNotFound   = errors.New("campaign not found")
+	ErrCouponNotFound     = errors.New("coupon not found")
+	ErrRedemptionNotFound = errors.New("redemption not found")
+	ErrDuplicateCode      = errors.New("coupon code already exists")
+)
+
+// Reasons a code can't be redeemed
+var (
+	ErrInactive         = errors.New("coupon is no longer valid")
+	ErrNotStarted       = errors.New("coupon is not valid yet")
+	ErrExpired          = errors.New("coupon has expired")
+	ErrCurrencyMismatch = errors.New("coupon is not valid for the order's currency")
+	ErrMinimumOrder     = errors.New("order is below the coupon's minimum")
+	ErrCodeLimit        = errors.New("coupon has been used up")
+	ErrCustomerLimit    = errors.New("coupon already used the maximum number of times")
+	// ErrOrderRedeemed is returned when the order already redeemed another
+	// code
+	ErrOrderRedeemed = errors.New("order already has a coupon")
+)
+
+// RejectionReason is the stable reason code of an error making a code
+// unredeemable, or "" for other errors
+func RejectionReason(err error) string {
+	switch {
+	case errors.Is(err, ErrCouponNotFound):
+		return "not_found"
+	case errors.Is(err, ErrInactive):
+		return "inactive"
+	case errors.Is(err, ErrNotStarted):
+		return "not_started"
+	case errors.Is(err, ErrExpired):
+		return "expired"
+	case errors.Is(err, ErrCurrencyMismatch):
+		return "currency_mismatch"
+	case errors.Is(err, ErrMinimumOrder):
+		return "minimum_order"
+	case errors.Is(err, ErrCodeLimit):
+		return "code_limit"
+	case errors.Is(err, ErrCustomerLimit):
+		return "customer_limit"
+	case errors.Is(err, ErrOrderRedeemed):
+		return "order_redeemed"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// CodesGeneratedTotal counts codes created, in batches or one at a time
+	CodesGeneratedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "coupon_codes_generated_total",
+		Help: "Coupon codes created",
+	})
+
+	// ValidationsTotal counts code checks by result: valid, a rejection
+	// reason such as expired or code_limit, or failed
+	ValidationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "coupon_validations_total",
+		Help: "Coupon code checks per result",
+	}, []string{"result"})
+
+	// RedemptionsTotal counts redemption attempts by result: redeemed,
+	// replayed (already redeemed on the order), a rejection reason, or
+	// failed
+	RedemptionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "coupon_redemptions_total",
+		Help: "Coupon redemption attempts per result",
+	}, []string{"result"})
+
+	// RedemptionsReleasedTotal counts redemptions undone, by trigger: api
+	// or order_event
+	RedemptionsReleasedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "coupon_redemptions_released_total",
+		Help: "Coupon redemptions released per trigger",
+	}, []string{"trigger"})
+
+	// OrderEventsTotal counts consumed order events by outcome: released,
+	// skipped (event types or orders without redemptions) or failed
+	OrderEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "coupon_order_events_total",
+		Help: "Order events applied to coupon redemptions, per event type and result",
+	}, []string{"event_type", "result"})
+)
@@ -0,0 +1,75 @@
+// Package redemptions releases the coupon redemptions of orders that
+// won't complete, from order-service's order events
+package redemptions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sharedevents "github.com/ecommerce-platform/shared/go/events"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/coupon-service/internal/metrics"
+	"github.com/ecommerce/coupon-service/internal/repository"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// Releaser gives the codes of cancelled and failed orders their uses back
+type Releaser struct {
+	repo   repository.CouponRepository
+	logger *zap.Logger
+}
+
+// NewReleaser creates a releaser writing to repo
+func NewReleaser(repo repository.CouponRepository, logger *zap.Logger) *Releaser {
+	return &Releaser{repo: repo, logger: logger}
+}
+
+// Handle applies an order event. It is a sharedkafka.Handler; failures are
+// retried and then dead-lettered. Releasing is idempotent, so redelivered
+// events are skipped, as are other order events.
+func (r *Releaser) Handle(ctx context.Context, msg kafkago.Message) error {
+	env, err := sharedevents.Decode(msg.Value)
+	if err != nil {
+		metrics.OrderEventsTotal.WithLabelValues("unknown", "failed").Inc()
+		return err
+	}
+
+	switch env.EventType {
+	case "order.cancelled", "order.payment_failed":
+	default:
+		metrics.OrderEventsTotal.WithLabelValues(env.EventType, "skipped").Inc()
+		return nil
+	}
+	if env.OrderID == "" {
+		metrics.OrderEventsTotal.WithLabelValues(env.EventType, "failed").Inc()
+		return fmt.Errorf("%s event has no order_id", env.EventType)
+	}
+
+	released, err := r.repo.Release(ctx, env.OrderID, time.Now())
+	if err != nil {
+		metrics.OrderEventsTotal.WithLabelValues(env.EventType, "failed").Inc()
+		logging.WithContext(ctx, r.logger).Warn("Failed to release order's coupons",
+			zap.String("event_type", env.EventType),
+			zap.String("order_id", env.OrderID),
+			zap.Error(err),
+		)
+		return err
+	}
+	if len(released) == 0 {
+		metrics.OrderEventsTotal.WithLabelValues(env.EventType, "skipped").Inc()
+		return nil
+	}
+
+	metrics.OrderEventsTotal.WithLabelValues(env.EventType, "released").Inc()
+	metrics.RedemptionsReleasedTotal.WithLabelValues("order_event").Add(float64(len(released)))
+	for _, redemption := range released {
+		logging.WithContext(ctx, r.logger).Info("Coupon redemption released",
+			zap.String("event_type", env.EventType),
+			zap.String("order_id", env.OrderID),
+			zap.String("code", redemption.Code),
+		)
+	}
+	return nil
+}
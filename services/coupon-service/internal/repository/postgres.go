@@ -0,0 +1,586 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	shareddb "github.com/ecommerce-platform/shared/go/db"
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce/coupon-service/internal/domain"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is the SQLSTATE of a duplicate key
+const uniqueViolation = "23505"
+
+// maxGenerateAttempts bounds how often a batch's colliding codes are
+// generated again; with random codes even one retry is rare
+const maxGenerateAttempts = 5
+
+const campaignColumns = `
+	id, name, discount_type, percent_off, amount_off, currency, min_order_amount,
+	max_redemptions_per_code, max_redemptions_per_customer, starts_at, expires_at,
+	active, created_by, created_at, updated_at
+`
+
+const couponColumns = `code, campaign_id, batch_id, redemption_count, created_at`
+
+const redemptionColumns = `
+	id, code, campaign_id, user_id, order_id, order_amount, discount_amount, currency,
+	status, redeemed_at, released_at
+`
+
+type postgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *sql.DB) CouponRepository {
+	return &postgresRepository{db: db}
+}
+
+// Ping checks that the database is reachable
+func (r *postgresRepository) Ping(ctx context.Context) error {
+	return shareddb.Check(ctx, r.db)
+}
+
+// CreateCampaign stores a campaign
+func (r *postgresRepository) CreateCampaign(ctx context.Context, campaign *domain.Campaign) error {
+	now := time.Now().UTC()
+	campaign.ID = uuid.New().String()
+	campaign.CreatedAt = now
+	campaign.UpdatedAt = now
+
+	query := `
+		INSERT INTO campaigns (
+			id, name, discount_type, percent_off, amount_off, currency, min_order_amount,
+			max_redemptions_per_code, max_redemptions_per_customer, starts_at, expires_at,
+			active, created_by, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		campaign.ID, campaign.Name, campaign.DiscountType, campaign.PercentOff, amountOff(campaign),
+		campaign.Currency(), campaign.MinOrderAmount.MinorUnits,
+		nullInt(campaign.MaxRedemptionsPerCode), nullInt(campaign.MaxRedemptionsPerCustomer),
+		campaign.StartsAt.UTC(), utcOrNil(campaign.ExpiresAt),
+		campaign.Active, campaign.CreatedBy, campaign.CreatedAt, campaign.UpdatedAt,
+	)
+	return err
+}
+
+// GetCampaign returns a campaign
+func (r *postgresRepository) GetCampaign(ctx context.Context, id string) (*domain.Campaign, error) {
+	query := `SELECT ` + campaignColumns + ` FROM campaigns WHERE id = $1`
+
+	campaign, err := scanCampaign(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrCampaignNotFound
+	}
+	return campaign, err
+}
+
+// UpdateCampaign replaces a campaign's name, limits, dates and active flag
+func (r *postgresRepository) UpdateCampaign(ctx context.Context, campaign *domain.Campaign) error {
+	campaign.UpdatedAt = time.Now().UTC()
+
+	query := `
+		UPDATE campaigns SET
+			name = $2, min_order_amount = $3, max_redemptions_per_code = $4,
+			max_redemptions_per_customer = $5, starts_at = $6, expires_at = $7, active = $8, updated_at = $9
+		WHERE id = $1
+	`
+	result, err := r.db.ExecContext(ctx, query,
+		campaign.ID, campaign.Name, campaign.MinOrderAmount.MinorUnits,
+		nullInt(campaign.MaxRedemptionsPerCode), nullInt(campaign.MaxRedemptionsPerCustomer),
+		campaign.StartsAt.UTC(), utcOrNil(campaign.ExpiresAt), campaign.Active, campaign.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return domain.ErrCampaignNotFound
+	}
+	return nil
+}
+
+// ListCampaigns returns campaigns, most recently created first
+func (r *postgresRepository) ListCampaigns(ctx context.Context, activeOnly bool) ([]*domain.Campaign, error) {
+	query := `
+		SELECT ` + campaignColumns + `
+		FROM campaigns
+		WHERE active OR NOT $1
+		ORDER BY created_at DESC, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	campaigns := []*domain.Campaign{}
+	for rows.Next() {
+		campaign, err := scanCampaign(rows)
+		if err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, campaign)
+	}
+
+	return campaigns, rows.Err()
+}
+
+// CreateBatch stores a batch and its generated codes in one transaction,
+// skipping codes that already exist and generating others in their place
+func (r *postgresRepository) CreateBatch(ctx context.Context, batch *domain.Batch, generate func(n int) ([]string, error)) ([]string, error) {
+	batch.ID = uuid.New().String()
+	batch.CreatedAt = time.Now().UTC()
+
+	var stored []string
+	err := shareddb.WithTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO batches (id, campaign_id, prefix, size, created_by, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, batch.ID, batch.CampaignID, batch.Prefix, batch.Size, batch.CreatedBy, batch.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		stored = make([]string, 0, batch.Size)
+		for attempt := 0; len(stored) < batch.Size; attempt++ {
+			if attempt == maxGenerateAttempts {
+				return fmt.Errorf("generated codes kept colliding: stored %d of %d", len(stored), batch.Size)
+			}
+			codes, err := generate(batch.Size - len(stored))
+			if err != nil {
+				return err
+			}
+
+			rows, err := tx.QueryContext(ctx, `
+				INSERT INTO coupons (code, campaign_id, batch_id, created_at)
+				SELECT UNNEST($1::TEXT[]), $2, $3, $4
+				ON CONFLICT (code) DO NOTHING
+				RETURNING code
+			`, pq.Array(codes), batch.CampaignID, batch.ID, batch.CreatedAt)
+			if err != nil {
+				return err
+			}
+			for rows.Next() {
+				var code string
+				if err := rows.Scan(&code); err != nil {
+					rows.Close()
+					return err
+				}
+				stored = append(stored, code)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// CreateCoupon stores a single code
+func (r *postgresRepository) CreateCoupon(ctx context.Context, coupon *domain.Coupon) error {
+	coupon.CreatedAt = time.Now().UTC()
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO coupons (code, campaign_id, batch_id, created_at) VALUES ($1, $2, $3, $4)
+	`, coupon.Code, coupon.CampaignID, nullString(coupon.BatchID), coupon.CreatedAt)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return domain.ErrDuplicateCode
+	}
+	return err
+}
+
+// GetCoupon returns a code
+func (r *postgresRepository) GetCoupon(ctx context.Context, code string) (*domain.Coupon, error) {
+	query := `SELECT ` + couponColumns + ` FROM coupons WHERE code = $1`
+
+	coupon, err := scanCoupon(r.db.QueryRowContext(ctx, query, code))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrCouponNotFound
+	}
+	return coupon, err
+}
+
+// ListCoupons retrieves up to limit of a campaign's codes after a position
+func (r *postgresRepository) ListCoupons(ctx context.Context, filter CouponFilter, limit int, after *CouponPosition) ([]*domain.Coupon, error) {
+	where, args := couponWhere(filter)
+	if after != nil {
+		args = append(args, after.Code)
+		where += ` AND code > $` + strconv.Itoa(len(args))
+	}
+	args = append(args, limit)
+
+	query := `
+		SELECT ` + couponColumns + `
+		FROM coupons
+		WHERE ` + where + `
+		ORDER BY code
+		LIMIT $` + strconv.Itoa(len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	coupons := []*domain.Coupon{}
+	for rows.Next() {
+		coupon, err := scanCoupon(rows)
+		if err != nil {
+			return nil, err
+		}
+		coupons = append(coupons, coupon)
+	}
+
+	return coupons, rows.Err()
+}
+
+// CountCoupons counts the codes matching filter
+func (r *postgresRepository) CountCoupons(ctx context.Context, filter CouponFilter) (int64, error) {
+	where, args := couponWhere(filter)
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM coupons WHERE `+where, args...).Scan(&count)
+	return count, err
+}
+
+func couponWhere(filter CouponFilter) (string, []interface{}) {
+	where := `campaign_id = $1`
+	args := []interface{}{filter.CampaignID}
+	if filter.BatchID != "" {
+		args = append(args, filter.BatchID)
+		where += ` AND batch_id = $2`
+	}
+	return where, args
+}
+
+// CustomerRedemptions counts a customer's redemptions of a campaign that
+// weren't released
+func (r *postgresRepository) CustomerRedemptions(ctx context.Context, campaignID, userID string) (int, error) {
+	return customerRedemptions(ctx, r.db, campaignID, userID)
+}
+
+func customerRedemptions(ctx context.Context, q shareddb.Querier, campaignID, userID string) (int, error) {
+	var count int
+	err := q.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM redemptions
+		WHERE campaign_id = $1 AND user_id = $2 AND status = 'redeemed'
+	`, campaignID, userID).Scan(&count)
+	return count, err
+}
+
+// Redeem records a redemption after checking the code's limits. The code's
+// row lock serializes redemptions of the code, and an advisory lock on the
+// campaign and customer serializes the customer's redemptions across codes,
+// so concurrent checkouts can't both take a code's last use or exceed the
+// customer's limit. Another advisory lock on the order serializes the
+// order's redemptions, so it can't get two codes at once.
+func (r *postgresRepository) Redeem(ctx context.Context, req RedeemRequest) (*domain.Redemption, bool, error) {
+	var redemption *domain.Redemption
+	var created bool
+	err := shareddb.WithTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		// Taken before the code's lock, so redemptions lock in the same order
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "redemptions:"+req.OrderID); err != nil {
+			return err
+		}
+
+		coupon, err := scanCoupon(tx.QueryRowContext(ctx,
+			`SELECT `+couponColumns+` FROM coupons WHERE code = $1 FOR UPDATE`, req.Code))
+		if err == sql.ErrNoRows {
+			return domain.ErrCouponNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		// An order has one code; redeeming it again changes nothing. This is
+		// read under the order's lock, so redemptions racing each other,
+		// with the same code or another, see the first one's.
+		existing, err := r.queryRedemptions(ctx, tx, `WHERE order_id = $1 AND status = 'redeemed'`, req.OrderID)
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			if existing[0].Code != coupon.Code {
+				return domain.ErrOrderRedeemed
+			}
+			redemption = existing[0]
+			return nil
+		}
+
+		campaign, err := scanCampaign(tx.QueryRowContext(ctx,
+			`SELECT `+campaignColumns+` FROM campaigns WHERE id = $1`, coupon.CampaignID))
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, campaign.ID+":"+req.UserID); err != nil {
+			return err
+		}
+		count, err := customerRedemptions(ctx, tx, campaign.ID, req.UserID)
+		if err != nil {
+			return err
+		}
+		if err := domain.CheckRedemption(campaign, coupon, count, req.OrderAmount, req.At); err != nil {
+			return err
+		}
+
+		redemption = &domain.Redemption{
+			ID:          uuid.New().String(),
+			Code:        coupon.Code,
+			CampaignID:  campaign.ID,
+			UserID:      req.UserID,
+			OrderID:     req.OrderID,
+			OrderAmount: req.OrderAmount,
+			Discount:    campaign.Discount(req.OrderAmount),
+			Status:      domain.RedemptionRedeemed,
+			RedeemedAt:  req.At.UTC(),
+		}
+
+		// A released redemption of the code on the order counts again
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO redemptions (
+				id, code, campaign_id, user_id, order_id, order_amount, discount_amount, currency,
+				status, redeemed_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			ON CONFLICT (code, order_id) DO UPDATE SET
+				user_id = EXCLUDED.user_id, order_amount = EXCLUDED.order_amount,
+				discount_amount = EXCLUDED.discount_amount, currency = EXCLUDED.currency,
+				status = EXCLUDED.status, redeemed_at = EXCLUDED.redeemed_at, released_at = NULL
+			RETURNING id
+		`,
+			redemption.ID, redemption.Code, redemption.CampaignID, redemption.UserID, redemption.OrderID,
+			redemption.OrderAmount.MinorUnits, redemption.Discount.MinorUnits, redemption.OrderAmount.Currency,
+			redemption.Status, redemption.RedeemedAt,
+		).Scan(&redemption.ID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE coupons SET redemption_count = redemption_count + 1 WHERE code = $1`, coupon.Code); err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return redemption, created, nil
+}
+
+// OrderRedemptions returns an order's redemptions, oldest first
+func (r *postgresRepository) OrderRedemptions(ctx context.Context, orderID string) ([]*domain.Redemption, error) {
+	return r.queryRedemptions(ctx, r.db, `WHERE order_id = $1`, orderID)
+}
+
+// Release marks an order's redemptions released and gives their uses back
+// to the codes, in one statement
+func (r *postgresRepository) Release(ctx context.Context, orderID string, at time.Time) ([]*domain.Redemption, error) {
+	query := `
+		WITH released AS (
+			UPDATE redemptions SET status = 'released', released_at = $2
+			WHERE order_id = $1 AND status = 'redeemed'
+			RETURNING ` + redemptionColumns + `
+		), returned AS (
+			UPDATE coupons c SET redemption_count = c.redemption_count - 1
+			FROM released
+			WHERE c.code = released.code
+		)
+		SELECT ` + redemptionColumns + ` FROM released ORDER BY redeemed_at, id
+	`
+	return scanRedemptions(r.db.QueryContext(ctx, query, orderID, at.UTC()))
+}
+
+func (r *postgresRepository) queryRedemptions(ctx context.Context, q shareddb.Querier, where string, args ...interface{}) ([]*domain.Redemption, error) {
+	query := `SELECT ` + redemptionColumns + ` FROM redemptions ` + where + ` ORDER BY redeemed_at, id`
+	return scanRedemptions(q.QueryContext(ctx, query, args...))
+}
+
+// Report summarizes a campaign's codes and redemptions; amounts are in the
+// campaign's currency, the only one its codes redeem in
+func (r *postgresRepository) Report(ctx context.Context, campaign *domain.Campaign) (*domain.Report, error) {
+	currency := campaign.Currency()
+	report := &domain.Report{
+		CampaignID: campaign.ID,
+		Status:     campaign.Status(time.Now()),
+		Daily:      []domain.DailyStat{},
+	}
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE redemption_count > 0)
+		FROM coupons
+		WHERE campaign_id = $1
+	`, campaign.ID).Scan(&report.CodesIssued, &report.CodesRedeemed)
+	if err != nil {
+		return nil, err
+	}
+
+	var discountTotal, orderTotal int64
+	err = r.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE status = 'redeemed'),
+			COUNT(*) FILTER (WHERE status = 'released'),
+			COUNT(DISTINCT user_id) FILTER (WHERE status = 'redeemed'),
+			COALESCE(SUM(discount_amount) FILTER (WHERE status = 'redeemed'), 0),
+			COALESCE(SUM(order_amount) FILTER (WHERE status = 'redeemed'), 0)
+		FROM redemptions
+		WHERE campaign_id = $1
+	`, campaign.ID).Scan(&report.Redemptions, &report.Released, &report.Customers, &discountTotal, &orderTotal)
+	if err != nil {
+		return nil, err
+	}
+	report.DiscountTotal = money.New(discountTotal, currency)
+	report.OrderTotal = money.New(orderTotal, currency)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT TO_CHAR(DATE_TRUNC('day', redeemed_at), 'YYYY-MM-DD'), COUNT(*), SUM(discount_amount)
+		FROM redemptions
+		WHERE campaign_id = $1 AND status = 'redeemed'
+		GROUP BY 1
+		ORDER BY 1
+	`, campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day domain.DailyStat
+		var discount int64
+		if err := rows.Scan(&day.Date, &day.Redemptions, &discount); err != nil {
+			return nil, err
+		}
+		day.Discount = money.New(discount, currency)
+		report.Daily = append(report.Daily, day)
+	}
+
+	return report, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCampaign(row scanner) (*domain.Campaign, error) {
+	campaign := &domain.Campaign{}
+	var amountOff, minOrderAmount int64
+	var currency string
+	var maxPerCode, maxPerCustomer sql.NullInt64
+	var expiresAt sql.NullTime
+	err := row.Scan(
+		&campaign.ID, &campaign.Name, &campaign.DiscountType, &campaign.PercentOff, &amountOff, &currency,
+		&minOrderAmount, &maxPerCode, &maxPerCustomer, &campaign.StartsAt, &expiresAt,
+		&campaign.Active, &campaign.CreatedBy, &campaign.CreatedAt, &campaign.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if campaign.DiscountType == domain.DiscountFixed {
+		amount := money.New(amountOff, currency)
+		campaign.AmountOff = &amount
+	}
+	campaign.MinOrderAmount = money.New(minOrderAmount, currency)
+	campaign.MaxRedemptionsPerCode = intOrNil(maxPerCode)
+	campaign.MaxRedemptionsPerCustomer = intOrNil(maxPerCustomer)
+	if expiresAt.Valid {
+		campaign.ExpiresAt = &expiresAt.Time
+	}
+	return campaign, nil
+}
+
+func scanCoupon(row scanner) (*domain.Coupon, error) {
+	coupon := &domain.Coupon{}
+	var batchID sql.NullString
+	err := row.Scan(&coupon.Code, &coupon.CampaignID, &batchID, &coupon.RedemptionCount, &coupon.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	coupon.BatchID = batchID.String
+	return coupon, nil
+}
+
+func scanRedemptions(rows *sql.Rows, err error) ([]*domain.Redemption, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	redemptions := []*domain.Redemption{}
+	for rows.Next() {
+		redemption := &domain.Redemption{}
+		var orderAmount, discount int64
+		var currency string
+		var releasedAt sql.NullTime
+		err := rows.Scan(
+			&redemption.ID, &redemption.Code, &redemption.CampaignID, &redemption.UserID, &redemption.OrderID,
+			&orderAmount, &discount, &currency, &redemption.Status, &redemption.RedeemedAt, &releasedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		redemption.OrderAmount = money.New(orderAmount, currency)
+		redemption.Discount = money.New(discount, currency)
+		if releasedAt.Valid {
+			redemption.ReleasedAt = &releasedAt.Time
+		}
+		redemptions = append(redemptions, redemption)
+	}
+
+	return redemptions, rows.Err()
+}
+
+// amountOff returns a fixed campaign's amount off as its column
+func amountOff(campaign *domain.Campaign) int64 {
+	if campaign.AmountOff == nil {
+		return 0
+	}
+	return campaign.AmountOff.MinorUnits
+}
+
+func nullInt(n *int) interface{} {
+	if n == nil {
+		return nil
+	}
+	return *n
+}
+
+func intOrNil(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
+}
+
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func utcOrNil(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
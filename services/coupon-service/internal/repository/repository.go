@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce/coupon-service/internal/domain"
+)
+
+// CouponRepository defines coupon data operations
+type CouponRepository interface {
+	// Campaigns
+	CreateCampaign(ctx context.Context, campaign *domain.Campaign) error
+	GetCampaign(ctx context.Context, id string) (*domain.Campaign, error)
+	// UpdateCampaign replaces a campaign's name, limits, dates and active
+	// flag; its discount can't change once codes are out
+	UpdateCampaign(ctx context.Context, campaign *domain.Campaign) error
+	// ListCampaigns returns campaigns, most recently created first
+	ListCampaigns(ctx context.Context, activeOnly bool) ([]*domain.Campaign, error)
+
+	// Codes
+	// CreateBatch stores a batch and batch.Size codes from generate, which
+	// is called again for as many codes as collided with existing ones. It
+	// returns the codes stored.
+	CreateBatch(ctx context.Context, batch *domain.Batch, generate func(n int) ([]string, error)) ([]string, error)
+	// CreateCoupon stores a single code, or returns domain.ErrDuplicateCode
+	CreateCoupon(ctx context.Context, coupon *domain.Coupon) error
+	GetCoupon(ctx context.Context, code string) (*domain.Coupon, error)
+	// ListCoupons returns up to limit of a campaign's codes after a
+	// position, sorted by code
+	ListCoupons(ctx context.Context, filter CouponFilter, limit int, after *CouponPosition) ([]*domain.Coupon, error)
+	CountCoupons(ctx context.Context, filter CouponFilter) (int64, error)
+
+	// Redemptions
+	// CustomerRedemptions counts a customer's redemptions of a campaign
+	// that weren't released
+	CustomerRedemptions(ctx context.Context, campaignID, userID string) (int, error)
+	// Redeem atomically checks a code's limits and records its redemption
+	// on an order. Redeeming a code again on the same order returns the
+	// existing redemption with created false. Codes that can't be redeemed
+	// return the domain error saying why.
+	Redeem(ctx context.Context, req RedeemRequest) (redemption *domain.Redemption, created bool, err error)
+	// OrderRedemptions returns the redemptions of an order, released ones
+	// included
+	OrderRedemptions(ctx context.Context, orderID string) ([]*domain.Redemption, error)
+	// Release undoes an order's redemptions so they no longer count
+	// towards limits, and returns those released; none for orders without
+	// redemptions or already released
+	Release(ctx context.Context, orderID string, at time.Time) ([]*domain.Redemption, error)
+
+	// Report summarizes a campaign's codes and redemptions
+	Report(ctx context.Context, campaign *domain.Campaign) (*domain.Report, error)
+
+	// Ping checks that the database is reachable
+	Ping(ctx context.Context) error
+}
+
+// RedeemRequest is a customer's use of a code on an order
+type RedeemRequest struct {
+	Code        string
+	UserID      string
+	OrderID     string
+	OrderAmount money.Money
+	At          time.Time
+}
+
+// CouponFilter selects the codes to list
+type CouponFilter struct {
+	CampaignID string
+	// BatchID, when set, lists only the batch's codes
+	BatchID string
+}
+
+// CouponPosition is the cursor position of code lists, sorted by code
+type CouponPosition struct {
+	Code string `json:"code"`
+}
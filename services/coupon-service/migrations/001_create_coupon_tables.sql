@@ -0,0 +1,67 @@
+-- Coupon campaigns: the discount and limits shared by their codes
+CREATE TABLE IF NOT EXISTS campaigns (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    discount_type VARCHAR(20) NOT NULL CHECK (discount_type IN ('percentage', 'fixed')),
+    percent_off INTEGER NOT NULL DEFAULT 0,
+    amount_off BIGINT NOT NULL DEFAULT 0,
+    -- currency of amount_off and min_order_amount
+    currency CHAR(3) NOT NULL,
+    min_order_amount BIGINT NOT NULL DEFAULT 0,
+    -- NULL limits are unlimited
+    max_redemptions_per_code INTEGER,
+    max_redemptions_per_customer INTEGER,
+    starts_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Batches of generated codes
+CREATE TABLE IF NOT EXISTS batches (
+    id VARCHAR(36) PRIMARY KEY,
+    campaign_id VARCHAR(36) NOT NULL REFERENCES campaigns(id),
+    prefix VARCHAR(20) NOT NULL DEFAULT '',
+    size INTEGER NOT NULL,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_batches_campaign ON batches(campaign_id, created_at DESC);
+
+-- Codes, stored uppercase. redemption_count counts redemptions not
+-- released, to enforce max_redemptions_per_code.
+CREATE TABLE IF NOT EXISTS coupons (
+    code VARCHAR(50) PRIMARY KEY,
+    campaign_id VARCHAR(36) NOT NULL REFERENCES campaigns(id),
+    -- batch_id is NULL for codes created one at a time
+    batch_id VARCHAR(36) REFERENCES batches(id),
+    redemption_count INTEGER NOT NULL DEFAULT 0 CHECK (redemption_count >= 0),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_coupons_campaign ON coupons(campaign_id, code);
+CREATE INDEX IF NOT EXISTS idx_coupons_batch ON coupons(batch_id, code);
+
+-- Redemptions, one per code and order. Released redemptions, of cancelled
+-- orders, no longer count towards limits.
+CREATE TABLE IF NOT EXISTS redemptions (
+    id VARCHAR(36) PRIMARY KEY,
+    code VARCHAR(50) NOT NULL REFERENCES coupons(code),
+    campaign_id VARCHAR(36) NOT NULL REFERENCES campaigns(id),
+    user_id VARCHAR(255) NOT NULL,
+    order_id VARCHAR(255) NOT NULL,
+    order_amount BIGINT NOT NULL,
+    discount_amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('redeemed', 'released')),
+    redeemed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    released_at TIMESTAMP,
+    UNIQUE (code, order_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_redemptions_order ON redemptions(order_id);
+CREATE INDEX IF NOT EXISTS idx_redemptions_campaign_user ON redemptions(campaign_id, user_id) WHERE status = 'redeemed';
+CREATE INDEX IF NOT EXISTS idx_redemptions_campaign_time ON redemptions(campaign_id, redeemed_at);
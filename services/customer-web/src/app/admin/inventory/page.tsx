@@ -44,6 +44,7 @@ export default function AdminInventoryPage() {
   const inventoryApi = axios.create({
     baseURL: process.env.NEXT_PUBLIC_INVENTORY_SERVICE_URL || 'http://localhost:8001',
     timeout: 10000,
+    withCredentials: true, // Stock management requires the auth cookie
   });
 
   const catalogApi = axios.create({
@@ -65,7 +66,7 @@ export default function AdminInventoryPage() {
         items = response.data;
       } else {
         const response = await inventoryApi.get('/api/v1/inventory', {
-          params: { limit: 100, offset: 0 },
+          params: { limit: 100 },
         });
         items = response.data.items || [];
       }
@@ -0,0 +1,614 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/audit"
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce-platform/shared/go/pagination"
+	"github.com/ecommerce/giftcard-service/internal/codes"
+	"github.com/ecommerce/giftcard-service/internal/delivery"
+	"github.com/ecommerce/giftcard-service/internal/domain"
+	"github.com/ecommerce/giftcard-service/internal/metrics"
+	"github.com/ecommerce/giftcard-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// PermissionManage allows issuing, listing, reloading and disabling cards
+// and releasing any order's redemptions; admins have it
+const PermissionManage = "giftcards:manage"
+
+// CodeGiftCardRejected is the error code of issues, redemptions and reloads
+// refused by the card's state or the policy; the reason field says why
+const CodeGiftCardRejected = "GIFT_CARD_REJECTED"
+
+func init() {
+	apperrors.Register(CodeGiftCardRejected, http.StatusUnprocessableEntity, "The gift card can't be issued, redeemed or reloaded; reason says why")
+}
+
+// Handler serves the gift card API
+type Handler struct {
+	repo      repository.GiftCardRepository
+	deliverer *delivery.Deliverer
+	policy    domain.Policy
+	auditor   *audit.Auditor
+	logger    *zap.Logger
+}
+
+// NewHandler creates a new handler
+func NewHandler(repo repository.GiftCardRepository, deliverer *delivery.Deliverer, policy domain.Policy, auditor *audit.Auditor, logger *zap.Logger) *Handler {
+	return &Handler{
+		repo:      repo,
+		deliverer: deliverer,
+		policy:    policy,
+		auditor:   auditor,
+		logger:    logger,
+	}
+}
+
+// RecipientRequest is who a card is emailed to
+type RecipientRequest struct {
+	Email      string `json:"email" binding:"required,email,max=255"`
+	Name       string `json:"name" binding:"max=200"`
+	SenderName string `json:"sender_name" binding:"max=200"`
+	Message    string `json:"message" binding:"max=1000"`
+}
+
+// IssueBatchRequest issues cards of the same amount; with recipients, one
+// is emailed to each
+type IssueBatchRequest struct {
+	Count      int                `json:"count" binding:"omitempty,min=1,max=1000"`
+	Amount     *money.Money       `json:"amount" binding:"required"`
+	Prefix     string             `json:"prefix" binding:"omitempty,max=20,alphanum"`
+	ExpiresAt  *time.Time         `json:"expires_at"`
+	Reloadable bool               `json:"reloadable"`
+	Recipients []RecipientRequest `json:"recipients" binding:"omitempty,max=1000,dive"`
+}
+
+// PurchaseRequest issues a card a customer bought, once the purchase is
+// paid for
+type PurchaseRequest struct {
+	// PurchaseID identifies the purchase, e.g. the order line, making
+	// retries safe
+	PurchaseID  string            `json:"purchase_id" binding:"required,max=255"`
+	OrderID     string            `json:"order_id" binding:"max=255"`
+	PurchaserID string            `json:"purchaser_id" binding:"max=255"`
+	Amount      *money.Money      `json:"amount" binding:"required"`
+	Recipient   *RecipientRequest `json:"recipient" binding:"required"`
+}
+
+// ReloadRequest adds to a card's balance; purchases reloading a card set
+// PurchaseID, which makes retries safe
+type ReloadRequest struct {
+	Amount     *money.Money `json:"amount" binding:"required"`
+	PurchaseID string       `json:"purchase_id" binding:"max=255"`
+}
+
+// BalanceRequest asks for a card's balance
+type BalanceRequest struct {
+	Code string `json:"code" binding:"required,max=50"`
+}
+
+// RedeemRequest uses a card on an order
+type RedeemRequest struct {
+	Code    string `json:"code" binding:"required,max=50"`
+	OrderID string `json:"order_id" binding:"required,max=255"`
+	// AmountDue is what is left to pay of the order; the card pays up to
+	// its balance of it
+	AmountDue *money.Money `json:"amount_due" binding:"required"`
+}
+
+// Balance is what a card has left to spend
+type Balance struct {
+	// Code is masked, all but its last four characters
+	Code      string      `json:"code"`
+	Status    string      `json:"status"`
+	Balance   money.Money `json:"balance"`
+	ExpiresAt *time.Time  `json:"expires_at,omitempty"`
+}
+
+// IssueBatch issues a batch of cards, emailing them to recipients if given
+// POST /api/v1/gift-cards/batches
+func (h *Handler) IssueBatch(c *gin.Context) {
+	var req IssueBatchRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	var invalid apperrors.ValidationErrors
+	if err := validateAmount("amount", req.Amount); err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+	count := req.Count
+	switch {
+	case len(req.Recipients) > 0 && count != 0 && count != len(req.Recipients):
+		invalid.Add("count", "eqfield", "count must match the number of recipients")
+	case len(req.Recipients) > 0:
+		count = len(req.Recipients)
+	case count == 0:
+		invalid.Add("count", "required", "count or recipients is required")
+	}
+	now := time.Now().UTC()
+	expiresAt := h.policy.ExpiresAt(now)
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(now) {
+			invalid.Add("expires_at", "gt", "expires_at must be in the future")
+		}
+		at := req.ExpiresAt.UTC()
+		expiresAt = &at
+	}
+	if err := invalid.Err(); err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+	if err := h.policy.CheckIssue(*req.Amount); err != nil {
+		abortRejected(c, err)
+		return
+	}
+
+	createdBy := c.GetString(sharedauth.ContextUserID)
+	batch := &domain.Batch{
+		Prefix:    codes.Normalize(req.Prefix),
+		Size:      count,
+		Amount:    *req.Amount,
+		CreatedBy: createdBy,
+	}
+	template := domain.GiftCard{
+		InitialBalance: *req.Amount,
+		Balance:        *req.Amount,
+		Reloadable:     req.Reloadable,
+		ExpiresAt:      expiresAt,
+		CreatedBy:      createdBy,
+	}
+	recipients := make([]domain.Recipient, len(req.Recipients))
+	for i, recipient := range req.Recipients {
+		recipients[i] = domain.Recipient(recipient)
+	}
+
+	ctx := c.Request.Context()
+	cards, err := h.repo.IssueBatch(ctx, batch, template, recipients, func(n int) ([]string, error) {
+		return codes.Generate(batch.Prefix, n)
+	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to issue gift cards"))
+		return
+	}
+
+	metrics.CardsIssuedTotal.WithLabelValues(string(domain.SourceBulk)).Add(float64(len(cards)))
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   "gift_cards.issued",
+		Resource: audit.Resource{Type: "gift_card_batch", ID: batch.ID},
+		After:    batch,
+	})
+	h.deliverer.Deliver(ctx, cards)
+
+	c.JSON(http.StatusCreated, gin.H{"batch": batch, "cards": cards})
+}
+
+// IssuePurchase issues a card a customer bought and emails it to its
+// recipient. Issuing the same purchase again returns the card issued
+// first, so the service selling cards can retry.
+// POST /api/v1/internal/gift-cards/purchases
+func (h *Handler) IssuePurchase(c *gin.Context) {
+	var req PurchaseRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	if err := validateAmount("amount", req.Amount); err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+	if err := h.policy.CheckIssue(*req.Amount); err != nil {
+		abortRejected(c, err)
+		return
+	}
+
+	recipient := domain.Recipient(*req.Recipient)
+	card := &domain.GiftCard{
+		InitialBalance: *req.Amount,
+		Balance:        *req.Amount,
+		PurchaseID:     req.PurchaseID,
+		PurchaserID:    req.PurchaserID,
+		OrderID:        req.OrderID,
+		Recipient:      &recipient,
+		DeliveryStatus: domain.DeliveryPending,
+		Reloadable:     true,
+		ExpiresAt:      h.policy.ExpiresAt(time.Now()),
+	}
+
+	ctx := c.Request.Context()
+	issued, created, err := h.repo.IssuePurchase(ctx, card, func(n int) ([]string, error) {
+		return codes.Generate("", n)
+	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to issue gift card"))
+		return
+	}
+	if !created {
+		c.JSON(http.StatusOK, issued)
+		return
+	}
+
+	metrics.CardsIssuedTotal.WithLabelValues(string(domain.SourcePurchase)).Inc()
+	h.deliverer.Deliver(ctx, []*domain.GiftCard{issued})
+
+	c.JSON(http.StatusCreated, issued)
+}
+
+// ListCards lists a page of cards, sorted by code, optionally only a
+// batch's, a source's or a customer's purchases
+// GET /api/v1/gift-cards/cards?batch_id=&source=&purchaser_id=&limit=&cursor=
+func (h *Handler) ListCards(c *gin.Context) {
+	params := pagination.FromQuery(c.Request.URL.Query())
+
+	var after *repository.CardPosition
+	if params.Cursor != "" {
+		after = &repository.CardPosition{}
+		if err := pagination.DecodeCursor(params.Cursor, after); err != nil {
+			apperrors.Abort(c, apperrors.NewBadRequest("Invalid cursor"))
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	filter := repository.CardFilter{
+		BatchID:     c.Query("batch_id"),
+		Source:      domain.Source(c.Query("source")),
+		PurchaserID: c.Query("purchaser_id"),
+	}
+	cards, err := h.repo.ListCards(ctx, filter, params.Limit+1, after)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Gift card"))
+		return
+	}
+
+	total, err := h.repo.CountCards(ctx, filter)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Gift card"))
+		return
+	}
+
+	page, err := pagination.NewPage(cards, params.Limit, total, func(card *domain.GiftCard) interface{} {
+		return repository.CardPosition{Code: card.Code}
+	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list gift cards"))
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetCard returns a card and its transactions
+// GET /api/v1/gift-cards/cards/:code
+func (h *Handler) GetCard(c *gin.Context) {
+	card, ok := h.card(c)
+	if !ok {
+		return
+	}
+
+	transactions, err := h.repo.Transactions(c.Request.Context(), card.Code)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Transaction"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"card": card, "transactions": transactions})
+}
+
+// ReloadCard adds to a card's balance on behalf of staff, e.g. as a
+// goodwill gesture
+// POST /api/v1/gift-cards/cards/:code/reloads
+func (h *Handler) ReloadCard(c *gin.Context) {
+	var req ReloadRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	before, ok := h.card(c)
+	if !ok {
+		return
+	}
+
+	card, created, ok := h.reload(c, before.Code, req, c.GetString(sharedauth.ContextUserID))
+	if !ok {
+		return
+	}
+	if created {
+		h.auditor.LogGin(c, audit.Entry{
+			Action:   "gift_card.reloaded",
+			Resource: audit.Resource{Type: "gift_card", ID: domain.Mask(card.Code)},
+			Before:   before.Balance,
+			After:    card.Balance,
+		})
+	}
+
+	c.JSON(http.StatusOK, card)
+}
+
+// ReloadPurchase adds a purchased top-up to a card; reloading with the
+// same purchase_id again changes nothing
+// POST /api/v1/internal/gift-cards/:code/reloads
+func (h *Handler) ReloadPurchase(c *gin.Context) {
+	var req ReloadRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	if req.PurchaseID == "" {
+		apperrors.Abort(c, apperrors.NewValidation(apperrors.FieldError{
+			Field: "purchase_id", Rule: "required", Message: "purchase_id is required",
+		}))
+		return
+	}
+
+	card, _, ok := h.reload(c, codes.Normalize(c.Param("code")), req, "")
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, card)
+}
+
+// reload applies a reload, aborting with why the policy refused it
+func (h *Handler) reload(c *gin.Context, code string, req ReloadRequest, createdBy string) (*domain.GiftCard, bool, bool) {
+	if err := validateAmount("amount", req.Amount); err != nil {
+		apperrors.Abort(c, err)
+		return nil, false, false
+	}
+
+	card, created, err := h.repo.Reload(c.Request.Context(), repository.ReloadRequest{
+		Code:      code,
+		Amount:    *req.Amount,
+		Reference: req.PurchaseID,
+		CreatedBy: createdBy,
+		Policy:    h.policy,
+		At:        time.Now(),
+	})
+	if errors.Is(err, domain.ErrCardNotFound) {
+		metrics.ReloadsTotal.WithLabelValues("not_found").Inc()
+		apperrors.Abort(c, apperrors.NewNotFound("Gift card"))
+		return nil, false, false
+	} else if reason := domain.RejectionReason(err); reason != "" {
+		metrics.ReloadsTotal.WithLabelValues(reason).Inc()
+		abortRejected(c, err)
+		return nil, false, false
+	} else if err != nil {
+		metrics.ReloadsTotal.WithLabelValues("failed").Inc()
+		apperrors.Abort(c, apperrors.FromSQL(err, "Gift card"))
+		return nil, false, false
+	}
+
+	if created {
+		metrics.ReloadsTotal.WithLabelValues("reloaded").Inc()
+	} else {
+		metrics.ReloadsTotal.WithLabelValues("replayed").Inc()
+	}
+	return card, created, true
+}
+
+// DisableCard stops a card from being redeemed or reloaded, e.g. when it
+// was reported stolen; its balance is kept
+// DELETE /api/v1/gift-cards/cards/:code
+func (h *Handler) DisableCard(c *gin.Context) {
+	before, ok := h.card(c)
+	if !ok {
+		return
+	}
+
+	card, err := h.repo.Disable(c.Request.Context(), before.Code)
+	if errors.Is(err, domain.ErrCardNotFound) {
+		apperrors.Abort(c, apperrors.NewNotFound("Gift card"))
+		return
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Gift card"))
+		return
+	}
+
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   "gift_card.disabled",
+		Resource: audit.Resource{Type: "gift_card", ID: domain.Mask(card.Code)},
+		Before:   before.Status(time.Now()),
+		After:    card.Status(time.Now()),
+	})
+
+	c.JSON(http.StatusOK, card)
+}
+
+// ResendCard emails a card to its recipient again, e.g. when they lost
+// the email
+// POST /api/v1/gift-cards/cards/:code/delivery
+func (h *Handler) ResendCard(c *gin.Context) {
+	card, ok := h.card(c)
+	if !ok {
+		return
+	}
+	if card.Recipient == nil {
+		apperrors.Abort(c, apperrors.NewConflict("The gift card has no recipient to email"))
+		return
+	}
+
+	if h.deliverer.Deliver(c.Request.Context(), []*domain.GiftCard{card}) == 0 {
+		apperrors.Abort(c, apperrors.New(http.StatusServiceUnavailable, "Failed to send the gift card; try again later"))
+		return
+	}
+
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   "gift_card.resent",
+		Resource: audit.Resource{Type: "gift_card", ID: domain.Mask(card.Code)},
+	})
+
+	c.JSON(http.StatusOK, card)
+}
+
+// CheckBalance returns what a card has left to spend and whether it can be
+// spent
+// POST /api/v1/gift-cards/balance
+func (h *Handler) CheckBalance(c *gin.Context) {
+	var req BalanceRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	card, err := h.repo.GetCard(c.Request.Context(), codes.Normalize(req.Code))
+	if errors.Is(err, domain.ErrCardNotFound) {
+		metrics.BalanceChecksTotal.WithLabelValues("not_found").Inc()
+		apperrors.Abort(c, apperrors.NewNotFound("Gift card"))
+		return
+	} else if err != nil {
+		metrics.BalanceChecksTotal.WithLabelValues("failed").Inc()
+		apperrors.Abort(c, apperrors.FromSQL(err, "Gift card"))
+		return
+	}
+
+	status := card.Status(time.Now())
+	metrics.BalanceChecksTotal.WithLabelValues(status).Inc()
+	c.JSON(http.StatusOK, Balance{
+		Code:      domain.Mask(card.Code),
+		Status:    status,
+		Balance:   card.Balance,
+		ExpiresAt: card.ExpiresAt,
+	})
+}
+
+// Redeem takes up to the amount due off a card for the caller's order,
+// atomically with its balance. Redeeming the same card on the same order
+// again returns the existing redemption, so checkouts can retry safely.
+// POST /api/v1/gift-cards/redemptions
+func (h *Handler) Redeem(c *gin.Context) {
+	var req RedeemRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	if err := validateAmount("amount_due", req.AmountDue); err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	redemption, created, err := h.repo.Redeem(c.Request.Context(), repository.RedeemRequest{
+		Code:      codes.Normalize(req.Code),
+		UserID:    c.GetString(sharedauth.ContextUserID),
+		OrderID:   req.OrderID,
+		AmountDue: *req.AmountDue,
+		At:        time.Now(),
+	})
+	if reason := domain.RejectionReason(err); reason != "" {
+		metrics.RedemptionsTotal.WithLabelValues(reason).Inc()
+		abortRejected(c, err)
+		return
+	} else if err != nil {
+		metrics.RedemptionsTotal.WithLabelValues("failed").Inc()
+		apperrors.Abort(c, apperrors.FromSQL(err, "Redemption"))
+		return
+	}
+
+	if !created {
+		metrics.RedemptionsTotal.WithLabelValues("replayed").Inc()
+		c.JSON(http.StatusOK, redemption)
+		return
+	}
+
+	metrics.RedemptionsTotal.WithLabelValues("redeemed").Inc()
+	c.JSON(http.StatusCreated, redemption)
+}
+
+// ReleaseRedemption undoes an order's redemption, e.g. when checkout fails
+// after redeeming, returning its amount to the card. The customer who
+// redeemed it, or a holder of giftcards:manage, can release it.
+// DELETE /api/v1/gift-cards/redemptions/:orderId
+func (h *Handler) ReleaseRedemption(c *gin.Context) {
+	ctx := c.Request.Context()
+	orderID := c.Param("orderId")
+
+	redemptions, err := h.repo.OrderRedemptions(ctx, orderID)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Redemption"))
+		return
+	}
+	if len(redemptions) == 0 {
+		apperrors.Abort(c, apperrors.NewNotFound("Redemption"))
+		return
+	}
+
+	claims, _ := sharedauth.ClaimsFrom(c)
+	manager := claims != nil && claims.HasPermission(PermissionManage)
+	if !manager && redemptions[0].UserID != c.GetString(sharedauth.ContextUserID) {
+		apperrors.Abort(c, apperrors.NewForbidden("Only the customer who redeemed the gift card can release it"))
+		return
+	}
+
+	released, err := h.repo.Release(ctx, orderID, time.Now())
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Redemption"))
+		return
+	}
+
+	metrics.RedemptionsReleasedTotal.WithLabelValues("api").Add(float64(len(released)))
+	if manager && len(released) > 0 {
+		h.auditor.LogGin(c, audit.Entry{
+			Action:   "redemption.released",
+			Resource: audit.Resource{Type: "order", ID: orderID},
+			Before:   redemptions,
+			After:    released,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"order_id": orderID, "released": released})
+}
+
+// HealthCheck reports whether the database is reachable
+func (h *Handler) HealthCheck(c *gin.Context) {
+	if err := h.repo.Ping(c.Request.Context()); err != nil {
+		h.logger.Warn("Health check failed", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unhealthy",
+			"service": "giftcard-service",
+			"version": "1.0.0",
+			"error":   "database unavailable",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "healthy",
+		"service": "giftcard-service",
+		"version": "1.0.0",
+	})
+}
+
+// card loads the card of the :code path parameter, aborting with 404 if
+// there is none
+func (h *Handler) card(c *gin.Context) (*domain.GiftCard, bool) {
+	card, err := h.repo.GetCard(c.Request.Context(), codes.Normalize(c.Param("code")))
+	if errors.Is(err, domain.ErrCardNotFound) {
+		apperrors.Abort(c, apperrors.NewNotFound("Gift card"))
+		return nil, false
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Gift card"))
+		return nil, false
+	}
+	return card, true
+}
+
+// abortRejected aborts with CodeGiftCardRejected and the reason err gives
+func abortRejected(c *gin.Context, err error) {
+	apperrors.Abort(c, apperrors.NewCode(CodeGiftCardRejected, err.Error()).
+		WithFields(map[string]interface{}{"reason": domain.RejectionReason(err)}))
+}
+
+// validateAmount rejects negative amounts and currencies that aren't ISO
+// 4217 codes
+func validateAmount(field string, amount *money.Money) *apperrors.AppError {
+	var invalid apperrors.ValidationErrors
+	if amount.IsNegative() {
+		invalid.Add(field+".minor_units", "min", field+" must not be negative")
+	}
+	if len(amount.Currency) != 3 {
+		invalid.Add(field+".currency", "len", "currency must be an ISO 4217 code, e.g. USD")
+	}
+	return invalid.Err()
+}
@@ -0,0 +1,60 @@
+// Package codes generates gift card codes that are hard to guess and easy
+// to type
+package codes
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+)
+
+// alphabet leaves out characters easily mistaken for others: 0, O, 1, I, L
+const alphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// Length is the number of random characters of a generated code. Codes
+// carry money, so they are longer than coupon codes: with 31 characters
+// that is about 7 * 10^23 codes.
+const Length = 16
+
+// Generate returns n distinct codes, each prefix followed by Length random
+// characters. Codes may still collide with existing ones; callers skip
+// those and generate more.
+func Generate(prefix string, n int) ([]string, error) {
+	prefix = Normalize(prefix)
+	seen := make(map[string]bool, n)
+	codes := make([]string, 0, n)
+	for len(codes) < n {
+		code, err := generate(prefix)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	return codes, nil
+}
+
+func generate(prefix string) (string, error) {
+	var b strings.Builder
+	b.Grow(len(prefix) + Length)
+	b.WriteString(prefix)
+
+	max := big.NewInt(int64(len(alphabet)))
+	for i := 0; i < Length; i++ {
+		index, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(alphabet[index.Int64()])
+	}
+	return b.String(), nil
+}
+
+// Normalize returns code as stored: uppercase, without the spaces and
+// dashes customers type codes with, e.g. from a printed card
+func Normalize(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return strings.NewReplacer(" ", "", "-", "").Replace(code)
+}
@@ -0,0 +1,109 @@
+// Package delivery publishes the gift_card.issued events that email cards
+// to their recipients, and publishes again those that failed
+package delivery
+
+import (
+	"context"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/giftcard-service/internal/domain"
+	"github.com/ecommerce/giftcard-service/internal/events"
+	"github.com/ecommerce/giftcard-service/internal/metrics"
+	"github.com/ecommerce/giftcard-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// retryBatchSize is how many pending cards each retry publishes at most
+const retryBatchSize = 100
+
+// Deliverer publishes cards' events and records which were published.
+// Cards are stored pending before their event is published, so a card
+// whose event failed is published again by Run rather than never emailed.
+type Deliverer struct {
+	repo      repository.GiftCardRepository
+	publisher events.Publisher
+	logger    *zap.Logger
+}
+
+// NewDeliverer creates a deliverer
+func NewDeliverer(repo repository.GiftCardRepository, publisher events.Publisher, logger *zap.Logger) *Deliverer {
+	return &Deliverer{repo: repo, publisher: publisher, logger: logger}
+}
+
+// Deliver publishes the events of the cards that have a recipient and
+// returns how many were published and recorded. Failures are logged and
+// left pending for Run.
+func (d *Deliverer) Deliver(ctx context.Context, cards []*domain.GiftCard) int {
+	logger := logging.WithContext(ctx, d.logger)
+
+	var delivered []string
+	for _, card := range cards {
+		if card.Recipient == nil {
+			continue
+		}
+		if err := d.publisher.PublishIssued(ctx, card); err != nil {
+			metrics.DeliveriesTotal.WithLabelValues("failed").Inc()
+			logger.Warn("Failed to publish gift card delivery, will retry",
+				zap.String("code", domain.Mask(card.Code)),
+				zap.Error(err),
+			)
+			continue
+		}
+		metrics.DeliveriesTotal.WithLabelValues("published").Inc()
+		delivered = append(delivered, card.Code)
+	}
+	if len(delivered) == 0 {
+		return 0
+	}
+
+	// A card left pending after publishing is published again: the
+	// recipient may get the email twice, but never not at all
+	at := time.Now()
+	if err := d.repo.MarkDelivered(ctx, delivered, at); err != nil {
+		logger.Error("Failed to record gift card deliveries", zap.Int("cards", len(delivered)), zap.Error(err))
+		return 0
+	}
+	sent := make(map[string]bool, len(delivered))
+	for _, code := range delivered {
+		sent[code] = true
+	}
+	for _, card := range cards {
+		if sent[card.Code] {
+			card.DeliveryStatus, card.DeliveredAt = domain.DeliverySent, &at
+		}
+	}
+	return len(delivered)
+}
+
+// Run publishes the events of pending cards every interval until ctx is
+// cancelled. Cards younger than an interval are left to the request that
+// issued them.
+func (d *Deliverer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				cards, err := d.repo.PendingDeliveries(ctx, time.Now().Add(-interval), retryBatchSize)
+				if err != nil {
+					d.logger.Error("Failed to load pending gift card deliveries", zap.Error(err))
+					break
+				}
+				if len(cards) == 0 {
+					break
+				}
+				d.logger.Info("Retrying gift card deliveries", zap.Int("cards", len(cards)))
+				// Stop until the next tick once some fail, e.g. while Kafka
+				// is down
+				if d.Deliver(ctx, cards) < len(cards) {
+					break
+				}
+			}
+		}
+	}
+}
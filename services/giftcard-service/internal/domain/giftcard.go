@@ -0,0 +1,295 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/money"
+)
+
+// Source is how a card was issued
+type Source string
+
+const (
+	// SourceBulk cards are issued by staff in batches, e.g. for promotions
+	// or corporate orders
+	SourceBulk Source = "bulk"
+	// SourcePurchase cards are bought by a customer, for themselves or as a
+	// gift
+	SourcePurchase Source = "purchase"
+)
+
+// DeliveryStatus is whether a card's gift_card.issued event, which emails
+// the code to its recipient, has been published
+type DeliveryStatus string
+
+const (
+	// DeliveryNone cards have no recipient to email; staff hand their codes
+	// out
+	DeliveryNone DeliveryStatus = "none"
+	// DeliveryPending cards are waiting for their event to be published
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliverySent cards' events were published
+	DeliverySent DeliveryStatus = "sent"
+)
+
+// Recipient is who a card is emailed to
+type Recipient struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+	// SenderName and Message are shown in the delivery email
+	SenderName string `json:"sender_name,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+// GiftCard is a code with a balance spent at checkout
+type GiftCard struct {
+	Code           string      `json:"code"`
+	InitialBalance money.Money `json:"initial_balance"`
+	// Balance is what is left to spend, in the card's currency
+	Balance money.Money `json:"balance"`
+	Source  Source      `json:"source"`
+	BatchID string      `json:"batch_id,omitempty"`
+	// PurchaseID is the purchase the card was bought with, which makes
+	// issuing it idempotent
+	PurchaseID  string `json:"purchase_id,omitempty"`
+	PurchaserID string `json:"purchaser_id,omitempty"`
+	OrderID     string `json:"order_id,omitempty"`
+	// Recipient is nil for cards not emailed to anyone
+	Recipient      *Recipient     `json:"recipient,omitempty"`
+	DeliveryStatus DeliveryStatus `json:"delivery_status"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty"`
+	// Reloadable cards can be topped up, within the reload policy
+	Reloadable bool       `json:"reloadable"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Disabled   bool       `json:"disabled"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Currency is the currency of the card's balance and the orders it pays
+func (g *GiftCard) Currency() string {
+	return g.Balance.Currency
+}
+
+// Status is the card's state at t: active, empty, expired or disabled
+func (g *GiftCard) Status(t time.Time) string {
+	switch {
+	case g.Disabled:
+		return "disabled"
+	case g.ExpiresAt != nil && !t.Before(*g.ExpiresAt):
+		return "expired"
+	case g.Balance.MinorUnits <= 0:
+		return "empty"
+	default:
+		return "active"
+	}
+}
+
+// CheckRedemption returns why the card can't pay towards an order in
+// currency at t, or nil
+func (g *GiftCard) CheckRedemption(currency string, t time.Time) error {
+	switch g.Status(t) {
+	case "disabled":
+		return ErrDisabled
+	case "expired":
+		return ErrExpired
+	case "empty":
+		return ErrEmpty
+	}
+	if currency != g.Currency() {
+		return ErrCurrencyMismatch
+	}
+	return nil
+}
+
+// Applied is how much of amountDue the card pays: all of it, or the card's
+// whole balance if that is less
+func (g *GiftCard) Applied(amountDue money.Money) money.Money {
+	applied := amountDue.MinorUnits
+	if applied > g.Balance.MinorUnits {
+		applied = g.Balance.MinorUnits
+	}
+	if applied < 0 {
+		applied = 0
+	}
+	return money.New(applied, g.Currency())
+}
+
+// Policy limits the amounts cards are issued and reloaded with and sets
+// how long they last
+type Policy struct {
+	// MinAmount is the smallest amount a card is issued or reloaded with,
+	// in the card's minor units
+	MinAmount int64
+	// MaxBalance is the most a card may hold, on issue and after reloads
+	MaxBalance int64
+	// Validity is how long cards last from issue; zero never expires them
+	Validity time.Duration
+	// ReloadExtendsExpiry restarts a reloaded card's validity
+	ReloadExtendsExpiry bool
+}
+
+// ExpiresAt is when a card issued or reloaded at t expires, or nil if
+// cards don't expire
+func (p Policy) ExpiresAt(t time.Time) *time.Time {
+	if p.Validity <= 0 {
+		return nil
+	}
+	expiresAt := t.Add(p.Validity).UTC()
+	return &expiresAt
+}
+
+// CheckIssue returns why a card can't be issued with amount, or nil
+func (p Policy) CheckIssue(amount money.Money) error {
+	if amount.MinorUnits < p.MinAmount {
+		return ErrBelowMinimum
+	}
+	if p.MaxBalance > 0 && amount.MinorUnits > p.MaxBalance {
+		return ErrAboveMaxBalance
+	}
+	return nil
+}
+
+// CheckReload returns why card can't be reloaded with amount at t, or nil
+func (p Policy) CheckReload(card *GiftCard, amount money.Money, t time.Time) error {
+	switch {
+	case card.Disabled:
+		return ErrDisabled
+	case !card.Reloadable:
+		return ErrNotReloadable
+	case card.ExpiresAt != nil && !t.Before(*card.ExpiresAt):
+		return ErrExpired
+	case amount.Currency != card.Currency():
+		return ErrCurrencyMismatch
+	case amount.MinorUnits < p.MinAmount:
+		return ErrBelowMinimum
+	case p.MaxBalance > 0 && card.Balance.MinorUnits+amount.MinorUnits > p.MaxBalance:
+		return ErrAboveMaxBalance
+	}
+	return nil
+}
+
+// Batch is a set of cards issued together
+type Batch struct {
+	ID        string      `json:"id"`
+	Prefix    string      `json:"prefix,omitempty"`
+	Size      int         `json:"size"`
+	Amount    money.Money `json:"amount"`
+	CreatedBy string      `json:"created_by,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// RedemptionStatus is whether a redemption still holds the card's balance
+type RedemptionStatus string
+
+const (
+	// RedemptionRedeemed pays towards an order and is taken off the balance
+	RedemptionRedeemed RedemptionStatus = "redeemed"
+	// RedemptionReleased was undone and its amount returned to the card,
+	// e.g. because the order was cancelled
+	RedemptionReleased RedemptionStatus = "released"
+)
+
+// Redemption is a card paying towards an order
+type Redemption struct {
+	ID      string           `json:"id"`
+	Code    string           `json:"code"`
+	UserID  string           `json:"user_id"`
+	OrderID string           `json:"order_id"`
+	Amount  money.Money      `json:"amount"`
+	Status  RedemptionStatus `json:"status"`
+	// Balance is the card's balance after the redemption; it is only set
+	// in the redemption's response
+	Balance    *money.Money `json:"balance,omitempty"`
+	RedeemedAt time.Time    `json:"redeemed_at"`
+	ReleasedAt *time.Time   `json:"released_at,omitempty"`
+}
+
+// TransactionType is what changed a card's balance
+type TransactionType string
+
+const (
+	TransactionIssue   TransactionType = "issue"
+	TransactionRedeem  TransactionType = "redeem"
+	TransactionRelease TransactionType = "release"
+	TransactionReload  TransactionType = "reload"
+)
+
+// Transaction is a change to a card's balance. Amounts are positive for
+// issues, releases and reloads and negative for redemptions, so a card's
+// transactions sum to its balance.
+type Transaction struct {
+	ID           string          `json:"id"`
+	Code         string          `json:"code"`
+	Type         TransactionType `json:"type"`
+	Amount       money.Money     `json:"amount"`
+	BalanceAfter money.Money     `json:"balance_after"`
+	OrderID      string          `json:"order_id,omitempty"`
+	// Reference identifies a reload's purchase, making it idempotent
+	Reference string    `json:"reference,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Common errors
+var (
+	ErrCardNotFound       = errors.New("gift card not found")
+	ErrRedemptionNotFound = errors.New("redemption not found")
+)
+
+// Reasons a card can't be issued, redeemed or reloaded
+var (
+	ErrDisabled         = errors.New("gift card has been disabled")
+	ErrExpired          = errors.New("gift card has expired")
+	ErrEmpty            = errors.New("gift card has no balance left")
+	ErrCurrencyMismatch = errors.New("gift card is not valid for the order's currency")
+	ErrNotReloadable    = errors.New("gift card can't be reloaded")
+	ErrBelowMinimum     = errors.New("amount is below the minimum")
+	ErrAboveMaxBalance  = errors.New("amount would take the card over its maximum balance")
+	// ErrOrderRedeemed is returned when the order already redeemed another
+	// card
+	ErrOrderRedeemed = errors.New("order already has a gift card")
+)
+
+// RejectionReason is the stable reason code of an error refusing a card's
+// redemption or reload, or "" for other errors
+func RejectionReason(err error) string {
+	switch {
+	case errors.Is(err, ErrCardNotFound):
+		return "not_found"
+	case errors.Is(err, ErrDisabled):
+		return "disabled"
+	case errors.Is(err, ErrExpired):
+		return "expired"
+	case errors.Is(err, ErrEmpty):
+		return "empty"
+	case errors.Is(err, ErrCurrencyMismatch):
+		return "currency_mismatch"
+	case errors.Is(err, ErrNotReloadable):
+		return "not_reloadable"
+	case errors.Is(err, ErrBelowMinimum):
+		return "below_minimum"
+	case errors.Is(err, ErrAboveMaxBalance):
+		return "max_balance"
+	case errors.Is(err, ErrOrderRedeemed):
+		return "order_redeemed"
+	default:
+		return ""
+	}
+}
+
+// Mask hides all but the last four characters of a code, for logs and
+// responses to anyone but staff
+func Mask(code string) string {
+	if len(code) <= 4 {
+		return code
+	}
+	masked := make([]byte, len(code))
+	for i := range masked {
+		masked[i] = '*'
+	}
+	copy(masked[len(code)-4:], code[len(code)-4:])
+	return string(masked)
+}
@@ -0,0 +1,75 @@
+package events
+
+import (
+	"context"
+
+	sharedevents "github.com/ecommerce-platform/shared/go/events"
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	"github.com/ecommerce/giftcard-service/internal/domain"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// Publisher publishes gift_card.issued events, for notification-service to
+// email cards to their recipients
+type Publisher interface {
+	PublishIssued(ctx context.Context, card *domain.GiftCard) error
+	Close() error
+}
+
+type kafkaPublisher struct {
+	producer *sharedkafka.Producer
+	logger   *zap.Logger
+}
+
+// NewKafkaPublisher creates a publisher. Events are keyed by code, so a
+// card's events are handled in order.
+func NewKafkaPublisher(brokers []string, topic string, logger *zap.Logger) Publisher {
+	producer := sharedkafka.NewProducer(sharedkafka.ProducerConfig{
+		Brokers: brokers,
+		Topic:   topic,
+	}, logger)
+
+	return &kafkaPublisher{
+		producer: producer,
+		logger:   logger,
+	}
+}
+
+// PublishIssued publishes a card's gift_card.issued event; the card must
+// have a recipient
+func (p *kafkaPublisher) PublishIssued(ctx context.Context, card *domain.GiftCard) error {
+	issued := &sharedevents.GiftCardIssued{
+		Code:           card.Code,
+		Balance:        card.Balance,
+		ExpiresAt:      card.ExpiresAt,
+		RecipientEmail: card.Recipient.Email,
+		RecipientName:  card.Recipient.Name,
+		SenderName:     card.Recipient.SenderName,
+		Message:        card.Recipient.Message,
+		PurchaserID:    card.PurchaserID,
+		OrderID:        card.OrderID,
+	}
+	data, err := sharedevents.Marshal(sharedevents.Envelope{OrderID: card.OrderID, UserID: card.PurchaserID}, issued)
+	if err != nil {
+		p.logger.Error("Failed to marshal event", zap.Error(err))
+		return err
+	}
+
+	message := kafka.Message{
+		Key:   []byte(card.Code),
+		Value: data,
+	}
+
+	if err := p.producer.Publish(ctx, message); err != nil {
+		p.logger.Error("Failed to publish event", zap.Error(err), zap.String("event_type", issued.EventType()))
+		return err
+	}
+
+	p.logger.Debug("Event published", zap.String("event_type", issued.EventType()), zap.String("code", domain.Mask(card.Code)))
+	return nil
+}
+
+func (p *kafkaPublisher) Close() error {
+	return p.producer.Close()
+}
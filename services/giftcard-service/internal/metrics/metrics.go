@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// CardsIssuedTotal counts cards issued, by source: bulk or purchase
+	CardsIssuedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "giftcard_cards_issued_total",
+		Help: "Gift cards issued per source",
+	}, []string{"source"})
+
+	// BalanceChecksTotal counts balance checks by result: the card's
+	// status, not_found or failed
+	BalanceChecksTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "giftcard_balance_checks_total",
+		Help: "Gift card balance checks per result",
+	}, []string{"result"})
+
+	// RedemptionsTotal counts redemption attempts by result: redeemed,
+	// replayed (already redeemed on the order), a rejection reason, or
+	// failed
+	RedemptionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "giftcard_redemptions_total",
+		Help: "Gift card redemption attempts per result",
+	}, []string{"result"})
+
+	// RedemptionsReleasedTotal counts redemptions undone, by trigger: api
+	// or order_event
+	RedemptionsReleasedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "giftcard_redemptions_released_total",
+		Help: "Gift card redemptions released per trigger",
+	}, []string{"trigger"})
+
+	// ReloadsTotal counts reload attempts by result: reloaded, replayed, a
+	// rejection reason, or failed
+	ReloadsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "giftcard_reloads_total",
+		Help: "Gift card reload attempts per result",
+	}, []string{"result"})
+
+	// DeliveriesTotal counts gift_card.issued events by result: published
+	// or failed, to be retried
+	DeliveriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "giftcard_deliveries_total",
+		Help: "Gift card delivery events per result",
+	}, []string{"result"})
+
+	// OrderEventsTotal counts consumed order events by outcome: released,
+	// skipped (event types or orders without redemptions) or failed
+	OrderEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "giftcard_order_events_total",
+		Help: "Order events applied to gift card redemptions, per event type and result",
+	}, []string{"event_type", "result"})
+)
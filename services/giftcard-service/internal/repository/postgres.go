@@ -0,0 +1,633 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	shareddb "github.com/ecommerce-platform/shared/go/db"
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce/giftcard-service/internal/domain"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// maxGenerateAttempts bounds how often colliding codes are generated
+// again; with random codes even one retry is rare
+const maxGenerateAttempts = 5
+
+const cardColumns = `
+	code, initial_balance, balance, currency, source, batch_id, purchase_id, purchaser_id, order_id,
+	recipient_email, recipient_name, sender_name, message, delivery_status, delivered_at,
+	reloadable, expires_at, disabled, created_by, created_at, updated_at
+`
+
+const redemptionColumns = `id, code, user_id, order_id, amount, currency, status, redeemed_at, released_at`
+
+const transactionColumns = `
+	id, code, type, amount, balance_after, currency, order_id, reference, created_by, created_at
+`
+
+type postgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *sql.DB) GiftCardRepository {
+	return &postgresRepository{db: db}
+}
+
+// Ping checks that the database is reachable
+func (r *postgresRepository) Ping(ctx context.Context) error {
+	return shareddb.Check(ctx, r.db)
+}
+
+// IssueBatch stores a batch and its cards in one transaction, skipping
+// codes that already exist and generating others in their place
+func (r *postgresRepository) IssueBatch(ctx context.Context, batch *domain.Batch, card domain.GiftCard, recipients []domain.Recipient, generate func(n int) ([]string, error)) ([]*domain.GiftCard, error) {
+	now := time.Now().UTC()
+	batch.ID = uuid.New().String()
+	batch.CreatedAt = now
+
+	var issued []*domain.GiftCard
+	err := shareddb.WithTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO batches (id, prefix, size, amount, currency, created_by, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, batch.ID, batch.Prefix, batch.Size, batch.Amount.MinorUnits, batch.Amount.Currency, batch.CreatedBy, batch.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		issued = make([]*domain.GiftCard, 0, batch.Size)
+		for attempt := 0; len(issued) < batch.Size; attempt++ {
+			if attempt == maxGenerateAttempts {
+				return fmt.Errorf("generated codes kept colliding: stored %d of %d", len(issued), batch.Size)
+			}
+			codes, err := generate(batch.Size - len(issued))
+			if err != nil {
+				return err
+			}
+
+			for _, code := range codes {
+				next := card
+				next.Code = code
+				next.Source = domain.SourceBulk
+				next.BatchID = batch.ID
+				next.CreatedAt, next.UpdatedAt = now, now
+				next.Recipient, next.DeliveryStatus = nil, domain.DeliveryNone
+				if len(recipients) > 0 {
+					recipient := recipients[len(issued)]
+					next.Recipient, next.DeliveryStatus = &recipient, domain.DeliveryPending
+				}
+
+				stored, err := insertCard(ctx, tx, &next)
+				if err != nil {
+					return err
+				}
+				if stored {
+					issued = append(issued, &next)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return issued, nil
+}
+
+// IssuePurchase stores a purchased card, or finds the card already issued
+// for the purchase
+func (r *postgresRepository) IssuePurchase(ctx context.Context, card *domain.GiftCard, generate func(n int) ([]string, error)) (*domain.GiftCard, bool, error) {
+	now := time.Now().UTC()
+	card.Source = domain.SourcePurchase
+	card.CreatedAt, card.UpdatedAt = now, now
+
+	for attempt := 0; attempt < maxGenerateAttempts; attempt++ {
+		codes, err := generate(1)
+		if err != nil {
+			return nil, false, err
+		}
+		card.Code = codes[0]
+
+		var stored bool
+		err = shareddb.WithTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+			stored, err = insertCard(ctx, tx, card)
+			return err
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		if stored {
+			return card, true, nil
+		}
+
+		// Either the purchase was issued already, or the code collided and
+		// another is tried
+		existing, err := scanCard(r.db.QueryRowContext(ctx,
+			`SELECT `+cardColumns+` FROM gift_cards WHERE purchase_id = $1`, card.PurchaseID))
+		if err == nil {
+			return existing, false, nil
+		}
+		if err != sql.ErrNoRows {
+			return nil, false, err
+		}
+	}
+	return nil, false, fmt.Errorf("generated codes kept colliding")
+}
+
+// insertCard stores a card and its issue transaction, unless its code or
+// purchase already exists, and reports whether it did
+func insertCard(ctx context.Context, tx *sql.Tx, card *domain.GiftCard) (bool, error) {
+	var recipient domain.Recipient
+	if card.Recipient != nil {
+		recipient = *card.Recipient
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO gift_cards (`+cardColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+		ON CONFLICT DO NOTHING
+	`,
+		card.Code, card.InitialBalance.MinorUnits, card.Balance.MinorUnits, card.Currency(), card.Source,
+		nullString(card.BatchID), nullString(card.PurchaseID), card.PurchaserID, card.OrderID,
+		recipient.Email, recipient.Name, recipient.SenderName, recipient.Message,
+		card.DeliveryStatus, utcOrNil(card.DeliveredAt), card.Reloadable, utcOrNil(card.ExpiresAt),
+		card.Disabled, card.CreatedBy, card.CreatedAt, card.UpdatedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	return true, insertTransaction(ctx, tx, &domain.Transaction{
+		Code:         card.Code,
+		Type:         domain.TransactionIssue,
+		Amount:       card.Balance,
+		BalanceAfter: card.Balance,
+		OrderID:      card.OrderID,
+		CreatedBy:    card.CreatedBy,
+		CreatedAt:    card.CreatedAt,
+	})
+}
+
+// GetCard returns a card
+func (r *postgresRepository) GetCard(ctx context.Context, code string) (*domain.GiftCard, error) {
+	card, err := scanCard(r.db.QueryRowContext(ctx, `SELECT `+cardColumns+` FROM gift_cards WHERE code = $1`, code))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrCardNotFound
+	}
+	return card, err
+}
+
+// ListCards retrieves up to limit cards after a position
+func (r *postgresRepository) ListCards(ctx context.Context, filter CardFilter, limit int, after *CardPosition) ([]*domain.GiftCard, error) {
+	where, args := cardWhere(filter)
+	if after != nil {
+		args = append(args, after.Code)
+		where += ` AND code > $` + strconv.Itoa(len(args))
+	}
+	args = append(args, limit)
+
+	query := `
+		SELECT ` + cardColumns + `
+		FROM gift_cards
+		WHERE ` + where + `
+		ORDER BY code
+		LIMIT $` + strconv.Itoa(len(args))
+
+	return scanCards(r.db.QueryContext(ctx, query, args...))
+}
+
+// CountCards counts the cards matching filter
+func (r *postgresRepository) CountCards(ctx context.Context, filter CardFilter) (int64, error) {
+	where, args := cardWhere(filter)
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM gift_cards WHERE `+where, args...).Scan(&count)
+	return count, err
+}
+
+func cardWhere(filter CardFilter) (string, []interface{}) {
+	where := `TRUE`
+	var args []interface{}
+	add := func(column, value string) {
+		if value == "" {
+			return
+		}
+		args = append(args, value)
+		where += ` AND ` + column + ` = $` + strconv.Itoa(len(args))
+	}
+	add("batch_id", filter.BatchID)
+	add("source", string(filter.Source))
+	add("purchaser_id", filter.PurchaserID)
+	return where, args
+}
+
+// Transactions returns a card's balance changes, oldest first
+func (r *postgresRepository) Transactions(ctx context.Context, code string) ([]*domain.Transaction, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+transactionColumns+` FROM transactions WHERE code = $1 ORDER BY created_at, id
+	`, code)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := []*domain.Transaction{}
+	for rows.Next() {
+		t := &domain.Transaction{}
+		var amount, balanceAfter int64
+		var currency string
+		err := rows.Scan(
+			&t.ID, &t.Code, &t.Type, &amount, &balanceAfter, &currency,
+			&t.OrderID, &t.Reference, &t.CreatedBy, &t.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		t.Amount = money.New(amount, currency)
+		t.BalanceAfter = money.New(balanceAfter, currency)
+		transactions = append(transactions, t)
+	}
+
+	return transactions, rows.Err()
+}
+
+// Reload adds to a card's balance under the card's row lock, so the
+// maximum balance holds against concurrent reloads and redemptions
+func (r *postgresRepository) Reload(ctx context.Context, req ReloadRequest) (*domain.GiftCard, bool, error) {
+	var card *domain.GiftCard
+	var created bool
+	err := shareddb.WithTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		var err error
+		if card, err = lockCard(ctx, tx, req.Code); err != nil {
+			return err
+		}
+
+		// A purchase reloads once; retries see the first reload under the
+		// card's lock
+		if req.Reference != "" {
+			var exists bool
+			err := tx.QueryRowContext(ctx, `
+				SELECT EXISTS (SELECT 1 FROM transactions WHERE code = $1 AND type = 'reload' AND reference = $2)
+			`, card.Code, req.Reference).Scan(&exists)
+			if err != nil || exists {
+				return err
+			}
+		}
+
+		if err := req.Policy.CheckReload(card, req.Amount, req.At); err != nil {
+			return err
+		}
+
+		at := req.At.UTC()
+		card.Balance = money.New(card.Balance.MinorUnits+req.Amount.MinorUnits, card.Currency())
+		// Restarting the validity never shortens it, nor expires cards
+		// issued without an expiry
+		if expiresAt := req.Policy.ExpiresAt(at); req.Policy.ReloadExtendsExpiry && expiresAt != nil &&
+			card.ExpiresAt != nil && expiresAt.After(*card.ExpiresAt) {
+			card.ExpiresAt = expiresAt
+		}
+		card.UpdatedAt = at
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE gift_cards SET balance = $2, expires_at = $3, updated_at = $4 WHERE code = $1
+		`, card.Code, card.Balance.MinorUnits, utcOrNil(card.ExpiresAt), card.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		err = insertTransaction(ctx, tx, &domain.Transaction{
+			Code:         card.Code,
+			Type:         domain.TransactionReload,
+			Amount:       req.Amount,
+			BalanceAfter: card.Balance,
+			Reference:    req.Reference,
+			CreatedBy:    req.CreatedBy,
+			CreatedAt:    at,
+		})
+		if err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return card, created, nil
+}
+
+// Disable marks a card disabled
+func (r *postgresRepository) Disable(ctx context.Context, code string) (*domain.GiftCard, error) {
+	card, err := scanCard(r.db.QueryRowContext(ctx, `
+		UPDATE gift_cards SET disabled = TRUE, updated_at = $2 WHERE code = $1
+		RETURNING `+cardColumns,
+		code, time.Now().UTC()))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrCardNotFound
+	}
+	return card, err
+}
+
+// Redeem records a redemption and takes it off the card's balance. The
+// card's row lock serializes its redemptions, so concurrent checkouts
+// can't both spend the same balance, and a lock on the order serializes
+// the order's, so it can't be paid with two cards at once.
+func (r *postgresRepository) Redeem(ctx context.Context, req RedeemRequest) (*domain.Redemption, bool, error) {
+	var redemption *domain.Redemption
+	var created bool
+	err := shareddb.WithTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		// Taken before the card's lock, so redemptions lock in the same order
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "redemptions:"+req.OrderID); err != nil {
+			return err
+		}
+
+		card, err := lockCard(ctx, tx, req.Code)
+		if err != nil {
+			return err
+		}
+
+		// An order has one card; redeeming it again changes nothing. This is
+		// read under the order's lock, so redemptions racing each other,
+		// with the same card or another, see the first one's.
+		existing, err := r.queryRedemptions(ctx, tx, `WHERE order_id = $1 AND status = 'redeemed'`, req.OrderID)
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			if existing[0].Code != card.Code {
+				return domain.ErrOrderRedeemed
+			}
+			redemption = existing[0]
+			redemption.Balance = &card.Balance
+			return nil
+		}
+
+		if err := card.CheckRedemption(req.AmountDue.Currency, req.At); err != nil {
+			return err
+		}
+		applied := card.Applied(req.AmountDue)
+		if applied.IsZero() {
+			// Nothing is due; the card isn't needed
+			return domain.ErrEmpty
+		}
+
+		at := req.At.UTC()
+		balance := money.New(card.Balance.MinorUnits-applied.MinorUnits, card.Currency())
+		redemption = &domain.Redemption{
+			ID:         uuid.New().String(),
+			Code:       card.Code,
+			UserID:     req.UserID,
+			OrderID:    req.OrderID,
+			Amount:     applied,
+			Status:     domain.RedemptionRedeemed,
+			Balance:    &balance,
+			RedeemedAt: at,
+		}
+
+		// A released redemption of the card on the order counts again
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO redemptions (id, code, user_id, order_id, amount, currency, status, redeemed_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (code, order_id) DO UPDATE SET
+				user_id = EXCLUDED.user_id, amount = EXCLUDED.amount, currency = EXCLUDED.currency,
+				status = EXCLUDED.status, redeemed_at = EXCLUDED.redeemed_at, released_at = NULL
+			RETURNING id
+		`,
+			redemption.ID, redemption.Code, redemption.UserID, redemption.OrderID,
+			applied.MinorUnits, applied.Currency, redemption.Status, redemption.RedeemedAt,
+		).Scan(&redemption.ID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE gift_cards SET balance = $2, updated_at = $3 WHERE code = $1`,
+			card.Code, balance.MinorUnits, at); err != nil {
+			return err
+		}
+
+		err = insertTransaction(ctx, tx, &domain.Transaction{
+			Code:         card.Code,
+			Type:         domain.TransactionRedeem,
+			Amount:       applied.Neg(),
+			BalanceAfter: balance,
+			OrderID:      req.OrderID,
+			CreatedBy:    req.UserID,
+			CreatedAt:    at,
+		})
+		if err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return redemption, created, nil
+}
+
+// OrderRedemptions returns an order's redemptions, oldest first
+func (r *postgresRepository) OrderRedemptions(ctx context.Context, orderID string) ([]*domain.Redemption, error) {
+	return r.queryRedemptions(ctx, r.db, `WHERE order_id = $1 ORDER BY redeemed_at, id`, orderID)
+}
+
+// Release marks an order's redemptions released and returns their amounts
+// to the cards, in one transaction
+func (r *postgresRepository) Release(ctx context.Context, orderID string, at time.Time) ([]*domain.Redemption, error) {
+	at = at.UTC()
+	var released []*domain.Redemption
+	err := shareddb.WithTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		var err error
+		released, err = r.queryRedemptions(ctx, tx, `WHERE order_id = $1 AND status = 'redeemed' ORDER BY redeemed_at, id FOR UPDATE`, orderID)
+		if err != nil {
+			return err
+		}
+
+		for _, redemption := range released {
+			var balance int64
+			err := tx.QueryRowContext(ctx, `
+				UPDATE gift_cards SET balance = balance + $2, updated_at = $3 WHERE code = $1 RETURNING balance
+			`, redemption.Code, redemption.Amount.MinorUnits, at).Scan(&balance)
+			if err != nil {
+				return err
+			}
+
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE redemptions SET status = 'released', released_at = $2 WHERE id = $1
+			`, redemption.ID, at); err != nil {
+				return err
+			}
+			redemption.Status = domain.RedemptionReleased
+			redemption.ReleasedAt = &at
+
+			err = insertTransaction(ctx, tx, &domain.Transaction{
+				Code:         redemption.Code,
+				Type:         domain.TransactionRelease,
+				Amount:       redemption.Amount,
+				BalanceAfter: money.New(balance, redemption.Amount.Currency),
+				OrderID:      orderID,
+				CreatedAt:    at,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return released, nil
+}
+
+func (r *postgresRepository) queryRedemptions(ctx context.Context, q shareddb.Querier, where string, args ...interface{}) ([]*domain.Redemption, error) {
+	query := `SELECT ` + redemptionColumns + ` FROM redemptions ` + where
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	redemptions := []*domain.Redemption{}
+	for rows.Next() {
+		redemption := &domain.Redemption{}
+		var amount int64
+		var currency string
+		var releasedAt sql.NullTime
+		err := rows.Scan(
+			&redemption.ID, &redemption.Code, &redemption.UserID, &redemption.OrderID,
+			&amount, &currency, &redemption.Status, &redemption.RedeemedAt, &releasedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		redemption.Amount = money.New(amount, currency)
+		if releasedAt.Valid {
+			redemption.ReleasedAt = &releasedAt.Time
+		}
+		redemptions = append(redemptions, redemption)
+	}
+
+	return redemptions, rows.Err()
+}
+
+// PendingDeliveries returns cards created before a time still waiting for
+// their event, oldest first
+func (r *postgresRepository) PendingDeliveries(ctx context.Context, before time.Time, limit int) ([]*domain.GiftCard, error) {
+	return scanCards(r.db.QueryContext(ctx, `
+		SELECT `+cardColumns+`
+		FROM gift_cards
+		WHERE delivery_status = 'pending' AND created_at < $1
+		ORDER BY created_at, code
+		LIMIT $2
+	`, before.UTC(), limit))
+}
+
+// MarkDelivered records that the cards' events were published
+func (r *postgresRepository) MarkDelivered(ctx context.Context, codes []string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE gift_cards SET delivery_status = 'sent', delivered_at = $2, updated_at = $2
+		WHERE code = ANY($1) AND delivery_status <> 'none'
+	`, pq.Array(codes), at.UTC())
+	return err
+}
+
+// lockCard loads a card and locks its row until the transaction ends
+func lockCard(ctx context.Context, tx *sql.Tx, code string) (*domain.GiftCard, error) {
+	card, err := scanCard(tx.QueryRowContext(ctx, `SELECT `+cardColumns+` FROM gift_cards WHERE code = $1 FOR UPDATE`, code))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrCardNotFound
+	}
+	return card, err
+}
+
+func insertTransaction(ctx context.Context, q shareddb.Querier, t *domain.Transaction) error {
+	t.ID = uuid.New().String()
+	_, err := q.ExecContext(ctx, `
+		INSERT INTO transactions (`+transactionColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`,
+		t.ID, t.Code, t.Type, t.Amount.MinorUnits, t.BalanceAfter.MinorUnits, t.Amount.Currency,
+		t.OrderID, t.Reference, t.CreatedBy, t.CreatedAt,
+	)
+	return err
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCard(row scanner) (*domain.GiftCard, error) {
+	card := &domain.GiftCard{}
+	var initialBalance, balance int64
+	var currency string
+	var batchID, purchaseID sql.NullString
+	var recipient domain.Recipient
+	var deliveredAt, expiresAt sql.NullTime
+	err := row.Scan(
+		&card.Code, &initialBalance, &balance, &currency, &card.Source, &batchID, &purchaseID,
+		&card.PurchaserID, &card.OrderID,
+		&recipient.Email, &recipient.Name, &recipient.SenderName, &recipient.Message,
+		&card.DeliveryStatus, &deliveredAt, &card.Reloadable, &expiresAt, &card.Disabled,
+		&card.CreatedBy, &card.CreatedAt, &card.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	card.InitialBalance = money.New(initialBalance, currency)
+	card.Balance = money.New(balance, currency)
+	card.BatchID = batchID.String
+	card.PurchaseID = purchaseID.String
+	if recipient.Email != "" {
+		card.Recipient = &recipient
+	}
+	if deliveredAt.Valid {
+		card.DeliveredAt = &deliveredAt.Time
+	}
+	if expiresAt.Valid {
+		card.ExpiresAt = &expiresAt.Time
+	}
+	return card, nil
+}
+
+func scanCards(rows *sql.Rows, err error) ([]*domain.GiftCard, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cards := []*domain.GiftCard{}
+	for rows.Next() {
+		card, err := scanCard(rows)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+
+	return cards, rows.Err()
+}
+
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func utcOrNil(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce/giftcard-service/internal/domain"
+)
+
+// GiftCardRepository defines gift card data operations
+type GiftCardRepository interface {
+	// Issuing
+	// IssueBatch stores a batch and batch.Size cards like card, with codes
+	// from generate, which is called again for as many codes as collided
+	// with existing ones. Cards are emailed to recipients, one each, when
+	// given. It returns the cards stored.
+	IssueBatch(ctx context.Context, batch *domain.Batch, card domain.GiftCard, recipients []domain.Recipient, generate func(n int) ([]string, error)) ([]*domain.GiftCard, error)
+	// IssuePurchase stores a purchased card with a code from generate.
+	// Issuing the same purchase again returns the existing card with
+	// created false.
+	IssuePurchase(ctx context.Context, card *domain.GiftCard, generate func(n int) ([]string, error)) (issued *domain.GiftCard, created bool, err error)
+
+	// Cards
+	GetCard(ctx context.Context, code string) (*domain.GiftCard, error)
+	// ListCards returns up to limit cards after a position, sorted by code
+	ListCards(ctx context.Context, filter CardFilter, limit int, after *CardPosition) ([]*domain.GiftCard, error)
+	CountCards(ctx context.Context, filter CardFilter) (int64, error)
+	// Transactions returns a card's balance changes, oldest first
+	Transactions(ctx context.Context, code string) ([]*domain.Transaction, error)
+	// Reload atomically checks the reload policy and adds to a card's
+	// balance. Reloading again with the same reference returns the card
+	// with created false. Reloads the policy refuses return the domain
+	// error saying why.
+	Reload(ctx context.Context, req ReloadRequest) (card *domain.GiftCard, created bool, err error)
+	// Disable stops a card from being redeemed or reloaded; its balance is
+	// kept
+	Disable(ctx context.Context, code string) (*domain.GiftCard, error)
+
+	// Redemptions
+	// Redeem atomically takes up to the amount due off a card's balance for
+	// an order. Redeeming a card again on the same order returns the
+	// existing redemption with created false. Cards that can't be redeemed
+	// return the domain error saying why.
+	Redeem(ctx context.Context, req RedeemRequest) (redemption *domain.Redemption, created bool, err error)
+	// OrderRedemptions returns the redemptions of an order, released ones
+	// included
+	OrderRedemptions(ctx context.Context, orderID string) ([]*domain.Redemption, error)
+	// Release undoes an order's redemptions, returning their amounts to the
+	// cards, and returns those released; none for orders without
+	// redemptions or already released
+	Release(ctx context.Context, orderID string, at time.Time) ([]*domain.Redemption, error)
+
+	// Delivery
+	// PendingDeliveries returns up to limit cards created before a time
+	// whose gift_card.issued event hasn't been published, oldest first
+	PendingDeliveries(ctx context.Context, before time.Time, limit int) ([]*domain.GiftCard, error)
+	// MarkDelivered records that the cards' events were published
+	MarkDelivered(ctx context.Context, codes []string, at time.Time) error
+
+	// Ping checks that the database is reachable
+	Ping(ctx context.Context) error
+}
+
+// RedeemRequest is a customer's use of a card on an order
+type RedeemRequest struct {
+	Code    string
+	UserID  string
+	OrderID string
+	// AmountDue is what is left to pay of the order; the card pays up to
+	// it
+	AmountDue money.Money
+	At        time.Time
+}
+
+// ReloadRequest adds to a card's balance within the policy
+type ReloadRequest struct {
+	Code   string
+	Amount money.Money
+	// Reference identifies the reload's purchase, making it idempotent;
+	// staff's reloads have none
+	Reference string
+	CreatedBy string
+	Policy    domain.Policy
+	At        time.Time
+}
+
+// CardFilter selects the cards to list
+type CardFilter struct {
+	// BatchID, when set, lists only the batch's cards
+	BatchID string
+	// Source, when set, lists only bulk or purchased cards
+	Source domain.Source
+	// PurchaserID, when set, lists only the cards a customer bought
+	PurchaserID string
+}
+
+// CardPosition is the cursor position of card lists, sorted by code
+type CardPosition struct {
+	Code string `json:"code"`
+}
@@ -0,0 +1,78 @@
+-- Batches of cards issued by staff
+CREATE TABLE IF NOT EXISTS batches (
+    id VARCHAR(36) PRIMARY KEY,
+    prefix VARCHAR(20) NOT NULL DEFAULT '',
+    size INTEGER NOT NULL,
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Gift cards, by code, stored uppercase. Amounts are in the currency's
+-- minor units.
+CREATE TABLE IF NOT EXISTS gift_cards (
+    code VARCHAR(50) PRIMARY KEY,
+    initial_balance BIGINT NOT NULL,
+    balance BIGINT NOT NULL CHECK (balance >= 0),
+    currency CHAR(3) NOT NULL,
+    source VARCHAR(20) NOT NULL CHECK (source IN ('bulk', 'purchase')),
+    -- batch_id is set for bulk cards, purchase_id for purchased ones
+    batch_id VARCHAR(36) REFERENCES batches(id),
+    purchase_id VARCHAR(255) UNIQUE,
+    purchaser_id VARCHAR(255) NOT NULL DEFAULT '',
+    order_id VARCHAR(255) NOT NULL DEFAULT '',
+    -- recipient_email is empty for cards not emailed to anyone
+    recipient_email VARCHAR(255) NOT NULL DEFAULT '',
+    recipient_name VARCHAR(200) NOT NULL DEFAULT '',
+    sender_name VARCHAR(200) NOT NULL DEFAULT '',
+    message TEXT NOT NULL DEFAULT '',
+    delivery_status VARCHAR(20) NOT NULL CHECK (delivery_status IN ('none', 'pending', 'sent')),
+    delivered_at TIMESTAMP,
+    reloadable BOOLEAN NOT NULL DEFAULT FALSE,
+    expires_at TIMESTAMP,
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_gift_cards_batch ON gift_cards(batch_id, code);
+CREATE INDEX IF NOT EXISTS idx_gift_cards_purchaser ON gift_cards(purchaser_id) WHERE purchaser_id <> '';
+CREATE INDEX IF NOT EXISTS idx_gift_cards_pending ON gift_cards(created_at) WHERE delivery_status = 'pending';
+
+-- Redemptions, one per card and order. Released redemptions, of cancelled
+-- orders, were returned to the card's balance.
+CREATE TABLE IF NOT EXISTS redemptions (
+    id VARCHAR(36) PRIMARY KEY,
+    code VARCHAR(50) NOT NULL REFERENCES gift_cards(code),
+    user_id VARCHAR(255) NOT NULL,
+    order_id VARCHAR(255) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('redeemed', 'released')),
+    redeemed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    released_at TIMESTAMP,
+    UNIQUE (code, order_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_redemptions_order ON redemptions(order_id);
+
+-- Every change to a card's balance; a card's transactions sum to its
+-- balance. reference makes a reload of a purchase idempotent.
+CREATE TABLE IF NOT EXISTS transactions (
+    id VARCHAR(36) PRIMARY KEY,
+    code VARCHAR(50) NOT NULL REFERENCES gift_cards(code),
+    type VARCHAR(20) NOT NULL CHECK (type IN ('issue', 'redeem', 'release', 'reload')),
+    amount BIGINT NOT NULL,
+    balance_after BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    order_id VARCHAR(255) NOT NULL DEFAULT '',
+    reference VARCHAR(255) NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_code ON transactions(code, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_reload_reference ON transactions(code, reference)
+    WHERE type = 'reload' AND reference <> '';
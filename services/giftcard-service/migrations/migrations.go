@@ -0,0 +1,10 @@
+// Package migrations embeds the service's database migrations, applied at
+// startup by shareddb.Migrate
+package migrations
+
+import "embed"
+
+// FS holds the migrations, applied in name order
+//
+//go:embed *.sql
+var FS embed.FS
@@ -2,21 +2,42 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	inventoryv1 "github.com/ecommerce-platform/proto/gen/go/ecommerce/inventory/v1"
+	"github.com/ecommerce-platform/shared/go/audit"
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	"github.com/ecommerce-platform/shared/go/broker"
+	sharedconfig "github.com/ecommerce-platform/shared/go/config"
+	shareddb "github.com/ecommerce-platform/shared/go/db"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	sharedevents "github.com/ecommerce-platform/shared/go/events"
+	"github.com/ecommerce-platform/shared/go/httpmetrics"
+	"github.com/ecommerce-platform/shared/go/interceptors"
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	"github.com/ecommerce-platform/shared/go/logging"
+	sharedmigrate "github.com/ecommerce-platform/shared/go/migrate"
+	"github.com/ecommerce-platform/shared/go/mtls"
+	"github.com/ecommerce-platform/shared/go/ratelimit"
+	"github.com/ecommerce-platform/shared/go/scheduler"
+	"github.com/ecommerce-platform/shared/go/validation"
 	"github.com/ecommerce/inventory-service/internal/api"
 	"github.com/ecommerce/inventory-service/internal/config"
 	"github.com/ecommerce/inventory-service/internal/events"
+	"github.com/ecommerce/inventory-service/internal/expiry"
 	"github.com/ecommerce/inventory-service/internal/middleware"
+	"github.com/ecommerce/inventory-service/internal/reorder"
 	"github.com/ecommerce/inventory-service/internal/repository"
-	"github.com/ecommerce/inventory-service/pkg/logger"
+	"github.com/ecommerce/inventory-service/internal/webhooks"
+	"github.com/ecommerce/inventory-service/migrations"
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
@@ -26,30 +47,63 @@ import (
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
 	"go.uber.org/zap"
-
-	_ "github.com/lib/pq"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
+// permissionInventoryWrite allows managing stock levels; admins have it
+const permissionInventoryWrite = "inventory:write"
+
+// migrateCommand runs a migration action and exits: up, the default,
+// applies pending migrations, e.g. as a deploy step ahead of servers started
+// with MIGRATE_ON_STARTUP=false; down [N] rolls back the last N, 1 by
+// default; version logs the current version; force V sets it once a failed
+// migration was fixed by hand
+const migrateCommand = "migrate"
+
 func main() {
+	// The first arguments may be a command; the rest are flags
+	command, args := splitCommand(os.Args[1:])
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.Load(args)
 	if err != nil {
 		fmt.Printf("Failed to load config: %v\n", err)
 		os.Exit(1)
 	}
 
 	// Initialize logger
-	log, err := logger.NewLogger(cfg.Environment)
+	log, err := logging.New(logging.Config{
+		ServiceName: "inventory-service",
+		Environment: cfg.Environment,
+		Level:       os.Getenv("LOG_LEVEL"),
+	})
 	if err != nil {
 		fmt.Printf("Failed to initialize logger: %v\n", err)
 		os.Exit(1)
 	}
 	defer log.Sync()
 
+	if len(command) > 0 {
+		if err := migrate(cfg, log, command[1:]); err != nil {
+			log.Fatal("Failed to migrate database", zap.Error(err))
+		}
+		return
+	}
+
 	log.Info("Starting Inventory Service",
 		zap.String("environment", cfg.Environment),
 		zap.Int("port", cfg.Port),
 	)
+	log.Info("Configuration loaded", zap.Any("config", sharedconfig.Redacted(cfg)))
+
+	// Initialize mTLS
+	serviceTLS, err := mtls.New(cfg.TLS, log)
+	if err != nil {
+		log.Fatal("Failed to initialize mTLS", zap.Error(err))
+	}
+	defer serviceTLS.Close()
 
 	// Initialize OpenTelemetry
 	cleanup, err := initTelemetry(cfg)
@@ -58,18 +112,25 @@ func main() {
 	}
 	defer cleanup()
 
+	ctx := context.Background()
+
 	// Initialize PostgreSQL
-	db, err := sql.Open("postgres", cfg.DatabaseURL)
+	db, err := shareddb.Open(ctx, shareddb.Config{
+		DSN:          cfg.DatabaseURL,
+		Name:         "inventory",
+		MaxOpenConns: cfg.DBMaxOpenConns,
+		MaxIdleConns: cfg.DBMaxIdleConns,
+	}, log)
 	if err != nil {
 		log.Fatal("Failed to connect to database", zap.Error(err))
 	}
 	defer db.Close()
 
-	// Test database connection
-	if err := db.Ping(); err != nil {
-		log.Fatal("Failed to ping database", zap.Error(err))
+	if cfg.MigrateOnStartup {
+		if err := sharedmigrate.Up(ctx, db, migrations.FS, migrations.VersionTable, log); err != nil {
+			log.Fatal("Failed to migrate database", zap.Error(err))
+		}
 	}
-	log.Info("Database connected")
 
 	// Initialize Redis
 	redisClient := redis.NewClient(&redis.Options{
@@ -80,7 +141,6 @@ func main() {
 	defer redisClient.Close()
 
 	// Test Redis connection
-	ctx := context.Background()
 	if err := redisClient.Ping(ctx).Err(); err != nil {
 		log.Fatal("Failed to connect to Redis", zap.Error(err))
 	}
@@ -90,45 +150,230 @@ func main() {
 	inventoryRepo := repository.NewPostgresRepository(db)
 	cacheRepo := repository.NewRedisRepository(redisClient)
 
-	// Initialize Kafka publisher
+	// Initialize event publisher on the configured message broker
 	brokers := strings.Split(cfg.KafkaBrokers, ",")
-	publisher := events.NewKafkaPublisher(brokers, cfg.KafkaTopic, log)
+	messageBroker, err := broker.New(broker.Config{
+		Type:         cfg.MessageBroker,
+		KafkaBrokers: brokers,
+		RabbitMQURL:  cfg.RabbitMQURL,
+		NATSURL:      cfg.NATSURL,
+	}, log)
+	if err != nil {
+		log.Fatal("Failed to create message broker", zap.Error(err))
+	}
+	defer messageBroker.Close()
+
+	// Published events are also queued for the webhooks subscribed to them
+	dispatcher := webhooks.New(inventoryRepo, webhooks.Config{
+		MaxAttempts:  cfg.WebhookMaxAttempts,
+		RetryBackoff: cfg.WebhookRetryBackoff,
+		MaxBackoff:   cfg.WebhookMaxBackoff,
+		Timeout:      cfg.WebhookTimeout,
+	}, log)
+	encoding := events.LegacyEncoding
+	if cfg.EventFormat == config.EventFormatCloudEvents {
+		encoding = events.CloudEventsEncoding(sharedevents.CloudEvents{
+			Source:        cfg.CloudEventsSource,
+			SchemaBaseURL: cfg.EventSchemaBaseURL,
+		})
+	}
+	publisher := events.NewPublisher(messageBroker.NewPublisher(cfg.KafkaTopic), encoding, dispatcher, log)
 	defer publisher.Close()
 
+	// Audit log of stock changes, in the database and on the audit topic
+	auditSinks := []audit.Sink{audit.NewPostgresSink(db)}
+	if cfg.AuditTopic != "" {
+		auditProducer := sharedkafka.NewProducer(sharedkafka.ProducerConfig{Brokers: brokers, Topic: cfg.AuditTopic}, log)
+		defer auditProducer.Close()
+		auditSinks = append(auditSinks, audit.NewKafkaSink(auditProducer))
+	}
+	auditor := audit.New(audit.Config{Service: "inventory-service", Sinks: auditSinks}, log)
+
 	// Initialize handler
-	handler := api.NewHandler(inventoryRepo, cacheRepo, publisher, cfg, log)
+	handler := api.NewHandler(inventoryRepo, cacheRepo, publisher, auditor, cfg, log)
+
+	// Scheduled jobs, each run by one replica at a time
+	jobs := scheduler.New(db, scheduler.Config{Service: "inventory-service"}, log)
+	if err := jobs.Register(scheduler.Job{
+		Name:     "reservations.expire",
+		Schedule: cfg.ReservationExpirySchedule,
+		Run:      expiry.New(inventoryRepo, cacheRepo, publisher, log).Run,
+	}); err != nil {
+		log.Fatal("Failed to register reservation expiry job", zap.Error(err))
+	}
+	if err := jobs.Register(scheduler.Job{
+		Name:     "lots.expire",
+		Schedule: cfg.LotExpirySchedule,
+		Run:      expiry.NewLotExpirer(inventoryRepo, cacheRepo, publisher, cfg.LotExpiryWarning, log).Run,
+	}); err != nil {
+		log.Fatal("Failed to register lot expiry job", zap.Error(err))
+	}
+	if err := jobs.Register(scheduler.Job{
+		Name:     "purchase_orders.draft",
+		Schedule: cfg.ReorderSchedule,
+		Run:      reorder.New(inventoryRepo, log).Run,
+	}); err != nil {
+		log.Fatal("Failed to register reorder job", zap.Error(err))
+	}
+	if err := jobs.Register(scheduler.Job{
+		Name:     "webhooks.deliver",
+		Schedule: cfg.WebhookDeliverySchedule,
+		Run:      dispatcher.Run,
+	}); err != nil {
+		log.Fatal("Failed to register webhook delivery job", zap.Error(err))
+	}
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	jobsDone := make(chan struct{})
+	go func() {
+		defer close(jobsDone)
+		jobs.Start(jobsCtx)
+	}()
+
+	// Initialize auth
+	verifier, err := sharedauth.NewVerifier(sharedauth.Config{
+		Secret:  cfg.JWTSecret,
+		JWKSURL: cfg.JWKSURL,
+		Issuer:  sharedauth.Issuer,
+	})
+	if err != nil {
+		log.Fatal("Failed to create token verifier", zap.Error(err))
+	}
+	authMiddleware := sharedauth.NewMiddleware(verifier, log)
 
 	// Setup Gin
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// Request bodies may use the shared rules, e.g. sku and e164
+	if err := validation.RegisterGin(); err != nil {
+		log.Fatal("Failed to register validation rules", zap.Error(err))
+	}
+
 	router := gin.New()
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES", zap.Error(err))
+	}
 	router.Use(gin.Recovery())
 	router.Use(middleware.CorrelationID())
 	router.Use(otelgin.Middleware("inventory-service"))
+	router.Use(httpmetrics.Middleware("inventory-service"))
+	router.Use(apperrors.Middleware(log))
 
-	// Health check
+	// Health checks: /health and /health/ready check dependencies, and
+	// /health/live only that the server is up
 	router.GET("/health", handler.HealthCheck)
+	router.GET("/health/live", handler.LivenessCheck)
+	router.GET("/health/ready", handler.ReadinessCheck(
+		api.Dependency{Name: "postgres", Ping: inventoryRepo.Ping},
+		api.Dependency{Name: "redis", Ping: cacheRepo.Ping},
+		api.Dependency{Name: messageBroker.Type(), Ping: messageBroker.Ping},
+	))
+
+	// Metrics for Prometheus
+	router.GET("/metrics", gin.WrapH(httpmetrics.Handler()))
+
+	// API routes, scoped to the tenant the gateway resolved
+	// Requests are limited per calling service or client IP, by rules
+	// counted separately, so a client listing heavily can still reserve.
+	// Only a valid service key counts as a calling service, so an arbitrary
+	// X-Service-Key can't buy a fresh allowance.
+	limiter := ratelimit.NewLimiter(redisClient, "inventory-service")
+	rateLimitKey := func(c *gin.Context) string {
+		if sharedauth.ValidServiceKey(c, cfg.ServiceAPIKey) {
+			return "service"
+		}
+		return "ip:" + c.ClientIP()
+	}
+	rateLimit := func(name string, perMinute int) gin.HandlerFunc {
+		if perMinute == 0 {
+			return func(c *gin.Context) { c.Next() }
+		}
+		rule := ratelimit.Rule{Name: name, Limit: perMinute, Window: time.Minute, Algorithm: ratelimit.TokenBucket}
+		return ratelimit.Middleware(limiter, rule, rateLimitKey, log)
+	}
+	limitReserve := rateLimit("reserve", cfg.RateLimitReservePerMinute)
+	limitList := rateLimit("list", cfg.RateLimitListPerMinute)
 
-	// API routes
 	v1 := router.Group("/api/v1")
+	v1.Use(sharedauth.ResolveTenant(), rateLimit("api", cfg.RateLimitPerMinute))
 	{
 		inventory := v1.Group("/inventory")
 		{
-			inventory.GET("", handler.ListInventoryItems)
-			inventory.POST("", handler.CreateInventoryItem)
+			inventory.GET("", limitList, handler.ListInventoryItems)
 			inventory.GET("/:id", handler.GetInventoryItem)
-			inventory.PUT("/:id", handler.UpdateInventoryItem)
-			inventory.POST("/:id/reserve", handler.ReserveInventory)
-			inventory.POST("/:id/adjust", handler.AdjustInventory)
-			inventory.GET("/low-stock", handler.GetLowStockItems)
+			inventory.POST("/:id/reserve", limitReserve, handler.ReserveInventory)
+			inventory.POST("/reserve-batch", limitReserve, handler.ReserveInventoryBatch)
+			inventory.GET("/low-stock", limitList, handler.GetLowStockItems)
+			inventory.GET("/sku/:sku", handler.GetInventoryItemBySKU)
+			inventory.POST("/sku/:sku/reserve", limitReserve, handler.ReserveInventoryBySKU)
+		}
+
+		// Stock management requires a user-service token
+		management := inventory.Group("", authMiddleware.Authenticate(), authMiddleware.RequirePermission(permissionInventoryWrite))
+		{
+			management.POST("", handler.CreateInventoryItem)
+			management.POST("/import", handler.ImportInventory)
+			management.PUT("/:id", handler.UpdateInventoryItem)
+			management.POST("/:id/adjust", handler.AdjustInventory)
+			management.POST("/:id/restock-return", handler.RestockReturn)
+			management.GET("/:id/history", handler.GetInventoryHistory)
+			management.GET("/valuation", handler.GetValuation)
+			management.GET("/:id/lots", handler.GetItemLots)
+			management.PUT("/sku/:sku", handler.UpdateInventoryItemBySKU)
+			management.POST("/sku/:sku/adjust", handler.AdjustInventoryBySKU)
+
+			management.POST("/transfers", handler.CreateTransfer)
+			management.GET("/transfers", handler.ListTransfers)
+			management.GET("/transfers/:transferId", handler.GetTransfer)
+			management.POST("/transfers/:transferId/complete", handler.CompleteTransfer)
+			management.POST("/transfers/:transferId/cancel", handler.CancelTransfer)
+
+			management.POST("/cycle-counts", handler.CreateCycleCount)
+			management.GET("/cycle-counts", handler.ListCycleCounts)
+			management.GET("/cycle-counts/:cycleCountId", handler.GetCycleCount)
+			management.POST("/cycle-counts/:cycleCountId/counts", handler.RecordCycleCount)
+			management.POST("/cycle-counts/:cycleCountId/apply", handler.ApplyCycleCount)
+			management.POST("/cycle-counts/:cycleCountId/cancel", handler.CancelCycleCount)
 		}
 
 		inventory.GET("/product/:productId", handler.GetInventoryByProductID)
+		inventory.GET("/product/:productId/locations", handler.GetProductLocations)
+		inventory.POST("/product/:productId/reserve", limitReserve, handler.ReserveInventoryByProduct)
+
+		// Purchase orders, drafted by the reorder job or by hand
+		purchaseOrders := v1.Group("/purchase-orders", authMiddleware.Authenticate(), authMiddleware.RequirePermission(permissionInventoryWrite))
+		{
+			purchaseOrders.GET("", handler.ListPurchaseOrders)
+			purchaseOrders.POST("", handler.CreatePurchaseOrder)
+			purchaseOrders.GET("/:purchaseOrderId", handler.GetPurchaseOrder)
+			purchaseOrders.POST("/:purchaseOrderId/order", handler.OrderPurchaseOrder)
+			purchaseOrders.POST("/:purchaseOrderId/receive", handler.ReceivePurchaseOrder)
+			purchaseOrders.POST("/:purchaseOrderId/cancel", handler.CancelPurchaseOrder)
+		}
+
+		// Webhooks pushing inventory events to partners
+		webhookRoutes := management.Group("/webhooks")
+		{
+			webhookRoutes.GET("", handler.ListWebhooks)
+			webhookRoutes.POST("", handler.CreateWebhook)
+			webhookRoutes.GET("/:webhookId", handler.GetWebhook)
+			webhookRoutes.PUT("/:webhookId", handler.UpdateWebhook)
+			webhookRoutes.DELETE("/:webhookId", handler.DeleteWebhook)
+			webhookRoutes.GET("/:webhookId/deliveries", handler.ListWebhookDeliveries)
+		}
+
+		// Lot and serial number tracing, e.g. for recalls
+		lots := v1.Group("", authMiddleware.Authenticate(), authMiddleware.RequirePermission(permissionInventoryWrite))
+		{
+			lots.GET("/lots/:lot/movements", handler.GetLotMovements)
+			lots.GET("/serials/:serial", handler.GetSerial)
+		}
 
 		reservations := v1.Group("/reservations")
 		{
+			reservations.GET("", limitList, handler.ListReservations)
+			reservations.POST("/:reservationId/confirm", handler.ConfirmReservation)
 			reservations.DELETE("/:reservationId", handler.ReleaseReservation)
 		}
 	}
@@ -145,11 +390,29 @@ func main() {
 	// Start server in goroutine
 	go func() {
 		log.Info("Server starting", zap.Int("port", cfg.Port))
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := serviceTLS.ListenAndServe(srv); err != nil && err != http.ErrServerClosed {
 			log.Fatal("Server failed to start", zap.Error(err))
 		}
 	}()
 
+	// Internal gRPC API for other services; like the public reservation
+	// endpoints, it doesn't authenticate callers. Calls act on the tenant in
+	// their x-tenant-id metadata.
+	grpcServer := grpc.NewServer(interceptors.ServerOptions(interceptors.ServerConfig{Logger: log, TLS: serviceTLS.ServerConfig()})...)
+	inventoryv1.RegisterInventoryServiceServer(grpcServer, api.NewInventoryServer(handler))
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+
+	grpcListener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
+	if err != nil {
+		log.Fatal("Failed to listen for gRPC", zap.Error(err))
+	}
+	go func() {
+		log.Info("gRPC server starting", zap.Int("port", cfg.GRPCPort))
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			log.Fatal("gRPC server failed", zap.Error(err))
+		}
+	}()
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -164,10 +427,95 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatal("Server forced to shutdown", zap.Error(err))
 	}
+	grpcServer.GracefulStop()
+
+	stopJobs()
+	select {
+	case <-jobsDone:
+	case <-ctx.Done():
+		log.Warn("Scheduled jobs didn't stop in time")
+	}
+
+	if err := auditor.Close(ctx); err != nil {
+		log.Error("Failed to write pending audit records", zap.Error(err))
+	}
 
 	log.Info("Server shutdown complete")
 }
 
+// splitCommand splits the migrate command and its arguments off the flags.
+// force takes its version even when it is negative, like -1.
+func splitCommand(args []string) (command, flags []string) {
+	if len(args) == 0 || args[0] != migrateCommand {
+		return nil, args
+	}
+
+	n := 1
+	if n < len(args) && !strings.HasPrefix(args[n], "-") {
+		action := args[n]
+		n++
+		if n < len(args) && (action == "force" || action == "down" && !strings.HasPrefix(args[n], "-")) {
+			n++
+		}
+	}
+	return args[:n], args[n:]
+}
+
+// migrate runs a migration action; see migrateCommand
+func migrate(cfg *config.Config, log *zap.Logger, args []string) error {
+	action := "up"
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+
+	ctx := context.Background()
+	db, err := shareddb.Open(ctx, shareddb.Config{
+		DSN:  cfg.DatabaseURL,
+		Name: "inventory",
+	}, log)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := sharedmigrate.New(ctx, db, migrations.FS, migrations.VersionTable, log)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	switch action {
+	case "up":
+		return migrator.Up()
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			if steps, err = strconv.Atoi(args[0]); err != nil {
+				return fmt.Errorf("invalid number of migrations to roll back: %q", args[0])
+			}
+		}
+		return migrator.Down(steps)
+	case "version":
+		version, dirty, err := migrator.Version()
+		if err != nil {
+			return err
+		}
+		log.Info("Migration version", zap.Uint("version", version), zap.Bool("dirty", dirty))
+		return nil
+	case "force":
+		if len(args) == 0 {
+			return fmt.Errorf("force needs the version to set")
+		}
+		version, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid migration version: %q", args[0])
+		}
+		return migrator.Force(version)
+	default:
+		return fmt.Errorf("unknown migrate action %q: use up, down, version or force", action)
+	}
+}
+
 func initTelemetry(cfg *config.Config) (func(), error) {
 	ctx := context.Background()
 
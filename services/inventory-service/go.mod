@@ -3,6 +3,26 @@ module github.com/ecommerce/inventory-service
 go 1.21
 
 require (
+	github.com/ecommerce-platform/proto v0.0.0
+	github.com/ecommerce-platform/shared/go/audit v0.0.0
+	github.com/ecommerce-platform/shared/go/auth v0.0.0
+	github.com/ecommerce-platform/shared/go/broker v0.0.0
+	github.com/ecommerce-platform/shared/go/config v0.0.0
+	github.com/ecommerce-platform/shared/go/db v0.0.0
+	github.com/ecommerce-platform/shared/go/errors v0.0.0
+	github.com/ecommerce-platform/shared/go/events v0.0.0
+	github.com/ecommerce-platform/shared/go/httpmetrics v0.0.0
+	github.com/ecommerce-platform/shared/go/interceptors v0.0.0
+	github.com/ecommerce-platform/shared/go/kafka v0.0.0
+	github.com/ecommerce-platform/shared/go/logging v0.0.0
+	github.com/ecommerce-platform/shared/go/migrate v0.0.0
+	github.com/ecommerce-platform/shared/go/money v0.0.0
+	github.com/ecommerce-platform/shared/go/mtls v0.0.0
+	github.com/ecommerce-platform/shared/go/pagination v0.0.0
+	github.com/ecommerce-platform/shared/go/ratelimit v0.0.0
+	github.com/ecommerce-platform/shared/go/scheduler v0.0.0
+	github.com/ecommerce-platform/shared/go/secrets v0.0.0
+	github.com/ecommerce-platform/shared/go/validation v0.0.0
 	github.com/gin-gonic/gin v1.9.1
 	github.com/google/uuid v1.5.0
 	github.com/lib/pq v1.10.9
@@ -13,7 +33,34 @@ require (
 	go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc v0.44.0
 	go.opentelemetry.io/otel/sdk v1.21.0
 	go.opentelemetry.io/otel/sdk/metric v1.21.0
+	go.opentelemetry.io/otel/trace v1.21.0
 	go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin v0.46.1
 	go.uber.org/zap v1.26.0
+	golang.org/x/sync v0.8.0
+	google.golang.org/grpc v1.59.0
+	google.golang.org/protobuf v1.31.0
 	github.com/stretchr/testify v1.8.4
 )
+
+replace (
+	github.com/ecommerce-platform/proto => ../../proto
+	github.com/ecommerce-platform/shared/go/audit => ../../shared/go/audit
+	github.com/ecommerce-platform/shared/go/auth => ../../shared/go/auth
+	github.com/ecommerce-platform/shared/go/broker => ../../shared/go/broker
+	github.com/ecommerce-platform/shared/go/config => ../../shared/go/config
+	github.com/ecommerce-platform/shared/go/db => ../../shared/go/db
+	github.com/ecommerce-platform/shared/go/errors => ../../shared/go/errors
+	github.com/ecommerce-platform/shared/go/events => ../../shared/go/events
+	github.com/ecommerce-platform/shared/go/httpmetrics => ../../shared/go/httpmetrics
+	github.com/ecommerce-platform/shared/go/interceptors => ../../shared/go/interceptors
+	github.com/ecommerce-platform/shared/go/kafka => ../../shared/go/kafka
+	github.com/ecommerce-platform/shared/go/logging => ../../shared/go/logging
+	github.com/ecommerce-platform/shared/go/migrate => ../../shared/go/migrate
+	github.com/ecommerce-platform/shared/go/money => ../../shared/go/money
+	github.com/ecommerce-platform/shared/go/mtls => ../../shared/go/mtls
+	github.com/ecommerce-platform/shared/go/pagination => ../../shared/go/pagination
+	github.com/ecommerce-platform/shared/go/ratelimit => ../../shared/go/ratelimit
+	github.com/ecommerce-platform/shared/go/scheduler => ../../shared/go/scheduler
+	github.com/ecommerce-platform/shared/go/secrets => ../../shared/go/secrets
+	github.com/ecommerce-platform/shared/go/validation => ../../shared/go/validation
+)
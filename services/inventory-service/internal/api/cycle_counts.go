@@ -0,0 +1,448 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/audit"
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/pagination"
+	"github.com/ecommerce/inventory-service/internal/domain"
+	"github.com/ecommerce/inventory-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// cycleCountPageSize is how many items a cycle count reads at a time
+const cycleCountPageSize = 500
+
+// CreateCycleCount starts counting a location, with a line for each item
+// there. A location has one count in progress at a time.
+func (h *Handler) CreateCycleCount(c *gin.Context) {
+	var req struct {
+		Location string `json:"location" binding:"required"`
+		Notes    string `json:"notes"`
+	}
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	count := &domain.CycleCount{
+		Location:  req.Location,
+		Status:    domain.CycleCountCounting,
+		CreatedBy: c.GetString(sharedauth.ContextUserID),
+		Notes:     req.Notes,
+	}
+	err := h.repo.InTx(ctx, func(repo repository.InventoryRepository) error {
+		counting, err := repo.ListCycleCounts(ctx, repository.CycleCountFilter{
+			Status:   domain.CycleCountCounting,
+			Location: req.Location,
+		}, 1, nil)
+		if err != nil {
+			return apperrors.Wrap(err, "Failed to list cycle counts")
+		}
+		if len(counting) > 0 {
+			return apperrors.New(http.StatusConflict, "Location is already being counted").WithFields(gin.H{"cycle_count_id": counting[0].ID})
+		}
+
+		count.Lines, err = cycleCountLines(ctx, repo, req.Location)
+		if err != nil {
+			return apperrors.Wrap(err, "Failed to list inventory items")
+		}
+		if len(count.Lines) == 0 {
+			return apperrors.New(http.StatusNotFound, "No inventory items at location")
+		}
+
+		if err := repo.CreateCycleCount(ctx, count); err != nil {
+			return apperrors.Wrap(err, "Failed to create cycle count")
+		}
+		return nil
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	h.cycleCountChanged(c, "cycle_count.created", count, nil)
+	c.JSON(http.StatusCreated, count)
+}
+
+// cycleCountLines returns a line for each item at location, expecting its
+// quantity
+func cycleCountLines(ctx context.Context, repo repository.InventoryRepository, location string) ([]domain.CycleCountLine, error) {
+	var lines []domain.CycleCountLine
+	var after *repository.ListPosition
+	for {
+		items, err := repo.List(ctx, repository.ItemFilter{Location: location}, cycleCountPageSize, after)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			lines = append(lines, domain.CycleCountLine{
+				ProductID:        item.ProductID,
+				SKU:              item.SKU,
+				ExpectedQuantity: item.Quantity,
+			})
+		}
+		if len(items) < cycleCountPageSize {
+			return lines, nil
+		}
+		last := items[len(items)-1]
+		after = &repository.ListPosition{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+}
+
+// RecordCycleCount records the quantities counted of a cycle count's SKUs,
+// each against its item's quantity now. Counting a SKU again replaces its
+// count.
+func (h *Handler) RecordCycleCount(c *gin.Context) {
+	var req struct {
+		Counts []struct {
+			SKU      string `json:"sku" binding:"required"`
+			Quantity *int   `json:"quantity" binding:"required,min=0"`
+		} `json:"counts" binding:"required,min=1,max=500,dive"`
+	}
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	actor := c.GetString(sharedauth.ContextUserID)
+	var count *domain.CycleCount
+	err := h.repo.InTx(ctx, func(repo repository.InventoryRepository) error {
+		var err error
+		count, err = h.openCycleCount(ctx, repo, c.Param("cycleCountId"))
+		if err != nil {
+			return err
+		}
+
+		var invalid apperrors.ValidationErrors
+		listed := make(map[string]bool, len(req.Counts))
+		for i, counted := range req.Counts {
+			if count.Line(counted.SKU) == nil {
+				invalid.Add(fmt.Sprintf("counts[%d].sku", i), "exists", "SKU is not on the cycle count")
+			} else if listed[counted.SKU] {
+				invalid.Add(fmt.Sprintf("counts[%d].sku", i), "unique", "SKU is listed more than once")
+			}
+			listed[counted.SKU] = true
+		}
+		if err := invalid.Err(); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, counted := range req.Counts {
+			line := count.Line(counted.SKU)
+			item, err := repo.GetByProductID(ctx, line.ProductID)
+			if err == domain.ErrNotFound {
+				return apperrors.New(http.StatusConflict, "Inventory item no longer exists").WithFields(gin.H{"sku": line.SKU})
+			}
+			if err != nil {
+				return apperrors.Wrap(err, "Failed to get inventory item")
+			}
+			line.Counted(item.Quantity, *counted.Quantity, actor, now)
+		}
+
+		if err := repo.UpdateCycleCount(ctx, count); err != nil {
+			return apperrors.Wrap(err, "Failed to update cycle count")
+		}
+		return nil
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, count)
+}
+
+// cycleCountAdjustment is an item adjusted by applying a cycle count's
+// variance
+type cycleCountAdjustment struct {
+	before     domain.InventoryItem
+	item       *domain.InventoryItem
+	adjustment *domain.InventoryAdjustment
+}
+
+// ApplyCycleCount applies the variances of the approved SKUs of a cycle
+// count as adjustments referencing it, and closes it. Variances that
+// aren't approved are left unapplied.
+func (h *Handler) ApplyCycleCount(c *gin.Context) {
+	var req struct {
+		Approved []string `json:"approved"`
+	}
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	actor := c.GetString(sharedauth.ContextUserID)
+	var count *domain.CycleCount
+	var adjusted []cycleCountAdjustment
+	err := h.repo.InTx(ctx, func(repo repository.InventoryRepository) error {
+		var err error
+		adjusted = nil
+		count, err = h.openCycleCount(ctx, repo, c.Param("cycleCountId"))
+		if err != nil {
+			return err
+		}
+
+		var invalid apperrors.ValidationErrors
+		for i, sku := range req.Approved {
+			field := fmt.Sprintf("approved[%d]", i)
+			switch line := count.Line(sku); {
+			case line == nil:
+				invalid.Add(field, "exists", "SKU is not on the cycle count")
+			case line.CountedQuantity == nil:
+				invalid.Add(field, "counted", "SKU hasn't been counted")
+			case line.Approved:
+				invalid.Add(field, "unique", "SKU is listed more than once")
+			default:
+				line.Approved = true
+			}
+		}
+		if err := invalid.Err(); err != nil {
+			return err
+		}
+
+		for i := range count.Lines {
+			line := &count.Lines[i]
+			if !line.Approved || line.Variance == 0 {
+				continue
+			}
+			applied, err := applyVariance(ctx, repo, count, line, actor)
+			if err != nil {
+				return err
+			}
+			adjusted = append(adjusted, applied)
+		}
+
+		now := time.Now()
+		count.Status = domain.CycleCountApplied
+		count.AppliedBy = actor
+		count.AppliedAt = &now
+		if err := repo.UpdateCycleCount(ctx, count); err != nil {
+			return apperrors.Wrap(err, "Failed to update cycle count")
+		}
+		return nil
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	for _, applied := range adjusted {
+		_ = h.cache.Delete(ctx, applied.item.ProductID)
+		h.auditor.LogGin(c, audit.Entry{
+			Action:   "inventory.adjusted",
+			Resource: audit.Resource{Type: "inventory_item", ID: applied.item.ID},
+			Before:   applied.before,
+			After:    applied.item,
+			Metadata: map[string]string{
+				"product_id":     applied.item.ProductID,
+				"quantity":       strconv.Itoa(applied.adjustment.Quantity),
+				"reason":         applied.adjustment.Reason,
+				"adjusted_by":    applied.adjustment.AdjustedBy,
+				"cycle_count_id": count.ID,
+			},
+		})
+		if err := h.publisher.PublishInventoryAdjusted(ctx, applied.item, applied.adjustment); err != nil {
+			h.logger.Error("Failed to publish adjustment event", zap.Error(err))
+		}
+		h.publishCrossing(ctx, applied.before, applied.item)
+	}
+	h.cycleCountChanged(c, "cycle_count.applied", count, map[string]string{"adjustments": strconv.Itoa(len(adjusted))})
+	c.JSON(http.StatusOK, count)
+}
+
+// applyVariance adjusts the item of a cycle count's line by its variance,
+// from its quantity now
+func applyVariance(ctx context.Context, repo repository.InventoryRepository, count *domain.CycleCount, line *domain.CycleCountLine, actor string) (cycleCountAdjustment, error) {
+	item, err := repo.GetByProductID(ctx, line.ProductID)
+	if err == domain.ErrNotFound {
+		return cycleCountAdjustment{}, apperrors.New(http.StatusConflict, "Inventory item no longer exists").WithFields(gin.H{"sku": line.SKU})
+	}
+	if err != nil {
+		return cycleCountAdjustment{}, apperrors.Wrap(err, "Failed to get inventory item")
+	}
+
+	before := *item
+	if line.Variance > 0 {
+		err = item.Add(line.Variance)
+	} else {
+		err = item.Deduct(-line.Variance)
+	}
+	if err != nil {
+		return cycleCountAdjustment{}, apperrors.New(http.StatusConflict, "Variance exceeds the item's stock").WithFields(gin.H{"sku": line.SKU, "quantity": before.Quantity})
+	}
+	if err := repo.Update(ctx, item); err != nil {
+		return cycleCountAdjustment{}, apperrors.Wrap(err, "Failed to adjust inventory")
+	}
+
+	adjustment := &domain.InventoryAdjustment{
+		ProductID:    item.ProductID,
+		Quantity:     line.Variance,
+		Reason:       domain.CycleCountReason,
+		AdjustedBy:   actor,
+		Notes:        count.Notes,
+		CycleCountID: count.ID,
+		UnitCost:     before.UnitCost,
+	}
+	if err := repo.CreateAdjustment(ctx, adjustment); err != nil {
+		return cycleCountAdjustment{}, apperrors.Wrap(err, "Failed to create adjustment record")
+	}
+	line.AdjustmentID = adjustment.ID
+
+	event := domain.NewHistoryEventSince(before, item, domain.HistoryAdjusted).
+		Referencing(domain.ReferenceAdjustment, adjustment.ID)
+	event.Actor = actor
+	event.Detail = domain.CycleCountReason
+	if err := repo.RecordHistory(ctx, event); err != nil {
+		return cycleCountAdjustment{}, apperrors.Wrap(err, "Failed to record history")
+	}
+	return cycleCountAdjustment{before: before, item: item, adjustment: adjustment}, nil
+}
+
+// CancelCycleCount abandons a cycle count in progress, applying none of
+// its variances. Cancelling it again changes nothing.
+func (h *Handler) CancelCycleCount(c *gin.Context) {
+	ctx := c.Request.Context()
+	var count *domain.CycleCount
+	cancelled := false
+	err := h.repo.InTx(ctx, func(repo repository.InventoryRepository) error {
+		var err error
+		count, err = h.getCycleCount(ctx, repo, c.Param("cycleCountId"))
+		if err != nil {
+			return err
+		}
+		switch count.Status {
+		case domain.CycleCountCancelled:
+			return nil
+		case domain.CycleCountCounting:
+		default:
+			return apperrors.New(http.StatusConflict, "Cycle count is "+count.Status)
+		}
+
+		now := time.Now()
+		count.Status = domain.CycleCountCancelled
+		count.CancelledAt = &now
+		if err := repo.UpdateCycleCount(ctx, count); err != nil {
+			return apperrors.Wrap(err, "Failed to update cycle count")
+		}
+		cancelled = true
+		return nil
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	if cancelled {
+		h.cycleCountChanged(c, "cycle_count.cancelled", count, nil)
+	}
+	c.JSON(http.StatusOK, count)
+}
+
+// getCycleCount retrieves a cycle count
+func (h *Handler) getCycleCount(ctx context.Context, repo repository.InventoryRepository, id string) (*domain.CycleCount, error) {
+	count, err := repo.GetCycleCount(ctx, id)
+	if err == domain.ErrCycleCountNotFound {
+		return nil, apperrors.New(http.StatusNotFound, "Cycle count not found")
+	}
+	if err != nil {
+		return nil, apperrors.Wrap(err, "Failed to get cycle count")
+	}
+	return count, nil
+}
+
+// openCycleCount retrieves a cycle count still being counted
+func (h *Handler) openCycleCount(ctx context.Context, repo repository.InventoryRepository, id string) (*domain.CycleCount, error) {
+	count, err := h.getCycleCount(ctx, repo, id)
+	if err != nil {
+		return nil, err
+	}
+	if count.Status != domain.CycleCountCounting {
+		return nil, apperrors.New(http.StatusConflict, "Cycle count is "+count.Status)
+	}
+	return count, nil
+}
+
+// cycleCountChanged audits a change to a cycle count
+func (h *Handler) cycleCountChanged(c *gin.Context, action string, count *domain.CycleCount, metadata map[string]string) {
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	metadata["location"] = count.Location
+	metadata["lines"] = strconv.Itoa(len(count.Lines))
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   action,
+		Resource: audit.Resource{Type: "cycle_count", ID: count.ID},
+		After:    count,
+		Metadata: metadata,
+	})
+
+	h.logger.Info("Cycle count "+count.Status,
+		zap.String("cycle_count_id", count.ID),
+		zap.String("location", count.Location),
+	)
+}
+
+// GetCycleCount retrieves a cycle count with its lines' counts and
+// variances
+func (h *Handler) GetCycleCount(c *gin.Context) {
+	count, err := h.getCycleCount(c.Request.Context(), h.repo, c.Param("cycleCountId"))
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, count)
+}
+
+// ListCycleCounts lists cycle counts newest first, a page at a time,
+// optionally with a status or of a location
+func (h *Handler) ListCycleCounts(c *gin.Context) {
+	params := pagination.FromQuery(c.Request.URL.Query())
+	filter := repository.CycleCountFilter{
+		Status:   c.Query("status"),
+		Location: c.Query("location"),
+	}
+	switch filter.Status {
+	case "", domain.CycleCountCounting, domain.CycleCountApplied, domain.CycleCountCancelled:
+	default:
+		apperrors.Abort(c, apperrors.NewBadRequest("status must be counting, applied or cancelled"))
+		return
+	}
+
+	var after *repository.ListPosition
+	if params.Cursor != "" {
+		after = &repository.ListPosition{}
+		if err := pagination.DecodeCursor(params.Cursor, after); err != nil {
+			apperrors.Abort(c, apperrors.NewBadRequest("Invalid cursor"))
+			return
+		}
+	}
+
+	counts, err := h.repo.ListCycleCounts(c.Request.Context(), filter, params.Limit+1, after)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list cycle counts"))
+		return
+	}
+
+	page, err := listPage(c, params, counts, func() (int64, error) {
+		return h.repo.CountCycleCounts(c.Request.Context(), filter)
+	}, func(count *domain.CycleCount) interface{} {
+		return repository.ListPosition{CreatedAt: count.CreatedAt, ID: count.ID}
+	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list cycle counts"))
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	inventoryv1 "github.com/ecommerce-platform/proto/gen/go/ecommerce/inventory/v1"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce/inventory-service/internal/domain"
+	"github.com/ecommerce/inventory-service/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// InventoryServer serves the ecommerce.inventory.v1 gRPC API with the
+// handler's stock lookups and reservations
+type InventoryServer struct {
+	inventoryv1.UnimplementedInventoryServiceServer
+	handler *Handler
+}
+
+// NewInventoryServer creates the gRPC API over handler
+func NewInventoryServer(handler *Handler) *InventoryServer {
+	return &InventoryServer{handler: handler}
+}
+
+// GetAvailability returns the stock of the requested products, leaving out
+// those without a stock record
+func (s *InventoryServer) GetAvailability(ctx context.Context, req *inventoryv1.GetAvailabilityRequest) (*inventoryv1.GetAvailabilityResponse, error) {
+	resp := &inventoryv1.GetAvailabilityResponse{}
+	for _, productID := range req.GetProductIds() {
+		item, err := s.handler.itemByProductID(ctx, productID)
+		if err == domain.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, apperrors.Wrap(err, "Failed to get inventory item")
+		}
+		resp.Items = append(resp.Items, toAvailability(item))
+	}
+	return resp, nil
+}
+
+// Reserve holds a product's stock for an order, as the HTTP reserve
+// endpoint does
+func (s *InventoryServer) Reserve(ctx context.Context, req *inventoryv1.ReserveRequest) (*inventoryv1.ReserveResponse, error) {
+	if req.GetProductId() == "" || req.GetOrderId() == "" || req.GetCustomerId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "product_id, order_id and customer_id are required")
+	}
+	if req.GetQuantity() < 1 {
+		return nil, status.Error(codes.InvalidArgument, "quantity must be at least 1")
+	}
+
+	item, reservation, err := s.handler.reserveStock(ctx, repository.ReservationRequest{ProductID: req.GetProductId()}, reserveRequest{
+		Quantity:   int(req.GetQuantity()),
+		OrderID:    req.GetOrderId(),
+		CustomerID: req.GetCustomerId(),
+	})
+	if err != nil {
+		// Over HTTP a shortfall is a conflict; the contract makes it a
+		// failed precondition, which callers don't mistake for a duplicate
+		var appErr *apperrors.AppError
+		if errors.As(err, &appErr) && appErr.StatusCode == http.StatusConflict {
+			return nil, status.Error(codes.FailedPrecondition, appErr.Message)
+		}
+		return nil, err
+	}
+
+	return &inventoryv1.ReserveResponse{
+		ReservationId: reservation.ID,
+		Status:        reservation.Status,
+		ExpiresAt:     timestamppb.New(reservation.ExpiresAt),
+		Item:          toAvailability(item),
+	}, nil
+}
+
+func toAvailability(item *domain.InventoryItem) *inventoryv1.Availability {
+	return &inventoryv1.Availability{
+		ItemId:            item.ID,
+		ProductId:         item.ProductID,
+		Quantity:          int32(item.Quantity),
+		ReservedQuantity:  int32(item.ReservedQuantity),
+		AvailableQuantity: int32(item.AvailableQuantity),
+		Status:            string(item.Status),
+	}
+}
@@ -1,30 +1,58 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/ecommerce-platform/shared/go/audit"
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce-platform/shared/go/pagination"
 	"github.com/ecommerce/inventory-service/internal/config"
 	"github.com/ecommerce/inventory-service/internal/domain"
 	"github.com/ecommerce/inventory-service/internal/events"
 	"github.com/ecommerce/inventory-service/internal/repository"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 )
 
+// itemCacheTTL is how long items stay in the cache
+const itemCacheTTL = 5 * time.Minute
+
+// earlyRefreshBeta scales how early cached items are refreshed; above 1
+// favours earlier refreshes
+const earlyRefreshBeta = 1.0
+
 type Handler struct {
 	repo      repository.InventoryRepository
 	cache     repository.CacheRepository
 	publisher events.Publisher
+	auditor   *audit.Auditor
 	config    *config.Config
 	logger    *zap.Logger
+
+	// loads shares a product's cache refresh between concurrent requests,
+	// and loadTime is how long the last one took, in nanoseconds
+	loads    singleflight.Group
+	loadTime atomic.Int64
 }
 
 func NewHandler(
 	repo repository.InventoryRepository,
 	cache repository.CacheRepository,
 	publisher events.Publisher,
+	auditor *audit.Auditor,
 	cfg *config.Config,
 	logger *zap.Logger,
 ) *Handler {
@@ -32,6 +60,7 @@ func NewHandler(
 		repo:      repo,
 		cache:     cache,
 		publisher: publisher,
+		auditor:   auditor,
 		config:    cfg,
 		logger:    logger,
 	}
@@ -41,15 +70,28 @@ func NewHandler(
 func (h *Handler) CreateInventoryItem(c *gin.Context) {
 	var item domain.InventoryItem
 
-	if err := c.ShouldBindJSON(&item); err != nil {
-		h.logger.Warn("Invalid request body", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+	if !apperrors.BindJSON(c, &item) {
+		return
+	}
+	var invalid apperrors.ValidationErrors
+	validCost(&invalid, "unit_cost", item.UnitCost)
+	if err := invalid.Err(); err != nil {
+		apperrors.Abort(c, err)
 		return
 	}
+	// New items have no lots to have expired
+	item.ExpiredQuantity = 0
 
-	if err := h.repo.Create(c.Request.Context(), &item); err != nil {
-		h.logger.Error("Failed to create inventory item", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create inventory item"})
+	err := h.repo.InTx(c.Request.Context(), func(repo repository.InventoryRepository) error {
+		if err := repo.Create(c.Request.Context(), &item); err != nil {
+			return err
+		}
+		event := domain.NewHistoryEvent(&item, domain.HistoryCreated, item.Quantity, item.ReservedQuantity)
+		event.Actor = c.GetString(sharedauth.ContextUserID)
+		return repo.RecordHistory(c.Request.Context(), event)
+	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to create inventory item"))
 		return
 	}
 
@@ -59,7 +101,7 @@ func (h *Handler) CreateInventoryItem(c *gin.Context) {
 	}
 
 	// Cache the item
-	if err := h.cache.Set(c.Request.Context(), item.ProductID, &item, 5*time.Minute); err != nil {
+	if err := h.cache.Set(c.Request.Context(), item.ProductID, &item, itemCacheTTL); err != nil {
 		h.logger.Warn("Failed to cache inventory item", zap.Error(err))
 	}
 
@@ -73,90 +115,351 @@ func (h *Handler) GetInventoryItem(c *gin.Context) {
 
 	item, err := h.repo.GetByID(c.Request.Context(), id)
 	if err == domain.ErrNotFound {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Inventory item not found"})
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Inventory item not found"))
 		return
 	}
 	if err != nil {
-		h.logger.Error("Failed to get inventory item", zap.Error(err), zap.String("id", id))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get inventory item"})
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get inventory item"))
 		return
 	}
 
 	c.JSON(http.StatusOK, item)
 }
 
+// GetInventoryItemBySKU retrieves an inventory item by SKU, as warehouse
+// systems key items
+func (h *Handler) GetInventoryItemBySKU(c *gin.Context) {
+	item, err := h.repo.GetBySKU(c.Request.Context(), c.Param("sku"))
+	if err == domain.ErrNotFound {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Inventory item not found"))
+		return
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get inventory item"))
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+// itemIDBySKU returns the ID of the item of the request's SKU, aborting
+// the request if there is none
+func (h *Handler) itemIDBySKU(c *gin.Context) (string, bool) {
+	item, err := h.repo.GetBySKU(c.Request.Context(), c.Param("sku"))
+	if err == domain.ErrNotFound {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Inventory item not found"))
+		return "", false
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get inventory item"))
+		return "", false
+	}
+	return item.ID, true
+}
+
 // GetInventoryByProductID retrieves inventory by product ID (with caching)
 func (h *Handler) GetInventoryByProductID(c *gin.Context) {
-	productID := c.Param("productId")
+	item, err := h.itemByProductID(c.Request.Context(), c.Param("productId"))
+	if err == domain.ErrNotFound {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Inventory item not found"))
+		return
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get inventory item"))
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
 
+// itemByProductID returns a product's stock record from the cache, or from
+// the database on a miss. Hits are refreshed early now and then as their
+// entry nears expiry, so a hot product's entry rarely expires under load.
+func (h *Handler) itemByProductID(ctx context.Context, productID string) (*domain.InventoryItem, error) {
 	// Try cache first
-	item, err := h.cache.Get(c.Request.Context(), productID)
+	item, ttl, err := h.cache.GetWithTTL(ctx, productID)
 	if err == nil && item != nil {
-		h.logger.Debug("Cache hit", zap.String("product_id", productID))
-		c.JSON(http.StatusOK, item)
-		return
+		if !h.refreshEarly(ttl) {
+			h.logger.Debug("Cache hit", zap.String("product_id", productID))
+			return item, nil
+		}
+
+		// Serve the cached item if the refresh fails
+		refreshed, err := h.loadItem(ctx, productID)
+		if err != nil {
+			h.logger.Warn("Failed to refresh cached inventory item", zap.String("product_id", productID), zap.Error(err))
+			return item, nil
+		}
+		return refreshed, nil
 	}
 
 	// Cache miss - query database
-	item, err = h.repo.GetByProductID(c.Request.Context(), productID)
-	if err == domain.ErrNotFound {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Inventory item not found"})
+	return h.loadItem(ctx, productID)
+}
+
+// loadItem reads a product's stock record from the database and caches
+// it. Concurrent loads of a product share one query, which outlives the
+// cancellation of the request that started it.
+func (h *Handler) loadItem(ctx context.Context, productID string) (*domain.InventoryItem, error) {
+	key := sharedauth.TenantFromContext(ctx) + "/" + productID
+	loaded, err, _ := h.loads.Do(key, func() (interface{}, error) {
+		ctx := context.WithoutCancel(ctx)
+		start := time.Now()
+		item, err := h.repo.GetByProductID(ctx, productID)
+		if err != nil {
+			return nil, err
+		}
+		h.loadTime.Store(int64(time.Since(start)))
+
+		// Cache the result
+		if err := h.cache.Set(ctx, productID, item, itemCacheTTL); err != nil {
+			h.logger.Warn("Failed to cache inventory item", zap.Error(err))
+		}
+		return item, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Callers each get their own copy
+	item := *loaded.(*domain.InventoryItem)
+	return &item, nil
+}
+
+// refreshEarly decides whether a cache hit expiring in ttl is refreshed
+// now, with probability rising as expiry nears and the longer loads take
+// (XFetch): a hot entry is refreshed by one request shortly before it
+// expires rather than by all of them after
+func (h *Handler) refreshEarly(ttl time.Duration) bool {
+	if ttl <= 0 {
+		return false
+	}
+	delta := float64(h.loadTime.Load())
+	return -delta*earlyRefreshBeta*math.Log(rand.Float64()) >= float64(ttl)
+}
+
+// maxListedProducts caps the product IDs an inventory listing filters by
+const maxListedProducts = 100
+
+// ListInventoryItems lists inventory items newest first, a page at a time,
+// optionally filtered by status, location, supplier, SKU, product and
+// quantity
+func (h *Handler) ListInventoryItems(c *gin.Context) {
+	params := pagination.FromQuery(c.Request.URL.Query())
+	filter, err := itemFilter(c)
+	if err != nil {
+		apperrors.Abort(c, err)
 		return
 	}
+
+	var after *repository.ListPosition
+	if params.Cursor != "" {
+		after = &repository.ListPosition{}
+		if err := pagination.DecodeCursor(params.Cursor, after); err != nil {
+			apperrors.Abort(c, apperrors.NewBadRequest("Invalid cursor"))
+			return
+		}
+	}
+
+	items, err := h.repo.List(c.Request.Context(), filter, params.Limit+1, after)
 	if err != nil {
-		h.logger.Error("Failed to get inventory item", zap.Error(err), zap.String("product_id", productID))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get inventory item"})
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list inventory items"))
 		return
 	}
 
-	// Cache the result
-	if err := h.cache.Set(c.Request.Context(), productID, item, 5*time.Minute); err != nil {
-		h.logger.Warn("Failed to cache inventory item", zap.Error(err))
+	page, err := listPage(c, params, items, func() (int64, error) {
+		return h.repo.Count(c.Request.Context(), filter)
+	}, func(item *domain.InventoryItem) interface{} {
+		return repository.ListPosition{CreatedAt: item.CreatedAt, ID: item.ID}
+	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list inventory items"))
+		return
 	}
 
-	c.JSON(http.StatusOK, item)
+	c.JSON(http.StatusOK, page)
 }
 
-// ListInventoryItems lists inventory items with pagination
-func (h *Handler) ListInventoryItems(c *gin.Context) {
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+// listPage builds a list's page from up to params.Limit+1 items, linked to
+// the next page. Its matches are counted only when the request asks for
+// include_total, which costs a scan of them.
+func listPage[T any](c *gin.Context, params pagination.Params, items []T, count func() (int64, error), position func(T) interface{}) (pagination.Page[T], error) {
+	if !params.IncludeTotal {
+		page, err := pagination.NewUncountedPage(items, params.Limit, position)
+		return page.WithLinks(c.Request.URL), err
+	}
 
-	if limit > 100 {
-		limit = 100
+	total, err := count()
+	if err != nil {
+		return pagination.Page[T]{}, err
 	}
+	page, err := pagination.NewPage(items, params.Limit, total, position)
+	return page.WithLinks(c.Request.URL), err
+}
 
-	items, err := h.repo.List(c.Request.Context(), limit, offset)
+// itemFilter reads an inventory listing's filter from the query: status,
+// location, supplier, sku_prefix, q to search SKUs, product_id repeated or
+// comma separated, and min_ and max_quantity and _available
+func itemFilter(c *gin.Context) (repository.ItemFilter, error) {
+	filter := repository.ItemFilter{
+		Status:    c.Query("status"),
+		Location:  c.Query("location"),
+		Supplier:  c.Query("supplier"),
+		SKUPrefix: strings.TrimSpace(c.Query("sku_prefix")),
+		Search:    strings.TrimSpace(c.Query("q")),
+	}
+
+	var invalid apperrors.ValidationErrors
+	switch domain.InventoryStatus(filter.Status) {
+	case "", domain.StatusInStock, domain.StatusLowStock, domain.StatusOutOfStock, domain.StatusReserved:
+	default:
+		invalid.Add("status", "oneof", "status must be in_stock, low_stock, out_of_stock or reserved")
+	}
+
+	for _, ids := range c.QueryArray("product_id") {
+		for _, id := range strings.Split(ids, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				filter.ProductIDs = append(filter.ProductIDs, id)
+			}
+		}
+	}
+	if len(filter.ProductIDs) > maxListedProducts {
+		invalid.Add("product_id", "max", fmt.Sprintf("At most %d product IDs can be listed", maxListedProducts))
+	}
+
+	for _, bound := range []struct {
+		param string
+		value **int
+	}{
+		{"min_quantity", &filter.MinQuantity},
+		{"max_quantity", &filter.MaxQuantity},
+		{"min_available", &filter.MinAvailable},
+		{"max_available", &filter.MaxAvailable},
+	} {
+		raw := c.Query(bound.param)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			invalid.Add(bound.param, "number", bound.param+" must be a whole number")
+			continue
+		}
+		*bound.value = &n
+	}
+	if filter.MinQuantity != nil && filter.MaxQuantity != nil && *filter.MinQuantity > *filter.MaxQuantity {
+		invalid.Add("max_quantity", "gtefield", "max_quantity must be at least min_quantity")
+	}
+	if filter.MinAvailable != nil && filter.MaxAvailable != nil && *filter.MinAvailable > *filter.MaxAvailable {
+		invalid.Add("max_available", "gtefield", "max_available must be at least min_available")
+	}
+
+	if err := invalid.Err(); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// GetInventoryHistory lists what happened to an inventory item, newest
+// first, a page at a time: its creation and updates, adjustments,
+// reservations, deductions, releases, expiries and transfers
+func (h *Handler) GetInventoryHistory(c *gin.Context) {
+	id := c.Param("id")
+	params := pagination.FromQuery(c.Request.URL.Query())
+
+	var after *repository.ListPosition
+	if params.Cursor != "" {
+		after = &repository.ListPosition{}
+		if err := pagination.DecodeCursor(params.Cursor, after); err != nil {
+			apperrors.Abort(c, apperrors.NewBadRequest("Invalid cursor"))
+			return
+		}
+	}
+
+	if _, err := h.repo.GetByID(c.Request.Context(), id); err == domain.ErrNotFound {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Inventory item not found"))
+		return
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get inventory item"))
+		return
+	}
+
+	events, err := h.repo.ListHistory(c.Request.Context(), id, params.Limit+1, after)
 	if err != nil {
-		h.logger.Error("Failed to list inventory items", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list inventory items"})
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get inventory history"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"items":  items,
-		"limit":  limit,
-		"offset": offset,
+	page, err := listPage(c, params, events, func() (int64, error) {
+		return h.repo.CountHistory(c.Request.Context(), id)
+	}, func(event *domain.HistoryEvent) interface{} {
+		return repository.ListPosition{CreatedAt: event.OccurredAt, ID: event.ID}
 	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get inventory history"))
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
 }
 
 // UpdateInventoryItem updates an inventory item
 func (h *Handler) UpdateInventoryItem(c *gin.Context) {
-	id := c.Param("id")
+	h.updateItem(c, c.Param("id"))
+}
 
+// UpdateInventoryItemBySKU updates the inventory item of a SKU
+func (h *Handler) UpdateInventoryItemBySKU(c *gin.Context) {
+	if id, ok := h.itemIDBySKU(c); ok {
+		h.updateItem(c, id)
+	}
+}
+
+// updateItem updates the inventory item with an ID. A unit cost, cost
+// method or allocation left out keeps the item's, as receipts average
+// their costs into it; its expired quantity is kept by its lots.
+func (h *Handler) updateItem(c *gin.Context, id string) {
 	var item domain.InventoryItem
-	if err := c.ShouldBindJSON(&item); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+	if !apperrors.BindJSON(c, &item) {
+		return
+	}
+	var invalid apperrors.ValidationErrors
+	validCost(&invalid, "unit_cost", item.UnitCost)
+	if err := invalid.Err(); err != nil {
+		apperrors.Abort(c, err)
 		return
 	}
 
 	item.ID = id
-	if err := h.repo.Update(c.Request.Context(), &item); err == domain.ErrNotFound {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Inventory item not found"})
+	var before domain.InventoryItem
+	err := h.repo.InTx(c.Request.Context(), func(repo repository.InventoryRepository) error {
+		current, err := repo.GetByID(c.Request.Context(), id)
+		if err != nil {
+			return err
+		}
+		before = *current
+		if item.UnitCost == nil {
+			item.UnitCost = current.UnitCost
+		}
+		if item.CostMethod == "" {
+			item.CostMethod = current.CostMethod
+		}
+		if item.Allocation == "" {
+			item.Allocation = current.Allocation
+		}
+		item.ExpiredQuantity = current.ExpiredQuantity
+		if err := repo.Update(c.Request.Context(), &item); err != nil {
+			return err
+		}
+		event := domain.NewHistoryEventSince(before, &item, domain.HistoryUpdated)
+		event.Actor = c.GetString(sharedauth.ContextUserID)
+		return repo.RecordHistory(c.Request.Context(), event)
+	})
+	if err == domain.ErrNotFound {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Inventory item not found"))
 		return
 	} else if err != nil {
-		h.logger.Error("Failed to update inventory item", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update inventory item"})
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to update inventory item"))
 		return
 	}
 
@@ -169,224 +472,706 @@ func (h *Handler) UpdateInventoryItem(c *gin.Context) {
 	if err := h.publisher.PublishInventoryUpdated(c.Request.Context(), &item); err != nil {
 		h.logger.Error("Failed to publish inventory updated event", zap.Error(err))
 	}
+	h.publishCrossing(c.Request.Context(), before, &item)
 
 	c.JSON(http.StatusOK, item)
 }
 
-// ReserveInventory reserves inventory for an order
+// ReserveInventory reserves an inventory item's stock for an order
 func (h *Handler) ReserveInventory(c *gin.Context) {
-	id := c.Param("id")
+	h.reserve(c, repository.ReservationRequest{ItemID: c.Param("id")})
+}
 
-	var req struct {
-		Quantity   int    `json:"quantity" binding:"required,min=1"`
-		OrderID    string `json:"order_id" binding:"required"`
-		CustomerID string `json:"customer_id" binding:"required"`
+// ReserveInventoryBySKU reserves the stock of a SKU's item for an order
+func (h *Handler) ReserveInventoryBySKU(c *gin.Context) {
+	if id, ok := h.itemIDBySKU(c); ok {
+		h.reserve(c, repository.ReservationRequest{ItemID: id})
 	}
+}
+
+// ReserveInventoryByProduct reserves a product's stock for an order
+func (h *Handler) ReserveInventoryByProduct(c *gin.Context) {
+	h.reserve(c, repository.ReservationRequest{ProductID: c.Param("productId")})
+}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+// reserve reserves the stock of target's item for the request's order
+func (h *Handler) reserve(c *gin.Context, target repository.ReservationRequest) {
+	var req reserveRequest
+	if !apperrors.BindJSON(c, &req) {
 		return
 	}
 
-	// Get inventory item
-	item, err := h.repo.GetByID(c.Request.Context(), id)
-	if err == domain.ErrNotFound {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Inventory item not found"})
+	item, reservation, err := h.reserveStock(c.Request.Context(), target, req)
+	if err != nil {
+		apperrors.Abort(c, err)
 		return
 	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reservation_id": reservation.ID,
+		"status":         reservation.Status,
+		"expires_at":     reservation.ExpiresAt,
+		"item":           item,
+	})
+}
+
+// reserveRequest asks for stock to be held for an order
+type reserveRequest struct {
+	Quantity   int    `json:"quantity" binding:"required,min=1"`
+	OrderID    string `json:"order_id" binding:"required"`
+	CustomerID string `json:"customer_id" binding:"required"`
+	// ExpiresInMinutes holds the stock longer than RESERVATION_TTL_MINUTES,
+	// up to MAX_RESERVATION_TTL_MINUTES, e.g. ahead of a subscription's
+	// delivery; 0 uses the default
+	ExpiresInMinutes int `json:"expires_in_minutes" binding:"omitempty,min=1"`
+}
+
+// ttl is how long the request's reservation holds its stock
+func (h *Handler) ttl(req reserveRequest) time.Duration {
+	minutes := h.config.ReservationTTL
+	if req.ExpiresInMinutes > 0 {
+		minutes = req.ExpiresInMinutes
+		if minutes > h.config.MaxReservationTTL {
+			minutes = h.config.MaxReservationTTL
+		}
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// reserveStock reserves the stock of target's item, for the HTTP and gRPC
+// APIs. Reserving is idempotent per order and product: a retried request
+// gets the order's reservation back rather than holding the stock twice.
+func (h *Handler) reserveStock(ctx context.Context, target repository.ReservationRequest, req reserveRequest) (*domain.InventoryItem, *domain.Reservation, error) {
+	target.Quantity = req.Quantity
+	target.OrderID = req.OrderID
+	target.CustomerID = req.CustomerID
+	target.ExpiresAt = time.Now().Add(h.ttl(req))
+
+	// Reservations by item ID lock its product too, so they take turns
+	// with reservations of the product
+	if target.ProductID == "" {
+		item, err := h.repo.GetByID(ctx, target.ItemID)
+		if err == domain.ErrNotFound {
+			return nil, nil, apperrors.New(http.StatusNotFound, "Inventory item not found")
+		}
+		if err != nil {
+			return nil, nil, apperrors.Wrap(err, "Failed to get inventory item")
+		}
+		target.ProductID = item.ProductID
+	}
+
+	unlock, err := h.lockStock(ctx, stockLockKey(target.ProductID))
 	if err != nil {
-		h.logger.Error("Failed to get inventory item", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get inventory item"})
-		return
+		return nil, nil, err
 	}
+	defer unlock()
 
-	// Reserve inventory
-	if err := item.Reserve(req.Quantity); err == domain.ErrInsufficientStock {
-		c.JSON(http.StatusConflict, gin.H{"error": "Insufficient stock", "available": item.AvailableQuantity})
-		return
-	} else if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// Reserve stock and record the reservation together, with the item
+	// locked, so concurrent reservations can't oversell
+	item, reservation, created, err := h.repo.ReserveWithinTx(ctx, target)
+	if err == domain.ErrNotFound {
+		return nil, nil, apperrors.New(http.StatusNotFound, "Inventory item not found")
+	}
+	if err == domain.ErrInsufficientStock {
+		return nil, nil, apperrors.New(http.StatusConflict, "Insufficient stock").WithFields(gin.H{"available": item.AvailableQuantity})
+	}
+	if err != nil {
+		return nil, nil, apperrors.Wrap(err, "Failed to reserve inventory")
+	}
+
+	if created {
+		// Invalidate cache
+		_ = h.cache.Delete(ctx, item.ProductID)
+
+		// Publish event
+		if err := h.publisher.PublishInventoryReserved(ctx, item, reservation); err != nil {
+			h.logger.Error("Failed to publish reservation event", zap.Error(err))
+		}
+		h.publishCrossing(ctx, unreserved(item, reservation), item)
+
+		h.logger.Info("Inventory reserved", zap.String("product_id", item.ProductID), zap.Int("quantity", req.Quantity))
+	}
+	return item, reservation, nil
+}
+
+// lockRetryInterval is how often a reservation tries again for a busy lock
+const lockRetryInterval = 20 * time.Millisecond
+
+// stockLockKey is the lock of a product's item, however it is reserved
+func stockLockKey(productID string) string {
+	return "product:" + productID
+}
+
+// lockStock takes the Redis locks on keys, in order, so replicas
+// reserving the same product take turns before each holds a database
+// connection waiting for its row lock. The row lock still keeps
+// reservations from overselling: if Redis fails they go on without its
+// locks. A lock still busy after RESERVATION_LOCK_WAIT fails with 503. The
+// returned function releases the locks.
+func (h *Handler) lockStock(ctx context.Context, keys ...string) (func(), error) {
+	type lock struct{ key, token string }
+	var held []lock
+	unlock := func() {
+		for _, l := range held {
+			if err := h.cache.Unlock(context.WithoutCancel(ctx), l.key, l.token); err != nil {
+				h.logger.Warn("Failed to release stock lock", zap.String("key", l.key), zap.Error(err))
+			}
+		}
+	}
+	if h.config.ReservationLockTTL <= 0 {
+		return unlock, nil
+	}
+
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	deadline := time.Now().Add(h.config.ReservationLockWait)
+	for _, key := range sorted {
+		for {
+			token, acquired, err := h.cache.Lock(ctx, key, h.config.ReservationLockTTL)
+			if err != nil {
+				h.logger.Warn("Failed to take stock lock, reserving with the row lock alone", zap.String("key", key), zap.Error(err))
+				break
+			}
+			if acquired {
+				held = append(held, lock{key, token})
+				break
+			}
+			if time.Now().After(deadline) {
+				unlock()
+				return nil, apperrors.New(http.StatusServiceUnavailable, "Stock is busy, try again")
+			}
+			select {
+			case <-ctx.Done():
+				unlock()
+				return nil, ctx.Err()
+			case <-time.After(lockRetryInterval):
+			}
+		}
+	}
+	return unlock, nil
+}
+
+// ReserveInventoryBatch reserves the stock of an order's items together:
+// either every item's stock is reserved or, when one is short or unknown,
+// none is. Like single reservations it is idempotent per order and
+// product.
+func (h *Handler) ReserveInventoryBatch(c *gin.Context) {
+	var req reserveBatchRequest
+	if !apperrors.BindJSON(c, &req) {
 		return
 	}
 
-	// Update database
-	if err := h.repo.Update(c.Request.Context(), item); err != nil {
-		h.logger.Error("Failed to update inventory", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reserve inventory"})
+	var invalid apperrors.ValidationErrors
+	listed := make(map[string]bool, len(req.Items))
+	for i, item := range req.Items {
+		if listed[item.ProductID] {
+			invalid.Add(fmt.Sprintf("items[%d].product_id", i), "unique", "Product is listed more than once")
+		}
+		listed[item.ProductID] = true
+	}
+	if err := invalid.Err(); err != nil {
+		apperrors.Abort(c, err)
 		return
 	}
 
-	// Create reservation record
-	reservation := &domain.Reservation{
-		ProductID:  item.ProductID,
-		Quantity:   req.Quantity,
-		OrderID:    req.OrderID,
-		CustomerID: req.CustomerID,
-		ExpiresAt:  time.Now().Add(time.Duration(h.config.ReservationTTL) * time.Minute),
-		Status:     "pending",
+	ctx := c.Request.Context()
+	expiresAt := time.Now().Add(h.ttl(reserveRequest{ExpiresInMinutes: req.ExpiresInMinutes}))
+	reqs := make([]repository.ReservationRequest, len(req.Items))
+	for i, item := range req.Items {
+		reqs[i] = repository.ReservationRequest{
+			ProductID:  item.ProductID,
+			Quantity:   item.Quantity,
+			OrderID:    req.OrderID,
+			CustomerID: req.CustomerID,
+			ExpiresAt:  expiresAt,
+		}
 	}
 
-	if err := h.repo.CreateReservation(c.Request.Context(), reservation); err != nil {
-		h.logger.Error("Failed to create reservation", zap.Error(err))
-		// Attempt to rollback
-		_ = item.ReleaseReservation(req.Quantity)
-		_ = h.repo.Update(c.Request.Context(), item)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reservation"})
+	keys := make([]string, len(reqs))
+	for i, r := range reqs {
+		keys[i] = stockLockKey(r.ProductID)
+	}
+	unlock, err := h.lockStock(ctx, keys...)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+	reserved, err := h.repo.ReserveAllWithinTx(ctx, reqs)
+	unlock()
+	if err != nil {
+		apperrors.Abort(c, batchReservationError(err))
 		return
 	}
 
-	// Invalidate cache
-	_ = h.cache.Delete(c.Request.Context(), item.ProductID)
+	reservations := make([]gin.H, len(reserved))
+	for i, r := range reserved {
+		reservations[i] = gin.H{
+			"reservation_id": r.Reservation.ID,
+			"status":         r.Reservation.Status,
+			"expires_at":     r.Reservation.ExpiresAt,
+			"item":           r.Item,
+		}
+		if !r.Created {
+			continue
+		}
 
-	// Publish event
-	if err := h.publisher.PublishInventoryReserved(c.Request.Context(), item, reservation); err != nil {
-		h.logger.Error("Failed to publish reservation event", zap.Error(err))
+		_ = h.cache.Delete(ctx, r.Item.ProductID)
+		if err := h.publisher.PublishInventoryReserved(ctx, r.Item, r.Reservation); err != nil {
+			h.logger.Error("Failed to publish reservation event", zap.Error(err))
+		}
+		h.publishCrossing(ctx, unreserved(r.Item, r.Reservation), r.Item)
+		h.logger.Info("Inventory reserved", zap.String("product_id", r.Item.ProductID), zap.Int("quantity", r.Reservation.Quantity))
 	}
 
-	h.logger.Info("Inventory reserved", zap.String("product_id", item.ProductID), zap.Int("quantity", req.Quantity))
-	c.JSON(http.StatusOK, gin.H{
-		"reservation_id": reservation.ID,
-		"expires_at":     reservation.ExpiresAt,
-		"item":           item,
-	})
+	c.JSON(http.StatusOK, gin.H{"reservations": reservations})
 }
 
-// ReleaseReservation releases a reservation
-func (h *Handler) ReleaseReservation(c *gin.Context) {
-	reservationID := c.Param("reservationId")
+// batchReservationError is the response to a batch reservation failing
+// with err, naming the product that failed
+func batchReservationError(err error) *apperrors.AppError {
+	var failed *repository.ReservationError
+	if errors.As(err, &failed) {
+		switch failed.Err {
+		case domain.ErrNotFound:
+			return apperrors.New(http.StatusNotFound, "Inventory item not found").WithFields(gin.H{"product_id": failed.Request.ProductID})
+		case domain.ErrInsufficientStock:
+			return apperrors.New(http.StatusConflict, "Insufficient stock").WithFields(gin.H{
+				"product_id": failed.Request.ProductID,
+				"available":  failed.Item.AvailableQuantity,
+			})
+		}
+	}
+	return apperrors.Wrap(err, "Failed to reserve inventory")
+}
+
+// reserveBatchRequest asks for the stock of an order's items to be held
+type reserveBatchRequest struct {
+	OrderID    string             `json:"order_id" binding:"required"`
+	CustomerID string             `json:"customer_id" binding:"required"`
+	Items      []reserveBatchItem `json:"items" binding:"required,min=1,max=100,dive"`
+	// ExpiresInMinutes is as for single reservations
+	ExpiresInMinutes int `json:"expires_in_minutes" binding:"omitempty,min=1"`
+}
+
+// reserveBatchItem is one product of a batch reservation
+type reserveBatchItem struct {
+	ProductID string `json:"product_id" binding:"required"`
+	Quantity  int    `json:"quantity" binding:"required,min=1"`
+}
 
-	// Get reservation
-	reservation, err := h.repo.GetReservation(c.Request.Context(), reservationID)
-	if err == domain.ErrReservationNotFound {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Reservation not found"})
+// ListReservations lists reservations newest first, a page at a time,
+// optionally of an order, customer or product, or with a status
+func (h *Handler) ListReservations(c *gin.Context) {
+	params := pagination.FromQuery(c.Request.URL.Query())
+	filter := repository.ReservationFilter{
+		OrderID:    c.Query("order_id"),
+		CustomerID: c.Query("customer_id"),
+		ProductID:  c.Query("product_id"),
+		Status:     c.Query("status"),
+	}
+	switch filter.Status {
+	case "", domain.ReservationPending, domain.ReservationConfirmed, domain.ReservationCancelled, domain.ReservationExpired:
+	default:
+		apperrors.Abort(c, apperrors.NewBadRequest("status must be pending, confirmed, cancelled or expired"))
 		return
 	}
+
+	var after *repository.ListPosition
+	if params.Cursor != "" {
+		after = &repository.ListPosition{}
+		if err := pagination.DecodeCursor(params.Cursor, after); err != nil {
+			apperrors.Abort(c, apperrors.NewBadRequest("Invalid cursor"))
+			return
+		}
+	}
+
+	reservations, err := h.repo.ListReservations(c.Request.Context(), filter, params.Limit+1, after)
 	if err != nil {
-		h.logger.Error("Failed to get reservation", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get reservation"})
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list reservations"))
 		return
 	}
 
-	// Get inventory item
-	item, err := h.repo.GetByProductID(c.Request.Context(), reservation.ProductID)
+	page, err := listPage(c, params, reservations, func() (int64, error) {
+		return h.repo.CountReservations(c.Request.Context(), filter)
+	}, func(reservation *domain.Reservation) interface{} {
+		return repository.ListPosition{CreatedAt: reservation.CreatedAt, ID: reservation.ID}
+	})
 	if err != nil {
-		h.logger.Error("Failed to get inventory item", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get inventory item"})
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list reservations"))
 		return
 	}
 
-	// Release reservation
-	if err := item.ReleaseReservation(reservation.Quantity); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	c.JSON(http.StatusOK, page)
+}
+
+// ConfirmReservation turns a pending reservation into a sale: its stock is
+// deducted and it no longer expires. Confirming again changes nothing.
+// Items allocating FEFO take the stock from the lots expiring first unless
+// the lots are given.
+func (h *Handler) ConfirmReservation(c *gin.Context) {
+	reservationID := c.Param("reservationId")
+	// The body is optional: the lots the stock is taken from
+	var req struct {
+		Lots []lotRequest `json:"lots" binding:"omitempty,max=100,dive"`
+	}
+	if c.Request.ContentLength != 0 && !apperrors.BindJSON(c, &req) {
 		return
 	}
 
-	// Update database
-	if err := h.repo.Update(c.Request.Context(), item); err != nil {
-		h.logger.Error("Failed to update inventory", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release reservation"})
+	var item *domain.InventoryItem
+	var before domain.InventoryItem
+	var reservation *domain.Reservation
+	confirmed := false
+	err := h.repo.InTx(c.Request.Context(), func(repo repository.InventoryRepository) error {
+		var err error
+		reservation, err = repo.GetReservation(c.Request.Context(), reservationID)
+		if err == domain.ErrReservationNotFound {
+			return apperrors.New(http.StatusNotFound, "Reservation not found")
+		}
+		if err != nil {
+			return apperrors.Wrap(err, "Failed to get reservation")
+		}
+
+		item, err = repo.GetByProductID(c.Request.Context(), reservation.ProductID)
+		if err != nil {
+			return apperrors.Wrap(err, "Failed to get inventory item")
+		}
+
+		switch {
+		case reservation.Status == domain.ReservationConfirmed:
+			return nil
+		case reservation.Status != domain.ReservationPending:
+			return apperrors.New(http.StatusConflict, "Reservation is "+reservation.Status)
+		case time.Now().After(reservation.ExpiresAt):
+			return apperrors.New(http.StatusConflict, domain.ErrReservationExpired.Error())
+		}
+
+		var invalid apperrors.ValidationErrors
+		validLots(&invalid, "lots", req.Lots, reservation.Quantity)
+		if err := invalid.Err(); err != nil {
+			return err
+		}
+		lots := req.Lots
+		if len(lots) == 0 && item.Allocation == domain.AllocationFEFO {
+			if lots, err = allocateLots(c.Request.Context(), repo, item, reservation.Quantity); err != nil {
+				return err
+			}
+		}
+
+		before = *item
+		if err := item.Deduct(reservation.Quantity); err != nil {
+			return apperrors.New(http.StatusConflict, err.Error())
+		}
+		if err := repo.Update(c.Request.Context(), item); err != nil {
+			return apperrors.Wrap(err, "Failed to confirm reservation")
+		}
+		if err := moveLots(c.Request.Context(), repo, item, lots, true, domain.ReferenceReservation, reservation.ID, c.GetString(sharedauth.ContextUserID)); err != nil {
+			return err
+		}
+
+		event := domain.NewHistoryEventSince(before, item, domain.HistoryDeducted).
+			Referencing(domain.ReferenceReservation, reservation.ID)
+		event.Detail = reservation.OrderID
+		if err := repo.RecordHistory(c.Request.Context(), event); err != nil {
+			return apperrors.Wrap(err, "Failed to record history")
+		}
+
+		reservation.Status = domain.ReservationConfirmed
+		if err := repo.UpdateReservation(c.Request.Context(), reservation); err != nil {
+			return apperrors.Wrap(err, "Failed to update reservation")
+		}
+		confirmed = true
+		return nil
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
 		return
 	}
 
-	// Update reservation status
-	reservation.Status = "cancelled"
-	if err := h.repo.UpdateReservation(c.Request.Context(), reservation); err != nil {
-		h.logger.Error("Failed to update reservation", zap.Error(err))
+	if confirmed {
+		// Invalidate cache
+		_ = h.cache.Delete(c.Request.Context(), item.ProductID)
+
+		// Publish event
+		if err := h.publisher.PublishInventoryUpdated(c.Request.Context(), item); err != nil {
+			h.logger.Error("Failed to publish inventory updated event", zap.Error(err))
+		}
+		h.publishCrossing(c.Request.Context(), before, item)
+
+		h.logger.Info("Reservation confirmed", zap.String("reservation_id", reservationID))
 	}
+	c.JSON(http.StatusOK, gin.H{
+		"reservation_id": reservation.ID,
+		"status":         reservation.Status,
+		"item":           item,
+	})
+}
 
-	// Invalidate cache
-	_ = h.cache.Delete(c.Request.Context(), item.ProductID)
+// ReleaseReservation releases a reservation
+func (h *Handler) ReleaseReservation(c *gin.Context) {
+	reservationID := c.Param("reservationId")
 
-	// Publish event
-	if err := h.publisher.PublishReservationReleased(c.Request.Context(), item, reservation); err != nil {
-		h.logger.Error("Failed to publish release event", zap.Error(err))
+	// Return the stock and cancel the reservation together. Releasing a
+	// reservation already cancelled or expired changes nothing.
+	var item *domain.InventoryItem
+	var reservation *domain.Reservation
+	released := true
+	err := h.repo.InTx(c.Request.Context(), func(repo repository.InventoryRepository) error {
+		var err error
+		reservation, err = repo.GetReservation(c.Request.Context(), reservationID)
+		if err == domain.ErrReservationNotFound {
+			return apperrors.New(http.StatusNotFound, "Reservation not found")
+		}
+		if err != nil {
+			return apperrors.Wrap(err, "Failed to get reservation")
+		}
+
+		item, err = repo.GetByProductID(c.Request.Context(), reservation.ProductID)
+		if err != nil {
+			return apperrors.Wrap(err, "Failed to get inventory item")
+		}
+
+		// A confirmed reservation's stock was deducted, so it is put back
+		before := *item
+		switch reservation.Status {
+		case domain.ReservationCancelled, domain.ReservationExpired:
+			released = false
+			return nil
+		case domain.ReservationConfirmed:
+			if err := item.Add(reservation.Quantity); err != nil {
+				return apperrors.New(http.StatusBadRequest, err.Error())
+			}
+		default:
+			if err := item.ReleaseReservation(reservation.Quantity); err != nil {
+				return apperrors.New(http.StatusBadRequest, err.Error())
+			}
+		}
+
+		if err := repo.Update(c.Request.Context(), item); err != nil {
+			return apperrors.Wrap(err, "Failed to release reservation")
+		}
+
+		event := domain.NewHistoryEventSince(before, item, domain.HistoryReleased).
+			Referencing(domain.ReferenceReservation, reservation.ID)
+		event.Detail = reservation.OrderID
+		if err := repo.RecordHistory(c.Request.Context(), event); err != nil {
+			return apperrors.Wrap(err, "Failed to record history")
+		}
+
+		reservation.Status = domain.ReservationCancelled
+		if err := repo.UpdateReservation(c.Request.Context(), reservation); err != nil {
+			return apperrors.Wrap(err, "Failed to update reservation")
+		}
+		return nil
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
 	}
 
-	h.logger.Info("Reservation released", zap.String("reservation_id", reservationID))
+	if released {
+		// Invalidate cache
+		_ = h.cache.Delete(c.Request.Context(), item.ProductID)
+
+		// Publish event
+		if err := h.publisher.PublishReservationReleased(c.Request.Context(), item, reservation); err != nil {
+			h.logger.Error("Failed to publish release event", zap.Error(err))
+		}
+
+		h.logger.Info("Reservation released", zap.String("reservation_id", reservationID))
+	}
 	c.JSON(http.StatusOK, item)
 }
 
 // AdjustInventory adjusts inventory quantity
 func (h *Handler) AdjustInventory(c *gin.Context) {
-	id := c.Param("id")
+	h.adjustItem(c, c.Param("id"))
+}
 
-	var req struct {
-		Quantity   int    `json:"quantity" binding:"required"`
-		Reason     string `json:"reason" binding:"required"`
-		AdjustedBy string `json:"adjusted_by" binding:"required"`
-		Notes      string `json:"notes"`
+// AdjustInventoryBySKU adjusts the quantity of a SKU's item
+func (h *Handler) AdjustInventoryBySKU(c *gin.Context) {
+	if id, ok := h.itemIDBySKU(c); ok {
+		h.adjustItem(c, id)
 	}
+}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
-		return
+// adjustItem adjusts the quantity of the inventory item with an ID. Stock
+// added at a unit cost is received into the item's unit cost, and stock
+// of lots received into or removed from them; stock removed from items
+// allocating FEFO without lots given comes from the lots expiring first.
+func (h *Handler) adjustItem(c *gin.Context, id string) {
+	var req struct {
+		Quantity   int          `json:"quantity" binding:"required"`
+		Reason     string       `json:"reason" binding:"required"`
+		AdjustedBy string       `json:"adjusted_by" binding:"required"`
+		Notes      string       `json:"notes"`
+		UnitCost   *money.Money `json:"unit_cost"`
+		Lots       []lotRequest `json:"lots" binding:"omitempty,max=100,dive"`
 	}
 
-	// Get inventory item
-	item, err := h.repo.GetByID(c.Request.Context(), id)
-	if err == domain.ErrNotFound {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Inventory item not found"})
+	if !apperrors.BindJSON(c, &req) {
 		return
 	}
-	if err != nil {
-		h.logger.Error("Failed to get inventory item", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get inventory item"})
-		return
+	var invalid apperrors.ValidationErrors
+	validCost(&invalid, "unit_cost", req.UnitCost)
+	if req.UnitCost != nil && req.Quantity < 0 {
+		invalid.Add("unit_cost", "excluded_if", "Only stock added has a unit cost")
 	}
-
-	// Apply adjustment
-	if req.Quantity > 0 {
-		_ = item.Add(req.Quantity)
+	if req.Quantity < 0 {
+		validLots(&invalid, "lots", req.Lots, -req.Quantity)
 	} else {
-		_ = item.Deduct(-req.Quantity)
+		validLots(&invalid, "lots", req.Lots, req.Quantity)
 	}
-
-	// Update database
-	if err := h.repo.Update(c.Request.Context(), item); err != nil {
-		h.logger.Error("Failed to update inventory", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to adjust inventory"})
+	if err := invalid.Err(); err != nil {
+		apperrors.Abort(c, err)
 		return
 	}
 
-	// Create adjustment record
-	adjustment := &domain.InventoryAdjustment{
-		ProductID:  item.ProductID,
-		Quantity:   req.Quantity,
-		Reason:     req.Reason,
-		AdjustedBy: req.AdjustedBy,
-		Notes:      req.Notes,
-	}
+	// Apply the adjustment and record it together
+	var item *domain.InventoryItem
+	var before domain.InventoryItem
+	var adjustment *domain.InventoryAdjustment
+	err := h.repo.InTx(c.Request.Context(), func(repo repository.InventoryRepository) error {
+		var err error
+		item, err = repo.GetByID(c.Request.Context(), id)
+		if err == domain.ErrNotFound {
+			return apperrors.New(http.StatusNotFound, "Inventory item not found")
+		}
+		if err != nil {
+			return apperrors.Wrap(err, "Failed to get inventory item")
+		}
+
+		lots := req.Lots
+		if req.Quantity < 0 && len(lots) == 0 && item.Allocation == domain.AllocationFEFO {
+			if lots, err = allocateLots(c.Request.Context(), repo, item, -req.Quantity); err != nil {
+				return err
+			}
+		}
+
+		before = *item
+		switch {
+		case req.UnitCost != nil:
+			if err := item.ReceiveAt(req.Quantity, *req.UnitCost); err == domain.ErrCostCurrency {
+				return apperrors.NewBadRequest("unit_cost must be in the item's cost currency").WithFields(gin.H{"currency": before.UnitCost.Currency})
+			}
+		case req.Quantity > 0:
+			_ = item.Add(req.Quantity)
+		default:
+			_ = item.Deduct(-req.Quantity)
+		}
 
-	if err := h.repo.CreateAdjustment(c.Request.Context(), adjustment); err != nil {
-		h.logger.Error("Failed to create adjustment record", zap.Error(err))
+		if err := repo.Update(c.Request.Context(), item); err != nil {
+			return apperrors.Wrap(err, "Failed to adjust inventory")
+		}
+
+		adjustment = &domain.InventoryAdjustment{
+			ProductID:  item.ProductID,
+			Quantity:   req.Quantity,
+			Reason:     req.Reason,
+			AdjustedBy: req.AdjustedBy,
+			Notes:      req.Notes,
+			UnitCost:   req.UnitCost,
+		}
+		if adjustment.UnitCost == nil {
+			adjustment.UnitCost = before.UnitCost
+		}
+		if err := repo.CreateAdjustment(c.Request.Context(), adjustment); err != nil {
+			return apperrors.Wrap(err, "Failed to create adjustment record")
+		}
+		if err := moveLots(c.Request.Context(), repo, item, lots, req.Quantity < 0, domain.ReferenceAdjustment, adjustment.ID, req.AdjustedBy); err != nil {
+			return err
+		}
+
+		event := domain.NewHistoryEventSince(before, item, domain.HistoryAdjusted).
+			Referencing(domain.ReferenceAdjustment, adjustment.ID)
+		event.Actor = req.AdjustedBy
+		event.Detail = req.Reason
+		if err := repo.RecordHistory(c.Request.Context(), event); err != nil {
+			return apperrors.Wrap(err, "Failed to record history")
+		}
+		return nil
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
 	}
 
 	// Invalidate cache
 	_ = h.cache.Delete(c.Request.Context(), item.ProductID)
 
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   "inventory.adjusted",
+		Resource: audit.Resource{Type: "inventory_item", ID: item.ID},
+		Before:   before,
+		After:    item,
+		Metadata: map[string]string{
+			"product_id":  item.ProductID,
+			"quantity":    strconv.Itoa(req.Quantity),
+			"reason":      req.Reason,
+			"adjusted_by": req.AdjustedBy,
+			"notes":       req.Notes,
+		},
+	})
+
 	// Publish event
 	if err := h.publisher.PublishInventoryAdjusted(c.Request.Context(), item, adjustment); err != nil {
 		h.logger.Error("Failed to publish adjustment event", zap.Error(err))
 	}
+	h.publishCrossing(c.Request.Context(), before, item)
 
 	h.logger.Info("Inventory adjusted", zap.String("product_id", item.ProductID), zap.Int("quantity", req.Quantity))
 	c.JSON(http.StatusOK, item)
 }
 
+// publishCrossing announces the item's available stock falling to its
+// reorder level or running out since before, so purchasing can restock it
+func (h *Handler) publishCrossing(ctx context.Context, before domain.InventoryItem, item *domain.InventoryItem) {
+	var err error
+	switch item.CrossedThreshold(before) {
+	case domain.StatusLowStock:
+		err = h.publisher.PublishLowStock(ctx, item)
+	case domain.StatusOutOfStock:
+		err = h.publisher.PublishOutOfStock(ctx, item)
+	default:
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to publish stock threshold event", zap.String("product_id", item.ProductID), zap.Error(err))
+	}
+}
+
+// unreserved is the item as it was before the reservation held its stock
+func unreserved(item *domain.InventoryItem, reservation *domain.Reservation) domain.InventoryItem {
+	before := *item
+	before.ReservedQuantity -= reservation.Quantity
+	before.AvailableQuantity += reservation.Quantity
+	return before
+}
+
 // GetLowStockItems retrieves items with low stock
 func (h *Handler) GetLowStockItems(c *gin.Context) {
 	items, err := h.repo.GetLowStockItems(c.Request.Context())
 	if err != nil {
-		h.logger.Error("Failed to get low stock items", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get low stock items"})
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get low stock items"))
 		return
 	}
 
 	c.JSON(http.StatusOK, items)
 }
 
-// HealthCheck handler
+// HealthCheck handler; unhealthy while the database is unreachable
 func (h *Handler) HealthCheck(c *gin.Context) {
+	if err := h.repo.Ping(c.Request.Context()); err != nil {
+		h.logger.Warn("Health check failed", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unhealthy",
+			"service": "inventory-service",
+			"version": "1.0.0",
+			"error":   "database unavailable",
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "healthy",
 		"service": "inventory-service",
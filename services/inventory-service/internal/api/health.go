@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// dependencyTimeout bounds each dependency's ping in a readiness check
+const dependencyTimeout = 2 * time.Second
+
+// Dependency is something the service can't serve requests without,
+// pinged by readiness checks
+type Dependency struct {
+	Name string
+	Ping func(ctx context.Context) error
+}
+
+// dependencyStatus is a dependency's state in a readiness check
+type dependencyStatus struct {
+	Status    string `json:"status"` // up, down
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// LivenessCheck reports the process is serving requests, without checking
+// its dependencies, so an outage of one doesn't get replicas restarted
+func (h *Handler) LivenessCheck(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "alive",
+		"service": "inventory-service",
+		"version": "1.0.0",
+	})
+}
+
+// ReadinessCheck returns a handler pinging each dependency concurrently,
+// each within dependencyTimeout, and reporting each one's status: 200 when
+// all are up, else 503 so load balancers route around the replica
+func (h *Handler) ReadinessCheck(dependencies ...Dependency) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		statuses := make(map[string]dependencyStatus, len(dependencies))
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, dependency := range dependencies {
+			wg.Add(1)
+			go func(dependency Dependency) {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(c.Request.Context(), dependencyTimeout)
+				defer cancel()
+
+				start := time.Now()
+				err := dependency.Ping(ctx)
+				status := dependencyStatus{Status: "up", LatencyMS: time.Since(start).Milliseconds()}
+				if err != nil {
+					status.Status = "down"
+					status.Error = err.Error()
+				}
+
+				mu.Lock()
+				statuses[dependency.Name] = status
+				mu.Unlock()
+			}(dependency)
+		}
+		wg.Wait()
+
+		code, overall := http.StatusOK, "ready"
+		for name, status := range statuses {
+			if status.Status != "up" {
+				code, overall = http.StatusServiceUnavailable, "not_ready"
+				h.logger.Warn("Readiness check failed", zap.String("dependency", name), zap.String("error", status.Error))
+			}
+		}
+
+		c.JSON(code, gin.H{
+			"status":       overall,
+			"service":      "inventory-service",
+			"version":      "1.0.0",
+			"dependencies": statuses,
+		})
+	}
+}
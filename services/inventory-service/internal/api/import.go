@@ -0,0 +1,263 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/validation"
+	"github.com/ecommerce/inventory-service/internal/domain"
+	"github.com/ecommerce/inventory-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxImportBytes caps the size of an import file
+	maxImportBytes = 10 << 20
+	// importBatchSize is how many rows are imported per transaction
+	importBatchSize = 500
+)
+
+// requiredImportColumns are the columns every import file has; it may also
+// have reorder_level, reorder_quantity, location and supplier
+var requiredImportColumns = []string{"sku", "product_id", "quantity"}
+
+// importRow is a valid row of an import file
+type importRow struct {
+	row int
+	repository.ItemImport
+}
+
+// importError is a row of an import file that wasn't imported
+type importError struct {
+	Row     int    `json:"row"`
+	SKU     string `json:"sku,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportInventory creates and updates inventory items from a CSV file,
+// uploaded as the body with a Content-Type of text/csv or as the file field
+// of a multipart form. Items are matched by SKU: a new SKU creates an item
+// and a known one updates its quantity, reorder levels, location and
+// supplier. Empty optional cells keep an existing item's values. Rows that
+// fail validation or can't be applied are skipped and reported by their
+// line in the file, the header being line 1.
+func (h *Handler) ImportInventory(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+
+	var file io.Reader = c.Request.Body
+	if c.ContentType() != "text/csv" {
+		header, err := c.FormFile("file")
+		if err != nil {
+			apperrors.Abort(c, apperrors.NewBadRequest("Upload a CSV file as the file field, or as a text/csv body"))
+			return
+		}
+		upload, err := header.Open()
+		if err != nil {
+			apperrors.Abort(c, apperrors.Wrap(err, "Failed to read upload"))
+			return
+		}
+		defer upload.Close()
+		file = upload
+	}
+
+	rows, failed, err := parseImportCSV(file)
+	if err != nil {
+		apperrors.Abort(c, apperrors.NewBadRequest(err.Error()))
+		return
+	}
+	total := len(rows) + len(failed)
+	if total == 0 {
+		apperrors.Abort(c, apperrors.NewBadRequest("CSV file has no rows"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	created, updated := 0, 0
+	for start := 0; start < len(rows); start += importBatchSize {
+		end := start + importBatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		batch := rows[start:end]
+		imports := make([]repository.ItemImport, len(batch))
+		for i, row := range batch {
+			imports[i] = row.ItemImport
+		}
+
+		results, err := h.repo.UpsertBySKU(ctx, imports)
+		if err != nil {
+			h.logger.Error("Failed to import inventory batch", zap.Int("first_row", batch[0].row), zap.Error(err))
+			for _, row := range batch {
+				failed = append(failed, importError{Row: row.row, SKU: row.SKU, Message: "Failed to import row, try again"})
+			}
+			continue
+		}
+
+		for i, result := range results {
+			if result.Err != nil {
+				failed = append(failed, importError{Row: batch[i].row, SKU: batch[i].SKU, Message: importErrorMessage(result.Err)})
+				continue
+			}
+
+			if result.Created {
+				created++
+				if err := h.publisher.PublishInventoryCreated(ctx, result.Item); err != nil {
+					h.logger.Error("Failed to publish inventory created event", zap.Error(err))
+				}
+			} else {
+				updated++
+				_ = h.cache.Delete(ctx, result.Item.ProductID)
+				if err := h.publisher.PublishInventoryUpdated(ctx, result.Item); err != nil {
+					h.logger.Error("Failed to publish inventory updated event", zap.Error(err))
+				}
+				h.publishCrossing(ctx, result.Before, result.Item)
+			}
+		}
+	}
+
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Row < failed[j].Row })
+
+	h.logger.Info("Inventory imported",
+		zap.Int("rows", total),
+		zap.Int("created", created),
+		zap.Int("updated", updated),
+		zap.Int("failed", len(failed)),
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"rows":    total,
+		"created": created,
+		"updated": updated,
+		"failed":  len(failed),
+		"errors":  failed,
+	})
+}
+
+// importErrorMessage explains why UpsertBySKU skipped a row
+func importErrorMessage(err error) string {
+	switch err {
+	case domain.ErrSKUConflict:
+		return "SKU belongs to another product"
+	case domain.ErrProductConflict:
+		return "Product already has another SKU"
+	case domain.ErrBelowReserved:
+		return "Quantity is below the quantity reserved"
+	default:
+		return err.Error()
+	}
+}
+
+// parseImportCSV reads an import file with a header row. It returns the
+// valid rows and the errors of the others, and fails if the file itself is
+// invalid.
+func parseImportCSV(r io.Reader) ([]importRow, []importError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, errors.New("CSV file has no header row")
+	}
+	columns := make(map[string]int)
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, required := range requiredImportColumns {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("CSV file has no %s column", required)
+		}
+	}
+
+	var rows []importRow
+	var failed []importError
+	seen := make(map[string]int)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, errors.New("invalid CSV file: " + err.Error())
+		}
+		line, _ := reader.FieldPos(0)
+
+		cell := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row, problem := parseImportRow(cell)
+		row.row = line
+		if problem == "" {
+			if first, ok := seen[row.SKU]; ok {
+				problem = fmt.Sprintf("SKU is already on line %d", first)
+			}
+		}
+		if problem != "" {
+			failed = append(failed, importError{Row: line, SKU: cell("sku"), Message: problem})
+			continue
+		}
+		seen[row.SKU] = line
+		rows = append(rows, row)
+	}
+	return rows, failed, nil
+}
+
+// parseImportRow validates a row's cells, returning the problem with it if
+// any
+func parseImportRow(cell func(column string) string) (importRow, string) {
+	row := importRow{ItemImport: repository.ItemImport{
+		SKU:       cell("sku"),
+		ProductID: cell("product_id"),
+	}}
+	if !validation.IsSKU(row.SKU) {
+		return row, "sku must be 6 to 20 upper case letters, digits and hyphens"
+	}
+	if row.ProductID == "" {
+		return row, "product_id is required"
+	}
+
+	quantity, err := strconv.Atoi(cell("quantity"))
+	if err != nil || quantity < 0 {
+		return row, "quantity must be a whole number, 0 or more"
+	}
+	row.Quantity = quantity
+
+	for _, optional := range []struct {
+		column string
+		value  **int
+	}{
+		{"reorder_level", &row.ReorderLevel},
+		{"reorder_quantity", &row.ReorderQuantity},
+	} {
+		raw := cell(optional.column)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return row, optional.column + " must be a whole number, 0 or more"
+		}
+		*optional.value = &n
+	}
+
+	if location := cell("location"); location != "" {
+		row.Location = &location
+	}
+	if supplier := cell("supplier"); supplier != "" {
+		row.Supplier = &supplier
+	}
+	return row, ""
+}
@@ -0,0 +1,247 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/pagination"
+	"github.com/ecommerce/inventory-service/internal/domain"
+	"github.com/ecommerce/inventory-service/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// lotRequest is stock received into or removed from a lot, optionally
+// with its units' serial numbers and, for stock received, the lot's expiry
+type lotRequest struct {
+	LotNumber string     `json:"lot_number" binding:"required,max=255"`
+	Quantity  int        `json:"quantity" binding:"required,min=1"`
+	Serials   []string   `json:"serial_numbers" binding:"omitempty,max=1000,dive,required,max=255"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// validLots adds to invalid the problems with lots moving quantity units,
+// at field: their quantities must add up to it, each lot be listed once,
+// and serial numbers, if given, number their lot's quantity, each listed
+// once
+func validLots(invalid *apperrors.ValidationErrors, field string, lots []lotRequest, quantity int) {
+	if len(lots) == 0 {
+		return
+	}
+
+	total := 0
+	listed := make(map[string]bool, len(lots))
+	serials := make(map[string]bool)
+	for i, lot := range lots {
+		total += lot.Quantity
+		if listed[lot.LotNumber] {
+			invalid.Add(fmt.Sprintf("%s[%d].lot_number", field, i), "unique", "Lot is listed more than once")
+		}
+		listed[lot.LotNumber] = true
+
+		if len(lot.Serials) > 0 && len(lot.Serials) != lot.Quantity {
+			invalid.Add(fmt.Sprintf("%s[%d].serial_numbers", field, i), "len", "Give a serial number for each unit of the lot")
+		}
+		for _, serial := range lot.Serials {
+			if serials[serial] {
+				invalid.Add(fmt.Sprintf("%s[%d].serial_numbers", field, i), "unique", "Serial number "+serial+" is listed more than once")
+			}
+			serials[serial] = true
+		}
+	}
+	if total != quantity {
+		invalid.Add(field, "sum", fmt.Sprintf("Lot quantities must add up to %d", quantity))
+	}
+}
+
+// moveLots receives item's stock into lots, creating new ones, or, if
+// removed, takes it out of them, recording each lot's movement against
+// what moved it. A lot's expiry is set by the first receipt giving one.
+// Expired lots' stock can only be adjusted out, e.g. when disposed of; it
+// counts in the item's expired quantity, which moveLots updates.
+func moveLots(ctx context.Context, repo repository.InventoryRepository, item *domain.InventoryItem, lots []lotRequest, removed bool, referenceType, referenceID, actor string) error {
+	expired := 0
+	for _, req := range lots {
+		quantity := req.Quantity
+		if removed {
+			quantity = -quantity
+		}
+
+		lot, err := repo.LockLot(ctx, item.ProductID, req.LotNumber)
+		exists := err == nil
+		switch {
+		case err == domain.ErrLotNotFound && removed:
+			return apperrors.New(http.StatusNotFound, "Lot not found").WithFields(gin.H{"lot_number": req.LotNumber})
+		case err == domain.ErrLotNotFound:
+			lot = &domain.Lot{ItemID: item.ID, ProductID: item.ProductID, Number: req.LotNumber}
+		case err != nil:
+			return apperrors.Wrap(err, "Failed to get lot")
+		}
+
+		switch {
+		case removed && referenceType != domain.ReferenceAdjustment && lot.ExpiredBy(time.Now()):
+			return apperrors.New(http.StatusConflict, domain.ErrLotExpired.Error()).WithFields(gin.H{"lot_number": lot.Number, "expires_at": lot.ExpiresAt})
+		case removed || req.ExpiresAt == nil:
+		case lot.ExpiresAt == nil:
+			lot.ExpiresAt = req.ExpiresAt
+		case !lot.ExpiresAt.Equal(*req.ExpiresAt):
+			return apperrors.New(http.StatusConflict, domain.ErrLotExpiry.Error()).WithFields(gin.H{"lot_number": lot.Number, "expires_at": lot.ExpiresAt})
+		}
+		if lot.Expired {
+			expired += quantity
+		}
+
+		if err := lot.Move(quantity); err != nil {
+			return apperrors.New(http.StatusConflict, err.Error()).WithFields(gin.H{"lot_number": lot.Number, "available": lot.Quantity})
+		}
+		if exists {
+			err = repo.UpdateLot(ctx, lot)
+		} else {
+			err = repo.CreateLot(ctx, lot)
+		}
+		if err != nil {
+			return apperrors.Wrap(err, "Failed to update lot")
+		}
+
+		if removed {
+			err = repo.RemoveSerials(ctx, lot, req.Serials)
+		} else {
+			err = repo.ReceiveSerials(ctx, lot, req.Serials)
+		}
+		if err == domain.ErrSerialInStock || err == domain.ErrSerialNotInLot {
+			return apperrors.New(http.StatusConflict, err.Error()).WithFields(gin.H{"lot_number": lot.Number})
+		}
+		if err != nil {
+			return apperrors.Wrap(err, "Failed to update serial numbers")
+		}
+
+		movement := &domain.LotMovement{
+			LotID:         lot.ID,
+			LotNumber:     lot.Number,
+			ItemID:        item.ID,
+			ProductID:     item.ProductID,
+			Quantity:      quantity,
+			Serials:       req.Serials,
+			ReferenceType: referenceType,
+			ReferenceID:   referenceID,
+			Actor:         actor,
+		}
+		if err := repo.RecordLotMovement(ctx, movement); err != nil {
+			return apperrors.Wrap(err, "Failed to record lot movement")
+		}
+	}
+
+	if expired != 0 {
+		item.ExpireStock(expired)
+		if err := repo.Update(ctx, item); err != nil {
+			return apperrors.Wrap(err, "Failed to update inventory item")
+		}
+	}
+	return nil
+}
+
+// allocateLots picks the lots quantity units of item's stock leave from,
+// first expired first out, for items allocating FEFO. Units the lots are
+// short of come from the item's stock outside lots, if it has enough;
+// expired stock never leaves this way.
+func allocateLots(ctx context.Context, repo repository.InventoryRepository, item *domain.InventoryItem, quantity int) ([]lotRequest, error) {
+	lots, err := repo.ListLots(ctx, item.ID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "Failed to list lots")
+	}
+
+	allocations, short := domain.AllocateFEFO(lots, quantity, time.Now())
+	untraced := item.Quantity
+	for _, lot := range lots {
+		untraced -= lot.Quantity
+	}
+	if short > 0 && short > untraced {
+		if untraced < 0 {
+			untraced = 0
+		}
+		return nil, apperrors.New(http.StatusConflict, "Insufficient unexpired stock").WithFields(gin.H{"available": quantity - short + untraced})
+	}
+
+	allocated := make([]lotRequest, len(allocations))
+	for i, allocation := range allocations {
+		allocated[i] = lotRequest{LotNumber: allocation.Lot.Number, Quantity: allocation.Quantity}
+	}
+	return allocated, nil
+}
+
+// GetItemLots lists an inventory item's lots with their stock, oldest
+// first
+func (h *Handler) GetItemLots(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.repo.GetByID(c.Request.Context(), id); err == domain.ErrNotFound {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Inventory item not found"))
+		return
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get inventory item"))
+		return
+	}
+
+	lots, err := h.repo.ListLots(c.Request.Context(), id)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list lots"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"lots": lots})
+}
+
+// GetLotMovements lists the stock received into and removed from a lot
+// number, newest first, a page at a time, of any product unless
+// product_id is given, e.g. to trace a recalled lot
+func (h *Handler) GetLotMovements(c *gin.Context) {
+	params := pagination.FromQuery(c.Request.URL.Query())
+	filter := repository.LotMovementFilter{
+		LotNumber: c.Param("lot"),
+		ProductID: c.Query("product_id"),
+	}
+
+	var after *repository.ListPosition
+	if params.Cursor != "" {
+		after = &repository.ListPosition{}
+		if err := pagination.DecodeCursor(params.Cursor, after); err != nil {
+			apperrors.Abort(c, apperrors.NewBadRequest("Invalid cursor"))
+			return
+		}
+	}
+
+	movements, err := h.repo.ListLotMovements(c.Request.Context(), filter, params.Limit+1, after)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list lot movements"))
+		return
+	}
+
+	page, err := listPage(c, params, movements, func() (int64, error) {
+		return h.repo.CountLotMovements(c.Request.Context(), filter)
+	}, func(movement *domain.LotMovement) interface{} {
+		return repository.ListPosition{CreatedAt: movement.OccurredAt, ID: movement.ID}
+	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list lot movements"))
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetSerial looks up the units with a serial number: their product, lot
+// and whether they are in stock
+func (h *Handler) GetSerial(c *gin.Context) {
+	serials, err := h.repo.GetSerials(c.Request.Context(), c.Param("serial"))
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get serial number"))
+		return
+	}
+	if len(serials) == 0 {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Serial number not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"serials": serials})
+}
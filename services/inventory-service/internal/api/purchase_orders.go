@@ -0,0 +1,317 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/audit"
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce-platform/shared/go/pagination"
+	"github.com/ecommerce/inventory-service/internal/domain"
+	"github.com/ecommerce/inventory-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// purchaseOrderRequest asks for stock to be ordered from a supplier
+type purchaseOrderRequest struct {
+	Supplier string `json:"supplier" binding:"required"`
+	Lines    []struct {
+		ProductID string       `json:"product_id" binding:"required"`
+		Quantity  int          `json:"quantity" binding:"required,min=1"`
+		UnitCost  *money.Money `json:"unit_cost"`
+	} `json:"lines" binding:"required,min=1,max=100,dive"`
+	Notes string `json:"notes"`
+}
+
+// CreatePurchaseOrder drafts a purchase order by hand, alongside those the
+// reorder job drafts
+func (h *Handler) CreatePurchaseOrder(c *gin.Context) {
+	var req purchaseOrderRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	var invalid apperrors.ValidationErrors
+	listed := make(map[string]bool, len(req.Lines))
+	for i, line := range req.Lines {
+		if listed[line.ProductID] {
+			invalid.Add(fmt.Sprintf("lines[%d].product_id", i), "unique", "Product is listed more than once")
+		}
+		listed[line.ProductID] = true
+		validCost(&invalid, fmt.Sprintf("lines[%d].unit_cost", i), line.UnitCost)
+	}
+	if err := invalid.Err(); err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	order := &domain.PurchaseOrder{
+		Supplier:  req.Supplier,
+		Status:    domain.PurchaseOrderDraft,
+		CreatedBy: c.GetString(sharedauth.ContextUserID),
+		Notes:     req.Notes,
+	}
+	err := h.repo.InTx(ctx, func(repo repository.InventoryRepository) error {
+		order.Lines = make([]domain.PurchaseOrderLine, len(req.Lines))
+		for i, line := range req.Lines {
+			item, err := repo.GetByProductID(ctx, line.ProductID)
+			if err == domain.ErrNotFound {
+				return apperrors.New(http.StatusNotFound, "Inventory item not found").WithFields(gin.H{"product_id": line.ProductID})
+			}
+			if err != nil {
+				return apperrors.Wrap(err, "Failed to get inventory item")
+			}
+			order.Lines[i] = domain.PurchaseOrderLine{ProductID: item.ProductID, SKU: item.SKU, Quantity: line.Quantity, UnitCost: line.UnitCost}
+		}
+
+		if err := repo.CreatePurchaseOrder(ctx, order); err != nil {
+			return apperrors.Wrap(err, "Failed to create purchase order")
+		}
+		return nil
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	h.purchaseOrderChanged(c, "purchase_order.created", order)
+	c.JSON(http.StatusCreated, order)
+}
+
+// OrderPurchaseOrder records that a draft purchase order was sent to its
+// supplier. Ordering it again changes nothing.
+func (h *Handler) OrderPurchaseOrder(c *gin.Context) {
+	h.advancePurchaseOrder(c, domain.PurchaseOrderOrdered)
+}
+
+// ReceivePurchaseOrder records that an ordered purchase order's stock
+// arrived, adding each line's quantity to its item, and to the lots the
+// optional body lists. Receiving it again changes nothing.
+func (h *Handler) ReceivePurchaseOrder(c *gin.Context) {
+	h.advancePurchaseOrder(c, domain.PurchaseOrderReceived)
+}
+
+// CancelPurchaseOrder cancels a purchase order whose stock hasn't
+// arrived. Cancelling it again changes nothing.
+func (h *Handler) CancelPurchaseOrder(c *gin.Context) {
+	h.advancePurchaseOrder(c, domain.PurchaseOrderCancelled)
+}
+
+// advancePurchaseOrder moves the purchase order of the request to status:
+// a draft can be ordered, an ordered one received, and either cancelled
+func (h *Handler) advancePurchaseOrder(c *gin.Context, status string) {
+	var req receiveRequest
+	if status == domain.PurchaseOrderReceived && c.Request.ContentLength != 0 && !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	actor := c.GetString(sharedauth.ContextUserID)
+	var order *domain.PurchaseOrder
+	var received []*domain.InventoryItem
+	advanced := false
+	err := h.repo.InTx(ctx, func(repo repository.InventoryRepository) error {
+		var err error
+		order, err = repo.GetPurchaseOrder(ctx, c.Param("purchaseOrderId"))
+		if err == domain.ErrPurchaseOrderNotFound {
+			return apperrors.New(http.StatusNotFound, "Purchase order not found")
+		}
+		if err != nil {
+			return apperrors.Wrap(err, "Failed to get purchase order")
+		}
+
+		now := time.Now()
+		switch {
+		case order.Status == status:
+			return nil
+		case status == domain.PurchaseOrderOrdered && order.Status == domain.PurchaseOrderDraft:
+			order.OrderedAt = &now
+		case status == domain.PurchaseOrderReceived && order.Status == domain.PurchaseOrderOrdered:
+			order.ReceivedAt = &now
+			received, err = receiveLines(ctx, repo, order, req.Lots, actor)
+			if err != nil {
+				return err
+			}
+		case status == domain.PurchaseOrderCancelled && order.Open():
+			order.CancelledAt = &now
+		default:
+			return apperrors.New(http.StatusConflict, "Purchase order is "+order.Status)
+		}
+
+		order.Status = status
+		if err := repo.UpdatePurchaseOrder(ctx, order); err != nil {
+			return apperrors.Wrap(err, "Failed to update purchase order")
+		}
+		advanced = true
+		return nil
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	if advanced {
+		for _, item := range received {
+			_ = h.cache.Delete(ctx, item.ProductID)
+			if err := h.publisher.PublishInventoryUpdated(ctx, item); err != nil {
+				h.logger.Error("Failed to publish inventory updated event", zap.Error(err))
+			}
+		}
+		h.purchaseOrderChanged(c, "purchase_order."+status, order)
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+// receiveRequest optionally lists the lots a purchase order's stock
+// arrived in
+type receiveRequest struct {
+	Lots []receivedLot `json:"lots" binding:"omitempty,max=1000,dive"`
+}
+
+// receivedLot is a lot of a purchase order line's product
+type receivedLot struct {
+	ProductID string `json:"product_id" binding:"required"`
+	lotRequest
+}
+
+// receiveLines adds a purchase order's lines' stock to their items, at the
+// lines' unit costs if known, and to the lots listed for their products,
+// returning the items
+func receiveLines(ctx context.Context, repo repository.InventoryRepository, order *domain.PurchaseOrder, lots []receivedLot, actor string) ([]*domain.InventoryItem, error) {
+	byProduct := make(map[string][]lotRequest)
+	for _, lot := range lots {
+		byProduct[lot.ProductID] = append(byProduct[lot.ProductID], lot.lotRequest)
+	}
+	var invalid apperrors.ValidationErrors
+	byLine := make([][]lotRequest, len(order.Lines))
+	for i, line := range order.Lines {
+		byLine[i] = byProduct[line.ProductID]
+		validLots(&invalid, "lots["+line.ProductID+"]", byLine[i], line.Quantity)
+		delete(byProduct, line.ProductID)
+	}
+	for productID := range byProduct {
+		invalid.Add("lots["+productID+"]", "oneof", "Product isn't on the purchase order")
+	}
+	if err := invalid.Err(); err != nil {
+		return nil, err
+	}
+
+	items := make([]*domain.InventoryItem, len(order.Lines))
+	for i, line := range order.Lines {
+		item, err := repo.GetByProductID(ctx, line.ProductID)
+		if err == domain.ErrNotFound {
+			return nil, apperrors.New(http.StatusConflict, "Inventory item no longer exists").WithFields(gin.H{"product_id": line.ProductID})
+		}
+		if err != nil {
+			return nil, apperrors.Wrap(err, "Failed to get inventory item")
+		}
+
+		if line.UnitCost != nil {
+			err = item.ReceiveAt(line.Quantity, *line.UnitCost)
+		} else {
+			err = item.Add(line.Quantity)
+		}
+		if err != nil {
+			return nil, apperrors.New(http.StatusConflict, err.Error()).WithFields(gin.H{"product_id": line.ProductID})
+		}
+		if err := repo.Update(ctx, item); err != nil {
+			return nil, apperrors.Wrap(err, "Failed to update inventory item")
+		}
+		if err := moveLots(ctx, repo, item, byLine[i], false, domain.ReferencePurchaseOrder, order.ID, actor); err != nil {
+			return nil, err
+		}
+
+		event := domain.NewHistoryEvent(item, domain.HistoryReceived, line.Quantity, 0).
+			Referencing(domain.ReferencePurchaseOrder, order.ID)
+		event.Actor = actor
+		event.Detail = order.Supplier
+		if err := repo.RecordHistory(ctx, event); err != nil {
+			return nil, apperrors.Wrap(err, "Failed to record history")
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+// purchaseOrderChanged audits a change to a purchase order
+func (h *Handler) purchaseOrderChanged(c *gin.Context, action string, order *domain.PurchaseOrder) {
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   action,
+		Resource: audit.Resource{Type: "purchase_order", ID: order.ID},
+		After:    order,
+		Metadata: map[string]string{
+			"supplier": order.Supplier,
+			"lines":    strconv.Itoa(len(order.Lines)),
+		},
+	})
+
+	h.logger.Info("Purchase order "+order.Status,
+		zap.String("purchase_order_id", order.ID),
+		zap.String("supplier", order.Supplier),
+	)
+}
+
+// GetPurchaseOrder retrieves a purchase order
+func (h *Handler) GetPurchaseOrder(c *gin.Context) {
+	order, err := h.repo.GetPurchaseOrder(c.Request.Context(), c.Param("purchaseOrderId"))
+	if err == domain.ErrPurchaseOrderNotFound {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Purchase order not found"))
+		return
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get purchase order"))
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// ListPurchaseOrders lists purchase orders newest first, a page at a
+// time, optionally with a status or of a supplier
+func (h *Handler) ListPurchaseOrders(c *gin.Context) {
+	params := pagination.FromQuery(c.Request.URL.Query())
+	filter := repository.PurchaseOrderFilter{
+		Status:   c.Query("status"),
+		Supplier: c.Query("supplier"),
+	}
+	switch filter.Status {
+	case "", domain.PurchaseOrderDraft, domain.PurchaseOrderOrdered, domain.PurchaseOrderReceived, domain.PurchaseOrderCancelled:
+	default:
+		apperrors.Abort(c, apperrors.NewBadRequest("status must be draft, ordered, received or cancelled"))
+		return
+	}
+
+	var after *repository.ListPosition
+	if params.Cursor != "" {
+		after = &repository.ListPosition{}
+		if err := pagination.DecodeCursor(params.Cursor, after); err != nil {
+			apperrors.Abort(c, apperrors.NewBadRequest("Invalid cursor"))
+			return
+		}
+	}
+
+	orders, err := h.repo.ListPurchaseOrders(c.Request.Context(), filter, params.Limit+1, after)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list purchase orders"))
+		return
+	}
+
+	page, err := listPage(c, params, orders, func() (int64, error) {
+		return h.repo.CountPurchaseOrders(c.Request.Context(), filter)
+	}, func(order *domain.PurchaseOrder) interface{} {
+		return repository.ListPosition{CreatedAt: order.CreatedAt, ID: order.ID}
+	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list purchase orders"))
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
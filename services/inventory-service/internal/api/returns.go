@@ -0,0 +1,148 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ecommerce-platform/shared/go/audit"
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce/inventory-service/internal/domain"
+	"github.com/ecommerce/inventory-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// restockReturnRequest is an order's returned units of an item, checked
+// back in. Each order line's return is checked in once.
+type restockReturnRequest struct {
+	OrderID   string                 `json:"order_id" binding:"required,max=255"`
+	LineID    string                 `json:"line_id" binding:"required,max=255"`
+	Quantity  int                    `json:"quantity" binding:"required,min=1"`
+	Condition domain.ReturnCondition `json:"condition" binding:"required,oneof=sellable damaged"`
+	Notes     string                 `json:"notes"`
+	// Lots are the lots sellable units go back into
+	Lots []lotRequest `json:"lots" binding:"omitempty,max=100,dive"`
+}
+
+// RestockReturn checks an order's returned units of an inventory item back
+// in: sellable units are added to its stock, at its unit cost, and damaged
+// ones only recorded. Either way the return is recorded as an adjustment
+// with the order and the units returned. A line already checked in is
+// rejected, so retries don't add its units twice.
+func (h *Handler) RestockReturn(c *gin.Context) {
+	var req restockReturnRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	var invalid apperrors.ValidationErrors
+	if req.Condition == domain.ReturnDamaged && len(req.Lots) > 0 {
+		invalid.Add("lots", "excluded_if", "Only sellable units go back into lots")
+	}
+	validLots(&invalid, "lots", req.Lots, req.Quantity)
+	if err := invalid.Err(); err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	restocked := 0
+	if req.Condition == domain.ReturnSellable {
+		restocked = req.Quantity
+	}
+	actor := c.GetString(sharedauth.ContextUserID)
+
+	var item *domain.InventoryItem
+	var before domain.InventoryItem
+	var adjustment *domain.InventoryAdjustment
+	err := h.repo.InTx(c.Request.Context(), func(repo repository.InventoryRepository) error {
+		var err error
+		item, err = repo.GetByID(c.Request.Context(), c.Param("id"))
+		if err == domain.ErrNotFound {
+			return apperrors.New(http.StatusNotFound, "Inventory item not found")
+		}
+		if err != nil {
+			return apperrors.Wrap(err, "Failed to get inventory item")
+		}
+
+		before = *item
+		if restocked > 0 {
+			_ = item.Add(restocked)
+			if err := repo.Update(c.Request.Context(), item); err != nil {
+				return apperrors.Wrap(err, "Failed to restock return")
+			}
+		}
+
+		adjustment = &domain.InventoryAdjustment{
+			ProductID:        item.ProductID,
+			Quantity:         restocked,
+			Reason:           domain.ReturnReason(req.Condition),
+			AdjustedBy:       actor,
+			Notes:            req.Notes,
+			UnitCost:         item.UnitCost,
+			OrderID:          req.OrderID,
+			ReturnedQuantity: req.Quantity,
+		}
+		if err := repo.CreateAdjustment(c.Request.Context(), adjustment); err != nil {
+			return apperrors.Wrap(err, "Failed to create adjustment record")
+		}
+		err = repo.RecordRestockedReturn(c.Request.Context(), req.OrderID, req.LineID, adjustment.ID)
+		if err == domain.ErrReturnRestocked {
+			return apperrors.New(http.StatusConflict, "Return was already restocked")
+		}
+		if err != nil {
+			return apperrors.Wrap(err, "Failed to record restocked return")
+		}
+		if err := moveLots(c.Request.Context(), repo, item, req.Lots, false, domain.ReferenceAdjustment, adjustment.ID, actor); err != nil {
+			return err
+		}
+
+		event := domain.NewHistoryEventSince(before, item, domain.HistoryReturned).
+			Referencing(domain.ReferenceAdjustment, adjustment.ID)
+		event.Actor = actor
+		event.Detail = req.OrderID
+		if err := repo.RecordHistory(c.Request.Context(), event); err != nil {
+			return apperrors.Wrap(err, "Failed to record history")
+		}
+		return nil
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	// Invalidate cache
+	_ = h.cache.Delete(c.Request.Context(), item.ProductID)
+
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   "inventory.return_restocked",
+		Resource: audit.Resource{Type: "inventory_item", ID: item.ID},
+		Before:   before,
+		After:    item,
+		Metadata: map[string]string{
+			"product_id": item.ProductID,
+			"order_id":   req.OrderID,
+			"line_id":    req.LineID,
+			"quantity":   strconv.Itoa(req.Quantity),
+			"condition":  string(req.Condition),
+			"notes":      req.Notes,
+		},
+	})
+
+	// Publish event
+	if err := h.publisher.PublishReturnRestocked(c.Request.Context(), item, adjustment, req.Condition); err != nil {
+		h.logger.Error("Failed to publish return restocked event", zap.Error(err))
+	}
+	h.publishCrossing(c.Request.Context(), before, item)
+
+	h.logger.Info("Return restocked",
+		zap.String("product_id", item.ProductID),
+		zap.String("order_id", req.OrderID),
+		zap.String("line_id", req.LineID),
+		zap.Int("quantity", req.Quantity),
+		zap.String("condition", string(req.Condition)),
+	)
+	c.JSON(http.StatusOK, gin.H{
+		"adjustment": adjustment,
+		"item":       item,
+	})
+}
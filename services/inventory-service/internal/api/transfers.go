@@ -0,0 +1,319 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/audit"
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/pagination"
+	"github.com/ecommerce/inventory-service/internal/domain"
+	"github.com/ecommerce/inventory-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// transferRequest asks for stock to be moved between locations
+type transferRequest struct {
+	ProductID    string `json:"product_id" binding:"required"`
+	FromLocation string `json:"from_location" binding:"required"`
+	ToLocation   string `json:"to_location" binding:"required,nefield=FromLocation"`
+	Quantity     int    `json:"quantity" binding:"required,min=1"`
+	Notes        string `json:"notes"`
+}
+
+// CreateTransfer starts moving a product's stock from one location to
+// another: the stock leaves the source now, and is in transit until the
+// transfer is completed or cancelled. Reserved stock can't be moved.
+func (h *Handler) CreateTransfer(c *gin.Context) {
+	var req transferRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	var item *domain.InventoryItem
+	var before domain.InventoryItem
+	var transfer *domain.Transfer
+	err := h.repo.InTx(ctx, func(repo repository.InventoryRepository) error {
+		locations, err := itemLocations(ctx, repo, req.ProductID)
+		if err != nil {
+			return err
+		}
+		item = locations.Item
+		before = *item
+
+		if err := locations.Remove(req.FromLocation, req.Quantity); err == domain.ErrInsufficientStock {
+			available := locations.At(req.FromLocation)
+			if item.AvailableQuantity < available {
+				available = item.AvailableQuantity
+			}
+			return apperrors.New(http.StatusConflict, "Insufficient stock at "+req.FromLocation).WithFields(gin.H{"available": available})
+		} else if err != nil {
+			return apperrors.New(http.StatusConflict, err.Error())
+		}
+		if err := saveLocations(ctx, repo, locations, req.FromLocation); err != nil {
+			return err
+		}
+
+		transfer = &domain.Transfer{
+			ProductID:    item.ProductID,
+			FromLocation: req.FromLocation,
+			ToLocation:   req.ToLocation,
+			Quantity:     req.Quantity,
+			Status:       domain.TransferInTransit,
+			RequestedBy:  c.GetString(sharedauth.ContextUserID),
+			Notes:        req.Notes,
+		}
+		if err := repo.CreateTransfer(ctx, transfer); err != nil {
+			return apperrors.Wrap(err, "Failed to create transfer")
+		}
+		return recordTransfer(ctx, repo, before, item, domain.HistoryTransferredOut, transfer, transfer.RequestedBy)
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	h.transferred(c, "inventory.transfer_created", transfer, &before, item)
+	c.JSON(http.StatusCreated, transfer)
+}
+
+// CompleteTransfer records that a transfer's stock arrived at its
+// destination. Completing it again changes nothing.
+func (h *Handler) CompleteTransfer(c *gin.Context) {
+	h.finishTransfer(c, domain.TransferCompleted)
+}
+
+// CancelTransfer returns an in-transit transfer's stock to its source.
+// Cancelling it again changes nothing.
+func (h *Handler) CancelTransfer(c *gin.Context) {
+	h.finishTransfer(c, domain.TransferCancelled)
+}
+
+// finishTransfer ends the transfer of the request, putting its stock at its
+// destination if it is completed or back at its source if cancelled
+func (h *Handler) finishTransfer(c *gin.Context, status string) {
+	ctx := c.Request.Context()
+	var item *domain.InventoryItem
+	var before domain.InventoryItem
+	var transfer *domain.Transfer
+	finished := false
+	err := h.repo.InTx(ctx, func(repo repository.InventoryRepository) error {
+		var err error
+		transfer, err = repo.GetTransfer(ctx, c.Param("transferId"))
+		if err == domain.ErrTransferNotFound {
+			return apperrors.New(http.StatusNotFound, "Transfer not found")
+		}
+		if err != nil {
+			return apperrors.Wrap(err, "Failed to get transfer")
+		}
+
+		switch transfer.Status {
+		case status:
+			return nil
+		case domain.TransferInTransit:
+		default:
+			return apperrors.New(http.StatusConflict, "Transfer is "+transfer.Status)
+		}
+
+		locations, err := itemLocations(ctx, repo, transfer.ProductID)
+		if err != nil {
+			return err
+		}
+		item = locations.Item
+		before = *item
+
+		now := time.Now()
+		location := transfer.ToLocation
+		if status == domain.TransferCompleted {
+			transfer.CompletedAt = &now
+		} else {
+			location = transfer.FromLocation
+			transfer.CancelledAt = &now
+		}
+		if err := locations.Put(location, transfer.Quantity); err != nil {
+			return apperrors.New(http.StatusConflict, err.Error())
+		}
+		if err := saveLocations(ctx, repo, locations, location); err != nil {
+			return err
+		}
+
+		transfer.Status = status
+		if err := repo.UpdateTransfer(ctx, transfer); err != nil {
+			return apperrors.Wrap(err, "Failed to update transfer")
+		}
+
+		eventType := domain.HistoryTransferredIn
+		if status == domain.TransferCancelled {
+			eventType = domain.HistoryTransferReturned
+		}
+		if err := recordTransfer(ctx, repo, before, item, eventType, transfer, c.GetString(sharedauth.ContextUserID)); err != nil {
+			return err
+		}
+		finished = true
+		return nil
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	if finished {
+		h.transferred(c, "inventory.transfer_"+status, transfer, &before, item)
+	}
+	c.JSON(http.StatusOK, transfer)
+}
+
+// itemLocations retrieves a product's item and its stock by location
+func itemLocations(ctx context.Context, repo repository.InventoryRepository, productID string) (domain.Locations, error) {
+	item, err := repo.GetByProductID(ctx, productID)
+	if err == domain.ErrNotFound {
+		return domain.Locations{}, apperrors.New(http.StatusNotFound, "Inventory item not found")
+	}
+	if err != nil {
+		return domain.Locations{}, apperrors.Wrap(err, "Failed to get inventory item")
+	}
+
+	away, err := repo.GetLocationStock(ctx, productID)
+	if err != nil {
+		return domain.Locations{}, apperrors.Wrap(err, "Failed to get stock by location")
+	}
+	return domain.Locations{Item: item, Away: away}, nil
+}
+
+// saveLocations stores an item and its stock at the location a transfer
+// changed
+func saveLocations(ctx context.Context, repo repository.InventoryRepository, locations domain.Locations, location string) error {
+	if err := repo.Update(ctx, locations.Item); err != nil {
+		return apperrors.Wrap(err, "Failed to update inventory item")
+	}
+	if location == locations.Item.Location {
+		return nil
+	}
+	if err := repo.SetLocationStock(ctx, locations.Item.ProductID, location, locations.Away[location]); err != nil {
+		return apperrors.Wrap(err, "Failed to update stock by location")
+	}
+	return nil
+}
+
+// recordTransfer adds a transfer's change to its item to the item's
+// history
+func recordTransfer(ctx context.Context, repo repository.InventoryRepository, before domain.InventoryItem, item *domain.InventoryItem, eventType string, transfer *domain.Transfer, actor string) error {
+	event := domain.NewHistoryEventSince(before, item, eventType).Referencing(domain.ReferenceTransfer, transfer.ID)
+	event.Actor = actor
+	event.Detail = transfer.FromLocation + " to " + transfer.ToLocation
+	if err := repo.RecordHistory(ctx, event); err != nil {
+		return apperrors.Wrap(err, "Failed to record history")
+	}
+	return nil
+}
+
+// transferred audits a change to a transfer and announces its item's new
+// quantity
+func (h *Handler) transferred(c *gin.Context, action string, transfer *domain.Transfer, before, item *domain.InventoryItem) {
+	ctx := c.Request.Context()
+	_ = h.cache.Delete(ctx, item.ProductID)
+
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   action,
+		Resource: audit.Resource{Type: "inventory_item", ID: item.ID},
+		Before:   before,
+		After:    item,
+		Metadata: map[string]string{
+			"product_id":    item.ProductID,
+			"transfer_id":   transfer.ID,
+			"from_location": transfer.FromLocation,
+			"to_location":   transfer.ToLocation,
+			"quantity":      strconv.Itoa(transfer.Quantity),
+		},
+	})
+
+	if err := h.publisher.PublishInventoryUpdated(ctx, item); err != nil {
+		h.logger.Error("Failed to publish inventory updated event", zap.Error(err))
+	}
+	h.publishCrossing(ctx, *before, item)
+
+	h.logger.Info("Transfer "+transfer.Status,
+		zap.String("transfer_id", transfer.ID),
+		zap.String("product_id", item.ProductID),
+		zap.Int("quantity", transfer.Quantity),
+	)
+}
+
+// GetTransfer retrieves a transfer
+func (h *Handler) GetTransfer(c *gin.Context) {
+	transfer, err := h.repo.GetTransfer(c.Request.Context(), c.Param("transferId"))
+	if err == domain.ErrTransferNotFound {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Transfer not found"))
+		return
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get transfer"))
+		return
+	}
+
+	c.JSON(http.StatusOK, transfer)
+}
+
+// ListTransfers lists transfers newest first, a page at a time, optionally
+// of a product_id, with a status, or from or to a location
+func (h *Handler) ListTransfers(c *gin.Context) {
+	params := pagination.FromQuery(c.Request.URL.Query())
+	filter := repository.TransferFilter{
+		ProductID: c.Query("product_id"),
+		Status:    c.Query("status"),
+		Location:  c.Query("location"),
+	}
+	switch filter.Status {
+	case "", domain.TransferInTransit, domain.TransferCompleted, domain.TransferCancelled:
+	default:
+		apperrors.Abort(c, apperrors.NewBadRequest("status must be in_transit, completed or cancelled"))
+		return
+	}
+
+	var after *repository.ListPosition
+	if params.Cursor != "" {
+		after = &repository.ListPosition{}
+		if err := pagination.DecodeCursor(params.Cursor, after); err != nil {
+			apperrors.Abort(c, apperrors.NewBadRequest("Invalid cursor"))
+			return
+		}
+	}
+
+	transfers, err := h.repo.ListTransfers(c.Request.Context(), filter, params.Limit+1, after)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list transfers"))
+		return
+	}
+
+	page, err := listPage(c, params, transfers, func() (int64, error) {
+		return h.repo.CountTransfers(c.Request.Context(), filter)
+	}, func(transfer *domain.Transfer) interface{} {
+		return repository.ListPosition{CreatedAt: transfer.CreatedAt, ID: transfer.ID}
+	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list transfers"))
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetProductLocations retrieves a product's stock at each location, its
+// item's own location first
+func (h *Handler) GetProductLocations(c *gin.Context) {
+	locations, err := itemLocations(c.Request.Context(), h.repo, c.Param("productId"))
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"product_id": locations.Item.ProductID,
+		"locations":  locations.Stock(),
+	})
+}
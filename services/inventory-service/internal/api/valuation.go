@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce-platform/shared/go/validation"
+	"github.com/ecommerce/inventory-service/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// costCurrency is a unit cost's currency, checked by the shared currency
+// rule; money.Money is bound by services that don't register the rules,
+// so it can't carry the tag itself
+type costCurrency struct {
+	Currency string `json:"currency" binding:"currency"`
+}
+
+// validCost adds to invalid the problems with the unit cost at field, if
+// any: it can't be negative and needs a currency. Its currency is
+// upper-cased.
+func validCost(invalid *apperrors.ValidationErrors, field string, cost *money.Money) {
+	if cost == nil {
+		return
+	}
+	*cost = money.New(cost.MinorUnits, cost.Currency)
+	if cost.IsNegative() {
+		invalid.Add(field+".minor_units", "min", "Unit cost can't be negative")
+	}
+	if validation.Struct(costCurrency{Currency: cost.Currency}) != nil {
+		invalid.Add(field+".currency", "currency", "Currency must be an ISO 4217 code, e.g. USD")
+	}
+}
+
+// GetValuation values the stock of the items with stock at their unit
+// cost, per item and in total per currency, for finance reporting. It
+// takes the inventory listing's filters, e.g. location or supplier.
+func (h *Handler) GetValuation(c *gin.Context) {
+	filter, err := itemFilter(c)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	items, err := h.repo.ListStocked(c.Request.Context(), filter)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to value inventory"))
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.Value(items))
+}
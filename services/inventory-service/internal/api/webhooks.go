@@ -0,0 +1,256 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ecommerce-platform/shared/go/audit"
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/pagination"
+	"github.com/ecommerce/inventory-service/internal/domain"
+	"github.com/ecommerce/inventory-service/internal/repository"
+	"github.com/ecommerce/inventory-service/internal/webhooks"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// webhookRequest subscribes a URL to inventory events. Active defaults to
+// true.
+type webhookRequest struct {
+	URL        string   `json:"url" binding:"required,url,max=2048"`
+	EventTypes []string `json:"event_types" binding:"required,min=1"`
+	Active     *bool    `json:"active"`
+}
+
+// validate rejects URLs that aren't http or https or don't lead to a
+// public address, and unknown or repeated event types
+func (r *webhookRequest) validate(ctx context.Context) error {
+	var invalid apperrors.ValidationErrors
+	if parsed, err := url.Parse(r.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		invalid.Add("url", "url", "url must be an http or https URL")
+	} else if err := webhooks.CheckURL(ctx, r.URL); err != nil {
+		invalid.Add("url", "public_url", "url must lead to a public address")
+	}
+	listed := make(map[string]bool, len(r.EventTypes))
+	for i, eventType := range r.EventTypes {
+		field := fmt.Sprintf("event_types[%d]", i)
+		if !domain.IsWebhookEventType(eventType) {
+			invalid.Add(field, "oneof", "event type must be one of "+strings.Join(domain.WebhookEventTypes, ", "))
+		} else if listed[eventType] {
+			invalid.Add(field, "unique", "Event type is listed more than once")
+		}
+		listed[eventType] = true
+	}
+	return invalid.Err()
+}
+
+// active reports whether the request's webhook is active
+func (r *webhookRequest) active() bool {
+	return r.Active == nil || *r.Active
+}
+
+// CreateWebhook subscribes a URL to inventory events. Deliveries are
+// signed with the given secret, or a generated one; either is only
+// returned here.
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	var req struct {
+		webhookRequest
+		Secret string `json:"secret" binding:"omitempty,min=16,max=255"`
+	}
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	if err := req.validate(c.Request.Context()); err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		generated := make([]byte, 32)
+		if _, err := rand.Read(generated); err != nil {
+			apperrors.Abort(c, apperrors.Wrap(err, "Failed to generate webhook secret"))
+			return
+		}
+		secret = hex.EncodeToString(generated)
+	}
+
+	webhook := &domain.Webhook{
+		URL:        req.URL,
+		Secret:     secret,
+		EventTypes: req.EventTypes,
+		Active:     req.active(),
+		CreatedBy:  c.GetString(sharedauth.ContextUserID),
+	}
+	if err := h.repo.CreateWebhook(c.Request.Context(), webhook); err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to create webhook"))
+		return
+	}
+
+	h.webhookChanged(c, "webhook.created", nil, webhook)
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// GetWebhook retrieves a webhook, without its secret
+func (h *Handler) GetWebhook(c *gin.Context) {
+	webhook, err := h.getWebhook(c)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, withoutSecret(webhook))
+}
+
+// ListWebhooks lists webhooks newest first, without their secrets
+func (h *Handler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.repo.ListWebhooks(c.Request.Context())
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list webhooks"))
+		return
+	}
+
+	listed := make([]*domain.Webhook, len(webhooks))
+	for i, webhook := range webhooks {
+		listed[i] = withoutSecret(webhook)
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": listed})
+}
+
+// UpdateWebhook replaces a webhook's URL, event types and whether it is
+// active. Its secret can't be changed; deactivating it stops new events
+// being queued, but those queued are still delivered.
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	var req webhookRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	if err := req.validate(c.Request.Context()); err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	webhook, err := h.getWebhook(c)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+	before := withoutSecret(webhook)
+
+	webhook.URL = req.URL
+	webhook.EventTypes = req.EventTypes
+	webhook.Active = req.active()
+	err = h.repo.UpdateWebhook(c.Request.Context(), webhook)
+	if err == domain.ErrWebhookNotFound {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Webhook not found"))
+		return
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to update webhook"))
+		return
+	}
+
+	after := withoutSecret(webhook)
+	h.webhookChanged(c, "webhook.updated", before, after)
+	c.JSON(http.StatusOK, after)
+}
+
+// DeleteWebhook deletes a webhook, with its deliveries
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	webhook, err := h.getWebhook(c)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	err = h.repo.DeleteWebhook(c.Request.Context(), webhook.ID)
+	if err != nil && err != domain.ErrWebhookNotFound {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to delete webhook"))
+		return
+	}
+
+	h.webhookChanged(c, "webhook.deleted", withoutSecret(webhook), nil)
+	c.Status(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries lists a webhook's deliveries newest first, a page
+// at a time, each with its attempts
+func (h *Handler) ListWebhookDeliveries(c *gin.Context) {
+	params := pagination.FromQuery(c.Request.URL.Query())
+	var after *repository.ListPosition
+	if params.Cursor != "" {
+		after = &repository.ListPosition{}
+		if err := pagination.DecodeCursor(params.Cursor, after); err != nil {
+			apperrors.Abort(c, apperrors.NewBadRequest("Invalid cursor"))
+			return
+		}
+	}
+
+	webhook, err := h.getWebhook(c)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	deliveries, err := h.repo.ListWebhookDeliveries(ctx, webhook.ID, params.Limit+1, after)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list webhook deliveries"))
+		return
+	}
+
+	page, err := listPage(c, params, deliveries, func() (int64, error) {
+		return h.repo.CountWebhookDeliveries(ctx, webhook.ID)
+	}, func(delivery *domain.WebhookDelivery) interface{} {
+		return repository.ListPosition{CreatedAt: delivery.CreatedAt, ID: delivery.ID}
+	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list webhook deliveries"))
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// getWebhook retrieves the webhook of the request
+func (h *Handler) getWebhook(c *gin.Context) (*domain.Webhook, error) {
+	webhook, err := h.repo.GetWebhook(c.Request.Context(), c.Param("webhookId"))
+	if err == domain.ErrWebhookNotFound {
+		return nil, apperrors.New(http.StatusNotFound, "Webhook not found")
+	}
+	if err != nil {
+		return nil, apperrors.Wrap(err, "Failed to get webhook")
+	}
+	return webhook, nil
+}
+
+// withoutSecret returns a copy of webhook without its secret
+func withoutSecret(webhook *domain.Webhook) *domain.Webhook {
+	copied := *webhook
+	copied.Secret = ""
+	return &copied
+}
+
+// webhookChanged audits a change to a webhook, never recording its secret
+func (h *Handler) webhookChanged(c *gin.Context, action string, before, after *domain.Webhook) {
+	entry := audit.Entry{
+		Action: action,
+	}
+	if before != nil {
+		entry.Resource = audit.Resource{Type: "webhook", ID: before.ID}
+		entry.Before = withoutSecret(before)
+	}
+	if after != nil {
+		entry.Resource = audit.Resource{Type: "webhook", ID: after.ID}
+		entry.After = withoutSecret(after)
+	}
+	h.auditor.LogGin(c, entry)
+
+	h.logger.Info("Webhook changed", zap.String("action", action), zap.String("webhook_id", entry.Resource.ID))
+}
@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/money"
+)
+
+// CostMethod is how an item's unit cost follows the stock it receives
+type CostMethod string
+
+const (
+	// CostWeightedAverage averages the cost of stock received into the
+	// unit cost, weighted by quantity
+	CostWeightedAverage CostMethod = "weighted_average"
+	// CostStandard keeps the unit cost set on the item, whatever stock
+	// costs when received
+	CostStandard CostMethod = "standard"
+)
+
+var ErrCostCurrency = errors.New("cost is in another currency than the item's unit cost")
+
+// ReceiveAt adds quantity to the stock at unitCost. Weighted average
+// items' unit cost becomes the average of their stock's and the stock
+// received, weighted by quantity and rounded to the nearest minor unit;
+// items without a unit cost, or weighted average items without stock, take
+// unitCost.
+func (i *InventoryItem) ReceiveAt(quantity int, unitCost money.Money) error {
+	if quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+	switch {
+	case i.UnitCost == nil:
+		i.UnitCost = &unitCost
+	case i.CostMethod == CostStandard:
+	case i.Quantity <= 0:
+		i.UnitCost = &unitCost
+	case i.UnitCost.Currency != unitCost.Currency:
+		return ErrCostCurrency
+	default:
+		held, received := int64(i.Quantity), int64(quantity)
+		total := held*i.UnitCost.MinorUnits + received*unitCost.MinorUnits
+		average := money.New((total+(held+received)/2)/(held+received), unitCost.Currency)
+		i.UnitCost = &average
+	}
+	return i.Add(quantity)
+}
+
+// ItemValuation is the value of an item's stock at its unit cost
+type ItemValuation struct {
+	ItemID     string     `json:"item_id"`
+	ProductID  string     `json:"product_id"`
+	SKU        string     `json:"sku"`
+	Location   string     `json:"location"`
+	Quantity   int        `json:"quantity"`
+	CostMethod CostMethod `json:"cost_method"`
+	// UnitCost and Value are nil for items without a unit cost, which
+	// aren't counted in the totals
+	UnitCost *money.Money `json:"unit_cost"`
+	Value    *money.Money `json:"value"`
+}
+
+// Valuation is the value of stock, per item and in total per currency
+type Valuation struct {
+	Items  []ItemValuation `json:"items"`
+	Totals []money.Money   `json:"totals"`
+	// UncostedItems is the number of items with stock but no unit cost
+	UncostedItems int       `json:"uncosted_items"`
+	ValuedAt      time.Time `json:"valued_at"`
+}
+
+// Value values items' stock on hand, reserved stock included, at their
+// unit cost
+func Value(items []*InventoryItem) *Valuation {
+	valuation := &Valuation{Items: make([]ItemValuation, len(items)), Totals: []money.Money{}, ValuedAt: time.Now()}
+	totals := make(map[string]int64)
+	for n, item := range items {
+		valuation.Items[n] = ItemValuation{
+			ItemID:     item.ID,
+			ProductID:  item.ProductID,
+			SKU:        item.SKU,
+			Location:   item.Location,
+			Quantity:   item.Quantity,
+			CostMethod: item.CostMethod,
+			UnitCost:   item.UnitCost,
+		}
+		if item.UnitCost == nil {
+			valuation.UncostedItems++
+			continue
+		}
+		value := item.UnitCost.Mul(int64(item.Quantity))
+		valuation.Items[n].Value = &value
+		totals[value.Currency] += value.MinorUnits
+	}
+
+	for currency, total := range totals {
+		valuation.Totals = append(valuation.Totals, money.New(total, currency))
+	}
+	sort.Slice(valuation.Totals, func(a, b int) bool {
+		return valuation.Totals[a].Currency < valuation.Totals[b].Currency
+	})
+	return valuation
+}
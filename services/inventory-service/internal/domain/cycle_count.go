@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// CycleCount is a stock-take of a location: its items' stock is counted,
+// compared with what the items record, and the approved variances are
+// applied as adjustments
+type CycleCount struct {
+	ID       string           `json:"id"`
+	Location string           `json:"location"`
+	Status   string           `json:"status"` // counting, applied, cancelled
+	Lines    []CycleCountLine `json:"lines"`
+	// CreatedBy is the user who started the count, and AppliedBy the one
+	// who applied its variances
+	CreatedBy   string     `json:"created_by"`
+	AppliedBy   string     `json:"applied_by,omitempty"`
+	Notes       string     `json:"notes"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+}
+
+// CycleCountLine is the count of one item on a cycle count
+type CycleCountLine struct {
+	ProductID string `json:"product_id"`
+	SKU       string `json:"sku"`
+	// ExpectedQuantity is the item's quantity when it was counted, or when
+	// the count started until then
+	ExpectedQuantity int `json:"expected_quantity"`
+	// CountedQuantity is nil until the item is counted
+	CountedQuantity *int       `json:"counted_quantity"`
+	Variance        int        `json:"variance"`
+	CountedBy       string     `json:"counted_by,omitempty"`
+	CountedAt       *time.Time `json:"counted_at,omitempty"`
+	// Approved lines' variances were applied, by AdjustmentID unless
+	// there was none
+	Approved     bool   `json:"approved"`
+	AdjustmentID string `json:"adjustment_id,omitempty"`
+}
+
+// Cycle count statuses
+const (
+	CycleCountCounting  = "counting"
+	CycleCountApplied   = "applied"
+	CycleCountCancelled = "cancelled"
+)
+
+// CycleCountReason is the reason of the adjustments applying cycle counts'
+// variances
+const CycleCountReason = "cycle_count"
+
+var ErrCycleCountNotFound = errors.New("cycle count not found")
+
+// Line returns the cycle count's line of a SKU, or nil
+func (c *CycleCount) Line(sku string) *CycleCountLine {
+	for i := range c.Lines {
+		if c.Lines[i].SKU == sku {
+			return &c.Lines[i]
+		}
+	}
+	return nil
+}
+
+// Counted records that counted units of the line's item were found when it
+// recorded expected
+func (l *CycleCountLine) Counted(expected, counted int, by string, at time.Time) {
+	l.ExpectedQuantity = expected
+	l.CountedQuantity = &counted
+	l.Variance = counted - expected
+	l.CountedBy = by
+	l.CountedAt = &at
+}
@@ -0,0 +1,89 @@
+package domain
+
+import "time"
+
+// HistoryEvent is a change to an inventory item, in the timeline its
+// history endpoint serves. It is recorded in the transaction of the change.
+type HistoryEvent struct {
+	ID        string `json:"id"`
+	ItemID    string `json:"item_id"`
+	ProductID string `json:"product_id"`
+	Type      string `json:"type"`
+	// QuantityChange and ReservedChange are how the change moved the
+	// item's quantity and reserved quantity
+	QuantityChange int `json:"quantity_change"`
+	ReservedChange int `json:"reserved_change"`
+	// Stock is the item's stock after the change; events recorded before
+	// history was kept don't have it
+	Stock *StockLevels `json:"stock,omitempty"`
+	// ReferenceType and ReferenceID name what made the change: an
+	// adjustment, reservation, transfer, import, purchase order or lot
+	ReferenceType string    `json:"reference_type,omitempty"`
+	ReferenceID   string    `json:"reference_id,omitempty"`
+	Actor         string    `json:"actor,omitempty"`
+	Detail        string    `json:"detail,omitempty"`
+	OccurredAt    time.Time `json:"occurred_at"`
+}
+
+// StockLevels are an item's quantities at a point in time
+type StockLevels struct {
+	Quantity          int `json:"quantity"`
+	ReservedQuantity  int `json:"reserved_quantity"`
+	AvailableQuantity int `json:"available_quantity"`
+}
+
+// History event types
+const (
+	HistoryCreated          = "created"
+	HistoryUpdated          = "updated"
+	HistoryAdjusted         = "adjusted"
+	HistoryReserved         = "reserved"
+	HistoryDeducted         = "deducted"
+	HistoryReleased         = "released"
+	HistoryExpired          = "expired"
+	HistoryTransferredOut   = "transferred_out"
+	HistoryTransferredIn    = "transferred_in"
+	HistoryTransferReturned = "transfer_returned"
+	HistoryReceived         = "received"
+	HistoryLotExpired       = "lot_expired"
+	HistoryReturned         = "returned"
+)
+
+// History reference types
+const (
+	ReferenceAdjustment    = "adjustment"
+	ReferenceReservation   = "reservation"
+	ReferenceTransfer      = "transfer"
+	ReferenceImport        = "import"
+	ReferencePurchaseOrder = "purchase_order"
+	ReferenceLot           = "lot"
+)
+
+// NewHistoryEvent records a change to item, which it has already been
+// through
+func NewHistoryEvent(item *InventoryItem, eventType string, quantityChange, reservedChange int) *HistoryEvent {
+	return &HistoryEvent{
+		ItemID:         item.ID,
+		ProductID:      item.ProductID,
+		Type:           eventType,
+		QuantityChange: quantityChange,
+		ReservedChange: reservedChange,
+		Stock: &StockLevels{
+			Quantity:          item.Quantity,
+			ReservedQuantity:  item.ReservedQuantity,
+			AvailableQuantity: item.AvailableQuantity,
+		},
+	}
+}
+
+// NewHistoryEventSince records the change to item since it was before
+func NewHistoryEventSince(before InventoryItem, item *InventoryItem, eventType string) *HistoryEvent {
+	return NewHistoryEvent(item, eventType, item.Quantity-before.Quantity, item.ReservedQuantity-before.ReservedQuantity)
+}
+
+// Referencing names what made the change
+func (e *HistoryEvent) Referencing(referenceType, referenceID string) *HistoryEvent {
+	e.ReferenceType = referenceType
+	e.ReferenceID = referenceID
+	return e
+}
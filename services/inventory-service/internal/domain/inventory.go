@@ -3,6 +3,8 @@ package domain
 import (
 	"errors"
 	"time"
+
+	"github.com/ecommerce-platform/shared/go/money"
 )
 
 // InventoryStatus represents the status of an inventory item
@@ -19,16 +21,27 @@ const (
 type InventoryItem struct {
 	ID                string          `json:"id"`
 	ProductID         string          `json:"product_id"`
-	SKU               string          `json:"sku"`
+	SKU               string          `json:"sku" binding:"omitempty,sku"`
 	Quantity          int             `json:"quantity"`
 	ReservedQuantity  int             `json:"reserved_quantity"`
+	// ExpiredQuantity is the stock in expired lots, which isn't available;
+	// it is kept by the lots, not set directly
+	ExpiredQuantity   int             `json:"expired_quantity"`
 	AvailableQuantity int             `json:"available_quantity"`
 	ReorderLevel      int             `json:"reorder_level"`
 	ReorderQuantity   int             `json:"reorder_quantity"`
 	Status            InventoryStatus `json:"status"`
 	Location          string          `json:"location"`
-	CreatedAt         time.Time       `json:"created_at"`
-	UpdatedAt         time.Time       `json:"updated_at"`
+	Supplier          string          `json:"supplier"`
+	// UnitCost is what a unit of the stock cost, nil until set or
+	// received at a cost; CostMethod is how receipts change it
+	UnitCost   *money.Money `json:"unit_cost,omitempty"`
+	CostMethod CostMethod   `json:"cost_method" binding:"omitempty,oneof=weighted_average standard"`
+	// Allocation is how stock leaving without lots given is taken from
+	// the item's lots
+	Allocation Allocation   `json:"allocation" binding:"omitempty,oneof=manual fefo"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
 }
 
 // Reservation represents a temporary hold on inventory
@@ -43,6 +56,14 @@ type Reservation struct {
 	CreatedAt     time.Time `json:"created_at"`
 }
 
+// Reservation statuses
+const (
+	ReservationPending   = "pending"
+	ReservationConfirmed = "confirmed"
+	ReservationCancelled = "cancelled"
+	ReservationExpired   = "expired"
+)
+
 // InventoryAdjustment represents a change in inventory
 type InventoryAdjustment struct {
 	ID           string    `json:"id"`
@@ -51,6 +72,15 @@ type InventoryAdjustment struct {
 	Reason       string    `json:"reason"`
 	AdjustedBy   string    `json:"adjusted_by"`
 	Notes        string    `json:"notes"`
+	// CycleCountID is the cycle count whose variance the adjustment applied
+	CycleCountID string    `json:"cycle_count_id,omitempty"`
+	// UnitCost is what a unit of stock added cost, or the item's unit cost
+	// when stock was removed
+	UnitCost     *money.Money `json:"unit_cost,omitempty"`
+	// OrderID and ReturnedQuantity are the order and units of a return
+	// the adjustment recorded; only sellable units change the quantity
+	OrderID          string `json:"order_id,omitempty"`
+	ReturnedQuantity int    `json:"returned_quantity,omitempty"`
 	CreatedAt    time.Time `json:"created_at"`
 }
 
@@ -61,11 +91,19 @@ var (
 	ErrNotFound          = errors.New("inventory item not found")
 	ErrReservationExpired = errors.New("reservation has expired")
 	ErrReservationNotFound = errors.New("reservation not found")
+	ErrSKUConflict = errors.New("SKU belongs to another product")
+	ErrProductConflict = errors.New("product has another SKU")
+	ErrBelowReserved = errors.New("quantity is below the reserved quantity")
+	ErrReturnRestocked = errors.New("return was already restocked")
 )
 
-// CalculateAvailableQuantity computes available quantity
+// CalculateAvailableQuantity computes available quantity: stock neither
+// reserved nor expired
 func (i *InventoryItem) CalculateAvailableQuantity() {
-	i.AvailableQuantity = i.Quantity - i.ReservedQuantity
+	i.AvailableQuantity = i.Quantity - i.ReservedQuantity - i.ExpiredQuantity
+	if i.AvailableQuantity < 0 {
+		i.AvailableQuantity = 0
+	}
 }
 
 // UpdateStatus updates the inventory status based on quantity
@@ -163,3 +201,18 @@ func (i *InventoryItem) Add(quantity int) error {
 func (i *InventoryItem) ShouldReorder() bool {
 	return i.AvailableQuantity <= i.ReorderLevel
 }
+
+// CrossedThreshold reports the stock threshold the item's available stock
+// fell past since before: StatusOutOfStock when it ran out,
+// StatusLowStock when it fell to its reorder level with some left, and ""
+// otherwise, including when it was already past it
+func (i *InventoryItem) CrossedThreshold(before InventoryItem) InventoryStatus {
+	switch {
+	case i.AvailableQuantity <= 0 && before.AvailableQuantity > 0:
+		return StatusOutOfStock
+	case i.AvailableQuantity > 0 && i.ShouldReorder() && !before.ShouldReorder():
+		return StatusLowStock
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,157 @@
+package domain
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// Allocation is how an item's stock is taken from its lots when it leaves
+// without lots given
+type Allocation string
+
+const (
+	// AllocationManual takes stock only from the lots given, leaving it
+	// untraced otherwise
+	AllocationManual Allocation = "manual"
+	// AllocationFEFO takes stock from the unexpired lots expiring first,
+	// first expired first out, e.g. for perishable goods
+	AllocationFEFO Allocation = "fefo"
+)
+
+// Lot is stock of a product received as one batch, traced by its lot
+// number, e.g. for recalls. Stock received without a lot isn't traced.
+type Lot struct {
+	ID        string `json:"id"`
+	ItemID    string `json:"item_id"`
+	ProductID string `json:"product_id"`
+	Number    string `json:"lot_number"`
+	// Quantity is the lot's stock on hand
+	Quantity int `json:"quantity"`
+	// ExpiresAt is when the lot's stock expires, for perishable goods
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Expired is set once the lot has expired and its stock counts in the
+	// item's expired quantity
+	Expired bool `json:"expired"`
+	// ExpiryNotified is set once the lot was announced as expiring soon
+	ExpiryNotified bool      `json:"-"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Serial is one serial-numbered unit of a product, in a lot
+type Serial struct {
+	Number    string    `json:"serial_number"`
+	ItemID    string    `json:"item_id"`
+	ProductID string    `json:"product_id"`
+	LotID     string    `json:"lot_id"`
+	LotNumber string    `json:"lot_number"`
+	Status    string    `json:"status"` // in_stock, removed
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Serial statuses
+const (
+	SerialInStock = "in_stock"
+	SerialRemoved = "removed"
+)
+
+// LotMovement is stock received into or removed from a lot: by an
+// adjustment, a purchase order's receipt or a reservation's confirmation
+type LotMovement struct {
+	ID        string `json:"id"`
+	LotID     string `json:"lot_id"`
+	LotNumber string `json:"lot_number"`
+	ItemID    string `json:"item_id"`
+	ProductID string `json:"product_id"`
+	// Quantity is positive for stock received, negative for stock removed
+	Quantity int      `json:"quantity"`
+	Serials  []string `json:"serial_numbers"`
+	// ReferenceType and ReferenceID name what moved the stock, as in the
+	// item's history
+	ReferenceType string    `json:"reference_type"`
+	ReferenceID   string    `json:"reference_id"`
+	Actor         string    `json:"actor,omitempty"`
+	OccurredAt    time.Time `json:"occurred_at"`
+}
+
+var (
+	ErrLotNotFound     = errors.New("lot not found")
+	ErrInsufficientLot = errors.New("insufficient stock in lot")
+	ErrSerialInStock   = errors.New("serial number is already in stock")
+	ErrSerialNotInLot  = errors.New("serial number is not in stock in the lot")
+	ErrLotExpiry       = errors.New("lot has another expiry date")
+	ErrLotExpired      = errors.New("lot has expired")
+)
+
+// Move receives quantity into the lot, or removes it if negative
+func (l *Lot) Move(quantity int) error {
+	if quantity == 0 {
+		return ErrInvalidQuantity
+	}
+	if l.Quantity+quantity < 0 {
+		return ErrInsufficientLot
+	}
+	l.Quantity += quantity
+	l.UpdatedAt = time.Now()
+	return nil
+}
+
+// ExpiredBy reports whether the lot has expired by t
+func (l *Lot) ExpiredBy(t time.Time) bool {
+	return l.Expired || l.ExpiresAt != nil && !l.ExpiresAt.After(t)
+}
+
+// LotAllocation is stock to take from a lot
+type LotAllocation struct {
+	Lot      *Lot
+	Quantity int
+}
+
+// AllocateFEFO takes quantity units from lots, first expired first out:
+// from the lots expiring soonest, then those without an expiry date,
+// oldest first. Lots expired by now aren't taken from. It returns how
+// many units to take from each lot, and how many the lots are short.
+func AllocateFEFO(lots []*Lot, quantity int, now time.Time) ([]LotAllocation, int) {
+	candidates := make([]*Lot, 0, len(lots))
+	for _, lot := range lots {
+		if lot.Quantity > 0 && !lot.ExpiredBy(now) {
+			candidates = append(candidates, lot)
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		x, y := candidates[a].ExpiresAt, candidates[b].ExpiresAt
+		switch {
+		case x == nil || y == nil:
+			return x != nil && y == nil
+		case !x.Equal(*y):
+			return x.Before(*y)
+		default:
+			return candidates[a].CreatedAt.Before(candidates[b].CreatedAt)
+		}
+	})
+
+	var allocations []LotAllocation
+	for _, lot := range candidates {
+		if quantity == 0 {
+			break
+		}
+		take := lot.Quantity
+		if take > quantity {
+			take = quantity
+		}
+		allocations = append(allocations, LotAllocation{Lot: lot, Quantity: take})
+		quantity -= take
+	}
+	return allocations, quantity
+}
+
+// ExpireStock moves quantity units of the item's stock into its expired
+// quantity, as a lot expires or stock is received into an expired lot, or
+// out of it if negative, as an expired lot's stock is removed
+func (i *InventoryItem) ExpireStock(quantity int) {
+	i.ExpiredQuantity += quantity
+	i.UpdateStatus()
+	i.UpdatedAt = time.Now()
+}
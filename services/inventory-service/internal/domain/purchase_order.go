@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/money"
+)
+
+// PurchaseOrder is stock ordered from a supplier. The reorder job drafts
+// one per supplier for the items at their reorder level; purchasing orders
+// it from the supplier, and receiving it adds its lines' stock to their
+// items.
+type PurchaseOrder struct {
+	ID       string              `json:"id"`
+	Supplier string              `json:"supplier"`
+	Status   string              `json:"status"` // draft, ordered, received, cancelled
+	Lines    []PurchaseOrderLine `json:"lines"`
+	// CreatedBy is the user who created the purchase order, or
+	// ReorderJob for the reorder job's drafts
+	CreatedBy   string     `json:"created_by"`
+	Notes       string     `json:"notes"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	OrderedAt   *time.Time `json:"ordered_at,omitempty"`
+	ReceivedAt  *time.Time `json:"received_at,omitempty"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+}
+
+// PurchaseOrderLine is the stock of one product on a purchase order
+type PurchaseOrderLine struct {
+	ProductID string `json:"product_id"`
+	SKU       string `json:"sku"`
+	Quantity  int    `json:"quantity"`
+	// UnitCost is what the supplier charges a unit, received into the
+	// item's unit cost; nil if not known
+	UnitCost *money.Money `json:"unit_cost,omitempty"`
+}
+
+// Purchase order statuses
+const (
+	PurchaseOrderDraft     = "draft"
+	PurchaseOrderOrdered   = "ordered"
+	PurchaseOrderReceived  = "received"
+	PurchaseOrderCancelled = "cancelled"
+)
+
+// ReorderJob is the CreatedBy of the purchase orders the reorder job drafts
+const ReorderJob = "reorder"
+
+var ErrPurchaseOrderNotFound = errors.New("purchase order not found")
+
+// Open reports whether the purchase order's stock is still to come
+func (p *PurchaseOrder) Open() bool {
+	return p.Status == PurchaseOrderDraft || p.Status == PurchaseOrderOrdered
+}
+
+// SuggestReorders drafts a purchase order per supplier for the items at
+// their reorder level, each line its item's reorder quantity. Items without
+// a supplier or reorder quantity, and products already on an open purchase
+// order, are left out.
+func SuggestReorders(items []*InventoryItem, onOrder map[string]bool) []*PurchaseOrder {
+	var orders []*PurchaseOrder
+	bySupplier := make(map[string]*PurchaseOrder)
+	for _, item := range items {
+		if !item.ShouldReorder() || item.Supplier == "" || item.ReorderQuantity <= 0 || onOrder[item.ProductID] {
+			continue
+		}
+		order, ok := bySupplier[item.Supplier]
+		if !ok {
+			order = &PurchaseOrder{
+				Supplier:  item.Supplier,
+				Status:    PurchaseOrderDraft,
+				CreatedBy: ReorderJob,
+			}
+			bySupplier[item.Supplier] = order
+			orders = append(orders, order)
+		}
+		order.Lines = append(order.Lines, PurchaseOrderLine{
+			ProductID: item.ProductID,
+			SKU:       item.SKU,
+			Quantity:  item.ReorderQuantity,
+		})
+	}
+	return orders
+}
@@ -0,0 +1,26 @@
+package domain
+
+// ReturnCondition is the state returned units come back in
+type ReturnCondition string
+
+const (
+	// ReturnSellable units are put back into stock
+	ReturnSellable ReturnCondition = "sellable"
+	// ReturnDamaged units are recorded but not put back into stock
+	ReturnDamaged ReturnCondition = "damaged"
+)
+
+// Reasons of the adjustments recording returns, by condition
+const (
+	ReasonReturnSellable = "return_sellable"
+	ReasonReturnDamaged  = "return_damaged"
+)
+
+// ReturnReason is the reason a return of units in condition is recorded
+// with
+func ReturnReason(condition ReturnCondition) string {
+	if condition == ReturnSellable {
+		return ReasonReturnSellable
+	}
+	return ReasonReturnDamaged
+}
@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// Transfer moves stock of a product from one location to another. Its
+// stock leaves the source when it is created, and arrives at the
+// destination when it is completed; cancelling it returns the stock to the
+// source.
+type Transfer struct {
+	ID           string     `json:"id"`
+	ProductID    string     `json:"product_id"`
+	FromLocation string     `json:"from_location"`
+	ToLocation   string     `json:"to_location"`
+	Quantity     int        `json:"quantity"`
+	Status       string     `json:"status"` // in_transit, completed, cancelled
+	RequestedBy  string     `json:"requested_by"`
+	Notes        string     `json:"notes"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	CancelledAt  *time.Time `json:"cancelled_at,omitempty"`
+}
+
+// Transfer statuses
+const (
+	TransferInTransit = "in_transit"
+	TransferCompleted = "completed"
+	TransferCancelled = "cancelled"
+)
+
+var (
+	ErrTransferNotFound = errors.New("transfer not found")
+	ErrNoHomeLocation   = errors.New("inventory item has no location")
+)
+
+// LocationStock is the stock of a product at one location
+type LocationStock struct {
+	Location string `json:"location"`
+	Quantity int    `json:"quantity"`
+	// Home is the item's own location, which holds the stock that isn't
+	// elsewhere, so adjustments and sales count against it
+	Home bool `json:"home"`
+}
+
+// Locations is an item's stock by location. Away holds the stock at
+// locations other than the item's home location; the rest of the item's
+// quantity is at home, including any Away once held at a location that has
+// since become its home.
+type Locations struct {
+	Item *InventoryItem
+	Away map[string]int
+}
+
+// At is the stock at location
+func (l Locations) At(location string) int {
+	if location == l.Item.Location {
+		away := 0
+		for at, quantity := range l.Away {
+			if at != l.Item.Location {
+				away += quantity
+			}
+		}
+		return l.Item.Quantity - away
+	}
+	return l.Away[location]
+}
+
+// Remove takes stock out of location, e.g. for a transfer in transit. Only
+// unreserved stock can leave.
+func (l Locations) Remove(location string, quantity int) error {
+	if quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+	if l.Item.Location == "" {
+		return ErrNoHomeLocation
+	}
+	if quantity > l.At(location) || quantity > l.Item.AvailableQuantity {
+		return ErrInsufficientStock
+	}
+
+	l.Item.Quantity -= quantity
+	if location != l.Item.Location {
+		l.Away[location] -= quantity
+	}
+	l.Item.UpdateStatus()
+	l.Item.UpdatedAt = time.Now()
+	return nil
+}
+
+// Put adds stock at location, e.g. a transfer arriving
+func (l Locations) Put(location string, quantity int) error {
+	if quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+	if l.Item.Location == "" {
+		return ErrNoHomeLocation
+	}
+
+	l.Item.Quantity += quantity
+	if location != l.Item.Location {
+		l.Away[location] += quantity
+	}
+	l.Item.UpdateStatus()
+	l.Item.UpdatedAt = time.Now()
+	return nil
+}
+
+// Stock lists the stock at each location, home first
+func (l Locations) Stock() []LocationStock {
+	stock := []LocationStock{{Location: l.Item.Location, Quantity: l.At(l.Item.Location), Home: true}}
+	for location, quantity := range l.Away {
+		if location != l.Item.Location && quantity > 0 {
+			stock = append(stock, LocationStock{Location: location, Quantity: quantity})
+		}
+	}
+	away := stock[1:]
+	sort.Slice(away, func(i, j int) bool { return away[i].Location < away[j].Location })
+	return stock
+}
@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Webhook is a partner's subscription to inventory events, pushed to its
+// URL and signed with its secret
+type Webhook struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// Secret signs deliveries; it is only shown when the webhook is created
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is an event to push to a webhook, tried until its URL
+// accepts it or it runs out of attempts
+type WebhookDelivery struct {
+	ID            string     `json:"id"`
+	WebhookID     string     `json:"webhook_id"`
+	EventType     string     `json:"event_type"`
+	Payload       []byte     `json:"-"`
+	Status        string     `json:"status"` // pending, delivered, failed
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	// History is the delivery's attempts, oldest first
+	History []WebhookAttempt `json:"history"`
+}
+
+// WebhookAttempt is one try at pushing a delivery
+type WebhookAttempt struct {
+	Attempt     int       `json:"attempt"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DurationMS  int64     `json:"duration_ms"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookEventTypes are the events webhooks can subscribe to
+var WebhookEventTypes = []string{
+	"inventory.created",
+	"inventory.updated",
+	"inventory.reserved",
+	"inventory.reservation_released",
+	"inventory.reservation_expired",
+	"inventory.adjusted",
+	"inventory.low_stock",
+	"inventory.out_of_stock",
+	"inventory.expiring_soon",
+	"inventory.return_restocked",
+}
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// IsWebhookEventType reports whether webhooks can subscribe to eventType
+func IsWebhookEventType(eventType string) bool {
+	for _, known := range WebhookEventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Attempted records an attempt at the delivery: it is delivered when the
+// attempt succeeded, else tried again after backoff, doubling from base up
+// to maxBackoff, until maxAttempts have failed
+func (d *WebhookDelivery) Attempted(attempt WebhookAttempt, succeeded bool, maxAttempts int, base, maxBackoff time.Duration) {
+	d.Attempts = attempt.Attempt
+	d.History = append(d.History, attempt)
+	d.NextAttemptAt = nil
+	if succeeded {
+		d.Status = DeliveryDelivered
+		d.LastError = ""
+		d.DeliveredAt = &attempt.AttemptedAt
+		return
+	}
+
+	d.LastError = attempt.Error
+	if d.Attempts >= maxAttempts {
+		d.Status = DeliveryFailed
+		return
+	}
+	backoff := base
+	for i := 1; i < d.Attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	next := attempt.AttemptedAt.Add(backoff)
+	d.NextAttemptAt = &next
+}
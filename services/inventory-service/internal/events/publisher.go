@@ -2,11 +2,11 @@ package events
 
 import (
 	"context"
-	"encoding/json"
-	"time"
 
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	"github.com/ecommerce-platform/shared/go/broker"
+	sharedevents "github.com/ecommerce-platform/shared/go/events"
 	"github.com/ecommerce/inventory-service/internal/domain"
-	"github.com/segmentio/kafka-go"
 	"go.uber.org/zap"
 )
 
@@ -15,151 +15,209 @@ type Publisher interface {
 	PublishInventoryUpdated(ctx context.Context, item *domain.InventoryItem) error
 	PublishInventoryReserved(ctx context.Context, item *domain.InventoryItem, reservation *domain.Reservation) error
 	PublishReservationReleased(ctx context.Context, item *domain.InventoryItem, reservation *domain.Reservation) error
+	PublishReservationExpired(ctx context.Context, item *domain.InventoryItem, reservation *domain.Reservation) error
 	PublishInventoryAdjusted(ctx context.Context, item *domain.InventoryItem, adjustment *domain.InventoryAdjustment) error
+	PublishLowStock(ctx context.Context, item *domain.InventoryItem) error
+	PublishOutOfStock(ctx context.Context, item *domain.InventoryItem) error
+	PublishExpiringSoon(ctx context.Context, item *domain.InventoryItem, lot *domain.Lot) error
+	PublishReturnRestocked(ctx context.Context, item *domain.InventoryItem, adjustment *domain.InventoryAdjustment, condition domain.ReturnCondition) error
 	Close() error
 }
 
-type kafkaPublisher struct {
-	writer *kafka.Writer
-	logger *zap.Logger
+// Forwarder passes published events on beyond the message broker, e.g. to
+// webhooks. data is the event as published, in its envelope.
+type Forwarder interface {
+	Forward(ctx context.Context, eventType string, data []byte) error
 }
 
-func NewKafkaPublisher(brokers []string, topic string, logger *zap.Logger) Publisher {
-	writer := &kafka.Writer{
-		Addr:         kafka.TCP(brokers...),
-		Topic:        topic,
-		Balancer:     &kafka.LeastBytes{},
-		RequiredAcks: kafka.RequireOne,
-		Async:        false,
-	}
+// Encoding is how events are encoded in messages
+type Encoding struct {
+	Marshal func(env sharedevents.Envelope, payload sharedevents.Payload) ([]byte, error)
+	// ContentType, if set, is sent as the messages' content-type header
+	ContentType string
+}
+
+// LegacyEncoding encodes events in the shared envelope, which every
+// consumer reads
+var LegacyEncoding = Encoding{Marshal: sharedevents.Marshal}
 
-	return &kafkaPublisher{
-		writer: writer,
-		logger: logger,
+// CloudEventsEncoding encodes events as structured-mode CloudEvents
+func CloudEventsEncoding(cloudEvents sharedevents.CloudEvents) Encoding {
+	return Encoding{
+		Marshal:     cloudEvents.Marshal,
+		ContentType: sharedevents.CloudEventsContentType,
 	}
 }
 
-type InventoryEvent struct {
-	EventType string                 `json:"event_type"`
-	ProductID string                 `json:"product_id"`
-	Timestamp time.Time              `json:"timestamp"`
-	Data      map[string]interface{} `json:"data"`
+type brokerPublisher struct {
+	publisher broker.Publisher
+	encoding  Encoding
+	forwarder Forwarder
+	logger    *zap.Logger
+}
+
+// NewPublisher creates a publisher writing to the inventory events topic
+// on whichever message broker publisher belongs to, in encoding. Events
+// are keyed by product, so a product's events stay in order. Each
+// published event is also given to forwarder, if not nil; failing to
+// forward it is only logged.
+func NewPublisher(publisher broker.Publisher, encoding Encoding, forwarder Forwarder, logger *zap.Logger) Publisher {
+	return &brokerPublisher{
+		publisher: publisher,
+		encoding:  encoding,
+		forwarder: forwarder,
+		logger:    logger,
+	}
 }
 
-func (p *kafkaPublisher) publishEvent(ctx context.Context, event *InventoryEvent) error {
-	data, err := json.Marshal(event)
+func (p *brokerPublisher) publishEvent(ctx context.Context, item *domain.InventoryItem, payload sharedevents.Payload) error {
+	data, err := p.encoding.Marshal(sharedevents.Envelope{
+		TenantID:  sharedauth.TenantFromContext(ctx),
+		ProductID: item.ProductID,
+	}, payload)
 	if err != nil {
 		p.logger.Error("Failed to marshal event", zap.Error(err))
 		return err
 	}
 
-	message := kafka.Message{
-		Key:   []byte(event.ProductID),
+	message := broker.Message{
+		Key:   []byte(item.ProductID),
 		Value: data,
-		Time:  event.Timestamp,
+	}
+	if p.encoding.ContentType != "" {
+		message.Headers = []broker.Header{{Key: "content-type", Value: []byte(p.encoding.ContentType)}}
 	}
 
-	if err := p.writer.WriteMessages(ctx, message); err != nil {
-		p.logger.Error("Failed to publish event", zap.Error(err), zap.String("event_type", event.EventType))
+	if err := p.publisher.Publish(ctx, message); err != nil {
+		p.logger.Error("Failed to publish event", zap.Error(err), zap.String("event_type", payload.EventType()))
 		return err
 	}
 
-	p.logger.Debug("Event published", zap.String("event_type", event.EventType), zap.String("product_id", event.ProductID))
+	p.logger.Debug("Event published", zap.String("event_type", payload.EventType()), zap.String("product_id", item.ProductID))
+
+	if p.forwarder != nil {
+		if err := p.forwarder.Forward(ctx, payload.EventType(), data); err != nil {
+			p.logger.Error("Failed to forward event", zap.Error(err), zap.String("event_type", payload.EventType()))
+		}
+	}
 	return nil
 }
 
-func (p *kafkaPublisher) PublishInventoryCreated(ctx context.Context, item *domain.InventoryItem) error {
-	event := &InventoryEvent{
-		EventType: "inventory.created",
-		ProductID: item.ProductID,
-		Timestamp: time.Now(),
-		Data: map[string]interface{}{
-			"id":                 item.ID,
-			"product_id":         item.ProductID,
-			"sku":                item.SKU,
-			"quantity":           item.Quantity,
-			"available_quantity": item.AvailableQuantity,
-			"status":             item.Status,
-		},
-	}
+func (p *brokerPublisher) PublishInventoryCreated(ctx context.Context, item *domain.InventoryItem) error {
+	return p.publishEvent(ctx, item, &sharedevents.InventoryCreated{
+		ID:                item.ID,
+		ProductID:         item.ProductID,
+		SKU:               item.SKU,
+		Quantity:          item.Quantity,
+		AvailableQuantity: item.AvailableQuantity,
+		Status:            string(item.Status),
+	})
+}
 
-	return p.publishEvent(ctx, event)
+func (p *brokerPublisher) PublishInventoryUpdated(ctx context.Context, item *domain.InventoryItem) error {
+	return p.publishEvent(ctx, item, &sharedevents.InventoryUpdated{
+		ID:                item.ID,
+		ProductID:         item.ProductID,
+		Quantity:          item.Quantity,
+		ReservedQuantity:  item.ReservedQuantity,
+		AvailableQuantity: item.AvailableQuantity,
+		Status:            string(item.Status),
+	})
 }
 
-func (p *kafkaPublisher) PublishInventoryUpdated(ctx context.Context, item *domain.InventoryItem) error {
-	event := &InventoryEvent{
-		EventType: "inventory.updated",
-		ProductID: item.ProductID,
-		Timestamp: time.Now(),
-		Data: map[string]interface{}{
-			"id":                 item.ID,
-			"product_id":         item.ProductID,
-			"quantity":           item.Quantity,
-			"reserved_quantity":  item.ReservedQuantity,
-			"available_quantity": item.AvailableQuantity,
-			"status":             item.Status,
-		},
-	}
+func (p *brokerPublisher) PublishInventoryReserved(ctx context.Context, item *domain.InventoryItem, reservation *domain.Reservation) error {
+	return p.publishEvent(ctx, item, &sharedevents.InventoryReserved{
+		ProductID:         item.ProductID,
+		ReservationID:     reservation.ID,
+		OrderID:           reservation.OrderID,
+		Quantity:          reservation.Quantity,
+		ReservedQuantity:  item.ReservedQuantity,
+		AvailableQuantity: item.AvailableQuantity,
+		ExpiresAt:         reservation.ExpiresAt,
+	})
+}
 
-	return p.publishEvent(ctx, event)
+func (p *brokerPublisher) PublishReservationReleased(ctx context.Context, item *domain.InventoryItem, reservation *domain.Reservation) error {
+	return p.publishEvent(ctx, item, &sharedevents.ReservationReleased{
+		ProductID:         item.ProductID,
+		ReservationID:     reservation.ID,
+		OrderID:           reservation.OrderID,
+		Quantity:          reservation.Quantity,
+		ReservedQuantity:  item.ReservedQuantity,
+		AvailableQuantity: item.AvailableQuantity,
+	})
 }
 
-func (p *kafkaPublisher) PublishInventoryReserved(ctx context.Context, item *domain.InventoryItem, reservation *domain.Reservation) error {
-	event := &InventoryEvent{
-		EventType: "inventory.reserved",
-		ProductID: item.ProductID,
-		Timestamp: time.Now(),
-		Data: map[string]interface{}{
-			"product_id":         item.ProductID,
-			"reservation_id":     reservation.ID,
-			"order_id":           reservation.OrderID,
-			"quantity":           reservation.Quantity,
-			"reserved_quantity":  item.ReservedQuantity,
-			"available_quantity": item.AvailableQuantity,
-			"expires_at":         reservation.ExpiresAt,
-		},
-	}
+func (p *brokerPublisher) PublishReservationExpired(ctx context.Context, item *domain.InventoryItem, reservation *domain.Reservation) error {
+	return p.publishEvent(ctx, item, &sharedevents.ReservationExpired{
+		ProductID:         item.ProductID,
+		ReservationID:     reservation.ID,
+		OrderID:           reservation.OrderID,
+		Quantity:          reservation.Quantity,
+		ReservedQuantity:  item.ReservedQuantity,
+		AvailableQuantity: item.AvailableQuantity,
+		ExpiresAt:         reservation.ExpiresAt,
+	})
+}
 
-	return p.publishEvent(ctx, event)
+func (p *brokerPublisher) PublishInventoryAdjusted(ctx context.Context, item *domain.InventoryItem, adjustment *domain.InventoryAdjustment) error {
+	return p.publishEvent(ctx, item, &sharedevents.InventoryAdjusted{
+		ProductID:         item.ProductID,
+		AdjustmentID:      adjustment.ID,
+		QuantityChange:    adjustment.Quantity,
+		NewQuantity:       item.Quantity,
+		AvailableQuantity: item.AvailableQuantity,
+		Reason:            adjustment.Reason,
+		AdjustedBy:        adjustment.AdjustedBy,
+	})
 }
 
-func (p *kafkaPublisher) PublishReservationReleased(ctx context.Context, item *domain.InventoryItem, reservation *domain.Reservation) error {
-	event := &InventoryEvent{
-		EventType: "inventory.reservation_released",
-		ProductID: item.ProductID,
-		Timestamp: time.Now(),
-		Data: map[string]interface{}{
-			"product_id":         item.ProductID,
-			"reservation_id":     reservation.ID,
-			"order_id":           reservation.OrderID,
-			"quantity":           reservation.Quantity,
-			"reserved_quantity":  item.ReservedQuantity,
-			"available_quantity": item.AvailableQuantity,
-		},
-	}
+func (p *brokerPublisher) PublishLowStock(ctx context.Context, item *domain.InventoryItem) error {
+	return p.publishEvent(ctx, item, &sharedevents.InventoryLowStock{
+		ProductID:         item.ProductID,
+		SKU:               item.SKU,
+		AvailableQuantity: item.AvailableQuantity,
+		ReorderLevel:      item.ReorderLevel,
+		ReorderQuantity:   item.ReorderQuantity,
+		Warehouse:         item.Location,
+	})
+}
 
-	return p.publishEvent(ctx, event)
+func (p *brokerPublisher) PublishOutOfStock(ctx context.Context, item *domain.InventoryItem) error {
+	return p.publishEvent(ctx, item, &sharedevents.InventoryOutOfStock{
+		ProductID:         item.ProductID,
+		SKU:               item.SKU,
+		AvailableQuantity: item.AvailableQuantity,
+		ReorderLevel:      item.ReorderLevel,
+		ReorderQuantity:   item.ReorderQuantity,
+		Warehouse:         item.Location,
+	})
 }
 
-func (p *kafkaPublisher) PublishInventoryAdjusted(ctx context.Context, item *domain.InventoryItem, adjustment *domain.InventoryAdjustment) error {
-	event := &InventoryEvent{
-		EventType: "inventory.adjusted",
+func (p *brokerPublisher) PublishExpiringSoon(ctx context.Context, item *domain.InventoryItem, lot *domain.Lot) error {
+	return p.publishEvent(ctx, item, &sharedevents.InventoryExpiringSoon{
 		ProductID: item.ProductID,
-		Timestamp: time.Now(),
-		Data: map[string]interface{}{
-			"product_id":         item.ProductID,
-			"adjustment_id":      adjustment.ID,
-			"quantity_change":    adjustment.Quantity,
-			"new_quantity":       item.Quantity,
-			"available_quantity": item.AvailableQuantity,
-			"reason":             adjustment.Reason,
-			"adjusted_by":        adjustment.AdjustedBy,
-		},
-	}
+		SKU:       item.SKU,
+		LotNumber: lot.Number,
+		Quantity:  lot.Quantity,
+		ExpiresAt: *lot.ExpiresAt,
+		Warehouse: item.Location,
+	})
+}
 
-	return p.publishEvent(ctx, event)
+func (p *brokerPublisher) PublishReturnRestocked(ctx context.Context, item *domain.InventoryItem, adjustment *domain.InventoryAdjustment, condition domain.ReturnCondition) error {
+	return p.publishEvent(ctx, item, &sharedevents.ReturnRestocked{
+		ProductID:         item.ProductID,
+		AdjustmentID:      adjustment.ID,
+		OrderID:           adjustment.OrderID,
+		Quantity:          adjustment.ReturnedQuantity,
+		Condition:         string(condition),
+		RestockedQuantity: adjustment.Quantity,
+		NewQuantity:       item.Quantity,
+		AvailableQuantity: item.AvailableQuantity,
+	})
 }
 
-func (p *kafkaPublisher) Close() error {
-	return p.writer.Close()
+func (p *brokerPublisher) Close() error {
+	return p.publisher.Close()
 }
@@ -0,0 +1,124 @@
+// Package expiry returns the stock of reservations that were neither
+// confirmed nor released before they expired, and takes the stock of
+// expired lots out of the available stock, run as scheduled jobs
+package expiry
+
+import (
+	"context"
+	"errors"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	"github.com/ecommerce/inventory-service/internal/domain"
+	"github.com/ecommerce/inventory-service/internal/events"
+	"github.com/ecommerce/inventory-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Expirer marks expired pending reservations expired and releases their
+// stock, publishing inventory.reservation_expired for each
+type Expirer struct {
+	repo      repository.InventoryRepository
+	cache     repository.CacheRepository
+	publisher events.Publisher
+	logger    *zap.Logger
+}
+
+// New creates an expirer
+func New(repo repository.InventoryRepository, cache repository.CacheRepository, publisher events.Publisher, logger *zap.Logger) *Expirer {
+	return &Expirer{
+		repo:      repo,
+		cache:     cache,
+		publisher: publisher,
+		logger:    logger,
+	}
+}
+
+// Run expires every tenant's reservations that are due. Reservations that
+// fail are left pending for the next run, and reported in the error.
+func (e *Expirer) Run(ctx context.Context) error {
+	tenants, err := e.repo.ExpiredReservationTenants(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, tenant := range tenants {
+		tenantCtx := sharedauth.WithTenant(ctx, tenant)
+		reservations, err := e.repo.GetExpiredReservations(tenantCtx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		expired := 0
+		for _, reservation := range reservations {
+			if err := e.expire(tenantCtx, reservation.ID); err != nil {
+				e.logger.Error("Failed to expire reservation",
+					zap.String("tenant_id", tenant),
+					zap.String("reservation_id", reservation.ID),
+					zap.Error(err),
+				)
+				errs = append(errs, err)
+				continue
+			}
+			expired++
+		}
+		if expired > 0 {
+			e.logger.Info("Reservations expired", zap.String("tenant_id", tenant), zap.Int("count", expired))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// expire releases a reservation's stock and marks it expired, unless it
+// was confirmed or released since it was listed
+func (e *Expirer) expire(ctx context.Context, reservationID string) error {
+	var item *domain.InventoryItem
+	var reservation *domain.Reservation
+	expired := false
+	err := e.repo.InTx(ctx, func(repo repository.InventoryRepository) error {
+		var err error
+		reservation, err = repo.GetReservation(ctx, reservationID)
+		if err != nil {
+			return err
+		}
+		if reservation.Status != domain.ReservationPending {
+			return nil
+		}
+
+		item, err = repo.GetByProductID(ctx, reservation.ProductID)
+		if err != nil {
+			return err
+		}
+		before := *item
+		if err := item.ReleaseReservation(reservation.Quantity); err != nil {
+			return err
+		}
+		if err := repo.Update(ctx, item); err != nil {
+			return err
+		}
+
+		event := domain.NewHistoryEventSince(before, item, domain.HistoryExpired).
+			Referencing(domain.ReferenceReservation, reservation.ID)
+		event.Detail = reservation.OrderID
+		if err := repo.RecordHistory(ctx, event); err != nil {
+			return err
+		}
+
+		reservation.Status = domain.ReservationExpired
+		if err := repo.UpdateReservation(ctx, reservation); err != nil {
+			return err
+		}
+		expired = true
+		return nil
+	})
+	if err != nil || !expired {
+		return err
+	}
+
+	_ = e.cache.Delete(ctx, item.ProductID)
+	if err := e.publisher.PublishReservationExpired(ctx, item, reservation); err != nil {
+		e.logger.Error("Failed to publish expiry event", zap.Error(err))
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package expiry
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	"github.com/ecommerce/inventory-service/internal/domain"
+	"github.com/ecommerce/inventory-service/internal/events"
+	"github.com/ecommerce/inventory-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// LotExpirer moves the stock of lots past their expiry into their items'
+// expired quantity, so it is no longer available, publishing
+// inventory.updated for each, and announces the lots expiring within the
+// warning period with inventory.expiring_soon, once per lot
+type LotExpirer struct {
+	repo      repository.InventoryRepository
+	cache     repository.CacheRepository
+	publisher events.Publisher
+	warning   time.Duration
+	logger    *zap.Logger
+}
+
+// NewLotExpirer creates a lot expirer announcing lots warning ahead of
+// their expiry
+func NewLotExpirer(repo repository.InventoryRepository, cache repository.CacheRepository, publisher events.Publisher, warning time.Duration, logger *zap.Logger) *LotExpirer {
+	return &LotExpirer{
+		repo:      repo,
+		cache:     cache,
+		publisher: publisher,
+		warning:   warning,
+		logger:    logger,
+	}
+}
+
+// Run expires and announces every tenant's lots that are due. Lots that
+// fail are left for the next run, and reported in the error.
+func (e *LotExpirer) Run(ctx context.Context) error {
+	before := time.Now().Add(e.warning)
+	tenants, err := e.repo.ExpiringLotTenants(ctx, before)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, tenant := range tenants {
+		tenantCtx := sharedauth.WithTenant(ctx, tenant)
+		lots, err := e.repo.GetExpiringLots(tenantCtx, before)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		expired, announced := 0, 0
+		for _, lot := range lots {
+			if lot.ExpiredBy(time.Now()) {
+				err = e.expire(tenantCtx, lot)
+				if err == nil {
+					expired++
+				}
+			} else {
+				err = e.announce(tenantCtx, lot)
+				if err == nil {
+					announced++
+				}
+			}
+			if err != nil {
+				e.logger.Error("Failed to expire lot",
+					zap.String("tenant_id", tenant),
+					zap.String("product_id", lot.ProductID),
+					zap.String("lot_number", lot.Number),
+					zap.Error(err),
+				)
+				errs = append(errs, err)
+			}
+		}
+		if expired > 0 || announced > 0 {
+			e.logger.Info("Lots expired", zap.String("tenant_id", tenant), zap.Int("expired", expired), zap.Int("expiring_soon", announced))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// expire moves the lot's stock into its item's expired quantity and marks
+// it expired, unless it was emptied or expired since it was listed
+func (e *LotExpirer) expire(ctx context.Context, listed *domain.Lot) error {
+	var item *domain.InventoryItem
+	var before domain.InventoryItem
+	expired := false
+	err := e.repo.InTx(ctx, func(repo repository.InventoryRepository) error {
+		lot, err := repo.LockLot(ctx, listed.ProductID, listed.Number)
+		if err != nil {
+			return err
+		}
+		if lot.Expired || lot.Quantity == 0 {
+			return nil
+		}
+
+		item, err = repo.GetByID(ctx, lot.ItemID)
+		if err != nil {
+			return err
+		}
+		before = *item
+		item.ExpireStock(lot.Quantity)
+		if err := repo.Update(ctx, item); err != nil {
+			return err
+		}
+
+		lot.Expired = true
+		if err := repo.UpdateLot(ctx, lot); err != nil {
+			return err
+		}
+
+		event := domain.NewHistoryEventSince(before, item, domain.HistoryLotExpired).
+			Referencing(domain.ReferenceLot, lot.ID)
+		event.Detail = lot.Number
+		if err := repo.RecordHistory(ctx, event); err != nil {
+			return err
+		}
+		expired = true
+		return nil
+	})
+	if err != nil || !expired {
+		return err
+	}
+
+	_ = e.cache.Delete(ctx, item.ProductID)
+	if err := e.publisher.PublishInventoryUpdated(ctx, item); err != nil {
+		e.logger.Error("Failed to publish inventory updated event", zap.Error(err))
+	}
+	switch item.CrossedThreshold(before) {
+	case domain.StatusLowStock:
+		err = e.publisher.PublishLowStock(ctx, item)
+	case domain.StatusOutOfStock:
+		err = e.publisher.PublishOutOfStock(ctx, item)
+	}
+	if err != nil {
+		e.logger.Error("Failed to publish stock threshold event", zap.String("product_id", item.ProductID), zap.Error(err))
+	}
+	return nil
+}
+
+// announce publishes inventory.expiring_soon for the lot, then marks it
+// announced; if publishing fails it is tried again on the next run
+func (e *LotExpirer) announce(ctx context.Context, lot *domain.Lot) error {
+	item, err := e.repo.GetByID(ctx, lot.ItemID)
+	if err != nil {
+		return err
+	}
+	if err := e.publisher.PublishExpiringSoon(ctx, item, lot); err != nil {
+		return err
+	}
+
+	return e.repo.InTx(ctx, func(repo repository.InventoryRepository) error {
+		lot, err := repo.LockLot(ctx, lot.ProductID, lot.Number)
+		if err != nil {
+			return err
+		}
+		lot.ExpiryNotified = true
+		return repo.UpdateLot(ctx, lot)
+	})
+}
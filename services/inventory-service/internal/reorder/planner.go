@@ -0,0 +1,85 @@
+// Package reorder drafts purchase orders for the items that fell to their
+// reorder level, run as a scheduled job
+package reorder
+
+import (
+	"context"
+	"errors"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	"github.com/ecommerce/inventory-service/internal/domain"
+	"github.com/ecommerce/inventory-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Planner drafts a purchase order per supplier for each tenant's
+// low-stock items that aren't on order yet
+type Planner struct {
+	repo   repository.InventoryRepository
+	logger *zap.Logger
+}
+
+// New creates a planner
+func New(repo repository.InventoryRepository, logger *zap.Logger) *Planner {
+	return &Planner{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Run drafts every tenant's purchase orders. Tenants that fail are tried
+// again on the next run, and reported in the error.
+func (p *Planner) Run(ctx context.Context) error {
+	tenants, err := p.repo.LowStockTenants(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, tenant := range tenants {
+		tenantCtx := sharedauth.WithTenant(ctx, tenant)
+		orders, err := p.draft(tenantCtx)
+		if err != nil {
+			p.logger.Error("Failed to draft purchase orders", zap.String("tenant_id", tenant), zap.Error(err))
+			errs = append(errs, err)
+			continue
+		}
+		for _, order := range orders {
+			p.logger.Info("Purchase order drafted",
+				zap.String("tenant_id", tenant),
+				zap.String("purchase_order_id", order.ID),
+				zap.String("supplier", order.Supplier),
+				zap.Int("lines", len(order.Lines)),
+			)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// draft creates the purchase orders of the tenant of ctx, in one
+// transaction so products aren't put on order twice
+func (p *Planner) draft(ctx context.Context) ([]*domain.PurchaseOrder, error) {
+	var orders []*domain.PurchaseOrder
+	err := p.repo.InTx(ctx, func(repo repository.InventoryRepository) error {
+		items, err := repo.GetLowStockItems(ctx)
+		if err != nil {
+			return err
+		}
+		onOrder, err := repo.ProductsOnOrder(ctx)
+		if err != nil {
+			return err
+		}
+
+		orders = domain.SuggestReorders(items, onOrder)
+		for _, order := range orders {
+			if err := repo.CreatePurchaseOrder(ctx, order); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
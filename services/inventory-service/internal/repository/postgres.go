@@ -3,21 +3,53 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	shareddb "github.com/ecommerce-platform/shared/go/db"
+	"github.com/ecommerce-platform/shared/go/money"
 	"github.com/ecommerce/inventory-service/internal/domain"
 	"github.com/google/uuid"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+// postgresRepository keeps every tenant's inventory in the same tables.
+// Each query is scoped to the tenant of its context, so no operation sees or
+// changes another tenant's rows.
 type postgresRepository struct {
-	db *sql.DB
+	// db runs queries: tx within InTx, else pool
+	db   shareddb.Querier
+	pool *sql.DB
+	tx   *sql.Tx
 }
 
 // NewPostgresRepository creates a new PostgreSQL repository
 func NewPostgresRepository(db *sql.DB) InventoryRepository {
-	return &postgresRepository{db: db}
+	return &postgresRepository{db: db, pool: db}
+}
+
+// InTx runs fn in a SERIALIZABLE transaction, retried on serialization
+// failures. Nested calls join the current transaction.
+func (r *postgresRepository) InTx(ctx context.Context, fn func(repo InventoryRepository) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+	return shareddb.WithSerializableTx(ctx, r.pool, func(tx *sql.Tx) error {
+		return fn(&postgresRepository{db: tx, pool: r.pool, tx: tx})
+	})
+}
+
+// tenantID is the tenant ctx's operations are scoped to
+func tenantID(ctx context.Context) string {
+	return sharedauth.TenantFromContext(ctx)
+}
+
+// Ping checks that the database is reachable
+func (r *postgresRepository) Ping(ctx context.Context) error {
+	return shareddb.Check(ctx, r.pool)
 }
 
 // Create creates a new inventory item
@@ -31,37 +63,80 @@ func (r *postgresRepository) Create(ctx context.Context, item *domain.InventoryI
 	item.UpdatedAt = now
 	item.CalculateAvailableQuantity()
 	item.UpdateStatus()
+	if item.CostMethod == "" {
+		item.CostMethod = domain.CostWeightedAverage
+	}
+	if item.Allocation == "" {
+		item.Allocation = domain.AllocationManual
+	}
 
 	query := `
 		INSERT INTO inventory_items (
-			id, product_id, sku, quantity, reserved_quantity, available_quantity,
-			reorder_level, reorder_quantity, status, location, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			id, product_id, sku, quantity, reserved_quantity, expired_quantity, available_quantity,
+			reorder_level, reorder_quantity, status, location, supplier,
+			unit_cost_minor, cost_currency, cost_method, allocation, created_at, updated_at, tenant_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
 	`
 
+	unitCost, currency := costColumns(item.UnitCost)
 	_, err := r.db.ExecContext(ctx, query,
-		item.ID, item.ProductID, item.SKU, item.Quantity, item.ReservedQuantity,
+		item.ID, item.ProductID, item.SKU, item.Quantity, item.ReservedQuantity, item.ExpiredQuantity,
 		item.AvailableQuantity, item.ReorderLevel, item.ReorderQuantity,
-		item.Status, item.Location, item.CreatedAt, item.UpdatedAt,
+		item.Status, item.Location, item.Supplier, unitCost, currency, item.CostMethod, item.Allocation,
+		item.CreatedAt, item.UpdatedAt, tenantID(ctx),
 	)
 
 	return err
 }
 
+// itemColumns are the columns scanItem reads
+const itemColumns = `id, product_id, sku, quantity, reserved_quantity, expired_quantity, available_quantity,
+	reorder_level, reorder_quantity, status, location, supplier,
+	unit_cost_minor, cost_currency, cost_method, allocation, created_at, updated_at`
+
+// scanItem reads an inventory item's itemColumns
+func scanItem(row rowScanner) (*domain.InventoryItem, error) {
+	item := &domain.InventoryItem{}
+	var unitCost sql.NullInt64
+	var currency string
+	err := row.Scan(
+		&item.ID, &item.ProductID, &item.SKU, &item.Quantity, &item.ReservedQuantity, &item.ExpiredQuantity,
+		&item.AvailableQuantity, &item.ReorderLevel, &item.ReorderQuantity,
+		&item.Status, &item.Location, &item.Supplier, &unitCost, &currency, &item.CostMethod, &item.Allocation,
+		&item.CreatedAt, &item.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	item.UnitCost = scanCost(unitCost, currency)
+	return item, nil
+}
+
+// costColumns are a cost's minor units, NULL without one, and currency
+func costColumns(cost *money.Money) (sql.NullInt64, string) {
+	if cost == nil {
+		return sql.NullInt64{}, ""
+	}
+	return sql.NullInt64{Int64: cost.MinorUnits, Valid: true}, cost.Currency
+}
+
+// scanCost is the cost read from costColumns
+func scanCost(minorUnits sql.NullInt64, currency string) *money.Money {
+	if !minorUnits.Valid {
+		return nil
+	}
+	cost := money.New(minorUnits.Int64, currency)
+	return &cost
+}
+
 // GetByID retrieves an inventory item by ID
 func (r *postgresRepository) GetByID(ctx context.Context, id string) (*domain.InventoryItem, error) {
 	query := `
-		SELECT id, product_id, sku, quantity, reserved_quantity, available_quantity,
-			   reorder_level, reorder_quantity, status, location, created_at, updated_at
-		FROM inventory_items WHERE id = $1
+		SELECT ` + itemColumns + `
+		FROM inventory_items WHERE id = $1 AND tenant_id = $2
 	`
 
-	item := &domain.InventoryItem{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&item.ID, &item.ProductID, &item.SKU, &item.Quantity, &item.ReservedQuantity,
-		&item.AvailableQuantity, &item.ReorderLevel, &item.ReorderQuantity,
-		&item.Status, &item.Location, &item.CreatedAt, &item.UpdatedAt,
-	)
+	item, err := scanItem(r.db.QueryRowContext(ctx, query, id, tenantID(ctx)))
 
 	if err == sql.ErrNoRows {
 		return nil, domain.ErrNotFound
@@ -73,17 +148,11 @@ func (r *postgresRepository) GetByID(ctx context.Context, id string) (*domain.In
 // GetByProductID retrieves an inventory item by product ID
 func (r *postgresRepository) GetByProductID(ctx context.Context, productID string) (*domain.InventoryItem, error) {
 	query := `
-		SELECT id, product_id, sku, quantity, reserved_quantity, available_quantity,
-			   reorder_level, reorder_quantity, status, location, created_at, updated_at
-		FROM inventory_items WHERE product_id = $1
+		SELECT ` + itemColumns + `
+		FROM inventory_items WHERE product_id = $1 AND tenant_id = $2
 	`
 
-	item := &domain.InventoryItem{}
-	err := r.db.QueryRowContext(ctx, query, productID).Scan(
-		&item.ID, &item.ProductID, &item.SKU, &item.Quantity, &item.ReservedQuantity,
-		&item.AvailableQuantity, &item.ReorderLevel, &item.ReorderQuantity,
-		&item.Status, &item.Location, &item.CreatedAt, &item.UpdatedAt,
-	)
+	item, err := scanItem(r.db.QueryRowContext(ctx, query, productID, tenantID(ctx)))
 
 	if err == sql.ErrNoRows {
 		return nil, domain.ErrNotFound
@@ -95,17 +164,11 @@ func (r *postgresRepository) GetByProductID(ctx context.Context, productID strin
 // GetBySKU retrieves an inventory item by SKU
 func (r *postgresRepository) GetBySKU(ctx context.Context, sku string) (*domain.InventoryItem, error) {
 	query := `
-		SELECT id, product_id, sku, quantity, reserved_quantity, available_quantity,
-			   reorder_level, reorder_quantity, status, location, created_at, updated_at
-		FROM inventory_items WHERE sku = $1
+		SELECT ` + itemColumns + `
+		FROM inventory_items WHERE sku = $1 AND tenant_id = $2
 	`
 
-	item := &domain.InventoryItem{}
-	err := r.db.QueryRowContext(ctx, query, sku).Scan(
-		&item.ID, &item.ProductID, &item.SKU, &item.Quantity, &item.ReservedQuantity,
-		&item.AvailableQuantity, &item.ReorderLevel, &item.ReorderQuantity,
-		&item.Status, &item.Location, &item.CreatedAt, &item.UpdatedAt,
-	)
+	item, err := scanItem(r.db.QueryRowContext(ctx, query, sku, tenantID(ctx)))
 
 	if err == sql.ErrNoRows {
 		return nil, domain.ErrNotFound
@@ -114,37 +177,100 @@ func (r *postgresRepository) GetBySKU(ctx context.Context, sku string) (*domain.
 	return item, err
 }
 
-// List retrieves inventory items with pagination
-func (r *postgresRepository) List(ctx context.Context, limit, offset int) ([]*domain.InventoryItem, error) {
+// itemConditions is the WHERE clause of filter, and its arguments after
+// args
+func itemConditions(ctx context.Context, filter ItemFilter, args []interface{}) (string, []interface{}) {
+	args = append(args, tenantID(ctx))
+	where := fmt.Sprintf("tenant_id = $%d", len(args))
+	for _, equal := range []struct {
+		column string
+		value  string
+	}{
+		{"status", filter.Status},
+		{"location", filter.Location},
+		{"supplier", filter.Supplier},
+	} {
+		if equal.value != "" {
+			args = append(args, equal.value)
+			where += fmt.Sprintf(" AND %s = $%d", equal.column, len(args))
+		}
+	}
+	if filter.SKUPrefix != "" {
+		args = append(args, escapeLike(filter.SKUPrefix)+"%")
+		where += fmt.Sprintf(" AND sku LIKE $%d", len(args))
+	}
+	if filter.Search != "" {
+		args = append(args, "%"+escapeLike(filter.Search)+"%")
+		where += fmt.Sprintf(" AND sku ILIKE $%d", len(args))
+	}
+	if len(filter.ProductIDs) > 0 {
+		args = append(args, pq.Array(filter.ProductIDs))
+		where += fmt.Sprintf(" AND product_id = ANY($%d)", len(args))
+	}
+	for _, bound := range []struct {
+		condition string
+		value     *int
+	}{
+		{"quantity >=", filter.MinQuantity},
+		{"quantity <=", filter.MaxQuantity},
+		{"available_quantity >=", filter.MinAvailable},
+		{"available_quantity <=", filter.MaxAvailable},
+	} {
+		if bound.value != nil {
+			args = append(args, *bound.value)
+			where += fmt.Sprintf(" AND %s $%d", bound.condition, len(args))
+		}
+	}
+	return where, args
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern, matching s literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// List retrieves up to limit inventory items matching filter after a
+// position, newest first
+func (r *postgresRepository) List(ctx context.Context, filter ItemFilter, limit int, after *ListPosition) ([]*domain.InventoryItem, error) {
+	where, args := itemConditions(ctx, filter, []interface{}{limit})
+	if after != nil {
+		args = append(args, after.CreatedAt, after.ID)
+		where += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+
 	query := `
-		SELECT id, product_id, sku, quantity, reserved_quantity, available_quantity,
-			   reorder_level, reorder_quantity, status, location, created_at, updated_at
+		SELECT ` + itemColumns + `
 		FROM inventory_items
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
+		WHERE ` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+	return r.queryInventoryItems(ctx, query, args...)
+}
 
-	var items []*domain.InventoryItem
-	for rows.Next() {
-		item := &domain.InventoryItem{}
-		err := rows.Scan(
-			&item.ID, &item.ProductID, &item.SKU, &item.Quantity, &item.ReservedQuantity,
-			&item.AvailableQuantity, &item.ReorderLevel, &item.ReorderQuantity,
-			&item.Status, &item.Location, &item.CreatedAt, &item.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		items = append(items, item)
-	}
+// ListStocked retrieves every inventory item with stock matching filter,
+// in SKU order
+func (r *postgresRepository) ListStocked(ctx context.Context, filter ItemFilter) ([]*domain.InventoryItem, error) {
+	where, args := itemConditions(ctx, filter, nil)
 
-	return items, rows.Err()
+	query := `
+		SELECT ` + itemColumns + `
+		FROM inventory_items
+		WHERE ` + where + ` AND quantity > 0
+		ORDER BY sku, id
+	`
+
+	return r.queryInventoryItems(ctx, query, args...)
+}
+
+// Count returns the number of inventory items matching filter
+func (r *postgresRepository) Count(ctx context.Context, filter ItemFilter) (int64, error) {
+	where, args := itemConditions(ctx, filter, nil)
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM inventory_items WHERE "+where, args...).Scan(&count)
+	return count, err
 }
 
 // Update updates an inventory item
@@ -152,19 +278,28 @@ func (r *postgresRepository) Update(ctx context.Context, item *domain.InventoryI
 	item.UpdatedAt = time.Now()
 	item.CalculateAvailableQuantity()
 	item.UpdateStatus()
+	if item.CostMethod == "" {
+		item.CostMethod = domain.CostWeightedAverage
+	}
+	if item.Allocation == "" {
+		item.Allocation = domain.AllocationManual
+	}
 
 	query := `
 		UPDATE inventory_items
-		SET quantity = $1, reserved_quantity = $2, available_quantity = $3,
-			reorder_level = $4, reorder_quantity = $5, status = $6,
-			location = $7, updated_at = $8
-		WHERE id = $9
+		SET quantity = $1, reserved_quantity = $2, expired_quantity = $3, available_quantity = $4,
+			reorder_level = $5, reorder_quantity = $6, status = $7,
+			location = $8, supplier = $9, unit_cost_minor = $10, cost_currency = $11,
+			cost_method = $12, allocation = $13, updated_at = $14
+		WHERE id = $15 AND tenant_id = $16
 	`
 
+	unitCost, currency := costColumns(item.UnitCost)
 	result, err := r.db.ExecContext(ctx, query,
-		item.Quantity, item.ReservedQuantity, item.AvailableQuantity,
+		item.Quantity, item.ReservedQuantity, item.ExpiredQuantity, item.AvailableQuantity,
 		item.ReorderLevel, item.ReorderQuantity, item.Status,
-		item.Location, item.UpdatedAt, item.ID,
+		item.Location, item.Supplier, unitCost, currency, item.CostMethod, item.Allocation,
+		item.UpdatedAt, item.ID, tenantID(ctx),
 	)
 
 	if err != nil {
@@ -183,11 +318,126 @@ func (r *postgresRepository) Update(ctx context.Context, item *domain.InventoryI
 	return nil
 }
 
+// UpsertBySKU imports items in a transaction, or the current one within
+// InTx. The items of the imports' SKUs and products are locked first, so
+// concurrent reservations and imports wait.
+func (r *postgresRepository) UpsertBySKU(ctx context.Context, imports []ItemImport) ([]ImportResult, error) {
+	var results []ImportResult
+	err := r.inLockingTx(ctx, func(repo *postgresRepository) error {
+		bySKU, byProduct, err := repo.lockImported(ctx, imports)
+		if err != nil {
+			return err
+		}
+
+		results = make([]ImportResult, len(imports))
+		for i, imp := range imports {
+			item, exists := bySKU[imp.SKU]
+			switch {
+			case !exists && byProduct[imp.ProductID] != nil:
+				results[i].Err = domain.ErrProductConflict
+				continue
+			case exists && item.ProductID != imp.ProductID:
+				results[i].Err = domain.ErrSKUConflict
+				continue
+			case exists && imp.Quantity < item.ReservedQuantity:
+				results[i].Err = domain.ErrBelowReserved
+				continue
+			}
+
+			change := imp.Quantity
+			var before domain.InventoryItem
+			if exists {
+				before = *item
+				change -= item.Quantity
+			} else {
+				item = &domain.InventoryItem{SKU: imp.SKU, ProductID: imp.ProductID}
+			}
+			item.Quantity = imp.Quantity
+			if imp.ReorderLevel != nil {
+				item.ReorderLevel = *imp.ReorderLevel
+			}
+			if imp.ReorderQuantity != nil {
+				item.ReorderQuantity = *imp.ReorderQuantity
+			}
+			if imp.Location != nil {
+				item.Location = *imp.Location
+			}
+			if imp.Supplier != nil {
+				item.Supplier = *imp.Supplier
+			}
+
+			if exists {
+				err = repo.Update(ctx, item)
+			} else {
+				err = repo.Create(ctx, item)
+				bySKU[item.SKU] = item
+				byProduct[item.ProductID] = item
+			}
+			if err != nil {
+				return err
+			}
+
+			eventType := domain.HistoryUpdated
+			if !exists {
+				eventType = domain.HistoryCreated
+			}
+			event := domain.NewHistoryEvent(item, eventType, change, 0).Referencing(domain.ReferenceImport, "")
+			if err := repo.RecordHistory(ctx, event); err != nil {
+				return err
+			}
+			results[i] = ImportResult{Item: item, Before: before, Created: !exists}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// lockImported retrieves the items of the imports' SKUs and products,
+// locking their rows until the end of the transaction
+func (r *postgresRepository) lockImported(ctx context.Context, imports []ItemImport) (bySKU, byProduct map[string]*domain.InventoryItem, err error) {
+	skus := make([]string, len(imports))
+	productIDs := make([]string, len(imports))
+	for i, imp := range imports {
+		skus[i] = imp.SKU
+		productIDs[i] = imp.ProductID
+	}
+
+	query := `
+		SELECT ` + itemColumns + `
+		FROM inventory_items
+		WHERE tenant_id = $1 AND (sku = ANY($2) OR product_id = ANY($3))
+		ORDER BY id
+		FOR UPDATE
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, tenantID(ctx), pq.Array(skus), pq.Array(productIDs))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	bySKU = make(map[string]*domain.InventoryItem)
+	byProduct = make(map[string]*domain.InventoryItem)
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		bySKU[item.SKU] = item
+		byProduct[item.ProductID] = item
+	}
+
+	return bySKU, byProduct, rows.Err()
+}
+
 // Delete deletes an inventory item
 func (r *postgresRepository) Delete(ctx context.Context, id string) error {
-	query := `DELETE FROM inventory_items WHERE id = $1`
+	query := `DELETE FROM inventory_items WHERE id = $1 AND tenant_id = $2`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := r.db.ExecContext(ctx, query, id, tenantID(ctx))
 	if err != nil {
 		return err
 	}
@@ -212,28 +462,165 @@ func (r *postgresRepository) CreateReservation(ctx context.Context, reservation
 	reservation.CreatedAt = time.Now()
 
 	query := `
-		INSERT INTO reservations (id, product_id, quantity, order_id, customer_id, expires_at, status, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO reservations (id, product_id, quantity, order_id, customer_id, expires_at, status, created_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
 
 	_, err := r.db.ExecContext(ctx, query,
 		reservation.ID, reservation.ProductID, reservation.Quantity,
 		reservation.OrderID, reservation.CustomerID, reservation.ExpiresAt,
-		reservation.Status, reservation.CreatedAt,
+		reservation.Status, reservation.CreatedAt, tenantID(ctx),
 	)
 
 	return err
 }
 
+// ReserveWithinTx reserves stock in a transaction, or the current one
+// within InTx. The item's row is locked with SELECT ... FOR UPDATE before
+// the order's reservations are read, so a concurrent reservation of the same
+// order and product sees the one this creates.
+func (r *postgresRepository) ReserveWithinTx(ctx context.Context, req ReservationRequest) (*domain.InventoryItem, *domain.Reservation, bool, error) {
+	var item *domain.InventoryItem
+	var reservation *domain.Reservation
+	created := false
+	err := r.inLockingTx(ctx, func(repo *postgresRepository) error {
+		var err error
+		item, reservation, created, err = repo.reserveLocked(ctx, req)
+		return err
+	})
+	if err == domain.ErrInsufficientStock {
+		return item, nil, false, err
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return item, reservation, created, nil
+}
+
+// ReserveAllWithinTx reserves every request's stock in one transaction, or
+// the current one within InTx. Items are locked in product ID order, so
+// concurrent batches sharing products can't deadlock.
+func (r *postgresRepository) ReserveAllWithinTx(ctx context.Context, reqs []ReservationRequest) ([]ReservedStock, error) {
+	order := make([]int, len(reqs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return reqs[order[a]].key() < reqs[order[b]].key()
+	})
+
+	var reserved []ReservedStock
+	err := r.inLockingTx(ctx, func(repo *postgresRepository) error {
+		reserved = make([]ReservedStock, len(reqs))
+		for _, i := range order {
+			item, reservation, created, err := repo.reserveLocked(ctx, reqs[i])
+			if err != nil {
+				return &ReservationError{Request: reqs[i], Item: item, Err: err}
+			}
+			reserved[i] = ReservedStock{Item: item, Reservation: reservation, Created: created}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reserved, nil
+}
+
+// inLockingTx runs fn in the current transaction, or else a new one. Row
+// locks order concurrent reservations, so READ COMMITTED suffices and they
+// wait rather than fail and retry.
+func (r *postgresRepository) inLockingTx(ctx context.Context, fn func(repo *postgresRepository) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+	return shareddb.WithTx(ctx, r.pool, nil, func(tx *sql.Tx) error {
+		return fn(&postgresRepository{db: tx, pool: r.pool, tx: tx})
+	})
+}
+
+// reserveLocked reserves req's stock within a transaction, locking its
+// item. With domain.ErrInsufficientStock it returns the item as it stands.
+func (r *postgresRepository) reserveLocked(ctx context.Context, req ReservationRequest) (*domain.InventoryItem, *domain.Reservation, bool, error) {
+	item, err := r.lockItem(ctx, req)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	held, err := r.GetReservationsByOrderID(ctx, req.OrderID)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	for _, held := range held {
+		if held.ProductID == item.ProductID && (held.Status == domain.ReservationPending || held.Status == domain.ReservationConfirmed) {
+			return item, held, false, nil
+		}
+	}
+
+	if err := item.Reserve(req.Quantity); err != nil {
+		return item, nil, false, err
+	}
+	if err := r.Update(ctx, item); err != nil {
+		return nil, nil, false, err
+	}
+
+	reservation := &domain.Reservation{
+		ProductID:  item.ProductID,
+		Quantity:   req.Quantity,
+		OrderID:    req.OrderID,
+		CustomerID: req.CustomerID,
+		ExpiresAt:  req.ExpiresAt,
+		Status:     domain.ReservationPending,
+	}
+	if err := r.CreateReservation(ctx, reservation); err != nil {
+		return nil, nil, false, err
+	}
+
+	event := domain.NewHistoryEvent(item, domain.HistoryReserved, 0, req.Quantity).
+		Referencing(domain.ReferenceReservation, reservation.ID)
+	event.Detail = req.OrderID
+	if err := r.RecordHistory(ctx, event); err != nil {
+		return nil, nil, false, err
+	}
+	return item, reservation, true, nil
+}
+
+// lockItem retrieves req's inventory item, locking its row until the end of
+// the transaction
+func (r *postgresRepository) lockItem(ctx context.Context, req ReservationRequest) (*domain.InventoryItem, error) {
+	query := `
+		SELECT ` + itemColumns + `
+		FROM inventory_items WHERE id = $1 AND tenant_id = $2
+		FOR UPDATE
+	`
+	key := req.ItemID
+	if key == "" {
+		query = `
+			SELECT ` + itemColumns + `
+			FROM inventory_items WHERE product_id = $1 AND tenant_id = $2
+			FOR UPDATE
+		`
+		key = req.ProductID
+	}
+
+	item, err := scanItem(r.db.QueryRowContext(ctx, query, key, tenantID(ctx)))
+
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+
+	return item, err
+}
+
 // GetReservation retrieves a reservation by ID
 func (r *postgresRepository) GetReservation(ctx context.Context, id string) (*domain.Reservation, error) {
 	query := `
 		SELECT id, product_id, quantity, order_id, customer_id, expires_at, status, created_at
-		FROM reservations WHERE id = $1
+		FROM reservations WHERE id = $1 AND tenant_id = $2
 	`
 
 	reservation := &domain.Reservation{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := r.db.QueryRowContext(ctx, query, id, tenantID(ctx)).Scan(
 		&reservation.ID, &reservation.ProductID, &reservation.Quantity,
 		&reservation.OrderID, &reservation.CustomerID, &reservation.ExpiresAt,
 		&reservation.Status, &reservation.CreatedAt,
@@ -250,22 +637,73 @@ func (r *postgresRepository) GetReservation(ctx context.Context, id string) (*do
 func (r *postgresRepository) GetReservationsByProductID(ctx context.Context, productID string) ([]*domain.Reservation, error) {
 	query := `
 		SELECT id, product_id, quantity, order_id, customer_id, expires_at, status, created_at
-		FROM reservations WHERE product_id = $1 AND status = 'pending'
+		FROM reservations WHERE product_id = $1 AND tenant_id = $2 AND status = 'pending'
 		ORDER BY created_at DESC
 	`
 
-	return r.queryReservations(ctx, query, productID)
+	return r.queryReservations(ctx, query, productID, tenantID(ctx))
 }
 
 // GetReservationsByOrderID retrieves reservations by order ID
 func (r *postgresRepository) GetReservationsByOrderID(ctx context.Context, orderID string) ([]*domain.Reservation, error) {
 	query := `
 		SELECT id, product_id, quantity, order_id, customer_id, expires_at, status, created_at
-		FROM reservations WHERE order_id = $1
+		FROM reservations WHERE order_id = $1 AND tenant_id = $2
 		ORDER BY created_at DESC
 	`
 
-	return r.queryReservations(ctx, query, orderID)
+	return r.queryReservations(ctx, query, orderID, tenantID(ctx))
+}
+
+// reservationConditions is the WHERE clause of filter, and its arguments
+// after args
+func reservationConditions(ctx context.Context, filter ReservationFilter, args []interface{}) (string, []interface{}) {
+	args = append(args, tenantID(ctx))
+	where := fmt.Sprintf("tenant_id = $%d", len(args))
+	if filter.OrderID != "" {
+		args = append(args, filter.OrderID)
+		where += fmt.Sprintf(" AND order_id = $%d", len(args))
+	}
+	if filter.CustomerID != "" {
+		args = append(args, filter.CustomerID)
+		where += fmt.Sprintf(" AND customer_id = $%d", len(args))
+	}
+	if filter.ProductID != "" {
+		args = append(args, filter.ProductID)
+		where += fmt.Sprintf(" AND product_id = $%d", len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	return where, args
+}
+
+// ListReservations retrieves up to limit reservations after a position,
+// newest first
+func (r *postgresRepository) ListReservations(ctx context.Context, filter ReservationFilter, limit int, after *ListPosition) ([]*domain.Reservation, error) {
+	where, args := reservationConditions(ctx, filter, []interface{}{limit})
+	if after != nil {
+		args = append(args, after.CreatedAt, after.ID)
+		where += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+
+	query := `
+		SELECT id, product_id, quantity, order_id, customer_id, expires_at, status, created_at
+		FROM reservations WHERE ` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1`
+
+	return r.queryReservations(ctx, query, args...)
+}
+
+// CountReservations returns the number of reservations matching filter
+func (r *postgresRepository) CountReservations(ctx context.Context, filter ReservationFilter) (int64, error) {
+	where, args := reservationConditions(ctx, filter, nil)
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM reservations WHERE "+where, args...).Scan(&count)
+	return count, err
 }
 
 // UpdateReservation updates a reservation
@@ -273,10 +711,10 @@ func (r *postgresRepository) UpdateReservation(ctx context.Context, reservation
 	query := `
 		UPDATE reservations
 		SET status = $1
-		WHERE id = $2
+		WHERE id = $2 AND tenant_id = $3
 	`
 
-	result, err := r.db.ExecContext(ctx, query, reservation.Status, reservation.ID)
+	result, err := r.db.ExecContext(ctx, query, reservation.Status, reservation.ID, tenantID(ctx))
 	if err != nil {
 		return err
 	}
@@ -295,9 +733,9 @@ func (r *postgresRepository) UpdateReservation(ctx context.Context, reservation
 
 // DeleteReservation deletes a reservation
 func (r *postgresRepository) DeleteReservation(ctx context.Context, id string) error {
-	query := `DELETE FROM reservations WHERE id = $1`
+	query := `DELETE FROM reservations WHERE id = $1 AND tenant_id = $2`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := r.db.ExecContext(ctx, query, id, tenantID(ctx))
 	if err != nil {
 		return err
 	}
@@ -319,10 +757,36 @@ func (r *postgresRepository) GetExpiredReservations(ctx context.Context) ([]*dom
 	query := `
 		SELECT id, product_id, quantity, order_id, customer_id, expires_at, status, created_at
 		FROM reservations
+		WHERE tenant_id = $1 AND status = 'pending' AND expires_at < $2
+	`
+
+	return r.queryReservations(ctx, query, tenantID(ctx), time.Now())
+}
+
+// ExpiredReservationTenants lists the tenants with expired pending
+// reservations
+func (r *postgresRepository) ExpiredReservationTenants(ctx context.Context) ([]string, error) {
+	query := `
+		SELECT DISTINCT tenant_id
+		FROM reservations
 		WHERE status = 'pending' AND expires_at < $1
 	`
 
-	return r.queryReservations(ctx, query, time.Now())
+	rows, err := r.db.QueryContext(ctx, query, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tenants []string
+	for rows.Next() {
+		var tenant string
+		if err := rows.Scan(&tenant); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, rows.Err()
 }
 
 // CreateAdjustment creates an inventory adjustment record
@@ -333,29 +797,55 @@ func (r *postgresRepository) CreateAdjustment(ctx context.Context, adjustment *d
 	adjustment.CreatedAt = time.Now()
 
 	query := `
-		INSERT INTO inventory_adjustments (id, product_id, quantity, reason, adjusted_by, notes, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO inventory_adjustments (
+			id, product_id, quantity, reason, adjusted_by, notes, cycle_count_id,
+			unit_cost_minor, cost_currency, order_id, returned_quantity, created_at, tenant_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 
+	unitCost, currency := costColumns(adjustment.UnitCost)
 	_, err := r.db.ExecContext(ctx, query,
 		adjustment.ID, adjustment.ProductID, adjustment.Quantity,
-		adjustment.Reason, adjustment.AdjustedBy, adjustment.Notes, adjustment.CreatedAt,
+		adjustment.Reason, adjustment.AdjustedBy, adjustment.Notes, adjustment.CycleCountID,
+		unitCost, currency, adjustment.OrderID, adjustment.ReturnedQuantity, adjustment.CreatedAt, tenantID(ctx),
 	)
 
 	return err
 }
 
+// RecordRestockedReturn records an order line's return as checked back in.
+// A concurrent restock of the same line waits on the row, then conflicts.
+func (r *postgresRepository) RecordRestockedReturn(ctx context.Context, orderID, lineID, adjustmentID string) error {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO restocked_returns (tenant_id, order_id, line_id, adjustment_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tenant_id, order_id, line_id) DO NOTHING
+	`, tenantID(ctx), orderID, lineID, adjustmentID)
+	if err != nil {
+		return err
+	}
+	recorded, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if recorded == 0 {
+		return domain.ErrReturnRestocked
+	}
+	return nil
+}
+
 // GetAdjustmentsByProductID retrieves adjustments for a product
 func (r *postgresRepository) GetAdjustmentsByProductID(ctx context.Context, productID string, limit int) ([]*domain.InventoryAdjustment, error) {
 	query := `
-		SELECT id, product_id, quantity, reason, adjusted_by, notes, created_at
+		SELECT id, product_id, quantity, reason, adjusted_by, notes, cycle_count_id,
+			   unit_cost_minor, cost_currency, order_id, returned_quantity, created_at
 		FROM inventory_adjustments
-		WHERE product_id = $1
+		WHERE product_id = $1 AND tenant_id = $3
 		ORDER BY created_at DESC
 		LIMIT $2
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, productID, limit)
+	rows, err := r.db.QueryContext(ctx, query, productID, limit, tenantID(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -364,85 +854,1606 @@ func (r *postgresRepository) GetAdjustmentsByProductID(ctx context.Context, prod
 	var adjustments []*domain.InventoryAdjustment
 	for rows.Next() {
 		adj := &domain.InventoryAdjustment{}
+		var unitCost sql.NullInt64
+		var currency string
 		err := rows.Scan(
 			&adj.ID, &adj.ProductID, &adj.Quantity, &adj.Reason,
-			&adj.AdjustedBy, &adj.Notes, &adj.CreatedAt,
+			&adj.AdjustedBy, &adj.Notes, &adj.CycleCountID, &unitCost, &currency,
+			&adj.OrderID, &adj.ReturnedQuantity, &adj.CreatedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
+		adj.UnitCost = scanCost(unitCost, currency)
 		adjustments = append(adjustments, adj)
 	}
 
 	return adjustments, rows.Err()
 }
 
-// GetLowStockItems retrieves items with low stock
-func (r *postgresRepository) GetLowStockItems(ctx context.Context) ([]*domain.InventoryItem, error) {
-	query := `
-		SELECT id, product_id, sku, quantity, reserved_quantity, available_quantity,
-			   reorder_level, reorder_quantity, status, location, created_at, updated_at
-		FROM inventory_items
-		WHERE status = 'low_stock' OR available_quantity <= reorder_level
-		ORDER BY available_quantity ASC
-	`
+// RecordHistory records a change to an inventory item
+func (r *postgresRepository) RecordHistory(ctx context.Context, event *domain.HistoryEvent) error {
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	event.OccurredAt = time.Now()
 
-	return r.queryInventoryItems(ctx, query)
-}
+	var quantity, reserved, available sql.NullInt64
+	if event.Stock != nil {
+		quantity = sql.NullInt64{Int64: int64(event.Stock.Quantity), Valid: true}
+		reserved = sql.NullInt64{Int64: int64(event.Stock.ReservedQuantity), Valid: true}
+		available = sql.NullInt64{Int64: int64(event.Stock.AvailableQuantity), Valid: true}
+	}
 
-// GetOutOfStockItems retrieves out of stock items
-func (r *postgresRepository) GetOutOfStockItems(ctx context.Context) ([]*domain.InventoryItem, error) {
 	query := `
-		SELECT id, product_id, sku, quantity, reserved_quantity, available_quantity,
-			   reorder_level, reorder_quantity, status, location, created_at, updated_at
-		FROM inventory_items
-		WHERE status = 'out_of_stock' OR available_quantity = 0
+		INSERT INTO inventory_events (
+			id, item_id, product_id, event_type, quantity_change, reserved_change,
+			quantity, reserved_quantity, available_quantity,
+			reference_type, reference_id, actor, detail, occurred_at, tenant_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 	`
 
-	return r.queryInventoryItems(ctx, query)
+	_, err := r.db.ExecContext(ctx, query,
+		event.ID, event.ItemID, event.ProductID, event.Type, event.QuantityChange, event.ReservedChange,
+		quantity, reserved, available,
+		event.ReferenceType, event.ReferenceID, event.Actor, event.Detail, event.OccurredAt, tenantID(ctx),
+	)
+
+	return err
 }
 
-// Helper methods
+// ListHistory retrieves up to limit of an item's changes after a position,
+// newest first
+func (r *postgresRepository) ListHistory(ctx context.Context, itemID string, limit int, after *ListPosition) ([]*domain.HistoryEvent, error) {
+	query := `
+		SELECT id, item_id, product_id, event_type, quantity_change, reserved_change,
+			   quantity, reserved_quantity, available_quantity,
+			   reference_type, reference_id, actor, detail, occurred_at
+		FROM inventory_events
+		WHERE item_id = $2 AND tenant_id = $3
+		ORDER BY occurred_at DESC, id DESC
+		LIMIT $1
+	`
+	args := []interface{}{limit, itemID, tenantID(ctx)}
+	if after != nil {
+		query = `
+			SELECT id, item_id, product_id, event_type, quantity_change, reserved_change,
+				   quantity, reserved_quantity, available_quantity,
+				   reference_type, reference_id, actor, detail, occurred_at
+			FROM inventory_events
+			WHERE item_id = $2 AND tenant_id = $3 AND (occurred_at, id) < ($4, $5)
+			ORDER BY occurred_at DESC, id DESC
+			LIMIT $1
+		`
+		args = append(args, after.CreatedAt, after.ID)
+	}
 
-func (r *postgresRepository) queryReservations(ctx context.Context, query string, args ...interface{}) ([]*domain.Reservation, error) {
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var reservations []*domain.Reservation
+	var events []*domain.HistoryEvent
 	for rows.Next() {
-		res := &domain.Reservation{}
+		event := &domain.HistoryEvent{}
+		var quantity, reserved, available sql.NullInt64
 		err := rows.Scan(
-			&res.ID, &res.ProductID, &res.Quantity,
-			&res.OrderID, &res.CustomerID, &res.ExpiresAt,
-			&res.Status, &res.CreatedAt,
+			&event.ID, &event.ItemID, &event.ProductID, &event.Type, &event.QuantityChange, &event.ReservedChange,
+			&quantity, &reserved, &available,
+			&event.ReferenceType, &event.ReferenceID, &event.Actor, &event.Detail, &event.OccurredAt,
 		)
 		if err != nil {
 			return nil, err
 		}
-		reservations = append(reservations, res)
+		if quantity.Valid {
+			event.Stock = &domain.StockLevels{
+				Quantity:          int(quantity.Int64),
+				ReservedQuantity:  int(reserved.Int64),
+				AvailableQuantity: int(available.Int64),
+			}
+		}
+		events = append(events, event)
 	}
 
-	return reservations, rows.Err()
+	return events, rows.Err()
 }
 
-func (r *postgresRepository) queryInventoryItems(ctx context.Context, query string, args ...interface{}) ([]*domain.InventoryItem, error) {
-	rows, err := r.db.QueryContext(ctx, query, args...)
+// CountHistory returns the number of an item's changes
+func (r *postgresRepository) CountHistory(ctx context.Context, itemID string) (int64, error) {
+	var count int64
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM inventory_events WHERE item_id = $1 AND tenant_id = $2",
+		itemID, tenantID(ctx),
+	).Scan(&count)
+	return count, err
+}
+
+// CreateTransfer creates a transfer
+func (r *postgresRepository) CreateTransfer(ctx context.Context, transfer *domain.Transfer) error {
+	if transfer.ID == "" {
+		transfer.ID = uuid.New().String()
+	}
+	now := time.Now()
+	transfer.CreatedAt = now
+	transfer.UpdatedAt = now
+
+	query := `
+		INSERT INTO stock_transfers (
+			id, product_id, from_location, to_location, quantity, status,
+			requested_by, notes, created_at, updated_at, tenant_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		transfer.ID, transfer.ProductID, transfer.FromLocation, transfer.ToLocation,
+		transfer.Quantity, transfer.Status, transfer.RequestedBy, transfer.Notes,
+		transfer.CreatedAt, transfer.UpdatedAt, tenantID(ctx),
+	)
+
+	return err
+}
+
+// transferColumns are the columns scanTransfer reads
+const transferColumns = `id, product_id, from_location, to_location, quantity, status,
+	requested_by, notes, created_at, updated_at, completed_at, cancelled_at`
+
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTransfer reads a transfer's transferColumns
+func scanTransfer(row rowScanner) (*domain.Transfer, error) {
+	transfer := &domain.Transfer{}
+	var completedAt, cancelledAt sql.NullTime
+	err := row.Scan(
+		&transfer.ID, &transfer.ProductID, &transfer.FromLocation, &transfer.ToLocation,
+		&transfer.Quantity, &transfer.Status, &transfer.RequestedBy, &transfer.Notes,
+		&transfer.CreatedAt, &transfer.UpdatedAt, &completedAt, &cancelledAt,
+	)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	if completedAt.Valid {
+		transfer.CompletedAt = &completedAt.Time
+	}
+	if cancelledAt.Valid {
+		transfer.CancelledAt = &cancelledAt.Time
+	}
+	return transfer, nil
+}
 
-	var items []*domain.InventoryItem
-	for rows.Next() {
-		item := &domain.InventoryItem{}
-		err := rows.Scan(
-			&item.ID, &item.ProductID, &item.SKU, &item.Quantity, &item.ReservedQuantity,
-			&item.AvailableQuantity, &item.ReorderLevel, &item.ReorderQuantity,
-			&item.Status, &item.Location, &item.CreatedAt, &item.UpdatedAt,
-		)
+// GetTransfer retrieves a transfer by ID
+func (r *postgresRepository) GetTransfer(ctx context.Context, id string) (*domain.Transfer, error) {
+	query := `SELECT ` + transferColumns + ` FROM stock_transfers WHERE id = $1 AND tenant_id = $2`
+
+	transfer, err := scanTransfer(r.db.QueryRowContext(ctx, query, id, tenantID(ctx)))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrTransferNotFound
+	}
+
+	return transfer, err
+}
+
+// UpdateTransfer updates a transfer's status
+func (r *postgresRepository) UpdateTransfer(ctx context.Context, transfer *domain.Transfer) error {
+	transfer.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE stock_transfers
+		SET status = $1, completed_at = $2, cancelled_at = $3, updated_at = $4
+		WHERE id = $5 AND tenant_id = $6
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		transfer.Status, transfer.CompletedAt, transfer.CancelledAt, transfer.UpdatedAt,
+		transfer.ID, tenantID(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return domain.ErrTransferNotFound
+	}
+
+	return nil
+}
+
+// transferConditions is the WHERE clause of filter, and its arguments
+// after args
+func transferConditions(ctx context.Context, filter TransferFilter, args []interface{}) (string, []interface{}) {
+	args = append(args, tenantID(ctx))
+	where := fmt.Sprintf("tenant_id = $%d", len(args))
+	if filter.ProductID != "" {
+		args = append(args, filter.ProductID)
+		where += fmt.Sprintf(" AND product_id = $%d", len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	if filter.Location != "" {
+		args = append(args, filter.Location)
+		where += fmt.Sprintf(" AND (from_location = $%d OR to_location = $%d)", len(args), len(args))
+	}
+	return where, args
+}
+
+// ListTransfers retrieves up to limit transfers after a position, newest
+// first
+func (r *postgresRepository) ListTransfers(ctx context.Context, filter TransferFilter, limit int, after *ListPosition) ([]*domain.Transfer, error) {
+	where, args := transferConditions(ctx, filter, []interface{}{limit})
+	if after != nil {
+		args = append(args, after.CreatedAt, after.ID)
+		where += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+
+	query := `SELECT ` + transferColumns + ` FROM stock_transfers WHERE ` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []*domain.Transfer
+	for rows.Next() {
+		transfer, err := scanTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, transfer)
+	}
+
+	return transfers, rows.Err()
+}
+
+// CountTransfers returns the number of transfers matching filter
+func (r *postgresRepository) CountTransfers(ctx context.Context, filter TransferFilter) (int64, error) {
+	where, args := transferConditions(ctx, filter, nil)
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM stock_transfers WHERE "+where, args...).Scan(&count)
+	return count, err
+}
+
+// GetLocationStock retrieves a product's stock away from its item's
+// location
+func (r *postgresRepository) GetLocationStock(ctx context.Context, productID string) (map[string]int, error) {
+	query := `
+		SELECT location, quantity FROM location_stock
+		WHERE product_id = $1 AND tenant_id = $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, productID, tenantID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stock := make(map[string]int)
+	for rows.Next() {
+		var location string
+		var quantity int
+		if err := rows.Scan(&location, &quantity); err != nil {
+			return nil, err
+		}
+		stock[location] = quantity
+	}
+
+	return stock, rows.Err()
+}
+
+// SetLocationStock sets a product's stock at a location away from its
+// item's, removing the location when none is left
+func (r *postgresRepository) SetLocationStock(ctx context.Context, productID, location string, quantity int) error {
+	if quantity == 0 {
+		_, err := r.db.ExecContext(ctx,
+			`DELETE FROM location_stock WHERE product_id = $1 AND location = $2 AND tenant_id = $3`,
+			productID, location, tenantID(ctx),
+		)
+		return err
+	}
+
+	query := `
+		INSERT INTO location_stock (product_id, location, quantity, updated_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tenant_id, product_id, location)
+		DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.db.ExecContext(ctx, query, productID, location, quantity, time.Now(), tenantID(ctx))
+	return err
+}
+
+// CreatePurchaseOrder creates a purchase order and its lines in a
+// transaction, or the current one within InTx
+func (r *postgresRepository) CreatePurchaseOrder(ctx context.Context, order *domain.PurchaseOrder) error {
+	if order.ID == "" {
+		order.ID = uuid.New().String()
+	}
+	now := time.Now()
+	order.CreatedAt = now
+	order.UpdatedAt = now
+
+	return r.inLockingTx(ctx, func(repo *postgresRepository) error {
+		query := `
+			INSERT INTO purchase_orders (
+				id, supplier, status, created_by, notes, created_at, updated_at, tenant_id
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`
+
+		_, err := repo.db.ExecContext(ctx, query,
+			order.ID, order.Supplier, order.Status, order.CreatedBy, order.Notes,
+			order.CreatedAt, order.UpdatedAt, tenantID(ctx),
+		)
+		if err != nil {
+			return err
+		}
+
+		for i, line := range order.Lines {
+			unitCost, currency := costColumns(line.UnitCost)
+			_, err := repo.db.ExecContext(ctx, `
+				INSERT INTO purchase_order_lines (
+					purchase_order_id, line_number, product_id, sku, quantity, unit_cost_minor, cost_currency
+				) VALUES ($1, $2, $3, $4, $5, $6, $7)
+			`, order.ID, i+1, line.ProductID, line.SKU, line.Quantity, unitCost, currency)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// purchaseOrderColumns are the columns scanPurchaseOrder reads
+const purchaseOrderColumns = `id, supplier, status, created_by, notes, created_at, updated_at,
+	ordered_at, received_at, cancelled_at`
+
+// scanPurchaseOrder reads a purchase order's purchaseOrderColumns
+func scanPurchaseOrder(row rowScanner) (*domain.PurchaseOrder, error) {
+	order := &domain.PurchaseOrder{}
+	var orderedAt, receivedAt, cancelledAt sql.NullTime
+	err := row.Scan(
+		&order.ID, &order.Supplier, &order.Status, &order.CreatedBy, &order.Notes,
+		&order.CreatedAt, &order.UpdatedAt, &orderedAt, &receivedAt, &cancelledAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, at := range []struct {
+		value sql.NullTime
+		field **time.Time
+	}{
+		{orderedAt, &order.OrderedAt},
+		{receivedAt, &order.ReceivedAt},
+		{cancelledAt, &order.CancelledAt},
+	} {
+		if at.value.Valid {
+			t := at.value.Time
+			*at.field = &t
+		}
+	}
+	return order, nil
+}
+
+// GetPurchaseOrder retrieves a purchase order and its lines by ID
+func (r *postgresRepository) GetPurchaseOrder(ctx context.Context, id string) (*domain.PurchaseOrder, error) {
+	query := `SELECT ` + purchaseOrderColumns + ` FROM purchase_orders WHERE id = $1 AND tenant_id = $2`
+
+	order, err := scanPurchaseOrder(r.db.QueryRowContext(ctx, query, id, tenantID(ctx)))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadPurchaseOrderLines(ctx, []*domain.PurchaseOrder{order}); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// loadPurchaseOrderLines retrieves the lines of purchase orders, in line
+// order
+func (r *postgresRepository) loadPurchaseOrderLines(ctx context.Context, orders []*domain.PurchaseOrder) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	byID := make(map[string]*domain.PurchaseOrder, len(orders))
+	ids := make([]string, len(orders))
+	for i, order := range orders {
+		order.Lines = []domain.PurchaseOrderLine{}
+		byID[order.ID] = order
+		ids[i] = order.ID
+	}
+
+	query := `
+		SELECT purchase_order_id, product_id, sku, quantity, unit_cost_minor, cost_currency
+		FROM purchase_order_lines
+		WHERE purchase_order_id = ANY($1)
+		ORDER BY purchase_order_id, line_number
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderID, currency string
+		var line domain.PurchaseOrderLine
+		var unitCost sql.NullInt64
+		if err := rows.Scan(&orderID, &line.ProductID, &line.SKU, &line.Quantity, &unitCost, &currency); err != nil {
+			return err
+		}
+		line.UnitCost = scanCost(unitCost, currency)
+		order := byID[orderID]
+		order.Lines = append(order.Lines, line)
+	}
+
+	return rows.Err()
+}
+
+// UpdatePurchaseOrder updates a purchase order's status
+func (r *postgresRepository) UpdatePurchaseOrder(ctx context.Context, order *domain.PurchaseOrder) error {
+	order.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE purchase_orders
+		SET status = $1, ordered_at = $2, received_at = $3, cancelled_at = $4, updated_at = $5
+		WHERE id = $6 AND tenant_id = $7
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		order.Status, order.OrderedAt, order.ReceivedAt, order.CancelledAt, order.UpdatedAt,
+		order.ID, tenantID(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return domain.ErrPurchaseOrderNotFound
+	}
+
+	return nil
+}
+
+// purchaseOrderConditions is the WHERE clause of filter, and its arguments
+// after args
+func purchaseOrderConditions(ctx context.Context, filter PurchaseOrderFilter, args []interface{}) (string, []interface{}) {
+	args = append(args, tenantID(ctx))
+	where := fmt.Sprintf("tenant_id = $%d", len(args))
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	if filter.Supplier != "" {
+		args = append(args, filter.Supplier)
+		where += fmt.Sprintf(" AND supplier = $%d", len(args))
+	}
+	return where, args
+}
+
+// ListPurchaseOrders retrieves up to limit purchase orders after a
+// position, newest first, with their lines
+func (r *postgresRepository) ListPurchaseOrders(ctx context.Context, filter PurchaseOrderFilter, limit int, after *ListPosition) ([]*domain.PurchaseOrder, error) {
+	where, args := purchaseOrderConditions(ctx, filter, []interface{}{limit})
+	if after != nil {
+		args = append(args, after.CreatedAt, after.ID)
+		where += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+
+	query := `SELECT ` + purchaseOrderColumns + ` FROM purchase_orders WHERE ` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []*domain.PurchaseOrder
+	for rows.Next() {
+		order, err := scanPurchaseOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadPurchaseOrderLines(ctx, orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// CountPurchaseOrders returns the number of purchase orders matching
+// filter
+func (r *postgresRepository) CountPurchaseOrders(ctx context.Context, filter PurchaseOrderFilter) (int64, error) {
+	where, args := purchaseOrderConditions(ctx, filter, nil)
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM purchase_orders WHERE "+where, args...).Scan(&count)
+	return count, err
+}
+
+// ProductsOnOrder retrieves the products on open purchase orders
+func (r *postgresRepository) ProductsOnOrder(ctx context.Context) (map[string]bool, error) {
+	query := `
+		SELECT DISTINCT l.product_id
+		FROM purchase_order_lines l
+		JOIN purchase_orders o ON o.id = l.purchase_order_id
+		WHERE o.tenant_id = $1 AND o.status IN ('draft', 'ordered')
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, tenantID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := make(map[string]bool)
+	for rows.Next() {
+		var productID string
+		if err := rows.Scan(&productID); err != nil {
+			return nil, err
+		}
+		products[productID] = true
+	}
+
+	return products, rows.Err()
+}
+
+// CreateCycleCount creates a cycle count and its lines in a transaction,
+// or the current one within InTx
+func (r *postgresRepository) CreateCycleCount(ctx context.Context, count *domain.CycleCount) error {
+	if count.ID == "" {
+		count.ID = uuid.New().String()
+	}
+	now := time.Now()
+	count.CreatedAt = now
+	count.UpdatedAt = now
+
+	return r.inLockingTx(ctx, func(repo *postgresRepository) error {
+		query := `
+			INSERT INTO cycle_counts (id, location, status, created_by, notes, created_at, updated_at, tenant_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`
+
+		_, err := repo.db.ExecContext(ctx, query,
+			count.ID, count.Location, count.Status, count.CreatedBy, count.Notes,
+			count.CreatedAt, count.UpdatedAt, tenantID(ctx),
+		)
+		if err != nil {
+			return err
+		}
+
+		for i, line := range count.Lines {
+			_, err := repo.db.ExecContext(ctx, `
+				INSERT INTO cycle_count_lines (cycle_count_id, line_number, product_id, sku, expected_quantity)
+				VALUES ($1, $2, $3, $4, $5)
+			`, count.ID, i+1, line.ProductID, line.SKU, line.ExpectedQuantity)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// cycleCountColumns are the columns scanCycleCount reads
+const cycleCountColumns = `id, location, status, created_by, applied_by, notes, created_at, updated_at,
+	applied_at, cancelled_at`
+
+// scanCycleCount reads a cycle count's cycleCountColumns
+func scanCycleCount(row rowScanner) (*domain.CycleCount, error) {
+	count := &domain.CycleCount{}
+	var appliedAt, cancelledAt sql.NullTime
+	err := row.Scan(
+		&count.ID, &count.Location, &count.Status, &count.CreatedBy, &count.AppliedBy, &count.Notes,
+		&count.CreatedAt, &count.UpdatedAt, &appliedAt, &cancelledAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if appliedAt.Valid {
+		count.AppliedAt = &appliedAt.Time
+	}
+	if cancelledAt.Valid {
+		count.CancelledAt = &cancelledAt.Time
+	}
+	return count, nil
+}
+
+// GetCycleCount retrieves a cycle count and its lines by ID
+func (r *postgresRepository) GetCycleCount(ctx context.Context, id string) (*domain.CycleCount, error) {
+	query := `SELECT ` + cycleCountColumns + ` FROM cycle_counts WHERE id = $1 AND tenant_id = $2`
+
+	count, err := scanCycleCount(r.db.QueryRowContext(ctx, query, id, tenantID(ctx)))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrCycleCountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadCycleCountLines(ctx, []*domain.CycleCount{count}); err != nil {
+		return nil, err
+	}
+	return count, nil
+}
+
+// loadCycleCountLines retrieves the lines of cycle counts, in line order
+func (r *postgresRepository) loadCycleCountLines(ctx context.Context, counts []*domain.CycleCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	byID := make(map[string]*domain.CycleCount, len(counts))
+	ids := make([]string, len(counts))
+	for i, count := range counts {
+		count.Lines = []domain.CycleCountLine{}
+		byID[count.ID] = count
+		ids[i] = count.ID
+	}
+
+	query := `
+		SELECT cycle_count_id, product_id, sku, expected_quantity, counted_quantity, variance,
+			counted_by, counted_at, approved, adjustment_id
+		FROM cycle_count_lines
+		WHERE cycle_count_id = ANY($1)
+		ORDER BY cycle_count_id, line_number
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var countID string
+		var line domain.CycleCountLine
+		var counted sql.NullInt64
+		var countedAt sql.NullTime
+		err := rows.Scan(
+			&countID, &line.ProductID, &line.SKU, &line.ExpectedQuantity, &counted, &line.Variance,
+			&line.CountedBy, &countedAt, &line.Approved, &line.AdjustmentID,
+		)
+		if err != nil {
+			return err
+		}
+		if counted.Valid {
+			quantity := int(counted.Int64)
+			line.CountedQuantity = &quantity
+		}
+		if countedAt.Valid {
+			line.CountedAt = &countedAt.Time
+		}
+		count := byID[countID]
+		count.Lines = append(count.Lines, line)
+	}
+
+	return rows.Err()
+}
+
+// UpdateCycleCount updates a cycle count's status and its lines' counts
+// in a transaction, or the current one within InTx
+func (r *postgresRepository) UpdateCycleCount(ctx context.Context, count *domain.CycleCount) error {
+	count.UpdatedAt = time.Now()
+
+	return r.inLockingTx(ctx, func(repo *postgresRepository) error {
+		query := `
+			UPDATE cycle_counts
+			SET status = $1, applied_by = $2, applied_at = $3, cancelled_at = $4, updated_at = $5
+			WHERE id = $6 AND tenant_id = $7
+		`
+
+		result, err := repo.db.ExecContext(ctx, query,
+			count.Status, count.AppliedBy, count.AppliedAt, count.CancelledAt, count.UpdatedAt,
+			count.ID, tenantID(ctx),
+		)
+		if err != nil {
+			return err
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return domain.ErrCycleCountNotFound
+		}
+
+		for i, line := range count.Lines {
+			_, err := repo.db.ExecContext(ctx, `
+				UPDATE cycle_count_lines
+				SET expected_quantity = $1, counted_quantity = $2, variance = $3, counted_by = $4,
+					counted_at = $5, approved = $6, adjustment_id = $7
+				WHERE cycle_count_id = $8 AND line_number = $9
+			`, line.ExpectedQuantity, line.CountedQuantity, line.Variance, line.CountedBy,
+				line.CountedAt, line.Approved, line.AdjustmentID, count.ID, i+1)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// cycleCountConditions is the WHERE clause of filter, and its arguments
+// after args
+func cycleCountConditions(ctx context.Context, filter CycleCountFilter, args []interface{}) (string, []interface{}) {
+	args = append(args, tenantID(ctx))
+	where := fmt.Sprintf("tenant_id = $%d", len(args))
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	if filter.Location != "" {
+		args = append(args, filter.Location)
+		where += fmt.Sprintf(" AND location = $%d", len(args))
+	}
+	return where, args
+}
+
+// ListCycleCounts retrieves up to limit cycle counts after a position,
+// newest first, with their lines
+func (r *postgresRepository) ListCycleCounts(ctx context.Context, filter CycleCountFilter, limit int, after *ListPosition) ([]*domain.CycleCount, error) {
+	where, args := cycleCountConditions(ctx, filter, []interface{}{limit})
+	if after != nil {
+		args = append(args, after.CreatedAt, after.ID)
+		where += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+
+	query := `SELECT ` + cycleCountColumns + ` FROM cycle_counts WHERE ` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []*domain.CycleCount
+	for rows.Next() {
+		count, err := scanCycleCount(rows)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadCycleCountLines(ctx, counts); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// CountCycleCounts returns the number of cycle counts matching filter
+func (r *postgresRepository) CountCycleCounts(ctx context.Context, filter CycleCountFilter) (int64, error) {
+	where, args := cycleCountConditions(ctx, filter, nil)
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM cycle_counts WHERE "+where, args...).Scan(&count)
+	return count, err
+}
+
+// CreateWebhook creates a webhook
+func (r *postgresRepository) CreateWebhook(ctx context.Context, webhook *domain.Webhook) error {
+	if webhook.ID == "" {
+		webhook.ID = uuid.New().String()
+	}
+	now := time.Now()
+	webhook.CreatedAt = now
+	webhook.UpdatedAt = now
+
+	query := `
+		INSERT INTO webhooks (id, url, secret, event_types, active, created_by, created_at, updated_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		webhook.ID, webhook.URL, webhook.Secret, pq.Array(webhook.EventTypes), webhook.Active,
+		webhook.CreatedBy, webhook.CreatedAt, webhook.UpdatedAt, tenantID(ctx),
+	)
+
+	return err
+}
+
+// webhookColumns are the columns scanWebhook reads
+const webhookColumns = `id, url, secret, event_types, active, created_by, created_at, updated_at`
+
+// scanWebhook reads a webhook's webhookColumns
+func scanWebhook(row rowScanner) (*domain.Webhook, error) {
+	webhook := &domain.Webhook{}
+	err := row.Scan(
+		&webhook.ID, &webhook.URL, &webhook.Secret, pq.Array(&webhook.EventTypes), &webhook.Active,
+		&webhook.CreatedBy, &webhook.CreatedAt, &webhook.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// GetWebhook retrieves a webhook by ID
+func (r *postgresRepository) GetWebhook(ctx context.Context, id string) (*domain.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1 AND tenant_id = $2`
+
+	webhook, err := scanWebhook(r.db.QueryRowContext(ctx, query, id, tenantID(ctx)))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrWebhookNotFound
+	}
+
+	return webhook, err
+}
+
+// UpdateWebhook updates a webhook's URL, event types and whether it is
+// active
+func (r *postgresRepository) UpdateWebhook(ctx context.Context, webhook *domain.Webhook) error {
+	webhook.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE webhooks
+		SET url = $1, event_types = $2, active = $3, updated_at = $4
+		WHERE id = $5 AND tenant_id = $6
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		webhook.URL, pq.Array(webhook.EventTypes), webhook.Active, webhook.UpdatedAt,
+		webhook.ID, tenantID(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return domain.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// DeleteWebhook deletes a webhook and its deliveries
+func (r *postgresRepository) DeleteWebhook(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1 AND tenant_id = $2`, id, tenantID(ctx))
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return domain.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// ListWebhooks retrieves every webhook, newest first
+func (r *postgresRepository) ListWebhooks(ctx context.Context) ([]*domain.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE tenant_id = $1 ORDER BY created_at DESC, id DESC`
+
+	return r.queryWebhooks(ctx, query, tenantID(ctx))
+}
+
+// ActiveWebhooks retrieves the active webhooks subscribed to eventType
+func (r *postgresRepository) ActiveWebhooks(ctx context.Context, eventType string) ([]*domain.Webhook, error) {
+	query := `
+		SELECT ` + webhookColumns + ` FROM webhooks
+		WHERE tenant_id = $1 AND active AND $2 = ANY(event_types)
+	`
+
+	return r.queryWebhooks(ctx, query, tenantID(ctx), eventType)
+}
+
+func (r *postgresRepository) queryWebhooks(ctx context.Context, query string, args ...interface{}) ([]*domain.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []*domain.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// CreateWebhookDelivery creates a delivery
+func (r *postgresRepository) CreateWebhookDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	if delivery.ID == "" {
+		delivery.ID = uuid.New().String()
+	}
+	delivery.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO webhook_deliveries (
+			id, webhook_id, event_type, payload, status, attempts, next_attempt_at, created_at, tenant_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		delivery.ID, delivery.WebhookID, delivery.EventType, delivery.Payload, delivery.Status,
+		delivery.Attempts, delivery.NextAttemptAt, delivery.CreatedAt, tenantID(ctx),
+	)
+
+	return err
+}
+
+// webhookDeliveryColumns are the columns scanWebhookDelivery reads
+const webhookDeliveryColumns = `id, webhook_id, event_type, payload, status, attempts, next_attempt_at,
+	last_error, created_at, delivered_at`
+
+// scanWebhookDelivery reads a delivery's webhookDeliveryColumns
+func scanWebhookDelivery(row rowScanner) (*domain.WebhookDelivery, error) {
+	delivery := &domain.WebhookDelivery{}
+	var nextAttemptAt, deliveredAt sql.NullTime
+	err := row.Scan(
+		&delivery.ID, &delivery.WebhookID, &delivery.EventType, &delivery.Payload, &delivery.Status,
+		&delivery.Attempts, &nextAttemptAt, &delivery.LastError, &delivery.CreatedAt, &deliveredAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if nextAttemptAt.Valid {
+		delivery.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	return delivery, nil
+}
+
+// DueWebhookDeliveries retrieves up to limit pending deliveries whose next
+// attempt is due, oldest first
+func (r *postgresRepository) DueWebhookDeliveries(ctx context.Context, limit int) ([]*domain.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries
+		WHERE tenant_id = $1 AND status = 'pending' AND next_attempt_at <= $2
+		ORDER BY next_attempt_at, id
+		LIMIT $3
+	`
+
+	return r.queryWebhookDeliveries(ctx, false, query, tenantID(ctx), time.Now(), limit)
+}
+
+// RecordWebhookAttempt saves a delivery's status and its latest attempt in
+// a transaction, or the current one within InTx
+func (r *postgresRepository) RecordWebhookAttempt(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	if len(delivery.History) == 0 {
+		return fmt.Errorf("delivery %s has no attempts", delivery.ID)
+	}
+	attempt := delivery.History[len(delivery.History)-1]
+
+	return r.inLockingTx(ctx, func(repo *postgresRepository) error {
+		query := `
+			UPDATE webhook_deliveries
+			SET status = $1, attempts = $2, next_attempt_at = $3, last_error = $4, delivered_at = $5
+			WHERE id = $6 AND tenant_id = $7
+		`
+
+		_, err := repo.db.ExecContext(ctx, query,
+			delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.LastError, delivery.DeliveredAt,
+			delivery.ID, tenantID(ctx),
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = repo.db.ExecContext(ctx, `
+			INSERT INTO webhook_attempts (delivery_id, attempt, status_code, error, duration_ms, attempted_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, delivery.ID, attempt.Attempt, attempt.StatusCode, attempt.Error, attempt.DurationMS, attempt.AttemptedAt)
+		return err
+	})
+}
+
+// ListWebhookDeliveries retrieves up to limit of a webhook's deliveries
+// after a position, newest first, with their attempts
+func (r *postgresRepository) ListWebhookDeliveries(ctx context.Context, webhookID string, limit int, after *ListPosition) ([]*domain.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries
+		WHERE webhook_id = $2 AND tenant_id = $3
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1
+	`
+	args := []interface{}{limit, webhookID, tenantID(ctx)}
+	if after != nil {
+		query = `
+			SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries
+			WHERE webhook_id = $2 AND tenant_id = $3 AND (created_at, id) < ($4, $5)
+			ORDER BY created_at DESC, id DESC
+			LIMIT $1
+		`
+		args = append(args, after.CreatedAt, after.ID)
+	}
+
+	return r.queryWebhookDeliveries(ctx, true, query, args...)
+}
+
+// CountWebhookDeliveries returns the number of a webhook's deliveries
+func (r *postgresRepository) CountWebhookDeliveries(ctx context.Context, webhookID string) (int64, error) {
+	var count int64
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = $1 AND tenant_id = $2",
+		webhookID, tenantID(ctx),
+	).Scan(&count)
+	return count, err
+}
+
+// DueWebhookTenants lists the tenants with deliveries due
+func (r *postgresRepository) DueWebhookTenants(ctx context.Context) ([]string, error) {
+	query := `
+		SELECT DISTINCT tenant_id
+		FROM webhook_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= $1
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tenants []string
+	for rows.Next() {
+		var tenant string
+		if err := rows.Scan(&tenant); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, rows.Err()
+}
+
+// queryWebhookDeliveries retrieves deliveries, and with history their
+// attempts, oldest first
+func (r *postgresRepository) queryWebhookDeliveries(ctx context.Context, history bool, query string, args ...interface{}) ([]*domain.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*domain.WebhookDelivery
+	byID := make(map[string]*domain.WebhookDelivery)
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		delivery.History = []domain.WebhookAttempt{}
+		deliveries = append(deliveries, delivery)
+		byID[delivery.ID] = delivery
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !history || len(deliveries) == 0 {
+		return deliveries, nil
+	}
+
+	ids := make([]string, len(deliveries))
+	for i, delivery := range deliveries {
+		ids[i] = delivery.ID
+	}
+	attempts, err := r.db.QueryContext(ctx, `
+		SELECT delivery_id, attempt, status_code, error, duration_ms, attempted_at
+		FROM webhook_attempts
+		WHERE delivery_id = ANY($1)
+		ORDER BY delivery_id, attempt
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer attempts.Close()
+
+	for attempts.Next() {
+		var deliveryID string
+		var attempt domain.WebhookAttempt
+		err := attempts.Scan(
+			&deliveryID, &attempt.Attempt, &attempt.StatusCode, &attempt.Error,
+			&attempt.DurationMS, &attempt.AttemptedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		delivery := byID[deliveryID]
+		delivery.History = append(delivery.History, attempt)
+	}
+
+	return deliveries, attempts.Err()
+}
+
+// lotColumns are the columns scanLot reads
+const lotColumns = `id, item_id, product_id, lot_number, quantity, expires_at, expired, expiry_notified,
+	created_at, updated_at`
+
+// scanLot reads a lot's lotColumns
+func scanLot(row rowScanner) (*domain.Lot, error) {
+	lot := &domain.Lot{}
+	var expiresAt sql.NullTime
+	err := row.Scan(
+		&lot.ID, &lot.ItemID, &lot.ProductID, &lot.Number, &lot.Quantity,
+		&expiresAt, &lot.Expired, &lot.ExpiryNotified, &lot.CreatedAt, &lot.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		lot.ExpiresAt = &expiresAt.Time
+	}
+	return lot, nil
+}
+
+// LockLot retrieves a product's lot by number, locking its row until the
+// end of the transaction
+func (r *postgresRepository) LockLot(ctx context.Context, productID, number string) (*domain.Lot, error) {
+	query := `
+		SELECT ` + lotColumns + `
+		FROM inventory_lots
+		WHERE product_id = $1 AND lot_number = $2 AND tenant_id = $3
+		FOR UPDATE
+	`
+
+	lot, err := scanLot(r.db.QueryRowContext(ctx, query, productID, number, tenantID(ctx)))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrLotNotFound
+	}
+	return lot, err
+}
+
+// CreateLot creates a lot
+func (r *postgresRepository) CreateLot(ctx context.Context, lot *domain.Lot) error {
+	if lot.ID == "" {
+		lot.ID = uuid.New().String()
+	}
+	now := time.Now()
+	lot.CreatedAt = now
+	lot.UpdatedAt = now
+
+	query := `
+		INSERT INTO inventory_lots (
+			id, item_id, product_id, lot_number, quantity, expires_at, expired, expiry_notified,
+			created_at, updated_at, tenant_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		lot.ID, lot.ItemID, lot.ProductID, lot.Number, lot.Quantity, lot.ExpiresAt, lot.Expired, lot.ExpiryNotified,
+		lot.CreatedAt, lot.UpdatedAt, tenantID(ctx),
+	)
+	return err
+}
+
+// UpdateLot updates a lot's quantity and expiry
+func (r *postgresRepository) UpdateLot(ctx context.Context, lot *domain.Lot) error {
+	lot.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE inventory_lots
+		SET quantity = $1, expires_at = $2, expired = $3, expiry_notified = $4, updated_at = $5
+		WHERE id = $6 AND tenant_id = $7
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		lot.Quantity, lot.ExpiresAt, lot.Expired, lot.ExpiryNotified, lot.UpdatedAt, lot.ID, tenantID(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return domain.ErrLotNotFound
+	}
+
+	return nil
+}
+
+// ListLots retrieves an item's lots, oldest first
+func (r *postgresRepository) ListLots(ctx context.Context, itemID string) ([]*domain.Lot, error) {
+	query := `
+		SELECT ` + lotColumns + `
+		FROM inventory_lots
+		WHERE item_id = $1 AND tenant_id = $2
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, itemID, tenantID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lots := []*domain.Lot{}
+	for rows.Next() {
+		lot, err := scanLot(rows)
+		if err != nil {
+			return nil, err
+		}
+		lots = append(lots, lot)
+	}
+
+	return lots, rows.Err()
+}
+
+// expiringLotConditions selects the lots with stock that expired by now
+// and haven't been expired yet, or will expire by before and haven't been
+// announced yet
+const expiringLotConditions = `quantity > 0 AND expires_at IS NOT NULL AND NOT expired
+	AND (expires_at <= $1 OR (NOT expiry_notified AND expires_at <= $2))`
+
+// GetExpiringLots retrieves the lots that expired and the lots that will
+// expire by before which are due to be expired or announced, soonest
+// expiring first
+func (r *postgresRepository) GetExpiringLots(ctx context.Context, before time.Time) ([]*domain.Lot, error) {
+	query := `
+		SELECT ` + lotColumns + `
+		FROM inventory_lots
+		WHERE ` + expiringLotConditions + ` AND tenant_id = $3
+		ORDER BY expires_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, time.Now(), before, tenantID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lots []*domain.Lot
+	for rows.Next() {
+		lot, err := scanLot(rows)
+		if err != nil {
+			return nil, err
+		}
+		lots = append(lots, lot)
+	}
+
+	return lots, rows.Err()
+}
+
+// ExpiringLotTenants lists the tenants with lots GetExpiringLots retrieves
+func (r *postgresRepository) ExpiringLotTenants(ctx context.Context, before time.Time) ([]string, error) {
+	query := `
+		SELECT DISTINCT tenant_id
+		FROM inventory_lots
+		WHERE ` + expiringLotConditions
+
+	rows, err := r.db.QueryContext(ctx, query, time.Now(), before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tenants []string
+	for rows.Next() {
+		var tenant string
+		if err := rows.Scan(&tenant); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, rows.Err()
+}
+
+// ReceiveSerials records serial numbers in stock in a lot. Units removed
+// before, e.g. returned, are received again; ErrSerialInStock is returned
+// if one is in stock already.
+func (r *postgresRepository) ReceiveSerials(ctx context.Context, lot *domain.Lot, serials []string) error {
+	if len(serials) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO inventory_serials (tenant_id, product_id, serial_number, item_id, lot_id, status, created_at, updated_at)
+		SELECT $1, $2, serial, $3, $4, 'in_stock', $5, $5
+		FROM unnest($6::text[]) AS serial
+		ON CONFLICT (tenant_id, product_id, serial_number) DO UPDATE
+		SET item_id = EXCLUDED.item_id, lot_id = EXCLUDED.lot_id, status = 'in_stock', updated_at = EXCLUDED.updated_at
+		WHERE inventory_serials.status <> 'in_stock'
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		tenantID(ctx), lot.ProductID, lot.ItemID, lot.ID, time.Now(), pq.Array(serials),
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows != int64(len(serials)) {
+		return domain.ErrSerialInStock
+	}
+
+	return nil
+}
+
+// RemoveSerials takes serial numbers out of a lot's stock, returning
+// ErrSerialNotInLot unless each was in it
+func (r *postgresRepository) RemoveSerials(ctx context.Context, lot *domain.Lot, serials []string) error {
+	if len(serials) == 0 {
+		return nil
+	}
+
+	query := `
+		UPDATE inventory_serials
+		SET status = 'removed', updated_at = $1
+		WHERE tenant_id = $2 AND product_id = $3 AND lot_id = $4 AND status = 'in_stock'
+			AND serial_number = ANY($5)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), tenantID(ctx), lot.ProductID, lot.ID, pq.Array(serials))
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows != int64(len(serials)) {
+		return domain.ErrSerialNotInLot
+	}
+
+	return nil
+}
+
+// GetSerials retrieves the units with a serial number, of any product
+func (r *postgresRepository) GetSerials(ctx context.Context, number string) ([]*domain.Serial, error) {
+	query := `
+		SELECT s.serial_number, s.item_id, s.product_id, s.lot_id, l.lot_number, s.status, s.created_at, s.updated_at
+		FROM inventory_serials s
+		JOIN inventory_lots l ON l.id = s.lot_id
+		WHERE s.serial_number = $1 AND s.tenant_id = $2
+		ORDER BY s.product_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, number, tenantID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var serials []*domain.Serial
+	for rows.Next() {
+		serial := &domain.Serial{}
+		err := rows.Scan(
+			&serial.Number, &serial.ItemID, &serial.ProductID, &serial.LotID, &serial.LotNumber,
+			&serial.Status, &serial.CreatedAt, &serial.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		serials = append(serials, serial)
+	}
+
+	return serials, rows.Err()
+}
+
+// RecordLotMovement records stock received into or removed from a lot, in
+// the transaction of the change
+func (r *postgresRepository) RecordLotMovement(ctx context.Context, movement *domain.LotMovement) error {
+	if movement.ID == "" {
+		movement.ID = uuid.New().String()
+	}
+	movement.OccurredAt = time.Now()
+	if movement.Serials == nil {
+		movement.Serials = []string{}
+	}
+
+	query := `
+		INSERT INTO lot_movements (
+			id, lot_id, lot_number, item_id, product_id, quantity, serial_numbers,
+			reference_type, reference_id, actor, occurred_at, tenant_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		movement.ID, movement.LotID, movement.LotNumber, movement.ItemID, movement.ProductID,
+		movement.Quantity, pq.Array(movement.Serials),
+		movement.ReferenceType, movement.ReferenceID, movement.Actor, movement.OccurredAt, tenantID(ctx),
+	)
+	return err
+}
+
+// lotMovementConditions is the WHERE clause of filter, and its arguments
+// after args
+func lotMovementConditions(ctx context.Context, filter LotMovementFilter, args []interface{}) (string, []interface{}) {
+	args = append(args, tenantID(ctx), filter.LotNumber)
+	where := fmt.Sprintf("tenant_id = $%d AND lot_number = $%d", len(args)-1, len(args))
+	if filter.ProductID != "" {
+		args = append(args, filter.ProductID)
+		where += fmt.Sprintf(" AND product_id = $%d", len(args))
+	}
+	return where, args
+}
+
+// ListLotMovements retrieves up to limit movements matching filter after
+// a position, newest first
+func (r *postgresRepository) ListLotMovements(ctx context.Context, filter LotMovementFilter, limit int, after *ListPosition) ([]*domain.LotMovement, error) {
+	where, args := lotMovementConditions(ctx, filter, []interface{}{limit})
+	if after != nil {
+		args = append(args, after.CreatedAt, after.ID)
+		where += fmt.Sprintf(" AND (occurred_at, id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+
+	query := `
+		SELECT id, lot_id, lot_number, item_id, product_id, quantity, serial_numbers,
+			   reference_type, reference_id, actor, occurred_at
+		FROM lot_movements
+		WHERE ` + where + `
+		ORDER BY occurred_at DESC, id DESC
+		LIMIT $1
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movements []*domain.LotMovement
+	for rows.Next() {
+		movement := &domain.LotMovement{}
+		err := rows.Scan(
+			&movement.ID, &movement.LotID, &movement.LotNumber, &movement.ItemID, &movement.ProductID,
+			&movement.Quantity, pq.Array(&movement.Serials),
+			&movement.ReferenceType, &movement.ReferenceID, &movement.Actor, &movement.OccurredAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movements = append(movements, movement)
+	}
+
+	return movements, rows.Err()
+}
+
+// CountLotMovements returns the number of movements matching filter
+func (r *postgresRepository) CountLotMovements(ctx context.Context, filter LotMovementFilter) (int64, error) {
+	where, args := lotMovementConditions(ctx, filter, nil)
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM lot_movements WHERE "+where, args...).Scan(&count)
+	return count, err
+}
+
+// GetLowStockItems retrieves items with low stock
+func (r *postgresRepository) GetLowStockItems(ctx context.Context) ([]*domain.InventoryItem, error) {
+	query := `
+		SELECT ` + itemColumns + `
+		FROM inventory_items
+		WHERE tenant_id = $1 AND (status = 'low_stock' OR available_quantity <= reorder_level)
+		ORDER BY available_quantity ASC
+	`
+
+	return r.queryInventoryItems(ctx, query, tenantID(ctx))
+}
+
+// GetOutOfStockItems retrieves out of stock items
+func (r *postgresRepository) GetOutOfStockItems(ctx context.Context) ([]*domain.InventoryItem, error) {
+	query := `
+		SELECT ` + itemColumns + `
+		FROM inventory_items
+		WHERE tenant_id = $1 AND (status = 'out_of_stock' OR available_quantity = 0)
+	`
+
+	return r.queryInventoryItems(ctx, query, tenantID(ctx))
+}
+
+// LowStockTenants lists the tenants with items at their reorder level
+func (r *postgresRepository) LowStockTenants(ctx context.Context) ([]string, error) {
+	query := `
+		SELECT DISTINCT tenant_id
+		FROM inventory_items
+		WHERE available_quantity <= reorder_level
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tenants []string
+	for rows.Next() {
+		var tenant string
+		if err := rows.Scan(&tenant); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, rows.Err()
+}
+
+// Helper methods
+
+func (r *postgresRepository) queryReservations(ctx context.Context, query string, args ...interface{}) ([]*domain.Reservation, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reservations []*domain.Reservation
+	for rows.Next() {
+		res := &domain.Reservation{}
+		err := rows.Scan(
+			&res.ID, &res.ProductID, &res.Quantity,
+			&res.OrderID, &res.CustomerID, &res.ExpiresAt,
+			&res.Status, &res.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, res)
+	}
+
+	return reservations, rows.Err()
+}
+
+func (r *postgresRepository) queryInventoryItems(ctx context.Context, query string, args ...interface{}) ([]*domain.InventoryItem, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*domain.InventoryItem
+	for rows.Next() {
+		item, err := scanItem(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -6,10 +6,23 @@ import (
 	"fmt"
 	"time"
 
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
 	"github.com/ecommerce/inventory-service/internal/domain"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
+// unlockScript deletes a lock only while it holds the caller's token, so a
+// lock that expired and was taken by someone else stays theirs
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// redisRepository caches items under keys prefixed with their tenant, so
+// tenants with the same product IDs don't share entries
 type redisRepository struct {
 	client *redis.Client
 }
@@ -19,13 +32,18 @@ func NewRedisRepository(client *redis.Client) CacheRepository {
 	return &redisRepository{client: client}
 }
 
-func (r *redisRepository) cacheKey(key string) string {
-	return fmt.Sprintf("inventory:%s", key)
+func (r *redisRepository) cacheKey(ctx context.Context, key string) string {
+	return fmt.Sprintf("inventory:%s:%s", sharedauth.TenantFromContext(ctx), key)
+}
+
+// lockKey is apart from the cache keys, so FlushAll leaves locks alone
+func (r *redisRepository) lockKey(ctx context.Context, key string) string {
+	return fmt.Sprintf("inventory-lock:%s:%s", sharedauth.TenantFromContext(ctx), key)
 }
 
 // Get retrieves an item from cache
 func (r *redisRepository) Get(ctx context.Context, key string) (*domain.InventoryItem, error) {
-	data, err := r.client.Get(ctx, r.cacheKey(key)).Bytes()
+	data, err := r.client.Get(ctx, r.cacheKey(ctx, key)).Bytes()
 	if err == redis.Nil {
 		return nil, nil // Cache miss
 	}
@@ -41,6 +59,33 @@ func (r *redisRepository) Get(ctx context.Context, key string) (*domain.Inventor
 	return &item, nil
 }
 
+// GetWithTTL retrieves an item from cache and how long until it expires,
+// in one round trip
+func (r *redisRepository) GetWithTTL(ctx context.Context, key string) (*domain.InventoryItem, time.Duration, error) {
+	cacheKey := r.cacheKey(ctx, key)
+	pipe := r.client.Pipeline()
+	get := pipe.Get(ctx, cacheKey)
+	ttl := pipe.PTTL(ctx, cacheKey)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, 0, err
+	}
+
+	data, err := get.Bytes()
+	if err == redis.Nil {
+		return nil, 0, nil // Cache miss
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var item domain.InventoryItem
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, 0, err
+	}
+
+	return &item, ttl.Val(), nil
+}
+
 // Set stores an item in cache
 func (r *redisRepository) Set(ctx context.Context, key string, item *domain.InventoryItem, ttl time.Duration) error {
 	data, err := json.Marshal(item)
@@ -48,17 +93,17 @@ func (r *redisRepository) Set(ctx context.Context, key string, item *domain.Inve
 		return err
 	}
 
-	return r.client.Set(ctx, r.cacheKey(key), data, ttl).Err()
+	return r.client.Set(ctx, r.cacheKey(ctx, key), data, ttl).Err()
 }
 
 // Delete removes an item from cache
 func (r *redisRepository) Delete(ctx context.Context, key string) error {
-	return r.client.Del(ctx, r.cacheKey(key)).Err()
+	return r.client.Del(ctx, r.cacheKey(ctx, key)).Err()
 }
 
-// FlushAll clears all cached inventory items
+// FlushAll clears the cached inventory items of ctx's tenant
 func (r *redisRepository) FlushAll(ctx context.Context) error {
-	pattern := r.cacheKey("*")
+	pattern := r.cacheKey(ctx, "*")
 	iter := r.client.Scan(ctx, 0, pattern, 0).Iterator()
 
 	for iter.Next(ctx) {
@@ -69,3 +114,23 @@ func (r *redisRepository) FlushAll(ctx context.Context) error {
 
 	return iter.Err()
 }
+
+// Lock takes the lock on key for ttl unless it is held
+func (r *redisRepository) Lock(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	token := uuid.New().String()
+	acquired, err := r.client.SetNX(ctx, r.lockKey(ctx, key), token, ttl).Result()
+	if err != nil {
+		return "", false, err
+	}
+	return token, acquired, nil
+}
+
+// Unlock releases the lock on key if token still holds it
+func (r *redisRepository) Unlock(ctx context.Context, key, token string) error {
+	return unlockScript.Run(ctx, r.client, []string{r.lockKey(ctx, key)}, token).Err()
+}
+
+// Ping checks that Redis is reachable
+func (r *redisRepository) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
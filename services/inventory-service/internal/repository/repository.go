@@ -7,16 +7,29 @@ import (
 	"github.com/ecommerce/inventory-service/internal/domain"
 )
 
-// InventoryRepository defines inventory data operations
+// InventoryRepository defines inventory data operations. Operations are
+// scoped to the tenant of their context, see sharedauth.TenantFromContext.
 type InventoryRepository interface {
 	// Inventory Items
 	Create(ctx context.Context, item *domain.InventoryItem) error
 	GetByID(ctx context.Context, id string) (*domain.InventoryItem, error)
 	GetByProductID(ctx context.Context, productID string) (*domain.InventoryItem, error)
 	GetBySKU(ctx context.Context, sku string) (*domain.InventoryItem, error)
-	List(ctx context.Context, limit, offset int) ([]*domain.InventoryItem, error)
+	// List lists up to limit items matching filter after a position,
+	// newest first
+	List(ctx context.Context, filter ItemFilter, limit int, after *ListPosition) ([]*domain.InventoryItem, error)
+	Count(ctx context.Context, filter ItemFilter) (int64, error)
+	// ListStocked retrieves every item with stock matching filter, in SKU
+	// order, e.g. to value it
+	ListStocked(ctx context.Context, filter ItemFilter) ([]*domain.InventoryItem, error)
 	Update(ctx context.Context, item *domain.InventoryItem) error
 	Delete(ctx context.Context, id string) error
+	// UpsertBySKU creates an item for each import, or updates the item of
+	// its SKU, keeping its reservations, in one transaction. Imports that
+	// can't be applied are skipped with their result's Err:
+	// domain.ErrSKUConflict, domain.ErrProductConflict or
+	// domain.ErrBelowReserved.
+	UpsertBySKU(ctx context.Context, imports []ItemImport) ([]ImportResult, error)
 
 	// Reservations
 	CreateReservation(ctx context.Context, reservation *domain.Reservation) error
@@ -26,20 +39,280 @@ type InventoryRepository interface {
 	UpdateReservation(ctx context.Context, reservation *domain.Reservation) error
 	DeleteReservation(ctx context.Context, id string) error
 	GetExpiredReservations(ctx context.Context) ([]*domain.Reservation, error)
+	// ListReservations lists up to limit reservations matching filter after
+	// a position, newest first
+	ListReservations(ctx context.Context, filter ReservationFilter, limit int, after *ListPosition) ([]*domain.Reservation, error)
+	CountReservations(ctx context.Context, filter ReservationFilter) (int64, error)
+	// ReserveWithinTx reserves an item's stock for an order and records the
+	// reservation in one transaction, locking the item's row so concurrent
+	// reservations of it wait their turn rather than oversell. An order
+	// holds one reservation per product: when it already holds the
+	// product's stock, that reservation is returned with created false.
+	// Fails with domain.ErrNotFound, or domain.ErrInsufficientStock along
+	// with the item.
+	ReserveWithinTx(ctx context.Context, req ReservationRequest) (item *domain.InventoryItem, reservation *domain.Reservation, created bool, err error)
+	// ReserveAllWithinTx reserves the stock of every request, e.g. an
+	// order's items, as ReserveWithinTx does, in one transaction: when one
+	// fails, with a *ReservationError, none are reserved
+	ReserveAllWithinTx(ctx context.Context, reqs []ReservationRequest) ([]ReservedStock, error)
+	// ExpiredReservationTenants lists the tenants with expired pending
+	// reservations. Unlike other operations it spans tenants, for the
+	// expiry job.
+	ExpiredReservationTenants(ctx context.Context) ([]string, error)
 
 	// Adjustments
 	CreateAdjustment(ctx context.Context, adjustment *domain.InventoryAdjustment) error
 	GetAdjustmentsByProductID(ctx context.Context, productID string, limit int) ([]*domain.InventoryAdjustment, error)
+	// RecordRestockedReturn records that an order line's return was checked
+	// back in by the adjustment, in the restock's transaction. Fails with
+	// domain.ErrReturnRestocked when the line's return already was.
+	RecordRestockedReturn(ctx context.Context, orderID, lineID, adjustmentID string) error
+
+	// History: RecordHistory records a change to an item, in the
+	// transaction of the change, and ListHistory lists up to limit of an
+	// item's changes after a position, newest first
+	RecordHistory(ctx context.Context, event *domain.HistoryEvent) error
+	ListHistory(ctx context.Context, itemID string, limit int, after *ListPosition) ([]*domain.HistoryEvent, error)
+	CountHistory(ctx context.Context, itemID string) (int64, error)
+
+	// Transfers
+	CreateTransfer(ctx context.Context, transfer *domain.Transfer) error
+	GetTransfer(ctx context.Context, id string) (*domain.Transfer, error)
+	UpdateTransfer(ctx context.Context, transfer *domain.Transfer) error
+	// ListTransfers lists up to limit transfers matching filter after a
+	// position, newest first
+	ListTransfers(ctx context.Context, filter TransferFilter, limit int, after *ListPosition) ([]*domain.Transfer, error)
+	CountTransfers(ctx context.Context, filter TransferFilter) (int64, error)
+
+	// Stock by location: GetLocationStock returns a product's stock away
+	// from its item's location, by location, and SetLocationStock sets it
+	GetLocationStock(ctx context.Context, productID string) (map[string]int, error)
+	SetLocationStock(ctx context.Context, productID, location string, quantity int) error
+
+	// Purchase orders: CreatePurchaseOrder creates one with its lines, and
+	// UpdatePurchaseOrder updates its status
+	CreatePurchaseOrder(ctx context.Context, order *domain.PurchaseOrder) error
+	GetPurchaseOrder(ctx context.Context, id string) (*domain.PurchaseOrder, error)
+	UpdatePurchaseOrder(ctx context.Context, order *domain.PurchaseOrder) error
+	// ListPurchaseOrders lists up to limit purchase orders matching filter
+	// after a position, newest first
+	ListPurchaseOrders(ctx context.Context, filter PurchaseOrderFilter, limit int, after *ListPosition) ([]*domain.PurchaseOrder, error)
+	CountPurchaseOrders(ctx context.Context, filter PurchaseOrderFilter) (int64, error)
+	// ProductsOnOrder returns the products on draft or ordered purchase
+	// orders
+	ProductsOnOrder(ctx context.Context) (map[string]bool, error)
+
+	// Cycle counts: CreateCycleCount creates one with its lines, and
+	// UpdateCycleCount updates its status and its lines' counts
+	CreateCycleCount(ctx context.Context, count *domain.CycleCount) error
+	GetCycleCount(ctx context.Context, id string) (*domain.CycleCount, error)
+	UpdateCycleCount(ctx context.Context, count *domain.CycleCount) error
+	// ListCycleCounts lists up to limit cycle counts matching filter after a
+	// position, newest first
+	ListCycleCounts(ctx context.Context, filter CycleCountFilter, limit int, after *ListPosition) ([]*domain.CycleCount, error)
+	CountCycleCounts(ctx context.Context, filter CycleCountFilter) (int64, error)
+
+	// Lots: LockLot retrieves a product's lot by number, locking it until
+	// the end of the transaction, and ReceiveSerials and RemoveSerials move
+	// a lot's serial-numbered units in and out of stock
+	LockLot(ctx context.Context, productID, number string) (*domain.Lot, error)
+	CreateLot(ctx context.Context, lot *domain.Lot) error
+	UpdateLot(ctx context.Context, lot *domain.Lot) error
+	ListLots(ctx context.Context, itemID string) ([]*domain.Lot, error)
+	ReceiveSerials(ctx context.Context, lot *domain.Lot, serials []string) error
+	RemoveSerials(ctx context.Context, lot *domain.Lot, serials []string) error
+	// GetExpiringLots retrieves the lots with stock that expired but
+	// haven't been expired yet, and those expiring by before not announced
+	// yet, for the expiry job, and ExpiringLotTenants lists the tenants
+	// with any
+	GetExpiringLots(ctx context.Context, before time.Time) ([]*domain.Lot, error)
+	ExpiringLotTenants(ctx context.Context, before time.Time) ([]string, error)
+	// GetSerials retrieves the units with a serial number, of any product
+	GetSerials(ctx context.Context, number string) ([]*domain.Serial, error)
+	// RecordLotMovement records stock moved in or out of a lot, in the
+	// transaction of the move, and ListLotMovements lists up to limit of
+	// the movements matching filter after a position, newest first
+	RecordLotMovement(ctx context.Context, movement *domain.LotMovement) error
+	ListLotMovements(ctx context.Context, filter LotMovementFilter, limit int, after *ListPosition) ([]*domain.LotMovement, error)
+	CountLotMovements(ctx context.Context, filter LotMovementFilter) (int64, error)
+
+	// Webhooks: GetWebhook includes the webhook's secret, and
+	// ActiveWebhooks lists the active webhooks subscribed to an event type
+	CreateWebhook(ctx context.Context, webhook *domain.Webhook) error
+	GetWebhook(ctx context.Context, id string) (*domain.Webhook, error)
+	UpdateWebhook(ctx context.Context, webhook *domain.Webhook) error
+	DeleteWebhook(ctx context.Context, id string) error
+	ListWebhooks(ctx context.Context) ([]*domain.Webhook, error)
+	ActiveWebhooks(ctx context.Context, eventType string) ([]*domain.Webhook, error)
+
+	// Webhook deliveries: RecordWebhookAttempt saves a delivery's status
+	// and its latest attempt, and ListWebhookDeliveries lists up to limit of
+	// a webhook's deliveries after a position, newest first, with their
+	// attempts
+	CreateWebhookDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	DueWebhookDeliveries(ctx context.Context, limit int) ([]*domain.WebhookDelivery, error)
+	RecordWebhookAttempt(ctx context.Context, delivery *domain.WebhookDelivery) error
+	ListWebhookDeliveries(ctx context.Context, webhookID string, limit int, after *ListPosition) ([]*domain.WebhookDelivery, error)
+	CountWebhookDeliveries(ctx context.Context, webhookID string) (int64, error)
+	// DueWebhookTenants lists the tenants with deliveries due. Unlike other
+	// operations it spans tenants, for the delivery job.
+	DueWebhookTenants(ctx context.Context) ([]string, error)
 
 	// Stock checks
 	GetLowStockItems(ctx context.Context) ([]*domain.InventoryItem, error)
 	GetOutOfStockItems(ctx context.Context) ([]*domain.InventoryItem, error)
+	// LowStockTenants lists the tenants with items at their reorder level.
+	// Unlike other operations it spans tenants, for the reorder job.
+	LowStockTenants(ctx context.Context) ([]string, error)
+
+	// InTx runs fn with a repository whose operations share a transaction,
+	// committed when fn returns nil. fn runs again when the transaction
+	// conflicts with a concurrent one, so it must not have other effects.
+	InTx(ctx context.Context, fn func(repo InventoryRepository) error) error
+	// Ping checks that the database is reachable
+	Ping(ctx context.Context) error
+}
+
+// ListPosition is the last item of a page of inventory items. Items are
+// listed newest first, with ties broken by ID.
+type ListPosition struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+}
+
+// ReservationRequest is stock to reserve with ReserveWithinTx
+type ReservationRequest struct {
+	// ItemID or, without one, ProductID is the item reserved
+	ItemID     string
+	ProductID  string
+	Quantity   int
+	OrderID    string
+	CustomerID string
+	ExpiresAt  time.Time
+}
+
+// key names the request's item, and orders row locks
+func (r ReservationRequest) key() string {
+	if r.ItemID != "" {
+		return "item " + r.ItemID
+	}
+	return "product " + r.ProductID
+}
+
+// ItemFilter narrows an inventory listing; empty fields match every item
+type ItemFilter struct {
+	Status    string
+	Location  string
+	Supplier  string
+	SKUPrefix string
+	// Search matches SKUs containing it, ignoring case
+	Search     string
+	ProductIDs []string
+	// MinQuantity to MaxAvailable bound the item's quantity and available
+	// quantity, inclusively
+	MinQuantity  *int
+	MaxQuantity  *int
+	MinAvailable *int
+	MaxAvailable *int
+}
+
+// ReservationFilter narrows a reservation listing; empty fields match every
+// reservation
+type ReservationFilter struct {
+	OrderID    string
+	CustomerID string
+	ProductID  string
+	Status     string
+}
+
+// TransferFilter narrows a transfer listing; empty fields match every
+// transfer
+type TransferFilter struct {
+	ProductID string
+	Status    string
+	// Location matches transfers from or to it
+	Location string
+}
+
+// PurchaseOrderFilter narrows a purchase order listing; empty fields match
+// every purchase order
+type PurchaseOrderFilter struct {
+	Status   string
+	Supplier string
+}
+
+// CycleCountFilter narrows a cycle count listing; empty fields match every
+// cycle count
+type CycleCountFilter struct {
+	Status   string
+	Location string
+}
+
+// LotMovementFilter selects a lot number's movements, of any product
+// unless ProductID is set
+type LotMovementFilter struct {
+	LotNumber string
+	ProductID string
+}
+
+// ItemImport is an inventory item to create, or update by SKU. Nil fields
+// keep an existing item's values, and are zero for a new item.
+type ItemImport struct {
+	SKU             string
+	ProductID       string
+	Quantity        int
+	ReorderLevel    *int
+	ReorderQuantity *int
+	Location        *string
+	Supplier        *string
+}
+
+// ImportResult is the item UpsertBySKU created or updated, or Err, why it
+// skipped the import. Before is an updated item as it was.
+type ImportResult struct {
+	Item    *domain.InventoryItem
+	Before  domain.InventoryItem
+	Created bool
+	Err     error
+}
+
+// ReservedStock is a reservation ReserveAllWithinTx made, or found the
+// order already held when Created is false, and its item
+type ReservedStock struct {
+	Item        *domain.InventoryItem
+	Reservation *domain.Reservation
+	Created     bool
+}
+
+// ReservationError is the request ReserveAllWithinTx failed to reserve.
+// With domain.ErrInsufficientStock, Item is the item as it stands.
+type ReservationError struct {
+	Request ReservationRequest
+	Item    *domain.InventoryItem
+	Err     error
 }
 
-// CacheRepository defines caching operations
+func (e *ReservationError) Error() string {
+	return "reserving " + e.Request.key() + ": " + e.Err.Error()
+}
+
+func (e *ReservationError) Unwrap() error { return e.Err }
+
+// CacheRepository defines caching operations, scoped to the tenant of
+// their context
 type CacheRepository interface {
 	Get(ctx context.Context, key string) (*domain.InventoryItem, error)
+	// GetWithTTL also returns how long until the item expires
+	GetWithTTL(ctx context.Context, key string) (*domain.InventoryItem, time.Duration, error)
 	Set(ctx context.Context, key string, item *domain.InventoryItem, ttl time.Duration) error
 	Delete(ctx context.Context, key string) error
 	FlushAll(ctx context.Context) error
+
+	// Lock takes the lock on key for ttl unless it is held, returning the
+	// token Unlock releases it with
+	Lock(ctx context.Context, key string, ttl time.Duration) (token string, acquired bool, err error)
+	Unlock(ctx context.Context, key, token string) error
+
+	// Ping checks that Redis is reachable
+	Ping(ctx context.Context) error
 }
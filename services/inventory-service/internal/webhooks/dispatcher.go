@@ -0,0 +1,187 @@
+// Package webhooks pushes inventory events to the webhooks subscribed to
+// them: published events are queued as deliveries, which a scheduled job
+// sends, signed with each webhook's secret, and retries with backoff
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	"github.com/ecommerce/inventory-service/internal/domain"
+	"github.com/ecommerce/inventory-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// batchSize is how many of a tenant's due deliveries a run sends
+const batchSize = 100
+
+// Config tunes delivery: an attempt gives up after Timeout, and failed
+// deliveries are retried after RetryBackoff, doubling up to MaxBackoff,
+// until MaxAttempts have failed
+type Config struct {
+	MaxAttempts  int
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+	Timeout      time.Duration
+}
+
+// Dispatcher queues published events for the webhooks subscribed to them,
+// and delivers them
+type Dispatcher struct {
+	repo   repository.InventoryRepository
+	client *http.Client
+	config Config
+	logger *zap.Logger
+}
+
+// New creates a dispatcher
+func New(repo repository.InventoryRepository, config Config, logger *zap.Logger) *Dispatcher {
+	return &Dispatcher{
+		repo:   repo,
+		client: newClient(config.Timeout),
+		config: config,
+		logger: logger,
+	}
+}
+
+// Forward queues a delivery of an event to each active webhook of the
+// tenant of ctx subscribed to its type
+func (d *Dispatcher) Forward(ctx context.Context, eventType string, data []byte) error {
+	webhooks, err := d.repo.ActiveWebhooks(ctx, eventType)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var errs []error
+	for _, webhook := range webhooks {
+		delivery := &domain.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventType:     eventType,
+			Payload:       data,
+			Status:        domain.DeliveryPending,
+			NextAttemptAt: &now,
+		}
+		if err := d.repo.CreateWebhookDelivery(ctx, delivery); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", webhook.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run sends every tenant's due deliveries. Deliveries that fail are
+// retried on a later run; only failing to load or record them is reported
+// in the error.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	tenants, err := d.repo.DueWebhookTenants(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, tenant := range tenants {
+		tenantCtx := sharedauth.WithTenant(ctx, tenant)
+		if err := d.deliverDue(tenantCtx); err != nil {
+			d.logger.Error("Failed to deliver webhooks", zap.String("tenant_id", tenant), zap.Error(err))
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deliverDue sends the due deliveries of the tenant of ctx
+func (d *Dispatcher) deliverDue(ctx context.Context) error {
+	deliveries, err := d.repo.DueWebhookDeliveries(ctx, batchSize)
+	if err != nil {
+		return err
+	}
+
+	webhooks := make(map[string]*domain.Webhook)
+	var errs []error
+	for _, delivery := range deliveries {
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			webhook, err = d.repo.GetWebhook(ctx, delivery.WebhookID)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+
+		attempt := d.send(ctx, webhook, delivery)
+		succeeded := attempt.Error == ""
+		delivery.Attempted(attempt, succeeded, d.config.MaxAttempts, d.config.RetryBackoff, d.config.MaxBackoff)
+		if err := d.repo.RecordWebhookAttempt(ctx, delivery); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if delivery.Status == domain.DeliveryFailed {
+			d.logger.Warn("Webhook delivery failed",
+				zap.String("webhook_id", webhook.ID),
+				zap.String("delivery_id", delivery.ID),
+				zap.Int("attempts", delivery.Attempts),
+				zap.String("error", delivery.LastError),
+			)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// send posts a delivery's payload to its webhook's URL. A 2xx response
+// delivers it; anything else is recorded as the attempt's error.
+func (d *Dispatcher) send(ctx context.Context, webhook *domain.Webhook, delivery *domain.WebhookDelivery) (attempt domain.WebhookAttempt) {
+	attempt = domain.WebhookAttempt{
+		Attempt:     delivery.Attempts + 1,
+		AttemptedAt: time.Now(),
+	}
+	defer func() {
+		attempt.DurationMS = time.Since(attempt.AttemptedAt).Milliseconds()
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	timestamp := strconv.FormatInt(attempt.AttemptedAt.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "inventory-service-webhooks")
+	req.Header.Set("X-Webhook-ID", webhook.ID)
+	req.Header.Set("X-Webhook-Delivery", delivery.ID)
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	resp.Body.Close()
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+	return attempt
+}
+
+// Sign returns the hex HMAC-SHA256 of timestamp, a dot and body, keyed
+// with secret: the X-Webhook-Signature receivers check deliveries against
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS inventory_adjustments;
+DROP TABLE IF EXISTS reservations;
+DROP TABLE IF EXISTS inventory_items;
+DROP FUNCTION IF EXISTS update_updated_at_column();
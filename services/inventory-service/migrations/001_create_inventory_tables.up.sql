@@ -14,9 +14,9 @@ CREATE TABLE IF NOT EXISTS inventory_items (
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE INDEX idx_inventory_product_id ON inventory_items(product_id);
-CREATE INDEX idx_inventory_sku ON inventory_items(sku);
-CREATE INDEX idx_inventory_status ON inventory_items(status);
+CREATE INDEX IF NOT EXISTS idx_inventory_product_id ON inventory_items(product_id);
+CREATE INDEX IF NOT EXISTS idx_inventory_sku ON inventory_items(sku);
+CREATE INDEX IF NOT EXISTS idx_inventory_status ON inventory_items(status);
 
 -- Create reservations table
 CREATE TABLE IF NOT EXISTS reservations (
@@ -31,10 +31,10 @@ CREATE TABLE IF NOT EXISTS reservations (
     FOREIGN KEY (product_id) REFERENCES inventory_items(product_id) ON DELETE CASCADE
 );
 
-CREATE INDEX idx_reservations_product_id ON reservations(product_id);
-CREATE INDEX idx_reservations_order_id ON reservations(order_id);
-CREATE INDEX idx_reservations_status ON reservations(status);
-CREATE INDEX idx_reservations_expires_at ON reservations(expires_at);
+CREATE INDEX IF NOT EXISTS idx_reservations_product_id ON reservations(product_id);
+CREATE INDEX IF NOT EXISTS idx_reservations_order_id ON reservations(order_id);
+CREATE INDEX IF NOT EXISTS idx_reservations_status ON reservations(status);
+CREATE INDEX IF NOT EXISTS idx_reservations_expires_at ON reservations(expires_at);
 
 -- Create inventory_adjustments table
 CREATE TABLE IF NOT EXISTS inventory_adjustments (
@@ -48,8 +48,8 @@ CREATE TABLE IF NOT EXISTS inventory_adjustments (
     FOREIGN KEY (product_id) REFERENCES inventory_items(product_id) ON DELETE CASCADE
 );
 
-CREATE INDEX idx_adjustments_product_id ON inventory_adjustments(product_id);
-CREATE INDEX idx_adjustments_created_at ON inventory_adjustments(created_at);
+CREATE INDEX IF NOT EXISTS idx_adjustments_product_id ON inventory_adjustments(product_id);
+CREATE INDEX IF NOT EXISTS idx_adjustments_created_at ON inventory_adjustments(created_at);
 
 -- Create trigger to update updated_at
 CREATE OR REPLACE FUNCTION update_updated_at_column()
@@ -60,5 +60,6 @@ BEGIN
 END;
 $$ language 'plpgsql';
 
+DROP TRIGGER IF EXISTS update_inventory_items_updated_at ON inventory_items;
 CREATE TRIGGER update_inventory_items_updated_at BEFORE UPDATE ON inventory_items
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,30 @@
+-- Fails if two tenants share a product ID or SKU
+DROP INDEX IF EXISTS idx_adjustments_tenant_product_id;
+CREATE INDEX IF NOT EXISTS idx_adjustments_product_id ON inventory_adjustments(product_id);
+
+DROP INDEX IF EXISTS idx_reservations_tenant_product_id;
+DROP INDEX IF EXISTS idx_reservations_tenant_order_id;
+CREATE INDEX IF NOT EXISTS idx_reservations_product_id ON reservations(product_id);
+CREATE INDEX IF NOT EXISTS idx_reservations_order_id ON reservations(order_id);
+
+DROP INDEX IF EXISTS idx_inventory_tenant_status;
+DROP INDEX IF EXISTS idx_inventory_tenant_created_at;
+CREATE INDEX IF NOT EXISTS idx_inventory_product_id ON inventory_items(product_id);
+CREATE INDEX IF NOT EXISTS idx_inventory_sku ON inventory_items(sku);
+CREATE INDEX IF NOT EXISTS idx_inventory_status ON inventory_items(status);
+
+ALTER TABLE inventory_adjustments DROP CONSTRAINT IF EXISTS inventory_adjustments_tenant_product_id_fkey;
+ALTER TABLE reservations DROP CONSTRAINT IF EXISTS reservations_tenant_product_id_fkey;
+ALTER TABLE inventory_items DROP CONSTRAINT IF EXISTS inventory_items_tenant_sku_key;
+ALTER TABLE inventory_items DROP CONSTRAINT IF EXISTS inventory_items_tenant_product_id_key;
+
+ALTER TABLE inventory_items ADD CONSTRAINT inventory_items_sku_key UNIQUE (sku);
+ALTER TABLE inventory_items ADD CONSTRAINT inventory_items_product_id_key UNIQUE (product_id);
+ALTER TABLE inventory_adjustments ADD CONSTRAINT inventory_adjustments_product_id_fkey
+    FOREIGN KEY (product_id) REFERENCES inventory_items(product_id) ON DELETE CASCADE;
+ALTER TABLE reservations ADD CONSTRAINT reservations_product_id_fkey
+    FOREIGN KEY (product_id) REFERENCES inventory_items(product_id) ON DELETE CASCADE;
+
+ALTER TABLE inventory_adjustments DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE reservations DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE inventory_items DROP COLUMN IF EXISTS tenant_id;
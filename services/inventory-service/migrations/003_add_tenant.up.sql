@@ -0,0 +1,34 @@
+-- Inventory belongs to a tenant, one storefront of a multi-tenant
+-- deployment; rows created before tenants belong to the default one
+ALTER TABLE inventory_items ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE inventory_adjustments ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+-- Product IDs and SKUs are unique per tenant, and reservations and
+-- adjustments reference an item of their own tenant
+ALTER TABLE reservations DROP CONSTRAINT IF EXISTS reservations_product_id_fkey;
+ALTER TABLE inventory_adjustments DROP CONSTRAINT IF EXISTS inventory_adjustments_product_id_fkey;
+ALTER TABLE inventory_items DROP CONSTRAINT IF EXISTS inventory_items_product_id_key;
+ALTER TABLE inventory_items DROP CONSTRAINT IF EXISTS inventory_items_sku_key;
+
+ALTER TABLE inventory_items ADD CONSTRAINT inventory_items_tenant_product_id_key UNIQUE (tenant_id, product_id);
+ALTER TABLE inventory_items ADD CONSTRAINT inventory_items_tenant_sku_key UNIQUE (tenant_id, sku);
+ALTER TABLE reservations ADD CONSTRAINT reservations_tenant_product_id_fkey
+    FOREIGN KEY (tenant_id, product_id) REFERENCES inventory_items(tenant_id, product_id) ON DELETE CASCADE;
+ALTER TABLE inventory_adjustments ADD CONSTRAINT inventory_adjustments_tenant_product_id_fkey
+    FOREIGN KEY (tenant_id, product_id) REFERENCES inventory_items(tenant_id, product_id) ON DELETE CASCADE;
+
+-- Every query filters on the tenant, so indexes lead with it
+DROP INDEX IF EXISTS idx_inventory_product_id;
+DROP INDEX IF EXISTS idx_inventory_sku;
+DROP INDEX IF EXISTS idx_inventory_status;
+CREATE INDEX IF NOT EXISTS idx_inventory_tenant_status ON inventory_items(tenant_id, status);
+CREATE INDEX IF NOT EXISTS idx_inventory_tenant_created_at ON inventory_items(tenant_id, created_at DESC, id DESC);
+
+DROP INDEX IF EXISTS idx_reservations_product_id;
+DROP INDEX IF EXISTS idx_reservations_order_id;
+CREATE INDEX IF NOT EXISTS idx_reservations_tenant_product_id ON reservations(tenant_id, product_id);
+CREATE INDEX IF NOT EXISTS idx_reservations_tenant_order_id ON reservations(tenant_id, order_id);
+
+DROP INDEX IF EXISTS idx_adjustments_product_id;
+CREATE INDEX IF NOT EXISTS idx_adjustments_tenant_product_id ON inventory_adjustments(tenant_id, product_id);
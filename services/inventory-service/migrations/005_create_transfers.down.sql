@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS stock_transfers;
+DROP TABLE IF EXISTS location_stock;
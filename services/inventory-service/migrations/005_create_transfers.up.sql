@@ -0,0 +1,33 @@
+-- Stock held away from an item's own location; the rest of the item's
+-- quantity is at its location
+CREATE TABLE IF NOT EXISTS location_stock (
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    product_id VARCHAR(255) NOT NULL,
+    location VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity >= 0),
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, product_id, location),
+    FOREIGN KEY (tenant_id, product_id) REFERENCES inventory_items(tenant_id, product_id) ON DELETE CASCADE
+);
+
+-- Transfers of stock between locations
+CREATE TABLE IF NOT EXISTS stock_transfers (
+    id VARCHAR(255) PRIMARY KEY,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    product_id VARCHAR(255) NOT NULL,
+    from_location VARCHAR(255) NOT NULL,
+    to_location VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    status VARCHAR(50) NOT NULL DEFAULT 'in_transit',
+    requested_by VARCHAR(255) NOT NULL,
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+    cancelled_at TIMESTAMP,
+    FOREIGN KEY (tenant_id, product_id) REFERENCES inventory_items(tenant_id, product_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfers_tenant_created_at ON stock_transfers(tenant_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_transfers_tenant_product_id ON stock_transfers(tenant_id, product_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_transfers_tenant_status ON stock_transfers(tenant_id, status);
@@ -0,0 +1,45 @@
+-- Every change to an inventory item, written in the transaction of the
+-- change, for the item's history
+CREATE TABLE IF NOT EXISTS inventory_events (
+    id VARCHAR(255) PRIMARY KEY,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    item_id VARCHAR(255) NOT NULL,
+    product_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    quantity_change INTEGER NOT NULL DEFAULT 0,
+    reserved_change INTEGER NOT NULL DEFAULT 0,
+    -- The item's stock after the change; NULL for backfilled events
+    quantity INTEGER,
+    reserved_quantity INTEGER,
+    available_quantity INTEGER,
+    reference_type VARCHAR(50) NOT NULL DEFAULT '',
+    reference_id VARCHAR(255) NOT NULL DEFAULT '',
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    detail TEXT NOT NULL DEFAULT '',
+    occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (tenant_id, product_id) REFERENCES inventory_items(tenant_id, product_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_events_tenant_item ON inventory_events(tenant_id, item_id, occurred_at DESC, id DESC);
+
+-- History starts with the adjustments and reservations made before it was
+-- kept
+INSERT INTO inventory_events (
+    id, tenant_id, item_id, product_id, event_type, quantity_change,
+    reference_type, reference_id, actor, detail, occurred_at
+)
+SELECT 'adjustment-' || a.id, a.tenant_id, i.id, a.product_id, 'adjusted', a.quantity,
+       'adjustment', a.id, a.adjusted_by, a.reason, a.created_at
+FROM inventory_adjustments a
+JOIN inventory_items i ON i.tenant_id = a.tenant_id AND i.product_id = a.product_id
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO inventory_events (
+    id, tenant_id, item_id, product_id, event_type, reserved_change,
+    reference_type, reference_id, detail, occurred_at
+)
+SELECT 'reservation-' || r.id, r.tenant_id, i.id, r.product_id, 'reserved', r.quantity,
+       'reservation', r.id, r.order_id, r.created_at
+FROM reservations r
+JOIN inventory_items i ON i.tenant_id = r.tenant_id AND i.product_id = r.product_id
+ON CONFLICT (id) DO NOTHING;
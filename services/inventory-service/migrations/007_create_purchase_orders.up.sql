@@ -0,0 +1,30 @@
+-- The supplier an item is reordered from, grouping its purchase orders
+ALTER TABLE inventory_items ADD COLUMN IF NOT EXISTS supplier VARCHAR(255) NOT NULL DEFAULT '';
+
+-- Stock ordered from suppliers, drafted by the reorder job or by hand
+CREATE TABLE IF NOT EXISTS purchase_orders (
+    id VARCHAR(255) PRIMARY KEY,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    supplier VARCHAR(255) NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'draft',
+    created_by VARCHAR(255) NOT NULL,
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ordered_at TIMESTAMP,
+    received_at TIMESTAMP,
+    cancelled_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS purchase_order_lines (
+    purchase_order_id VARCHAR(255) NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    line_number INTEGER NOT NULL,
+    product_id VARCHAR(255) NOT NULL,
+    sku VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (purchase_order_id, line_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_tenant_created_at ON purchase_orders(tenant_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_tenant_status ON purchase_orders(tenant_id, status);
+CREATE INDEX IF NOT EXISTS idx_purchase_order_lines_product_id ON purchase_order_lines(product_id);
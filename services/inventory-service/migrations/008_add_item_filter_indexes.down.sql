@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_inventory_tenant_sku_pattern;
+DROP INDEX IF EXISTS idx_inventory_tenant_location;
@@ -0,0 +1,4 @@
+-- SKU prefix searches of the inventory listing, which the unique
+-- (tenant_id, sku) index can't serve outside the C collation
+CREATE INDEX IF NOT EXISTS idx_inventory_tenant_sku_pattern ON inventory_items(tenant_id, sku varchar_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_inventory_tenant_location ON inventory_items(tenant_id, location);
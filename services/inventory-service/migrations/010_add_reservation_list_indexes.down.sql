@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_reservations_tenant_created_at;
+DROP INDEX IF EXISTS idx_reservations_tenant_customer_id;
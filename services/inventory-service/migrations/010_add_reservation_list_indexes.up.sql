@@ -0,0 +1,3 @@
+-- The reservation listing, newest first, and its customer filter
+CREATE INDEX IF NOT EXISTS idx_reservations_tenant_created_at ON reservations(tenant_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_reservations_tenant_customer_id ON reservations(tenant_id, customer_id);
@@ -0,0 +1,36 @@
+-- Stock-takes of a location, each item's count and its variance from the
+-- recorded stock
+CREATE TABLE IF NOT EXISTS cycle_counts (
+    id VARCHAR(255) PRIMARY KEY,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    location VARCHAR(255) NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'counting',
+    created_by VARCHAR(255) NOT NULL,
+    applied_by VARCHAR(255) NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    applied_at TIMESTAMP,
+    cancelled_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS cycle_count_lines (
+    cycle_count_id VARCHAR(255) NOT NULL REFERENCES cycle_counts(id) ON DELETE CASCADE,
+    line_number INTEGER NOT NULL,
+    product_id VARCHAR(255) NOT NULL,
+    sku VARCHAR(255) NOT NULL,
+    expected_quantity INTEGER NOT NULL,
+    counted_quantity INTEGER CHECK (counted_quantity >= 0),
+    variance INTEGER NOT NULL DEFAULT 0,
+    counted_by VARCHAR(255) NOT NULL DEFAULT '',
+    counted_at TIMESTAMP,
+    approved BOOLEAN NOT NULL DEFAULT FALSE,
+    adjustment_id VARCHAR(255) NOT NULL DEFAULT '',
+    PRIMARY KEY (cycle_count_id, line_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cycle_counts_tenant_created_at ON cycle_counts(tenant_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_cycle_counts_tenant_location_status ON cycle_counts(tenant_id, location, status);
+
+-- The cycle count whose variance an adjustment applied
+ALTER TABLE inventory_adjustments ADD COLUMN IF NOT EXISTS cycle_count_id VARCHAR(255) NOT NULL DEFAULT '';
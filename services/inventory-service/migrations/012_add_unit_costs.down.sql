@@ -0,0 +1,9 @@
+ALTER TABLE purchase_order_lines DROP COLUMN IF EXISTS unit_cost_minor;
+ALTER TABLE purchase_order_lines DROP COLUMN IF EXISTS cost_currency;
+
+ALTER TABLE inventory_adjustments DROP COLUMN IF EXISTS unit_cost_minor;
+ALTER TABLE inventory_adjustments DROP COLUMN IF EXISTS cost_currency;
+
+ALTER TABLE inventory_items DROP COLUMN IF EXISTS unit_cost_minor;
+ALTER TABLE inventory_items DROP COLUMN IF EXISTS cost_currency;
+ALTER TABLE inventory_items DROP COLUMN IF EXISTS cost_method;
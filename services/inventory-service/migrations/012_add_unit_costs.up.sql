@@ -0,0 +1,14 @@
+-- What a unit of each item's stock cost, in minor units of cost_currency,
+-- and how receipts change it: weighted_average or standard
+ALTER TABLE inventory_items ADD COLUMN IF NOT EXISTS unit_cost_minor BIGINT CHECK (unit_cost_minor >= 0);
+ALTER TABLE inventory_items ADD COLUMN IF NOT EXISTS cost_currency VARCHAR(3) NOT NULL DEFAULT '';
+ALTER TABLE inventory_items ADD COLUMN IF NOT EXISTS cost_method VARCHAR(50) NOT NULL DEFAULT 'weighted_average';
+
+-- The unit cost of stock added by an adjustment, or removed at the item's
+-- cost
+ALTER TABLE inventory_adjustments ADD COLUMN IF NOT EXISTS unit_cost_minor BIGINT;
+ALTER TABLE inventory_adjustments ADD COLUMN IF NOT EXISTS cost_currency VARCHAR(3) NOT NULL DEFAULT '';
+
+-- What the supplier charges a unit of a purchase order line
+ALTER TABLE purchase_order_lines ADD COLUMN IF NOT EXISTS unit_cost_minor BIGINT CHECK (unit_cost_minor >= 0);
+ALTER TABLE purchase_order_lines ADD COLUMN IF NOT EXISTS cost_currency VARCHAR(3) NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS lot_movements;
+DROP TABLE IF EXISTS inventory_serials;
+DROP TABLE IF EXISTS inventory_lots;
@@ -0,0 +1,47 @@
+-- Stock received in batches traced by lot number, e.g. for recalls
+CREATE TABLE IF NOT EXISTS inventory_lots (
+    id VARCHAR(255) PRIMARY KEY,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    item_id VARCHAR(255) NOT NULL,
+    product_id VARCHAR(255) NOT NULL,
+    lot_number VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tenant_id, product_id, lot_number),
+    FOREIGN KEY (tenant_id, product_id) REFERENCES inventory_items(tenant_id, product_id) ON DELETE CASCADE
+);
+
+-- Serial-numbered units, each in a lot; removed units keep their row, so
+-- recalls can trace where they went
+CREATE TABLE IF NOT EXISTS inventory_serials (
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    product_id VARCHAR(255) NOT NULL,
+    serial_number VARCHAR(255) NOT NULL,
+    item_id VARCHAR(255) NOT NULL,
+    lot_id VARCHAR(255) NOT NULL REFERENCES inventory_lots(id) ON DELETE CASCADE,
+    status VARCHAR(50) NOT NULL DEFAULT 'in_stock',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, product_id, serial_number)
+);
+
+-- Every receipt into and removal from a lot, with what made it
+CREATE TABLE IF NOT EXISTS lot_movements (
+    id VARCHAR(255) PRIMARY KEY,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    lot_id VARCHAR(255) NOT NULL REFERENCES inventory_lots(id) ON DELETE CASCADE,
+    lot_number VARCHAR(255) NOT NULL,
+    item_id VARCHAR(255) NOT NULL,
+    product_id VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL,
+    serial_numbers TEXT[] NOT NULL DEFAULT '{}',
+    reference_type VARCHAR(50) NOT NULL,
+    reference_id VARCHAR(255) NOT NULL,
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_lots_item_id ON inventory_lots(item_id);
+CREATE INDEX IF NOT EXISTS idx_inventory_serials_serial_number ON inventory_serials(tenant_id, serial_number);
+CREATE INDEX IF NOT EXISTS idx_lot_movements_lot_number ON lot_movements(tenant_id, lot_number, occurred_at DESC, id DESC);
@@ -0,0 +1,7 @@
+ALTER TABLE inventory_items DROP COLUMN IF EXISTS expired_quantity;
+ALTER TABLE inventory_items DROP COLUMN IF EXISTS allocation;
+
+DROP INDEX IF EXISTS idx_inventory_lots_expires_at;
+ALTER TABLE inventory_lots DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE inventory_lots DROP COLUMN IF EXISTS expired;
+ALTER TABLE inventory_lots DROP COLUMN IF EXISTS expiry_notified;
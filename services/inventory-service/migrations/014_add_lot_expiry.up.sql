@@ -0,0 +1,14 @@
+-- When perishable lots expire, whether they have, and whether they were
+-- announced as expiring soon
+ALTER TABLE inventory_lots ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP;
+ALTER TABLE inventory_lots ADD COLUMN IF NOT EXISTS expired BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE inventory_lots ADD COLUMN IF NOT EXISTS expiry_notified BOOLEAN NOT NULL DEFAULT false;
+
+-- Stock in expired lots, which isn't available, and how stock leaving
+-- without lots given is taken from them: manual or fefo
+ALTER TABLE inventory_items ADD COLUMN IF NOT EXISTS expired_quantity INTEGER NOT NULL DEFAULT 0 CHECK (expired_quantity >= 0);
+ALTER TABLE inventory_items ADD COLUMN IF NOT EXISTS allocation VARCHAR(50) NOT NULL DEFAULT 'manual';
+
+-- Lots the expiry job has yet to expire or announce
+CREATE INDEX IF NOT EXISTS idx_inventory_lots_expires_at ON inventory_lots(expires_at)
+    WHERE expires_at IS NOT NULL AND NOT expired AND quantity > 0;
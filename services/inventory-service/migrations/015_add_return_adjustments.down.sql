@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_adjustments_order_id;
+ALTER TABLE inventory_adjustments DROP COLUMN IF EXISTS order_id;
+ALTER TABLE inventory_adjustments DROP COLUMN IF EXISTS returned_quantity;
@@ -0,0 +1,6 @@
+-- The order and units of a return checked back in: sellable units are
+-- added to stock, damaged ones only recorded
+ALTER TABLE inventory_adjustments ADD COLUMN IF NOT EXISTS order_id VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE inventory_adjustments ADD COLUMN IF NOT EXISTS returned_quantity INTEGER NOT NULL DEFAULT 0 CHECK (returned_quantity >= 0);
+
+CREATE INDEX IF NOT EXISTS idx_adjustments_order_id ON inventory_adjustments(tenant_id, order_id) WHERE order_id <> '';
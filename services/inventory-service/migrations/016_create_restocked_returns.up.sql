@@ -0,0 +1,10 @@
+-- Order lines whose returned units were checked back in, so a retried
+-- restock doesn't add them to stock twice
+CREATE TABLE IF NOT EXISTS restocked_returns (
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    order_id VARCHAR(255) NOT NULL,
+    line_id VARCHAR(255) NOT NULL,
+    adjustment_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, order_id, line_id)
+);
@@ -0,0 +1,13 @@
+// Package migrations embeds the service's database migrations, applied
+// with sharedmigrate
+package migrations
+
+import "embed"
+
+// VersionTable records the version of the last migration applied
+const VersionTable = "inventory_schema_version"
+
+// FS holds the migrations, applied in version order
+//
+//go:embed *.sql
+var FS embed.FS
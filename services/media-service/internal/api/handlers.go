@@ -0,0 +1,402 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/pagination"
+	"github.com/ecommerce/media-service/internal/domain"
+	"github.com/ecommerce/media-service/internal/imaging"
+	"github.com/ecommerce/media-service/internal/media"
+	"github.com/ecommerce/media-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// maxFilenameLength bounds the names uploads are kept under
+const maxFilenameLength = 255
+
+// Handler serves the media API
+type Handler struct {
+	media  *media.Service
+	logger *zap.Logger
+}
+
+// NewHandler creates a new handler
+func NewHandler(mediaService *media.Service, logger *zap.Logger) *Handler {
+	return &Handler{
+		media:  mediaService,
+		logger: logger,
+	}
+}
+
+// Upload stores a file for the caller. The multipart form has the file,
+// its kind and what it belongs to. Customers upload their avatar; admins
+// product images; admins and support attachments, for a customer with
+// user_id.
+// POST /api/v1/media
+func (h *Handler) Upload(c *gin.Context) {
+	claims, _ := sharedauth.ClaimsFrom(c)
+	upload, ok := h.readUpload(c)
+	if !ok {
+		return
+	}
+
+	switch upload.Kind {
+	case domain.KindAvatar:
+		// Avatars always belong to the caller
+		upload.OwnerType, upload.OwnerID, upload.UserID = "user", claims.UserID, claims.UserID
+	case domain.KindProductImage:
+		if !claims.HasRole(sharedauth.RoleAdmin) {
+			apperrors.Abort(c, apperrors.NewForbidden("Only admins upload product images"))
+			return
+		}
+		upload.UserID = ""
+	default:
+		if domain.Rules[upload.Kind].ServiceOnly {
+			apperrors.Abort(c, apperrors.NewForbidden("Files of this kind are uploaded by services"))
+			return
+		}
+		if !claims.HasRole(sharedauth.RoleAdmin, sharedauth.RoleSupport) {
+			apperrors.Abort(c, apperrors.NewForbidden("Only staff upload attachments"))
+			return
+		}
+	}
+	upload.UploadedBy = claims.UserID
+
+	h.store(c, upload)
+}
+
+// InternalUpload stores a file for a service, e.g. an order's invoice or a
+// return's label. user_id names the customer who may download it.
+// POST /api/v1/internal/media
+func (h *Handler) InternalUpload(c *gin.Context) {
+	upload, ok := h.readUpload(c)
+	if !ok {
+		return
+	}
+	h.store(c, upload)
+}
+
+// store stores an upload and responds with its record
+func (h *Handler) store(c *gin.Context, upload media.Upload) {
+	asset, err := h.media.Store(c.Request.Context(), upload)
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	h.respond(c, asset, http.StatusCreated)
+}
+
+// readUpload reads the multipart form of an upload, aborting the request
+// if it is invalid
+func (h *Handler) readUpload(c *gin.Context) (media.Upload, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxRequestBytes())
+
+	kind := domain.Kind(c.PostForm("kind"))
+	rules, err := domain.RulesFor(kind)
+	if err != nil {
+		h.abort(c, err)
+		return media.Upload{}, false
+	}
+
+	header, err := c.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		h.abort(c, domain.ErrTooLarge)
+		return media.Upload{}, false
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.NewBadRequest("A file is required"))
+		return media.Upload{}, false
+	}
+	if header.Size > rules.MaxBytes {
+		h.abort(c, domain.ErrTooLarge)
+		return media.Upload{}, false
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to read upload"))
+		return media.Upload{}, false
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, rules.MaxBytes+1))
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to read upload"))
+		return media.Upload{}, false
+	}
+	if len(data) == 0 {
+		apperrors.Abort(c, apperrors.NewBadRequest("The file is empty"))
+		return media.Upload{}, false
+	}
+
+	return media.Upload{
+		Kind:      kind,
+		OwnerType: strings.TrimSpace(c.PostForm("owner_type")),
+		OwnerID:   strings.TrimSpace(c.PostForm("owner_id")),
+		UserID:    strings.TrimSpace(c.PostForm("user_id")),
+		Filename:  cleanFilename(header.Filename),
+		Data:      data,
+	}, true
+}
+
+// GetMedia returns a file's record with its download URLs: signed URLs,
+// valid for a limited time, for private files
+// GET /api/v1/media/:id
+func (h *Handler) GetMedia(c *gin.Context) {
+	asset, ok := h.readable(c)
+	if !ok {
+		return
+	}
+
+	h.respond(c, asset, http.StatusOK)
+}
+
+// InternalGetMedia returns any file's record with its download URLs
+// GET /api/v1/internal/media/:id
+func (h *Handler) InternalGetMedia(c *gin.Context) {
+	asset, err := h.media.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	h.respond(c, asset, http.StatusOK)
+}
+
+// ListMedia lists files, newest first. Customers see theirs; staff see
+// any, filtered with ?kind=, ?owner_type=, ?owner_id= and ?user_id=.
+// GET /api/v1/media
+func (h *Handler) ListMedia(c *gin.Context) {
+	claims, _ := sharedauth.ClaimsFrom(c)
+	filter := repository.Filter{
+		Kind:      domain.Kind(c.Query("kind")),
+		OwnerType: c.Query("owner_type"),
+		OwnerID:   c.Query("owner_id"),
+		UserID:    c.Query("user_id"),
+	}
+	if filter.Kind != "" {
+		if _, err := domain.RulesFor(filter.Kind); err != nil {
+			h.abort(c, err)
+			return
+		}
+	}
+	if !isStaff(claims) {
+		filter.UserID = claims.UserID
+	}
+
+	params := pagination.FromQuery(c.Request.URL.Query())
+	var after *repository.ListPosition
+	if params.Cursor != "" {
+		after = &repository.ListPosition{}
+		if err := pagination.DecodeCursor(params.Cursor, after); err != nil {
+			apperrors.Abort(c, apperrors.NewBadRequest("Invalid cursor"))
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	assets, total, err := h.media.List(ctx, filter, params.Limit+1, after)
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	page, err := pagination.NewPage(assets, params.Limit, total, func(asset *domain.Asset) interface{} {
+		return repository.ListPosition{CreatedAt: asset.CreatedAt, ID: asset.ID}
+	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list media"))
+		return
+	}
+	for _, asset := range page.Items {
+		if err := h.media.SetURLs(ctx, asset); err != nil {
+			apperrors.Abort(c, apperrors.Wrap(err, "Failed to list media"))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// DeleteMedia deletes a file and its variants. Admins delete any; others
+// the files they uploaded, and customers their avatars.
+// DELETE /api/v1/media/:id
+func (h *Handler) DeleteMedia(c *gin.Context) {
+	claims, _ := sharedauth.ClaimsFrom(c)
+	asset, ok := h.readable(c)
+	if !ok {
+		return
+	}
+	if !claims.HasRole(sharedauth.RoleAdmin) && asset.UploadedBy != claims.UserID &&
+		!(asset.Kind == domain.KindAvatar && asset.UserID == claims.UserID) {
+		apperrors.Abort(c, apperrors.NewForbidden("You can't delete this file"))
+		return
+	}
+
+	if err := h.media.Delete(c.Request.Context(), asset); err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetContent redirects to a public file, or with ?variant= one of its
+// variants, through a signed URL. These stable URLs are the ones catalog
+// products and profiles keep; images too small for a variant are served
+// as they are. Private files aren't served here.
+// GET /api/v1/media/:id/content
+func (h *Handler) GetContent(c *gin.Context) {
+	asset, err := h.media.Get(c.Request.Context(), c.Param("id"))
+	if err == nil && !asset.Public() {
+		err = domain.ErrAssetNotFound
+	}
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	signedURL, err := h.media.SignedURL(c.Request.Context(), asset, c.Query("variant"))
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to sign URL"))
+		return
+	}
+
+	// Browsers and CDNs may reuse the redirect while the URL is valid
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.media.URLTTL().Seconds())/2))
+	c.Redirect(http.StatusFound, signedURL)
+}
+
+// InternalGetContent streams a file, for services that send it on, such as
+// notification-service attaching documents to emails
+// GET /api/v1/internal/media/:id/content
+func (h *Handler) InternalGetContent(c *gin.Context) {
+	ctx := c.Request.Context()
+	asset, err := h.media.Get(ctx, c.Param("id"))
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	body, err := h.media.Open(ctx, asset)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to read file"))
+		return
+	}
+	defer body.Close()
+
+	c.DataFromReader(http.StatusOK, asset.SizeBytes, asset.ContentType, body, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": asset.Filename}),
+		"X-Checksum-SHA256":   asset.Checksum,
+	})
+}
+
+// HealthCheck reports whether the database and the bucket are reachable
+func (h *Handler) HealthCheck(c *gin.Context) {
+	if err := h.media.Ping(c.Request.Context()); err != nil {
+		h.logger.Warn("Health check failed", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unhealthy",
+			"service": "media-service",
+			"version": "1.0.0",
+			"error":   "dependencies unavailable",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "healthy",
+		"service": "media-service",
+		"version": "1.0.0",
+	})
+}
+
+// readable loads the file of the :id path parameter, aborting with 404 if
+// there is none or the caller may not read it: private files are read by
+// their customer, their uploader and staff
+func (h *Handler) readable(c *gin.Context) (*domain.Asset, bool) {
+	claims, _ := sharedauth.ClaimsFrom(c)
+	asset, err := h.media.Get(c.Request.Context(), c.Param("id"))
+	if err == nil && !asset.Public() && !isStaff(claims) &&
+		asset.UserID != claims.UserID && asset.UploadedBy != claims.UserID {
+		err = domain.ErrAssetNotFound
+	}
+	if err != nil {
+		h.abort(c, err)
+		return nil, false
+	}
+	return asset, true
+}
+
+// respond responds with an asset and its download URLs
+func (h *Handler) respond(c *gin.Context, asset *domain.Asset, status int) {
+	if err := h.media.SetURLs(c.Request.Context(), asset); err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to sign URLs"))
+		return
+	}
+
+	c.JSON(status, asset)
+}
+
+func (h *Handler) abort(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrAssetNotFound):
+		apperrors.Abort(c, apperrors.NewNotFound("Media"))
+	case errors.Is(err, domain.ErrUnknownKind):
+		apperrors.Abort(c, apperrors.NewBadRequest("Unknown kind"))
+	case errors.Is(err, domain.ErrTooLarge):
+		apperrors.Abort(c, apperrors.New(http.StatusRequestEntityTooLarge, "File is too large"))
+	case errors.Is(err, imaging.ErrTooManyPixels):
+		apperrors.Abort(c, apperrors.New(http.StatusRequestEntityTooLarge, "Image dimensions are too large"))
+	case errors.Is(err, domain.ErrContentType):
+		apperrors.Abort(c, apperrors.New(http.StatusUnsupportedMediaType, "File type not allowed for this kind"))
+	case errors.Is(err, domain.ErrInfected):
+		apperrors.Abort(c, apperrors.New(http.StatusUnprocessableEntity, "File failed the virus scan"))
+	case errors.Is(err, media.ErrScanFailed):
+		apperrors.Abort(c, apperrors.New(http.StatusServiceUnavailable, "File couldn't be scanned, please try again"))
+	default:
+		apperrors.Abort(c, apperrors.FromSQL(err, "Media"))
+	}
+}
+
+// maxRequestBytes bounds upload requests: the largest file any kind
+// allows, and room for the rest of the form
+func maxRequestBytes() int64 {
+	var largest int64
+	for _, rules := range domain.Rules {
+		if rules.MaxBytes > largest {
+			largest = rules.MaxBytes
+		}
+	}
+	return largest + 1<<20
+}
+
+// cleanFilename keeps the base name of an upload's name, for downloads
+func cleanFilename(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	if len(name) > maxFilenameLength {
+		ext := filepath.Ext(name)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		name = strings.ToValidUTF8(name[:maxFilenameLength-len(ext)], "") + ext
+	}
+	return name
+}
+
+func isStaff(claims *sharedauth.Claims) bool {
+	return claims != nil && claims.HasRole(sharedauth.RoleAdmin, sharedauth.RoleSupport)
+}
@@ -0,0 +1,174 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrAssetNotFound = errors.New("media asset not found")
+	ErrUnknownKind   = errors.New("unknown media kind")
+	ErrTooLarge      = errors.New("file is too large")
+	ErrContentType   = errors.New("file type not allowed")
+	ErrInfected      = errors.New("file failed the virus scan")
+)
+
+// Kind is what a file is for, which decides who may upload and read it,
+// the types and sizes it may have and the variants made of it
+type Kind string
+
+const (
+	// KindProductImage is a catalog product's image, uploaded by admins
+	KindProductImage Kind = "product_image"
+	// KindAvatar is a customer's profile picture
+	KindAvatar Kind = "avatar"
+	// KindInvoice is an order's invoice, uploaded by order-service
+	KindInvoice Kind = "invoice"
+	// KindReturnLabel is a return's shipping label, uploaded by
+	// returns-service
+	KindReturnLabel Kind = "return_label"
+	// KindAttachment is a document sent with notifications, uploaded by
+	// staff or services
+	KindAttachment Kind = "attachment"
+)
+
+// VariantSpec is a resized copy made of every image of a kind. Images are
+// scaled to fit within Size pixels, or cropped to a Size square, and never
+// enlarged; variants an image is too small for are skipped.
+type VariantSpec struct {
+	Name   string
+	Size   int
+	Square bool
+}
+
+// KindRules are what a kind allows
+type KindRules struct {
+	// Public files are readable by anyone with their URL; the others only
+	// by their customer, staff and services
+	Public bool
+	// ServiceOnly kinds are uploaded by services, not through the public API
+	ServiceOnly  bool
+	MaxBytes     int64
+	ContentTypes []string
+	Variants     []VariantSpec
+}
+
+// Images are the image types accepted for resizing
+var Images = []string{"image/jpeg", "image/png", "image/gif"}
+
+// Rules are the rules of each kind
+var Rules = map[Kind]KindRules{
+	KindProductImage: {
+		Public:       true,
+		MaxBytes:     10 << 20,
+		ContentTypes: Images,
+		Variants: []VariantSpec{
+			{Name: "thumb", Size: 150},
+			{Name: "small", Size: 400},
+			{Name: "medium", Size: 800},
+			{Name: "large", Size: 1600},
+		},
+	},
+	KindAvatar: {
+		Public:       true,
+		MaxBytes:     5 << 20,
+		ContentTypes: Images,
+		Variants: []VariantSpec{
+			{Name: "small", Size: 64, Square: true},
+			{Name: "medium", Size: 128, Square: true},
+			{Name: "large", Size: 256, Square: true},
+		},
+	},
+	KindInvoice: {
+		ServiceOnly:  true,
+		MaxBytes:     10 << 20,
+		ContentTypes: []string{"application/pdf"},
+	},
+	KindReturnLabel: {
+		ServiceOnly:  true,
+		MaxBytes:     5 << 20,
+		ContentTypes: []string{"application/pdf", "image/png"},
+	},
+	KindAttachment: {
+		MaxBytes:     20 << 20,
+		ContentTypes: append([]string{"application/pdf", "text/plain; charset=utf-8"}, Images...),
+	},
+}
+
+// RulesFor returns kind's rules, or ErrUnknownKind
+func RulesFor(kind Kind) (KindRules, error) {
+	rules, ok := Rules[kind]
+	if !ok {
+		return KindRules{}, ErrUnknownKind
+	}
+	return rules, nil
+}
+
+// Allows reports whether the rules accept a file of contentType
+func (r KindRules) Allows(contentType string) bool {
+	for _, allowed := range r.ContentTypes {
+		if allowed == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// Asset is a stored file and the variants made of it
+type Asset struct {
+	ID       string `json:"id"`
+	TenantID string `json:"-"`
+	Kind     Kind   `json:"kind"`
+	// OwnerType and OwnerID are what the file belongs to, e.g. a product,
+	// order or return
+	OwnerType string `json:"owner_type,omitempty"`
+	OwnerID   string `json:"owner_id,omitempty"`
+	// UserID is the customer who may read a private file, if any
+	UserID      string    `json:"user_id,omitempty"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	Checksum    string    `json:"checksum_sha256"`
+	Width       int       `json:"width,omitempty"`
+	Height      int       `json:"height,omitempty"`
+	Variants    []Variant `json:"variants,omitempty"`
+	// UploadedBy is the uploading user; empty for services
+	UploadedBy string    `json:"uploaded_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// URL is where the file can be downloaded, set when it is returned
+	URL string `json:"url,omitempty"`
+}
+
+// Variant is a resized copy of an image
+type Variant struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	SizeBytes   int64  `json:"size_bytes"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	URL         string `json:"url,omitempty"`
+}
+
+// Original names the uploaded file among an asset's objects
+const Original = "original"
+
+// Key is the storage key of the asset's original or one of its variants
+func (a *Asset) Key(variant string) string {
+	return a.TenantID + "/" + string(a.Kind) + "/" + a.ID + "/" + variant
+}
+
+// Variant returns the named variant, if the asset has it
+func (a *Asset) Variant(name string) (*Variant, bool) {
+	for i := range a.Variants {
+		if a.Variants[i].Name == name {
+			return &a.Variants[i], true
+		}
+	}
+	return nil, false
+}
+
+// Public reports whether anyone may read the asset
+func (a *Asset) Public() bool {
+	return Rules[a.Kind].Public
+}
@@ -0,0 +1,155 @@
+// Package imaging prepares uploaded images: it re-encodes them without
+// their metadata, such as the location a photo was taken at, and makes
+// their resized variants
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // decodes uploaded GIFs
+	"image/jpeg"
+	"image/png"
+
+	"github.com/ecommerce/media-service/internal/domain"
+	"golang.org/x/image/draw"
+)
+
+// MaxPixels bounds the images decoded, so a small file can't claim
+// dimensions that exhaust memory
+const MaxPixels = 40_000_000
+
+// jpegQuality is the quality JPEGs are re-encoded at
+const jpegQuality = 88
+
+// ErrTooManyPixels rejects images larger than MaxPixels
+var ErrTooManyPixels = errors.New("image dimensions are too large")
+
+// Encoded is an encoded image
+type Encoded struct {
+	Name        string
+	ContentType string
+	Data        []byte
+	Width       int
+	Height      int
+}
+
+// Result is a prepared upload: the original to store in place of the
+// uploaded file, and its variants
+type Result struct {
+	Original Encoded
+	Variants []Encoded
+}
+
+// Process decodes an image of contentType, turns it upright and makes the
+// variants of specs. JPEGs and PNGs are re-encoded to drop their metadata;
+// GIFs are kept as uploaded, so animations survive, with PNG variants of
+// their first frame.
+func Process(data []byte, contentType string, specs []domain.VariantSpec) (*Result, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	result := &Result{}
+	switch contentType {
+	case "image/jpeg":
+		img = orient(img, jpegOrientation(data))
+		result.Original, err = encode(domain.Original, img, contentType)
+	case "image/png":
+		result.Original, err = encode(domain.Original, img, contentType)
+	case "image/gif":
+		bounds := img.Bounds()
+		result.Original = Encoded{Name: domain.Original, ContentType: contentType, Data: data, Width: bounds.Dx(), Height: bounds.Dy()}
+		contentType = "image/png"
+	default:
+		return nil, domain.ErrContentType
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, spec := range specs {
+		resized, ok := resize(img, spec)
+		if !ok {
+			continue
+		}
+		variant, err := encode(spec.Name, resized, contentType)
+		if err != nil {
+			return nil, err
+		}
+		result.Variants = append(result.Variants, variant)
+	}
+	return result, nil
+}
+
+// resize scales img to fit spec, cropping it to a centred square first for
+// square specs. It returns false when img is too small for spec.
+func resize(img image.Image, spec domain.VariantSpec) (image.Image, bool) {
+	bounds := img.Bounds()
+	if spec.Square {
+		side := min(bounds.Dx(), bounds.Dy())
+		if side < spec.Size {
+			return nil, false
+		}
+		x := bounds.Min.X + (bounds.Dx()-side)/2
+		y := bounds.Min.Y + (bounds.Dy()-side)/2
+		bounds = image.Rect(x, y, x+side, y+side)
+		return scale(img, bounds, spec.Size, spec.Size), true
+	}
+
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= spec.Size && height <= spec.Size {
+		return nil, false
+	}
+	if width >= height {
+		height = max(1, height*spec.Size/width)
+		width = spec.Size
+	} else {
+		width = max(1, width*spec.Size/height)
+		height = spec.Size
+	}
+	return scale(img, bounds, width, height), true
+}
+
+// scale draws the src area of img into a width by height image
+func scale(img image.Image, src image.Rectangle, width, height int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Src, nil)
+	return dst
+}
+
+// encode encodes img as contentType, JPEG or PNG
+func encode(name string, img image.Image, contentType string) (Encoded, error) {
+	var buf bytes.Buffer
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	case "image/png":
+		err = png.Encode(&buf, img)
+	default:
+		return Encoded{}, domain.ErrContentType
+	}
+	if err != nil {
+		return Encoded{}, fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+
+	bounds := img.Bounds()
+	return Encoded{
+		Name:        name,
+		ContentType: contentType,
+		Data:        buf.Bytes(),
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+	}, nil
+}
@@ -0,0 +1,328 @@
+// Package media stores uploads: it checks them against their kind's rules,
+// scans them, makes the variants of images and keeps them in storage with
+// a record of each
+package media
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/media-service/internal/domain"
+	"github.com/ecommerce/media-service/internal/imaging"
+	"github.com/ecommerce/media-service/internal/metrics"
+	"github.com/ecommerce/media-service/internal/repository"
+	"github.com/ecommerce/media-service/internal/scanner"
+	"github.com/ecommerce/media-service/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ErrScanFailed is returned when an upload couldn't be scanned; it isn't
+// stored
+var ErrScanFailed = errors.New("file couldn't be scanned")
+
+// Upload is a file to store
+type Upload struct {
+	Kind      domain.Kind
+	OwnerType string
+	OwnerID   string
+	// UserID is the customer who may read a private file
+	UserID     string
+	Filename   string
+	Data       []byte
+	UploadedBy string
+}
+
+// Service stores and serves media
+type Service struct {
+	repo        repository.MediaRepository
+	store       *storage.Store
+	scanner     scanner.Scanner
+	scanTimeout time.Duration
+	publicURL   string
+	logger      *zap.Logger
+}
+
+// NewService creates a media service. Public files get stable URLs under
+// publicURL, which redirect to a signed URL of the file.
+func NewService(repo repository.MediaRepository, store *storage.Store, scan scanner.Scanner, scanTimeout time.Duration, publicURL string, logger *zap.Logger) *Service {
+	return &Service{
+		repo:        repo,
+		store:       store,
+		scanner:     scan,
+		scanTimeout: scanTimeout,
+		publicURL:   strings.TrimRight(publicURL, "/"),
+		logger:      logger,
+	}
+}
+
+// Store checks, scans and stores an upload. It returns domain.ErrTooLarge,
+// domain.ErrContentType or imaging.ErrTooManyPixels for files the kind
+// doesn't allow, domain.ErrInfected for files the scanner flags and
+// ErrScanFailed when the scanner couldn't be reached.
+func (s *Service) Store(ctx context.Context, upload Upload) (*domain.Asset, error) {
+	logger := logging.WithContext(ctx, s.logger).With(zap.String("kind", string(upload.Kind)))
+
+	rules, err := domain.RulesFor(upload.Kind)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(upload.Data)) > rules.MaxBytes {
+		metrics.UploadsTotal.WithLabelValues(string(upload.Kind), "rejected").Inc()
+		return nil, domain.ErrTooLarge
+	}
+	// The type is taken from the content, not the name or header the
+	// client sent
+	contentType := http.DetectContentType(upload.Data)
+	if !rules.Allows(contentType) {
+		metrics.UploadsTotal.WithLabelValues(string(upload.Kind), "rejected").Inc()
+		return nil, domain.ErrContentType
+	}
+
+	if err := s.scan(ctx, upload, logger); err != nil {
+		return nil, err
+	}
+
+	asset := &domain.Asset{
+		ID:          uuid.New().String(),
+		Kind:        upload.Kind,
+		OwnerType:   upload.OwnerType,
+		OwnerID:     upload.OwnerID,
+		UserID:      upload.UserID,
+		Filename:    upload.Filename,
+		ContentType: contentType,
+		UploadedBy:  upload.UploadedBy,
+	}
+	// The record's tenant places the files, so it is set before they are
+	asset.TenantID = sharedauth.TenantFromContext(ctx)
+
+	objects := []storage.Object{{Key: asset.Key(domain.Original), ContentType: contentType, Data: upload.Data}}
+	if isImage(contentType) {
+		processed, err := imaging.Process(upload.Data, contentType, rules.Variants)
+		if errors.Is(err, imaging.ErrTooManyPixels) {
+			metrics.UploadsTotal.WithLabelValues(string(upload.Kind), "rejected").Inc()
+			return nil, err
+		}
+		if err != nil {
+			// The type was sniffed from a valid header, but the rest isn't
+			// an image
+			metrics.UploadsTotal.WithLabelValues(string(upload.Kind), "rejected").Inc()
+			logger.Info("Rejected undecodable image", zap.Error(err))
+			return nil, domain.ErrContentType
+		}
+
+		original := processed.Original
+		objects[0].Data = original.Data
+		asset.Width, asset.Height = original.Width, original.Height
+		for _, variant := range processed.Variants {
+			objects = append(objects, storage.Object{Key: asset.Key(variant.Name), ContentType: variant.ContentType, Data: variant.Data})
+			asset.Variants = append(asset.Variants, domain.Variant{
+				Name:        variant.Name,
+				ContentType: variant.ContentType,
+				SizeBytes:   int64(len(variant.Data)),
+				Width:       variant.Width,
+				Height:      variant.Height,
+			})
+		}
+	}
+
+	// Size and checksum are of the file as stored, which is downloaded
+	checksum := sha256.Sum256(objects[0].Data)
+	asset.SizeBytes = int64(len(objects[0].Data))
+	asset.Checksum = hex.EncodeToString(checksum[:])
+
+	for i, object := range objects {
+		if err := s.store.Put(ctx, object); err != nil {
+			s.cleanup(asset, objects[:i], logger)
+			metrics.UploadsTotal.WithLabelValues(string(upload.Kind), "failed").Inc()
+			return nil, err
+		}
+	}
+	if err := s.repo.Create(ctx, asset); err != nil {
+		s.cleanup(asset, objects, logger)
+		metrics.UploadsTotal.WithLabelValues(string(upload.Kind), "failed").Inc()
+		return nil, fmt.Errorf("failed to record upload: %w", err)
+	}
+
+	metrics.UploadsTotal.WithLabelValues(string(upload.Kind), "stored").Inc()
+	metrics.UploadBytes.WithLabelValues(string(upload.Kind)).Observe(float64(asset.SizeBytes))
+	logger.Info("Upload stored",
+		zap.String("media_id", asset.ID),
+		zap.String("content_type", asset.ContentType),
+		zap.Int64("size_bytes", asset.SizeBytes),
+		zap.Int("variants", len(asset.Variants)),
+	)
+	return asset, nil
+}
+
+// scan rejects infected uploads and those that couldn't be scanned
+func (s *Service) scan(ctx context.Context, upload Upload, logger *zap.Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, s.scanTimeout)
+	defer cancel()
+
+	start := time.Now()
+	verdict, err := s.scanner.Scan(ctx, upload.Data)
+	metrics.ScanDuration.WithLabelValues(s.scanner.Name()).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.ScansTotal.WithLabelValues(s.scanner.Name(), "error").Inc()
+		metrics.UploadsTotal.WithLabelValues(string(upload.Kind), "failed").Inc()
+		logger.Error("Failed to scan upload", zap.String("scanner", s.scanner.Name()), zap.Error(err))
+		return ErrScanFailed
+	}
+	if !verdict.Clean {
+		metrics.ScansTotal.WithLabelValues(s.scanner.Name(), "infected").Inc()
+		metrics.UploadsTotal.WithLabelValues(string(upload.Kind), "infected").Inc()
+		logger.Warn("Rejected infected upload",
+			zap.String("scanner", s.scanner.Name()),
+			zap.String("signature", verdict.Signature),
+			zap.String("filename", upload.Filename),
+			zap.String("uploaded_by", upload.UploadedBy),
+		)
+		return domain.ErrInfected
+	}
+	metrics.ScansTotal.WithLabelValues(s.scanner.Name(), "clean").Inc()
+	return nil
+}
+
+// cleanup removes the stored files of an upload that failed
+func (s *Service) cleanup(asset *domain.Asset, objects []storage.Object, logger *zap.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	keys := make([]string, len(objects))
+	for i, object := range objects {
+		keys[i] = object.Key
+	}
+	if err := s.store.Delete(ctx, keys...); err != nil {
+		logger.Warn("Failed to remove files of failed upload", zap.String("media_id", asset.ID), zap.Error(err))
+	}
+}
+
+// Get returns an asset
+func (s *Service) Get(ctx context.Context, id string) (*domain.Asset, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// List returns up to limit assets matching filter after a position,
+// newest first, and how many match in all
+func (s *Service) List(ctx context.Context, filter repository.Filter, limit int, after *repository.ListPosition) ([]*domain.Asset, int64, error) {
+	assets, err := s.repo.List(ctx, filter, limit, after)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return assets, total, nil
+}
+
+// Delete removes an asset's record and its files
+func (s *Service) Delete(ctx context.Context, asset *domain.Asset) error {
+	if err := s.repo.Delete(ctx, asset.ID); err != nil {
+		return err
+	}
+
+	keys := []string{asset.Key(domain.Original)}
+	for _, variant := range asset.Variants {
+		keys = append(keys, asset.Key(variant.Name))
+	}
+	// The record is gone, so files left behind are unreachable; they are
+	// logged for cleaning up by hand
+	if err := s.store.Delete(ctx, keys...); err != nil {
+		logging.WithContext(ctx, s.logger).Error("Failed to delete files of deleted asset",
+			zap.String("media_id", asset.ID),
+			zap.Strings("keys", keys),
+			zap.Error(err),
+		)
+	}
+	return nil
+}
+
+// SetURLs sets the URLs the asset and its variants are downloaded from:
+// stable URLs for public assets, signed URLs, valid for the store's TTL,
+// for private ones
+func (s *Service) SetURLs(ctx context.Context, asset *domain.Asset) error {
+	if asset.Public() {
+		asset.URL = s.ContentURL(asset, "")
+		for i := range asset.Variants {
+			asset.Variants[i].URL = s.ContentURL(asset, asset.Variants[i].Name)
+		}
+		return nil
+	}
+
+	var err error
+	if asset.URL, err = s.store.SignedURL(ctx, asset.Key(domain.Original), asset.Filename); err != nil {
+		return err
+	}
+	for i := range asset.Variants {
+		if asset.Variants[i].URL, err = s.store.SignedURL(ctx, asset.Key(asset.Variants[i].Name), ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ContentURL is the stable URL of a public asset or, with a name, one of
+// its variants, which catalog and profiles keep
+func (s *Service) ContentURL(asset *domain.Asset, variant string) string {
+	contentURL := s.publicURL + "/api/v1/media/" + url.PathEscape(asset.ID) + "/content"
+	if variant != "" {
+		contentURL += "?variant=" + url.QueryEscape(variant)
+	}
+	return contentURL
+}
+
+// SignedURL returns a signed URL of the named variant, or of the original
+// when the asset has no such variant: images too small for a variant are
+// served as they are
+func (s *Service) SignedURL(ctx context.Context, asset *domain.Asset, variant string) (string, error) {
+	if _, ok := asset.Variant(variant); ok {
+		return s.store.SignedURL(ctx, asset.Key(variant), "")
+	}
+	filename := ""
+	if !asset.Public() {
+		filename = asset.Filename
+	}
+	return s.store.SignedURL(ctx, asset.Key(domain.Original), filename)
+}
+
+// Open opens an asset's original file; the caller closes it
+func (s *Service) Open(ctx context.Context, asset *domain.Asset) (io.ReadCloser, error) {
+	return s.store.Get(ctx, asset.Key(domain.Original))
+}
+
+// URLTTL is how long signed URLs are valid
+func (s *Service) URLTTL() time.Duration {
+	return s.store.TTL()
+}
+
+// Ping checks that the database and the bucket are reachable
+func (s *Service) Ping(ctx context.Context) error {
+	if err := s.repo.Ping(ctx); err != nil {
+		return fmt.Errorf("database: %w", err)
+	}
+	if err := s.store.Ping(ctx); err != nil {
+		return fmt.Errorf("storage: %w", err)
+	}
+	return nil
+}
+
+func isImage(contentType string) bool {
+	for _, image := range domain.Images {
+		if image == contentType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// UploadsTotal counts uploads by kind and result: stored, rejected
+	// (type, size or dimensions not allowed), infected or failed
+	UploadsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "media_uploads_total",
+		Help: "Uploads, per kind and result",
+	}, []string{"kind", "result"})
+
+	// UploadBytes is the size of stored uploads, before variants
+	UploadBytes = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "media_upload_bytes",
+		Help:    "Size of stored uploads",
+		Buckets: prometheus.ExponentialBuckets(16<<10, 4, 7),
+	}, []string{"kind"})
+
+	// ScansTotal counts virus scans by scanner and result: clean, infected
+	// or error
+	ScansTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "media_scans_total",
+		Help: "Virus scans of uploads, per scanner and result",
+	}, []string{"scanner", "result"})
+
+	// ScanDuration is how long scans took
+	ScanDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "media_scan_duration_seconds",
+		Help:    "Virus scan duration",
+		Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"scanner"})
+)
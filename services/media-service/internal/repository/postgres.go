@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	shareddb "github.com/ecommerce-platform/shared/go/db"
+	"github.com/ecommerce/media-service/internal/domain"
+	"github.com/google/uuid"
+)
+
+const assetColumns = `id, tenant_id, kind, owner_type, owner_id, user_id, filename, content_type,
+	size_bytes, checksum_sha256, width, height, variants, uploaded_by, created_at`
+
+type postgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *sql.DB) MediaRepository {
+	return &postgresRepository{db: db}
+}
+
+// tenantID is the tenant ctx's operations are scoped to
+func tenantID(ctx context.Context) string {
+	return sharedauth.TenantFromContext(ctx)
+}
+
+// Ping checks that the database is reachable
+func (r *postgresRepository) Ping(ctx context.Context) error {
+	return shareddb.Check(ctx, r.db)
+}
+
+// Create stores an asset's record
+func (r *postgresRepository) Create(ctx context.Context, asset *domain.Asset) error {
+	if asset.ID == "" {
+		asset.ID = uuid.New().String()
+	}
+	if asset.CreatedAt.IsZero() {
+		asset.CreatedAt = time.Now().UTC()
+	}
+	asset.TenantID = tenantID(ctx)
+
+	if asset.Variants == nil {
+		asset.Variants = []domain.Variant{}
+	}
+	variants, err := json.Marshal(asset.Variants)
+	if err != nil {
+		return fmt.Errorf("failed to encode variants: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO media_assets (`+assetColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`, asset.ID, asset.TenantID, asset.Kind, asset.OwnerType, asset.OwnerID, asset.UserID,
+		asset.Filename, asset.ContentType, asset.SizeBytes, asset.Checksum,
+		asset.Width, asset.Height, variants, asset.UploadedBy, asset.CreatedAt)
+	return err
+}
+
+// Get returns an asset
+func (r *postgresRepository) Get(ctx context.Context, id string) (*domain.Asset, error) {
+	asset, err := scanAsset(r.db.QueryRowContext(ctx, `
+		SELECT `+assetColumns+` FROM media_assets WHERE tenant_id = $1 AND id = $2
+	`, tenantID(ctx), id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrAssetNotFound
+	}
+	return asset, err
+}
+
+// List returns up to limit assets matching filter after a position, newest
+// first
+func (r *postgresRepository) List(ctx context.Context, filter Filter, limit int, after *ListPosition) ([]*domain.Asset, error) {
+	where, args := filter.where(ctx)
+	if after != nil {
+		args = append(args, after.CreatedAt, after.ID)
+		where = append(where, fmt.Sprintf("(created_at, id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+	args = append(args, limit)
+
+	query := `SELECT ` + assetColumns + ` FROM media_assets WHERE ` + strings.Join(where, " AND ") +
+		fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assets []*domain.Asset
+	for rows.Next() {
+		asset, err := scanAsset(rows)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, asset)
+	}
+	return assets, rows.Err()
+}
+
+// Count returns how many assets match filter
+func (r *postgresRepository) Count(ctx context.Context, filter Filter) (int64, error) {
+	where, args := filter.where(ctx)
+	var count int64
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM media_assets WHERE `+strings.Join(where, " AND "), args...).Scan(&count)
+	return count, err
+}
+
+// where returns the conditions and arguments selecting the filter's assets
+// in ctx's tenant
+func (f Filter) where(ctx context.Context) ([]string, []interface{}) {
+	where := []string{"tenant_id = $1"}
+	args := []interface{}{tenantID(ctx)}
+	add := func(column, value string) {
+		if value == "" {
+			return
+		}
+		args = append(args, value)
+		where = append(where, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	add("kind", string(f.Kind))
+	add("owner_type", f.OwnerType)
+	add("owner_id", f.OwnerID)
+	add("user_id", f.UserID)
+	return where, args
+}
+
+// Delete removes an asset's record
+func (r *postgresRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM media_assets WHERE tenant_id = $1 AND id = $2`, tenantID(ctx), id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrAssetNotFound
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAsset(row scanner) (*domain.Asset, error) {
+	var asset domain.Asset
+	var variants []byte
+	if err := row.Scan(
+		&asset.ID, &asset.TenantID, &asset.Kind, &asset.OwnerType, &asset.OwnerID, &asset.UserID,
+		&asset.Filename, &asset.ContentType, &asset.SizeBytes, &asset.Checksum,
+		&asset.Width, &asset.Height, &variants, &asset.UploadedBy, &asset.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(variants, &asset.Variants); err != nil {
+		return nil, fmt.Errorf("failed to decode variants: %w", err)
+	}
+	return &asset, nil
+}
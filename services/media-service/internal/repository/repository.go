@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ecommerce/media-service/internal/domain"
+)
+
+// Filter selects assets; empty fields match any
+type Filter struct {
+	Kind      domain.Kind
+	OwnerType string
+	OwnerID   string
+	UserID    string
+}
+
+// ListPosition is the last asset of a page, by creation time with ties
+// broken by ID
+type ListPosition struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+}
+
+// MediaRepository stores asset records. Operations are scoped to the
+// tenant of their context.
+type MediaRepository interface {
+	// Create stores an asset's record, setting its ID and creation time
+	// unless they are set
+	Create(ctx context.Context, asset *domain.Asset) error
+	// Get returns an asset, or domain.ErrAssetNotFound
+	Get(ctx context.Context, id string) (*domain.Asset, error)
+	// List returns up to limit assets matching filter after a position,
+	// newest first
+	List(ctx context.Context, filter Filter, limit int, after *ListPosition) ([]*domain.Asset, error)
+	Count(ctx context.Context, filter Filter) (int64, error)
+	// Delete removes an asset's record, or returns domain.ErrAssetNotFound
+	Delete(ctx context.Context, id string) error
+
+	// Ping checks that the database is reachable
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,85 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamdChunkSize is the size of the chunks files are streamed to clamd in;
+// clamd's StreamMaxLength bounds the whole file
+const clamdChunkSize = 64 << 10
+
+// clamAV scans files with clamd's INSTREAM command over TCP
+type clamAV struct {
+	addr    string
+	timeout time.Duration
+}
+
+// NewClamAV creates a scanner using the clamd at addr, e.g. clamav:3310
+func NewClamAV(addr string, timeout time.Duration) Scanner {
+	return &clamAV{addr: addr, timeout: timeout}
+}
+
+func (c *clamAV) Name() string {
+	return "clamav"
+}
+
+// Scan streams the file to clamd and reads its verdict: "stream: OK", or
+// "stream: <signature> FOUND"
+func (c *clamAV) Scan(ctx context.Context, data []byte) (Verdict, error) {
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return Verdict{}, err
+	}
+
+	// Each chunk is prefixed with its length; a zero length ends the file
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return Verdict{}, fmt.Errorf("failed to send to clamd: %w", err)
+	}
+	size := make([]byte, 4)
+	for start := 0; start < len(data); start += clamdChunkSize {
+		chunk := data[start:min(start+clamdChunkSize, len(data))]
+		binary.BigEndian.PutUint32(size, uint32(len(chunk)))
+		if _, err := conn.Write(size); err != nil {
+			return Verdict{}, fmt.Errorf("failed to send to clamd: %w", err)
+		}
+		if _, err := conn.Write(chunk); err != nil {
+			return Verdict{}, fmt.Errorf("failed to send to clamd: %w", err)
+		}
+	}
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := conn.Write(size); err != nil {
+		return Verdict{}, fmt.Errorf("failed to send to clamd: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	reply = strings.TrimSpace(strings.TrimPrefix(strings.TrimSuffix(reply, "\x00"), "stream:"))
+
+	switch {
+	case reply == "OK":
+		return Verdict{Clean: true}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return Verdict{Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	default:
+		// e.g. "INSTREAM size limit exceeded. ERROR"
+		return Verdict{}, fmt.Errorf("clamd failed to scan: %s", reply)
+	}
+}
@@ -0,0 +1,63 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// httpScanner has an external service scan files. The file is POSTed as
+// the request body, with its type in Content-Type, and the service answers
+// 200 with {"clean": true} or {"clean": false, "signature": "..."}.
+type httpScanner struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewHTTP creates a scanner POSTing files to url through transport
+func NewHTTP(url string, timeout time.Duration, transport http.RoundTripper) Scanner {
+	return &httpScanner{
+		url:        url,
+		httpClient: &http.Client{Timeout: timeout, Transport: transport},
+	}
+}
+
+func (s *httpScanner) Name() string {
+	return "http"
+}
+
+// Scan sends the file to the scanning service
+func (s *httpScanner) Scan(ctx context.Context, data []byte) (Verdict, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return Verdict{}, err
+	}
+	req.Header.Set("Content-Type", http.DetectContentType(data))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to call scanner: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return Verdict{}, fmt.Errorf("scanner returned status %d: %s", resp.StatusCode, detail)
+	}
+
+	var verdict struct {
+		Clean     *bool  `json:"clean"`
+		Signature string `json:"signature"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&verdict); err != nil {
+		return Verdict{}, fmt.Errorf("failed to decode scanner verdict: %w", err)
+	}
+	if verdict.Clean == nil {
+		return Verdict{}, fmt.Errorf("scanner verdict has no clean field")
+	}
+	return Verdict{Clean: *verdict.Clean, Signature: verdict.Signature}, nil
+}
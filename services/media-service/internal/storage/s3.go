@@ -0,0 +1,119 @@
+// Package storage keeps media files in an S3-compatible bucket and signs
+// the URLs they are downloaded from
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Object is a file to store
+type Object struct {
+	Key         string
+	ContentType string
+	Data        []byte
+}
+
+// Store keeps files in a bucket. The bucket stays private: files are read
+// through URLs signed for a limited time.
+type Store struct {
+	client  *s3.Client
+	presign *s3.PresignClient
+	bucket  string
+	ttl     time.Duration
+}
+
+// NewS3 creates a store in bucket whose signed URLs are valid for ttl
+func NewS3(client *s3.Client, bucket string, ttl time.Duration) *Store {
+	return &Store{
+		client:  client,
+		presign: s3.NewPresignClient(client),
+		bucket:  bucket,
+		ttl:     ttl,
+	}
+}
+
+// TTL is how long signed URLs are valid
+func (s *Store) TTL() time.Duration {
+	return s.ttl
+}
+
+// Put stores a file, replacing any at its key
+func (s *Store) Put(ctx context.Context, object Object) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(object.Key),
+		Body:        bytes.NewReader(object.Data),
+		ContentType: aws.String(object.ContentType),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", object.Key, err)
+	}
+	return nil
+}
+
+// Get opens a stored file; the caller closes it
+func (s *Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	return out.Body, nil
+}
+
+// Delete removes files; keys that don't exist are ignored
+func (s *Store) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	objects := make([]s3types.ObjectIdentifier, len(keys))
+	for i, key := range keys {
+		objects[i] = s3types.ObjectIdentifier{Key: aws.String(key)}
+	}
+	out, err := s.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(s.bucket),
+		Delete: &s3types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete objects: %w", err)
+	}
+	if len(out.Errors) > 0 {
+		return fmt.Errorf("failed to delete %s: %s", aws.ToString(out.Errors[0].Key), aws.ToString(out.Errors[0].Message))
+	}
+	return nil
+}
+
+// SignedURL returns a URL downloading key until the TTL passes. With a
+// filename, browsers save the file under that name rather than show it.
+func (s *Store) SignedURL(ctx context.Context, key, filename string) (string, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+	if filename != "" {
+		input.ResponseContentDisposition = aws.String(mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+
+	request, err := s.presign.PresignGetObject(ctx, input, s3.WithPresignExpires(s.ttl))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign URL for %s: %w", key, err)
+	}
+	return request.URL, nil
+}
+
+// Ping checks that the bucket is reachable
+func (s *Store) Ping(ctx context.Context) error {
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(s.bucket)})
+	return err
+}
@@ -0,0 +1,26 @@
+-- Uploaded files. The files themselves, and their variants, are in the
+-- bucket under <tenant_id>/<kind>/<id>/<original|variant name>.
+CREATE TABLE IF NOT EXISTS media_assets (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    kind VARCHAR(32) NOT NULL,
+    -- What the file belongs to, e.g. a product, order or return
+    owner_type VARCHAR(32) NOT NULL DEFAULT '',
+    owner_id VARCHAR(255) NOT NULL DEFAULT '',
+    -- The customer who may read a private file; empty for none
+    user_id VARCHAR(255) NOT NULL DEFAULT '',
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    checksum_sha256 CHAR(64) NOT NULL,
+    width INTEGER NOT NULL DEFAULT 0,
+    height INTEGER NOT NULL DEFAULT 0,
+    -- Resized copies of images: name, content type, size and dimensions
+    variants JSONB NOT NULL DEFAULT '[]',
+    -- Empty for files uploaded by services
+    uploaded_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_assets_owner ON media_assets(tenant_id, owner_type, owner_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_media_assets_user ON media_assets(tenant_id, user_id, created_at);
@@ -2,23 +2,67 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/ecommerce-platform/shared/go/audit"
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	"github.com/ecommerce-platform/shared/go/broker"
+	sharedconfig "github.com/ecommerce-platform/shared/go/config"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/httpmetrics"
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce-platform/shared/go/mtls"
+	"github.com/ecommerce-platform/shared/go/scheduler"
+	"github.com/ecommerce/notification-service/internal/branding"
+	"github.com/ecommerce/notification-service/internal/campaign"
+	"github.com/ecommerce/notification-service/internal/coalesce"
 	"github.com/ecommerce/notification-service/internal/config"
 	"github.com/ecommerce/notification-service/internal/consumer"
+	"github.com/ecommerce/notification-service/internal/digest"
 	"github.com/ecommerce/notification-service/internal/email"
+	"github.com/ecommerce/notification-service/internal/events"
 	"github.com/ecommerce/notification-service/internal/handlers"
+	"github.com/ecommerce/notification-service/internal/media"
+	"github.com/ecommerce/notification-service/internal/middleware"
+	"github.com/ecommerce/notification-service/internal/preferences"
+	"github.com/ecommerce/notification-service/internal/quiethours"
+	"github.com/ecommerce/notification-service/internal/ratelimit"
+	"github.com/ecommerce/notification-service/internal/replay"
+	"github.com/ecommerce/notification-service/internal/routing"
+	"github.com/ecommerce/notification-service/internal/schema"
 	"github.com/ecommerce/notification-service/internal/sms"
+	"github.com/ecommerce/notification-service/internal/store"
+	"github.com/ecommerce/notification-service/internal/subscriptions"
 	"github.com/ecommerce/notification-service/internal/templates"
+	"github.com/ecommerce/notification-service/internal/tracking"
+	"github.com/ecommerce/notification-service/internal/whatsapp"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
 	"go.uber.org/zap"
+
+	_ "github.com/lib/pq"
 )
 
 func main() {
 	// Initialize logger
-	logger, err := initLogger()
+	logger, err := logging.New(logging.Config{
+		ServiceName: "notification-service",
+		Environment: os.Getenv("ENVIRONMENT"),
+		Level:       os.Getenv("LOG_LEVEL"),
+	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
 		os.Exit(1)
@@ -33,48 +77,400 @@ func main() {
 		logger.Fatal("Failed to load configuration", zap.Error(err))
 	}
 
-	logger.Info("Configuration loaded",
-		zap.Strings("kafka_brokers", cfg.KafkaBrokers),
-		zap.Strings("kafka_topics", cfg.KafkaTopics),
-		zap.String("smtp_host", cfg.SMTPHost),
-		zap.Int("smtp_port", cfg.SMTPPort),
-	)
+	logger.Info("Configuration loaded", zap.Any("config", sharedconfig.Redacted(cfg)))
+
+	// Initialize mTLS
+	serviceTLS, err := mtls.New(cfg.TLS, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize mTLS", zap.Error(err))
+	}
+	defer serviceTLS.Close()
+
+	// Initialize tracing
+	cleanup, err := initTelemetry(cfg)
+	if err != nil {
+		logger.Fatal("Failed to initialize telemetry", zap.Error(err))
+	}
+	defer cleanup()
+
+	// Initialize PostgreSQL (notification history)
+	db, err := sql.Open("postgres", cfg.DatabaseURL)
+	if err != nil {
+		logger.Fatal("Failed to connect to database", zap.Error(err))
+	}
+	defer db.Close()
+
+	if err := db.Ping(); err != nil {
+		logger.Fatal("Failed to ping database", zap.Error(err))
+	}
+	logger.Info("Database connected")
+
+	notificationStore := store.NewPostgresStore(db)
+	digestStore := store.NewPostgresDigestStore(db)
+	deadLetterStore := store.NewPostgresDeadLetterStore(db)
+	inboxStore := store.NewPostgresInboxStore(db)
+	scheduledStore := store.NewPostgresScheduledStore(db)
+	smsSpendStore := store.NewPostgresSMSSpendStore(db)
+	manualSendStore := store.NewPostgresManualSendStore(db)
+	brandStore := store.NewPostgresBrandStore(db)
+	campaignStore := store.NewPostgresCampaignStore(db)
+	suppressionStore := store.NewPostgresSuppressionStore(db)
+
+	// Audit log of brand and template changes, in the database and on the
+	// audit topic
+	auditSinks := []audit.Sink{audit.NewPostgresSink(db)}
+	if cfg.AuditTopic != "" {
+		auditProducer := sharedkafka.NewProducer(sharedkafka.ProducerConfig{Brokers: cfg.KafkaBrokers, Topic: cfg.AuditTopic}, logger)
+		defer auditProducer.Close()
+		auditSinks = append(auditSinks, audit.NewKafkaSink(auditProducer))
+	}
+	auditor := audit.New(audit.Config{Service: "notification-service", Sinks: auditSinks}, logger)
+
+	// Initialize Redis (rate limiting)
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddr,
+		Password: cfg.RedisPassword,
+		DB:       cfg.RedisDB,
+	})
+	defer redisClient.Close()
+
+	if err := redisClient.Ping(context.Background()).Err(); err != nil {
+		logger.Fatal("Failed to connect to Redis", zap.Error(err))
+	}
+	logger.Info("Redis connected")
+
+	limiter := ratelimit.NewLimiter(redisClient, map[string]int{
+		string(store.ChannelEmail):    cfg.EmailRateLimitPerHour,
+		string(store.ChannelSMS):      cfg.SMSRateLimitPerHour,
+		string(store.ChannelWhatsApp): cfg.WhatsAppRateLimit,
+	})
+
+	// Initialize preferences client
+	preferencesClient := preferences.NewClient(cfg, serviceTLS.Transport(), logger)
+	logger.Info("Preferences client initialized", zap.String("user_service_url", cfg.UserServiceURL))
+
+	// Storefront brands; events without a brand_id use the default brand
+	brands := branding.NewBrands(cfg, brandStore, logger)
 
 	// Initialize template engine
-	templateEngine, err := templates.NewTemplateEngine(cfg.TemplatesDir, logger)
+	templateEngine, err := templates.NewTemplateEngine(cfg.TemplatesDir, branding.Template(brands.Default()), logger)
 	if err != nil {
 		logger.Fatal("Failed to initialize template engine", zap.Error(err))
 	}
+	templateEngine.OnReload(func(file string) {
+		auditor.Log(context.Background(), audit.Entry{
+			Action:   "template.reloaded",
+			Resource: audit.Resource{Type: "template_file", ID: file},
+			Metadata: map[string]string{"templates_dir": cfg.TemplatesDir},
+		})
+	})
 	logger.Info("Template engine initialized")
 
 	// Initialize email sender
-	emailSender := email.NewEmailSender(cfg, logger)
-	logger.Info("Email sender initialized")
+	emailSender, err := email.NewEmailSender(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize email sender", zap.Error(err))
+	}
+	logger.Info("Email sender initialized", zap.Strings("providers", cfg.EmailProviders))
 
 	// Initialize SMS sender
-	smsSender := sms.NewSMSSender(cfg, logger)
-	logger.Info("SMS sender initialized")
+	smsSender, err := sms.NewSMSSender(context.Background(), cfg, smsSpendStore, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize SMS sender", zap.Error(err))
+	}
+	logger.Info("SMS sender initialized", zap.Strings("providers", cfg.SMSProviders))
+
+	// Initialize WhatsApp sender
+	whatsAppSender, err := whatsapp.NewWhatsAppSender(cfg, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize WhatsApp sender", zap.Error(err))
+	}
+	logger.Info("WhatsApp sender initialized", zap.Strings("providers", cfg.WhatsAppProviders))
+
+	// Initialize quiet hours
+	quietHours, err := quiethours.NewPolicy(cfg)
+	if err != nil {
+		logger.Fatal("Failed to initialize quiet hours", zap.Error(err))
+	}
+	logger.Info("Quiet hours initialized",
+		zap.String("start", cfg.QuietHoursStart),
+		zap.String("end", cfg.QuietHoursEnd),
+		zap.Strings("categories", cfg.QuietHoursCategories),
+		zap.String("default_timezone", cfg.DefaultTimezone),
+	)
+
+	// Initialize channel policies
+	channelPolicies, err := routing.NewPolicies(cfg)
+	if err != nil {
+		logger.Fatal("Failed to initialize channel policies", zap.Error(err))
+	}
+	logger.Info("Channel policies initialized", zap.Any("overrides", cfg.ChannelPolicies))
+
+	// Initialize click tracking
+	clickTracker := tracking.NewClickTracker(cfg)
+	if cfg.ClickTrackingBaseURL != "" {
+		logger.Info("Click tracking enabled",
+			zap.String("base_url", cfg.ClickTrackingBaseURL),
+			zap.Strings("categories", cfg.ClickTrackingCategories),
+		)
+	}
+
+	// Delivery status events close the loop for order-service and analytics
+	statusEvents := events.NewPublisher(cfg, logger)
+	if cfg.StatusEventsTopic != "" {
+		logger.Info("Delivery status events enabled", zap.String("topic", cfg.StatusEventsTopic))
+	}
 
 	// Initialize notification handler
 	notificationHandler := handlers.NewNotificationHandler(
 		emailSender,
 		smsSender,
+		whatsAppSender,
 		templateEngine,
+		notificationStore,
+		digestStore,
+		inboxStore,
+		scheduledStore,
+		quietHours,
+		preferencesClient,
+		subscriptions.NewClient(cfg, serviceTLS.Transport(), logger),
+		media.NewClient(cfg, serviceTLS.Transport(), logger),
+		limiter,
+		channelPolicies,
+		clickTracker,
+		brands,
+		statusEvents,
+		cfg,
 		logger,
 	)
 	logger.Info("Notification handler initialized")
 
-	// Initialize Kafka consumer
-	kafkaConsumer := consumer.NewConsumer(cfg, notificationHandler, logger)
-	logger.Info("Kafka consumer initialized")
+	// Collapse bursts of the same event per customer and order
+	coalesceBuffer := coalesce.NewBuffer(redisClient, notificationHandler, cfg, logger)
+
+	// Scheduled jobs, each run by one replica at a time
+	jobs := scheduler.New(db, scheduler.Config{Service: "notification-service"}, logger)
+
+	// Digests are sent by a scheduled job
+	digestDispatcher := digest.NewDispatcher(
+		digestStore,
+		notificationStore,
+		templateEngine,
+		emailSender,
+		quietHours,
+		brands,
+		statusEvents,
+		time.Duration(cfg.DigestWindow)*time.Minute,
+		logger,
+	)
+	if err := jobs.Register(scheduler.Job{
+		Name:     "digests.dispatch",
+		Schedule: cfg.DigestSchedule,
+		Run:      digestDispatcher.Run,
+	}); err != nil {
+		logger.Fatal("Failed to register digest job", zap.Error(err))
+	}
+
+	// Initialize scheduled notification releaser
+	releaser := quiethours.NewReleaser(scheduledStore, notificationStore, emailSender, smsSender, clickTracker, brands, statusEvents, logger)
+
+	// Initialize campaign runner; campaigns have their own worker pool
+	campaignRunner := campaign.NewRunner(
+		campaignStore,
+		suppressionStore,
+		notificationStore,
+		templateEngine,
+		emailSender,
+		preferencesClient,
+		clickTracker,
+		brands,
+		statusEvents,
+		cfg,
+		logger,
+	)
+
+	// Initialize HTTP handlers
+	healthHandler := handlers.NewHealthHandler(db, redisClient, cfg, logger)
+
+	webhookHandler := handlers.NewWebhookHandler(notificationStore, suppressionStore, sms.NewTwilioProvider(cfg), statusEvents, cfg, logger)
+	templateHandler := handlers.NewTemplateHandler(templateEngine, emailSender, notificationStore, brands, cfg, logger)
+	brandHandler := handlers.NewBrandHandler(brandStore, brands, auditor, logger)
+	inboxHandler := handlers.NewInboxHandler(inboxStore, logger)
+	clickHandler := handlers.NewClickHandler(notificationStore, clickTracker, logger)
+	campaignHandler := handlers.NewCampaignHandler(campaignStore, brandStore, templateEngine, logger)
+	suppressionHandler := handlers.NewSuppressionHandler(suppressionStore, logger)
+	smsSpendHandler := handlers.NewSMSSpendHandler(smsSpendStore, cfg, logger)
+	verifier, err := sharedauth.NewVerifier(sharedauth.Config{Secret: cfg.JWTSecret, Issuer: sharedauth.Issuer})
+	if err != nil {
+		logger.Fatal("Failed to create token verifier", zap.Error(err))
+	}
+	authMiddleware := sharedauth.NewMiddleware(verifier, logger)
+
+	// Load event schemas
+	schemaRegistry, err := schema.NewRegistry()
+	if err != nil {
+		logger.Fatal("Failed to load event schemas", zap.Error(err))
+	}
+	logger.Info("Event schemas loaded", zap.Int("event_types", schemaRegistry.Len()))
+
+	// Initialize consumer on the configured message broker
+	messageBroker, err := broker.New(broker.Config{
+		Type:         cfg.MessageBroker,
+		KafkaBrokers: cfg.KafkaBrokers,
+		RabbitMQURL:  cfg.RabbitMQURL,
+		NATSURL:      cfg.NATSURL,
+	}, logger)
+	if err != nil {
+		logger.Fatal("Failed to create message broker", zap.Error(err))
+	}
+	defer messageBroker.Close()
+
+	eventConsumer := consumer.NewConsumer(cfg, messageBroker, coalesceBuffer, schemaRegistry, deadLetterStore, logger)
+	logger.Info("Consumer initialized", zap.String("broker", messageBroker.Type()))
+
+	dlqHandler := handlers.NewDLQHandler(deadLetterStore, eventConsumer, logger)
+
+	// Replays read Kafka partitions directly, so they are only served when
+	// events are consumed from Kafka
+	replayer := replay.NewReplayer(cfg.KafkaBrokers, eventConsumer, logger)
+	replayHandler := handlers.NewReplayHandler(replayer, logger)
+
+	// Manual sends skip the coalesce buffer so support sees the outcome
+	manualSendHandler := handlers.NewManualSendHandler(notificationHandler, schemaRegistry, notificationStore, manualSendStore, logger)
+
+	// Setup Gin
+	if cfg.Environment == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router := gin.New()
+	router.Use(httpmetrics.Middleware("notification-service"), gin.Recovery(), apperrors.Middleware(logger))
+
+	// Health checks and metrics
+	router.GET("/healthz", healthHandler.Healthz)
+	router.GET("/readyz", healthHandler.Readyz)
+	router.GET("/metrics", gin.WrapH(httpmetrics.Handler()))
+
+	v1 := router.Group("/api/v1")
+	{
+		webhooks := v1.Group("/webhooks")
+		{
+			webhooks.POST("/twilio/status", webhookHandler.TwilioStatus)
+			webhooks.POST("/sendgrid/events", webhookHandler.SendGridEvents)
+		}
+
+		v1.GET("/clicks/:token", clickHandler.Click)
+
+		inbox := v1.Group("/users/:id/notifications")
+		inbox.Use(authMiddleware.Authenticate(), middleware.RequireSelfOrAdmin(logger))
+		{
+			inbox.GET("", inboxHandler.List)
+			inbox.POST("/read-all", inboxHandler.MarkAllRead)
+			inbox.POST("/:notificationId/read", inboxHandler.MarkRead)
+		}
+
+		tmpl := v1.Group("/templates")
+		tmpl.Use(sharedauth.RequireServiceKey(cfg.ServiceAPIKey, logger))
+		{
+			tmpl.POST("/:name/preview", templateHandler.Preview)
+			tmpl.POST("/:name/test-send", templateHandler.TestSend)
+			tmpl.GET("/:name/variants", templateHandler.Variants)
+
+			tmpl.GET("/brands", brandHandler.List)
+			tmpl.GET("/brands/:brandId", brandHandler.Get)
+			tmpl.PUT("/brands/:brandId", brandHandler.Put)
+			tmpl.DELETE("/brands/:brandId", brandHandler.Delete)
+		}
+
+		smsGroup := v1.Group("/sms")
+		smsGroup.Use(sharedauth.RequireServiceKey(cfg.ServiceAPIKey, logger))
+		{
+			smsGroup.GET("/spend", smsSpendHandler.Spend)
+		}
+
+		dlq := v1.Group("/dlq")
+		dlq.Use(sharedauth.RequireServiceKey(cfg.ServiceAPIKey, logger))
+		{
+			dlq.GET("", dlqHandler.List)
+			dlq.GET("/:id", dlqHandler.Get)
+			dlq.POST("/redrive", dlqHandler.Redrive)
+			dlq.POST("/purge", dlqHandler.Purge)
+		}
+
+		if messageBroker.Type() == broker.TypeKafka {
+			replays := v1.Group("/replays")
+			replays.Use(sharedauth.RequireServiceKey(cfg.ServiceAPIKey, logger))
+			{
+				replays.POST("", replayHandler.Start)
+				replays.GET("", replayHandler.List)
+				replays.GET("/:id", replayHandler.Get)
+				replays.POST("/:id/cancel", replayHandler.Cancel)
+			}
+		}
+
+		manual := v1.Group("/notifications")
+		manual.Use(authMiddleware.Authenticate(), authMiddleware.RequireRole(sharedauth.RoleAdmin, sharedauth.RoleSupport))
+		{
+			manual.POST("/send", manualSendHandler.Send)
+			manual.GET("/manual-sends", manualSendHandler.List)
+			manual.GET("/history", manualSendHandler.History)
+		}
+
+		campaigns := v1.Group("/campaigns")
+		campaigns.Use(authMiddleware.Authenticate(), authMiddleware.RequireRole(sharedauth.RoleAdmin))
+		{
+			campaigns.POST("/segments", campaignHandler.CreateSegment)
+			campaigns.GET("/segments", campaignHandler.ListSegments)
+			campaigns.POST("", campaignHandler.Create)
+			campaigns.GET("", campaignHandler.List)
+			campaigns.GET("/:id", campaignHandler.Get)
+			campaigns.POST("/:id/pause", campaignHandler.Pause)
+			campaigns.POST("/:id/resume", campaignHandler.Resume)
+			campaigns.POST("/:id/cancel", campaignHandler.Cancel)
+		}
+
+		suppressions := v1.Group("/suppressions")
+		suppressions.Use(authMiddleware.Authenticate(), authMiddleware.RequireRole(sharedauth.RoleAdmin))
+		{
+			suppressions.GET("", suppressionHandler.List)
+			suppressions.POST("", suppressionHandler.Add)
+			suppressions.DELETE("/:address", suppressionHandler.Remove)
+		}
+	}
+
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Port),
+		Handler:      router,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
 
 	// Start consumer in a goroutine
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	errChan := make(chan error, 1)
+	errChan := make(chan error, 2)
 	go func() {
-		if err := kafkaConsumer.Start(ctx, cfg.KafkaTopics); err != nil {
+		if err := eventConsumer.Start(ctx, cfg.KafkaTopics); err != nil {
+			errChan <- err
+		}
+	}()
+
+	go jobs.Start(ctx)
+	go releaser.Start(ctx)
+	go campaignRunner.Start(ctx)
+	go coalesceBuffer.Start(ctx)
+
+	go func() {
+		if err := templateEngine.Watch(ctx); err != nil {
+			logger.Error("Template hot-reload disabled", zap.Error(err))
+		}
+	}()
+
+	go func() {
+		logger.Info("HTTP server starting", zap.Int("port", cfg.Port))
+		if err := serviceTLS.ListenAndServe(srv); err != nil && err != http.ErrServerClosed {
 			errChan <- err
 		}
 	}()
@@ -91,24 +487,75 @@ func main() {
 	case <-sigChan:
 		logger.Info("Received shutdown signal")
 	case err := <-errChan:
-		logger.Error("Consumer error", zap.Error(err))
+		logger.Error("Service error", zap.Error(err))
 	}
 
 	// Graceful shutdown
 	logger.Info("Shutting down gracefully...")
 	cancel()
+	replayer.Shutdown()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("HTTP server forced to shutdown", zap.Error(err))
+	}
+
+	if err := auditor.Close(shutdownCtx); err != nil {
+		logger.Error("Failed to write pending audit records", zap.Error(err))
+	}
+
+	if err := eventConsumer.Close(); err != nil {
+		logger.Error("Failed to close consumer", zap.Error(err))
+	}
 
-	if err := kafkaConsumer.Close(); err != nil {
-		logger.Error("Failed to close Kafka consumer", zap.Error(err))
+	if err := statusEvents.Close(); err != nil {
+		logger.Error("Failed to flush status events", zap.Error(err))
 	}
 
 	logger.Info("Notification Service stopped")
 }
 
-func initLogger() (*zap.Logger, error) {
-	env := os.Getenv("ENVIRONMENT")
-	if env == "production" {
-		return zap.NewProduction()
+func initTelemetry(cfg *config.Config) (func(), error) {
+	ctx := context.Background()
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String("notification-service"),
+			semconv.ServiceVersionKey.String("1.0.0"),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
 	}
-	return zap.NewDevelopment()
+
+	traceExporter, err := otlptracegrpc.New(ctx,
+		otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint),
+		otlptracegrpc.WithInsecure(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	bsp := sdktrace.NewBatchSpanProcessor(traceExporter)
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())),
+		sdktrace.WithResource(res),
+		sdktrace.WithSpanProcessor(bsp),
+	)
+
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			fmt.Printf("Failed to shutdown tracer provider: %v\n", err)
+		}
+	}, nil
 }
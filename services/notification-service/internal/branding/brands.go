@@ -0,0 +1,133 @@
+package branding
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ecommerce/notification-service/internal/config"
+	"github.com/ecommerce/notification-service/internal/store"
+	"github.com/ecommerce/notification-service/internal/templates"
+	"go.uber.org/zap"
+)
+
+// cacheTTL bounds how long a brand change takes to reach other replicas
+const cacheTTL = time.Minute
+
+// Brands resolves the storefront brand notifications are themed for. The
+// default brand comes from configuration; others are managed through the
+// template API and cached briefly.
+type Brands struct {
+	store    store.BrandStore
+	defaults store.Brand
+	logger   *zap.Logger
+
+	mu    sync.Mutex
+	cache map[string]cachedBrand
+}
+
+type cachedBrand struct {
+	brand   *store.Brand
+	expires time.Time
+}
+
+// NewBrands creates a brand resolver
+func NewBrands(cfg *config.Config, brandStore store.BrandStore, logger *zap.Logger) *Brands {
+	return &Brands{
+		store: brandStore,
+		defaults: store.Brand{
+			Name:          cfg.BrandName,
+			LogoURL:       cfg.BrandLogoURL,
+			PrimaryColor:  cfg.BrandPrimaryColor,
+			SupportEmail:  cfg.SupportEmail,
+			StorefrontURL: cfg.StorefrontURL,
+			FromName:      cfg.FromName,
+			FromEmail:     cfg.FromEmail,
+		},
+		logger: logger,
+		cache:  make(map[string]cachedBrand),
+	}
+}
+
+// Default returns the default brand
+func (b *Brands) Default() *store.Brand {
+	brand := b.defaults
+	return &brand
+}
+
+// Resolve returns a brand with its empty settings filled in from the default
+// brand. No ID, an unknown one or a failed lookup resolve to the default
+// brand, so theming never stops a notification.
+func (b *Brands) Resolve(ctx context.Context, id string) *store.Brand {
+	if id == "" || b.store == nil {
+		return b.Default()
+	}
+
+	b.mu.Lock()
+	cached, ok := b.cache[id]
+	b.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		brand := *cached.brand
+		return &brand
+	}
+
+	found, err := b.store.GetBrand(ctx, id)
+	if err != nil && err != store.ErrNotFound {
+		b.logger.Error("Failed to load brand, using default", zap.String("brand_id", id), zap.Error(err))
+		return b.Default()
+	}
+
+	resolved := b.Default()
+	if err == store.ErrNotFound {
+		b.logger.Warn("Unknown brand, using default", zap.String("brand_id", id))
+	} else {
+		resolved = b.merge(found)
+	}
+
+	b.mu.Lock()
+	b.cache[id] = cachedBrand{brand: resolved, expires: time.Now().Add(cacheTTL)}
+	b.mu.Unlock()
+
+	brand := *resolved
+	return &brand
+}
+
+// Invalidate drops a changed brand from this replica's cache
+func (b *Brands) Invalidate(id string) {
+	b.mu.Lock()
+	delete(b.cache, id)
+	b.mu.Unlock()
+}
+
+// merge fills a brand's empty settings from the default brand. Emails are
+// sent in the brand's name unless it sets its own sender name.
+func (b *Brands) merge(brand *store.Brand) *store.Brand {
+	merged := *brand
+	fill := func(value *string, fallback string) {
+		if *value == "" {
+			*value = fallback
+		}
+	}
+	fill(&merged.Name, b.defaults.Name)
+	fill(&merged.LogoURL, b.defaults.LogoURL)
+	fill(&merged.PrimaryColor, b.defaults.PrimaryColor)
+	fill(&merged.SupportEmail, b.defaults.SupportEmail)
+	fill(&merged.StorefrontURL, b.defaults.StorefrontURL)
+	fill(&merged.FooterText, b.defaults.FooterText)
+	fill(&merged.FromName, merged.Name)
+	fill(&merged.FromEmail, b.defaults.FromEmail)
+	return &merged
+}
+
+// Template returns a brand as templates see it
+func Template(brand *store.Brand) templates.Brand {
+	return templates.Brand{
+		ID:            brand.ID,
+		Name:          brand.Name,
+		LogoURL:       brand.LogoURL,
+		PrimaryColor:  brand.PrimaryColor,
+		SupportEmail:  brand.SupportEmail,
+		StorefrontURL: brand.StorefrontURL,
+		FooterText:    brand.FooterText,
+	}
+}
@@ -0,0 +1,114 @@
+package breaker
+
+import (
+	"sync"
+	"time"
+)
+
+// State is the state of a circuit breaker
+type State int
+
+const (
+	// StateClosed lets every call through
+	StateClosed State = iota
+	// StateHalfOpen lets a trial call through after the cooldown
+	StateHalfOpen
+	// StateOpen rejects calls until the cooldown has elapsed
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// Breaker is a consecutive-failure circuit breaker. It opens after threshold
+// consecutive failures, and after cooldown lets calls through again in the
+// half-open state; the next success closes it, the next failure re-opens it.
+type Breaker struct {
+	threshold     int
+	cooldown      time.Duration
+	onStateChange func(State)
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+}
+
+// New creates a new circuit breaker. onStateChange, if not nil, is called
+// with the new state on every transition.
+func New(threshold int, cooldown time.Duration, onStateChange func(State)) *Breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	b := &Breaker{
+		threshold:     threshold,
+		cooldown:      cooldown,
+		onStateChange: onStateChange,
+	}
+	if onStateChange != nil {
+		onStateChange(StateClosed)
+	}
+	return b
+}
+
+// Allow reports whether a call may be attempted
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen {
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(StateHalfOpen)
+	}
+	return true
+}
+
+// Success records a successful call
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	if b.state != StateClosed {
+		b.setState(StateClosed)
+	}
+}
+
+// Failure records a failed call
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == StateHalfOpen || (b.state == StateClosed && b.failures >= b.threshold) {
+		b.openedAt = time.Now()
+		b.setState(StateOpen)
+	}
+}
+
+// State returns the current state
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *Breaker) setState(state State) {
+	b.state = state
+	if b.onStateChange != nil {
+		b.onStateChange(state)
+	}
+}
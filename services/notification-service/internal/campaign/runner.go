@@ -0,0 +1,346 @@
+package campaign
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ecommerce/notification-service/internal/branding"
+	"github.com/ecommerce/notification-service/internal/config"
+	"github.com/ecommerce/notification-service/internal/email"
+	"github.com/ecommerce/notification-service/internal/events"
+	"github.com/ecommerce/notification-service/internal/metrics"
+	"github.com/ecommerce/notification-service/internal/preferences"
+	"github.com/ecommerce/notification-service/internal/store"
+	"github.com/ecommerce/notification-service/internal/templates"
+	"github.com/ecommerce/notification-service/internal/tracking"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// EventType is the event type campaign emails are recorded under. It isn't a
+// listed category, so campaigns are marketing.
+const EventType = "campaign"
+
+// staleClaim is how long a claimed recipient may go unsent before another
+// instance takes it over
+const staleClaim = 10 * time.Minute
+
+// Runner sends scheduled campaigns. Recipients are claimed in batches and
+// sent by a dedicated worker pool, paced per email provider, so campaigns
+// never compete with event-driven notifications for workers.
+type Runner struct {
+	campaigns      store.CampaignStore
+	suppressions   store.SuppressionStore
+	notifications  store.NotificationStore
+	templateEngine *templates.TemplateEngine
+	emailSender    *email.EmailSender
+	preferences    *preferences.Client
+	clicks         *tracking.ClickTracker
+	brands         *branding.Brands
+	statusEvents   *events.Publisher
+	throttle       *providerThrottle
+	workers        int
+	batchSize      int
+	interval       time.Duration
+	logger         *zap.Logger
+}
+
+// NewRunner creates a new campaign runner
+func NewRunner(
+	campaigns store.CampaignStore,
+	suppressions store.SuppressionStore,
+	notifications store.NotificationStore,
+	templateEngine *templates.TemplateEngine,
+	emailSender *email.EmailSender,
+	preferencesClient *preferences.Client,
+	clickTracker *tracking.ClickTracker,
+	brands *branding.Brands,
+	statusEvents *events.Publisher,
+	cfg *config.Config,
+	logger *zap.Logger,
+) *Runner {
+	return &Runner{
+		campaigns:      campaigns,
+		suppressions:   suppressions,
+		notifications:  notifications,
+		templateEngine: templateEngine,
+		emailSender:    emailSender,
+		preferences:    preferencesClient,
+		clicks:         clickTracker,
+		brands:         brands,
+		statusEvents:   statusEvents,
+		throttle:       newProviderThrottle(cfg.CampaignProviderRates),
+		workers:        cfg.CampaignWorkers,
+		batchSize:      cfg.CampaignBatchSize,
+		interval:       5 * time.Second,
+		logger:         logger,
+	}
+}
+
+// Start runs due campaigns until the context is cancelled
+func (r *Runner) Start(ctx context.Context) {
+	r.logger.Info("Starting campaign runner",
+		zap.Int("workers", r.workers),
+		zap.Int("batch_size", r.batchSize),
+	)
+	defer r.throttle.stop()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Stopping campaign runner")
+			return
+		case <-ticker.C:
+			r.tick(ctx)
+		}
+	}
+}
+
+// tick starts due campaigns and sends running ones a batch at a time, in
+// turn, until none has pending recipients. The running list is re-read after
+// every round, so a pause takes effect after the batch in flight.
+func (r *Runner) tick(ctx context.Context) {
+	now := time.Now()
+
+	started, err := r.campaigns.StartDueCampaigns(ctx, now)
+	if err != nil {
+		r.logger.Error("Failed to start due campaigns", zap.Error(err))
+		return
+	}
+	for _, id := range started {
+		r.logger.Info("Campaign started", zap.String("campaign_id", id))
+	}
+
+	if released, err := r.campaigns.ReleaseStaleRecipients(ctx, now.Add(-staleClaim)); err != nil {
+		r.logger.Error("Failed to release stale campaign recipients", zap.Error(err))
+	} else if released > 0 {
+		r.logger.Warn("Released stale campaign recipients", zap.Int64("count", released))
+	}
+
+	for ctx.Err() == nil {
+		running, err := r.campaigns.ListRunningCampaigns(ctx)
+		if err != nil {
+			r.logger.Error("Failed to list running campaigns", zap.Error(err))
+			return
+		}
+
+		sent := 0
+		for _, c := range running {
+			sent += r.runBatch(ctx, c)
+		}
+		if sent == 0 {
+			break
+		}
+	}
+
+	completed, err := r.campaigns.CompleteFinishedCampaigns(ctx, time.Now())
+	if err != nil {
+		r.logger.Error("Failed to complete finished campaigns", zap.Error(err))
+		return
+	}
+	for _, id := range completed {
+		r.logger.Info("Campaign completed", zap.String("campaign_id", id))
+	}
+}
+
+// runBatch claims and sends one batch of a campaign's recipients and returns
+// how many were claimed
+func (r *Runner) runBatch(ctx context.Context, c *store.Campaign) int {
+	recipients, err := r.campaigns.ClaimRecipients(ctx, c.ID, r.batchSize)
+	if err != nil {
+		r.logger.Error("Failed to claim campaign recipients", zap.String("campaign_id", c.ID), zap.Error(err))
+		return 0
+	}
+	if len(recipients) == 0 {
+		return 0
+	}
+
+	// The list is read per batch, so addresses suppressed mid-campaign are
+	// skipped from the next batch on
+	addresses := make([]string, len(recipients))
+	for i, recipient := range recipients {
+		addresses[i] = recipient.Email
+	}
+	suppressed, err := r.suppressions.Suppressed(ctx, addresses)
+	if err != nil {
+		// Sending to bounced or complaining addresses hurts deliverability, so
+		// the batch stays claimed and is retried once the claim goes stale
+		r.logger.Error("Failed to check suppression list, postponing batch", zap.String("campaign_id", c.ID), zap.Error(err))
+		return 0
+	}
+
+	brand := r.brands.Resolve(ctx, c.BrandID)
+	jobs := make(chan *store.CampaignRecipient)
+	var wg sync.WaitGroup
+	for i := 0; i < r.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for recipient := range jobs {
+				r.send(ctx, c, brand, recipient, suppressed)
+			}
+		}()
+	}
+	for _, recipient := range recipients {
+		jobs <- recipient
+	}
+	close(jobs)
+	wg.Wait()
+
+	return len(recipients)
+}
+
+// send emails one recipient, unless they are suppressed or opted out of
+// marketing, and records the outcome on the recipient and in the
+// notification history
+func (r *Runner) send(ctx context.Context, c *store.Campaign, brand *store.Brand, recipient *store.CampaignRecipient, suppressed map[string]string) {
+	// Left claimed, the recipient is retried once its claim goes stale
+	if ctx.Err() != nil {
+		return
+	}
+
+	record := &store.Notification{
+		EventType: EventType,
+		Channel:   store.ChannelEmail,
+		Template:  c.Template,
+		Recipient: recipient.Email,
+		UserID:    recipient.UserID,
+		EventKey:  fmt.Sprintf("campaign/%s/%d", c.ID, recipient.ID),
+		BrandID:   c.BrandID,
+	}
+
+	// A recipient released after their email went out must not get it twice
+	if delivered, err := r.notifications.HasDelivered(ctx, record.EventKey, record.Channel, record.Template); err == nil && delivered {
+		recipient.Status = store.RecipientSent
+		r.updateRecipient(ctx, recipient)
+		return
+	}
+
+	if reason := r.suppressionReason(ctx, recipient, suppressed); reason != "" {
+		record.Status = store.StatusSuppressed
+		record.Reason = reason
+		r.finish(ctx, c, recipient, record)
+		return
+	}
+
+	data := make(map[string]interface{}, len(c.Data)+len(recipient.Data)+2)
+	for key, value := range c.Data {
+		data[key] = value
+	}
+	for key, value := range recipient.Data {
+		data[key] = value
+	}
+	data["CustomerName"] = recipient.Name
+	data["Email"] = recipient.Email
+	data = templates.WithBrand(data, branding.Template(brand))
+
+	subject, body, variant, err := r.templateEngine.RenderFor(c.Template, recipient.Email, data)
+	if err != nil {
+		record.Status = store.StatusFailed
+		record.Reason = fmt.Sprintf("failed to render template: %v", err)
+		r.finish(ctx, c, recipient, record)
+		return
+	}
+	record.Variant = variant
+
+	if r.clicks.Enabled(c.Template, preferences.CategoryMarketing) {
+		record.ID = uuid.New().String()
+		body = r.clicks.RewriteLinks(record.ID, body)
+	}
+
+	start := time.Now()
+	result, err := r.emailSender.Send(email.WithThrottle(ctx, r.throttle), email.Email{
+		To:        recipient.Email,
+		Subject:   subject,
+		Body:      body,
+		IsHTML:    true,
+		FromName:  brand.FromName,
+		FromEmail: brand.FromEmail,
+	})
+	// A send interrupted by shutdown is retried after a restart
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	metrics.SendDuration.WithLabelValues(string(record.Channel), record.Template, record.EventType).Observe(time.Since(start).Seconds())
+	if result != nil {
+		record.Provider = result.Provider
+		record.MessageID = result.MessageID
+	}
+	if err != nil {
+		record.Status = store.StatusFailed
+		record.Reason = err.Error()
+	} else {
+		record.Status = store.StatusSent
+	}
+
+	// The email went out, so its outcome is recorded even during shutdown
+	r.finish(context.WithoutCancel(ctx), c, recipient, record)
+}
+
+// suppressionReason returns why a recipient must not be emailed, or ""
+func (r *Runner) suppressionReason(ctx context.Context, recipient *store.CampaignRecipient, suppressed map[string]string) string {
+	if reason, ok := suppressed[strings.ToLower(strings.TrimSpace(recipient.Email))]; ok {
+		return "suppression list: " + reason
+	}
+
+	// Preferences that can't be loaded don't block the email, as for
+	// event-driven notifications
+	if recipient.UserID != "" && r.preferences != nil {
+		prefs, err := r.preferences.Get(ctx, recipient.UserID)
+		if err != nil {
+			r.logger.Warn("Failed to load preferences for campaign recipient",
+				zap.String("user_id", recipient.UserID),
+				zap.Error(err),
+			)
+		} else if allowed, reason := prefs.Allows(string(store.ChannelEmail), preferences.CategoryMarketing); !allowed {
+			return reason
+		}
+	}
+
+	return ""
+}
+
+// finish records a recipient's notification and outcome
+func (r *Runner) finish(ctx context.Context, c *store.Campaign, recipient *store.CampaignRecipient, record *store.Notification) {
+	metrics.NotificationsTotal.
+		WithLabelValues(string(record.Channel), record.Template, record.EventType, string(record.Status)).
+		Inc()
+	if record.Variant != "" && record.Status == store.StatusSent {
+		metrics.VariantSendsTotal.WithLabelValues(record.Template, record.Variant).Inc()
+	}
+
+	if err := r.notifications.Create(ctx, record); err != nil {
+		r.logger.Error("Failed to record campaign notification", zap.String("campaign_id", c.ID), zap.Error(err))
+	}
+	r.statusEvents.PublishStatus(ctx, record)
+
+	switch record.Status {
+	case store.StatusSent:
+		recipient.Status = store.RecipientSent
+	case store.StatusSuppressed:
+		recipient.Status = store.RecipientSuppressed
+	default:
+		recipient.Status = store.RecipientFailed
+	}
+	recipient.Error = record.Reason
+	recipient.NotificationID = record.ID
+	metrics.CampaignRecipientsTotal.WithLabelValues(c.Template, recipient.Status).Inc()
+
+	r.updateRecipient(ctx, recipient)
+}
+
+func (r *Runner) updateRecipient(ctx context.Context, recipient *store.CampaignRecipient) {
+	if err := r.campaigns.UpdateRecipient(ctx, recipient); err != nil {
+		r.logger.Error("Failed to update campaign recipient",
+			zap.String("campaign_id", recipient.CampaignID),
+			zap.Int64("recipient_id", recipient.ID),
+			zap.Error(err),
+		)
+	}
+}
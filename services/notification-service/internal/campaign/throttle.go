@@ -0,0 +1,42 @@
+package campaign
+
+import (
+	"context"
+	"time"
+)
+
+// providerThrottle paces campaign emails per provider. Ticks that nobody
+// waits for are dropped, so an idle provider never builds up a burst.
+type providerThrottle struct {
+	tickers map[string]*time.Ticker
+}
+
+func newProviderThrottle(rates map[string]int) *providerThrottle {
+	tickers := make(map[string]*time.Ticker, len(rates))
+	for provider, rate := range rates {
+		tickers[provider] = time.NewTicker(time.Second / time.Duration(rate))
+	}
+	return &providerThrottle{tickers: tickers}
+}
+
+// Wait blocks until the provider may send another email. Providers without a
+// rate aren't throttled.
+func (t *providerThrottle) Wait(ctx context.Context, provider string) error {
+	ticker, ok := t.tickers[provider]
+	if !ok {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-ticker.C:
+		return nil
+	}
+}
+
+func (t *providerThrottle) stop() {
+	for _, ticker := range t.tickers {
+		ticker.Stop()
+	}
+}
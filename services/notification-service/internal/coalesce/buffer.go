@@ -0,0 +1,193 @@
+package coalesce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/notification-service/internal/config"
+	"github.com/ecommerce/notification-service/internal/consumer"
+	"github.com/ecommerce/notification-service/internal/metrics"
+	"github.com/ecommerce/notification-service/internal/replay"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// dueKey is a sorted set of pending event keys scored by flush time
+const dueKey = "notification:coalesce:due"
+
+// flushBatchSize bounds the events flushed per tick
+const flushBatchSize = 100
+
+// Buffer collapses bursts of the same event type for the same customer and
+// order into one notification. The first event opens a window; events that
+// arrive before it closes replace the pending one, and only the latest is
+// handled when the window closes. Pending events are kept in Redis so they
+// survive restarts and are flushed by exactly one instance.
+type Buffer struct {
+	client   *redis.Client
+	next     consumer.EventHandler
+	types    map[string]bool
+	window   time.Duration
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+// pending is a buffered event with the context needed to handle it later
+type pending struct {
+	Event         consumer.Event `json:"event"`
+	Key           string         `json:"key"`
+	CorrelationID string         `json:"correlation_id"`
+}
+
+// NewBuffer creates a coalescing buffer in front of next for the event types
+// in COALESCE_EVENT_TYPES
+func NewBuffer(client *redis.Client, next consumer.EventHandler, cfg *config.Config, logger *zap.Logger) *Buffer {
+	types := make(map[string]bool, len(cfg.CoalesceEventTypes))
+	for _, t := range cfg.CoalesceEventTypes {
+		types[t] = true
+	}
+
+	return &Buffer{
+		client:   client,
+		next:     next,
+		types:    types,
+		window:   time.Duration(cfg.CoalesceWindow) * time.Second,
+		interval: time.Second,
+		logger:   logger,
+	}
+}
+
+// Handle buffers coalesced event types and passes everything else straight
+// to the next handler. Replays are never buffered so their outcomes are
+// reported in the run.
+func (b *Buffer) Handle(ctx context.Context, event consumer.Event) error {
+	key := b.key(event)
+	if key == "" || replay.FromContext(ctx) != nil {
+		return b.next.Handle(ctx, event)
+	}
+
+	payload, err := json.Marshal(pending{
+		Event:         event,
+		Key:           event.Key,
+		CorrelationID: logging.CorrelationID(ctx),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode event for coalescing: %w", err)
+	}
+
+	// The event is kept well past its flush time in case flushing falls behind
+	pipe := b.client.TxPipeline()
+	pipe.Set(ctx, key, payload, 10*b.window+time.Hour)
+	scheduled := pipe.ZAddNX(ctx, dueKey, redis.Z{
+		Score:  float64(time.Now().Add(b.window).Unix()),
+		Member: key,
+	})
+	if _, err := pipe.Exec(ctx); err != nil {
+		// Without Redis, send now rather than lose the notification
+		logging.WithContext(ctx, b.logger).Warn("Coalescing unavailable, handling event now", zap.Error(err))
+		return b.next.Handle(ctx, event)
+	}
+
+	if scheduled.Val() == 0 {
+		metrics.EventsCoalescedTotal.WithLabelValues(event.EventType).Inc()
+		logging.WithContext(ctx, b.logger).Info("Event coalesced with a pending one",
+			zap.String("event_type", event.EventType),
+			zap.String("order_id", event.OrderID),
+		)
+	}
+
+	return nil
+}
+
+// key identifies the (customer, order, event type) an event is coalesced by,
+// or returns "" when the event is not coalesced
+func (b *Buffer) key(event consumer.Event) string {
+	if b.window <= 0 || !b.types[event.EventType] {
+		return ""
+	}
+
+	customer, _ := event.Data["user_id"].(string)
+	if customer == "" {
+		customer, _ = event.Data["customer_email"].(string)
+	}
+	if customer == "" && event.OrderID == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("notification:coalesce:event:%s:%s:%s", event.EventType, customer, event.OrderID)
+}
+
+// Start flushes closed windows until the context is cancelled
+func (b *Buffer) Start(ctx context.Context) {
+	if len(b.types) == 0 || b.window <= 0 {
+		return
+	}
+
+	b.logger.Info("Starting coalescing buffer",
+		zap.Duration("window", b.window),
+		zap.Int("event_types", len(b.types)),
+	)
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.logger.Info("Stopping coalescing buffer")
+			return
+		case <-ticker.C:
+			b.flush(ctx)
+		}
+	}
+}
+
+// flush handles the latest event of every closed window
+func (b *Buffer) flush(ctx context.Context) {
+	keys, err := b.client.ZRangeByScore(ctx, dueKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(time.Now().Unix(), 10),
+		Count: flushBatchSize,
+	}).Result()
+	if err != nil {
+		b.logger.Error("Failed to list due coalesced events", zap.Error(err))
+		return
+	}
+
+	for _, key := range keys {
+		// Removing the key claims it, so only one instance flushes a window
+		claimed, err := b.client.ZRem(ctx, dueKey, key).Result()
+		if err != nil || claimed == 0 {
+			continue
+		}
+
+		payload, err := b.client.GetDel(ctx, key).Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			b.logger.Error("Failed to load coalesced event", zap.String("key", key), zap.Error(err))
+			continue
+		}
+
+		var p pending
+		if err := json.Unmarshal(payload, &p); err != nil {
+			b.logger.Error("Failed to decode coalesced event", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		p.Event.Key = p.Key
+
+		eventCtx := logging.WithCorrelationID(ctx, p.CorrelationID)
+		if err := b.next.Handle(eventCtx, p.Event); err != nil {
+			logging.WithContext(eventCtx, b.logger).Error("Failed to handle coalesced event",
+				zap.String("event_type", p.Event.EventType),
+				zap.String("order_id", p.Event.OrderID),
+				zap.Error(err),
+			)
+		}
+	}
+}
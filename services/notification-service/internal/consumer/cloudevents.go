@@ -0,0 +1,164 @@
+package consumer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Message formats, as reported by the event format metric
+const (
+	FormatBespoke              = "bespoke"
+	FormatCloudEventStructured = "cloudevents_structured"
+	FormatCloudEventBinary     = "cloudevents_binary"
+)
+
+const (
+	cloudEventsSpecVersion   = "1.0"
+	cloudEventsContentType   = "application/cloudevents+json"
+	cloudEventsHeaderPrefix  = "ce_"
+	kafkaContentTypeHeader   = "content-type"
+	cloudEventsSpecAttribute = "specversion"
+)
+
+// cloudEventIDs maps CloudEvents extension attributes (which must be
+// lowercase alphanumeric) to the bespoke event's ID fields
+var cloudEventIDs = map[string]string{
+	"orderid":   "order_id",
+	"paymentid": "payment_id",
+	"productid": "product_id",
+	"tenantid":  "tenant_id",
+}
+
+// normalizeMessage returns the message value as bespoke event JSON. CloudEvents
+// 1.0 messages in structured or binary mode are converted, with the type
+// (minus typePrefix) as event_type; anything else is returned unchanged.
+func normalizeMessage(msg kafka.Message, typePrefix string) ([]byte, string, error) {
+	headers := make(map[string]string, len(msg.Headers))
+	for _, h := range msg.Headers {
+		headers[strings.ToLower(h.Key)] = string(h.Value)
+	}
+
+	if _, ok := headers[cloudEventsHeaderPrefix+cloudEventsSpecAttribute]; ok {
+		payload, err := fromBinaryCloudEvent(headers, msg.Value, typePrefix)
+		return payload, FormatCloudEventBinary, err
+	}
+
+	if strings.HasPrefix(headers[kafkaContentTypeHeader], cloudEventsContentType) || looksLikeCloudEvent(msg.Value) {
+		payload, err := fromStructuredCloudEvent(msg.Value, typePrefix)
+		return payload, FormatCloudEventStructured, err
+	}
+
+	return msg.Value, FormatBespoke, nil
+}
+
+// looksLikeCloudEvent catches structured events sent without a content-type
+// header. Bespoke events never carry specversion.
+func looksLikeCloudEvent(value []byte) bool {
+	if !bytes.Contains(value, []byte(`"`+cloudEventsSpecAttribute+`"`)) {
+		return false
+	}
+
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(value, &probe); err != nil {
+		return false
+	}
+	_, hasSpec := probe[cloudEventsSpecAttribute]
+	_, hasEventType := probe["event_type"]
+	return hasSpec && !hasEventType
+}
+
+func fromStructuredCloudEvent(value []byte, typePrefix string) ([]byte, error) {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(value, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid CloudEvent: %w", err)
+	}
+
+	attributes := make(map[string]string, len(envelope))
+	for name, raw := range envelope {
+		if name == "data" || name == "data_base64" {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			// Non-string extension values are kept in their JSON form
+			s = string(raw)
+		}
+		attributes[name] = s
+	}
+
+	data := []byte(envelope["data"])
+	if encoded, ok := envelope["data_base64"]; ok {
+		var s string
+		if err := json.Unmarshal(encoded, &s); err != nil {
+			return nil, fmt.Errorf("invalid CloudEvent data_base64: %w", err)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CloudEvent data_base64: %w", err)
+		}
+		data = decoded
+	}
+
+	return toBespokeEvent(attributes, data, typePrefix)
+}
+
+func fromBinaryCloudEvent(headers map[string]string, value []byte, typePrefix string) ([]byte, error) {
+	attributes := make(map[string]string, len(headers))
+	for key, v := range headers {
+		if strings.HasPrefix(key, cloudEventsHeaderPrefix) {
+			attributes[strings.TrimPrefix(key, cloudEventsHeaderPrefix)] = v
+		}
+	}
+	// In binary mode the content-type header is the event's datacontenttype
+	if contentType, ok := headers[kafkaContentTypeHeader]; ok {
+		attributes["datacontenttype"] = contentType
+	}
+
+	return toBespokeEvent(attributes, value, typePrefix)
+}
+
+func toBespokeEvent(attributes map[string]string, data []byte, typePrefix string) ([]byte, error) {
+	if version := attributes[cloudEventsSpecAttribute]; version != cloudEventsSpecVersion {
+		return nil, fmt.Errorf("unsupported CloudEvents specversion %q", version)
+	}
+	if attributes["type"] == "" {
+		return nil, fmt.Errorf("CloudEvent is missing type")
+	}
+	if contentType := attributes["datacontenttype"]; contentType != "" && !strings.Contains(contentType, "json") {
+		return nil, fmt.Errorf("unsupported CloudEvent datacontenttype %q", contentType)
+	}
+
+	payload := map[string]interface{}{}
+	if len(bytes.TrimSpace(data)) > 0 && !bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, fmt.Errorf("CloudEvent data must be a JSON object: %w", err)
+		}
+		payload = fields
+	}
+
+	event := map[string]interface{}{
+		"event_type": strings.TrimPrefix(attributes["type"], typePrefix),
+		"timestamp":  attributes["time"],
+		"data":       payload,
+	}
+
+	for extension, field := range cloudEventIDs {
+		if id := attributes[extension]; id != "" {
+			event[field] = id
+		} else if id, ok := payload[field].(string); ok {
+			event[field] = id
+		}
+	}
+
+	if version := attributes["schemaversion"]; version != "" {
+		event["schema_version"] = version
+	}
+
+	return json.Marshal(event)
+}
@@ -4,129 +4,252 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	"github.com/ecommerce-platform/shared/go/broker"
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce-platform/shared/go/validation"
 	"github.com/ecommerce/notification-service/internal/config"
-	"github.com/ecommerce/notification-service/internal/handlers"
+	"github.com/ecommerce/notification-service/internal/metrics"
+	"github.com/ecommerce/notification-service/internal/store"
+	"github.com/ecommerce/notification-service/internal/tracing"
 	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
-// Event represents a Kafka event
+// Event represents an event read from the message broker
 type Event struct {
 	EventType string                 `json:"event_type"`
 	OrderID   string                 `json:"order_id"`
 	PaymentID string                 `json:"payment_id"`
+	ProductID string                 `json:"product_id"`
 	Timestamp string                 `json:"timestamp"`
 	Data      map[string]interface{} `json:"data"`
+	// TenantID is the tenant the event was published for; empty for the
+	// default one
+	TenantID string `json:"tenant_id,omitempty"`
+
+	// Key identifies the message the event was read from
+	Key string `json:"-"`
+	// TriggeredBy attributes manually sent notifications to whoever sent
+	// them; empty for events read from the broker
+	TriggeredBy string `json:"-"`
 }
 
-// Consumer handles Kafka message consumption
-type Consumer struct {
-	reader  *kafka.Reader
-	handler *handlers.NotificationHandler
-	logger  *zap.Logger
+// Tenant is the tenant the event was published for
+func (e Event) Tenant() string {
+	if e.TenantID == "" {
+		return sharedauth.DefaultTenant
+	}
+	return e.TenantID
+}
+
+// EventKey identifies a message as topic/partition/offset, or on RabbitMQ
+// as topic/message ID, the same on every delivery
+func EventKey(msg kafka.Message) string {
+	return broker.MessageID(msg)
+}
+
+// EventHandler processes a decoded event
+type EventHandler interface {
+	Handle(ctx context.Context, event Event) error
 }
 
-// NewConsumer creates a new Kafka consumer
-func NewConsumer(cfg *config.Config, handler *handlers.NotificationHandler, logger *zap.Logger) *Consumer {
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:  cfg.KafkaBrokers,
-		GroupID:  cfg.ConsumerGroup,
-		Topic:    "", // Will subscribe to multiple topics
-		MinBytes: 10e3,
-		MaxBytes: 10e6,
-	})
+// Consumer handles message consumption
+type Consumer struct {
+	broker      broker.Broker
+	typePrefix  string
+	handler     EventHandler
+	schemas     *validation.Registry
+	deadLetters store.DeadLetterStore
+	lanes       []Lane
+	running     sync.WaitGroup
+	logger      *zap.Logger
+}
 
+// NewConsumer creates a consumer reading from messageBroker. Messages are processed in
+// priority or bulk lanes by event type, validated against schemas before
+// dispatch, and written to deadLetters for inspection and redrive when they
+// fail validation or processing.
+func NewConsumer(cfg *config.Config, messageBroker broker.Broker, handler EventHandler, schemas *validation.Registry, deadLetters store.DeadLetterStore, logger *zap.Logger) *Consumer {
 	return &Consumer{
-		reader:  reader,
-		handler: handler,
-		logger:  logger,
+		broker:      messageBroker,
+		typePrefix:  cfg.CloudEventsTypePrefix,
+		handler:     handler,
+		schemas:     schemas,
+		deadLetters: deadLetters,
+		lanes:       lanesFromConfig(cfg),
+		logger:      logger,
 	}
 }
 
-// Start starts consuming messages
+// Start starts consuming messages. Each lane runs its own consumer group
+// and worker pool.
 func (c *Consumer) Start(ctx context.Context, topics []string) error {
-	c.logger.Info("Starting Kafka consumer", zap.Strings("topics", topics))
-
-	// Create readers for each topic
-	readers := make([]*kafka.Reader, len(topics))
-	for i, topic := range topics {
-		readers[i] = kafka.NewReader(kafka.ReaderConfig{
-			Brokers:  c.reader.Config().Brokers,
-			GroupID:  c.reader.Config().GroupID,
-			Topic:    topic,
-			MinBytes: 10e3,
-			MaxBytes: 10e6,
-		})
-	}
-
-	// Start a goroutine for each topic
-	for _, reader := range readers {
-		go c.consumeTopic(ctx, reader)
+	c.running.Add(1)
+	defer c.running.Done()
+
+	c.logger.Info("Starting consumer", zap.String("broker", c.broker.Type()), zap.Strings("topics", topics))
+
+	var wg sync.WaitGroup
+	for _, lane := range c.lanes {
+		c.logger.Info("Starting lane",
+			zap.String("lane", lane.Name),
+			zap.String("group_id", lane.GroupID),
+			zap.Int("concurrency", lane.Concurrency),
+			zap.Int("rate_per_second", lane.RatePerSecond),
+		)
+
+		laneConsumer := c.broker.NewConsumer(broker.ConsumerConfig{
+			Group:         lane.GroupID,
+			Topics:        topics,
+			StartAtLatest: lane.StartAtLatest,
+			Concurrency:   lane.Concurrency,
+			QueueSize:     lane.QueueSize,
+			RatePerSecond: lane.RatePerSecond,
+			MaxAttempts:   3,
+			Route:         c.route(lane),
+			Retried:       c.retried,
+			DeadLetters:   broker.DeadLetterFunc(c.deadLetter),
+		}, c.Process)
+
+		wg.Add(1)
+		go func(lane Lane) {
+			defer wg.Done()
+			if err := laneConsumer.Run(ctx); err != nil {
+				c.logger.Error("Failed to close lane consumer", zap.String("lane", lane.Name), zap.Error(err))
+			}
+		}(lane)
 	}
 
 	<-ctx.Done()
-	c.logger.Info("Stopping Kafka consumer")
-
-	// Close all readers
-	for _, reader := range readers {
-		if err := reader.Close(); err != nil {
-			c.logger.Error("Failed to close reader", zap.Error(err))
-		}
-	}
+	c.logger.Info("Stopping consumer")
+	wg.Wait()
 
 	return nil
 }
 
-func (c *Consumer) consumeTopic(ctx context.Context, reader *kafka.Reader) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			msg, err := reader.FetchMessage(ctx)
-			if err != nil {
-				if err == context.Canceled {
-					return
-				}
-				c.logger.Error("Failed to fetch message", zap.Error(err))
-				continue
-			}
-
-			if err := c.processMessage(ctx, msg); err != nil {
-				c.logger.Error("Failed to process message",
-					zap.Error(err),
-					zap.String("topic", msg.Topic),
-					zap.Int64("offset", msg.Offset),
-				)
-			}
+// envelope is the part of a message read before it is queued: enough to pick
+// its lane and ordering key
+type envelope struct {
+	EventType string                 `json:"event_type"`
+	OrderID   string                 `json:"order_id"`
+	Data      map[string]interface{} `json:"data"`
+}
 
-			// Commit the message
-			if err := reader.CommitMessages(ctx, msg); err != nil {
-				c.logger.Error("Failed to commit message", zap.Error(err))
-			}
-		}
+// peek reads a message's envelope without validating it. Fields that can't
+// be decoded are left empty; json skips mistyped fields and keeps the rest.
+func (c *Consumer) peek(msg kafka.Message) envelope {
+	var env envelope
+	if payload, _, err := normalizeMessage(msg, c.typePrefix); err == nil {
+		_ = json.Unmarshal(payload, &env)
 	}
+	return env
 }
 
-func (c *Consumer) processMessage(ctx context.Context, msg kafka.Message) error {
-	c.logger.Debug("Processing message",
+// eventType reads a message's event type without validating it, or returns
+// "" when the message can't be decoded
+func (c *Consumer) eventType(msg kafka.Message) string {
+	return c.peek(msg).EventType
+}
+
+// Process continues the producer's trace and correlation ID, then hands the
+// event to the handler inside a consumer span. It is also used to redrive
+// dead-lettered messages.
+func (c *Consumer) Process(ctx context.Context, msg kafka.Message) error {
+	ctx, span := sharedkafka.StartSpan(ctx, tracing.Tracer(), msg)
+	defer span.End()
+
+	logger := logging.WithContext(ctx, c.logger)
+	logger.Debug("Processing message",
 		zap.String("topic", msg.Topic),
 		zap.Int64("offset", msg.Offset),
 		zap.String("key", string(msg.Key)),
 	)
 
+	err := c.handleMessage(ctx, msg)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		logger.Error("Failed to process message",
+			zap.Error(err),
+			zap.String("topic", msg.Topic),
+			zap.Int64("offset", msg.Offset),
+		)
+	}
+	return err
+}
+
+// retried counts a failed attempt at a message that will be retried
+func (c *Consumer) retried(ctx context.Context, msg kafka.Message, err error) {
+	metrics.RetriesTotal.WithLabelValues(msg.Topic, c.eventType(msg)).Inc()
+}
+
+// deadLetter stores a message that failed processing
+func (c *Consumer) deadLetter(ctx context.Context, msg kafka.Message, processErr error) error {
+	eventType := c.eventType(msg)
+
+	metrics.DeadLettersTotal.WithLabelValues(msg.Topic, eventType).Inc()
+
+	if c.deadLetters == nil {
+		return nil
+	}
+
+	headers := make(map[string]string, len(msg.Headers))
+	for _, h := range msg.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+
+	letter := &store.DeadLetter{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       string(msg.Key),
+		Payload:   string(msg.Value),
+		Headers:   headers,
+		EventType: eventType,
+		Error:     processErr.Error(),
+	}
+	return c.deadLetters.AddDeadLetter(ctx, letter)
+}
+
+func (c *Consumer) handleMessage(ctx context.Context, msg kafka.Message) error {
+	// Accept both bespoke and CloudEvents messages
+	payload, format, err := normalizeMessage(msg, c.typePrefix)
+	if err != nil {
+		return err
+	}
+	metrics.EventFormatsTotal.WithLabelValues(msg.Topic, format).Inc()
+
 	// Parse event
 	var event Event
-	if err := json.Unmarshal(msg.Value, &event); err != nil {
+	if err := json.Unmarshal(payload, &event); err != nil {
 		return fmt.Errorf("failed to unmarshal event: %w", err)
 	}
+	event.Key = EventKey(msg)
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("event_type", event.EventType))
+
+	// Reject malformed events before they reach the handlers
+	if c.schemas != nil {
+		if err := c.schemas.Validate(payload); err != nil {
+			metrics.InvalidEventsTotal.WithLabelValues(event.EventType).Inc()
+			return err
+		}
+	}
 
 	// Route to appropriate handler
 	return c.handler.Handle(ctx, event)
 }
 
-// Close closes the consumer
+// Close waits for Start to return after its context is cancelled, so that
+// in-flight messages finish and are acknowledged.
 func (c *Consumer) Close() error {
-	return c.reader.Close()
+	c.running.Wait()
+	return nil
 }
@@ -0,0 +1,93 @@
+package consumer
+
+import (
+	"fmt"
+
+	"github.com/ecommerce-platform/shared/go/broker"
+	"github.com/ecommerce/notification-service/internal/config"
+	"github.com/ecommerce/notification-service/internal/preferences"
+)
+
+// Processing lanes
+const (
+	// LanePriority carries transactional, security and operational events
+	LanePriority = "priority"
+	// LaneBulk carries marketing traffic such as campaigns and review requests
+	LaneBulk = "bulk"
+)
+
+// LaneFor returns the lane an event type is processed in. Messages whose type
+// can't be read go to the priority lane so they are dead-lettered promptly.
+func LaneFor(eventType string) string {
+	if eventType != "" && preferences.CategoryFor(eventType) == preferences.CategoryMarketing {
+		return LaneBulk
+	}
+	return LanePriority
+}
+
+// Lane is a queue and worker pool for one class of traffic. Each lane reads
+// the topics with its own consumer group and skips other lanes' events, so a
+// backlog in one lane never holds up another. Within a lane, events with the
+// same ordering key always go to the same worker, so they are processed one
+// at a time in the order they were read.
+type Lane struct {
+	Name          string
+	GroupID       string
+	StartAtLatest bool
+	Concurrency   int
+	RatePerSecond int // 0 = unlimited
+	QueueSize     int
+}
+
+// lanesFromConfig returns the priority and bulk lanes. The priority lane keeps
+// the service's existing consumer group; the bulk lane's group starts at the
+// latest message so its first deployment doesn't replay topic history.
+func lanesFromConfig(cfg *config.Config) []Lane {
+	return []Lane{
+		{
+			Name:          LanePriority,
+			GroupID:       cfg.ConsumerGroup,
+			Concurrency:   cfg.PriorityLaneConcurrency,
+			RatePerSecond: cfg.PriorityLaneRate,
+			QueueSize:     cfg.LaneQueueSize,
+		},
+		{
+			Name:          LaneBulk,
+			GroupID:       cfg.ConsumerGroup + "-" + LaneBulk,
+			StartAtLatest: true,
+			Concurrency:   cfg.BulkLaneConcurrency,
+			RatePerSecond: cfg.BulkLaneRate,
+			QueueSize:     cfg.LaneQueueSize,
+		},
+	}
+}
+
+// orderingKey returns the key whose events must be processed in order: the
+// order, else the customer, else the message key. Messages without any
+// keep their partition's order.
+func orderingKey(msg broker.Message, env envelope) string {
+	if env.OrderID != "" {
+		return "order:" + env.OrderID
+	}
+	for _, field := range []string{"user_id", "customer_id", "customer_email"} {
+		if value, ok := env.Data[field]; ok && value != nil && value != "" {
+			return "customer:" + fmt.Sprint(value)
+		}
+	}
+	if len(msg.Key) > 0 {
+		return "key:" + string(msg.Key)
+	}
+	return fmt.Sprintf("partition:%s/%d", msg.Topic, msg.Partition)
+}
+
+// route returns a lane's routing: the lane skips other lanes' events and
+// orders its own by orderingKey
+func (c *Consumer) route(lane Lane) func(broker.Message) (string, bool) {
+	return func(msg broker.Message) (string, bool) {
+		env := c.peek(msg)
+		if LaneFor(env.EventType) != lane.Name {
+			return "", false
+		}
+		return orderingKey(msg, env), true
+	}
+}
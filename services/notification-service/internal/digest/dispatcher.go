@@ -0,0 +1,167 @@
+package digest
+
+import (
+	"context"
+	"time"
+
+	"github.com/ecommerce/notification-service/internal/branding"
+	"github.com/ecommerce/notification-service/internal/email"
+	"github.com/ecommerce/notification-service/internal/events"
+	"github.com/ecommerce/notification-service/internal/metrics"
+	"github.com/ecommerce/notification-service/internal/quiethours"
+	"github.com/ecommerce/notification-service/internal/store"
+	"github.com/ecommerce/notification-service/internal/templates"
+	"go.uber.org/zap"
+)
+
+// Dispatcher sends digest emails for recipients whose collection window
+// has closed, run as a scheduled job
+type Dispatcher struct {
+	digests        store.DigestStore
+	notifications  store.NotificationStore
+	templateEngine *templates.TemplateEngine
+	emailSender    *email.EmailSender
+	quietHours     *quiethours.Policy
+	brands         *branding.Brands
+	statusEvents   *events.Publisher
+	window         time.Duration
+	logger         *zap.Logger
+}
+
+// NewDispatcher creates a new digest dispatcher
+func NewDispatcher(
+	digests store.DigestStore,
+	notifications store.NotificationStore,
+	templateEngine *templates.TemplateEngine,
+	emailSender *email.EmailSender,
+	quietHours *quiethours.Policy,
+	brands *branding.Brands,
+	statusEvents *events.Publisher,
+	window time.Duration,
+	logger *zap.Logger,
+) *Dispatcher {
+	return &Dispatcher{
+		digests:        digests,
+		notifications:  notifications,
+		templateEngine: templateEngine,
+		emailSender:    emailSender,
+		quietHours:     quietHours,
+		brands:         brands,
+		statusEvents:   statusEvents,
+		window:         window,
+		logger:         logger,
+	}
+}
+
+// Run sends a digest to every recipient whose window has closed. Digests
+// that fail stay pending for the next run; only failing to list the
+// recipients fails the run.
+func (s *Dispatcher) Run(ctx context.Context) error {
+	recipients, err := s.digests.ListDueDigestRecipients(ctx, time.Now().Add(-s.window))
+	if err != nil {
+		return err
+	}
+
+	for _, recipient := range recipients {
+		if err := s.sendDigest(ctx, recipient); err != nil {
+			s.logger.Error("Failed to send digest",
+				zap.String("recipient", recipient),
+				zap.Error(err),
+			)
+		}
+	}
+	return nil
+}
+
+func (s *Dispatcher) sendDigest(ctx context.Context, recipient string) error {
+	items, err := s.digests.ListPendingDigestItems(ctx, recipient)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	// Digests are non-urgent: hold them while it is quiet hours for the
+	// recipient, using the timezone of their most recent item
+	if s.quietHours.Enabled() {
+		loc := s.quietHours.Location(items[len(items)-1].Timezone)
+		if releaseAt, quiet := s.quietHours.ReleaseAt(time.Now(), loc); quiet {
+			s.logger.Debug("Digest held for quiet hours",
+				zap.String("recipient", recipient),
+				zap.Time("release_at", releaseAt),
+			)
+			return nil
+		}
+	}
+
+	// Like the timezone, the brand is the most recent item's
+	brand := s.brands.Resolve(ctx, items[len(items)-1].BrandID)
+	subject, body, err := s.templateEngine.Render("digest", templates.WithBrand(map[string]interface{}{
+		"Items": items,
+		"Count": len(items),
+	}, branding.Template(brand)))
+	if err != nil {
+		return err
+	}
+
+	record := &store.Notification{
+		EventType: "digest",
+		Channel:   store.ChannelEmail,
+		Template:  "digest",
+		Recipient: recipient,
+		UserID:    items[0].UserID,
+		BrandID:   items[len(items)-1].BrandID,
+	}
+
+	start := time.Now()
+	result, sendErr := s.emailSender.Send(ctx, email.Email{
+		To:        recipient,
+		Subject:   subject,
+		Body:      body,
+		FromName:  brand.FromName,
+		FromEmail: brand.FromEmail,
+	})
+	metrics.SendDuration.
+		WithLabelValues(string(record.Channel), record.Template, record.EventType).
+		Observe(time.Since(start).Seconds())
+	if result != nil {
+		record.Provider = result.Provider
+		record.MessageID = result.MessageID
+	}
+	if sendErr != nil {
+		record.Status = store.StatusFailed
+		record.Reason = sendErr.Error()
+	} else {
+		record.Status = store.StatusSent
+	}
+
+	metrics.NotificationsTotal.
+		WithLabelValues(string(record.Channel), record.Template, record.EventType, string(record.Status)).
+		Inc()
+
+	if err := s.notifications.Create(ctx, record); err != nil {
+		s.logger.Error("Failed to record digest notification", zap.Error(err))
+	}
+	s.statusEvents.PublishStatus(ctx, record)
+
+	// Leave items pending so the next run retries the digest
+	if sendErr != nil {
+		return sendErr
+	}
+
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	if err := s.digests.MarkDigestItemsSent(ctx, ids); err != nil {
+		return err
+	}
+
+	s.logger.Info("Digest email sent",
+		zap.String("recipient", recipient),
+		zap.Int("items", len(items)),
+	)
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package email
+
+import "context"
+
+// channel labels email metrics
+const channel = "email"
+
+// Provider delivers email through a specific backend (SMTP, SendGrid, SES...)
+type Provider interface {
+	// Name identifies the provider in logs and notification records
+	Name() string
+	// Send delivers the email and returns the provider's message ID, if any
+	Send(ctx context.Context, email Email) (string, error)
+}
+
+// Result describes a successful delivery
+type Result struct {
+	Provider  string
+	MessageID string
+}
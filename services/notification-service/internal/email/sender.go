@@ -2,34 +2,80 @@ package email
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"html/template"
+	"time"
 
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/notification-service/internal/breaker"
 	"github.com/ecommerce/notification-service/internal/config"
+	"github.com/ecommerce/notification-service/internal/metrics"
+	"github.com/ecommerce/notification-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
-	gomail "gopkg.in/gomail.v2"
 )
 
-// EmailSender handles email sending
+// EmailSender handles email sending through the configured providers,
+// failing over to the next provider when one errors or its circuit is open
 type EmailSender struct {
-	config *config.Config
-	logger *zap.Logger
-	dialer *gomail.Dialer
+	config  *config.Config
+	logger  *zap.Logger
+	routes  []route
+	timeout time.Duration
 }
 
-// NewEmailSender creates a new email sender
-func NewEmailSender(cfg *config.Config, logger *zap.Logger) *EmailSender {
-	dialer := gomail.NewDialer(
-		cfg.SMTPHost,
-		cfg.SMTPPort,
-		cfg.SMTPUsername,
-		cfg.SMTPPassword,
-	)
+// route pairs a provider with its circuit breaker
+type route struct {
+	provider Provider
+	breaker  *breaker.Breaker
+}
+
+// NewEmailSender creates a new email sender using the providers listed in
+// EMAIL_PROVIDERS, in failover order
+func NewEmailSender(ctx context.Context, cfg *config.Config, logger *zap.Logger) (*EmailSender, error) {
+	cooldown := time.Duration(cfg.CircuitBreakerCooldown) * time.Second
+
+	routes := make([]route, 0, len(cfg.EmailProviders))
+	for _, name := range cfg.EmailProviders {
+		provider, err := NewProvider(ctx, cfg, name)
+		if err != nil {
+			return nil, err
+		}
+
+		providerName := provider.Name()
+		routes = append(routes, route{
+			provider: provider,
+			breaker: breaker.New(cfg.CircuitBreakerThreshold, cooldown, func(state breaker.State) {
+				metrics.ProviderCircuitState.WithLabelValues(channel, providerName).Set(float64(state))
+				if state == breaker.StateOpen {
+					logger.Warn("Email provider circuit opened", zap.String("provider", providerName))
+				}
+			}),
+		})
+	}
 
 	return &EmailSender{
-		config: cfg,
-		logger: logger,
-		dialer: dialer,
+		config:  cfg,
+		logger:  logger,
+		routes:  routes,
+		timeout: time.Duration(cfg.ProviderTimeout) * time.Second,
+	}, nil
+}
+
+// NewProvider creates the named email provider
+func NewProvider(ctx context.Context, cfg *config.Config, name string) (Provider, error) {
+	switch name {
+	case "smtp":
+		return NewSMTPProvider(cfg), nil
+	case "sendgrid":
+		return NewSendGridProvider(cfg)
+	case "ses":
+		return NewSESProvider(ctx, cfg)
+	default:
+		return nil, fmt.Errorf("unknown email provider: %s", name)
 	}
 }
 
@@ -39,50 +85,135 @@ type Email struct {
 	Subject string
 	Body    string
 	IsHTML  bool
+	// Sender of a brand; empty uses FROM_NAME and FROM_EMAIL
+	FromName  string
+	FromEmail string
+	// Attachments are sent as files of the email
+	Attachments []Attachment
+}
+
+// Attachment is a file sent with an email
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// sender returns the name and address the email is sent from
+func (e Email) sender(cfg *config.Config) (name, address string) {
+	name, address = e.FromName, e.FromEmail
+	if name == "" {
+		name = cfg.FromName
+	}
+	if address == "" {
+		address = cfg.FromEmail
+	}
+	return name, address
 }
 
-// Send sends an email
-func (s *EmailSender) Send(email Email) error {
-	s.logger.Info("Sending email",
+// Send sends an email through the first healthy provider and returns which
+// provider delivered it
+func (s *EmailSender) Send(ctx context.Context, email Email) (*Result, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "email.send",
+		trace.WithAttributes(attribute.String("notification.channel", channel)),
+	)
+	defer span.End()
+	logger := logging.WithContext(ctx, s.logger)
+
+	logger.Info("Sending email",
 		zap.String("to", email.To),
 		zap.String("subject", email.Subject),
+		zap.Int("attachments", len(email.Attachments)),
 	)
 
 	// In development mode, just log instead of sending
-	if s.config.Environment == "development" || s.config.SMTPUsername == "" {
-		s.logger.Info("Email (simulated)",
+	if s.simulated() {
+		logger.Info("Email (simulated)",
 			zap.String("to", email.To),
 			zap.String("subject", email.Subject),
 			zap.String("body_preview", truncate(email.Body, 100)),
 		)
-		return nil
-	}
-
-	m := gomail.NewMessage()
-	m.SetHeader("From", fmt.Sprintf("%s <%s>", s.config.FromName, s.config.FromEmail))
-	m.SetHeader("To", email.To)
-	m.SetHeader("Subject", email.Subject)
-
-	if email.IsHTML {
-		m.SetBody("text/html", email.Body)
-	} else {
-		m.SetBody("text/plain", email.Body)
+		span.SetAttributes(attribute.String("notification.provider", "simulated"))
+		return &Result{Provider: "simulated"}, nil
 	}
 
-	if err := s.dialer.DialAndSend(m); err != nil {
-		s.logger.Error("Failed to send email",
+	var lastErr error
+	for i, r := range s.routes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		name := r.provider.Name()
+		if !r.breaker.Allow() {
+			logger.Warn("Email provider circuit open, skipping", zap.String("provider", name))
+			continue
+		}
+
+		if throttle := throttleFromContext(ctx); throttle != nil {
+			if err := throttle.Wait(ctx, name); err != nil {
+				return nil, err
+			}
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, s.timeout)
+		messageID, err := r.provider.Send(attemptCtx, email)
+		cancel()
+
+		if err != nil {
+			r.breaker.Failure()
+			metrics.ProviderFailures.WithLabelValues(channel, name).Inc()
+			logger.Error("Failed to send email",
+				zap.String("to", email.To),
+				zap.String("provider", name),
+				zap.Error(err),
+			)
+			span.AddEvent("provider failed", trace.WithAttributes(
+				attribute.String("notification.provider", name),
+				attribute.String("error", err.Error()),
+			))
+			lastErr = err
+			continue
+		}
+
+		r.breaker.Success()
+		if i > 0 {
+			metrics.ProviderFailovers.WithLabelValues(channel, name).Inc()
+		}
+
+		logger.Info("Email sent successfully",
 			zap.String("to", email.To),
-			zap.Error(err),
+			zap.String("provider", name),
+			zap.String("message_id", messageID),
 		)
-		return err
+		span.SetAttributes(
+			attribute.String("notification.provider", name),
+			attribute.String("notification.message_id", messageID),
+		)
+		return &Result{Provider: name, MessageID: messageID}, nil
+	}
+
+	err := fmt.Errorf("no email provider available: all circuits open")
+	if lastErr != nil {
+		err = fmt.Errorf("all email providers failed: %w", lastErr)
 	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return nil, err
+}
 
-	s.logger.Info("Email sent successfully", zap.String("to", email.To))
-	return nil
+// simulated reports whether emails should only be logged
+func (s *EmailSender) simulated() bool {
+	if s.config.Environment == "development" {
+		return true
+	}
+	// SMTP without credentials can't deliver; API providers validate their own keys
+	return len(s.routes) == 1 &&
+		s.routes[0].provider.Name() == "smtp" &&
+		s.config.SMTPUsername == ""
 }
 
 // SendFromTemplate sends an email using a template
-func (s *EmailSender) SendFromTemplate(to, subject, templateName string, data interface{}) error {
+func (s *EmailSender) SendFromTemplate(ctx context.Context, to, subject, templateName string, data interface{}) error {
 	tmpl, err := template.ParseFiles(fmt.Sprintf("internal/templates/%s.html", templateName))
 	if err != nil {
 		s.logger.Error("Failed to parse template",
@@ -101,12 +232,13 @@ func (s *EmailSender) SendFromTemplate(to, subject, templateName string, data in
 		return err
 	}
 
-	return s.Send(Email{
+	_, err = s.Send(ctx, Email{
 		To:      to,
 		Subject: subject,
 		Body:    body.String(),
 		IsHTML:  true,
 	})
+	return err
 }
 
 func truncate(s string, maxLen int) string {
@@ -0,0 +1,146 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ecommerce/notification-service/internal/config"
+)
+
+const sendGridEndpoint = "https://api.sendgrid.com/v3/mail/send"
+
+// sendGridProvider sends email through the SendGrid v3 Web API
+type sendGridProvider struct {
+	config     *config.Config
+	httpClient *http.Client
+}
+
+// NewSendGridProvider creates a new SendGrid email provider
+func NewSendGridProvider(cfg *config.Config) (Provider, error) {
+	if cfg.SendGridAPIKey == "" {
+		return nil, fmt.Errorf("SENDGRID_API_KEY is required for the sendgrid provider")
+	}
+
+	return &sendGridProvider{
+		config:     cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (p *sendGridProvider) Name() string {
+	return "sendgrid"
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridRequest struct {
+	Personalizations []struct {
+		To []sendGridAddress `json:"to"`
+	} `json:"personalizations"`
+	From    sendGridAddress `json:"from"`
+	Subject string          `json:"subject"`
+	Content []struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"content"`
+	Attachments []sendGridAttachment `json:"attachments,omitempty"`
+}
+
+type sendGridAttachment struct {
+	Content     string `json:"content"` // base64
+	Type        string `json:"type"`
+	Filename    string `json:"filename"`
+	Disposition string `json:"disposition"`
+}
+
+// Send sends an email via SendGrid and returns the X-Message-Id header
+func (p *sendGridProvider) Send(ctx context.Context, email Email) (string, error) {
+	contentType := "text/plain"
+	if email.IsHTML {
+		contentType = "text/html"
+	}
+
+	var payload sendGridRequest
+	payload.Personalizations = make([]struct {
+		To []sendGridAddress `json:"to"`
+	}, 1)
+	payload.Personalizations[0].To = []sendGridAddress{{Email: email.To}}
+	fromName, fromEmail := email.sender(p.config)
+	payload.From = sendGridAddress{Email: fromEmail, Name: fromName}
+	payload.Subject = email.Subject
+	payload.Content = []struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}{{Type: contentType, Value: email.Body}}
+	for _, attachment := range email.Attachments {
+		payload.Attachments = append(payload.Attachments, sendGridAttachment{
+			Content:     base64.StdEncoding.EncodeToString(attachment.Data),
+			Type:        attachment.ContentType,
+			Filename:    attachment.Filename,
+			Disposition: "attachment",
+		})
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode sendgrid request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sendGridEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.config.SendGridAPIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("sendgrid request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("sendgrid returned status %d: %s", resp.StatusCode, detail)
+	}
+
+	return resp.Header.Get("X-Message-Id"), nil
+}
+
+// ValidateSendGridSignature verifies the signature of a SendGrid event webhook
+// request. publicKey is the base64 DER key from the SendGrid console; the
+// signature covers the timestamp header followed by the raw request body.
+func ValidateSendGridSignature(publicKey, signature, timestamp string, body []byte) bool {
+	der, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return false
+	}
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return false
+	}
+	key, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return false
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	digest := sha256.Sum256(append([]byte(timestamp), body...))
+	return ecdsa.VerifyASN1(key, digest[:], sig)
+}
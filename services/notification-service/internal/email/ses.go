@@ -0,0 +1,88 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+	"github.com/ecommerce/notification-service/internal/config"
+)
+
+// sesProvider sends email through the Amazon SES v2 API
+type sesProvider struct {
+	config *config.Config
+	client *sesv2.Client
+}
+
+// NewSESProvider creates a new Amazon SES email provider. Credentials are
+// resolved through the default AWS chain (env vars, shared config, IAM role).
+func NewSESProvider(ctx context.Context, cfg *config.Config) (Provider, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(cfg.AWSRegion))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return &sesProvider{
+		config: cfg,
+		client: sesv2.NewFromConfig(awsCfg),
+	}, nil
+}
+
+func (p *sesProvider) Name() string {
+	return "ses"
+}
+
+// Send sends an email via SES and returns the SES message ID. Simple
+// messages can't carry attachments, so emails with them are sent as the raw
+// MIME message.
+func (p *sesProvider) Send(ctx context.Context, email Email) (string, error) {
+	content, err := p.content(email)
+	if err != nil {
+		return "", err
+	}
+
+	fromName, fromEmail := email.sender(p.config)
+	input := &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String(fmt.Sprintf("%s <%s>", fromName, fromEmail)),
+		Destination:      &types.Destination{ToAddresses: []string{email.To}},
+		Content:          content,
+	}
+	if p.config.SESConfigurationSet != "" {
+		input.ConfigurationSetName = aws.String(p.config.SESConfigurationSet)
+	}
+
+	out, err := p.client.SendEmail(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("ses send failed: %w", err)
+	}
+
+	return aws.ToString(out.MessageId), nil
+}
+
+// content is the SES content of an email
+func (p *sesProvider) content(email Email) (*types.EmailContent, error) {
+	if len(email.Attachments) > 0 {
+		var raw bytes.Buffer
+		if _, err := newMessage(email, p.config).WriteTo(&raw); err != nil {
+			return nil, fmt.Errorf("failed to build raw message: %w", err)
+		}
+		return &types.EmailContent{Raw: &types.RawMessage{Data: raw.Bytes()}}, nil
+	}
+
+	body := &types.Body{}
+	if email.IsHTML {
+		body.Html = &types.Content{Data: aws.String(email.Body), Charset: aws.String("UTF-8")}
+	} else {
+		body.Text = &types.Content{Data: aws.String(email.Body), Charset: aws.String("UTF-8")}
+	}
+	return &types.EmailContent{
+		Simple: &types.Message{
+			Subject: &types.Content{Data: aws.String(email.Subject), Charset: aws.String("UTF-8")},
+			Body:    body,
+		},
+	}, nil
+}
@@ -0,0 +1,70 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/ecommerce/notification-service/internal/config"
+	gomail "gopkg.in/gomail.v2"
+)
+
+// smtpProvider sends email over SMTP
+type smtpProvider struct {
+	config *config.Config
+	dialer *gomail.Dialer
+}
+
+// NewSMTPProvider creates a new SMTP email provider
+func NewSMTPProvider(cfg *config.Config) Provider {
+	return &smtpProvider{
+		config: cfg,
+		dialer: gomail.NewDialer(
+			cfg.SMTPHost,
+			cfg.SMTPPort,
+			cfg.SMTPUsername,
+			cfg.SMTPPassword,
+		),
+	}
+}
+
+func (p *smtpProvider) Name() string {
+	return "smtp"
+}
+
+// Send sends an email over SMTP. SMTP has no provider message ID.
+func (p *smtpProvider) Send(ctx context.Context, email Email) (string, error) {
+	m := newMessage(email, p.config)
+	if err := p.dialer.DialAndSend(m); err != nil {
+		return "", err
+	}
+
+	return "", nil
+}
+
+// newMessage builds the MIME message of an email, with its attachments
+func newMessage(email Email, cfg *config.Config) *gomail.Message {
+	m := gomail.NewMessage()
+	fromName, fromEmail := email.sender(cfg)
+	m.SetHeader("From", fmt.Sprintf("%s <%s>", fromName, fromEmail))
+	m.SetHeader("To", email.To)
+	m.SetHeader("Subject", email.Subject)
+
+	if email.IsHTML {
+		m.SetBody("text/html", email.Body)
+	} else {
+		m.SetBody("text/plain", email.Body)
+	}
+
+	for _, attachment := range email.Attachments {
+		data := attachment.Data
+		m.Attach(attachment.Filename,
+			gomail.SetHeader(map[string][]string{"Content-Type": {attachment.ContentType}}),
+			gomail.SetCopyFunc(func(w io.Writer) error {
+				_, err := w.Write(data)
+				return err
+			}),
+		)
+	}
+	return m
+}
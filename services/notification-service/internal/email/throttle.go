@@ -0,0 +1,23 @@
+package email
+
+import "context"
+
+// Throttle paces sends per provider, e.g. to keep bulk sends within each
+// provider's sending rate
+type Throttle interface {
+	Wait(ctx context.Context, provider string) error
+}
+
+type throttleKey struct{}
+
+// WithThrottle returns a context whose sends wait on t before each provider
+// attempt. Sends without one are never held back.
+func WithThrottle(ctx context.Context, t Throttle) context.Context {
+	return context.WithValue(ctx, throttleKey{}, t)
+}
+
+// throttleFromContext returns the throttle of a context, or nil
+func throttleFromContext(ctx context.Context) Throttle {
+	t, _ := ctx.Value(throttleKey{}).(Throttle)
+	return t
+}
@@ -0,0 +1,171 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/notification-service/internal/config"
+	"github.com/ecommerce/notification-service/internal/metrics"
+	"github.com/ecommerce/notification-service/internal/store"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// Delivery status event types
+const (
+	EventSent    = "notification.sent"
+	EventFailed  = "notification.failed"
+	EventBounced = "notification.bounced"
+)
+
+// eventTypeHeader lets consumers route status events without decoding them
+const eventTypeHeader = "event_type"
+
+// StatusEvent tells other services whether a customer was informed of an
+// event. The envelope matches the events this service consumes.
+type StatusEvent struct {
+	EventType string     `json:"event_type"`
+	OrderID   string     `json:"order_id,omitempty"`
+	Timestamp time.Time  `json:"timestamp"`
+	Data      StatusData `json:"data"`
+}
+
+// StatusData describes the notification a status event is about
+type StatusData struct {
+	NotificationID  string `json:"notification_id"`
+	Channel         string `json:"channel"`
+	Template        string `json:"template"`
+	Variant         string `json:"variant,omitempty"`
+	SourceEventType string `json:"source_event_type"`
+	SourceEventKey  string `json:"source_event_key,omitempty"`
+	UserID          string `json:"user_id,omitempty"`
+	Provider        string `json:"provider,omitempty"`
+	MessageID       string `json:"message_id,omitempty"`
+	Reason          string `json:"reason,omitempty"`
+	CorrelationID   string `json:"correlation_id,omitempty"`
+	TriggeredBy     string `json:"triggered_by,omitempty"`
+}
+
+// Publisher publishes notification delivery status events. Publishing is
+// asynchronous and never fails a notification; with no topic configured it
+// does nothing.
+type Publisher struct {
+	producer *sharedkafka.Producer
+	logger   *zap.Logger
+}
+
+// NewPublisher creates a status event publisher for STATUS_EVENTS_TOPIC
+func NewPublisher(cfg *config.Config, logger *zap.Logger) *Publisher {
+	p := &Publisher{logger: logger}
+	if cfg.StatusEventsTopic == "" {
+		return p
+	}
+
+	p.producer = sharedkafka.NewProducer(sharedkafka.ProducerConfig{
+		Brokers:    cfg.KafkaBrokers,
+		Topic:      cfg.StatusEventsTopic,
+		Async:      true,
+		Completion: p.completed,
+	}, logger)
+	return p
+}
+
+// PublishStatus publishes notification.sent or notification.failed for a
+// notification that was just sent or failed to send. Other statuses are
+// not published.
+func (p *Publisher) PublishStatus(ctx context.Context, n *store.Notification) {
+	switch n.Status {
+	case store.StatusSent:
+		p.Publish(ctx, EventSent, n)
+	case store.StatusFailed:
+		p.Publish(ctx, EventFailed, n)
+	}
+}
+
+// Publish publishes a status event for a notification. Events are keyed by
+// order, else user, so a customer's events stay in order.
+func (p *Publisher) Publish(ctx context.Context, eventType string, n *store.Notification) {
+	if p.producer == nil {
+		return
+	}
+
+	correlationID := n.CorrelationID
+	if correlationID == "" {
+		correlationID = logging.CorrelationID(ctx)
+	}
+
+	event := StatusEvent{
+		EventType: eventType,
+		OrderID:   n.OrderID,
+		Timestamp: time.Now().UTC(),
+		Data: StatusData{
+			NotificationID:  n.ID,
+			Channel:         string(n.Channel),
+			Template:        n.Template,
+			Variant:         n.Variant,
+			SourceEventType: n.EventType,
+			SourceEventKey:  n.EventKey,
+			UserID:          n.UserID,
+			Provider:        n.Provider,
+			MessageID:       n.MessageID,
+			Reason:          n.Reason,
+			CorrelationID:   correlationID,
+			TriggeredBy:     n.TriggeredBy,
+		},
+	}
+
+	value, err := json.Marshal(event)
+	if err != nil {
+		p.logger.Error("Failed to marshal status event", zap.String("event_type", eventType), zap.Error(err))
+		return
+	}
+
+	key := n.OrderID
+	if key == "" {
+		key = n.UserID
+	}
+	if key == "" {
+		key = n.ID
+	}
+
+	msg := kafka.Message{
+		Key:     []byte(key),
+		Value:   value,
+		Time:    event.Timestamp,
+		Headers: []kafka.Header{{Key: eventTypeHeader, Value: []byte(eventType)}},
+	}
+	if correlationID != "" {
+		ctx = logging.WithCorrelationID(ctx, correlationID)
+	}
+
+	// Async writes only fail here on a closed writer; delivery errors are
+	// reported to completed
+	if err := p.producer.Publish(ctx, msg); err != nil {
+		p.logger.Error("Failed to publish status event", zap.String("event_type", eventType), zap.Error(err))
+		metrics.StatusEventsTotal.WithLabelValues(eventType, "failed").Inc()
+	}
+}
+
+// completed records the outcome of an asynchronous batch write
+func (p *Publisher) completed(messages []kafka.Message, err error) {
+	result := "published"
+	if err != nil {
+		result = "failed"
+		p.logger.Error("Failed to publish status events", zap.Int("count", len(messages)), zap.Error(err))
+	}
+	for _, msg := range messages {
+		eventType := sharedkafka.Header(msg, eventTypeHeader)
+		metrics.StatusEventsTotal.WithLabelValues(eventType, result).Inc()
+	}
+}
+
+// Close flushes pending events
+func (p *Publisher) Close() error {
+	if p.producer == nil {
+		return nil
+	}
+	return p.producer.Close()
+}
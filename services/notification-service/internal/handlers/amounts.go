@@ -0,0 +1,41 @@
+package handlers
+
+import "github.com/ecommerce-platform/shared/go/money"
+
+// legacyCurrency is the currency of v1 amounts, which were float dollars,
+// when the event doesn't name one
+const legacyCurrency = "USD"
+
+// amountOf reads an amount of event data: a v2 {"minor_units": ...,
+// "currency": ...} object, or a v1 number of major units in currency (a
+// string naming it, or nil for dollars). ok is false for missing or
+// malformed amounts.
+func amountOf(raw interface{}, currency interface{}) (amount money.Money, ok bool) {
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		minor, isNumber := v["minor_units"].(float64)
+		code, isString := v["currency"].(string)
+		if !isNumber || !isString || code == "" || minor != float64(int64(minor)) {
+			return money.Money{}, false
+		}
+		return money.New(int64(minor), code), true
+	case float64:
+		code, _ := currency.(string)
+		if code == "" {
+			code = legacyCurrency
+		}
+		return money.FromMajor(v, code), true
+	}
+	return money.Money{}, false
+}
+
+// amountData is an amount of event data for templates, which format it as
+// e.g. $129.97. Missing amounts are nil, so that they fail template
+// validation instead of rendering as $0.00.
+func amountData(raw interface{}, currency interface{}) interface{} {
+	amount, ok := amountOf(raw, currency)
+	if !ok {
+		return nil
+	}
+	return amount
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	"github.com/ecommerce/notification-service/internal/consumer"
+	"github.com/ecommerce/notification-service/internal/subscriptions"
+	"go.uber.org/zap"
+)
+
+// sendBackInStock tells every customer subscribed to a product that it is
+// available again. Subscribers come from the event, else from the
+// subscriptions service, which only knows the default tenant's. Products are
+// throttled with a per-tenant cooldown in Redis, so stock flapping around
+// zero doesn't email the same subscribers repeatedly.
+func (h *NotificationHandler) sendBackInStock(ctx context.Context, event consumer.Event) error {
+	productID := event.ProductID
+	if productID == "" {
+		productID, _ = event.Data["product_id"].(string)
+	}
+	if productID == "" {
+		return fmt.Errorf("missing product_id in event")
+	}
+
+	subscribers, carried := subscriptions.FromEventData(event.Data)
+	if !carried {
+		if event.Tenant() != sharedauth.DefaultTenant {
+			h.log(ctx).Warn("No subscribers in event of another tenant than the subscriptions service's, dropping back-in-stock event",
+				zap.String("tenant_id", event.Tenant()),
+				zap.String("product_id", productID),
+			)
+			return nil
+		}
+		if !h.subscriptions.Enabled() {
+			h.log(ctx).Warn("No subscribers in event and no BACK_IN_STOCK_SUBSCRIBERS_URL configured, dropping back-in-stock event",
+				zap.String("product_id", productID),
+			)
+			return nil
+		}
+		var err error
+		if subscribers, err = h.subscriptions.BackInStock(ctx, productID); err != nil {
+			return fmt.Errorf("failed to load back-in-stock subscribers: %w", err)
+		}
+	}
+	if len(subscribers) == 0 {
+		h.log(ctx).Debug("No back-in-stock subscribers", zap.String("product_id", productID))
+		return nil
+	}
+
+	cooldownKey := "back_in_stock:" + event.Tenant() + ":" + productID
+
+	// A dry-run replay must not start a cooldown that would hold back live sends
+	if h.limiter != nil && !isDryRun(ctx) {
+		period := time.Duration(h.config.BackInStockCooldown) * time.Minute
+		ok, err := h.limiter.Cooldown(ctx, cooldownKey, period)
+		if err != nil {
+			h.log(ctx).Warn("Back-in-stock cooldown unavailable, sending anyway", zap.Error(err))
+		} else if !ok {
+			h.log(ctx).Info("Product back in stock again within cooldown, not notifying subscribers",
+				zap.String("product_id", productID),
+				zap.Int("subscribers", len(subscribers)),
+			)
+			return nil
+		}
+	}
+
+	productName, _ := event.Data["product_name"].(string)
+	productURL, _ := event.Data["product_url"].(string)
+	if productURL == "" {
+		productURL = h.brand(ctx, event).StorefrontURL + "/products/" + url.PathEscape(productID)
+	}
+
+	var lastErr error
+	notified := 0
+	for _, subscriber := range subscribers {
+		err := h.notify(ctx, subscriberEvent(event, subscriber), notification{
+			template: "back_in_stock",
+			emailKey: "customer_email",
+			data: map[string]interface{}{
+				"ProductID":         productID,
+				"ProductName":       productName,
+				"ProductURL":        productURL,
+				"ImageURL":          event.Data["image_url"],
+				"Price":             amountData(event.Data["price"], event.Data["currency"]),
+				"AvailableQuantity": event.Data["available_quantity"],
+				"CustomerName":      subscriber.Name,
+			},
+			inApp: fmt.Sprintf("%s is back in stock.", productName),
+			link:  "/products/" + url.PathEscape(productID),
+		})
+		if err != nil {
+			h.log(ctx).Error("Failed to send back-in-stock notification",
+				zap.String("product_id", productID),
+				zap.String("subscriber", subscriber.ID()),
+				zap.Error(err),
+			)
+			lastErr = err
+			continue
+		}
+		notified++
+	}
+
+	// Nobody was notified, so let the event be retried and the next one for
+	// this product go out
+	if notified == 0 && lastErr != nil {
+		if h.limiter != nil {
+			if err := h.limiter.ResetCooldown(ctx, cooldownKey); err != nil {
+				h.log(ctx).Warn("Failed to reset back-in-stock cooldown", zap.Error(err))
+			}
+		}
+		return fmt.Errorf("failed to send back-in-stock notifications: %w", lastErr)
+	}
+
+	h.log(ctx).Info("Back-in-stock notifications sent",
+		zap.String("product_id", productID),
+		zap.Int("subscribers", len(subscribers)),
+		zap.Int("failed", len(subscribers)-notified),
+	)
+	return nil
+}
+
+// subscriberEvent is the event as seen by one subscriber: their contact
+// details in place of the subscriber list, and a key of its own so replays
+// dedupe per subscriber
+func subscriberEvent(event consumer.Event, subscriber subscriptions.Subscriber) consumer.Event {
+	data := make(map[string]interface{}, len(event.Data)+4)
+	for key, value := range event.Data {
+		if key == "subscribers" {
+			continue
+		}
+		data[key] = value
+	}
+	data["customer_email"] = subscriber.Email
+	data["customer_name"] = subscriber.Name
+	data["user_id"] = subscriber.UserID
+	data["customer_phone"] = subscriber.Phone
+
+	scoped := event
+	scoped.Data = data
+	if event.Key != "" {
+		scoped.Key = event.Key + "/" + subscriber.ID()
+	}
+	return scoped
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/ecommerce-platform/shared/go/audit"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce/notification-service/internal/branding"
+	"github.com/ecommerce/notification-service/internal/store"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// brandIDPattern keeps brand IDs short, lowercase and URL-safe
+var brandIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// BrandHandler manages the storefront brands templates are themed for
+type BrandHandler struct {
+	store   store.BrandStore
+	brands  *branding.Brands
+	auditor *audit.Auditor
+	logger  *zap.Logger
+}
+
+// NewBrandHandler creates a new brand handler. Changes to brands are audit
+// logged.
+func NewBrandHandler(brandStore store.BrandStore, brands *branding.Brands, auditor *audit.Auditor, logger *zap.Logger) *BrandHandler {
+	return &BrandHandler{
+		store:   brandStore,
+		brands:  brands,
+		auditor: auditor,
+		logger:  logger,
+	}
+}
+
+// BrandRequest is a brand's settings. Empty settings fall back to the
+// default brand's; the sender name falls back to the brand name.
+type BrandRequest struct {
+	Name          string `json:"name" binding:"required,max=255"`
+	LogoURL       string `json:"logo_url"`
+	PrimaryColor  string `json:"primary_color" binding:"omitempty,hexcolor,max=9"`
+	SupportEmail  string `json:"support_email" binding:"omitempty,email"`
+	StorefrontURL string `json:"storefront_url"`
+	FooterText    string `json:"footer_text" binding:"max=1000"`
+	FromName      string `json:"from_name" binding:"max=255"`
+	FromEmail     string `json:"from_email" binding:"omitempty,email"`
+}
+
+// List returns the default brand and all configured brands
+func (h *BrandHandler) List(c *gin.Context) {
+	brands, err := h.store.ListBrands(c.Request.Context())
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list brands"))
+		return
+	}
+	if brands == nil {
+		brands = []*store.Brand{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"default": h.brands.Default(),
+		"brands":  brands,
+	})
+}
+
+// Get returns a brand's settings and how they resolve with the defaults
+func (h *BrandHandler) Get(c *gin.Context) {
+	brand, err := h.store.GetBrand(c.Request.Context(), c.Param("brandId"))
+	if err == store.ErrNotFound {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Brand not found"))
+		return
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get brand"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"brand":    brand,
+		"resolved": h.brands.Resolve(c.Request.Context(), brand.ID),
+	})
+}
+
+// Put creates or replaces a brand
+func (h *BrandHandler) Put(c *gin.Context) {
+	id := c.Param("brandId")
+	if !brandIDPattern.MatchString(id) {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "Brand ID must be 1-64 lowercase letters, digits, - or _"))
+		return
+	}
+
+	var req BrandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+	for field, value := range map[string]string{"logo_url": req.LogoURL, "storefront_url": req.StorefrontURL} {
+		if value != "" && !webURL(value) {
+			apperrors.Abort(c, apperrors.New(http.StatusBadRequest, field+" must be an absolute http(s) URL"))
+			return
+		}
+	}
+	// The sender name ends up in the From header
+	if strings.ContainsAny(req.FromName, "\r\n<>\"") {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "from_name must not contain line breaks, quotes or angle brackets"))
+		return
+	}
+
+	brand := &store.Brand{
+		ID:            id,
+		Name:          req.Name,
+		LogoURL:       req.LogoURL,
+		PrimaryColor:  req.PrimaryColor,
+		SupportEmail:  req.SupportEmail,
+		StorefrontURL: strings.TrimSuffix(req.StorefrontURL, "/"),
+		FooterText:    req.FooterText,
+		FromName:      req.FromName,
+		FromEmail:     req.FromEmail,
+	}
+
+	before, err := h.store.GetBrand(c.Request.Context(), id)
+	if err != nil && err != store.ErrNotFound {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get brand"))
+		return
+	}
+	if err := h.store.UpsertBrand(c.Request.Context(), brand); err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to save brand"))
+		return
+	}
+	h.brands.Invalidate(id)
+
+	entry := audit.Entry{Action: "brand.created", Resource: audit.Resource{Type: "brand", ID: id}, After: brand}
+	if before != nil {
+		entry.Action = "brand.updated"
+		entry.Before = before
+	}
+	h.auditor.LogGin(c, entry)
+
+	h.logger.Info("Brand saved", zap.String("brand_id", id))
+	c.JSON(http.StatusOK, brand)
+}
+
+// Delete removes a brand; its events fall back to the default brand
+func (h *BrandHandler) Delete(c *gin.Context) {
+	id := c.Param("brandId")
+	before, err := h.store.GetBrand(c.Request.Context(), id)
+	if err == nil {
+		err = h.store.DeleteBrand(c.Request.Context(), id)
+	}
+	if err == store.ErrNotFound {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Brand not found"))
+		return
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to delete brand"))
+		return
+	}
+	h.brands.Invalidate(id)
+
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   "brand.deleted",
+		Resource: audit.Resource{Type: "brand", ID: id},
+		Before:   before,
+	})
+
+	h.logger.Info("Brand deleted", zap.String("brand_id", id))
+	c.Status(http.StatusNoContent)
+}
+
+// webURL reports whether s is an absolute http(s) URL
+func webURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/contact"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce/notification-service/internal/store"
+	"github.com/ecommerce/notification-service/internal/templates"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// maxSegmentUpload bounds the size of an uploaded segment
+const maxSegmentUpload = 64 << 20
+
+// maxInvalidReported bounds how many invalid rows a segment upload reports
+const maxInvalidReported = 20
+
+// CampaignHandler manages segments and bulk email campaigns
+type CampaignHandler struct {
+	store          store.CampaignStore
+	brands         store.BrandStore
+	templateEngine *templates.TemplateEngine
+	logger         *zap.Logger
+}
+
+// NewCampaignHandler creates a new campaign handler
+func NewCampaignHandler(campaignStore store.CampaignStore, brandStore store.BrandStore, templateEngine *templates.TemplateEngine, logger *zap.Logger) *CampaignHandler {
+	return &CampaignHandler{
+		store:          campaignStore,
+		brands:         brandStore,
+		templateEngine: templateEngine,
+		logger:         logger,
+	}
+}
+
+// SegmentRequest uploads a segment as JSON
+type SegmentRequest struct {
+	Name       string                `json:"name" binding:"required,max=255"`
+	Recipients []store.SegmentMember `json:"recipients" binding:"required"`
+}
+
+// CampaignRequest schedules a campaign. It starts right away without a
+// scheduled time.
+type CampaignRequest struct {
+	Name        string                 `json:"name" binding:"required,max=255"`
+	Template    string                 `json:"template" binding:"required"`
+	SegmentID   string                 `json:"segment_id" binding:"required"`
+	BrandID     string                 `json:"brand_id"`
+	Data        map[string]interface{} `json:"data"`
+	ScheduledAt *time.Time             `json:"scheduled_at"`
+}
+
+// invalidRow is a segment row that was skipped
+type invalidRow struct {
+	Row   int    `json:"row"`
+	Email string `json:"email"`
+}
+
+// CreateSegment uploads a segment, either as JSON or as CSV with a
+// Content-Type of text/csv and the name in the name query parameter. CSV
+// files need an email column; user_id and name columns are optional and any
+// other column becomes template data. Rows with an invalid email address are
+// skipped and duplicates are dropped.
+func (h *CampaignHandler) CreateSegment(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSegmentUpload)
+
+	var name string
+	var members []store.SegmentMember
+	if c.ContentType() == "text/csv" {
+		name = strings.TrimSpace(c.Query("name"))
+		if name == "" {
+			apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "name query parameter is required"))
+			return
+		}
+
+		var err error
+		members, err = parseSegmentCSV(c.Request.Body)
+		if err != nil {
+			apperrors.Abort(c, apperrors.New(http.StatusBadRequest, err.Error()))
+			return
+		}
+	} else {
+		var req SegmentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apperrors.Abort(c, apperrors.New(http.StatusBadRequest, err.Error()))
+			return
+		}
+		name = req.Name
+		members = req.Recipients
+	}
+
+	valid, invalid, invalidCount := cleanSegment(members)
+	if len(valid) == 0 {
+		apperrors.Abort(c, apperrors.New(http.StatusUnprocessableEntity, "Segment has no valid recipients").
+			WithFields(gin.H{"invalid": invalid}))
+		return
+	}
+
+	segment := &store.Segment{
+		Name:      name,
+		CreatedBy: c.GetString("user_id"),
+	}
+	if err := h.store.CreateSegment(c.Request.Context(), segment, valid); err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to create segment"))
+		return
+	}
+
+	h.logger.Info("Segment created",
+		zap.String("segment_id", segment.ID),
+		zap.Int("size", segment.Size),
+		zap.Int("invalid", invalidCount),
+		zap.String("created_by", segment.CreatedBy),
+	)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"segment":       segment,
+		"invalid_count": invalidCount,
+		"invalid":       invalid,
+	})
+}
+
+// parseSegmentCSV reads segment members from a CSV file with a header row
+func parseSegmentCSV(r io.Reader) ([]store.SegmentMember, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("CSV file has no header row")
+	}
+	emailColumn := -1
+	for i, column := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(column))
+		if header[i] == "email" {
+			emailColumn = i
+		}
+	}
+	if emailColumn < 0 {
+		return nil, errors.New("CSV file has no email column")
+	}
+
+	var members []store.SegmentMember
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.New("invalid CSV file: " + err.Error())
+		}
+
+		member := store.SegmentMember{Data: make(map[string]interface{})}
+		for i, value := range record {
+			if i >= len(header) || header[i] == "" {
+				continue
+			}
+			switch header[i] {
+			case "email":
+				member.Email = value
+			case "user_id":
+				member.UserID = value
+			case "name":
+				member.Name = value
+			default:
+				member.Data[header[i]] = value
+			}
+		}
+		members = append(members, member)
+	}
+
+	return members, nil
+}
+
+// cleanSegment normalizes and validates members' addresses and drops
+// duplicates. It returns the valid members, the first invalid rows and how
+// many rows were invalid.
+func cleanSegment(members []store.SegmentMember) ([]store.SegmentMember, []invalidRow, int) {
+	valid := make([]store.SegmentMember, 0, len(members))
+	invalid := []invalidRow{}
+	invalidCount := 0
+	seen := make(map[string]bool, len(members))
+
+	for i, member := range members {
+		address, err := contact.NormalizeEmail(member.Email)
+		if err != nil {
+			invalidCount++
+			if len(invalid) < maxInvalidReported {
+				invalid = append(invalid, invalidRow{Row: i + 1, Email: member.Email})
+			}
+			continue
+		}
+		if seen[address] {
+			continue
+		}
+		seen[address] = true
+
+		member.Email = address
+		valid = append(valid, member)
+	}
+
+	return valid, invalid, invalidCount
+}
+
+// ListSegments returns uploaded segments, newest first
+func (h *CampaignHandler) ListSegments(c *gin.Context) {
+	limit, offset := pagination(c)
+
+	segments, err := h.store.ListSegments(c.Request.Context(), limit, offset)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list segments"))
+		return
+	}
+	if segments == nil {
+		segments = []*store.Segment{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"segments": segments, "limit": limit, "offset": offset})
+}
+
+// Create schedules a campaign of a template to a segment
+func (h *CampaignHandler) Create(c *gin.Context) {
+	var req CampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	if !h.templateEngine.Has(req.Template) {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Template not found"))
+		return
+	}
+
+	// Unknown brands would silently fall back to the default for every email
+	if req.BrandID != "" {
+		if _, err := h.brands.GetBrand(c.Request.Context(), req.BrandID); err == store.ErrNotFound {
+			apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Brand not found"))
+			return
+		} else if err != nil {
+			apperrors.Abort(c, apperrors.Wrap(err, "Failed to create campaign"))
+			return
+		}
+	}
+
+	campaign := &store.Campaign{
+		Name:      req.Name,
+		Template:  req.Template,
+		SegmentID: req.SegmentID,
+		BrandID:   req.BrandID,
+		Data:      req.Data,
+		CreatedBy: c.GetString("user_id"),
+	}
+	if req.ScheduledAt != nil {
+		campaign.ScheduledAt = *req.ScheduledAt
+	}
+
+	err := h.store.CreateCampaign(c.Request.Context(), campaign)
+	if err == store.ErrNotFound {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Segment not found"))
+		return
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to create campaign"))
+		return
+	}
+
+	h.logger.Info("Campaign scheduled",
+		zap.String("campaign_id", campaign.ID),
+		zap.String("template", campaign.Template),
+		zap.Int("recipients", campaign.Progress.Total),
+		zap.Time("scheduled_at", campaign.ScheduledAt),
+		zap.String("created_by", campaign.CreatedBy),
+	)
+
+	c.JSON(http.StatusCreated, campaign)
+}
+
+// List returns campaigns with their progress, newest first
+func (h *CampaignHandler) List(c *gin.Context) {
+	limit, offset := pagination(c)
+
+	campaigns, err := h.store.ListCampaigns(c.Request.Context(), limit, offset)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list campaigns"))
+		return
+	}
+	if campaigns == nil {
+		campaigns = []*store.Campaign{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"campaigns": campaigns, "limit": limit, "offset": offset})
+}
+
+// Get returns a campaign with its progress
+func (h *CampaignHandler) Get(c *gin.Context) {
+	campaign, err := h.store.GetCampaign(c.Request.Context(), c.Param("id"))
+	if err == store.ErrNotFound {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Campaign not found"))
+		return
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get campaign"))
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+// Pause stops sending a scheduled or running campaign after the batch in
+// flight
+func (h *CampaignHandler) Pause(c *gin.Context) {
+	h.transition(c, []string{store.CampaignScheduled, store.CampaignRunning}, store.CampaignPaused, "paused")
+}
+
+// Resume lets a paused campaign continue. It is rescheduled, so it starts
+// again on the next run once its scheduled time has come.
+func (h *CampaignHandler) Resume(c *gin.Context) {
+	h.transition(c, []string{store.CampaignPaused}, store.CampaignScheduled, "resumed")
+}
+
+// Cancel stops a campaign for good; unsent recipients are never emailed
+func (h *CampaignHandler) Cancel(c *gin.Context) {
+	h.transition(c, []string{store.CampaignScheduled, store.CampaignRunning, store.CampaignPaused}, store.CampaignCancelled, "cancelled")
+}
+
+// transition moves a campaign to a status; action names it in the conflict
+// error
+func (h *CampaignHandler) transition(c *gin.Context, from []string, to, action string) {
+	id := c.Param("id")
+
+	err := h.store.TransitionCampaign(c.Request.Context(), id, from, to)
+	if err == store.ErrNotFound {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Campaign not found"))
+		return
+	}
+	if err == store.ErrCampaignState {
+		apperrors.Abort(c, apperrors.New(http.StatusConflict, "Campaign can't be "+action+" from its current status"))
+		return
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to update campaign"))
+		return
+	}
+
+	h.logger.Info("Campaign status changed",
+		zap.String("campaign_id", id),
+		zap.String("status", to),
+		zap.String("user_id", c.GetString("user_id")),
+	)
+
+	h.Get(c)
+}
+
+// pagination reads the limit and offset query parameters
+func pagination(c *gin.Context) (limit, offset int) {
+	limit, _ = strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
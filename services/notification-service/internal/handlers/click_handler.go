@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce/notification-service/internal/store"
+	"github.com/ecommerce/notification-service/internal/tracking"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ClickHandler serves the redirect behind tracked email links
+type ClickHandler struct {
+	store  store.NotificationStore
+	clicks *tracking.ClickTracker
+	logger *zap.Logger
+}
+
+// NewClickHandler creates a new click-tracking handler
+func NewClickHandler(notificationStore store.NotificationStore, clicks *tracking.ClickTracker, logger *zap.Logger) *ClickHandler {
+	return &ClickHandler{
+		store:  notificationStore,
+		clicks: clicks,
+		logger: logger,
+	}
+}
+
+// Click records a click on a tracked link and redirects to its target. The
+// customer is redirected even when the click can't be recorded.
+func (h *ClickHandler) Click(c *gin.Context) {
+	notificationID, target, err := h.clicks.Resolve(c.Param("token"))
+	if err != nil {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Link not found"))
+		return
+	}
+
+	if err := h.store.RecordClick(c.Request.Context(), notificationID, target); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			h.logger.Warn("Click on a link of an unknown notification", zap.String("notification_id", notificationID))
+		} else {
+			h.logger.Error("Failed to record click",
+				zap.String("notification_id", notificationID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	c.Redirect(http.StatusFound, target)
+}
@@ -0,0 +1,783 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/contact"
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/notification-service/internal/branding"
+	"github.com/ecommerce/notification-service/internal/consumer"
+	"github.com/ecommerce/notification-service/internal/email"
+	"github.com/ecommerce/notification-service/internal/metrics"
+	"github.com/ecommerce/notification-service/internal/preferences"
+	"github.com/ecommerce/notification-service/internal/replay"
+	"github.com/ecommerce/notification-service/internal/routing"
+	"github.com/ecommerce/notification-service/internal/store"
+	"github.com/ecommerce/notification-service/internal/templates"
+	"github.com/ecommerce/notification-service/internal/whatsapp"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// notification is the content of a customer notification on every channel.
+// Channels without content (no in-app text, no mobile message) are skipped.
+type notification struct {
+	template string
+	emailKey string // event data field holding the recipient address
+	data     map[string]interface{}
+	inApp    string // in-app body; the title is the email subject
+	link     string
+	mobile   *whatsapp.Message
+	// attachments are media-service IDs of files sent with the email
+	attachments []string
+}
+
+// notify renders a notification and delivers it as the event type's channel
+// policy says: every step in turn, each on the first of its channels that
+// sends. Failures are logged and fall through to the step's next channel;
+// an email failure with no fallback fails the event so it is retried.
+func (h *NotificationHandler) notify(ctx context.Context, event consumer.Event, n notification) error {
+	policy := h.policies.For(event.EventType)
+
+	to, _ := event.Data[n.emailKey].(string)
+	if to == "" && policy.Uses(routing.Email) {
+		return fmt.Errorf("missing %s in event data", n.emailKey)
+	}
+
+	// Variants are picked per customer; without an email address, by user
+	recipient := to
+	if recipient == "" {
+		recipient = userIDFromEvent(event)
+	}
+	data := templates.WithBrand(n.data, branding.Template(h.brand(ctx, event)))
+	attachments := append(mediaIDs(event.Data["attachments"]), n.attachments...)
+	subject, body, variant, err := h.templateEngine.RenderFor(n.template, recipient, data)
+	if err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+
+	for _, step := range policy {
+		for i, channel := range step {
+			var sent bool
+			var err error
+			switch channel {
+			case routing.Email:
+				sent, err = h.deliverEmail(ctx, event, n.template, variant, to, subject, body, attachments)
+			case routing.InApp:
+				if n.inApp != "" {
+					sent, err = h.deliverInApp(ctx, event, n.template, subject, n.inApp, n.link)
+				}
+			case routing.SMS, routing.WhatsApp, routing.Mobile:
+				if phone, _ := event.Data["customer_phone"].(string); phone != "" && n.mobile != nil {
+					var used store.Channel
+					used, err = h.deliverMobile(ctx, event, channel, phone, *n.mobile)
+					if used != "" {
+						sent, channel = true, routing.Channel(used)
+					}
+				}
+			}
+
+			if err != nil {
+				if channel == routing.Email && i == len(step)-1 {
+					return fmt.Errorf("failed to send email: %w", err)
+				}
+				h.log(ctx).Error("Failed to send notification",
+					zap.String("template", n.template),
+					zap.String("channel", string(channel)),
+					zap.Error(err),
+				)
+				continue
+			}
+			if sent {
+				h.log(ctx).Info("Notification sent",
+					zap.String("template", n.template),
+					zap.String("channel", string(channel)),
+					zap.String("order_id", event.OrderID),
+					zap.String("user_id", userIDFromEvent(event)),
+				)
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// deliverEmail checks preferences and rate limits, sends the email (or
+// queues it for the recipient's digest, or holds it for their quiet hours)
+// and records the outcome, including the template's A/B test variant. Links
+// are routed through click tracking when it is enabled for the template.
+// The address is normalized first; invalid addresses are recorded as
+// suppressed rather than sent. Emails with attachments, documents such as
+// invoices and return labels, are never digested or held. It reports whether
+// the email was actually sent.
+func (h *NotificationHandler) deliverEmail(ctx context.Context, event consumer.Event, templateName, variant, to, subject, body string, attachments []string) (bool, error) {
+	normalized, err := contact.NormalizeEmail(to)
+	if err != nil {
+		h.rejectRecipient(ctx, event, store.ChannelEmail, templateName, to, err)
+		return false, nil
+	}
+	to = normalized
+
+	record := h.newRecord(event, store.ChannelEmail, templateName, to)
+	record.Variant = variant
+
+	if h.replayCheck(ctx, event, record) {
+		return false, nil
+	}
+
+	if allowed, reason := h.preferencesAllow(ctx, event, record.Channel); !allowed {
+		h.suppress(ctx, record, reason)
+		return false, nil
+	}
+
+	if len(attachments) == 0 && h.digestEnabled(event.EventType) {
+		h.queueForDigest(ctx, event, record, subject, "low-priority category")
+		return false, nil
+	}
+
+	if releaseAt, quiet := h.quietUntil(ctx, event); quiet && len(attachments) == 0 {
+		h.schedule(ctx, record, subject, body, releaseAt)
+		return false, nil
+	}
+
+	if allowed, reason := h.withinRateLimit(ctx, record); !allowed {
+		if h.config.RateLimitOverflow == "digest" && h.canDigest(event.EventType) && len(attachments) == 0 {
+			h.queueForDigest(ctx, event, record, subject, reason)
+		} else {
+			h.suppress(ctx, record, reason)
+		}
+		return false, nil
+	}
+
+	if h.clicks.Enabled(templateName, preferences.CategoryFor(event.EventType)) {
+		// Links identify the notification record, so it needs its ID up front
+		record.ID = uuid.New().String()
+		body = h.clicks.RewriteLinks(record.ID, body)
+	}
+
+	files, err := h.fetchAttachments(ctx, event, attachments)
+	if err != nil {
+		h.recordResult(ctx, record, err)
+		return false, err
+	}
+
+	brand := h.brands.Resolve(ctx, record.BrandID)
+	start := time.Now()
+	result, err := h.emailSender.Send(ctx, email.Email{
+		To:          to,
+		Subject:     subject,
+		Body:        body,
+		FromName:    brand.FromName,
+		FromEmail:   brand.FromEmail,
+		Attachments: files,
+	})
+	h.observeSend(record, start)
+	if result != nil {
+		record.Provider = result.Provider
+		record.MessageID = result.MessageID
+	}
+	h.recordResult(ctx, record, err)
+
+	return err == nil, err
+}
+
+// fetchAttachments fetches the files of an email from media-service.
+// Without it, emails are sent without their files.
+func (h *NotificationHandler) fetchAttachments(ctx context.Context, event consumer.Event, mediaIDs []string) ([]email.Attachment, error) {
+	if len(mediaIDs) == 0 {
+		return nil, nil
+	}
+	if !h.media.Enabled() {
+		h.log(ctx).Warn("Sending email without attachments: MEDIA_SERVICE_URL is not configured",
+			zap.Strings("media_ids", mediaIDs),
+		)
+		return nil, nil
+	}
+	files := make([]email.Attachment, 0, len(mediaIDs))
+	for _, id := range mediaIDs {
+		file, err := h.media.Attachment(ctx, event.TenantID, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch attachment: %w", err)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// mediaIDs reads a list of media-service IDs from event data
+func mediaIDs(raw interface{}) []string {
+	list, _ := raw.([]interface{})
+	ids := make([]string, 0, len(list))
+	for _, item := range list {
+		if id, ok := item.(string); ok && id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// deliverSMS checks preferences, quiet hours and rate limits, sends the SMS
+// and records the outcome. SMS is never digested; overflow is always dropped.
+// It reports whether the SMS was actually sent.
+func (h *NotificationHandler) deliverSMS(ctx context.Context, event consumer.Event, to, message string) (bool, error) {
+	record := h.newRecord(event, store.ChannelSMS, "", to)
+
+	if h.replayCheck(ctx, event, record) {
+		return false, nil
+	}
+
+	if allowed, reason := h.preferencesAllow(ctx, event, record.Channel); !allowed {
+		h.suppress(ctx, record, reason)
+		return false, nil
+	}
+
+	if releaseAt, quiet := h.quietUntil(ctx, event); quiet {
+		h.schedule(ctx, record, "", message, releaseAt)
+		return false, nil
+	}
+
+	if allowed, reason := h.withinRateLimit(ctx, record); !allowed {
+		h.suppress(ctx, record, reason)
+		return false, nil
+	}
+
+	start := time.Now()
+	result, err := h.smsSender.Send(ctx, to, message)
+	h.observeSend(record, start)
+	if result != nil {
+		record.Provider = result.Provider
+		record.MessageID = result.MessageID
+		record.Segments = result.Segments
+		record.Cost = result.Cost
+	}
+	h.recordResult(ctx, record, err)
+
+	return err == nil, err
+}
+
+// deliverWhatsApp checks preferences and rate limits, sends a WhatsApp
+// template message and records the outcome. It reports whether it was sent.
+func (h *NotificationHandler) deliverWhatsApp(ctx context.Context, event consumer.Event, to string, msg whatsapp.Message) (bool, error) {
+	record := h.newRecord(event, store.ChannelWhatsApp, msg.Template, to)
+
+	if h.replayCheck(ctx, event, record) {
+		return false, nil
+	}
+
+	if allowed, reason := h.allowed(ctx, event, record); !allowed {
+		h.suppress(ctx, record, reason)
+		return false, nil
+	}
+
+	start := time.Now()
+	result, err := h.whatsAppSender.Send(ctx, to, msg)
+	h.observeSend(record, start)
+	if result != nil {
+		record.Provider = result.Provider
+		record.MessageID = result.MessageID
+	}
+	h.recordResult(ctx, record, err)
+
+	return err == nil, err
+}
+
+// deliverMobile sends a mobile notification over the given policy channel:
+// routing.Mobile uses WhatsApp when the customer opted in and WhatsApp is
+// enabled for their country, otherwise (or when WhatsApp fails) SMS;
+// routing.WhatsApp and routing.SMS use only that channel. The number is
+// normalized to E.164 first; numbers that can't receive SMS are recorded as
+// suppressed rather than sent. Once the daily SMS budget is spent, non-exempt
+// SMS go by email instead. It reports the channel used, if any.
+func (h *NotificationHandler) deliverMobile(ctx context.Context, event consumer.Event, via routing.Channel, to string, msg whatsapp.Message) (store.Channel, error) {
+	normalized, err := h.smsSender.ValidatePhoneNumber(to)
+	if err != nil {
+		h.rejectRecipient(ctx, event, store.ChannelSMS, "", to, err)
+		return "", nil
+	}
+	to = normalized
+
+	if via != routing.SMS && h.whatsAppSender != nil && h.whatsAppSender.EnabledFor(to) &&
+		h.loadPreferences(ctx, event).OptedIn(string(store.ChannelWhatsApp)) {
+		sent, err := h.deliverWhatsApp(ctx, event, to, msg)
+		if err == nil {
+			if sent {
+				return store.ChannelWhatsApp, nil
+			}
+			return "", nil
+		}
+		if via == routing.WhatsApp {
+			return "", err
+		}
+		h.log(ctx).Warn("WhatsApp failed, falling back to SMS", zap.Error(err))
+	}
+	if via == routing.WhatsApp {
+		return "", nil
+	}
+
+	if h.smsSender.BudgetExceeded(ctx) && !h.smsSender.BudgetExempt(preferences.CategoryFor(event.EventType)) {
+		return h.downgradeSMS(ctx, event, to, msg)
+	}
+
+	sent, err := h.deliverSMS(ctx, event, to, msg.Text)
+	if err != nil || !sent {
+		return "", err
+	}
+	return store.ChannelSMS, nil
+}
+
+// rejectRecipient records a notification that wasn't attempted because the
+// recipient's address or number is invalid
+func (h *NotificationHandler) rejectRecipient(ctx context.Context, event consumer.Event, channel store.Channel, templateName, recipient string, err error) {
+	code := contact.CodeInvalidFormat
+	var contactErr *contact.Error
+	if errors.As(err, &contactErr) {
+		code = contactErr.Code
+	}
+	metrics.InvalidRecipientsTotal.WithLabelValues(string(channel), code).Inc()
+
+	record := h.newRecord(event, channel, templateName, recipient)
+	h.suppress(ctx, record, err.Error())
+}
+
+// downgradeSMS handles an SMS the daily budget can't cover: it is recorded as
+// suppressed and its text is emailed to the customer instead, unless the
+// event's email for the same template already reached them.
+// It reports the channel used, if any.
+func (h *NotificationHandler) downgradeSMS(ctx context.Context, event consumer.Event, to string, msg whatsapp.Message) (store.Channel, error) {
+	record := h.newRecord(event, store.ChannelSMS, "", to)
+	if h.replayCheck(ctx, event, record) {
+		return "", nil
+	}
+	h.suppress(ctx, record, "daily SMS budget exceeded")
+
+	customerEmail, _ := event.Data["customer_email"].(string)
+	if customerEmail == "" {
+		metrics.SMSBudgetDowngrades.WithLabelValues(event.EventType, "dropped").Inc()
+		return "", nil
+	}
+
+	if h.store != nil && event.Key != "" {
+		emailed, err := h.store.HasDelivered(ctx, event.Key, store.ChannelEmail, msg.Template)
+		if err != nil {
+			h.log(ctx).Warn("Failed to check for an email sent for this event, emailing SMS text", zap.Error(err))
+		} else if emailed {
+			metrics.SMSBudgetDowngrades.WithLabelValues(event.EventType, "already_emailed").Inc()
+			return "", nil
+		}
+	}
+
+	subject, body, err := h.templateEngine.Render("sms_fallback", templates.WithBrand(map[string]interface{}{
+		"CustomerName": event.Data["customer_name"],
+		"OrderNumber":  event.Data["order_number"],
+		"Message":      msg.Text,
+	}, branding.Template(h.brand(ctx, event))))
+	if err != nil {
+		return "", fmt.Errorf("failed to render SMS fallback email: %w", err)
+	}
+
+	sent, err := h.deliverEmail(ctx, event, "sms_fallback", "", customerEmail, subject, body, nil)
+	if err != nil || !sent {
+		metrics.SMSBudgetDowngrades.WithLabelValues(event.EventType, "email_failed").Inc()
+		return "", err
+	}
+	metrics.SMSBudgetDowngrades.WithLabelValues(event.EventType, "emailed").Inc()
+	return store.ChannelEmail, nil
+}
+
+// deliverInApp adds a notification to the user's in-app inbox and records the
+// outcome. In-app items are neither rate limited nor digested, and events
+// without a user_id are skipped. It reports whether the item was added.
+func (h *NotificationHandler) deliverInApp(ctx context.Context, event consumer.Event, templateName, title, body, link string) (bool, error) {
+	userID := userIDFromEvent(event)
+	if h.inbox == nil || userID == "" {
+		return false, nil
+	}
+
+	record := h.newRecord(event, store.ChannelInApp, templateName, userID)
+
+	if h.replayCheck(ctx, event, record) {
+		return false, nil
+	}
+
+	if allowed, reason := h.preferencesAllow(ctx, event, record.Channel); !allowed {
+		h.suppress(ctx, record, reason)
+		return false, nil
+	}
+
+	err := h.inbox.CreateInboxItem(ctx, &store.InboxItem{
+		UserID:    userID,
+		EventType: event.EventType,
+		Template:  templateName,
+		Title:     title,
+		Body:      body,
+		Link:      link,
+	})
+	h.recordResult(ctx, record, err)
+
+	return err == nil, err
+}
+
+// allowed runs the pre-send checks for a notification and returns the
+// suppression reason when it must not be sent
+func (h *NotificationHandler) allowed(ctx context.Context, event consumer.Event, record *store.Notification) (bool, string) {
+	if ok, reason := h.preferencesAllow(ctx, event, record.Channel); !ok {
+		return false, reason
+	}
+
+	return h.withinRateLimit(ctx, record)
+}
+
+// preferencesAllow consults the user's preferences for the event's category.
+// Preferences carried in the event take precedence over user-service.
+// Lookup failures fail open so transactional messages are never lost.
+func (h *NotificationHandler) preferencesAllow(ctx context.Context, event consumer.Event, channel store.Channel) (bool, string) {
+	return h.loadPreferences(ctx, event).Allows(string(channel), preferences.CategoryFor(event.EventType))
+}
+
+// loadPreferences returns the preferences carried in the event, else the
+// user's preferences from user-service, else nil
+func (h *NotificationHandler) loadPreferences(ctx context.Context, event consumer.Event) *preferences.Preferences {
+	prefs := preferences.FromEventData(event.Data)
+
+	if prefs == nil && h.preferences != nil {
+		if userID := userIDFromEvent(event); userID != "" {
+			fetched, err := h.preferences.Get(ctx, userID)
+			if err != nil {
+				h.log(ctx).Warn("Failed to load notification preferences, sending anyway",
+					zap.String("user_id", userID),
+					zap.Error(err),
+				)
+			} else {
+				prefs = fetched
+			}
+		}
+	}
+
+	return prefs
+}
+
+// withinRateLimit applies the per-recipient hourly throttle. The user ID is
+// used as the throttle key when known so a customer can't be flooded across
+// addresses. Ops alerts are throttled per SKU instead, and security messages,
+// e.g. password resets, are never throttled: holding one back locks the
+// customer out. Redis failures fail open.
+func (h *NotificationHandler) withinRateLimit(ctx context.Context, record *store.Notification) (bool, string) {
+	if h.limiter == nil {
+		return true, ""
+	}
+	switch preferences.CategoryFor(record.EventType) {
+	case preferences.CategoryOperational, preferences.CategorySecurity:
+		return true, ""
+	}
+
+	key := record.UserID
+	if key == "" {
+		key = record.Recipient
+	}
+
+	ok, err := h.limiter.Allow(ctx, string(record.Channel), key)
+	if err != nil {
+		h.log(ctx).Warn("Rate limiter unavailable, sending anyway", zap.Error(err))
+		return true, ""
+	}
+	if !ok {
+		return false, fmt.Sprintf("rate limit exceeded: more than %d %s per hour",
+			h.limiter.Limit(string(record.Channel)), record.Channel)
+	}
+
+	return true, ""
+}
+
+// canDigest reports whether events of this type may ever be held for a
+// digest. Security messages are time-sensitive and always go out immediately.
+func (h *NotificationHandler) canDigest(eventType string) bool {
+	return h.digests != nil && preferences.CategoryFor(eventType) != preferences.CategorySecurity
+}
+
+// digestEnabled reports whether events of this type are batched into digests
+func (h *NotificationHandler) digestEnabled(eventType string) bool {
+	if !h.canDigest(eventType) {
+		return false
+	}
+
+	category := preferences.CategoryFor(eventType)
+	for _, c := range h.config.DigestCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// queueForDigest holds an email for the recipient's next digest
+func (h *NotificationHandler) queueForDigest(ctx context.Context, event consumer.Event, record *store.Notification, subject, reason string) {
+	item := &store.DigestItem{
+		UserID:    record.UserID,
+		Recipient: record.Recipient,
+		EventType: record.EventType,
+		Template:  record.Template,
+		Subject:   subject,
+		Timezone:  h.recipientTimezone(ctx, event),
+		BrandID:   record.BrandID,
+	}
+
+	if err := h.digests.AddDigestItem(ctx, item); err != nil {
+		h.log(ctx).Error("Failed to queue digest item, dropping notification",
+			zap.String("event_type", record.EventType),
+			zap.Error(err),
+		)
+		h.suppress(ctx, record, "digest queue unavailable")
+		return
+	}
+
+	h.log(ctx).Info("Notification queued for digest",
+		zap.String("event_type", record.EventType),
+		zap.String("user_id", record.UserID),
+		zap.String("reason", reason),
+	)
+
+	record.Status = store.StatusDigested
+	record.Reason = reason
+	h.saveRecord(ctx, record)
+}
+
+// recipientTimezone returns the recipient's IANA timezone from the event, else
+// from their user-service profile, else "" (DEFAULT_TIMEZONE applies)
+func (h *NotificationHandler) recipientTimezone(ctx context.Context, event consumer.Event) string {
+	if timezone, ok := event.Data["customer_timezone"].(string); ok && timezone != "" {
+		return timezone
+	}
+	if prefs := h.loadPreferences(ctx, event); prefs != nil {
+		return prefs.Timezone
+	}
+	return ""
+}
+
+// quietUntil reports whether a notification for this event must be held for
+// the recipient's quiet hours and, if so, when their daytime window opens.
+// Only non-urgent categories are held.
+func (h *NotificationHandler) quietUntil(ctx context.Context, event consumer.Event) (time.Time, bool) {
+	if h.scheduled == nil || !h.quietHours.Applies(preferences.CategoryFor(event.EventType)) {
+		return time.Time{}, false
+	}
+
+	loc := h.quietHours.Location(h.recipientTimezone(ctx, event))
+	return h.quietHours.ReleaseAt(time.Now(), loc)
+}
+
+// schedule holds a rendered notification until the recipient's quiet hours end
+func (h *NotificationHandler) schedule(ctx context.Context, record *store.Notification, subject, body string, releaseAt time.Time) {
+	err := h.scheduled.ScheduleNotification(ctx, &store.ScheduledNotification{
+		Channel:   record.Channel,
+		Recipient: record.Recipient,
+		UserID:    record.UserID,
+		OrderID:   record.OrderID,
+		EventType: record.EventType,
+		EventKey:  record.EventKey,
+		Template:  record.Template,
+		Variant:   record.Variant,
+		Subject:   subject,
+		Body:      body,
+		ReleaseAt: releaseAt,
+		// The released notification's status events continue this trail
+		CorrelationID: logging.CorrelationID(ctx),
+		BrandID:       record.BrandID,
+	})
+	if err != nil {
+		h.log(ctx).Error("Failed to schedule notification, dropping it",
+			zap.String("event_type", record.EventType),
+			zap.Error(err),
+		)
+		h.suppress(ctx, record, "scheduled store unavailable")
+		return
+	}
+
+	h.log(ctx).Info("Notification held for quiet hours",
+		zap.String("event_type", record.EventType),
+		zap.String("channel", string(record.Channel)),
+		zap.String("user_id", record.UserID),
+		zap.Time("release_at", releaseAt),
+	)
+
+	record.Status = store.StatusScheduled
+	record.Reason = "quiet hours until " + releaseAt.Format(time.RFC3339)
+	h.saveRecord(ctx, record)
+}
+
+func (h *NotificationHandler) newRecord(event consumer.Event, channel store.Channel, templateName, recipient string) *store.Notification {
+	return &store.Notification{
+		EventType: event.EventType,
+		Channel:   channel,
+		Template:  templateName,
+		Recipient: recipient,
+		UserID:    userIDFromEvent(event),
+		OrderID:   event.OrderID,
+		EventKey:  event.Key,
+		// Manual sends are attributed to whoever triggered them
+		TriggeredBy: event.TriggeredBy,
+		BrandID:     brandIDFromEvent(event),
+	}
+}
+
+// deliveredBefore reports whether a retried event's notification was
+// delivered by an earlier attempt, so a retry only resends what failed. If
+// that can't be checked, it is resent rather than risk losing it.
+func (h *NotificationHandler) deliveredBefore(ctx context.Context, record *store.Notification) bool {
+	if h.store == nil || record.EventKey == "" || sharedkafka.Attempt(ctx) <= 1 {
+		return false
+	}
+
+	delivered, err := h.store.HasDelivered(ctx, record.EventKey, record.Channel, record.Template)
+	if err != nil {
+		h.log(ctx).Warn("Failed to check for an earlier delivery, resending", zap.Error(err))
+		return false
+	}
+	return delivered
+}
+
+// replayCheck applies replay semantics before a notification is sent and
+// reports whether delivery must stop there: the notification was already
+// delivered for this event, or the replay is a dry run, which only records
+// what would happen. Live traffic is only stopped on a retry, for what an
+// earlier attempt already delivered.
+func (h *NotificationHandler) replayCheck(ctx context.Context, event consumer.Event, record *store.Notification) bool {
+	run := replay.FromContext(ctx)
+	if run == nil {
+		return h.deliveredBefore(ctx, record)
+	}
+
+	outcome := replayOutcome(record)
+
+	if h.store != nil && record.EventKey != "" {
+		delivered, err := h.store.HasDelivered(ctx, record.EventKey, record.Channel, record.Template)
+		if err != nil {
+			// Without dedupe a resend could reach the customer twice
+			outcome.Result = string(store.StatusFailed)
+			outcome.Reason = fmt.Sprintf("dedupe check failed: %v", err)
+			run.Record(outcome)
+			return true
+		}
+		if delivered {
+			outcome.Result = replay.ResultDuplicate
+			run.Record(outcome)
+			return true
+		}
+	}
+
+	if !run.DryRun() {
+		return false
+	}
+
+	if allowed, reason := h.preferencesAllow(ctx, event, record.Channel); !allowed {
+		outcome.Result = replay.ResultWouldSuppress
+		outcome.Reason = reason
+	} else {
+		outcome.Result = replay.ResultWouldSend
+	}
+	run.Record(outcome)
+	return true
+}
+
+// isDryRun reports whether ctx belongs to a dry-run replay
+func isDryRun(ctx context.Context) bool {
+	run := replay.FromContext(ctx)
+	return run != nil && run.DryRun()
+}
+
+func replayOutcome(record *store.Notification) replay.Outcome {
+	recipient := record.Recipient
+	if record.Channel == store.ChannelSMS {
+		recipient = maskPhone(recipient)
+	}
+
+	return replay.Outcome{
+		EventKey:  record.EventKey,
+		EventType: record.EventType,
+		Channel:   string(record.Channel),
+		Template:  record.Template,
+		Recipient: recipient,
+		Result:    string(record.Status),
+		Reason:    record.Reason,
+	}
+}
+
+func (h *NotificationHandler) suppress(ctx context.Context, record *store.Notification, reason string) {
+	h.log(ctx).Info("Notification suppressed",
+		zap.String("event_type", record.EventType),
+		zap.String("channel", string(record.Channel)),
+		zap.String("user_id", record.UserID),
+		zap.String("reason", reason),
+	)
+
+	record.Status = store.StatusSuppressed
+	record.Reason = reason
+	h.saveRecord(ctx, record)
+}
+
+func (h *NotificationHandler) recordResult(ctx context.Context, record *store.Notification, sendErr error) {
+	if sendErr != nil {
+		record.Status = store.StatusFailed
+		record.Reason = sendErr.Error()
+	} else {
+		record.Status = store.StatusSent
+	}
+	h.saveRecord(ctx, record)
+}
+
+// observeSend records provider send latency
+func (h *NotificationHandler) observeSend(record *store.Notification, start time.Time) {
+	metrics.SendDuration.
+		WithLabelValues(string(record.Channel), record.Template, record.EventType).
+		Observe(time.Since(start).Seconds())
+}
+
+// saveRecord counts the outcome and persists it to notification history
+func (h *NotificationHandler) saveRecord(ctx context.Context, record *store.Notification) {
+	metrics.NotificationsTotal.
+		WithLabelValues(string(record.Channel), record.Template, record.EventType, string(record.Status)).
+		Inc()
+	if record.Variant != "" && record.Status == store.StatusSent {
+		metrics.VariantSendsTotal.WithLabelValues(record.Template, record.Variant).Inc()
+	}
+
+	if run := replay.FromContext(ctx); run != nil {
+		run.Record(replayOutcome(record))
+	}
+
+	if record.CorrelationID == "" {
+		record.CorrelationID = logging.CorrelationID(ctx)
+	}
+	if h.store != nil {
+		if err := h.store.Create(ctx, record); err != nil {
+			h.log(ctx).Error("Failed to record notification",
+				zap.String("event_type", record.EventType),
+				zap.String("channel", string(record.Channel)),
+				zap.Error(err),
+			)
+		}
+	}
+
+	h.statusEvents.PublishStatus(ctx, record)
+}
+
+func userIDFromEvent(event consumer.Event) string {
+	userID, _ := event.Data["user_id"].(string)
+	return userID
+}
+
+// brandIDFromEvent returns the storefront brand of an event, "" for the
+// default brand
+func brandIDFromEvent(event consumer.Event) string {
+	brandID, _ := event.Data["brand_id"].(string)
+	return brandID
+}
+
+// brand returns the storefront brand an event's notifications are themed for
+func (h *NotificationHandler) brand(ctx context.Context, event consumer.Event) *store.Brand {
+	return h.brands.Resolve(ctx, brandIDFromEvent(event))
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce/notification-service/internal/consumer"
+	"github.com/ecommerce/notification-service/internal/store"
+	"github.com/gin-gonic/gin"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// DLQHandler exposes the dead letter queue for inspection, redrive and purge
+type DLQHandler struct {
+	deadLetters store.DeadLetterStore
+	consumer    *consumer.Consumer
+	logger      *zap.Logger
+}
+
+// NewDLQHandler creates a new DLQ handler
+func NewDLQHandler(deadLetters store.DeadLetterStore, kafkaConsumer *consumer.Consumer, logger *zap.Logger) *DLQHandler {
+	return &DLQHandler{
+		deadLetters: deadLetters,
+		consumer:    kafkaConsumer,
+		logger:      logger,
+	}
+}
+
+// DeadLetterIDsRequest selects dead letters to redrive or purge
+type DeadLetterIDsRequest struct {
+	IDs []string `json:"ids" binding:"required,min=1,max=100"`
+}
+
+// RedriveResult is the outcome of redriving a single dead letter
+type RedriveResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// List returns dead letters with the given status (default pending)
+func (h *DLQHandler) List(c *gin.Context) {
+	status := store.DeadLetterStatus(c.DefaultQuery("status", string(store.DeadLetterPending)))
+	if status != store.DeadLetterPending && status != store.DeadLetterRedriven {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "status must be pending or redriven"))
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	letters, err := h.deadLetters.ListDeadLetters(c.Request.Context(), status, limit, offset)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list dead letters"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dead_letters": letters,
+		"limit":        limit,
+		"offset":       offset,
+	})
+}
+
+// Get returns a single dead letter
+func (h *DLQHandler) Get(c *gin.Context) {
+	letter, err := h.deadLetters.GetDeadLetter(c.Request.Context(), c.Param("id"))
+	if err == store.ErrNotFound {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Dead letter not found"))
+		return
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get dead letter"))
+		return
+	}
+
+	c.JSON(http.StatusOK, letter)
+}
+
+// Redrive reprocesses the selected dead letters through the consumer pipeline.
+// Each message keeps its original headers, so traces and correlation IDs
+// carry over.
+func (h *DLQHandler) Redrive(c *gin.Context) {
+	var req DeadLetterIDsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+	results := make([]RedriveResult, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		results = append(results, h.redrive(ctx, id))
+	}
+
+	h.logger.Info("Dead letters redriven", zap.Int("count", len(results)))
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+func (h *DLQHandler) redrive(ctx context.Context, id string) RedriveResult {
+	letter, err := h.deadLetters.GetDeadLetter(ctx, id)
+	if err == store.ErrNotFound {
+		return RedriveResult{ID: id, Status: "not_found"}
+	}
+	if err != nil {
+		return RedriveResult{ID: id, Status: "error", Error: err.Error()}
+	}
+	if letter.Status == store.DeadLetterRedriven {
+		return RedriveResult{ID: id, Status: "skipped", Error: "already redriven"}
+	}
+
+	msg := kafka.Message{
+		Topic:     letter.Topic,
+		Partition: letter.Partition,
+		Offset:    letter.Offset,
+		Key:       []byte(letter.Key),
+		Value:     []byte(letter.Payload),
+	}
+	for key, value := range letter.Headers {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: key, Value: []byte(value)})
+	}
+
+	if processErr := h.consumer.Process(ctx, msg); processErr != nil {
+		if err := h.deadLetters.RecordDeadLetterFailure(ctx, id, processErr.Error()); err != nil {
+			h.logger.Error("Failed to record redrive failure", zap.String("id", id), zap.Error(err))
+		}
+		return RedriveResult{ID: id, Status: "failed", Error: processErr.Error()}
+	}
+
+	if err := h.deadLetters.MarkDeadLetterRedriven(ctx, id); err != nil {
+		h.logger.Error("Failed to mark dead letter redriven", zap.String("id", id), zap.Error(err))
+	}
+	return RedriveResult{ID: id, Status: string(store.DeadLetterRedriven)}
+}
+
+// Purge permanently deletes poison messages from the dead letter queue
+func (h *DLQHandler) Purge(c *gin.Context) {
+	var req DeadLetterIDsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	deleted, err := h.deadLetters.DeleteDeadLetters(c.Request.Context(), req.IDs)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to purge dead letters"))
+		return
+	}
+
+	h.logger.Info("Dead letters purged", zap.Int64("count", deleted))
+	c.JSON(http.StatusOK, gin.H{"purged": deleted})
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ecommerce/notification-service/internal/consumer"
+)
+
+// sendGiftCard emails a gift card's code to its recipient, from
+// giftcard-service's gift_card.issued events. The recipient needn't be a
+// customer, so nothing is sent in-app.
+func (h *NotificationHandler) sendGiftCard(ctx context.Context, event consumer.Event) error {
+	code, _ := event.Data["code"].(string)
+	if code == "" {
+		return fmt.Errorf("missing code in event")
+	}
+
+	data := map[string]interface{}{
+		"Code":          code,
+		"Balance":       amountData(event.Data["balance"], nil),
+		"RecipientName": event.Data["recipient_name"],
+		"SenderName":    event.Data["sender_name"],
+		"Message":       event.Data["message"],
+	}
+	if raw, _ := event.Data["expires_at"].(string); raw != "" {
+		if expiresAt, err := time.Parse(time.RFC3339, raw); err == nil {
+			data["ExpiresAt"] = expiresAt.Format("January 2, 2006")
+		}
+	}
+
+	return h.notify(ctx, event, notification{
+		template: "gift_card",
+		emailKey: "recipient_email",
+		data:     data,
+	})
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ecommerce/notification-service/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+const readinessTimeout = 3 * time.Second
+
+// HealthHandler serves liveness and readiness probes
+type HealthHandler struct {
+	db     *sql.DB
+	redis  *redis.Client
+	config *config.Config
+	logger *zap.Logger
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(db *sql.DB, redisClient *redis.Client, cfg *config.Config, logger *zap.Logger) *HealthHandler {
+	return &HealthHandler{
+		db:     db,
+		redis:  redisClient,
+		config: cfg,
+		logger: logger,
+	}
+}
+
+// Healthz reports that the process is up
+func (h *HealthHandler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "healthy",
+		"service": "notification-service",
+		"version": "1.0.0",
+	})
+}
+
+// Readyz checks the dependencies needed to process notifications
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	checks := map[string]error{
+		"database": h.db.PingContext(ctx),
+		"redis":    h.redis.Ping(ctx).Err(),
+		"kafka":    h.checkKafka(ctx),
+	}
+	if h.usesSMTP() {
+		checks["smtp"] = h.checkSMTP(ctx)
+	}
+
+	ready := true
+	results := make(map[string]string, len(checks))
+	for name, err := range checks {
+		if err != nil {
+			ready = false
+			results[name] = err.Error()
+			h.logger.Warn("Readiness check failed", zap.String("check", name), zap.Error(err))
+			continue
+		}
+		results[name] = "ok"
+	}
+
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "checks": results})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": results})
+}
+
+// checkKafka succeeds if any configured broker accepts a connection
+func (h *HealthHandler) checkKafka(ctx context.Context) error {
+	var lastErr error
+	for _, broker := range h.config.KafkaBrokers {
+		conn, err := kafka.DialContext(ctx, "tcp", broker)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		conn.Close()
+		return nil
+	}
+	return fmt.Errorf("no kafka broker reachable: %w", lastErr)
+}
+
+// checkSMTP verifies the SMTP server accepts TCP connections
+func (h *HealthHandler) checkSMTP(ctx context.Context) error {
+	addr := net.JoinHostPort(h.config.SMTPHost, fmt.Sprint(h.config.SMTPPort))
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// usesSMTP reports whether SMTP is one of the configured email providers
+func (h *HealthHandler) usesSMTP() bool {
+	for _, provider := range h.config.EmailProviders {
+		if provider == "smtp" {
+			return true
+		}
+	}
+	return false
+}
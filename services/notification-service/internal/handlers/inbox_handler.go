@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce/notification-service/internal/store"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// InboxHandler serves a user's in-app notification feed
+type InboxHandler struct {
+	inbox  store.InboxStore
+	logger *zap.Logger
+}
+
+// NewInboxHandler creates a new inbox handler
+func NewInboxHandler(inbox store.InboxStore, logger *zap.Logger) *InboxHandler {
+	return &InboxHandler{
+		inbox:  inbox,
+		logger: logger,
+	}
+}
+
+// List returns a user's in-app notifications, newest first, with the unread count
+func (h *InboxHandler) List(c *gin.Context) {
+	userID := c.Param("id")
+	unreadOnly := c.Query("unread") == "true"
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	items, err := h.inbox.ListInboxItems(c.Request.Context(), userID, unreadOnly, limit, offset)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list notifications"))
+		return
+	}
+
+	unread, err := h.inbox.CountUnread(c.Request.Context(), userID)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list notifications"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": items,
+		"unread_count":  unread,
+		"limit":         limit,
+		"offset":        offset,
+	})
+}
+
+// MarkRead marks a single notification as read
+func (h *InboxHandler) MarkRead(c *gin.Context) {
+	userID := c.Param("id")
+
+	err := h.inbox.MarkRead(c.Request.Context(), userID, c.Param("notificationId"))
+	if err == store.ErrNotFound {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Notification not found"))
+		return
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to mark notification as read"))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// MarkAllRead marks all of a user's notifications as read
+func (h *InboxHandler) MarkAllRead(c *gin.Context) {
+	userID := c.Param("id")
+
+	updated, err := h.inbox.MarkAllRead(c.Request.Context(), userID)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to mark notifications as read"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
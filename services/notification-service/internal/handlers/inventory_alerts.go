@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ecommerce/notification-service/internal/consumer"
+	"go.uber.org/zap"
+)
+
+// sendInventoryAlert emails a low-stock or reorder alert to the ops
+// distribution list. Alerts for the same SKU and event type are sent at most
+// once per INVENTORY_ALERT_COOLDOWN_MINUTES so a flapping item doesn't spam.
+// Cooldowns are per tenant, as tenants can have the same SKUs.
+func (h *NotificationHandler) sendInventoryAlert(ctx context.Context, event consumer.Event, templateName string) error {
+	if len(h.config.OpsAlertEmails) == 0 {
+		h.log(ctx).Warn("No OPS_ALERT_EMAILS configured, dropping inventory alert",
+			zap.String("event_type", event.EventType),
+		)
+		return nil
+	}
+
+	sku, _ := event.Data["sku"].(string)
+	productID := event.ProductID
+	if productID == "" {
+		productID, _ = event.Data["product_id"].(string)
+	}
+	if sku == "" && productID == "" {
+		return fmt.Errorf("missing sku and product_id in event data")
+	}
+
+	item := sku
+	if item == "" {
+		item = productID
+	}
+	cooldownKey := fmt.Sprintf("%s:%s:%s", event.Tenant(), event.EventType, item)
+
+	// A dry-run replay must not start a cooldown that would hold back live alerts
+	if h.limiter != nil && !isDryRun(ctx) {
+		period := time.Duration(h.config.InventoryAlertCooldown) * time.Minute
+		ok, err := h.limiter.Cooldown(ctx, cooldownKey, period)
+		if err != nil {
+			h.log(ctx).Warn("Alert cooldown unavailable, sending anyway", zap.Error(err))
+		} else if !ok {
+			h.log(ctx).Debug("Inventory alert in cooldown",
+				zap.String("event_type", event.EventType),
+				zap.String("item", item),
+			)
+			return nil
+		}
+	}
+
+	data := map[string]interface{}{
+		"SKU":               sku,
+		"ProductID":         productID,
+		"AvailableQuantity": event.Data["available_quantity"],
+		"ReorderLevel":      event.Data["reorder_level"],
+		"ReorderQuantity":   event.Data["reorder_quantity"],
+		"Warehouse":         event.Data["warehouse"],
+	}
+
+	subject, body, err := h.templateEngine.Render(templateName, data)
+	if err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+
+	var lastErr error
+	delivered := false
+	for _, recipient := range h.config.OpsAlertEmails {
+		sent, err := h.deliverEmail(ctx, event, templateName, "", recipient, subject, body, nil)
+		if err != nil {
+			h.log(ctx).Error("Failed to send inventory alert",
+				zap.String("email", recipient),
+				zap.Error(err),
+			)
+			lastErr = err
+			continue
+		}
+		delivered = delivered || sent
+	}
+
+	// Nobody got the alert, so let the next event for this SKU retry it
+	if !delivered && lastErr != nil {
+		if h.limiter != nil {
+			if err := h.limiter.ResetCooldown(ctx, cooldownKey); err != nil {
+				h.log(ctx).Warn("Failed to reset alert cooldown", zap.Error(err))
+			}
+		}
+		return fmt.Errorf("failed to send inventory alert: %w", lastErr)
+	}
+
+	if delivered {
+		h.log(ctx).Info("Inventory alert sent",
+			zap.String("event_type", event.EventType),
+			zap.String("item", item),
+			zap.Strings("recipients", h.config.OpsAlertEmails),
+		)
+	}
+
+	return nil
+}
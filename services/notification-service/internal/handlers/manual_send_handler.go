@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce-platform/shared/go/validation"
+	"github.com/ecommerce/notification-service/internal/consumer"
+	"github.com/ecommerce/notification-service/internal/metrics"
+	"github.com/ecommerce/notification-service/internal/store"
+	"github.com/ecommerce/notification-service/internal/templates"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// manualTemplates are the templates support may send, with the event each one
+// is sent for. Security and inventory notifications are left to the systems
+// that raise them.
+var manualTemplates = map[string]string{
+	"order_confirmation":    "order.created",
+	"payment_confirmation":  "payment.successful",
+	"payment_failure":       "payment.failed",
+	"shipping_notification": "order.shipped",
+	"delivery_notification": "order.delivered",
+	"order_cancellation":    "order.cancelled",
+	"welcome":               "user.registered",
+}
+
+// ManualSendHandler lets support send a customer notification, e.g. resend an
+// order confirmation, without replaying Kafka messages. Every send is audited.
+type ManualSendHandler struct {
+	handler consumer.EventHandler
+	schemas *validation.Registry
+	store   store.NotificationStore
+	audit   store.ManualSendStore
+	logger  *zap.Logger
+}
+
+// NewManualSendHandler creates a new manual send handler
+func NewManualSendHandler(handler consumer.EventHandler, schemas *validation.Registry, notificationStore store.NotificationStore, audit store.ManualSendStore, logger *zap.Logger) *ManualSendHandler {
+	return &ManualSendHandler{
+		handler: handler,
+		schemas: schemas,
+		store:   notificationStore,
+		audit:   audit,
+		logger:  logger,
+	}
+}
+
+// ManualSendRequest is a notification to send to a customer. Data is the
+// event data the template is rendered from, as the producing service would
+// publish it.
+type ManualSendRequest struct {
+	Template  string                 `json:"template" binding:"required"`
+	OrderID   string                 `json:"order_id"`
+	PaymentID string                 `json:"payment_id"`
+	Data      map[string]interface{} `json:"data" binding:"required"`
+	Reason    string                 `json:"reason" binding:"required"`
+	TicketID  string                 `json:"ticket_id"`
+}
+
+// ManualSendResponse reports what was sent on each channel
+type ManualSendResponse struct {
+	ID            string                `json:"id"`
+	Template      string                `json:"template"`
+	EventType     string                `json:"event_type"`
+	CorrelationID string                `json:"correlation_id"`
+	Notifications []*store.Notification `json:"notifications"`
+}
+
+// Send runs a notification through the same pipeline as its Kafka event, so
+// preferences, quiet hours and channel policies all apply
+func (h *ManualSendHandler) Send(c *gin.Context) {
+	var req ManualSendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	eventType, ok := manualTemplates[req.Template]
+	if !ok {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "Template can't be sent manually"))
+		return
+	}
+
+	agentID := c.GetString("user_id")
+	send := &store.ManualSend{
+		ID:               uuid.New().String(),
+		Template:         req.Template,
+		EventType:        eventType,
+		OrderID:          req.OrderID,
+		RequestedBy:      agentID,
+		RequestedByEmail: c.GetString("user_email"),
+		RequestedByRole:  c.GetString("user_role"),
+		Reason:           req.Reason,
+		TicketID:         req.TicketID,
+	}
+	send.UserID, _ = req.Data["user_id"].(string)
+	if send.Recipient, _ = req.Data["customer_email"].(string); send.Recipient == "" {
+		send.Recipient, _ = req.Data["email"].(string)
+	}
+
+	event := consumer.Event{
+		EventType:   eventType,
+		OrderID:     req.OrderID,
+		PaymentID:   req.PaymentID,
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Data:        req.Data,
+		Key:         "manual/" + send.ID,
+		TriggeredBy: "support:" + agentID,
+	}
+
+	// Manual events must be as valid as published ones
+	payload, err := json.Marshal(event)
+	if err != nil {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+	if err := h.schemas.Validate(payload); err != nil {
+		var invalid *validation.ValidationError
+		if errors.As(err, &invalid) {
+			apperrors.Abort(c, apperrors.New(http.StatusUnprocessableEntity, "Invalid event data").WithFields(gin.H{"fields": invalid.Fields}))
+			return
+		}
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	correlationID := c.GetHeader(logging.CorrelationIDHeader)
+	if correlationID == "" {
+		correlationID = uuid.New().String()
+	}
+	ctx := logging.WithCorrelationID(c.Request.Context(), correlationID)
+
+	sendErr := h.handler.Handle(ctx, event)
+
+	send.Status = store.ManualSendSent
+	if sendErr != nil {
+		send.Status = store.ManualSendFailed
+		send.Error = sendErr.Error()
+	}
+	metrics.ManualSendsTotal.WithLabelValues(req.Template, send.Status).Inc()
+
+	logger := logging.WithContext(ctx, h.logger)
+	logger.Info("Manual notification send",
+		zap.String("manual_send_id", send.ID),
+		zap.String("template", req.Template),
+		zap.String("order_id", req.OrderID),
+		zap.String("requested_by", agentID),
+		zap.String("ticket_id", req.TicketID),
+		zap.String("status", send.Status),
+	)
+
+	if err := h.audit.CreateManualSend(ctx, send); err != nil {
+		logger.Error("Failed to record manual send", zap.String("manual_send_id", send.ID), zap.Error(err))
+	}
+
+	if sendErr != nil {
+		var missing *templates.MissingFieldsError
+		if errors.As(sendErr, &missing) {
+			apperrors.Abort(c, apperrors.New(http.StatusUnprocessableEntity, "Missing template data").
+				WithFields(gin.H{"id": send.ID, "missing_fields": missing.Fields}))
+			return
+		}
+		apperrors.Abort(c, apperrors.New(http.StatusBadGateway, sendErr.Error()).WithFields(gin.H{"id": send.ID}))
+		return
+	}
+
+	notifications, err := h.store.ListByEventKey(ctx, event.Key)
+	if err != nil {
+		logger.Error("Failed to load manual send outcomes", zap.String("manual_send_id", send.ID), zap.Error(err))
+	}
+	if notifications == nil {
+		notifications = []*store.Notification{}
+	}
+
+	c.JSON(http.StatusOK, ManualSendResponse{
+		ID:            send.ID,
+		Template:      req.Template,
+		EventType:     eventType,
+		CorrelationID: correlationID,
+		Notifications: notifications,
+	})
+}
+
+// List returns the manual send audit log, newest first, optionally for one
+// order (?order_id=) or customer (?user_id=)
+func (h *ManualSendHandler) List(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	sends, err := h.audit.ListManualSends(c.Request.Context(), c.Query("order_id"), c.Query("user_id"), limit, offset)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list manual sends"))
+		return
+	}
+	if sends == nil {
+		sends = []*store.ManualSend{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"manual_sends": sends, "limit": limit, "offset": offset})
+}
+
+// History returns the notifications sent for one order (?order_id=) or to
+// one customer (?user_id=), newest first
+func (h *ManualSendHandler) History(c *gin.Context) {
+	orderID, userID := c.Query("order_id"), c.Query("user_id")
+	if (orderID == "") == (userID == "") {
+		apperrors.Abort(c, apperrors.NewValidation(apperrors.FieldError{Field: "order_id", Rule: "required", Message: "exactly one of order_id and user_id is required"}))
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	var (
+		notifications []*store.Notification
+		err           error
+	)
+	if orderID != "" {
+		notifications, err = h.store.ListByOrderID(c.Request.Context(), orderID, limit, offset)
+	} else {
+		notifications, err = h.store.ListByUserID(c.Request.Context(), userID, limit, offset)
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list notifications"))
+		return
+	}
+	if notifications == nil {
+		notifications = []*store.Notification{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notifications": notifications, "limit": limit, "offset": offset})
+}
@@ -5,10 +5,23 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/notification-service/internal/branding"
+	"github.com/ecommerce/notification-service/internal/config"
 	"github.com/ecommerce/notification-service/internal/consumer"
 	"github.com/ecommerce/notification-service/internal/email"
+	"github.com/ecommerce/notification-service/internal/events"
+	"github.com/ecommerce/notification-service/internal/media"
+	"github.com/ecommerce/notification-service/internal/preferences"
+	"github.com/ecommerce/notification-service/internal/quiethours"
+	"github.com/ecommerce/notification-service/internal/ratelimit"
+	"github.com/ecommerce/notification-service/internal/routing"
 	"github.com/ecommerce/notification-service/internal/sms"
+	"github.com/ecommerce/notification-service/internal/store"
+	"github.com/ecommerce/notification-service/internal/subscriptions"
 	"github.com/ecommerce/notification-service/internal/templates"
+	"github.com/ecommerce/notification-service/internal/tracking"
+	"github.com/ecommerce/notification-service/internal/whatsapp"
 	"go.uber.org/zap"
 )
 
@@ -16,7 +29,22 @@ import (
 type NotificationHandler struct {
 	emailSender    *email.EmailSender
 	smsSender      *sms.SMSSender
+	whatsAppSender *whatsapp.WhatsAppSender
 	templateEngine *templates.TemplateEngine
+	store          store.NotificationStore
+	digests        store.DigestStore
+	inbox          store.InboxStore
+	scheduled      store.ScheduledStore
+	quietHours     *quiethours.Policy
+	preferences    *preferences.Client
+	subscriptions  *subscriptions.Client
+	media          *media.Client
+	limiter        *ratelimit.Limiter
+	policies       *routing.Policies
+	clicks         *tracking.ClickTracker
+	brands         *branding.Brands
+	statusEvents   *events.Publisher
+	config         *config.Config
 	logger         *zap.Logger
 }
 
@@ -24,20 +52,50 @@ type NotificationHandler struct {
 func NewNotificationHandler(
 	emailSender *email.EmailSender,
 	smsSender *sms.SMSSender,
+	whatsAppSender *whatsapp.WhatsAppSender,
 	templateEngine *templates.TemplateEngine,
+	notificationStore store.NotificationStore,
+	digestStore store.DigestStore,
+	inboxStore store.InboxStore,
+	scheduledStore store.ScheduledStore,
+	quietHours *quiethours.Policy,
+	preferencesClient *preferences.Client,
+	subscriptionsClient *subscriptions.Client,
+	mediaClient *media.Client,
+	limiter *ratelimit.Limiter,
+	policies *routing.Policies,
+	clickTracker *tracking.ClickTracker,
+	brands *branding.Brands,
+	statusEvents *events.Publisher,
+	cfg *config.Config,
 	logger *zap.Logger,
 ) *NotificationHandler {
 	return &NotificationHandler{
 		emailSender:    emailSender,
 		smsSender:      smsSender,
+		whatsAppSender: whatsAppSender,
 		templateEngine: templateEngine,
+		store:          notificationStore,
+		digests:        digestStore,
+		inbox:          inboxStore,
+		scheduled:      scheduledStore,
+		quietHours:     quietHours,
+		preferences:    preferencesClient,
+		subscriptions:  subscriptionsClient,
+		media:          mediaClient,
+		limiter:        limiter,
+		policies:       policies,
+		clicks:         clickTracker,
+		brands:         brands,
+		statusEvents:   statusEvents,
+		config:         cfg,
 		logger:         logger,
 	}
 }
 
 // Handle routes events to appropriate notification methods
 func (h *NotificationHandler) Handle(ctx context.Context, event consumer.Event) error {
-	h.logger.Info("Handling notification event",
+	h.log(ctx).Info("Handling notification event",
 		zap.String("event_type", event.EventType),
 		zap.String("order_id", event.OrderID),
 		zap.String("payment_id", event.PaymentID),
@@ -56,288 +114,298 @@ func (h *NotificationHandler) Handle(ctx context.Context, event consumer.Event)
 		return h.sendDeliveryNotification(ctx, event)
 	case "order.cancelled":
 		return h.sendOrderCancellation(ctx, event)
+	case "user.registered":
+		return h.sendWelcomeEmail(ctx, event)
+	case "user.password_reset_requested":
+		return h.sendPasswordReset(ctx, event)
+	case "user.password_changed":
+		return h.sendPasswordChanged(ctx, event)
+	case "user.new_device_login":
+		return h.sendNewDeviceLogin(ctx, event)
+	case "inventory.low_stock":
+		return h.sendInventoryAlert(ctx, event, "low_stock_alert")
+	case "inventory.out_of_stock":
+		return h.sendInventoryAlert(ctx, event, "out_of_stock_alert")
+	case "inventory.reorder_requested":
+		return h.sendInventoryAlert(ctx, event, "reorder_request")
+	case "inventory.back_in_stock":
+		return h.sendBackInStock(ctx, event)
+	case "wishlist.price_dropped":
+		return h.sendPriceDrop(ctx, event)
+	case "review.requested":
+		return h.sendReviewRequest(ctx, event)
+	case "review.created":
+		return h.cancelReviewRequest(ctx, event)
+	case "return.requested", "return.approved", "return.rejected", "return.received", "return.refunded":
+		return h.sendReturnUpdate(ctx, event)
+	case "gift_card.issued":
+		return h.sendGiftCard(ctx, event)
+	case "subscription.created", "subscription.renewal_upcoming", "subscription.paused", "subscription.resumed",
+		"subscription.skipped", "subscription.cancelled", "subscription.payment_failed":
+		return h.sendSubscriptionUpdate(ctx, event)
 	default:
-		h.logger.Warn("Unknown event type", zap.String("event_type", event.EventType))
+		h.log(ctx).Warn("Unknown event type", zap.String("event_type", event.EventType))
 		return nil
 	}
 }
 
 func (h *NotificationHandler) sendOrderConfirmation(ctx context.Context, event consumer.Event) error {
-	// Extract data from event
-	customerEmail, ok := event.Data["customer_email"].(string)
-	if !ok || customerEmail == "" {
-		return fmt.Errorf("missing customer_email in event data")
-	}
-
 	orderNumber, _ := event.Data["order_number"].(string)
-	totalAmount, _ := event.Data["total_amount"].(float64)
-	items := event.Data["items"]
-
-	// Render email template
-	data := map[string]interface{}{
-		"OrderID":      event.OrderID,
-		"OrderNumber":  orderNumber,
-		"TotalAmount":  totalAmount,
-		"Items":        items,
-		"CustomerName": event.Data["customer_name"],
-	}
-
-	subject, body, err := h.templateEngine.Render("order_confirmation", data)
-	if err != nil {
-		return fmt.Errorf("failed to render template: %w", err)
-	}
-
-	// Send email
-	emailMsg := email.Email{
-		To:      customerEmail,
-		Subject: subject,
-		Body:    body,
-	}
+	totalAmount, _ := amountOf(event.Data["total_amount"], event.Data["currency"])
+
+	return h.notify(ctx, event, notification{
+		template: "order_confirmation",
+		emailKey: "customer_email",
+		data: map[string]interface{}{
+			"OrderID":      event.OrderID,
+			"OrderNumber":  orderNumber,
+			"TotalAmount":  amountData(event.Data["total_amount"], event.Data["currency"]),
+			"Items":        orderItems(event.Data["items"], event.Data["currency"]),
+			"CustomerName": event.Data["customer_name"],
+		},
+		inApp: fmt.Sprintf("Your order %s has been confirmed. Total: %s", orderNumber, totalAmount),
+		link:  orderLink(event.OrderID),
+		mobile: &whatsapp.Message{
+			Template:   "order_confirmation",
+			Parameters: []string{orderNumber, totalAmount.String(), event.OrderID},
+			Text: fmt.Sprintf("Your order %s has been confirmed! Total: %s. Track your order at %s/orders/%s",
+				orderNumber, totalAmount, h.brand(ctx, event).StorefrontURL, event.OrderID),
+		},
+	})
+}
 
-	if err := h.emailSender.Send(emailMsg); err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+// orderItems maps the items of an order event to the fields the order
+// templates use, with v1 prices in currency. Items that aren't objects are
+// skipped; nil means no items.
+func orderItems(raw interface{}, currency interface{}) []map[string]interface{} {
+	list, _ := raw.([]interface{})
+	if len(list) == 0 {
+		return nil
 	}
 
-	h.logger.Info("Order confirmation email sent",
-		zap.String("order_id", event.OrderID),
-		zap.String("email", customerEmail),
-	)
-
-	// Send SMS if phone number is provided
-	if phone, ok := event.Data["customer_phone"].(string); ok && phone != "" {
-		smsMsg := fmt.Sprintf("Your order %s has been confirmed! Total: $%.2f. Track your order at https://shop.example.com/orders/%s",
-			orderNumber, totalAmount, event.OrderID)
-		if err := h.smsSender.Send(phone, smsMsg); err != nil {
-			h.logger.Error("Failed to send SMS", zap.Error(err))
-			// Don't fail the entire notification if SMS fails
-		} else {
-			h.logger.Info("Order confirmation SMS sent",
-				zap.String("order_id", event.OrderID),
-				zap.String("phone", maskPhone(phone)),
-			)
+	items := make([]map[string]interface{}, 0, len(list))
+	for _, entry := range list {
+		item, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
 		}
-	}
-
-	return nil
+		name := item["name"]
+		if name == nil {
+			name = item["product_name"]
+		}
+		items = append(items, map[string]interface{}{
+			"ProductID":   item["product_id"],
+			"SKU":         item["sku"],
+			"ProductName": name,
+			"Quantity":    item["quantity"],
+			"Price":       amountData(item["price"], currency),
+		})
+	}
+	return items
 }
 
 func (h *NotificationHandler) sendPaymentConfirmation(ctx context.Context, event consumer.Event) error {
-	customerEmail, ok := event.Data["customer_email"].(string)
-	if !ok || customerEmail == "" {
-		return fmt.Errorf("missing customer_email in event data")
-	}
-
 	orderNumber, _ := event.Data["order_number"].(string)
-	amount, _ := event.Data["amount"].(float64)
+	amount, _ := amountOf(event.Data["amount"], event.Data["currency"])
 	paymentMethod, _ := event.Data["payment_method"].(string)
 
-	data := map[string]interface{}{
-		"OrderID":       event.OrderID,
-		"OrderNumber":   orderNumber,
-		"PaymentID":     event.PaymentID,
-		"Amount":        amount,
-		"PaymentMethod": paymentMethod,
-		"TransactionID": event.Data["transaction_id"],
-		"CustomerName":  event.Data["customer_name"],
-	}
-
-	subject, body, err := h.templateEngine.Render("payment_confirmation", data)
-	if err != nil {
-		return fmt.Errorf("failed to render template: %w", err)
-	}
-
-	emailMsg := email.Email{
-		To:      customerEmail,
-		Subject: subject,
-		Body:    body,
-	}
-
-	if err := h.emailSender.Send(emailMsg); err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
-	}
-
-	h.logger.Info("Payment confirmation email sent",
-		zap.String("order_id", event.OrderID),
-		zap.String("payment_id", event.PaymentID),
-		zap.String("email", customerEmail),
-	)
-
-	return nil
+	return h.notify(ctx, event, notification{
+		template: "payment_confirmation",
+		emailKey: "customer_email",
+		data: map[string]interface{}{
+			"OrderID":       event.OrderID,
+			"OrderNumber":   orderNumber,
+			"PaymentID":     event.PaymentID,
+			"Amount":        amountData(event.Data["amount"], event.Data["currency"]),
+			"PaymentMethod": paymentMethod,
+			"TransactionID": event.Data["transaction_id"],
+			"CustomerName":  event.Data["customer_name"],
+		},
+		inApp: fmt.Sprintf("We received your payment of %s for order %s.", amount, orderNumber),
+		link:  orderLink(event.OrderID),
+		mobile: &whatsapp.Message{
+			Template:   "payment_confirmation",
+			Parameters: []string{amount.String(), orderNumber},
+			Text:       fmt.Sprintf("We received your payment of %s for order %s. Thank you!", amount, orderNumber),
+		},
+	})
 }
 
 func (h *NotificationHandler) sendPaymentFailure(ctx context.Context, event consumer.Event) error {
-	customerEmail, ok := event.Data["customer_email"].(string)
-	if !ok || customerEmail == "" {
-		return fmt.Errorf("missing customer_email in event data")
-	}
-
 	orderNumber, _ := event.Data["order_number"].(string)
-	amount, _ := event.Data["amount"].(float64)
 	errorMessage, _ := event.Data["error_message"].(string)
 
-	data := map[string]interface{}{
-		"OrderID":      event.OrderID,
-		"OrderNumber":  orderNumber,
-		"Amount":       amount,
-		"ErrorMessage": errorMessage,
-		"CustomerName": event.Data["customer_name"],
-	}
-
-	subject, body, err := h.templateEngine.Render("payment_failure", data)
-	if err != nil {
-		return fmt.Errorf("failed to render template: %w", err)
-	}
-
-	emailMsg := email.Email{
-		To:      customerEmail,
-		Subject: subject,
-		Body:    body,
-	}
-
-	if err := h.emailSender.Send(emailMsg); err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
-	}
-
-	h.logger.Info("Payment failure email sent",
-		zap.String("order_id", event.OrderID),
-		zap.String("email", customerEmail),
-	)
-
-	return nil
+	return h.notify(ctx, event, notification{
+		template: "payment_failure",
+		emailKey: "customer_email",
+		data: map[string]interface{}{
+			"OrderID":      event.OrderID,
+			"OrderNumber":  orderNumber,
+			"Amount":       amountData(event.Data["amount"], event.Data["currency"]),
+			"ErrorMessage": errorMessage,
+			"CustomerName": event.Data["customer_name"],
+		},
+		inApp: fmt.Sprintf("Your payment for order %s didn't go through. Please update your payment method.", orderNumber),
+		link:  orderLink(event.OrderID),
+		mobile: &whatsapp.Message{
+			Template:   "payment_failure",
+			Parameters: []string{orderNumber, event.OrderID},
+			Text: fmt.Sprintf("Your payment for order %s didn't go through. Update your payment method at %s/orders/%s",
+				orderNumber, h.brand(ctx, event).StorefrontURL, event.OrderID),
+		},
+	})
 }
 
 func (h *NotificationHandler) sendShippingNotification(ctx context.Context, event consumer.Event) error {
-	customerEmail, ok := event.Data["customer_email"].(string)
-	if !ok || customerEmail == "" {
-		return fmt.Errorf("missing customer_email in event data")
-	}
-
 	orderNumber, _ := event.Data["order_number"].(string)
 	trackingNumber, _ := event.Data["tracking_number"].(string)
 	carrier, _ := event.Data["carrier"].(string)
 
-	data := map[string]interface{}{
-		"OrderID":        event.OrderID,
-		"OrderNumber":    orderNumber,
-		"TrackingNumber": trackingNumber,
-		"Carrier":        carrier,
-		"CustomerName":   event.Data["customer_name"],
-	}
-
-	subject, body, err := h.templateEngine.Render("shipping_notification", data)
-	if err != nil {
-		return fmt.Errorf("failed to render template: %w", err)
-	}
-
-	emailMsg := email.Email{
-		To:      customerEmail,
-		Subject: subject,
-		Body:    body,
-	}
-
-	if err := h.emailSender.Send(emailMsg); err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
-	}
-
-	h.logger.Info("Shipping notification email sent",
-		zap.String("order_id", event.OrderID),
-		zap.String("email", customerEmail),
-	)
-
-	// Send SMS notification
-	if phone, ok := event.Data["customer_phone"].(string); ok && phone != "" {
-		smsMsg := fmt.Sprintf("Your order %s has shipped! Track with %s: %s",
-			orderNumber, carrier, trackingNumber)
-		if err := h.smsSender.Send(phone, smsMsg); err != nil {
-			h.logger.Error("Failed to send SMS", zap.Error(err))
-		} else {
-			h.logger.Info("Shipping notification SMS sent",
-				zap.String("order_id", event.OrderID),
-				zap.String("phone", maskPhone(phone)),
-			)
-		}
-	}
-
-	return nil
+	return h.notify(ctx, event, notification{
+		template: "shipping_notification",
+		emailKey: "customer_email",
+		data: map[string]interface{}{
+			"OrderID":        event.OrderID,
+			"OrderNumber":    orderNumber,
+			"TrackingNumber": trackingNumber,
+			"Carrier":        carrier,
+			"CustomerName":   event.Data["customer_name"],
+		},
+		inApp: fmt.Sprintf("Your order %s is on its way.", orderNumber),
+		link:  orderLink(event.OrderID),
+		mobile: &whatsapp.Message{
+			Template:   "shipping_notification",
+			Parameters: []string{orderNumber, carrier, trackingNumber},
+			Text:       fmt.Sprintf("Your order %s has shipped! Track with %s: %s", orderNumber, carrier, trackingNumber),
+		},
+	})
 }
 
 func (h *NotificationHandler) sendDeliveryNotification(ctx context.Context, event consumer.Event) error {
-	customerEmail, ok := event.Data["customer_email"].(string)
-	if !ok || customerEmail == "" {
-		return fmt.Errorf("missing customer_email in event data")
-	}
-
 	orderNumber, _ := event.Data["order_number"].(string)
 
-	data := map[string]interface{}{
-		"OrderID":      event.OrderID,
-		"OrderNumber":  orderNumber,
-		"CustomerName": event.Data["customer_name"],
-	}
-
-	subject, body, err := h.templateEngine.Render("delivery_notification", data)
-	if err != nil {
-		return fmt.Errorf("failed to render template: %w", err)
-	}
-
-	emailMsg := email.Email{
-		To:      customerEmail,
-		Subject: subject,
-		Body:    body,
-	}
-
-	if err := h.emailSender.Send(emailMsg); err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
-	}
-
-	h.logger.Info("Delivery notification email sent",
-		zap.String("order_id", event.OrderID),
-		zap.String("email", customerEmail),
-	)
-
-	return nil
+	return h.notify(ctx, event, notification{
+		template: "delivery_notification",
+		emailKey: "customer_email",
+		data: map[string]interface{}{
+			"OrderID":      event.OrderID,
+			"OrderNumber":  orderNumber,
+			"CustomerName": event.Data["customer_name"],
+		},
+		inApp: fmt.Sprintf("Your order %s has been delivered.", orderNumber),
+		link:  orderLink(event.OrderID),
+		mobile: &whatsapp.Message{
+			Template:   "delivery_notification",
+			Parameters: []string{orderNumber},
+			Text:       fmt.Sprintf("Your order %s has been delivered. Enjoy!", orderNumber),
+		},
+	})
 }
 
 func (h *NotificationHandler) sendOrderCancellation(ctx context.Context, event consumer.Event) error {
-	customerEmail, ok := event.Data["customer_email"].(string)
-	if !ok || customerEmail == "" {
-		return fmt.Errorf("missing customer_email in event data")
-	}
-
 	orderNumber, _ := event.Data["order_number"].(string)
 	reason, _ := event.Data["cancellation_reason"].(string)
 
-	data := map[string]interface{}{
-		"OrderID":      event.OrderID,
-		"OrderNumber":  orderNumber,
-		"Reason":       reason,
-		"CustomerName": event.Data["customer_name"],
-	}
+	return h.notify(ctx, event, notification{
+		template: "order_cancellation",
+		emailKey: "customer_email",
+		data: map[string]interface{}{
+			"OrderID":      event.OrderID,
+			"OrderNumber":  orderNumber,
+			"Reason":       reason,
+			"CustomerName": event.Data["customer_name"],
+		},
+		inApp: fmt.Sprintf("Your order %s has been cancelled.", orderNumber),
+		link:  orderLink(event.OrderID),
+		mobile: &whatsapp.Message{
+			Template:   "order_cancellation",
+			Parameters: []string{orderNumber},
+			Text:       fmt.Sprintf("Your order %s has been cancelled.", orderNumber),
+		},
+	})
+}
 
-	subject, body, err := h.templateEngine.Render("order_cancellation", data)
-	if err != nil {
-		return fmt.Errorf("failed to render template: %w", err)
-	}
+func (h *NotificationHandler) sendWelcomeEmail(ctx context.Context, event consumer.Event) error {
+	verificationURL, _ := event.Data["verification_url"].(string)
+
+	return h.notify(ctx, event, notification{
+		template: "welcome",
+		emailKey: "email",
+		data: map[string]interface{}{
+			"FirstName":       event.Data["first_name"],
+			"VerificationURL": verificationURL,
+		},
+	})
+}
 
-	emailMsg := email.Email{
-		To:      customerEmail,
-		Subject: subject,
-		Body:    body,
-	}
+func (h *NotificationHandler) sendPasswordReset(ctx context.Context, event consumer.Event) error {
+	resetURL, ok := event.Data["reset_url"].(string)
+	if !ok || resetURL == "" {
+		return fmt.Errorf("missing reset_url in event data")
+	}
+
+	return h.notify(ctx, event, notification{
+		template: "password_reset",
+		emailKey: "email",
+		data: map[string]interface{}{
+			"FirstName":        event.Data["first_name"],
+			"ResetURL":         resetURL,
+			"ExpiresInMinutes": event.Data["expires_in_minutes"],
+		},
+	})
+}
 
-	if err := h.emailSender.Send(emailMsg); err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
-	}
+func (h *NotificationHandler) sendPasswordChanged(ctx context.Context, event consumer.Event) error {
+	return h.notify(ctx, event, notification{
+		template: "password_changed",
+		emailKey: "email",
+		data: map[string]interface{}{
+			"FirstName": event.Data["first_name"],
+			"ChangedAt": event.Timestamp,
+		},
+		inApp: "The password for your account was changed. If this wasn't you, reset your password immediately.",
+	})
+}
 
-	h.logger.Info("Order cancellation email sent",
-		zap.String("order_id", event.OrderID),
-		zap.String("email", customerEmail),
-	)
+func (h *NotificationHandler) sendNewDeviceLogin(ctx context.Context, event consumer.Event) error {
+	device, _ := event.Data["device"].(string)
+
+	return h.notify(ctx, event, notification{
+		template: "new_device_login",
+		emailKey: "email",
+		data: map[string]interface{}{
+			"FirstName": event.Data["first_name"],
+			"Device":    event.Data["device"],
+			"IPAddress": event.Data["ip_address"],
+			"Location":  event.Data["location"],
+			"LoginAt":   event.Timestamp,
+		},
+		inApp: fmt.Sprintf("New sign-in to your account %s. If this wasn't you, reset your password.", deviceLabel(device)),
+	})
+}
 
-	return nil
+// deviceLabel describes the device of a sign-in for short messages
+func deviceLabel(device string) string {
+	if device == "" {
+		return "from a new device"
+	}
+	return "from " + device
+}
+
+// log returns the handler logger annotated with the event's correlation and trace IDs
+func (h *NotificationHandler) log(ctx context.Context) *zap.Logger {
+	return logging.WithContext(ctx, h.logger)
 }
 
 // maskPhone masks phone number for logging (shows last 4 digits)
+// orderLink is the storefront path of an order, used by in-app notifications
+func orderLink(orderID string) string {
+	if orderID == "" {
+		return ""
+	}
+	return "/orders/" + orderID
+}
+
 func maskPhone(phone string) string {
 	if len(phone) <= 4 {
 		return "****"
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/ecommerce/notification-service/internal/consumer"
+	"github.com/ecommerce/notification-service/internal/subscriptions"
+	"go.uber.org/zap"
+)
+
+// sendPriceDrop tells the customers wishing for a product that its price
+// went down. wishlist-service publishes the drop with the customers to
+// tell; like back-in-stock emails it is marketing, so preferences and
+// quiet hours apply.
+func (h *NotificationHandler) sendPriceDrop(ctx context.Context, event consumer.Event) error {
+	productID := event.ProductID
+	if productID == "" {
+		productID, _ = event.Data["product_id"].(string)
+	}
+	if productID == "" {
+		return fmt.Errorf("missing product_id in event")
+	}
+
+	subscribers, _ := subscriptions.FromEventData(event.Data)
+	if len(subscribers) == 0 {
+		h.log(ctx).Debug("No customers to tell of price drop", zap.String("product_id", productID))
+		return nil
+	}
+
+	productName, _ := event.Data["product_name"].(string)
+	productURL, _ := event.Data["product_url"].(string)
+	if productURL == "" {
+		productURL = h.brand(ctx, event).StorefrontURL + "/products/" + url.PathEscape(productID)
+	}
+	newPrice, _ := amountOf(event.Data["new_price"], nil)
+
+	var lastErr error
+	notified := 0
+	for _, subscriber := range subscribers {
+		err := h.notify(ctx, subscriberEvent(event, subscriber), notification{
+			template: "price_drop",
+			emailKey: "customer_email",
+			data: map[string]interface{}{
+				"ProductID":    productID,
+				"ProductName":  productName,
+				"ProductURL":   productURL,
+				"ImageURL":     event.Data["image_url"],
+				"OldPrice":     amountData(event.Data["old_price"], nil),
+				"NewPrice":     amountData(event.Data["new_price"], nil),
+				"CustomerName": subscriber.Name,
+			},
+			inApp: fmt.Sprintf("%s on your wishlist is now %s.", productName, newPrice),
+			link:  "/products/" + url.PathEscape(productID),
+		})
+		if err != nil {
+			h.log(ctx).Error("Failed to send price drop notification",
+				zap.String("product_id", productID),
+				zap.String("subscriber", subscriber.ID()),
+				zap.Error(err),
+			)
+			lastErr = err
+			continue
+		}
+		notified++
+	}
+
+	// Nobody was notified, so let the event be retried
+	if notified == 0 && lastErr != nil {
+		return fmt.Errorf("failed to send price drop notifications: %w", lastErr)
+	}
+
+	h.log(ctx).Info("Price drop notifications sent",
+		zap.String("product_id", productID),
+		zap.Int("subscribers", len(subscribers)),
+		zap.Int("failed", len(subscribers)-notified),
+	)
+	return nil
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce/notification-service/internal/replay"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ReplayHandler starts and reports on event replays
+type ReplayHandler struct {
+	replayer *replay.Replayer
+	logger   *zap.Logger
+}
+
+// NewReplayHandler creates a new replay handler
+func NewReplayHandler(replayer *replay.Replayer, logger *zap.Logger) *ReplayHandler {
+	return &ReplayHandler{
+		replayer: replayer,
+		logger:   logger,
+	}
+}
+
+// Start begins replaying a topic range in the background
+func (h *ReplayHandler) Start(c *gin.Context) {
+	var req replay.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	run, err := h.replayer.Start(req)
+	if errors.Is(err, replay.ErrReplayRunning) {
+		apperrors.Abort(c, apperrors.New(http.StatusConflict, err.Error()))
+		return
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	h.logger.Info("Replay requested",
+		zap.String("replay_id", run.ID),
+		zap.String("topic", req.Topic),
+		zap.String("mode", string(req.Mode)),
+	)
+
+	c.JSON(http.StatusAccepted, run.Summary())
+}
+
+// List returns all replays since the service started, newest first
+func (h *ReplayHandler) List(c *gin.Context) {
+	runs := h.replayer.List()
+
+	summaries := make([]replay.Summary, len(runs))
+	for i, run := range runs {
+		summary := run.Summary()
+		summary.Outcomes = nil
+		summaries[i] = summary
+	}
+
+	c.JSON(http.StatusOK, gin.H{"replays": summaries})
+}
+
+// Get returns a replay's progress and per-notification outcomes
+func (h *ReplayHandler) Get(c *gin.Context) {
+	run, err := h.replayer.Get(c.Param("id"))
+	if err != nil {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Replay not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, run.Summary())
+}
+
+// Cancel stops a running replay
+func (h *ReplayHandler) Cancel(c *gin.Context) {
+	if err := h.replayer.Cancel(c.Param("id")); err != nil {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Replay not found"))
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
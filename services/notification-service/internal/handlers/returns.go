@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/ecommerce/notification-service/internal/consumer"
+	"go.uber.org/zap"
+)
+
+// sendReturnUpdate tells a customer how their return is going, from
+// returns-service's return.* events: requested, approved (with the label
+// to send it back with), rejected, received and refunded
+func (h *NotificationHandler) sendReturnUpdate(ctx context.Context, event consumer.Event) error {
+	returnID, _ := event.Data["return_id"].(string)
+	if returnID == "" {
+		return fmt.Errorf("missing return_id in event")
+	}
+	status, _ := event.Data["status"].(string)
+	orderNumber, _ := event.Data["order_number"].(string)
+
+	data := map[string]interface{}{
+		"ReturnID":     returnID,
+		"OrderID":      event.OrderID,
+		"OrderNumber":  orderNumber,
+		"CustomerName": event.Data["customer_name"],
+		"Status":       status,
+		"Items":        orderItems(event.Data["items"], nil),
+		"RefundAmount": amountData(event.Data["refund_amount"], nil),
+		"Reason":       event.Data["reason"],
+	}
+	var attachments []string
+	if label, ok := event.Data["label"].(map[string]interface{}); ok {
+		data["LabelURL"] = label["label_url"]
+		data["Carrier"] = label["carrier"]
+		data["TrackingNumber"] = label["tracking_number"]
+		// A label stored in media-service is attached to the email
+		if mediaID, _ := label["media_id"].(string); mediaID != "" {
+			attachments = append(attachments, mediaID)
+		}
+	}
+
+	h.log(ctx).Debug("Sending return update",
+		zap.String("return_id", returnID),
+		zap.String("status", status),
+	)
+
+	return h.notify(ctx, event, notification{
+		template: "return_update",
+		emailKey: "customer_email",
+		data:     data,
+		inApp:    returnInAppText(status, orderNumber),
+		link:     "/returns/" + url.PathEscape(returnID),
+
+		attachments: attachments,
+	})
+}
+
+// returnInAppText is the in-app text of a return update
+func returnInAppText(status, orderNumber string) string {
+	of := "your return"
+	if orderNumber != "" {
+		of = fmt.Sprintf("your return from order %s", orderNumber)
+	}
+
+	switch status {
+	case "requested":
+		return fmt.Sprintf("We've received %s request and will review it shortly.", of)
+	case "approved":
+		return fmt.Sprintf("Good news: %s was approved. Send the items back to us.", of)
+	case "rejected":
+		return fmt.Sprintf("Sorry, %s couldn't be accepted.", of)
+	case "received":
+		return fmt.Sprintf("We've received the items of %s.", of)
+	case "refunded":
+		return fmt.Sprintf("The refund for %s is on its way.", of)
+	default:
+		return fmt.Sprintf("There's an update on %s.", of)
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/ecommerce/notification-service/internal/consumer"
+	"go.uber.org/zap"
+)
+
+// sendReviewRequest asks a customer to review the items of a delivered
+// order they haven't reviewed yet. review-service publishes the request a
+// while after delivery; it is marketing, so quiet hours may hold it.
+func (h *NotificationHandler) sendReviewRequest(ctx context.Context, event consumer.Event) error {
+	orderNumber, _ := event.Data["order_number"].(string)
+	storefront := h.brand(ctx, event).StorefrontURL
+
+	items := reviewItems(event.Data["items"], storefront, event.OrderID)
+	if len(items) == 0 {
+		h.log(ctx).Debug("Review request has no items left to review", zap.String("order_id", event.OrderID))
+		return nil
+	}
+
+	inApp := "How was your order? Let us know with a review."
+	if orderNumber != "" {
+		inApp = fmt.Sprintf("How was your order %s? Let us know with a review.", orderNumber)
+	}
+
+	return h.notify(ctx, event, notification{
+		template: "review_request",
+		emailKey: "customer_email",
+		data: map[string]interface{}{
+			"OrderID":      event.OrderID,
+			"OrderNumber":  orderNumber,
+			"CustomerName": event.Data["customer_name"],
+			"Items":        items,
+		},
+		inApp: inApp,
+		link:  reviewLink(event.OrderID),
+	})
+}
+
+// cancelReviewRequest drops an order's review request still held for the
+// customer's quiet hours once they have reviewed the order, so they aren't
+// asked for a review they just wrote
+func (h *NotificationHandler) cancelReviewRequest(ctx context.Context, event consumer.Event) error {
+	if h.scheduled == nil || event.OrderID == "" || isDryRun(ctx) {
+		return nil
+	}
+
+	cancelled, err := h.scheduled.CancelScheduled(ctx, event.OrderID, "review.requested")
+	if err != nil {
+		return fmt.Errorf("failed to cancel held review requests: %w", err)
+	}
+	if cancelled > 0 {
+		h.log(ctx).Info("Held review request cancelled, order already reviewed",
+			zap.String("order_id", event.OrderID),
+			zap.Int64("cancelled", cancelled),
+		)
+	}
+	return nil
+}
+
+// reviewItems maps the items of a review request to the fields the
+// review_request template uses, each with a link to its review form
+func reviewItems(raw interface{}, storefront, orderID string) []map[string]interface{} {
+	list, _ := raw.([]interface{})
+
+	items := make([]map[string]interface{}, 0, len(list))
+	for _, entry := range list {
+		item, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		productID, _ := item["product_id"].(string)
+		if productID == "" {
+			continue
+		}
+		name, _ := item["name"].(string)
+		if name == "" {
+			name = "Your item"
+		}
+		items = append(items, map[string]interface{}{
+			"ProductID":   productID,
+			"ProductName": name,
+			"ReviewURL":   storefront + reviewLink(orderID) + "?product_id=" + url.QueryEscape(productID),
+		})
+	}
+	return items
+}
+
+// reviewLink is the storefront page for reviewing an order's items
+func reviewLink(orderID string) string {
+	return "/orders/" + url.PathEscape(orderID) + "/review"
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce/notification-service/internal/config"
+	"github.com/ecommerce/notification-service/internal/store"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// maxSpendDays bounds the date range of a spend report
+const maxSpendDays = 366
+
+// SMSSpendHandler reports SMS spend against the daily budget
+type SMSSpendHandler struct {
+	spend  store.SMSSpendStore
+	config *config.Config
+	logger *zap.Logger
+}
+
+// NewSMSSpendHandler creates a new SMS spend handler
+func NewSMSSpendHandler(spend store.SMSSpendStore, cfg *config.Config, logger *zap.Logger) *SMSSpendHandler {
+	return &SMSSpendHandler{
+		spend:  spend,
+		config: cfg,
+		logger: logger,
+	}
+}
+
+// SMSSpendReport is SMS spend per day and country over a date range, with
+// today's spend against the daily budget
+type SMSSpendReport struct {
+	From            string            `json:"from"`
+	To              string            `json:"to"`
+	Days            []*store.SMSSpend `json:"days"`
+	Countries       []*CountrySpend   `json:"countries"`
+	Messages        int               `json:"messages"`
+	Segments        int               `json:"segments"`
+	Cost            float64           `json:"cost"`
+	TodayCost       float64           `json:"today_cost"`
+	DailyBudget     float64           `json:"daily_budget,omitempty"`
+	RemainingBudget *float64          `json:"remaining_budget,omitempty"` // today's
+}
+
+// CountrySpend is SMS spend to one country over a report's date range
+type CountrySpend struct {
+	Country  string  `json:"country"`
+	Messages int     `json:"messages"`
+	Segments int     `json:"segments"`
+	Cost     float64 `json:"cost"`
+}
+
+// Spend returns SMS spend for the UTC days from..to (YYYY-MM-DD, inclusive).
+// Both default to today; from defaults to to when only to is given.
+func (h *SMSSpendHandler) Spend(c *gin.Context) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	to, err := parseDay(c.Query("to"), today)
+	if err != nil {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "to must be a date (YYYY-MM-DD)"))
+		return
+	}
+	from, err := parseDay(c.Query("from"), to)
+	if err != nil {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "from must be a date (YYYY-MM-DD)"))
+		return
+	}
+	if from.After(to) {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "from must not be after to"))
+		return
+	}
+	if to.Sub(from) >= maxSpendDays*24*time.Hour {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "date range must not exceed 366 days"))
+		return
+	}
+
+	rows, err := h.spend.ListSMSSpend(c.Request.Context(), from, to)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to load SMS spend"))
+		return
+	}
+
+	todayCost, err := h.spend.SMSSpendTotal(c.Request.Context(), today)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to load SMS spend"))
+		return
+	}
+
+	report := SMSSpendReport{
+		From:      from.Format("2006-01-02"),
+		To:        to.Format("2006-01-02"),
+		Days:      rows,
+		Countries: []*CountrySpend{},
+		TodayCost: todayCost,
+	}
+	if report.Days == nil {
+		report.Days = []*store.SMSSpend{}
+	}
+
+	byCountry := make(map[string]*CountrySpend)
+	for _, row := range rows {
+		total, ok := byCountry[row.Country]
+		if !ok {
+			total = &CountrySpend{Country: row.Country}
+			byCountry[row.Country] = total
+			report.Countries = append(report.Countries, total)
+		}
+		total.Messages += row.Messages
+		total.Segments += row.Segments
+		total.Cost += row.Cost
+
+		report.Messages += row.Messages
+		report.Segments += row.Segments
+		report.Cost += row.Cost
+	}
+
+	if h.config.SMSDailyBudget > 0 {
+		remaining := h.config.SMSDailyBudget - todayCost
+		if remaining < 0 {
+			remaining = 0
+		}
+		report.DailyBudget = h.config.SMSDailyBudget
+		report.RemainingBudget = &remaining
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// parseDay parses a YYYY-MM-DD date as a UTC day, or returns def when empty
+func parseDay(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
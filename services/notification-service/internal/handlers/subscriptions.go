@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ecommerce/notification-service/internal/consumer"
+	"go.uber.org/zap"
+)
+
+// sendSubscriptionUpdate tells a customer about their recurring order, from
+// subscription-service's subscription.* events: subscribed, an order
+// coming up, paused, resumed, an order skipped, cancelled, and payments
+// that failed
+func (h *NotificationHandler) sendSubscriptionUpdate(ctx context.Context, event consumer.Event) error {
+	subscriptionID, _ := event.Data["subscription_id"].(string)
+	if subscriptionID == "" {
+		return fmt.Errorf("missing subscription_id in event")
+	}
+	change := strings.TrimPrefix(event.EventType, "subscription.")
+
+	data := map[string]interface{}{
+		"SubscriptionID": subscriptionID,
+		"Event":          change,
+		"Status":         event.Data["status"],
+		"CustomerName":   event.Data["customer_name"],
+		"Interval":       intervalText(event.Data["interval"]),
+		"Items":          orderItems(event.Data["items"], nil),
+		"Total":          amountData(event.Data["total"], nil),
+		"NextOrderAt":    eventDate(event.Data["next_order_at"]),
+		"ResumeAt":       eventDate(event.Data["resume_at"]),
+		"SkippedOrderAt": eventDate(event.Data["skipped_order_at"]),
+		"NextAttemptAt":  eventDate(event.Data["next_attempt_at"]),
+		"Attempt":        event.Data["attempt"],
+		"Reason":         event.Data["reason"],
+		"ErrorMessage":   event.Data["error"],
+	}
+
+	h.log(ctx).Debug("Sending subscription update",
+		zap.String("subscription_id", subscriptionID),
+		zap.String("event_type", event.EventType),
+	)
+
+	return h.notify(ctx, event, notification{
+		template: "subscription_update",
+		emailKey: "customer_email",
+		data:     data,
+		inApp:    subscriptionInAppText(change, data),
+		link:     "/account/subscriptions/" + url.PathEscape(subscriptionID),
+	})
+}
+
+// intervalText describes a subscription event's interval, e.g. "every 2
+// weeks"
+func intervalText(raw interface{}) string {
+	interval, _ := raw.(map[string]interface{})
+	unit, _ := interval["unit"].(string)
+	count, _ := interval["count"].(float64)
+	if unit == "" {
+		return ""
+	}
+	if count <= 1 {
+		return "every " + unit
+	}
+	return fmt.Sprintf("every %d %ss", int(count), unit)
+}
+
+// eventDate formats an event's RFC 3339 time as a date, or "" without one
+func eventDate(raw interface{}) string {
+	value, _ := raw.(string)
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return ""
+	}
+	return at.Format("January 2, 2006")
+}
+
+// subscriptionInAppText is the in-app text of a subscription update
+func subscriptionInAppText(change string, data map[string]interface{}) string {
+	next, _ := data["NextOrderAt"].(string)
+	switch change {
+	case "created":
+		return fmt.Sprintf("You're subscribed! Your first order is placed on %s.", next)
+	case "renewal_upcoming":
+		return fmt.Sprintf("Your next subscription order is placed on %s. You can still skip or pause it.", next)
+	case "paused":
+		if resumeAt, _ := data["ResumeAt"].(string); resumeAt != "" {
+			return fmt.Sprintf("Your subscription is paused until %s.", resumeAt)
+		}
+		return "Your subscription is paused until you resume it."
+	case "resumed":
+		return fmt.Sprintf("Your subscription is back on. Your next order is placed on %s.", next)
+	case "skipped":
+		return fmt.Sprintf("We've skipped your subscription order. Your next order is placed on %s.", next)
+	case "cancelled":
+		return "Your subscription was cancelled."
+	case "payment_failed":
+		if retry, _ := data["NextAttemptAt"].(string); retry != "" {
+			return fmt.Sprintf("We couldn't take payment for your subscription order. We'll try again on %s.", retry)
+		}
+		return "We couldn't take payment for your subscription order, so your subscription was cancelled."
+	default:
+		return "There's an update on your subscription."
+	}
+}
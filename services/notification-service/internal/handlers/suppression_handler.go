@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce/notification-service/internal/metrics"
+	"github.com/ecommerce/notification-service/internal/store"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// SuppressionHandler manages the list of addresses campaigns never email
+type SuppressionHandler struct {
+	store  store.SuppressionStore
+	logger *zap.Logger
+}
+
+// NewSuppressionHandler creates a new suppression handler
+func NewSuppressionHandler(suppressionStore store.SuppressionStore, logger *zap.Logger) *SuppressionHandler {
+	return &SuppressionHandler{
+		store:  suppressionStore,
+		logger: logger,
+	}
+}
+
+// SuppressionRequest suppresses an address by hand
+type SuppressionRequest struct {
+	Address string `json:"address" binding:"required,email"`
+	Reason  string `json:"reason" binding:"omitempty,oneof=bounce complaint unsubscribe manual"`
+}
+
+// List returns suppressed addresses, newest first
+func (h *SuppressionHandler) List(c *gin.Context) {
+	limit, offset := pagination(c)
+
+	suppressions, err := h.store.ListSuppressions(c.Request.Context(), limit, offset)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list suppressions"))
+		return
+	}
+	if suppressions == nil {
+		suppressions = []*store.Suppression{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suppressions": suppressions, "limit": limit, "offset": offset})
+}
+
+// Add suppresses an address; the reason defaults to manual
+func (h *SuppressionHandler) Add(c *gin.Context) {
+	var req SuppressionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+	if req.Reason == "" {
+		req.Reason = store.SuppressionManual
+	}
+
+	suppression := &store.Suppression{
+		Address: req.Address,
+		Reason:  req.Reason,
+		Source:  c.GetString("user_id"),
+	}
+	if err := h.store.AddSuppression(c.Request.Context(), suppression); err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to add suppression"))
+		return
+	}
+	metrics.SuppressionsTotal.WithLabelValues(suppression.Reason, "manual").Inc()
+
+	h.logger.Info("Address suppressed",
+		zap.String("reason", suppression.Reason),
+		zap.String("user_id", suppression.Source),
+	)
+
+	c.JSON(http.StatusCreated, suppression)
+}
+
+// Remove lifts the suppression of an address
+func (h *SuppressionHandler) Remove(c *gin.Context) {
+	err := h.store.RemoveSuppression(c.Request.Context(), c.Param("address"))
+	if err == store.ErrNotFound {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Address is not suppressed"))
+		return
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to remove suppression"))
+		return
+	}
+
+	h.logger.Info("Suppression removed", zap.String("user_id", c.GetString("user_id")))
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce/notification-service/internal/branding"
+	"github.com/ecommerce/notification-service/internal/config"
+	"github.com/ecommerce/notification-service/internal/email"
+	"github.com/ecommerce/notification-service/internal/store"
+	"github.com/ecommerce/notification-service/internal/templates"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// TemplateHandler lets template changes be checked before real traffic hits them
+type TemplateHandler struct {
+	templateEngine *templates.TemplateEngine
+	emailSender    *email.EmailSender
+	store          store.NotificationStore
+	brands         *branding.Brands
+	config         *config.Config
+	logger         *zap.Logger
+}
+
+// NewTemplateHandler creates a new template handler
+func NewTemplateHandler(templateEngine *templates.TemplateEngine, emailSender *email.EmailSender, notificationStore store.NotificationStore, brands *branding.Brands, cfg *config.Config, logger *zap.Logger) *TemplateHandler {
+	return &TemplateHandler{
+		templateEngine: templateEngine,
+		emailSender:    emailSender,
+		store:          notificationStore,
+		brands:         brands,
+		config:         cfg,
+		logger:         logger,
+	}
+}
+
+// PreviewRequest overrides sample template data and optionally picks an A/B
+// test variant and a brand
+type PreviewRequest struct {
+	Variant string                 `json:"variant"`
+	BrandID string                 `json:"brand_id"`
+	Data    map[string]interface{} `json:"data"`
+}
+
+// TestSendRequest is a request to send a rendered template to an internal address
+type TestSendRequest struct {
+	To      string                 `json:"to" binding:"required,email"`
+	Variant string                 `json:"variant"`
+	BrandID string                 `json:"brand_id"`
+	Data    map[string]interface{} `json:"data"`
+}
+
+// VariantReport is an A/B test variant's configuration and results
+type VariantReport struct {
+	Variant   string  `json:"variant"`
+	Weight    int     `json:"weight"` // 0 once the variant leaves the test
+	Subject   string  `json:"subject,omitempty"`
+	Sent      int     `json:"sent"`
+	Delivered int     `json:"delivered"`
+	Failed    int     `json:"failed"`
+	Opened    int     `json:"opened"`
+	Clicked   int     `json:"clicked"`
+	OpenRate  float64 `json:"open_rate"`
+	ClickRate float64 `json:"click_rate"`
+}
+
+// Preview renders a template with sample data, overridden by any data in the request
+func (h *TemplateHandler) Preview(c *gin.Context) {
+	var req PreviewRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apperrors.Abort(c, apperrors.New(http.StatusBadRequest, err.Error()))
+			return
+		}
+	}
+
+	name := c.Param("name")
+	subject, body, ok := h.render(c, name, req.Variant, req.BrandID, req.Data)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"template": name,
+		"variant":  req.Variant,
+		"subject":  subject,
+		"html":     body,
+	})
+}
+
+// TestSend renders a template and emails it to a whitelisted internal address.
+// Test sends bypass preferences, rate limits and notification history.
+func (h *TemplateHandler) TestSend(c *gin.Context) {
+	var req TestSendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	if !h.testRecipientAllowed(req.To) {
+		apperrors.Abort(c, apperrors.New(http.StatusForbidden, "Recipient is not an allowed test address"))
+		return
+	}
+
+	name := c.Param("name")
+	subject, body, ok := h.render(c, name, req.Variant, req.BrandID, req.Data)
+	if !ok {
+		return
+	}
+
+	brand := h.brands.Resolve(c.Request.Context(), req.BrandID)
+	result, err := h.emailSender.Send(c.Request.Context(), email.Email{
+		To:        req.To,
+		Subject:   "[TEST] " + subject,
+		Body:      body,
+		IsHTML:    true,
+		FromName:  brand.FromName,
+		FromEmail: brand.FromEmail,
+	})
+	if err != nil {
+		h.logger.Error("Template test send failed",
+			zap.String("template", name),
+			zap.Error(err),
+		)
+		apperrors.Abort(c, apperrors.New(http.StatusBadGateway, "Failed to send test email"))
+		return
+	}
+
+	h.logger.Info("Template test email sent",
+		zap.String("template", name),
+		zap.String("email", req.To),
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"template":            name,
+		"to":                  req.To,
+		"subject":             "[TEST] " + subject,
+		"provider":            result.Provider,
+		"provider_message_id": result.MessageID,
+	})
+}
+
+// render merges request data over the template's sample data and renders it
+// for a brand, writing an error response on failure
+func (h *TemplateHandler) render(c *gin.Context, name, variant, brandID string, overrides map[string]interface{}) (string, string, bool) {
+	data := templates.SampleData(name)
+	for key, value := range overrides {
+		data[key] = value
+	}
+	data = templates.WithBrand(data, branding.Template(h.brands.Resolve(c.Request.Context(), brandID)))
+
+	subject, body, err := h.templateEngine.RenderVariant(name, variant, data)
+	if errors.Is(err, templates.ErrTemplateNotFound) {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Template not found"))
+		return "", "", false
+	}
+	var missing *templates.MissingFieldsError
+	if errors.As(err, &missing) {
+		apperrors.Abort(c, apperrors.New(http.StatusUnprocessableEntity, err.Error()).WithFields(gin.H{"missing_fields": missing.Fields}))
+		return "", "", false
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.New(http.StatusUnprocessableEntity, err.Error()))
+		return "", "", false
+	}
+
+	return subject, body, true
+}
+
+// Variants reports a template's A/B test variants with their sends, opens and
+// clicks over the last ?days=30 days. Rates are per sent email.
+func (h *TemplateHandler) Variants(c *gin.Context) {
+	name := c.Param("name")
+	if !h.templateEngine.Has(name) {
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Template not found"))
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days <= 0 {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "days must be a positive integer"))
+		return
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	stats, err := h.store.VariantStats(c.Request.Context(), name, since)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to load variant stats"))
+		return
+	}
+
+	byVariant := make(map[string]store.VariantStats, len(stats))
+	for _, s := range stats {
+		byVariant[s.Variant] = s
+	}
+
+	// Configured variants first, then any that were retired from the test
+	reports := make([]VariantReport, 0, len(stats))
+	for _, v := range h.templateEngine.Variants(name) {
+		s := byVariant[v.Name]
+		reports = append(reports, newVariantReport(v, s))
+		delete(byVariant, v.Name)
+	}
+	for _, s := range stats {
+		if _, retired := byVariant[s.Variant]; retired {
+			reports = append(reports, newVariantReport(templates.Variant{Name: s.Variant}, s))
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"template": name,
+		"since":    since,
+		"variants": reports,
+	})
+}
+
+func newVariantReport(v templates.Variant, s store.VariantStats) VariantReport {
+	report := VariantReport{
+		Variant:   v.Name,
+		Weight:    v.Weight,
+		Subject:   v.Subject,
+		Sent:      s.Sent,
+		Delivered: s.Delivered,
+		Failed:    s.Failed,
+		Opened:    s.Opened,
+		Clicked:   s.Clicked,
+	}
+	if s.Sent > 0 {
+		report.OpenRate = float64(s.Opened) / float64(s.Sent)
+		report.ClickRate = float64(s.Clicked) / float64(s.Sent)
+	}
+	return report
+}
+
+// testRecipientAllowed checks an address against TEMPLATE_TEST_RECIPIENTS,
+// where entries starting with "@" allow a whole domain
+func (h *TemplateHandler) testRecipientAllowed(address string) bool {
+	address = strings.ToLower(strings.TrimSpace(address))
+	for _, allowed := range h.config.TemplateTestRecipients {
+		allowed = strings.ToLower(allowed)
+		if strings.HasPrefix(allowed, "@") {
+			if strings.HasSuffix(address, allowed) {
+				return true
+			}
+		} else if address == allowed {
+			return true
+		}
+	}
+	return false
+}
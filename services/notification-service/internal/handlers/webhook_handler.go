@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce/notification-service/internal/config"
+	"github.com/ecommerce/notification-service/internal/email"
+	"github.com/ecommerce/notification-service/internal/events"
+	"github.com/ecommerce/notification-service/internal/metrics"
+	"github.com/ecommerce/notification-service/internal/sms"
+	"github.com/ecommerce/notification-service/internal/store"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// twilioStatuses maps terminal Twilio message statuses to notification statuses.
+// Intermediate statuses (queued, sending, sent) are ignored so that a late
+// callback can't move a delivered message backwards.
+var twilioStatuses = map[string]store.Status{
+	"delivered":   store.StatusDelivered,
+	"undelivered": store.StatusFailed,
+	"failed":      store.StatusFailed,
+}
+
+// sendGridStatuses maps terminal SendGrid events to notification statuses
+var sendGridStatuses = map[string]store.Status{
+	"delivered": store.StatusDelivered,
+	"bounce":    store.StatusFailed,
+	"dropped":   store.StatusFailed,
+}
+
+// sendGridStatusEvents maps SendGrid failures to the status event published
+var sendGridStatusEvents = map[string]string{
+	"bounce":  events.EventBounced,
+	"dropped": events.EventFailed,
+}
+
+// sendGridEngagements maps SendGrid engagement events to recorded engagement
+var sendGridEngagements = map[string]store.Engagement{
+	"open":  store.EngagementOpen,
+	"click": store.EngagementClick,
+}
+
+// sendGridSuppressions maps SendGrid events to the reason the address is
+// added to the suppression list with. Blocks are temporary and aren't
+// suppressed.
+var sendGridSuppressions = map[string]string{
+	"bounce":            store.SuppressionBounce,
+	"spamreport":        store.SuppressionComplaint,
+	"unsubscribe":       store.SuppressionUnsubscribe,
+	"group_unsubscribe": store.SuppressionUnsubscribe,
+}
+
+// sendGridEvent is one entry of a SendGrid event webhook batch
+type sendGridEvent struct {
+	Event     string `json:"event"`
+	Type      string `json:"type"` // bounce or blocked, for bounce events
+	Email     string `json:"email"`
+	MessageID string `json:"sg_message_id"`
+	Reason    string `json:"reason"`
+}
+
+// WebhookHandler ingests delivery status callbacks from providers
+type WebhookHandler struct {
+	store        store.NotificationStore
+	suppressions store.SuppressionStore
+	twilio       *sms.TwilioProvider
+	statusEvents *events.Publisher
+	config       *config.Config
+	logger       *zap.Logger
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(notificationStore store.NotificationStore, suppressions store.SuppressionStore, twilio *sms.TwilioProvider, statusEvents *events.Publisher, cfg *config.Config, logger *zap.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		store:        notificationStore,
+		suppressions: suppressions,
+		twilio:       twilio,
+		statusEvents: statusEvents,
+		config:       cfg,
+		logger:       logger,
+	}
+}
+
+// TwilioStatus handles Twilio message status callbacks
+func (h *WebhookHandler) TwilioStatus(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "Invalid form body"))
+		return
+	}
+
+	// Signatures are computed over the public callback URL, so without it
+	// no callback can be verified and all are rejected
+	if h.config.Environment != "development" {
+		if h.config.TwilioStatusCallbackURL == "" {
+			h.logger.Warn("Rejected Twilio callback: TWILIO_STATUS_CALLBACK_URL is not set")
+			apperrors.Abort(c, apperrors.New(http.StatusForbidden, "Status callbacks aren't configured"))
+			return
+		}
+		signature := c.GetHeader("X-Twilio-Signature")
+		if !h.twilio.ValidateSignature(h.config.TwilioStatusCallbackURL, c.Request.PostForm, signature) {
+			h.logger.Warn("Rejected Twilio callback with invalid signature")
+			apperrors.Abort(c, apperrors.New(http.StatusForbidden, "Invalid signature"))
+			return
+		}
+	}
+
+	messageSID := c.Request.PostForm.Get("MessageSid")
+	messageStatus := c.Request.PostForm.Get("MessageStatus")
+	if messageSID == "" || messageStatus == "" {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "MessageSid and MessageStatus are required"))
+		return
+	}
+
+	status, ok := twilioStatuses[messageStatus]
+	if !ok {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	reason := ""
+	if errorCode := c.Request.PostForm.Get("ErrorCode"); errorCode != "" {
+		reason = fmt.Sprintf("twilio %s (error %s)", messageStatus, errorCode)
+	}
+
+	err := h.store.UpdateStatusByMessageID(c.Request.Context(), h.twilio.Name(), messageSID, status, reason)
+	if err == store.ErrNotFound {
+		h.logger.Warn("Twilio callback for unknown message", zap.String("message_sid", messageSID))
+		apperrors.Abort(c, apperrors.New(http.StatusNotFound, "Notification not found"))
+		return
+	}
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to update status"))
+		return
+	}
+
+	h.logger.Info("SMS delivery status updated",
+		zap.String("message_sid", messageSID),
+		zap.String("status", string(status)),
+	)
+	if status == store.StatusFailed {
+		h.publishStatusEvent(c.Request.Context(), h.twilio.Name(), messageSID, events.EventFailed)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// SendGridEvents handles SendGrid event webhook batches: delivery outcomes
+// update the notification status, and opens and clicks are recorded for
+// template A/B test reporting. Events for unknown messages are skipped.
+func (h *WebhookHandler) SendGridEvents(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "Invalid body"))
+		return
+	}
+
+	// Without the verification key no batch can be verified, and unverified
+	// batches could forge delivery outcomes and A/B engagement, so all are
+	// rejected
+	if h.config.Environment != "development" {
+		if h.config.SendGridWebhookPublicKey == "" {
+			h.logger.Warn("Rejected SendGrid webhook: SENDGRID_WEBHOOK_PUBLIC_KEY is not set")
+			apperrors.Abort(c, apperrors.New(http.StatusForbidden, "Event webhooks aren't configured"))
+			return
+		}
+		signature := c.GetHeader("X-Twilio-Email-Event-Webhook-Signature")
+		timestamp := c.GetHeader("X-Twilio-Email-Event-Webhook-Timestamp")
+		if !email.ValidateSendGridSignature(h.config.SendGridWebhookPublicKey, signature, timestamp, body) {
+			h.logger.Warn("Rejected SendGrid webhook with invalid signature")
+			apperrors.Abort(c, apperrors.New(http.StatusForbidden, "Invalid signature"))
+			return
+		}
+	}
+
+	var events []sendGridEvent
+	if err := json.Unmarshal(body, &events); err != nil {
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "Invalid event batch"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	for _, event := range events {
+		if reason, ok := sendGridSuppressions[event.Event]; ok && event.Email != "" && event.Type != "blocked" {
+			if err := h.suppress(ctx, event.Email, reason); err != nil {
+				apperrors.Abort(c, apperrors.Wrap(err, "Failed to apply events"))
+				return
+			}
+		}
+
+		// sg_message_id is the X-Message-Id returned on send plus a suffix
+		messageID, _, _ := strings.Cut(event.MessageID, ".")
+		if messageID == "" {
+			continue
+		}
+
+		if status, ok := sendGridStatuses[event.Event]; ok {
+			reason := ""
+			if status == store.StatusFailed {
+				reason = fmt.Sprintf("sendgrid %s: %s", event.Event, event.Reason)
+			}
+			err = h.store.UpdateStatusByMessageID(ctx, "sendgrid", messageID, status, reason)
+		} else if engagement, ok := sendGridEngagements[event.Event]; ok {
+			err = h.store.RecordEngagement(ctx, "sendgrid", messageID, engagement)
+		} else {
+			continue
+		}
+
+		if err == store.ErrNotFound {
+			h.logger.Debug("SendGrid event for unknown message", zap.String("message_id", messageID))
+			continue
+		}
+		if err != nil {
+			// SendGrid retries the whole batch; updates are idempotent
+			apperrors.Abort(c, apperrors.Wrap(err, "Failed to apply events"))
+			return
+		}
+
+		if eventType, ok := sendGridStatusEvents[event.Event]; ok {
+			h.publishStatusEvent(ctx, "sendgrid", messageID, eventType)
+		}
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// suppress adds an address SendGrid reported as undeliverable or unwilling to
+// the suppression list
+func (h *WebhookHandler) suppress(ctx context.Context, address, reason string) error {
+	if err := h.suppressions.AddSuppression(ctx, &store.Suppression{
+		Address: address,
+		Reason:  reason,
+		Source:  "sendgrid",
+	}); err != nil {
+		return err
+	}
+	metrics.SuppressionsTotal.WithLabelValues(reason, "sendgrid").Inc()
+	return nil
+}
+
+// publishStatusEvent publishes a status event for a notification a provider
+// reported as not delivered after it was sent
+func (h *WebhookHandler) publishStatusEvent(ctx context.Context, provider, messageID, eventType string) {
+	n, err := h.store.GetByMessageID(ctx, provider, messageID)
+	if err != nil {
+		h.logger.Error("Failed to load notification for status event",
+			zap.String("provider", provider),
+			zap.String("message_id", messageID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	h.statusEvents.Publish(ctx, eventType, n)
+}
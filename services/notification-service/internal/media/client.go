@@ -0,0 +1,97 @@
+// Package media fetches the files emails are sent with from media-service
+package media
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ecommerce/notification-service/internal/config"
+	"github.com/ecommerce/notification-service/internal/email"
+	"go.uber.org/zap"
+)
+
+// maxAttachmentBytes bounds a fetched file, above media-service's largest
+// kind
+const maxAttachmentBytes = 25 << 20
+
+// Client fetches files from media-service's internal routes
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+	logger     *zap.Logger
+}
+
+// NewClient creates a new media client, calling through transport
+func NewClient(cfg *config.Config, transport http.RoundTripper, logger *zap.Logger) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(cfg.MediaServiceURL, "/"),
+		apiKey:     cfg.MediaServiceAPIKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second, Transport: transport},
+		logger:     logger,
+	}
+}
+
+// Enabled reports whether files can be fetched
+func (c *Client) Enabled() bool {
+	return c != nil && c.baseURL != ""
+}
+
+// Attachment fetches a file of a tenant, empty for the default one, as an
+// email attachment
+func (c *Client) Attachment(ctx context.Context, tenant, mediaID string) (email.Attachment, error) {
+	if !c.Enabled() {
+		return email.Attachment{}, fmt.Errorf("MEDIA_SERVICE_URL is not configured")
+	}
+
+	endpoint := c.baseURL + "/api/v1/internal/media/" + url.PathEscape(mediaID) + "/content"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return email.Attachment{}, err
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-Service-Key", c.apiKey)
+	}
+	if tenant != "" {
+		req.Header.Set("X-Tenant-ID", tenant)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return email.Attachment{}, fmt.Errorf("failed to fetch media %s: %w", mediaID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return email.Attachment{}, fmt.Errorf("media-service returned status %d for media %s", resp.StatusCode, mediaID)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAttachmentBytes+1))
+	if err != nil {
+		return email.Attachment{}, fmt.Errorf("failed to read media %s: %w", mediaID, err)
+	}
+	if len(data) > maxAttachmentBytes {
+		return email.Attachment{}, fmt.Errorf("media %s is too large to attach", mediaID)
+	}
+
+	filename := mediaID
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		filename = params["filename"]
+	}
+
+	c.logger.Debug("Fetched attachment",
+		zap.String("media_id", mediaID),
+		zap.Int("size_bytes", len(data)),
+	)
+	return email.Attachment{
+		Filename:    filename,
+		ContentType: resp.Header.Get("Content-Type"),
+		Data:        data,
+	}, nil
+}
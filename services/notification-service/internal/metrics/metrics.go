@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// NotificationsTotal counts notification outcomes. status is the recorded
+	// notification status (sent, failed, suppressed, digested, ...).
+	NotificationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notifications_total",
+		Help: "Notification outcomes per channel, template, event type and status",
+	}, []string{"channel", "template", "event_type", "status"})
+
+	// SendDuration measures how long provider sends take, including failover
+	SendDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "notification_send_duration_seconds",
+		Help:    "Time spent sending a notification through its providers",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"channel", "template", "event_type"})
+
+	// InvalidRecipientsTotal counts notifications skipped because the
+	// recipient's address can't receive them, by validation error code
+	InvalidRecipientsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_invalid_recipients_total",
+		Help: "Notifications not sent to invalid recipient addresses, per channel and reason",
+	}, []string{"channel", "reason"})
+
+	// VariantSendsTotal counts emails sent per template A/B test variant;
+	// opens and clicks are in notification history
+	VariantSendsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_template_variant_sends_total",
+		Help: "Emails sent per template and A/B test variant",
+	}, []string{"template", "variant"})
+
+	// RetriesTotal counts failed attempts at a message that are retried
+	RetriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_retries_total",
+		Help: "Failed message processing attempts that are retried, per topic and event type",
+	}, []string{"topic", "event_type"})
+
+	// DeadLettersTotal counts messages that failed processing and were dead-lettered
+	DeadLettersTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_dead_letters_total",
+		Help: "Messages moved to the dead letter queue, per topic and event type",
+	}, []string{"topic", "event_type"})
+
+	// EventFormatsTotal counts consumed messages by wire format, to track
+	// producers' migration to CloudEvents
+	EventFormatsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_event_formats_total",
+		Help: "Consumed messages per topic and format (bespoke, cloudevents_structured, cloudevents_binary)",
+	}, []string{"topic", "format"})
+
+	// EventsCoalescedTotal counts events that replaced a pending event of the
+	// same type, customer and order, i.e. notifications not sent
+	EventsCoalescedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_events_coalesced_total",
+		Help: "Events collapsed into a later event of the same type, customer and order",
+	}, []string{"event_type"})
+
+	// InvalidEventsTotal counts events rejected by schema validation
+	InvalidEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_invalid_events_total",
+		Help: "Events that failed schema validation, per event type",
+	}, []string{"event_type"})
+
+	// ProviderCircuitState is the circuit breaker state of each delivery
+	// provider: 0 = closed, 1 = half-open, 2 = open
+	ProviderCircuitState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "notification_provider_circuit_state",
+		Help: "Circuit breaker state per provider (0=closed, 1=half-open, 2=open)",
+	}, []string{"channel", "provider"})
+
+	// ProviderFailures counts failed send attempts per provider
+	ProviderFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_provider_failures_total",
+		Help: "Failed send attempts per provider",
+	}, []string{"channel", "provider"})
+
+	// ProviderFailovers counts sends delivered by a provider other than the primary
+	ProviderFailovers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_provider_failovers_total",
+		Help: "Sends delivered by a fallback provider",
+	}, []string{"channel", "provider"})
+
+	// SMSSegmentsTotal counts billed SMS segments per recipient country
+	SMSSegmentsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_sms_segments_total",
+		Help: "Billed SMS segments per recipient country",
+	}, []string{"country", "provider"})
+
+	// SMSCostTotal sums SMS spend in USD per recipient country
+	SMSCostTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_sms_cost_usd_total",
+		Help: "SMS spend in USD per recipient country",
+	}, []string{"country", "provider"})
+
+	// SMSBudgetDowngrades counts SMS not sent because the daily SMS budget
+	// was spent, by what happened instead: emailed, email_failed,
+	// already_emailed or dropped
+	SMSBudgetDowngrades = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_sms_budget_downgrades_total",
+		Help: "SMS not sent because the daily SMS budget was exceeded",
+	}, []string{"event_type", "outcome"})
+
+	// StatusEventsTotal counts delivery status events published to Kafka
+	StatusEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_status_events_total",
+		Help: "Delivery status events published to Kafka",
+	}, []string{"event_type", "result"})
+
+	// ManualSendsTotal counts notifications sent by support through the API
+	ManualSendsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_manual_sends_total",
+		Help: "Notifications sent manually by support",
+	}, []string{"template", "status"})
+
+	// CampaignRecipientsTotal counts campaign emails by outcome (sent,
+	// failed, suppressed)
+	CampaignRecipientsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_campaign_recipients_total",
+		Help: "Campaign recipients processed per template and outcome",
+	}, []string{"template", "status"})
+
+	// SuppressionsTotal counts addresses added to the suppression list
+	SuppressionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_suppressions_total",
+		Help: "Addresses added to the bulk email suppression list",
+	}, []string{"reason", "source"})
+)
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RequireSelfOrAdmin only lets users reach routes for their own :id,
+// unless they are an admin. It must follow sharedauth's Authenticate.
+func RequireSelfOrAdmin(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(sharedauth.ContextUserRole) == sharedauth.RoleAdmin || c.GetString(sharedauth.ContextUserID) == c.Param("id") {
+			c.Next()
+			return
+		}
+
+		logger.Warn("Access denied - not the resource owner",
+			zap.String("user_id", c.GetString(sharedauth.ContextUserID)),
+			zap.String("requested_user_id", c.Param("id")),
+		)
+
+		apperrors.Abort(c, apperrors.New(http.StatusForbidden, "Insufficient permissions"))
+	}
+}
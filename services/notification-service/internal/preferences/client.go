@@ -0,0 +1,108 @@
+package preferences
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ecommerce/notification-service/internal/config"
+	"go.uber.org/zap"
+)
+
+type cacheEntry struct {
+	prefs     *Preferences
+	expiresAt time.Time
+}
+
+// Client fetches user notification preferences from user-service and caches them
+type Client struct {
+	baseURL    string
+	apiKey     string
+	ttl        time.Duration
+	httpClient *http.Client
+	logger     *zap.Logger
+
+	mu    sync.RWMutex
+	cache map[string]cacheEntry
+}
+
+// NewClient creates a new preferences client, calling user-service through
+// transport
+func NewClient(cfg *config.Config, transport http.RoundTripper, logger *zap.Logger) *Client {
+	return &Client{
+		baseURL:    cfg.UserServiceURL,
+		apiKey:     cfg.UserServiceAPIKey,
+		ttl:        time.Duration(cfg.PreferencesCacheTTL) * time.Second,
+		httpClient: &http.Client{Timeout: 3 * time.Second, Transport: transport},
+		logger:     logger,
+		cache:      make(map[string]cacheEntry),
+	}
+}
+
+// Get returns the preferences for a user, using the cache when possible
+func (c *Client) Get(ctx context.Context, userID string) (*Preferences, error) {
+	c.mu.RLock()
+	entry, ok := c.cache[userID]
+	c.mu.RUnlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.prefs, nil
+	}
+
+	prefs, err := c.fetch(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.cache[userID] = cacheEntry{prefs: prefs, expiresAt: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return prefs, nil
+}
+
+// Invalidate drops a cached entry for a user
+func (c *Client) Invalidate(userID string) {
+	c.mu.Lock()
+	delete(c.cache, userID)
+	c.mu.Unlock()
+}
+
+func (c *Client) fetch(ctx context.Context, userID string) (*Preferences, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/internal/users/%s/notification-preferences",
+		c.baseURL, url.PathEscape(userID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-Service-Key", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch preferences: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// No stored preferences means defaults (everything enabled)
+	if resp.StatusCode == http.StatusNotFound {
+		return &Preferences{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("user-service returned status %d", resp.StatusCode)
+	}
+
+	var prefs Preferences
+	if err := json.NewDecoder(resp.Body).Decode(&prefs); err != nil {
+		return nil, fmt.Errorf("failed to decode preferences: %w", err)
+	}
+
+	c.logger.Debug("Fetched notification preferences", zap.String("user_id", userID))
+	return &prefs, nil
+}
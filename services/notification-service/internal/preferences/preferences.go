@@ -0,0 +1,115 @@
+package preferences
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Notification categories
+const (
+	CategoryTransactional = "transactional"
+	CategoryMarketing     = "marketing"
+	CategoryOperational   = "operational"
+	CategorySecurity      = "security"
+)
+
+// eventCategories maps event types to preference categories
+var eventCategories = map[string]string{
+	"order.created":      CategoryTransactional,
+	"payment.successful": CategoryTransactional,
+	"payment.failed":     CategoryTransactional,
+	"order.shipped":      CategoryTransactional,
+	"order.delivered":    CategoryTransactional,
+	"order.cancelled":    CategoryTransactional,
+	"user.registered":    CategoryTransactional,
+	"return.requested":   CategoryTransactional,
+	"return.approved":    CategoryTransactional,
+	"return.rejected":    CategoryTransactional,
+	"return.received":    CategoryTransactional,
+	"return.refunded":    CategoryTransactional,
+	"gift_card.issued":   CategoryTransactional,
+
+	"subscription.created":          CategoryTransactional,
+	"subscription.renewal_upcoming": CategoryTransactional,
+	"subscription.paused":           CategoryTransactional,
+	"subscription.resumed":          CategoryTransactional,
+	"subscription.skipped":          CategoryTransactional,
+	"subscription.cancelled":        CategoryTransactional,
+	"subscription.payment_failed":   CategoryTransactional,
+
+	"user.password_reset_requested": CategorySecurity,
+	"user.password_changed":         CategorySecurity,
+	"user.new_device_login":         CategorySecurity,
+
+	"inventory.low_stock":         CategoryOperational,
+	"inventory.out_of_stock":      CategoryOperational,
+	"inventory.reorder_requested": CategoryOperational,
+}
+
+// CategoryFor returns the preference category of an event type. Event
+// types that aren't listed are marketing.
+func CategoryFor(eventType string) string {
+	if category, ok := eventCategories[eventType]; ok {
+		return category
+	}
+	return CategoryMarketing
+}
+
+// Preferences holds a user's channel and category opt-ins and timezone.
+// Missing keys are treated as opted in.
+type Preferences struct {
+	Channels   map[string]bool `json:"channels"`
+	Categories map[string]bool `json:"categories"`
+	Timezone   string          `json:"timezone,omitempty"`
+}
+
+// Allows reports whether a notification on the given channel and category
+// may be sent, and the suppression reason when it may not.
+// Transactional messages can be routed away from a channel but not opted out of.
+// Security messages (password resets, new-device logins) ignore preferences entirely.
+func (p *Preferences) Allows(channel, category string) (bool, string) {
+	if p == nil || category == CategorySecurity {
+		return true, ""
+	}
+
+	if enabled, ok := p.Channels[channel]; ok && !enabled {
+		return false, fmt.Sprintf("user disabled %s channel", channel)
+	}
+
+	if category == CategoryTransactional {
+		return true, ""
+	}
+
+	if enabled, ok := p.Categories[category]; ok && !enabled {
+		return false, fmt.Sprintf("user opted out of %s notifications", category)
+	}
+
+	return true, ""
+}
+
+// OptedIn reports whether the user explicitly enabled a channel. Used for
+// opt-in channels such as WhatsApp, which are never assumed.
+func (p *Preferences) OptedIn(channel string) bool {
+	return p != nil && p.Channels[channel]
+}
+
+// FromEventData extracts preferences carried in an event payload under the
+// "notification_preferences" key. It returns nil when none are present.
+func FromEventData(data map[string]interface{}) *Preferences {
+	raw, ok := data["notification_preferences"]
+	if !ok || raw == nil {
+		return nil
+	}
+
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+
+	var prefs Preferences
+	if err := json.Unmarshal(encoded, &prefs); err != nil {
+		return nil
+	}
+
+	return &prefs
+}
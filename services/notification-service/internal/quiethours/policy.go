@@ -0,0 +1,104 @@
+package quiethours
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ecommerce/notification-service/internal/config"
+)
+
+// Policy decides when non-urgent notifications may reach a recipient. Quiet
+// hours run from start to end in the recipient's local time and may wrap
+// midnight, e.g. 21:00-08:00.
+type Policy struct {
+	start      int // minutes after local midnight
+	end        int
+	categories map[string]bool
+	fallback   *time.Location
+}
+
+// NewPolicy creates a quiet hours policy from QUIET_HOURS_* configuration.
+// Equal start and end times, or no categories, disable quiet hours.
+func NewPolicy(cfg *config.Config) (*Policy, error) {
+	start, err := parseClock(cfg.QuietHoursStart)
+	if err != nil {
+		return nil, fmt.Errorf("invalid QUIET_HOURS_START: %w", err)
+	}
+	end, err := parseClock(cfg.QuietHoursEnd)
+	if err != nil {
+		return nil, fmt.Errorf("invalid QUIET_HOURS_END: %w", err)
+	}
+	fallback, err := time.LoadLocation(cfg.DefaultTimezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DEFAULT_TIMEZONE: %w", err)
+	}
+
+	categories := make(map[string]bool, len(cfg.QuietHoursCategories))
+	for _, c := range cfg.QuietHoursCategories {
+		categories[c] = true
+	}
+
+	return &Policy{
+		start:      start,
+		end:        end,
+		categories: categories,
+		fallback:   fallback,
+	}, nil
+}
+
+// parseClock parses an HH:MM time of day into minutes after midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM, got %q", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Enabled reports whether quiet hours are in effect at all
+func (p *Policy) Enabled() bool {
+	return p != nil && p.start != p.end && len(p.categories) > 0
+}
+
+// Applies reports whether notifications in a category are held during quiet hours
+func (p *Policy) Applies(category string) bool {
+	return p.Enabled() && p.categories[category]
+}
+
+// Location resolves an IANA timezone name, falling back to DEFAULT_TIMEZONE
+// when it is empty or unknown
+func (p *Policy) Location(timezone string) *time.Location {
+	if timezone != "" {
+		if loc, err := time.LoadLocation(timezone); err == nil {
+			return loc
+		}
+	}
+	return p.fallback
+}
+
+// ReleaseAt reports whether now falls in quiet hours in loc and, if so, when
+// the recipient's daytime window next opens
+func (p *Policy) ReleaseAt(now time.Time, loc *time.Location) (time.Time, bool) {
+	if !p.Enabled() {
+		return time.Time{}, false
+	}
+
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+
+	var quiet bool
+	if p.start < p.end {
+		quiet = minute >= p.start && minute < p.end
+	} else {
+		quiet = minute >= p.start || minute < p.end
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	opens := time.Date(local.Year(), local.Month(), local.Day(), p.end/60, p.end%60, 0, 0, loc)
+	if !opens.After(local) {
+		opens = opens.AddDate(0, 0, 1)
+	}
+	return opens, true
+}
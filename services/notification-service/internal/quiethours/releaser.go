@@ -0,0 +1,197 @@
+package quiethours
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/notification-service/internal/branding"
+	"github.com/ecommerce/notification-service/internal/email"
+	"github.com/ecommerce/notification-service/internal/events"
+	"github.com/ecommerce/notification-service/internal/metrics"
+	"github.com/ecommerce/notification-service/internal/preferences"
+	"github.com/ecommerce/notification-service/internal/sms"
+	"github.com/ecommerce/notification-service/internal/store"
+	"github.com/ecommerce/notification-service/internal/tracking"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// releaseBatchSize bounds the notifications released per tick
+const releaseBatchSize = 100
+
+// Releaser periodically sends scheduled notifications whose recipient's
+// daytime window has opened
+type Releaser struct {
+	scheduled     store.ScheduledStore
+	notifications store.NotificationStore
+	emailSender   *email.EmailSender
+	smsSender     *sms.SMSSender
+	clicks        *tracking.ClickTracker
+	brands        *branding.Brands
+	statusEvents  *events.Publisher
+	interval      time.Duration
+	logger        *zap.Logger
+}
+
+// NewReleaser creates a new scheduled notification releaser
+func NewReleaser(
+	scheduled store.ScheduledStore,
+	notifications store.NotificationStore,
+	emailSender *email.EmailSender,
+	smsSender *sms.SMSSender,
+	clickTracker *tracking.ClickTracker,
+	brands *branding.Brands,
+	statusEvents *events.Publisher,
+	logger *zap.Logger,
+) *Releaser {
+	return &Releaser{
+		scheduled:     scheduled,
+		notifications: notifications,
+		emailSender:   emailSender,
+		smsSender:     smsSender,
+		clicks:        clickTracker,
+		brands:        brands,
+		statusEvents:  statusEvents,
+		interval:      time.Minute,
+		logger:        logger,
+	}
+}
+
+// Start runs the releaser until the context is cancelled
+func (r *Releaser) Start(ctx context.Context) {
+	r.logger.Info("Starting scheduled notification releaser", zap.Duration("interval", r.interval))
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Stopping scheduled notification releaser")
+			return
+		case <-ticker.C:
+			r.release(ctx)
+		}
+	}
+}
+
+// release sends every scheduled notification that is due
+func (r *Releaser) release(ctx context.Context) {
+	due, err := r.scheduled.ListDueScheduled(ctx, time.Now(), releaseBatchSize)
+	if err != nil {
+		r.logger.Error("Failed to list due scheduled notifications", zap.Error(err))
+		return
+	}
+
+	for _, n := range due {
+		if err := r.send(ctx, n); err != nil {
+			r.logger.Error("Failed to release scheduled notification",
+				zap.String("id", n.ID),
+				zap.String("event_type", n.EventType),
+				zap.String("channel", string(n.Channel)),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+func (r *Releaser) send(ctx context.Context, n *store.ScheduledNotification) error {
+	if n.CorrelationID != "" {
+		ctx = logging.WithCorrelationID(ctx, n.CorrelationID)
+	}
+
+	record := &store.Notification{
+		EventType:     n.EventType,
+		Channel:       n.Channel,
+		Template:      n.Template,
+		Variant:       n.Variant,
+		Recipient:     n.Recipient,
+		UserID:        n.UserID,
+		OrderID:       n.OrderID,
+		EventKey:      n.EventKey,
+		CorrelationID: n.CorrelationID,
+		BrandID:       n.BrandID,
+	}
+
+	start := time.Now()
+	var providerName, messageID string
+	var sendErr error
+	var smsResult *sms.Result
+	switch n.Channel {
+	case store.ChannelEmail:
+		body := n.Body
+		if r.clicks.Enabled(n.Template, preferences.CategoryFor(n.EventType)) {
+			record.ID = uuid.New().String()
+			body = r.clicks.RewriteLinks(record.ID, body)
+		}
+		brand := r.brands.Resolve(ctx, n.BrandID)
+		result, err := r.emailSender.Send(ctx, email.Email{
+			To:        n.Recipient,
+			Subject:   n.Subject,
+			Body:      body,
+			FromName:  brand.FromName,
+			FromEmail: brand.FromEmail,
+		})
+		if result != nil {
+			providerName, messageID = result.Provider, result.MessageID
+		}
+		sendErr = err
+	case store.ChannelSMS:
+		result, err := r.smsSender.Send(ctx, n.Recipient, n.Body)
+		if result != nil {
+			providerName, messageID = result.Provider, result.MessageID
+			smsResult = result
+		}
+		sendErr = err
+	default:
+		return fmt.Errorf("unsupported channel for scheduled notification: %s", n.Channel)
+	}
+	metrics.SendDuration.
+		WithLabelValues(string(record.Channel), record.Template, record.EventType).
+		Observe(time.Since(start).Seconds())
+
+	record.Provider = providerName
+	record.MessageID = messageID
+	if smsResult != nil {
+		record.Segments = smsResult.Segments
+		record.Cost = smsResult.Cost
+	}
+	if sendErr != nil {
+		record.Status = store.StatusFailed
+		record.Reason = sendErr.Error()
+	} else {
+		record.Status = store.StatusSent
+	}
+
+	metrics.NotificationsTotal.
+		WithLabelValues(string(record.Channel), record.Template, record.EventType, string(record.Status)).
+		Inc()
+	if record.Variant != "" && record.Status == store.StatusSent {
+		metrics.VariantSendsTotal.WithLabelValues(record.Template, record.Variant).Inc()
+	}
+
+	if err := r.notifications.Create(ctx, record); err != nil {
+		r.logger.Error("Failed to record released notification", zap.Error(err))
+	}
+	r.statusEvents.PublishStatus(ctx, record)
+
+	// Leave the notification scheduled so the next tick retries it
+	if sendErr != nil {
+		return sendErr
+	}
+
+	if err := r.scheduled.MarkScheduledSent(ctx, n.ID); err != nil {
+		return err
+	}
+
+	r.logger.Info("Scheduled notification released",
+		zap.String("event_type", n.EventType),
+		zap.String("channel", string(n.Channel)),
+		zap.String("user_id", n.UserID),
+		zap.Duration("held_for", time.Since(n.CreatedAt)),
+	)
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Limiter throttles notifications per recipient and channel using
+// fixed one-hour windows stored in Redis
+type Limiter struct {
+	client *redis.Client
+	limits map[string]int
+	window time.Duration
+}
+
+// NewLimiter creates a new per-recipient limiter. limits maps a channel
+// name to the maximum number of sends allowed per window; a channel
+// without a positive limit is not throttled.
+func NewLimiter(client *redis.Client, limits map[string]int) *Limiter {
+	return &Limiter{
+		client: client,
+		limits: limits,
+		window: time.Hour,
+	}
+}
+
+// Allow records a send attempt and reports whether it is within the limit
+func (l *Limiter) Allow(ctx context.Context, channel, recipient string) (bool, error) {
+	limit, ok := l.limits[channel]
+	if !ok || limit <= 0 {
+		return true, nil
+	}
+
+	bucket := time.Now().Truncate(l.window).Unix()
+	key := fmt.Sprintf("notification:ratelimit:%s:%s:%d", channel, recipient, bucket)
+
+	pipe := l.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, l.window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+
+	return incr.Val() <= int64(limit), nil
+}
+
+// Limit returns the configured limit for a channel
+func (l *Limiter) Limit(channel string) int {
+	return l.limits[channel]
+}
+
+// Cooldown reports whether key is outside its cooldown period and, if so,
+// starts a new one. Used to stop repeated alerts for the same subject.
+func (l *Limiter) Cooldown(ctx context.Context, key string, period time.Duration) (bool, error) {
+	return l.client.SetNX(ctx, "notification:cooldown:"+key, 1, period).Result()
+}
+
+// ResetCooldown clears a cooldown so the next alert for key is sent
+func (l *Limiter) ResetCooldown(ctx context.Context, key string) error {
+	return l.client.Del(ctx, "notification:cooldown:"+key).Err()
+}
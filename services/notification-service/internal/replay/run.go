@@ -0,0 +1,176 @@
+package replay
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Mode controls whether a replay sends notifications
+type Mode string
+
+const (
+	// ModeDryRun reports what would be sent without sending or recording anything
+	ModeDryRun Mode = "dry-run"
+	// ModeResend sends notifications that were not already delivered
+	ModeResend Mode = "resend"
+)
+
+// Results of a replayed notification or message
+const (
+	ResultWouldSend     = "would_send"
+	ResultWouldSuppress = "would_suppress"
+	ResultDuplicate     = "duplicate"
+	ResultInvalid       = "invalid"
+)
+
+// RunStatus is the state of a replay run
+type RunStatus string
+
+const (
+	RunRunning   RunStatus = "running"
+	RunCompleted RunStatus = "completed"
+	RunFailed    RunStatus = "failed"
+	RunCancelled RunStatus = "cancelled"
+)
+
+// maxOutcomes caps the per-notification outcomes kept for a run; the totals
+// in Counts are always complete
+const maxOutcomes = 500
+
+// Outcome is what a replay did, or would do, with one notification
+type Outcome struct {
+	EventKey  string `json:"event_key"`
+	EventType string `json:"event_type"`
+	Channel   string `json:"channel,omitempty"`
+	Template  string `json:"template,omitempty"`
+	Recipient string `json:"recipient,omitempty"`
+	Result    string `json:"result"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Run tracks a single replay and collects its outcomes
+type Run struct {
+	ID      string  `json:"id"`
+	Request Request `json:"request"`
+
+	mu         sync.Mutex
+	status     RunStatus
+	err        string
+	scanned    int
+	counts     map[string]int
+	outcomes   []Outcome
+	truncated  bool
+	startedAt  time.Time
+	finishedAt time.Time
+	cancel     context.CancelFunc
+}
+
+// Summary is a point-in-time view of a run
+type Summary struct {
+	ID         string         `json:"id"`
+	Request    Request        `json:"request"`
+	Status     RunStatus      `json:"status"`
+	Error      string         `json:"error,omitempty"`
+	Scanned    int            `json:"messages_scanned"`
+	Counts     map[string]int `json:"counts"`
+	Outcomes   []Outcome      `json:"outcomes"`
+	Truncated  bool           `json:"outcomes_truncated"`
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+}
+
+func newRun(id string, req Request, cancel context.CancelFunc) *Run {
+	return &Run{
+		ID:        id,
+		Request:   req,
+		status:    RunRunning,
+		counts:    make(map[string]int),
+		startedAt: time.Now(),
+		cancel:    cancel,
+	}
+}
+
+// DryRun reports whether the run must not send or record anything
+func (r *Run) DryRun() bool {
+	return r.Request.Mode == ModeDryRun
+}
+
+// Record adds the outcome of one notification or message
+func (r *Run) Record(outcome Outcome) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counts[outcome.Result]++
+	if len(r.outcomes) < maxOutcomes {
+		r.outcomes = append(r.outcomes, outcome)
+	} else {
+		r.truncated = true
+	}
+}
+
+func (r *Run) scannedMessage() {
+	r.mu.Lock()
+	r.scanned++
+	r.mu.Unlock()
+}
+
+func (r *Run) finish(status RunStatus, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.status = status
+	if err != nil {
+		r.err = err.Error()
+	}
+	r.finishedAt = time.Now()
+}
+
+// Running reports whether the run is still in progress
+func (r *Run) Running() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status == RunRunning
+}
+
+// Summary returns a snapshot of the run's progress
+func (r *Run) Summary() Summary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make(map[string]int, len(r.counts))
+	for result, n := range r.counts {
+		counts[result] = n
+	}
+
+	summary := Summary{
+		ID:        r.ID,
+		Request:   r.Request,
+		Status:    r.status,
+		Error:     r.err,
+		Scanned:   r.scanned,
+		Counts:    counts,
+		Outcomes:  append([]Outcome(nil), r.outcomes...),
+		Truncated: r.truncated,
+		StartedAt: r.startedAt,
+	}
+	if !r.finishedAt.IsZero() {
+		finishedAt := r.finishedAt
+		summary.FinishedAt = &finishedAt
+	}
+
+	return summary
+}
+
+type runKey struct{}
+
+// WithRun marks a context as belonging to a replay run
+func WithRun(ctx context.Context, run *Run) context.Context {
+	return context.WithValue(ctx, runKey{}, run)
+}
+
+// FromContext returns the replay run of a context, or nil for live traffic
+func FromContext(ctx context.Context) *Run {
+	run, _ := ctx.Value(runKey{}).(*Run)
+	return run
+}
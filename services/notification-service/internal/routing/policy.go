@@ -0,0 +1,144 @@
+package routing
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ecommerce/notification-service/internal/config"
+)
+
+// Channel is a channel a policy can route a notification to
+type Channel string
+
+const (
+	Email    Channel = "email"
+	SMS      Channel = "sms"
+	WhatsApp Channel = "whatsapp"
+	InApp    Channel = "in_app"
+	// Mobile is WhatsApp for customers who opted in, else SMS
+	Mobile Channel = "mobile"
+)
+
+var channels = map[Channel]bool{Email: true, SMS: true, WhatsApp: true, InApp: true, Mobile: true}
+
+// Step is a fallback chain: its channels are tried in order until one sends
+type Step []Channel
+
+// Policy lists the steps of delivering one event type. Every step is
+// delivered, so a customer gets at most one message per step.
+type Policy []Step
+
+// defaultPolicies is the channel plan of each customer event type, unless
+// CHANNEL_POLICIES overrides it. Event types without a policy send email.
+var defaultPolicies = map[string]string{
+	"order.created":                 "email+in_app+mobile",
+	"payment.successful":            "email+in_app",
+	"payment.failed":                "email+in_app",
+	"order.shipped":                 "email+in_app+mobile",
+	"order.delivered":               "email+in_app",
+	"order.cancelled":               "email+in_app",
+	"user.registered":               "email",
+	"user.password_reset_requested": "email",
+	"user.password_changed":         "email",
+	"user.new_device_login":         "email",
+	"inventory.back_in_stock":       "email+in_app",
+	"review.requested":              "email+in_app",
+	"wishlist.price_dropped":        "email+in_app",
+	"return.requested":              "email+in_app",
+	"return.approved":               "email+in_app",
+	"return.rejected":               "email+in_app",
+	"return.received":               "email+in_app",
+	"return.refunded":               "email+in_app",
+	"gift_card.issued":              "email",
+	"subscription.created":          "email+in_app",
+	"subscription.renewal_upcoming": "email+in_app",
+	"subscription.paused":           "email+in_app",
+	"subscription.resumed":          "email+in_app",
+	"subscription.skipped":          "email+in_app",
+	"subscription.cancelled":        "email+in_app",
+	"subscription.payment_failed":   "email+in_app",
+}
+
+// Parse reads a policy: steps are joined with "+" and the channels of a
+// step with ">", e.g. "email+in_app>sms" sends email, and in-app or else SMS
+func Parse(value string) (Policy, error) {
+	var policy Policy
+	for _, rawStep := range strings.Split(value, "+") {
+		var step Step
+		seen := make(map[Channel]bool)
+		for _, raw := range strings.Split(rawStep, ">") {
+			channel := Channel(strings.TrimSpace(raw))
+			if !channels[channel] {
+				return nil, fmt.Errorf("unknown channel %q", channel)
+			}
+			if seen[channel] {
+				return nil, fmt.Errorf("channel %q repeated in %q", channel, rawStep)
+			}
+			seen[channel] = true
+			step = append(step, channel)
+		}
+		policy = append(policy, step)
+	}
+	return policy, nil
+}
+
+// Uses reports whether any step of the policy may use a channel
+func (p Policy) Uses(channel Channel) bool {
+	for _, step := range p {
+		for _, c := range step {
+			if c == channel {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String formats the policy the way Parse reads it
+func (p Policy) String() string {
+	steps := make([]string, len(p))
+	for i, step := range p {
+		names := make([]string, len(step))
+		for j, c := range step {
+			names[j] = string(c)
+		}
+		steps[i] = strings.Join(names, ">")
+	}
+	return strings.Join(steps, "+")
+}
+
+// Policies holds the channel policy of every event type
+type Policies struct {
+	byEventType map[string]Policy
+}
+
+// NewPolicies builds the channel policies from the defaults and the per event
+// type overrides in CHANNEL_POLICIES
+func NewPolicies(cfg *config.Config) (*Policies, error) {
+	byEventType := make(map[string]Policy, len(defaultPolicies)+len(cfg.ChannelPolicies))
+	for eventType, value := range defaultPolicies {
+		policy, err := Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default channel policy for %s: %w", eventType, err)
+		}
+		byEventType[eventType] = policy
+	}
+
+	for eventType, value := range cfg.ChannelPolicies {
+		policy, err := Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CHANNEL_POLICIES entry for %s: %w", eventType, err)
+		}
+		byEventType[eventType] = policy
+	}
+
+	return &Policies{byEventType: byEventType}, nil
+}
+
+// For returns the channel policy of an event type
+func (p *Policies) For(eventType string) Policy {
+	if policy, ok := p.byEventType[eventType]; ok {
+		return policy
+	}
+	return Policy{{Email}}
+}
@@ -0,0 +1,19 @@
+// Package schema holds the JSON Schemas of the events the service consumes,
+// validated by the shared validation package
+package schema
+
+import (
+	"embed"
+
+	"github.com/ecommerce-platform/shared/go/validation"
+)
+
+// FS holds the schemas, at schemas/v<version>/<event_type>.json
+//
+//go:embed schemas/v*/*.json
+var FS embed.FS
+
+// NewRegistry loads the embedded event schemas
+func NewRegistry() (*validation.Registry, error) {
+	return validation.NewRegistry(FS)
+}
@@ -0,0 +1,123 @@
+package sms
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/ecommerce-platform/shared/go/contact"
+	"github.com/ecommerce/notification-service/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// UnknownCountry is the country of numbers without a known numbering plan
+const UnknownCountry = "ZZ"
+
+// GSM 03.38 characters. Those in the extension table take two septets.
+const (
+	gsmBasic     = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞ\x1bÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+	gsmExtension = "\f^{}\\[~]|€"
+)
+
+// Segments returns how many parts a message is billed as. GSM-7 messages fit
+// 160 characters in one segment and 153 per part when split; anything else is
+// sent as UCS-2, at 70 and 67 UTF-16 units.
+func Segments(message string) int {
+	septets := 0
+	for _, c := range message {
+		switch {
+		case strings.ContainsRune(gsmBasic, c):
+			septets++
+		case strings.ContainsRune(gsmExtension, c):
+			septets += 2
+		default:
+			return parts(len(utf16.Encode([]rune(message))), 70, 67)
+		}
+	}
+	return parts(septets, 160, 153)
+}
+
+func parts(length, single, multi int) int {
+	if length <= single {
+		return 1
+	}
+	return (length + multi - 1) / multi
+}
+
+// CountryOf returns the ISO 3166-1 alpha-2 code of an E.164 number's country,
+// or UnknownCountry
+func CountryOf(phone string) string {
+	p, err := contact.ParsePhone(phone, "")
+	if err != nil || p.Region() == "" {
+		return UnknownCountry
+	}
+	return p.Region()
+}
+
+// deliveryResult prices a message the provider accepted. Costs the provider
+// didn't report are estimated from SMS_SEGMENT_COSTS.
+func (s *SMSSender) deliveryResult(provider, to, message string, receipt Receipt) *Result {
+	result := &Result{
+		Provider:  provider,
+		MessageID: receipt.MessageID,
+		Country:   CountryOf(to),
+		Segments:  receipt.Segments,
+		Cost:      receipt.Cost,
+	}
+	if result.Segments == 0 {
+		result.Segments = Segments(message)
+	}
+	if result.Cost == 0 {
+		perSegment, ok := s.config.SMSSegmentCosts[result.Country]
+		if !ok {
+			perSegment = s.config.SMSDefaultSegmentCost
+		}
+		result.Cost = perSegment * float64(result.Segments)
+	}
+	return result
+}
+
+// recordSpend counts a delivered message's segments and cost in metrics and
+// adds them to today's spend. Failures are logged; the message was sent.
+func (s *SMSSender) recordSpend(ctx context.Context, logger *zap.Logger, result *Result) {
+	metrics.SMSSegmentsTotal.WithLabelValues(result.Country, result.Provider).Add(float64(result.Segments))
+	metrics.SMSCostTotal.WithLabelValues(result.Country, result.Provider).Add(result.Cost)
+
+	if s.spend == nil {
+		return
+	}
+	if err := s.spend.AddSMSSpend(ctx, time.Now(), result.Country, result.Segments, result.Cost); err != nil {
+		logger.Error("Failed to record SMS spend",
+			zap.String("country", result.Country),
+			zap.Float64("cost", result.Cost),
+			zap.Error(err),
+		)
+	}
+}
+
+// BudgetExceeded reports whether today's (UTC) SMS spend has reached
+// SMS_DAILY_BUDGET. Spend lookup failures fail open.
+func (s *SMSSender) BudgetExceeded(ctx context.Context) bool {
+	if s.config.SMSDailyBudget <= 0 || s.spend == nil {
+		return false
+	}
+
+	spent, err := s.spend.SMSSpendTotal(ctx, time.Now())
+	if err != nil {
+		s.logger.Warn("Failed to load SMS spend, ignoring budget", zap.Error(err))
+		return false
+	}
+	return spent >= s.config.SMSDailyBudget
+}
+
+// BudgetExempt reports whether SMS of a notification category are sent even
+// when the daily budget is spent
+func (s *SMSSender) BudgetExempt(category string) bool {
+	for _, c := range s.config.SMSBudgetExemptCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package sms
+
+import "context"
+
+// channel labels SMS metrics
+const channel = "sms"
+
+// Provider delivers SMS through a specific backend
+type Provider interface {
+	// Name identifies the provider in logs and notification records
+	Name() string
+	// Send delivers the message and returns the provider's receipt
+	Send(ctx context.Context, to, message string) (Receipt, error)
+}
+
+// Receipt is what a provider reports about an accepted message. Segments and
+// Cost are zero when the provider doesn't report them.
+type Receipt struct {
+	MessageID string
+	Segments  int
+	Cost      float64 // USD
+}
+
+// Result describes a successful delivery and what it cost
+type Result struct {
+	Provider  string
+	MessageID string
+	Country   string  // ISO 3166-1 alpha-2 code of the recipient
+	Segments  int     // billed message parts
+	Cost      float64 // USD, reported by the provider or estimated from SMS_SEGMENT_COSTS
+}
@@ -1,61 +1,176 @@
 package sms
 
 import (
+	"context"
 	"fmt"
+	"time"
 
+	"github.com/ecommerce-platform/shared/go/contact"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/notification-service/internal/breaker"
 	"github.com/ecommerce/notification-service/internal/config"
+	"github.com/ecommerce/notification-service/internal/metrics"
+	"github.com/ecommerce/notification-service/internal/store"
+	"github.com/ecommerce/notification-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
-// SMSSender handles sending SMS messages
+// SMSSender handles sending SMS messages through the configured providers,
+// failing over to the next provider when one errors or its circuit is open.
+// What each message costs is added to the daily spend.
 type SMSSender struct {
-	config *config.Config
-	logger *zap.Logger
+	config  *config.Config
+	logger  *zap.Logger
+	routes  []route
+	timeout time.Duration
+	spend   store.SMSSpendStore
 }
 
-// NewSMSSender creates a new SMS sender
-func NewSMSSender(cfg *config.Config, logger *zap.Logger) *SMSSender {
+// route pairs a provider with its circuit breaker
+type route struct {
+	provider Provider
+	breaker  *breaker.Breaker
+}
+
+// NewSMSSender creates a new SMS sender using the providers listed in
+// SMS_PROVIDERS, in failover order. Twilio is skipped when it has no credentials.
+func NewSMSSender(ctx context.Context, cfg *config.Config, spendStore store.SMSSpendStore, logger *zap.Logger) (*SMSSender, error) {
+	cooldown := time.Duration(cfg.CircuitBreakerCooldown) * time.Second
+
+	routes := make([]route, 0, len(cfg.SMSProviders))
+	for _, name := range cfg.SMSProviders {
+		if name == "twilio" && cfg.TwilioAccountSID == "" {
+			logger.Warn("Twilio credentials not configured, skipping provider")
+			continue
+		}
+
+		provider, err := NewProvider(ctx, cfg, name)
+		if err != nil {
+			return nil, err
+		}
+
+		providerName := provider.Name()
+		routes = append(routes, route{
+			provider: provider,
+			breaker: breaker.New(cfg.CircuitBreakerThreshold, cooldown, func(state breaker.State) {
+				metrics.ProviderCircuitState.WithLabelValues(channel, providerName).Set(float64(state))
+				if state == breaker.StateOpen {
+					logger.Warn("SMS provider circuit opened", zap.String("provider", providerName))
+				}
+			}),
+		})
+	}
+
 	return &SMSSender{
-		config: cfg,
-		logger: logger,
+		config:  cfg,
+		logger:  logger,
+		routes:  routes,
+		timeout: time.Duration(cfg.ProviderTimeout) * time.Second,
+		spend:   spendStore,
+	}, nil
+}
+
+// NewProvider creates the named SMS provider
+func NewProvider(ctx context.Context, cfg *config.Config, name string) (Provider, error) {
+	switch name {
+	case "twilio":
+		return NewTwilioProvider(cfg), nil
+	case "sns":
+		return NewSNSProvider(ctx, cfg)
+	default:
+		return nil, fmt.Errorf("unknown SMS provider: %s", name)
 	}
 }
 
-// Send sends an SMS message
-func (s *SMSSender) Send(to string, message string) error {
-	// In development mode or without Twilio credentials, simulate sending
-	if s.config.Environment == "development" || s.config.TwilioAccountSID == "" {
-		s.logger.Info("SMS (simulated)",
+// Send sends an SMS message through the first healthy provider and returns
+// which provider delivered it and what it cost
+func (s *SMSSender) Send(ctx context.Context, to string, message string) (*Result, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "sms.send",
+		trace.WithAttributes(attribute.String("notification.channel", channel)),
+	)
+	defer span.End()
+	logger := logging.WithContext(ctx, s.logger)
+
+	// In development mode or without any configured provider, simulate sending
+	if s.config.Environment == "development" || len(s.routes) == 0 {
+		logger.Info("SMS (simulated)",
 			zap.String("to", to),
 			zap.String("message", message),
 		)
-		return nil
+		span.SetAttributes(attribute.String("notification.provider", "simulated"))
+		return &Result{Provider: "simulated", Country: CountryOf(to), Segments: Segments(message)}, nil
 	}
 
-	// Production mode: integrate with Twilio
-	// Note: Full Twilio integration would require the Twilio SDK
-	// For now, we'll log that we would send via Twilio
-	s.logger.Info("SMS would be sent via Twilio",
-		zap.String("to", to),
-		zap.String("message", message),
-		zap.String("account_sid", s.config.TwilioAccountSID),
-	)
+	var lastErr error
+	for i, r := range s.routes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 
-	// In a production implementation, you would use:
-	// client := twilio.NewRestClient()
-	// params := &twilioApi.CreateMessageParams{}
-	// params.SetTo(to)
-	// params.SetFrom(s.config.TwilioPhoneNumber)
-	// params.SetBody(message)
-	// resp, err := client.Api.CreateMessage(params)
+		name := r.provider.Name()
+		if !r.breaker.Allow() {
+			logger.Warn("SMS provider circuit open, skipping", zap.String("provider", name))
+			continue
+		}
 
-	return nil
+		attemptCtx, cancel := context.WithTimeout(ctx, s.timeout)
+		receipt, err := r.provider.Send(attemptCtx, to, message)
+		cancel()
+
+		if err != nil {
+			r.breaker.Failure()
+			metrics.ProviderFailures.WithLabelValues(channel, name).Inc()
+			logger.Error("Failed to send SMS",
+				zap.String("provider", name),
+				zap.Error(err),
+			)
+			span.AddEvent("provider failed", trace.WithAttributes(
+				attribute.String("notification.provider", name),
+				attribute.String("error", err.Error()),
+			))
+			lastErr = err
+			continue
+		}
+
+		r.breaker.Success()
+		if i > 0 {
+			metrics.ProviderFailovers.WithLabelValues(channel, name).Inc()
+		}
+
+		result := s.deliveryResult(name, to, message, receipt)
+		s.recordSpend(ctx, logger, result)
+
+		logger.Info("SMS sent",
+			zap.String("provider", name),
+			zap.String("message_id", result.MessageID),
+			zap.String("country", result.Country),
+			zap.Int("segments", result.Segments),
+			zap.Float64("cost", result.Cost),
+		)
+		span.SetAttributes(
+			attribute.String("notification.provider", name),
+			attribute.String("notification.message_id", result.MessageID),
+			attribute.Int("sms.segments", result.Segments),
+		)
+		return result, nil
+	}
+
+	err := fmt.Errorf("no SMS provider available: all circuits open")
+	if lastErr != nil {
+		err = fmt.Errorf("all SMS providers failed: %w", lastErr)
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return nil, err
 }
 
 // SendBulk sends SMS to multiple recipients
-func (s *SMSSender) SendBulk(recipients []string, message string) error {
+func (s *SMSSender) SendBulk(ctx context.Context, recipients []string, message string) error {
 	for _, recipient := range recipients {
-		if err := s.Send(recipient, message); err != nil {
+		if _, err := s.Send(ctx, recipient, message); err != nil {
 			s.logger.Error("Failed to send SMS",
 				zap.String("recipient", recipient),
 				zap.Error(err),
@@ -66,11 +181,13 @@ func (s *SMSSender) SendBulk(recipients []string, message string) error {
 	return nil
 }
 
-// ValidatePhoneNumber validates a phone number format
-func (s *SMSSender) ValidatePhoneNumber(phone string) error {
-	// Basic validation - in production, use a proper phone validation library
-	if len(phone) < 10 {
-		return fmt.Errorf("phone number too short")
+// ValidatePhoneNumber checks that a phone number can receive SMS and returns
+// it in E.164 form. National numbers are read as SMS_DEFAULT_COUNTRY numbers.
+// With SMS_REJECT_LANDLINES, numbers in landline ranges are rejected.
+// Errors are *contact.Error.
+func (s *SMSSender) ValidatePhoneNumber(phone string) (string, error) {
+	if s.config.SMSRejectLandlines {
+		return contact.NormalizeMobile(phone, s.config.SMSDefaultCountry)
 	}
-	return nil
+	return contact.NormalizePhone(phone, s.config.SMSDefaultCountry)
 }
@@ -0,0 +1,52 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/ecommerce/notification-service/internal/config"
+)
+
+// snsProvider sends SMS through Amazon SNS direct publish
+type snsProvider struct {
+	client *sns.Client
+}
+
+// NewSNSProvider creates a new Amazon SNS SMS provider. Credentials are
+// resolved through the default AWS chain (env vars, shared config, IAM role).
+func NewSNSProvider(ctx context.Context, cfg *config.Config) (Provider, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(cfg.AWSRegion))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return &snsProvider{client: sns.NewFromConfig(awsCfg)}, nil
+}
+
+func (p *snsProvider) Name() string {
+	return "sns"
+}
+
+// Send publishes a transactional SMS and returns the SNS message ID. SNS
+// doesn't report segments or price at publish time.
+func (p *snsProvider) Send(ctx context.Context, to, message string) (Receipt, error) {
+	out, err := p.client.Publish(ctx, &sns.PublishInput{
+		PhoneNumber: aws.String(to),
+		Message:     aws.String(message),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"AWS.SNS.SMS.SMSType": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String("Transactional"),
+			},
+		},
+	})
+	if err != nil {
+		return Receipt{}, fmt.Errorf("sns publish failed: %w", err)
+	}
+
+	return Receipt{MessageID: aws.ToString(out.MessageId)}, nil
+}
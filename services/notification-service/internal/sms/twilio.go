@@ -0,0 +1,126 @@
+package sms
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ecommerce/notification-service/internal/config"
+)
+
+const twilioAPIBase = "https://api.twilio.com/2010-04-01"
+
+// TwilioProvider sends SMS through the Twilio Messages REST API
+type TwilioProvider struct {
+	config     *config.Config
+	httpClient *http.Client
+}
+
+// NewTwilioProvider creates a new Twilio SMS provider
+func NewTwilioProvider(cfg *config.Config) *TwilioProvider {
+	return &TwilioProvider{
+		config:     cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *TwilioProvider) Name() string {
+	return "twilio"
+}
+
+type twilioMessageResponse struct {
+	SID          string  `json:"sid"`
+	Status       string  `json:"status"`
+	NumSegments  string  `json:"num_segments"`
+	Price        *string `json:"price"` // negative, e.g. "-0.00790"; null until Twilio has priced it
+	PriceUnit    string  `json:"price_unit"`
+	ErrorCode    *int    `json:"error_code"`
+	ErrorMessage string  `json:"error_message"`
+}
+
+type twilioErrorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Send creates a message via the Twilio API and returns its SID, segment
+// count and, when Twilio has already priced it in USD, its cost
+func (p *TwilioProvider) Send(ctx context.Context, to, message string) (Receipt, error) {
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("From", p.config.TwilioFromNumber)
+	form.Set("Body", message)
+	if p.config.TwilioStatusCallbackURL != "" {
+		form.Set("StatusCallback", p.config.TwilioStatusCallbackURL)
+	}
+
+	endpoint := fmt.Sprintf("%s/Accounts/%s/Messages.json", twilioAPIBase, p.config.TwilioAccountSID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Receipt{}, err
+	}
+	req.SetBasicAuth(p.config.TwilioAccountSID, p.config.TwilioAuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return Receipt{}, fmt.Errorf("twilio request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr twilioErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		return Receipt{}, fmt.Errorf("twilio returned status %d (code %d): %s", resp.StatusCode, apiErr.Code, apiErr.Message)
+	}
+
+	var msg twilioMessageResponse
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+		return Receipt{}, fmt.Errorf("failed to decode twilio response: %w", err)
+	}
+	if msg.ErrorCode != nil {
+		return Receipt{}, fmt.Errorf("twilio error %d: %s", *msg.ErrorCode, msg.ErrorMessage)
+	}
+
+	receipt := Receipt{MessageID: msg.SID}
+	receipt.Segments, _ = strconv.Atoi(msg.NumSegments)
+	if msg.Price != nil && (msg.PriceUnit == "" || strings.EqualFold(msg.PriceUnit, "USD")) {
+		if price, err := strconv.ParseFloat(*msg.Price, 64); err == nil {
+			receipt.Cost = math.Abs(price)
+		}
+	}
+	return receipt, nil
+}
+
+// ValidateSignature verifies the X-Twilio-Signature header of a webhook
+// request. fullURL must be the exact public URL Twilio called.
+func (p *TwilioProvider) ValidateSignature(fullURL string, params url.Values, signature string) bool {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var payload strings.Builder
+	payload.WriteString(fullURL)
+	for _, key := range keys {
+		payload.WriteString(key)
+		payload.WriteString(params.Get(key))
+	}
+
+	mac := hmac.New(sha1.New, []byte(p.config.TwilioAuthToken))
+	mac.Write([]byte(payload.String()))
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Brand is a storefront served by the notification service. Empty fields
+// fall back to the default brand's.
+type Brand struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	LogoURL       string    `json:"logo_url,omitempty"`
+	PrimaryColor  string    `json:"primary_color,omitempty"`
+	SupportEmail  string    `json:"support_email,omitempty"`
+	StorefrontURL string    `json:"storefront_url,omitempty"`
+	FooterText    string    `json:"footer_text,omitempty"`
+	FromName      string    `json:"from_name,omitempty"`
+	FromEmail     string    `json:"from_email,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// BrandStore manages storefront brands
+type BrandStore interface {
+	ListBrands(ctx context.Context) ([]*Brand, error)
+	GetBrand(ctx context.Context, id string) (*Brand, error)
+	UpsertBrand(ctx context.Context, brand *Brand) error
+	DeleteBrand(ctx context.Context, id string) error
+}
+
+type postgresBrandStore struct {
+	db *sql.DB
+}
+
+// NewPostgresBrandStore creates a new PostgreSQL brand store
+func NewPostgresBrandStore(db *sql.DB) BrandStore {
+	return &postgresBrandStore{db: db}
+}
+
+// ListBrands returns all brands by ID
+func (s *postgresBrandStore) ListBrands(ctx context.Context) ([]*Brand, error) {
+	query := `
+		SELECT id, name, logo_url, primary_color, support_email, storefront_url,
+		       footer_text, from_name, from_email, created_at, updated_at
+		FROM brands
+		ORDER BY id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var brands []*Brand
+	for rows.Next() {
+		b := &Brand{}
+		err := rows.Scan(
+			&b.ID, &b.Name, &b.LogoURL, &b.PrimaryColor, &b.SupportEmail, &b.StorefrontURL,
+			&b.FooterText, &b.FromName, &b.FromEmail, &b.CreatedAt, &b.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		brands = append(brands, b)
+	}
+
+	return brands, rows.Err()
+}
+
+// GetBrand retrieves a brand by ID
+func (s *postgresBrandStore) GetBrand(ctx context.Context, id string) (*Brand, error) {
+	query := `
+		SELECT id, name, logo_url, primary_color, support_email, storefront_url,
+		       footer_text, from_name, from_email, created_at, updated_at
+		FROM brands WHERE id = $1
+	`
+
+	b := &Brand{}
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&b.ID, &b.Name, &b.LogoURL, &b.PrimaryColor, &b.SupportEmail, &b.StorefrontURL,
+		&b.FooterText, &b.FromName, &b.FromEmail, &b.CreatedAt, &b.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+
+	return b, err
+}
+
+// UpsertBrand creates a brand or replaces an existing one's settings
+func (s *postgresBrandStore) UpsertBrand(ctx context.Context, b *Brand) error {
+	now := time.Now()
+
+	query := `
+		INSERT INTO brands (
+			id, name, logo_url, primary_color, support_email, storefront_url,
+			footer_text, from_name, from_email, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			logo_url = EXCLUDED.logo_url,
+			primary_color = EXCLUDED.primary_color,
+			support_email = EXCLUDED.support_email,
+			storefront_url = EXCLUDED.storefront_url,
+			footer_text = EXCLUDED.footer_text,
+			from_name = EXCLUDED.from_name,
+			from_email = EXCLUDED.from_email,
+			updated_at = EXCLUDED.updated_at
+		RETURNING created_at, updated_at
+	`
+
+	return s.db.QueryRowContext(ctx, query,
+		b.ID, b.Name, b.LogoURL, b.PrimaryColor, b.SupportEmail, b.StorefrontURL,
+		b.FooterText, b.FromName, b.FromEmail, now,
+	).Scan(&b.CreatedAt, &b.UpdatedAt)
+}
+
+// DeleteBrand removes a brand; its notifications fall back to the default
+// brand
+func (s *postgresBrandStore) DeleteBrand(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM brands WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
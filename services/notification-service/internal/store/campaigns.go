@@ -0,0 +1,517 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Campaign statuses
+const (
+	CampaignScheduled = "scheduled"
+	CampaignRunning   = "running"
+	CampaignPaused    = "paused"
+	CampaignCompleted = "completed"
+	CampaignCancelled = "cancelled"
+)
+
+// Campaign recipient statuses
+const (
+	RecipientPending    = "pending"
+	RecipientSending    = "sending"
+	RecipientSent       = "sent"
+	RecipientFailed     = "failed"
+	RecipientSuppressed = "suppressed"
+)
+
+// ErrCampaignState is returned when a campaign can't move to the requested
+// status from the one it is in
+var ErrCampaignState = errors.New("campaign is not in a state that allows this")
+
+// Segment is an uploaded list of campaign recipients
+type Segment struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Size      int       `json:"size"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SegmentMember is one recipient of a segment. Data is merged into the
+// template data of their email.
+type SegmentMember struct {
+	Email  string                 `json:"email"`
+	UserID string                 `json:"user_id,omitempty"`
+	Name   string                 `json:"name,omitempty"`
+	Data   map[string]interface{} `json:"data,omitempty"`
+}
+
+// Campaign is a bulk email of one template to a segment
+type Campaign struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Template    string                 `json:"template"`
+	SegmentID   string                 `json:"segment_id"`
+	BrandID     string                 `json:"brand_id,omitempty"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+	Status      string                 `json:"status"`
+	ScheduledAt time.Time              `json:"scheduled_at"`
+	CreatedBy   string                 `json:"created_by,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+	StartedAt   *time.Time             `json:"started_at,omitempty"`
+	CompletedAt *time.Time             `json:"completed_at,omitempty"`
+	Progress    CampaignProgress       `json:"progress"`
+}
+
+// CampaignProgress counts a campaign's recipients by outcome
+type CampaignProgress struct {
+	Total      int `json:"total"`
+	Pending    int `json:"pending"` // including recipients being sent to
+	Sent       int `json:"sent"`
+	Failed     int `json:"failed"`
+	Suppressed int `json:"suppressed"`
+}
+
+// CampaignRecipient is one email of a campaign
+type CampaignRecipient struct {
+	ID             int64                  `json:"id"`
+	CampaignID     string                 `json:"campaign_id"`
+	Email          string                 `json:"email"`
+	UserID         string                 `json:"user_id,omitempty"`
+	Name           string                 `json:"name,omitempty"`
+	Data           map[string]interface{} `json:"data,omitempty"`
+	Status         string                 `json:"status"`
+	Error          string                 `json:"error,omitempty"`
+	NotificationID string                 `json:"notification_id,omitempty"`
+}
+
+// CampaignStore persists segments, campaigns and their recipients. Recipients
+// are claimed in batches, so several instances can send one campaign.
+type CampaignStore interface {
+	CreateSegment(ctx context.Context, segment *Segment, members []SegmentMember) error
+	ListSegments(ctx context.Context, limit, offset int) ([]*Segment, error)
+	CreateCampaign(ctx context.Context, campaign *Campaign) error
+	GetCampaign(ctx context.Context, id string) (*Campaign, error)
+	ListCampaigns(ctx context.Context, limit, offset int) ([]*Campaign, error)
+	TransitionCampaign(ctx context.Context, id string, from []string, to string) error
+	StartDueCampaigns(ctx context.Context, now time.Time) ([]string, error)
+	ListRunningCampaigns(ctx context.Context) ([]*Campaign, error)
+	ClaimRecipients(ctx context.Context, campaignID string, limit int) ([]*CampaignRecipient, error)
+	UpdateRecipient(ctx context.Context, recipient *CampaignRecipient) error
+	ReleaseStaleRecipients(ctx context.Context, claimedBefore time.Time) (int64, error)
+	CompleteFinishedCampaigns(ctx context.Context, now time.Time) ([]string, error)
+}
+
+type postgresCampaignStore struct {
+	db *sql.DB
+}
+
+// NewPostgresCampaignStore creates a new PostgreSQL campaign store
+func NewPostgresCampaignStore(db *sql.DB) CampaignStore {
+	return &postgresCampaignStore{db: db}
+}
+
+// CreateSegment stores a segment and its members. Members must have distinct
+// email addresses.
+func (s *postgresCampaignStore) CreateSegment(ctx context.Context, segment *Segment, members []SegmentMember) error {
+	if segment.ID == "" {
+		segment.ID = uuid.New().String()
+	}
+	segment.Size = len(members)
+	segment.CreatedAt = time.Now()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO segments (id, name, size, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, segment.ID, segment.Name, segment.Size, segment.CreatedBy, segment.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	// COPY keeps uploads of hundreds of thousands of members fast
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("segment_members", "segment_id", "email", "user_id", "name", "data"))
+	if err != nil {
+		return err
+	}
+	for _, m := range members {
+		data, err := json.Marshal(m.Data)
+		if err != nil {
+			stmt.Close()
+			return err
+		}
+		if m.Data == nil {
+			data = []byte("{}")
+		}
+		if _, err := stmt.ExecContext(ctx, segment.ID, m.Email, m.UserID, m.Name, string(data)); err != nil {
+			stmt.Close()
+			return err
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return err
+	}
+	if err := stmt.Close(); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListSegments returns segments, newest first
+func (s *postgresCampaignStore) ListSegments(ctx context.Context, limit, offset int) ([]*Segment, error) {
+	query := `
+		SELECT id, name, size, created_by, created_at
+		FROM segments
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var segments []*Segment
+	for rows.Next() {
+		segment := &Segment{}
+		if err := rows.Scan(&segment.ID, &segment.Name, &segment.Size, &segment.CreatedBy, &segment.CreatedAt); err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+
+	return segments, rows.Err()
+}
+
+// CreateCampaign stores a campaign and copies its segment's members as its
+// recipients, so later changes to the segment don't affect it. It returns
+// ErrNotFound when the segment doesn't exist.
+func (s *postgresCampaignStore) CreateCampaign(ctx context.Context, c *Campaign) error {
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	now := time.Now()
+	c.CreatedAt = now
+	c.UpdatedAt = now
+	if c.ScheduledAt.IsZero() {
+		c.ScheduledAt = now
+	}
+	c.Status = CampaignScheduled
+
+	data, err := json.Marshal(c.Data)
+	if err != nil {
+		return err
+	}
+	if c.Data == nil {
+		data = []byte("{}")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM segments WHERE id = $1)`, c.SegmentID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO campaigns (id, name, template, segment_id, brand_id, data, status, scheduled_at, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, c.ID, c.Name, c.Template, c.SegmentID, c.BrandID, string(data), c.Status, c.ScheduledAt, c.CreatedBy, c.CreatedAt, c.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO campaign_recipients (campaign_id, email, user_id, name, data)
+		SELECT $1, email, user_id, name, data
+		FROM segment_members
+		WHERE segment_id = $2
+	`, c.ID, c.SegmentID)
+	if err != nil {
+		return err
+	}
+	recipients, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	c.Progress = CampaignProgress{Total: int(recipients), Pending: int(recipients)}
+
+	return tx.Commit()
+}
+
+// campaignColumns selects a campaign with its progress; queries using it
+// join campaign_recipients as r and group by c.id
+const campaignColumns = `
+	c.id, c.name, c.template, c.segment_id, c.brand_id, c.data, c.status, c.scheduled_at,
+	c.created_by, c.created_at, c.updated_at, c.started_at, c.completed_at,
+	COUNT(r.id),
+	COUNT(r.id) FILTER (WHERE r.status IN ('pending', 'sending')),
+	COUNT(r.id) FILTER (WHERE r.status = 'sent'),
+	COUNT(r.id) FILTER (WHERE r.status = 'failed'),
+	COUNT(r.id) FILTER (WHERE r.status = 'suppressed')
+`
+
+func scanCampaign(row rowScanner) (*Campaign, error) {
+	c := &Campaign{}
+	var data []byte
+	err := row.Scan(
+		&c.ID, &c.Name, &c.Template, &c.SegmentID, &c.BrandID, &data, &c.Status, &c.ScheduledAt,
+		&c.CreatedBy, &c.CreatedAt, &c.UpdatedAt, &c.StartedAt, &c.CompletedAt,
+		&c.Progress.Total, &c.Progress.Pending, &c.Progress.Sent, &c.Progress.Failed, &c.Progress.Suppressed,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.Data); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetCampaign retrieves a campaign and its progress
+func (s *postgresCampaignStore) GetCampaign(ctx context.Context, id string) (*Campaign, error) {
+	query := `
+		SELECT ` + campaignColumns + `
+		FROM campaigns c
+		LEFT JOIN campaign_recipients r ON r.campaign_id = c.id
+		WHERE c.id = $1
+		GROUP BY c.id
+	`
+
+	campaign, err := scanCampaign(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+
+	return campaign, err
+}
+
+// ListCampaigns returns campaigns with their progress, newest first
+func (s *postgresCampaignStore) ListCampaigns(ctx context.Context, limit, offset int) ([]*Campaign, error) {
+	query := `
+		SELECT ` + campaignColumns + `
+		FROM campaigns c
+		LEFT JOIN campaign_recipients r ON r.campaign_id = c.id
+		GROUP BY c.id
+		ORDER BY c.created_at DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	return s.queryCampaigns(ctx, query, limit, offset)
+}
+
+// ListRunningCampaigns returns the campaigns being sent, oldest first
+func (s *postgresCampaignStore) ListRunningCampaigns(ctx context.Context) ([]*Campaign, error) {
+	query := `
+		SELECT ` + campaignColumns + `
+		FROM campaigns c
+		LEFT JOIN campaign_recipients r ON r.campaign_id = c.id
+		WHERE c.status = $1
+		GROUP BY c.id
+		ORDER BY c.started_at ASC
+	`
+
+	return s.queryCampaigns(ctx, query, CampaignRunning)
+}
+
+func (s *postgresCampaignStore) queryCampaigns(ctx context.Context, query string, args ...interface{}) ([]*Campaign, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var campaigns []*Campaign
+	for rows.Next() {
+		campaign, err := scanCampaign(rows)
+		if err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, campaign)
+	}
+
+	return campaigns, rows.Err()
+}
+
+// TransitionCampaign moves a campaign to a new status if it is in one of
+// the from statuses. It returns ErrNotFound for unknown campaigns and
+// ErrCampaignState for campaigns in any other status.
+func (s *postgresCampaignStore) TransitionCampaign(ctx context.Context, id string, from []string, to string) error {
+	now := time.Now()
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE campaigns
+		SET status = $1, updated_at = $2,
+		    completed_at = CASE WHEN $1 IN ('completed', 'cancelled') THEN $2 ELSE completed_at END
+		WHERE id = $3 AND status = ANY($4)
+	`, to, now, id, pq.Array(from))
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM campaigns WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return ErrCampaignState
+}
+
+// StartDueCampaigns moves scheduled campaigns whose time has come to running
+// and returns their IDs
+func (s *postgresCampaignStore) StartDueCampaigns(ctx context.Context, now time.Time) ([]string, error) {
+	query := `
+		UPDATE campaigns
+		SET status = $1, started_at = COALESCE(started_at, $2), updated_at = $2
+		WHERE status = $3 AND scheduled_at <= $2
+		RETURNING id
+	`
+
+	return s.queryIDs(ctx, query, CampaignRunning, now, CampaignScheduled)
+}
+
+// ClaimRecipients marks up to limit pending recipients of a campaign as being
+// sent to and returns them. Rows locked by another instance are skipped.
+func (s *postgresCampaignStore) ClaimRecipients(ctx context.Context, campaignID string, limit int) ([]*CampaignRecipient, error) {
+	query := `
+		UPDATE campaign_recipients
+		SET status = $1, claimed_at = $2
+		WHERE id IN (
+			SELECT id FROM campaign_recipients
+			WHERE campaign_id = $3 AND status = $4
+			ORDER BY id
+			LIMIT $5
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, campaign_id, email, user_id, name, data, status
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, RecipientSending, time.Now(), campaignID, RecipientPending, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []*CampaignRecipient
+	for rows.Next() {
+		r := &CampaignRecipient{}
+		var data []byte
+		if err := rows.Scan(&r.ID, &r.CampaignID, &r.Email, &r.UserID, &r.Name, &data, &r.Status); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &r.Data); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, r)
+	}
+
+	return recipients, rows.Err()
+}
+
+// UpdateRecipient records the outcome of a recipient's email
+func (s *postgresCampaignStore) UpdateRecipient(ctx context.Context, r *CampaignRecipient) error {
+	var sentAt *time.Time
+	if r.Status == RecipientSent {
+		now := time.Now()
+		sentAt = &now
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE campaign_recipients
+		SET status = $1, error = $2, notification_id = $3, sent_at = $4
+		WHERE id = $5
+	`, r.Status, r.Error, r.NotificationID, sentAt, r.ID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// ReleaseStaleRecipients returns recipients claimed before the given time,
+// by an instance that stopped before sending to them, to pending
+func (s *postgresCampaignStore) ReleaseStaleRecipients(ctx context.Context, claimedBefore time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE campaign_recipients
+		SET status = $1, claimed_at = NULL
+		WHERE status = $2 AND claimed_at < $3
+	`, RecipientPending, RecipientSending, claimedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// CompleteFinishedCampaigns marks running campaigns without pending
+// recipients completed and returns their IDs
+func (s *postgresCampaignStore) CompleteFinishedCampaigns(ctx context.Context, now time.Time) ([]string, error) {
+	query := `
+		UPDATE campaigns c
+		SET status = $1, completed_at = $2, updated_at = $2
+		WHERE c.status = $3 AND NOT EXISTS (
+			SELECT 1 FROM campaign_recipients r
+			WHERE r.campaign_id = c.id AND r.status IN ($4, $5)
+		)
+		RETURNING c.id
+	`
+
+	return s.queryIDs(ctx, query, CampaignCompleted, now, CampaignRunning, RecipientPending, RecipientSending)
+}
+
+func (s *postgresCampaignStore) queryIDs(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -0,0 +1,215 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// DeadLetterStatus is the state of a dead-lettered message
+type DeadLetterStatus string
+
+const (
+	DeadLetterPending  DeadLetterStatus = "pending"
+	DeadLetterRedriven DeadLetterStatus = "redriven"
+)
+
+// DeadLetter is a Kafka message that failed processing
+type DeadLetter struct {
+	ID        string            `json:"id"`
+	Topic     string            `json:"topic"`
+	Partition int               `json:"partition"`
+	Offset    int64             `json:"offset"`
+	Key       string            `json:"key,omitempty"`
+	Payload   string            `json:"payload"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	EventType string            `json:"event_type,omitempty"`
+	Error     string            `json:"error"`
+	Attempts  int               `json:"attempts"`
+	Status    DeadLetterStatus  `json:"status"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// DeadLetterStore holds messages that failed processing until they are
+// redriven or purged
+type DeadLetterStore interface {
+	AddDeadLetter(ctx context.Context, letter *DeadLetter) error
+	GetDeadLetter(ctx context.Context, id string) (*DeadLetter, error)
+	ListDeadLetters(ctx context.Context, status DeadLetterStatus, limit, offset int) ([]*DeadLetter, error)
+	MarkDeadLetterRedriven(ctx context.Context, id string) error
+	RecordDeadLetterFailure(ctx context.Context, id, reason string) error
+	DeleteDeadLetters(ctx context.Context, ids []string) (int64, error)
+}
+
+type postgresDeadLetterStore struct {
+	db *sql.DB
+}
+
+// NewPostgresDeadLetterStore creates a new PostgreSQL dead letter store
+func NewPostgresDeadLetterStore(db *sql.DB) DeadLetterStore {
+	return &postgresDeadLetterStore{db: db}
+}
+
+// AddDeadLetter records a failed message. A message that is dead-lettered
+// again (e.g. after a replay) updates the existing entry.
+func (s *postgresDeadLetterStore) AddDeadLetter(ctx context.Context, letter *DeadLetter) error {
+	if letter.ID == "" {
+		letter.ID = uuid.New().String()
+	}
+	now := time.Now()
+	letter.CreatedAt = now
+	letter.UpdatedAt = now
+	letter.Status = DeadLetterPending
+	letter.Attempts = 1
+
+	headers, err := json.Marshal(letter.Headers)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO dead_letters (
+			id, topic, partition, "offset", message_key, payload, headers,
+			event_type, error, attempts, status, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (topic, partition, "offset") DO UPDATE
+		SET error = EXCLUDED.error,
+			attempts = dead_letters.attempts + 1,
+			status = EXCLUDED.status,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err = s.db.ExecContext(ctx, query,
+		letter.ID, letter.Topic, letter.Partition, letter.Offset, letter.Key,
+		letter.Payload, headers, letter.EventType, letter.Error, letter.Attempts,
+		letter.Status, letter.CreatedAt, letter.UpdatedAt,
+	)
+
+	return err
+}
+
+// GetDeadLetter retrieves a dead letter by ID
+func (s *postgresDeadLetterStore) GetDeadLetter(ctx context.Context, id string) (*DeadLetter, error) {
+	query := `
+		SELECT id, topic, partition, "offset", message_key, payload, headers,
+			   event_type, error, attempts, status, created_at, updated_at
+		FROM dead_letters WHERE id = $1
+	`
+
+	letter, err := scanDeadLetter(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+
+	return letter, err
+}
+
+// ListDeadLetters lists dead letters with the given status, oldest first
+func (s *postgresDeadLetterStore) ListDeadLetters(ctx context.Context, status DeadLetterStatus, limit, offset int) ([]*DeadLetter, error) {
+	query := `
+		SELECT id, topic, partition, "offset", message_key, payload, headers,
+			   event_type, error, attempts, status, created_at, updated_at
+		FROM dead_letters
+		WHERE status = $1
+		ORDER BY created_at
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var letters []*DeadLetter
+	for rows.Next() {
+		letter, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+
+	return letters, rows.Err()
+}
+
+// MarkDeadLetterRedriven marks a dead letter as successfully reprocessed
+func (s *postgresDeadLetterStore) MarkDeadLetterRedriven(ctx context.Context, id string) error {
+	query := `
+		UPDATE dead_letters
+		SET status = $1, attempts = attempts + 1, updated_at = $2
+		WHERE id = $3
+	`
+
+	return s.execOne(ctx, query, DeadLetterRedriven, time.Now(), id)
+}
+
+// RecordDeadLetterFailure records a failed redrive attempt
+func (s *postgresDeadLetterStore) RecordDeadLetterFailure(ctx context.Context, id, reason string) error {
+	query := `
+		UPDATE dead_letters
+		SET error = $1, attempts = attempts + 1, updated_at = $2
+		WHERE id = $3
+	`
+
+	return s.execOne(ctx, query, reason, time.Now(), id)
+}
+
+// DeleteDeadLetters purges dead letters and returns how many were removed
+func (s *postgresDeadLetterStore) DeleteDeadLetters(ctx context.Context, ids []string) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM dead_letters WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (s *postgresDeadLetterStore) execOne(ctx context.Context, query string, args ...interface{}) error {
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDeadLetter(row rowScanner) (*DeadLetter, error) {
+	letter := &DeadLetter{}
+	var headers []byte
+
+	err := row.Scan(
+		&letter.ID, &letter.Topic, &letter.Partition, &letter.Offset, &letter.Key,
+		&letter.Payload, &headers, &letter.EventType, &letter.Error, &letter.Attempts,
+		&letter.Status, &letter.CreatedAt, &letter.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(headers) > 0 {
+		if err := json.Unmarshal(headers, &letter.Headers); err != nil {
+			return nil, err
+		}
+	}
+
+	return letter, nil
+}
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// DigestItem is a low-priority notification held for the next digest email
+type DigestItem struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id,omitempty"`
+	Recipient string     `json:"recipient"`
+	EventType string     `json:"event_type"`
+	Template  string     `json:"template"`
+	Subject   string     `json:"subject"`
+	Timezone  string     `json:"timezone,omitempty"`
+	BrandID   string     `json:"brand_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+}
+
+// DigestStore holds pending digest items until they are sent
+type DigestStore interface {
+	AddDigestItem(ctx context.Context, item *DigestItem) error
+	ListDueDigestRecipients(ctx context.Context, olderThan time.Time) ([]string, error)
+	ListPendingDigestItems(ctx context.Context, recipient string) ([]*DigestItem, error)
+	MarkDigestItemsSent(ctx context.Context, ids []string) error
+}
+
+type postgresDigestStore struct {
+	db *sql.DB
+}
+
+// NewPostgresDigestStore creates a new PostgreSQL digest store
+func NewPostgresDigestStore(db *sql.DB) DigestStore {
+	return &postgresDigestStore{db: db}
+}
+
+// AddDigestItem queues an item for the recipient's next digest
+func (s *postgresDigestStore) AddDigestItem(ctx context.Context, item *DigestItem) error {
+	if item.ID == "" {
+		item.ID = uuid.New().String()
+	}
+	item.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO digest_items (id, user_id, recipient, event_type, template, subject, timezone, brand_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := s.db.ExecContext(ctx, query,
+		item.ID, item.UserID, item.Recipient, item.EventType,
+		item.Template, item.Subject, item.Timezone, item.BrandID, item.CreatedAt,
+	)
+
+	return err
+}
+
+// ListDueDigestRecipients returns recipients whose oldest pending item was
+// queued before the given time, i.e. whose digest window has closed
+func (s *postgresDigestStore) ListDueDigestRecipients(ctx context.Context, olderThan time.Time) ([]string, error) {
+	query := `
+		SELECT recipient
+		FROM digest_items
+		WHERE sent_at IS NULL
+		GROUP BY recipient
+		HAVING MIN(created_at) <= $1
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, olderThan)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []string
+	for rows.Next() {
+		var recipient string
+		if err := rows.Scan(&recipient); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	return recipients, rows.Err()
+}
+
+// ListPendingDigestItems returns unsent items for a recipient, oldest first
+func (s *postgresDigestStore) ListPendingDigestItems(ctx context.Context, recipient string) ([]*DigestItem, error) {
+	query := `
+		SELECT id, user_id, recipient, event_type, template, subject, timezone, brand_id, created_at, sent_at
+		FROM digest_items
+		WHERE recipient = $1 AND sent_at IS NULL
+		ORDER BY created_at ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, recipient)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*DigestItem
+	for rows.Next() {
+		item := &DigestItem{}
+		err := rows.Scan(
+			&item.ID, &item.UserID, &item.Recipient, &item.EventType,
+			&item.Template, &item.Subject, &item.Timezone, &item.BrandID, &item.CreatedAt, &item.SentAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// MarkDigestItemsSent marks items as included in a sent digest
+func (s *postgresDigestStore) MarkDigestItemsSent(ctx context.Context, ids []string) error {
+	query := `UPDATE digest_items SET sent_at = $1 WHERE id = ANY($2)`
+
+	_, err := s.db.ExecContext(ctx, query, time.Now(), pq.Array(ids))
+	return err
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InboxItem is an in-app notification shown in the user's storefront feed
+type InboxItem struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	EventType string     `json:"event_type"`
+	Template  string     `json:"template,omitempty"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	Link      string     `json:"link,omitempty"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// InboxStore persists per-user in-app notifications
+type InboxStore interface {
+	CreateInboxItem(ctx context.Context, item *InboxItem) error
+	ListInboxItems(ctx context.Context, userID string, unreadOnly bool, limit, offset int) ([]*InboxItem, error)
+	CountUnread(ctx context.Context, userID string) (int, error)
+	MarkRead(ctx context.Context, userID, id string) error
+	MarkAllRead(ctx context.Context, userID string) (int64, error)
+}
+
+type postgresInboxStore struct {
+	db *sql.DB
+}
+
+// NewPostgresInboxStore creates a new PostgreSQL inbox store
+func NewPostgresInboxStore(db *sql.DB) InboxStore {
+	return &postgresInboxStore{db: db}
+}
+
+// CreateInboxItem adds an item to a user's inbox
+func (s *postgresInboxStore) CreateInboxItem(ctx context.Context, item *InboxItem) error {
+	if item.ID == "" {
+		item.ID = uuid.New().String()
+	}
+	item.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO inbox_items (id, user_id, event_type, template, title, body, link, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := s.db.ExecContext(ctx, query,
+		item.ID, item.UserID, item.EventType, item.Template,
+		item.Title, item.Body, item.Link, item.CreatedAt,
+	)
+
+	return err
+}
+
+// ListInboxItems lists a user's inbox, newest first
+func (s *postgresInboxStore) ListInboxItems(ctx context.Context, userID string, unreadOnly bool, limit, offset int) ([]*InboxItem, error) {
+	query := `
+		SELECT id, user_id, event_type, template, title, body, link, read_at, created_at
+		FROM inbox_items
+		WHERE user_id = $1 AND ($2 = FALSE OR read_at IS NULL)
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*InboxItem{}
+	for rows.Next() {
+		item := &InboxItem{}
+		var readAt sql.NullTime
+		err := rows.Scan(
+			&item.ID, &item.UserID, &item.EventType, &item.Template,
+			&item.Title, &item.Body, &item.Link, &readAt, &item.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if readAt.Valid {
+			item.ReadAt = &readAt.Time
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// CountUnread counts a user's unread inbox items
+func (s *postgresInboxStore) CountUnread(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM inbox_items WHERE user_id = $1 AND read_at IS NULL`,
+		userID,
+	).Scan(&count)
+
+	return count, err
+}
+
+// MarkRead marks one of a user's inbox items as read. Items that are
+// already read keep their original read time.
+func (s *postgresInboxStore) MarkRead(ctx context.Context, userID, id string) error {
+	query := `
+		UPDATE inbox_items
+		SET read_at = COALESCE(read_at, $1)
+		WHERE id = $2 AND user_id = $3
+	`
+
+	result, err := s.db.ExecContext(ctx, query, time.Now(), id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// MarkAllRead marks all of a user's unread items as read and returns how
+// many were updated
+func (s *postgresInboxStore) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE inbox_items SET read_at = $1 WHERE user_id = $2 AND read_at IS NULL`,
+		time.Now(), userID,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Manual send statuses
+const (
+	ManualSendSent   = "sent"
+	ManualSendFailed = "failed"
+)
+
+// ManualSend is the audit record of a notification a support agent sent
+// through the API
+type ManualSend struct {
+	ID               string    `json:"id"`
+	Template         string    `json:"template"`
+	EventType        string    `json:"event_type"`
+	UserID           string    `json:"user_id,omitempty"`
+	OrderID          string    `json:"order_id,omitempty"`
+	Recipient        string    `json:"recipient,omitempty"`
+	RequestedBy      string    `json:"requested_by"`
+	RequestedByEmail string    `json:"requested_by_email,omitempty"`
+	RequestedByRole  string    `json:"requested_by_role,omitempty"`
+	Reason           string    `json:"reason"`
+	TicketID         string    `json:"ticket_id,omitempty"`
+	Status           string    `json:"status"`
+	Error            string    `json:"error,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// ManualSendStore keeps the audit log of manual sends
+type ManualSendStore interface {
+	CreateManualSend(ctx context.Context, m *ManualSend) error
+	ListManualSends(ctx context.Context, orderID, userID string, limit, offset int) ([]*ManualSend, error)
+}
+
+type postgresManualSendStore struct {
+	db *sql.DB
+}
+
+// NewPostgresManualSendStore creates a new PostgreSQL manual send audit store
+func NewPostgresManualSendStore(db *sql.DB) ManualSendStore {
+	return &postgresManualSendStore{db: db}
+}
+
+// CreateManualSend records a manual send
+func (s *postgresManualSendStore) CreateManualSend(ctx context.Context, m *ManualSend) error {
+	if m.ID == "" {
+		m.ID = uuid.New().String()
+	}
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO manual_sends (
+			id, template, event_type, user_id, order_id, recipient, requested_by,
+			requested_by_email, requested_by_role, reason, ticket_id, status, error, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	_, err := s.db.ExecContext(ctx, query,
+		m.ID, m.Template, m.EventType, m.UserID, m.OrderID, m.Recipient, m.RequestedBy,
+		m.RequestedByEmail, m.RequestedByRole, m.Reason, m.TicketID, m.Status, m.Error, m.CreatedAt,
+	)
+
+	return err
+}
+
+// ListManualSends returns manual sends newest first, optionally only those
+// for one order or one customer
+func (s *postgresManualSendStore) ListManualSends(ctx context.Context, orderID, userID string, limit, offset int) ([]*ManualSend, error) {
+	query := `
+		SELECT id, template, event_type, user_id, order_id, recipient, requested_by,
+		       requested_by_email, requested_by_role, reason, ticket_id, status, error, created_at
+		FROM manual_sends
+		WHERE ($1 = '' OR order_id = $1) AND ($2 = '' OR user_id = $2)
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, orderID, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sends []*ManualSend
+	for rows.Next() {
+		m := &ManualSend{}
+		err := rows.Scan(
+			&m.ID, &m.Template, &m.EventType, &m.UserID, &m.OrderID, &m.Recipient, &m.RequestedBy,
+			&m.RequestedByEmail, &m.RequestedByRole, &m.Reason, &m.TicketID, &m.Status, &m.Error, &m.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sends = append(sends, m)
+	}
+
+	return sends, rows.Err()
+}
@@ -0,0 +1,379 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a new PostgreSQL notification store
+func NewPostgresStore(db *sql.DB) NotificationStore {
+	return &postgresStore{db: db}
+}
+
+// Create records a notification attempt
+func (s *postgresStore) Create(ctx context.Context, n *Notification) error {
+	if n.ID == "" {
+		n.ID = uuid.New().String()
+	}
+
+	now := time.Now()
+	n.CreatedAt = now
+	n.UpdatedAt = now
+
+	query := `
+		INSERT INTO notifications (
+			id, event_type, channel, template, recipient, user_id, order_id,
+			status, reason, provider, provider_message_id, event_key, variant, segments, cost,
+			triggered_by, correlation_id, brand_id, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
+	`
+
+	_, err := s.db.ExecContext(ctx, query,
+		n.ID, n.EventType, n.Channel, n.Template, n.Recipient, n.UserID, n.OrderID,
+		n.Status, n.Reason, n.Provider, n.MessageID, n.EventKey, n.Variant, n.Segments, n.Cost,
+		n.TriggeredBy, n.CorrelationID, n.BrandID, n.CreatedAt, n.UpdatedAt,
+	)
+
+	return err
+}
+
+// GetByID retrieves a notification by ID
+func (s *postgresStore) GetByID(ctx context.Context, id string) (*Notification, error) {
+	query := `
+		SELECT id, event_type, channel, template, recipient, user_id, order_id,
+			   status, reason, provider, provider_message_id, event_key, variant, segments, cost,
+			   triggered_by, correlation_id, brand_id, opened_at, clicked_at, created_at, updated_at
+		FROM notifications WHERE id = $1
+	`
+
+	n := &Notification{}
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&n.ID, &n.EventType, &n.Channel, &n.Template, &n.Recipient, &n.UserID, &n.OrderID,
+		&n.Status, &n.Reason, &n.Provider, &n.MessageID, &n.EventKey, &n.Variant, &n.Segments, &n.Cost,
+		&n.TriggeredBy, &n.CorrelationID, &n.BrandID, &n.OpenedAt, &n.ClickedAt, &n.CreatedAt, &n.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+
+	return n, err
+}
+
+// ListByUserID retrieves a user's notification history with pagination
+func (s *postgresStore) ListByUserID(ctx context.Context, userID string, limit, offset int) ([]*Notification, error) {
+	query := `
+		SELECT id, event_type, channel, template, recipient, user_id, order_id,
+			   status, reason, provider, provider_message_id, event_key, variant, segments, cost,
+			   triggered_by, correlation_id, brand_id, opened_at, clicked_at, created_at, updated_at
+		FROM notifications
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*Notification
+	for rows.Next() {
+		n := &Notification{}
+		err := rows.Scan(
+			&n.ID, &n.EventType, &n.Channel, &n.Template, &n.Recipient, &n.UserID, &n.OrderID,
+			&n.Status, &n.Reason, &n.Provider, &n.MessageID, &n.EventKey, &n.Variant, &n.Segments, &n.Cost,
+			&n.TriggeredBy, &n.CorrelationID, &n.BrandID, &n.OpenedAt, &n.ClickedAt, &n.CreatedAt, &n.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+
+	return notifications, rows.Err()
+}
+
+// ListByOrderID retrieves an order's notification history with pagination
+func (s *postgresStore) ListByOrderID(ctx context.Context, orderID string, limit, offset int) ([]*Notification, error) {
+	query := `
+		SELECT id, event_type, channel, template, recipient, user_id, order_id,
+			   status, reason, provider, provider_message_id, event_key, variant, segments, cost,
+			   triggered_by, correlation_id, brand_id, opened_at, clicked_at, created_at, updated_at
+		FROM notifications
+		WHERE order_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, orderID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*Notification
+	for rows.Next() {
+		n := &Notification{}
+		err := rows.Scan(
+			&n.ID, &n.EventType, &n.Channel, &n.Template, &n.Recipient, &n.UserID, &n.OrderID,
+			&n.Status, &n.Reason, &n.Provider, &n.MessageID, &n.EventKey, &n.Variant, &n.Segments, &n.Cost,
+			&n.TriggeredBy, &n.CorrelationID, &n.BrandID, &n.OpenedAt, &n.ClickedAt, &n.CreatedAt, &n.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+
+	return notifications, rows.Err()
+}
+
+// ListByEventKey retrieves every notification recorded for one event, oldest
+// first
+func (s *postgresStore) ListByEventKey(ctx context.Context, eventKey string) ([]*Notification, error) {
+	query := `
+		SELECT id, event_type, channel, template, recipient, user_id, order_id,
+			   status, reason, provider, provider_message_id, event_key, variant, segments, cost,
+			   triggered_by, correlation_id, brand_id, opened_at, clicked_at, created_at, updated_at
+		FROM notifications
+		WHERE event_key = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, eventKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*Notification
+	for rows.Next() {
+		n := &Notification{}
+		err := rows.Scan(
+			&n.ID, &n.EventType, &n.Channel, &n.Template, &n.Recipient, &n.UserID, &n.OrderID,
+			&n.Status, &n.Reason, &n.Provider, &n.MessageID, &n.EventKey, &n.Variant, &n.Segments, &n.Cost,
+			&n.TriggeredBy, &n.CorrelationID, &n.BrandID, &n.OpenedAt, &n.ClickedAt, &n.CreatedAt, &n.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+
+	return notifications, rows.Err()
+}
+
+// UpdateStatus updates the delivery status of a notification
+func (s *postgresStore) UpdateStatus(ctx context.Context, id string, status Status, reason string) error {
+	query := `
+		UPDATE notifications
+		SET status = $1, reason = $2, updated_at = $3
+		WHERE id = $4
+	`
+
+	result, err := s.db.ExecContext(ctx, query, status, reason, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// UpdateStatusByMessageID updates the delivery status of a notification
+// identified by the provider's message ID, as reported by delivery callbacks
+func (s *postgresStore) UpdateStatusByMessageID(ctx context.Context, provider, messageID string, status Status, reason string) error {
+	query := `
+		UPDATE notifications
+		SET status = $1, reason = $2, updated_at = $3
+		WHERE provider = $4 AND provider_message_id = $5
+	`
+
+	result, err := s.db.ExecContext(ctx, query, status, reason, time.Now(), provider, messageID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetByMessageID retrieves a notification by the provider's message ID
+func (s *postgresStore) GetByMessageID(ctx context.Context, provider, messageID string) (*Notification, error) {
+	query := `
+		SELECT id, event_type, channel, template, recipient, user_id, order_id,
+			   status, reason, provider, provider_message_id, event_key, variant, segments, cost,
+			   triggered_by, correlation_id, brand_id, opened_at, clicked_at, created_at, updated_at
+		FROM notifications WHERE provider = $1 AND provider_message_id = $2
+	`
+
+	n := &Notification{}
+	err := s.db.QueryRowContext(ctx, query, provider, messageID).Scan(
+		&n.ID, &n.EventType, &n.Channel, &n.Template, &n.Recipient, &n.UserID, &n.OrderID,
+		&n.Status, &n.Reason, &n.Provider, &n.MessageID, &n.EventKey, &n.Variant, &n.Segments, &n.Cost,
+		&n.TriggeredBy, &n.CorrelationID, &n.BrandID, &n.OpenedAt, &n.ClickedAt, &n.CreatedAt, &n.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+
+	return n, err
+}
+
+// HasDelivered reports whether a notification from the given source event was
+// already sent, delivered, queued for a digest or scheduled on a channel and template
+func (s *postgresStore) HasDelivered(ctx context.Context, eventKey string, channel Channel, template string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM notifications
+			WHERE event_key = $1 AND channel = $2 AND template = $3
+			  AND status IN ($4, $5, $6, $7)
+		)
+	`
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx, query, eventKey, channel, template,
+		StatusSent, StatusDelivered, StatusDigested, StatusScheduled,
+	).Scan(&exists)
+
+	return exists, err
+}
+
+// RecordEngagement records the first open or click of a notification
+// identified by the provider's message ID. An open is implied by a click.
+func (s *postgresStore) RecordEngagement(ctx context.Context, provider, messageID string, engagement Engagement) error {
+	var query string
+	switch engagement {
+	case EngagementOpen:
+		query = `
+			UPDATE notifications
+			SET opened_at = COALESCE(opened_at, $1), updated_at = $1
+			WHERE provider = $2 AND provider_message_id = $3
+		`
+	case EngagementClick:
+		query = `
+			UPDATE notifications
+			SET opened_at = COALESCE(opened_at, $1), clicked_at = COALESCE(clicked_at, $1), updated_at = $1
+			WHERE provider = $2 AND provider_message_id = $3
+		`
+	default:
+		return fmt.Errorf("unknown engagement: %s", engagement)
+	}
+
+	result, err := s.db.ExecContext(ctx, query, time.Now(), provider, messageID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// RecordClick records a click on a tracked link of a notification and marks
+// it clicked (and opened) if it wasn't already
+func (s *postgresStore) RecordClick(ctx context.Context, id, url string) error {
+	now := time.Now()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE notifications
+		SET opened_at = COALESCE(opened_at, $1), clicked_at = COALESCE(clicked_at, $1), updated_at = $1
+		WHERE id = $2
+	`, now, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO notification_clicks (notification_id, url, clicked_at) VALUES ($1, $2, $3)`,
+		id, url, now,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// VariantStats counts sends and engagement per variant of a template since
+// the given time. Only notifications sent as part of an A/B test are counted.
+func (s *postgresStore) VariantStats(ctx context.Context, template string, since time.Time) ([]VariantStats, error) {
+	query := `
+		SELECT variant,
+		       COUNT(*) FILTER (WHERE status IN ($3, $4)),
+		       COUNT(*) FILTER (WHERE status = $4),
+		       COUNT(*) FILTER (WHERE status = $5),
+		       COUNT(opened_at),
+		       COUNT(clicked_at)
+		FROM notifications
+		WHERE template = $1 AND variant <> '' AND created_at >= $2
+		GROUP BY variant
+		ORDER BY variant
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, template, since, StatusSent, StatusDelivered, StatusFailed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []VariantStats
+	for rows.Next() {
+		var v VariantStats
+		if err := rows.Scan(&v.Variant, &v.Sent, &v.Delivered, &v.Failed, &v.Opened, &v.Clicked); err != nil {
+			return nil, err
+		}
+		stats = append(stats, v)
+	}
+
+	return stats, rows.Err()
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ScheduledNotification is a rendered notification held until release_at,
+// e.g. a marketing email deferred until the recipient's quiet hours end
+type ScheduledNotification struct {
+	ID            string     `json:"id"`
+	Channel       Channel    `json:"channel"`
+	Recipient     string     `json:"recipient"`
+	UserID        string     `json:"user_id,omitempty"`
+	OrderID       string     `json:"order_id,omitempty"`
+	EventType     string     `json:"event_type"`
+	EventKey      string     `json:"event_key,omitempty"`
+	Template      string     `json:"template"`
+	Variant       string     `json:"variant,omitempty"`
+	Subject       string     `json:"subject,omitempty"`
+	Body          string     `json:"body"`
+	ReleaseAt     time.Time  `json:"release_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CorrelationID string     `json:"correlation_id,omitempty"`
+	BrandID       string     `json:"brand_id,omitempty"`
+}
+
+// ScheduledStore holds deferred notifications until they are released
+type ScheduledStore interface {
+	ScheduleNotification(ctx context.Context, n *ScheduledNotification) error
+	ListDueScheduled(ctx context.Context, now time.Time, limit int) ([]*ScheduledNotification, error)
+	MarkScheduledSent(ctx context.Context, id string) error
+	CancelScheduled(ctx context.Context, orderID, eventType string) (int64, error)
+}
+
+type postgresScheduledStore struct {
+	db *sql.DB
+}
+
+// NewPostgresScheduledStore creates a new PostgreSQL scheduled notification store
+func NewPostgresScheduledStore(db *sql.DB) ScheduledStore {
+	return &postgresScheduledStore{db: db}
+}
+
+// ScheduleNotification holds a notification until its release time. Release
+// times are stored in UTC since the column has no timezone.
+func (s *postgresScheduledStore) ScheduleNotification(ctx context.Context, n *ScheduledNotification) error {
+	if n.ID == "" {
+		n.ID = uuid.New().String()
+	}
+	n.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO scheduled_notifications (
+			id, channel, recipient, user_id, order_id, event_type, event_key,
+			template, variant, subject, body, release_at, created_at, correlation_id, brand_id
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`
+
+	_, err := s.db.ExecContext(ctx, query,
+		n.ID, n.Channel, n.Recipient, n.UserID, n.OrderID, n.EventType, n.EventKey,
+		n.Template, n.Variant, n.Subject, n.Body, n.ReleaseAt.UTC(), n.CreatedAt, n.CorrelationID, n.BrandID,
+	)
+
+	return err
+}
+
+// ListDueScheduled returns unsent notifications whose release time has
+// passed, oldest release first
+func (s *postgresScheduledStore) ListDueScheduled(ctx context.Context, now time.Time, limit int) ([]*ScheduledNotification, error) {
+	query := `
+		SELECT id, channel, recipient, user_id, order_id, event_type, event_key,
+		       template, variant, subject, body, release_at, created_at, sent_at, correlation_id, brand_id
+		FROM scheduled_notifications
+		WHERE sent_at IS NULL AND release_at <= $1
+		ORDER BY release_at ASC
+		LIMIT $2
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scheduled []*ScheduledNotification
+	for rows.Next() {
+		n := &ScheduledNotification{}
+		err := rows.Scan(
+			&n.ID, &n.Channel, &n.Recipient, &n.UserID, &n.OrderID, &n.EventType, &n.EventKey,
+			&n.Template, &n.Variant, &n.Subject, &n.Body, &n.ReleaseAt, &n.CreatedAt, &n.SentAt,
+			&n.CorrelationID, &n.BrandID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		scheduled = append(scheduled, n)
+	}
+
+	return scheduled, rows.Err()
+}
+
+// MarkScheduledSent marks a scheduled notification as released
+func (s *postgresScheduledStore) MarkScheduledSent(ctx context.Context, id string) error {
+	query := `UPDATE scheduled_notifications SET sent_at = $1 WHERE id = $2 AND sent_at IS NULL`
+
+	result, err := s.db.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// CancelScheduled drops an order's unsent notifications of an event type,
+// e.g. a review request held for quiet hours once the customer has
+// reviewed the order, and returns how many were dropped
+func (s *postgresScheduledStore) CancelScheduled(ctx context.Context, orderID, eventType string) (int64, error) {
+	query := `DELETE FROM scheduled_notifications WHERE order_id = $1 AND event_type = $2 AND sent_at IS NULL`
+
+	result, err := s.db.ExecContext(ctx, query, orderID, eventType)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// SMSSpend is what was spent on SMS to one country on one UTC day
+type SMSSpend struct {
+	Day      time.Time `json:"day"`
+	Country  string    `json:"country"`
+	Messages int       `json:"messages"`
+	Segments int       `json:"segments"`
+	Cost     float64   `json:"cost"` // USD
+}
+
+// SMSSpendStore aggregates SMS spend per day and country
+type SMSSpendStore interface {
+	AddSMSSpend(ctx context.Context, day time.Time, country string, segments int, cost float64) error
+	SMSSpendTotal(ctx context.Context, day time.Time) (float64, error)
+	ListSMSSpend(ctx context.Context, from, to time.Time) ([]*SMSSpend, error)
+}
+
+type postgresSMSSpendStore struct {
+	db *sql.DB
+}
+
+// NewPostgresSMSSpendStore creates a new PostgreSQL SMS spend store
+func NewPostgresSMSSpendStore(db *sql.DB) SMSSpendStore {
+	return &postgresSMSSpendStore{db: db}
+}
+
+// AddSMSSpend adds one sent message to the day's spend for a country
+func (s *postgresSMSSpendStore) AddSMSSpend(ctx context.Context, day time.Time, country string, segments int, cost float64) error {
+	query := `
+		INSERT INTO sms_spend (day, country, messages, segments, cost, updated_at)
+		VALUES ($1, $2, 1, $3, $4, $5)
+		ON CONFLICT (day, country) DO UPDATE SET
+			messages = sms_spend.messages + 1,
+			segments = sms_spend.segments + EXCLUDED.segments,
+			cost = sms_spend.cost + EXCLUDED.cost,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := s.db.ExecContext(ctx, query, day.UTC().Format("2006-01-02"), country, segments, cost, time.Now())
+	return err
+}
+
+// SMSSpendTotal returns the USD spent on SMS to all countries on a UTC day
+func (s *postgresSMSSpendStore) SMSSpendTotal(ctx context.Context, day time.Time) (float64, error) {
+	query := `SELECT COALESCE(SUM(cost), 0) FROM sms_spend WHERE day = $1`
+
+	var total float64
+	err := s.db.QueryRowContext(ctx, query, day.UTC().Format("2006-01-02")).Scan(&total)
+	return total, err
+}
+
+// ListSMSSpend returns spend per day and country for the UTC days from..to
+// inclusive, newest day first
+func (s *postgresSMSSpendStore) ListSMSSpend(ctx context.Context, from, to time.Time) ([]*SMSSpend, error) {
+	query := `
+		SELECT day, country, messages, segments, cost
+		FROM sms_spend
+		WHERE day BETWEEN $1 AND $2
+		ORDER BY day DESC, cost DESC, country
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, from.UTC().Format("2006-01-02"), to.UTC().Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var spend []*SMSSpend
+	for rows.Next() {
+		row := &SMSSpend{}
+		if err := rows.Scan(&row.Day, &row.Country, &row.Messages, &row.Segments, &row.Cost); err != nil {
+			return nil, err
+		}
+		spend = append(spend, row)
+	}
+
+	return spend, rows.Err()
+}
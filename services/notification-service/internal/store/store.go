@@ -0,0 +1,94 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Channel identifies the delivery channel of a notification
+type Channel string
+
+const (
+	ChannelEmail    Channel = "email"
+	ChannelSMS      Channel = "sms"
+	ChannelInApp    Channel = "in_app"
+	ChannelWhatsApp Channel = "whatsapp"
+)
+
+// Status represents the delivery status of a notification
+type Status string
+
+const (
+	StatusSent       Status = "sent"
+	StatusFailed     Status = "failed"
+	StatusSuppressed Status = "suppressed"
+	StatusDigested   Status = "digested"
+	StatusScheduled  Status = "scheduled"
+	StatusDelivered  Status = "delivered"
+)
+
+// Notification is a record of a single notification attempt
+type Notification struct {
+	ID            string     `json:"id"`
+	EventType     string     `json:"event_type"`
+	Channel       Channel    `json:"channel"`
+	Template      string     `json:"template"`
+	Recipient     string     `json:"recipient"`
+	UserID        string     `json:"user_id,omitempty"`
+	OrderID       string     `json:"order_id,omitempty"`
+	Status        Status     `json:"status"`
+	Reason        string     `json:"reason,omitempty"`
+	Provider      string     `json:"provider,omitempty"`
+	MessageID     string     `json:"provider_message_id,omitempty"`
+	EventKey      string     `json:"event_key,omitempty"`
+	Variant       string     `json:"variant,omitempty"`        // template A/B test variant, if any
+	Segments      int        `json:"segments,omitempty"`       // billed SMS segments
+	Cost          float64    `json:"cost,omitempty"`           // SMS cost in USD
+	TriggeredBy   string     `json:"triggered_by,omitempty"`   // who sent a manual notification, e.g. support:<user_id>
+	CorrelationID string     `json:"correlation_id,omitempty"` // of the event that caused the notification
+	BrandID       string     `json:"brand_id,omitempty"`       // storefront brand, empty for the default
+	OpenedAt      *time.Time `json:"opened_at,omitempty"`
+	ClickedAt     *time.Time `json:"clicked_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Engagement is a recipient interaction reported by a provider webhook
+type Engagement string
+
+const (
+	EngagementOpen  Engagement = "open"
+	EngagementClick Engagement = "click"
+)
+
+// VariantStats counts outcomes of one template variant
+type VariantStats struct {
+	Variant   string `json:"variant"`
+	Sent      int    `json:"sent"`
+	Delivered int    `json:"delivered"`
+	Failed    int    `json:"failed"`
+	Opened    int    `json:"opened"`
+	Clicked   int    `json:"clicked"`
+}
+
+// Common errors
+var (
+	ErrNotFound = errors.New("notification not found")
+)
+
+// NotificationStore persists notification history
+type NotificationStore interface {
+	Create(ctx context.Context, notification *Notification) error
+	GetByID(ctx context.Context, id string) (*Notification, error)
+	ListByUserID(ctx context.Context, userID string, limit, offset int) ([]*Notification, error)
+	ListByOrderID(ctx context.Context, orderID string, limit, offset int) ([]*Notification, error)
+	ListByEventKey(ctx context.Context, eventKey string) ([]*Notification, error)
+	UpdateStatus(ctx context.Context, id string, status Status, reason string) error
+	UpdateStatusByMessageID(ctx context.Context, provider, messageID string, status Status, reason string) error
+	GetByMessageID(ctx context.Context, provider, messageID string) (*Notification, error)
+	HasDelivered(ctx context.Context, eventKey string, channel Channel, template string) (bool, error)
+	RecordEngagement(ctx context.Context, provider, messageID string, engagement Engagement) error
+	RecordClick(ctx context.Context, id, url string) error
+	VariantStats(ctx context.Context, template string, since time.Time) ([]VariantStats, error)
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Suppression reasons
+const (
+	SuppressionBounce      = "bounce"
+	SuppressionComplaint   = "complaint"
+	SuppressionUnsubscribe = "unsubscribe"
+	SuppressionManual      = "manual"
+)
+
+// Suppression is an email address that bulk email is never sent to
+type Suppression struct {
+	Address   string    `json:"address"`
+	Reason    string    `json:"reason"`
+	Source    string    `json:"source,omitempty"` // e.g. sendgrid or the user who added it
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SuppressionStore keeps the suppression list. Addresses are compared
+// case-insensitively.
+type SuppressionStore interface {
+	AddSuppression(ctx context.Context, suppression *Suppression) error
+	RemoveSuppression(ctx context.Context, address string) error
+	ListSuppressions(ctx context.Context, limit, offset int) ([]*Suppression, error)
+	Suppressed(ctx context.Context, addresses []string) (map[string]string, error)
+}
+
+type postgresSuppressionStore struct {
+	db *sql.DB
+}
+
+// NewPostgresSuppressionStore creates a new PostgreSQL suppression store
+func NewPostgresSuppressionStore(db *sql.DB) SuppressionStore {
+	return &postgresSuppressionStore{db: db}
+}
+
+// AddSuppression suppresses an address. An address that is already
+// suppressed keeps its original reason.
+func (s *postgresSuppressionStore) AddSuppression(ctx context.Context, suppression *Suppression) error {
+	suppression.Address = strings.ToLower(strings.TrimSpace(suppression.Address))
+	suppression.CreatedAt = time.Now()
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO suppressions (address, reason, source, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (address) DO NOTHING
+	`, suppression.Address, suppression.Reason, suppression.Source, suppression.CreatedAt)
+
+	return err
+}
+
+// RemoveSuppression lifts the suppression of an address
+func (s *postgresSuppressionStore) RemoveSuppression(ctx context.Context, address string) error {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM suppressions WHERE address = $1`,
+		strings.ToLower(strings.TrimSpace(address)),
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// ListSuppressions returns suppressed addresses, newest first
+func (s *postgresSuppressionStore) ListSuppressions(ctx context.Context, limit, offset int) ([]*Suppression, error) {
+	query := `
+		SELECT address, reason, source, created_at
+		FROM suppressions
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var suppressions []*Suppression
+	for rows.Next() {
+		suppression := &Suppression{}
+		if err := rows.Scan(&suppression.Address, &suppression.Reason, &suppression.Source, &suppression.CreatedAt); err != nil {
+			return nil, err
+		}
+		suppressions = append(suppressions, suppression)
+	}
+
+	return suppressions, rows.Err()
+}
+
+// Suppressed returns the reason of every suppressed address among the given
+// ones, keyed by the lower-cased address
+func (s *postgresSuppressionStore) Suppressed(ctx context.Context, addresses []string) (map[string]string, error) {
+	lowered := make([]string, len(addresses))
+	for i, address := range addresses {
+		lowered[i] = strings.ToLower(strings.TrimSpace(address))
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT address, reason FROM suppressions WHERE address = ANY($1)`,
+		pq.Array(lowered),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suppressed := make(map[string]string)
+	for rows.Next() {
+		var address, reason string
+		if err := rows.Scan(&address, &reason); err != nil {
+			return nil, err
+		}
+		suppressed[address] = reason
+	}
+
+	return suppressed, rows.Err()
+}
@@ -0,0 +1,133 @@
+package subscriptions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ecommerce/notification-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// Subscriber is a customer who asked to be told when a product is back in stock
+type Subscriber struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+	Name   string `json:"name"`
+	Phone  string `json:"phone"`
+}
+
+// ID identifies a subscriber: their user ID, else their email address
+func (s Subscriber) ID() string {
+	if s.UserID != "" {
+		return s.UserID
+	}
+	return strings.ToLower(s.Email)
+}
+
+// Client fetches a product's back-in-stock subscribers from the service that
+// owns them
+type Client struct {
+	urlTemplate string
+	apiKey      string
+	httpClient  *http.Client
+	logger      *zap.Logger
+}
+
+// NewClient creates a new subscriptions client, calling through transport
+func NewClient(cfg *config.Config, transport http.RoundTripper, logger *zap.Logger) *Client {
+	return &Client{
+		urlTemplate: cfg.BackInStockSubscribersURL,
+		apiKey:      cfg.BackInStockAPIKey,
+		httpClient:  &http.Client{Timeout: 10 * time.Second, Transport: transport},
+		logger:      logger,
+	}
+}
+
+// Enabled reports whether subscribers can be fetched
+func (c *Client) Enabled() bool {
+	return c != nil && c.urlTemplate != ""
+}
+
+// BackInStock returns the subscribers waiting for a product
+func (c *Client) BackInStock(ctx context.Context, productID string) ([]Subscriber, error) {
+	if !c.Enabled() {
+		return nil, fmt.Errorf("BACK_IN_STOCK_SUBSCRIBERS_URL is not configured")
+	}
+
+	endpoint := strings.ReplaceAll(c.urlTemplate, "{product_id}", url.PathEscape(productID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-Service-Key", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subscribers: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// No subscriptions for the product
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("subscriptions service returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Subscribers []Subscriber `json:"subscribers"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode subscribers: %w", err)
+	}
+
+	c.logger.Debug("Fetched back-in-stock subscribers",
+		zap.String("product_id", productID),
+		zap.Int("count", len(body.Subscribers)),
+	)
+	return valid(body.Subscribers), nil
+}
+
+// FromEventData extracts subscribers carried in an event payload under the
+// "subscribers" key. ok is false when the event carries none, so they must
+// be fetched.
+func FromEventData(data map[string]interface{}) (subscribers []Subscriber, ok bool) {
+	raw, ok := data["subscribers"]
+	if !ok || raw == nil {
+		return nil, false
+	}
+
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, false
+	}
+	if err := json.Unmarshal(encoded, &subscribers); err != nil {
+		return nil, false
+	}
+
+	return valid(subscribers), true
+}
+
+// valid drops subscribers that can't be reached and repeats of the same one
+func valid(subscribers []Subscriber) []Subscriber {
+	seen := make(map[string]bool, len(subscribers))
+	kept := make([]Subscriber, 0, len(subscribers))
+	for _, s := range subscribers {
+		s.Email = strings.TrimSpace(s.Email)
+		id := s.ID()
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		kept = append(kept, s)
+	}
+	return kept
+}
@@ -0,0 +1,47 @@
+package templates
+
+// BrandKey is the template data key of the storefront brand, e.g.
+// {{.Brand.Name}} or {{.Brand.StorefrontURL}}
+const BrandKey = "Brand"
+
+// Brand is the storefront a notification is sent for, as templates see it
+type Brand struct {
+	ID            string
+	Name          string
+	LogoURL       string
+	PrimaryColor  string // header and button color; empty keeps the template's own
+	SupportEmail  string
+	StorefrontURL string
+	FooterText    string
+}
+
+// data is the brand as template data. A map rather than the struct, so the
+// values are sanitized like any other data.
+func (b Brand) data() map[string]interface{} {
+	return map[string]interface{}{
+		"ID":            b.ID,
+		"Name":          b.Name,
+		"LogoURL":       b.LogoURL,
+		"PrimaryColor":  b.PrimaryColor,
+		"SupportEmail":  b.SupportEmail,
+		"StorefrontURL": b.StorefrontURL,
+		"FooterText":    b.FooterText,
+	}
+}
+
+// WithBrand returns a copy of data themed for a brand
+func WithBrand(data map[string]interface{}, brand Brand) map[string]interface{} {
+	branded := make(map[string]interface{}, len(data)+1)
+	for key, value := range data {
+		branded[key] = value
+	}
+	branded[BrandKey] = brand.data()
+	return branded
+}
+
+// brandName is the storefront name for subject lines
+func brandName(data map[string]interface{}) string {
+	brand, _ := data[BrandKey].(map[string]interface{})
+	name, _ := brand["Name"].(string)
+	return name
+}
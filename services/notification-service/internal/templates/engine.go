@@ -2,68 +2,250 @@ package templates
 
 import (
 	"bytes"
+	"context"
+	"embed"
+	"errors"
 	"fmt"
-	"html/template"
+	"io/fs"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 
+	"github.com/fsnotify/fsnotify"
 	"go.uber.org/zap"
 )
 
-// TemplateEngine handles email template rendering
+//go:embed html/*.html
+var embeddedTemplates embed.FS
+
+// ErrTemplateNotFound is returned when rendering an unknown template
+var ErrTemplateNotFound = errors.New("template not found")
+
+// TemplateEngine handles email template rendering. Templates are embedded in
+// the binary; when a templates directory is configured, files there override
+// the embedded versions and can be hot-reloaded with Watch, along with the
+// A/B tests configured in variants.json. A template file's extension declares
+// its language: .html (Go html/template) or .mustache, or that of an engine
+// added with RegisterEngine. Data without a brand is rendered for the
+// default brand.
 type TemplateEngine struct {
-	templates map[string]*template.Template
-	logger    *zap.Logger
+	templatesDir string
+	defaultBrand Brand
+	logger       *zap.Logger
+
+	mu          sync.RWMutex
+	templates   map[string]Renderer
+	experiments map[string][]Variant
+	onReload    func(file string)
 }
 
 // NewTemplateEngine creates a new template engine
-func NewTemplateEngine(templatesDir string, logger *zap.Logger) (*TemplateEngine, error) {
+func NewTemplateEngine(templatesDir string, defaultBrand Brand, logger *zap.Logger) (*TemplateEngine, error) {
 	engine := &TemplateEngine{
-		templates: make(map[string]*template.Template),
-		logger:    logger,
+		templatesDir: templatesDir,
+		defaultBrand: defaultBrand,
+		logger:       logger,
+		templates:    make(map[string]Renderer),
 	}
 
-	// Define templates
-	templateNames := []string{
-		"order_confirmation",
-		"payment_confirmation",
-		"payment_failure",
-		"shipping_notification",
-		"delivery_notification",
-		"order_cancellation",
+	names, err := fs.Glob(embeddedTemplates, "html/*.html")
+	if err != nil {
+		return nil, err
 	}
 
-	// If templatesDir is provided, load from files
-	// Otherwise, use embedded templates
-	if templatesDir != "" {
-		for _, name := range templateNames {
-			tmplPath := filepath.Join(templatesDir, name+".html")
-			tmpl, err := template.ParseFiles(tmplPath)
-			if err != nil {
-				logger.Warn("Failed to load template file, using embedded",
-					zap.String("template", name),
-					zap.Error(err),
-				)
-				engine.templates[name] = getEmbeddedTemplate(name)
-			} else {
-				engine.templates[name] = tmpl
-			}
+	for _, path := range names {
+		name := strings.TrimSuffix(filepath.Base(path), ".html")
+
+		src, err := embeddedTemplates.ReadFile(path)
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		// Load embedded templates
-		for _, name := range templateNames {
-			engine.templates[name] = getEmbeddedTemplate(name)
+		tmpl, err := parseTemplate(EngineGo, "", name, string(src))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse embedded template %s: %w", name, err)
 		}
+		engine.templates[name] = tmpl
+
+		// If templatesDir is provided, files there take precedence
+		if templatesDir != "" {
+			engine.loadFromDir(name)
+		}
+	}
+
+	if templatesDir != "" {
+		engine.loadExperiments()
 	}
 
 	return engine, nil
 }
 
+// templateFile finds a template's file in the templates directory and the
+// language its extension declares. Several files for one template, e.g. a
+// stale welcome.html next to a new welcome.mustache, are an error.
+func (e *TemplateEngine) templateFile(name string) (path, engine string, err error) {
+	var found []string
+	for _, extension := range templateExtensions() {
+		candidate := filepath.Join(e.templatesDir, name+extension)
+		if _, err := os.Stat(candidate); err == nil {
+			found = append(found, filepath.Base(candidate))
+			path, engine = candidate, engineForExtension(extension)
+		}
+	}
+	if len(found) > 1 {
+		return "", "", fmt.Errorf("template %s has several files: %s", name, strings.Join(found, ", "))
+	}
+	return path, engine, nil
+}
+
+// loadFromDir replaces a template with the version in the templates
+// directory, keeping the current one if the file is missing or invalid. It
+// reports whether the template was replaced.
+func (e *TemplateEngine) loadFromDir(name string) bool {
+	tmplPath, engine, err := e.templateFile(name)
+	if err == nil && tmplPath == "" {
+		return false
+	}
+
+	var tmpl Renderer
+	if err == nil {
+		var src []byte
+		if src, err = os.ReadFile(tmplPath); err == nil {
+			tmpl, err = parseTemplate(engine, e.templatesDir, name, string(src))
+		}
+	}
+	if err != nil {
+		e.logger.Warn("Failed to load template file, keeping current version",
+			zap.String("template", name),
+			zap.Error(err),
+		)
+		return false
+	}
+
+	e.mu.Lock()
+	e.templates[name] = tmpl
+	e.mu.Unlock()
+	return true
+}
+
+// OnReload registers fn to be called with a file's name, e.g.
+// welcome.html or variants.json, after Watch applies a change to it, such
+// as to audit template edits
+func (e *TemplateEngine) OnReload(fn func(file string)) {
+	e.mu.Lock()
+	e.onReload = fn
+	e.mu.Unlock()
+}
+
+// reloaded calls the OnReload callback, if any
+func (e *TemplateEngine) reloaded(file string) {
+	e.mu.RLock()
+	fn := e.onReload
+	e.mu.RUnlock()
+	if fn != nil {
+		fn(file)
+	}
+}
+
+// Watch reloads templates from the templates directory as their files
+// change, until ctx is cancelled. It is a no-op without a templates directory.
+func (e *TemplateEngine) Watch(ctx context.Context) error {
+	if e.templatesDir == "" {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(e.templatesDir); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", e.templatesDir, err)
+	}
+	e.logger.Info("Watching templates directory", zap.String("dir", e.templatesDir))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+				continue
+			}
+
+			if filepath.Base(event.Name) == variantsFile {
+				if e.loadExperiments() {
+					e.reloaded(variantsFile)
+				}
+				continue
+			}
+
+			extension := filepath.Ext(event.Name)
+			name := strings.TrimSuffix(filepath.Base(event.Name), extension)
+			if engineForExtension(extension) == "" || !e.Has(baseTemplateName(name)) {
+				continue
+			}
+
+			if e.loadFromDir(name) {
+				e.logger.Info("Template reloaded", zap.String("template", name))
+				e.reloaded(filepath.Base(event.Name))
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			e.logger.Error("Template watcher error", zap.Error(err))
+		}
+	}
+}
+
+// Has reports whether a template with the given name exists
+func (e *TemplateEngine) Has(name string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	_, ok := e.templates[name]
+	return ok
+}
+
 // Render renders a template with the given data
 func (e *TemplateEngine) Render(templateName string, data map[string]interface{}) (subject string, body string, err error) {
+	return e.RenderVariant(templateName, "", data)
+}
+
+// RenderVariant renders an A/B test variant of a template. The control
+// variant, "" and unknown variants render the template itself.
+func (e *TemplateEngine) RenderVariant(templateName, variant string, data map[string]interface{}) (subject string, body string, err error) {
+	e.mu.RLock()
 	tmpl, ok := e.templates[templateName]
+	var subjectOverride string
+	if variant != "" && variant != ControlVariant {
+		if variantTmpl, found := e.templates[variantKey(templateName, variant)]; found {
+			tmpl = variantTmpl
+		}
+		for _, v := range e.experiments[templateName] {
+			if v.Name == variant {
+				subjectOverride = v.Subject
+			}
+		}
+	}
+	e.mu.RUnlock()
 	if !ok {
-		return "", "", fmt.Errorf("template not found: %s", templateName)
+		return "", "", fmt.Errorf("%w: %s", ErrTemplateNotFound, templateName)
+	}
+
+	// Fail fast rather than send blank placeholders
+	if err := ValidateData(templateName, data); err != nil {
+		return "", "", err
+	}
+
+	if _, ok := data[BrandKey]; !ok {
+		data = WithBrand(data, e.defaultBrand)
 	}
+	data = sanitizeData(data)
 
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, data); err != nil {
@@ -72,6 +254,10 @@ func (e *TemplateEngine) Render(templateName string, data map[string]interface{}
 
 	// Get subject from template name
 	subject = getSubjectForTemplate(templateName, data)
+	if subjectOverride != "" {
+		subject = subjectOverride
+	}
+	subject = sanitizeSubject(subject)
 	body = buf.String()
 
 	return subject, body, nil
@@ -102,309 +288,115 @@ func getSubjectForTemplate(templateName string, data map[string]interface{}) str
 		return "Your Order Has Been Delivered"
 	case "order_cancellation":
 		return "Order Cancelled"
+	case "digest":
+		if count, ok := data["Count"].(int); ok && count > 0 {
+			return fmt.Sprintf("Your Updates (%d)", count)
+		}
+		return "Your Updates"
+	case "welcome":
+		if url, ok := data["VerificationURL"].(string); ok && url != "" {
+			return "Welcome! Please Verify Your Email"
+		}
+		return "Welcome to " + brandName(data)
+	case "password_reset":
+		return "Reset Your Password"
+	case "password_changed":
+		return "Your Password Was Changed"
+	case "new_device_login":
+		return "New Sign-In to Your Account"
+	case "sms_fallback":
+		if orderNumber != "" {
+			return fmt.Sprintf("Update on Your Order %s", orderNumber)
+		}
+		return "An Update from " + brandName(data)
+	case "low_stock_alert":
+		return fmt.Sprintf("[Inventory] Low Stock: %s", inventoryItemLabel(data))
+	case "out_of_stock_alert":
+		return fmt.Sprintf("[Inventory] Out of Stock: %s", inventoryItemLabel(data))
+	case "reorder_request":
+		return fmt.Sprintf("[Inventory] Reorder Requested: %s", inventoryItemLabel(data))
+	case "back_in_stock":
+		if name, ok := data["ProductName"].(string); ok && name != "" {
+			return fmt.Sprintf("%s Is Back in Stock", name)
+		}
+		return "Back in Stock"
+	case "price_drop":
+		if name, ok := data["ProductName"].(string); ok && name != "" {
+			return fmt.Sprintf("Price Drop on %s", name)
+		}
+		return "Price Drop on Your Wishlist"
+	case "review_request":
+		if orderNumber != "" {
+			return fmt.Sprintf("How Was Your Order %s?", orderNumber)
+		}
+		return "How Was Your Order?"
+	case "return_update":
+		return returnSubject(data, orderNumber)
+	case "subscription_update":
+		return subscriptionSubject(data)
+	case "gift_card":
+		if sender, ok := data["SenderName"].(string); ok && sender != "" {
+			return fmt.Sprintf("%s Sent You a %s Gift Card", sender, brandName(data))
+		}
+		return fmt.Sprintf("Your %s Gift Card", brandName(data))
 	default:
-		return "Notification from E-Commerce Platform"
+		return "Notification from " + brandName(data)
 	}
 }
 
-func getEmbeddedTemplate(name string) *template.Template {
-	var tmplStr string
+// returnSubject is the subject of a return update, by the return's status
+func returnSubject(data map[string]interface{}, orderNumber string) string {
+	of := ""
+	if orderNumber != "" {
+		of = " - " + orderNumber
+	}
 
-	switch name {
-	case "order_confirmation":
-		tmplStr = orderConfirmationTemplate
-	case "payment_confirmation":
-		tmplStr = paymentConfirmationTemplate
-	case "payment_failure":
-		tmplStr = paymentFailureTemplate
-	case "shipping_notification":
-		tmplStr = shippingNotificationTemplate
-	case "delivery_notification":
-		tmplStr = deliveryNotificationTemplate
-	case "order_cancellation":
-		tmplStr = orderCancellationTemplate
+	status, _ := data["Status"].(string)
+	switch status {
+	case "requested":
+		return "We Received Your Return Request" + of
+	case "approved":
+		return "Your Return Was Approved" + of
+	case "rejected":
+		return "Your Return Request Was Declined" + of
+	case "received":
+		return "We Received Your Returned Items" + of
+	case "refunded":
+		return "Your Refund Is on Its Way" + of
 	default:
-		tmplStr = "<html><body><h1>Notification</h1></body></html>"
+		return "Update on Your Return" + of
 	}
+}
 
-	tmpl, _ := template.New(name).Parse(tmplStr)
-	return tmpl
+// subscriptionSubject is the subject of a subscription update, by the
+// change it is about
+func subscriptionSubject(data map[string]interface{}) string {
+	change, _ := data["Event"].(string)
+	switch change {
+	case "created":
+		return "Your Subscription Is Set Up"
+	case "renewal_upcoming":
+		return "Your Next Subscription Order Is Coming Up"
+	case "paused":
+		return "Your Subscription Is Paused"
+	case "resumed":
+		return "Your Subscription Is Back On"
+	case "skipped":
+		return "Your Subscription Order Was Skipped"
+	case "cancelled":
+		return "Your Subscription Was Cancelled"
+	case "payment_failed":
+		return "Payment for Your Subscription Order Failed"
+	default:
+		return "Update on Your Subscription"
+	}
 }
 
-// Embedded HTML templates
-const orderConfirmationTemplate = `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <style>
-        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; }
-        .header { background-color: #4CAF50; color: white; padding: 20px; text-align: center; }
-        .content { padding: 20px; }
-        .order-details { background-color: #f5f5f5; padding: 15px; margin: 20px 0; border-radius: 5px; }
-        .footer { background-color: #f5f5f5; padding: 15px; text-align: center; font-size: 12px; color: #666; }
-        .button { background-color: #4CAF50; color: white; padding: 10px 20px; text-decoration: none; border-radius: 5px; display: inline-block; margin: 10px 0; }
-        table { width: 100%; border-collapse: collapse; }
-        th, td { padding: 10px; text-align: left; border-bottom: 1px solid #ddd; }
-    </style>
-</head>
-<body>
-    <div class="header">
-        <h1>Order Confirmed!</h1>
-    </div>
-    <div class="content">
-        <p>Hi {{.CustomerName}},</p>
-        <p>Thank you for your order! We're preparing your items for shipment.</p>
-
-        <div class="order-details">
-            <h2>Order Details</h2>
-            <p><strong>Order Number:</strong> {{.OrderNumber}}</p>
-            <p><strong>Order ID:</strong> {{.OrderID}}</p>
-            <p><strong>Total Amount:</strong> ${{printf "%.2f" .TotalAmount}}</p>
-        </div>
-
-        {{if .Items}}
-        <h3>Items Ordered:</h3>
-        <table>
-            <thead>
-                <tr>
-                    <th>Product</th>
-                    <th>Quantity</th>
-                    <th>Price</th>
-                </tr>
-            </thead>
-            <tbody>
-                {{range .Items}}
-                <tr>
-                    <td>{{.ProductName}}</td>
-                    <td>{{.Quantity}}</td>
-                    <td>${{printf "%.2f" .Price}}</td>
-                </tr>
-                {{end}}
-            </tbody>
-        </table>
-        {{end}}
-
-        <p style="text-align: center;">
-            <a href="https://shop.example.com/orders/{{.OrderID}}" class="button">Track Your Order</a>
-        </p>
-
-        <p>You'll receive another email when your order ships.</p>
-    </div>
-    <div class="footer">
-        <p>Questions? Contact us at support@example.com</p>
-        <p>&copy; 2024 E-Commerce Platform. All rights reserved.</p>
-    </div>
-</body>
-</html>
-`
-
-const paymentConfirmationTemplate = `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <style>
-        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; }
-        .header { background-color: #2196F3; color: white; padding: 20px; text-align: center; }
-        .content { padding: 20px; }
-        .payment-details { background-color: #f5f5f5; padding: 15px; margin: 20px 0; border-radius: 5px; }
-        .footer { background-color: #f5f5f5; padding: 15px; text-align: center; font-size: 12px; color: #666; }
-        .checkmark { font-size: 48px; color: #4CAF50; }
-    </style>
-</head>
-<body>
-    <div class="header">
-        <div class="checkmark">✓</div>
-        <h1>Payment Received</h1>
-    </div>
-    <div class="content">
-        <p>Hi {{.CustomerName}},</p>
-        <p>Your payment has been successfully processed.</p>
-
-        <div class="payment-details">
-            <h2>Payment Details</h2>
-            <p><strong>Order Number:</strong> {{.OrderNumber}}</p>
-            <p><strong>Payment ID:</strong> {{.PaymentID}}</p>
-            <p><strong>Transaction ID:</strong> {{.TransactionID}}</p>
-            <p><strong>Amount:</strong> ${{printf "%.2f" .Amount}}</p>
-            <p><strong>Payment Method:</strong> {{.PaymentMethod}}</p>
-        </div>
-
-        <p>Your order is now being processed and will ship soon.</p>
-    </div>
-    <div class="footer">
-        <p>Questions? Contact us at support@example.com</p>
-        <p>&copy; 2024 E-Commerce Platform. All rights reserved.</p>
-    </div>
-</body>
-</html>
-`
-
-const paymentFailureTemplate = `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <style>
-        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; }
-        .header { background-color: #f44336; color: white; padding: 20px; text-align: center; }
-        .content { padding: 20px; }
-        .error-details { background-color: #ffebee; padding: 15px; margin: 20px 0; border-radius: 5px; border-left: 4px solid #f44336; }
-        .footer { background-color: #f5f5f5; padding: 15px; text-align: center; font-size: 12px; color: #666; }
-        .button { background-color: #f44336; color: white; padding: 10px 20px; text-decoration: none; border-radius: 5px; display: inline-block; margin: 10px 0; }
-    </style>
-</head>
-<body>
-    <div class="header">
-        <h1>Payment Failed</h1>
-    </div>
-    <div class="content">
-        <p>Hi {{.CustomerName}},</p>
-        <p>Unfortunately, we were unable to process your payment for order {{.OrderNumber}}.</p>
-
-        <div class="error-details">
-            <h3>Error Details</h3>
-            <p><strong>Order Number:</strong> {{.OrderNumber}}</p>
-            <p><strong>Amount:</strong> ${{printf "%.2f" .Amount}}</p>
-            <p><strong>Error:</strong> {{.ErrorMessage}}</p>
-        </div>
-
-        <p>Please try again with a different payment method, or contact your bank if the problem persists.</p>
-
-        <p style="text-align: center;">
-            <a href="https://shop.example.com/orders/{{.OrderID}}/retry-payment" class="button">Retry Payment</a>
-        </p>
-    </div>
-    <div class="footer">
-        <p>Need help? Contact us at support@example.com</p>
-        <p>&copy; 2024 E-Commerce Platform. All rights reserved.</p>
-    </div>
-</body>
-</html>
-`
-
-const shippingNotificationTemplate = `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <style>
-        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; }
-        .header { background-color: #FF9800; color: white; padding: 20px; text-align: center; }
-        .content { padding: 20px; }
-        .shipping-details { background-color: #fff3e0; padding: 15px; margin: 20px 0; border-radius: 5px; }
-        .footer { background-color: #f5f5f5; padding: 15px; text-align: center; font-size: 12px; color: #666; }
-        .button { background-color: #FF9800; color: white; padding: 10px 20px; text-decoration: none; border-radius: 5px; display: inline-block; margin: 10px 0; }
-    </style>
-</head>
-<body>
-    <div class="header">
-        <h1>📦 Your Order Has Shipped!</h1>
-    </div>
-    <div class="content">
-        <p>Hi {{.CustomerName}},</p>
-        <p>Great news! Your order is on its way.</p>
-
-        <div class="shipping-details">
-            <h2>Shipping Information</h2>
-            <p><strong>Order Number:</strong> {{.OrderNumber}}</p>
-            <p><strong>Carrier:</strong> {{.Carrier}}</p>
-            <p><strong>Tracking Number:</strong> {{.TrackingNumber}}</p>
-        </div>
-
-        <p style="text-align: center;">
-            <a href="https://shop.example.com/track/{{.TrackingNumber}}" class="button">Track Your Package</a>
-        </p>
-
-        <p>You'll receive another notification when your package is delivered.</p>
-    </div>
-    <div class="footer">
-        <p>Questions? Contact us at support@example.com</p>
-        <p>&copy; 2024 E-Commerce Platform. All rights reserved.</p>
-    </div>
-</body>
-</html>
-`
-
-const deliveryNotificationTemplate = `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <style>
-        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; }
-        .header { background-color: #4CAF50; color: white; padding: 20px; text-align: center; }
-        .content { padding: 20px; }
-        .delivery-details { background-color: #e8f5e9; padding: 15px; margin: 20px 0; border-radius: 5px; }
-        .footer { background-color: #f5f5f5; padding: 15px; text-align: center; font-size: 12px; color: #666; }
-        .button { background-color: #4CAF50; color: white; padding: 10px 20px; text-decoration: none; border-radius: 5px; display: inline-block; margin: 10px 0; }
-    </style>
-</head>
-<body>
-    <div class="header">
-        <h1>🎉 Delivered!</h1>
-    </div>
-    <div class="content">
-        <p>Hi {{.CustomerName}},</p>
-        <p>Your order has been delivered! We hope you enjoy your purchase.</p>
-
-        <div class="delivery-details">
-            <h2>Delivery Confirmation</h2>
-            <p><strong>Order Number:</strong> {{.OrderNumber}}</p>
-            <p>Your package has been successfully delivered.</p>
-        </div>
-
-        <p>How was your experience? We'd love to hear your feedback!</p>
-
-        <p style="text-align: center;">
-            <a href="https://shop.example.com/orders/{{.OrderID}}/review" class="button">Leave a Review</a>
-        </p>
-    </div>
-    <div class="footer">
-        <p>Questions? Contact us at support@example.com</p>
-        <p>&copy; 2024 E-Commerce Platform. All rights reserved.</p>
-    </div>
-</body>
-</html>
-`
-
-const orderCancellationTemplate = `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <style>
-        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; }
-        .header { background-color: #9E9E9E; color: white; padding: 20px; text-align: center; }
-        .content { padding: 20px; }
-        .cancellation-details { background-color: #f5f5f5; padding: 15px; margin: 20px 0; border-radius: 5px; }
-        .footer { background-color: #f5f5f5; padding: 15px; text-align: center; font-size: 12px; color: #666; }
-    </style>
-</head>
-<body>
-    <div class="header">
-        <h1>Order Cancelled</h1>
-    </div>
-    <div class="content">
-        <p>Hi {{.CustomerName}},</p>
-        <p>Your order has been cancelled as requested.</p>
-
-        <div class="cancellation-details">
-            <h2>Cancellation Details</h2>
-            <p><strong>Order Number:</strong> {{.OrderNumber}}</p>
-            {{if .Reason}}
-            <p><strong>Reason:</strong> {{.Reason}}</p>
-            {{end}}
-        </div>
-
-        <p>If you paid for this order, your refund will be processed within 5-7 business days.</p>
-        <p>We hope to serve you again soon!</p>
-    </div>
-    <div class="footer">
-        <p>Questions? Contact us at support@example.com</p>
-        <p>&copy; 2024 E-Commerce Platform. All rights reserved.</p>
-    </div>
-</body>
-</html>
-`
+// inventoryItemLabel identifies the item in inventory alert subjects
+func inventoryItemLabel(data map[string]interface{}) string {
+	if sku, ok := data["SKU"].(string); ok && sku != "" {
+		return sku
+	}
+	productID, _ := data["ProductID"].(string)
+	return productID
+}
@@ -0,0 +1,97 @@
+package templates
+
+import (
+	"fmt"
+	"strings"
+)
+
+// requiredFields lists the data each template renders unconditionally, so
+// that an event missing them fails instead of sending an email with blank
+// placeholders. "Items[].Price" requires Price on every element of Items.
+var requiredFields = map[string][]string{
+	"order_confirmation": {
+		"CustomerName", "OrderID", "OrderNumber", "TotalAmount",
+		"Items", "Items[].ProductName", "Items[].Quantity", "Items[].Price",
+	},
+	"payment_confirmation":  {"CustomerName", "OrderNumber", "PaymentID", "TransactionID", "Amount", "PaymentMethod"},
+	"payment_failure":       {"CustomerName", "OrderID", "OrderNumber", "Amount", "ErrorMessage"},
+	"shipping_notification": {"CustomerName", "OrderNumber", "Carrier", "TrackingNumber"},
+	"delivery_notification": {"CustomerName", "OrderID", "OrderNumber"},
+	"order_cancellation":    {"CustomerName", "OrderNumber"},
+	"password_reset":        {"ResetURL"},
+	"low_stock_alert":       {"ProductID", "AvailableQuantity"},
+	"out_of_stock_alert":    {"ProductID", "AvailableQuantity"},
+	"reorder_request":       {"ProductID", "AvailableQuantity"},
+	"back_in_stock":         {"ProductName", "ProductURL"},
+	"price_drop":            {"ProductName", "ProductURL", "OldPrice", "NewPrice"},
+	"review_request":        {"Items", "Items[].ProductName", "Items[].ReviewURL"},
+	"return_update":         {"ReturnID", "Status", "RefundAmount", "Items", "Items[].ProductName", "Items[].Quantity"},
+	"gift_card":             {"Code", "Balance"},
+	"subscription_update":   {"SubscriptionID", "Event", "Items", "Items[].ProductName", "Items[].Quantity"},
+	"digest":                {"Count", "Items", "Items[].Subject", "Items[].CreatedAt"},
+	"sms_fallback":          {"Message"},
+}
+
+// MissingFieldsError reports template data that lacks required fields
+type MissingFieldsError struct {
+	Template string
+	Fields   []string
+}
+
+func (e *MissingFieldsError) Error() string {
+	return fmt.Sprintf("template %s is missing required data: %s", e.Template, strings.Join(e.Fields, ", "))
+}
+
+// RequiredFields returns the fields a template requires in its data
+func RequiredFields(templateName string) []string {
+	return append([]string(nil), requiredFields[templateName]...)
+}
+
+// ValidateData checks data against a template's required fields and returns
+// a *MissingFieldsError listing every one that is absent, nil, an empty string
+// or an empty list. Zero numbers count as present.
+func ValidateData(templateName string, data map[string]interface{}) error {
+	var missing []string
+	for _, field := range requiredFields[templateName] {
+		missing = append(missing, missingFields(data, field, "")...)
+	}
+	if len(missing) > 0 {
+		return &MissingFieldsError{Template: templateName, Fields: missing}
+	}
+	return nil
+}
+
+// missingFields checks one field path against a value and returns the paths
+// that are missing, e.g. Items[1].Price
+func missingFields(value interface{}, field, prefix string) []string {
+	key, rest, nested := strings.Cut(field, "[].")
+	fieldValue, _ := property(value, key)
+
+	if !nested {
+		if blank(fieldValue) {
+			return []string{prefix + key}
+		}
+		return nil
+	}
+
+	// An absent or empty list is reported by the list's own requirement
+	items, _ := toList(fieldValue)
+	var missing []string
+	for i, item := range items {
+		missing = append(missing, missingFields(item, rest, fmt.Sprintf("%s%s[%d].", prefix, key, i))...)
+	}
+	return missing
+}
+
+func blank(v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(value) == ""
+	}
+	if items, ok := toList(v); ok {
+		return len(items) == 0
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package templates
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/cbroglie/mustache"
+)
+
+// mustacheParser parses Mustache templates with github.com/cbroglie/mustache.
+// Partials are included from the templates directory, as <name>.mustache;
+// without one, they render empty.
+type mustacheParser struct{}
+
+func (mustacheParser) Parse(name, text string) (Renderer, error) {
+	return mustacheParser{}.ParseDir("", name, text)
+}
+
+func (mustacheParser) ParseDir(dir, name, text string) (Renderer, error) {
+	var partials mustache.PartialProvider = &mustache.StaticProvider{}
+	if dir != "" {
+		partials = &mustache.FileProvider{Paths: []string{dir}, Extensions: []string{".mustache"}}
+	}
+
+	tmpl, err := mustache.ParseStringPartials(text, partials)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+	return mustacheTemplate{tmpl}, nil
+}
+
+// mustacheTemplate is a Mustache Renderer. {{name}} is HTML-escaped, and
+// {{{name}}} and {{&name}} are not.
+type mustacheTemplate struct {
+	tmpl *mustache.Template
+}
+
+func (t mustacheTemplate) Execute(w io.Writer, data map[string]interface{}) error {
+	return t.tmpl.FRender(w, data)
+}
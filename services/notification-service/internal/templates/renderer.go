@@ -0,0 +1,159 @@
+package templates
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Template languages
+const (
+	EngineGo       = "go"
+	EngineMustache = "mustache"
+)
+
+// Renderer is a parsed template
+type Renderer interface {
+	Execute(w io.Writer, data map[string]interface{}) error
+}
+
+// Parser parses template source written in one template language
+type Parser interface {
+	Parse(name, text string) (Renderer, error)
+}
+
+// DirParser is a Parser of a language whose templates include others, e.g.
+// Mustache partials, which it reads from the templates directory
+type DirParser interface {
+	Parser
+	ParseDir(dir, name, text string) (Renderer, error)
+}
+
+// ParserFunc adapts a function to a Parser
+type ParserFunc func(name, text string) (Renderer, error)
+
+// Parse calls f(name, text)
+func (f ParserFunc) Parse(name, text string) (Renderer, error) {
+	return f(name, text)
+}
+
+// registry maps template languages to their parsers, and the file extensions
+// that declare a template's language to the language
+var registry = struct {
+	sync.RWMutex
+	parsers    map[string]Parser
+	extensions map[string]string
+}{
+	parsers:    make(map[string]Parser),
+	extensions: make(map[string]string),
+}
+
+func init() {
+	RegisterEngine(EngineGo, ".html", ParserFunc(func(name, text string) (Renderer, error) {
+		tmpl, err := template.New(name).Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		return goTemplate{tmpl}, nil
+	}))
+	RegisterEngine(EngineMustache, ".mustache", mustacheParser{})
+}
+
+// goTemplate is a Go html/template Renderer
+type goTemplate struct {
+	tmpl *template.Template
+}
+
+func (t goTemplate) Execute(w io.Writer, data map[string]interface{}) error {
+	return t.tmpl.Execute(w, data)
+}
+
+// RegisterEngine makes a template language available to templates whose file
+// has the given extension, e.g. RegisterEngine("liquid", ".liquid", parser).
+// Go html/template (.html) is the default.
+func RegisterEngine(name, extension string, parser Parser) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.parsers[name] = parser
+	registry.extensions[extension] = name
+}
+
+// engineForExtension returns the template language of a file extension, or ""
+func engineForExtension(extension string) string {
+	registry.RLock()
+	defer registry.RUnlock()
+	return registry.extensions[extension]
+}
+
+// templateExtensions returns the registered file extensions, sorted
+func templateExtensions() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	extensions := make([]string, 0, len(registry.extensions))
+	for extension := range registry.extensions {
+		extensions = append(extensions, extension)
+	}
+	sort.Strings(extensions)
+	return extensions
+}
+
+// parseTemplate parses source written in the given template language, read
+// from dir, or embedded if dir is ""
+func parseTemplate(engine, dir, name, text string) (Renderer, error) {
+	registry.RLock()
+	parser, ok := registry.parsers[engine]
+	registry.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown template engine %q", engine)
+	}
+	if dirParser, ok := parser.(DirParser); ok && dir != "" {
+		return dirParser.ParseDir(dir, name, text)
+	}
+	return parser.Parse(name, text)
+}
+
+// property looks up a map key or exported struct field of a value
+func property(v interface{}, key string) (interface{}, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		value := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+		if !value.IsValid() {
+			return nil, false
+		}
+		return value.Interface(), true
+	case reflect.Struct:
+		field := rv.FieldByName(key)
+		if !field.IsValid() || !field.CanInterface() {
+			return nil, false
+		}
+		return field.Interface(), true
+	}
+	return nil, false
+}
+
+// toList returns the elements of a slice or array
+func toList(v interface{}) ([]interface{}, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items, true
+}
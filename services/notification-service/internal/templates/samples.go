@@ -0,0 +1,180 @@
+package templates
+
+import (
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/money"
+)
+
+// SampleData returns representative data for previewing a template.
+// Each call returns a fresh map so callers may modify it.
+func SampleData(name string) map[string]interface{} {
+	switch name {
+	case "order_confirmation":
+		return map[string]interface{}{
+			"OrderID":      "ord_sample123",
+			"OrderNumber":  "ORD-20240115-00001",
+			"TotalAmount":  money.New(12997, "USD"),
+			"CustomerName": "Jane Doe",
+			"Items": []map[string]interface{}{
+				{"ProductName": "Wireless Mouse", "Quantity": 1, "Price": money.New(2999, "USD")},
+				{"ProductName": "USB-C Cable", "Quantity": 2, "Price": money.New(4999, "USD")},
+			},
+		}
+	case "payment_confirmation":
+		return map[string]interface{}{
+			"OrderID":       "ord_sample123",
+			"OrderNumber":   "ORD-20240115-00001",
+			"PaymentID":     "pay_sample456",
+			"Amount":        money.New(12997, "USD"),
+			"PaymentMethod": "credit_card",
+			"TransactionID": "txn_sample789",
+			"CustomerName":  "Jane Doe",
+		}
+	case "payment_failure":
+		return map[string]interface{}{
+			"OrderID":      "ord_sample123",
+			"OrderNumber":  "ORD-20240115-00001",
+			"Amount":       money.New(12997, "USD"),
+			"ErrorMessage": "Card declined",
+			"CustomerName": "Jane Doe",
+		}
+	case "shipping_notification":
+		return map[string]interface{}{
+			"OrderID":        "ord_sample123",
+			"OrderNumber":    "ORD-20240115-00001",
+			"TrackingNumber": "1Z999AA10123456784",
+			"Carrier":        "UPS",
+			"CustomerName":   "Jane Doe",
+		}
+	case "delivery_notification":
+		return map[string]interface{}{
+			"OrderID":      "ord_sample123",
+			"OrderNumber":  "ORD-20240115-00001",
+			"CustomerName": "Jane Doe",
+		}
+	case "order_cancellation":
+		return map[string]interface{}{
+			"OrderID":      "ord_sample123",
+			"OrderNumber":  "ORD-20240115-00001",
+			"Reason":       "Customer request",
+			"CustomerName": "Jane Doe",
+		}
+	case "digest":
+		now := time.Now()
+		return map[string]interface{}{
+			"Count": 2,
+			"Items": []map[string]interface{}{
+				{"Subject": "New arrivals this week", "CreatedAt": now.Add(-2 * time.Hour)},
+				{"Subject": "Your wishlist item is on sale", "CreatedAt": now.Add(-30 * time.Minute)},
+			},
+		}
+	case "welcome":
+		return map[string]interface{}{
+			"FirstName":       "Jane",
+			"VerificationURL": "https://shop.example.com/verify-email?token=sample",
+		}
+	case "password_reset":
+		return map[string]interface{}{
+			"FirstName":        "Jane",
+			"ResetURL":         "https://shop.example.com/reset-password?token=sample",
+			"ExpiresInMinutes": 30,
+		}
+	case "password_changed":
+		return map[string]interface{}{
+			"FirstName": "Jane",
+			"ChangedAt": "2024-01-15T10:30:00Z",
+		}
+	case "new_device_login":
+		return map[string]interface{}{
+			"FirstName": "Jane",
+			"Device":    "Chrome on macOS",
+			"IPAddress": "203.0.113.42",
+			"Location":  "Berlin, Germany",
+			"LoginAt":   "2024-01-15T10:30:00Z",
+		}
+	case "sms_fallback":
+		return map[string]interface{}{
+			"CustomerName": "Jane Doe",
+			"OrderNumber":  "ORD-20240115-00001",
+			"Message":      "Your order ORD-20240115-00001 has shipped! Track with UPS: 1Z999AA10123456784",
+		}
+	case "low_stock_alert", "out_of_stock_alert", "reorder_request":
+		return map[string]interface{}{
+			"SKU":               "SKU-MOUSE-001",
+			"ProductID":         "prod_sample123",
+			"AvailableQuantity": 4,
+			"ReorderLevel":      10,
+			"ReorderQuantity":   50,
+			"Warehouse":         "main",
+		}
+	case "back_in_stock":
+		return map[string]interface{}{
+			"ProductID":         "prod_sample123",
+			"ProductName":       "Wireless Mouse",
+			"ProductURL":        "https://shop.example.com/products/prod_sample123",
+			"Price":             money.New(2999, "USD"),
+			"AvailableQuantity": 25,
+			"CustomerName":      "Jane Doe",
+		}
+	case "price_drop":
+		return map[string]interface{}{
+			"ProductID":    "prod_sample123",
+			"ProductName":  "Wireless Mouse",
+			"ProductURL":   "https://shop.example.com/products/prod_sample123",
+			"OldPrice":     money.New(2999, "USD"),
+			"NewPrice":     money.New(2499, "USD"),
+			"CustomerName": "Jane Doe",
+		}
+	case "review_request":
+		return map[string]interface{}{
+			"OrderID":      "ord_sample123",
+			"OrderNumber":  "ORD-20240115-00001",
+			"CustomerName": "Jane Doe",
+			"Items": []map[string]interface{}{
+				{"ProductID": "prod_sample123", "ProductName": "Wireless Mouse", "ReviewURL": "https://shop.example.com/orders/ord_sample123/review?product_id=prod_sample123"},
+				{"ProductID": "prod_sample456", "ProductName": "USB-C Cable", "ReviewURL": "https://shop.example.com/orders/ord_sample123/review?product_id=prod_sample456"},
+			},
+		}
+	case "return_update":
+		return map[string]interface{}{
+			"ReturnID":       "ret_sample123",
+			"OrderID":        "ord_sample123",
+			"OrderNumber":    "ORD-20240115-00001",
+			"CustomerName":   "Jane Doe",
+			"Status":         "approved",
+			"RefundAmount":   money.New(4997, "USD"),
+			"Carrier":        "UPS",
+			"TrackingNumber": "1Z999AA10123456784",
+			"LabelURL":       "https://shop.example.com/returns/ret_sample123/label.pdf",
+			"Items": []map[string]interface{}{
+				{"ProductID": "prod_sample123", "ProductName": "Wireless Mouse", "Quantity": 1},
+				{"ProductID": "prod_sample456", "ProductName": "USB-C Cable", "Quantity": 2},
+			},
+		}
+	case "gift_card":
+		return map[string]interface{}{
+			"Code":          "K7XM2Q9RHTW4PN3C",
+			"Balance":       money.New(5000, "USD"),
+			"ExpiresAt":     "January 15, 2029",
+			"RecipientName": "Sam Lee",
+			"SenderName":    "Jane Doe",
+			"Message":       "Happy birthday!",
+		}
+	case "subscription_update":
+		return map[string]interface{}{
+			"SubscriptionID": "sub_sample123",
+			"Event":          "renewal_upcoming",
+			"Status":         "active",
+			"CustomerName":   "Jane Doe",
+			"Interval":       "every 2 weeks",
+			"NextOrderAt":    "January 15, 2024",
+			"Total":          money.New(3600, "USD"),
+			"Items": []map[string]interface{}{
+				{"ProductID": "prod_sample123", "ProductName": "Coffee Beans 1kg", "Quantity": 2, "Price": money.New(1800, "USD")},
+			},
+		}
+	default:
+		return map[string]interface{}{}
+	}
+}
@@ -0,0 +1,91 @@
+package templates
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// unsafeURLSchemes run code or embed content when a value lands in an href
+// or src attribute. html/template already filters them; Mustache and other
+// engines only escape, so they are neutralized in the data for every engine.
+var unsafeURLSchemes = []string{"javascript:", "vbscript:", "data:"}
+
+// unsafeURL replaces data values that are unsafe URLs, like html/template does
+const unsafeURL = "about:invalid#unsafe"
+
+// sanitizeData returns a copy of template data that is safe to inject into
+// HTML whatever the template language: strings lose control and bidirectional
+// override characters, and unsafe URLs are replaced. Escaping is left to the
+// engines, which escape all data.
+func sanitizeData(data map[string]interface{}) map[string]interface{} {
+	clean, _ := sanitizeValue(reflect.ValueOf(data)).Interface().(map[string]interface{})
+	return clean
+}
+
+func sanitizeValue(v reflect.Value) reflect.Value {
+	if !v.IsValid() {
+		return v
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		clean := reflect.New(v.Type()).Elem()
+		clean.SetString(sanitizeString(v.String()))
+		return clean
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		clean := reflect.New(v.Type()).Elem()
+		clean.Set(sanitizeValue(v.Elem()))
+		return clean
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		clean := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			clean.SetMapIndex(iter.Key(), sanitizeValue(iter.Value()))
+		}
+		return clean
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		clean := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			clean.Index(i).Set(sanitizeValue(v.Index(i)))
+		}
+		return clean
+	}
+
+	// Numbers, times and structs carry no markup of their own
+	return v
+}
+
+func sanitizeString(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			return r
+		case unicode.IsControl(r), unicode.Is(unicode.Bidi_Control, r):
+			return -1
+		}
+		return r
+	}, s)
+
+	scheme := strings.ToLower(strings.TrimLeftFunc(s, unicode.IsSpace))
+	for _, unsafe := range unsafeURLSchemes {
+		if strings.HasPrefix(scheme, unsafe) {
+			return unsafeURL
+		}
+	}
+	return s
+}
+
+// sanitizeSubject keeps a subject line to one line so data can't inject mail headers
+func sanitizeSubject(subject string) string {
+	return strings.Join(strings.Fields(subject), " ")
+}
@@ -0,0 +1,156 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// ControlVariant is the variant that renders the template itself
+const ControlVariant = "control"
+
+// variantsFile configures A/B tests; it lives in the templates directory
+const variantsFile = "variants.json"
+
+// Variant is one arm of a template A/B test. Variants other than control
+// render <template>@<name>.<ext> from the templates directory when it exists,
+// else the template itself, and may override the subject line.
+type Variant struct {
+	Name    string `json:"name"`
+	Weight  int    `json:"weight"`
+	Subject string `json:"subject,omitempty"`
+}
+
+// loadExperiments reads A/B tests from variants.json in the templates
+// directory, e.g. {"welcome": [{"name": "control", "weight": 50},
+// {"name": "short", "weight": 50}]}. Invalid tests are skipped; an unreadable
+// file keeps the current tests. It reports whether the tests were replaced.
+func (e *TemplateEngine) loadExperiments() bool {
+	raw, err := os.ReadFile(filepath.Join(e.templatesDir, variantsFile))
+	if os.IsNotExist(err) {
+		e.setExperiments(nil)
+		return true
+	}
+	if err != nil {
+		e.logger.Warn("Failed to read template variants, keeping current tests", zap.Error(err))
+		return false
+	}
+
+	var configured map[string][]Variant
+	if err := json.Unmarshal(raw, &configured); err != nil {
+		e.logger.Warn("Invalid template variants file, keeping current tests", zap.Error(err))
+		return false
+	}
+
+	experiments := make(map[string][]Variant, len(configured))
+	for name, variants := range configured {
+		if err := e.validateExperiment(name, variants); err != nil {
+			e.logger.Warn("Skipping invalid template A/B test", zap.String("template", name), zap.Error(err))
+			continue
+		}
+		for _, v := range variants {
+			if v.Name != ControlVariant {
+				e.loadVariantFile(name, v.Name)
+			}
+		}
+		experiments[name] = variants
+	}
+
+	e.setExperiments(experiments)
+	e.logger.Info("Template A/B tests loaded", zap.Int("tests", len(experiments)))
+	return true
+}
+
+func (e *TemplateEngine) validateExperiment(name string, variants []Variant) error {
+	if !e.Has(name) {
+		return fmt.Errorf("unknown template")
+	}
+	if len(variants) < 2 {
+		return fmt.Errorf("at least two variants are required")
+	}
+
+	seen := make(map[string]bool, len(variants))
+	for _, v := range variants {
+		if v.Name == "" || strings.ContainsAny(v.Name, "@/\\") {
+			return fmt.Errorf("invalid variant name %q", v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("duplicate variant %q", v.Name)
+		}
+		if v.Weight <= 0 {
+			return fmt.Errorf("variant %q must have a positive weight", v.Name)
+		}
+		seen[v.Name] = true
+	}
+	return nil
+}
+
+// loadVariantFile loads <template>@<variant>.<ext> if the templates directory has it
+func (e *TemplateEngine) loadVariantFile(name, variant string) {
+	e.loadFromDir(variantKey(name, variant))
+}
+
+func (e *TemplateEngine) setExperiments(experiments map[string][]Variant) {
+	e.mu.Lock()
+	e.experiments = experiments
+	e.mu.Unlock()
+}
+
+// Variants returns the A/B test variants of a template, or nil when it isn't
+// being tested
+func (e *TemplateEngine) Variants(templateName string) []Variant {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]Variant(nil), e.experiments[templateName]...)
+}
+
+// PickVariant chooses the variant of a template a recipient gets, or "" when
+// the template isn't being tested. The choice is a stable hash of the
+// recipient, so a customer keeps seeing the same variant.
+func (e *TemplateEngine) PickVariant(templateName, recipient string) string {
+	variants := e.Variants(templateName)
+	if len(variants) == 0 {
+		return ""
+	}
+
+	total := 0
+	for _, v := range variants {
+		total += v.Weight
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(templateName + "/" + strings.ToLower(recipient)))
+	point := int(h.Sum32() % uint32(total))
+
+	for _, v := range variants {
+		if point < v.Weight {
+			return v.Name
+		}
+		point -= v.Weight
+	}
+	return variants[len(variants)-1].Name
+}
+
+// RenderFor picks the recipient's variant of a template and renders it,
+// returning the variant ("" when the template isn't being tested)
+func (e *TemplateEngine) RenderFor(templateName, recipient string, data map[string]interface{}) (subject, body, variant string, err error) {
+	variant = e.PickVariant(templateName, recipient)
+	subject, body, err = e.RenderVariant(templateName, variant, data)
+	return subject, body, variant, err
+}
+
+// variantKey is the template map key of a variant's own HTML
+func variantKey(name, variant string) string {
+	return name + "@" + variant
+}
+
+// baseTemplateName strips the variant from a template file name
+func baseTemplateName(name string) string {
+	base, _, _ := strings.Cut(name, "@")
+	return base
+}
@@ -0,0 +1,11 @@
+package tracing
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer returns the notification service tracer
+func Tracer() trace.Tracer {
+	return otel.Tracer("notification-service")
+}
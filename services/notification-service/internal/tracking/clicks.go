@@ -0,0 +1,115 @@
+package tracking
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/ecommerce/notification-service/internal/config"
+)
+
+// clickPath is the route of the tracking redirect, below CLICK_TRACKING_BASE_URL
+const clickPath = "/api/v1/clicks/"
+
+// signatureLength is the number of HMAC bytes kept in a link token
+const signatureLength = 16
+
+// ErrInvalidLink is returned for link tokens that weren't issued by this service
+var ErrInvalidLink = errors.New("invalid tracking link")
+
+// anchorHref matches the href attribute of an <a> tag
+var anchorHref = regexp.MustCompile(`(?i)(<a\b[^>]*?\bhref\s*=\s*)("[^"]*"|'[^']*')`)
+
+// ClickTracker rewrites the links of outgoing emails to pass through the
+// click-tracking redirect, so each click is attributed to the notification
+type ClickTracker struct {
+	baseURL    string
+	secret     []byte
+	categories []string
+	templates  map[string]bool
+}
+
+// NewClickTracker creates a click tracker. Tracking is off when
+// CLICK_TRACKING_BASE_URL is empty.
+func NewClickTracker(cfg *config.Config) *ClickTracker {
+	return &ClickTracker{
+		baseURL:    strings.TrimRight(cfg.ClickTrackingBaseURL, "/"),
+		secret:     []byte(cfg.ClickTrackingSecret),
+		categories: cfg.ClickTrackingCategories,
+		templates:  cfg.ClickTrackingTemplates,
+	}
+}
+
+// Enabled reports whether links in a template's emails are tracked. A
+// per-template setting takes precedence over the notification category.
+func (t *ClickTracker) Enabled(templateName, category string) bool {
+	if t.baseURL == "" {
+		return false
+	}
+	if enabled, ok := t.templates[templateName]; ok {
+		return enabled
+	}
+	for _, c := range t.categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// RewriteLinks points every http(s) link of an HTML body at the tracking
+// redirect for the notification. Other links (mailto:, tel:, anchors) are kept.
+func (t *ClickTracker) RewriteLinks(notificationID, body string) string {
+	return anchorHref.ReplaceAllStringFunc(body, func(match string) string {
+		parts := anchorHref.FindStringSubmatch(match)
+		prefix, quoted := parts[1], parts[2]
+
+		target := html.UnescapeString(quoted[1 : len(quoted)-1])
+		lower := strings.ToLower(strings.TrimSpace(target))
+		if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+			return match
+		}
+		if strings.HasPrefix(target, t.baseURL+clickPath) {
+			return match
+		}
+
+		return prefix + `"` + html.EscapeString(t.link(notificationID, strings.TrimSpace(target))) + `"`
+	})
+}
+
+// link returns the tracking URL of a notification's link. The token carries
+// the notification ID and target URL, signed so the redirect can't be abused
+// to send visitors anywhere.
+func (t *ClickTracker) link(notificationID, target string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(notificationID + "\n" + target))
+	return t.baseURL + clickPath + payload + "." + t.sign(payload)
+}
+
+// Resolve verifies a link token and returns the notification and target URL
+func (t *ClickTracker) Resolve(token string) (notificationID, target string, err error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(t.sign(payload))) {
+		return "", "", ErrInvalidLink
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", "", ErrInvalidLink
+	}
+
+	notificationID, target, ok = strings.Cut(string(raw), "\n")
+	if !ok || notificationID == "" || target == "" {
+		return "", "", ErrInvalidLink
+	}
+	return notificationID, target, nil
+}
+
+func (t *ClickTracker) sign(payload string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:signatureLength])
+}
@@ -0,0 +1,122 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ecommerce/notification-service/internal/config"
+)
+
+const metaGraphAPIBase = "https://graph.facebook.com"
+
+// metaProvider sends WhatsApp template messages through the Meta WhatsApp
+// Cloud API. Notification template names are used as the approved template
+// names.
+type metaProvider struct {
+	config     *config.Config
+	httpClient *http.Client
+}
+
+// NewMetaProvider creates a new Meta Cloud API provider
+func NewMetaProvider(cfg *config.Config) Provider {
+	return &metaProvider{
+		config:     cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *metaProvider) Name() string {
+	return "meta"
+}
+
+type metaParameter struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type metaComponent struct {
+	Type       string          `json:"type"`
+	Parameters []metaParameter `json:"parameters"`
+}
+
+type metaTemplate struct {
+	Name     string `json:"name"`
+	Language struct {
+		Code string `json:"code"`
+	} `json:"language"`
+	Components []metaComponent `json:"components,omitempty"`
+}
+
+type metaMessageRequest struct {
+	MessagingProduct string        `json:"messaging_product"`
+	To               string        `json:"to"`
+	Type             string        `json:"type"`
+	Template         *metaTemplate `json:"template"`
+}
+
+type metaMessageResponse struct {
+	Messages []struct {
+		ID string `json:"id"`
+	} `json:"messages"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Send sends a template message and returns its WhatsApp message ID
+func (p *metaProvider) Send(ctx context.Context, to string, msg Message) (string, error) {
+	tmpl := &metaTemplate{Name: msg.Template}
+	tmpl.Language.Code = p.config.WhatsAppTemplateLanguage
+	if len(msg.Parameters) > 0 {
+		params := make([]metaParameter, len(msg.Parameters))
+		for i, value := range msg.Parameters {
+			params[i] = metaParameter{Type: "text", Text: value}
+		}
+		tmpl.Components = []metaComponent{{Type: "body", Parameters: params}}
+	}
+
+	payload, err := json.Marshal(metaMessageRequest{
+		MessagingProduct: "whatsapp",
+		To:               strings.TrimPrefix(to, "+"),
+		Type:             "template",
+		Template:         tmpl,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := fmt.Sprintf("%s/%s/%s/messages", metaGraphAPIBase, p.config.MetaWhatsAppAPIVersion, p.config.MetaWhatsAppPhoneNumberID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.config.MetaWhatsAppToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("meta request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var out metaMessageResponse
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+
+	if resp.StatusCode >= 300 || out.Error != nil {
+		if out.Error != nil {
+			return "", fmt.Errorf("meta returned status %d (code %d): %s", resp.StatusCode, out.Error.Code, out.Error.Message)
+		}
+		return "", fmt.Errorf("meta returned status %d", resp.StatusCode)
+	}
+	if len(out.Messages) == 0 {
+		return "", fmt.Errorf("meta response contained no message ID")
+	}
+
+	return out.Messages[0].ID, nil
+}
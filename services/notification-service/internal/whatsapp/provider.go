@@ -0,0 +1,31 @@
+package whatsapp
+
+import "context"
+
+// channel labels WhatsApp metrics
+const channel = "whatsapp"
+
+// Message is a WhatsApp template message. Business-initiated messages must use
+// a template approved by WhatsApp; Parameters fill its body placeholders in order.
+type Message struct {
+	// Template is the notification template name, e.g. "order_confirmation"
+	Template   string
+	Parameters []string
+	// Text is the rendered message, sent as a session message by providers
+	// that have no approved template mapped for Template
+	Text string
+}
+
+// Provider delivers WhatsApp messages through a specific backend
+type Provider interface {
+	// Name identifies the provider in logs and notification records
+	Name() string
+	// Send delivers the message and returns the provider's message ID
+	Send(ctx context.Context, to string, msg Message) (string, error)
+}
+
+// Result describes a successful delivery
+type Result struct {
+	Provider  string
+	MessageID string
+}
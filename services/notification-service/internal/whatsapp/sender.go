@@ -0,0 +1,186 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/notification-service/internal/breaker"
+	"github.com/ecommerce/notification-service/internal/config"
+	"github.com/ecommerce/notification-service/internal/metrics"
+	"github.com/ecommerce/notification-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// WhatsAppSender sends WhatsApp messages through the configured providers,
+// failing over to the next provider when one errors or its circuit is open
+type WhatsAppSender struct {
+	config  *config.Config
+	logger  *zap.Logger
+	routes  []route
+	timeout time.Duration
+}
+
+// route pairs a provider with its circuit breaker
+type route struct {
+	provider Provider
+	breaker  *breaker.Breaker
+}
+
+// NewWhatsAppSender creates a new WhatsApp sender using the providers listed
+// in WHATSAPP_PROVIDERS, in failover order. Providers without credentials are
+// skipped; with no providers the channel is disabled.
+func NewWhatsAppSender(cfg *config.Config, logger *zap.Logger) (*WhatsAppSender, error) {
+	cooldown := time.Duration(cfg.CircuitBreakerCooldown) * time.Second
+
+	routes := make([]route, 0, len(cfg.WhatsAppProviders))
+	for _, name := range cfg.WhatsAppProviders {
+		if name == "twilio" && (cfg.TwilioAccountSID == "" || cfg.TwilioWhatsAppFrom == "") {
+			logger.Warn("Twilio WhatsApp not configured, skipping provider")
+			continue
+		}
+		if name == "meta" && (cfg.MetaWhatsAppToken == "" || cfg.MetaWhatsAppPhoneNumberID == "") {
+			logger.Warn("Meta WhatsApp Cloud API not configured, skipping provider")
+			continue
+		}
+
+		provider, err := NewProvider(cfg, name)
+		if err != nil {
+			return nil, err
+		}
+
+		providerName := provider.Name()
+		routes = append(routes, route{
+			provider: provider,
+			breaker: breaker.New(cfg.CircuitBreakerThreshold, cooldown, func(state breaker.State) {
+				metrics.ProviderCircuitState.WithLabelValues(channel, providerName).Set(float64(state))
+				if state == breaker.StateOpen {
+					logger.Warn("WhatsApp provider circuit opened", zap.String("provider", providerName))
+				}
+			}),
+		})
+	}
+
+	return &WhatsAppSender{
+		config:  cfg,
+		logger:  logger,
+		routes:  routes,
+		timeout: time.Duration(cfg.ProviderTimeout) * time.Second,
+	}, nil
+}
+
+// NewProvider creates the named WhatsApp provider
+func NewProvider(cfg *config.Config, name string) (Provider, error) {
+	switch name {
+	case "twilio":
+		return NewTwilioProvider(cfg), nil
+	case "meta":
+		return NewMetaProvider(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown WhatsApp provider: %s", name)
+	}
+}
+
+// EnabledFor reports whether WhatsApp may be used for an E.164 phone number:
+// a provider is configured (or sends are simulated in development) and the
+// number's country calling code is in WHATSAPP_COUNTRY_CODES (all when empty)
+func (s *WhatsAppSender) EnabledFor(phone string) bool {
+	if len(s.routes) == 0 && s.config.Environment != "development" {
+		return false
+	}
+	if len(s.config.WhatsAppCountryCodes) == 0 {
+		return true
+	}
+
+	number := strings.TrimPrefix(phone, "+")
+	for _, code := range s.config.WhatsAppCountryCodes {
+		if strings.HasPrefix(number, strings.TrimPrefix(code, "+")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Send sends a WhatsApp message through the first healthy provider and
+// returns which provider delivered it
+func (s *WhatsAppSender) Send(ctx context.Context, to string, msg Message) (*Result, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "whatsapp.send",
+		trace.WithAttributes(
+			attribute.String("notification.channel", channel),
+			attribute.String("notification.template", msg.Template),
+		),
+	)
+	defer span.End()
+	logger := logging.WithContext(ctx, s.logger)
+
+	// In development mode or without any configured provider, simulate sending
+	if s.config.Environment == "development" || len(s.routes) == 0 {
+		logger.Info("WhatsApp (simulated)",
+			zap.String("to", to),
+			zap.String("template", msg.Template),
+			zap.Strings("parameters", msg.Parameters),
+		)
+		span.SetAttributes(attribute.String("notification.provider", "simulated"))
+		return &Result{Provider: "simulated"}, nil
+	}
+
+	var lastErr error
+	for i, r := range s.routes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		name := r.provider.Name()
+		if !r.breaker.Allow() {
+			logger.Warn("WhatsApp provider circuit open, skipping", zap.String("provider", name))
+			continue
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, s.timeout)
+		messageID, err := r.provider.Send(attemptCtx, to, msg)
+		cancel()
+
+		if err != nil {
+			r.breaker.Failure()
+			metrics.ProviderFailures.WithLabelValues(channel, name).Inc()
+			logger.Error("Failed to send WhatsApp message",
+				zap.String("provider", name),
+				zap.Error(err),
+			)
+			span.AddEvent("provider failed", trace.WithAttributes(
+				attribute.String("notification.provider", name),
+				attribute.String("error", err.Error()),
+			))
+			lastErr = err
+			continue
+		}
+
+		r.breaker.Success()
+		if i > 0 {
+			metrics.ProviderFailovers.WithLabelValues(channel, name).Inc()
+		}
+
+		logger.Info("WhatsApp message sent",
+			zap.String("provider", name),
+			zap.String("message_id", messageID),
+		)
+		span.SetAttributes(
+			attribute.String("notification.provider", name),
+			attribute.String("notification.message_id", messageID),
+		)
+		return &Result{Provider: name, MessageID: messageID}, nil
+	}
+
+	err := fmt.Errorf("no WhatsApp provider available: all circuits open")
+	if lastErr != nil {
+		err = fmt.Errorf("all WhatsApp providers failed: %w", lastErr)
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return nil, err
+}
@@ -0,0 +1,103 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ecommerce/notification-service/internal/config"
+)
+
+const twilioAPIBase = "https://api.twilio.com/2010-04-01"
+
+// twilioProvider sends WhatsApp messages through the Twilio Messages API.
+// Templates are sent as Content API templates (ContentSid), mapped from
+// notification template names by TWILIO_WHATSAPP_CONTENT_SIDS.
+type twilioProvider struct {
+	config     *config.Config
+	httpClient *http.Client
+}
+
+// NewTwilioProvider creates a new Twilio WhatsApp provider
+func NewTwilioProvider(cfg *config.Config) Provider {
+	return &twilioProvider{
+		config:     cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *twilioProvider) Name() string {
+	return "twilio"
+}
+
+type twilioMessageResponse struct {
+	SID          string `json:"sid"`
+	ErrorCode    *int   `json:"error_code"`
+	ErrorMessage string `json:"error_message"`
+}
+
+type twilioErrorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Send creates a WhatsApp message via the Twilio API and returns its SID
+func (p *twilioProvider) Send(ctx context.Context, to string, msg Message) (string, error) {
+	form := url.Values{}
+	form.Set("To", "whatsapp:"+to)
+	form.Set("From", "whatsapp:"+p.config.TwilioWhatsAppFrom)
+	if p.config.TwilioStatusCallbackURL != "" {
+		form.Set("StatusCallback", p.config.TwilioStatusCallbackURL)
+	}
+
+	if contentSID, ok := p.config.TwilioWhatsAppContentSIDs[msg.Template]; ok {
+		variables := make(map[string]string, len(msg.Parameters))
+		for i, param := range msg.Parameters {
+			variables[strconv.Itoa(i+1)] = param
+		}
+		encoded, err := json.Marshal(variables)
+		if err != nil {
+			return "", err
+		}
+		form.Set("ContentSid", contentSID)
+		form.Set("ContentVariables", string(encoded))
+	} else {
+		// Only delivered inside the customer's 24-hour session window
+		form.Set("Body", msg.Text)
+	}
+
+	endpoint := fmt.Sprintf("%s/Accounts/%s/Messages.json", twilioAPIBase, p.config.TwilioAccountSID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(p.config.TwilioAccountSID, p.config.TwilioAuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("twilio request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr twilioErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		return "", fmt.Errorf("twilio returned status %d (code %d): %s", resp.StatusCode, apiErr.Code, apiErr.Message)
+	}
+
+	var out twilioMessageResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("failed to decode twilio response: %w", err)
+	}
+	if out.ErrorCode != nil {
+		return "", fmt.Errorf("twilio error %d: %s", *out.ErrorCode, out.ErrorMessage)
+	}
+
+	return out.SID, nil
+}
@@ -0,0 +1,13 @@
+-- Create digest_items table (low-priority notifications batched per recipient)
+CREATE TABLE IF NOT EXISTS digest_items (
+    id VARCHAR(255) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL DEFAULT '',
+    recipient VARCHAR(255) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    template VARCHAR(100) NOT NULL DEFAULT '',
+    subject TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP
+);
+
+CREATE INDEX idx_digest_items_pending ON digest_items(recipient, created_at) WHERE sent_at IS NULL;
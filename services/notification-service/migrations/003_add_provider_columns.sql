@@ -0,0 +1,5 @@
+-- Track which provider delivered each notification and its message ID
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS provider VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS provider_message_id VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX idx_notifications_provider_message_id ON notifications(provider_message_id);
@@ -0,0 +1,19 @@
+-- Create dead_letters table (messages that failed processing, for inspection and redrive)
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id VARCHAR(255) PRIMARY KEY,
+    topic VARCHAR(255) NOT NULL,
+    partition INTEGER NOT NULL,
+    "offset" BIGINT NOT NULL,
+    message_key TEXT NOT NULL DEFAULT '',
+    payload TEXT NOT NULL,
+    headers JSONB NOT NULL DEFAULT '{}',
+    event_type VARCHAR(100) NOT NULL DEFAULT '',
+    error TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_dead_letters_status ON dead_letters(status, created_at);
+CREATE UNIQUE INDEX idx_dead_letters_message ON dead_letters(topic, partition, "offset");
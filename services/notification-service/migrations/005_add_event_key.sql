@@ -0,0 +1,5 @@
+-- Identify the Kafka message (topic/partition/offset) each notification came
+-- from, so replays can skip notifications that were already delivered
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS event_key VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX idx_notifications_event_key ON notifications(event_key, channel, template);
@@ -0,0 +1,15 @@
+-- In-app notification inbox (storefront bell-icon feed)
+CREATE TABLE IF NOT EXISTS inbox_items (
+    id VARCHAR(255) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    template VARCHAR(100) NOT NULL DEFAULT '',
+    title VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    link VARCHAR(1024) NOT NULL DEFAULT '',
+    read_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_inbox_items_user_created ON inbox_items(user_id, created_at DESC);
+CREATE INDEX idx_inbox_items_user_unread ON inbox_items(user_id) WHERE read_at IS NULL;
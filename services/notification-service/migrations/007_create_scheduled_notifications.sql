@@ -0,0 +1,21 @@
+-- Notifications deferred until the recipient's quiet hours end
+CREATE TABLE IF NOT EXISTS scheduled_notifications (
+    id VARCHAR(255) PRIMARY KEY,
+    channel VARCHAR(50) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL DEFAULT '',
+    order_id VARCHAR(255) NOT NULL DEFAULT '',
+    event_type VARCHAR(100) NOT NULL,
+    event_key VARCHAR(255) NOT NULL DEFAULT '',
+    template VARCHAR(100) NOT NULL DEFAULT '',
+    subject TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+    release_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP
+);
+
+CREATE INDEX idx_scheduled_notifications_due ON scheduled_notifications(release_at) WHERE sent_at IS NULL;
+
+-- Recipient timezone, so digests are also held during quiet hours
+ALTER TABLE digest_items ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,8 @@
+-- Template A/B test variant and engagement reported by provider webhooks
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS variant VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS opened_at TIMESTAMP;
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS clicked_at TIMESTAMP;
+
+CREATE INDEX idx_notifications_template_variant ON notifications(template, variant, created_at) WHERE variant <> '';
+
+ALTER TABLE scheduled_notifications ADD COLUMN IF NOT EXISTS variant VARCHAR(100) NOT NULL DEFAULT '';
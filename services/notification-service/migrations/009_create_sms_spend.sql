@@ -0,0 +1,14 @@
+-- SMS spend per UTC day and recipient country, for cost reporting and the daily budget
+CREATE TABLE IF NOT EXISTS sms_spend (
+    day DATE NOT NULL,
+    country VARCHAR(2) NOT NULL,
+    messages INTEGER NOT NULL DEFAULT 0,
+    segments INTEGER NOT NULL DEFAULT 0,
+    cost NUMERIC(12, 4) NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (day, country)
+);
+
+-- Billed segments and USD cost of each SMS
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS segments INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS cost NUMERIC(12, 4) NOT NULL DEFAULT 0;
@@ -0,0 +1,9 @@
+-- Clicks on tracked email links, one row per click
+CREATE TABLE IF NOT EXISTS notification_clicks (
+    id BIGSERIAL PRIMARY KEY,
+    notification_id VARCHAR(255) NOT NULL REFERENCES notifications(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    clicked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_notification_clicks_notification_id ON notification_clicks(notification_id);
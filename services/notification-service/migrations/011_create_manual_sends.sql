@@ -0,0 +1,23 @@
+-- Who triggered a manually sent notification; empty for event-driven ones
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS triggered_by VARCHAR(255) NOT NULL DEFAULT '';
+
+-- Audit log of notifications sent by support through the API
+CREATE TABLE IF NOT EXISTS manual_sends (
+    id VARCHAR(255) PRIMARY KEY,
+    template VARCHAR(100) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    user_id VARCHAR(255) NOT NULL DEFAULT '',
+    order_id VARCHAR(255) NOT NULL DEFAULT '',
+    recipient VARCHAR(255) NOT NULL DEFAULT '',
+    requested_by VARCHAR(255) NOT NULL,
+    requested_by_email VARCHAR(255) NOT NULL DEFAULT '',
+    requested_by_role VARCHAR(50) NOT NULL DEFAULT '',
+    reason TEXT NOT NULL,
+    ticket_id VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(50) NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_manual_sends_created_at ON manual_sends(created_at);
+CREATE INDEX idx_manual_sends_order_id ON manual_sends(order_id);
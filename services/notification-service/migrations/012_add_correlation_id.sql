@@ -0,0 +1,4 @@
+-- Correlation ID of the event behind a notification, published with its
+-- delivery status events
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS correlation_id VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE scheduled_notifications ADD COLUMN IF NOT EXISTS correlation_id VARCHAR(255) NOT NULL DEFAULT '';
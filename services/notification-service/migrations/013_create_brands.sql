@@ -0,0 +1,20 @@
+-- Storefront brands; empty settings fall back to the default brand
+CREATE TABLE IF NOT EXISTS brands (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    logo_url TEXT NOT NULL DEFAULT '',
+    primary_color VARCHAR(9) NOT NULL DEFAULT '',
+    support_email VARCHAR(255) NOT NULL DEFAULT '',
+    storefront_url TEXT NOT NULL DEFAULT '',
+    footer_text TEXT NOT NULL DEFAULT '',
+    from_name VARCHAR(255) NOT NULL DEFAULT '',
+    from_email VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Brand a notification was sent for; held ones keep it to be themed and
+-- sent as the brand later
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS brand_id VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE scheduled_notifications ADD COLUMN IF NOT EXISTS brand_id VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE digest_items ADD COLUMN IF NOT EXISTS brand_id VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,63 @@
+-- Uploaded recipient lists campaigns are sent to
+CREATE TABLE IF NOT EXISTS segments (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    size INTEGER NOT NULL DEFAULT 0,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS segment_members (
+    segment_id VARCHAR(255) NOT NULL REFERENCES segments(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL DEFAULT '',
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    data JSONB NOT NULL DEFAULT '{}',
+    PRIMARY KEY (segment_id, email)
+);
+
+-- Bulk email sends of one template to a segment
+CREATE TABLE IF NOT EXISTS campaigns (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    template VARCHAR(100) NOT NULL,
+    segment_id VARCHAR(255) NOT NULL REFERENCES segments(id),
+    brand_id VARCHAR(64) NOT NULL DEFAULT '',
+    data JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(50) NOT NULL,
+    scheduled_at TIMESTAMP NOT NULL,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX idx_campaigns_status_scheduled_at ON campaigns(status, scheduled_at);
+CREATE INDEX idx_campaigns_created_at ON campaigns(created_at);
+
+-- A campaign's recipients, copied from its segment when it is created
+CREATE TABLE IF NOT EXISTS campaign_recipients (
+    id BIGSERIAL PRIMARY KEY,
+    campaign_id VARCHAR(255) NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL DEFAULT '',
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    data JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(50) NOT NULL DEFAULT 'pending',
+    error TEXT NOT NULL DEFAULT '',
+    notification_id VARCHAR(255) NOT NULL DEFAULT '',
+    claimed_at TIMESTAMP,
+    sent_at TIMESTAMP
+);
+
+CREATE INDEX idx_campaign_recipients_campaign_status ON campaign_recipients(campaign_id, status);
+
+-- Addresses that must not receive bulk email: bounces, spam complaints,
+-- unsubscribes and manual entries
+CREATE TABLE IF NOT EXISTS suppressions (
+    address VARCHAR(255) PRIMARY KEY,
+    reason VARCHAR(50) NOT NULL,
+    source VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
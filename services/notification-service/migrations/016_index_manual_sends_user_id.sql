@@ -0,0 +1,2 @@
+-- Manual sends are listed per customer by the backoffice
+CREATE INDEX IF NOT EXISTS idx_manual_sends_user_id ON manual_sends(user_id);
@@ -0,0 +1,7 @@
+-- Store the shipment of shipped orders, previously only sent in the
+-- order.shipped event
+-- Migration: 003_add_shipment_tracking.sql
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS tracking_number VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS carrier VARCHAR(50);
@@ -0,0 +1,69 @@
+-- Checkout sagas: where each order's checkout got to and what it holds in
+-- other services, so a checkout interrupted by a crash or a timeout is
+-- resumed or compensated
+-- Migration: 004_create_checkout_sagas.sql
+
+CREATE TABLE IF NOT EXISTS checkout_sagas (
+    id VARCHAR(255) PRIMARY KEY,
+    order_id VARCHAR(255) NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'running',
+    -- step is the step being run, or the one that failed once compensating
+    step VARCHAR(50) NOT NULL,
+
+    -- What the checkout holds: inventory-service reservation IDs by product
+    -- ID, payment-service's payment and shipping-service's shipment
+    reservations JSONB NOT NULL DEFAULT '{}',
+    payment_id VARCHAR(255),
+    transaction_id VARCHAR(255),
+    payment_intent_id VARCHAR(255),
+    shipment_id VARCHAR(255),
+
+    failure_reason TEXT,
+    attempts INTEGER NOT NULL DEFAULT 1,
+
+    -- A running saga past deadline_at is compensated; one whose lease,
+    -- locked_until, has run out is taken over by the recovery loop
+    deadline_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP,
+
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT chk_checkout_sagas_status CHECK (status IN (
+        'running',
+        'compensating',
+        'completed',
+        'compensated'
+    ))
+);
+
+CREATE INDEX IF NOT EXISTS idx_checkout_sagas_unfinished
+    ON checkout_sagas(locked_until)
+    WHERE status IN ('running', 'compensating');
+
+-- Every step run or compensated, for support to follow a checkout
+CREATE TABLE IF NOT EXISTS checkout_saga_steps (
+    id SERIAL PRIMARY KEY,
+    saga_id VARCHAR(255) NOT NULL REFERENCES checkout_sagas(id) ON DELETE CASCADE,
+    step VARCHAR(50) NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('execute', 'compensate')),
+    outcome VARCHAR(20) NOT NULL CHECK (outcome IN ('succeeded', 'failed')),
+    detail TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_checkout_saga_steps_saga_id ON checkout_saga_steps(saga_id);
+
+-- Checkout authorizes payments, capturing them when orders ship, and voids
+-- them when it fails
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS chk_orders_payment_status;
+ALTER TABLE orders
+    ADD CONSTRAINT chk_orders_payment_status CHECK (payment_status IN (
+        'pending',
+        'authorized',
+        'captured',
+        'failed',
+        'refunded',
+        'partially_refunded',
+        'voided'
+    ));
@@ -0,0 +1,6 @@
+-- Store the part of an order's total paid with a gift card; the payment
+-- covers the rest
+-- Migration: 005_add_gift_card_amount.sql
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS gift_card_amount DECIMAL(10, 2) NOT NULL DEFAULT 0;
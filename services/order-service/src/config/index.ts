@@ -25,6 +25,31 @@ export const config = {
     inventoryUrl: process.env.INVENTORY_SERVICE_URL || 'http://inventory-service:8080',
     paymentUrl: process.env.PAYMENT_SERVICE_URL || 'http://payment-service:8001',
     cartUrl: process.env.CART_SERVICE_URL || 'http://cart-service:3000',
+    // pricingUrl is pricing-service, which prices orders; empty takes item
+    // prices from the request
+    pricingUrl: process.env.PRICING_SERVICE_URL || '',
+    // couponUrl is coupon-service, which redeems coupon codes; empty
+    // rejects orders with a couponCode
+    couponUrl: process.env.COUPON_SERVICE_URL || '',
+    // giftCardUrl is giftcard-service, which redeems gift cards; empty
+    // rejects orders with a giftCardCode
+    giftCardUrl: process.env.GIFTCARD_SERVICE_URL || '',
+    // taxUrl is tax-service, which calculates and records orders' tax;
+    // empty charges the flat order.taxRate
+    taxUrl: process.env.TAX_SERVICE_URL || '',
+    // shippingUrl is shipping-service, which creates orders' shipments at
+    // checkout; empty leaves the shipment to be given when orders ship
+    shippingUrl: process.env.SHIPPING_SERVICE_URL || '',
+  },
+
+  // The checkout saga: a checkout not finished within timeoutSeconds is
+  // compensated. An instance running a saga holds it for leaseSeconds at a
+  // time; the recovery loop takes over sagas whose lease ran out every
+  // recoveryIntervalSeconds.
+  saga: {
+    timeoutSeconds: parseInt(process.env.SAGA_TIMEOUT_SECONDS || '120', 10),
+    leaseSeconds: parseInt(process.env.SAGA_LEASE_SECONDS || '30', 10),
+    recoveryIntervalSeconds: parseInt(process.env.SAGA_RECOVERY_INTERVAL_SECONDS || '15', 10),
   },
 
   otel: {
@@ -1,10 +1,33 @@
 import { PoolClient } from 'pg';
 import { v4 as uuidv4 } from 'uuid';
 import { query, transaction } from './pool';
+import { config } from '../config';
 import { Order, OrderItem, OrderStatus, PaymentStatus, CreateOrderRequest } from '../models/order';
 
+// orderTotals prices an order as create stores it, in dollars:
+// discountAmount is taken off the subtotal before tax, and taxAmount, when
+// given, replaces the flat TAX_RATE
+export function orderTotals(orderData: CreateOrderRequest, discountAmount = 0, taxAmount?: number) {
+  const subtotal = orderData.items.reduce((sum, item) => sum + item.price * item.quantity, 0);
+  const tax = taxAmount ?? (subtotal - discountAmount) * config.order.taxRate;
+  const shippingAmount = 9.99;
+  const totalAmount = subtotal - discountAmount + tax + shippingAmount;
+  return { subtotal, tax, shippingAmount, totalAmount };
+}
+
 export class OrderRepository {
-  async create(orderData: CreateOrderRequest): Promise<Order> {
+  // create stores an order; discountAmount is taken off the subtotal
+  // before tax. orderId is given when the order's ID is needed before it
+  // is stored, e.g. to redeem a coupon on it. taxAmount is the order's tax,
+  // e.g. from tax-service; without it the flat TAX_RATE applies.
+  // giftCardAmount is the part of the total a gift card paid.
+  async create(
+    orderData: CreateOrderRequest,
+    discountAmount = 0,
+    orderId: string = uuidv4(),
+    taxAmount?: number,
+    giftCardAmount = 0
+  ): Promise<Order> {
     return transaction(async (client) => {
       // Generate order number (format: ORD-YYYYMMDD-XXXXX)
       const date = new Date();
@@ -12,22 +35,17 @@ export class OrderRepository {
       const random = Math.floor(Math.random() * 100000).toString().padStart(5, '0');
       const orderNumber = `ORD-${dateStr}-${random}`;
 
-      const orderId = uuidv4();
-
       // Calculate totals
-      const subtotal = orderData.items.reduce((sum, item) => sum + item.price * item.quantity, 0);
-      const taxAmount = subtotal * 0.08; // 8% tax
-      const shippingAmount = 9.99;
-      const totalAmount = subtotal + taxAmount + shippingAmount;
+      const { subtotal, tax, shippingAmount, totalAmount } = orderTotals(orderData, discountAmount, taxAmount);
 
       // Insert order
       const orderQuery = `
         INSERT INTO orders (
           id, order_number, user_id, status, payment_status, payment_method,
-          subtotal, tax_amount, shipping_amount, discount_amount, total_amount,
+          subtotal, tax_amount, shipping_amount, discount_amount, total_amount, gift_card_amount,
           shipping_address, billing_address, customer_notes,
           created_at, updated_at
-        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
         RETURNING *
       `;
 
@@ -39,10 +57,11 @@ export class OrderRepository {
         PaymentStatus.PENDING,
         orderData.paymentMethod,
         subtotal,
-        taxAmount,
+        tax,
         shippingAmount,
-        0, // discount_amount
+        discountAmount,
         totalAmount,
+        giftCardAmount,
         JSON.stringify(orderData.shippingAddress),
         JSON.stringify(orderData.billingAddress),
         orderData.customerNotes || null,
@@ -118,10 +137,13 @@ export class OrderRepository {
       shippingAmount: parseFloat(orderRow.shipping_amount),
       discountAmount: parseFloat(orderRow.discount_amount),
       totalAmount: parseFloat(orderRow.total_amount),
+      giftCardAmount: parseFloat(orderRow.gift_card_amount),
       shippingAddress: JSON.parse(orderRow.shipping_address),
       billingAddress: JSON.parse(orderRow.billing_address),
       paymentIntentId: orderRow.payment_intent_id,
       transactionId: orderRow.transaction_id,
+      trackingNumber: orderRow.tracking_number ?? undefined,
+      carrier: orderRow.carrier ?? undefined,
       customerNotes: orderRow.customer_notes,
       internalNotes: orderRow.internal_notes,
       createdAt: orderRow.created_at,
@@ -164,6 +186,34 @@ export class OrderRepository {
     });
   }
 
+  async markShipped(orderId: string, trackingNumber: string, carrier: string): Promise<Order> {
+    return transaction(async (client) => {
+      const updateQuery = `
+        UPDATE orders
+        SET status = $1, tracking_number = $2, carrier = $3, shipped_at = $4, updated_at = $4
+        WHERE id = $5
+      `;
+
+      await client.query(updateQuery, [OrderStatus.SHIPPED, trackingNumber, carrier, new Date(), orderId]);
+
+      return this.findById(orderId, client);
+    });
+  }
+
+  async markDelivered(orderId: string): Promise<Order> {
+    return transaction(async (client) => {
+      const updateQuery = `
+        UPDATE orders
+        SET status = $1, delivered_at = $2, updated_at = $2
+        WHERE id = $3
+      `;
+
+      await client.query(updateQuery, [OrderStatus.DELIVERED, new Date(), orderId]);
+
+      return this.findById(orderId, client);
+    });
+  }
+
   async updatePaymentStatus(
     orderId: string,
     paymentStatus: PaymentStatus,
@@ -189,11 +239,12 @@ export class OrderRepository {
         orderId,
       ]);
 
-      // If payment captured, update order status to confirmed
-      if (paymentStatus === PaymentStatus.CAPTURED) {
+      // If payment authorized or captured, confirm an order awaiting it;
+      // payments captured when orders ship leave their status alone
+      if (paymentStatus === PaymentStatus.AUTHORIZED || paymentStatus === PaymentStatus.CAPTURED) {
         await client.query(
-          'UPDATE orders SET status = $1 WHERE id = $2',
-          [OrderStatus.CONFIRMED, orderId]
+          'UPDATE orders SET status = $1 WHERE id = $2 AND status IN ($3, $4)',
+          [OrderStatus.CONFIRMED, orderId, OrderStatus.PENDING, OrderStatus.PAYMENT_PENDING]
         );
       }
 
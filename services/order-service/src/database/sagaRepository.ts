@@ -0,0 +1,116 @@
+import { v4 as uuidv4 } from 'uuid';
+import { query } from './pool';
+import { CheckoutSaga, SagaStatus, SagaStep } from '../models/saga';
+
+export class SagaRepository {
+  // create starts an order's saga at its first step, leased to the caller
+  // until lockedUntil. It returns null if the order's checkout already
+  // started.
+  async create(orderId: string, deadlineAt: Date, lockedUntil: Date): Promise<CheckoutSaga | null> {
+    const result = await query(
+      `
+        INSERT INTO checkout_sagas (id, order_id, status, step, deadline_at, locked_until)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (order_id) DO NOTHING
+        RETURNING *
+      `,
+      [uuidv4(), orderId, SagaStatus.RUNNING, SagaStep.RESERVE_INVENTORY, deadlineAt, lockedUntil]
+    );
+
+    return result.rows.length ? this.toSaga(result.rows[0]) : null;
+  }
+
+  async findByOrderId(orderId: string): Promise<CheckoutSaga | null> {
+    const result = await query('SELECT * FROM checkout_sagas WHERE order_id = $1', [orderId]);
+    return result.rows.length ? this.toSaga(result.rows[0]) : null;
+  }
+
+  // save stores the saga's progress, renewing its lease until lockedUntil;
+  // a finished saga's lease is dropped
+  async save(saga: CheckoutSaga, lockedUntil: Date | null): Promise<void> {
+    await query(
+      `
+        UPDATE checkout_sagas
+        SET status = $1, step = $2, reservations = $3, payment_id = $4, transaction_id = $5,
+            payment_intent_id = $6, shipment_id = $7, failure_reason = $8, locked_until = $9,
+            updated_at = $10
+        WHERE id = $11
+      `,
+      [
+        saga.status,
+        saga.step,
+        JSON.stringify(saga.reservations),
+        saga.paymentId || null,
+        saga.transactionId || null,
+        saga.paymentIntentId || null,
+        saga.shipmentId || null,
+        saga.failureReason || null,
+        lockedUntil,
+        new Date(),
+        saga.id,
+      ]
+    );
+    saga.lockedUntil = lockedUntil ?? undefined;
+  }
+
+  // claimAbandoned leases up to limit unfinished sagas whose lease has run
+  // out, e.g. because the instance running them stopped, to the caller
+  // until lockedUntil. Sagas another instance is claiming are skipped.
+  async claimAbandoned(limit: number, lockedUntil: Date): Promise<CheckoutSaga[]> {
+    const result = await query(
+      `
+        UPDATE checkout_sagas
+        SET locked_until = $1, attempts = attempts + 1, updated_at = NOW()
+        WHERE id IN (
+          SELECT id FROM checkout_sagas
+          WHERE status IN ($2, $3) AND (locked_until IS NULL OR locked_until < NOW())
+          ORDER BY locked_until NULLS FIRST
+          LIMIT $4
+          FOR UPDATE SKIP LOCKED
+        )
+        RETURNING *
+      `,
+      [lockedUntil, SagaStatus.RUNNING, SagaStatus.COMPENSATING, limit]
+    );
+
+    return result.rows.map((row: any) => this.toSaga(row));
+  }
+
+  // recordStep logs a step run or compensated, with what went wrong if it
+  // failed
+  async recordStep(
+    sagaId: string,
+    step: SagaStep,
+    action: 'execute' | 'compensate',
+    outcome: 'succeeded' | 'failed',
+    detail?: string
+  ): Promise<void> {
+    await query(
+      `
+        INSERT INTO checkout_saga_steps (saga_id, step, action, outcome, detail)
+        VALUES ($1, $2, $3, $4, $5)
+      `,
+      [sagaId, step, action, outcome, detail || null]
+    );
+  }
+
+  private toSaga(row: any): CheckoutSaga {
+    return {
+      id: row.id,
+      orderId: row.order_id,
+      status: row.status,
+      step: row.step,
+      reservations: row.reservations || {},
+      paymentId: row.payment_id ?? undefined,
+      transactionId: row.transaction_id ?? undefined,
+      paymentIntentId: row.payment_intent_id ?? undefined,
+      shipmentId: row.shipment_id ?? undefined,
+      failureReason: row.failure_reason ?? undefined,
+      attempts: row.attempts,
+      deadlineAt: row.deadline_at,
+      lockedUntil: row.locked_until ?? undefined,
+      createdAt: row.created_at,
+      updatedAt: row.updated_at,
+    };
+  }
+}
@@ -6,9 +6,12 @@ import cookieParser from 'cookie-parser';
 import { config } from './config';
 import { getPool, closePool } from './database/pool';
 import { OrderRepository } from './database/orderRepository';
+import { SagaRepository } from './database/sagaRepository';
 import { OrderService } from './services/orderService';
 import { EventPublisher } from './services/eventPublisher';
+import { CheckoutOrchestrator } from './saga/checkoutSaga';
 import { createOrderRoutes } from './routes/orderRoutes';
+import { createInternalOrderRoutes } from './routes/internalRoutes';
 import { correlationIdMiddleware } from './middleware/correlation';
 import { logger } from './middleware/logger';
 
@@ -51,7 +54,11 @@ async function main() {
   await eventPublisher.connect();
 
   const orderRepo = new OrderRepository();
-  const orderService = new OrderService(orderRepo, eventPublisher);
+  const checkout = new CheckoutOrchestrator(orderRepo, new SagaRepository(), eventPublisher);
+  const orderService = new OrderService(orderRepo, eventPublisher, checkout);
+
+  // Resume or compensate checkouts left unfinished, e.g. by a restart
+  checkout.startRecovery();
 
   // Routes
   app.get('/health', async (req, res) => {
@@ -73,6 +80,7 @@ async function main() {
   });
 
   app.use('/api/v1/orders', createOrderRoutes(orderService));
+  app.use(createInternalOrderRoutes(orderService));
 
   // Error handling
   app.use((err: Error, req: express.Request, res: express.Response, next: express.NextFunction) => {
@@ -91,6 +99,7 @@ async function main() {
   // Graceful shutdown
   const shutdown = async () => {
     logger.info('Shutting down gracefully...');
+    checkout.stopRecovery();
 
     server.close(async () => {
       await closePool();
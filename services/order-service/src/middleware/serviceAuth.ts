@@ -48,6 +48,16 @@ const SERVICE_REGISTRY: Record<string, ServiceCredentials> = {
     apiKey: process.env.INVENTORY_SERVICE_API_KEY || 'dev-inventory-service-key-change-in-production',
     allowedEndpoints: ['/api/v1/orders/:orderId/inventory'], // Can update inventory status
   },
+  'subscription-service': {
+    serviceName: 'subscription-service',
+    apiKey: process.env.SUBSCRIPTION_SERVICE_API_KEY || 'dev-subscription-service-key-change-in-production',
+    // Places, reads and pays customers' recurring orders
+    allowedEndpoints: [
+      '/api/v1/internal/orders',
+      '/api/v1/internal/orders/:orderId',
+      '/api/v1/internal/orders/:orderId/payment',
+    ],
+  },
 };
 
 /**
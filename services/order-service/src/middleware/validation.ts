@@ -79,6 +79,11 @@ export const createOrderSchema = Joi.object({
     'any.required': 'Payment method is required',
   }),
   paymentDetails: Joi.object().optional(), // Don't validate - should use external payment provider
+  promoCodes: Joi.array().items(Joi.string().trim().min(1).max(50)).max(5).optional().messages({
+    'array.max': 'At most 5 promotion codes can be applied',
+  }),
+  couponCode: Joi.string().trim().min(1).max(50).optional(),
+  giftCardCode: Joi.string().trim().min(1).max(50).optional(),
 });
 
 /**
@@ -16,6 +16,7 @@ export enum PaymentStatus {
   CAPTURED = 'captured',
   FAILED = 'failed',
   REFUNDED = 'refunded',
+  VOIDED = 'voided',
 }
 
 export enum PaymentMethod {
@@ -67,6 +68,9 @@ export interface Order {
   shippingAmount: number;
   discountAmount: number;
   totalAmount: number;
+  // giftCardAmount is the part of totalAmount paid with a gift card; the
+  // payment covers the rest
+  giftCardAmount: number;
 
   // Addresses
   shippingAddress: ShippingAddress;
@@ -76,6 +80,10 @@ export interface Order {
   paymentIntentId?: string;
   transactionId?: string;
 
+  // Shipment, once shipped
+  trackingNumber?: string;
+  carrier?: string;
+
   // Metadata
   customerNotes?: string;
   internalNotes?: string;
@@ -102,6 +110,78 @@ export interface CreateOrderRequest {
   billingAddress: BillingAddress;
   paymentMethod: PaymentMethod;
   customerNotes?: string;
+  // promoCodes are the promotion codes the customer entered, applied when
+  // pricing-service prices the order
+  promoCodes?: string[];
+  // couponCode is a coupon the customer entered, redeemed with
+  // coupon-service on the subtotal left after promotions
+  couponCode?: string;
+  // giftCardCode is a gift card the customer entered, redeemed with
+  // giftcard-service on the total, up to its balance
+  giftCardCode?: string;
+}
+
+// amountDue is what an order's payment covers, in dollars: its total less
+// what a gift card paid
+export function amountDue(order: Order): number {
+  return Math.max(Math.round((order.totalAmount - order.giftCardAmount) * 100), 0) / 100;
+}
+
+// An amount in minor units, e.g. cents, as pricing-service returns it
+export interface Money {
+  minor_units: number;
+  currency: string;
+}
+
+// An order's items priced by pricing-service
+export interface PriceQuote {
+  currency: string;
+  lines: Array<{
+    product_id: string;
+    quantity: number;
+    unit_price: Money;
+    subtotal: Money;
+    discount: Money;
+    total: Money;
+    promotion_id?: string;
+  }>;
+  subtotal: Money;
+  discount_total: Money;
+  total: Money;
+  promotions: Array<{ id: string; name: string; code?: string; type: string; discount: Money }>;
+  unused_codes?: string[];
+  quoted_at: string;
+}
+
+// A coupon redeemed on an order by coupon-service
+export interface CouponRedemption {
+  id: string;
+  code: string;
+  order_id: string;
+  order_amount: Money;
+  discount: Money;
+  status: string;
+}
+
+// A gift card redeemed on an order by giftcard-service; amount is what the
+// card paid and balance what it has left
+export interface GiftCardRedemption {
+  id: string;
+  code: string;
+  order_id: string;
+  amount: Money;
+  balance: Money;
+  status: string;
+}
+
+// An order's tax calculated and recorded by tax-service
+export interface TaxCalculation {
+  id: string;
+  reference: string;
+  provider: string;
+  currency: string;
+  taxable_amount: Money;
+  tax_amount: Money;
 }
 
 export interface UpdateOrderStatusRequest {
@@ -131,4 +211,7 @@ export interface PaymentResponse {
   transactionId?: string;
   paymentIntentId?: string;
   error?: string;
+  // pending is set while the checkout saga is still retrying or
+  // compensating; the order's status tells how it ended
+  pending?: boolean;
 }
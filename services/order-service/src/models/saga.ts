@@ -0,0 +1,62 @@
+export enum SagaStatus {
+  RUNNING = 'running',
+  COMPENSATING = 'compensating',
+  COMPLETED = 'completed',
+  COMPENSATED = 'compensated',
+}
+
+// The checkout's steps, in the order they run
+export enum SagaStep {
+  RESERVE_INVENTORY = 'reserve_inventory',
+  AUTHORIZE_PAYMENT = 'authorize_payment',
+  CONFIRM_RESERVATION = 'confirm_reservation',
+  CREATE_SHIPMENT = 'create_shipment',
+}
+
+export const SAGA_STEPS: SagaStep[] = [
+  SagaStep.RESERVE_INVENTORY,
+  SagaStep.AUTHORIZE_PAYMENT,
+  SagaStep.CONFIRM_RESERVATION,
+  SagaStep.CREATE_SHIPMENT,
+];
+
+// An order's checkout saga: the step it is at and what it holds in other
+// services, to be released if it fails
+export interface CheckoutSaga {
+  id: string;
+  orderId: string;
+  status: SagaStatus;
+  step: SagaStep;
+
+  // inventory-service reservation IDs by product ID
+  reservations: Record<string, string>;
+  paymentId?: string;
+  transactionId?: string;
+  paymentIntentId?: string;
+  shipmentId?: string;
+
+  failureReason?: string;
+  attempts: number;
+
+  deadlineAt: Date;
+  lockedUntil?: Date;
+  createdAt: Date;
+  updatedAt: Date;
+}
+
+// A payment authorized by payment-service
+export interface PaymentAuthorization {
+  success: boolean;
+  payment_id?: string;
+  transaction_id?: string;
+  payment_intent_id?: string;
+  status: string;
+  error?: string;
+}
+
+// A shipment created by shipping-service
+export interface Shipment {
+  id: string;
+  carrier?: string;
+  tracking_number?: string;
+}
@@ -0,0 +1,115 @@
+import { Router, Request, Response } from 'express';
+import { OrderService } from '../services/orderService';
+import { CreateOrderRequest } from '../models/order';
+import { logger } from '../middleware/logger';
+import { authenticateService } from '../middleware/serviceAuth';
+
+// createInternalOrderRoutes serves other services, authenticated with their
+// service credentials rather than a customer's token: subscription-service
+// places and pays its customers' recurring orders. Routes are registered
+// with their full paths, which the request signatures cover.
+export function createInternalOrderRoutes(orderService: OrderService): Router {
+  const router = Router();
+
+  // Create an order for a customer. An orderId makes it idempotent: an
+  // order that exists already is returned with 200.
+  router.post('/api/v1/internal/orders', authenticateService, async (req: Request, res: Response) => {
+    try {
+      const { orderId, inventoryReserved, ...orderData }: CreateOrderRequest & {
+        orderId?: string;
+        inventoryReserved?: boolean;
+      } = req.body;
+
+      if (!orderData.userId || !orderData.items || orderData.items.length === 0) {
+        return res.status(400).json({ error: 'Missing required fields' });
+      }
+
+      if (!orderData.shippingAddress || !orderData.billingAddress) {
+        return res.status(400).json({ error: 'Shipping and billing addresses are required' });
+      }
+
+      if (orderData.couponCode || orderData.giftCardCode) {
+        return res.status(400).json({ error: 'Coupons and gift cards need the customer\'s token' });
+      }
+
+      if (orderId) {
+        const existing = await orderService.getOrder(orderId).catch(() => null);
+        if (existing) {
+          if (existing.userId !== orderData.userId) {
+            return res.status(409).json({ error: 'Order ID is taken' });
+          }
+          return res.json({ order: existing });
+        }
+      }
+
+      const order = await orderService.createOrder(orderData, undefined, { orderId, inventoryReserved });
+      logger.info('Order created for service', { orderId: order.id, serviceId: req.serviceId });
+      res.status(201).json({ order });
+    } catch (error: any) {
+      logger.error('Failed to create order for service', { serviceId: req.serviceId, error: error.message });
+
+      if (
+        error.message?.includes('Insufficient inventory') ||
+        error.message?.includes('not found') ||
+        error.message?.startsWith('Tax not calculated')
+      ) {
+        return res.status(400).json({ error: error.message });
+      }
+
+      res.status(500).json({ error: 'Failed to create order' });
+    }
+  });
+
+  // Get an order
+  router.get('/api/v1/internal/orders/:orderId', authenticateService, async (req: Request, res: Response) => {
+    try {
+      const order = await orderService.getOrder(req.params.orderId);
+      res.json({ order });
+    } catch (error: any) {
+      if (error.message === 'Order not found') {
+        return res.status(404).json({ error: 'Order not found' });
+      }
+
+      logger.error('Failed to get order for service', { serviceId: req.serviceId, error: error.message });
+      res.status(500).json({ error: 'Failed to get order' });
+    }
+  });
+
+  // Check out an order, as the customer's payment request does
+  router.post('/api/v1/internal/orders/:orderId/payment', authenticateService, async (req: Request, res: Response) => {
+    try {
+      const paymentResponse = await orderService.processPayment(req.params.orderId);
+
+      if (paymentResponse.success) {
+        res.json({
+          success: true,
+          message: 'Payment processed successfully',
+          transactionId: paymentResponse.transactionId,
+        });
+      } else if (paymentResponse.pending) {
+        res.status(202).json({
+          success: false,
+          pending: true,
+          message: 'Checkout is in progress',
+        });
+      } else {
+        res.status(400).json({
+          success: false,
+          error: paymentResponse.error || 'Payment failed',
+        });
+      }
+    } catch (error: any) {
+      if (error.message === 'Order not found') {
+        return res.status(404).json({ error: 'Order not found' });
+      }
+      if (error.message?.startsWith('Order cannot be paid')) {
+        return res.status(409).json({ error: error.message });
+      }
+
+      logger.error('Payment processing failed for service', { serviceId: req.serviceId, error: error.message });
+      res.status(500).json({ error: error.message });
+    }
+  });
+
+  return router;
+}
@@ -4,6 +4,16 @@ import { CreateOrderRequest, UpdateOrderStatusRequest, OrderStatus } from '../mo
 import { logger } from '../middleware/logger';
 import { authenticateJWT, requireAdmin } from '../middleware/auth';
 
+// bearerToken returns the token the request was authenticated with, from
+// the Authorization header or the auth_token cookie
+function bearerToken(req: Request): string | undefined {
+  const [scheme, token] = (req.headers.authorization || '').split(' ');
+  if (scheme === 'Bearer' && token) {
+    return token;
+  }
+  return req.cookies?.auth_token;
+}
+
 export function createOrderRoutes(orderService: OrderService): Router {
   const router = Router();
 
@@ -27,12 +37,18 @@ export function createOrderRoutes(orderService: OrderService): Router {
         return res.status(400).json({ error: 'Shipping and billing addresses are required' });
       }
 
-      const order = await orderService.createOrder(orderData);
+      const order = await orderService.createOrder(orderData, bearerToken(req));
       res.status(201).json({ order });
     } catch (error: any) {
       logger.error('Failed to create order', { error: error.message });
 
-      if (error.message?.includes('Insufficient inventory') || error.message?.includes('not found')) {
+      if (
+        error.message?.includes('Insufficient inventory') ||
+        error.message?.includes('not found') ||
+        error.message?.startsWith('Coupon') ||
+        error.message?.startsWith('Gift card') ||
+        error.message?.startsWith('Tax not calculated')
+      ) {
         return res.status(400).json({ error: error.message });
       }
 
@@ -103,6 +119,13 @@ export function createOrderRoutes(orderService: OrderService): Router {
           message: 'Payment processed successfully',
           transactionId: paymentResponse.transactionId,
         });
+      } else if (paymentResponse.pending) {
+        // The checkout is being retried; the order's status tells how it ends
+        res.status(202).json({
+          success: false,
+          pending: true,
+          message: 'Checkout is in progress',
+        });
       } else {
         res.status(400).json({
           success: false,
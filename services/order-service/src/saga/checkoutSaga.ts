@@ -0,0 +1,478 @@
+import axios from 'axios';
+import { OrderRepository } from '../database/orderRepository';
+import { SagaRepository } from '../database/sagaRepository';
+import { EventPublisher } from '../services/eventPublisher';
+import { config } from '../config';
+import { logger } from '../middleware/logger';
+import { Order, OrderStatus, PaymentStatus, amountDue } from '../models/order';
+import { CheckoutSaga, SagaStatus, SagaStep, SAGA_STEPS, PaymentAuthorization, Shipment } from '../models/saga';
+
+// How many abandoned sagas one recovery pass takes over
+const RECOVERY_BATCH_SIZE = 20;
+
+// StepFailure is a step another service refused, e.g. for stock it doesn't
+// have or a declined payment. Retrying won't change the answer, so the saga
+// is compensated; other errors, such as timeouts, leave the step to be
+// retried.
+class StepFailure extends Error {}
+
+// refused reports whether a service answered with a client error, which
+// retrying won't change
+function refused(error: any): boolean {
+  const status = error.response?.status;
+  return status !== undefined && status >= 400 && status < 500 && status !== 408 && status !== 429;
+}
+
+// CheckoutOrchestrator runs orders' checkout as a saga: it reserves the
+// items' inventory, authorizes the payment, confirms the reservations and
+// creates the shipment. If a step is refused, or the checkout doesn't
+// finish within SAGA_TIMEOUT_SECONDS, the steps before it are compensated:
+// the payment is voided and the reservations released. Each step's outcome
+// is stored, so a checkout interrupted midway, e.g. by a restart, is
+// resumed or compensated by the recovery loop.
+export class CheckoutOrchestrator {
+  private recoveryTimer?: NodeJS.Timeout;
+  private recovering = false;
+
+  constructor(
+    private orderRepo: OrderRepository,
+    private sagaRepo: SagaRepository,
+    private eventPublisher: EventPublisher
+  ) {}
+
+  // start runs the checkout of an order awaiting payment and returns its
+  // saga as far as it got: completed, compensated, or still running or
+  // compensating while a service fails, to be finished by recovery. It
+  // returns null if the order's checkout already started.
+  async start(order: Order): Promise<CheckoutSaga | null> {
+    const deadline = new Date(Date.now() + config.saga.timeoutSeconds * 1000);
+    const saga = await this.sagaRepo.create(order.id, deadline, this.lease());
+    if (!saga) {
+      return null;
+    }
+
+    logger.info('Checkout saga started', { orderId: order.id, sagaId: saga.id });
+    return this.run(saga, order);
+  }
+
+  // capture takes the payment checkout authorized for an order, when the
+  // order ships. Orders a gift card paid in full have no payment to take.
+  async capture(order: Order): Promise<void> {
+    const saga = await this.sagaRepo.findByOrderId(order.id);
+    if (saga?.status === SagaStatus.COMPLETED && !saga.paymentId && amountDue(order) === 0) {
+      await this.orderRepo.updatePaymentStatus(order.id, PaymentStatus.CAPTURED);
+      logger.info('Order paid by gift card', { orderId: order.id });
+      return;
+    }
+    if (!saga?.paymentId) {
+      throw new Error('Order has no authorized payment');
+    }
+
+    let payment: PaymentAuthorization;
+    try {
+      const response = await axios.post(
+        `${config.services.paymentUrl}/api/v1/payments/${saga.paymentId}/capture`,
+        {},
+        { timeout: 10000 }
+      );
+      payment = response.data;
+    } catch (error: any) {
+      logger.error('Payment capture failed', { orderId: order.id, paymentId: saga.paymentId, error: error.message });
+      throw new Error('Failed to capture payment');
+    }
+
+    await this.orderRepo.updatePaymentStatus(
+      order.id,
+      PaymentStatus.CAPTURED,
+      payment.transaction_id,
+      payment.payment_intent_id
+    );
+    logger.info('Payment captured', { orderId: order.id, paymentId: saga.paymentId });
+  }
+
+  // release gives back what a completed checkout holds for an order being
+  // cancelled: its reservations and, unless captured, its payment's
+  // authorization. An order whose checkout is still running can't be
+  // cancelled yet.
+  async release(order: Order): Promise<void> {
+    const saga = await this.sagaRepo.findByOrderId(order.id);
+    if (!saga || saga.status === SagaStatus.COMPENSATED) {
+      return;
+    }
+    if (saga.status !== SagaStatus.COMPLETED) {
+      throw new Error('Checkout is in progress');
+    }
+
+    if (order.paymentStatus === PaymentStatus.AUTHORIZED) {
+      await this.voidPayment(saga, order);
+      await this.orderRepo.updatePaymentStatus(
+        order.id,
+        PaymentStatus.VOIDED,
+        saga.transactionId,
+        saga.paymentIntentId
+      );
+    }
+    await this.releaseReservations(saga);
+  }
+
+  // startRecovery periodically takes over abandoned sagas; see recover
+  startRecovery(): void {
+    this.recoveryTimer = setInterval(() => {
+      this.recover().catch((error) => {
+        logger.error('Checkout saga recovery failed', { error: error.message });
+      });
+    }, config.saga.recoveryIntervalSeconds * 1000);
+  }
+
+  stopRecovery(): void {
+    if (this.recoveryTimer) {
+      clearInterval(this.recoveryTimer);
+    }
+  }
+
+  // recover takes over the sagas no instance is running, because a step
+  // failed or the instance running them stopped, once their lease has run
+  // out: they are resumed or, past their deadline, compensated.
+  async recover(): Promise<void> {
+    if (this.recovering) {
+      return;
+    }
+    this.recovering = true;
+
+    try {
+      const sagas = await this.sagaRepo.claimAbandoned(RECOVERY_BATCH_SIZE, this.lease());
+      for (const saga of sagas) {
+        logger.info('Recovering checkout saga', {
+          orderId: saga.orderId,
+          sagaId: saga.id,
+          status: saga.status,
+          step: saga.step,
+          attempts: saga.attempts,
+        });
+
+        try {
+          const order = await this.orderRepo.findById(saga.orderId);
+          await this.run(saga, order);
+        } catch (error: any) {
+          logger.error('Failed to recover checkout saga', { sagaId: saga.id, error: error.message });
+        }
+      }
+    } finally {
+      this.recovering = false;
+    }
+  }
+
+  // run runs the saga's steps from the one it is at, then compensates it if
+  // one was refused or it ran out of time. A step or compensation failing
+  // otherwise stops it, leaving it to recovery.
+  private async run(saga: CheckoutSaga, order: Order): Promise<CheckoutSaga> {
+    while (saga.status === SagaStatus.RUNNING) {
+      if (Date.now() >= new Date(saga.deadlineAt).getTime()) {
+        await this.fail(saga, `Checkout timed out at ${saga.step}`);
+        break;
+      }
+
+      try {
+        await this.execute(saga, order);
+        await this.sagaRepo.recordStep(saga.id, saga.step, 'execute', 'succeeded');
+        await this.advance(saga, order);
+      } catch (error: any) {
+        await this.sagaRepo.recordStep(saga.id, saga.step, 'execute', 'failed', error.message);
+        if (!(error instanceof StepFailure)) {
+          logger.warn('Checkout step failed, will retry', { orderId: order.id, step: saga.step, error: error.message });
+          return saga;
+        }
+        await this.fail(saga, error.message);
+      }
+    }
+
+    if (saga.status === SagaStatus.COMPENSATING) {
+      try {
+        await this.compensate(saga, order);
+      } catch (error: any) {
+        logger.warn('Checkout compensation failed, will retry', { orderId: order.id, error: error.message });
+      }
+    }
+    return saga;
+  }
+
+  private async execute(saga: CheckoutSaga, order: Order): Promise<void> {
+    switch (saga.step) {
+      case SagaStep.RESERVE_INVENTORY:
+        return this.reserveInventory(saga, order);
+      case SagaStep.AUTHORIZE_PAYMENT:
+        return this.authorizePayment(saga, order);
+      case SagaStep.CONFIRM_RESERVATION:
+        return this.confirmReservations(saga);
+      case SagaStep.CREATE_SHIPMENT:
+        return this.createShipment(saga, order);
+    }
+  }
+
+  // advance moves the saga on to its next step, or completes it after the
+  // last one
+  private async advance(saga: CheckoutSaga, order: Order): Promise<void> {
+    const next = SAGA_STEPS[SAGA_STEPS.indexOf(saga.step) + 1];
+    if (next) {
+      saga.step = next;
+      await this.sagaRepo.save(saga, this.lease());
+      return;
+    }
+
+    // The order is confirmed before the saga is finished, so recovery
+    // confirms it if this fails
+    await this.orderRepo.updatePaymentStatus(
+      order.id,
+      PaymentStatus.AUTHORIZED,
+      saga.transactionId,
+      saga.paymentIntentId
+    );
+    saga.status = SagaStatus.COMPLETED;
+    await this.sagaRepo.save(saga, null);
+
+    await this.eventPublisher.publishPaymentSuccessful(order, {
+      success: true,
+      transactionId: saga.transactionId,
+      paymentIntentId: saga.paymentIntentId,
+    });
+    logger.info('Checkout saga completed', { orderId: order.id, sagaId: saga.id });
+  }
+
+  // fail stops the saga at its current step, to be compensated
+  private async fail(saga: CheckoutSaga, reason: string): Promise<void> {
+    saga.status = SagaStatus.COMPENSATING;
+    saga.failureReason = reason;
+    await this.sagaRepo.save(saga, this.lease());
+    logger.warn('Checkout saga failed, compensating', { orderId: saga.orderId, step: saga.step, reason });
+  }
+
+  // compensate undoes the steps before the one that failed, latest first,
+  // then fails the order
+  private async compensate(saga: CheckoutSaga, order: Order): Promise<void> {
+    await this.voidPayment(saga, order);
+    await this.releaseReservations(saga);
+    await this.failOrder(saga, order);
+
+    saga.status = SagaStatus.COMPENSATED;
+    await this.sagaRepo.save(saga, null);
+    logger.info('Checkout saga compensated', { orderId: order.id, sagaId: saga.id });
+  }
+
+  // failOrder ends the order of a compensated saga: a payment that failed
+  // fails the order, and anything else cancels it
+  private async failOrder(saga: CheckoutSaga, order: Order): Promise<void> {
+    const current = await this.orderRepo.findById(order.id);
+    if (current.status !== OrderStatus.PAYMENT_PENDING) {
+      return;
+    }
+
+    const reason = saga.failureReason || 'Checkout failed';
+    if (saga.step === SagaStep.AUTHORIZE_PAYMENT) {
+      await this.orderRepo.updatePaymentStatus(order.id, PaymentStatus.FAILED);
+      await this.orderRepo.updateStatus(order.id, OrderStatus.PAYMENT_FAILED, reason);
+      await this.eventPublisher.publishPaymentFailed(current, reason);
+      return;
+    }
+
+    if (saga.paymentId) {
+      await this.orderRepo.updatePaymentStatus(
+        order.id,
+        PaymentStatus.VOIDED,
+        saga.transactionId,
+        saga.paymentIntentId
+      );
+    }
+    const cancelled = await this.orderRepo.cancel(order.id, reason);
+    await this.eventPublisher.publishOrderCancelled(cancelled, reason);
+  }
+
+  // reserveInventory reserves each item's stock. Reservations are stored
+  // as they are made; inventory-service returns an order's reservation of
+  // a product again rather than reserving it twice, so the step can be
+  // retried.
+  private async reserveInventory(saga: CheckoutSaga, order: Order): Promise<void> {
+    for (const item of order.items) {
+      if (saga.reservations[item.productId]) {
+        continue;
+      }
+
+      try {
+        const response = await axios.post(
+          `${config.services.inventoryUrl}/api/v1/inventory/product/${item.productId}/reserve`,
+          {
+            quantity: item.quantity,
+            order_id: order.id,
+            customer_id: order.userId,
+          },
+          { timeout: 5000 }
+        );
+        saga.reservations[item.productId] = response.data.reservation_id;
+      } catch (error: any) {
+        if (error.response?.status === 409) {
+          throw new StepFailure(`Insufficient inventory for product ${item.productId}`);
+        }
+        if (error.response?.status === 404) {
+          throw new StepFailure(`Product ${item.productId} not found in inventory`);
+        }
+        throw error;
+      }
+      await this.sagaRepo.save(saga, this.lease());
+    }
+  }
+
+  // authorizePayment has payment-service hold the order's total, less what
+  // a gift card paid; orders a gift card paid in full skip it.
+  // payment-service returns an order's authorization again rather than
+  // authorizing it twice, so the step can be retried.
+  private async authorizePayment(saga: CheckoutSaga, order: Order): Promise<void> {
+    const amount = amountDue(order);
+    if (amount === 0) {
+      return;
+    }
+
+    let payment: PaymentAuthorization;
+    try {
+      const response = await axios.post(
+        `${config.services.paymentUrl}/api/v1/payments/authorize`,
+        {
+          order_id: order.id,
+          amount,
+          currency: 'USD',
+          payment_method: order.paymentMethod,
+        },
+        { timeout: 10000 }
+      );
+      payment = response.data;
+    } catch (error: any) {
+      if (refused(error)) {
+        throw new StepFailure(`Payment not authorized: ${error.response.data?.detail || error.message}`);
+      }
+      throw error;
+    }
+
+    if (!payment.success) {
+      throw new StepFailure(payment.error || 'Payment declined');
+    }
+
+    saga.paymentId = payment.payment_id;
+    saga.transactionId = payment.transaction_id;
+    saga.paymentIntentId = payment.payment_intent_id;
+    await this.sagaRepo.save(saga, this.lease());
+  }
+
+  // confirmReservations turns the reservations into sales, deducting their
+  // stock. Confirming a confirmed reservation changes nothing.
+  private async confirmReservations(saga: CheckoutSaga): Promise<void> {
+    for (const [productId, reservationId] of Object.entries(saga.reservations)) {
+      try {
+        await axios.post(
+          `${config.services.inventoryUrl}/api/v1/reservations/${reservationId}/confirm`,
+          {},
+          { timeout: 5000 }
+        );
+      } catch (error: any) {
+        if (refused(error)) {
+          throw new StepFailure(
+            `Reservation of product ${productId} not confirmed: ${error.response.data?.error || error.message}`
+          );
+        }
+        throw error;
+      }
+    }
+  }
+
+  // createShipment has shipping-service create the order's shipment.
+  // Without SHIPPING_SERVICE_URL there is none to create: the carrier and
+  // tracking number are given when the order ships.
+  private async createShipment(saga: CheckoutSaga, order: Order): Promise<void> {
+    if (!config.services.shippingUrl || saga.shipmentId) {
+      return;
+    }
+
+    let shipment: Shipment;
+    try {
+      const response = await axios.post(
+        `${config.services.shippingUrl}/api/v1/shipping/shipments`,
+        {
+          order_id: order.id,
+          shipping_address: order.shippingAddress,
+          items: order.items.map(item => ({ product_id: item.productId, sku: item.sku, quantity: item.quantity })),
+        },
+        { timeout: 5000 }
+      );
+      shipment = response.data;
+    } catch (error: any) {
+      if (refused(error)) {
+        throw new StepFailure(`Shipment not created: ${error.response.data?.error || error.message}`);
+      }
+      throw error;
+    }
+
+    saga.shipmentId = shipment.id;
+    await this.sagaRepo.save(saga, this.lease());
+  }
+
+  // voidPayment releases the order's payment authorization. If the saga
+  // failed authorizing the payment, e.g. timing out, payment-service may
+  // still have authorized it, so the order's latest payment is looked up.
+  private async voidPayment(saga: CheckoutSaga, order: Order): Promise<void> {
+    if (!saga.paymentId && saga.step === SagaStep.AUTHORIZE_PAYMENT) {
+      saga.paymentId = await this.findPaymentId(order.id);
+    }
+    if (!saga.paymentId) {
+      return;
+    }
+
+    try {
+      await axios.post(`${config.services.paymentUrl}/api/v1/payments/${saga.paymentId}/void`, {}, { timeout: 10000 });
+    } catch (error: any) {
+      if (error.response?.status !== 404) {
+        await this.sagaRepo.recordStep(saga.id, SagaStep.AUTHORIZE_PAYMENT, 'compensate', 'failed', error.message);
+        throw error;
+      }
+    }
+    await this.sagaRepo.recordStep(saga.id, SagaStep.AUTHORIZE_PAYMENT, 'compensate', 'succeeded');
+  }
+
+  // releaseReservations cancels the order's reservations, returning their
+  // stock, confirmed or not. Releasing a released reservation changes
+  // nothing.
+  private async releaseReservations(saga: CheckoutSaga): Promise<void> {
+    const reservationIds = Object.values(saga.reservations);
+    if (reservationIds.length === 0) {
+      return;
+    }
+
+    for (const reservationId of reservationIds) {
+      try {
+        await axios.delete(`${config.services.inventoryUrl}/api/v1/reservations/${reservationId}`, { timeout: 5000 });
+      } catch (error: any) {
+        if (error.response?.status !== 404) {
+          await this.sagaRepo.recordStep(saga.id, SagaStep.RESERVE_INVENTORY, 'compensate', 'failed', error.message);
+          throw error;
+        }
+      }
+    }
+    await this.sagaRepo.recordStep(saga.id, SagaStep.RESERVE_INVENTORY, 'compensate', 'succeeded');
+  }
+
+  // findPaymentId returns the ID of the order's latest payment, if any
+  private async findPaymentId(orderId: string): Promise<string | undefined> {
+    try {
+      const response = await axios.get(`${config.services.paymentUrl}/api/v1/payments/order/${orderId}`, {
+        timeout: 5000,
+      });
+      return response.data.id;
+    } catch (error: any) {
+      if (error.response?.status === 404) {
+        return undefined;
+      }
+      throw error;
+    }
+  }
+
+  // lease is when a lease taken now runs out, letting recovery take over
+  private lease(): Date {
+    return new Date(Date.now() + config.saga.leaseSeconds * 1000);
+  }
+}
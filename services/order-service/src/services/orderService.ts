@@ -1,6 +1,8 @@
 import axios from 'axios';
-import { OrderRepository } from '../database/orderRepository';
+import { v4 as uuidv4 } from 'uuid';
+import { OrderRepository, orderTotals } from '../database/orderRepository';
 import { EventPublisher } from './eventPublisher';
+import { CheckoutOrchestrator } from '../saga/checkoutSaga';
 import { config } from '../config';
 import { logger } from '../middleware/logger';
 import {
@@ -8,35 +10,70 @@ import {
   OrderStatus,
   PaymentStatus,
   CreateOrderRequest,
-  PaymentRequest,
   PaymentResponse,
+  PriceQuote,
+  CouponRedemption,
+  GiftCardRedemption,
+  TaxCalculation,
+  amountDue,
 } from '../models/order';
+import { SagaStatus } from '../models/saga';
+
+// CreateOrderOptions are what services placing orders for customers may set
+export interface CreateOrderOptions {
+  // orderId is the ID to create the order with
+  orderId?: string;
+  // inventoryReserved skips the inventory check: the order's stock is
+  // reserved under orderId already, and checkout gets those reservations
+  // back
+  inventoryReserved?: boolean;
+}
 
 export class OrderService {
   constructor(
     private orderRepo: OrderRepository,
-    private eventPublisher: EventPublisher
+    private eventPublisher: EventPublisher,
+    private checkout: CheckoutOrchestrator
   ) {}
 
-  async createOrder(orderData: CreateOrderRequest): Promise<Order> {
+  // createOrder places an order; its stock is reserved at checkout, when it
+  // is paid. authToken is the customer's, passed on to pricing-service so
+  // their segment promotions apply, to coupon-service and giftcard-service
+  // to redeem their coupon and gift card and to tax-service to record the
+  // order's tax. Services placing orders for customers, e.g.
+  // subscription-service, may choose the order's ID, and skip the
+  // inventory check if they reserved its stock under that ID already.
+  async createOrder(orderData: CreateOrderRequest, authToken?: string, options: CreateOrderOptions = {}): Promise<Order> {
     logger.info('Creating order', { userId: orderData.userId, itemCount: orderData.items.length });
 
     // Step 1: Validate inventory availability
-    await this.validateInventory(orderData.items);
+    if (!options.inventoryReserved) {
+      await this.validateInventory(orderData.items);
+    }
 
-    // Step 2: Create order in database
-    const order = await this.orderRepo.create(orderData);
+    // Step 2: Price the items and apply promotions
+    const discountAmount = await this.priceOrder(orderData, authToken);
 
-    // Step 3: Reserve inventory
+    // Step 3: Redeem the coupon, if any, on the order about to be created
+    const orderId = options.orderId || uuidv4();
+    const couponDiscount = await this.redeemCoupon(orderData, orderId, discountAmount, authToken);
+
+    // Step 4: Calculate tax, redeem the gift card, if any, on the total and
+    // create order in database
+    let order: Order;
     try {
-      await this.reserveInventory(order);
+      const discount = discountAmount + couponDiscount;
+      const taxAmount = await this.calculateTax(orderData, orderId, discount, authToken);
+      const { totalAmount } = orderTotals(orderData, discount, taxAmount);
+      const giftCardAmount = await this.redeemGiftCard(orderData, orderId, totalAmount, authToken);
+      order = await this.orderRepo.create(orderData, discount, orderId, taxAmount, giftCardAmount);
     } catch (error) {
-      logger.error('Failed to reserve inventory', { orderId: order.id, error });
-      await this.orderRepo.cancel(order.id, 'Inventory reservation failed');
-      throw new Error('Failed to reserve inventory');
+      await this.releaseGiftCard(orderData, orderId, authToken);
+      await this.releaseCoupon(orderData, orderId, authToken);
+      throw error;
     }
 
-    // Step 4: Clear user's cart
+    // Step 5: Clear user's cart
     try {
       await this.clearCart(orderData.userId);
     } catch (error) {
@@ -44,13 +81,17 @@ export class OrderService {
       // Non-fatal - continue
     }
 
-    // Step 5: Publish order created event
+    // Step 6: Publish order created event
     await this.eventPublisher.publishOrderCreated(order);
 
     logger.info('Order created successfully', { orderId: order.id, orderNumber: order.orderNumber });
     return order;
   }
 
+  // processPayment checks out an order: the checkout saga reserves its
+  // inventory, authorizes its payment, confirms the reservations and
+  // creates its shipment, compensating the steps taken if one fails. The
+  // payment is captured when the order ships.
   async processPayment(orderId: string): Promise<PaymentResponse> {
     logger.info('Processing payment', { orderId });
 
@@ -63,59 +104,21 @@ export class OrderService {
     // Update order status to payment pending
     await this.orderRepo.updateStatus(orderId, OrderStatus.PAYMENT_PENDING);
 
-    // Call payment service
-    const paymentRequest: PaymentRequest = {
-      orderId: order.id,
-      amount: order.totalAmount,
-      currency: 'USD',
-      paymentMethod: order.paymentMethod,
-    };
-
-    try {
-      const response = await axios.post(
-        `${config.services.paymentUrl}/api/v1/payments/process`,
-        paymentRequest,
-        { timeout: 10000 }
-      );
-
-      const paymentResponse: PaymentResponse = response.data;
-
-      if (paymentResponse.success) {
-        // Update order with payment details
-        await this.orderRepo.updatePaymentStatus(
-          orderId,
-          PaymentStatus.CAPTURED,
-          paymentResponse.transactionId,
-          paymentResponse.paymentIntentId
-        );
-
-        // Publish payment successful event
-        await this.eventPublisher.publishPaymentSuccessful(order, paymentResponse);
-
-        logger.info('Payment successful', { orderId, transactionId: paymentResponse.transactionId });
-      } else {
-        // Payment failed
-        await this.orderRepo.updatePaymentStatus(orderId, PaymentStatus.FAILED);
-        await this.orderRepo.updateStatus(orderId, OrderStatus.PAYMENT_FAILED);
-
-        // Release inventory reservations
-        await this.releaseInventory(order);
-
-        // Publish payment failed event
-        await this.eventPublisher.publishPaymentFailed(order, paymentResponse.error);
-
-        logger.warn('Payment failed', { orderId, error: paymentResponse.error });
-      }
-
-      return paymentResponse;
-    } catch (error: any) {
-      logger.error('Payment service error', { orderId, error: error.message });
-
-      await this.orderRepo.updatePaymentStatus(orderId, PaymentStatus.FAILED);
-      await this.orderRepo.updateStatus(orderId, OrderStatus.PAYMENT_FAILED);
-      await this.releaseInventory(order);
+    const saga = await this.checkout.start(order);
+    if (!saga) {
+      return { success: false, pending: true, error: 'Checkout is already in progress' };
+    }
 
-      throw new Error('Payment processing failed');
+    switch (saga.status) {
+      case SagaStatus.COMPLETED:
+        logger.info('Payment successful', { orderId, transactionId: saga.transactionId });
+        return { success: true, transactionId: saga.transactionId, paymentIntentId: saga.paymentIntentId };
+      case SagaStatus.COMPENSATED:
+        logger.warn('Payment failed', { orderId, error: saga.failureReason });
+        return { success: false, error: saga.failureReason };
+      default:
+        // A service is failing: recovery finishes the checkout
+        return { success: false, pending: true, error: saga.failureReason };
     }
   }
 
@@ -147,12 +150,12 @@ export class OrderService {
       throw new Error(`Cannot cancel order in status: ${order.status}`);
     }
 
+    // Release the inventory and payment authorization checkout holds
+    await this.checkout.release(order);
+
     // Cancel order
     const cancelledOrder = await this.orderRepo.cancel(orderId, reason);
 
-    // Release inventory
-    await this.releaseInventory(order);
-
     // If payment was captured, initiate refund
     if (order.paymentStatus === PaymentStatus.CAPTURED) {
       await this.initiateRefund(order);
@@ -175,11 +178,13 @@ export class OrderService {
       throw new Error(`Cannot ship order in status: ${order.status}`);
     }
 
-    // Update order status to shipped
-    const shippedOrder = await this.orderRepo.updateStatus(orderId, OrderStatus.SHIPPED);
+    // Capture the payment checkout authorized
+    if (order.paymentStatus === PaymentStatus.AUTHORIZED) {
+      await this.checkout.capture(order);
+    }
 
-    // In a full implementation, we would store tracking details in the database
-    // For now, we'll include them in the event
+    // Update order status to shipped, with its tracking details
+    const shippedOrder = await this.orderRepo.markShipped(orderId, trackingNumber, carrier);
 
     // Publish shipped event
     await this.eventPublisher.publishOrderShipped(shippedOrder, trackingNumber, carrier);
@@ -199,7 +204,7 @@ export class OrderService {
     }
 
     // Update order status to delivered
-    const deliveredOrder = await this.orderRepo.updateStatus(orderId, OrderStatus.DELIVERED);
+    const deliveredOrder = await this.orderRepo.markDelivered(orderId);
 
     // Publish delivered event
     await this.eventPublisher.publishOrderDelivered(deliveredOrder);
@@ -238,29 +243,225 @@ export class OrderService {
     }
   }
 
-  private async reserveInventory(order: Order): Promise<void> {
-    logger.debug('Reserving inventory', { orderId: order.id });
+  // priceOrder replaces the items' prices with pricing-service's current
+  // ones and returns the promotions' discount. Without pricing-service,
+  // prices are taken from the request and nothing is discounted.
+  private async priceOrder(orderData: CreateOrderRequest, authToken?: string): Promise<number> {
+    if (!config.services.pricingUrl) {
+      return 0;
+    }
 
-    for (const item of order.items) {
-      await axios.post(
-        `${config.services.inventoryUrl}/api/v1/inventory/product/${item.productId}/reserve`,
+    let quote: PriceQuote;
+    try {
+      const response = await axios.post(
+        `${config.services.pricingUrl}/api/v1/pricing/quotes`,
         {
-          quantity: item.quantity,
-          order_id: order.id,
-          customer_id: order.userId,
+          items: orderData.items.map(item => ({ product_id: item.productId, quantity: item.quantity })),
+          promo_codes: orderData.promoCodes || [],
         },
-        { timeout: 5000 }
+        {
+          timeout: 5000,
+          headers: authToken ? { Authorization: `Bearer ${authToken}` } : undefined,
+        }
       );
+      quote = response.data;
+    } catch (error: any) {
+      if (error.response?.status === 422) {
+        const productIds: string[] = error.response.data?.product_ids || [];
+        throw new Error(`Price not found for products: ${productIds.join(', ') || 'unknown'}`);
+      }
+      logger.error('Pricing service error', { userId: orderData.userId, error: error.message });
+      throw new Error('Failed to price order');
+    }
+
+    // Orders are kept in dollars
+    if (quote.currency !== 'USD') {
+      throw new Error(`Unsupported order currency: ${quote.currency}`);
+    }
+
+    const unitPrices = new Map(quote.lines.map(line => [line.product_id, line.unit_price.minor_units]));
+    for (const item of orderData.items) {
+      item.price = (unitPrices.get(item.productId) ?? 0) / 100;
+    }
+
+    if (quote.unused_codes?.length) {
+      logger.info('Promotion codes not applied', { userId: orderData.userId, codes: quote.unused_codes });
     }
+    return quote.discount_total.minor_units / 100;
   }
 
-  private async releaseInventory(order: Order): Promise<void> {
-    logger.debug('Releasing inventory', { orderId: order.id });
+  // redeemCoupon redeems the order's couponCode with coupon-service on the
+  // subtotal left after promotionDiscount, and returns the coupon's
+  // discount in dollars. Redeeming is idempotent per order, so a retried
+  // request doesn't use the code twice.
+  private async redeemCoupon(
+    orderData: CreateOrderRequest,
+    orderId: string,
+    promotionDiscount: number,
+    authToken?: string
+  ): Promise<number> {
+    if (!orderData.couponCode) {
+      return 0;
+    }
+    if (!config.services.couponUrl) {
+      throw new Error('Coupons are not available');
+    }
+
+    const subtotal = orderData.items.reduce((sum, item) => sum + item.price * item.quantity, 0);
+    const orderAmount = Math.max(Math.round((subtotal - promotionDiscount) * 100), 0);
 
-    // Get reservations for this order and release them
-    // This would require the inventory service to support querying by order_id
-    // For now, we'll skip the actual API call in this implementation
-    logger.info('Inventory release requested', { orderId: order.id });
+    try {
+      const response = await axios.post(
+        `${config.services.couponUrl}/api/v1/coupons/redemptions`,
+        {
+          code: orderData.couponCode,
+          order_id: orderId,
+          order_amount: { minor_units: orderAmount, currency: 'USD' },
+        },
+        {
+          timeout: 5000,
+          headers: authToken ? { Authorization: `Bearer ${authToken}` } : undefined,
+        }
+      );
+      const redemption: CouponRedemption = response.data;
+      return redemption.discount.minor_units / 100;
+    } catch (error: any) {
+      if (error.response?.status === 422) {
+        throw new Error(`Coupon not applied: ${error.response.data?.error || 'the code is not valid'}`);
+      }
+      logger.error('Coupon service error', { userId: orderData.userId, orderId, error: error.message });
+      throw new Error('Failed to redeem coupon');
+    }
+  }
+
+  // redeemGiftCard redeems the order's giftCardCode with giftcard-service
+  // on its totalAmount, in dollars, and returns what the card paid: the
+  // total, or the card's balance if less. Redeeming is idempotent per
+  // order, so a retried request doesn't spend the card twice.
+  private async redeemGiftCard(
+    orderData: CreateOrderRequest,
+    orderId: string,
+    totalAmount: number,
+    authToken?: string
+  ): Promise<number> {
+    if (!orderData.giftCardCode) {
+      return 0;
+    }
+    if (!config.services.giftCardUrl) {
+      throw new Error('Gift cards are not available');
+    }
+
+    try {
+      const response = await axios.post(
+        `${config.services.giftCardUrl}/api/v1/gift-cards/redemptions`,
+        {
+          code: orderData.giftCardCode,
+          order_id: orderId,
+          amount_due: { minor_units: Math.max(Math.round(totalAmount * 100), 0), currency: 'USD' },
+        },
+        {
+          timeout: 5000,
+          headers: authToken ? { Authorization: `Bearer ${authToken}` } : undefined,
+        }
+      );
+      const redemption: GiftCardRedemption = response.data;
+      return redemption.amount.minor_units / 100;
+    } catch (error: any) {
+      if (error.response?.status === 422) {
+        throw new Error(`Gift card not applied: ${error.response.data?.error || 'the card is not valid'}`);
+      }
+      logger.error('Gift card service error', { userId: orderData.userId, orderId, error: error.message });
+      throw new Error('Failed to redeem gift card');
+    }
+  }
+
+  // calculateTax returns the order's tax in dollars on the subtotal less
+  // discountAmount. tax-service taxes each item by the shipping address and
+  // the product's tax class and records the calculation against the order;
+  // without it, the flat TAX_RATE applies.
+  private async calculateTax(
+    orderData: CreateOrderRequest,
+    orderId: string,
+    discountAmount: number,
+    authToken?: string
+  ): Promise<number> {
+    const subtotal = orderData.items.reduce((sum, item) => sum + item.price * item.quantity, 0);
+    if (!config.services.taxUrl) {
+      return Math.max(subtotal - discountAmount, 0) * config.order.taxRate;
+    }
+
+    const lines = orderData.items.map(item => ({
+      product_id: item.productId,
+      amount: { minor_units: Math.round(item.price * item.quantity * 100), currency: 'USD' },
+    }));
+    const linesTotal = lines.reduce((sum, line) => sum + line.amount.minor_units, 0);
+    const discount = Math.min(Math.max(Math.round(discountAmount * 100), 0), linesTotal);
+
+    try {
+      const response = await axios.post(
+        `${config.services.taxUrl}/api/v1/tax/calculations`,
+        {
+          reference: orderId,
+          address: {
+            country: orderData.shippingAddress.country,
+            region: orderData.shippingAddress.state,
+            postal_code: orderData.shippingAddress.postalCode,
+          },
+          lines,
+          discount: { minor_units: discount, currency: 'USD' },
+        },
+        {
+          timeout: 5000,
+          headers: authToken ? { Authorization: `Bearer ${authToken}` } : undefined,
+        }
+      );
+      const calculation: TaxCalculation = response.data;
+      return calculation.tax_amount.minor_units / 100;
+    } catch (error: any) {
+      if (error.response?.status === 400) {
+        throw new Error(`Tax not calculated: ${error.response.data?.error || 'invalid shipping address'}`);
+      }
+      logger.error('Tax service error', { userId: orderData.userId, orderId, error: error.message });
+      throw new Error('Failed to calculate tax');
+    }
+  }
+
+  // releaseCoupon gives back the coupon redeemed on an order that wasn't
+  // placed. Failures are logged: the redemption stays until released by
+  // hand.
+  private async releaseCoupon(orderData: CreateOrderRequest, orderId: string, authToken?: string): Promise<void> {
+    if (!orderData.couponCode || !config.services.couponUrl) {
+      return;
+    }
+
+    try {
+      await axios.delete(`${config.services.couponUrl}/api/v1/coupons/redemptions/${orderId}`, {
+        timeout: 5000,
+        headers: authToken ? { Authorization: `Bearer ${authToken}` } : undefined,
+      });
+    } catch (error: any) {
+      logger.warn('Failed to release coupon', { orderId, code: orderData.couponCode, error: error.message });
+    }
+  }
+
+  // releaseGiftCard returns the gift card amount redeemed on an order that
+  // wasn't placed to the card. Failures are logged: the redemption stays
+  // until released by hand.
+  private async releaseGiftCard(orderData: CreateOrderRequest, orderId: string, authToken?: string): Promise<void> {
+    if (!orderData.giftCardCode || !config.services.giftCardUrl) {
+      return;
+    }
+
+    try {
+      await axios.delete(`${config.services.giftCardUrl}/api/v1/gift-cards/redemptions/${orderId}`, {
+        timeout: 5000,
+        headers: authToken ? { Authorization: `Bearer ${authToken}` } : undefined,
+      });
+    } catch (error: any) {
+      if (error.response?.status !== 404) {
+        logger.warn('Failed to release gift card', { orderId, error: error.message });
+      }
+    }
   }
 
   private async clearCart(userId: string): Promise<void> {
@@ -284,7 +485,7 @@ export class OrderService {
         {
           orderId: order.id,
           transactionId: order.transactionId,
-          amount: order.totalAmount,
+          amount: amountDue(order),
         },
         { timeout: 10000 }
       );
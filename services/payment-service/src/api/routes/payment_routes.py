@@ -10,6 +10,7 @@ from ...services.payment_processor import PaymentProcessor
 from ...services.event_publisher import EventPublisher
 from ...models.payment import (
     ProcessPaymentRequest,
+    CaptureRequest,
     PaymentResponse,
     RefundRequest,
     RefundResponse,
@@ -63,6 +64,80 @@ async def process_payment(
         )
 
 
+@router.post("/authorize", response_model=PaymentResponse, status_code=status.HTTP_200_OK)
+async def authorize_payment(
+    request: ProcessPaymentRequest,
+    service: PaymentService = Depends(get_payment_service),
+):
+    """
+    Authorize a payment for an order without capturing it.
+
+    The funds are held until the payment is captured or voided. Authorizing
+    an order already authorized returns its payment. A declined
+    authorization answers 200 with success false, as /process does.
+    """
+    logger.info("Authorizing payment", order_id=request.order_id, amount=request.amount)
+
+    try:
+        return await service.authorize_payment(request)
+
+    except Exception as e:
+        logger.error("Payment authorization failed", error=str(e))
+        raise HTTPException(
+            status_code=status.HTTP_500_INTERNAL_SERVER_ERROR,
+            detail=f"Payment authorization failed: {str(e)}",
+        )
+
+
+@router.post("/{payment_id}/capture", response_model=PaymentResponse, status_code=status.HTTP_200_OK)
+async def capture_payment(
+    payment_id: str,
+    request: CaptureRequest = CaptureRequest(),
+    service: PaymentService = Depends(get_payment_service),
+):
+    """Capture an authorized payment; capturing it again returns it"""
+    logger.info("Capturing payment", payment_id=payment_id)
+
+    response = await service.capture_payment(payment_id, request)
+
+    if not response:
+        raise HTTPException(
+            status_code=status.HTTP_404_NOT_FOUND,
+            detail="Payment not found",
+        )
+    if not response.success:
+        raise HTTPException(
+            status_code=status.HTTP_409_CONFLICT,
+            detail=response.error,
+        )
+
+    return response
+
+
+@router.post("/{payment_id}/void", response_model=PaymentResponse, status_code=status.HTTP_200_OK)
+async def void_payment(
+    payment_id: str,
+    service: PaymentService = Depends(get_payment_service),
+):
+    """Void an authorized payment, releasing the funds; voiding it again returns it"""
+    logger.info("Voiding payment", payment_id=payment_id)
+
+    response = await service.void_payment(payment_id)
+
+    if not response:
+        raise HTTPException(
+            status_code=status.HTTP_404_NOT_FOUND,
+            detail="Payment not found",
+        )
+    if not response.success:
+        raise HTTPException(
+            status_code=status.HTTP_409_CONFLICT,
+            detail=response.error,
+        )
+
+    return response
+
+
 @router.post("/refund", response_model=RefundResponse, status_code=status.HTTP_200_OK)
 async def refund_payment(
     request: RefundRequest,
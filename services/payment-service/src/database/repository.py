@@ -87,6 +87,60 @@ class PaymentRepository:
 
         return await self.get_by_id(payment_id)
 
+    async def update_payment_authorized(
+        self,
+        payment_id: str,
+        transaction_id: str,
+        payment_intent_id: str,
+        provider_response: dict,
+    ) -> Payment:
+        """Update payment with authorization details; funds are held, not captured"""
+        now = datetime.utcnow()
+
+        stmt = (
+            update(PaymentModel)
+            .where(PaymentModel.id == payment_id)
+            .values(
+                status=PaymentStatus.AUTHORIZED.value,
+                transaction_id=transaction_id,
+                payment_intent_id=payment_intent_id,
+                provider_response=provider_response,
+                authorized_at=now,
+                updated_at=now,
+            )
+        )
+
+        await self.session.execute(stmt)
+        await self.session.commit()
+
+        return await self.get_by_id(payment_id)
+
+    async def update_payment_captured(
+        self,
+        payment_id: str,
+        transaction_id: str,
+        provider_response: dict,
+    ) -> Payment:
+        """Update an authorized payment with capture details"""
+        now = datetime.utcnow()
+
+        stmt = (
+            update(PaymentModel)
+            .where(PaymentModel.id == payment_id)
+            .values(
+                status=PaymentStatus.CAPTURED.value,
+                transaction_id=transaction_id,
+                provider_response=provider_response,
+                captured_at=now,
+                updated_at=now,
+            )
+        )
+
+        await self.session.execute(stmt)
+        await self.session.commit()
+
+        return await self.get_by_id(payment_id)
+
     async def update_payment_failure(
         self,
         payment_id: str,
@@ -144,8 +198,13 @@ class PaymentRepository:
         return self._to_domain(db_payment)
 
     async def get_by_order_id(self, order_id: str) -> Optional[Payment]:
-        """Get payment by order ID"""
-        stmt = select(PaymentModel).where(PaymentModel.order_id == order_id)
+        """Get an order's latest payment; a declined order may be paid again"""
+        stmt = (
+            select(PaymentModel)
+            .where(PaymentModel.order_id == order_id)
+            .order_by(PaymentModel.created_at.desc())
+            .limit(1)
+        )
         result = await self.session.execute(stmt)
         db_payment = result.scalar_one_or_none()
 
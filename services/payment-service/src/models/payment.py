@@ -20,6 +20,7 @@ class PaymentStatus(str, Enum):
     FAILED = "failed"
     REFUNDED = "refunded"
     PARTIALLY_REFUNDED = "partially_refunded"
+    VOIDED = "voided"
 
 
 class Currency(str, Enum):
@@ -66,8 +67,13 @@ class Payment(BaseModel):
         from_attributes = True
 
 
+class CaptureRequest(BaseModel):
+    amount: Optional[float] = Field(None, gt=0)
+
+
 class PaymentResponse(BaseModel):
     success: bool
+    payment_id: Optional[str] = None
     transaction_id: Optional[str] = None
     payment_intent_id: Optional[str] = None
     status: PaymentStatus
@@ -7,6 +7,7 @@ from ..models.payment import (
     Payment,
     PaymentStatus,
     ProcessPaymentRequest,
+    CaptureRequest,
     PaymentResponse,
     RefundRequest,
     RefundResponse,
@@ -116,6 +117,153 @@ class PaymentService:
                 error=f"Payment processing error: {str(e)}",
             )
 
+    async def authorize_payment(self, request: ProcessPaymentRequest) -> PaymentResponse:
+        """Authorize a payment for an order, holding the funds until it is captured or voided"""
+        logger.info("Authorizing payment request", order_id=request.order_id, amount=request.amount)
+
+        # Authorizing is idempotent per order: a retry gets the order's
+        # authorization back rather than holding the funds twice
+        existing = await self.repository.get_by_order_id(request.order_id)
+        if existing and existing.status in [PaymentStatus.AUTHORIZED, PaymentStatus.CAPTURED]:
+            logger.info("Payment already authorized", payment_id=existing.id, order_id=request.order_id)
+            return self._to_response(existing)
+
+        # Create payment record
+        payment = await self.repository.create_payment(
+            order_id=request.order_id,
+            amount=request.amount,
+            currency=request.currency,
+            payment_method=request.payment_method,
+        )
+
+        try:
+            await self.repository.update_status(payment.id, PaymentStatus.PROCESSING)
+
+            result = await self.processor.authorize_payment(
+                amount=request.amount,
+                payment_method=request.payment_method,
+                payment_details=request.payment_method_details or {},
+            )
+
+            if result["success"]:
+                payment = await self.repository.update_payment_authorized(
+                    payment_id=payment.id,
+                    transaction_id=result["transaction_id"],
+                    payment_intent_id=result["payment_intent_id"],
+                    provider_response=result,
+                )
+
+                logger.info("Payment authorized", payment_id=payment.id, order_id=request.order_id)
+                return self._to_response(payment)
+            else:
+                error_message = result.get("error_message", "Payment authorization failed")
+
+                payment = await self.repository.update_payment_failure(
+                    payment_id=payment.id,
+                    error_message=error_message,
+                    provider_response=result,
+                )
+
+                # Publish failure event
+                await self.event_publisher.publish_payment_failed(payment, error_message)
+
+                logger.warning("Payment authorization declined", payment_id=payment.id, error=error_message)
+
+                return PaymentResponse(
+                    success=False,
+                    payment_id=payment.id,
+                    status=PaymentStatus.FAILED,
+                    error=error_message,
+                )
+
+        except Exception as e:
+            logger.error("Payment authorization error", payment_id=payment.id, error=str(e))
+
+            await self.repository.update_payment_failure(
+                payment_id=payment.id,
+                error_message=str(e),
+            )
+
+            return PaymentResponse(
+                success=False,
+                payment_id=payment.id,
+                status=PaymentStatus.FAILED,
+                error=f"Payment authorization error: {str(e)}",
+            )
+
+    async def capture_payment(self, payment_id: str, request: CaptureRequest) -> Optional[PaymentResponse]:
+        """Capture an authorized payment, by default its full amount; None if it doesn't exist"""
+        payment = await self.repository.get_by_id(payment_id)
+        if not payment:
+            return None
+
+        if payment.status == PaymentStatus.CAPTURED:
+            return self._to_response(payment)
+        if payment.status != PaymentStatus.AUTHORIZED:
+            return PaymentResponse(
+                success=False,
+                payment_id=payment.id,
+                status=payment.status,
+                error=f"Payment in status {payment.status.value} cannot be captured",
+            )
+
+        amount = request.amount or payment.amount
+        result = await self.processor.capture_payment(payment.payment_intent_id, amount)
+
+        if not result["success"]:
+            logger.warning("Payment capture failed", payment_id=payment.id)
+            return PaymentResponse(
+                success=False,
+                payment_id=payment.id,
+                status=payment.status,
+                error=result.get("error_message", "Payment capture failed"),
+            )
+
+        payment = await self.repository.update_payment_captured(
+            payment_id=payment.id,
+            transaction_id=result["transaction_id"],
+            provider_response=result,
+        )
+
+        # Publish success event
+        await self.event_publisher.publish_payment_successful(payment)
+
+        logger.info("Payment captured", payment_id=payment.id, transaction_id=payment.transaction_id)
+        return self._to_response(payment)
+
+    async def void_payment(self, payment_id: str) -> Optional[PaymentResponse]:
+        """Void an authorized payment, releasing the held funds; None if it doesn't exist"""
+        payment = await self.repository.get_by_id(payment_id)
+        if not payment:
+            return None
+
+        # Voiding is idempotent, and a payment never authorized holds nothing
+        if payment.status in [PaymentStatus.VOIDED, PaymentStatus.FAILED]:
+            return self._to_response(payment)
+        if payment.status != PaymentStatus.AUTHORIZED:
+            return PaymentResponse(
+                success=False,
+                payment_id=payment.id,
+                status=payment.status,
+                error=f"Payment in status {payment.status.value} cannot be voided",
+            )
+
+        result = await self.processor.void_authorization(payment.payment_intent_id)
+
+        if not result["success"]:
+            logger.warning("Payment void failed", payment_id=payment.id)
+            return PaymentResponse(
+                success=False,
+                payment_id=payment.id,
+                status=payment.status,
+                error=result.get("error_message", "Payment void failed"),
+            )
+
+        payment = await self.repository.update_status(payment.id, PaymentStatus.VOIDED)
+
+        logger.info("Payment voided", payment_id=payment.id)
+        return self._to_response(payment)
+
     async def refund_payment(self, request: RefundRequest) -> RefundResponse:
         """Refund a payment"""
         logger.info(
@@ -214,3 +362,13 @@ class PaymentService:
     async def get_payment_by_transaction(self, transaction_id: str) -> Optional[Payment]:
         """Get payment by transaction ID"""
         return await self.repository.get_by_transaction_id(transaction_id)
+
+    def _to_response(self, payment: Payment) -> PaymentResponse:
+        """Describe a payment in a successful response"""
+        return PaymentResponse(
+            success=True,
+            payment_id=payment.id,
+            transaction_id=payment.transaction_id,
+            payment_intent_id=payment.payment_intent_id,
+            status=payment.status,
+        )
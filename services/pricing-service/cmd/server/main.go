@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	pricingv1 "github.com/ecommerce-platform/proto/gen/go/ecommerce/pricing/v1"
+	"github.com/ecommerce-platform/shared/go/audit"
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	sharedconfig "github.com/ecommerce-platform/shared/go/config"
+	shareddb "github.com/ecommerce-platform/shared/go/db"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/httpmetrics"
+	"github.com/ecommerce-platform/shared/go/interceptors"
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce-platform/shared/go/mtls"
+	"github.com/ecommerce/pricing-service/internal/api"
+	"github.com/ecommerce/pricing-service/internal/config"
+	"github.com/ecommerce/pricing-service/internal/events"
+	"github.com/ecommerce/pricing-service/internal/middleware"
+	"github.com/ecommerce/pricing-service/internal/repository"
+	"github.com/ecommerce/pricing-service/internal/scheduler"
+	"github.com/ecommerce/pricing-service/migrations"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func main() {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Initialize logger
+	log, err := logging.New(logging.Config{
+		ServiceName: "pricing-service",
+		Environment: cfg.Environment,
+		Level:       os.Getenv("LOG_LEVEL"),
+	})
+	if err != nil {
+		fmt.Printf("Failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer log.Sync()
+
+	log.Info("Starting Pricing Service",
+		zap.String("environment", cfg.Environment),
+		zap.Int("port", cfg.Port),
+	)
+	log.Info("Configuration loaded", zap.Any("config", sharedconfig.Redacted(cfg)))
+
+	// Initialize mTLS
+	serviceTLS, err := mtls.New(cfg.TLS, log)
+	if err != nil {
+		log.Fatal("Failed to initialize mTLS", zap.Error(err))
+	}
+	defer serviceTLS.Close()
+
+	// Initialize OpenTelemetry
+	cleanup, err := initTelemetry(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize telemetry", zap.Error(err))
+	}
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Initialize PostgreSQL
+	db, err := shareddb.Open(ctx, shareddb.Config{
+		DSN:          cfg.DatabaseURL,
+		Name:         "pricing",
+		MaxOpenConns: cfg.DBMaxOpenConns,
+		MaxIdleConns: cfg.DBMaxIdleConns,
+	}, log)
+	if err != nil {
+		log.Fatal("Failed to connect to database", zap.Error(err))
+	}
+	defer db.Close()
+
+	if err := shareddb.Migrate(ctx, db, migrations.FS, log); err != nil {
+		log.Fatal("Failed to migrate database", zap.Error(err))
+	}
+
+	// Initialize repository
+	pricingRepo := repository.NewPostgresRepository(db)
+
+	// Initialize Kafka publisher
+	brokers := strings.Split(cfg.KafkaBrokers, ",")
+	publisher := events.NewKafkaPublisher(brokers, cfg.PriceEventsTopic, log)
+	defer publisher.Close()
+
+	// Audit log of price, promotion and segment changes, in the database
+	// and on the audit topic
+	auditSinks := []audit.Sink{audit.NewPostgresSink(db)}
+	if cfg.AuditTopic != "" {
+		auditProducer := sharedkafka.NewProducer(sharedkafka.ProducerConfig{Brokers: brokers, Topic: cfg.AuditTopic}, log)
+		defer auditProducer.Close()
+		auditSinks = append(auditSinks, audit.NewKafkaSink(auditProducer))
+	}
+	auditor := audit.New(audit.Config{Service: "pricing-service", Sinks: auditSinks}, log)
+
+	// Announce prices as they take effect
+	announcer := scheduler.New(pricingRepo, publisher, time.Duration(cfg.AnnounceInterval)*time.Second, log)
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	announcerDone := make(chan struct{})
+	go func() {
+		defer close(announcerDone)
+		announcer.Start(backgroundCtx)
+	}()
+
+	// Initialize handler
+	handler := api.NewHandler(pricingRepo, announcer, auditor, log)
+
+	// Initialize auth
+	verifier, err := sharedauth.NewVerifier(sharedauth.Config{
+		Secret:  cfg.JWTSecret,
+		JWKSURL: cfg.JWKSURL,
+		Issuer:  sharedauth.Issuer,
+	})
+	if err != nil {
+		log.Fatal("Failed to create token verifier", zap.Error(err))
+	}
+	authMiddleware := sharedauth.NewMiddleware(verifier, log)
+
+	// Setup Gin
+	if cfg.Environment == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(middleware.CorrelationID())
+	router.Use(otelgin.Middleware("pricing-service"))
+	router.Use(httpmetrics.Middleware("pricing-service"))
+	router.Use(apperrors.Middleware(log))
+
+	// Health check
+	router.GET("/health", handler.HealthCheck)
+
+	// Metrics for Prometheus
+	router.GET("/metrics", gin.WrapH(httpmetrics.Handler()))
+
+	// API routes, for the default tenant only
+	v1 := router.Group("/api/v1", sharedauth.RequireDefaultTenant())
+	{
+		pricing := v1.Group("/pricing")
+		{
+			pricing.GET("/prices", handler.ListPrices)
+			pricing.GET("/prices/:productId", handler.GetPrice)
+
+			// Guests are quoted without segment promotions; signed-in
+			// customers with theirs
+			pricing.POST("/quotes", optionalAuth(authMiddleware), handler.Quote)
+		}
+
+		manage := pricing.Group("", authMiddleware.Authenticate(), authMiddleware.RequirePermission(api.PermissionManage))
+		{
+			manage.POST("/prices/:productId", handler.SetPrice)
+			manage.GET("/prices/:productId/schedule", handler.ListScheduledPrices)
+			manage.DELETE("/prices/:productId/schedule/:id", handler.CancelScheduledPrice)
+
+			manage.POST("/promotions", handler.CreatePromotion)
+			manage.GET("/promotions", handler.ListPromotions)
+			manage.GET("/promotions/:id", handler.GetPromotion)
+			manage.PUT("/promotions/:id", handler.UpdatePromotion)
+			manage.DELETE("/promotions/:id", handler.DeactivatePromotion)
+
+			manage.GET("/customers/:userId/segments", handler.GetSegments)
+			manage.PUT("/customers/:userId/segments", handler.SetSegments)
+		}
+	}
+
+	// Create HTTP server
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Port),
+		Handler:      router,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// Start server in goroutine
+	go func() {
+		log.Info("Server starting", zap.Int("port", cfg.Port))
+		if err := serviceTLS.ListenAndServe(srv); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Server failed to start", zap.Error(err))
+		}
+	}()
+
+	// Internal gRPC API for checkout; like POST /quotes, it doesn't require
+	// a token
+	grpcServer := grpc.NewServer(interceptors.ServerOptions(interceptors.ServerConfig{Logger: log, TLS: serviceTLS.ServerConfig()})...)
+	pricingv1.RegisterPricingServiceServer(grpcServer, api.NewPricingServer(handler))
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+
+	grpcListener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
+	if err != nil {
+		log.Fatal("Failed to listen for gRPC", zap.Error(err))
+	}
+	go func() {
+		log.Info("gRPC server starting", zap.Int("port", cfg.GRPCPort))
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			log.Fatal("gRPC server failed", zap.Error(err))
+		}
+	}()
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Info("Shutting down server...")
+
+	// Graceful shutdown
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Fatal("Server forced to shutdown", zap.Error(err))
+	}
+	grpcServer.GracefulStop()
+
+	// Changes left unannounced are announced on restart
+	stopBackground()
+	select {
+	case <-announcerDone:
+	case <-shutdownCtx.Done():
+		log.Warn("Price announcer didn't stop in time")
+	}
+
+	if err := auditor.Close(shutdownCtx); err != nil {
+		log.Error("Failed to write pending audit records", zap.Error(err))
+	}
+
+	log.Info("Server shutdown complete")
+}
+
+// optionalAuth authenticates requests that carry a token and lets those
+// without one through anonymously
+func optionalAuth(m *sharedauth.Middleware) gin.HandlerFunc {
+	authenticate := m.Authenticate()
+	return func(c *gin.Context) {
+		if sharedauth.Token(c) == "" {
+			c.Next()
+			return
+		}
+		authenticate(c)
+	}
+}
+
+func initTelemetry(cfg *config.Config) (func(), error) {
+	ctx := context.Background()
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String("pricing-service"),
+			semconv.ServiceVersionKey.String("1.0.0"),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	traceExporter, err := otlptracegrpc.New(ctx,
+		otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint),
+		otlptracegrpc.WithInsecure(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	bsp := sdktrace.NewBatchSpanProcessor(traceExporter)
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithResource(res),
+		sdktrace.WithSpanProcessor(bsp),
+	)
+
+	otel.SetTracerProvider(tracerProvider)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			fmt.Printf("Failed to shutdown tracer provider: %v\n", err)
+		}
+	}, nil
+}
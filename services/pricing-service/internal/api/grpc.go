@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	pricingv1 "github.com/ecommerce-platform/proto/gen/go/ecommerce/pricing/v1"
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce/pricing-service/internal/quote"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// PricingServer serves the ecommerce.pricing.v1 gRPC API, quoting as the
+// HTTP API does
+type PricingServer struct {
+	pricingv1.UnimplementedPricingServiceServer
+	handler *Handler
+}
+
+// NewPricingServer creates the gRPC API over handler
+func NewPricingServer(handler *Handler) *PricingServer {
+	return &PricingServer{handler: handler}
+}
+
+// Quote prices a basket at the current prices, with the segment
+// promotions of the customer it names
+func (s *PricingServer) Quote(ctx context.Context, req *pricingv1.QuoteRequest) (*pricingv1.QuoteResponse, error) {
+	// The same limits as QuoteRequest's bindings
+	if len(req.GetItems()) < 1 || len(req.GetItems()) > 100 {
+		return nil, status.Error(codes.InvalidArgument, "items must have 1 to 100 lines")
+	}
+	if len(req.GetPromoCodes()) > 5 {
+		return nil, status.Error(codes.InvalidArgument, "At most 5 promo_codes can be entered")
+	}
+	items := make([]quote.Item, len(req.GetItems()))
+	for i, item := range req.GetItems() {
+		if item.GetProductId() == "" || len(item.GetProductId()) > 255 {
+			return nil, status.Error(codes.InvalidArgument, "product_id is required")
+		}
+		if item.GetQuantity() < 1 || item.GetQuantity() > 1000 {
+			return nil, status.Error(codes.InvalidArgument, "quantity must be 1 to 1000")
+		}
+		items[i] = quote.Item{ProductID: item.GetProductId(), Quantity: int(item.GetQuantity())}
+	}
+
+	q, err := s.handler.quoteBasket(ctx, items, req.GetPromoCodes(), req.GetCustomerId(), time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pricingv1.QuoteResponse{
+		Currency:      q.Currency,
+		Subtotal:      toMoney(q.Subtotal),
+		DiscountTotal: toMoney(q.DiscountTotal),
+		Total:         toMoney(q.Total),
+		UnusedCodes:   q.UnusedCodes,
+		QuotedAt:      timestamppb.New(q.QuotedAt),
+	}
+	for _, line := range q.Lines {
+		resp.Lines = append(resp.Lines, &pricingv1.QuoteLine{
+			ProductId:   line.ProductID,
+			Quantity:    int32(line.Quantity),
+			UnitPrice:   toMoney(line.UnitPrice),
+			Subtotal:    toMoney(line.Subtotal),
+			Discount:    toMoney(line.Discount),
+			Total:       toMoney(line.Total),
+			PromotionId: line.PromotionID,
+		})
+	}
+	for _, promotion := range q.Promotions {
+		resp.Promotions = append(resp.Promotions, &pricingv1.AppliedPromotion{
+			Id:       promotion.ID,
+			Name:     promotion.Name,
+			Code:     promotion.Code,
+			Type:     string(promotion.Type),
+			Discount: toMoney(promotion.Discount),
+		})
+	}
+	return resp, nil
+}
+
+func toMoney(m money.Money) *pricingv1.Money {
+	return &pricingv1.Money{MinorUnits: m.MinorUnits, Currency: m.Currency}
+}
@@ -0,0 +1,618 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/audit"
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce/pricing-service/internal/domain"
+	"github.com/ecommerce/pricing-service/internal/metrics"
+	"github.com/ecommerce/pricing-service/internal/quote"
+	"github.com/ecommerce/pricing-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// PermissionManage allows setting prices, promotions and customer
+// segments; admins have it
+const PermissionManage = "pricing:manage"
+
+// Error codes of quotes that can't be priced
+const (
+	CodeUnpricedProducts = "UNPRICED_PRODUCTS"
+	CodeMixedCurrencies  = "MIXED_CURRENCIES"
+)
+
+// maxBatchProducts bounds the products of a batch price lookup
+const maxBatchProducts = 100
+
+func init() {
+	apperrors.Register(CodeUnpricedProducts, http.StatusUnprocessableEntity, "Products of the basket have no price, listed in product_ids")
+	apperrors.Register(CodeMixedCurrencies, http.StatusUnprocessableEntity, "Products of the basket are priced in different currencies")
+}
+
+// Notifier is told when a price is set to take effect at once, so its
+// price.changed goes out without waiting for the next check
+type Notifier interface {
+	Notify()
+}
+
+// Handler serves the pricing API
+type Handler struct {
+	repo     repository.PricingRepository
+	notifier Notifier
+	auditor  *audit.Auditor
+	logger   *zap.Logger
+}
+
+// NewHandler creates a new handler
+func NewHandler(repo repository.PricingRepository, notifier Notifier, auditor *audit.Auditor, logger *zap.Logger) *Handler {
+	return &Handler{
+		repo:     repo,
+		notifier: notifier,
+		auditor:  auditor,
+		logger:   logger,
+	}
+}
+
+// SetPriceRequest sets a product's price, at once or from EffectiveAt
+type SetPriceRequest struct {
+	Price       *money.Money `json:"price" binding:"required"`
+	EffectiveAt *time.Time   `json:"effective_at"`
+}
+
+// PromotionRequest is a promotion rule; which amounts are required depends
+// on the type
+type PromotionRequest struct {
+	Name        string               `json:"name" binding:"required,max=200"`
+	Code        string               `json:"code" binding:"max=50"`
+	Type        domain.PromotionType `json:"type" binding:"required,oneof=percentage fixed bogo"`
+	PercentOff  int                  `json:"percent_off" binding:"min=0,max=100"`
+	AmountOff   *money.Money         `json:"amount_off"`
+	BuyQuantity int                  `json:"buy_quantity" binding:"min=0,max=100"`
+	GetQuantity int                  `json:"get_quantity" binding:"min=0,max=100"`
+	ProductIDs  []string             `json:"product_ids" binding:"max=1000,dive,required,max=255"`
+	Segments    []string             `json:"segments" binding:"max=20,dive,required,max=50"`
+	Priority    int                  `json:"priority"`
+	StartsAt    *time.Time           `json:"starts_at"`
+	EndsAt      *time.Time           `json:"ends_at"`
+	// Active defaults to true
+	Active *bool `json:"active"`
+}
+
+// SetSegmentsRequest replaces a customer's segments
+type SetSegmentsRequest struct {
+	Segments []string `json:"segments" binding:"max=20,dive,required,max=50"`
+}
+
+// QuoteItem is a basket line to price
+type QuoteItem struct {
+	ProductID string `json:"product_id" binding:"required,max=255"`
+	Quantity  int    `json:"quantity" binding:"required,min=1,max=1000"`
+}
+
+// QuoteRequest is a basket to price, with the promotion codes the customer
+// entered
+type QuoteRequest struct {
+	Items      []QuoteItem `json:"items" binding:"required,min=1,max=100,dive"`
+	PromoCodes []string    `json:"promo_codes" binding:"max=5,dive,max=50"`
+	// At prices the basket at another time, e.g. to preview a sale; it
+	// needs the pricing:manage permission
+	At *time.Time `json:"at"`
+}
+
+// GetPrice returns a product's current price
+// GET /api/v1/pricing/prices/:productId
+func (h *Handler) GetPrice(c *gin.Context) {
+	productID := c.Param("productId")
+
+	prices, err := h.repo.CurrentPrices(c.Request.Context(), []string{productID}, time.Now())
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Price"))
+		return
+	}
+	price, ok := prices[productID]
+	if !ok {
+		apperrors.Abort(c, apperrors.NewNotFound("Price"))
+		return
+	}
+
+	c.JSON(http.StatusOK, price)
+}
+
+// ListPrices returns the current prices of up to 100 products; products
+// without a price are left out
+// GET /api/v1/pricing/prices?product_ids=
+func (h *Handler) ListPrices(c *gin.Context) {
+	var productIDs []string
+	for _, id := range strings.Split(c.Query("product_ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			productIDs = append(productIDs, id)
+		}
+	}
+	if len(productIDs) == 0 || len(productIDs) > maxBatchProducts {
+		apperrors.Abort(c, apperrors.NewValidation(apperrors.FieldError{
+			Field:   "product_ids",
+			Rule:    "max",
+			Message: "product_ids must list 1 to 100 comma-separated product IDs",
+		}))
+		return
+	}
+
+	prices, err := h.repo.CurrentPrices(c.Request.Context(), productIDs, time.Now())
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Price"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prices": prices})
+}
+
+// SetPrice sets a product's price, taking effect at once or, with a future
+// effective_at, scheduled
+// POST /api/v1/pricing/prices/:productId
+func (h *Handler) SetPrice(c *gin.Context) {
+	var req SetPriceRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	var invalid apperrors.ValidationErrors
+	if req.Price.IsNegative() {
+		invalid.Add("price.minor_units", "min", "price must not be negative")
+	}
+	if len(req.Price.Currency) != 3 {
+		invalid.Add("price.currency", "len", "currency must be an ISO 4217 code, e.g. USD")
+	}
+	if err := invalid.Err(); err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	price := &domain.Price{
+		ProductID:   c.Param("productId"),
+		Amount:      *req.Price,
+		EffectiveAt: now,
+		CreatedBy:   c.GetString(sharedauth.ContextUserID),
+	}
+	if req.EffectiveAt != nil && req.EffectiveAt.After(now) {
+		price.EffectiveAt = *req.EffectiveAt
+		price.Scheduled = true
+	}
+
+	if err := h.repo.CreatePrice(c.Request.Context(), price); err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Price"))
+		return
+	}
+
+	kind, action := "immediate", "price.set"
+	if price.Scheduled {
+		kind, action = "scheduled", "price.scheduled"
+	} else {
+		h.notifier.Notify()
+	}
+	metrics.PricesSetTotal.WithLabelValues(kind).Inc()
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   action,
+		Resource: audit.Resource{Type: "product", ID: price.ProductID},
+		After:    price,
+	})
+
+	c.JSON(http.StatusCreated, price)
+}
+
+// ListScheduledPrices lists a product's prices that haven't taken effect,
+// soonest first
+// GET /api/v1/pricing/prices/:productId/schedule
+func (h *Handler) ListScheduledPrices(c *gin.Context) {
+	prices, err := h.repo.UpcomingPrices(c.Request.Context(), c.Param("productId"), time.Now())
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Price"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prices": prices})
+}
+
+// CancelScheduledPrice cancels a price change that hasn't taken effect
+// DELETE /api/v1/pricing/prices/:productId/schedule/:id
+func (h *Handler) CancelScheduledPrice(c *gin.Context) {
+	productID, id := c.Param("productId"), c.Param("id")
+
+	err := h.repo.CancelScheduledPrice(c.Request.Context(), productID, id, time.Now())
+	switch {
+	case errors.Is(err, domain.ErrPriceNotFound):
+		apperrors.Abort(c, apperrors.NewNotFound("Price"))
+		return
+	case errors.Is(err, domain.ErrAlreadyEffective):
+		apperrors.Abort(c, apperrors.NewConflict("The price has already taken effect"))
+		return
+	case err != nil:
+		apperrors.Abort(c, apperrors.FromSQL(err, "Price"))
+		return
+	}
+
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   "price.schedule_cancelled",
+		Resource: audit.Resource{Type: "product", ID: productID},
+		Metadata: map[string]string{"price_id": id},
+	})
+
+	c.Status(http.StatusNoContent)
+}
+
+// CreatePromotion creates a promotion rule
+// POST /api/v1/pricing/promotions
+func (h *Handler) CreatePromotion(c *gin.Context) {
+	var req PromotionRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	promotion, invalid := promotionFrom(&req)
+	if invalid != nil {
+		apperrors.Abort(c, invalid)
+		return
+	}
+
+	if err := h.repo.CreatePromotion(c.Request.Context(), promotion); errors.Is(err, domain.ErrDuplicateCode) {
+		apperrors.Abort(c, apperrors.NewConflict("An active promotion already has this code"))
+		return
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Promotion"))
+		return
+	}
+
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   "promotion.created",
+		Resource: audit.Resource{Type: "promotion", ID: promotion.ID},
+		After:    promotion,
+	})
+
+	c.JSON(http.StatusCreated, promotion)
+}
+
+// ListPromotions lists promotions, newest first, optionally only active
+// ones or those for a product
+// GET /api/v1/pricing/promotions?active=true&product_id=
+func (h *Handler) ListPromotions(c *gin.Context) {
+	filter := repository.PromotionFilter{
+		ActiveOnly: c.Query("active") == "true",
+		ProductID:  c.Query("product_id"),
+	}
+
+	promotions, err := h.repo.ListPromotions(c.Request.Context(), filter)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Promotion"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"promotions": promotions})
+}
+
+// GetPromotion returns a promotion
+// GET /api/v1/pricing/promotions/:id
+func (h *Handler) GetPromotion(c *gin.Context) {
+	promotion, err := h.repo.GetPromotion(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, domain.ErrPromotionNotFound) {
+		apperrors.Abort(c, apperrors.NewNotFound("Promotion"))
+		return
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Promotion"))
+		return
+	}
+
+	c.JSON(http.StatusOK, promotion)
+}
+
+// UpdatePromotion replaces a promotion's rule
+// PUT /api/v1/pricing/promotions/:id
+func (h *Handler) UpdatePromotion(c *gin.Context) {
+	var req PromotionRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	after, invalid := promotionFrom(&req)
+	if invalid != nil {
+		apperrors.Abort(c, invalid)
+		return
+	}
+
+	ctx := c.Request.Context()
+	before, err := h.repo.GetPromotion(ctx, c.Param("id"))
+	if errors.Is(err, domain.ErrPromotionNotFound) {
+		apperrors.Abort(c, apperrors.NewNotFound("Promotion"))
+		return
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Promotion"))
+		return
+	}
+
+	after.ID = before.ID
+	after.CreatedAt = before.CreatedAt
+	if req.StartsAt == nil {
+		after.StartsAt = before.StartsAt
+	}
+	h.update(c, before, after, "promotion.updated")
+}
+
+// DeactivatePromotion ends a promotion; it stays listed, inactive
+// DELETE /api/v1/pricing/promotions/:id
+func (h *Handler) DeactivatePromotion(c *gin.Context) {
+	before, err := h.repo.GetPromotion(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, domain.ErrPromotionNotFound) {
+		apperrors.Abort(c, apperrors.NewNotFound("Promotion"))
+		return
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Promotion"))
+		return
+	}
+
+	after := *before
+	after.Active = false
+	h.update(c, before, &after, "promotion.deactivated")
+}
+
+// update stores a promotion's new rule and audits it
+func (h *Handler) update(c *gin.Context, before, after *domain.Promotion, action string) {
+	err := h.repo.UpdatePromotion(c.Request.Context(), after)
+	switch {
+	case errors.Is(err, domain.ErrPromotionNotFound):
+		apperrors.Abort(c, apperrors.NewNotFound("Promotion"))
+		return
+	case errors.Is(err, domain.ErrDuplicateCode):
+		apperrors.Abort(c, apperrors.NewConflict("An active promotion already has this code"))
+		return
+	case err != nil:
+		apperrors.Abort(c, apperrors.FromSQL(err, "Promotion"))
+		return
+	}
+
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   action,
+		Resource: audit.Resource{Type: "promotion", ID: after.ID},
+		Before:   before,
+		After:    after,
+	})
+
+	c.JSON(http.StatusOK, after)
+}
+
+// GetSegments returns a customer's segments
+// GET /api/v1/pricing/customers/:userId/segments
+func (h *Handler) GetSegments(c *gin.Context) {
+	userID := c.Param("userId")
+
+	segments, err := h.repo.Segments(c.Request.Context(), userID)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Segment"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "segments": segments})
+}
+
+// SetSegments replaces a customer's segments
+// PUT /api/v1/pricing/customers/:userId/segments
+func (h *Handler) SetSegments(c *gin.Context) {
+	var req SetSegmentsRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.Param("userId")
+	before, err := h.repo.Segments(ctx, userID)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Segment"))
+		return
+	}
+
+	segments := normalizeSegments(req.Segments)
+	if err := h.repo.SetSegments(ctx, userID, segments); err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Segment"))
+		return
+	}
+
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   "customer.segments_updated",
+		Resource: audit.Resource{Type: "user", ID: userID},
+		Before:   before,
+		After:    segments,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "segments": segments})
+}
+
+// Quote prices a basket: each line at the product's current price less
+// the best promotion for it. Signed-in customers get their segments'
+// promotions.
+// POST /api/v1/pricing/quotes
+func (h *Handler) Quote(c *gin.Context) {
+	var req QuoteRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	at := time.Now().UTC()
+	if req.At != nil {
+		claims, ok := sharedauth.ClaimsFrom(c)
+		if !ok || !claims.HasPermission(PermissionManage) {
+			apperrors.Abort(c, apperrors.NewForbidden("Quoting at another time requires the pricing:manage permission"))
+			return
+		}
+		at = req.At.UTC()
+	}
+
+	items := make([]quote.Item, len(req.Items))
+	for i, item := range req.Items {
+		items[i] = quote.Item{ProductID: item.ProductID, Quantity: item.Quantity}
+	}
+
+	q, err := h.quoteBasket(c.Request.Context(), items, req.PromoCodes, c.GetString(sharedauth.ContextUserID), at)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, q)
+}
+
+// quoteBasket prices items at the prices and promotions running at at, for
+// the HTTP and gRPC APIs. userID, if not empty, is the customer whose
+// segments' promotions apply.
+func (h *Handler) quoteBasket(ctx context.Context, items []quote.Item, codes []string, userID string, at time.Time) (*quote.Quote, error) {
+	productIDs := make([]string, len(items))
+	for i, item := range items {
+		productIDs[i] = item.ProductID
+	}
+
+	var segments []string
+	if userID != "" {
+		var err error
+		if segments, err = h.repo.Segments(ctx, userID); err != nil {
+			metrics.QuotesTotal.WithLabelValues("failed").Inc()
+			return nil, apperrors.FromSQL(err, "Segment")
+		}
+	}
+
+	current, err := h.repo.CurrentPrices(ctx, productIDs, at)
+	if err != nil {
+		metrics.QuotesTotal.WithLabelValues("failed").Inc()
+		return nil, apperrors.FromSQL(err, "Price")
+	}
+	prices := make(map[string]money.Money, len(current))
+	for productID, price := range current {
+		prices[productID] = price.Amount
+	}
+
+	promotions, err := h.repo.RunningPromotions(ctx, at)
+	if err != nil {
+		metrics.QuotesTotal.WithLabelValues("failed").Inc()
+		return nil, apperrors.FromSQL(err, "Promotion")
+	}
+
+	q, err := quote.Compute(quote.Request{Items: items, Codes: codes, Segments: segments, At: at}, prices, promotions)
+	var unpriced *quote.UnpricedError
+	switch {
+	case errors.As(err, &unpriced):
+		metrics.QuotesTotal.WithLabelValues("unpriced").Inc()
+		return nil, apperrors.NewCode(CodeUnpricedProducts, "Some products have no price").
+			WithFields(map[string]interface{}{"product_ids": unpriced.ProductIDs})
+	case errors.Is(err, quote.ErrMixedCurrencies):
+		metrics.QuotesTotal.WithLabelValues("unpriced").Inc()
+		return nil, apperrors.NewCode(CodeMixedCurrencies, "Products are priced in different currencies")
+	case err != nil:
+		metrics.QuotesTotal.WithLabelValues("failed").Inc()
+		return nil, apperrors.Wrap(err, "Failed to quote basket")
+	}
+
+	metrics.QuotesTotal.WithLabelValues("quoted").Inc()
+	for _, promotion := range q.Promotions {
+		metrics.PromotionsAppliedTotal.WithLabelValues(string(promotion.Type)).Inc()
+	}
+	return q, nil
+}
+
+// HealthCheck reports whether the database is reachable
+func (h *Handler) HealthCheck(c *gin.Context) {
+	if err := h.repo.Ping(c.Request.Context()); err != nil {
+		h.logger.Warn("Health check failed", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unhealthy",
+			"service": "pricing-service",
+			"version": "1.0.0",
+			"error":   "database unavailable",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "healthy",
+		"service": "pricing-service",
+		"version": "1.0.0",
+	})
+}
+
+// promotionFrom validates req's type-specific fields and returns the
+// promotion it describes
+func promotionFrom(req *PromotionRequest) (*domain.Promotion, *apperrors.AppError) {
+	var invalid apperrors.ValidationErrors
+
+	switch req.Type {
+	case domain.PromotionPercentage:
+		if req.PercentOff < 1 {
+			invalid.Add("percent_off", "required", "percent_off must be 1 to 100 for percentage promotions")
+		}
+	case domain.PromotionFixed:
+		if req.AmountOff == nil || req.AmountOff.MinorUnits < 1 || len(req.AmountOff.Currency) != 3 {
+			invalid.Add("amount_off", "required", "amount_off must be a positive amount for fixed promotions")
+		}
+	case domain.PromotionBOGO:
+		if req.BuyQuantity < 1 || req.GetQuantity < 1 {
+			invalid.Add("buy_quantity", "required", "buy_quantity and get_quantity must be positive for bogo promotions")
+		}
+	}
+
+	startsAt := time.Now().UTC()
+	if req.StartsAt != nil {
+		startsAt = req.StartsAt.UTC()
+	}
+	if req.EndsAt != nil && !req.EndsAt.After(startsAt) {
+		invalid.Add("ends_at", "gtfield", "ends_at must be after starts_at")
+	}
+	if err := invalid.Err(); err != nil {
+		return nil, err
+	}
+
+	promotion := &domain.Promotion{
+		Name:       strings.TrimSpace(req.Name),
+		Code:       strings.ToUpper(strings.TrimSpace(req.Code)),
+		Type:       req.Type,
+		ProductIDs: req.ProductIDs,
+		Segments:   normalizeSegments(req.Segments),
+		Priority:   req.Priority,
+		StartsAt:   startsAt,
+		Active:     req.Active == nil || *req.Active,
+	}
+	if promotion.ProductIDs == nil {
+		promotion.ProductIDs = []string{}
+	}
+	if req.EndsAt != nil {
+		endsAt := req.EndsAt.UTC()
+		promotion.EndsAt = &endsAt
+	}
+
+	// Only the amounts of the promotion's type are kept
+	switch req.Type {
+	case domain.PromotionPercentage:
+		promotion.PercentOff = req.PercentOff
+	case domain.PromotionFixed:
+		promotion.AmountOff = req.AmountOff
+	case domain.PromotionBOGO:
+		promotion.BuyQuantity = req.BuyQuantity
+		promotion.GetQuantity = req.GetQuantity
+	}
+	return promotion, nil
+}
+
+// normalizeSegments lowercases segments and sorts them without duplicates
+func normalizeSegments(segments []string) []string {
+	seen := make(map[string]bool, len(segments))
+	normalized := []string{}
+	for _, segment := range segments {
+		segment = strings.ToLower(strings.TrimSpace(segment))
+		if segment != "" && !seen[segment] {
+			seen[segment] = true
+			normalized = append(normalized, segment)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/money"
+)
+
+// Price is a product's base price from EffectiveAt until the next one
+type Price struct {
+	ID          string      `json:"id"`
+	ProductID   string      `json:"product_id"`
+	Amount      money.Money `json:"price"`
+	EffectiveAt time.Time   `json:"effective_at"`
+	// Scheduled is true for prices set ahead of time
+	Scheduled bool      `json:"scheduled"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PromotionType is how a promotion discounts
+type PromotionType string
+
+const (
+	// PromotionPercentage takes PercentOff percent off the line
+	PromotionPercentage PromotionType = "percentage"
+	// PromotionFixed takes AmountOff off each unit, down to zero
+	PromotionFixed PromotionType = "fixed"
+	// PromotionBOGO gives GetQuantity units free for every BuyQuantity
+	// bought, e.g. buy one get one free
+	PromotionBOGO PromotionType = "bogo"
+)
+
+// Promotion is a discount rule
+type Promotion struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Code, when set, must be entered by the customer
+	Code        string        `json:"code,omitempty"`
+	Type        PromotionType `json:"type"`
+	PercentOff  int           `json:"percent_off,omitempty"`
+	AmountOff   *money.Money  `json:"amount_off,omitempty"`
+	BuyQuantity int           `json:"buy_quantity,omitempty"`
+	GetQuantity int           `json:"get_quantity,omitempty"`
+	// ProductIDs limits the promotion to products; empty is every product
+	ProductIDs []string `json:"product_ids"`
+	// Segments limits the promotion to customers in any of them; empty is
+	// every customer
+	Segments []string `json:"segments"`
+	// Priority breaks ties between promotions saving the same, highest
+	// first
+	Priority  int        `json:"priority"`
+	StartsAt  time.Time  `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at,omitempty"`
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// RunsAt reports whether the promotion is active at t
+func (p *Promotion) RunsAt(t time.Time) bool {
+	return p.Active && !t.Before(p.StartsAt) && (p.EndsAt == nil || t.Before(*p.EndsAt))
+}
+
+// Common errors
+var (
+	ErrPriceNotFound     = errors.New("price not found")
+	ErrPromotionNotFound = errors.New("promotion not found")
+	// ErrDuplicateCode is returned when an active promotion already has
+	// the code
+	ErrDuplicateCode = errors.New("promotion code already in use")
+	// ErrAlreadyEffective is returned when cancelling a scheduled price
+	// that has taken effect
+	ErrAlreadyEffective = errors.New("price already in effect")
+)
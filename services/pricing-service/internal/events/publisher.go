@@ -0,0 +1,74 @@
+package events
+
+import (
+	"context"
+
+	sharedevents "github.com/ecommerce-platform/shared/go/events"
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce/pricing-service/internal/domain"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+type Publisher interface {
+	// PublishPriceChanged announces that price took effect, replacing
+	// oldPrice, or nil for a product's first price
+	PublishPriceChanged(ctx context.Context, price *domain.Price, oldPrice *money.Money) error
+	Close() error
+}
+
+type kafkaPublisher struct {
+	producer *sharedkafka.Producer
+	logger   *zap.Logger
+}
+
+// NewKafkaPublisher creates a publisher. Events are keyed by product, so a
+// product's price changes reach consumers in order.
+func NewKafkaPublisher(brokers []string, topic string, logger *zap.Logger) Publisher {
+	producer := sharedkafka.NewProducer(sharedkafka.ProducerConfig{
+		Brokers: brokers,
+		Topic:   topic,
+	}, logger)
+
+	return &kafkaPublisher{
+		producer: producer,
+		logger:   logger,
+	}
+}
+
+func (p *kafkaPublisher) publishEvent(ctx context.Context, env sharedevents.Envelope, payload sharedevents.Payload) error {
+	data, err := sharedevents.Marshal(env, payload)
+	if err != nil {
+		p.logger.Error("Failed to marshal event", zap.Error(err))
+		return err
+	}
+
+	message := kafka.Message{
+		Key:   []byte(env.ProductID),
+		Value: data,
+	}
+
+	if err := p.producer.Publish(ctx, message); err != nil {
+		p.logger.Error("Failed to publish event", zap.Error(err), zap.String("event_type", payload.EventType()))
+		return err
+	}
+
+	p.logger.Debug("Event published", zap.String("event_type", payload.EventType()), zap.String("product_id", env.ProductID))
+	return nil
+}
+
+func (p *kafkaPublisher) PublishPriceChanged(ctx context.Context, price *domain.Price, oldPrice *money.Money) error {
+	env := sharedevents.Envelope{ProductID: price.ProductID}
+	return p.publishEvent(ctx, env, &sharedevents.PriceChanged{
+		ProductID:   price.ProductID,
+		OldPrice:    oldPrice,
+		NewPrice:    price.Amount,
+		EffectiveAt: price.EffectiveAt,
+		Scheduled:   price.Scheduled,
+	})
+}
+
+func (p *kafkaPublisher) Close() error {
+	return p.producer.Close()
+}
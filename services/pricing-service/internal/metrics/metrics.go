@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// PricesSetTotal counts prices set by admins, taking effect now or
+	// scheduled
+	PricesSetTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pricing_prices_set_total",
+		Help: "Prices set, per kind: immediate or scheduled",
+	}, []string{"kind"})
+
+	// PriceChangesAnnouncedTotal counts price.changed events by result:
+	// published or failed
+	PriceChangesAnnouncedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pricing_price_changes_announced_total",
+		Help: "price.changed events for prices taking effect, per result",
+	}, []string{"result"})
+
+	// QuotesTotal counts basket quotes by result: quoted, unpriced (some
+	// product has no price) or failed
+	QuotesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pricing_quotes_total",
+		Help: "Basket quotes, per result",
+	}, []string{"result"})
+
+	// PromotionsAppliedTotal counts promotions applied in quotes, by type
+	PromotionsAppliedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pricing_promotions_applied_total",
+		Help: "Promotions discounting quoted baskets, per promotion type",
+	}, []string{"type"})
+)
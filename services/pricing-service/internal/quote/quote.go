@@ -0,0 +1,287 @@
+// Package quote prices a basket: base prices less the best promotion on
+// each line. It is a pure function of its inputs, in integer minor units,
+// so the same basket at the same time always costs the same.
+package quote
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce/pricing-service/internal/domain"
+)
+
+// ErrMixedCurrencies is returned for baskets priced in several currencies
+var ErrMixedCurrencies = errors.New("products are priced in different currencies")
+
+// UnpricedError lists the products of a basket without a price
+type UnpricedError struct {
+	ProductIDs []string
+}
+
+func (e *UnpricedError) Error() string {
+	return fmt.Sprintf("no price for products: %s", strings.Join(e.ProductIDs, ", "))
+}
+
+// Item is a basket line
+type Item struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+// Request is a basket to price for a customer
+type Request struct {
+	Items []Item
+	// Codes are the promotion codes the customer entered
+	Codes []string
+	// Segments are the customer's segments
+	Segments []string
+	// At is when the basket is priced
+	At time.Time
+}
+
+// Quote is a priced basket
+type Quote struct {
+	Currency      string             `json:"currency"`
+	Lines         []Line             `json:"lines"`
+	Subtotal      money.Money        `json:"subtotal"`
+	DiscountTotal money.Money        `json:"discount_total"`
+	Total         money.Money        `json:"total"`
+	Promotions    []AppliedPromotion `json:"promotions"`
+	// UnusedCodes are entered codes that discounted nothing: unknown,
+	// expired, or not for these products or this customer
+	UnusedCodes []string  `json:"unused_codes,omitempty"`
+	QuotedAt    time.Time `json:"quoted_at"`
+}
+
+// Line is a priced basket line
+type Line struct {
+	ProductID   string      `json:"product_id"`
+	Quantity    int         `json:"quantity"`
+	UnitPrice   money.Money `json:"unit_price"`
+	Subtotal    money.Money `json:"subtotal"`
+	Discount    money.Money `json:"discount"`
+	Total       money.Money `json:"total"`
+	PromotionID string      `json:"promotion_id,omitempty"`
+}
+
+// AppliedPromotion is a promotion that discounted the basket
+type AppliedPromotion struct {
+	ID       string               `json:"id"`
+	Name     string               `json:"name"`
+	Code     string               `json:"code,omitempty"`
+	Type     domain.PromotionType `json:"type"`
+	Discount money.Money          `json:"discount"`
+}
+
+// Compute prices req from the products' prices at req.At and the
+// promotions running then. Lines of the same product are merged.
+// Promotions don't stack: each line gets the one saving the most, ties
+// going to the highest priority, then the lowest ID.
+func Compute(req Request, prices map[string]money.Money, promotions []domain.Promotion) (*Quote, error) {
+	items := mergeItems(req.Items)
+
+	var unpriced []string
+	currency := ""
+	for _, item := range items {
+		price, ok := prices[item.ProductID]
+		if !ok {
+			unpriced = append(unpriced, item.ProductID)
+			continue
+		}
+		if currency == "" {
+			currency = price.Currency
+		} else if price.Currency != currency {
+			return nil, ErrMixedCurrencies
+		}
+	}
+	if len(unpriced) > 0 {
+		return nil, &UnpricedError{ProductIDs: unpriced}
+	}
+
+	candidates := eligible(promotions, req)
+	usedCodes := make(map[string]bool)
+	applied := make(map[string]*AppliedPromotion)
+	var appliedOrder []string
+
+	quote := &Quote{
+		Currency:      currency,
+		Lines:         make([]Line, 0, len(items)),
+		Subtotal:      money.New(0, currency),
+		DiscountTotal: money.New(0, currency),
+		QuotedAt:      req.At,
+	}
+	for _, item := range items {
+		unit := prices[item.ProductID]
+		line := Line{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			UnitPrice: unit,
+			Subtotal:  unit.Mul(int64(item.Quantity)),
+			Discount:  money.New(0, currency),
+		}
+
+		var best *domain.Promotion
+		for i := range candidates {
+			promotion := &candidates[i]
+			if !promotion.appliesTo(item.ProductID) {
+				continue
+			}
+			if discount := lineDiscount(&promotion.Promotion, line); discount > line.Discount.MinorUnits {
+				line.Discount = money.New(discount, currency)
+				best = &promotion.Promotion
+			}
+		}
+
+		line.Total = money.New(line.Subtotal.MinorUnits-line.Discount.MinorUnits, currency)
+		if best != nil {
+			line.PromotionID = best.ID
+			if best.Code != "" {
+				usedCodes[normalizeCode(best.Code)] = true
+			}
+			if applied[best.ID] == nil {
+				applied[best.ID] = &AppliedPromotion{ID: best.ID, Name: best.Name, Code: best.Code, Type: best.Type, Discount: money.New(0, currency)}
+				appliedOrder = append(appliedOrder, best.ID)
+			}
+			applied[best.ID].Discount.MinorUnits += line.Discount.MinorUnits
+		}
+
+		quote.Subtotal.MinorUnits += line.Subtotal.MinorUnits
+		quote.DiscountTotal.MinorUnits += line.Discount.MinorUnits
+		quote.Lines = append(quote.Lines, line)
+	}
+	quote.Total = money.New(quote.Subtotal.MinorUnits-quote.DiscountTotal.MinorUnits, currency)
+
+	quote.Promotions = make([]AppliedPromotion, 0, len(appliedOrder))
+	for _, id := range appliedOrder {
+		quote.Promotions = append(quote.Promotions, *applied[id])
+	}
+	for _, code := range req.Codes {
+		if normalized := normalizeCode(code); normalized != "" && !usedCodes[normalized] {
+			quote.UnusedCodes = append(quote.UnusedCodes, code)
+		}
+	}
+	return quote, nil
+}
+
+// candidate is a promotion eligible for the basket
+type candidate struct {
+	domain.Promotion
+	products map[string]bool
+}
+
+func (c *candidate) appliesTo(productID string) bool {
+	return len(c.products) == 0 || c.products[productID]
+}
+
+// eligible returns the promotions running at req.At for the customer's
+// segments and codes, in tie-break order
+func eligible(promotions []domain.Promotion, req Request) []candidate {
+	codes := make(map[string]bool, len(req.Codes))
+	for _, code := range req.Codes {
+		codes[normalizeCode(code)] = true
+	}
+	segments := make(map[string]bool, len(req.Segments))
+	for _, segment := range req.Segments {
+		segments[segment] = true
+	}
+
+	var candidates []candidate
+	for _, promotion := range promotions {
+		if !promotion.RunsAt(req.At) {
+			continue
+		}
+		if promotion.Code != "" && !codes[normalizeCode(promotion.Code)] {
+			continue
+		}
+		if len(promotion.Segments) > 0 && !anyIn(promotion.Segments, segments) {
+			continue
+		}
+
+		c := candidate{Promotion: promotion}
+		if len(promotion.ProductIDs) > 0 {
+			c.products = make(map[string]bool, len(promotion.ProductIDs))
+			for _, productID := range promotion.ProductIDs {
+				c.products[productID] = true
+			}
+		}
+		candidates = append(candidates, c)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Priority != candidates[j].Priority {
+			return candidates[i].Priority > candidates[j].Priority
+		}
+		return candidates[i].ID < candidates[j].ID
+	})
+	return candidates
+}
+
+// lineDiscount is what promotion takes off line, in minor units, at most
+// the line's subtotal
+func lineDiscount(promotion *domain.Promotion, line Line) int64 {
+	unit := line.UnitPrice.MinorUnits
+	quantity := int64(line.Quantity)
+
+	var discount int64
+	switch promotion.Type {
+	case domain.PromotionPercentage:
+		// Rounded half up to the minor unit
+		discount = (line.Subtotal.MinorUnits*int64(promotion.PercentOff) + 50) / 100
+	case domain.PromotionFixed:
+		if promotion.AmountOff == nil || promotion.AmountOff.Currency != line.UnitPrice.Currency {
+			return 0
+		}
+		perUnit := promotion.AmountOff.MinorUnits
+		if perUnit > unit {
+			perUnit = unit
+		}
+		discount = perUnit * quantity
+	case domain.PromotionBOGO:
+		group := int64(promotion.BuyQuantity + promotion.GetQuantity)
+		if promotion.BuyQuantity < 1 || promotion.GetQuantity < 1 {
+			return 0
+		}
+		discount = quantity / group * int64(promotion.GetQuantity) * unit
+	}
+
+	if discount > line.Subtotal.MinorUnits {
+		discount = line.Subtotal.MinorUnits
+	}
+	if discount < 0 {
+		return 0
+	}
+	return discount
+}
+
+// mergeItems sums the quantities of repeated products, keeping the order
+// each first appears in
+func mergeItems(items []Item) []Item {
+	merged := make([]Item, 0, len(items))
+	index := make(map[string]int, len(items))
+	for _, item := range items {
+		if i, ok := index[item.ProductID]; ok {
+			merged[i].Quantity += item.Quantity
+			continue
+		}
+		index[item.ProductID] = len(merged)
+		merged = append(merged, item)
+	}
+	return merged
+}
+
+func anyIn(values []string, set map[string]bool) bool {
+	for _, value := range values {
+		if set[value] {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
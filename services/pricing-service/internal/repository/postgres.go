@@ -0,0 +1,441 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	shareddb "github.com/ecommerce-platform/shared/go/db"
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce/pricing-service/internal/domain"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is the SQLSTATE of a duplicate key
+const uniqueViolation = "23505"
+
+const priceColumns = `id, product_id, minor_units, currency, effective_at, scheduled, created_by, created_at`
+
+const promotionColumns = `
+	id, name, code, type, percent_off, amount_off, currency, buy_quantity, get_quantity,
+	product_ids, segments, priority, starts_at, ends_at, active, created_at, updated_at
+`
+
+type postgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *sql.DB) PricingRepository {
+	return &postgresRepository{db: db}
+}
+
+// Ping checks that the database is reachable
+func (r *postgresRepository) Ping(ctx context.Context) error {
+	return shareddb.Check(ctx, r.db)
+}
+
+// CreatePrice stores a price
+func (r *postgresRepository) CreatePrice(ctx context.Context, price *domain.Price) error {
+	price.ID = uuid.New().String()
+	price.CreatedAt = time.Now().UTC()
+	price.EffectiveAt = price.EffectiveAt.UTC()
+
+	query := `
+		INSERT INTO prices (id, product_id, minor_units, currency, effective_at, scheduled, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		price.ID, price.ProductID, price.Amount.MinorUnits, price.Amount.Currency,
+		price.EffectiveAt, price.Scheduled, price.CreatedBy, price.CreatedAt,
+	)
+	return err
+}
+
+// CurrentPrices returns each product's latest price effective by at. Prices
+// effective at the same time are ordered by when they were set.
+func (r *postgresRepository) CurrentPrices(ctx context.Context, productIDs []string, at time.Time) (map[string]*domain.Price, error) {
+	query := `
+		SELECT DISTINCT ON (product_id) ` + priceColumns + `
+		FROM prices
+		WHERE product_id = ANY($1) AND effective_at <= $2
+		ORDER BY product_id, effective_at DESC, created_at DESC, id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(productIDs), at.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make(map[string]*domain.Price, len(productIDs))
+	for rows.Next() {
+		price, err := scanPrice(rows)
+		if err != nil {
+			return nil, err
+		}
+		prices[price.ProductID] = price
+	}
+
+	return prices, rows.Err()
+}
+
+// UpcomingPrices returns a product's prices taking effect after the given
+// time, soonest first
+func (r *postgresRepository) UpcomingPrices(ctx context.Context, productID string, after time.Time) ([]*domain.Price, error) {
+	query := `
+		SELECT ` + priceColumns + `
+		FROM prices
+		WHERE product_id = $1 AND effective_at > $2
+		ORDER BY effective_at, created_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, productID, after.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := []*domain.Price{}
+	for rows.Next() {
+		price, err := scanPrice(rows)
+		if err != nil {
+			return nil, err
+		}
+		prices = append(prices, price)
+	}
+
+	return prices, rows.Err()
+}
+
+// CancelScheduledPrice deletes a price that hasn't taken effect
+func (r *postgresRepository) CancelScheduledPrice(ctx context.Context, productID, id string, after time.Time) error {
+	var deleted bool
+	err := r.db.QueryRowContext(ctx, `
+		WITH target AS (
+			SELECT id, effective_at > $3 AS pending FROM prices WHERE id = $1 AND product_id = $2
+		), removed AS (
+			DELETE FROM prices WHERE id IN (SELECT id FROM target WHERE pending) RETURNING id
+		)
+		SELECT EXISTS (SELECT 1 FROM removed) FROM target
+	`, id, productID, after.UTC()).Scan(&deleted)
+	if err == sql.ErrNoRows {
+		return domain.ErrPriceNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return domain.ErrAlreadyEffective
+	}
+	return nil
+}
+
+// UnannouncedChanges returns prices in effect by at that haven't been
+// announced, each with the amount of the price before it
+func (r *postgresRepository) UnannouncedChanges(ctx context.Context, at time.Time, limit int) ([]*PriceChange, error) {
+	query := `
+		SELECT p.id, p.product_id, p.minor_units, p.currency, p.effective_at, p.scheduled, p.created_by, p.created_at,
+		       prev.minor_units, prev.currency
+		FROM prices p
+		LEFT JOIN LATERAL (
+			SELECT q.minor_units, q.currency
+			FROM prices q
+			WHERE q.product_id = p.product_id
+			  AND (q.effective_at, q.created_at, q.id) < (p.effective_at, p.created_at, p.id)
+			ORDER BY q.effective_at DESC, q.created_at DESC, q.id DESC
+			LIMIT 1
+		) prev ON TRUE
+		WHERE p.announced_at IS NULL AND p.effective_at <= $1
+		ORDER BY p.effective_at, p.created_at, p.id
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, at.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []*PriceChange{}
+	for rows.Next() {
+		price := &domain.Price{}
+		var currency string
+		var oldMinorUnits sql.NullInt64
+		var oldCurrency sql.NullString
+		err := rows.Scan(
+			&price.ID, &price.ProductID, &price.Amount.MinorUnits, &currency, &price.EffectiveAt,
+			&price.Scheduled, &price.CreatedBy, &price.CreatedAt, &oldMinorUnits, &oldCurrency,
+		)
+		if err != nil {
+			return nil, err
+		}
+		price.Amount = money.New(price.Amount.MinorUnits, currency)
+
+		change := &PriceChange{Price: price}
+		if oldMinorUnits.Valid {
+			old := money.New(oldMinorUnits.Int64, oldCurrency.String)
+			change.OldPrice = &old
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
+
+// MarkAnnounced records that a price's price.changed was published
+func (r *postgresRepository) MarkAnnounced(ctx context.Context, id string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE prices SET announced_at = $2 WHERE id = $1`, id, at.UTC())
+	return err
+}
+
+// CreatePromotion stores a promotion
+func (r *postgresRepository) CreatePromotion(ctx context.Context, promotion *domain.Promotion) error {
+	now := time.Now().UTC()
+	promotion.ID = uuid.New().String()
+	promotion.CreatedAt = now
+	promotion.UpdatedAt = now
+
+	amountOff, currency := promotionAmount(promotion)
+	productIDs, segments := textArray(promotion.ProductIDs), textArray(promotion.Segments)
+	query := `
+		INSERT INTO promotions (
+			id, name, code, type, percent_off, amount_off, currency, buy_quantity, get_quantity,
+			product_ids, segments, priority, starts_at, ends_at, active, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		promotion.ID, promotion.Name, nullString(promotion.Code), promotion.Type, promotion.PercentOff,
+		amountOff, currency, promotion.BuyQuantity, promotion.GetQuantity,
+		productIDs, segments, promotion.Priority,
+		promotion.StartsAt.UTC(), utcOrNil(promotion.EndsAt), promotion.Active, promotion.CreatedAt, promotion.UpdatedAt,
+	)
+	return promotionError(err)
+}
+
+// GetPromotion returns a promotion
+func (r *postgresRepository) GetPromotion(ctx context.Context, id string) (*domain.Promotion, error) {
+	query := `SELECT ` + promotionColumns + ` FROM promotions WHERE id = $1`
+
+	promotion, err := scanPromotion(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrPromotionNotFound
+	}
+	return promotion, err
+}
+
+// UpdatePromotion replaces a promotion's rule
+func (r *postgresRepository) UpdatePromotion(ctx context.Context, promotion *domain.Promotion) error {
+	promotion.UpdatedAt = time.Now().UTC()
+
+	amountOff, currency := promotionAmount(promotion)
+	productIDs, segments := textArray(promotion.ProductIDs), textArray(promotion.Segments)
+	query := `
+		UPDATE promotions SET
+			name = $2, code = $3, type = $4, percent_off = $5, amount_off = $6, currency = $7,
+			buy_quantity = $8, get_quantity = $9, product_ids = $10, segments = $11, priority = $12,
+			starts_at = $13, ends_at = $14, active = $15, updated_at = $16
+		WHERE id = $1
+	`
+	result, err := r.db.ExecContext(ctx, query,
+		promotion.ID, promotion.Name, nullString(promotion.Code), promotion.Type, promotion.PercentOff,
+		amountOff, currency, promotion.BuyQuantity, promotion.GetQuantity,
+		productIDs, segments, promotion.Priority,
+		promotion.StartsAt.UTC(), utcOrNil(promotion.EndsAt), promotion.Active, promotion.UpdatedAt,
+	)
+	if err != nil {
+		return promotionError(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrPromotionNotFound
+	}
+	return nil
+}
+
+// ListPromotions returns promotions, most recently created first
+func (r *postgresRepository) ListPromotions(ctx context.Context, filter PromotionFilter) ([]*domain.Promotion, error) {
+	query := `
+		SELECT ` + promotionColumns + `
+		FROM promotions
+		WHERE (NOT $1 OR active)
+		  AND ($2 = '' OR product_ids = '{}' OR $2 = ANY(product_ids))
+		ORDER BY created_at DESC, id DESC
+		LIMIT 500
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, filter.ActiveOnly, filter.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	promotions := []*domain.Promotion{}
+	for rows.Next() {
+		promotion, err := scanPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, promotion)
+	}
+
+	return promotions, rows.Err()
+}
+
+// RunningPromotions returns the active promotions running at the given time
+func (r *postgresRepository) RunningPromotions(ctx context.Context, at time.Time) ([]domain.Promotion, error) {
+	query := `
+		SELECT ` + promotionColumns + `
+		FROM promotions
+		WHERE active AND starts_at <= $1 AND (ends_at IS NULL OR ends_at > $1)
+		ORDER BY id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, at.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var promotions []domain.Promotion
+	for rows.Next() {
+		promotion, err := scanPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, *promotion)
+	}
+
+	return promotions, rows.Err()
+}
+
+// Segments returns a customer's segments, sorted
+func (r *postgresRepository) Segments(ctx context.Context, userID string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT segment FROM customer_segments WHERE user_id = $1 ORDER BY segment`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	segments := []string{}
+	for rows.Next() {
+		var segment string
+		if err := rows.Scan(&segment); err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+
+	return segments, rows.Err()
+}
+
+// SetSegments replaces a customer's segments
+func (r *postgresRepository) SetSegments(ctx context.Context, userID string, segments []string) error {
+	return shareddb.WithTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM customer_segments WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+		if len(segments) == 0 {
+			return nil
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO customer_segments (user_id, segment)
+			SELECT $1, UNNEST($2::TEXT[])
+			ON CONFLICT DO NOTHING
+		`, userID, pq.Array(segments))
+		return err
+	})
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPrice(row scanner) (*domain.Price, error) {
+	price := &domain.Price{}
+	var currency string
+	err := row.Scan(
+		&price.ID, &price.ProductID, &price.Amount.MinorUnits, &currency, &price.EffectiveAt,
+		&price.Scheduled, &price.CreatedBy, &price.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	price.Amount = money.New(price.Amount.MinorUnits, currency)
+	return price, nil
+}
+
+func scanPromotion(row scanner) (*domain.Promotion, error) {
+	promotion := &domain.Promotion{}
+	var code sql.NullString
+	var amountOff int64
+	var currency string
+	var productIDs, segments pq.StringArray
+	var endsAt sql.NullTime
+	err := row.Scan(
+		&promotion.ID, &promotion.Name, &code, &promotion.Type, &promotion.PercentOff, &amountOff, &currency,
+		&promotion.BuyQuantity, &promotion.GetQuantity, &productIDs, &segments, &promotion.Priority,
+		&promotion.StartsAt, &endsAt, &promotion.Active, &promotion.CreatedAt, &promotion.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	promotion.Code = code.String
+	if promotion.Type == domain.PromotionFixed {
+		amount := money.New(amountOff, currency)
+		promotion.AmountOff = &amount
+	}
+	promotion.ProductIDs = []string(productIDs)
+	promotion.Segments = []string(segments)
+	if endsAt.Valid {
+		promotion.EndsAt = &endsAt.Time
+	}
+	return promotion, nil
+}
+
+// promotionAmount returns a fixed promotion's amount off as its columns
+func promotionAmount(promotion *domain.Promotion) (int64, string) {
+	if promotion.AmountOff == nil {
+		return 0, ""
+	}
+	return promotion.AmountOff.MinorUnits, promotion.AmountOff.Currency
+}
+
+// promotionError maps an active promotion's duplicate code to
+// domain.ErrDuplicateCode
+func promotionError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return domain.ErrDuplicateCode
+	}
+	return err
+}
+
+// textArray passes values as an array, empty rather than NULL when nil
+func textArray(values []string) interface{} {
+	if values == nil {
+		values = []string{}
+	}
+	return pq.Array(values)
+}
+
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func utcOrNil(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce/pricing-service/internal/domain"
+)
+
+// PricingRepository defines pricing data operations
+type PricingRepository interface {
+	// Prices
+	// CreatePrice stores a price, in effect from its EffectiveAt
+	CreatePrice(ctx context.Context, price *domain.Price) error
+	// CurrentPrices returns the prices in effect at the given time of the
+	// products that have one, by product ID
+	CurrentPrices(ctx context.Context, productIDs []string, at time.Time) (map[string]*domain.Price, error)
+	// UpcomingPrices returns a product's prices taking effect after the
+	// given time, soonest first
+	UpcomingPrices(ctx context.Context, productID string, after time.Time) ([]*domain.Price, error)
+	// CancelScheduledPrice deletes a product's price that takes effect
+	// after the given time, or returns domain.ErrPriceNotFound or
+	// domain.ErrAlreadyEffective
+	CancelScheduledPrice(ctx context.Context, productID, id string, after time.Time) error
+	// UnannouncedChanges returns up to limit prices in effect by the given
+	// time whose price.changed hasn't been published, oldest first
+	UnannouncedChanges(ctx context.Context, at time.Time, limit int) ([]*PriceChange, error)
+	// MarkAnnounced records that a price's price.changed was published
+	MarkAnnounced(ctx context.Context, id string, at time.Time) error
+
+	// Promotions
+	// CreatePromotion stores a promotion, or returns
+	// domain.ErrDuplicateCode
+	CreatePromotion(ctx context.Context, promotion *domain.Promotion) error
+	GetPromotion(ctx context.Context, id string) (*domain.Promotion, error)
+	// UpdatePromotion replaces a promotion's rule, or returns
+	// domain.ErrDuplicateCode
+	UpdatePromotion(ctx context.Context, promotion *domain.Promotion) error
+	// ListPromotions returns promotions, most recently created first
+	ListPromotions(ctx context.Context, filter PromotionFilter) ([]*domain.Promotion, error)
+	// RunningPromotions returns the active promotions running at the given
+	// time
+	RunningPromotions(ctx context.Context, at time.Time) ([]domain.Promotion, error)
+
+	// Segments
+	// Segments returns a customer's segments, sorted
+	Segments(ctx context.Context, userID string) ([]string, error)
+	// SetSegments replaces a customer's segments
+	SetSegments(ctx context.Context, userID string, segments []string) error
+
+	// Ping checks that the database is reachable
+	Ping(ctx context.Context) error
+}
+
+// PriceChange is a price that took effect and the amount it replaced, if
+// the product had a price before
+type PriceChange struct {
+	Price    *domain.Price
+	OldPrice *money.Money
+}
+
+// PromotionFilter selects the promotions to list
+type PromotionFilter struct {
+	// ActiveOnly leaves out deactivated promotions
+	ActiveOnly bool
+	// ProductID, when set, lists only promotions for the product, including
+	// those for every product
+	ProductID string
+}
@@ -0,0 +1,112 @@
+// Package scheduler announces price changes as they take effect, by
+// publishing price.changed events for prices set now and scheduled ones
+// once their time comes
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/ecommerce/pricing-service/internal/events"
+	"github.com/ecommerce/pricing-service/internal/metrics"
+	"github.com/ecommerce/pricing-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// announceBatchSize bounds the prices announced per pass
+const announceBatchSize = 100
+
+// Announcer publishes price.changed for each price once it is in effect.
+// Prices are marked announced after publishing, so an event may be
+// published twice, e.g. across a restart, but never lost.
+type Announcer struct {
+	repo      repository.PricingRepository
+	publisher events.Publisher
+	interval  time.Duration
+	wake      chan struct{}
+	logger    *zap.Logger
+}
+
+// New creates an announcer checking for prices taking effect every
+// interval
+func New(repo repository.PricingRepository, publisher events.Publisher, interval time.Duration, logger *zap.Logger) *Announcer {
+	return &Announcer{
+		repo:      repo,
+		publisher: publisher,
+		interval:  interval,
+		wake:      make(chan struct{}, 1),
+		logger:    logger,
+	}
+}
+
+// Notify asks the announcer to check for prices now, e.g. after a price is
+// set to take effect at once, rather than at its next tick
+func (a *Announcer) Notify() {
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start runs the announcer until the context is cancelled
+func (a *Announcer) Start(ctx context.Context) {
+	a.logger.Info("Starting price announcer", zap.Duration("interval", a.interval))
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	a.announce(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			a.logger.Info("Stopping price announcer")
+			return
+		case <-ticker.C:
+			a.announce(ctx)
+		case <-a.wake:
+			a.announce(ctx)
+		}
+	}
+}
+
+// announce publishes the changes in effect that haven't been announced,
+// in the order they took effect
+func (a *Announcer) announce(ctx context.Context) {
+	for {
+		changes, err := a.repo.UnannouncedChanges(ctx, time.Now(), announceBatchSize)
+		if err != nil {
+			a.logger.Error("Failed to load price changes to announce", zap.Error(err))
+			return
+		}
+
+		for _, change := range changes {
+			if err := a.publisher.PublishPriceChanged(ctx, change.Price, change.OldPrice); err != nil {
+				metrics.PriceChangesAnnouncedTotal.WithLabelValues("failed").Inc()
+				// Later changes of the product wait, so they aren't
+				// announced out of order
+				a.logger.Error("Failed to publish price change, will retry",
+					zap.String("price_id", change.Price.ID),
+					zap.String("product_id", change.Price.ProductID),
+					zap.Error(err),
+				)
+				return
+			}
+			metrics.PriceChangesAnnouncedTotal.WithLabelValues("published").Inc()
+
+			if err := a.repo.MarkAnnounced(ctx, change.Price.ID, time.Now()); err != nil {
+				a.logger.Error("Failed to mark price change announced",
+					zap.String("price_id", change.Price.ID),
+					zap.Error(err),
+				)
+				return
+			}
+		}
+
+		if len(changes) > 0 {
+			a.logger.Info("Price changes announced", zap.Int("count", len(changes)))
+		}
+		if len(changes) < announceBatchSize {
+			return
+		}
+	}
+}
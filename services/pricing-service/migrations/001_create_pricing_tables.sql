@@ -0,0 +1,52 @@
+-- Base prices, as a history: a product's price at any time is its latest
+-- row effective by then. Prices set now are effective at once; scheduled
+-- ones later.
+CREATE TABLE IF NOT EXISTS prices (
+    id VARCHAR(36) PRIMARY KEY,
+    product_id VARCHAR(255) NOT NULL,
+    minor_units BIGINT NOT NULL CHECK (minor_units >= 0),
+    currency CHAR(3) NOT NULL,
+    effective_at TIMESTAMP NOT NULL,
+    scheduled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- announced_at is set once price.changed is published
+    announced_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_prices_product_effective ON prices(product_id, effective_at DESC, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_prices_unannounced ON prices(effective_at) WHERE announced_at IS NULL;
+
+-- Promotion rules
+CREATE TABLE IF NOT EXISTS promotions (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    -- code, when set, must be entered by the customer
+    code VARCHAR(50),
+    type VARCHAR(20) NOT NULL CHECK (type IN ('percentage', 'fixed', 'bogo')),
+    percent_off INTEGER NOT NULL DEFAULT 0,
+    amount_off BIGINT NOT NULL DEFAULT 0,
+    currency CHAR(3) NOT NULL DEFAULT '',
+    buy_quantity INTEGER NOT NULL DEFAULT 0,
+    get_quantity INTEGER NOT NULL DEFAULT 0,
+    -- empty arrays target every product, and every customer
+    product_ids TEXT[] NOT NULL DEFAULT '{}',
+    segments TEXT[] NOT NULL DEFAULT '{}',
+    priority INTEGER NOT NULL DEFAULT 0,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_promotions_code ON promotions(UPPER(code)) WHERE code IS NOT NULL AND active;
+CREATE INDEX IF NOT EXISTS idx_promotions_window ON promotions(starts_at, ends_at) WHERE active;
+
+-- Customer segments targeted by promotions, e.g. vip
+CREATE TABLE IF NOT EXISTS customer_segments (
+    user_id VARCHAR(255) NOT NULL,
+    segment VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, segment)
+);
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce/recommendation-service/internal/domain"
+	"github.com/ecommerce/recommendation-service/internal/recommender"
+	"github.com/ecommerce/recommendation-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultLimit = 10
+	maxLimit     = 50
+)
+
+// Handler serves the recommendation API
+type Handler struct {
+	recommender *recommender.Recommender
+	repo        repository.RecommendationRepository
+	logger      *zap.Logger
+}
+
+// NewHandler creates a new handler
+func NewHandler(recommender *recommender.Recommender, repo repository.RecommendationRepository, logger *zap.Logger) *Handler {
+	return &Handler{
+		recommender: recommender,
+		repo:        repo,
+		logger:      logger,
+	}
+}
+
+// GetProductRecommendations lists products to show alongside a product:
+// what its customers also bought (default) or what is bought with it
+// GET /api/v1/recommendations/:productId?model=&limit=
+func (h *Handler) GetProductRecommendations(c *gin.Context) {
+	model := domain.Model(c.DefaultQuery("model", string(domain.ModelAlsoBought)))
+	if !model.Valid() {
+		apperrors.Abort(c, apperrors.NewValidation(apperrors.FieldError{
+			Field:   "model",
+			Rule:    "oneof",
+			Message: "model must be one of also_bought, bought_together",
+		}))
+		return
+	}
+	limit, ok := limitParam(c)
+	if !ok {
+		return
+	}
+
+	recommendations, err := h.recommender.ForProduct(c.Request.Context(), model, c.Param("productId"), limit)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to load recommendations"))
+		return
+	}
+
+	c.JSON(http.StatusOK, recommendations)
+}
+
+// GetHomeRecommendations lists the customer's homepage recommendations
+// GET /api/v1/recommendations/home?limit=
+func (h *Handler) GetHomeRecommendations(c *gin.Context) {
+	limit, ok := limitParam(c)
+	if !ok {
+		return
+	}
+
+	recommendations, err := h.recommender.ForUser(c.Request.Context(), c.GetString(sharedauth.ContextUserID), limit)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to load recommendations"))
+		return
+	}
+
+	c.JSON(http.StatusOK, recommendations)
+}
+
+// HealthCheck reports whether the database is reachable, and when the
+// models were last trained
+func (h *Handler) HealthCheck(c *gin.Context) {
+	ctx := c.Request.Context()
+	if err := h.repo.Ping(ctx); err != nil {
+		h.logger.Warn("Health check failed", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unhealthy",
+			"service": "recommendation-service",
+			"version": "1.0.0",
+			"error":   "database unavailable",
+		})
+		return
+	}
+
+	response := gin.H{
+		"status":  "healthy",
+		"service": "recommendation-service",
+		"version": "1.0.0",
+	}
+	if run, err := h.repo.LatestRun(ctx); err == nil {
+		response["models_trained_at"] = run.FinishedAt
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// limitParam reads the limit query parameter, aborting with a validation
+// error if it is out of range
+func limitParam(c *gin.Context) (int, bool) {
+	raw := c.Query("limit")
+	if raw == "" {
+		return defaultLimit, true
+	}
+
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 || limit > maxLimit {
+		apperrors.Abort(c, apperrors.NewValidation(apperrors.FieldError{
+			Field:   "limit",
+			Rule:    "range",
+			Message: "limit must be between 1 and " + strconv.Itoa(maxLimit),
+		}))
+		return 0, false
+	}
+	return limit, true
+}
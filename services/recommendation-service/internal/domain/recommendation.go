@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// SignalKind is how a shopper showed interest in a product
+type SignalKind string
+
+const (
+	// SignalView is a product page view
+	SignalView SignalKind = "view"
+	// SignalCart is a product added to the cart
+	SignalCart SignalKind = "cart"
+	// SignalPurchase is a product ordered
+	SignalPurchase SignalKind = "purchase"
+)
+
+// Weight is how strongly the signal shows interest: buying says more than
+// looking
+func (k SignalKind) Weight() int {
+	switch k {
+	case SignalPurchase:
+		return 4
+	case SignalCart:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// Interaction is a visitor's interest in a product
+type Interaction struct {
+	// VisitorID is the user ID, or SessionVisitor's ID for anonymous
+	// shoppers
+	VisitorID  string
+	ProductID  string
+	Kind       SignalKind
+	OccurredAt time.Time
+}
+
+// SessionVisitor identifies an anonymous shopper by session
+func SessionVisitor(sessionID string) string {
+	return "session:" + sessionID
+}
+
+// Model is a kind of product-to-product recommendation
+type Model string
+
+const (
+	// ModelAlsoBought relates products the same customers were interested
+	// in: customers who bought this also bought
+	ModelAlsoBought Model = "also_bought"
+	// ModelBoughtTogether relates products ordered in the same order
+	ModelBoughtTogether Model = "bought_together"
+)
+
+// Valid reports whether m is a known model
+func (m Model) Valid() bool {
+	return m == ModelAlsoBought || m == ModelBoughtTogether
+}
+
+// Reasons a product is recommended
+const (
+	ReasonAlsoBought     = "also_bought"
+	ReasonBoughtTogether = "bought_together"
+	ReasonPersonalized   = "personalized"
+	ReasonPopular        = "popular"
+)
+
+// Recommendation is a recommended product. Product details are left to the
+// catalog.
+type Recommendation struct {
+	ProductID string  `json:"product_id"`
+	Score     float64 `json:"score"`
+	Reason    string  `json:"reason"`
+}
+
+// ProductRecommendations are the products recommended alongside a product
+type ProductRecommendations struct {
+	ProductID string           `json:"product_id"`
+	Model     Model            `json:"model"`
+	Items     []Recommendation `json:"items"`
+}
+
+// UserRecommendations are a customer's homepage recommendations
+type UserRecommendations struct {
+	UserID string           `json:"user_id"`
+	Items  []Recommendation `json:"items"`
+}
+
+// TrainingRun summarizes a training run
+type TrainingRun struct {
+	ID                  int64     `json:"id"`
+	StartedAt           time.Time `json:"started_at"`
+	FinishedAt          time.Time `json:"finished_at"`
+	WindowStart         time.Time `json:"window_start"`
+	InteractionsPruned  int64     `json:"interactions_pruned"`
+	Products            int       `json:"products"`
+	AlsoBoughtPairs     int       `json:"also_bought_pairs"`
+	BoughtTogetherPairs int       `json:"bought_together_pairs"`
+}
+
+// Common errors
+var (
+	// ErrTrainingInProgress is returned when another replica is training
+	ErrTrainingInProgress = errors.New("training already in progress")
+	// ErrNotTrained is returned before the first training run
+	ErrNotTrained = errors.New("models not trained yet")
+)
@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// SignalEventsTotal counts consumed events by outcome: recorded,
+	// skipped (event types or events without a shopper that carry no
+	// signal) or failed
+	SignalEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "recommendation_signal_events_total",
+		Help: "Order, cart and view events recorded as signals, per event type and result",
+	}, []string{"event_type", "result"})
+
+	// TrainingRunsTotal counts training runs by result: trained, skipped
+	// (another replica was training) or failed
+	TrainingRunsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "recommendation_training_runs_total",
+		Help: "Model training runs, per result",
+	}, []string{"result"})
+
+	// TrainingDuration measures successful training runs
+	TrainingDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "recommendation_training_duration_seconds",
+		Help:    "Duration of model training runs",
+		Buckets: []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900},
+	})
+
+	// RecommendationsServedTotal counts served recommendation lists by kind
+	// (product or user) and cache result (hit or miss)
+	RecommendationsServedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "recommendations_served_total",
+		Help: "Recommendation lists served, per kind and cache result",
+	}, []string{"kind", "cache"})
+)
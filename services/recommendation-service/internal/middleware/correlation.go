@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CorrelationID middleware adds correlation ID to requests, including their
+// context so logging.WithContext logs it
+func CorrelationID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		correlationID := c.GetHeader(logging.CorrelationIDHeader)
+		if correlationID == "" {
+			correlationID = uuid.New().String()
+		}
+
+		c.Set("correlation_id", correlationID)
+		c.Request = c.Request.WithContext(logging.WithCorrelationID(c.Request.Context(), correlationID))
+		c.Header(logging.CorrelationIDHeader, correlationID)
+
+		c.Next()
+	}
+}
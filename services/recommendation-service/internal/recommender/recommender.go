@@ -0,0 +1,135 @@
+// Package recommender serves recommendations from the trained models,
+// topped up with popular products, through the cache
+package recommender
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/recommendation-service/internal/domain"
+	"github.com/ecommerce/recommendation-service/internal/metrics"
+	"github.com/ecommerce/recommendation-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Recommender builds recommendation lists
+type Recommender struct {
+	repo       repository.RecommendationRepository
+	cache      repository.CacheRepository
+	productTTL time.Duration
+	userTTL    time.Duration
+	logger     *zap.Logger
+}
+
+// New creates a recommender caching product lists for productTTL and
+// users' for userTTL
+func New(repo repository.RecommendationRepository, cache repository.CacheRepository, productTTL, userTTL time.Duration, logger *zap.Logger) *Recommender {
+	return &Recommender{
+		repo:       repo,
+		cache:      cache,
+		productTTL: productTTL,
+		userTTL:    userTTL,
+		logger:     logger,
+	}
+}
+
+// ForProduct returns up to limit products related to productID by model,
+// topped up with popular products
+func (r *Recommender) ForProduct(ctx context.Context, model domain.Model, productID string, limit int) (*domain.ProductRecommendations, error) {
+	key := fmt.Sprintf("product:%s:%s:%d", model, productID, limit)
+
+	var cached domain.ProductRecommendations
+	if r.getCached(ctx, key, &cached) {
+		metrics.RecommendationsServedTotal.WithLabelValues("product", "hit").Inc()
+		return &cached, nil
+	}
+
+	items, err := r.repo.Related(ctx, model, productID, limit)
+	if err != nil {
+		return nil, err
+	}
+	items, err = r.topUp(ctx, items, limit, "", productID)
+	if err != nil {
+		return nil, err
+	}
+
+	recommendations := &domain.ProductRecommendations{ProductID: productID, Model: model, Items: items}
+	r.setCached(ctx, key, recommendations, r.productTTL)
+	metrics.RecommendationsServedTotal.WithLabelValues("product", "miss").Inc()
+	return recommendations, nil
+}
+
+// ForUser returns up to limit homepage recommendations for a customer,
+// from their recent interests, topped up with popular products they
+// haven't bought
+func (r *Recommender) ForUser(ctx context.Context, userID string, limit int) (*domain.UserRecommendations, error) {
+	key := fmt.Sprintf("user:%s:%d", userID, limit)
+
+	var cached domain.UserRecommendations
+	if r.getCached(ctx, key, &cached) {
+		metrics.RecommendationsServedTotal.WithLabelValues("user", "hit").Inc()
+		return &cached, nil
+	}
+
+	items, err := r.repo.ForVisitor(ctx, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	items, err = r.topUp(ctx, items, limit, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	recommendations := &domain.UserRecommendations{UserID: userID, Items: items}
+	r.setCached(ctx, key, recommendations, r.userTTL)
+	metrics.RecommendationsServedTotal.WithLabelValues("user", "miss").Inc()
+	return recommendations, nil
+}
+
+// topUp appends popular products to items until there are limit, leaving
+// out products already listed, excluded or bought by purchasedBy
+func (r *Recommender) topUp(ctx context.Context, items []domain.Recommendation, limit int, purchasedBy string, exclude ...string) ([]domain.Recommendation, error) {
+	if len(items) >= limit {
+		return items, nil
+	}
+
+	skip := make(map[string]bool, len(items)+len(exclude))
+	for _, productID := range exclude {
+		skip[productID] = true
+	}
+	for _, item := range items {
+		skip[item.ProductID] = true
+	}
+
+	popular, err := r.repo.Popular(ctx, purchasedBy, limit+len(skip))
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range popular {
+		if len(items) >= limit {
+			break
+		}
+		if !skip[item.ProductID] {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// getCached reads a cached list; the cache being down only costs a query
+func (r *Recommender) getCached(ctx context.Context, key string, dest interface{}) bool {
+	found, err := r.cache.Get(ctx, key, dest)
+	if err != nil {
+		logging.WithContext(ctx, r.logger).Warn("Failed to read cached recommendations", zap.String("key", key), zap.Error(err))
+		return false
+	}
+	return found
+}
+
+func (r *Recommender) setCached(ctx context.Context, key string, value interface{}, ttl time.Duration) {
+	if err := r.cache.Set(ctx, key, value, ttl); err != nil {
+		logging.WithContext(ctx, r.logger).Warn("Failed to cache recommendations", zap.String("key", key), zap.Error(err))
+	}
+}
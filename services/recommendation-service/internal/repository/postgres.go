@@ -0,0 +1,314 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	shareddb "github.com/ecommerce-platform/shared/go/db"
+	"github.com/ecommerce/recommendation-service/internal/domain"
+)
+
+// trainingLockID is the Postgres advisory lock held while training, so
+// replicas on the same schedule train once
+const trainingLockID = 7_262_071_500
+
+// visitorInterests bounds the recent products of a visitor that are
+// related: in training, so a heavy browser doesn't dominate the pairs,
+// and for their homepage, so it follows what they look at now
+const visitorInterests = 20
+
+type postgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *sql.DB) RecommendationRepository {
+	return &postgresRepository{db: db}
+}
+
+// Ping checks that the database is reachable
+func (r *postgresRepository) Ping(ctx context.Context) error {
+	return shareddb.Check(ctx, r.db)
+}
+
+// RecordInteractions stores interactions in one transaction
+func (r *postgresRepository) RecordInteractions(ctx context.Context, interactions []domain.Interaction) error {
+	return shareddb.WithTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		for _, interaction := range interactions {
+			occurredAt := interaction.OccurredAt.UTC()
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO interactions (visitor_id, product_id, kind, weight, first_seen_at, last_seen_at)
+				VALUES ($1, $2, $3, $4, $5, $5)
+				ON CONFLICT (visitor_id, product_id, kind) DO UPDATE SET
+					occurrences = interactions.occurrences + 1,
+					first_seen_at = LEAST(interactions.first_seen_at, EXCLUDED.first_seen_at),
+					last_seen_at = GREATEST(interactions.last_seen_at, EXCLUDED.last_seen_at)
+			`, interaction.VisitorID, interaction.ProductID, interaction.Kind, interaction.Kind.Weight(), occurredAt)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RecordOrder stores an order's products; redelivered orders change
+// nothing
+func (r *postgresRepository) RecordOrder(ctx context.Context, orderID string, productIDs []string, orderedAt time.Time) error {
+	return shareddb.WithTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		for _, productID := range productIDs {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO order_items (order_id, product_id, ordered_at)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (order_id, product_id) DO NOTHING
+			`, orderID, productID, orderedAt.UTC())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RemoveOrder deletes an order's products
+func (r *postgresRepository) RemoveOrder(ctx context.Context, orderID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM order_items WHERE order_id = $1`, orderID)
+	return err
+}
+
+// Train rebuilds the models. Readers keep seeing the previous models until
+// the transaction commits.
+func (r *postgresRepository) Train(ctx context.Context, params TrainingParams) (*domain.TrainingRun, error) {
+	run := &domain.TrainingRun{
+		StartedAt:   time.Now().UTC(),
+		WindowStart: params.Since.UTC(),
+	}
+
+	err := shareddb.WithTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		var locked bool
+		if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, trainingLockID).Scan(&locked); err != nil {
+			return err
+		}
+		if !locked {
+			return domain.ErrTrainingInProgress
+		}
+
+		pruned, err := exec(ctx, tx, `DELETE FROM interactions WHERE last_seen_at < $1`, run.WindowStart)
+		if err != nil {
+			return err
+		}
+		run.InteractionsPruned = pruned
+		if _, err := exec(ctx, tx, `DELETE FROM order_items WHERE ordered_at < $1`, run.WindowStart); err != nil {
+			return err
+		}
+
+		if _, err := exec(ctx, tx, `DELETE FROM product_similarities`); err != nil {
+			return err
+		}
+
+		// Also bought: cosine similarity of the products' weighted interest
+		// across visitors, counting each shared visitor's weaker interest
+		alsoBought, err := exec(ctx, tx, `
+			INSERT INTO product_similarities (model, product_id, related_product_id, score, support)
+			WITH interests AS (
+				SELECT visitor_id, product_id, MAX(weight) AS weight,
+					ROW_NUMBER() OVER (PARTITION BY visitor_id ORDER BY MAX(last_seen_at) DESC, product_id) AS recency
+				FROM interactions
+				GROUP BY visitor_id, product_id
+			),
+			affinity AS (
+				SELECT visitor_id, product_id, weight FROM interests WHERE recency <= $4
+			),
+			totals AS (
+				SELECT product_id, SUM(weight)::float8 AS total FROM affinity GROUP BY product_id
+			),
+			pairs AS (
+				SELECT a.product_id, b.product_id AS related_product_id,
+					SUM(LEAST(a.weight, b.weight))::float8 AS strength, COUNT(*) AS support
+				FROM affinity a
+				JOIN affinity b ON b.visitor_id = a.visitor_id AND b.product_id <> a.product_id
+				GROUP BY a.product_id, b.product_id
+				HAVING COUNT(*) >= $2
+			),
+			ranked AS (
+				SELECT p.product_id, p.related_product_id, p.support,
+					p.strength / SQRT(ta.total * tb.total) AS score,
+					ROW_NUMBER() OVER (
+						PARTITION BY p.product_id
+						ORDER BY p.strength / SQRT(ta.total * tb.total) DESC, p.related_product_id
+					) AS rank
+				FROM pairs p
+				JOIN totals ta ON ta.product_id = p.product_id
+				JOIN totals tb ON tb.product_id = p.related_product_id
+			)
+			SELECT $1::text, product_id, related_product_id, score, support FROM ranked WHERE rank <= $3
+		`, string(domain.ModelAlsoBought), params.MinSupport, params.MaxRelated, visitorInterests)
+		if err != nil {
+			return err
+		}
+		run.AlsoBoughtPairs = int(alsoBought)
+
+		// Bought together: the share of a product's orders that also
+		// contained the related product
+		boughtTogether, err := exec(ctx, tx, `
+			INSERT INTO product_similarities (model, product_id, related_product_id, score, support)
+			WITH totals AS (
+				SELECT product_id, COUNT(*)::float8 AS orders FROM order_items GROUP BY product_id
+			),
+			pairs AS (
+				SELECT a.product_id, b.product_id AS related_product_id, COUNT(*) AS orders
+				FROM order_items a
+				JOIN order_items b ON b.order_id = a.order_id AND b.product_id <> a.product_id
+				GROUP BY a.product_id, b.product_id
+				HAVING COUNT(*) >= $2
+			),
+			ranked AS (
+				SELECT p.product_id, p.related_product_id, p.orders,
+					p.orders / t.orders AS score,
+					ROW_NUMBER() OVER (PARTITION BY p.product_id ORDER BY p.orders DESC, p.related_product_id) AS rank
+				FROM pairs p
+				JOIN totals t ON t.product_id = p.product_id
+			)
+			SELECT $1::text, product_id, related_product_id, score, orders FROM ranked WHERE rank <= $3
+		`, string(domain.ModelBoughtTogether), params.MinSupport, params.MaxRelated)
+		if err != nil {
+			return err
+		}
+		run.BoughtTogetherPairs = int(boughtTogether)
+
+		if _, err := exec(ctx, tx, `DELETE FROM popular_products`); err != nil {
+			return err
+		}
+		if _, err := exec(ctx, tx, `
+			INSERT INTO popular_products (product_id, score)
+			SELECT product_id, SUM(weight)::float8 AS score
+			FROM interactions
+			GROUP BY product_id
+			ORDER BY score DESC, product_id
+			LIMIT $1
+		`, params.MaxPopular); err != nil {
+			return err
+		}
+
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(DISTINCT product_id) FROM interactions`).Scan(&run.Products); err != nil {
+			return err
+		}
+
+		run.FinishedAt = time.Now().UTC()
+		return tx.QueryRowContext(ctx, `
+			INSERT INTO training_runs (started_at, finished_at, window_start, interactions_pruned, products, also_bought_pairs, bought_together_pairs)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id
+		`, run.StartedAt, run.FinishedAt, run.WindowStart, run.InteractionsPruned,
+			run.Products, run.AlsoBoughtPairs, run.BoughtTogetherPairs,
+		).Scan(&run.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// LatestRun returns the last training run
+func (r *postgresRepository) LatestRun(ctx context.Context) (*domain.TrainingRun, error) {
+	var run domain.TrainingRun
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, started_at, finished_at, window_start, interactions_pruned, products, also_bought_pairs, bought_together_pairs
+		FROM training_runs
+		ORDER BY id DESC
+		LIMIT 1
+	`).Scan(&run.ID, &run.StartedAt, &run.FinishedAt, &run.WindowStart, &run.InteractionsPruned,
+		&run.Products, &run.AlsoBoughtPairs, &run.BoughtTogetherPairs)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotTrained
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// Related returns a product's related products by model
+func (r *postgresRepository) Related(ctx context.Context, model domain.Model, productID string, limit int) ([]domain.Recommendation, error) {
+	reason := domain.ReasonAlsoBought
+	if model == domain.ModelBoughtTogether {
+		reason = domain.ReasonBoughtTogether
+	}
+
+	return r.recommendations(ctx, reason, `
+		SELECT related_product_id, score
+		FROM product_similarities
+		WHERE model = $1 AND product_id = $2
+		ORDER BY score DESC, related_product_id
+		LIMIT $3
+	`, string(model), productID, limit)
+}
+
+// ForVisitor sums, over the visitor's recent interests, the also-bought
+// scores of related products, weighted by how strong each interest is
+func (r *postgresRepository) ForVisitor(ctx context.Context, visitorID string, limit int) ([]domain.Recommendation, error) {
+	return r.recommendations(ctx, domain.ReasonPersonalized, `
+		WITH recent AS (
+			SELECT product_id, MAX(weight) AS weight
+			FROM interactions
+			WHERE visitor_id = $1
+			GROUP BY product_id
+			ORDER BY MAX(last_seen_at) DESC, product_id
+			LIMIT $3
+		)
+		SELECT s.related_product_id, SUM(s.score * r.weight) AS score
+		FROM recent r
+		JOIN product_similarities s ON s.model = $2 AND s.product_id = r.product_id
+		WHERE NOT EXISTS (
+			SELECT 1 FROM interactions i
+			WHERE i.visitor_id = $1 AND i.product_id = s.related_product_id AND i.kind = $4
+		)
+		GROUP BY s.related_product_id
+		ORDER BY score DESC, s.related_product_id
+		LIMIT $5
+	`, visitorID, string(domain.ModelAlsoBought), visitorInterests, string(domain.SignalPurchase), limit)
+}
+
+// Popular returns the most popular products
+func (r *postgresRepository) Popular(ctx context.Context, purchasedBy string, limit int) ([]domain.Recommendation, error) {
+	return r.recommendations(ctx, domain.ReasonPopular, `
+		SELECT p.product_id, p.score
+		FROM popular_products p
+		WHERE $1::text = '' OR NOT EXISTS (
+			SELECT 1 FROM interactions i
+			WHERE i.visitor_id = $1 AND i.product_id = p.product_id AND i.kind = $2
+		)
+		ORDER BY p.score DESC, p.product_id
+		LIMIT $3
+	`, purchasedBy, string(domain.SignalPurchase), limit)
+}
+
+// recommendations runs a query of product IDs and scores
+func (r *postgresRepository) recommendations(ctx context.Context, reason, query string, args ...interface{}) ([]domain.Recommendation, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recommendations := []domain.Recommendation{}
+	for rows.Next() {
+		recommendation := domain.Recommendation{Reason: reason}
+		if err := rows.Scan(&recommendation.ProductID, &recommendation.Score); err != nil {
+			return nil, err
+		}
+		recommendations = append(recommendations, recommendation)
+	}
+	return recommendations, rows.Err()
+}
+
+// exec runs a statement, returning the rows it affected
+func exec(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (int64, error) {
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type redisRepository struct {
+	client *redis.Client
+}
+
+// NewRedisRepository creates a new Redis cache repository
+func NewRedisRepository(client *redis.Client) CacheRepository {
+	return &redisRepository{client: client}
+}
+
+func (r *redisRepository) cacheKey(key string) string {
+	return fmt.Sprintf("recommendations:%s", key)
+}
+
+// Get retrieves cached recommendations
+func (r *redisRepository) Get(ctx context.Context, key string, dest interface{}) (bool, error) {
+	data, err := r.client.Get(ctx, r.cacheKey(key)).Bytes()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := json.Unmarshal(data, dest); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Set caches recommendations
+func (r *redisRepository) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return r.client.Set(ctx, r.cacheKey(key), data, ttl).Err()
+}
+
+// FlushAll clears all cached recommendations
+func (r *redisRepository) FlushAll(ctx context.Context) error {
+	pattern := r.cacheKey("*")
+	iter := r.client.Scan(ctx, 0, pattern, 0).Iterator()
+
+	for iter.Next(ctx) {
+		if err := r.client.Del(ctx, iter.Val()).Err(); err != nil {
+			return err
+		}
+	}
+
+	return iter.Err()
+}
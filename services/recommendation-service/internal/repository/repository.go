@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ecommerce/recommendation-service/internal/domain"
+)
+
+// RecommendationRepository defines signal and model data operations
+type RecommendationRepository interface {
+	// Signals
+	// RecordInteractions stores shoppers' interest in products. Repeated
+	// interactions of a kind count as one, seen again.
+	RecordInteractions(ctx context.Context, interactions []domain.Interaction) error
+	// RecordOrder stores the products ordered together in an order
+	RecordOrder(ctx context.Context, orderID string, productIDs []string, orderedAt time.Time) error
+	// RemoveOrder forgets a cancelled order's products bought together
+	RemoveOrder(ctx context.Context, orderID string) error
+
+	// Training
+	// Train prunes signals older than the window and rebuilds the models
+	// from the rest, in one transaction. It returns
+	// domain.ErrTrainingInProgress while another replica trains.
+	Train(ctx context.Context, params TrainingParams) (*domain.TrainingRun, error)
+	// LatestRun returns the last training run, or domain.ErrNotTrained
+	LatestRun(ctx context.Context) (*domain.TrainingRun, error)
+
+	// Recommendations
+	// Related returns up to limit products most related to productID by
+	// model, best first
+	Related(ctx context.Context, model domain.Model, productID string, limit int) ([]domain.Recommendation, error)
+	// ForVisitor returns up to limit products related to the visitor's
+	// recent interests, leaving out what they bought, best first
+	ForVisitor(ctx context.Context, visitorID string, limit int) ([]domain.Recommendation, error)
+	// Popular returns up to limit of the most popular products, leaving out
+	// those bought by purchasedBy, if set
+	Popular(ctx context.Context, purchasedBy string, limit int) ([]domain.Recommendation, error)
+
+	// Ping checks that the database is reachable
+	Ping(ctx context.Context) error
+}
+
+// CacheRepository caches recommendations
+type CacheRepository interface {
+	// Get decodes the value at key into dest, reporting whether it was
+	// cached
+	Get(ctx context.Context, key string, dest interface{}) (bool, error)
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	// FlushAll drops every cached recommendation, e.g. once models are
+	// retrained
+	FlushAll(ctx context.Context) error
+}
+
+// TrainingParams tune a training run
+type TrainingParams struct {
+	// Since is the start of the training window; older signals are
+	// pruned
+	Since time.Time
+	// MaxRelated bounds the related products kept per product and model
+	MaxRelated int
+	// MinSupport is the number of visitors, or orders, two products must
+	// share to be related
+	MinSupport int
+	// MaxPopular bounds the popular products kept
+	MaxPopular int
+}
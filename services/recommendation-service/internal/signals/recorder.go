@@ -0,0 +1,157 @@
+// Package signals records shoppers' interest in products from order, cart
+// and view events, the input of model training
+package signals
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	sharedevents "github.com/ecommerce-platform/shared/go/events"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/recommendation-service/internal/domain"
+	"github.com/ecommerce/recommendation-service/internal/metrics"
+	"github.com/ecommerce/recommendation-service/internal/repository"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// Recorder applies events to the recorded signals
+type Recorder struct {
+	repo   repository.RecommendationRepository
+	logger *zap.Logger
+}
+
+// NewRecorder creates a recorder writing to repo
+func NewRecorder(repo repository.RecommendationRepository, logger *zap.Logger) *Recorder {
+	return &Recorder{repo: repo, logger: logger}
+}
+
+// cartItemAdded is the data of cart-service's cart.item_added events
+type cartItemAdded struct {
+	ProductID string `json:"product_id"`
+}
+
+// Handle records an event. It is a sharedkafka.Handler; failures are
+// retried and then dead-lettered. Other events are ignored.
+func (r *Recorder) Handle(ctx context.Context, msg kafkago.Message) error {
+	env, err := sharedevents.Decode(msg.Value)
+	if err != nil {
+		metrics.SignalEventsTotal.WithLabelValues("unknown", "failed").Inc()
+		return err
+	}
+
+	recorded, err := r.apply(ctx, env)
+	if err != nil {
+		metrics.SignalEventsTotal.WithLabelValues(env.EventType, "failed").Inc()
+		logging.WithContext(ctx, r.logger).Warn("Failed to record event",
+			zap.String("event_type", env.EventType),
+			zap.String("topic", msg.Topic),
+			zap.Error(err),
+		)
+		return err
+	}
+	if !recorded {
+		metrics.SignalEventsTotal.WithLabelValues(env.EventType, "skipped").Inc()
+		return nil
+	}
+
+	metrics.SignalEventsTotal.WithLabelValues(env.EventType, "recorded").Inc()
+	return nil
+}
+
+// apply records an event and reports whether it carried a signal
+func (r *Recorder) apply(ctx context.Context, env *sharedevents.Envelope) (bool, error) {
+	occurredAt := env.Timestamp
+	if occurredAt.IsZero() {
+		occurredAt = time.Now()
+	}
+
+	switch env.EventType {
+	case "order.created":
+		var created sharedevents.OrderCreated
+		if err := env.DecodeData(&created); err != nil {
+			return false, err
+		}
+		userID := created.UserID
+		if userID == "" {
+			userID = env.UserID
+		}
+
+		var productIDs []string
+		var interactions []domain.Interaction
+		for _, item := range created.Items {
+			if item.ProductID == "" {
+				continue
+			}
+			productIDs = append(productIDs, item.ProductID)
+			if userID != "" {
+				interactions = append(interactions, domain.Interaction{
+					VisitorID:  userID,
+					ProductID:  item.ProductID,
+					Kind:       domain.SignalPurchase,
+					OccurredAt: occurredAt,
+				})
+			}
+		}
+		if len(productIDs) == 0 {
+			return false, nil
+		}
+
+		if env.OrderID != "" {
+			if err := r.repo.RecordOrder(ctx, env.OrderID, productIDs, occurredAt); err != nil {
+				return false, err
+			}
+		}
+		return true, r.repo.RecordInteractions(ctx, interactions)
+
+	case "order.cancelled":
+		// The customer was still interested; only the basket is forgotten
+		if env.OrderID == "" {
+			return false, nil
+		}
+		return true, r.repo.RemoveOrder(ctx, env.OrderID)
+
+	case "cart.item_added":
+		var added cartItemAdded
+		if err := json.Unmarshal(env.Data, &added); err != nil {
+			return false, fmt.Errorf("failed to decode %s data: %w", env.EventType, err)
+		}
+		if env.UserID == "" || added.ProductID == "" {
+			return false, nil
+		}
+		return true, r.repo.RecordInteractions(ctx, []domain.Interaction{{
+			VisitorID:  env.UserID,
+			ProductID:  added.ProductID,
+			Kind:       domain.SignalCart,
+			OccurredAt: occurredAt,
+		}})
+
+	case "product.viewed":
+		var viewed sharedevents.ProductViewed
+		if err := env.DecodeData(&viewed); err != nil {
+			return false, err
+		}
+		productID := viewed.ProductID
+		if productID == "" {
+			productID = env.ProductID
+		}
+		visitorID := viewed.UserID
+		if visitorID == "" && viewed.SessionID != "" {
+			visitorID = domain.SessionVisitor(viewed.SessionID)
+		}
+		if visitorID == "" || productID == "" {
+			return false, nil
+		}
+		return true, r.repo.RecordInteractions(ctx, []domain.Interaction{{
+			VisitorID:  visitorID,
+			ProductID:  productID,
+			Kind:       domain.SignalView,
+			OccurredAt: occurredAt,
+		}})
+
+	default:
+		return false, nil
+	}
+}
@@ -0,0 +1,109 @@
+// Package training periodically rebuilds the recommendation models from
+// the recorded signals
+package training
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ecommerce/recommendation-service/internal/domain"
+	"github.com/ecommerce/recommendation-service/internal/metrics"
+	"github.com/ecommerce/recommendation-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Config tunes training
+type Config struct {
+	// Interval is how often models are retrained
+	Interval time.Duration
+	// Window is how far back signals are used; older ones are pruned
+	Window     time.Duration
+	MaxRelated int
+	MinSupport int
+	MaxPopular int
+}
+
+// Trainer retrains the models on a schedule. Replicas share the schedule;
+// whichever gets the training lock trains.
+type Trainer struct {
+	repo   repository.RecommendationRepository
+	cache  repository.CacheRepository
+	cfg    Config
+	logger *zap.Logger
+}
+
+// New creates a trainer
+func New(repo repository.RecommendationRepository, cache repository.CacheRepository, cfg Config, logger *zap.Logger) *Trainer {
+	return &Trainer{
+		repo:   repo,
+		cache:  cache,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// Start trains at once, unless the models are fresher than the interval,
+// then on every tick until the context is cancelled
+func (t *Trainer) Start(ctx context.Context) {
+	t.logger.Info("Starting model trainer",
+		zap.Duration("interval", t.cfg.Interval),
+		zap.Duration("window", t.cfg.Window),
+	)
+
+	latest, err := t.repo.LatestRun(ctx)
+	if err != nil || time.Since(latest.FinishedAt) >= t.cfg.Interval {
+		t.Train(ctx)
+	}
+
+	ticker := time.NewTicker(t.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.logger.Info("Stopping model trainer")
+			return
+		case <-ticker.C:
+			t.Train(ctx)
+		}
+	}
+}
+
+// Train runs one training and drops the cached recommendations of the
+// previous models
+func (t *Trainer) Train(ctx context.Context) {
+	start := time.Now()
+	run, err := t.repo.Train(ctx, repository.TrainingParams{
+		Since:      start.Add(-t.cfg.Window),
+		MaxRelated: t.cfg.MaxRelated,
+		MinSupport: t.cfg.MinSupport,
+		MaxPopular: t.cfg.MaxPopular,
+	})
+	if errors.Is(err, domain.ErrTrainingInProgress) {
+		metrics.TrainingRunsTotal.WithLabelValues("skipped").Inc()
+		t.logger.Info("Another replica is training, skipping")
+		return
+	}
+	if err != nil {
+		metrics.TrainingRunsTotal.WithLabelValues("failed").Inc()
+		t.logger.Error("Model training failed", zap.Error(err))
+		return
+	}
+
+	metrics.TrainingRunsTotal.WithLabelValues("trained").Inc()
+	metrics.TrainingDuration.Observe(time.Since(start).Seconds())
+	t.logger.Info("Models trained",
+		zap.Int64("run_id", run.ID),
+		zap.Int("products", run.Products),
+		zap.Int("also_bought_pairs", run.AlsoBoughtPairs),
+		zap.Int("bought_together_pairs", run.BoughtTogetherPairs),
+		zap.Int64("interactions_pruned", run.InteractionsPruned),
+		zap.Duration("duration", time.Since(start)),
+	)
+
+	// Cached lists expire on their own otherwise
+	if err := t.cache.FlushAll(ctx); err != nil {
+		t.logger.Warn("Failed to flush cached recommendations", zap.Error(err))
+	}
+}
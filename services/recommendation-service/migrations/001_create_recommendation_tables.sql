@@ -0,0 +1,60 @@
+-- Shoppers' interest in products, from views, carts and purchases: one row
+-- per visitor, product and kind. Visitors are user IDs, or
+-- "session:<id>" for anonymous views.
+CREATE TABLE IF NOT EXISTS interactions (
+    visitor_id VARCHAR(255) NOT NULL,
+    product_id VARCHAR(255) NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    weight SMALLINT NOT NULL,
+    occurrences INTEGER NOT NULL DEFAULT 1,
+    first_seen_at TIMESTAMP NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (visitor_id, product_id, kind)
+);
+
+CREATE INDEX IF NOT EXISTS idx_interactions_last_seen_at ON interactions(last_seen_at);
+CREATE INDEX IF NOT EXISTS idx_interactions_product_id ON interactions(product_id);
+
+-- Order lines, for products bought in the same order
+CREATE TABLE IF NOT EXISTS order_items (
+    order_id VARCHAR(255) NOT NULL,
+    product_id VARCHAR(255) NOT NULL,
+    ordered_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (order_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_items_ordered_at ON order_items(ordered_at);
+
+-- Trained models: each product's most related products per model,
+-- replaced by every training run
+CREATE TABLE IF NOT EXISTS product_similarities (
+    model VARCHAR(30) NOT NULL,
+    product_id VARCHAR(255) NOT NULL,
+    related_product_id VARCHAR(255) NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    support INTEGER NOT NULL,
+    PRIMARY KEY (model, product_id, related_product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_similarities_ranking ON product_similarities(model, product_id, score DESC);
+
+-- The most popular products, recommended when too few related ones are
+-- known
+CREATE TABLE IF NOT EXISTS popular_products (
+    product_id VARCHAR(255) PRIMARY KEY,
+    score DOUBLE PRECISION NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_popular_products_score ON popular_products(score DESC);
+
+-- Training runs, latest last
+CREATE TABLE IF NOT EXISTS training_runs (
+    id BIGSERIAL PRIMARY KEY,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP NOT NULL,
+    window_start TIMESTAMP NOT NULL,
+    interactions_pruned BIGINT NOT NULL DEFAULT 0,
+    products INTEGER NOT NULL DEFAULT 0,
+    also_bought_pairs INTEGER NOT NULL DEFAULT 0,
+    bought_together_pairs INTEGER NOT NULL DEFAULT 0
+);
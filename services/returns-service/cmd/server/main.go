@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/audit"
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	sharedconfig "github.com/ecommerce-platform/shared/go/config"
+	shareddb "github.com/ecommerce-platform/shared/go/db"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/httpmetrics"
+	"github.com/ecommerce-platform/shared/go/interceptors"
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce-platform/shared/go/mtls"
+	"github.com/ecommerce/returns-service/internal/api"
+	"github.com/ecommerce/returns-service/internal/config"
+	"github.com/ecommerce/returns-service/internal/events"
+	"github.com/ecommerce/returns-service/internal/inventory"
+	"github.com/ecommerce/returns-service/internal/labels"
+	"github.com/ecommerce/returns-service/internal/middleware"
+	"github.com/ecommerce/returns-service/internal/orders"
+	"github.com/ecommerce/returns-service/internal/payments"
+	"github.com/ecommerce/returns-service/internal/repository"
+	"github.com/ecommerce/returns-service/migrations"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Initialize logger
+	log, err := logging.New(logging.Config{
+		ServiceName: "returns-service",
+		Environment: cfg.Environment,
+		Level:       os.Getenv("LOG_LEVEL"),
+	})
+	if err != nil {
+		fmt.Printf("Failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer log.Sync()
+
+	log.Info("Starting Returns Service",
+		zap.String("environment", cfg.Environment),
+		zap.Int("port", cfg.Port),
+	)
+	log.Info("Configuration loaded", zap.Any("config", sharedconfig.Redacted(cfg)))
+
+	// Initialize mTLS
+	serviceTLS, err := mtls.New(cfg.TLS, log)
+	if err != nil {
+		log.Fatal("Failed to initialize mTLS", zap.Error(err))
+	}
+	defer serviceTLS.Close()
+
+	// Initialize OpenTelemetry
+	cleanup, err := initTelemetry(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize telemetry", zap.Error(err))
+	}
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Initialize PostgreSQL
+	db, err := shareddb.Open(ctx, shareddb.Config{
+		DSN:          cfg.DatabaseURL,
+		Name:         "returns",
+		MaxOpenConns: cfg.DBMaxOpenConns,
+		MaxIdleConns: cfg.DBMaxIdleConns,
+	}, log)
+	if err != nil {
+		log.Fatal("Failed to connect to database", zap.Error(err))
+	}
+	defer db.Close()
+
+	if err := shareddb.Migrate(ctx, db, migrations.FS, log); err != nil {
+		log.Fatal("Failed to migrate database", zap.Error(err))
+	}
+
+	// Initialize repository
+	returnRepo := repository.NewPostgresRepository(db)
+
+	// Initialize Kafka publisher
+	brokers := strings.Split(cfg.KafkaBrokers, ",")
+	publisher := events.NewKafkaPublisher(brokers, cfg.ReturnEventsTopic, log)
+	defer publisher.Close()
+
+	// Audit log of staff decisions on returns, in the database and on the
+	// audit topic
+	auditSinks := []audit.Sink{audit.NewPostgresSink(db)}
+	if cfg.AuditTopic != "" {
+		auditProducer := sharedkafka.NewProducer(sharedkafka.ProducerConfig{Brokers: brokers, Topic: cfg.AuditTopic}, log)
+		defer auditProducer.Close()
+		auditSinks = append(auditSinks, audit.NewKafkaSink(auditProducer))
+	}
+	auditor := audit.New(audit.Config{Service: "returns-service", Sinks: auditSinks}, log)
+
+	// Record delivered orders from order events; the topic is keyed by
+	// order, so an order's events are applied in order
+	dlqProducer := sharedkafka.NewProducer(sharedkafka.ProducerConfig{Brokers: brokers}, log)
+	defer dlqProducer.Close()
+
+	orderConsumer := sharedkafka.NewConsumer(sharedkafka.ConsumerConfig{
+		Brokers:     brokers,
+		GroupID:     cfg.KafkaGroupID,
+		Topics:      []string{cfg.OrderEventsTopic},
+		MaxAttempts: 3,
+		DeadLetters: sharedkafka.NewTopicDeadLetters(dlqProducer),
+		Tracer:      otel.Tracer("returns-service"),
+	}, orders.NewRecorder(returnRepo, log).Handle, log)
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	consumerDone := make(chan struct{})
+	go func() {
+		defer close(consumerDone)
+		if err := orderConsumer.Run(backgroundCtx); err != nil {
+			log.Error("Order event consumer stopped", zap.Error(err))
+		}
+	}()
+
+	// Return labels come from shipping-service, when there is one
+	var labelProvider labels.Provider
+	if cfg.ShippingServiceURL != "" {
+		labelProvider = labels.NewShippingProvider(cfg.ShippingServiceURL, serviceTLS.Transport())
+	} else {
+		log.Warn("SHIPPING_SERVICE_URL not set, returns are approved without labels")
+	}
+
+	// Stock records are looked up over inventory-service's gRPC API
+	inventoryConn, err := grpc.Dial(cfg.InventoryGRPCAddr,
+		interceptors.DialOptions(interceptors.ClientConfig{TLS: serviceTLS.ClientConfig()})...,
+	)
+	if err != nil {
+		log.Fatal("Failed to create inventory-service gRPC client", zap.Error(err))
+	}
+	defer inventoryConn.Close()
+
+	// Initialize handler
+	handler := api.NewHandler(
+		returnRepo,
+		publisher,
+		auditor,
+		labelProvider,
+		inventory.NewClient(cfg.InventoryServiceURL, serviceTLS.Transport(), inventoryConn),
+		payments.NewClient(cfg.PaymentServiceURL, serviceTLS.Transport()),
+		time.Duration(cfg.ReturnWindowDays)*24*time.Hour,
+		log,
+	)
+
+	// Initialize auth
+	verifier, err := sharedauth.NewVerifier(sharedauth.Config{
+		Secret:  cfg.JWTSecret,
+		JWKSURL: cfg.JWKSURL,
+		Issuer:  sharedauth.Issuer,
+	})
+	if err != nil {
+		log.Fatal("Failed to create token verifier", zap.Error(err))
+	}
+	authMiddleware := sharedauth.NewMiddleware(verifier, log)
+
+	// Setup Gin
+	if cfg.Environment == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(middleware.CorrelationID())
+	router.Use(otelgin.Middleware("returns-service"))
+	router.Use(httpmetrics.Middleware("returns-service"))
+	router.Use(apperrors.Middleware(log))
+
+	// Health check
+	router.GET("/health", handler.HealthCheck)
+
+	// Metrics for Prometheus
+	router.GET("/metrics", gin.WrapH(httpmetrics.Handler()))
+
+	// API routes, for the default tenant only
+	v1 := router.Group("/api/v1", sharedauth.RequireDefaultTenant())
+	{
+		returns := v1.Group("/returns", authMiddleware.Authenticate())
+		{
+			// Customers return what they received
+			returns.GET("", handler.ListMyReturns)
+			returns.POST("", handler.CreateReturn)
+			returns.GET("/orders/:orderId", handler.GetReturnableOrder)
+			returns.GET("/:id", handler.GetReturn)
+			returns.POST("/:id/cancel", handler.CancelReturn)
+		}
+
+		staff := returns.Group("", authMiddleware.RequirePermission(api.PermissionManage))
+		{
+			staff.GET("/manage", handler.ListReturns)
+			staff.POST("/:id/approve", handler.ApproveReturn)
+			staff.POST("/:id/reject", handler.RejectReturn)
+			staff.POST("/:id/receive", handler.ReceiveReturn)
+			staff.POST("/:id/refund", handler.RefundReturn)
+		}
+	}
+
+	// Create HTTP server
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Port),
+		Handler:      router,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// Start server in goroutine
+	go func() {
+		log.Info("Server starting", zap.Int("port", cfg.Port))
+		if err := serviceTLS.ListenAndServe(srv); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Server failed to start", zap.Error(err))
+		}
+	}()
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Info("Shutting down server...")
+
+	// Graceful shutdown
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Fatal("Server forced to shutdown", zap.Error(err))
+	}
+
+	// Events being recorded are left uncommitted and redelivered on restart
+	stopBackground()
+	select {
+	case <-consumerDone:
+	case <-shutdownCtx.Done():
+		log.Warn("Order event consumer didn't stop in time")
+	}
+
+	if err := auditor.Close(shutdownCtx); err != nil {
+		log.Error("Failed to write pending audit records", zap.Error(err))
+	}
+
+	log.Info("Server shutdown complete")
+}
+
+func initTelemetry(cfg *config.Config) (func(), error) {
+	ctx := context.Background()
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String("returns-service"),
+			semconv.ServiceVersionKey.String("1.0.0"),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	traceExporter, err := otlptracegrpc.New(ctx,
+		otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint),
+		otlptracegrpc.WithInsecure(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	bsp := sdktrace.NewBatchSpanProcessor(traceExporter)
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithResource(res),
+		sdktrace.WithSpanProcessor(bsp),
+	)
+
+	otel.SetTracerProvider(tracerProvider)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			fmt.Printf("Failed to shutdown tracer provider: %v\n", err)
+		}
+	}, nil
+}
@@ -0,0 +1,663 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/audit"
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce-platform/shared/go/pagination"
+	"github.com/ecommerce/returns-service/internal/domain"
+	"github.com/ecommerce/returns-service/internal/events"
+	"github.com/ecommerce/returns-service/internal/inventory"
+	"github.com/ecommerce/returns-service/internal/labels"
+	"github.com/ecommerce/returns-service/internal/metrics"
+	"github.com/ecommerce/returns-service/internal/payments"
+	"github.com/ecommerce/returns-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// PermissionManage lets staff approve, reject, receive and refund returns
+const PermissionManage = "returns:manage"
+
+// Handler serves the returns API
+type Handler struct {
+	repo      repository.ReturnRepository
+	publisher events.Publisher
+	auditor   *audit.Auditor
+	// labels generates return labels; nil approves returns without one
+	labels    labels.Provider
+	inventory *inventory.Client
+	payments  *payments.Client
+	// window is how long after delivery items may be returned
+	window time.Duration
+	logger *zap.Logger
+}
+
+// NewHandler creates a new handler
+func NewHandler(
+	repo repository.ReturnRepository,
+	publisher events.Publisher,
+	auditor *audit.Auditor,
+	labelProvider labels.Provider,
+	inventoryClient *inventory.Client,
+	paymentsClient *payments.Client,
+	window time.Duration,
+	logger *zap.Logger,
+) *Handler {
+	return &Handler{
+		repo:      repo,
+		publisher: publisher,
+		auditor:   auditor,
+		labels:    labelProvider,
+		inventory: inventoryClient,
+		payments:  paymentsClient,
+		window:    window,
+		logger:    logger,
+	}
+}
+
+// CreateReturnRequest asks to return items of a delivered order
+type CreateReturnRequest struct {
+	OrderID  string              `json:"order_id" binding:"required,max=255"`
+	Reason   string              `json:"reason" binding:"required"`
+	Comments string              `json:"comments" binding:"max=1000"`
+	Items    []ReturnItemRequest `json:"items" binding:"required,min=1,max=100,dive"`
+}
+
+// ReturnItemRequest is a product to return and how many of it
+type ReturnItemRequest struct {
+	ProductID string `json:"product_id" binding:"required,max=255"`
+	Quantity  int    `json:"quantity" binding:"required,min=1"`
+}
+
+// RejectRequest explains why a return is refused
+type RejectRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// ReceiveRequest records a return's arrival; Restock defaults to true,
+// false keeps damaged items out of stock
+type ReceiveRequest struct {
+	Restock *bool `json:"restock"`
+}
+
+// ReturnableOrder is what of a customer's order may still be returned
+type ReturnableOrder struct {
+	OrderID     string           `json:"order_id"`
+	OrderNumber string           `json:"order_number,omitempty"`
+	Status      string           `json:"status"`
+	DeliveredAt *time.Time       `json:"delivered_at,omitempty"`
+	ReturnBy    *time.Time       `json:"return_by,omitempty"`
+	Returnable  bool             `json:"returnable"`
+	Lines       []ReturnableLine `json:"lines"`
+}
+
+// ReturnableLine is an order line with how many of it may be returned
+type ReturnableLine struct {
+	domain.OrderLine
+	Returnable int `json:"returnable"`
+}
+
+// CreateReturn asks to return items of one of the caller's delivered
+// orders, within the return window
+// POST /api/v1/returns
+func (h *Handler) CreateReturn(c *gin.Context) {
+	var req CreateReturnRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	var invalid apperrors.ValidationErrors
+	if !domain.ValidReason(req.Reason) {
+		invalid.Add("reason", "oneof", "reason must be one of "+strings.Join(domain.Reasons, ", "))
+	}
+	seen := make(map[string]bool, len(req.Items))
+	for i, item := range req.Items {
+		if seen[item.ProductID] {
+			invalid.Add(fmt.Sprintf("items[%d].product_id", i), "unique", "each product may be listed once")
+		}
+		seen[item.ProductID] = true
+	}
+	if err := invalid.Err(); err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString(sharedauth.ContextUserID)
+
+	var ret *domain.Return
+	err := h.repo.InTx(ctx, func(repo repository.ReturnRepository) error {
+		order, err := repo.LockOrder(ctx, req.OrderID)
+		if err == nil && order.UserID != userID {
+			err = domain.ErrOrderNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		deadline, delivered := order.ReturnDeadline(h.window)
+		if !delivered {
+			return apperrors.NewConflict("Only delivered orders can be returned")
+		}
+		if time.Now().After(deadline) {
+			return apperrors.NewConflict("The return window for this order closed on " + deadline.Format("2006-01-02"))
+		}
+
+		ret = &domain.Return{
+			OrderID:       order.OrderID,
+			OrderNumber:   order.OrderNumber,
+			UserID:        userID,
+			CustomerEmail: callerEmail(c),
+			CustomerName:  order.CustomerName,
+			Status:        domain.StatusRequested,
+			Reason:        req.Reason,
+			Comments:      req.Comments,
+		}
+		if ret.CustomerEmail == "" {
+			ret.CustomerEmail = order.CustomerEmail
+		}
+
+		var invalid apperrors.ValidationErrors
+		var amounts []money.Money
+		for i, item := range req.Items {
+			line, ok := order.Line(item.ProductID)
+			if !ok {
+				invalid.Add(fmt.Sprintf("items[%d].product_id", i), "exists", "product isn't in the order")
+				continue
+			}
+			if item.Quantity > line.Returnable() {
+				invalid.Add(fmt.Sprintf("items[%d].quantity", i), "max", fmt.Sprintf("at most %d of this product can be returned", line.Returnable()))
+				continue
+			}
+			ret.Items = append(ret.Items, domain.Item{
+				ProductID: line.ProductID,
+				SKU:       line.SKU,
+				Name:      line.Name,
+				Quantity:  item.Quantity,
+				UnitPrice: line.UnitPrice,
+			})
+			amounts = append(amounts, line.UnitPrice.Mul(int64(item.Quantity)))
+		}
+		if err := invalid.Err(); err != nil {
+			return err
+		}
+
+		if ret.RefundAmount, err = money.Sum(amounts...); err != nil {
+			return apperrors.NewConflict("Items bought in different currencies must be returned separately")
+		}
+		return repo.Create(ctx, ret)
+	})
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	metrics.ReturnsTotal.WithLabelValues(string(domain.StatusRequested)).Inc()
+	h.publish(ctx, ret, "return.requested", h.publisher.PublishRequested)
+
+	c.JSON(http.StatusCreated, ret)
+}
+
+// ListMyReturns lists the caller's returns, newest first
+// GET /api/v1/returns
+func (h *Handler) ListMyReturns(c *gin.Context) {
+	h.list(c, repository.ListFilter{UserID: c.GetString(sharedauth.ContextUserID)}, true)
+}
+
+// GetReturnableOrder shows what of one of the caller's orders may still be
+// returned, and until when
+// GET /api/v1/returns/orders/:orderId
+func (h *Handler) GetReturnableOrder(c *gin.Context) {
+	order, err := h.repo.GetOrder(c.Request.Context(), c.Param("orderId"))
+	if err == nil && order.UserID != c.GetString(sharedauth.ContextUserID) {
+		err = domain.ErrOrderNotFound
+	}
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	view := ReturnableOrder{
+		OrderID:     order.OrderID,
+		OrderNumber: order.OrderNumber,
+		Status:      order.Status,
+		DeliveredAt: order.DeliveredAt,
+		Lines:       make([]ReturnableLine, 0, len(order.Lines)),
+	}
+	if deadline, ok := order.ReturnDeadline(h.window); ok {
+		view.ReturnBy = &deadline
+		view.Returnable = time.Now().Before(deadline)
+	}
+	for _, line := range order.Lines {
+		returnable := 0
+		if view.Returnable {
+			returnable = line.Returnable()
+		}
+		view.Lines = append(view.Lines, ReturnableLine{OrderLine: line, Returnable: returnable})
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// GetReturn returns one of the caller's returns, or any return to staff
+// GET /api/v1/returns/:id
+func (h *Handler) GetReturn(c *gin.Context) {
+	ret, err := h.repo.GetByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	staff := isStaff(c)
+	if !staff && ret.UserID != c.GetString(sharedauth.ContextUserID) {
+		h.abort(c, domain.ErrReturnNotFound)
+		return
+	}
+	if !staff {
+		ret.HideStaff()
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
+
+// CancelReturn withdraws one of the caller's returns before it is received
+// POST /api/v1/returns/:id/cancel
+func (h *Handler) CancelReturn(c *gin.Context) {
+	userID := c.GetString(sharedauth.ContextUserID)
+	ctx := c.Request.Context()
+
+	var ret *domain.Return
+	changed := false
+	err := h.repo.InTx(ctx, func(repo repository.ReturnRepository) error {
+		var err error
+		ret, err = repo.GetForUpdate(ctx, c.Param("id"))
+		if err == nil && ret.UserID != userID {
+			err = domain.ErrReturnNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		switch ret.Status {
+		case domain.StatusCancelled:
+			return nil
+		case domain.StatusRequested, domain.StatusApproved:
+		default:
+			return apperrors.NewConflict("Returns can't be cancelled once " + string(ret.Status))
+		}
+
+		ret.Status = domain.StatusCancelled
+		changed = true
+		return repo.Update(ctx, ret)
+	})
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	if changed {
+		metrics.ReturnsTotal.WithLabelValues(string(domain.StatusCancelled)).Inc()
+	}
+	ret.HideStaff()
+	c.JSON(http.StatusOK, ret)
+}
+
+// ListReturns lists returns for staff, oldest first, optionally of one
+// status, e.g. ?status=requested for those awaiting a decision, or of one
+// customer, ?user_id=
+// GET /api/v1/returns/manage
+func (h *Handler) ListReturns(c *gin.Context) {
+	status := domain.Status(c.Query("status"))
+	if status != "" && !status.Valid() {
+		apperrors.Abort(c, apperrors.NewBadRequest("Invalid status"))
+		return
+	}
+
+	h.list(c, repository.ListFilter{UserID: c.Query("user_id"), Status: status, OldestFirst: true}, false)
+}
+
+// ApproveReturn accepts a requested return and generates its label. If the
+// label can't be generated the return stays requested, so approving can
+// be retried.
+// POST /api/v1/returns/:id/approve
+func (h *Handler) ApproveReturn(c *gin.Context) {
+	h.transition(c, domain.StatusApproved, func(ctx context.Context, ret *domain.Return) error {
+		if ret.Status != domain.StatusRequested {
+			return apperrors.NewConflict("Only requested returns can be approved")
+		}
+
+		if h.labels != nil {
+			label, err := h.labels.CreateLabel(ctx, ret)
+			if err != nil {
+				metrics.CallsTotal.WithLabelValues("shipping", "failed").Inc()
+				return apperrors.ErrServiceUnavailable.WithMessage("Failed to generate the return label").WithCause(err)
+			}
+			metrics.CallsTotal.WithLabelValues("shipping", "ok").Inc()
+			ret.Label = label
+		}
+
+		now := time.Now().UTC()
+		ret.DecidedBy = c.GetString(sharedauth.ContextUserID)
+		ret.DecidedAt = &now
+		return nil
+	}, nil, h.publisher.PublishApproved)
+}
+
+// RejectReturn refuses a requested return
+// POST /api/v1/returns/:id/reject
+func (h *Handler) RejectReturn(c *gin.Context) {
+	var req RejectRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	h.transition(c, domain.StatusRejected, func(ctx context.Context, ret *domain.Return) error {
+		if ret.Status != domain.StatusRequested {
+			return apperrors.NewConflict("Only requested returns can be rejected")
+		}
+
+		now := time.Now().UTC()
+		ret.DecidedBy = c.GetString(sharedauth.ContextUserID)
+		ret.DecidedAt = &now
+		ret.RejectionReason = req.Reason
+		return nil
+	}, map[string]string{"reason": req.Reason}, h.publisher.PublishRejected)
+}
+
+// ReceiveReturn records that an approved return arrived, first putting its
+// items back into stock through inventory-service with the caller's token
+// unless restock is false. Items already restocked by an earlier, failed
+// attempt aren't restocked again.
+// POST /api/v1/returns/:id/receive
+func (h *Handler) ReceiveReturn(c *gin.Context) {
+	var req ReceiveRequest
+	if c.Request.ContentLength != 0 && !apperrors.BindJSON(c, &req) {
+		return
+	}
+	restock := req.Restock == nil || *req.Restock
+
+	ctx := c.Request.Context()
+	ret, err := h.repo.GetByID(ctx, c.Param("id"))
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	if ret.Status == domain.StatusApproved && restock {
+		if err := h.restock(c, ret); err != nil {
+			apperrors.Abort(c, err)
+			return
+		}
+	}
+
+	h.transition(c, domain.StatusReceived, func(ctx context.Context, ret *domain.Return) error {
+		if ret.Status != domain.StatusApproved {
+			return apperrors.NewConflict("Only approved returns can be received")
+		}
+
+		now := time.Now().UTC()
+		ret.ReceivedBy = c.GetString(sharedauth.ContextUserID)
+		ret.ReceivedAt = &now
+		ret.Restocked = restock
+		return nil
+	}, map[string]string{"restocked": fmt.Sprint(restock)}, h.publisher.PublishReceived)
+}
+
+// RefundReturn refunds a received return's items, at the price they were
+// bought for, through payment-service. The return stays locked while
+// payment-service refunds it, so it is refunded once.
+// POST /api/v1/returns/:id/refund
+func (h *Handler) RefundReturn(c *gin.Context) {
+	h.transition(c, domain.StatusRefunded, func(ctx context.Context, ret *domain.Return) error {
+		if ret.Status != domain.StatusReceived {
+			return apperrors.NewConflict("Only received returns can be refunded")
+		}
+
+		if ret.RefundAmount.MinorUnits > 0 {
+			refundID, err := h.payments.Refund(ctx, ret.OrderID, ret.RefundAmount, "Return "+ret.ID)
+			var rejected *payments.RejectedError
+			if errors.As(err, &rejected) {
+				metrics.CallsTotal.WithLabelValues("payment", "rejected").Inc()
+				return apperrors.NewConflict(rejected.Message)
+			} else if err != nil {
+				metrics.CallsTotal.WithLabelValues("payment", "failed").Inc()
+				return apperrors.ErrServiceUnavailable.WithMessage("Failed to refund the return").WithCause(err)
+			}
+			metrics.CallsTotal.WithLabelValues("payment", "ok").Inc()
+			metrics.RefundedMinorUnitsTotal.WithLabelValues(ret.RefundAmount.Currency).Add(float64(ret.RefundAmount.MinorUnits))
+			ret.RefundID = refundID
+
+			logging.WithContext(ctx, h.logger).Info("Return refunded",
+				zap.String("return_id", ret.ID),
+				zap.String("order_id", ret.OrderID),
+				zap.String("refund_id", refundID),
+			)
+		}
+
+		now := time.Now().UTC()
+		ret.RefundedAt = &now
+		return nil
+	}, nil, h.publisher.PublishRefunded)
+}
+
+// HealthCheck reports whether the database is reachable
+func (h *Handler) HealthCheck(c *gin.Context) {
+	if err := h.repo.Ping(c.Request.Context()); err != nil {
+		h.logger.Warn("Health check failed", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unhealthy",
+			"service": "returns-service",
+			"version": "1.0.0",
+			"error":   "database unavailable",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "healthy",
+		"service": "returns-service",
+		"version": "1.0.0",
+	})
+}
+
+// transition moves the return of the :id path parameter to status in a
+// transaction, after apply checks and fills in the change; apply runs with
+// the return locked. Repeating a transition changes nothing. Changes are
+// audit logged with metadata and announced with publish.
+func (h *Handler) transition(
+	c *gin.Context,
+	status domain.Status,
+	apply func(ctx context.Context, ret *domain.Return) error,
+	metadata map[string]string,
+	publish func(ctx context.Context, ret *domain.Return) error,
+) {
+	ctx := c.Request.Context()
+
+	var before, after domain.Return
+	err := h.repo.InTx(ctx, func(repo repository.ReturnRepository) error {
+		ret, err := repo.GetForUpdate(ctx, c.Param("id"))
+		if err != nil {
+			return err
+		}
+		before = *ret
+		if ret.Status == status {
+			after = *ret
+			return nil
+		}
+
+		if err := apply(ctx, ret); err != nil {
+			return err
+		}
+		ret.Status = status
+		after = *ret
+		return repo.Update(ctx, ret)
+	})
+	if err != nil {
+		if status == domain.StatusRefunded && after.RefundID != "" {
+			// payment-service refunded the return but it couldn't be
+			// recorded; it must not be refunded again
+			logging.WithContext(ctx, h.logger).Error("Refunded return not recorded as refunded",
+				zap.String("return_id", after.ID),
+				zap.String("refund_id", after.RefundID),
+				zap.Error(err),
+			)
+		}
+		h.abort(c, err)
+		return
+	}
+
+	if before.Status != after.Status {
+		metrics.ReturnsTotal.WithLabelValues(string(status)).Inc()
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		metadata["order_id"] = after.OrderID
+		h.auditor.LogGin(c, audit.Entry{
+			Action:   "return." + string(status),
+			Resource: audit.Resource{Type: "return", ID: after.ID},
+			Before:   before,
+			After:    after,
+			Metadata: metadata,
+		})
+		h.publish(ctx, &after, "return."+string(status), publish)
+	}
+
+	c.JSON(http.StatusOK, after)
+}
+
+// restock puts a return's items not yet restocked back into stock,
+// claiming each first so concurrent receipts don't restock it twice
+func (h *Handler) restock(c *gin.Context, ret *domain.Return) error {
+	ctx := c.Request.Context()
+	staffID := c.GetString(sharedauth.ContextUserID)
+
+	for _, item := range ret.Items {
+		if item.RestockedAt != nil {
+			continue
+		}
+		claimed, err := h.repo.ClaimRestock(ctx, ret.ID, item.ProductID, time.Now())
+		if err != nil {
+			return apperrors.FromSQL(err, "Return")
+		}
+		if !claimed {
+			continue
+		}
+
+		err = h.inventory.Restock(ctx, sharedauth.Token(c), item.ProductID, item.Quantity, staffID, "Return "+ret.ID)
+		if err == nil {
+			metrics.CallsTotal.WithLabelValues("inventory", "ok").Inc()
+			continue
+		}
+
+		if releaseErr := h.repo.ReleaseRestock(ctx, ret.ID, item.ProductID); releaseErr != nil {
+			logging.WithContext(ctx, h.logger).Error("Failed to release restock claim",
+				zap.String("return_id", ret.ID),
+				zap.String("product_id", item.ProductID),
+				zap.Error(releaseErr),
+			)
+		}
+
+		var rejected *inventory.RejectedError
+		if errors.As(err, &rejected) {
+			metrics.CallsTotal.WithLabelValues("inventory", "rejected").Inc()
+			if rejected.StatusCode == http.StatusForbidden {
+				return apperrors.NewForbidden("Restocking needs the inventory:write permission; receive the return with restock false instead")
+			}
+			return apperrors.NewConflict(rejected.Message)
+		}
+		metrics.CallsTotal.WithLabelValues("inventory", "failed").Inc()
+		return apperrors.ErrServiceUnavailable.WithMessage("Failed to restock the returned items").WithCause(err)
+	}
+	return nil
+}
+
+// list renders a page of returns matching filter; customers' pages leave
+// out who handled them
+func (h *Handler) list(c *gin.Context, filter repository.ListFilter, customer bool) {
+	params := pagination.FromQuery(c.Request.URL.Query())
+
+	var after *repository.ListPosition
+	if params.Cursor != "" {
+		after = &repository.ListPosition{}
+		if err := pagination.DecodeCursor(params.Cursor, after); err != nil {
+			apperrors.Abort(c, apperrors.NewBadRequest("Invalid cursor"))
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	returns, err := h.repo.List(ctx, filter, params.Limit+1, after)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Return"))
+		return
+	}
+
+	total, err := h.repo.Count(ctx, filter)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Return"))
+		return
+	}
+
+	if customer {
+		for _, ret := range returns {
+			ret.HideStaff()
+		}
+	}
+
+	page, err := pagination.NewPage(returns, params.Limit, total, func(ret *domain.Return) interface{} {
+		return repository.ListPosition{CreatedAt: ret.CreatedAt, ID: ret.ID}
+	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list returns"))
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// publish announces a change of a return. The change is stored either way;
+// a lost event only means the customer isn't told of it.
+func (h *Handler) publish(ctx context.Context, ret *domain.Return, eventType string, publish func(ctx context.Context, ret *domain.Return) error) {
+	if err := publish(ctx, ret); err != nil {
+		logging.WithContext(ctx, h.logger).Warn("Failed to publish "+eventType,
+			zap.String("return_id", ret.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+// abort responds with the API error of a repository error
+func (h *Handler) abort(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrReturnNotFound):
+		apperrors.Abort(c, apperrors.NewNotFound("Return"))
+	case errors.Is(err, domain.ErrOrderNotFound):
+		apperrors.Abort(c, apperrors.NewNotFound("Order"))
+	default:
+		apperrors.Abort(c, apperrors.FromSQL(err, "Return"))
+	}
+}
+
+// isStaff reports whether the caller may manage returns
+func isStaff(c *gin.Context) bool {
+	claims, ok := sharedauth.ClaimsFrom(c)
+	return ok && claims.HasPermission(PermissionManage)
+}
+
+// callerEmail is the email address in the caller's token, if any
+func callerEmail(c *gin.Context) string {
+	if claims, ok := sharedauth.ClaimsFrom(c); ok {
+		return claims.Email
+	}
+	return ""
+}
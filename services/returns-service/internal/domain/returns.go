@@ -0,0 +1,169 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/money"
+)
+
+var (
+	ErrReturnNotFound = errors.New("return not found")
+	ErrOrderNotFound  = errors.New("order not found")
+)
+
+// Status is where a return is in its workflow:
+// requested → approved → received → refunded, or rejected or cancelled
+// before it is received
+type Status string
+
+const (
+	// StatusRequested returns await staff approval
+	StatusRequested Status = "requested"
+	// StatusApproved returns may be sent back, with their label if any
+	StatusApproved Status = "approved"
+	// StatusRejected returns were refused by staff
+	StatusRejected Status = "rejected"
+	// StatusCancelled returns were withdrawn by the customer
+	StatusCancelled Status = "cancelled"
+	// StatusReceived returns arrived back at the warehouse
+	StatusReceived Status = "received"
+	// StatusRefunded returns were refunded through payment-service
+	StatusRefunded Status = "refunded"
+)
+
+// Valid reports whether s is a known status
+func (s Status) Valid() bool {
+	switch s {
+	case StatusRequested, StatusApproved, StatusRejected, StatusCancelled, StatusReceived, StatusRefunded:
+		return true
+	}
+	return false
+}
+
+// Open reports whether returns in status s still hold their items, so the
+// same items can't be returned twice
+func (s Status) Open() bool {
+	return s != StatusRejected && s != StatusCancelled
+}
+
+// Reasons customers may give for a return
+var Reasons = []string{"damaged", "defective", "wrong_item", "not_as_described", "no_longer_needed", "other"}
+
+// ValidReason reports whether reason is one of Reasons
+func ValidReason(reason string) bool {
+	for _, r := range Reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// Order statuses, as far as returns are concerned
+const (
+	OrderPlaced    = "placed"
+	OrderDelivered = "delivered"
+	OrderCancelled = "cancelled"
+)
+
+// Order is an order as seen on order events
+type Order struct {
+	OrderID       string
+	UserID        string
+	OrderNumber   string
+	CustomerEmail string
+	CustomerName  string
+	Status        string
+	DeliveredAt   *time.Time
+	Lines         []OrderLine
+}
+
+// OrderLine is a product bought in an order. Returned is how many of it
+// are on open returns.
+type OrderLine struct {
+	ProductID string      `json:"product_id"`
+	SKU       string      `json:"sku,omitempty"`
+	Name      string      `json:"name,omitempty"`
+	Quantity  int         `json:"quantity"`
+	UnitPrice money.Money `json:"unit_price"`
+	Returned  int         `json:"returned"`
+}
+
+// Returnable is how many more of the line may be returned
+func (l *OrderLine) Returnable() int {
+	if l.Returned >= l.Quantity {
+		return 0
+	}
+	return l.Quantity - l.Returned
+}
+
+// Line returns the order's line of a product, if it has one
+func (o *Order) Line(productID string) (*OrderLine, bool) {
+	for i := range o.Lines {
+		if o.Lines[i].ProductID == productID {
+			return &o.Lines[i], true
+		}
+	}
+	return nil, false
+}
+
+// ReturnDeadline is when the order's return window of window closes; orders
+// not delivered have none
+func (o *Order) ReturnDeadline(window time.Duration) (time.Time, bool) {
+	if o.Status != OrderDelivered || o.DeliveredAt == nil {
+		return time.Time{}, false
+	}
+	return o.DeliveredAt.Add(window), true
+}
+
+// Return is a customer's request to send back items of a delivered order,
+// and what became of it
+type Return struct {
+	ID              string      `json:"id"`
+	OrderID         string      `json:"order_id"`
+	OrderNumber     string      `json:"order_number,omitempty"`
+	UserID          string      `json:"user_id"`
+	CustomerEmail   string      `json:"-"`
+	CustomerName    string      `json:"-"`
+	Status          Status      `json:"status"`
+	Reason          string      `json:"reason"`
+	Comments        string      `json:"comments,omitempty"`
+	Items           []Item      `json:"items"`
+	RefundAmount    money.Money `json:"refund_amount"`
+	Label           *Label      `json:"label,omitempty"`
+	DecidedBy       string      `json:"decided_by,omitempty"`
+	DecidedAt       *time.Time  `json:"decided_at,omitempty"`
+	RejectionReason string      `json:"rejection_reason,omitempty"`
+	ReceivedBy      string      `json:"received_by,omitempty"`
+	ReceivedAt      *time.Time  `json:"received_at,omitempty"`
+	// Restocked is whether the received items went back into stock
+	Restocked  bool       `json:"restocked"`
+	RefundID   string     `json:"refund_id,omitempty"`
+	RefundedAt *time.Time `json:"refunded_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Item is a product being returned, at the price it was bought for
+type Item struct {
+	ProductID   string      `json:"product_id"`
+	SKU         string      `json:"sku,omitempty"`
+	Name        string      `json:"name,omitempty"`
+	Quantity    int         `json:"quantity"`
+	UnitPrice   money.Money `json:"unit_price"`
+	RestockedAt *time.Time  `json:"restocked_at,omitempty"`
+}
+
+// Label is the shipping label a customer sends a return back with
+type Label struct {
+	Carrier        string `json:"carrier,omitempty"`
+	TrackingNumber string `json:"tracking_number,omitempty"`
+	URL            string `json:"url"`
+}
+
+// HideStaff clears who handled the return, for showing it to the customer
+func (r *Return) HideStaff() {
+	r.DecidedBy = ""
+	r.ReceivedBy = ""
+}
@@ -0,0 +1,133 @@
+package events
+
+import (
+	"context"
+
+	sharedevents "github.com/ecommerce-platform/shared/go/events"
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	"github.com/ecommerce/returns-service/internal/domain"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// Publisher publishes return.* events, for notification-service to tell
+// customers how their return is going
+type Publisher interface {
+	PublishRequested(ctx context.Context, ret *domain.Return) error
+	PublishApproved(ctx context.Context, ret *domain.Return) error
+	PublishRejected(ctx context.Context, ret *domain.Return) error
+	PublishReceived(ctx context.Context, ret *domain.Return) error
+	PublishRefunded(ctx context.Context, ret *domain.Return) error
+	Close() error
+}
+
+type kafkaPublisher struct {
+	producer *sharedkafka.Producer
+	logger   *zap.Logger
+}
+
+// NewKafkaPublisher creates a publisher. Events are keyed by order, so an
+// order's return events are handled in order.
+func NewKafkaPublisher(brokers []string, topic string, logger *zap.Logger) Publisher {
+	producer := sharedkafka.NewProducer(sharedkafka.ProducerConfig{
+		Brokers: brokers,
+		Topic:   topic,
+	}, logger)
+
+	return &kafkaPublisher{
+		producer: producer,
+		logger:   logger,
+	}
+}
+
+func (p *kafkaPublisher) publishEvent(ctx context.Context, ret *domain.Return, payload sharedevents.Payload) error {
+	env := sharedevents.Envelope{OrderID: ret.OrderID, UserID: ret.UserID}
+	data, err := sharedevents.Marshal(env, payload)
+	if err != nil {
+		p.logger.Error("Failed to marshal event", zap.Error(err))
+		return err
+	}
+
+	message := kafka.Message{
+		Key:   []byte(ret.OrderID),
+		Value: data,
+	}
+
+	if err := p.producer.Publish(ctx, message); err != nil {
+		p.logger.Error("Failed to publish event", zap.Error(err), zap.String("event_type", payload.EventType()))
+		return err
+	}
+
+	p.logger.Debug("Event published", zap.String("event_type", payload.EventType()), zap.String("return_id", ret.ID))
+	return nil
+}
+
+func (p *kafkaPublisher) PublishRequested(ctx context.Context, ret *domain.Return) error {
+	return p.publishEvent(ctx, ret, &sharedevents.ReturnRequested{
+		ReturnUpdate: update(ret),
+		Reason:       ret.Reason,
+	})
+}
+
+func (p *kafkaPublisher) PublishApproved(ctx context.Context, ret *domain.Return) error {
+	approved := &sharedevents.ReturnApproved{ReturnUpdate: update(ret)}
+	if ret.Label != nil {
+		approved.Label = &sharedevents.ReturnLabel{
+			Carrier:        ret.Label.Carrier,
+			TrackingNumber: ret.Label.TrackingNumber,
+			LabelURL:       ret.Label.URL,
+		}
+	}
+	return p.publishEvent(ctx, ret, approved)
+}
+
+func (p *kafkaPublisher) PublishRejected(ctx context.Context, ret *domain.Return) error {
+	return p.publishEvent(ctx, ret, &sharedevents.ReturnRejected{
+		ReturnUpdate: update(ret),
+		Reason:       ret.RejectionReason,
+	})
+}
+
+func (p *kafkaPublisher) PublishReceived(ctx context.Context, ret *domain.Return) error {
+	return p.publishEvent(ctx, ret, &sharedevents.ReturnReceived{
+		ReturnUpdate: update(ret),
+		Restocked:    ret.Restocked,
+	})
+}
+
+func (p *kafkaPublisher) PublishRefunded(ctx context.Context, ret *domain.Return) error {
+	return p.publishEvent(ctx, ret, &sharedevents.ReturnRefunded{
+		ReturnUpdate: update(ret),
+		RefundID:     ret.RefundID,
+	})
+}
+
+func (p *kafkaPublisher) Close() error {
+	return p.producer.Close()
+}
+
+// update is the data every return event carries
+func update(ret *domain.Return) sharedevents.ReturnUpdate {
+	items := make([]sharedevents.ReturnItem, 0, len(ret.Items))
+	for _, item := range ret.Items {
+		items = append(items, sharedevents.ReturnItem{
+			ProductID: item.ProductID,
+			SKU:       item.SKU,
+			Name:      item.Name,
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+		})
+	}
+
+	return sharedevents.ReturnUpdate{
+		ReturnID:      ret.ID,
+		OrderID:       ret.OrderID,
+		OrderNumber:   ret.OrderNumber,
+		UserID:        ret.UserID,
+		CustomerEmail: ret.CustomerEmail,
+		CustomerName:  ret.CustomerName,
+		Status:        string(ret.Status),
+		Items:         items,
+		RefundAmount:  ret.RefundAmount,
+	}
+}
@@ -0,0 +1,140 @@
+// Package inventory puts returned items back into stock in
+// inventory-service
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	inventoryv1 "github.com/ecommerce-platform/proto/gen/go/ecommerce/inventory/v1"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"google.golang.org/grpc"
+)
+
+// AdjustmentReason is the reason returned stock is recorded with
+const AdjustmentReason = "return"
+
+// RejectedError is inventory-service refusing an adjustment, e.g. because
+// the product has no stock record or the caller may not adjust stock;
+// Message is safe to show staff
+type RejectedError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *RejectedError) Error() string {
+	return "inventory rejected the adjustment: " + e.Message
+}
+
+// Client calls inventory-service on behalf of staff: stock records are
+// looked up over its gRPC API, and adjusted over HTTP with the staff
+// member's token
+type Client struct {
+	baseURL      string
+	httpClient   *http.Client
+	availability inventoryv1.InventoryServiceClient
+}
+
+// NewClient creates a client of the inventory-service at baseURL, called
+// through transport, and at conn for gRPC calls
+func NewClient(baseURL string, transport http.RoundTripper, conn grpc.ClientConnInterface) *Client {
+	return &Client{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		httpClient:   &http.Client{Timeout: 10 * time.Second, Transport: transport},
+		availability: inventoryv1.NewInventoryServiceClient(conn),
+	}
+}
+
+// Restock adds quantity of a product back to its stock, as an adjustment
+// with reason "return" made by adjustedBy. token is the staff member's,
+// whose inventory:write permission the adjustment needs. Adjustments
+// inventory-service refuses return a *RejectedError.
+func (c *Client) Restock(ctx context.Context, token, productID string, quantity int, adjustedBy, notes string) error {
+	itemID, err := c.itemID(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"quantity":    quantity,
+		"reason":      AdjustmentReason,
+		"adjusted_by": adjustedBy,
+		"notes":       notes,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, "/api/v1/inventory/"+url.PathEscape(itemID)+"/adjust", token, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	return failure(resp, "adjust stock of product "+productID)
+}
+
+// itemID looks up the ID of a product's stock record
+func (c *Client) itemID(ctx context.Context, productID string) (string, error) {
+	resp, err := c.availability.GetAvailability(ctx, &inventoryv1.GetAvailabilityRequest{ProductIds: []string{productID}})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up stock of product %s: %w", productID, err)
+	}
+
+	for _, item := range resp.GetItems() {
+		if item.GetProductId() == productID && item.GetItemId() != "" {
+			return item.GetItemId(), nil
+		}
+	}
+	return "", &RejectedError{StatusCode: http.StatusNotFound, Message: "Product " + productID + " has no stock record"}
+}
+
+func (c *Client) do(ctx context.Context, method, path, token string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if correlationID := logging.CorrelationID(ctx); correlationID != "" {
+		req.Header.Set(logging.CorrelationIDHeader, correlationID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call inventory-service: %w", err)
+	}
+	return resp, nil
+}
+
+// failure reads inventory-service's error response: refusals of the
+// request are *RejectedError, anything else a plain error
+func failure(resp *http.Response, action string) error {
+	var body struct {
+		Error string `json:"error"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+
+	switch resp.StatusCode {
+	case http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict:
+		message := body.Error
+		if message == "" {
+			message = "Failed to " + action
+		}
+		return &RejectedError{StatusCode: resp.StatusCode, Message: message}
+	}
+	return fmt.Errorf("inventory-service returned status %d to %s: %s", resp.StatusCode, action, body.Error)
+}
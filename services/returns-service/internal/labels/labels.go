@@ -0,0 +1,106 @@
+// Package labels generates the shipping labels customers send returns back
+// with
+package labels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/returns-service/internal/domain"
+)
+
+// Provider generates return labels
+type Provider interface {
+	// CreateLabel generates the label of an approved return
+	CreateLabel(ctx context.Context, ret *domain.Return) (*domain.Label, error)
+}
+
+// shippingProvider has shipping-service generate labels
+type shippingProvider struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewShippingProvider creates a provider calling the shipping-service at
+// baseURL through transport
+func NewShippingProvider(baseURL string, transport http.RoundTripper) Provider {
+	return &shippingProvider{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second, Transport: transport},
+	}
+}
+
+// labelRequest is the body of shipping-service's
+// POST /api/v1/shipping/return-labels
+type labelRequest struct {
+	ReturnID string      `json:"return_id"`
+	OrderID  string      `json:"order_id"`
+	Items    []labelItem `json:"items"`
+}
+
+type labelItem struct {
+	ProductID string `json:"product_id"`
+	SKU       string `json:"sku,omitempty"`
+	Quantity  int    `json:"quantity"`
+}
+
+// CreateLabel asks shipping-service for a prepaid label shipping the
+// return's items back to the warehouse
+func (p *shippingProvider) CreateLabel(ctx context.Context, ret *domain.Return) (*domain.Label, error) {
+	request := labelRequest{ReturnID: ret.ID, OrderID: ret.OrderID}
+	for _, item := range ret.Items {
+		request.Items = append(request.Items, labelItem{ProductID: item.ProductID, SKU: item.SKU, Quantity: item.Quantity})
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/v1/shipping/return-labels", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if correlationID := logging.CorrelationID(ctx); correlationID != "" {
+		req.Header.Set(logging.CorrelationIDHeader, correlationID)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call shipping-service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&failure)
+		return nil, fmt.Errorf("shipping-service returned status %d: %s", resp.StatusCode, failure.Error)
+	}
+
+	var label struct {
+		Carrier        string `json:"carrier"`
+		TrackingNumber string `json:"tracking_number"`
+		LabelURL       string `json:"label_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&label); err != nil {
+		return nil, fmt.Errorf("failed to decode label: %w", err)
+	}
+	if label.LabelURL == "" {
+		return nil, errors.New("shipping-service returned a label without label_url")
+	}
+
+	return &domain.Label{
+		Carrier:        label.Carrier,
+		TrackingNumber: label.TrackingNumber,
+		URL:            label.LabelURL,
+	}, nil
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// OrderEventsTotal counts consumed order events by outcome: recorded,
+	// skipped (event types that don't affect returns) or failed
+	OrderEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "returns_order_events_total",
+		Help: "Order events applied to order records, per event type and result",
+	}, []string{"event_type", "result"})
+
+	// ReturnsTotal counts returns moving to a status: requested, approved,
+	// rejected, cancelled, received or refunded
+	ReturnsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "returns_total",
+		Help: "Returns moving to each status",
+	}, []string{"status"})
+
+	// CallsTotal counts calls to other services by service and result:
+	// ok, rejected (the service refused, e.g. a refund larger than the
+	// payment) or failed
+	CallsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "returns_service_calls_total",
+		Help: "Calls to shipping, inventory and payment services, per service and result",
+	}, []string{"service", "result"})
+
+	// RefundedMinorUnitsTotal adds up refunds, in minor units per currency
+	RefundedMinorUnitsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "returns_refunded_minor_units_total",
+		Help: "Amount refunded for returns, in minor units per currency",
+	}, []string{"currency"})
+)
@@ -0,0 +1,139 @@
+// Package orders records what customers bought and received from
+// order-service's order events, so returns can be limited to delivered
+// order lines
+package orders
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	sharedevents "github.com/ecommerce-platform/shared/go/events"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/returns-service/internal/domain"
+	"github.com/ecommerce/returns-service/internal/metrics"
+	"github.com/ecommerce/returns-service/internal/repository"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// Recorder applies order events to the order records
+type Recorder struct {
+	repo   repository.ReturnRepository
+	logger *zap.Logger
+}
+
+// NewRecorder creates a recorder writing to repo
+func NewRecorder(repo repository.ReturnRepository, logger *zap.Logger) *Recorder {
+	return &Recorder{repo: repo, logger: logger}
+}
+
+// orderDelivered is the data of order-service's order.delivered events
+type orderDelivered struct {
+	OrderNumber   string     `json:"order_number"`
+	UserID        string     `json:"user_id"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+	CustomerEmail string     `json:"customer_email"`
+	CustomerName  string     `json:"customer_name"`
+}
+
+// Handle applies an order event. It is a sharedkafka.Handler; failures are
+// retried and then dead-lettered. Other order events are ignored.
+func (r *Recorder) Handle(ctx context.Context, msg kafkago.Message) error {
+	env, err := sharedevents.Decode(msg.Value)
+	if err != nil {
+		metrics.OrderEventsTotal.WithLabelValues("unknown", "failed").Inc()
+		return err
+	}
+	if env.OrderID == "" {
+		metrics.OrderEventsTotal.WithLabelValues(env.EventType, "failed").Inc()
+		return fmt.Errorf("%s event has no order_id", env.EventType)
+	}
+
+	recorded, err := r.apply(ctx, env)
+	if err != nil {
+		metrics.OrderEventsTotal.WithLabelValues(env.EventType, "failed").Inc()
+		logging.WithContext(ctx, r.logger).Warn("Failed to record order event",
+			zap.String("event_type", env.EventType),
+			zap.String("order_id", env.OrderID),
+			zap.Error(err),
+		)
+		return err
+	}
+	if !recorded {
+		metrics.OrderEventsTotal.WithLabelValues(env.EventType, "skipped").Inc()
+		return nil
+	}
+
+	metrics.OrderEventsTotal.WithLabelValues(env.EventType, "recorded").Inc()
+	logging.WithContext(ctx, r.logger).Debug("Order event recorded",
+		zap.String("event_type", env.EventType),
+		zap.String("order_id", env.OrderID),
+	)
+	return nil
+}
+
+// apply records an event and reports whether it affects returns
+func (r *Recorder) apply(ctx context.Context, env *sharedevents.Envelope) (bool, error) {
+	switch env.EventType {
+	case "order.created":
+		var created sharedevents.OrderCreated
+		if err := env.DecodeData(&created); err != nil {
+			return false, err
+		}
+		return true, r.repo.SaveOrder(ctx, &domain.Order{
+			OrderID:       env.OrderID,
+			UserID:        created.UserID,
+			OrderNumber:   created.OrderNumber,
+			CustomerEmail: created.CustomerEmail,
+			CustomerName:  created.CustomerName,
+			Lines:         linesOf(created.Items),
+		})
+
+	case "order.delivered":
+		var delivered orderDelivered
+		if err := json.Unmarshal(env.Data, &delivered); err != nil {
+			return false, fmt.Errorf("failed to decode %s data: %w", env.EventType, err)
+		}
+		return true, r.repo.MarkDelivered(ctx, &domain.Order{
+			OrderID:       env.OrderID,
+			UserID:        delivered.UserID,
+			OrderNumber:   delivered.OrderNumber,
+			CustomerEmail: delivered.CustomerEmail,
+			CustomerName:  delivered.CustomerName,
+			DeliveredAt:   delivered.DeliveredAt,
+		})
+
+	case "order.cancelled":
+		return true, r.repo.MarkCancelled(ctx, env.OrderID)
+
+	default:
+		return false, nil
+	}
+}
+
+// linesOf turns an order's items into one line per product, adding up
+// the quantities of a product listed twice
+func linesOf(items []sharedevents.OrderItem) []domain.OrderLine {
+	var lines []domain.OrderLine
+	index := make(map[string]int)
+	for _, item := range items {
+		if item.ProductID == "" || item.Quantity <= 0 {
+			continue
+		}
+		if i, ok := index[item.ProductID]; ok {
+			lines[i].Quantity += item.Quantity
+			continue
+		}
+		index[item.ProductID] = len(lines)
+		lines = append(lines, domain.OrderLine{
+			ProductID: item.ProductID,
+			SKU:       item.SKU,
+			Name:      item.Name,
+			Quantity:  item.Quantity,
+			UnitPrice: item.Price,
+		})
+	}
+	return lines
+}
@@ -0,0 +1,164 @@
+// Package payments refunds returns through payment-service
+package payments
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce-platform/shared/go/money"
+)
+
+// RejectedError is payment-service refusing a refund, e.g. because the
+// order has no captured payment or the amount exceeds it; Message is safe
+// to show staff
+type RejectedError struct {
+	Message string
+}
+
+func (e *RejectedError) Error() string {
+	return "payment rejected the refund: " + e.Message
+}
+
+// Client calls payment-service
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a client of the payment-service at baseURL, calling it
+// through transport
+func NewClient(baseURL string, transport http.RoundTripper) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second, Transport: transport},
+	}
+}
+
+// payment is payment-service's payment of an order, as far as refunds
+// need it
+type payment struct {
+	TransactionID string `json:"transaction_id"`
+	Currency      string `json:"currency"`
+}
+
+// Refund refunds amount of an order's payment and returns payment-service's
+// refund ID. Refunds payment-service refuses return a *RejectedError.
+func (c *Client) Refund(ctx context.Context, orderID string, amount money.Money, reason string) (string, error) {
+	paid, err := c.paymentOf(ctx, orderID)
+	if err != nil {
+		return "", err
+	}
+	if paid.TransactionID == "" {
+		return "", &RejectedError{Message: "The order's payment has no transaction to refund"}
+	}
+	if !strings.EqualFold(paid.Currency, amount.Currency) {
+		return "", &RejectedError{Message: fmt.Sprintf("The order was paid in %s, not %s", paid.Currency, amount.Currency)}
+	}
+
+	// payment-service takes amounts in major units
+	major, err := strconv.ParseFloat(amount.Decimal(), 64)
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"transaction_id": paid.TransactionID,
+		"amount":         major,
+		"reason":         reason,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, "/api/v1/payments/refund", body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", failure(resp, "refund")
+	}
+
+	var refund struct {
+		Success  bool   `json:"success"`
+		RefundID string `json:"refund_id"`
+		Error    string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&refund); err != nil {
+		return "", fmt.Errorf("failed to decode refund: %w", err)
+	}
+	if !refund.Success {
+		if refund.Error == "" {
+			refund.Error = "The refund was declined"
+		}
+		return "", &RejectedError{Message: refund.Error}
+	}
+	return refund.RefundID, nil
+}
+
+// paymentOf looks up an order's payment
+func (c *Client) paymentOf(ctx context.Context, orderID string) (*payment, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/api/v1/payments/order/"+url.PathEscape(orderID), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &RejectedError{Message: "The order has no payment to refund"}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, failure(resp, "look up the order's payment")
+	}
+
+	var paid payment
+	if err := json.NewDecoder(resp.Body).Decode(&paid); err != nil {
+		return nil, fmt.Errorf("failed to decode payment: %w", err)
+	}
+	return &paid, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if correlationID := logging.CorrelationID(ctx); correlationID != "" {
+		req.Header.Set(logging.CorrelationIDHeader, correlationID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call payment-service: %w", err)
+	}
+	return resp, nil
+}
+
+// failure reads payment-service's error response, whose message is its
+// "detail": 400s are *RejectedError, anything else a plain error
+func failure(resp *http.Response, action string) error {
+	var body struct {
+		Detail interface{} `json:"detail"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	detail, _ := body.Detail.(string)
+
+	if resp.StatusCode == http.StatusBadRequest {
+		if detail == "" {
+			detail = "Failed to " + action
+		}
+		return &RejectedError{Message: detail}
+	}
+	return fmt.Errorf("payment-service returned status %d to %s: %s", resp.StatusCode, action, detail)
+}
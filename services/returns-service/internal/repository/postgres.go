@@ -0,0 +1,473 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	shareddb "github.com/ecommerce-platform/shared/go/db"
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce/returns-service/internal/domain"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// returnColumns selects a return with its order's number and customer
+// name, from returns r joined to orders o
+const returnColumns = `
+	r.id, r.order_id, o.order_number, r.user_id, r.customer_email, o.customer_name,
+	r.status, r.reason, r.comments, r.refund_amount_minor, r.currency,
+	r.label_carrier, r.label_tracking_number, r.label_url,
+	r.decided_by, r.decided_at, r.rejection_reason, r.received_by, r.received_at, r.restocked,
+	r.refund_id, r.refunded_at, r.created_at, r.updated_at
+`
+
+const returnsFrom = ` FROM returns r JOIN orders o ON o.order_id = r.order_id`
+
+// closedStatuses are those of returns that no longer hold their items
+var closedStatuses = pq.Array([]string{string(domain.StatusRejected), string(domain.StatusCancelled)})
+
+type postgresRepository struct {
+	// db runs queries: tx within InTx, else pool
+	db   shareddb.Querier
+	pool *sql.DB
+	tx   *sql.Tx
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *sql.DB) ReturnRepository {
+	return &postgresRepository{db: db, pool: db}
+}
+
+// InTx runs fn in a transaction. Nested calls join the current transaction.
+func (r *postgresRepository) InTx(ctx context.Context, fn func(repo ReturnRepository) error) error {
+	return r.inTx(ctx, func(repo *postgresRepository) error { return fn(repo) })
+}
+
+func (r *postgresRepository) inTx(ctx context.Context, fn func(repo *postgresRepository) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+	return shareddb.WithTx(ctx, r.pool, nil, func(tx *sql.Tx) error {
+		return fn(&postgresRepository{db: tx, pool: r.pool, tx: tx})
+	})
+}
+
+// Ping checks that the database is reachable
+func (r *postgresRepository) Ping(ctx context.Context) error {
+	return shareddb.Check(ctx, r.pool)
+}
+
+// SaveOrder records a placed order and its lines. Redelivered events leave
+// a delivered or cancelled order's status alone.
+func (r *postgresRepository) SaveOrder(ctx context.Context, order *domain.Order) error {
+	return r.inTx(ctx, func(repo *postgresRepository) error {
+		query := `
+			INSERT INTO orders (order_id, user_id, order_number, customer_email, customer_name, status)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (order_id) DO UPDATE SET
+				user_id = EXCLUDED.user_id,
+				order_number = EXCLUDED.order_number,
+				customer_email = COALESCE(NULLIF(EXCLUDED.customer_email, ''), orders.customer_email),
+				customer_name = COALESCE(NULLIF(EXCLUDED.customer_name, ''), orders.customer_name),
+				updated_at = CURRENT_TIMESTAMP
+		`
+		_, err := repo.db.ExecContext(ctx, query,
+			order.OrderID, order.UserID, order.OrderNumber, order.CustomerEmail, order.CustomerName, domain.OrderPlaced,
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, line := range order.Lines {
+			_, err := repo.db.ExecContext(ctx, `
+				INSERT INTO order_lines (order_id, product_id, sku, name, quantity, unit_price_minor, currency)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				ON CONFLICT (order_id, product_id) DO UPDATE SET
+					sku = EXCLUDED.sku,
+					name = EXCLUDED.name,
+					quantity = EXCLUDED.quantity,
+					unit_price_minor = EXCLUDED.unit_price_minor,
+					currency = EXCLUDED.currency
+			`, order.OrderID, line.ProductID, line.SKU, line.Name, line.Quantity,
+				line.UnitPrice.MinorUnits, line.UnitPrice.Currency)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MarkDelivered records an order's delivery. Orders delivered before the
+// service saw their order.created are recorded without lines, so nothing
+// in them can be returned.
+func (r *postgresRepository) MarkDelivered(ctx context.Context, order *domain.Order) error {
+	deliveredAt := time.Now().UTC()
+	if order.DeliveredAt != nil {
+		deliveredAt = order.DeliveredAt.UTC()
+	}
+
+	query := `
+		INSERT INTO orders (order_id, user_id, order_number, customer_email, customer_name, status, delivered_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (order_id) DO UPDATE SET
+			user_id = COALESCE(NULLIF(EXCLUDED.user_id, ''), orders.user_id),
+			order_number = COALESCE(NULLIF(EXCLUDED.order_number, ''), orders.order_number),
+			customer_email = COALESCE(NULLIF(EXCLUDED.customer_email, ''), orders.customer_email),
+			customer_name = COALESCE(NULLIF(EXCLUDED.customer_name, ''), orders.customer_name),
+			status = EXCLUDED.status,
+			delivered_at = COALESCE(orders.delivered_at, EXCLUDED.delivered_at),
+			updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		order.OrderID, order.UserID, order.OrderNumber, order.CustomerEmail, order.CustomerName,
+		domain.OrderDelivered, deliveredAt,
+	)
+	return err
+}
+
+// MarkCancelled records an order's cancellation
+func (r *postgresRepository) MarkCancelled(ctx context.Context, orderID string) error {
+	query := `
+		INSERT INTO orders (order_id, status)
+		VALUES ($1, $2)
+		ON CONFLICT (order_id) DO UPDATE SET status = EXCLUDED.status, updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := r.db.ExecContext(ctx, query, orderID, domain.OrderCancelled)
+	return err
+}
+
+// GetOrder retrieves an order with its lines
+func (r *postgresRepository) GetOrder(ctx context.Context, orderID string) (*domain.Order, error) {
+	return r.getOrder(ctx, "", orderID)
+}
+
+// LockOrder retrieves an order with its lines, locking it until the
+// transaction ends
+func (r *postgresRepository) LockOrder(ctx context.Context, orderID string) (*domain.Order, error) {
+	return r.getOrder(ctx, " FOR UPDATE", orderID)
+}
+
+func (r *postgresRepository) getOrder(ctx context.Context, lock, orderID string) (*domain.Order, error) {
+	query := `
+		SELECT order_id, user_id, order_number, customer_email, customer_name, status, delivered_at
+		FROM orders WHERE order_id = $1` + lock
+
+	order := &domain.Order{}
+	err := r.db.QueryRowContext(ctx, query, orderID).Scan(
+		&order.OrderID, &order.UserID, &order.OrderNumber, &order.CustomerEmail, &order.CustomerName,
+		&order.Status, &order.DeliveredAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Returned counts the line's items on returns still open
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT l.product_id, l.sku, l.name, l.quantity, l.unit_price_minor, l.currency,
+		       COALESCE((
+		           SELECT SUM(i.quantity)
+		           FROM return_items i
+		           JOIN returns r ON r.id = i.return_id
+		           WHERE r.order_id = l.order_id AND i.product_id = l.product_id AND r.status <> ALL($2)
+		       ), 0)
+		FROM order_lines l
+		WHERE l.order_id = $1
+		ORDER BY l.name, l.product_id
+	`, orderID, closedStatuses)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line domain.OrderLine
+		var minor int64
+		var currency string
+		if err := rows.Scan(&line.ProductID, &line.SKU, &line.Name, &line.Quantity, &minor, &currency, &line.Returned); err != nil {
+			return nil, err
+		}
+		line.UnitPrice = money.New(minor, currency)
+		order.Lines = append(order.Lines, line)
+	}
+
+	return order, rows.Err()
+}
+
+// Create stores a return with its items
+func (r *postgresRepository) Create(ctx context.Context, ret *domain.Return) error {
+	if ret.ID == "" {
+		ret.ID = uuid.New().String()
+	}
+
+	now := time.Now().UTC()
+	ret.CreatedAt = now
+	ret.UpdatedAt = now
+
+	return r.inTx(ctx, func(repo *postgresRepository) error {
+		query := `
+			INSERT INTO returns (
+				id, order_id, user_id, customer_email, status, reason, comments,
+				refund_amount_minor, currency, created_at, updated_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		`
+		_, err := repo.db.ExecContext(ctx, query,
+			ret.ID, ret.OrderID, ret.UserID, ret.CustomerEmail, ret.Status, ret.Reason, ret.Comments,
+			ret.RefundAmount.MinorUnits, ret.RefundAmount.Currency, ret.CreatedAt, ret.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range ret.Items {
+			_, err := repo.db.ExecContext(ctx, `
+				INSERT INTO return_items (return_id, product_id, sku, name, quantity, unit_price_minor, currency)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+			`, ret.ID, item.ProductID, item.SKU, item.Name, item.Quantity,
+				item.UnitPrice.MinorUnits, item.UnitPrice.Currency)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetByID retrieves a return by ID
+func (r *postgresRepository) GetByID(ctx context.Context, id string) (*domain.Return, error) {
+	return r.get(ctx, `SELECT `+returnColumns+returnsFrom+` WHERE r.id = $1`, id)
+}
+
+// GetForUpdate retrieves a return by ID, locking it until the transaction
+// ends
+func (r *postgresRepository) GetForUpdate(ctx context.Context, id string) (*domain.Return, error) {
+	return r.get(ctx, `SELECT `+returnColumns+returnsFrom+` WHERE r.id = $1 FOR UPDATE OF r`, id)
+}
+
+func (r *postgresRepository) get(ctx context.Context, query, id string) (*domain.Return, error) {
+	ret, err := scanReturn(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrReturnNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadItems(ctx, []*domain.Return{ret}); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// List retrieves up to limit returns matching filter after a position
+func (r *postgresRepository) List(ctx context.Context, filter ListFilter, limit int, after *ListPosition) ([]*domain.Return, error) {
+	where, args := filter.where()
+
+	order, cmp := "DESC", "<"
+	if filter.OldestFirst {
+		order, cmp = "ASC", ">"
+	}
+	if after != nil {
+		args = append(args, after.CreatedAt, after.ID)
+		where = append(where, fmt.Sprintf("(r.created_at, r.id) %s ($%d, $%d)", cmp, len(args)-1, len(args)))
+	}
+	args = append(args, limit)
+
+	query := `SELECT ` + returnColumns + returnsFrom + whereClause(where) +
+		fmt.Sprintf(" ORDER BY r.created_at %s, r.id %s LIMIT $%d", order, order, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var returns []*domain.Return
+	for rows.Next() {
+		ret, err := scanReturn(rows)
+		if err != nil {
+			return nil, err
+		}
+		returns = append(returns, ret)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadItems(ctx, returns); err != nil {
+		return nil, err
+	}
+	return returns, nil
+}
+
+// Count returns the number of returns matching filter
+func (r *postgresRepository) Count(ctx context.Context, filter ListFilter) (int64, error) {
+	where, args := filter.where()
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM returns r"+whereClause(where), args...).Scan(&count)
+	return count, err
+}
+
+// Update stores a return's status, decision, label, receipt and refund
+func (r *postgresRepository) Update(ctx context.Context, ret *domain.Return) error {
+	ret.UpdatedAt = time.Now().UTC()
+
+	var carrier, tracking, labelURL string
+	if ret.Label != nil {
+		carrier, tracking, labelURL = ret.Label.Carrier, ret.Label.TrackingNumber, ret.Label.URL
+	}
+
+	query := `
+		UPDATE returns SET
+			status = $1, label_carrier = $2, label_tracking_number = $3, label_url = $4,
+			decided_by = $5, decided_at = $6, rejection_reason = $7,
+			received_by = $8, received_at = $9, restocked = $10,
+			refund_id = $11, refunded_at = $12, updated_at = $13
+		WHERE id = $14
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		ret.Status, carrier, tracking, labelURL,
+		ret.DecidedBy, ret.DecidedAt, ret.RejectionReason,
+		ret.ReceivedBy, ret.ReceivedAt, ret.Restocked,
+		ret.RefundID, ret.RefundedAt, ret.UpdatedAt, ret.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrReturnNotFound
+	}
+
+	return nil
+}
+
+// ClaimRestock marks an item restocked unless it already is
+func (r *postgresRepository) ClaimRestock(ctx context.Context, returnID, productID string, at time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE return_items SET restocked_at = $1
+		WHERE return_id = $2 AND product_id = $3 AND restocked_at IS NULL
+	`, at.UTC(), returnID, productID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// ReleaseRestock clears an item's restock claim
+func (r *postgresRepository) ReleaseRestock(ctx context.Context, returnID, productID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE return_items SET restocked_at = NULL
+		WHERE return_id = $1 AND product_id = $2
+	`, returnID, productID)
+	return err
+}
+
+// loadItems fills in the items of returns
+func (r *postgresRepository) loadItems(ctx context.Context, returns []*domain.Return) error {
+	if len(returns) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*domain.Return, len(returns))
+	ids := make([]string, 0, len(returns))
+	for _, ret := range returns {
+		ret.Items = []domain.Item{}
+		byID[ret.ID] = ret
+		ids = append(ids, ret.ID)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT return_id, product_id, sku, name, quantity, unit_price_minor, currency, restocked_at
+		FROM return_items
+		WHERE return_id = ANY($1)
+		ORDER BY return_id, name, product_id
+	`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var returnID string
+		var item domain.Item
+		var minor int64
+		var currency string
+		if err := rows.Scan(&returnID, &item.ProductID, &item.SKU, &item.Name, &item.Quantity, &minor, &currency, &item.RestockedAt); err != nil {
+			return err
+		}
+		item.UnitPrice = money.New(minor, currency)
+		if ret, ok := byID[returnID]; ok {
+			ret.Items = append(ret.Items, item)
+		}
+	}
+
+	return rows.Err()
+}
+
+// where returns the filter's conditions and their arguments
+func (f ListFilter) where() ([]string, []interface{}) {
+	var where []string
+	var args []interface{}
+	if f.UserID != "" {
+		args = append(args, f.UserID)
+		where = append(where, fmt.Sprintf("r.user_id = $%d", len(args)))
+	}
+	if f.Status != "" {
+		args = append(args, f.Status)
+		where = append(where, fmt.Sprintf("r.status = $%d", len(args)))
+	}
+	return where, args
+}
+
+func whereClause(where []string) string {
+	if len(where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(where, " AND ")
+}
+
+// scanner is a *sql.Row or *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanReturn(row scanner) (*domain.Return, error) {
+	ret := &domain.Return{}
+	var refundMinor int64
+	var currency string
+	var label domain.Label
+	err := row.Scan(
+		&ret.ID, &ret.OrderID, &ret.OrderNumber, &ret.UserID, &ret.CustomerEmail, &ret.CustomerName,
+		&ret.Status, &ret.Reason, &ret.Comments, &refundMinor, &currency,
+		&label.Carrier, &label.TrackingNumber, &label.URL,
+		&ret.DecidedBy, &ret.DecidedAt, &ret.RejectionReason, &ret.ReceivedBy, &ret.ReceivedAt, &ret.Restocked,
+		&ret.RefundID, &ret.RefundedAt, &ret.CreatedAt, &ret.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ret.RefundAmount = money.New(refundMinor, currency)
+	if label.URL != "" {
+		ret.Label = &label
+	}
+	return ret, nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ecommerce/returns-service/internal/domain"
+)
+
+// ReturnRepository defines return data operations
+type ReturnRepository interface {
+	// Orders
+	// SaveOrder records a placed order and its lines
+	SaveOrder(ctx context.Context, order *domain.Order) error
+	// MarkDelivered records an order's delivery, filling in the customer
+	// details order.created didn't carry
+	MarkDelivered(ctx context.Context, order *domain.Order) error
+	// MarkCancelled records an order's cancellation
+	MarkCancelled(ctx context.Context, orderID string) error
+	// GetOrder returns an order with its lines and how many of each are on
+	// open returns, or domain.ErrOrderNotFound
+	GetOrder(ctx context.Context, orderID string) (*domain.Order, error)
+	// LockOrder is GetOrder, locking the order until the transaction ends
+	// so concurrent requests can't return the same items twice
+	LockOrder(ctx context.Context, orderID string) (*domain.Order, error)
+
+	// Returns
+	// Create stores a return with its items
+	Create(ctx context.Context, ret *domain.Return) error
+	GetByID(ctx context.Context, id string) (*domain.Return, error)
+	// GetForUpdate returns a return locked until the transaction ends
+	GetForUpdate(ctx context.Context, id string) (*domain.Return, error)
+	List(ctx context.Context, filter ListFilter, limit int, after *ListPosition) ([]*domain.Return, error)
+	Count(ctx context.Context, filter ListFilter) (int64, error)
+	// Update stores a return's status and what came with it: the decision,
+	// label, receipt and refund
+	Update(ctx context.Context, ret *domain.Return) error
+	// ClaimRestock marks an item restocked at at, reporting false if it
+	// already was, so concurrent receipts restock it once
+	ClaimRestock(ctx context.Context, returnID, productID string, at time.Time) (bool, error)
+	// ReleaseRestock undoes ClaimRestock when restocking failed
+	ReleaseRestock(ctx context.Context, returnID, productID string) error
+
+	// InTx runs fn with a repository whose operations share a transaction,
+	// committed when fn returns nil
+	InTx(ctx context.Context, fn func(repo ReturnRepository) error) error
+	// Ping checks that the database is reachable
+	Ping(ctx context.Context) error
+}
+
+// ListFilter selects the returns to list
+type ListFilter struct {
+	UserID string // empty for every customer
+	Status domain.Status
+	// OldestFirst lists in request order, e.g. for the staff queue; lists
+	// are newest first otherwise
+	OldestFirst bool
+}
+
+// ListPosition is the last return of a page, by creation time with ties
+// broken by ID
+type ListPosition struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+}
@@ -0,0 +1,94 @@
+-- Orders as seen on order events, for checking what customers may return
+CREATE TABLE IF NOT EXISTS orders (
+    order_id VARCHAR(255) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL DEFAULT '',
+    order_number VARCHAR(100) NOT NULL DEFAULT '',
+    customer_email VARCHAR(255) NOT NULL DEFAULT '',
+    customer_name VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'placed',
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_user_id ON orders(user_id);
+
+CREATE TABLE IF NOT EXISTS order_lines (
+    order_id VARCHAR(255) NOT NULL REFERENCES orders(order_id) ON DELETE CASCADE,
+    product_id VARCHAR(255) NOT NULL,
+    sku VARCHAR(100) NOT NULL DEFAULT '',
+    name VARCHAR(500) NOT NULL DEFAULT '',
+    quantity INTEGER NOT NULL,
+    unit_price_minor BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    PRIMARY KEY (order_id, product_id)
+);
+
+-- Returns, from request to refund. refund_amount_minor is what the items
+-- cost when ordered.
+CREATE TABLE IF NOT EXISTS returns (
+    id VARCHAR(36) PRIMARY KEY,
+    order_id VARCHAR(255) NOT NULL REFERENCES orders(order_id),
+    user_id VARCHAR(255) NOT NULL,
+    customer_email VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'requested',
+    reason VARCHAR(50) NOT NULL,
+    comments VARCHAR(1000) NOT NULL DEFAULT '',
+    refund_amount_minor BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    label_carrier VARCHAR(100) NOT NULL DEFAULT '',
+    label_tracking_number VARCHAR(255) NOT NULL DEFAULT '',
+    label_url VARCHAR(2000) NOT NULL DEFAULT '',
+    decided_by VARCHAR(255) NOT NULL DEFAULT '',
+    decided_at TIMESTAMP,
+    rejection_reason VARCHAR(500) NOT NULL DEFAULT '',
+    received_by VARCHAR(255) NOT NULL DEFAULT '',
+    received_at TIMESTAMP,
+    restocked BOOLEAN NOT NULL DEFAULT FALSE,
+    refund_id VARCHAR(255) NOT NULL DEFAULT '',
+    refunded_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_returns_user ON returns(user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_returns_status ON returns(status, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_returns_order_id ON returns(order_id);
+
+-- restocked_at is set once an item's stock is back in inventory-service, so
+-- a retried receipt doesn't restock it twice
+CREATE TABLE IF NOT EXISTS return_items (
+    return_id VARCHAR(36) NOT NULL REFERENCES returns(id) ON DELETE CASCADE,
+    product_id VARCHAR(255) NOT NULL,
+    sku VARCHAR(100) NOT NULL DEFAULT '',
+    name VARCHAR(500) NOT NULL DEFAULT '',
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    unit_price_minor BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    restocked_at TIMESTAMP,
+    PRIMARY KEY (return_id, product_id)
+);
+
+-- Create audit_log table, as in shared/go/audit/schema.sql
+CREATE TABLE IF NOT EXISTS audit_log (
+    id VARCHAR(36) PRIMARY KEY,
+    occurred_at TIMESTAMP NOT NULL,
+    service VARCHAR(100) NOT NULL,
+    actor_type VARCHAR(20) NOT NULL,
+    actor_id VARCHAR(255) NOT NULL DEFAULT '',
+    actor_email VARCHAR(255) NOT NULL DEFAULT '',
+    actor_role VARCHAR(50) NOT NULL DEFAULT '',
+    actor_ip VARCHAR(64) NOT NULL DEFAULT '',
+    action VARCHAR(100) NOT NULL,
+    resource_type VARCHAR(100) NOT NULL,
+    resource_id VARCHAR(255) NOT NULL,
+    before_state JSONB,
+    after_state JSONB,
+    metadata JSONB NOT NULL DEFAULT '{}',
+    correlation_id VARCHAR(255) NOT NULL DEFAULT '',
+    trace_id VARCHAR(32) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_resource ON audit_log(resource_type, resource_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_occurred_at ON audit_log(occurred_at);
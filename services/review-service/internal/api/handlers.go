@@ -0,0 +1,309 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/audit"
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce-platform/shared/go/pagination"
+	"github.com/ecommerce/review-service/internal/domain"
+	"github.com/ecommerce/review-service/internal/events"
+	"github.com/ecommerce/review-service/internal/metrics"
+	"github.com/ecommerce/review-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Handler serves the review API
+type Handler struct {
+	repo      repository.ReviewRepository
+	publisher events.Publisher
+	auditor   *audit.Auditor
+	logger    *zap.Logger
+}
+
+// NewHandler creates a new handler
+func NewHandler(repo repository.ReviewRepository, publisher events.Publisher, auditor *audit.Auditor, logger *zap.Logger) *Handler {
+	return &Handler{
+		repo:      repo,
+		publisher: publisher,
+		auditor:   auditor,
+		logger:    logger,
+	}
+}
+
+// CreateReviewRequest is a customer's review of a delivered order item
+type CreateReviewRequest struct {
+	OrderID   string `json:"order_id" binding:"required"`
+	ProductID string `json:"product_id" binding:"required"`
+	Rating    int    `json:"rating" binding:"required,min=1,max=5"`
+	Title     string `json:"title" binding:"max=200"`
+	Body      string `json:"body" binding:"max=5000"`
+}
+
+// RejectReviewRequest gives the reason a review is rejected
+type RejectReviewRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// CreateReview posts a review of a product the customer received. It
+// awaits moderation before it is shown.
+// POST /api/v1/reviews
+func (h *Handler) CreateReview(c *gin.Context) {
+	var req CreateReviewRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString(sharedauth.ContextUserID)
+
+	purchase, err := h.repo.DeliveredPurchase(ctx, userID, req.OrderID, req.ProductID)
+	if errors.Is(err, domain.ErrNotPurchased) {
+		apperrors.Abort(c, apperrors.NewForbidden("Only delivered order items can be reviewed"))
+		return
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Order"))
+		return
+	}
+
+	review := &domain.Review{
+		ProductID: req.ProductID,
+		OrderID:   req.OrderID,
+		UserID:    userID,
+		Author:    displayName(purchase.CustomerName),
+		Rating:    req.Rating,
+		Title:     strings.TrimSpace(req.Title),
+		Body:      strings.TrimSpace(req.Body),
+		Status:    domain.StatusPending,
+	}
+	if err := h.repo.Create(ctx, review); errors.Is(err, domain.ErrAlreadyReviewed) {
+		apperrors.Abort(c, apperrors.NewConflict("You have already reviewed this product"))
+		return
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Review"))
+		return
+	}
+	metrics.ReviewsSubmittedTotal.Inc()
+
+	// The review is stored either way; a lost event only means a review
+	// request may still go out for the order
+	if err := h.publisher.PublishReviewCreated(ctx, review); err != nil {
+		logging.WithContext(ctx, h.logger).Warn("Failed to publish review.created",
+			zap.String("review_id", review.ID),
+			zap.Error(err),
+		)
+	}
+
+	c.JSON(http.StatusCreated, review)
+}
+
+// ListMyReviews lists the customer's own reviews, in any status, newest
+// first
+// GET /api/v1/reviews/mine
+func (h *Handler) ListMyReviews(c *gin.Context) {
+	h.list(c, repository.ListFilter{UserID: c.GetString(sharedauth.ContextUserID)}, false)
+}
+
+// ListReviewable lists the customer's delivered items they haven't
+// reviewed, optionally of one order
+// GET /api/v1/reviews/reviewable?order_id=
+func (h *Handler) ListReviewable(c *gin.Context) {
+	items, err := h.repo.ReviewableItems(c.Request.Context(), c.GetString(sharedauth.ContextUserID), c.Query("order_id"))
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Order"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// ListProductReviews lists a product's approved reviews, newest first
+// GET /api/v1/reviews/products/:productId
+func (h *Handler) ListProductReviews(c *gin.Context) {
+	h.list(c, repository.ListFilter{ProductID: c.Param("productId"), Status: domain.StatusApproved}, true)
+}
+
+// GetProductRating returns a product's average rating and the number of
+// reviews per star, from its approved reviews
+// GET /api/v1/reviews/products/:productId/rating
+func (h *Handler) GetProductRating(c *gin.Context) {
+	rating, err := h.repo.GetRating(c.Request.Context(), c.Param("productId"))
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Rating"))
+		return
+	}
+
+	c.JSON(http.StatusOK, rating)
+}
+
+// ListModerationQueue lists reviews in a moderation status, pending by
+// default, oldest first
+// GET /api/v1/reviews/moderation?status=
+func (h *Handler) ListModerationQueue(c *gin.Context) {
+	status := domain.ReviewStatus(c.DefaultQuery("status", string(domain.StatusPending)))
+	if !status.Valid() {
+		apperrors.Abort(c, apperrors.NewValidation(apperrors.FieldError{
+			Field:   "status",
+			Rule:    "oneof",
+			Message: "status must be one of pending, approved, rejected",
+		}))
+		return
+	}
+
+	h.list(c, repository.ListFilter{Status: status, OldestFirst: true}, false)
+}
+
+// ApproveReview publishes a review and counts it towards the product's
+// rating
+// POST /api/v1/reviews/:id/approve
+func (h *Handler) ApproveReview(c *gin.Context) {
+	h.moderate(c, domain.StatusApproved, "")
+}
+
+// RejectReview hides a review, pending or already approved, and takes it
+// out of the product's rating
+// POST /api/v1/reviews/:id/reject
+func (h *Handler) RejectReview(c *gin.Context) {
+	var req RejectReviewRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	h.moderate(c, domain.StatusRejected, strings.TrimSpace(req.Reason))
+}
+
+// moderate moves a review to status and refreshes its product's rating in
+// the same transaction. Repeating a decision changes nothing.
+func (h *Handler) moderate(c *gin.Context, status domain.ReviewStatus, reason string) {
+	ctx := c.Request.Context()
+
+	var before, after domain.Review
+	err := h.repo.InTx(ctx, func(repo repository.ReviewRepository) error {
+		review, err := repo.GetForUpdate(ctx, c.Param("id"))
+		if err != nil {
+			return err
+		}
+		before, after = *review, *review
+		if review.Status == status {
+			return nil
+		}
+
+		now := time.Now().UTC()
+		after.Status = status
+		after.ModeratedBy = c.GetString(sharedauth.ContextUserID)
+		after.ModeratedAt = &now
+		after.RejectionReason = reason
+		if err := repo.Moderate(ctx, &after); err != nil {
+			return err
+		}
+		return repo.RefreshRating(ctx, after.ProductID)
+	})
+	if errors.Is(err, domain.ErrNotFound) {
+		apperrors.Abort(c, apperrors.NewNotFound("Review"))
+		return
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Review"))
+		return
+	}
+
+	if before.Status != after.Status {
+		metrics.ReviewsModeratedTotal.WithLabelValues(string(status)).Inc()
+		metadata := map[string]string{"product_id": after.ProductID}
+		if reason != "" {
+			metadata["reason"] = reason
+		}
+		h.auditor.LogGin(c, audit.Entry{
+			Action:   "review." + string(status),
+			Resource: audit.Resource{Type: "review", ID: after.ID},
+			Before:   before,
+			After:    after,
+			Metadata: metadata,
+		})
+	}
+
+	c.JSON(http.StatusOK, after)
+}
+
+// list renders a page of reviews matching filter; public pages leave out
+// who wrote and moderated them
+func (h *Handler) list(c *gin.Context, filter repository.ListFilter, public bool) {
+	params := pagination.FromQuery(c.Request.URL.Query())
+
+	var after *repository.ListPosition
+	if params.Cursor != "" {
+		after = &repository.ListPosition{}
+		if err := pagination.DecodeCursor(params.Cursor, after); err != nil {
+			apperrors.Abort(c, apperrors.NewBadRequest("Invalid cursor"))
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	reviews, err := h.repo.List(ctx, filter, params.Limit+1, after)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Review"))
+		return
+	}
+
+	total, err := h.repo.Count(ctx, filter)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Review"))
+		return
+	}
+
+	if public {
+		for _, review := range reviews {
+			review.HidePrivate()
+		}
+	}
+
+	page, err := pagination.NewPage(reviews, params.Limit, total, func(review *domain.Review) interface{} {
+		return repository.ListPosition{CreatedAt: review.CreatedAt, ID: review.ID}
+	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list reviews"))
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// HealthCheck reports whether the database is reachable
+func (h *Handler) HealthCheck(c *gin.Context) {
+	if err := h.repo.Ping(c.Request.Context()); err != nil {
+		h.logger.Warn("Health check failed", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unhealthy",
+			"service": "review-service",
+			"version": "1.0.0",
+			"error":   "database unavailable",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "healthy",
+		"service": "review-service",
+		"version": "1.0.0",
+	})
+}
+
+// displayName shows reviewers by first name and last initial, e.g.
+// "Jane D.", rather than their full name
+func displayName(fullName string) string {
+	parts := strings.Fields(fullName)
+	switch len(parts) {
+	case 0:
+		return ""
+	case 1:
+		return parts[0]
+	default:
+		last := []rune(parts[len(parts)-1])
+		return parts[0] + " " + string(last[0]) + "."
+	}
+}
@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ReviewStatus is where a review is in moderation
+type ReviewStatus string
+
+const (
+	// StatusPending reviews await a moderator and aren't shown
+	StatusPending ReviewStatus = "pending"
+	// StatusApproved reviews are shown and count towards the rating
+	StatusApproved ReviewStatus = "approved"
+	// StatusRejected reviews are hidden from the storefront
+	StatusRejected ReviewStatus = "rejected"
+)
+
+// Valid reports whether s is a known status
+func (s ReviewStatus) Valid() bool {
+	switch s {
+	case StatusPending, StatusApproved, StatusRejected:
+		return true
+	}
+	return false
+}
+
+// Order statuses, as far as reviews are concerned
+const (
+	OrderPlaced    = "placed"
+	OrderDelivered = "delivered"
+	OrderCancelled = "cancelled"
+)
+
+// Review is a customer's rating of a product they bought
+type Review struct {
+	ID        string       `json:"id"`
+	ProductID string       `json:"product_id"`
+	OrderID   string       `json:"order_id"`
+	UserID    string       `json:"user_id"`
+	Author    string       `json:"author,omitempty"`
+	Rating    int          `json:"rating"`
+	Title     string       `json:"title,omitempty"`
+	Body      string       `json:"body,omitempty"`
+	Status    ReviewStatus `json:"status"`
+	// VerifiedPurchase is always true: reviews can only be posted for
+	// delivered order items
+	VerifiedPurchase bool       `json:"verified_purchase"`
+	ModeratedBy      string     `json:"moderated_by,omitempty"`
+	ModeratedAt      *time.Time `json:"moderated_at,omitempty"`
+	RejectionReason  string     `json:"rejection_reason,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// HidePrivate clears what the storefront mustn't show: who wrote the
+// review, in which order, and who moderated it
+func (r *Review) HidePrivate() {
+	r.UserID = ""
+	r.OrderID = ""
+	r.ModeratedBy = ""
+	r.ModeratedAt = nil
+	r.RejectionReason = ""
+}
+
+// PurchasedOrder is an order as seen on order events
+type PurchasedOrder struct {
+	OrderID       string
+	UserID        string
+	OrderNumber   string
+	CustomerEmail string
+	CustomerName  string
+	Status        string
+	DeliveredAt   *time.Time
+	Items         []PurchasedItem
+}
+
+// PurchasedItem is a product bought in an order
+type PurchasedItem struct {
+	ProductID string `json:"product_id"`
+	Name      string `json:"name,omitempty"`
+}
+
+// ProductRating aggregates a product's approved reviews
+type ProductRating struct {
+	ProductID   string  `json:"product_id"`
+	ReviewCount int     `json:"review_count"`
+	Average     float64 `json:"average_rating"`
+	// Distribution counts reviews per star, "1" to "5"
+	Distribution map[string]int `json:"distribution"`
+}
+
+// Common errors
+var (
+	ErrNotFound = errors.New("review not found")
+	// ErrNotPurchased is returned for reviews of products the customer
+	// hasn't received in the given order
+	ErrNotPurchased = errors.New("product was not delivered to the customer in this order")
+	// ErrAlreadyReviewed is returned when the customer already reviewed the
+	// product
+	ErrAlreadyReviewed = errors.New("product already reviewed")
+)
@@ -0,0 +1,91 @@
+package events
+
+import (
+	"context"
+
+	sharedevents "github.com/ecommerce-platform/shared/go/events"
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	"github.com/ecommerce/review-service/internal/domain"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+type Publisher interface {
+	PublishReviewCreated(ctx context.Context, review *domain.Review) error
+	PublishReviewRequested(ctx context.Context, order *domain.PurchasedOrder) error
+	Close() error
+}
+
+type kafkaPublisher struct {
+	producer *sharedkafka.Producer
+	logger   *zap.Logger
+}
+
+// NewKafkaPublisher creates a publisher. Events are keyed by order, so an
+// order's review request reaches consumers before the reviews it prompts.
+func NewKafkaPublisher(brokers []string, topic string, logger *zap.Logger) Publisher {
+	producer := sharedkafka.NewProducer(sharedkafka.ProducerConfig{
+		Brokers: brokers,
+		Topic:   topic,
+	}, logger)
+
+	return &kafkaPublisher{
+		producer: producer,
+		logger:   logger,
+	}
+}
+
+func (p *kafkaPublisher) publishEvent(ctx context.Context, env sharedevents.Envelope, payload sharedevents.Payload) error {
+	data, err := sharedevents.Marshal(env, payload)
+	if err != nil {
+		p.logger.Error("Failed to marshal event", zap.Error(err))
+		return err
+	}
+
+	message := kafka.Message{
+		Key:   []byte(env.OrderID),
+		Value: data,
+	}
+
+	if err := p.producer.Publish(ctx, message); err != nil {
+		p.logger.Error("Failed to publish event", zap.Error(err), zap.String("event_type", payload.EventType()))
+		return err
+	}
+
+	p.logger.Debug("Event published", zap.String("event_type", payload.EventType()), zap.String("order_id", env.OrderID))
+	return nil
+}
+
+func (p *kafkaPublisher) PublishReviewCreated(ctx context.Context, review *domain.Review) error {
+	env := sharedevents.Envelope{OrderID: review.OrderID, ProductID: review.ProductID, UserID: review.UserID}
+	return p.publishEvent(ctx, env, &sharedevents.ReviewCreated{
+		ReviewID:  review.ID,
+		ProductID: review.ProductID,
+		OrderID:   review.OrderID,
+		UserID:    review.UserID,
+		Rating:    review.Rating,
+		Title:     review.Title,
+		Status:    string(review.Status),
+	})
+}
+
+func (p *kafkaPublisher) PublishReviewRequested(ctx context.Context, order *domain.PurchasedOrder) error {
+	items := make([]sharedevents.ReviewableItem, len(order.Items))
+	for i, item := range order.Items {
+		items[i] = sharedevents.ReviewableItem{ProductID: item.ProductID, Name: item.Name}
+	}
+
+	env := sharedevents.Envelope{OrderID: order.OrderID, UserID: order.UserID}
+	return p.publishEvent(ctx, env, &sharedevents.ReviewRequested{
+		OrderID:       order.OrderID,
+		OrderNumber:   order.OrderNumber,
+		UserID:        order.UserID,
+		CustomerEmail: order.CustomerEmail,
+		CustomerName:  order.CustomerName,
+		Items:         items,
+	})
+}
+
+func (p *kafkaPublisher) Close() error {
+	return p.producer.Close()
+}
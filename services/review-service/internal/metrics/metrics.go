@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// OrderEventsTotal counts consumed order events by outcome: recorded,
+	// skipped (event types that don't affect purchases) or failed
+	OrderEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "review_order_events_total",
+		Help: "Order events applied to purchase records, per event type and result",
+	}, []string{"event_type", "result"})
+
+	// ReviewsSubmittedTotal counts reviews posted by customers
+	ReviewsSubmittedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "reviews_submitted_total",
+		Help: "Reviews posted by customers",
+	})
+
+	// ReviewsModeratedTotal counts moderation decisions: approved or
+	// rejected
+	ReviewsModeratedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "reviews_moderated_total",
+		Help: "Moderation decisions per resulting status",
+	}, []string{"status"})
+
+	// ReviewRequestsTotal counts delivered orders processed for review
+	// requests by result: requested, skipped (nothing left to review) or
+	// failed
+	ReviewRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "review_requests_total",
+		Help: "Review requests for delivered orders, per result",
+	}, []string{"result"})
+)
@@ -0,0 +1,119 @@
+// Package purchases records what customers bought and received from
+// order-service's order events, so reviews can be limited to verified
+// purchases
+package purchases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	sharedevents "github.com/ecommerce-platform/shared/go/events"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/review-service/internal/domain"
+	"github.com/ecommerce/review-service/internal/metrics"
+	"github.com/ecommerce/review-service/internal/repository"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// Recorder applies order events to the purchase records
+type Recorder struct {
+	repo   repository.ReviewRepository
+	logger *zap.Logger
+}
+
+// NewRecorder creates a recorder writing to repo
+func NewRecorder(repo repository.ReviewRepository, logger *zap.Logger) *Recorder {
+	return &Recorder{repo: repo, logger: logger}
+}
+
+// orderDelivered is the data of order-service's order.delivered events
+type orderDelivered struct {
+	OrderNumber   string     `json:"order_number"`
+	UserID        string     `json:"user_id"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+	CustomerEmail string     `json:"customer_email"`
+	CustomerName  string     `json:"customer_name"`
+}
+
+// Handle applies an order event. It is a sharedkafka.Handler; failures are
+// retried and then dead-lettered. Other order events are ignored.
+func (r *Recorder) Handle(ctx context.Context, msg kafkago.Message) error {
+	env, err := sharedevents.Decode(msg.Value)
+	if err != nil {
+		metrics.OrderEventsTotal.WithLabelValues("unknown", "failed").Inc()
+		return err
+	}
+	if env.OrderID == "" {
+		metrics.OrderEventsTotal.WithLabelValues(env.EventType, "failed").Inc()
+		return fmt.Errorf("%s event has no order_id", env.EventType)
+	}
+
+	recorded, err := r.apply(ctx, env)
+	if err != nil {
+		metrics.OrderEventsTotal.WithLabelValues(env.EventType, "failed").Inc()
+		logging.WithContext(ctx, r.logger).Warn("Failed to record order event",
+			zap.String("event_type", env.EventType),
+			zap.String("order_id", env.OrderID),
+			zap.Error(err),
+		)
+		return err
+	}
+	if !recorded {
+		metrics.OrderEventsTotal.WithLabelValues(env.EventType, "skipped").Inc()
+		return nil
+	}
+
+	metrics.OrderEventsTotal.WithLabelValues(env.EventType, "recorded").Inc()
+	logging.WithContext(ctx, r.logger).Debug("Order event recorded",
+		zap.String("event_type", env.EventType),
+		zap.String("order_id", env.OrderID),
+	)
+	return nil
+}
+
+// apply records an event and reports whether it affects purchases
+func (r *Recorder) apply(ctx context.Context, env *sharedevents.Envelope) (bool, error) {
+	switch env.EventType {
+	case "order.created":
+		var created sharedevents.OrderCreated
+		if err := env.DecodeData(&created); err != nil {
+			return false, err
+		}
+		order := &domain.PurchasedOrder{
+			OrderID:       env.OrderID,
+			UserID:        created.UserID,
+			OrderNumber:   created.OrderNumber,
+			CustomerEmail: created.CustomerEmail,
+			CustomerName:  created.CustomerName,
+		}
+		for _, item := range created.Items {
+			if item.ProductID != "" {
+				order.Items = append(order.Items, domain.PurchasedItem{ProductID: item.ProductID, Name: item.Name})
+			}
+		}
+		return true, r.repo.SaveOrder(ctx, order)
+
+	case "order.delivered":
+		var delivered orderDelivered
+		if err := json.Unmarshal(env.Data, &delivered); err != nil {
+			return false, fmt.Errorf("failed to decode %s data: %w", env.EventType, err)
+		}
+		return true, r.repo.MarkDelivered(ctx, &domain.PurchasedOrder{
+			OrderID:       env.OrderID,
+			UserID:        delivered.UserID,
+			OrderNumber:   delivered.OrderNumber,
+			CustomerEmail: delivered.CustomerEmail,
+			CustomerName:  delivered.CustomerName,
+			DeliveredAt:   delivered.DeliveredAt,
+		})
+
+	case "order.cancelled":
+		return true, r.repo.MarkCancelled(ctx, env.OrderID)
+
+	default:
+		return false, nil
+	}
+}
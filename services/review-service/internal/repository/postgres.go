@@ -0,0 +1,470 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	shareddb "github.com/ecommerce-platform/shared/go/db"
+	"github.com/ecommerce/review-service/internal/domain"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is the SQLSTATE of a duplicate key
+const uniqueViolation = "23505"
+
+const reviewColumns = `
+	id, product_id, order_id, user_id, author_name, rating, title, body, status,
+	moderated_by, moderated_at, rejection_reason, created_at, updated_at
+`
+
+type postgresRepository struct {
+	// db runs queries: tx within InTx, else pool
+	db   shareddb.Querier
+	pool *sql.DB
+	tx   *sql.Tx
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *sql.DB) ReviewRepository {
+	return &postgresRepository{db: db, pool: db}
+}
+
+// InTx runs fn in a transaction. Nested calls join the current transaction.
+func (r *postgresRepository) InTx(ctx context.Context, fn func(repo ReviewRepository) error) error {
+	return r.inTx(ctx, func(repo *postgresRepository) error { return fn(repo) })
+}
+
+func (r *postgresRepository) inTx(ctx context.Context, fn func(repo *postgresRepository) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+	return shareddb.WithTx(ctx, r.pool, nil, func(tx *sql.Tx) error {
+		return fn(&postgresRepository{db: tx, pool: r.pool, tx: tx})
+	})
+}
+
+// Ping checks that the database is reachable
+func (r *postgresRepository) Ping(ctx context.Context) error {
+	return shareddb.Check(ctx, r.pool)
+}
+
+// SaveOrder records a placed order and its items. Redelivered events leave
+// a delivered or cancelled order's status alone.
+func (r *postgresRepository) SaveOrder(ctx context.Context, order *domain.PurchasedOrder) error {
+	return r.inTx(ctx, func(repo *postgresRepository) error {
+		query := `
+			INSERT INTO purchased_orders (order_id, user_id, order_number, customer_email, customer_name, status)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (order_id) DO UPDATE SET
+				user_id = EXCLUDED.user_id,
+				order_number = EXCLUDED.order_number,
+				customer_email = EXCLUDED.customer_email,
+				customer_name = EXCLUDED.customer_name,
+				updated_at = CURRENT_TIMESTAMP
+		`
+		_, err := repo.db.ExecContext(ctx, query,
+			order.OrderID, order.UserID, order.OrderNumber, order.CustomerEmail, order.CustomerName, domain.OrderPlaced,
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range order.Items {
+			_, err := repo.db.ExecContext(ctx, `
+				INSERT INTO purchased_items (order_id, product_id, name)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (order_id, product_id) DO UPDATE SET name = EXCLUDED.name
+			`, order.OrderID, item.ProductID, item.Name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MarkDelivered records an order's delivery. Orders placed before the
+// service saw their order.created are recorded without items, so they
+// can't be reviewed.
+func (r *postgresRepository) MarkDelivered(ctx context.Context, order *domain.PurchasedOrder) error {
+	deliveredAt := time.Now().UTC()
+	if order.DeliveredAt != nil {
+		deliveredAt = order.DeliveredAt.UTC()
+	}
+
+	query := `
+		INSERT INTO purchased_orders (order_id, user_id, order_number, customer_email, customer_name, status, delivered_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (order_id) DO UPDATE SET
+			user_id = COALESCE(NULLIF(EXCLUDED.user_id, ''), purchased_orders.user_id),
+			order_number = COALESCE(NULLIF(EXCLUDED.order_number, ''), purchased_orders.order_number),
+			customer_email = COALESCE(NULLIF(EXCLUDED.customer_email, ''), purchased_orders.customer_email),
+			customer_name = COALESCE(NULLIF(EXCLUDED.customer_name, ''), purchased_orders.customer_name),
+			status = EXCLUDED.status,
+			delivered_at = COALESCE(purchased_orders.delivered_at, EXCLUDED.delivered_at),
+			updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		order.OrderID, order.UserID, order.OrderNumber, order.CustomerEmail, order.CustomerName,
+		domain.OrderDelivered, deliveredAt,
+	)
+	return err
+}
+
+// MarkCancelled records an order's cancellation
+func (r *postgresRepository) MarkCancelled(ctx context.Context, orderID string) error {
+	query := `
+		INSERT INTO purchased_orders (order_id, status)
+		VALUES ($1, $2)
+		ON CONFLICT (order_id) DO UPDATE SET status = EXCLUDED.status, updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := r.db.ExecContext(ctx, query, orderID, domain.OrderCancelled)
+	return err
+}
+
+// DeliveredPurchase returns the user's delivered order with just the given
+// product among its items
+func (r *postgresRepository) DeliveredPurchase(ctx context.Context, userID, orderID, productID string) (*domain.PurchasedOrder, error) {
+	query := `
+		SELECT o.order_id, o.user_id, o.order_number, o.customer_email, o.customer_name, o.delivered_at,
+		       i.product_id, i.name
+		FROM purchased_items i
+		JOIN purchased_orders o ON o.order_id = i.order_id
+		WHERE o.order_id = $1 AND o.user_id = $2 AND o.status = $3 AND i.product_id = $4
+	`
+
+	order := &domain.PurchasedOrder{Status: domain.OrderDelivered}
+	var item domain.PurchasedItem
+	err := r.db.QueryRowContext(ctx, query, orderID, userID, domain.OrderDelivered, productID).Scan(
+		&order.OrderID, &order.UserID, &order.OrderNumber, &order.CustomerEmail, &order.CustomerName,
+		&order.DeliveredAt, &item.ProductID, &item.Name,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotPurchased
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	order.Items = []domain.PurchasedItem{item}
+	return order, nil
+}
+
+// ReviewableItems returns the user's delivered items they haven't reviewed,
+// most recently delivered first
+func (r *postgresRepository) ReviewableItems(ctx context.Context, userID, orderID string) ([]ReviewableItem, error) {
+	query := `
+		SELECT o.order_id, o.order_number, i.product_id, i.name, o.delivered_at
+		FROM purchased_items i
+		JOIN purchased_orders o ON o.order_id = i.order_id
+		WHERE o.user_id = $1 AND o.status = $2 AND ($3 = '' OR o.order_id = $3)
+		  AND NOT EXISTS (
+			SELECT 1 FROM reviews rv WHERE rv.user_id = o.user_id AND rv.product_id = i.product_id
+		  )
+		ORDER BY o.delivered_at DESC, i.product_id
+		LIMIT 100
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, domain.OrderDelivered, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []ReviewableItem{}
+	for rows.Next() {
+		var item ReviewableItem
+		if err := rows.Scan(&item.OrderID, &item.OrderNumber, &item.ProductID, &item.Name, &item.DeliveredAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// DueReviewRequests returns delivered orders due a review request, oldest
+// delivery first
+func (r *postgresRepository) DueReviewRequests(ctx context.Context, deliveredBefore time.Time, limit int) ([]*domain.PurchasedOrder, error) {
+	query := `
+		SELECT order_id, user_id, order_number, customer_email, customer_name, delivered_at
+		FROM purchased_orders
+		WHERE status = $1 AND review_requested_at IS NULL AND delivered_at <= $2
+		ORDER BY delivered_at
+		LIMIT $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, domain.OrderDelivered, deliveredBefore.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []*domain.PurchasedOrder
+	for rows.Next() {
+		order := &domain.PurchasedOrder{Status: domain.OrderDelivered}
+		err := rows.Scan(&order.OrderID, &order.UserID, &order.OrderNumber,
+			&order.CustomerEmail, &order.CustomerName, &order.DeliveredAt)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, order := range orders {
+		items, err := r.ReviewableItems(ctx, order.UserID, order.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			order.Items = append(order.Items, domain.PurchasedItem{ProductID: item.ProductID, Name: item.Name})
+		}
+	}
+
+	return orders, nil
+}
+
+// MarkReviewRequested records that the customer was asked to review an order
+func (r *postgresRepository) MarkReviewRequested(ctx context.Context, orderID string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE purchased_orders SET review_requested_at = $1, updated_at = CURRENT_TIMESTAMP WHERE order_id = $2`,
+		at.UTC(), orderID,
+	)
+	return err
+}
+
+// Create stores a review
+func (r *postgresRepository) Create(ctx context.Context, review *domain.Review) error {
+	if review.ID == "" {
+		review.ID = uuid.New().String()
+	}
+
+	now := time.Now().UTC()
+	review.CreatedAt = now
+	review.UpdatedAt = now
+	review.VerifiedPurchase = true
+
+	query := `
+		INSERT INTO reviews (
+			id, product_id, order_id, user_id, author_name, rating, title, body, status, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		review.ID, review.ProductID, review.OrderID, review.UserID, review.Author,
+		review.Rating, review.Title, review.Body, review.Status, review.CreatedAt, review.UpdatedAt,
+	)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return domain.ErrAlreadyReviewed
+	}
+	return err
+}
+
+// GetByID retrieves a review by ID
+func (r *postgresRepository) GetByID(ctx context.Context, id string) (*domain.Review, error) {
+	return r.get(ctx, `SELECT `+reviewColumns+` FROM reviews WHERE id = $1`, id)
+}
+
+// GetForUpdate retrieves a review by ID, locking it until the transaction
+// ends
+func (r *postgresRepository) GetForUpdate(ctx context.Context, id string) (*domain.Review, error) {
+	return r.get(ctx, `SELECT `+reviewColumns+` FROM reviews WHERE id = $1 FOR UPDATE`, id)
+}
+
+func (r *postgresRepository) get(ctx context.Context, query, id string) (*domain.Review, error) {
+	review, err := scanReview(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	return review, err
+}
+
+// List retrieves up to limit reviews matching filter after a position
+func (r *postgresRepository) List(ctx context.Context, filter ListFilter, limit int, after *ListPosition) ([]*domain.Review, error) {
+	where, args := filter.where()
+
+	order, cmp := "DESC", "<"
+	if filter.OldestFirst {
+		order, cmp = "ASC", ">"
+	}
+	if after != nil {
+		args = append(args, after.CreatedAt, after.ID)
+		where = append(where, fmt.Sprintf("(created_at, id) %s ($%d, $%d)", cmp, len(args)-1, len(args)))
+	}
+	args = append(args, limit)
+
+	query := `SELECT ` + reviewColumns + ` FROM reviews` + whereClause(where) +
+		fmt.Sprintf(" ORDER BY created_at %s, id %s LIMIT $%d", order, order, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reviews []*domain.Review
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, rows.Err()
+}
+
+// Count returns the number of reviews matching filter
+func (r *postgresRepository) Count(ctx context.Context, filter ListFilter) (int64, error) {
+	where, args := filter.where()
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM reviews"+whereClause(where), args...).Scan(&count)
+	return count, err
+}
+
+// Moderate stores a review's moderation decision
+func (r *postgresRepository) Moderate(ctx context.Context, review *domain.Review) error {
+	review.UpdatedAt = time.Now().UTC()
+
+	query := `
+		UPDATE reviews
+		SET status = $1, moderated_by = $2, moderated_at = $3, rejection_reason = $4, updated_at = $5
+		WHERE id = $6
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		review.Status, review.ModeratedBy, review.ModeratedAt, review.RejectionReason, review.UpdatedAt, review.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+// RefreshRating recomputes a product's rating from its approved reviews
+func (r *postgresRepository) RefreshRating(ctx context.Context, productID string) error {
+	query := `
+		INSERT INTO product_ratings (
+			product_id, review_count, rating_sum, one_star, two_star, three_star, four_star, five_star, updated_at
+		)
+		SELECT $1,
+			COUNT(*),
+			COALESCE(SUM(rating), 0),
+			COUNT(*) FILTER (WHERE rating = 1),
+			COUNT(*) FILTER (WHERE rating = 2),
+			COUNT(*) FILTER (WHERE rating = 3),
+			COUNT(*) FILTER (WHERE rating = 4),
+			COUNT(*) FILTER (WHERE rating = 5),
+			CURRENT_TIMESTAMP
+		FROM reviews
+		WHERE product_id = $1 AND status = $2
+		ON CONFLICT (product_id) DO UPDATE SET
+			review_count = EXCLUDED.review_count,
+			rating_sum = EXCLUDED.rating_sum,
+			one_star = EXCLUDED.one_star,
+			two_star = EXCLUDED.two_star,
+			three_star = EXCLUDED.three_star,
+			four_star = EXCLUDED.four_star,
+			five_star = EXCLUDED.five_star,
+			updated_at = EXCLUDED.updated_at
+	`
+	_, err := r.db.ExecContext(ctx, query, productID, domain.StatusApproved)
+	return err
+}
+
+// GetRating returns a product's rating
+func (r *postgresRepository) GetRating(ctx context.Context, productID string) (*domain.ProductRating, error) {
+	query := `
+		SELECT review_count, rating_sum, one_star, two_star, three_star, four_star, five_star
+		FROM product_ratings WHERE product_id = $1
+	`
+
+	var count, sum int
+	stars := make([]int, 5)
+	err := r.db.QueryRowContext(ctx, query, productID).Scan(
+		&count, &sum, &stars[0], &stars[1], &stars[2], &stars[3], &stars[4],
+	)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	rating := &domain.ProductRating{
+		ProductID:    productID,
+		ReviewCount:  count,
+		Distribution: make(map[string]int, len(stars)),
+	}
+	if count > 0 {
+		// Rounded to two decimals, e.g. 4.33
+		rating.Average = math.Round(float64(sum)/float64(count)*100) / 100
+	}
+	for i, n := range stars {
+		rating.Distribution[fmt.Sprint(i+1)] = n
+	}
+	return rating, nil
+}
+
+// where returns the filter's conditions and their arguments
+func (f ListFilter) where() ([]string, []interface{}) {
+	var where []string
+	var args []interface{}
+	if f.ProductID != "" {
+		args = append(args, f.ProductID)
+		where = append(where, fmt.Sprintf("product_id = $%d", len(args)))
+	}
+	if f.UserID != "" {
+		args = append(args, f.UserID)
+		where = append(where, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if f.Status != "" {
+		args = append(args, f.Status)
+		where = append(where, fmt.Sprintf("status = $%d", len(args)))
+	}
+	return where, args
+}
+
+func whereClause(where []string) string {
+	if len(where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(where, " AND ")
+}
+
+// scanner is a *sql.Row or *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanReview(row scanner) (*domain.Review, error) {
+	review := &domain.Review{VerifiedPurchase: true}
+	err := row.Scan(
+		&review.ID, &review.ProductID, &review.OrderID, &review.UserID, &review.Author,
+		&review.Rating, &review.Title, &review.Body, &review.Status,
+		&review.ModeratedBy, &review.ModeratedAt, &review.RejectionReason,
+		&review.CreatedAt, &review.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return review, nil
+}
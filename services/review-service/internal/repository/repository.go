@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ecommerce/review-service/internal/domain"
+)
+
+// ReviewRepository defines review data operations
+type ReviewRepository interface {
+	// Purchases
+	// SaveOrder records a placed order and its items
+	SaveOrder(ctx context.Context, order *domain.PurchasedOrder) error
+	// MarkDelivered records an order's delivery, filling in the customer
+	// details order.created didn't carry
+	MarkDelivered(ctx context.Context, order *domain.PurchasedOrder) error
+	// MarkCancelled records an order's cancellation; its items can no
+	// longer be reviewed
+	MarkCancelled(ctx context.Context, orderID string) error
+	// DeliveredPurchase returns the user's delivered order with just the
+	// given product among its items, or domain.ErrNotPurchased
+	DeliveredPurchase(ctx context.Context, userID, orderID, productID string) (*domain.PurchasedOrder, error)
+	// ReviewableItems returns the user's delivered items they haven't
+	// reviewed, of one order or, with an empty orderID, of all
+	ReviewableItems(ctx context.Context, userID, orderID string) ([]ReviewableItem, error)
+	// DueReviewRequests returns up to limit orders delivered before
+	// deliveredBefore that haven't been asked to be reviewed, with their
+	// items not yet reviewed
+	DueReviewRequests(ctx context.Context, deliveredBefore time.Time, limit int) ([]*domain.PurchasedOrder, error)
+	// MarkReviewRequested records that the customer was asked to review an
+	// order
+	MarkReviewRequested(ctx context.Context, orderID string, at time.Time) error
+
+	// Reviews
+	// Create stores a review, or returns domain.ErrAlreadyReviewed
+	Create(ctx context.Context, review *domain.Review) error
+	GetByID(ctx context.Context, id string) (*domain.Review, error)
+	// GetForUpdate returns a review locked until the transaction ends
+	GetForUpdate(ctx context.Context, id string) (*domain.Review, error)
+	List(ctx context.Context, filter ListFilter, limit int, after *ListPosition) ([]*domain.Review, error)
+	Count(ctx context.Context, filter ListFilter) (int64, error)
+	// Moderate stores a review's moderation decision
+	Moderate(ctx context.Context, review *domain.Review) error
+
+	// Ratings
+	// RefreshRating recomputes a product's rating from its approved reviews
+	RefreshRating(ctx context.Context, productID string) error
+	// GetRating returns a product's rating; products without approved
+	// reviews have a zero rating
+	GetRating(ctx context.Context, productID string) (*domain.ProductRating, error)
+
+	// InTx runs fn with a repository whose operations share a transaction,
+	// committed when fn returns nil
+	InTx(ctx context.Context, fn func(repo ReviewRepository) error) error
+	// Ping checks that the database is reachable
+	Ping(ctx context.Context) error
+}
+
+// ListFilter selects the reviews to list
+type ListFilter struct {
+	ProductID string // empty for every product
+	UserID    string // empty for every customer
+	Status    domain.ReviewStatus
+	// OldestFirst lists in submission order, e.g. for the moderation
+	// queue; lists are newest first otherwise
+	OldestFirst bool
+}
+
+// ListPosition is the last review of a page, by creation time with ties
+// broken by ID
+type ListPosition struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+}
+
+// ReviewableItem is a delivered item the customer may review
+type ReviewableItem struct {
+	OrderID     string    `json:"order_id"`
+	OrderNumber string    `json:"order_number,omitempty"`
+	ProductID   string    `json:"product_id"`
+	Name        string    `json:"name,omitempty"`
+	DeliveredAt time.Time `json:"delivered_at"`
+}
@@ -0,0 +1,96 @@
+// Package requester asks customers to review their delivered orders, by
+// publishing review.requested events that notification-service emails
+package requester
+
+import (
+	"context"
+	"time"
+
+	"github.com/ecommerce/review-service/internal/events"
+	"github.com/ecommerce/review-service/internal/metrics"
+	"github.com/ecommerce/review-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// requestBatchSize bounds the orders processed per tick
+const requestBatchSize = 100
+
+// Requester periodically publishes review requests for orders delivered
+// longer ago than the delay. Each order is requested at most once.
+type Requester struct {
+	repo      repository.ReviewRepository
+	publisher events.Publisher
+	delay     time.Duration
+	interval  time.Duration
+	logger    *zap.Logger
+}
+
+// New creates a requester
+func New(repo repository.ReviewRepository, publisher events.Publisher, delay, interval time.Duration, logger *zap.Logger) *Requester {
+	return &Requester{
+		repo:      repo,
+		publisher: publisher,
+		delay:     delay,
+		interval:  interval,
+		logger:    logger,
+	}
+}
+
+// Start runs the requester until the context is cancelled
+func (r *Requester) Start(ctx context.Context) {
+	r.logger.Info("Starting review requester",
+		zap.Duration("delay", r.delay),
+		zap.Duration("interval", r.interval),
+	)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Stopping review requester")
+			return
+		case <-ticker.C:
+			r.request(ctx)
+		}
+	}
+}
+
+// request publishes the review requests that are due
+func (r *Requester) request(ctx context.Context) {
+	orders, err := r.repo.DueReviewRequests(ctx, time.Now().Add(-r.delay), requestBatchSize)
+	if err != nil {
+		r.logger.Error("Failed to load due review requests", zap.Error(err))
+		return
+	}
+
+	for _, order := range orders {
+		// Customers who already reviewed everything, or whose address is
+		// unknown, aren't asked
+		if len(order.Items) > 0 && order.CustomerEmail != "" {
+			if err := r.publisher.PublishReviewRequested(ctx, order); err != nil {
+				metrics.ReviewRequestsTotal.WithLabelValues("failed").Inc()
+				r.logger.Error("Failed to publish review request, will retry",
+					zap.String("order_id", order.OrderID),
+					zap.Error(err),
+				)
+				continue
+			}
+			metrics.ReviewRequestsTotal.WithLabelValues("requested").Inc()
+		} else {
+			metrics.ReviewRequestsTotal.WithLabelValues("skipped").Inc()
+		}
+
+		if err := r.repo.MarkReviewRequested(ctx, order.OrderID, time.Now()); err != nil {
+			r.logger.Error("Failed to mark review requested",
+				zap.String("order_id", order.OrderID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	if len(orders) > 0 {
+		r.logger.Info("Review requests processed", zap.Int("orders", len(orders)))
+	}
+}
@@ -0,0 +1,61 @@
+-- Orders as seen on order events, for verified-purchase checks and review
+-- requests
+CREATE TABLE IF NOT EXISTS purchased_orders (
+    order_id VARCHAR(255) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL DEFAULT '',
+    order_number VARCHAR(100) NOT NULL DEFAULT '',
+    customer_email VARCHAR(255) NOT NULL DEFAULT '',
+    customer_name VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'placed',
+    delivered_at TIMESTAMP,
+    review_requested_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchased_orders_user_id ON purchased_orders(user_id);
+CREATE INDEX IF NOT EXISTS idx_purchased_orders_review_due ON purchased_orders(delivered_at)
+    WHERE status = 'delivered' AND review_requested_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS purchased_items (
+    order_id VARCHAR(255) NOT NULL REFERENCES purchased_orders(order_id) ON DELETE CASCADE,
+    product_id VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    PRIMARY KEY (order_id, product_id)
+);
+
+-- Reviews, one per customer and product
+CREATE TABLE IF NOT EXISTS reviews (
+    id VARCHAR(36) PRIMARY KEY,
+    product_id VARCHAR(255) NOT NULL,
+    order_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    author_name VARCHAR(255) NOT NULL DEFAULT '',
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    title VARCHAR(200) NOT NULL DEFAULT '',
+    body TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    moderated_by VARCHAR(255) NOT NULL DEFAULT '',
+    moderated_at TIMESTAMP,
+    rejection_reason VARCHAR(500) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reviews_product_status ON reviews(product_id, status, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_reviews_status ON reviews(status, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_reviews_order_id ON reviews(order_id);
+
+-- Approved reviews aggregated per product, refreshed on moderation
+CREATE TABLE IF NOT EXISTS product_ratings (
+    product_id VARCHAR(255) PRIMARY KEY,
+    review_count INTEGER NOT NULL DEFAULT 0,
+    rating_sum INTEGER NOT NULL DEFAULT 0,
+    one_star INTEGER NOT NULL DEFAULT 0,
+    two_star INTEGER NOT NULL DEFAULT 0,
+    three_star INTEGER NOT NULL DEFAULT 0,
+    four_star INTEGER NOT NULL DEFAULT 0,
+    five_star INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
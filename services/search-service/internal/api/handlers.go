@@ -0,0 +1,191 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/search-service/internal/index"
+	"github.com/ecommerce/search-service/internal/metrics"
+	"github.com/ecommerce/search-service/internal/search"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Page sizes
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// maxQueryLength bounds the search text; longer queries are typically
+// pasted by mistake and expensive to match fuzzily
+const maxQueryLength = 200
+
+// Handler handles HTTP requests
+type Handler struct {
+	searcher *search.Searcher
+	index    *index.Client
+	logger   *zap.Logger
+}
+
+// NewHandler creates a new handler
+func NewHandler(searcher *search.Searcher, idx *index.Client, logger *zap.Logger) *Handler {
+	return &Handler{
+		searcher: searcher,
+		index:    idx,
+		logger:   logger,
+	}
+}
+
+// SearchProducts searches the storefront's products
+// GET /api/v1/search/products?q=&category=&brand=&min_price=&max_price=&in_stock=&attr.<name>=&sort=&page=&page_size=
+func (h *Handler) SearchProducts(c *gin.Context) {
+	query, invalid := parseQuery(c)
+	if err := invalid.Err(); err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	start := time.Now()
+	result, err := h.searcher.Search(c.Request.Context(), query)
+	metrics.QueryDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.QueriesTotal.WithLabelValues("failed").Inc()
+		logging.WithContext(c.Request.Context(), h.logger).Error("Search failed", zap.String("query", query.Text), zap.Error(err))
+		apperrors.Abort(c, apperrors.NewCode(apperrors.CodeServiceUnavailable, "Search is unavailable").WithCause(err))
+		return
+	}
+
+	if result.Total == 0 {
+		metrics.QueriesTotal.WithLabelValues("empty").Inc()
+	} else {
+		metrics.QueriesTotal.WithLabelValues("ok").Inc()
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// parseQuery reads a search from the query string. Filters with several
+// values may repeat the parameter or separate values with commas.
+func parseQuery(c *gin.Context) (search.Query, apperrors.ValidationErrors) {
+	var invalid apperrors.ValidationErrors
+	query := search.Query{
+		Text:       strings.TrimSpace(c.Query("q")),
+		Categories: listParam(c, "category"),
+		Brands:     listParam(c, "brand"),
+		Attributes: map[string][]string{},
+		Sort:       c.DefaultQuery("sort", search.SortRelevance),
+		Page:       1,
+		PageSize:   defaultPageSize,
+	}
+
+	if len(query.Text) > maxQueryLength {
+		invalid.Add("q", "max", "q must be at most 200 characters")
+	}
+
+	for _, bound := range []struct {
+		name  string
+		value **float64
+	}{{"min_price", &query.MinPrice}, {"max_price", &query.MaxPrice}} {
+		raw := c.Query(bound.name)
+		if raw == "" {
+			continue
+		}
+		price, err := strconv.ParseFloat(raw, 64)
+		if err != nil || price < 0 {
+			invalid.Add(bound.name, "gte", bound.name+" must be a number of at least 0")
+			continue
+		}
+		*bound.value = &price
+	}
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		invalid.Add("max_price", "gtefield", "max_price must be at least min_price")
+	}
+
+	if raw := c.Query("in_stock"); raw != "" {
+		inStock, err := strconv.ParseBool(raw)
+		if err != nil {
+			invalid.Add("in_stock", "boolean", "in_stock must be true or false")
+		}
+		query.InStockOnly = inStock
+	}
+
+	for param := range c.Request.URL.Query() {
+		name, ok := strings.CutPrefix(param, "attr.")
+		if values := listParam(c, param); ok && name != "" && len(values) > 0 {
+			query.Attributes[strings.ToLower(name)] = values
+		}
+	}
+
+	if !validSort(query.Sort) {
+		invalid.Add("sort", "oneof", "sort must be one of "+strings.Join(search.Sorts, ", "))
+	}
+
+	if raw := c.Query("page"); raw != "" {
+		page, err := strconv.Atoi(raw)
+		if err != nil || page < 1 {
+			invalid.Add("page", "min", "page must be at least 1")
+		} else {
+			query.Page = page
+		}
+	}
+	if raw := c.Query("page_size"); raw != "" {
+		size, err := strconv.Atoi(raw)
+		if err != nil || size < 1 || size > maxPageSize {
+			invalid.Add("page_size", "max", "page_size must be between 1 and 100")
+		} else {
+			query.PageSize = size
+		}
+	}
+	if query.Page*query.PageSize > search.MaxResults {
+		invalid.Add("page", "max", "only the first 10000 results can be paged through; narrow the search")
+	}
+
+	return query, invalid
+}
+
+// listParam returns a parameter's values, from repeated parameters and
+// comma-separated lists
+func listParam(c *gin.Context, name string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(name) {
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+func validSort(sort string) bool {
+	for _, s := range search.Sorts {
+		if sort == s {
+			return true
+		}
+	}
+	return false
+}
+
+// HealthCheck handler; unhealthy while the search cluster is unreachable
+func (h *Handler) HealthCheck(c *gin.Context) {
+	if err := h.index.Ping(c.Request.Context()); err != nil {
+		h.logger.Warn("Health check failed", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unhealthy",
+			"service": "search-service",
+			"version": "1.0.0",
+			"error":   "search cluster unavailable",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "healthy",
+		"service": "search-service",
+		"version": "1.0.0",
+	})
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"errors"
+	"os"
+
+	sharedconfig "github.com/ecommerce-platform/shared/go/config"
+	"github.com/ecommerce-platform/shared/go/mtls"
+	"github.com/ecommerce-platform/shared/go/secrets"
+)
+
+// Config holds application configuration
+type Config struct {
+	// Server
+	Port        int    `env:"PORT" flag:"port" default:"8086"`
+	Environment string `env:"ENVIRONMENT" default:"development"`
+
+	// Search engine: Elasticsearch or OpenSearch
+	SearchURL      string `env:"SEARCH_URL" default:"http://opensearch:9200"`
+	SearchUsername string `env:"SEARCH_USERNAME"`
+	SearchPassword string `env:"SEARCH_PASSWORD" secret:"true"`
+	// SearchIndex is the alias queries and updates go through; the index
+	// behind it is created at startup when missing
+	SearchIndex string `env:"SEARCH_INDEX" default:"storefront-products"`
+
+	// Kafka
+	KafkaBrokers       string `env:"KAFKA_BROKERS" default:"kafka:9092"`
+	KafkaGroupID       string `env:"KAFKA_GROUP_ID" default:"search-service"`
+	ProductEventsTopic string `env:"PRODUCT_EVENTS_TOPIC" default:"product-events"`
+	InventoryTopic     string `env:"INVENTORY_EVENTS_TOPIC" default:"inventory-events"`
+	// IndexerConcurrency is how many events are indexed at once; events of
+	// the same product are always indexed in order
+	IndexerConcurrency int `env:"INDEXER_CONCURRENCY" default:"4"`
+
+	// Ranking
+	// LowStockThreshold is the available quantity at or below which an
+	// in-stock product is ranked as low stock
+	LowStockThreshold int `env:"LOW_STOCK_THRESHOLD" default:"5"`
+	// OutOfStockPenalty multiplies the relevance of products that can't be
+	// bought, so they sink below comparable products in stock
+	OutOfStockPenalty float64 `env:"OUT_OF_STOCK_PENALTY" default:"0.2"`
+
+	// OpenTelemetry
+	OTLPEndpoint string `env:"OTLP_ENDPOINT" default:"otel-collector:4317"`
+
+	// Mutual TLS for the service's servers and its clients of other
+	// services
+	TLS mtls.Config
+}
+
+// Load loads configuration from flags and environment variables
+func Load() (*Config, error) {
+	resolver, err := secrets.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := sharedconfig.LoadWith(&cfg, sharedconfig.Options{Args: os.Args[1:], Secrets: resolver}); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate rejects settings the service can't run with
+func (c *Config) Validate() error {
+	if err := c.TLS.Validate(); err != nil {
+		return err
+	}
+	if c.IndexerConcurrency < 1 {
+		return errors.New("invalid INDEXER_CONCURRENCY: must be a positive integer")
+	}
+	if c.LowStockThreshold < 0 {
+		return errors.New("invalid LOW_STOCK_THRESHOLD: must not be negative")
+	}
+	if c.OutOfStockPenalty <= 0 || c.OutOfStockPenalty > 1 {
+		return errors.New("invalid OUT_OF_STOCK_PENALTY: must be greater than 0 and at most 1")
+	}
+	return nil
+}
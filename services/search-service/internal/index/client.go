@@ -0,0 +1,153 @@
+// Package index maintains the storefront product index in Elasticsearch or
+// OpenSearch, through the REST API both share
+package index
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Config configures a Client
+type Config struct {
+	// URL of the cluster, e.g. http://opensearch:9200
+	URL      string
+	Username string
+	Password string
+	// Index is the alias the product index is used through
+	Index string
+	// LowStockThreshold is the available quantity at or below which a
+	// product's availability is low_stock
+	LowStockThreshold int
+}
+
+// Client talks to the search cluster
+type Client struct {
+	cfg        Config
+	httpClient *http.Client
+}
+
+// Error is an error response from the cluster
+type Error struct {
+	StatusCode int
+	Type       string
+	Reason     string
+}
+
+func (e *Error) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("search cluster returned %d", e.StatusCode)
+	}
+	return fmt.Sprintf("search cluster returned %d: %s: %s", e.StatusCode, e.Type, e.Reason)
+}
+
+// NewClient creates a client for the cluster at cfg.URL
+func NewClient(cfg Config) *Client {
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+	return &Client{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Index returns the alias the client reads and writes
+func (c *Client) Index() string {
+	return c.cfg.Index
+}
+
+// Ping checks that the cluster is reachable and not red
+func (c *Client) Ping(ctx context.Context) error {
+	var health struct {
+		Status string `json:"status"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/_cluster/health", nil, &health); err != nil {
+		return err
+	}
+	if health.Status == "red" {
+		return fmt.Errorf("search cluster status is red")
+	}
+	return nil
+}
+
+// Search runs a search request against the index, decoding the response
+// into out
+func (c *Client) Search(ctx context.Context, request interface{}, out interface{}) error {
+	return c.do(ctx, http.MethodPost, "/"+url.PathEscape(c.cfg.Index)+"/_search", request, out)
+}
+
+// do sends a request with a JSON body, if any, and decodes a successful
+// response into out, if given. Error responses are returned as *Error.
+func (c *Client) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.cfg.URL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.cfg.Username != "" {
+		req.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("search cluster request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return errorFrom(resp)
+	}
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode search cluster response: %w", err)
+	}
+	return nil
+}
+
+// errorFrom reads the cluster's error body, {"error": {"type", "reason"}}
+func errorFrom(resp *http.Response) *Error {
+	var body struct {
+		Error json.RawMessage `json:"error"`
+	}
+	e := &Error{StatusCode: resp.StatusCode}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil || len(body.Error) == 0 {
+		return e
+	}
+
+	var detail struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal(body.Error, &detail); err == nil {
+		e.Type, e.Reason = detail.Type, detail.Reason
+	} else {
+		// Some errors are a plain string
+		_ = json.Unmarshal(body.Error, &e.Reason)
+	}
+	return e
+}
+
+// IsNotFound reports whether err is a 404 from the cluster
+func IsNotFound(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,137 @@
+package index
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Availability of a product
+const (
+	InStock    = "in_stock"
+	LowStock   = "low_stock"
+	OutOfStock = "out_of_stock"
+)
+
+// Product is a product's catalog fields in the index
+type Product struct {
+	ID          string      `json:"id"`
+	SKU         string      `json:"sku"`
+	Slug        string      `json:"slug"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Category    string      `json:"category"`
+	Brand       string      `json:"brand,omitempty"`
+	Tags        []string    `json:"tags"`
+	Attributes  []Attribute `json:"attributes"`
+	// Price is in major units of Currency
+	Price       float64  `json:"price"`
+	Currency    string   `json:"currency"`
+	Image       string   `json:"image,omitempty"`
+	Rating      *float64 `json:"rating"`
+	ReviewCount int      `json:"review_count"`
+	// ListedInStock is the catalog's in_stock flag, used until
+	// inventory-service tracks the product's stock
+	ListedInStock bool      `json:"listed_in_stock"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Attribute is a product specification, e.g. ram: 16GB
+type Attribute struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Document is a product as stored in the index: its catalog fields and its
+// availability
+type Document struct {
+	Product
+	// AvailableQuantity is nil until inventory-service tracks the product
+	AvailableQuantity *int   `json:"available_quantity"`
+	Availability      string `json:"availability"`
+	Purchasable       bool   `json:"purchasable"`
+}
+
+// mergeScript merges partial updates into a product's document and derives
+// its availability from its stock, when tracked, or else from its listing.
+// Catalog and inventory updates each set only their own fields, so they can
+// arrive in any order.
+const mergeScript = `
+ctx._source.putAll(params.fields);
+def available = ctx._source.available_quantity;
+if (available != null) {
+  ctx._source.availability = available <= 0 ? 'out_of_stock' : (available <= params.low_stock ? 'low_stock' : 'in_stock');
+} else {
+  ctx._source.availability = ctx._source.listed_in_stock == false ? 'out_of_stock' : 'in_stock';
+}
+ctx._source.purchasable = ctx._source.availability != 'out_of_stock';
+`
+
+// UpsertProduct indexes a product's catalog fields, keeping its availability
+func (c *Client) UpsertProduct(ctx context.Context, p Product) error {
+	return c.merge(ctx, p.ID, map[string]interface{}{
+		"id":              p.ID,
+		"sku":             p.SKU,
+		"slug":            p.Slug,
+		"name":            p.Name,
+		"description":     p.Description,
+		"category":        p.Category,
+		"brand":           p.Brand,
+		"tags":            p.Tags,
+		"attributes":      p.Attributes,
+		"price":           p.Price,
+		"currency":        p.Currency,
+		"image":           p.Image,
+		"rating":          p.Rating,
+		"review_count":    p.ReviewCount,
+		"listed_in_stock": p.ListedInStock,
+		"created_at":      p.CreatedAt,
+		"updated_at":      p.UpdatedAt,
+	})
+}
+
+// SetAvailableQuantity records how much of a product can be bought. Stock
+// may be tracked before the product is in the catalog; such documents have
+// no name and are left out of searches until it is.
+func (c *Client) SetAvailableQuantity(ctx context.Context, productID string, available int) error {
+	return c.merge(ctx, productID, map[string]interface{}{
+		"id":                 productID,
+		"available_quantity": available,
+	})
+}
+
+// DeleteProduct removes a product from the index; products already absent
+// are ignored
+func (c *Client) DeleteProduct(ctx context.Context, productID string) error {
+	err := c.do(ctx, http.MethodDelete, c.docPath("_doc", productID), nil, nil)
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// merge applies mergeScript with fields to a product's document, creating
+// it if needed
+func (c *Client) merge(ctx context.Context, productID string, fields map[string]interface{}) error {
+	body := map[string]interface{}{
+		"scripted_upsert": true,
+		"upsert":          map[string]interface{}{},
+		"script": map[string]interface{}{
+			"lang":   "painless",
+			"source": mergeScript,
+			"params": map[string]interface{}{
+				"fields":    fields,
+				"low_stock": c.cfg.LowStockThreshold,
+			},
+		},
+	}
+	// Concurrent catalog and inventory updates of a product conflict;
+	// the script is reapplied to the latest version
+	return c.do(ctx, http.MethodPost, c.docPath("_update", productID)+"?retry_on_conflict=5", body, nil)
+}
+
+func (c *Client) docPath(endpoint, productID string) string {
+	return "/" + url.PathEscape(c.cfg.Index) + "/" + endpoint + "/" + url.PathEscape(productID)
+}
@@ -0,0 +1,107 @@
+package index
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// version is the suffix of the index behind the alias. Bump it when the
+// mapping changes incompatibly: a new index is created, and products are
+// indexed into it as their events arrive or are replayed.
+const version = "v1"
+
+// textAnalyzer folds case and accents, so "cafe" finds "Café"
+const textAnalyzer = "product_text"
+
+// settings is the index's analysis configuration and mapping
+var settings = map[string]interface{}{
+	"settings": map[string]interface{}{
+		"number_of_shards": 1,
+		"analysis": map[string]interface{}{
+			"analyzer": map[string]interface{}{
+				textAnalyzer: map[string]interface{}{
+					"type":      "custom",
+					"tokenizer": "standard",
+					"filter":    []string{"lowercase", "asciifolding"},
+				},
+			},
+			"normalizer": map[string]interface{}{
+				"folded": map[string]interface{}{
+					"type":   "custom",
+					"filter": []string{"lowercase", "asciifolding"},
+				},
+			},
+		},
+	},
+	"mappings": map[string]interface{}{
+		// Fields the service doesn't know are stored but not indexed
+		"dynamic": false,
+		"properties": map[string]interface{}{
+			"id":          keyword(),
+			"sku":         map[string]interface{}{"type": "keyword", "normalizer": "folded"},
+			"slug":        keyword(),
+			"name":        keywordText(),
+			"description": text(),
+			"category":    keywordText(),
+			"brand":       keywordText(),
+			"tags":        keywordText(),
+			"attributes": map[string]interface{}{
+				"type": "nested",
+				"properties": map[string]interface{}{
+					"name":  keyword(),
+					"value": keywordText(),
+				},
+			},
+			"price":              map[string]interface{}{"type": "double"},
+			"currency":           keyword(),
+			"image":              map[string]interface{}{"type": "keyword", "index": false},
+			"rating":             map[string]interface{}{"type": "float"},
+			"review_count":       map[string]interface{}{"type": "integer"},
+			"listed_in_stock":    map[string]interface{}{"type": "boolean"},
+			"available_quantity": map[string]interface{}{"type": "integer"},
+			"availability":       keyword(),
+			"purchasable":        map[string]interface{}{"type": "boolean"},
+			"created_at":         map[string]interface{}{"type": "date"},
+			"updated_at":         map[string]interface{}{"type": "date"},
+		},
+	},
+}
+
+func keyword() map[string]interface{} {
+	return map[string]interface{}{"type": "keyword"}
+}
+
+func text() map[string]interface{} {
+	return map[string]interface{}{"type": "text", "analyzer": textAnalyzer}
+}
+
+// keywordText is an exact keyword, for filters and facets, with a text
+// subfield for matching words in it
+func keywordText() map[string]interface{} {
+	return map[string]interface{}{
+		"type":   "keyword",
+		"fields": map[string]interface{}{"text": text()},
+	}
+}
+
+// EnsureIndex creates the product index and its alias unless the alias
+// already exists
+func (c *Client) EnsureIndex(ctx context.Context) error {
+	alias := url.PathEscape(c.cfg.Index)
+	err := c.do(ctx, http.MethodHead, "/"+alias, nil, nil)
+	if err == nil {
+		return nil
+	}
+	if !IsNotFound(err) {
+		return err
+	}
+
+	body := map[string]interface{}{
+		"aliases": map[string]interface{}{c.cfg.Index: map[string]interface{}{}},
+	}
+	for k, v := range settings {
+		body[k] = v
+	}
+	return c.do(ctx, http.MethodPut, "/"+alias+"-"+version, body, nil)
+}
@@ -0,0 +1,139 @@
+package indexer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ecommerce/search-service/internal/index"
+)
+
+// catalogProduct is a product in catalog-service's product events
+type catalogProduct struct {
+	ID             string          `json:"id"`
+	SKU            string          `json:"sku"`
+	Slug           string          `json:"slug"`
+	Name           string          `json:"name"`
+	Description    string          `json:"description"`
+	Price          float64         `json:"price"`
+	Currency       string          `json:"currency"`
+	Category       string          `json:"category"`
+	Brand          *string         `json:"brand"`
+	Images         []string        `json:"images"`
+	Specifications *specifications `json:"specifications"`
+	Tags           []string        `json:"tags"`
+	InStock        *bool           `json:"in_stock"`
+	Rating         *float64        `json:"rating"`
+	ReviewCount    int             `json:"review_count"`
+	CreatedAt      timestamp       `json:"created_at"`
+	UpdatedAt      timestamp       `json:"updated_at"`
+}
+
+// specifications are a product's well-known specifications plus any others
+type specifications struct {
+	Processor  *string                `json:"processor"`
+	RAM        *string                `json:"ram"`
+	Storage    *string                `json:"storage"`
+	Display    *string                `json:"display"`
+	Graphics   *string                `json:"graphics"`
+	Battery    *string                `json:"battery"`
+	Additional map[string]interface{} `json:"additional"`
+}
+
+// document converts the product to its index fields
+func (p *catalogProduct) document(productID string) index.Product {
+	doc := index.Product{
+		ID:            productID,
+		SKU:           p.SKU,
+		Slug:          p.Slug,
+		Name:          p.Name,
+		Description:   p.Description,
+		Category:      p.Category,
+		Tags:          p.Tags,
+		Attributes:    p.Specifications.attributes(),
+		Price:         p.Price,
+		Currency:      strings.ToUpper(p.Currency),
+		Rating:        p.Rating,
+		ReviewCount:   p.ReviewCount,
+		ListedInStock: p.InStock == nil || *p.InStock,
+		CreatedAt:     time.Time(p.CreatedAt),
+		UpdatedAt:     time.Time(p.UpdatedAt),
+	}
+	if p.Brand != nil {
+		doc.Brand = *p.Brand
+	}
+	if doc.Currency == "" {
+		doc.Currency = "USD"
+	}
+	if len(p.Images) > 0 {
+		doc.Image = p.Images[0]
+	}
+	if doc.Tags == nil {
+		doc.Tags = []string{}
+	}
+	return doc
+}
+
+// attributes flattens the specifications into name/value pairs, so any
+// specification can be filtered on and faceted. Additional specifications
+// that aren't scalars are left out.
+func (s *specifications) attributes() []index.Attribute {
+	attrs := []index.Attribute{}
+	if s == nil {
+		return attrs
+	}
+
+	for _, known := range []struct {
+		name  string
+		value *string
+	}{
+		{"processor", s.Processor},
+		{"ram", s.RAM},
+		{"storage", s.Storage},
+		{"display", s.Display},
+		{"graphics", s.Graphics},
+		{"battery", s.Battery},
+	} {
+		if known.value != nil && *known.value != "" {
+			attrs = append(attrs, index.Attribute{Name: known.name, Value: *known.value})
+		}
+	}
+
+	names := make([]string, 0, len(s.Additional))
+	for name := range s.Additional {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch v := s.Additional[name].(type) {
+		case string:
+			if v != "" {
+				attrs = append(attrs, index.Attribute{Name: strings.ToLower(name), Value: v})
+			}
+		case float64, bool:
+			attrs = append(attrs, index.Attribute{Name: strings.ToLower(name), Value: fmt.Sprint(v)})
+		}
+	}
+	return attrs
+}
+
+// timestamp is a time in catalog events, which catalog-service's models
+// write without a time zone when they are UTC
+type timestamp time.Time
+
+func (t *timestamp) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil || s == "" {
+		// null and non-strings leave the time zero
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"} {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			*t = timestamp(parsed.UTC())
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid time %q", s)
+}
@@ -0,0 +1,147 @@
+// Package indexer keeps the product index up to date from catalog-service's
+// product events and inventory-service's stock events
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	sharedevents "github.com/ecommerce-platform/shared/go/events"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/search-service/internal/index"
+	"github.com/ecommerce/search-service/internal/metrics"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// errSkipped marks events that don't affect the index
+var errSkipped = errors.New("event doesn't affect the index")
+
+// Indexer applies events to the index
+type Indexer struct {
+	index  *index.Client
+	logger *zap.Logger
+}
+
+// New creates an indexer writing to idx
+func New(idx *index.Client, logger *zap.Logger) *Indexer {
+	return &Indexer{index: idx, logger: logger}
+}
+
+// Handle applies an event from either topic. It is a sharedkafka.Handler;
+// failures are retried and then dead-lettered.
+func (i *Indexer) Handle(ctx context.Context, msg kafkago.Message) error {
+	env, err := sharedevents.Decode(msg.Value)
+	if err != nil {
+		metrics.EventsIndexedTotal.WithLabelValues("unknown", "failed").Inc()
+		return err
+	}
+
+	err = i.apply(ctx, env)
+	switch {
+	case errors.Is(err, errSkipped):
+		metrics.EventsIndexedTotal.WithLabelValues(env.EventType, "skipped").Inc()
+		return nil
+	case err != nil:
+		metrics.EventsIndexedTotal.WithLabelValues(env.EventType, "failed").Inc()
+		logging.WithContext(ctx, i.logger).Warn("Failed to index event",
+			zap.String("event_type", env.EventType),
+			zap.String("product_id", env.ProductID),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	metrics.EventsIndexedTotal.WithLabelValues(env.EventType, "indexed").Inc()
+	logging.WithContext(ctx, i.logger).Debug("Event indexed",
+		zap.String("event_type", env.EventType),
+		zap.String("product_id", env.ProductID),
+	)
+	return nil
+}
+
+func (i *Indexer) apply(ctx context.Context, env *sharedevents.Envelope) error {
+	switch env.EventType {
+	case "product.created", "product.updated":
+		var product catalogProduct
+		if err := json.Unmarshal(env.Data, &product); err != nil {
+			return fmt.Errorf("failed to decode %s data: %w", env.EventType, err)
+		}
+		productID := productIDOf(env, product.ID)
+		if productID == "" {
+			return fmt.Errorf("%s event has no product_id", env.EventType)
+		}
+		return i.index.UpsertProduct(ctx, product.document(productID))
+
+	case "product.deleted":
+		var deleted struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(env.Data, &deleted); err != nil {
+			return fmt.Errorf("failed to decode %s data: %w", env.EventType, err)
+		}
+		productID := productIDOf(env, deleted.ID)
+		if productID == "" {
+			return fmt.Errorf("%s event has no product_id", env.EventType)
+		}
+		return i.index.DeleteProduct(ctx, productID)
+	}
+
+	// The index holds catalog-service's products, which are the default
+	// tenant's; other tenants' stock of the same product IDs isn't theirs
+	if env.TenantID != "" && env.TenantID != sharedauth.DefaultTenant {
+		return errSkipped
+	}
+	productID, available, err := availabilityOf(env)
+	if err != nil {
+		return err
+	}
+	if productID == "" {
+		return fmt.Errorf("%s event has no product_id", env.EventType)
+	}
+	return i.index.SetAvailableQuantity(ctx, productID, available)
+}
+
+// availabilityOf reads the product and its available quantity from a stock
+// event; other events are errSkipped
+func availabilityOf(env *sharedevents.Envelope) (string, int, error) {
+	switch env.EventType {
+	case "inventory.created":
+		var e sharedevents.InventoryCreated
+		err := env.DecodeData(&e)
+		return e.ProductID, e.AvailableQuantity, err
+	case "inventory.updated":
+		var e sharedevents.InventoryUpdated
+		err := env.DecodeData(&e)
+		return e.ProductID, e.AvailableQuantity, err
+	case "inventory.reserved":
+		var e sharedevents.InventoryReserved
+		err := env.DecodeData(&e)
+		return e.ProductID, e.AvailableQuantity, err
+	case "inventory.reservation_released":
+		var e sharedevents.ReservationReleased
+		err := env.DecodeData(&e)
+		return e.ProductID, e.AvailableQuantity, err
+	case "inventory.reservation_expired":
+		var e sharedevents.ReservationExpired
+		err := env.DecodeData(&e)
+		return e.ProductID, e.AvailableQuantity, err
+	case "inventory.adjusted":
+		var e sharedevents.InventoryAdjusted
+		err := env.DecodeData(&e)
+		return e.ProductID, e.AvailableQuantity, err
+	default:
+		return "", 0, errSkipped
+	}
+}
+
+// productIDOf prefers the envelope's product_id, which partitions the topic
+func productIDOf(env *sharedevents.Envelope, fallback string) string {
+	if env.ProductID != "" {
+		return env.ProductID
+	}
+	return fallback
+}
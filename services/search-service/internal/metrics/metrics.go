@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// EventsIndexedTotal counts consumed events by outcome: indexed,
+	// skipped (event types that don't affect search) or failed
+	EventsIndexedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "search_events_indexed_total",
+		Help: "Product and inventory events applied to the search index, per event type and result",
+	}, []string{"event_type", "result"})
+
+	// QueriesTotal counts storefront searches by result: ok, empty (no
+	// products matched) or failed
+	QueriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "search_queries_total",
+		Help: "Storefront search queries per result",
+	}, []string{"result"})
+
+	// QueryDuration measures searches as seen by the service, including
+	// the cluster round trip
+	QueryDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "search_query_duration_seconds",
+		Help:    "Time spent answering storefront search queries",
+		Buckets: prometheus.DefBuckets,
+	})
+)
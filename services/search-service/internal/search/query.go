@@ -0,0 +1,231 @@
+// Package search answers storefront product searches from the index: typo
+// tolerant matching, filters with facet counts, and ranking that puts
+// products customers can buy first
+package search
+
+import (
+	"github.com/ecommerce/search-service/internal/index"
+)
+
+// Sort orders
+const (
+	SortRelevance = "relevance"
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+	SortRating    = "rating"
+	SortNewest    = "newest"
+)
+
+// Sorts are the supported sort orders
+var Sorts = []string{SortRelevance, SortPriceAsc, SortPriceDesc, SortRating, SortNewest}
+
+// MaxResults bounds page*page_size: the cluster's default
+// index.max_result_window
+const MaxResults = 10000
+
+// Query is a storefront search
+type Query struct {
+	// Text is matched against names, brands, categories, tags and
+	// descriptions, forgiving typos; empty browses every product
+	Text       string
+	Categories []string
+	Brands     []string
+	MinPrice   *float64
+	MaxPrice   *float64
+	// InStockOnly leaves out products that can't be bought
+	InStockOnly bool
+	// Attributes filters on specifications, e.g. ram: [16GB, 32GB]
+	Attributes map[string][]string
+	Sort       string
+	Page       int
+	PageSize   int
+}
+
+// priceRanges are the price facet's buckets, in major units
+var priceRanges = []PriceRange{
+	{To: ptr(25)},
+	{From: ptr(25), To: ptr(50)},
+	{From: ptr(50), To: ptr(100)},
+	{From: ptr(100), To: ptr(250)},
+	{From: ptr(250), To: ptr(500)},
+	{From: ptr(500)},
+}
+
+func ptr(f float64) *float64 {
+	return &f
+}
+
+type object = map[string]interface{}
+
+// request builds the search request. Facet filters are applied after the
+// aggregations, as a post_filter, and each facet is counted with the other
+// facets' filters, so a selected brand doesn't hide the other brands'
+// counts.
+func (q Query) request(outOfStockPenalty float64) object {
+	filters := q.facetFilters()
+
+	req := object{
+		"query":            q.scoredQuery(outOfStockPenalty),
+		"sort":             q.sort(),
+		"from":             (q.Page - 1) * q.PageSize,
+		"size":             q.PageSize,
+		"track_total_hits": true,
+		"aggs":             facetAggs(filters),
+	}
+	if all := allFilters(filters, ""); len(all) > 0 {
+		req["post_filter"] = object{"bool": object{"filter": all}}
+	}
+	return req
+}
+
+// scoredQuery matches the text in catalog products, ranking products that
+// are out of stock lower
+func (q Query) scoredQuery(outOfStockPenalty float64) object {
+	match := object{"match_all": object{}}
+	if q.Text != "" {
+		match = object{"bool": object{
+			"should": []object{
+				{"multi_match": object{
+					"query":          q.Text,
+					"fields":         []string{"name.text^4", "brand.text^3", "category.text^2", "tags.text^2", "description"},
+					"type":           "best_fields",
+					"operator":       "and",
+					"fuzziness":      "AUTO",
+					"prefix_length":  1,
+					"max_expansions": 50,
+				}},
+				// Partly typed names, e.g. "macbo"
+				{"match_phrase_prefix": object{"name.text": object{"query": q.Text, "boost": 2}}},
+				{"term": object{"sku": object{"value": q.Text, "boost": 10}}},
+			},
+			"minimum_should_match": 1,
+		}}
+	}
+
+	return object{"function_score": object{
+		"query": object{"bool": object{
+			"must": match,
+			// Documents of products only known from stock events have no name
+			"filter": []object{{"exists": object{"field": "name"}}},
+		}},
+		"functions": []object{{
+			"filter": object{"term": object{"availability": index.OutOfStock}},
+			"weight": outOfStockPenalty,
+		}},
+		"score_mode": "multiply",
+		"boost_mode": "multiply",
+	}}
+}
+
+// sort orders results; products that can be bought come first in every
+// order but relevance, where their score already ranks them higher
+func (q Query) sort() []object {
+	purchasableFirst := object{"purchasable": object{"order": "desc"}}
+	tieBreak := object{"id": object{"order": "asc"}}
+
+	switch q.Sort {
+	case SortPriceAsc:
+		return []object{purchasableFirst, {"price": object{"order": "asc"}}, tieBreak}
+	case SortPriceDesc:
+		return []object{purchasableFirst, {"price": object{"order": "desc"}}, tieBreak}
+	case SortRating:
+		return []object{purchasableFirst, {"rating": object{"order": "desc", "missing": "_last"}}, {"review_count": object{"order": "desc"}}, tieBreak}
+	case SortNewest:
+		return []object{purchasableFirst, {"created_at": object{"order": "desc"}}, tieBreak}
+	default:
+		return []object{{"_score": object{"order": "desc"}}, {"review_count": object{"order": "desc"}}, tieBreak}
+	}
+}
+
+// Facet names
+const (
+	facetCategory     = "category"
+	facetBrand        = "brand"
+	facetAvailability = "availability"
+	facetPrice        = "price"
+	facetAttributes   = "attributes"
+)
+
+// facetFilters returns the query's filters by the facet they narrow
+func (q Query) facetFilters() map[string][]object {
+	filters := map[string][]object{}
+	if len(q.Categories) > 0 {
+		filters[facetCategory] = []object{{"terms": object{"category": q.Categories}}}
+	}
+	if len(q.Brands) > 0 {
+		filters[facetBrand] = []object{{"terms": object{"brand": q.Brands}}}
+	}
+	if q.InStockOnly {
+		filters[facetAvailability] = []object{{"term": object{"purchasable": true}}}
+	}
+	if q.MinPrice != nil || q.MaxPrice != nil {
+		bounds := object{}
+		if q.MinPrice != nil {
+			bounds["gte"] = *q.MinPrice
+		}
+		if q.MaxPrice != nil {
+			bounds["lte"] = *q.MaxPrice
+		}
+		filters[facetPrice] = []object{{"range": object{"price": bounds}}}
+	}
+	for name, values := range q.Attributes {
+		filters[facetAttributes] = append(filters[facetAttributes], object{"nested": object{
+			"path": "attributes",
+			"query": object{"bool": object{"filter": []object{
+				{"term": object{"attributes.name": name}},
+				{"terms": object{"attributes.value": values}},
+			}}},
+		}})
+	}
+	return filters
+}
+
+// allFilters returns every filter but those of the except facet
+func allFilters(filters map[string][]object, except string) []object {
+	all := []object{}
+	for facet, clauses := range filters {
+		if facet != except {
+			all = append(all, clauses...)
+		}
+	}
+	return all
+}
+
+// facetAggs counts each facet's values among the products matching every
+// other facet's filters. Attribute counts ignore all attribute filters.
+func facetAggs(filters map[string][]object) object {
+	ranges := make([]object, 0, len(priceRanges))
+	for _, r := range priceRanges {
+		bucket := object{}
+		if r.From != nil {
+			bucket["from"] = *r.From
+		}
+		if r.To != nil {
+			bucket["to"] = *r.To
+		}
+		ranges = append(ranges, bucket)
+	}
+
+	counts := map[string]object{
+		facetCategory:     {"terms": object{"field": "category", "size": 20}},
+		facetBrand:        {"terms": object{"field": "brand", "size": 20}},
+		facetAvailability: {"terms": object{"field": "availability", "size": 3}},
+		facetPrice:        {"range": object{"field": "price", "ranges": ranges}},
+		facetAttributes: {
+			"nested": object{"path": "attributes"},
+			"aggs": object{"names": object{
+				"terms": object{"field": "attributes.name", "size": 20},
+				"aggs":  object{"values": object{"terms": object{"field": "attributes.value", "size": 10}}},
+			}},
+		},
+	}
+
+	aggs := object{}
+	for facet, count := range counts {
+		aggs[facet] = object{
+			"filter": object{"bool": object{"filter": allFilters(filters, facet)}},
+			"aggs":   object{"values": count},
+		}
+	}
+	return aggs
+}
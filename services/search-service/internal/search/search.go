@@ -0,0 +1,182 @@
+package search
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce/search-service/internal/index"
+)
+
+// Result is a page of search results with facet counts
+type Result struct {
+	Items    []Item `json:"items"`
+	Total    int64  `json:"total"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"page_size"`
+	Facets   Facets `json:"facets"`
+}
+
+// Item is a product in search results
+type Item struct {
+	ID          string      `json:"id"`
+	SKU         string      `json:"sku"`
+	Slug        string      `json:"slug"`
+	Name        string      `json:"name"`
+	Brand       string      `json:"brand,omitempty"`
+	Category    string      `json:"category"`
+	Image       string      `json:"image,omitempty"`
+	Price       money.Money `json:"price"`
+	Rating      *float64    `json:"rating,omitempty"`
+	ReviewCount int         `json:"review_count"`
+	// Availability is in_stock, low_stock or out_of_stock
+	Availability      string `json:"availability"`
+	AvailableQuantity *int   `json:"available_quantity,omitempty"`
+}
+
+// Facets counts the products matching the search by each filterable value
+type Facets struct {
+	Category     []Bucket            `json:"category"`
+	Brand        []Bucket            `json:"brand"`
+	Availability []Bucket            `json:"availability"`
+	Price        []PriceRange        `json:"price"`
+	Attributes   map[string][]Bucket `json:"attributes"`
+}
+
+// Bucket is a facet value and how many products have it
+type Bucket struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// PriceRange is a price facet bucket: From inclusive, To exclusive, in
+// major units
+type PriceRange struct {
+	From  *float64 `json:"from,omitempty"`
+	To    *float64 `json:"to,omitempty"`
+	Count int64    `json:"count"`
+}
+
+// Searcher runs searches
+type Searcher struct {
+	index             *index.Client
+	outOfStockPenalty float64
+}
+
+// NewSearcher creates a searcher. Products that are out of stock have
+// their relevance multiplied by outOfStockPenalty.
+func NewSearcher(idx *index.Client, outOfStockPenalty float64) *Searcher {
+	return &Searcher{index: idx, outOfStockPenalty: outOfStockPenalty}
+}
+
+// Search runs q, whose page and page size must be valid
+func (s *Searcher) Search(ctx context.Context, q Query) (*Result, error) {
+	var resp searchResponse
+	if err := s.index.Search(ctx, q.request(s.outOfStockPenalty), &resp); err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+
+	result := &Result{
+		Items:    make([]Item, 0, len(resp.Hits.Hits)),
+		Total:    resp.Hits.Total.Value,
+		Page:     q.Page,
+		PageSize: q.PageSize,
+		Facets:   resp.Aggregations.facets(),
+	}
+	for _, hit := range resp.Hits.Hits {
+		result.Items = append(result.Items, itemFrom(hit.Source))
+	}
+	return result, nil
+}
+
+func itemFrom(doc index.Document) Item {
+	return Item{
+		ID:                doc.ID,
+		SKU:               doc.SKU,
+		Slug:              doc.Slug,
+		Name:              doc.Name,
+		Brand:             doc.Brand,
+		Category:          doc.Category,
+		Image:             doc.Image,
+		Price:             money.FromMajor(doc.Price, doc.Currency),
+		Rating:            doc.Rating,
+		ReviewCount:       doc.ReviewCount,
+		Availability:      doc.Availability,
+		AvailableQuantity: doc.AvailableQuantity,
+	}
+}
+
+// searchResponse is the part of the cluster's search response used
+type searchResponse struct {
+	Hits struct {
+		Total struct {
+			Value int64 `json:"value"`
+		} `json:"total"`
+		Hits []struct {
+			Source index.Document `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+	Aggregations aggregations `json:"aggregations"`
+}
+
+// aggregations are the facet counts requested by facetAggs; each facet is
+// a filter aggregation around the actual counts in values
+type aggregations struct {
+	Category     struct{ Values terms }        `json:"category"`
+	Brand        struct{ Values terms }        `json:"brand"`
+	Availability struct{ Values terms }        `json:"availability"`
+	Price        struct{ Values rangeBuckets } `json:"price"`
+	Attributes   struct {
+		Values struct {
+			Names struct {
+				Buckets []struct {
+					Key    string `json:"key"`
+					Values terms  `json:"values"`
+				} `json:"buckets"`
+			} `json:"names"`
+		}
+	} `json:"attributes"`
+}
+
+type terms struct {
+	Buckets []struct {
+		Key      string `json:"key"`
+		DocCount int64  `json:"doc_count"`
+	} `json:"buckets"`
+}
+
+type rangeBuckets struct {
+	Buckets []struct {
+		From     *float64 `json:"from"`
+		To       *float64 `json:"to"`
+		DocCount int64    `json:"doc_count"`
+	} `json:"buckets"`
+}
+
+func (a aggregations) facets() Facets {
+	facets := Facets{
+		Category:     a.Category.Values.buckets(),
+		Brand:        a.Brand.Values.buckets(),
+		Availability: a.Availability.Values.buckets(),
+		Price:        []PriceRange{},
+		Attributes:   map[string][]Bucket{},
+	}
+	for _, b := range a.Price.Values.Buckets {
+		if b.DocCount > 0 {
+			facets.Price = append(facets.Price, PriceRange{From: b.From, To: b.To, Count: b.DocCount})
+		}
+	}
+	for _, name := range a.Attributes.Values.Names.Buckets {
+		facets.Attributes[name.Key] = name.Values.buckets()
+	}
+	return facets
+}
+
+// buckets returns the terms most frequent first, as the cluster orders them
+func (t terms) buckets() []Bucket {
+	buckets := make([]Bucket, 0, len(t.Buckets))
+	for _, b := range t.Buckets {
+		buckets = append(buckets, Bucket{Value: b.Key, Count: b.DocCount})
+	}
+	return buckets
+}
@@ -0,0 +1,418 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	sharedevents "github.com/ecommerce-platform/shared/go/events"
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce-platform/shared/go/pagination"
+	"github.com/ecommerce/subscription-service/internal/domain"
+	"github.com/ecommerce/subscription-service/internal/events"
+	"github.com/ecommerce/subscription-service/internal/inventory"
+	"github.com/ecommerce/subscription-service/internal/metrics"
+	"github.com/ecommerce/subscription-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// recentCycles is how many of a subscription's latest cycles it is
+// returned with
+const recentCycles = 12
+
+// Handler serves the subscription API
+type Handler struct {
+	repo      repository.SubscriptionRepository
+	inventory *inventory.Client
+	publisher events.Publisher
+	logger    *zap.Logger
+}
+
+// NewHandler creates a new handler
+func NewHandler(repo repository.SubscriptionRepository, inventory *inventory.Client, publisher events.Publisher, logger *zap.Logger) *Handler {
+	return &Handler{
+		repo:      repo,
+		inventory: inventory,
+		publisher: publisher,
+		logger:    logger,
+	}
+}
+
+// ItemRequest is a product delivered every cycle
+type ItemRequest struct {
+	ProductID string       `json:"product_id" binding:"required,max=255"`
+	SKU       string       `json:"sku" binding:"max=100"`
+	Name      string       `json:"name" binding:"max=255"`
+	Quantity  int          `json:"quantity" binding:"required,min=1,max=1000"`
+	Price     *money.Money `json:"price" binding:"required"`
+}
+
+// AddressRequest is a shipping or billing address
+type AddressRequest struct {
+	FullName     string `json:"full_name" binding:"required,max=200"`
+	AddressLine1 string `json:"address_line1" binding:"required,max=255"`
+	AddressLine2 string `json:"address_line2" binding:"max=255"`
+	City         string `json:"city" binding:"required,max=100"`
+	State        string `json:"state" binding:"required,max=100"`
+	PostalCode   string `json:"postal_code" binding:"required,max=20"`
+	Country      string `json:"country" binding:"required,max=100"`
+	Phone        string `json:"phone" binding:"required,max=50"`
+}
+
+// IntervalRequest is how often orders are placed, e.g. every 2 weeks
+type IntervalRequest struct {
+	Unit  string `json:"unit" binding:"required,oneof=day week month"`
+	Count int    `json:"count" binding:"required,min=1,max=365"`
+}
+
+// CreateRequest subscribes the caller to a recurring order. The first
+// order is placed at StartAt, or one interval from now, the customer
+// having just ordered; the billing address defaults to the shipping one.
+type CreateRequest struct {
+	Items           []ItemRequest   `json:"items" binding:"required,min=1,max=50,dive"`
+	ShippingAddress *AddressRequest `json:"shipping_address" binding:"required"`
+	BillingAddress  *AddressRequest `json:"billing_address"`
+	PaymentMethod   string          `json:"payment_method" binding:"required,oneof=credit_card debit_card paypal stripe"`
+	Interval        IntervalRequest `json:"interval" binding:"required"`
+	StartAt         *time.Time      `json:"start_at"`
+}
+
+// PauseRequest pauses a subscription until the customer resumes it, or
+// until ResumeAt if set
+type PauseRequest struct {
+	ResumeAt *time.Time `json:"resume_at"`
+}
+
+// CancelRequest cancels a subscription, saying why if the customer wants
+type CancelRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// Create subscribes the caller to a recurring order
+// POST /api/v1/subscriptions
+func (h *Handler) Create(c *gin.Context) {
+	var req CreateRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	now := time.Now().UTC()
+	if err := validateCreate(req, now); err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+	interval := domain.Interval{Unit: domain.IntervalUnit(req.Interval.Unit), Count: req.Interval.Count}
+	nextOrderAt := interval.After(now)
+	if req.StartAt != nil {
+		nextOrderAt = req.StartAt.UTC()
+	}
+
+	items := make([]domain.Item, len(req.Items))
+	for i, item := range req.Items {
+		items[i] = domain.Item{
+			ProductID: item.ProductID,
+			SKU:       item.SKU,
+			Name:      item.Name,
+			Quantity:  item.Quantity,
+			Price:     *item.Price,
+		}
+	}
+	billing := req.ShippingAddress
+	if req.BillingAddress != nil {
+		billing = req.BillingAddress
+	}
+
+	sub := &domain.Subscription{
+		ID:              uuid.New().String(),
+		UserID:          c.GetString(sharedauth.ContextUserID),
+		CustomerEmail:   c.GetString(sharedauth.ContextUserEmail),
+		Items:           items,
+		ShippingAddress: domain.Address(*req.ShippingAddress),
+		BillingAddress:  domain.Address(*billing),
+		PaymentMethod:   req.PaymentMethod,
+		Interval:        interval,
+		Status:          domain.StatusActive,
+		NextOrderAt:     nextOrderAt,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	ctx := c.Request.Context()
+	if err := h.repo.Create(ctx, sub); err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Subscription"))
+		return
+	}
+
+	metrics.SubscriptionChangesTotal.WithLabelValues("create").Inc()
+	h.publish(ctx, sub, &sharedevents.SubscriptionCreated{SubscriptionUpdate: events.Update(sub)})
+
+	c.JSON(http.StatusCreated, sub)
+}
+
+// List lists a page of the caller's subscriptions, newest first
+// GET /api/v1/subscriptions?limit=&cursor=
+func (h *Handler) List(c *gin.Context) {
+	params := pagination.FromQuery(c.Request.URL.Query())
+
+	var after *repository.Position
+	if params.Cursor != "" {
+		after = &repository.Position{}
+		if err := pagination.DecodeCursor(params.Cursor, after); err != nil {
+			apperrors.Abort(c, apperrors.NewBadRequest("Invalid cursor"))
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString(sharedauth.ContextUserID)
+	subs, err := h.repo.List(ctx, userID, params.Limit+1, after)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Subscription"))
+		return
+	}
+
+	total, err := h.repo.Count(ctx, userID)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Subscription"))
+		return
+	}
+
+	page, err := pagination.NewPage(subs, params.Limit, total, func(sub *domain.Subscription) interface{} {
+		return repository.Position{CreatedAt: sub.CreatedAt, ID: sub.ID}
+	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list subscriptions"))
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// Get returns one of the caller's subscriptions and its latest cycles
+// GET /api/v1/subscriptions/:id
+func (h *Handler) Get(c *gin.Context) {
+	ctx := c.Request.Context()
+	sub, err := h.repo.Get(ctx, c.Param("id"))
+	if errors.Is(err, domain.ErrNotFound) || (err == nil && sub.UserID != c.GetString(sharedauth.ContextUserID)) {
+		apperrors.Abort(c, apperrors.NewNotFound("Subscription"))
+		return
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Subscription"))
+		return
+	}
+
+	cycles, err := h.repo.Cycles(ctx, sub.ID, recentCycles)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Cycle"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subscription": sub, "cycles": cycles})
+}
+
+// Pause stops a subscription ordering until the customer resumes it, or
+// until resume_at. Stock reserved for the next order is released.
+// POST /api/v1/subscriptions/:id/pause
+func (h *Handler) Pause(c *gin.Context) {
+	var req PauseRequest
+	if c.Request.ContentLength != 0 && !apperrors.BindJSON(c, &req) {
+		return
+	}
+	now := time.Now().UTC()
+	var resumeAt *time.Time
+	if req.ResumeAt != nil {
+		if !req.ResumeAt.After(now) {
+			apperrors.Abort(c, apperrors.NewValidation(apperrors.FieldError{
+				Field: "resume_at", Rule: "gt", Message: "resume_at must be in the future",
+			}))
+			return
+		}
+		at := req.ResumeAt.UTC()
+		resumeAt = &at
+	}
+
+	var released []string
+	sub, ok := h.change(c, domain.ActionPause, func(sub *domain.Subscription, cycle *domain.Cycle) error {
+		released = sub.Pause(cycle, resumeAt, now)
+		return nil
+	})
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	h.release(ctx, released)
+	h.publish(ctx, sub, &sharedevents.SubscriptionPaused{SubscriptionUpdate: events.Update(sub), ResumeAt: resumeAt})
+
+	c.JSON(http.StatusOK, sub)
+}
+
+// Resume restarts a paused subscription. If its next order's time passed
+// while paused, the order is skipped and the next cycle's placed instead.
+// POST /api/v1/subscriptions/:id/resume
+func (h *Handler) Resume(c *gin.Context) {
+	now := time.Now().UTC()
+	sub, ok := h.change(c, domain.ActionResume, func(sub *domain.Subscription, cycle *domain.Cycle) error {
+		sub.Resume(cycle, now)
+		return nil
+	})
+	if !ok {
+		return
+	}
+
+	h.publish(c.Request.Context(), sub, &sharedevents.SubscriptionResumed{SubscriptionUpdate: events.Update(sub)})
+
+	c.JSON(http.StatusOK, sub)
+}
+
+// Skip skips a subscription's next order, releasing its reserved stock;
+// orders placed already can't be skipped
+// POST /api/v1/subscriptions/:id/skip
+func (h *Handler) Skip(c *gin.Context) {
+	now := time.Now().UTC()
+	var skippedAt time.Time
+	var released []string
+	sub, ok := h.change(c, domain.ActionSkip, func(sub *domain.Subscription, cycle *domain.Cycle) error {
+		skippedAt = sub.NextOrderAt
+		var err error
+		released, err = sub.Skip(cycle, now)
+		return err
+	})
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	h.release(ctx, released)
+	h.publish(ctx, sub, &sharedevents.SubscriptionSkipped{SubscriptionUpdate: events.Update(sub), SkippedOrderAt: skippedAt})
+
+	c.JSON(http.StatusOK, sub)
+}
+
+// Cancel ends a subscription. Its next order is skipped, its reserved
+// stock released, unless the order was placed already and is being paid.
+// POST /api/v1/subscriptions/:id/cancel
+func (h *Handler) Cancel(c *gin.Context) {
+	var req CancelRequest
+	if c.Request.ContentLength != 0 && !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	now := time.Now().UTC()
+	var released []string
+	sub, ok := h.change(c, domain.ActionCancel, func(sub *domain.Subscription, cycle *domain.Cycle) error {
+		released = sub.Cancel(cycle, req.Reason, now)
+		return nil
+	})
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	h.release(ctx, released)
+	h.publish(ctx, sub, &sharedevents.SubscriptionCancelled{SubscriptionUpdate: events.Update(sub), Reason: req.Reason})
+
+	c.JSON(http.StatusOK, sub)
+}
+
+// HealthCheck reports whether the database is reachable
+func (h *Handler) HealthCheck(c *gin.Context) {
+	if err := h.repo.Ping(c.Request.Context()); err != nil {
+		h.logger.Warn("Health check failed", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unhealthy",
+			"service": "subscription-service",
+			"version": "1.0.0",
+			"error":   "database unavailable",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "healthy",
+		"service": "subscription-service",
+		"version": "1.0.0",
+	})
+}
+
+// change applies an action to the caller's subscription of the :id path
+// parameter and its next cycle, aborting with 404 for other customers'
+// subscriptions and 409 for actions its status or next order doesn't
+// allow
+func (h *Handler) change(c *gin.Context, action domain.Action, apply func(*domain.Subscription, *domain.Cycle) error) (*domain.Subscription, bool) {
+	userID := c.GetString(sharedauth.ContextUserID)
+	sub, _, err := h.repo.Update(c.Request.Context(), c.Param("id"), func(sub *domain.Subscription, cycle *domain.Cycle) error {
+		if sub.UserID != userID {
+			return domain.ErrNotFound
+		}
+		if err := sub.CheckTransition(action); err != nil {
+			return err
+		}
+		return apply(sub, cycle)
+	})
+
+	var transition *domain.TransitionError
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		apperrors.Abort(c, apperrors.NewNotFound("Subscription"))
+		return nil, false
+	case errors.As(err, &transition), errors.Is(err, domain.ErrOrderPlaced):
+		apperrors.Abort(c, apperrors.NewConflict(err.Error()))
+		return nil, false
+	case err != nil:
+		apperrors.Abort(c, apperrors.FromSQL(err, "Subscription"))
+		return nil, false
+	}
+
+	metrics.SubscriptionChangesTotal.WithLabelValues(string(action)).Inc()
+	return sub, true
+}
+
+// release releases reservations of a cycle given up, logging failures;
+// their stock comes back when they expire anyway
+func (h *Handler) release(ctx context.Context, reservationIDs []string) {
+	for _, id := range reservationIDs {
+		if err := h.inventory.Release(ctx, id); err != nil {
+			h.logger.Warn("Failed to release reservation", zap.String("reservation_id", id), zap.Error(err))
+		}
+	}
+}
+
+// publish publishes a subscription's event. Events are notifications, so
+// failures, which the publisher logs, don't fail the change.
+func (h *Handler) publish(ctx context.Context, sub *domain.Subscription, event sharedevents.Payload) {
+	_ = h.publisher.Publish(ctx, sub, event)
+}
+
+// validateCreate rejects prices that are negative, not ISO 4217 or in
+// different currencies, products listed twice and start times in the past
+func validateCreate(req CreateRequest, now time.Time) *apperrors.AppError {
+	var invalid apperrors.ValidationErrors
+	seen := make(map[string]bool, len(req.Items))
+	currency := req.Items[0].Price.Currency
+	for i, item := range req.Items {
+		field := "items[" + strconv.Itoa(i) + "]"
+		if item.Price.IsNegative() {
+			invalid.Add(field+".price.minor_units", "min", "price must not be negative")
+		}
+		if len(item.Price.Currency) != 3 {
+			invalid.Add(field+".price.currency", "len", "currency must be an ISO 4217 code, e.g. USD")
+		} else if item.Price.Currency != currency {
+			invalid.Add(field+".price.currency", "eqfield", "items must be priced in the same currency")
+		}
+		if seen[item.ProductID] {
+			invalid.Add(field+".product_id", "unique", "each product may be listed once")
+		}
+		seen[item.ProductID] = true
+	}
+	if req.StartAt != nil && !req.StartAt.After(now) {
+		invalid.Add("start_at", "gt", "start_at must be in the future")
+	}
+	return invalid.Err()
+}
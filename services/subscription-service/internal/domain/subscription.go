@@ -0,0 +1,318 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/money"
+)
+
+// Status is where a subscription stands
+type Status string
+
+const (
+	// StatusActive subscriptions place an order every cycle
+	StatusActive Status = "active"
+	// StatusPaused subscriptions place no orders until resumed, by the
+	// customer or at their resume_at
+	StatusPaused Status = "paused"
+	// StatusPastDue subscriptions' last order failed to pay; it is retried
+	// until paid or out of attempts, which cancels the subscription
+	StatusPastDue Status = "past_due"
+	// StatusCancelled subscriptions are over
+	StatusCancelled Status = "cancelled"
+)
+
+// IntervalUnit is the unit of a subscription's interval
+type IntervalUnit string
+
+const (
+	IntervalDay   IntervalUnit = "day"
+	IntervalWeek  IntervalUnit = "week"
+	IntervalMonth IntervalUnit = "month"
+)
+
+// Interval is how often a subscription places an order, e.g. every 2 weeks
+type Interval struct {
+	Unit  IntervalUnit `json:"unit"`
+	Count int          `json:"count"`
+}
+
+// After is the time one interval after t. Months are calendar months, so
+// an order on the 31st is followed by one on the 1st after a 30 day month.
+func (i Interval) After(t time.Time) time.Time {
+	switch i.Unit {
+	case IntervalDay:
+		return t.AddDate(0, 0, i.Count)
+	case IntervalWeek:
+		return t.AddDate(0, 0, 7*i.Count)
+	default:
+		return t.AddDate(0, i.Count, 0)
+	}
+}
+
+// Item is a product delivered every cycle
+type Item struct {
+	ProductID string `json:"product_id"`
+	SKU       string `json:"sku"`
+	Name      string `json:"name"`
+	Quantity  int    `json:"quantity"`
+	// Price is what the item was subscribed at; pricing-service reprices
+	// each cycle's order when order-service uses it
+	Price money.Money `json:"price"`
+}
+
+// Address is where orders are delivered or billed to
+type Address struct {
+	FullName     string `json:"full_name"`
+	AddressLine1 string `json:"address_line1"`
+	AddressLine2 string `json:"address_line2,omitempty"`
+	City         string `json:"city"`
+	State        string `json:"state"`
+	PostalCode   string `json:"postal_code"`
+	Country      string `json:"country"`
+	Phone        string `json:"phone"`
+}
+
+// Subscription is a customer's recurring order
+type Subscription struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+	// CustomerEmail is where the subscription's notifications are sent
+	CustomerEmail   string   `json:"customer_email"`
+	Items           []Item   `json:"items"`
+	ShippingAddress Address  `json:"shipping_address"`
+	BillingAddress  Address  `json:"billing_address"`
+	PaymentMethod   string   `json:"payment_method"`
+	Interval        Interval `json:"interval"`
+	Status          Status   `json:"status"`
+	// NextOrderAt is when the next cycle's order is placed
+	NextOrderAt time.Time `json:"next_order_at"`
+	// ResumeAt is when a paused subscription resumes by itself; nil waits
+	// for the customer
+	ResumeAt     *time.Time `json:"resume_at,omitempty"`
+	PausedAt     *time.Time `json:"paused_at,omitempty"`
+	CancelledAt  *time.Time `json:"cancelled_at,omitempty"`
+	CancelReason string     `json:"cancel_reason,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Total is what a cycle's items cost at their subscribed prices
+func (s *Subscription) Total() (money.Money, error) {
+	amounts := make([]money.Money, len(s.Items))
+	for i, item := range s.Items {
+		amounts[i] = item.Price.Mul(int64(item.Quantity))
+	}
+	return money.Sum(amounts...)
+}
+
+// NextAfter is the first cycle after the one at NextOrderAt that is later
+// than t, so subscriptions resumed after a long pause don't order for the
+// cycles they missed
+func (s *Subscription) NextAfter(t time.Time) time.Time {
+	next := s.Interval.After(s.NextOrderAt)
+	for !next.After(t) {
+		next = s.Interval.After(next)
+	}
+	return next
+}
+
+// CheckTransition returns why the subscription can't be paused, resumed,
+// skipped or cancelled, or nil
+func (s *Subscription) CheckTransition(action Action) error {
+	allowed := false
+	switch action {
+	case ActionPause, ActionSkip:
+		allowed = s.Status == StatusActive
+	case ActionResume:
+		allowed = s.Status == StatusPaused
+	case ActionCancel:
+		allowed = s.Status != StatusCancelled
+	}
+	if !allowed {
+		return &TransitionError{Action: action, Status: s.Status}
+	}
+	return nil
+}
+
+// Action is a change customers make to their subscription
+type Action string
+
+const (
+	ActionPause  Action = "pause"
+	ActionResume Action = "resume"
+	ActionSkip   Action = "skip"
+	ActionCancel Action = "cancel"
+)
+
+// TransitionError is an action the subscription's status doesn't allow,
+// e.g. resuming an active subscription
+type TransitionError struct {
+	Action Action
+	Status Status
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("a subscription can't %s while %s", e.Action, e.Status)
+}
+
+// Pause stops the subscription ordering until resumed, by the customer or
+// at resumeAt if set. The next cycle keeps its time but gives up its
+// reserved stock, whose reservation IDs are returned for releasing.
+func (s *Subscription) Pause(cycle *Cycle, resumeAt *time.Time, now time.Time) []string {
+	s.Status = StatusPaused
+	s.PausedAt = &now
+	s.ResumeAt = resumeAt
+	return cycle.unreserve()
+}
+
+// Resume restarts a paused subscription. A next order whose time passed
+// while paused moves to the next cycle after now, skipping the cycle
+// scheduled for it.
+func (s *Subscription) Resume(cycle *Cycle, now time.Time) {
+	s.Status = StatusActive
+	s.PausedAt, s.ResumeAt = nil, nil
+	if s.NextOrderAt.After(now) {
+		return
+	}
+	s.NextOrderAt = s.NextAfter(now)
+	if cycle != nil && !cycle.Placed() {
+		cycle.Status = CycleSkipped
+	}
+}
+
+// Skip moves the next order to the following cycle, skipping the cycle
+// scheduled for it. It returns the skipped cycle's reservation IDs for
+// releasing, or ErrOrderPlaced if its order was placed already.
+func (s *Subscription) Skip(cycle *Cycle, now time.Time) ([]string, error) {
+	if cycle != nil && cycle.Placed() {
+		return nil, ErrOrderPlaced
+	}
+	s.NextOrderAt = s.NextAfter(now)
+	if cycle == nil {
+		return nil, nil
+	}
+	released := cycle.unreserve()
+	cycle.Status = CycleSkipped
+	return released, nil
+}
+
+// Cancel ends the subscription. A next order not placed yet is skipped and
+// one waiting for a payment retry fails; an order being paid goes ahead.
+// It returns the reservation IDs of the skipped cycle for releasing.
+func (s *Subscription) Cancel(cycle *Cycle, reason string, now time.Time) []string {
+	s.Status = StatusCancelled
+	s.CancelledAt = &now
+	s.CancelReason = reason
+	s.PausedAt, s.ResumeAt = nil, nil
+	if cycle == nil {
+		return nil
+	}
+
+	switch cycle.Status {
+	case CycleScheduled:
+		released := cycle.unreserve()
+		cycle.Status = CycleSkipped
+		return released
+	case CycleRetrying:
+		cycle.Status = CycleFailed
+		cycle.NextAttemptAt = nil
+	}
+	return nil
+}
+
+// CycleStatus is where a cycle's order stands
+type CycleStatus string
+
+const (
+	// CycleScheduled cycles wait for their time; their stock may be
+	// reserved already
+	CycleScheduled CycleStatus = "scheduled"
+	// CycleOrdered cycles placed their order and wait for its payment
+	CycleOrdered CycleStatus = "ordered"
+	// CycleRetrying cycles' payment failed; a new order is placed at
+	// NextAttemptAt
+	CycleRetrying CycleStatus = "retrying"
+	// CyclePaid cycles' order was paid
+	CyclePaid CycleStatus = "paid"
+	// CycleFailed cycles ran out of payment attempts
+	CycleFailed CycleStatus = "failed"
+	// CycleSkipped cycles were skipped by the customer
+	CycleSkipped CycleStatus = "skipped"
+)
+
+// Cycle is one of a subscription's orders, from the stock reserved ahead
+// of it until it is paid
+type Cycle struct {
+	ID             string      `json:"id"`
+	SubscriptionID string      `json:"subscription_id"`
+	ScheduledAt    time.Time   `json:"scheduled_at"`
+	Status         CycleStatus `json:"status"`
+	// OrderID is the current attempt's order; every payment retry places a
+	// new one, as order-service doesn't take payment again for a failed
+	// order
+	OrderID string `json:"order_id"`
+	// ReservationIDs hold the cycle's stock, reserved under its first
+	// order ID
+	ReservationIDs []string   `json:"reservation_ids,omitempty"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Placed is whether the cycle's order was placed already, so it can no
+// longer be skipped
+func (c *Cycle) Placed() bool {
+	return c.Status != CycleScheduled && c.Status != CycleSkipped
+}
+
+// unreserve gives up the stock reserved for a cycle not placed yet and
+// returns the reservation IDs to release. Placed cycles' reservations are
+// their order's, and nil cycles have none.
+func (c *Cycle) unreserve() []string {
+	if c == nil || c.Status != CycleScheduled {
+		return nil
+	}
+	released := c.ReservationIDs
+	c.ReservationIDs = nil
+	return released
+}
+
+// Dunning is how failed payments are retried
+type Dunning struct {
+	// MaxAttempts includes the first; a cycle that fails them all cancels
+	// its subscription
+	MaxAttempts int
+	// RetryIntervals are the waits before each retry; the last is repeated
+	RetryIntervals []time.Duration
+}
+
+// NextAttempt is when a cycle that failed its attempts so far is retried,
+// or nil when it is out of attempts
+func (d Dunning) NextAttempt(attempts int, failedAt time.Time) *time.Time {
+	if attempts >= d.MaxAttempts || len(d.RetryIntervals) == 0 {
+		return nil
+	}
+	i := attempts - 1
+	if i >= len(d.RetryIntervals) {
+		i = len(d.RetryIntervals) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	at := failedAt.Add(d.RetryIntervals[i])
+	return &at
+}
+
+// Common errors
+var (
+	ErrNotFound      = errors.New("subscription not found")
+	ErrCycleNotFound = errors.New("cycle not found")
+	// ErrOrderPlaced is returned when skipping a cycle whose order was
+	// placed already
+	ErrOrderPlaced = errors.New("the next order was placed already")
+)
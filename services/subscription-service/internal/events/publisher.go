@@ -0,0 +1,98 @@
+package events
+
+import (
+	"context"
+
+	sharedevents "github.com/ecommerce-platform/shared/go/events"
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	"github.com/ecommerce/subscription-service/internal/domain"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// Publisher publishes subscription.* events, which notification-service
+// emails to customers
+type Publisher interface {
+	// Publish publishes event, built from sub with Update
+	Publish(ctx context.Context, sub *domain.Subscription, event sharedevents.Payload) error
+	Close() error
+}
+
+type kafkaPublisher struct {
+	producer *sharedkafka.Producer
+	logger   *zap.Logger
+}
+
+// NewKafkaPublisher creates a publisher. Events are keyed by subscription,
+// so a subscription's events are handled in order.
+func NewKafkaPublisher(brokers []string, topic string, logger *zap.Logger) Publisher {
+	producer := sharedkafka.NewProducer(sharedkafka.ProducerConfig{
+		Brokers: brokers,
+		Topic:   topic,
+	}, logger)
+
+	return &kafkaPublisher{
+		producer: producer,
+		logger:   logger,
+	}
+}
+
+// Update is the data every subscription event carries, for sub as it
+// stands
+func Update(sub *domain.Subscription) sharedevents.SubscriptionUpdate {
+	update := sharedevents.SubscriptionUpdate{
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		CustomerEmail:  sub.CustomerEmail,
+		CustomerName:   sub.ShippingAddress.FullName,
+		Status:         string(sub.Status),
+		Interval:       sharedevents.SubscriptionInterval{Unit: string(sub.Interval.Unit), Count: sub.Interval.Count},
+		Items:          make([]sharedevents.SubscriptionItem, len(sub.Items)),
+	}
+	if sub.Status != domain.StatusCancelled {
+		next := sub.NextOrderAt
+		update.NextOrderAt = &next
+	}
+	for i, item := range sub.Items {
+		update.Items[i] = sharedevents.SubscriptionItem{
+			ProductID: item.ProductID,
+			SKU:       item.SKU,
+			Name:      item.Name,
+			Quantity:  item.Quantity,
+			Price:     item.Price,
+		}
+	}
+	// Items are validated to share a currency when subscribing
+	update.Total, _ = sub.Total()
+	return update
+}
+
+// Publish publishes a subscription's event
+func (p *kafkaPublisher) Publish(ctx context.Context, sub *domain.Subscription, event sharedevents.Payload) error {
+	env := sharedevents.Envelope{UserID: sub.UserID}
+	if failed, ok := event.(*sharedevents.SubscriptionPaymentFailed); ok {
+		env.OrderID = failed.OrderID
+	}
+	data, err := sharedevents.Marshal(env, event)
+	if err != nil {
+		p.logger.Error("Failed to marshal event", zap.Error(err))
+		return err
+	}
+
+	message := kafka.Message{
+		Key:   []byte(sub.ID),
+		Value: data,
+	}
+
+	if err := p.producer.Publish(ctx, message); err != nil {
+		p.logger.Error("Failed to publish event", zap.Error(err), zap.String("event_type", event.EventType()))
+		return err
+	}
+
+	p.logger.Debug("Event published", zap.String("event_type", event.EventType()), zap.String("subscription_id", sub.ID))
+	return nil
+}
+
+func (p *kafkaPublisher) Close() error {
+	return p.producer.Close()
+}
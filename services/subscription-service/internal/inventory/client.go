@@ -0,0 +1,135 @@
+// Package inventory holds subscriptions' stock in inventory-service ahead
+// of their orders
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/logging"
+)
+
+// RejectedError is inventory-service refusing a reservation, e.g. because
+// the product is out of stock or has no stock record
+type RejectedError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *RejectedError) Error() string {
+	return "inventory rejected the reservation: " + e.Message
+}
+
+// Reservation is stock held for an order
+type Reservation struct {
+	ID        string    `json:"reservation_id"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Client reserves and releases stock in inventory-service
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a client of the inventory-service at baseURL, called
+// through transport
+func NewClient(baseURL string, transport http.RoundTripper) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second, Transport: transport},
+	}
+}
+
+// Reserve holds quantity of a product for an order for ttl. Reserving is
+// idempotent per order and product, so retries get the order's
+// reservation back; order-service's checkout of the order does too.
+// Reservations inventory-service refuses return a *RejectedError.
+func (c *Client) Reserve(ctx context.Context, productID, orderID, customerID string, quantity int, ttl time.Duration) (*Reservation, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"quantity":           quantity,
+		"order_id":           orderID,
+		"customer_id":        customerID,
+		"expires_in_minutes": int(ttl.Minutes()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, "/api/v1/inventory/product/"+url.PathEscape(productID)+"/reserve", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, failure(resp, "reserve product "+productID)
+	}
+
+	var reservation Reservation
+	if err := json.NewDecoder(resp.Body).Decode(&reservation); err != nil {
+		return nil, fmt.Errorf("failed to decode reservation: %w", err)
+	}
+	return &reservation, nil
+}
+
+// Release returns a reservation's stock; releasing a reservation that is
+// gone already succeeds
+func (c *Client) Release(ctx context.Context, reservationID string) error {
+	resp, err := c.do(ctx, http.MethodDelete, "/api/v1/reservations/"+url.PathEscape(reservationID), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	}
+	return failure(resp, "release reservation "+reservationID)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if correlationID := logging.CorrelationID(ctx); correlationID != "" {
+		req.Header.Set(logging.CorrelationIDHeader, correlationID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call inventory-service: %w", err)
+	}
+	return resp, nil
+}
+
+// failure reads inventory-service's error response: refusals of the
+// request are *RejectedError, anything else a plain error
+func failure(resp *http.Response, action string) error {
+	var body struct {
+		Error string `json:"error"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+
+	switch resp.StatusCode {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity:
+		message := body.Error
+		if message == "" {
+			message = "Failed to " + action
+		}
+		return &RejectedError{StatusCode: resp.StatusCode, Message: message}
+	}
+	return fmt.Errorf("inventory-service returned status %d to %s: %s", resp.StatusCode, action, body.Error)
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// SubscriptionChangesTotal counts customers' changes to their
+	// subscriptions, by action: create, pause, resume, skip or cancel
+	SubscriptionChangesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "subscription_changes_total",
+		Help: "Subscription changes per action",
+	}, []string{"action"})
+
+	// ReservationsTotal counts cycles' advance stock reservations by
+	// result: reserved, rejected (e.g. out of stock; the order is placed
+	// without) or failed
+	ReservationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "subscription_reservations_total",
+		Help: "Advance stock reservations of subscription cycles per result",
+	}, []string{"result"})
+
+	// OrdersTotal counts cycles' order attempts by result: paid, pending
+	// (order-service is retrying the checkout), declined, rejected (the
+	// order couldn't be placed) or failed
+	OrdersTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "subscription_orders_total",
+		Help: "Subscription order attempts per result",
+	}, []string{"result"})
+
+	// DunningTotal counts cycles whose payment failed by outcome: retrying
+	// or cancelled, out of attempts
+	DunningTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "subscription_dunning_total",
+		Help: "Failed subscription payments per outcome",
+	}, []string{"outcome"})
+
+	// OrderEventsTotal counts consumed order events by outcome: applied,
+	// skipped (event types or orders of no cycle) or failed
+	OrderEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "subscription_order_events_total",
+		Help: "Order events applied to subscription cycles, per event type and result",
+	}, []string{"event_type", "result"})
+)
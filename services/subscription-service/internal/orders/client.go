@@ -0,0 +1,241 @@
+// Package orders places and pays subscriptions' orders through
+// order-service's internal routes
+package orders
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/logging"
+)
+
+// ServiceID is who the client signs its requests as; order-service knows
+// it and its key
+const ServiceID = "subscription-service"
+
+// Order statuses the client acts on
+const (
+	StatusPending        = "pending"
+	StatusPaymentPending = "payment_pending"
+	StatusPaymentFailed  = "payment_failed"
+	StatusCancelled      = "cancelled"
+)
+
+// RejectedError is order-service refusing an order or its payment, e.g.
+// because a product is out of stock or the card was declined; Message says
+// why
+type RejectedError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *RejectedError) Error() string {
+	return "order-service rejected the order: " + e.Message
+}
+
+// Item is an order line, as order-service takes it
+type Item struct {
+	ProductID string  `json:"productId"`
+	SKU       string  `json:"sku"`
+	Name      string  `json:"name"`
+	Price     float64 `json:"price"`
+	Quantity  int     `json:"quantity"`
+}
+
+// Address is a shipping or billing address, as order-service takes it
+type Address struct {
+	FullName     string `json:"fullName"`
+	AddressLine1 string `json:"addressLine1"`
+	AddressLine2 string `json:"addressLine2,omitempty"`
+	City         string `json:"city"`
+	State        string `json:"state"`
+	PostalCode   string `json:"postalCode"`
+	Country      string `json:"country"`
+	Phone        string `json:"phone"`
+}
+
+// CreateRequest is an order placed for a customer. OrderID makes it
+// idempotent, and InventoryReserved skips order-service's stock check for
+// stock reserved under OrderID already.
+type CreateRequest struct {
+	OrderID           string  `json:"orderId"`
+	UserID            string  `json:"userId"`
+	Items             []Item  `json:"items"`
+	ShippingAddress   Address `json:"shippingAddress"`
+	BillingAddress    Address `json:"billingAddress"`
+	PaymentMethod     string  `json:"paymentMethod"`
+	CustomerNotes     string  `json:"customerNotes,omitempty"`
+	InventoryReserved bool    `json:"inventoryReserved"`
+}
+
+// Order is what the client reads of order-service's orders
+type Order struct {
+	ID          string `json:"id"`
+	OrderNumber string `json:"orderNumber"`
+	Status      string `json:"status"`
+}
+
+// PaymentResult is how checking out an order went: paid, still being
+// retried by order-service, or declined with Error
+type PaymentResult struct {
+	Paid    bool
+	Pending bool
+	Error   string
+}
+
+// Client calls order-service's internal routes, signing each request with
+// the service's key
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewClient creates a client of the order-service at baseURL, called
+// through transport
+func NewClient(baseURL, apiKey string, transport http.RoundTripper) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second, Transport: transport},
+	}
+}
+
+// Create places an order, or returns the order placed with its ID
+// already. Orders order-service refuses return a *RejectedError.
+func (c *Client) Create(ctx context.Context, req CreateRequest) (*Order, error) {
+	var body struct {
+		Order Order `json:"order"`
+	}
+	status, err := c.do(ctx, http.MethodPost, "/api/v1/internal/orders", req, &body)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK && status != http.StatusCreated {
+		return nil, fmt.Errorf("order-service returned status %d creating order %s", status, req.OrderID)
+	}
+	return &body.Order, nil
+}
+
+// Get returns an order
+func (c *Client) Get(ctx context.Context, orderID string) (*Order, error) {
+	var body struct {
+		Order Order `json:"order"`
+	}
+	status, err := c.do(ctx, http.MethodGet, "/api/v1/internal/orders/"+url.PathEscape(orderID), nil, &body)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("order-service returned status %d getting order %s", status, orderID)
+	}
+	return &body.Order, nil
+}
+
+// Pay checks out an order. Declined payments are a result, not an error;
+// orders that can't be paid any more return a *RejectedError with status
+// 409.
+func (c *Client) Pay(ctx context.Context, orderID string) (*PaymentResult, error) {
+	var body struct {
+		Success bool   `json:"success"`
+		Pending bool   `json:"pending"`
+		Error   string `json:"error"`
+	}
+	status, err := c.do(ctx, http.MethodPost, "/api/v1/internal/orders/"+url.PathEscape(orderID)+"/payment", nil, &body)
+	var rejected *RejectedError
+	if errors.As(err, &rejected) && rejected.StatusCode == http.StatusBadRequest {
+		return &PaymentResult{Error: rejected.Message}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch status {
+	case http.StatusOK:
+		return &PaymentResult{Paid: body.Success}, nil
+	case http.StatusAccepted:
+		return &PaymentResult{Pending: true}, nil
+	}
+	return nil, fmt.Errorf("order-service returned status %d paying order %s", status, orderID)
+}
+
+// do sends a signed request and decodes a successful response into out.
+// Refusals of the request are *RejectedError; other failures, which may
+// succeed when retried, plain errors.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) (int, error) {
+	// order-service signs the JSON body Express parsed, or {} for requests
+	// without one, serialized as JSON.stringify does
+	if in == nil {
+		in = map[string]interface{}{}
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(in); err != nil {
+		return 0, err
+	}
+	body := bytes.TrimRight(buf.Bytes(), "\n")
+
+	var reader io.Reader
+	if method != http.MethodGet {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	req.Header.Set("X-Service-Id", ServiceID)
+	req.Header.Set("X-Service-Key", c.apiKey)
+	req.Header.Set("X-Timestamp", timestamp)
+	req.Header.Set("X-Signature", c.sign(method, path, body, timestamp))
+	if reader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if correlationID := logging.CorrelationID(ctx); correlationID != "" {
+		req.Header.Set(logging.CorrelationIDHeader, correlationID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to call order-service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to decode order-service response: %w", err)
+		}
+		return resp.StatusCode, nil
+	}
+
+	var failure struct {
+		Error string `json:"error"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&failure)
+	switch resp.StatusCode {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusConflict:
+		return resp.StatusCode, &RejectedError{StatusCode: resp.StatusCode, Message: failure.Error}
+	}
+	return resp.StatusCode, fmt.Errorf("order-service returned status %d to %s %s: %s", resp.StatusCode, method, path, failure.Error)
+}
+
+// sign is order-service's request signature: an HMAC-SHA256 of the
+// method, path, body and timestamp with the service's key
+func (c *Client) sign(method, path string, body []byte, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(c.apiKey))
+	mac.Write([]byte(method + ":" + path + ":" + string(body) + ":" + timestamp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,553 @@
+// Package renewal places subscriptions' orders, run as a scheduled job: it
+// reserves each order's stock ahead of time, places and pays the order
+// when it is due, and retries failed payments with new orders until the
+// subscription runs out of attempts
+package renewal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	sharedevents "github.com/ecommerce-platform/shared/go/events"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/subscription-service/internal/domain"
+	"github.com/ecommerce/subscription-service/internal/events"
+	"github.com/ecommerce/subscription-service/internal/inventory"
+	"github.com/ecommerce/subscription-service/internal/metrics"
+	"github.com/ecommerce/subscription-service/internal/orders"
+	"github.com/ecommerce/subscription-service/internal/repository"
+	"github.com/google/uuid"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// batchSize bounds the subscriptions and cycles each step of a run takes
+// on; the rest wait for the next run
+const batchSize = 100
+
+// CancelReasonPaymentFailed is the cancel reason of subscriptions whose
+// order ran out of payment attempts
+const CancelReasonPaymentFailed = "payment_failed"
+
+// errUnchanged aborts updates of subscriptions and cycles that changed
+// since they were listed, e.g. skipped by the customer
+var errUnchanged = errors.New("subscription changed")
+
+// Config is how renewals are timed
+type Config struct {
+	// ReserveAhead is how long before an order its stock is reserved
+	ReserveAhead time.Duration
+	// ReservationGrace is how long reservations are held past their
+	// order's time, for orders placed late
+	ReservationGrace time.Duration
+	// Dunning is how failed payments are retried
+	Dunning domain.Dunning
+}
+
+// Renewer places subscriptions' orders when they are due
+type Renewer struct {
+	repo      repository.SubscriptionRepository
+	orders    *orders.Client
+	inventory *inventory.Client
+	publisher events.Publisher
+	cfg       Config
+	logger    *zap.Logger
+}
+
+// New creates a renewer
+func New(
+	repo repository.SubscriptionRepository,
+	orders *orders.Client,
+	inventory *inventory.Client,
+	publisher events.Publisher,
+	cfg Config,
+	logger *zap.Logger,
+) *Renewer {
+	return &Renewer{
+		repo:      repo,
+		orders:    orders,
+		inventory: inventory,
+		publisher: publisher,
+		cfg:       cfg,
+		logger:    logger,
+	}
+}
+
+// Run resumes paused subscriptions whose resume date came, schedules the
+// cycles of orders coming up, reserving their stock, and places the
+// orders that are due. Subscriptions and cycles that fail are left for
+// the next run, and reported in the error.
+func (r *Renewer) Run(ctx context.Context) error {
+	now := time.Now().UTC()
+	return errors.Join(
+		r.resumeDue(ctx, now),
+		r.scheduleUpcoming(ctx, now),
+		r.placeDue(ctx, now),
+	)
+}
+
+// resumeDue resumes the paused subscriptions set to resume by now
+func (r *Renewer) resumeDue(ctx context.Context, now time.Time) error {
+	ids, err := r.repo.DueResumes(ctx, now, batchSize)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, id := range ids {
+		sub, _, err := r.repo.Update(ctx, id, func(sub *domain.Subscription, cycle *domain.Cycle) error {
+			if sub.Status != domain.StatusPaused || sub.ResumeAt == nil || sub.ResumeAt.After(now) {
+				return errUnchanged
+			}
+			sub.Resume(cycle, now)
+			return nil
+		})
+		if errors.Is(err, errUnchanged) {
+			continue
+		}
+		if err != nil {
+			r.logger.Error("Failed to resume subscription", zap.String("subscription_id", id), zap.Error(err))
+			errs = append(errs, err)
+			continue
+		}
+
+		metrics.SubscriptionChangesTotal.WithLabelValues(string(domain.ActionResume)).Inc()
+		r.logger.Info("Subscription resumed", zap.String("subscription_id", id))
+		r.publish(ctx, sub, &sharedevents.SubscriptionResumed{SubscriptionUpdate: events.Update(sub)})
+	}
+	return errors.Join(errs...)
+}
+
+// scheduleUpcoming schedules the cycles of orders due within ReserveAhead
+func (r *Renewer) scheduleUpcoming(ctx context.Context, now time.Time) error {
+	subs, err := r.repo.Unscheduled(ctx, now.Add(r.cfg.ReserveAhead), batchSize)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, sub := range subs {
+		if err := r.schedule(ctx, sub, now); err != nil {
+			r.logger.Error("Failed to schedule subscription cycle", zap.String("subscription_id", sub.ID), zap.Error(err))
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// schedule creates the cycle of a subscription's next order and reserves
+// its stock under the order's ID, so the order finds it held. Stock that
+// can't be reserved is left to order-service's check when the order is
+// placed.
+func (r *Renewer) schedule(ctx context.Context, sub *domain.Subscription, now time.Time) error {
+	cycle := &domain.Cycle{
+		ID:             uuid.New().String(),
+		SubscriptionID: sub.ID,
+		ScheduledAt:    sub.NextOrderAt,
+		Status:         domain.CycleScheduled,
+		OrderID:        uuid.New().String(),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	created, err := r.repo.CreateCycle(ctx, cycle)
+	if err != nil || !created {
+		return err
+	}
+
+	reservationIDs := r.reserve(ctx, sub, cycle, now)
+	sub, _, err = r.repo.UpdateCycle(ctx, cycle.ID, func(sub *domain.Subscription, c *domain.Cycle) error {
+		// Skipped, or paused, since the cycle was created
+		if c.Status != domain.CycleScheduled || sub.Status != domain.StatusActive {
+			return errUnchanged
+		}
+		c.ReservationIDs = reservationIDs
+		return nil
+	})
+	if err != nil {
+		r.release(ctx, reservationIDs)
+		if errors.Is(err, errUnchanged) {
+			return nil
+		}
+		return err
+	}
+
+	if cycle.ScheduledAt.After(now) {
+		r.publish(ctx, sub, &sharedevents.SubscriptionRenewalUpcoming{SubscriptionUpdate: events.Update(sub)})
+	}
+	return nil
+}
+
+// reserve reserves a cycle's items until ReservationGrace after its
+// order's time, returning the reservations; all or none are returned
+func (r *Renewer) reserve(ctx context.Context, sub *domain.Subscription, cycle *domain.Cycle, now time.Time) []string {
+	ttl := r.cfg.ReservationGrace
+	if cycle.ScheduledAt.After(now) {
+		ttl += cycle.ScheduledAt.Sub(now)
+	}
+
+	reservationIDs := make([]string, 0, len(sub.Items))
+	for _, item := range sub.Items {
+		reservation, err := r.inventory.Reserve(ctx, item.ProductID, cycle.OrderID, sub.UserID, item.Quantity, ttl)
+		if err != nil {
+			result := "failed"
+			var rejected *inventory.RejectedError
+			if errors.As(err, &rejected) {
+				result = "rejected"
+			}
+			metrics.ReservationsTotal.WithLabelValues(result).Inc()
+			r.logger.Warn("Failed to reserve subscription stock; the order is placed without",
+				zap.String("subscription_id", sub.ID),
+				zap.String("cycle_id", cycle.ID),
+				zap.String("product_id", item.ProductID),
+				zap.Error(err),
+			)
+			r.release(ctx, reservationIDs)
+			return nil
+		}
+		reservationIDs = append(reservationIDs, reservation.ID)
+	}
+
+	metrics.ReservationsTotal.WithLabelValues("reserved").Inc()
+	return reservationIDs
+}
+
+// release releases reservations, logging failures; their stock comes back
+// when they expire anyway
+func (r *Renewer) release(ctx context.Context, reservationIDs []string) {
+	for _, id := range reservationIDs {
+		if err := r.inventory.Release(ctx, id); err != nil {
+			r.logger.Warn("Failed to release reservation", zap.String("reservation_id", id), zap.Error(err))
+		}
+	}
+}
+
+// placeDue places the orders due by now
+func (r *Renewer) placeDue(ctx context.Context, now time.Time) error {
+	cycles, err := r.repo.DueCycles(ctx, now, batchSize)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, cycle := range cycles {
+		if err := r.place(ctx, cycle.ID); err != nil {
+			r.logger.Error("Failed to place subscription order",
+				zap.String("subscription_id", cycle.SubscriptionID),
+				zap.String("cycle_id", cycle.ID),
+				zap.String("order_id", cycle.OrderID),
+				zap.Error(err),
+			)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// place places a cycle's order and pays it. The cycle is marked ordered
+// first, so the customer can no longer skip it; its order ID makes placing
+// it again, e.g. after a crash, return the same order.
+func (r *Renewer) place(ctx context.Context, cycleID string) error {
+	sub, cycle, err := r.repo.UpdateCycle(ctx, cycleID, func(sub *domain.Subscription, c *domain.Cycle) error {
+		switch {
+		case c.Status == domain.CycleScheduled && sub.Status == domain.StatusActive:
+		case c.Status == domain.CycleRetrying && sub.Status == domain.StatusPastDue:
+		case c.Status == domain.CycleOrdered:
+		default:
+			return errUnchanged
+		}
+		c.Status = domain.CycleOrdered
+		c.NextAttemptAt = nil
+		return nil
+	})
+	if errors.Is(err, errUnchanged) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	req, err := orderRequest(sub, cycle)
+	if err != nil {
+		return err
+	}
+	if _, err := r.orders.Create(ctx, req); err != nil {
+		return r.orderFailed(ctx, cycle, err)
+	}
+
+	result, err := r.orders.Pay(ctx, cycle.OrderID)
+	var rejected *orders.RejectedError
+	if errors.As(err, &rejected) && rejected.StatusCode == http.StatusConflict {
+		// Checked out already, by an earlier run
+		return r.reconcile(ctx, cycle)
+	}
+	if err != nil {
+		return r.orderFailed(ctx, cycle, err)
+	}
+
+	switch {
+	case result.Paid:
+		metrics.OrdersTotal.WithLabelValues("paid").Inc()
+		_, err = r.paid(ctx, cycle.ID, cycle.OrderID)
+	case result.Pending:
+		// order-service is retrying the checkout; its order event says how
+		// it went
+		metrics.OrdersTotal.WithLabelValues("pending").Inc()
+	default:
+		metrics.OrdersTotal.WithLabelValues("declined").Inc()
+		_, err = r.failed(ctx, cycle.ID, cycle.OrderID, result.Error)
+	}
+	return err
+}
+
+// orderFailed handles a failure to place or pay a cycle's order: orders
+// order-service refused fail the payment, and anything else is retried by
+// the next run
+func (r *Renewer) orderFailed(ctx context.Context, cycle *domain.Cycle, err error) error {
+	var rejected *orders.RejectedError
+	if !errors.As(err, &rejected) {
+		metrics.OrdersTotal.WithLabelValues("failed").Inc()
+		return err
+	}
+
+	metrics.OrdersTotal.WithLabelValues("rejected").Inc()
+	_, err = r.failed(ctx, cycle.ID, cycle.OrderID, rejected.Message)
+	return err
+}
+
+// reconcile applies the status of a cycle's order checked out already
+func (r *Renewer) reconcile(ctx context.Context, cycle *domain.Cycle) error {
+	order, err := r.orders.Get(ctx, cycle.OrderID)
+	if err != nil {
+		return err
+	}
+
+	switch order.Status {
+	case orders.StatusPending, orders.StatusPaymentPending:
+		return nil
+	case orders.StatusPaymentFailed, orders.StatusCancelled:
+		_, err = r.failed(ctx, cycle.ID, cycle.OrderID, "order "+order.Status)
+	default:
+		_, err = r.paid(ctx, cycle.ID, cycle.OrderID)
+	}
+	return err
+}
+
+// paid marks a cycle whose order orderID was paid paid. Its subscription
+// moves on to its next order after now, and a past due one is active
+// again. It returns whether the cycle was waiting for the order.
+func (r *Renewer) paid(ctx context.Context, cycleID, orderID string) (bool, error) {
+	now := time.Now().UTC()
+	sub, _, err := r.repo.UpdateCycle(ctx, cycleID, func(sub *domain.Subscription, c *domain.Cycle) error {
+		if c.OrderID != orderID || c.Status != domain.CycleOrdered {
+			return errUnchanged
+		}
+		c.Status = domain.CyclePaid
+		if sub.Status == domain.StatusPastDue {
+			sub.Status = domain.StatusActive
+		}
+		if sub.Status != domain.StatusCancelled && sub.NextOrderAt.Equal(c.ScheduledAt) {
+			sub.NextOrderAt = sub.NextAfter(now)
+		}
+		return nil
+	})
+	if errors.Is(err, errUnchanged) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	r.logger.Info("Subscription order paid",
+		zap.String("subscription_id", sub.ID),
+		zap.String("order_id", orderID),
+	)
+	return true, nil
+}
+
+// failed records the failed payment of a cycle's order orderID. The cycle
+// is retried with a new order after the dunning policy's wait, its
+// subscription past due until paid, or, out of attempts, fails and
+// cancels its subscription. Subscriptions paused since the order was
+// placed are past due too: the cycle is owed. It returns whether the
+// cycle was waiting for the order.
+func (r *Renewer) failed(ctx context.Context, cycleID, orderID, reason string) (bool, error) {
+	now := time.Now().UTC()
+	var released []string
+	cancelled := false
+	sub, cycle, err := r.repo.UpdateCycle(ctx, cycleID, func(sub *domain.Subscription, c *domain.Cycle) error {
+		if c.OrderID != orderID || c.Status != domain.CycleOrdered {
+			return errUnchanged
+		}
+		c.Attempts++
+		c.LastError = reason
+		// The order's checkout released its stock, or will as it expires
+		released, c.ReservationIDs = c.ReservationIDs, nil
+
+		if sub.Status != domain.StatusCancelled {
+			c.NextAttemptAt = r.cfg.Dunning.NextAttempt(c.Attempts, now)
+		}
+		if c.NextAttemptAt != nil {
+			c.Status = domain.CycleRetrying
+			c.OrderID = uuid.New().String()
+			sub.Status = domain.StatusPastDue
+			sub.PausedAt, sub.ResumeAt = nil, nil
+			return nil
+		}
+
+		c.Status = domain.CycleFailed
+		if sub.Status != domain.StatusCancelled {
+			sub.Cancel(c, CancelReasonPaymentFailed, now)
+			cancelled = true
+		}
+		return nil
+	})
+	if errors.Is(err, errUnchanged) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	r.release(ctx, released)
+
+	outcome := "retrying"
+	if cycle.Status == domain.CycleFailed {
+		outcome = "cancelled"
+	}
+	metrics.DunningTotal.WithLabelValues(outcome).Inc()
+	r.logger.Warn("Subscription order payment failed",
+		zap.String("subscription_id", sub.ID),
+		zap.String("order_id", orderID),
+		zap.Int("attempt", cycle.Attempts),
+		zap.String("outcome", outcome),
+		zap.String("error", reason),
+	)
+
+	r.publish(ctx, sub, &sharedevents.SubscriptionPaymentFailed{
+		SubscriptionUpdate: events.Update(sub),
+		OrderID:            orderID,
+		Attempt:            cycle.Attempts,
+		NextAttemptAt:      cycle.NextAttemptAt,
+		Error:              reason,
+	})
+	if cancelled {
+		r.publish(ctx, sub, &sharedevents.SubscriptionCancelled{
+			SubscriptionUpdate: events.Update(sub),
+			Reason:             sub.CancelReason,
+		})
+	}
+	return true, nil
+}
+
+// Handle applies an order event. It is a sharedkafka.Handler; failures are
+// retried and then dead-lettered. Payment events of the orders cycles are
+// waiting for settle them, such as checkouts order-service retried; other
+// events, and redelivered ones, are skipped.
+func (r *Renewer) Handle(ctx context.Context, msg kafkago.Message) error {
+	env, err := sharedevents.Decode(msg.Value)
+	if err != nil {
+		metrics.OrderEventsTotal.WithLabelValues("unknown", "failed").Inc()
+		return err
+	}
+
+	switch env.EventType {
+	case "order.payment_successful", "order.payment_failed":
+	default:
+		metrics.OrderEventsTotal.WithLabelValues(env.EventType, "skipped").Inc()
+		return nil
+	}
+	if env.OrderID == "" {
+		metrics.OrderEventsTotal.WithLabelValues(env.EventType, "failed").Inc()
+		return fmt.Errorf("%s event has no order_id", env.EventType)
+	}
+
+	cycle, err := r.repo.CycleByOrder(ctx, env.OrderID)
+	if errors.Is(err, domain.ErrCycleNotFound) {
+		metrics.OrderEventsTotal.WithLabelValues(env.EventType, "skipped").Inc()
+		return nil
+	}
+	if err != nil {
+		metrics.OrderEventsTotal.WithLabelValues(env.EventType, "failed").Inc()
+		return err
+	}
+
+	var applied bool
+	if env.EventType == "order.payment_successful" {
+		applied, err = r.paid(ctx, cycle.ID, env.OrderID)
+	} else {
+		var data struct {
+			Error string `json:"error"`
+		}
+		_ = json.Unmarshal(env.Data, &data)
+		applied, err = r.failed(ctx, cycle.ID, env.OrderID, data.Error)
+	}
+	if err != nil {
+		metrics.OrderEventsTotal.WithLabelValues(env.EventType, "failed").Inc()
+		logging.WithContext(ctx, r.logger).Warn("Failed to apply order event to subscription cycle",
+			zap.String("event_type", env.EventType),
+			zap.String("order_id", env.OrderID),
+			zap.Error(err),
+		)
+		return err
+	}
+	if !applied {
+		metrics.OrderEventsTotal.WithLabelValues(env.EventType, "skipped").Inc()
+		return nil
+	}
+	metrics.OrderEventsTotal.WithLabelValues(env.EventType, "applied").Inc()
+	return nil
+}
+
+// publish publishes a subscription's event. Events are notifications, so
+// failures, which the publisher logs, don't fail the change.
+func (r *Renewer) publish(ctx context.Context, sub *domain.Subscription, event sharedevents.Payload) {
+	_ = r.publisher.Publish(ctx, sub, event)
+}
+
+// orderRequest is the order of a cycle, for its subscription's customer
+func orderRequest(sub *domain.Subscription, cycle *domain.Cycle) (orders.CreateRequest, error) {
+	items := make([]orders.Item, len(sub.Items))
+	for i, item := range sub.Items {
+		price, err := strconv.ParseFloat(item.Price.Decimal(), 64)
+		if err != nil {
+			return orders.CreateRequest{}, fmt.Errorf("invalid price of product %s: %w", item.ProductID, err)
+		}
+		items[i] = orders.Item{
+			ProductID: item.ProductID,
+			SKU:       item.SKU,
+			Name:      item.Name,
+			Price:     price,
+			Quantity:  item.Quantity,
+		}
+	}
+
+	return orders.CreateRequest{
+		OrderID:         cycle.OrderID,
+		UserID:          sub.UserID,
+		Items:           items,
+		ShippingAddress: orderAddress(sub.ShippingAddress),
+		BillingAddress:  orderAddress(sub.BillingAddress),
+		PaymentMethod:   sub.PaymentMethod,
+		CustomerNotes:   "Subscription " + sub.ID,
+		// Stock is reserved under the first attempt's order only; retries'
+		// new orders are checked by order-service
+		InventoryReserved: len(cycle.ReservationIDs) > 0 && cycle.Attempts == 0,
+	}, nil
+}
+
+func orderAddress(a domain.Address) orders.Address {
+	return orders.Address{
+		FullName:     a.FullName,
+		AddressLine1: a.AddressLine1,
+		AddressLine2: a.AddressLine2,
+		City:         a.City,
+		State:        a.State,
+		PostalCode:   a.PostalCode,
+		Country:      a.Country,
+		Phone:        a.Phone,
+	}
+}
@@ -0,0 +1,409 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	shareddb "github.com/ecommerce-platform/shared/go/db"
+	"github.com/ecommerce/subscription-service/internal/domain"
+)
+
+const subscriptionColumns = `
+	id, user_id, customer_email, items, shipping_address, billing_address, payment_method,
+	interval_unit, interval_count, status, next_order_at, resume_at, paused_at, cancelled_at,
+	cancel_reason, created_at, updated_at
+`
+
+const cycleColumns = `
+	id, subscription_id, scheduled_at, status, order_id, reservation_ids, attempts,
+	next_attempt_at, last_error, created_at, updated_at
+`
+
+type postgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *sql.DB) SubscriptionRepository {
+	return &postgresRepository{db: db}
+}
+
+// Ping checks that the database is reachable
+func (r *postgresRepository) Ping(ctx context.Context) error {
+	return shareddb.Check(ctx, r.db)
+}
+
+// Create stores a new subscription
+func (r *postgresRepository) Create(ctx context.Context, sub *domain.Subscription) error {
+	items, shipping, billing, err := subscriptionJSON(sub)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO subscriptions (`+subscriptionColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+	`,
+		sub.ID, sub.UserID, sub.CustomerEmail, items, shipping, billing, sub.PaymentMethod,
+		sub.Interval.Unit, sub.Interval.Count, sub.Status, sub.NextOrderAt.UTC(), utcOrNil(sub.ResumeAt),
+		utcOrNil(sub.PausedAt), utcOrNil(sub.CancelledAt), sub.CancelReason, sub.CreatedAt, sub.UpdatedAt,
+	)
+	return err
+}
+
+// Get returns a subscription
+func (r *postgresRepository) Get(ctx context.Context, id string) (*domain.Subscription, error) {
+	sub, err := scanSubscription(r.db.QueryRowContext(ctx, `SELECT `+subscriptionColumns+` FROM subscriptions WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	return sub, err
+}
+
+// List retrieves up to limit of a customer's subscriptions after a position
+func (r *postgresRepository) List(ctx context.Context, userID string, limit int, after *Position) ([]*domain.Subscription, error) {
+	where := `user_id = $1`
+	args := []interface{}{userID}
+	if after != nil {
+		args = append(args, after.CreatedAt, after.ID)
+		where += ` AND (created_at, id) < ($2, $3)`
+	}
+	args = append(args, limit)
+
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM subscriptions
+		WHERE ` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $` + strconv.Itoa(len(args))
+
+	return scanSubscriptions(r.db.QueryContext(ctx, query, args...))
+}
+
+// Count counts a customer's subscriptions
+func (r *postgresRepository) Count(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM subscriptions WHERE user_id = $1`, userID).Scan(&count)
+	return count, err
+}
+
+// Update changes a subscription and its next cycle under their row locks,
+// so customers' changes and the renewal job don't race
+func (r *postgresRepository) Update(ctx context.Context, id string, apply func(*domain.Subscription, *domain.Cycle) error) (*domain.Subscription, *domain.Cycle, error) {
+	var sub *domain.Subscription
+	var cycle *domain.Cycle
+	err := shareddb.WithTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		var err error
+		if sub, err = lockSubscription(ctx, tx, id); err != nil {
+			return err
+		}
+		cycle, err = scanCycle(tx.QueryRowContext(ctx, `
+			SELECT `+cycleColumns+` FROM cycles WHERE subscription_id = $1 AND scheduled_at = $2 FOR UPDATE
+		`, sub.ID, sub.NextOrderAt))
+		if err == sql.ErrNoRows {
+			cycle, err = nil, nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return applyAndSave(ctx, tx, sub, cycle, apply)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return sub, cycle, nil
+}
+
+// Cycles returns a subscription's latest cycles
+func (r *postgresRepository) Cycles(ctx context.Context, subscriptionID string, limit int) ([]*domain.Cycle, error) {
+	return scanCycles(r.db.QueryContext(ctx, `
+		SELECT `+cycleColumns+` FROM cycles WHERE subscription_id = $1 ORDER BY scheduled_at DESC LIMIT $2
+	`, subscriptionID, limit))
+}
+
+// CreateCycle stores a cycle, unless its time was scheduled already
+func (r *postgresRepository) CreateCycle(ctx context.Context, cycle *domain.Cycle) (bool, error) {
+	reservations, err := json.Marshal(nonNil(cycle.ReservationIDs))
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO cycles (`+cycleColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (subscription_id, scheduled_at) DO NOTHING
+	`,
+		cycle.ID, cycle.SubscriptionID, cycle.ScheduledAt.UTC(), cycle.Status, cycle.OrderID, reservations,
+		cycle.Attempts, utcOrNil(cycle.NextAttemptAt), cycle.LastError, cycle.CreatedAt, cycle.UpdatedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, _ := result.RowsAffected()
+	return n == 1, nil
+}
+
+// UpdateCycle changes a cycle and its subscription under their row locks,
+// taken subscription first as Update takes them
+func (r *postgresRepository) UpdateCycle(ctx context.Context, cycleID string, apply func(*domain.Subscription, *domain.Cycle) error) (*domain.Subscription, *domain.Cycle, error) {
+	var sub *domain.Subscription
+	var cycle *domain.Cycle
+	err := shareddb.WithTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		var subscriptionID string
+		err := tx.QueryRowContext(ctx, `SELECT subscription_id FROM cycles WHERE id = $1`, cycleID).Scan(&subscriptionID)
+		if err == sql.ErrNoRows {
+			return domain.ErrCycleNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if sub, err = lockSubscription(ctx, tx, subscriptionID); err != nil {
+			return err
+		}
+		cycle, err = scanCycle(tx.QueryRowContext(ctx, `SELECT `+cycleColumns+` FROM cycles WHERE id = $1 FOR UPDATE`, cycleID))
+		if err != nil {
+			return err
+		}
+
+		return applyAndSave(ctx, tx, sub, cycle, apply)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return sub, cycle, nil
+}
+
+// CycleByOrder returns the cycle whose current order is orderID
+func (r *postgresRepository) CycleByOrder(ctx context.Context, orderID string) (*domain.Cycle, error) {
+	cycle, err := scanCycle(r.db.QueryRowContext(ctx, `SELECT `+cycleColumns+` FROM cycles WHERE order_id = $1`, orderID))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrCycleNotFound
+	}
+	return cycle, err
+}
+
+// Unscheduled returns active subscriptions ordering before a time without
+// a cycle for their next order
+func (r *postgresRepository) Unscheduled(ctx context.Context, before time.Time, limit int) ([]*domain.Subscription, error) {
+	return scanSubscriptions(r.db.QueryContext(ctx, `
+		SELECT `+subscriptionColumns+`
+		FROM subscriptions s
+		WHERE status = 'active' AND next_order_at < $1
+			AND NOT EXISTS (SELECT 1 FROM cycles c WHERE c.subscription_id = s.id AND c.scheduled_at = s.next_order_at)
+		ORDER BY next_order_at
+		LIMIT $2
+	`, before.UTC(), limit))
+}
+
+// DueCycles returns the cycles whose order is due at a time, and ordered
+// cycles untouched for 15 minutes, whose order may not have been placed
+// or paid
+func (r *postgresRepository) DueCycles(ctx context.Context, at time.Time, limit int) ([]*domain.Cycle, error) {
+	return scanCycles(r.db.QueryContext(ctx, `
+		SELECT `+cycleColumns+`
+		FROM cycles c
+		WHERE (status = 'scheduled' AND scheduled_at <= $1
+				AND EXISTS (SELECT 1 FROM subscriptions s WHERE s.id = c.subscription_id AND s.status = 'active'))
+			OR (status = 'retrying' AND next_attempt_at <= $1
+				AND EXISTS (SELECT 1 FROM subscriptions s WHERE s.id = c.subscription_id AND s.status = 'past_due'))
+			OR (status = 'ordered' AND updated_at <= $1 - INTERVAL '15 minutes')
+		ORDER BY COALESCE(next_attempt_at, scheduled_at)
+		LIMIT $2
+	`, at.UTC(), limit))
+}
+
+// DueResumes returns the paused subscriptions set to resume by a time
+func (r *postgresRepository) DueResumes(ctx context.Context, at time.Time, limit int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id FROM subscriptions WHERE status = 'paused' AND resume_at <= $1 ORDER BY resume_at LIMIT $2
+	`, at.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// applyAndSave runs apply on a locked subscription and cycle and writes
+// them back
+func applyAndSave(ctx context.Context, tx *sql.Tx, sub *domain.Subscription, cycle *domain.Cycle, apply func(*domain.Subscription, *domain.Cycle) error) error {
+	if err := apply(sub, cycle); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	sub.UpdatedAt = now
+	_, err := tx.ExecContext(ctx, `
+		UPDATE subscriptions
+		SET status = $2, next_order_at = $3, resume_at = $4, paused_at = $5, cancelled_at = $6,
+			cancel_reason = $7, updated_at = $8
+		WHERE id = $1
+	`,
+		sub.ID, sub.Status, sub.NextOrderAt.UTC(), utcOrNil(sub.ResumeAt), utcOrNil(sub.PausedAt),
+		utcOrNil(sub.CancelledAt), sub.CancelReason, sub.UpdatedAt,
+	)
+	if err != nil || cycle == nil {
+		return err
+	}
+
+	reservations, err := json.Marshal(nonNil(cycle.ReservationIDs))
+	if err != nil {
+		return err
+	}
+	cycle.UpdatedAt = now
+	_, err = tx.ExecContext(ctx, `
+		UPDATE cycles
+		SET status = $2, order_id = $3, reservation_ids = $4, attempts = $5, next_attempt_at = $6,
+			last_error = $7, updated_at = $8
+		WHERE id = $1
+	`,
+		cycle.ID, cycle.Status, cycle.OrderID, reservations, cycle.Attempts, utcOrNil(cycle.NextAttemptAt),
+		cycle.LastError, cycle.UpdatedAt,
+	)
+	return err
+}
+
+// lockSubscription loads a subscription and locks its row until the
+// transaction ends
+func lockSubscription(ctx context.Context, tx *sql.Tx, id string) (*domain.Subscription, error) {
+	sub, err := scanSubscription(tx.QueryRowContext(ctx, `SELECT `+subscriptionColumns+` FROM subscriptions WHERE id = $1 FOR UPDATE`, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNotFound
+	}
+	return sub, err
+}
+
+func subscriptionJSON(sub *domain.Subscription) (items, shipping, billing []byte, err error) {
+	if items, err = json.Marshal(sub.Items); err != nil {
+		return nil, nil, nil, err
+	}
+	if shipping, err = json.Marshal(sub.ShippingAddress); err != nil {
+		return nil, nil, nil, err
+	}
+	if billing, err = json.Marshal(sub.BillingAddress); err != nil {
+		return nil, nil, nil, err
+	}
+	return items, shipping, billing, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSubscription(row scanner) (*domain.Subscription, error) {
+	sub := &domain.Subscription{}
+	var items, shipping, billing []byte
+	var resumeAt, pausedAt, cancelledAt sql.NullTime
+	err := row.Scan(
+		&sub.ID, &sub.UserID, &sub.CustomerEmail, &items, &shipping, &billing, &sub.PaymentMethod,
+		&sub.Interval.Unit, &sub.Interval.Count, &sub.Status, &sub.NextOrderAt, &resumeAt, &pausedAt,
+		&cancelledAt, &sub.CancelReason, &sub.CreatedAt, &sub.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(items, &sub.Items); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(shipping, &sub.ShippingAddress); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(billing, &sub.BillingAddress); err != nil {
+		return nil, err
+	}
+	sub.ResumeAt = timeOrNil(resumeAt)
+	sub.PausedAt = timeOrNil(pausedAt)
+	sub.CancelledAt = timeOrNil(cancelledAt)
+	return sub, nil
+}
+
+func scanSubscriptions(rows *sql.Rows, err error) ([]*domain.Subscription, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []*domain.Subscription{}
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+
+	return subs, rows.Err()
+}
+
+func scanCycle(row scanner) (*domain.Cycle, error) {
+	cycle := &domain.Cycle{}
+	var reservations []byte
+	var nextAttemptAt sql.NullTime
+	err := row.Scan(
+		&cycle.ID, &cycle.SubscriptionID, &cycle.ScheduledAt, &cycle.Status, &cycle.OrderID, &reservations,
+		&cycle.Attempts, &nextAttemptAt, &cycle.LastError, &cycle.CreatedAt, &cycle.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(reservations, &cycle.ReservationIDs); err != nil {
+		return nil, err
+	}
+	cycle.NextAttemptAt = timeOrNil(nextAttemptAt)
+	return cycle, nil
+}
+
+func scanCycles(rows *sql.Rows, err error) ([]*domain.Cycle, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cycles := []*domain.Cycle{}
+	for rows.Next() {
+		cycle, err := scanCycle(rows)
+		if err != nil {
+			return nil, err
+		}
+		cycles = append(cycles, cycle)
+	}
+
+	return cycles, rows.Err()
+}
+
+func nonNil(ids []string) []string {
+	if ids == nil {
+		return []string{}
+	}
+	return ids
+}
+
+func timeOrNil(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func utcOrNil(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
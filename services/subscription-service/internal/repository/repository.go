@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ecommerce/subscription-service/internal/domain"
+)
+
+// SubscriptionRepository defines subscription data operations
+type SubscriptionRepository interface {
+	// Subscriptions
+	Create(ctx context.Context, sub *domain.Subscription) error
+	Get(ctx context.Context, id string) (*domain.Subscription, error)
+	// List returns up to limit of a customer's subscriptions after a
+	// position, newest first
+	List(ctx context.Context, userID string, limit int, after *Position) ([]*domain.Subscription, error)
+	Count(ctx context.Context, userID string) (int64, error)
+	// Update locks a subscription and the cycle at its next order time, nil
+	// if none was scheduled yet, and stores them as apply leaves them.
+	// apply's error aborts the update and is returned.
+	Update(ctx context.Context, id string, apply func(*domain.Subscription, *domain.Cycle) error) (*domain.Subscription, *domain.Cycle, error)
+
+	// Cycles
+	// Cycles returns a subscription's latest cycles, newest first
+	Cycles(ctx context.Context, subscriptionID string, limit int) ([]*domain.Cycle, error)
+	// CreateCycle stores a cycle unless its subscription has one at the
+	// same time already, returning whether it did
+	CreateCycle(ctx context.Context, cycle *domain.Cycle) (bool, error)
+	// UpdateCycle locks a cycle and its subscription and stores them as
+	// apply leaves them, like Update
+	UpdateCycle(ctx context.Context, cycleID string, apply func(*domain.Subscription, *domain.Cycle) error) (*domain.Subscription, *domain.Cycle, error)
+	// CycleByOrder returns the cycle whose current order is orderID
+	CycleByOrder(ctx context.Context, orderID string) (*domain.Cycle, error)
+
+	// Renewals
+	// Unscheduled returns up to limit active subscriptions ordering before
+	// a time that have no cycle for their next order yet, soonest first
+	Unscheduled(ctx context.Context, before time.Time, limit int) ([]*domain.Subscription, error)
+	// DueCycles returns up to limit cycles whose order is due at a time:
+	// scheduled cycles of active subscriptions, retries of past due ones,
+	// and ordered cycles gone quiet, whose order is checked on again;
+	// oldest first
+	DueCycles(ctx context.Context, at time.Time, limit int) ([]*domain.Cycle, error)
+	// DueResumes returns the IDs of up to limit paused subscriptions set to
+	// resume by a time
+	DueResumes(ctx context.Context, at time.Time, limit int) ([]string, error)
+
+	// Ping checks that the database is reachable
+	Ping(ctx context.Context) error
+}
+
+// Position is the cursor position of subscription lists, sorted newest
+// first
+type Position struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+}
@@ -0,0 +1,49 @@
+-- Subscriptions: what a customer gets delivered, and how often. Items and
+-- addresses are stored as placed in each cycle's order.
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    customer_email VARCHAR(255) NOT NULL,
+    items JSONB NOT NULL,
+    shipping_address JSONB NOT NULL,
+    billing_address JSONB NOT NULL,
+    payment_method VARCHAR(50) NOT NULL,
+    interval_unit VARCHAR(10) NOT NULL CHECK (interval_unit IN ('day', 'week', 'month')),
+    interval_count INTEGER NOT NULL CHECK (interval_count > 0),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('active', 'paused', 'past_due', 'cancelled')),
+    -- next_order_at is when the next cycle's order is placed
+    next_order_at TIMESTAMP NOT NULL,
+    -- resume_at, for paused subscriptions, is when they resume by themselves
+    resume_at TIMESTAMP,
+    paused_at TIMESTAMP,
+    cancelled_at TIMESTAMP,
+    cancel_reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_user ON subscriptions(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_next_order ON subscriptions(next_order_at) WHERE status IN ('active', 'past_due');
+CREATE INDEX IF NOT EXISTS idx_subscriptions_resume ON subscriptions(resume_at) WHERE status = 'paused';
+
+-- Cycles: one per subscription and scheduled time, from the stock reserved
+-- ahead of it to its order's payment. order_id is the current attempt's
+-- order; every payment retry places a new one.
+CREATE TABLE IF NOT EXISTS cycles (
+    id VARCHAR(36) PRIMARY KEY,
+    subscription_id VARCHAR(36) NOT NULL REFERENCES subscriptions(id),
+    scheduled_at TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('scheduled', 'ordered', 'retrying', 'paid', 'failed', 'skipped')),
+    order_id VARCHAR(36) NOT NULL UNIQUE,
+    -- reservation_ids hold the stock reserved under the first order_id
+    reservation_ids JSONB NOT NULL DEFAULT '[]',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (subscription_id, scheduled_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cycles_due ON cycles(scheduled_at) WHERE status = 'scheduled';
+CREATE INDEX IF NOT EXISTS idx_cycles_retry ON cycles(next_attempt_at) WHERE status = 'retrying';
@@ -0,0 +1,523 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ecommerce-platform/shared/go/audit"
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce-platform/shared/go/pagination"
+	"github.com/ecommerce/tax-service/internal/calculator"
+	"github.com/ecommerce/tax-service/internal/domain"
+	"github.com/ecommerce/tax-service/internal/metrics"
+	"github.com/ecommerce/tax-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// PermissionManage allows managing jurisdictions, their rates and product
+// tax classes, and reading any calculation; admins have it
+const PermissionManage = "tax:manage"
+
+// CodeMixedCurrencies is the error code of calculations with amounts in
+// several currencies
+const CodeMixedCurrencies = "MIXED_CURRENCIES"
+
+func init() {
+	apperrors.Register(CodeMixedCurrencies, http.StatusUnprocessableEntity, "The lines and discount of a tax calculation are in different currencies")
+}
+
+// Handler serves the tax API
+type Handler struct {
+	calculator *calculator.Calculator
+	repo       repository.TaxRepository
+	cache      repository.CacheRepository
+	auditor    *audit.Auditor
+	logger     *zap.Logger
+}
+
+// NewHandler creates a new handler
+func NewHandler(calc *calculator.Calculator, repo repository.TaxRepository, cache repository.CacheRepository, auditor *audit.Auditor, logger *zap.Logger) *Handler {
+	return &Handler{
+		calculator: calc,
+		repo:       repo,
+		cache:      cache,
+		auditor:    auditor,
+		logger:     logger,
+	}
+}
+
+// AddressRequest is a destination address
+type AddressRequest struct {
+	Country    string `json:"country" binding:"required,min=2,max=3,alpha"`
+	Region     string `json:"region" binding:"max=10"`
+	PostalCode string `json:"postal_code" binding:"max=20"`
+}
+
+// CalculationLine is an order line to tax: the product's price times its
+// quantity
+type CalculationLine struct {
+	ProductID string       `json:"product_id" binding:"required,max=255"`
+	Amount    *money.Money `json:"amount" binding:"required"`
+	// TaxClass overrides the product's tax class
+	TaxClass string `json:"tax_class" binding:"max=50"`
+}
+
+// CalculateRequest is the lines of an order shipped to an address
+type CalculateRequest struct {
+	// Reference, e.g. the order ID, records the calculation
+	Reference string            `json:"reference" binding:"max=255"`
+	Address   *AddressRequest   `json:"address" binding:"required"`
+	Lines     []CalculationLine `json:"lines" binding:"required,min=1,max=500,dive"`
+	// Discount is an order-wide discount taken off the lines before tax
+	Discount *money.Money `json:"discount"`
+}
+
+// JurisdictionRequest is a jurisdiction's location and rates
+type JurisdictionRequest struct {
+	Name         string `json:"name" binding:"required,max=200"`
+	Country      string `json:"country" binding:"required,min=2,max=3,alpha"`
+	Region       string `json:"region" binding:"max=10"`
+	PostalPrefix string `json:"postal_prefix" binding:"max=20"`
+	// Rates are per tax class and need a standard rate
+	Rates map[string]domain.Rate `json:"rates" binding:"required,min=1,max=50"`
+}
+
+// SetProductClassRequest sets a product's tax class
+type SetProductClassRequest struct {
+	TaxClass string `json:"tax_class" binding:"required,max=50"`
+}
+
+// Calculate computes the tax of an order's lines shipped to an address.
+// With a reference the calculation is recorded for the caller.
+// POST /api/v1/tax/calculations
+func (h *Handler) Calculate(c *gin.Context) {
+	var req CalculateRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	var invalid apperrors.ValidationErrors
+	var total int64
+	lines := make([]domain.RequestLine, len(req.Lines))
+	for i, line := range req.Lines {
+		if line.Amount.IsNegative() || len(line.Amount.Currency) != 3 {
+			invalid.Add("lines.amount", "min", "line amounts must not be negative and need an ISO 4217 currency")
+		}
+		if line.TaxClass != "" && !domain.ValidClass(line.TaxClass) {
+			invalid.Add("lines.tax_class", "format", "tax_class must be lowercase letters, digits and underscores")
+		}
+		total += line.Amount.MinorUnits
+		lines[i] = domain.RequestLine{ProductID: line.ProductID, Amount: *line.Amount, TaxClass: line.TaxClass}
+	}
+	if req.Discount != nil && (req.Discount.IsNegative() || req.Discount.MinorUnits > total) {
+		invalid.Add("discount.minor_units", "max", "discount must be between zero and the lines' total")
+	}
+	if err := invalid.Err(); err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	calculation, err := h.calculator.Calculate(c.Request.Context(), &domain.Request{
+		Reference: req.Reference,
+		UserID:    c.GetString(sharedauth.ContextUserID),
+		Address: domain.Address{
+			Country:    req.Address.Country,
+			Region:     req.Address.Region,
+			PostalCode: req.Address.PostalCode,
+		},
+		Lines:    lines,
+		Discount: req.Discount,
+	})
+	if errors.Is(err, calculator.ErrMixedCurrencies) {
+		apperrors.Abort(c, apperrors.NewCode(CodeMixedCurrencies, "Amounts are in different currencies"))
+		return
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Calculation"))
+		return
+	}
+
+	status := http.StatusOK
+	if calculation.ID != "" {
+		status = http.StatusCreated
+	}
+	c.JSON(status, calculation)
+}
+
+// GetCalculation returns a recorded calculation; the customer it was made
+// for, or a holder of tax:manage, can read it
+// GET /api/v1/tax/calculations/:id
+func (h *Handler) GetCalculation(c *gin.Context) {
+	calculation, err := h.repo.GetCalculation(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, domain.ErrCalculationNotFound) {
+		apperrors.Abort(c, apperrors.NewNotFound("Calculation"))
+		return
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Calculation"))
+		return
+	}
+
+	claims, _ := sharedauth.ClaimsFrom(c)
+	manager := claims != nil && claims.HasPermission(PermissionManage)
+	if !manager && calculation.UserID != c.GetString(sharedauth.ContextUserID) {
+		// Other customers' calculations aren't revealed to exist
+		apperrors.Abort(c, apperrors.NewNotFound("Calculation"))
+		return
+	}
+
+	c.JSON(http.StatusOK, calculation)
+}
+
+// ListCalculations lists a page of recorded calculations, newest first,
+// optionally only those of a reference
+// GET /api/v1/tax/calculations?reference=&limit=&cursor=
+func (h *Handler) ListCalculations(c *gin.Context) {
+	params := pagination.FromQuery(c.Request.URL.Query())
+
+	var after *repository.CalculationPosition
+	if params.Cursor != "" {
+		after = &repository.CalculationPosition{}
+		if err := pagination.DecodeCursor(params.Cursor, after); err != nil {
+			apperrors.Abort(c, apperrors.NewBadRequest("Invalid cursor"))
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	filter := repository.CalculationFilter{Reference: c.Query("reference")}
+	calculations, err := h.repo.ListCalculations(ctx, filter, params.Limit+1, after)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Calculation"))
+		return
+	}
+
+	total, err := h.repo.CountCalculations(ctx, filter)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Calculation"))
+		return
+	}
+
+	page, err := pagination.NewPage(calculations, params.Limit, total, func(calculation *domain.Calculation) interface{} {
+		return repository.CalculationPosition{CreatedAt: calculation.CreatedAt, ID: calculation.ID}
+	})
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to list calculations"))
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// CreateJurisdiction creates a jurisdiction with its rates
+// POST /api/v1/tax/jurisdictions
+func (h *Handler) CreateJurisdiction(c *gin.Context) {
+	var req JurisdictionRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	jurisdiction, invalid := jurisdictionFrom(&req)
+	if invalid != nil {
+		apperrors.Abort(c, invalid)
+		return
+	}
+	jurisdiction.CreatedBy = c.GetString(sharedauth.ContextUserID)
+
+	if err := h.repo.CreateJurisdiction(c.Request.Context(), jurisdiction); errors.Is(err, domain.ErrDuplicateJurisdiction) {
+		apperrors.Abort(c, apperrors.NewConflict("A jurisdiction with this name already covers this location"))
+		return
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Jurisdiction"))
+		return
+	}
+
+	h.ratesChanged(c, audit.Entry{
+		Action:   "jurisdiction.created",
+		Resource: audit.Resource{Type: "jurisdiction", ID: jurisdiction.ID},
+		After:    jurisdiction,
+	})
+
+	c.JSON(http.StatusCreated, jurisdiction)
+}
+
+// ListJurisdictions lists jurisdictions by location, optionally of one
+// country
+// GET /api/v1/tax/jurisdictions?country=
+func (h *Handler) ListJurisdictions(c *gin.Context) {
+	address := domain.Address{Country: c.Query("country")}
+	address.Normalize()
+
+	jurisdictions, err := h.repo.ListJurisdictions(c.Request.Context(), address.Country)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Jurisdiction"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"jurisdictions": jurisdictions})
+}
+
+// GetJurisdiction returns a jurisdiction with its rates
+// GET /api/v1/tax/jurisdictions/:id
+func (h *Handler) GetJurisdiction(c *gin.Context) {
+	jurisdiction, ok := h.jurisdiction(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, jurisdiction)
+}
+
+// UpdateJurisdiction replaces a jurisdiction's name, location and rates
+// PUT /api/v1/tax/jurisdictions/:id
+func (h *Handler) UpdateJurisdiction(c *gin.Context) {
+	var req JurisdictionRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	after, invalid := jurisdictionFrom(&req)
+	if invalid != nil {
+		apperrors.Abort(c, invalid)
+		return
+	}
+
+	before, ok := h.jurisdiction(c)
+	if !ok {
+		return
+	}
+	after.ID = before.ID
+	after.CreatedBy = before.CreatedBy
+	after.CreatedAt = before.CreatedAt
+
+	err := h.repo.UpdateJurisdiction(c.Request.Context(), after)
+	switch {
+	case errors.Is(err, domain.ErrJurisdictionNotFound):
+		apperrors.Abort(c, apperrors.NewNotFound("Jurisdiction"))
+		return
+	case errors.Is(err, domain.ErrDuplicateJurisdiction):
+		apperrors.Abort(c, apperrors.NewConflict("A jurisdiction with this name already covers this location"))
+		return
+	case err != nil:
+		apperrors.Abort(c, apperrors.FromSQL(err, "Jurisdiction"))
+		return
+	}
+
+	h.ratesChanged(c, audit.Entry{
+		Action:   "jurisdiction.updated",
+		Resource: audit.Resource{Type: "jurisdiction", ID: after.ID},
+		Before:   before,
+		After:    after,
+	})
+
+	c.JSON(http.StatusOK, after)
+}
+
+// DeleteJurisdiction deletes a jurisdiction; recorded calculations keep the
+// rates it had
+// DELETE /api/v1/tax/jurisdictions/:id
+func (h *Handler) DeleteJurisdiction(c *gin.Context) {
+	before, ok := h.jurisdiction(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteJurisdiction(c.Request.Context(), before.ID); errors.Is(err, domain.ErrJurisdictionNotFound) {
+		apperrors.Abort(c, apperrors.NewNotFound("Jurisdiction"))
+		return
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Jurisdiction"))
+		return
+	}
+
+	h.ratesChanged(c, audit.Entry{
+		Action:   "jurisdiction.deleted",
+		Resource: audit.Resource{Type: "jurisdiction", ID: before.ID},
+		Before:   before,
+	})
+
+	c.Status(http.StatusNoContent)
+}
+
+// jurisdiction loads the jurisdiction of the :id route parameter, aborting
+// the request when it can't
+func (h *Handler) jurisdiction(c *gin.Context) (*domain.Jurisdiction, bool) {
+	jurisdiction, err := h.repo.GetJurisdiction(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, domain.ErrJurisdictionNotFound) {
+		apperrors.Abort(c, apperrors.NewNotFound("Jurisdiction"))
+		return nil, false
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Jurisdiction"))
+		return nil, false
+	}
+	return jurisdiction, true
+}
+
+// GetProductClass returns a product's tax class; products without one
+// are standard
+// GET /api/v1/tax/products/:productId/class
+func (h *Handler) GetProductClass(c *gin.Context) {
+	class, err := h.repo.GetProductClass(c.Request.Context(), c.Param("productId"))
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Product class"))
+		return
+	}
+
+	c.JSON(http.StatusOK, class)
+}
+
+// SetProductClass sets a product's tax class, e.g. food or digital_goods
+// PUT /api/v1/tax/products/:productId/class
+func (h *Handler) SetProductClass(c *gin.Context) {
+	var req SetProductClassRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	taxClass := strings.ToLower(strings.TrimSpace(req.TaxClass))
+	if !domain.ValidClass(taxClass) {
+		apperrors.Abort(c, apperrors.NewValidation(apperrors.FieldError{
+			Field:   "tax_class",
+			Rule:    "format",
+			Message: "tax_class must be lowercase letters, digits and underscores",
+		}))
+		return
+	}
+
+	ctx := c.Request.Context()
+	productID := c.Param("productId")
+	before, err := h.repo.GetProductClass(ctx, productID)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Product class"))
+		return
+	}
+
+	after := &domain.ProductClass{
+		ProductID: productID,
+		TaxClass:  taxClass,
+		UpdatedBy: c.GetString(sharedauth.ContextUserID),
+	}
+	if err := h.repo.SetProductClass(ctx, after); err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Product class"))
+		return
+	}
+
+	metrics.RateChangesTotal.WithLabelValues("product_class.set").Inc()
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   "product_class.set",
+		Resource: audit.Resource{Type: "product", ID: productID},
+		Before:   before,
+		After:    after,
+	})
+
+	c.JSON(http.StatusOK, after)
+}
+
+// DeleteProductClass makes a product standard again
+// DELETE /api/v1/tax/products/:productId/class
+func (h *Handler) DeleteProductClass(c *gin.Context) {
+	ctx := c.Request.Context()
+	productID := c.Param("productId")
+	before, err := h.repo.GetProductClass(ctx, productID)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Product class"))
+		return
+	}
+
+	deleted, err := h.repo.DeleteProductClass(ctx, productID)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Product class"))
+		return
+	}
+	if !deleted {
+		apperrors.Abort(c, apperrors.NewNotFound("Product class"))
+		return
+	}
+
+	metrics.RateChangesTotal.WithLabelValues("product_class.deleted").Inc()
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   "product_class.deleted",
+		Resource: audit.Resource{Type: "product", ID: productID},
+		Before:   before,
+	})
+
+	c.Status(http.StatusNoContent)
+}
+
+// HealthCheck reports whether the database is reachable
+func (h *Handler) HealthCheck(c *gin.Context) {
+	if err := h.repo.Ping(c.Request.Context()); err != nil {
+		h.logger.Warn("Health check failed", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unhealthy",
+			"service": "tax-service",
+			"version": "1.0.0",
+			"error":   "database unavailable",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "healthy",
+		"service": "tax-service",
+		"version": "1.0.0",
+	})
+}
+
+// ratesChanged audits a jurisdiction change and drops the cached
+// jurisdictions, so calculations use the new rates at once. Failing to
+// drop them is logged: they expire with the cache TTL.
+func (h *Handler) ratesChanged(c *gin.Context, entry audit.Entry) {
+	metrics.RateChangesTotal.WithLabelValues(entry.Action).Inc()
+	h.auditor.LogGin(c, entry)
+
+	if err := h.cache.FlushAll(c.Request.Context()); err != nil {
+		logging.WithContext(c.Request.Context(), h.logger).Warn("Failed to clear cached jurisdictions", zap.Error(err))
+	}
+}
+
+// jurisdictionFrom validates req's location and rates and returns the
+// jurisdiction it describes
+func jurisdictionFrom(req *JurisdictionRequest) (*domain.Jurisdiction, *apperrors.AppError) {
+	address := domain.Address{Country: req.Country, Region: req.Region}
+	address.Normalize()
+	prefix := domain.NormalizePostalCode(req.PostalPrefix)
+
+	var invalid apperrors.ValidationErrors
+	if len(address.Country) != 2 {
+		invalid.Add("country", "len", "country must be an ISO 3166-1 alpha-2 code, e.g. US")
+	}
+	if prefix != "" && address.Region == "" {
+		invalid.Add("postal_prefix", "required_with", "postal_prefix needs a region")
+	}
+	for _, r := range prefix {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			invalid.Add("postal_prefix", "alphanum", "postal_prefix must be letters and digits")
+			break
+		}
+	}
+
+	rates := make(map[string]domain.Rate, len(req.Rates))
+	for class, rate := range req.Rates {
+		class = strings.ToLower(strings.TrimSpace(class))
+		if !domain.ValidClass(class) {
+			invalid.Add("rates", "format", "tax classes must be lowercase letters, digits and underscores")
+			break
+		}
+		rates[class] = rate
+	}
+	if _, ok := rates[domain.DefaultClass]; !ok {
+		invalid.Add("rates.standard", "required", "rates must include a standard rate")
+	}
+	if err := invalid.Err(); err != nil {
+		return nil, err
+	}
+
+	return &domain.Jurisdiction{
+		Name:         strings.TrimSpace(req.Name),
+		Country:      address.Country,
+		Region:       address.Region,
+		PostalPrefix: prefix,
+		Rates:        rates,
+	}, nil
+}
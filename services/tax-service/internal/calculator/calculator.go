@@ -0,0 +1,106 @@
+// Package calculator computes the tax of order lines with a Provider, the
+// jurisdiction tables or a tax service such as Avalara or TaxJar, and
+// stores the calculations made for orders as the audit trail of the rates
+// they applied
+package calculator
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce/tax-service/internal/domain"
+	"github.com/ecommerce/tax-service/internal/metrics"
+	"github.com/ecommerce/tax-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// ErrMixedCurrencies is returned for requests with amounts in several
+// currencies
+var ErrMixedCurrencies = errors.New("amounts are in different currencies")
+
+// Provider computes tax. Tables is the built-in one; a tax service is
+// plugged in by implementing it.
+type Provider interface {
+	// Name identifies the provider in calculations, e.g. "tables"
+	Name() string
+	// Calculate computes req's tax line by line. Lines are in req's order
+	// and amounts in one currency; Reference, UserID and CreatedAt are
+	// left to the caller.
+	Calculate(ctx context.Context, req *domain.Request) (*domain.Calculation, error)
+}
+
+// Calculator calculates tax with a provider and records calculations
+type Calculator struct {
+	provider Provider
+	repo     repository.TaxRepository
+	logger   *zap.Logger
+}
+
+// New creates a calculator using provider
+func New(provider Provider, repo repository.TaxRepository, logger *zap.Logger) *Calculator {
+	return &Calculator{
+		provider: provider,
+		repo:     repo,
+		logger:   logger,
+	}
+}
+
+// Calculate computes req's tax. Calculations with a Reference, e.g. an
+// order's, are stored, so the rates an order was charged can be looked up
+// after they change.
+func (c *Calculator) Calculate(ctx context.Context, req *domain.Request) (*domain.Calculation, error) {
+	name := c.provider.Name()
+	req.Address.Normalize()
+	if err := checkCurrencies(req); err != nil {
+		metrics.CalculationsTotal.WithLabelValues(name, "invalid").Inc()
+		return nil, err
+	}
+
+	calculation, err := c.provider.Calculate(ctx, req)
+	if err != nil {
+		metrics.CalculationsTotal.WithLabelValues(name, "failed").Inc()
+		return nil, err
+	}
+	calculation.Reference = req.Reference
+	calculation.UserID = req.UserID
+	calculation.Provider = name
+	calculation.CreatedAt = time.Now().UTC()
+
+	if req.Reference == "" {
+		metrics.CalculationsTotal.WithLabelValues(name, "calculated").Inc()
+		return calculation, nil
+	}
+	if err := c.repo.SaveCalculation(ctx, calculation); err != nil {
+		metrics.CalculationsTotal.WithLabelValues(name, "failed").Inc()
+		return nil, err
+	}
+
+	metrics.CalculationsTotal.WithLabelValues(name, "recorded").Inc()
+	logging.WithContext(ctx, c.logger).Info("Tax calculation recorded",
+		zap.String("calculation_id", calculation.ID),
+		zap.String("reference", calculation.Reference),
+		zap.Int64("tax_amount", calculation.TaxAmount.MinorUnits),
+		zap.String("currency", calculation.Currency),
+	)
+	return calculation, nil
+}
+
+// checkCurrencies rejects requests whose lines and discount aren't in one
+// currency
+func checkCurrencies(req *domain.Request) error {
+	if len(req.Lines) == 0 {
+		return nil
+	}
+	currency := req.Lines[0].Amount.Currency
+	for _, line := range req.Lines[1:] {
+		if line.Amount.Currency != currency {
+			return ErrMixedCurrencies
+		}
+	}
+	if req.Discount != nil && req.Discount.Currency != currency {
+		return ErrMixedCurrencies
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package calculator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce/tax-service/internal/domain"
+	"github.com/ecommerce/tax-service/internal/metrics"
+	"github.com/ecommerce/tax-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// ProviderTables names calculations made from the jurisdiction tables
+const ProviderTables = "tables"
+
+// Tables calculates tax from the jurisdiction tables and the products' tax
+// classes, caching the jurisdictions covering each address
+type Tables struct {
+	repo   repository.TaxRepository
+	cache  repository.CacheRepository
+	ttl    time.Duration
+	logger *zap.Logger
+}
+
+// NewTables creates the tables provider, caching jurisdictions for ttl
+func NewTables(repo repository.TaxRepository, cache repository.CacheRepository, ttl time.Duration, logger *zap.Logger) *Tables {
+	return &Tables{
+		repo:   repo,
+		cache:  cache,
+		ttl:    ttl,
+		logger: logger,
+	}
+}
+
+// Name identifies the provider in calculations
+func (t *Tables) Name() string {
+	return ProviderTables
+}
+
+// Calculate taxes each line at the rates for its tax class of every
+// jurisdiction covering the address. Addresses no jurisdiction covers are
+// taxed at zero.
+func (t *Tables) Calculate(ctx context.Context, req *domain.Request) (*domain.Calculation, error) {
+	productIDs := make([]string, 0, len(req.Lines))
+	for _, line := range req.Lines {
+		if line.TaxClass == "" {
+			productIDs = append(productIDs, line.ProductID)
+		}
+	}
+	classes := map[string]string{}
+	if len(productIDs) > 0 {
+		var err error
+		if classes, err = t.repo.ProductClasses(ctx, productIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	jurisdictions, err := t.jurisdictions(ctx, req.Address)
+	if err != nil {
+		return nil, err
+	}
+	if len(jurisdictions) == 0 {
+		metrics.UnmatchedAddressesTotal.WithLabelValues(req.Address.Country).Inc()
+	}
+
+	return Compute(req, classes, jurisdictions), nil
+}
+
+// Compute taxes req's lines: the discount is spread over them, then each
+// jurisdiction's rate for the line's class is applied to what is left, and
+// rounded, separately. classes are the products' tax classes; lines of
+// products without one are standard.
+func Compute(req *domain.Request, classes map[string]string, jurisdictions []*domain.Jurisdiction) *domain.Calculation {
+	currency := ""
+	if len(req.Lines) > 0 {
+		currency = req.Lines[0].Amount.Currency
+	}
+
+	amounts := make([]money.Money, len(req.Lines))
+	for i, line := range req.Lines {
+		amounts[i] = line.Amount
+	}
+	discount := money.New(0, currency)
+	if req.Discount != nil {
+		discount = *req.Discount
+	}
+	discounts := domain.AllocateDiscount(amounts, discount)
+
+	calculation := &domain.Calculation{
+		Currency:      currency,
+		Address:       req.Address,
+		Lines:         make([]domain.Line, 0, len(req.Lines)),
+		TaxableAmount: money.New(0, currency),
+		TaxAmount:     money.New(0, currency),
+	}
+	for i, requested := range req.Lines {
+		class := requested.TaxClass
+		if class == "" {
+			class = classes[requested.ProductID]
+		}
+		if class == "" {
+			class = domain.DefaultClass
+		}
+
+		line := domain.Line{
+			ProductID:     requested.ProductID,
+			TaxClass:      class,
+			Amount:        requested.Amount,
+			Discount:      discounts[i],
+			TaxableAmount: money.New(requested.Amount.MinorUnits-discounts[i].MinorUnits, currency),
+			Tax:           money.New(0, currency),
+			Jurisdictions: make([]domain.JurisdictionTax, 0, len(jurisdictions)),
+		}
+		for _, jurisdiction := range jurisdictions {
+			rate := jurisdiction.RateFor(class)
+			tax := rate.Of(line.TaxableAmount)
+			line.Jurisdictions = append(line.Jurisdictions, domain.JurisdictionTax{
+				JurisdictionID: jurisdiction.ID,
+				Name:           jurisdiction.Name,
+				Rate:           rate,
+				Tax:            tax,
+			})
+			line.Rate += rate
+			line.Tax.MinorUnits += tax.MinorUnits
+		}
+
+		calculation.TaxableAmount.MinorUnits += line.TaxableAmount.MinorUnits
+		calculation.TaxAmount.MinorUnits += line.Tax.MinorUnits
+		calculation.Lines = append(calculation.Lines, line)
+	}
+	return calculation
+}
+
+// jurisdictions returns the jurisdictions covering address, from the cache
+// when they are there; the cache being down only costs a query
+func (t *Tables) jurisdictions(ctx context.Context, address domain.Address) ([]*domain.Jurisdiction, error) {
+	key := fmt.Sprintf("jurisdictions:%s:%s:%s", address.Country, address.Region, address.PostalCode)
+	log := logging.WithContext(ctx, t.logger)
+
+	var cached []*domain.Jurisdiction
+	found, err := t.cache.Get(ctx, key, &cached)
+	if err != nil {
+		log.Warn("Failed to read cached jurisdictions", zap.String("key", key), zap.Error(err))
+	}
+	if found {
+		metrics.JurisdictionLookupsTotal.WithLabelValues("hit").Inc()
+		return cached, nil
+	}
+
+	jurisdictions, err := t.repo.MatchJurisdictions(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	metrics.JurisdictionLookupsTotal.WithLabelValues("miss").Inc()
+
+	if err := t.cache.Set(ctx, key, jurisdictions, t.ttl); err != nil {
+		log.Warn("Failed to cache jurisdictions", zap.String("key", key), zap.Error(err))
+	}
+	return jurisdictions, nil
+}
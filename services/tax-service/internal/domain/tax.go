@@ -0,0 +1,259 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/money"
+)
+
+var (
+	ErrJurisdictionNotFound = errors.New("jurisdiction not found")
+	ErrCalculationNotFound  = errors.New("tax calculation not found")
+	// ErrDuplicateJurisdiction is a jurisdiction with the name and
+	// location of another
+	ErrDuplicateJurisdiction = errors.New("jurisdiction already exists")
+)
+
+// DefaultClass is the tax class of products without one, and the rate
+// every jurisdiction has
+const DefaultClass = "standard"
+
+var classPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// ValidClass reports whether s can name a tax class, e.g. "standard",
+// "food" or "digital_goods"
+func ValidClass(s string) bool {
+	return classPattern.MatchString(s)
+}
+
+// rateScale is how many Rate units make 100%
+const rateScale = 1_000_000
+
+// Rate is a tax rate in millionths, so 72500 is 7.25%. It is written in
+// JSON as a decimal fraction string, e.g. "0.0725".
+type Rate int64
+
+// ParseRate reads a decimal fraction between 0 and 1 with up to six
+// decimals, e.g. "0.08875"
+func ParseRate(s string) (Rate, error) {
+	whole, frac, _ := strings.Cut(strings.TrimSpace(s), ".")
+	if whole == "" && frac == "" || len(frac) > 6 || strings.HasPrefix(whole, "-") || strings.HasPrefix(whole, "+") {
+		return 0, fmt.Errorf("invalid rate %q: must be a fraction with up to 6 decimals, e.g. 0.0725", s)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	frac += strings.Repeat("0", 6-len(frac))
+
+	w, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q: must be a fraction with up to 6 decimals, e.g. 0.0725", s)
+	}
+	f, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid rate %q: must be a fraction with up to 6 decimals, e.g. 0.0725", s)
+	}
+	if w > 1 || w == 1 && f > 0 {
+		return 0, fmt.Errorf("invalid rate %q: must be at most 1", s)
+	}
+	return Rate(w*rateScale + f), nil
+}
+
+// String formats the rate as a decimal fraction without trailing zeros
+func (r Rate) String() string {
+	s := fmt.Sprintf("%d.%06d", int64(r)/rateScale, int64(r)%rateScale)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(r.String())), nil
+}
+
+// UnmarshalJSON reads a rate from a decimal string or number
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	rate, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = rate
+	return nil
+}
+
+// Of is the tax at this rate on amount, rounded half away from zero
+func (r Rate) Of(amount money.Money) money.Money {
+	product := amount.MinorUnits * int64(r)
+	if product < 0 {
+		return money.New(-((-product + rateScale/2) / rateScale), amount.Currency)
+	}
+	return money.New((product+rateScale/2)/rateScale, amount.Currency)
+}
+
+// Address is where an order is shipped, as far as tax depends on it
+type Address struct {
+	// Country is an ISO 3166-1 alpha-2 code
+	Country string `json:"country"`
+	// Region is the state or province code, e.g. "CA"
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+}
+
+// countryAliases are the alpha-3 codes storefronts send for alpha-2 ones
+var countryAliases = map[string]string{
+	"USA": "US",
+	"CAN": "CA",
+	"GBR": "GB",
+	"AUS": "AU",
+	"DEU": "DE",
+	"FRA": "FR",
+}
+
+// Normalize upper-cases the address, drops spaces and dashes from its
+// postal code and maps known alpha-3 country codes to alpha-2
+func (a *Address) Normalize() {
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+	if alpha2, ok := countryAliases[a.Country]; ok {
+		a.Country = alpha2
+	}
+	a.Region = strings.ToUpper(strings.TrimSpace(a.Region))
+	a.PostalCode = NormalizePostalCode(a.PostalCode)
+}
+
+// NormalizePostalCode upper-cases a postal code or prefix and drops its
+// spaces and dashes, so "sw1a 1aa" and "SW1A1AA" match
+func NormalizePostalCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+}
+
+// Jurisdiction is an area with its tax rates: a country, a region of it,
+// or the postal codes of a region starting with PostalPrefix
+type Jurisdiction struct {
+	ID           string          `json:"id"`
+	Name         string          `json:"name"`
+	Country      string          `json:"country"`
+	Region       string          `json:"region"`
+	PostalPrefix string          `json:"postal_prefix"`
+	Rates        map[string]Rate `json:"rates"`
+	CreatedBy    string          `json:"created_by,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// RateFor is the jurisdiction's rate for a tax class, or its standard
+// rate if the class has none
+func (j *Jurisdiction) RateFor(class string) Rate {
+	if rate, ok := j.Rates[class]; ok {
+		return rate
+	}
+	return j.Rates[DefaultClass]
+}
+
+// ProductClass is a product's tax class
+type ProductClass struct {
+	ProductID string     `json:"product_id"`
+	TaxClass  string     `json:"tax_class"`
+	UpdatedBy string     `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Request is what to calculate the tax of: lines shipped to an address,
+// less an order-wide discount
+type Request struct {
+	// Reference identifies what the tax is for, e.g. an order ID
+	Reference string
+	// UserID is the customer the calculation was made for
+	UserID  string
+	Address Address
+	Lines   []RequestLine
+	// Discount is taken off the lines in proportion to their amounts
+	// before tax; nil for none
+	Discount *money.Money
+}
+
+// RequestLine is a product and its price times quantity
+type RequestLine struct {
+	ProductID string
+	Amount    money.Money
+	// TaxClass overrides the product's; empty uses it
+	TaxClass string
+}
+
+// Calculation is the tax of a Request, line by line and jurisdiction by
+// jurisdiction
+type Calculation struct {
+	ID        string  `json:"id,omitempty"`
+	Reference string  `json:"reference,omitempty"`
+	UserID    string  `json:"user_id,omitempty"`
+	Provider  string  `json:"provider"`
+	Currency  string  `json:"currency"`
+	Address   Address `json:"address"`
+	Lines     []Line  `json:"lines"`
+	// TaxableAmount is the lines' amounts less the discount
+	TaxableAmount money.Money `json:"taxable_amount"`
+	TaxAmount     money.Money `json:"tax_amount"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+// Line is the tax of a request line
+type Line struct {
+	ProductID     string      `json:"product_id"`
+	TaxClass      string      `json:"tax_class"`
+	Amount        money.Money `json:"amount"`
+	Discount      money.Money `json:"discount"`
+	TaxableAmount money.Money `json:"taxable_amount"`
+	// Rate is the jurisdictions' rates combined
+	Rate          Rate              `json:"rate"`
+	Tax           money.Money       `json:"tax"`
+	Jurisdictions []JurisdictionTax `json:"jurisdictions"`
+}
+
+// JurisdictionTax is a jurisdiction's share of a line's tax
+type JurisdictionTax struct {
+	JurisdictionID string      `json:"jurisdiction_id,omitempty"`
+	Name           string      `json:"name"`
+	Rate           Rate        `json:"rate"`
+	Tax            money.Money `json:"tax"`
+}
+
+// AllocateDiscount splits discount over amounts in proportion to them; the
+// minor units lost to rounding go to the first amounts. discount must not
+// exceed their total.
+func AllocateDiscount(amounts []money.Money, discount money.Money) []money.Money {
+	shares := make([]money.Money, len(amounts))
+	var total int64
+	for i, amount := range amounts {
+		shares[i] = money.New(0, discount.Currency)
+		total += amount.MinorUnits
+	}
+	if total <= 0 || discount.MinorUnits <= 0 {
+		return shares
+	}
+
+	remaining := discount.MinorUnits
+	for i, amount := range amounts {
+		share := discount.MinorUnits * amount.MinorUnits / total
+		shares[i].MinorUnits = share
+		remaining -= share
+	}
+	for i := 0; remaining > 0; i = (i + 1) % len(amounts) {
+		if shares[i].MinorUnits < amounts[i].MinorUnits {
+			shares[i].MinorUnits++
+			remaining--
+		}
+	}
+	return shares
+}
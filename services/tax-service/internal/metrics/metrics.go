@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// CalculationsTotal counts tax calculations by provider and result:
+	// calculated, recorded (calculated and stored for a reference),
+	// invalid (e.g. mixed currencies) or failed
+	CalculationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tax_calculations_total",
+		Help: "Tax calculations, per provider and result",
+	}, []string{"provider", "result"})
+
+	// JurisdictionLookupsTotal counts lookups of the jurisdictions covering
+	// an address by result: hit or miss in the cache
+	JurisdictionLookupsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tax_jurisdiction_lookups_total",
+		Help: "Jurisdiction lookups for addresses, per cache result",
+	}, []string{"result"})
+
+	// UnmatchedAddressesTotal counts calculations for addresses no
+	// jurisdiction covers, taxed at zero, by country
+	UnmatchedAddressesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tax_unmatched_addresses_total",
+		Help: "Calculations for addresses without a jurisdiction, per country",
+	}, []string{"country"})
+
+	// RateChangesTotal counts jurisdiction and product class changes by
+	// action, e.g. jurisdiction.created or product_class.set
+	RateChangesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tax_rate_changes_total",
+		Help: "Changes to jurisdictions and product tax classes, per action",
+	}, []string{"action"})
+)
@@ -0,0 +1,409 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	shareddb "github.com/ecommerce-platform/shared/go/db"
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce/tax-service/internal/domain"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is the SQLSTATE of a duplicate key
+const uniqueViolation = "23505"
+
+const jurisdictionColumns = `id, name, country, region, postal_prefix, created_by, created_at, updated_at`
+
+const calculationColumns = `
+	id, reference, user_id, provider, currency, address, lines, taxable_amount, tax_amount, created_at
+`
+
+type postgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *sql.DB) TaxRepository {
+	return &postgresRepository{db: db}
+}
+
+// Ping checks that the database is reachable
+func (r *postgresRepository) Ping(ctx context.Context) error {
+	return shareddb.Check(ctx, r.db)
+}
+
+// CreateJurisdiction stores a jurisdiction and its rates in one transaction
+func (r *postgresRepository) CreateJurisdiction(ctx context.Context, jurisdiction *domain.Jurisdiction) error {
+	now := time.Now().UTC()
+	jurisdiction.ID = uuid.New().String()
+	jurisdiction.CreatedAt = now
+	jurisdiction.UpdatedAt = now
+
+	err := shareddb.WithTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO jurisdictions (id, name, country, region, postal_prefix, created_by, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`,
+			jurisdiction.ID, jurisdiction.Name, jurisdiction.Country, jurisdiction.Region,
+			jurisdiction.PostalPrefix, jurisdiction.CreatedBy, jurisdiction.CreatedAt, jurisdiction.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		return insertRates(ctx, tx, jurisdiction)
+	})
+	return duplicateJurisdiction(err)
+}
+
+// GetJurisdiction returns a jurisdiction with its rates
+func (r *postgresRepository) GetJurisdiction(ctx context.Context, id string) (*domain.Jurisdiction, error) {
+	jurisdictions, err := r.queryJurisdictions(ctx, `WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(jurisdictions) == 0 {
+		return nil, domain.ErrJurisdictionNotFound
+	}
+	return jurisdictions[0], nil
+}
+
+// UpdateJurisdiction replaces a jurisdiction's name, location and rates in
+// one transaction
+func (r *postgresRepository) UpdateJurisdiction(ctx context.Context, jurisdiction *domain.Jurisdiction) error {
+	jurisdiction.UpdatedAt = time.Now().UTC()
+
+	err := shareddb.WithTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE jurisdictions SET name = $2, country = $3, region = $4, postal_prefix = $5, updated_at = $6
+			WHERE id = $1
+		`,
+			jurisdiction.ID, jurisdiction.Name, jurisdiction.Country, jurisdiction.Region,
+			jurisdiction.PostalPrefix, jurisdiction.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return domain.ErrJurisdictionNotFound
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM jurisdiction_rates WHERE jurisdiction_id = $1`, jurisdiction.ID); err != nil {
+			return err
+		}
+		return insertRates(ctx, tx, jurisdiction)
+	})
+	return duplicateJurisdiction(err)
+}
+
+// DeleteJurisdiction deletes a jurisdiction; its rates go with it
+func (r *postgresRepository) DeleteJurisdiction(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM jurisdictions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return domain.ErrJurisdictionNotFound
+	}
+	return nil
+}
+
+// ListJurisdictions returns jurisdictions sorted by location, of one country
+// when country isn't empty
+func (r *postgresRepository) ListJurisdictions(ctx context.Context, country string) ([]*domain.Jurisdiction, error) {
+	return r.queryJurisdictions(ctx, `WHERE $1 = '' OR country = $1`, country)
+}
+
+// MatchJurisdictions returns the jurisdictions covering an address, the
+// widest first
+func (r *postgresRepository) MatchJurisdictions(ctx context.Context, address domain.Address) ([]*domain.Jurisdiction, error) {
+	return r.queryJurisdictions(ctx, `
+		WHERE country = $1
+			AND (region = '' OR region = $2)
+			AND (postal_prefix = '' OR $3 LIKE postal_prefix || '%')
+	`, address.Country, address.Region, address.PostalCode)
+}
+
+// queryJurisdictions returns the jurisdictions matching where, sorted by
+// location, with their rates
+func (r *postgresRepository) queryJurisdictions(ctx context.Context, where string, args ...interface{}) ([]*domain.Jurisdiction, error) {
+	query := `
+		SELECT ` + jurisdictionColumns + `
+		FROM jurisdictions
+		` + where + `
+		ORDER BY country, region, postal_prefix, name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jurisdictions := []*domain.Jurisdiction{}
+	byID := make(map[string]*domain.Jurisdiction)
+	for rows.Next() {
+		jurisdiction := &domain.Jurisdiction{Rates: map[string]domain.Rate{}}
+		err := rows.Scan(
+			&jurisdiction.ID, &jurisdiction.Name, &jurisdiction.Country, &jurisdiction.Region,
+			&jurisdiction.PostalPrefix, &jurisdiction.CreatedBy, &jurisdiction.CreatedAt, &jurisdiction.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		jurisdictions = append(jurisdictions, jurisdiction)
+		byID[jurisdiction.ID] = jurisdiction
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(jurisdictions) == 0 {
+		return jurisdictions, nil
+	}
+
+	ids := make([]string, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	rateRows, err := r.db.QueryContext(ctx, `
+		SELECT jurisdiction_id, tax_class, rate::TEXT FROM jurisdiction_rates WHERE jurisdiction_id = ANY($1)
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rateRows.Close()
+
+	for rateRows.Next() {
+		var id, class, value string
+		if err := rateRows.Scan(&id, &class, &value); err != nil {
+			return nil, err
+		}
+		rate, err := domain.ParseRate(value)
+		if err != nil {
+			return nil, err
+		}
+		byID[id].Rates[class] = rate
+	}
+
+	return jurisdictions, rateRows.Err()
+}
+
+func insertRates(ctx context.Context, tx *sql.Tx, jurisdiction *domain.Jurisdiction) error {
+	classes := make([]string, 0, len(jurisdiction.Rates))
+	for class := range jurisdiction.Rates {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+
+	for _, class := range classes {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO jurisdiction_rates (jurisdiction_id, tax_class, rate) VALUES ($1, $2, $3)
+		`, jurisdiction.ID, class, jurisdiction.Rates[class].String())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// duplicateJurisdiction maps a unique violation to
+// domain.ErrDuplicateJurisdiction
+func duplicateJurisdiction(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return domain.ErrDuplicateJurisdiction
+	}
+	return err
+}
+
+// ProductClasses returns the tax classes of those of productIDs that have
+// one
+func (r *postgresRepository) ProductClasses(ctx context.Context, productIDs []string) (map[string]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT product_id, tax_class FROM product_tax_classes WHERE product_id = ANY($1)
+	`, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	classes := make(map[string]string, len(productIDs))
+	for rows.Next() {
+		var productID, class string
+		if err := rows.Scan(&productID, &class); err != nil {
+			return nil, err
+		}
+		classes[productID] = class
+	}
+
+	return classes, rows.Err()
+}
+
+// GetProductClass returns a product's tax class, the default one for
+// products without
+func (r *postgresRepository) GetProductClass(ctx context.Context, productID string) (*domain.ProductClass, error) {
+	class := &domain.ProductClass{ProductID: productID}
+	var updatedAt time.Time
+	err := r.db.QueryRowContext(ctx, `
+		SELECT tax_class, updated_by, updated_at FROM product_tax_classes WHERE product_id = $1
+	`, productID).Scan(&class.TaxClass, &class.UpdatedBy, &updatedAt)
+	if err == sql.ErrNoRows {
+		class.TaxClass = domain.DefaultClass
+		return class, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	class.UpdatedAt = &updatedAt
+	return class, nil
+}
+
+// SetProductClass sets a product's tax class
+func (r *postgresRepository) SetProductClass(ctx context.Context, class *domain.ProductClass) error {
+	now := time.Now().UTC()
+	class.UpdatedAt = &now
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO product_tax_classes (product_id, tax_class, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (product_id) DO UPDATE SET
+			tax_class = EXCLUDED.tax_class, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at
+	`, class.ProductID, class.TaxClass, class.UpdatedBy, now)
+	return err
+}
+
+// DeleteProductClass makes a product standard again
+func (r *postgresRepository) DeleteProductClass(ctx context.Context, productID string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM product_tax_classes WHERE product_id = $1`, productID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// SaveCalculation stores a calculation with the rates applied to each line
+func (r *postgresRepository) SaveCalculation(ctx context.Context, calculation *domain.Calculation) error {
+	calculation.ID = uuid.New().String()
+	if calculation.CreatedAt.IsZero() {
+		calculation.CreatedAt = time.Now().UTC()
+	}
+
+	address, err := json.Marshal(calculation.Address)
+	if err != nil {
+		return err
+	}
+	lines, err := json.Marshal(calculation.Lines)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO tax_calculations (
+			id, reference, user_id, provider, currency, address, lines, taxable_amount, tax_amount, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`,
+		calculation.ID, calculation.Reference, calculation.UserID, calculation.Provider, calculation.Currency,
+		address, lines, calculation.TaxableAmount.MinorUnits, calculation.TaxAmount.MinorUnits,
+		calculation.CreatedAt.UTC(),
+	)
+	return err
+}
+
+// GetCalculation returns a stored calculation
+func (r *postgresRepository) GetCalculation(ctx context.Context, id string) (*domain.Calculation, error) {
+	query := `SELECT ` + calculationColumns + ` FROM tax_calculations WHERE id = $1`
+
+	calculation, err := scanCalculation(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrCalculationNotFound
+	}
+	return calculation, err
+}
+
+// ListCalculations retrieves up to limit calculations after a position,
+// newest first
+func (r *postgresRepository) ListCalculations(ctx context.Context, filter CalculationFilter, limit int, after *CalculationPosition) ([]*domain.Calculation, error) {
+	where, args := calculationWhere(filter)
+	if after != nil {
+		args = append(args, after.CreatedAt, after.ID)
+		where += ` AND (created_at, id) < ($` + strconv.Itoa(len(args)-1) + `, $` + strconv.Itoa(len(args)) + `)`
+	}
+	args = append(args, limit)
+
+	query := `
+		SELECT ` + calculationColumns + `
+		FROM tax_calculations
+		WHERE ` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $` + strconv.Itoa(len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	calculations := []*domain.Calculation{}
+	for rows.Next() {
+		calculation, err := scanCalculation(rows)
+		if err != nil {
+			return nil, err
+		}
+		calculations = append(calculations, calculation)
+	}
+
+	return calculations, rows.Err()
+}
+
+// CountCalculations counts the calculations matching filter
+func (r *postgresRepository) CountCalculations(ctx context.Context, filter CalculationFilter) (int64, error) {
+	where, args := calculationWhere(filter)
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tax_calculations WHERE `+where, args...).Scan(&count)
+	return count, err
+}
+
+func calculationWhere(filter CalculationFilter) (string, []interface{}) {
+	where := `TRUE`
+	var args []interface{}
+	if filter.Reference != "" {
+		args = append(args, filter.Reference)
+		where += ` AND reference = $1`
+	}
+	return where, args
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCalculation(row scanner) (*domain.Calculation, error) {
+	calculation := &domain.Calculation{}
+	var address, lines []byte
+	var taxable, tax int64
+	err := row.Scan(
+		&calculation.ID, &calculation.Reference, &calculation.UserID, &calculation.Provider,
+		&calculation.Currency, &address, &lines, &taxable, &tax, &calculation.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(address, &calculation.Address); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(lines, &calculation.Lines); err != nil {
+		return nil, err
+	}
+	calculation.TaxableAmount = money.New(taxable, calculation.Currency)
+	calculation.TaxAmount = money.New(tax, calculation.Currency)
+	return calculation, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ecommerce/tax-service/internal/domain"
+)
+
+// TaxRepository defines tax data operations
+type TaxRepository interface {
+	// Jurisdictions
+	// CreateJurisdiction stores a jurisdiction and its rates, or returns
+	// domain.ErrDuplicateJurisdiction
+	CreateJurisdiction(ctx context.Context, jurisdiction *domain.Jurisdiction) error
+	GetJurisdiction(ctx context.Context, id string) (*domain.Jurisdiction, error)
+	// UpdateJurisdiction replaces a jurisdiction's name, location and rates
+	UpdateJurisdiction(ctx context.Context, jurisdiction *domain.Jurisdiction) error
+	DeleteJurisdiction(ctx context.Context, id string) error
+	// ListJurisdictions returns jurisdictions sorted by location, of one
+	// country when country isn't empty
+	ListJurisdictions(ctx context.Context, country string) ([]*domain.Jurisdiction, error)
+	// MatchJurisdictions returns the jurisdictions covering an address:
+	// those of its country without a region or of its region, without a
+	// postal prefix or with one its postal code starts with
+	MatchJurisdictions(ctx context.Context, address domain.Address) ([]*domain.Jurisdiction, error)
+
+	// Product tax classes
+	// ProductClasses returns the tax classes of those of productIDs that
+	// have one
+	ProductClasses(ctx context.Context, productIDs []string) (map[string]string, error)
+	GetProductClass(ctx context.Context, productID string) (*domain.ProductClass, error)
+	SetProductClass(ctx context.Context, class *domain.ProductClass) error
+	// DeleteProductClass makes a product standard again, reporting whether
+	// it had a class
+	DeleteProductClass(ctx context.Context, productID string) (bool, error)
+
+	// Calculations
+	SaveCalculation(ctx context.Context, calculation *domain.Calculation) error
+	GetCalculation(ctx context.Context, id string) (*domain.Calculation, error)
+	// ListCalculations returns up to limit calculations after a position,
+	// newest first
+	ListCalculations(ctx context.Context, filter CalculationFilter, limit int, after *CalculationPosition) ([]*domain.Calculation, error)
+	CountCalculations(ctx context.Context, filter CalculationFilter) (int64, error)
+
+	// Ping checks that the database is reachable
+	Ping(ctx context.Context) error
+}
+
+// CalculationFilter selects the calculations to list
+type CalculationFilter struct {
+	// Reference, when set, lists only the calculations of e.g. one order
+	Reference string
+}
+
+// CalculationPosition is the cursor position of calculation lists
+type CalculationPosition struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+}
+
+// CacheRepository caches the jurisdictions covering addresses
+type CacheRepository interface {
+	// Get decodes the value at key into dest, reporting whether it was
+	// cached
+	Get(ctx context.Context, key string, dest interface{}) (bool, error)
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	// FlushAll drops every cached value, e.g. once rates change
+	FlushAll(ctx context.Context) error
+}
@@ -0,0 +1,56 @@
+-- Tax jurisdictions: a country, or a region of it, or the postal codes of
+-- a region starting with a prefix. Every jurisdiction covering an address
+-- applies, so state, county and city taxes add up.
+CREATE TABLE IF NOT EXISTS jurisdictions (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    -- ISO 3166-1 alpha-2
+    country CHAR(2) NOT NULL,
+    -- '' covers the whole country
+    region VARCHAR(10) NOT NULL DEFAULT '',
+    -- '' covers the whole region
+    postal_prefix VARCHAR(20) NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (country, region, postal_prefix, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_jurisdictions_location ON jurisdictions(country, region);
+
+-- A jurisdiction's rate per product tax class, as a fraction, e.g.
+-- 0.0725; classes without a rate pay the 'standard' one
+CREATE TABLE IF NOT EXISTS jurisdiction_rates (
+    jurisdiction_id VARCHAR(36) NOT NULL REFERENCES jurisdictions(id) ON DELETE CASCADE,
+    tax_class VARCHAR(50) NOT NULL,
+    rate NUMERIC(7, 6) NOT NULL CHECK (rate >= 0 AND rate <= 1),
+    PRIMARY KEY (jurisdiction_id, tax_class)
+);
+
+-- Products' tax classes; products not listed are 'standard'
+CREATE TABLE IF NOT EXISTS product_tax_classes (
+    product_id VARCHAR(255) PRIMARY KEY,
+    tax_class VARCHAR(50) NOT NULL,
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Calculations made for orders, with the rates applied to each line, so
+-- an order's tax can be explained after rates change
+CREATE TABLE IF NOT EXISTS tax_calculations (
+    id VARCHAR(36) PRIMARY KEY,
+    -- the caller's reference, e.g. the order ID
+    reference VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL DEFAULT '',
+    provider VARCHAR(50) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    address JSONB NOT NULL,
+    lines JSONB NOT NULL,
+    -- minor units
+    taxable_amount BIGINT NOT NULL,
+    tax_amount BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_tax_calculations_reference ON tax_calculations(reference, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_tax_calculations_created_at ON tax_calculations(created_at DESC, id DESC);
@@ -0,0 +1,23 @@
+-- Example state-level sales tax rates, so orders are taxed out of the box.
+-- They aren't kept current: manage real rates through the API, or a
+-- provider such as Avalara or TaxJar.
+INSERT INTO jurisdictions (id, name, country, region, created_by) VALUES
+    ('us-ca', 'California', 'US', 'CA', 'seed'),
+    ('us-fl', 'Florida', 'US', 'FL', 'seed'),
+    ('us-ny', 'New York', 'US', 'NY', 'seed'),
+    ('us-tx', 'Texas', 'US', 'TX', 'seed'),
+    ('us-wa', 'Washington', 'US', 'WA', 'seed')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO jurisdiction_rates (jurisdiction_id, tax_class, rate) VALUES
+    ('us-ca', 'standard', 0.0725),
+    ('us-ca', 'food', 0),
+    ('us-fl', 'standard', 0.06),
+    ('us-fl', 'food', 0),
+    ('us-ny', 'standard', 0.04),
+    ('us-ny', 'food', 0),
+    ('us-tx', 'standard', 0.0625),
+    ('us-tx', 'food', 0),
+    ('us-wa', 'standard', 0.065),
+    ('us-wa', 'food', 0)
+ON CONFLICT DO NOTHING;
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,76 +12,148 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
-
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	userv1 "github.com/ecommerce-platform/proto/gen/go/ecommerce/user/v1"
+	"github.com/ecommerce-platform/shared/go/audit"
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	sharedconfig "github.com/ecommerce-platform/shared/go/config"
+	shareddb "github.com/ecommerce-platform/shared/go/db"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/httpmetrics"
+	"github.com/ecommerce-platform/shared/go/interceptors"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce-platform/shared/go/mtls"
+	"github.com/ecommerce-platform/shared/go/ratelimit"
+	"github.com/ecommerce-platform/shared/go/validation"
 	"github.com/ecommerce/user-service/internal/auth"
 	"github.com/ecommerce/user-service/internal/config"
 	"github.com/ecommerce/user-service/internal/database"
 	"github.com/ecommerce/user-service/internal/handlers"
-	"github.com/ecommerce/user-service/internal/middleware"
+	"github.com/ecommerce/user-service/internal/media"
 	"github.com/ecommerce/user-service/internal/routes"
 	"github.com/ecommerce/user-service/internal/services"
+	"github.com/ecommerce/user-service/migrations"
 )
 
 func main() {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Initialize logger
-	logger, err := zap.NewProduction()
+	logger, err := logging.New(logging.Config{
+		ServiceName: "user-service",
+		Environment: cfg.Environment,
+		Level:       os.Getenv("LOG_LEVEL"),
+	})
 	if err != nil {
 		panic(fmt.Sprintf("Failed to initialize logger: %v", err))
 	}
 	defer logger.Sync()
 
-	// Load configuration
-	cfg := config.Load()
-	logger.Info("Configuration loaded",
-		zap.String("environment", cfg.Environment),
-		zap.String("port", cfg.Port),
-	)
+	logger.Info("Configuration loaded", zap.Any("config", sharedconfig.Redacted(cfg)))
+
+	// Initialize mTLS
+	serviceTLS, err := mtls.New(cfg.TLS, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize mTLS", zap.Error(err))
+	}
+	defer serviceTLS.Close()
 
 	// Connect to database
-	db, err := database.Connect(cfg, logger)
+	db, err := database.Connect(context.Background(), cfg, logger)
 	if err != nil {
 		logger.Fatal("Failed to connect to database", zap.Error(err))
 	}
 	defer db.Close()
 
-	// Initialize database schema
-	if err := database.InitSchema(db, logger); err != nil {
-		logger.Fatal("Failed to initialize database schema", zap.Error(err))
+	// Apply pending migrations
+	if err := shareddb.Migrate(context.Background(), db, migrations.FS, logger); err != nil {
+		logger.Fatal("Failed to migrate database", zap.Error(err))
 	}
 
 	// Initialize repositories
 	userRepo := database.NewUserRepository(db)
 
 	// Initialize services
-	jwtService := auth.NewJWTService(cfg)
-	userService := services.NewUserService(userRepo, jwtService, logger)
+	verifier, err := sharedauth.NewVerifier(sharedauth.Config{Secret: cfg.JWTSecret, Issuer: sharedauth.Issuer})
+	if err != nil {
+		logger.Fatal("Failed to create token verifier", zap.Error(err))
+	}
+	jwtService := auth.NewJWTService(cfg, verifier)
+	mediaClient := media.NewClient(cfg.MediaServiceURL, cfg.MediaServiceAPIKey, serviceTLS.Transport())
+	userService := services.NewUserService(userRepo, jwtService, mediaClient, logger)
+
+	// Admin changes to users are audit logged
+	auditor := audit.New(audit.Config{
+		Service: "user-service",
+		Sinks:   []audit.Sink{audit.NewPostgresSink(db)},
+	}, logger)
 
 	// Initialize handlers
 	userHandler := handlers.NewUserHandler(userService, logger)
+	healthHandler := handlers.NewHealthHandler(db, logger)
+	adminHandler := handlers.NewAdminHandler(userService, auditor, logger)
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(jwtService, logger)
+	authMiddleware := sharedauth.NewMiddleware(verifier, logger)
+
+	// Rate limiting is shared across replicas through Redis
+	var limiter *ratelimit.Limiter
+	if cfg.RedisAddr != "" {
+		redisClient := redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+		})
+		defer redisClient.Close()
+		limiter = ratelimit.NewLimiter(redisClient, "user-service")
+	} else {
+		logger.Warn("REDIS_ADDR not set, rate limiting disabled")
+	}
 
 	// Setup router
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// Request bodies may use the shared rules, e.g. sku and e164
+	if err := validation.RegisterGin(); err != nil {
+		logger.Fatal("Failed to register validation rules", zap.Error(err))
+	}
+
 	router := gin.Default()
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logger.Fatal("Invalid TRUSTED_PROXIES", zap.Error(err))
+	}
+	router.Use(httpmetrics.Middleware("user-service"))
 
 	// CORS middleware
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:3001"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// Renders errors handlers abort with
+	router.Use(apperrors.Middleware(logger))
+
+	// Metrics for Prometheus
+	router.GET("/metrics", gin.WrapH(httpmetrics.Handler()))
+
 	// Setup routes
-	routes.SetupRoutes(router, userHandler, authMiddleware)
+	routes.SetupRoutes(router, healthHandler, userHandler, adminHandler, authMiddleware, sharedauth.RequireServiceKey(cfg.ServiceAPIKey, logger), limiter, logger)
 
 	// Create HTTP server
 	srv := &http.Server{
@@ -91,11 +164,27 @@ func main() {
 	// Start server in goroutine
 	go func() {
 		logger.Info("Starting User Service", zap.String("port", cfg.Port))
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := serviceTLS.ListenAndServe(srv); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("Failed to start server", zap.Error(err))
 		}
 	}()
 
+	// Internal gRPC API; token validation is public over HTTP too
+	grpcServer := grpc.NewServer(interceptors.ServerOptions(interceptors.ServerConfig{Logger: logger, TLS: serviceTLS.ServerConfig()})...)
+	userv1.RegisterAuthServiceServer(grpcServer, handlers.NewAuthServer(userService))
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+
+	grpcListener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+	if err != nil {
+		logger.Fatal("Failed to listen for gRPC", zap.Error(err))
+	}
+	go func() {
+		logger.Info("Starting gRPC server", zap.String("port", cfg.GRPCPort))
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			logger.Fatal("Failed to serve gRPC", zap.Error(err))
+		}
+	}()
+
 	// Wait for interrupt signal for graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -110,6 +199,11 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Fatal("Server forced to shutdown", zap.Error(err))
 	}
+	grpcServer.GracefulStop()
+
+	if err := auditor.Close(ctx); err != nil {
+		logger.Error("Failed to write pending audit records", zap.Error(err))
+	}
 
 	logger.Info("User Service stopped")
 }
@@ -4,38 +4,36 @@ import (
 	"fmt"
 	"time"
 
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
 	"github.com/golang-jwt/jwt/v5"
 
 	"github.com/ecommerce/user-service/internal/config"
 	"github.com/ecommerce/user-service/internal/models"
 )
 
-type Claims struct {
-	UserID string          `json:"user_id"`
-	Email  string          `json:"email"`
-	Role   models.UserRole `json:"role"`
-	jwt.RegisteredClaims
-}
-
+// JWTService issues tokens; they are verified by the shared verifier, as in
+// every other service
 type JWTService struct {
-	config *config.Config
+	config   *config.Config
+	verifier *sharedauth.Verifier
 }
 
-func NewJWTService(cfg *config.Config) *JWTService {
-	return &JWTService{config: cfg}
+func NewJWTService(cfg *config.Config, verifier *sharedauth.Verifier) *JWTService {
+	return &JWTService{config: cfg, verifier: verifier}
 }
 
 func (s *JWTService) GenerateToken(user *models.User) (string, error) {
 	expirationTime := time.Now().Add(time.Duration(s.config.JWTExpiryHours) * time.Hour)
 
-	claims := &Claims{
-		UserID: user.ID,
-		Email:  user.Email,
-		Role:   user.Role,
+	claims := &sharedauth.Claims{
+		UserID:   user.ID,
+		Email:    user.Email,
+		Role:     string(user.Role),
+		TenantID: user.TenantID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "ecommerce-user-service",
+			Issuer:    sharedauth.Issuer,
 			Subject:   user.ID,
 		},
 	}
@@ -49,26 +47,8 @@ func (s *JWTService) GenerateToken(user *models.User) (string, error) {
 	return tokenString, nil
 }
 
-func (s *JWTService) ValidateToken(tokenString string) (*Claims, error) {
-	claims := &Claims{}
-
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		// Validate signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.config.JWTSecret), nil
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse token: %w", err)
-	}
-
-	if !token.Valid {
-		return nil, fmt.Errorf("invalid token")
-	}
-
-	return claims, nil
+func (s *JWTService) ValidateToken(tokenString string) (*sharedauth.Claims, error) {
+	return s.verifier.Verify(tokenString)
 }
 
 func (s *JWTService) RefreshToken(oldTokenString string) (string, error) {
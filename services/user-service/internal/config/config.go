@@ -1,46 +1,74 @@
 package config
 
 import (
+	"errors"
 	"os"
-	"strconv"
+
+	sharedconfig "github.com/ecommerce-platform/shared/go/config"
+	"github.com/ecommerce-platform/shared/go/mtls"
+	"github.com/ecommerce-platform/shared/go/secrets"
 )
 
+// defaultJWTSecret only suits local development; it matches JWT_SECRET's
+// default tag
+const defaultJWTSecret = "your-secret-key-change-in-production"
+
 type Config struct {
-	Port           string
-	DBHost         string
-	DBPort         string
-	DBUser         string
-	DBPassword     string
-	DBName         string
-	JWTSecret      string
-	JWTExpiryHours int
-	Environment    string
+	Port           string `env:"PORT" flag:"port" default:"8084"`
+	GRPCPort       string `env:"GRPC_PORT" flag:"grpc-port" default:"9084"` // internal ecommerce.user.v1 API
+	DBHost         string `env:"DB_HOST" default:"localhost"`
+	DBPort         string `env:"DB_PORT" default:"5432"`
+	DBUser         string `env:"DB_USER" default:"postgres"`
+	DBPassword     string `env:"DB_PASSWORD" default:"postgres" secret:"true"`
+	DBName         string `env:"DB_NAME" default:"users_db"`
+	DBMaxOpenConns int    `env:"DB_MAX_OPEN_CONNS" default:"25"`
+	DBMaxIdleConns int    `env:"DB_MAX_IDLE_CONNS" default:"10"`
+	JWTSecret      string `env:"JWT_SECRET" default:"your-secret-key-change-in-production" secret:"true"`
+	JWTExpiryHours int    `env:"JWT_EXPIRY_HOURS" default:"24"`
+	Environment    string `env:"ENVIRONMENT" default:"development"`
+	ServiceAPIKey  string `env:"SERVICE_API_KEY" secret:"true"`
+
+	// Redis backs rate limiting, which is off when RedisAddr is empty
+	RedisAddr     string `env:"REDIS_ADDR"`
+	RedisPassword string `env:"REDIS_PASSWORD" secret:"true"`
+	RedisDB       int    `env:"REDIS_DB" default:"0"`
+
+	// Proxies, as IPs or CIDRs, whose X-Forwarded-For is believed for the
+	// client IP that rate limits count by; with none it is the peer address
+	TrustedProxies []string `env:"TRUSTED_PROXIES"`
+
+	// media-service, which avatars are uploaded to; empty disables avatars
+	MediaServiceURL    string `env:"MEDIA_SERVICE_URL"`
+	MediaServiceAPIKey string `env:"MEDIA_SERVICE_API_KEY" secret:"true"`
+
+	// Mutual TLS for the service's servers and its clients of other
+	// services
+	TLS mtls.Config
 }
 
-func Load() *Config {
-	jwtExpiryHours := 24 // default 24 hours
-	if exp := os.Getenv("JWT_EXPIRY_HOURS"); exp != "" {
-		if hours, err := strconv.Atoi(exp); err == nil {
-			jwtExpiryHours = hours
-		}
+func Load() (*Config, error) {
+	resolver, err := secrets.FromEnv()
+	if err != nil {
+		return nil, err
 	}
 
-	return &Config{
-		Port:           getEnv("PORT", "8084"),
-		DBHost:         getEnv("DB_HOST", "localhost"),
-		DBPort:         getEnv("DB_PORT", "5432"),
-		DBUser:         getEnv("DB_USER", "postgres"),
-		DBPassword:     getEnv("DB_PASSWORD", "postgres"),
-		DBName:         getEnv("DB_NAME", "users_db"),
-		JWTSecret:      getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
-		JWTExpiryHours: jwtExpiryHours,
-		Environment:    getEnv("ENVIRONMENT", "development"),
+	var cfg Config
+	if err := sharedconfig.LoadWith(&cfg, sharedconfig.Options{Args: os.Args[1:], Secrets: resolver}); err != nil {
+		return nil, err
 	}
+	return &cfg, nil
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// Validate rejects settings the service can't run safely with
+func (c *Config) Validate() error {
+	if err := c.TLS.Validate(); err != nil {
+		return err
+	}
+	if c.JWTExpiryHours < 1 {
+		return errors.New("invalid JWT_EXPIRY_HOURS: must be a positive integer")
+	}
+	if c.Environment == "production" && c.JWTSecret == defaultJWTSecret {
+		return errors.New("JWT_SECRET must be set in production")
 	}
-	return defaultValue
+	return nil
 }
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ecommerce/user-service/internal/models"
+)
+
+func (r *UserRepository) FindNotificationPreferences(userID string) (*models.NotificationPreferences, error) {
+	prefs := &models.NotificationPreferences{UserID: userID}
+	var channels, categories []byte
+
+	query := `
+		SELECT channels, categories, timezone, updated_at
+		FROM notification_preferences
+		WHERE user_id = $1
+	`
+
+	err := r.db.QueryRow(query, userID).Scan(&channels, &categories, &prefs.Timezone, &prefs.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("notification preferences not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find notification preferences: %w", err)
+	}
+
+	if err := json.Unmarshal(channels, &prefs.Channels); err != nil {
+		return nil, fmt.Errorf("failed to decode channels: %w", err)
+	}
+	if err := json.Unmarshal(categories, &prefs.Categories); err != nil {
+		return nil, fmt.Errorf("failed to decode categories: %w", err)
+	}
+
+	return prefs, nil
+}
+
+func (r *UserRepository) UpsertNotificationPreferences(prefs *models.NotificationPreferences) error {
+	prefs.UpdatedAt = time.Now()
+
+	channels, err := json.Marshal(prefs.Channels)
+	if err != nil {
+		return fmt.Errorf("failed to encode channels: %w", err)
+	}
+	categories, err := json.Marshal(prefs.Categories)
+	if err != nil {
+		return fmt.Errorf("failed to encode categories: %w", err)
+	}
+
+	query := `
+		INSERT INTO notification_preferences (user_id, channels, categories, timezone, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET channels = EXCLUDED.channels, categories = EXCLUDED.categories,
+		    timezone = EXCLUDED.timezone, updated_at = EXCLUDED.updated_at
+	`
+
+	if _, err := r.db.Exec(query, prefs.UserID, channels, categories, prefs.Timezone, prefs.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to save notification preferences: %w", err)
+	}
+
+	return nil
+}
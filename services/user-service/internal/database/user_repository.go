@@ -23,13 +23,14 @@ func (r *UserRepository) Create(user *models.User) error {
 	user.UpdatedAt = time.Now()
 
 	query := `
-		INSERT INTO users (id, email, password_hash, first_name, last_name, phone, role, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO users (id, tenant_id, email, password_hash, first_name, last_name, phone, role, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 	`
 
 	_, err := r.db.Exec(
 		query,
 		user.ID,
+		user.TenantID,
 		user.Email,
 		user.PasswordHash,
 		user.FirstName,
@@ -48,22 +49,28 @@ func (r *UserRepository) Create(user *models.User) error {
 	return nil
 }
 
-func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
+// FindByEmail finds a tenant's user by email address, ignoring case:
+// addresses are stored lower-cased, except those registered before they
+// were normalized
+func (r *UserRepository) FindByEmail(tenantID, email string) (*models.User, error) {
 	user := &models.User{}
 
 	query := `
-		SELECT id, email, password_hash, first_name, last_name, phone, role, is_active, created_at, updated_at
+		SELECT id, tenant_id, email, password_hash, first_name, last_name, phone, avatar_media_id, avatar_url, role, is_active, created_at, updated_at
 		FROM users
-		WHERE email = $1
+		WHERE tenant_id = $1 AND LOWER(email) = LOWER($2)
 	`
 
-	err := r.db.QueryRow(query, email).Scan(
+	err := r.db.QueryRow(query, tenantID, email).Scan(
 		&user.ID,
+		&user.TenantID,
 		&user.Email,
 		&user.PasswordHash,
 		&user.FirstName,
 		&user.LastName,
 		&user.Phone,
+		&user.AvatarMediaID,
+		&user.AvatarURL,
 		&user.Role,
 		&user.IsActive,
 		&user.CreatedAt,
@@ -84,18 +91,21 @@ func (r *UserRepository) FindByID(id string) (*models.User, error) {
 	user := &models.User{}
 
 	query := `
-		SELECT id, email, password_hash, first_name, last_name, phone, role, is_active, created_at, updated_at
+		SELECT id, tenant_id, email, password_hash, first_name, last_name, phone, avatar_media_id, avatar_url, role, is_active, created_at, updated_at
 		FROM users
 		WHERE id = $1
 	`
 
 	err := r.db.QueryRow(query, id).Scan(
 		&user.ID,
+		&user.TenantID,
 		&user.Email,
 		&user.PasswordHash,
 		&user.FirstName,
 		&user.LastName,
 		&user.Phone,
+		&user.AvatarMediaID,
+		&user.AvatarURL,
 		&user.Role,
 		&user.IsActive,
 		&user.CreatedAt,
@@ -170,11 +180,87 @@ func (r *UserRepository) UpdatePassword(userID, newPasswordHash string) error {
 	return nil
 }
 
-func (r *UserRepository) EmailExists(email string) (bool, error) {
+// SetAvatar sets a user's avatar; empty values remove it
+func (r *UserRepository) SetAvatar(userID, mediaID, avatarURL string) error {
+	query := `
+		UPDATE users
+		SET avatar_media_id = $1, avatar_url = $2, updated_at = $3
+		WHERE id = $4
+	`
+
+	result, err := r.db.Exec(query, mediaID, avatarURL, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to update avatar: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// UpdateRole changes a user's role
+func (r *UserRepository) UpdateRole(userID string, role models.UserRole) error {
+	query := `
+		UPDATE users
+		SET role = $1, updated_at = $2
+		WHERE id = $3
+	`
+
+	result, err := r.db.Exec(query, role, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// SetActive activates or deactivates a user
+func (r *UserRepository) SetActive(userID string, active bool) error {
+	query := `
+		UPDATE users
+		SET is_active = $1, updated_at = $2
+		WHERE id = $3
+	`
+
+	result, err := r.db.Exec(query, active, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to update status: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// EmailExists reports whether the address is registered in the tenant
+func (r *UserRepository) EmailExists(tenantID, email string) (bool, error) {
 	var exists bool
-	query := `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE tenant_id = $1 AND LOWER(email) = LOWER($2))`
 
-	err := r.db.QueryRow(query, email).Scan(&exists)
+	err := r.db.QueryRow(query, tenantID, email).Scan(&exists)
 	if err != nil {
 		return false, fmt.Errorf("failed to check email existence: %w", err)
 	}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ecommerce-platform/shared/go/audit"
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/ecommerce/user-service/internal/models"
+	"github.com/ecommerce/user-service/internal/services"
+)
+
+// AdminHandler lets admins manage users. Every change is audit logged.
+type AdminHandler struct {
+	userService *services.UserService
+	auditor     *audit.Auditor
+	logger      *zap.Logger
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(userService *services.UserService, auditor *audit.Auditor, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{
+		userService: userService,
+		auditor:     auditor,
+		logger:      logger,
+	}
+}
+
+// GetUser returns any user of the admin's tenant
+// GET /admin/users/:id
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	user, err := h.userService.GetTenantUser(sharedauth.TenantFromContext(c.Request.Context()), c.Param("id"))
+	if err != nil {
+		h.abort(c, err, "Failed to get user")
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateRole changes a user's role
+// PUT /admin/users/:id/role
+func (h *AdminHandler) UpdateRole(c *gin.Context) {
+	var req models.UpdateUserRoleRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	before, after, err := h.userService.SetUserRole(
+		sharedauth.TenantFromContext(c.Request.Context()),
+		c.GetString(sharedauth.ContextUserID),
+		c.Param("id"),
+		req.Role,
+	)
+	if err != nil {
+		h.abort(c, err, "Failed to update role")
+		return
+	}
+
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   "user.role_changed",
+		Resource: audit.Resource{Type: "user", ID: after.ID},
+		Before:   before,
+		After:    after,
+		Metadata: map[string]string{"role": string(req.Role)},
+	})
+
+	c.JSON(http.StatusOK, after)
+}
+
+// UpdateStatus activates or deactivates a user
+// PUT /admin/users/:id/status
+func (h *AdminHandler) UpdateStatus(c *gin.Context) {
+	var req models.UpdateUserStatusRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	before, after, err := h.userService.SetUserActive(
+		sharedauth.TenantFromContext(c.Request.Context()),
+		c.GetString(sharedauth.ContextUserID),
+		c.Param("id"),
+		*req.IsActive,
+	)
+	if err != nil {
+		h.abort(c, err, "Failed to update status")
+		return
+	}
+
+	action := "user.deactivated"
+	if *req.IsActive {
+		action = "user.activated"
+	}
+	h.auditor.LogGin(c, audit.Entry{
+		Action:   action,
+		Resource: audit.Resource{Type: "user", ID: after.ID},
+		Before:   before,
+		After:    after,
+		Metadata: map[string]string{"is_active": strconv.FormatBool(*req.IsActive)},
+	})
+
+	c.JSON(http.StatusOK, after)
+}
+
+// abort renders a user service error
+func (h *AdminHandler) abort(c *gin.Context, err error, message string) {
+	switch {
+	case err == services.ErrSelfAdministration:
+		apperrors.Abort(c, apperrors.NewForbidden(err.Error()))
+	case strings.HasSuffix(err.Error(), "user not found"):
+		apperrors.Abort(c, apperrors.NewNotFound("User"))
+	default:
+		apperrors.Abort(c, apperrors.Wrap(err, message))
+	}
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+
+	userv1 "github.com/ecommerce-platform/proto/gen/go/ecommerce/user/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ecommerce/user-service/internal/services"
+)
+
+// AuthServer serves the ecommerce.user.v1 gRPC API, for services checking
+// tokens on their hot path
+type AuthServer struct {
+	userv1.UnimplementedAuthServiceServer
+	userService *services.UserService
+}
+
+func NewAuthServer(userService *services.UserService) *AuthServer {
+	return &AuthServer{userService: userService}
+}
+
+// ValidateToken verifies a token as POST /auth/validate does, answering
+// invalid tokens with valid false rather than an error
+func (s *AuthServer) ValidateToken(ctx context.Context, req *userv1.ValidateTokenRequest) (*userv1.ValidateTokenResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Token is required")
+	}
+
+	claims, err := s.userService.ValidateToken(req.GetToken())
+	if err != nil {
+		return &userv1.ValidateTokenResponse{Valid: false}, nil
+	}
+
+	return &userv1.ValidateTokenResponse{
+		Valid:       true,
+		UserId:      claims.UserID,
+		Email:       claims.Email,
+		Role:        claims.Role,
+		Permissions: claims.Permissions,
+	}, nil
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	shareddb "github.com/ecommerce-platform/shared/go/db"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// HealthHandler reports whether the service can serve requests
+type HealthHandler struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(db *sql.DB, logger *zap.Logger) *HealthHandler {
+	return &HealthHandler{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// HealthCheck returns service health status; unhealthy while the database
+// is unreachable
+// GET /health
+func (h *HealthHandler) HealthCheck(c *gin.Context) {
+	if err := shareddb.Check(c.Request.Context(), h.db); err != nil {
+		h.logger.Warn("Health check failed", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unhealthy",
+			"service": "user-service",
+			"error":   "database unavailable",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "healthy",
+		"service": "user-service",
+	})
+}
@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	"github.com/ecommerce-platform/shared/go/contact"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
@@ -28,18 +33,21 @@ func (h *UserHandler) Register(c *gin.Context) {
 	var req models.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.Warn("Invalid registration request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "Invalid request data").WithFields(gin.H{"details": err.Error()}))
 		return
 	}
 
-	response, err := h.userService.Register(req)
+	response, err := h.userService.Register(sharedauth.TenantFromContext(c.Request.Context()), req)
 	if err != nil {
+		if invalid, ok := invalidContact(err); ok {
+			apperrors.Abort(c, invalid)
+			return
+		}
 		if err.Error() == "email already registered" {
-			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			apperrors.Abort(c, apperrors.New(http.StatusConflict, err.Error()))
 			return
 		}
-		h.logger.Error("Failed to register user", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to register user"))
 		return
 	}
 
@@ -66,13 +74,13 @@ func (h *UserHandler) Login(c *gin.Context) {
 	var req models.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.Warn("Invalid login request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "Invalid request data").WithFields(gin.H{"details": err.Error()}))
 		return
 	}
 
-	response, err := h.userService.Login(req)
+	response, err := h.userService.Login(sharedauth.TenantFromContext(c.Request.Context()), req)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		apperrors.Abort(c, apperrors.New(http.StatusUnauthorized, err.Error()))
 		return
 	}
 
@@ -115,14 +123,13 @@ func (h *UserHandler) Logout(c *gin.Context) {
 func (h *UserHandler) GetProfile(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apperrors.Abort(c, apperrors.New(http.StatusUnauthorized, "User not authenticated"))
 		return
 	}
 
 	user, err := h.userService.GetProfile(userID.(string))
 	if err != nil {
-		h.logger.Error("Failed to get user profile", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get profile"})
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get profile"))
 		return
 	}
 
@@ -134,50 +141,102 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apperrors.Abort(c, apperrors.New(http.StatusUnauthorized, "User not authenticated"))
 		return
 	}
 
 	var req models.UpdateProfileRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.Warn("Invalid update profile request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "Invalid request data").WithFields(gin.H{"details": err.Error()}))
 		return
 	}
 
 	user, err := h.userService.UpdateProfile(userID.(string), req)
 	if err != nil {
-		h.logger.Error("Failed to update profile", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		if invalid, ok := invalidContact(err); ok {
+			apperrors.Abort(c, invalid)
+			return
+		}
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to update profile"))
 		return
 	}
 
 	c.JSON(http.StatusOK, user)
 }
 
+// SetAvatar sets the current user's avatar to an image they uploaded to
+// media-service
+// PUT /users/profile/avatar
+func (h *UserHandler) SetAvatar(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apperrors.Abort(c, apperrors.New(http.StatusUnauthorized, "User not authenticated"))
+		return
+	}
+
+	var req models.SetAvatarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid set avatar request", zap.Error(err))
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "Invalid request data").WithFields(gin.H{"details": err.Error()}))
+		return
+	}
+
+	user, err := h.userService.SetAvatar(c.Request.Context(), userID.(string), req.MediaID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidAvatar):
+			apperrors.Abort(c, apperrors.New(http.StatusBadRequest, err.Error()))
+		case errors.Is(err, services.ErrAvatarsUnavailable):
+			apperrors.Abort(c, apperrors.New(http.StatusServiceUnavailable, err.Error()))
+		default:
+			apperrors.Abort(c, apperrors.Wrap(err, "Failed to set avatar"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// RemoveAvatar removes the current user's avatar
+// DELETE /users/profile/avatar
+func (h *UserHandler) RemoveAvatar(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apperrors.Abort(c, apperrors.New(http.StatusUnauthorized, "User not authenticated"))
+		return
+	}
+
+	if err := h.userService.RemoveAvatar(userID.(string)); err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to remove avatar"))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
 // ChangePassword changes the current user's password
 // POST /users/change-password
 func (h *UserHandler) ChangePassword(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apperrors.Abort(c, apperrors.New(http.StatusUnauthorized, "User not authenticated"))
 		return
 	}
 
 	var req models.ChangePasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.Warn("Invalid change password request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "Invalid request data").WithFields(gin.H{"details": err.Error()}))
 		return
 	}
 
 	if err := h.userService.ChangePassword(userID.(string), req); err != nil {
 		if err.Error() == "current password is incorrect" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			apperrors.Abort(c, apperrors.New(http.StatusBadRequest, err.Error()))
 			return
 		}
-		h.logger.Error("Failed to change password", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to change password"))
 		return
 	}
 
@@ -192,29 +251,110 @@ func (h *UserHandler) ValidateToken(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "Token is required"))
 		return
 	}
 
 	claims, err := h.userService.ValidateToken(req.Token)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"valid": false, "error": "Invalid token"})
+		apperrors.Abort(c, apperrors.New(http.StatusUnauthorized, "Invalid token").WithFields(gin.H{"valid": false}))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"valid":   true,
-		"user_id": claims.UserID,
-		"email":   claims.Email,
-		"role":    claims.Role,
+		"valid":     true,
+		"user_id":   claims.UserID,
+		"email":     claims.Email,
+		"role":      claims.Role,
+		"tenant_id": claims.Tenant(),
 	})
 }
 
-// HealthCheck returns service health status
-// GET /health
-func (h *UserHandler) HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "healthy",
-		"service": "user-service",
-	})
+// GetNotificationPreferences returns the current user's notification preferences
+// GET /users/notification-preferences
+func (h *UserHandler) GetNotificationPreferences(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apperrors.Abort(c, apperrors.New(http.StatusUnauthorized, "User not authenticated"))
+		return
+	}
+
+	prefs, err := h.userService.GetNotificationPreferences(userID.(string))
+	if err != nil {
+		if err.Error() == "notification preferences not found" {
+			// No stored preferences means everything is enabled
+			c.JSON(http.StatusOK, models.NotificationPreferences{
+				UserID:     userID.(string),
+				Channels:   map[string]bool{},
+				Categories: map[string]bool{},
+			})
+			return
+		}
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get notification preferences"))
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdateNotificationPreferences replaces the current user's notification preferences
+// PUT /users/notification-preferences
+func (h *UserHandler) UpdateNotificationPreferences(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apperrors.Abort(c, apperrors.New(http.StatusUnauthorized, "User not authenticated"))
+		return
+	}
+
+	var req models.UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("Invalid notification preferences request", zap.Error(err))
+		apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "Invalid request data").WithFields(gin.H{"details": err.Error()}))
+		return
+	}
+
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "Invalid timezone").WithFields(gin.H{"details": err.Error()}))
+			return
+		}
+	}
+
+	prefs, err := h.userService.UpdateNotificationPreferences(userID.(string), req)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to update notification preferences"))
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// GetUserNotificationPreferences returns a user's notification preferences for internal services
+// GET /internal/users/:id/notification-preferences
+func (h *UserHandler) GetUserNotificationPreferences(c *gin.Context) {
+	prefs, err := h.userService.GetNotificationPreferences(c.Param("id"))
+	if err != nil {
+		if err.Error() == "notification preferences not found" {
+			apperrors.Abort(c, apperrors.New(http.StatusNotFound, err.Error()))
+			return
+		}
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to get notification preferences"))
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// invalidContact converts an invalid email address or phone number into a
+// VALIDATION_FAILED error naming the field
+func invalidContact(err error) (*apperrors.AppError, bool) {
+	var contactErr *contact.Error
+	if !errors.As(err, &contactErr) {
+		return nil, false
+	}
+	return apperrors.NewValidation(apperrors.FieldError{
+		Field:   contactErr.Kind,
+		Rule:    contactErr.Code,
+		Message: contactErr.Kind + " is invalid: " + contactErr.Message,
+	}), true
 }
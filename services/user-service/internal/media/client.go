@@ -0,0 +1,82 @@
+// Package media looks up the images users upload to media-service
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned for media the tenant doesn't have
+var ErrNotFound = errors.New("media not found")
+
+// Asset is the part of a media-service record the service uses
+type Asset struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind"`
+	UserID string `json:"user_id"`
+	// URL is the stable URL of public media
+	URL string `json:"url"`
+}
+
+// Client calls media-service's internal routes
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewClient creates a media client for the service at baseURL, calling
+// through transport
+func NewClient(baseURL, apiKey string, transport http.RoundTripper) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 10 * time.Second, Transport: transport},
+	}
+}
+
+// Enabled reports whether media can be looked up
+func (c *Client) Enabled() bool {
+	return c != nil && c.baseURL != ""
+}
+
+// Get returns a tenant's media record, or ErrNotFound
+func (c *Client) Get(ctx context.Context, tenantID, id string) (*Asset, error) {
+	if !c.Enabled() {
+		return nil, errors.New("MEDIA_SERVICE_URL is not configured")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/v1/internal/media/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Tenant-ID", tenantID)
+	if c.apiKey != "" {
+		req.Header.Set("X-Service-Key", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch media: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("media-service returned status %d", resp.StatusCode)
+	}
+
+	var asset Asset
+	if err := json.NewDecoder(resp.Body).Decode(&asset); err != nil {
+		return nil, fmt.Errorf("failed to decode media: %w", err)
+	}
+	return &asset, nil
+}
@@ -9,23 +9,29 @@ type UserRole string
 const (
 	RoleCustomer UserRole = "customer"
 	RoleAdmin    UserRole = "admin"
+	RoleSupport  UserRole = "support"
 )
 
 type User struct {
-	ID           string    `json:"id"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"` // Never expose password hash in JSON
-	FirstName    string    `json:"first_name"`
-	LastName     string    `json:"last_name"`
-	Phone        string    `json:"phone,omitempty"`
-	Role         UserRole  `json:"role"`
-	IsActive     bool      `json:"is_active"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID            string    `json:"id"`
+	TenantID      string    `json:"tenant_id"`
+	Email         string    `json:"email"`
+	PasswordHash  string    `json:"-"` // Never expose password hash in JSON
+	FirstName     string    `json:"first_name"`
+	LastName      string    `json:"last_name"`
+	Phone         string    `json:"phone,omitempty"`
+	AvatarMediaID string    `json:"avatar_media_id,omitempty"` // avatar image in media-service
+	AvatarURL     string    `json:"avatar_url,omitempty"`      // stable URL the avatar is shown from
+	Role          UserRole  `json:"role"`
+	IsActive      bool      `json:"is_active"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
+// RegisterRequest is validated further by the user service: email and phone
+// must be valid contacts, see shared/go/contact
 type RegisterRequest struct {
-	Email     string `json:"email" binding:"required,email"`
+	Email     string `json:"email" binding:"required"`
 	Password  string `json:"password" binding:"required,min=8"`
 	FirstName string `json:"first_name" binding:"required"`
 	LastName  string `json:"last_name" binding:"required"`
@@ -33,7 +39,7 @@ type RegisterRequest struct {
 }
 
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
+	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
 
@@ -48,7 +54,39 @@ type UpdateProfileRequest struct {
 	Phone     string `json:"phone,omitempty"`
 }
 
+// SetAvatarRequest sets the avatar to an image the user uploaded to
+// media-service
+type SetAvatarRequest struct {
+	MediaID string `json:"media_id" binding:"required"`
+}
+
 type ChangePasswordRequest struct {
 	CurrentPassword string `json:"current_password" binding:"required"`
 	NewPassword     string `json:"new_password" binding:"required,min=8"`
 }
+
+// UpdateUserRoleRequest changes a user's role; admin only
+type UpdateUserRoleRequest struct {
+	Role UserRole `json:"role" binding:"required,oneof=customer admin support"`
+}
+
+// UpdateUserStatusRequest activates or deactivates a user; admin only
+type UpdateUserStatusRequest struct {
+	IsActive *bool `json:"is_active" binding:"required"`
+}
+
+// NotificationPreferences holds a user's notification channel and category opt-ins
+type NotificationPreferences struct {
+	UserID     string          `json:"user_id"`
+	Channels   map[string]bool `json:"channels"`
+	Categories map[string]bool `json:"categories"`
+	// IANA timezone, e.g. Europe/Berlin, used for quiet hours; empty means unknown
+	Timezone  string    `json:"timezone,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	Channels   map[string]bool `json:"channels"`
+	Categories map[string]bool `json:"categories"`
+	Timezone   string          `json:"timezone"`
+}
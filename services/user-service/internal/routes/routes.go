@@ -1,39 +1,90 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	"github.com/ecommerce-platform/shared/go/ratelimit"
 	"github.com/ecommerce/user-service/internal/handlers"
-	"github.com/ecommerce/user-service/internal/middleware"
 )
 
+// Rate limits: credential endpoints are limited per IP to slow down
+// brute-force attempts, authenticated ones per user
+var (
+	credentialsLimit = ratelimit.Rule{Name: "credentials", Limit: 10, Window: 15 * time.Minute}
+	usersLimit       = ratelimit.Rule{Name: "users", Limit: 100, Window: time.Minute, Algorithm: ratelimit.TokenBucket}
+)
+
+// SetupRoutes registers the service's routes. A nil limiter disables rate
+// limiting.
 func SetupRoutes(
 	router *gin.Engine,
+	healthHandler *handlers.HealthHandler,
 	userHandler *handlers.UserHandler,
-	authMiddleware *middleware.AuthMiddleware,
+	adminHandler *handlers.AdminHandler,
+	authMiddleware *sharedauth.Middleware,
+	serviceAuth gin.HandlerFunc,
+	limiter *ratelimit.Limiter,
+	logger *zap.Logger,
 ) {
+	rateLimit := func(rule ratelimit.Rule, key ratelimit.KeyFunc) gin.HandlerFunc {
+		if limiter == nil {
+			return func(c *gin.Context) { c.Next() }
+		}
+		return ratelimit.Middleware(limiter, rule, key, logger)
+	}
+	limitCredentials := rateLimit(credentialsLimit, ratelimit.ByIP())
+
 	// Health check
-	router.GET("/health", userHandler.HealthCheck)
+	router.GET("/health", healthHandler.HealthCheck)
 
-	// API v1
+	// API v1, scoped to the tenant the gateway resolved
 	v1 := router.Group("/api/v1")
+	v1.Use(sharedauth.ResolveTenant())
 	{
 		// Public auth routes
 		auth := v1.Group("/auth")
 		{
-			auth.POST("/register", userHandler.Register)
-			auth.POST("/login", userHandler.Login)
+			auth.POST("/register", limitCredentials, userHandler.Register)
+			auth.POST("/login", limitCredentials, userHandler.Login)
 			auth.POST("/logout", userHandler.Logout)
 			auth.POST("/validate", userHandler.ValidateToken)
 		}
 
 		// Protected user routes
 		users := v1.Group("/users")
-		users.Use(authMiddleware.Authenticate())
+		users.Use(authMiddleware.Authenticate(), rateLimit(usersLimit, ratelimit.ByUser(sharedauth.ContextUserID)))
 		{
 			users.GET("/profile", userHandler.GetProfile)
 			users.PUT("/profile", userHandler.UpdateProfile)
-			users.POST("/change-password", userHandler.ChangePassword)
+			users.PUT("/profile/avatar", userHandler.SetAvatar)
+			users.DELETE("/profile/avatar", userHandler.RemoveAvatar)
+			users.POST("/change-password", limitCredentials, userHandler.ChangePassword)
+			users.GET("/notification-preferences", userHandler.GetNotificationPreferences)
+			users.PUT("/notification-preferences", userHandler.UpdateNotificationPreferences)
+		}
+
+		// User management, for admins only
+		admin := v1.Group("/admin")
+		admin.Use(
+			authMiddleware.Authenticate(),
+			authMiddleware.RequireRole(sharedauth.RoleAdmin),
+			rateLimit(usersLimit, ratelimit.ByUser(sharedauth.ContextUserID)),
+		)
+		{
+			admin.GET("/users/:id", adminHandler.GetUser)
+			admin.PUT("/users/:id/role", adminHandler.UpdateRole)
+			admin.PUT("/users/:id/status", adminHandler.UpdateStatus)
+		}
+
+		// Internal service-to-service routes
+		internal := v1.Group("/internal")
+		internal.Use(serviceAuth)
+		{
+			internal.GET("/users/:id/notification-preferences", userHandler.GetUserNotificationPreferences)
 		}
 	}
 }
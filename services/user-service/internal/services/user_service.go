@@ -1,32 +1,62 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	"go.uber.org/zap"
 
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	"github.com/ecommerce-platform/shared/go/contact"
 	"github.com/ecommerce/user-service/internal/auth"
 	"github.com/ecommerce/user-service/internal/database"
+	"github.com/ecommerce/user-service/internal/media"
 	"github.com/ecommerce/user-service/internal/models"
 )
 
+// ErrSelfAdministration is returned when admins change their own role or
+// status, which could leave no admin able to undo it
+var ErrSelfAdministration = errors.New("admins cannot change their own role or status")
+
+// ErrInvalidAvatar is returned for avatars that aren't an avatar image the
+// user uploaded
+var ErrInvalidAvatar = errors.New("media is not an avatar uploaded by the user")
+
+// ErrAvatarsUnavailable is returned when media-service isn't configured
+var ErrAvatarsUnavailable = errors.New("avatars are not available")
+
 type UserService struct {
 	repo       *database.UserRepository
 	jwtService *auth.JWTService
+	media      *media.Client
 	logger     *zap.Logger
 }
 
-func NewUserService(repo *database.UserRepository, jwtService *auth.JWTService, logger *zap.Logger) *UserService {
+func NewUserService(repo *database.UserRepository, jwtService *auth.JWTService, mediaClient *media.Client, logger *zap.Logger) *UserService {
 	return &UserService{
 		repo:       repo,
 		jwtService: jwtService,
+		media:      mediaClient,
 		logger:     logger,
 	}
 }
 
-func (s *UserService) Register(req models.RegisterRequest) (*models.LoginResponse, error) {
+// Register creates a customer account in a tenant. The email address and
+// phone number are stored normalized; invalid ones are returned as
+// *contact.Error.
+func (s *UserService) Register(tenantID string, req models.RegisterRequest) (*models.LoginResponse, error) {
+	email, err := contact.NormalizeEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+	phone, err := normalizePhone(req.Phone)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if email already exists
-	exists, err := s.repo.EmailExists(req.Email)
+	exists, err := s.repo.EmailExists(tenantID, email)
 	if err != nil {
 		s.logger.Error("Failed to check email existence", zap.Error(err))
 		return nil, fmt.Errorf("failed to check email: %w", err)
@@ -44,11 +74,12 @@ func (s *UserService) Register(req models.RegisterRequest) (*models.LoginRespons
 
 	// Create user
 	user := &models.User{
-		Email:        req.Email,
+		TenantID:     tenantID,
+		Email:        email,
 		PasswordHash: passwordHash,
 		FirstName:    req.FirstName,
 		LastName:     req.LastName,
-		Phone:        req.Phone,
+		Phone:        phone,
 		Role:         models.RoleCustomer, // Default role
 		IsActive:     true,
 	}
@@ -67,6 +98,7 @@ func (s *UserService) Register(req models.RegisterRequest) (*models.LoginRespons
 
 	s.logger.Info("User registered successfully",
 		zap.String("user_id", user.ID),
+		zap.String("tenant_id", user.TenantID),
 		zap.String("email", user.Email),
 	)
 
@@ -76,9 +108,18 @@ func (s *UserService) Register(req models.RegisterRequest) (*models.LoginRespons
 	}, nil
 }
 
-func (s *UserService) Login(req models.LoginRequest) (*models.LoginResponse, error) {
+// Login signs a user in to a tenant's storefront; accounts of other
+// tenants don't exist there
+func (s *UserService) Login(tenantID string, req models.LoginRequest) (*models.LoginResponse, error) {
+	// Invalid addresses can't belong to an account
+	email, err := contact.NormalizeEmail(req.Email)
+	if err != nil {
+		s.logger.Warn("Login attempt with invalid email", zap.String("email", req.Email))
+		return nil, fmt.Errorf("invalid credentials")
+	}
+
 	// Find user by email
-	user, err := s.repo.FindByEmail(req.Email)
+	user, err := s.repo.FindByEmail(tenantID, email)
 	if err != nil {
 		s.logger.Warn("Login attempt with non-existent email", zap.String("email", req.Email))
 		return nil, fmt.Errorf("invalid credentials")
@@ -127,6 +168,19 @@ func (s *UserService) GetProfile(userID string) (*models.User, error) {
 	return user, nil
 }
 
+// GetTenantUser returns a user of the tenant, for its admins. Users of other
+// tenants are not found.
+func (s *UserService) GetTenantUser(tenantID, userID string) (*models.User, error) {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TenantID != tenantID {
+		return nil, fmt.Errorf("user not found")
+	}
+	return user, nil
+}
+
 func (s *UserService) UpdateProfile(userID string, req models.UpdateProfileRequest) (*models.User, error) {
 	user, err := s.repo.FindByID(userID)
 	if err != nil {
@@ -142,7 +196,11 @@ func (s *UserService) UpdateProfile(userID string, req models.UpdateProfileReque
 		user.LastName = req.LastName
 	}
 	if req.Phone != "" {
-		user.Phone = req.Phone
+		phone, err := normalizePhone(req.Phone)
+		if err != nil {
+			return nil, err
+		}
+		user.Phone = phone
 	}
 
 	if err := s.repo.Update(user); err != nil {
@@ -155,6 +213,64 @@ func (s *UserService) UpdateProfile(userID string, req models.UpdateProfileReque
 	return user, nil
 }
 
+// SetAvatar sets a user's avatar to an image they uploaded to media-service
+// as an avatar, shown from its stable URL. The previous image is left in
+// media-service for the user to delete.
+func (s *UserService) SetAvatar(ctx context.Context, userID, mediaID string) (*models.User, error) {
+	if !s.media.Enabled() {
+		return nil, ErrAvatarsUnavailable
+	}
+
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		s.logger.Error("Failed to find user for avatar", zap.String("user_id", userID), zap.Error(err))
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	asset, err := s.media.Get(ctx, user.TenantID, mediaID)
+	if errors.Is(err, media.ErrNotFound) {
+		return nil, ErrInvalidAvatar
+	}
+	if err != nil {
+		s.logger.Error("Failed to look up avatar", zap.String("media_id", mediaID), zap.Error(err))
+		return nil, fmt.Errorf("failed to look up avatar: %w", err)
+	}
+	if asset.Kind != "avatar" || asset.UserID != userID || asset.URL == "" {
+		return nil, ErrInvalidAvatar
+	}
+
+	if err := s.repo.SetAvatar(userID, asset.ID, asset.URL); err != nil {
+		s.logger.Error("Failed to set avatar", zap.String("user_id", userID), zap.Error(err))
+		return nil, fmt.Errorf("failed to set avatar: %w", err)
+	}
+	user.AvatarMediaID, user.AvatarURL = asset.ID, asset.URL
+
+	s.logger.Info("User avatar updated", zap.String("user_id", userID), zap.String("media_id", asset.ID))
+
+	return user, nil
+}
+
+// RemoveAvatar removes a user's avatar
+func (s *UserService) RemoveAvatar(userID string) error {
+	if err := s.repo.SetAvatar(userID, "", ""); err != nil {
+		s.logger.Error("Failed to remove avatar", zap.String("user_id", userID), zap.Error(err))
+		return fmt.Errorf("failed to remove avatar: %w", err)
+	}
+
+	s.logger.Info("User avatar removed", zap.String("user_id", userID))
+
+	return nil
+}
+
+// normalizePhone returns a phone number in E.164 form, or "" for none.
+// Numbers must include their country code, as users may live anywhere.
+func normalizePhone(phone string) (string, error) {
+	if phone == "" {
+		return "", nil
+	}
+	return contact.NormalizePhone(phone, "")
+}
+
 func (s *UserService) ChangePassword(userID string, req models.ChangePasswordRequest) error {
 	user, err := s.repo.FindByID(userID)
 	if err != nil {
@@ -186,7 +302,7 @@ func (s *UserService) ChangePassword(userID string, req models.ChangePasswordReq
 	return nil
 }
 
-func (s *UserService) ValidateToken(tokenString string) (*auth.Claims, error) {
+func (s *UserService) ValidateToken(tokenString string) (*sharedauth.Claims, error) {
 	claims, err := s.jwtService.ValidateToken(tokenString)
 	if err != nil {
 		return nil, fmt.Errorf("invalid token: %w", err)
@@ -194,3 +310,104 @@ func (s *UserService) ValidateToken(tokenString string) (*auth.Claims, error) {
 
 	return claims, nil
 }
+
+func (s *UserService) GetNotificationPreferences(userID string) (*models.NotificationPreferences, error) {
+	prefs, err := s.repo.FindNotificationPreferences(userID)
+	if err != nil {
+		if err.Error() == "notification preferences not found" {
+			return nil, err
+		}
+		s.logger.Error("Failed to get notification preferences", zap.String("user_id", userID), zap.Error(err))
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+
+	return prefs, nil
+}
+
+func (s *UserService) UpdateNotificationPreferences(userID string, req models.UpdateNotificationPreferencesRequest) (*models.NotificationPreferences, error) {
+	prefs := &models.NotificationPreferences{
+		UserID:     userID,
+		Channels:   req.Channels,
+		Categories: req.Categories,
+		Timezone:   req.Timezone,
+	}
+	if prefs.Channels == nil {
+		prefs.Channels = map[string]bool{}
+	}
+	if prefs.Categories == nil {
+		prefs.Categories = map[string]bool{}
+	}
+
+	if err := s.repo.UpsertNotificationPreferences(prefs); err != nil {
+		s.logger.Error("Failed to update notification preferences", zap.String("user_id", userID), zap.Error(err))
+		return nil, fmt.Errorf("failed to update notification preferences: %w", err)
+	}
+
+	s.logger.Info("Notification preferences updated", zap.String("user_id", userID))
+
+	return prefs, nil
+}
+
+// SetUserRole changes the role of a user of the tenant for one of its
+// admins, returning the user before and after the change. Tokens already
+// issued keep the old role until they expire.
+func (s *UserService) SetUserRole(tenantID, adminID, userID string, role models.UserRole) (before, after *models.User, err error) {
+	if adminID == userID {
+		return nil, nil, ErrSelfAdministration
+	}
+
+	before, err = s.GetTenantUser(tenantID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.repo.UpdateRole(userID, role); err != nil {
+		s.logger.Error("Failed to update user role", zap.String("user_id", userID), zap.Error(err))
+		return nil, nil, fmt.Errorf("failed to update role: %w", err)
+	}
+
+	after, err = s.repo.FindByID(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.logger.Info("User role changed",
+		zap.String("user_id", userID),
+		zap.String("admin_id", adminID),
+		zap.String("role", string(role)),
+	)
+
+	return before, after, nil
+}
+
+// SetUserActive activates or deactivates a user of the tenant for one of
+// its admins, returning the user before and after the change. Inactive
+// users can't log in; tokens already issued stay valid until they expire.
+func (s *UserService) SetUserActive(tenantID, adminID, userID string, active bool) (before, after *models.User, err error) {
+	if adminID == userID {
+		return nil, nil, ErrSelfAdministration
+	}
+
+	before, err = s.GetTenantUser(tenantID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.repo.SetActive(userID, active); err != nil {
+		s.logger.Error("Failed to update user status", zap.String("user_id", userID), zap.Error(err))
+		return nil, nil, fmt.Errorf("failed to update status: %w", err)
+	}
+
+	after, err = s.repo.FindByID(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.logger.Info("User status changed",
+		zap.String("user_id", userID),
+		zap.String("admin_id", adminID),
+		zap.Bool("is_active", active),
+	)
+
+	return before, after, nil
+}
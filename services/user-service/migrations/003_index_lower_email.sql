@@ -0,0 +1,3 @@
+-- Emails are looked up case-insensitively, as addresses registered before
+-- they were normalized may have upper case letters
+CREATE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email));
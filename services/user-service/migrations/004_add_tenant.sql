@@ -0,0 +1,12 @@
+-- Users belong to a tenant, one storefront of a multi-tenant deployment;
+-- those registered before tenants belong to the default one
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+-- An address can register once per tenant, so the same shopper can have an
+-- account on two storefronts
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+ALTER TABLE users ADD CONSTRAINT users_tenant_email_key UNIQUE (tenant_id, email);
+
+-- Emails are looked up case-insensitively within a tenant
+DROP INDEX IF EXISTS idx_users_email_lower;
+CREATE INDEX IF NOT EXISTS idx_users_tenant_email_lower ON users (tenant_id, LOWER(email));
@@ -0,0 +1,4 @@
+-- A user's avatar is an image stored in media-service: its ID, to replace
+-- it, and the stable URL profiles show it from
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_media_id VARCHAR(36) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_url TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,404 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce/wishlist-service/internal/cart"
+	"github.com/ecommerce/wishlist-service/internal/domain"
+	"github.com/ecommerce/wishlist-service/internal/metrics"
+	"github.com/ecommerce/wishlist-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Handler serves the wishlist API
+type Handler struct {
+	repo   repository.WishlistRepository
+	cart   *cart.Client
+	logger *zap.Logger
+}
+
+// NewHandler creates a new handler
+func NewHandler(repo repository.WishlistRepository, cartClient *cart.Client, logger *zap.Logger) *Handler {
+	return &Handler{
+		repo:   repo,
+		cart:   cartClient,
+		logger: logger,
+	}
+}
+
+// WishlistRequest names a wishlist
+type WishlistRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// AddItemRequest puts a product on a wishlist
+type AddItemRequest struct {
+	ProductID string `json:"product_id" binding:"required,max=255"`
+	Note      string `json:"note" binding:"max=500"`
+}
+
+// MoveToCartRequest moves an item to the caller's cart; Quantity defaults
+// to 1
+type MoveToCartRequest struct {
+	Quantity int `json:"quantity" binding:"omitempty,min=1,max=10"`
+}
+
+// ListWishlists lists the caller's wishlists with their items
+// GET /api/v1/wishlists
+func (h *Handler) ListWishlists(c *gin.Context) {
+	wishlists, err := h.repo.ListWishlists(c.Request.Context(), c.GetString(sharedauth.ContextUserID))
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Wishlist"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"wishlists": wishlists})
+}
+
+// CreateWishlist creates a wishlist for the caller
+// POST /api/v1/wishlists
+func (h *Handler) CreateWishlist(c *gin.Context) {
+	var req WishlistRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+
+	wishlist := &domain.Wishlist{
+		UserID:    c.GetString(sharedauth.ContextUserID),
+		UserEmail: callerEmail(c),
+		Name:      req.Name,
+	}
+	err := h.repo.CreateWishlist(c.Request.Context(), wishlist)
+	if errors.Is(err, domain.ErrTooManyWishlists) {
+		apperrors.Abort(c, apperrors.NewConflict("You can have at most "+strconv.Itoa(domain.MaxWishlistsPerUser)+" wishlists"))
+		return
+	} else if err != nil {
+		apperrors.Abort(c, apperrors.FromSQL(err, "Wishlist"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, wishlist)
+}
+
+// GetWishlist returns one of the caller's wishlists
+// GET /api/v1/wishlists/:id
+func (h *Handler) GetWishlist(c *gin.Context) {
+	wishlist, ok := h.ownWishlist(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, wishlist)
+}
+
+// RenameWishlist renames one of the caller's wishlists
+// PUT /api/v1/wishlists/:id
+func (h *Handler) RenameWishlist(c *gin.Context) {
+	var req WishlistRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	wishlist, ok := h.ownWishlist(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.RenameWishlist(c.Request.Context(), wishlist.ID, req.Name); err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	h.respondWishlist(c, wishlist.ID, http.StatusOK)
+}
+
+// DeleteWishlist deletes one of the caller's wishlists and its items
+// DELETE /api/v1/wishlists/:id
+func (h *Handler) DeleteWishlist(c *gin.Context) {
+	wishlist, ok := h.ownWishlist(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteWishlist(c.Request.Context(), wishlist.ID); err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// AddItem puts a product on one of the caller's wishlists, or replaces
+// its note if it is there already
+// POST /api/v1/wishlists/:id/items
+func (h *Handler) AddItem(c *gin.Context) {
+	var req AddItemRequest
+	if !apperrors.BindJSON(c, &req) {
+		return
+	}
+	wishlist, ok := h.ownWishlist(c)
+	if !ok {
+		return
+	}
+
+	item := &domain.Item{ProductID: req.ProductID, Note: req.Note}
+	created, err := h.repo.AddItem(c.Request.Context(), wishlist.ID, callerEmail(c), item)
+	if errors.Is(err, domain.ErrTooManyItems) {
+		apperrors.Abort(c, apperrors.NewConflict("A wishlist can have at most "+strconv.Itoa(domain.MaxItemsPerWishlist)+" items"))
+		return
+	} else if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	if !created {
+		h.respondWishlist(c, wishlist.ID, http.StatusOK)
+		return
+	}
+
+	metrics.ItemsAddedTotal.Inc()
+	h.respondWishlist(c, wishlist.ID, http.StatusCreated)
+}
+
+// RemoveItem takes a product off one of the caller's wishlists
+// DELETE /api/v1/wishlists/:id/items/:productId
+func (h *Handler) RemoveItem(c *gin.Context) {
+	wishlist, ok := h.ownWishlist(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.RemoveItem(c.Request.Context(), wishlist.ID, c.Param("productId")); err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// MoveToCart adds an item to the caller's cart in cart-service, at its
+// current price, and takes it off the wishlist
+// POST /api/v1/wishlists/:id/items/:productId/move-to-cart
+func (h *Handler) MoveToCart(c *gin.Context) {
+	var req MoveToCartRequest
+	if c.Request.ContentLength != 0 && !apperrors.BindJSON(c, &req) {
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+
+	wishlist, ok := h.ownWishlist(c)
+	if !ok {
+		return
+	}
+	productID := c.Param("productId")
+	if !hasItem(wishlist, productID) {
+		apperrors.Abort(c, apperrors.NewNotFound("Item"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	product, err := h.repo.GetProduct(ctx, productID)
+	if err != nil && !errors.Is(err, domain.ErrProductNotInCache) {
+		metrics.MovedToCartTotal.WithLabelValues("failed").Inc()
+		apperrors.Abort(c, apperrors.FromSQL(err, "Product"))
+		return
+	}
+	if product == nil || product.SKU == "" || product.Name == "" || product.Price == nil {
+		metrics.MovedToCartTotal.WithLabelValues("unavailable").Inc()
+		apperrors.Abort(c, apperrors.NewConflict("The product's details aren't known yet; try again shortly"))
+		return
+	}
+	price, err := strconv.ParseFloat(product.Price.Decimal(), 64)
+	if err != nil {
+		metrics.MovedToCartTotal.WithLabelValues("failed").Inc()
+		apperrors.Abort(c, apperrors.Wrap(err, "Failed to move the item to the cart"))
+		return
+	}
+
+	err = h.cart.AddItem(ctx, sharedauth.Token(c), cart.Item{
+		ProductID: product.ProductID,
+		SKU:       product.SKU,
+		Name:      product.Name,
+		Price:     price,
+		Quantity:  req.Quantity,
+		ImageURL:  product.ImageURL,
+	})
+	var rejected *cart.RejectedError
+	if errors.As(err, &rejected) {
+		metrics.MovedToCartTotal.WithLabelValues("rejected").Inc()
+		apperrors.Abort(c, apperrors.NewConflict(rejected.Message))
+		return
+	} else if err != nil {
+		metrics.MovedToCartTotal.WithLabelValues("failed").Inc()
+		apperrors.Abort(c, apperrors.ErrServiceUnavailable.WithMessage("Failed to add the item to the cart").WithCause(err))
+		return
+	}
+	metrics.MovedToCartTotal.WithLabelValues("moved").Inc()
+
+	// The item is in the cart either way; one left on the wishlist is
+	// only a nuisance, so it doesn't fail the move
+	removed := true
+	if err := h.repo.RemoveItem(ctx, wishlist.ID, productID); err != nil && !errors.Is(err, domain.ErrItemNotFound) {
+		h.logger.Warn("Failed to remove item moved to the cart from the wishlist",
+			zap.String("wishlist_id", wishlist.ID),
+			zap.String("product_id", productID),
+			zap.Error(err),
+		)
+		removed = false
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"product_id": productID,
+		"quantity":   req.Quantity,
+		"removed":    removed,
+	})
+}
+
+// ShareWishlist shares one of the caller's wishlists with anyone who has
+// its link; sharing it again returns the same link
+// POST /api/v1/wishlists/:id/share
+func (h *Handler) ShareWishlist(c *gin.Context) {
+	wishlist, ok := h.ownWishlist(c)
+	if !ok {
+		return
+	}
+
+	token := wishlist.ShareToken
+	if token == "" {
+		var err error
+		if token, err = shareToken(); err != nil {
+			apperrors.Abort(c, apperrors.Wrap(err, "Failed to share the wishlist"))
+			return
+		}
+		if err := h.repo.SetShareToken(c.Request.Context(), wishlist.ID, token); err != nil {
+			h.abort(c, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"share_token": token,
+		"path":        "/api/v1/wishlists/shared/" + token,
+	})
+}
+
+// UnshareWishlist stops sharing one of the caller's wishlists; its link
+// no longer works
+// DELETE /api/v1/wishlists/:id/share
+func (h *Handler) UnshareWishlist(c *gin.Context) {
+	wishlist, ok := h.ownWishlist(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.SetShareToken(c.Request.Context(), wishlist.ID, ""); err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetSharedWishlist returns a shared wishlist to anyone with its link,
+// without its owner
+// GET /api/v1/wishlists/shared/:token
+func (h *Handler) GetSharedWishlist(c *gin.Context) {
+	wishlist, err := h.repo.GetSharedWishlist(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, wishlist.SharedView())
+}
+
+// HealthCheck reports whether the database is reachable
+func (h *Handler) HealthCheck(c *gin.Context) {
+	if err := h.repo.Ping(c.Request.Context()); err != nil {
+		h.logger.Warn("Health check failed", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unhealthy",
+			"service": "wishlist-service",
+			"version": "1.0.0",
+			"error":   "database unavailable",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "healthy",
+		"service": "wishlist-service",
+		"version": "1.0.0",
+	})
+}
+
+// ownWishlist loads the wishlist of the :id path parameter, aborting with
+// 404 if there is none or it is another customer's
+func (h *Handler) ownWishlist(c *gin.Context) (*domain.Wishlist, bool) {
+	wishlist, err := h.repo.GetWishlist(c.Request.Context(), c.Param("id"))
+	if err == nil && wishlist.UserID != c.GetString(sharedauth.ContextUserID) {
+		err = domain.ErrWishlistNotFound
+	}
+	if err != nil {
+		h.abort(c, err)
+		return nil, false
+	}
+	return wishlist, true
+}
+
+// respondWishlist responds with a wishlist as changed
+func (h *Handler) respondWishlist(c *gin.Context, id string, status int) {
+	wishlist, err := h.repo.GetWishlist(c.Request.Context(), id)
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	c.JSON(status, wishlist)
+}
+
+// abort responds with the API error of a repository error
+func (h *Handler) abort(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrWishlistNotFound):
+		apperrors.Abort(c, apperrors.NewNotFound("Wishlist"))
+	case errors.Is(err, domain.ErrItemNotFound):
+		apperrors.Abort(c, apperrors.NewNotFound("Item"))
+	default:
+		apperrors.Abort(c, apperrors.FromSQL(err, "Wishlist"))
+	}
+}
+
+// callerEmail is the email address in the caller's token, if any
+func callerEmail(c *gin.Context) string {
+	if claims, ok := sharedauth.ClaimsFrom(c); ok {
+		return claims.Email
+	}
+	return ""
+}
+
+func hasItem(wishlist *domain.Wishlist, productID string) bool {
+	for _, item := range wishlist.Items {
+		if item.ProductID == productID {
+			return true
+		}
+	}
+	return false
+}
+
+// shareToken returns a random, unguessable token for a wishlist's link
+func shareToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,88 @@
+// Package cart adds wishlist items to customers' carts in cart-service
+package cart
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/logging"
+)
+
+// Item is an item to add to a cart, as cart-service's POST
+// /api/v1/cart/items takes it. Price is in major units.
+type Item struct {
+	ProductID string  `json:"productId"`
+	SKU       string  `json:"sku"`
+	Name      string  `json:"name"`
+	Price     float64 `json:"price"`
+	Quantity  int     `json:"quantity"`
+	ImageURL  string  `json:"imageUrl,omitempty"`
+}
+
+// RejectedError is cart-service refusing an item, e.g. because the cart
+// is full; Message is safe to show the customer
+type RejectedError struct {
+	Message string
+}
+
+func (e *RejectedError) Error() string {
+	return "cart rejected the item: " + e.Message
+}
+
+// Client calls cart-service on behalf of customers
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a client of the cart-service at baseURL, calling it
+// through transport
+func NewClient(baseURL string, transport http.RoundTripper) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second, Transport: transport},
+	}
+}
+
+// AddItem adds an item to the cart of the customer token belongs to.
+// Items cart-service refuses return a *RejectedError.
+func (c *Client) AddItem(ctx context.Context, token string, item Item) error {
+	body, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/cart/items", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	if correlationID := logging.CorrelationID(ctx); correlationID != "" {
+		req.Header.Set(logging.CorrelationIDHeader, correlationID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call cart-service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusCreated {
+		return nil
+	}
+
+	var failure struct {
+		Error string `json:"error"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&failure)
+	if resp.StatusCode == http.StatusBadRequest && failure.Error != "" {
+		return &RejectedError{Message: failure.Error}
+	}
+	return fmt.Errorf("cart-service returned status %d: %s", resp.StatusCode, failure.Error)
+}
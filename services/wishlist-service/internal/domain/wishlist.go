@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/money"
+)
+
+// Limits on a customer's wishlists, so one customer can't grow the tables
+// or the notifications of a product without bound
+const (
+	MaxWishlistsPerUser = 20
+	MaxItemsPerWishlist = 200
+)
+
+var (
+	ErrWishlistNotFound  = errors.New("wishlist not found")
+	ErrItemNotFound      = errors.New("item not on the wishlist")
+	ErrTooManyWishlists  = errors.New("too many wishlists")
+	ErrTooManyItems      = errors.New("too many items on the wishlist")
+	ErrProductNotInCache = errors.New("product details not known yet")
+)
+
+// Wishlist is a customer's named list of products
+type Wishlist struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id,omitempty"`
+	UserEmail string `json:"-"`
+	Name      string `json:"name"`
+	// ShareToken, when set, lets anyone with the link view the list
+	ShareToken string    `json:"share_token,omitempty"`
+	Items      []*Item   `json:"items"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Item is a product on a wishlist, with what is known about it
+type Item struct {
+	ProductID string    `json:"product_id"`
+	Note      string    `json:"note,omitempty"`
+	AddedAt   time.Time `json:"added_at"`
+	Product   *Product  `json:"product,omitempty"`
+}
+
+// Product is a product as last seen in catalog, pricing and inventory
+// events
+type Product struct {
+	ProductID string       `json:"product_id"`
+	SKU       string       `json:"sku,omitempty"`
+	Name      string       `json:"name,omitempty"`
+	ImageURL  string       `json:"image_url,omitempty"`
+	Price     *money.Money `json:"price,omitempty"`
+	// AvailableQuantity is nil until a stock event is seen
+	AvailableQuantity *int      `json:"available_quantity,omitempty"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// InStock reports whether the product is known to be available
+func (p *Product) InStock() bool {
+	return p.AvailableQuantity != nil && *p.AvailableQuantity > 0
+}
+
+// Wisher is a customer with a product on one of their wishlists
+type Wisher struct {
+	UserID string
+	Email  string
+}
+
+// SharedView is a shared wishlist as anyone with its link sees it, without
+// its owner
+func (w *Wishlist) SharedView() *Wishlist {
+	return &Wishlist{
+		ID:        w.ID,
+		Name:      w.Name,
+		Items:     w.Items,
+		CreatedAt: w.CreatedAt,
+		UpdatedAt: w.UpdatedAt,
+	}
+}
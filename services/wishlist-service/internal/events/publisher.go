@@ -0,0 +1,102 @@
+package events
+
+import (
+	"context"
+
+	sharedevents "github.com/ecommerce-platform/shared/go/events"
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce/wishlist-service/internal/domain"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+type Publisher interface {
+	// PublishBackInStock tells wishers that a product is available again
+	PublishBackInStock(ctx context.Context, product *domain.Product, wishers []domain.Wisher) error
+	// PublishPriceDropped tells wishers that a product's price went down
+	// from oldPrice
+	PublishPriceDropped(ctx context.Context, product *domain.Product, oldPrice money.Money, wishers []domain.Wisher) error
+	Close() error
+}
+
+type kafkaPublisher struct {
+	producer *sharedkafka.Producer
+	logger   *zap.Logger
+}
+
+// NewKafkaPublisher creates a publisher. Events are keyed by product, so
+// notification-service handles a product's events in order.
+func NewKafkaPublisher(brokers []string, topic string, logger *zap.Logger) Publisher {
+	producer := sharedkafka.NewProducer(sharedkafka.ProducerConfig{
+		Brokers: brokers,
+		Topic:   topic,
+	}, logger)
+
+	return &kafkaPublisher{
+		producer: producer,
+		logger:   logger,
+	}
+}
+
+func (p *kafkaPublisher) publishEvent(ctx context.Context, env sharedevents.Envelope, payload sharedevents.Payload) error {
+	data, err := sharedevents.Marshal(env, payload)
+	if err != nil {
+		p.logger.Error("Failed to marshal event", zap.Error(err))
+		return err
+	}
+
+	message := kafka.Message{
+		Key:   []byte(env.ProductID),
+		Value: data,
+	}
+
+	if err := p.producer.Publish(ctx, message); err != nil {
+		p.logger.Error("Failed to publish event", zap.Error(err), zap.String("event_type", payload.EventType()))
+		return err
+	}
+
+	p.logger.Debug("Event published", zap.String("event_type", payload.EventType()), zap.String("product_id", env.ProductID))
+	return nil
+}
+
+func (p *kafkaPublisher) PublishBackInStock(ctx context.Context, product *domain.Product, wishers []domain.Wisher) error {
+	available := 0
+	if product.AvailableQuantity != nil {
+		available = *product.AvailableQuantity
+	}
+
+	env := sharedevents.Envelope{ProductID: product.ProductID}
+	return p.publishEvent(ctx, env, &sharedevents.BackInStock{
+		ProductID:         product.ProductID,
+		ProductName:       product.Name,
+		ImageURL:          product.ImageURL,
+		Price:             product.Price,
+		AvailableQuantity: available,
+		Subscribers:       subscribers(wishers),
+	})
+}
+
+func (p *kafkaPublisher) PublishPriceDropped(ctx context.Context, product *domain.Product, oldPrice money.Money, wishers []domain.Wisher) error {
+	env := sharedevents.Envelope{ProductID: product.ProductID}
+	return p.publishEvent(ctx, env, &sharedevents.WishlistPriceDropped{
+		ProductID:   product.ProductID,
+		ProductName: product.Name,
+		ImageURL:    product.ImageURL,
+		OldPrice:    oldPrice,
+		NewPrice:    *product.Price,
+		Subscribers: subscribers(wishers),
+	})
+}
+
+func (p *kafkaPublisher) Close() error {
+	return p.producer.Close()
+}
+
+func subscribers(wishers []domain.Wisher) []sharedevents.Subscriber {
+	list := make([]sharedevents.Subscriber, 0, len(wishers))
+	for _, wisher := range wishers {
+		list = append(list, sharedevents.Subscriber{UserID: wisher.UserID, Email: wisher.Email})
+	}
+	return list
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// ItemsAddedTotal counts products put on wishlists
+	ItemsAddedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "wishlist_items_added_total",
+		Help: "Products put on wishlists",
+	})
+
+	// MovedToCartTotal counts items moved to carts by result: moved,
+	// unavailable (product details not known yet), rejected by cart-service
+	// or failed
+	MovedToCartTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wishlist_moved_to_cart_total",
+		Help: "Wishlist items moved to carts, per result",
+	}, []string{"result"})
+
+	// ProductEventsTotal counts catalog, pricing and stock events by type
+	// and result: applied, skipped or failed
+	ProductEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wishlist_product_events_total",
+		Help: "Product events consumed, per event type and result",
+	}, []string{"event_type", "result"})
+
+	// NotificationsPublishedTotal counts inventory.back_in_stock and
+	// wishlist.price_dropped events by result: published or failed
+	NotificationsPublishedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wishlist_notifications_published_total",
+		Help: "Restock and price drop events published for wishers, per event type and result",
+	}, []string{"event_type", "result"})
+)
@@ -0,0 +1,438 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	shareddb "github.com/ecommerce-platform/shared/go/db"
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce/wishlist-service/internal/domain"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const wishlistColumns = `id, user_id, user_email, name, share_token, created_at, updated_at`
+
+const productColumns = `product_id, sku, name, image_url, price_minor, currency, available_quantity, updated_at`
+
+type postgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *sql.DB) WishlistRepository {
+	return &postgresRepository{db: db}
+}
+
+// Ping checks that the database is reachable
+func (r *postgresRepository) Ping(ctx context.Context) error {
+	return shareddb.Check(ctx, r.db)
+}
+
+// CreateWishlist stores a wishlist within its owner's limit. The owner's
+// lock keeps concurrent creations from both taking the last one.
+func (r *postgresRepository) CreateWishlist(ctx context.Context, wishlist *domain.Wishlist) error {
+	now := time.Now().UTC()
+	wishlist.ID = uuid.New().String()
+	wishlist.Items = []*domain.Item{}
+	wishlist.CreatedAt = now
+	wishlist.UpdatedAt = now
+
+	return shareddb.WithTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "wishlists:"+wishlist.UserID); err != nil {
+			return err
+		}
+
+		var count int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM wishlists WHERE user_id = $1`, wishlist.UserID).Scan(&count); err != nil {
+			return err
+		}
+		if count >= domain.MaxWishlistsPerUser {
+			return domain.ErrTooManyWishlists
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO wishlists (id, user_id, user_email, name, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, wishlist.ID, wishlist.UserID, wishlist.UserEmail, wishlist.Name, wishlist.CreatedAt, wishlist.UpdatedAt)
+		return err
+	})
+}
+
+// GetWishlist returns a wishlist with its items
+func (r *postgresRepository) GetWishlist(ctx context.Context, id string) (*domain.Wishlist, error) {
+	return r.getWishlist(ctx, `SELECT `+wishlistColumns+` FROM wishlists WHERE id = $1`, id)
+}
+
+// GetSharedWishlist returns the wishlist shared with token
+func (r *postgresRepository) GetSharedWishlist(ctx context.Context, token string) (*domain.Wishlist, error) {
+	return r.getWishlist(ctx, `SELECT `+wishlistColumns+` FROM wishlists WHERE share_token = $1`, token)
+}
+
+func (r *postgresRepository) getWishlist(ctx context.Context, query string, arg string) (*domain.Wishlist, error) {
+	wishlist, err := scanWishlist(r.db.QueryRowContext(ctx, query, arg))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrWishlistNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadItems(ctx, []*domain.Wishlist{wishlist}); err != nil {
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+// ListWishlists returns a customer's wishlists with their items, oldest
+// first
+func (r *postgresRepository) ListWishlists(ctx context.Context, userID string) ([]*domain.Wishlist, error) {
+	query := `
+		SELECT ` + wishlistColumns + `
+		FROM wishlists
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wishlists := []*domain.Wishlist{}
+	for rows.Next() {
+		wishlist, err := scanWishlist(rows)
+		if err != nil {
+			return nil, err
+		}
+		wishlists = append(wishlists, wishlist)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadItems(ctx, wishlists); err != nil {
+		return nil, err
+	}
+	return wishlists, nil
+}
+
+// loadItems sets the items of wishlists, most recently added first, with
+// what is known of their products
+func (r *postgresRepository) loadItems(ctx context.Context, wishlists []*domain.Wishlist) error {
+	if len(wishlists) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*domain.Wishlist, len(wishlists))
+	ids := make([]string, 0, len(wishlists))
+	for _, wishlist := range wishlists {
+		wishlist.Items = []*domain.Item{}
+		byID[wishlist.ID] = wishlist
+		ids = append(ids, wishlist.ID)
+	}
+
+	query := `
+		SELECT i.wishlist_id, i.product_id, i.note, i.added_at,
+			p.sku, p.name, p.image_url, p.price_minor, p.currency, p.available_quantity, p.updated_at
+		FROM wishlist_items i
+		LEFT JOIN products p ON p.product_id = i.product_id
+		WHERE i.wishlist_id = ANY($1)
+		ORDER BY i.added_at DESC, i.product_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var wishlistID string
+		item := &domain.Item{}
+		var sku, name, imageURL, currency sql.NullString
+		var price, available sql.NullInt64
+		var updatedAt sql.NullTime
+		err := rows.Scan(&wishlistID, &item.ProductID, &item.Note, &item.AddedAt,
+			&sku, &name, &imageURL, &price, &currency, &available, &updatedAt)
+		if err != nil {
+			return err
+		}
+
+		// Products not seen in any event yet have no row
+		if updatedAt.Valid {
+			item.Product = &domain.Product{
+				ProductID:         item.ProductID,
+				SKU:               sku.String,
+				Name:              name.String,
+				ImageURL:          imageURL.String,
+				Price:             priceOrNil(price, currency),
+				AvailableQuantity: intOrNil(available),
+				UpdatedAt:         updatedAt.Time,
+			}
+		}
+
+		wishlist := byID[wishlistID]
+		wishlist.Items = append(wishlist.Items, item)
+	}
+	return rows.Err()
+}
+
+// RenameWishlist changes a wishlist's name
+func (r *postgresRepository) RenameWishlist(ctx context.Context, id, name string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE wishlists SET name = $2, updated_at = $3 WHERE id = $1
+	`, id, name, time.Now().UTC())
+	return wishlistAffected(result, err)
+}
+
+// DeleteWishlist deletes a wishlist and its items
+func (r *postgresRepository) DeleteWishlist(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM wishlists WHERE id = $1`, id)
+	return wishlistAffected(result, err)
+}
+
+// SetShareToken shares a wishlist with token, or stops sharing it
+func (r *postgresRepository) SetShareToken(ctx context.Context, id, token string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE wishlists SET share_token = $2, updated_at = $3 WHERE id = $1
+	`, id, nullString(token), time.Now().UTC())
+	return wishlistAffected(result, err)
+}
+
+// AddItem puts a product on a wishlist within its limit. Locking the
+// wishlist keeps concurrent additions from both taking the last place.
+func (r *postgresRepository) AddItem(ctx context.Context, wishlistID, userEmail string, item *domain.Item) (bool, error) {
+	now := time.Now().UTC()
+
+	var created bool
+	err := shareddb.WithTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE wishlists
+			SET user_email = CASE WHEN $2 = '' THEN user_email ELSE $2 END, updated_at = $3
+			WHERE id = $1
+		`, wishlistID, userEmail, now)
+		if err := wishlistAffected(result, err); err != nil {
+			return err
+		}
+
+		var count int
+		var exists bool
+		err = tx.QueryRowContext(ctx, `
+			SELECT COUNT(*), COALESCE(BOOL_OR(product_id = $2), FALSE)
+			FROM wishlist_items
+			WHERE wishlist_id = $1
+		`, wishlistID, item.ProductID).Scan(&count, &exists)
+		if err != nil {
+			return err
+		}
+		if !exists && count >= domain.MaxItemsPerWishlist {
+			return domain.ErrTooManyItems
+		}
+
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO wishlist_items (wishlist_id, product_id, note, added_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (wishlist_id, product_id) DO UPDATE SET note = EXCLUDED.note
+			RETURNING added_at
+		`, wishlistID, item.ProductID, item.Note, now).Scan(&item.AddedAt)
+		created = !exists
+		return err
+	})
+	return created, err
+}
+
+// RemoveItem takes a product off a wishlist
+func (r *postgresRepository) RemoveItem(ctx context.Context, wishlistID, productID string) error {
+	return shareddb.WithTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			DELETE FROM wishlist_items WHERE wishlist_id = $1 AND product_id = $2
+		`, wishlistID, productID)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return domain.ErrItemNotFound
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE wishlists SET updated_at = $2 WHERE id = $1`, wishlistID, time.Now().UTC())
+		return err
+	})
+}
+
+// GetProduct returns what is known of a product
+func (r *postgresRepository) GetProduct(ctx context.Context, productID string) (*domain.Product, error) {
+	query := `SELECT ` + productColumns + ` FROM products WHERE product_id = $1`
+
+	product, err := scanProduct(r.db.QueryRowContext(ctx, query, productID))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrProductNotInCache
+	}
+	return product, err
+}
+
+// SaveProductDetails stores a product's catalog details. Prices from
+// pricing-service take precedence over the catalog's.
+func (r *postgresRepository) SaveProductDetails(ctx context.Context, product *domain.Product) error {
+	var priceMinor, currency interface{}
+	if product.Price != nil {
+		priceMinor, currency = product.Price.MinorUnits, product.Price.Currency
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO products (product_id, sku, name, image_url, price_minor, currency, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (product_id) DO UPDATE SET
+			sku = EXCLUDED.sku,
+			name = EXCLUDED.name,
+			image_url = EXCLUDED.image_url,
+			price_minor = COALESCE(products.price_minor, EXCLUDED.price_minor),
+			currency = COALESCE(products.currency, EXCLUDED.currency),
+			updated_at = EXCLUDED.updated_at
+	`, product.ProductID, product.SKU, product.Name, product.ImageURL, priceMinor, currency, time.Now().UTC())
+	return err
+}
+
+// SetPrice stores a product's current price
+func (r *postgresRepository) SetPrice(ctx context.Context, productID string, price money.Money) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO products (product_id, price_minor, currency, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (product_id) DO UPDATE SET
+			price_minor = EXCLUDED.price_minor,
+			currency = EXCLUDED.currency,
+			updated_at = EXCLUDED.updated_at
+	`, productID, price.MinorUnits, price.Currency, time.Now().UTC())
+	return err
+}
+
+// SetAvailability stores a product's available quantity, calling restocked
+// within the transaction when it goes from none to some
+func (r *postgresRepository) SetAvailability(ctx context.Context, productID string, available int, restocked func(*domain.Product) error) error {
+	return shareddb.WithTx(ctx, r.db, nil, func(tx *sql.Tx) error {
+		var previous sql.NullInt64
+		err := tx.QueryRowContext(ctx, `
+			SELECT available_quantity FROM products WHERE product_id = $1 FOR UPDATE
+		`, productID).Scan(&previous)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		product, err := scanProduct(tx.QueryRowContext(ctx, `
+			INSERT INTO products (product_id, available_quantity, updated_at)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (product_id) DO UPDATE SET
+				available_quantity = EXCLUDED.available_quantity,
+				updated_at = EXCLUDED.updated_at
+			RETURNING `+productColumns, productID, available, time.Now().UTC()))
+		if err != nil {
+			return err
+		}
+
+		// An unknown previous quantity isn't a restock: the product may
+		// never have been out of stock
+		if previous.Valid && previous.Int64 <= 0 && available > 0 {
+			return restocked(product)
+		}
+		return nil
+	})
+}
+
+// Wishers returns the customers wishing for a product, with the address
+// of their most recently changed wishlist
+func (r *postgresRepository) Wishers(ctx context.Context, productID string) ([]domain.Wisher, error) {
+	query := `
+		SELECT DISTINCT ON (w.user_id) w.user_id, w.user_email
+		FROM wishlist_items i
+		JOIN wishlists w ON w.id = i.wishlist_id
+		WHERE i.product_id = $1 AND w.user_email <> ''
+		ORDER BY w.user_id, w.updated_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wishers := []domain.Wisher{}
+	for rows.Next() {
+		var wisher domain.Wisher
+		if err := rows.Scan(&wisher.UserID, &wisher.Email); err != nil {
+			return nil, err
+		}
+		wishers = append(wishers, wisher)
+	}
+	return wishers, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWishlist(row scanner) (*domain.Wishlist, error) {
+	wishlist := &domain.Wishlist{}
+	var shareToken sql.NullString
+	err := row.Scan(
+		&wishlist.ID, &wishlist.UserID, &wishlist.UserEmail, &wishlist.Name, &shareToken,
+		&wishlist.CreatedAt, &wishlist.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	wishlist.ShareToken = shareToken.String
+	return wishlist, nil
+}
+
+func scanProduct(row scanner) (*domain.Product, error) {
+	product := &domain.Product{}
+	var price, available sql.NullInt64
+	var currency sql.NullString
+	err := row.Scan(
+		&product.ProductID, &product.SKU, &product.Name, &product.ImageURL, &price, &currency,
+		&available, &product.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	product.Price = priceOrNil(price, currency)
+	product.AvailableQuantity = intOrNil(available)
+	return product, nil
+}
+
+// wishlistAffected turns an update of no wishlist into
+// domain.ErrWishlistNotFound
+func wishlistAffected(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return domain.ErrWishlistNotFound
+	}
+	return nil
+}
+
+func priceOrNil(minor sql.NullInt64, currency sql.NullString) *money.Money {
+	if !minor.Valid || !currency.Valid {
+		return nil
+	}
+	price := money.New(minor.Int64, currency.String)
+	return &price
+}
+
+func intOrNil(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
+}
+
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce/wishlist-service/internal/domain"
+)
+
+// WishlistRepository defines wishlist data operations
+type WishlistRepository interface {
+	// Wishlists, returned with their items and what is known of their
+	// products
+	// CreateWishlist stores a wishlist, or returns
+	// domain.ErrTooManyWishlists if its owner has domain.MaxWishlistsPerUser
+	CreateWishlist(ctx context.Context, wishlist *domain.Wishlist) error
+	GetWishlist(ctx context.Context, id string) (*domain.Wishlist, error)
+	// GetSharedWishlist returns the wishlist shared with token
+	GetSharedWishlist(ctx context.Context, token string) (*domain.Wishlist, error)
+	// ListWishlists returns a customer's wishlists, oldest first
+	ListWishlists(ctx context.Context, userID string) ([]*domain.Wishlist, error)
+	RenameWishlist(ctx context.Context, id, name string) error
+	DeleteWishlist(ctx context.Context, id string) error
+	// SetShareToken shares a wishlist with whoever has token; an empty
+	// token stops sharing it
+	SetShareToken(ctx context.Context, id, token string) error
+
+	// Items
+	// AddItem puts a product on a wishlist, or replaces its note if it is
+	// there already, and records the owner's current email address. It
+	// returns domain.ErrTooManyItems if the wishlist has
+	// domain.MaxItemsPerWishlist.
+	AddItem(ctx context.Context, wishlistID, userEmail string, item *domain.Item) (created bool, err error)
+	// RemoveItem takes a product off a wishlist, or returns
+	// domain.ErrItemNotFound
+	RemoveItem(ctx context.Context, wishlistID, productID string) error
+
+	// Products
+	// GetProduct returns what is known of a product, or
+	// domain.ErrProductNotInCache
+	GetProduct(ctx context.Context, productID string) (*domain.Product, error)
+	// SaveProductDetails stores a product's SKU, name and image, and its
+	// price unless one was already set by SetPrice
+	SaveProductDetails(ctx context.Context, product *domain.Product) error
+	// SetPrice stores a product's current price
+	SetPrice(ctx context.Context, productID string, price money.Money) error
+	// SetAvailability stores a product's available quantity. When it goes
+	// from none to some, restocked is called with the product before the
+	// change commits; if restocked fails, the change is rolled back so a
+	// retry sees the restock again.
+	SetAvailability(ctx context.Context, productID string, available int, restocked func(*domain.Product) error) error
+	// Wishers returns the customers with a product on any of their
+	// wishlists, once each
+	Wishers(ctx context.Context, productID string) ([]domain.Wisher, error)
+
+	// Ping checks that the database is reachable
+	Ping(ctx context.Context) error
+}
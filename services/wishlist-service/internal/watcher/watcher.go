@@ -0,0 +1,224 @@
+// Package watcher follows the products on wishlists through catalog,
+// pricing and inventory events, and tells the customers wishing for a
+// product when it is back in stock or cheaper
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	sharedevents "github.com/ecommerce-platform/shared/go/events"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/ecommerce-platform/shared/go/money"
+	"github.com/ecommerce/wishlist-service/internal/domain"
+	"github.com/ecommerce/wishlist-service/internal/events"
+	"github.com/ecommerce/wishlist-service/internal/metrics"
+	"github.com/ecommerce/wishlist-service/internal/repository"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// errSkipped marks events that don't concern wishlists
+var errSkipped = errors.New("event doesn't concern wishlists")
+
+// Watcher applies product events to the product cache
+type Watcher struct {
+	repo      repository.WishlistRepository
+	publisher events.Publisher
+	logger    *zap.Logger
+}
+
+// New creates a watcher writing to repo and notifying through publisher
+func New(repo repository.WishlistRepository, publisher events.Publisher, logger *zap.Logger) *Watcher {
+	return &Watcher{repo: repo, publisher: publisher, logger: logger}
+}
+
+// catalogProduct is the data of catalog-service's product events, as far
+// as wishlists show it and carts need it
+type catalogProduct struct {
+	ID       string   `json:"id"`
+	SKU      string   `json:"sku"`
+	Name     string   `json:"name"`
+	Price    *float64 `json:"price"`
+	Currency string   `json:"currency"`
+	Images   []string `json:"images"`
+}
+
+// Handle applies an event from any of the topics. It is a
+// sharedkafka.Handler; failures are retried and then dead-lettered.
+func (w *Watcher) Handle(ctx context.Context, msg kafkago.Message) error {
+	env, err := sharedevents.Decode(msg.Value)
+	if err != nil {
+		metrics.ProductEventsTotal.WithLabelValues("unknown", "failed").Inc()
+		return err
+	}
+
+	err = w.apply(ctx, env)
+	switch {
+	case errors.Is(err, errSkipped):
+		metrics.ProductEventsTotal.WithLabelValues(env.EventType, "skipped").Inc()
+		return nil
+	case err != nil:
+		metrics.ProductEventsTotal.WithLabelValues(env.EventType, "failed").Inc()
+		logging.WithContext(ctx, w.logger).Warn("Failed to apply product event",
+			zap.String("event_type", env.EventType),
+			zap.String("product_id", env.ProductID),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	metrics.ProductEventsTotal.WithLabelValues(env.EventType, "applied").Inc()
+	return nil
+}
+
+func (w *Watcher) apply(ctx context.Context, env *sharedevents.Envelope) error {
+	switch env.EventType {
+	case "product.created", "product.updated":
+		var product catalogProduct
+		if err := json.Unmarshal(env.Data, &product); err != nil {
+			return fmt.Errorf("failed to decode %s data: %w", env.EventType, err)
+		}
+		productID := productIDOf(env, product.ID)
+		if productID == "" {
+			return fmt.Errorf("%s event has no product_id", env.EventType)
+		}
+		return w.repo.SaveProductDetails(ctx, product.details(productID))
+
+	case "price.changed":
+		var changed sharedevents.PriceChanged
+		if err := env.DecodeData(&changed); err != nil {
+			return err
+		}
+		productID := productIDOf(env, changed.ProductID)
+		if productID == "" {
+			return fmt.Errorf("%s event has no product_id", env.EventType)
+		}
+		if err := w.repo.SetPrice(ctx, productID, changed.NewPrice); err != nil {
+			return err
+		}
+		if changed.OldPrice == nil {
+			return nil
+		}
+		if cmp, err := changed.NewPrice.Cmp(*changed.OldPrice); err != nil || cmp >= 0 {
+			return nil
+		}
+		return w.priceDropped(ctx, productID, *changed.OldPrice)
+	}
+
+	productID, available, err := availabilityOf(env)
+	if err != nil {
+		return err
+	}
+	if productID == "" {
+		return fmt.Errorf("%s event has no product_id", env.EventType)
+	}
+	return w.repo.SetAvailability(ctx, productID, available, func(product *domain.Product) error {
+		return w.notify(ctx, "inventory.back_in_stock", product, func(wishers []domain.Wisher) error {
+			return w.publisher.PublishBackInStock(ctx, product, wishers)
+		})
+	})
+}
+
+// priceDropped tells the customers wishing for a product that its price
+// went down from oldPrice
+func (w *Watcher) priceDropped(ctx context.Context, productID string, oldPrice money.Money) error {
+	product, err := w.repo.GetProduct(ctx, productID)
+	if err != nil {
+		return err
+	}
+	return w.notify(ctx, "wishlist.price_dropped", product, func(wishers []domain.Wisher) error {
+		return w.publisher.PublishPriceDropped(ctx, product, oldPrice, wishers)
+	})
+}
+
+// notify publishes an event for the customers wishing for product, if
+// any. Products without a name yet can't be described, so aren't
+// announced.
+func (w *Watcher) notify(ctx context.Context, eventType string, product *domain.Product, publish func([]domain.Wisher) error) error {
+	wishers, err := w.repo.Wishers(ctx, product.ProductID)
+	if err != nil {
+		return err
+	}
+	if len(wishers) == 0 {
+		return nil
+	}
+	if product.Name == "" {
+		logging.WithContext(ctx, w.logger).Warn("Product has no name yet, not notifying wishers",
+			zap.String("event_type", eventType),
+			zap.String("product_id", product.ProductID),
+			zap.Int("wishers", len(wishers)),
+		)
+		return nil
+	}
+
+	if err := publish(wishers); err != nil {
+		metrics.NotificationsPublishedTotal.WithLabelValues(eventType, "failed").Inc()
+		return err
+	}
+
+	metrics.NotificationsPublishedTotal.WithLabelValues(eventType, "published").Inc()
+	logging.WithContext(ctx, w.logger).Info("Wishers notified",
+		zap.String("event_type", eventType),
+		zap.String("product_id", product.ProductID),
+		zap.Int("wishers", len(wishers)),
+	)
+	return nil
+}
+
+// details is what wishlists keep of a catalog product: its first image,
+// and its price in minor units
+func (p *catalogProduct) details(productID string) *domain.Product {
+	product := &domain.Product{ProductID: productID, SKU: p.SKU, Name: p.Name}
+	if len(p.Images) > 0 {
+		product.ImageURL = p.Images[0]
+	}
+	if p.Price != nil && len(p.Currency) == 3 {
+		price := money.FromMajor(*p.Price, p.Currency)
+		product.Price = &price
+	}
+	return product
+}
+
+// availabilityOf reads the product and its available quantity from a stock
+// event; other events are errSkipped
+func availabilityOf(env *sharedevents.Envelope) (string, int, error) {
+	switch env.EventType {
+	case "inventory.created":
+		var e sharedevents.InventoryCreated
+		err := env.DecodeData(&e)
+		return productIDOf(env, e.ProductID), e.AvailableQuantity, err
+	case "inventory.updated":
+		var e sharedevents.InventoryUpdated
+		err := env.DecodeData(&e)
+		return productIDOf(env, e.ProductID), e.AvailableQuantity, err
+	case "inventory.reserved":
+		var e sharedevents.InventoryReserved
+		err := env.DecodeData(&e)
+		return productIDOf(env, e.ProductID), e.AvailableQuantity, err
+	case "inventory.reservation_released":
+		var e sharedevents.ReservationReleased
+		err := env.DecodeData(&e)
+		return productIDOf(env, e.ProductID), e.AvailableQuantity, err
+	case "inventory.reservation_expired":
+		var e sharedevents.ReservationExpired
+		err := env.DecodeData(&e)
+		return productIDOf(env, e.ProductID), e.AvailableQuantity, err
+	case "inventory.adjusted":
+		var e sharedevents.InventoryAdjusted
+		err := env.DecodeData(&e)
+		return productIDOf(env, e.ProductID), e.AvailableQuantity, err
+	default:
+		return "", 0, errSkipped
+	}
+}
+
+// productIDOf prefers the envelope's product_id, which partitions the topic
+func productIDOf(env *sharedevents.Envelope, fallback string) string {
+	if env.ProductID != "" {
+		return env.ProductID
+	}
+	return fallback
+}
@@ -0,0 +1,38 @@
+-- Customers' wishlists. user_email is the owner's address as of their last
+-- change, for telling them about restocks and price drops.
+CREATE TABLE IF NOT EXISTS wishlists (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    user_email VARCHAR(255) NOT NULL DEFAULT '',
+    name VARCHAR(100) NOT NULL,
+    -- share_token is NULL for private lists
+    share_token VARCHAR(64) UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_wishlists_user ON wishlists(user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS wishlist_items (
+    wishlist_id VARCHAR(36) NOT NULL REFERENCES wishlists(id) ON DELETE CASCADE,
+    product_id VARCHAR(255) NOT NULL,
+    note VARCHAR(500) NOT NULL DEFAULT '',
+    added_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (wishlist_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_wishlist_items_product ON wishlist_items(product_id);
+
+-- Products as last seen in catalog, pricing and inventory events. Fields
+-- not seen yet are NULL or empty; a NULL available_quantity is unknown, so
+-- the first stock event isn't mistaken for a restock.
+CREATE TABLE IF NOT EXISTS products (
+    product_id VARCHAR(255) PRIMARY KEY,
+    sku VARCHAR(100) NOT NULL DEFAULT '',
+    name VARCHAR(500) NOT NULL DEFAULT '',
+    image_url VARCHAR(2000) NOT NULL DEFAULT '',
+    price_minor BIGINT,
+    currency CHAR(3),
+    available_quantity INTEGER,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,314 @@
+// Package audit records who changed what in Go services: a standard audit
+// record written asynchronously, in batches, to Postgres and/or a Kafka
+// audit topic, so recording a change never slows down or fails the request
+// that made it
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Actor types
+const (
+	// ActorUser is a user authenticated with a user-service token
+	ActorUser = "user"
+	// ActorService is another service, e.g. the admin dashboard calling with
+	// its service key
+	ActorService = "service"
+	// ActorSystem is the service itself, e.g. reloading a changed file
+	ActorSystem = "system"
+)
+
+// ErrClosed is returned by Close when the auditor was already closed
+var ErrClosed = errors.New("audit: auditor closed")
+
+// Record is an audited change
+type Record struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Service   string    `json:"service"`
+	Actor     Actor     `json:"actor"`
+	// Action is what was done, as <resource>.<verb>, e.g.
+	// inventory.adjusted or user.role_changed
+	Action   string   `json:"action"`
+	Resource Resource `json:"resource"`
+	// Before and After are the resource's state around the change; Before
+	// is empty for creations and After for deletions
+	Before        json.RawMessage   `json:"before,omitempty"`
+	After         json.RawMessage   `json:"after,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+	CorrelationID string            `json:"correlation_id,omitempty"`
+	TraceID       string            `json:"trace_id,omitempty"`
+}
+
+// Actor is who made a change
+type Actor struct {
+	Type  string `json:"type"`
+	ID    string `json:"id,omitempty"`
+	Email string `json:"email,omitempty"`
+	Role  string `json:"role,omitempty"`
+	IP    string `json:"ip,omitempty"`
+}
+
+// Resource is what was changed
+type Resource struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// Entry is a change to record. Before and After are marshalled to JSON
+// when the entry is logged, so the values may be modified afterwards.
+type Entry struct {
+	Action   string
+	Resource Resource
+	Before   interface{}
+	After    interface{}
+	Metadata map[string]string
+}
+
+// Sink stores audit records
+type Sink interface {
+	// Write stores a batch of records
+	Write(ctx context.Context, records []Record) error
+}
+
+// Config configures an Auditor. Zero values get defaults.
+type Config struct {
+	// Service names the service in its records
+	Service string
+	// Sinks receive every record; records are dropped without any
+	Sinks []Sink
+	// BufferSize is how many records may wait to be written; records
+	// logged while the buffer is full are dropped. Default 1000.
+	BufferSize int
+	// BatchSize is the most records written at once; default 100
+	BatchSize int
+	// FlushInterval is the longest a record waits for its batch to fill;
+	// default 1s
+	FlushInterval time.Duration
+	// WriteTimeout bounds each sink's write of a batch; default 10s
+	WriteTimeout time.Duration
+}
+
+// Auditor records changes. Log queues records and returns at once; a
+// background goroutine writes them to the sinks in batches.
+type Auditor struct {
+	cfg    Config
+	logger *zap.Logger
+
+	mu      sync.RWMutex
+	closed  bool
+	records chan Record
+	done    chan struct{}
+}
+
+// New creates an auditor and starts writing its records. Close it on
+// shutdown to write the records still queued.
+func New(cfg Config, logger *zap.Logger) *Auditor {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1000
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 10 * time.Second
+	}
+
+	a := &Auditor{
+		cfg:     cfg,
+		logger:  logger,
+		records: make(chan Record, cfg.BufferSize),
+		done:    make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Log records a change made in ctx. The actor is the user authenticated in
+// ctx, the actor stored with WithActor, or else the system; the correlation
+// and trace IDs also come from ctx. Records that can't be queued are
+// dropped and logged, never failing the caller.
+func (a *Auditor) Log(ctx context.Context, e Entry) {
+	rec := Record{
+		ID:            uuid.New().String(),
+		Timestamp:     time.Now().UTC(),
+		Service:       a.cfg.Service,
+		Actor:         actorFrom(ctx),
+		Action:        e.Action,
+		Resource:      e.Resource,
+		Before:        a.marshal(e.Before, e.Action),
+		After:         a.marshal(e.After, e.Action),
+		Metadata:      e.Metadata,
+		CorrelationID: logging.CorrelationID(ctx),
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		rec.TraceID = sc.TraceID().String()
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		a.drop(rec, "auditor closed")
+		return
+	}
+	select {
+	case a.records <- rec:
+	default:
+		a.drop(rec, "buffer full")
+	}
+}
+
+// marshal snapshots a before or after state; states that can't be
+// marshalled are left out
+func (a *Auditor) marshal(state interface{}, action string) json.RawMessage {
+	if state == nil {
+		return nil
+	}
+	raw, err := json.Marshal(state)
+	if err != nil {
+		a.logger.Warn("Failed to marshal audit state", zap.String("action", action), zap.Error(err))
+		return nil
+	}
+	return raw
+}
+
+// drop logs a record that won't be written, so it can still be recovered
+// from the service's logs
+func (a *Auditor) drop(rec Record, reason string) {
+	RecordsTotal.WithLabelValues(a.cfg.Service, "dropped").Inc()
+	a.logger.Error("Audit record dropped", zap.String("reason", reason), zap.Any("record", rec))
+}
+
+// Close stops accepting records and waits until the queued ones are
+// written, or ctx is done
+func (a *Auditor) Close(ctx context.Context) error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return ErrClosed
+	}
+	a.closed = true
+	close(a.records)
+	a.mu.Unlock()
+
+	select {
+	case <-a.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (a *Auditor) run() {
+	defer close(a.done)
+
+	ticker := time.NewTicker(a.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Record, 0, a.cfg.BatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			a.write(batch)
+			batch = batch[:0]
+		}
+	}
+
+	for {
+		select {
+		case rec, ok := <-a.records:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, rec)
+			if len(batch) >= a.cfg.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// write writes a batch to every sink. A sink's failure doesn't stop the
+// others; the records it failed to store are logged instead.
+func (a *Auditor) write(batch []Record) {
+	if len(a.cfg.Sinks) == 0 {
+		for _, rec := range batch {
+			a.drop(rec, "no sinks")
+		}
+		return
+	}
+
+	for _, sink := range a.cfg.Sinks {
+		ctx, cancel := context.WithTimeout(context.Background(), a.cfg.WriteTimeout)
+		err := sink.Write(ctx, batch)
+		cancel()
+
+		if err != nil {
+			RecordsTotal.WithLabelValues(a.cfg.Service, "failed").Add(float64(len(batch)))
+			a.logger.Error("Failed to write audit records",
+				zap.String("sink", sinkName(sink)),
+				zap.Int("records", len(batch)),
+				zap.Error(err),
+			)
+			for _, rec := range batch {
+				a.logger.Warn("Unwritten audit record", zap.String("sink", sinkName(sink)), zap.Any("record", rec))
+			}
+			continue
+		}
+		RecordsTotal.WithLabelValues(a.cfg.Service, "written").Add(float64(len(batch)))
+	}
+}
+
+// sinkName names a sink in logs
+func sinkName(sink Sink) string {
+	return fmt.Sprintf("%T", sink)
+}
+
+// actorKey stores an Actor in a context
+type actorKey struct{}
+
+// WithActor stores the actor of changes made in ctx, e.g. a service that
+// called with its service key. Users authenticated by sharedauth take
+// precedence; an actor IP is kept when it has none.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// actorFrom returns the actor of changes made in ctx
+func actorFrom(ctx context.Context) Actor {
+	stored, hasStored := ctx.Value(actorKey{}).(Actor)
+
+	if claims, ok := sharedauth.FromContext(ctx); ok {
+		return Actor{
+			Type:  ActorUser,
+			ID:    claims.UserID,
+			Email: claims.Email,
+			Role:  claims.Role,
+			IP:    stored.IP,
+		}
+	}
+	if hasStored {
+		if stored.Type == "" {
+			stored.Type = ActorSystem
+		}
+		return stored
+	}
+	return Actor{Type: ActorSystem}
+}
@@ -0,0 +1,27 @@
+package audit
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc/peer"
+)
+
+// GRPCContext returns a gRPC call's context with the peer's IP as the
+// actor's. The caller's claims, stored by the shared auth interceptors,
+// make the user the actor.
+func GRPCContext(ctx context.Context) context.Context {
+	actor := Actor{Type: ActorSystem}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		actor.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(actor.IP); err == nil {
+			actor.IP = host
+		}
+	}
+	return WithActor(ctx, actor)
+}
+
+// LogGRPC records a change made by a gRPC call
+func (a *Auditor) LogGRPC(ctx context.Context, e Entry) {
+	a.Log(GRPCContext(ctx), e)
+}
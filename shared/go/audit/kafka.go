@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// DefaultTopic is the Kafka topic audit records are published to
+const DefaultTopic = "audit-events"
+
+// KafkaSink publishes records as JSON to an audit topic, keyed by
+// resource so each resource's changes stay in order, for consumers such as
+// a SIEM or a central audit store
+type KafkaSink struct {
+	producer *sharedkafka.Producer
+}
+
+// NewKafkaSink creates a sink publishing with producer, which should be
+// configured with the audit topic, e.g. DefaultTopic
+func NewKafkaSink(producer *sharedkafka.Producer) *KafkaSink {
+	return &KafkaSink{producer: producer}
+}
+
+// Write publishes a batch
+func (s *KafkaSink) Write(ctx context.Context, records []Record) error {
+	messages := make([]kafkago.Message, 0, len(records))
+	for _, rec := range records {
+		value, err := json.Marshal(rec)
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit record: %w", err)
+		}
+		messages = append(messages, kafkago.Message{
+			Key:   []byte(rec.Resource.Type + "/" + rec.Resource.ID),
+			Value: value,
+			Headers: []kafkago.Header{
+				{Key: "action", Value: []byte(rec.Action)},
+				{Key: "service", Value: []byte(rec.Service)},
+			},
+		})
+	}
+
+	if err := s.producer.Publish(ctx, messages...); err != nil {
+		return fmt.Errorf("failed to publish audit records: %w", err)
+	}
+	return nil
+}
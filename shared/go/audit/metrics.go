@@ -0,0 +1,14 @@
+package audit
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// RecordsTotal counts audit records by result: written, counted once per
+// sink; failed, when a sink couldn't store them; or dropped, when they
+// couldn't be queued
+var RecordsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "audit_records_total",
+	Help: "Audit records by result",
+}, []string{"service", "result"})
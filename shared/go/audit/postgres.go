@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Schema creates the audit_log table PostgresSink writes to. Services with
+// migration files copy it into a migration; those that create their schema
+// at startup run it with EnsureSchema.
+//
+//go:embed schema.sql
+var Schema string
+
+// auditColumns are the audit_log columns a record is inserted into
+var auditColumns = []string{
+	"id", "occurred_at", "service",
+	"actor_type", "actor_id", "actor_email", "actor_role", "actor_ip",
+	"action", "resource_type", "resource_id",
+	"before_state", "after_state", "metadata",
+	"correlation_id", "trace_id",
+}
+
+// EnsureSchema creates the audit_log table if it doesn't exist
+func EnsureSchema(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, Schema); err != nil {
+		return fmt.Errorf("failed to create audit_log table: %w", err)
+	}
+	return nil
+}
+
+// PostgresSink writes records to the audit_log table of the service's
+// database
+type PostgresSink struct {
+	db *sql.DB
+}
+
+// NewPostgresSink creates a sink writing to db's audit_log table
+func NewPostgresSink(db *sql.DB) *PostgresSink {
+	return &PostgresSink{db: db}
+}
+
+// Write inserts a batch with a single statement. Records already stored,
+// e.g. by a retried batch, are skipped.
+func (s *PostgresSink) Write(ctx context.Context, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	rows := make([]string, 0, len(records))
+	args := make([]interface{}, 0, len(records)*len(auditColumns))
+	for _, rec := range records {
+		metadata, err := json.Marshal(rec.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit metadata: %w", err)
+		}
+		if rec.Metadata == nil {
+			metadata = []byte("{}")
+		}
+
+		placeholders := make([]string, len(auditColumns))
+		for i := range placeholders {
+			placeholders[i] = fmt.Sprintf("$%d", len(args)+i+1)
+		}
+		rows = append(rows, "("+strings.Join(placeholders, ", ")+")")
+
+		args = append(args,
+			rec.ID, rec.Timestamp, rec.Service,
+			rec.Actor.Type, rec.Actor.ID, rec.Actor.Email, rec.Actor.Role, rec.Actor.IP,
+			rec.Action, rec.Resource.Type, rec.Resource.ID,
+			jsonColumn(rec.Before), jsonColumn(rec.After), string(metadata),
+			rec.CorrelationID, rec.TraceID,
+		)
+	}
+
+	query := fmt.Sprintf(
+		"INSERT INTO audit_log (%s) VALUES %s ON CONFLICT (id) DO NOTHING",
+		strings.Join(auditColumns, ", "),
+		strings.Join(rows, ", "),
+	)
+	if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to insert audit records: %w", err)
+	}
+	return nil
+}
+
+// jsonColumn passes a state as text, which Postgres casts to JSONB, or NULL
+// when there is none
+func jsonColumn(raw json.RawMessage) sql.NullString {
+	return sql.NullString{String: string(raw), Valid: len(raw) > 0}
+}
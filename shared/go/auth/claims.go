@@ -0,0 +1,81 @@
+// Package auth verifies the JWTs user-service issues and provides the Gin
+// middleware that protects Go services' routes with them
+package auth
+
+import (
+	"context"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// User roles
+const (
+	RoleCustomer = "customer"
+	// RoleAdmin may act on any user's data and has every permission
+	RoleAdmin = "admin"
+	// RoleSupport may send notifications to customers on their behalf
+	RoleSupport = "support"
+)
+
+// Issuer is the iss claim of tokens user-service issues
+const Issuer = "ecommerce-user-service"
+
+// PermissionAll grants every permission
+const PermissionAll = "*"
+
+// RolePermissions are the permissions each role has in addition to those
+// in its tokens
+var RolePermissions = map[string][]string{
+	RoleAdmin: {PermissionAll},
+}
+
+// Claims are the claims of tokens issued by user-service
+type Claims struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+	// Permissions are granted by the token itself, e.g. to a service
+	Permissions []string `json:"permissions,omitempty"`
+	// TenantID is the storefront the user belongs to; empty in tokens
+	// issued before tenants, which belong to DefaultTenant
+	TenantID string `json:"tenant_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// HasRole reports whether the claims have one of roles
+func (c *Claims) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if c.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether the token or the role grants permission
+func (c *Claims) HasPermission(permission string) bool {
+	return contains(c.Permissions, permission) || contains(RolePermissions[c.Role], permission)
+}
+
+func contains(permissions []string, permission string) bool {
+	for _, p := range permissions {
+		if p == permission || p == PermissionAll {
+			return true
+		}
+	}
+	return false
+}
+
+type claimsKey struct{}
+
+// NewContext returns ctx carrying claims, for code that only has a
+// context, e.g. gRPC handlers and the services Gin handlers call
+func NewContext(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// FromContext returns the claims in ctx, if any
+func FromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Gin context keys Authenticate sets
+const (
+	ContextUserID    = "user_id"
+	ContextUserEmail = "user_email"
+	ContextUserRole  = "user_role"
+	ContextClaims    = "auth_claims"
+)
+
+// TokenCookie is the cookie the storefront keeps its token in
+const TokenCookie = "auth_token"
+
+// Middleware authenticates users by their user-service JWT
+type Middleware struct {
+	verifier *Verifier
+	logger   *zap.Logger
+}
+
+// NewMiddleware creates a new auth middleware
+func NewMiddleware(verifier *Verifier, logger *zap.Logger) *Middleware {
+	return &Middleware{
+		verifier: verifier,
+		logger:   logger,
+	}
+}
+
+// Authenticate validates JWT token from Authorization header or cookie
+func (m *Middleware) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := Token(c)
+		if token == "" {
+			apperrors.Abort(c, apperrors.New(http.StatusUnauthorized, "Authorization required"))
+			return
+		}
+		claims, err := m.verifier.Verify(token)
+		if err != nil {
+			m.logger.Warn("Invalid token", zap.Error(err))
+			apperrors.Abort(c, apperrors.New(http.StatusUnauthorized, "Invalid or expired token"))
+			return
+		}
+
+		// A token only works on its own tenant's storefront
+		if tenant, ok := c.Get(ContextTenantID); ok && tenant != claims.Tenant() {
+			m.logger.Warn("Token used on another tenant",
+				zap.String("user_id", claims.UserID),
+				zap.String("token_tenant", claims.Tenant()),
+				zap.Any("request_tenant", tenant),
+			)
+			apperrors.Abort(c, apperrors.New(http.StatusForbidden, "Token belongs to another tenant"))
+			return
+		}
+
+		// Set user info in context
+		c.Set(ContextUserID, claims.UserID)
+		c.Set(ContextUserEmail, claims.Email)
+		c.Set(ContextUserRole, claims.Role)
+		c.Set(ContextClaims, claims)
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), claims))
+
+		c.Next()
+	}
+}
+
+// RequireRole only lets users with one of the given roles through. It must
+// follow Authenticate.
+func (m *Middleware) RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := ClaimsFrom(c)
+		if !ok {
+			apperrors.Abort(c, apperrors.New(http.StatusUnauthorized, "Authorization required"))
+			return
+		}
+		if claims.HasRole(roles...) {
+			c.Next()
+			return
+		}
+
+		m.logger.Warn("Access denied - insufficient role",
+			zap.String("user_id", claims.UserID),
+			zap.String("role", claims.Role),
+			zap.Strings("required_roles", roles),
+		)
+
+		apperrors.Abort(c, apperrors.New(http.StatusForbidden, "Insufficient permissions"))
+	}
+}
+
+// RequirePermission only lets users with all the given permissions
+// through. It must follow Authenticate.
+func (m *Middleware) RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := ClaimsFrom(c)
+		if !ok {
+			apperrors.Abort(c, apperrors.New(http.StatusUnauthorized, "Authorization required"))
+			return
+		}
+		for _, permission := range permissions {
+			if !claims.HasPermission(permission) {
+				m.logger.Warn("Access denied - missing permission",
+					zap.String("user_id", claims.UserID),
+					zap.String("role", claims.Role),
+					zap.String("permission", permission),
+				)
+				apperrors.Abort(c, apperrors.New(http.StatusForbidden, "Insufficient permissions"))
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// Token returns the request's bearer token, or else its auth_token cookie
+func Token(c *gin.Context) string {
+	// Extract token from "Bearer <token>"
+	parts := strings.Split(c.GetHeader("Authorization"), " ")
+	if len(parts) == 2 && parts[0] == "Bearer" && parts[1] != "" {
+		return parts[1]
+	}
+
+	if token, err := c.Cookie(TokenCookie); err == nil {
+		return token
+	}
+	return ""
+}
+
+// ClaimsFrom returns the claims Authenticate verified
+func ClaimsFrom(c *gin.Context) (*Claims, bool) {
+	value, ok := c.Get(ContextClaims)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := value.(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ServiceKeyHeader is the header services call each other's internal and
+// admin routes with
+const ServiceKeyHeader = "X-Service-Key"
+
+// ValidServiceKey reports whether the request carries key in
+// X-Service-Key. It is always false when no key is configured.
+func ValidServiceKey(c *gin.Context, key string) bool {
+	if key == "" {
+		return false
+	}
+	provided := c.GetHeader(ServiceKeyHeader)
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(key)) == 1
+}
+
+// RequireServiceKey only lets through requests carrying key in
+// X-Service-Key; others get 401. Without a key every request gets 503, so a
+// missing SERVICE_API_KEY never leaves the routes open.
+func RequireServiceKey(key string, logger *zap.Logger) gin.HandlerFunc {
+	if key == "" {
+		logger.Warn("No service key configured, internal routes will reject every request")
+	}
+
+	return func(c *gin.Context) {
+		if key == "" {
+			apperrors.Abort(c, apperrors.New(http.StatusServiceUnavailable, "Internal routes aren't configured"))
+			return
+		}
+		if !ValidServiceKey(c, key) {
+			logger.Warn("Rejected request with invalid service key",
+				zap.String("path", c.Request.URL.Path),
+			)
+			apperrors.Abort(c, apperrors.New(http.StatusUnauthorized, "Invalid service key"))
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultTenant is the storefront of requests and tokens that don't name
+// one, so single-shop deployments and services that aren't tenant-aware
+// keep working unchanged
+const DefaultTenant = "default"
+
+// TenantHeader carries the tenant the API gateway resolved from the
+// request's host. The gateway overwrites any value clients send.
+const TenantHeader = "X-Tenant-ID"
+
+// ContextTenantID is the Gin context key ResolveTenant sets
+const ContextTenantID = "tenant_id"
+
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// ValidTenantID reports whether id is a well-formed tenant ID: up to 63
+// lower case letters, digits and hyphens, not starting with a hyphen
+func ValidTenantID(id string) bool {
+	return tenantIDPattern.MatchString(id)
+}
+
+// Tenant is the tenant the token was issued for
+func (c *Claims) Tenant() string {
+	if c.TenantID == "" {
+		return DefaultTenant
+	}
+	return c.TenantID
+}
+
+type tenantKey struct{}
+
+// WithTenant returns ctx scoped to tenant, for work that isn't done for a
+// request, e.g. a background job handling each tenant's rows in turn
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant ctx is scoped to: the one set by
+// WithTenant or ResolveTenant, else that of the claims in ctx, else
+// DefaultTenant
+func TenantFromContext(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok && tenant != "" {
+		return tenant
+	}
+	if claims, ok := FromContext(ctx); ok {
+		return claims.Tenant()
+	}
+	return DefaultTenant
+}
+
+// ResolveTenant scopes the request to the tenant in its X-Tenant-ID header,
+// or DefaultTenant without one. Malformed IDs are rejected. Authenticate,
+// when it follows, rejects tokens issued for another tenant.
+func ResolveTenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenant := c.GetHeader(TenantHeader)
+		if tenant == "" {
+			tenant = DefaultTenant
+		}
+		if !ValidTenantID(tenant) {
+			apperrors.Abort(c, apperrors.New(http.StatusBadRequest, "Invalid tenant ID"))
+			return
+		}
+
+		c.Set(ContextTenantID, tenant)
+		c.Request = c.Request.WithContext(WithTenant(c.Request.Context(), tenant))
+		c.Next()
+	}
+}
+
+// RequireDefaultTenant scopes the request to DefaultTenant and rejects
+// requests for any other tenant, for services that don't isolate tenants'
+// data, so other storefronts can't read or change the default tenant's.
+// Authenticate, when it follows, rejects tokens issued for another tenant.
+func RequireDefaultTenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tenant := c.GetHeader(TenantHeader); tenant != "" && tenant != DefaultTenant {
+			apperrors.Abort(c, apperrors.New(http.StatusForbidden, "Not available for this storefront"))
+			return
+		}
+
+		c.Set(ContextTenantID, DefaultTenant)
+		c.Request = c.Request.WithContext(WithTenant(c.Request.Context(), DefaultTenant))
+		c.Next()
+	}
+}
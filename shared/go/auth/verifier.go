@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Config configures a Verifier. At least one of Secret and JWKSURL must be
+// set; with both, HMAC tokens are checked against Secret and RSA or ECDSA
+// tokens against the key set.
+type Config struct {
+	// Secret verifies HMAC-signed tokens, e.g. user-service's JWT_SECRET
+	Secret string
+	// JWKSURL serves the public keys of asymmetrically signed tokens
+	JWKSURL string
+	// JWKSRefresh is how often the key set is refetched (default 15m)
+	JWKSRefresh time.Duration
+	// HTTPClient fetches the key set (default a client with a 10s timeout)
+	HTTPClient *http.Client
+
+	// Issuer and Audience are checked when set
+	Issuer   string
+	Audience string
+	// Leeway tolerates clock skew when checking expiry
+	Leeway time.Duration
+}
+
+// Verifier parses tokens and validates their signature and claims
+type Verifier struct {
+	secret []byte
+	jwks   *jwks
+	parser *jwt.Parser
+}
+
+var (
+	hmacMethods       = []string{"HS256", "HS384", "HS512"}
+	asymmetricMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+)
+
+// NewVerifier creates a verifier
+func NewVerifier(cfg Config) (*Verifier, error) {
+	if cfg.Secret == "" && cfg.JWKSURL == "" {
+		return nil, errors.New("auth: a secret or JWKS URL is required")
+	}
+
+	v := &Verifier{}
+	var methods []string
+	if cfg.Secret != "" {
+		v.secret = []byte(cfg.Secret)
+		methods = append(methods, hmacMethods...)
+	}
+	if cfg.JWKSURL != "" {
+		if cfg.JWKSRefresh <= 0 {
+			cfg.JWKSRefresh = 15 * time.Minute
+		}
+		if cfg.HTTPClient == nil {
+			cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+		}
+		v.jwks = newJWKS(cfg.JWKSURL, cfg.JWKSRefresh, cfg.HTTPClient)
+		methods = append(methods, asymmetricMethods...)
+	}
+
+	options := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired(), jwt.WithLeeway(cfg.Leeway)}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+	v.parser = jwt.NewParser(options...)
+	return v, nil
+}
+
+// Verify parses a token and checks its signature, expiry and, when
+// configured, issuer and audience
+func (v *Verifier) Verify(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+
+	token, err := v.parser.ParseWithClaims(tokenString, claims, v.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token: %w", err)
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	return claims, nil
+}
+
+// key returns the key a token's signature is checked with
+func (v *Verifier) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if v.secret != nil {
+			return v.secret, nil
+		}
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA:
+		if v.jwks != nil {
+			kid, _ := token.Header["kid"].(string)
+			return v.jwks.key(kid)
+		}
+	}
+	return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+}
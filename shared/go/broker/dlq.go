@@ -0,0 +1,41 @@
+package broker
+
+import (
+	"context"
+	"strconv"
+
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+)
+
+// TopicDeadLetters publishes failed messages to their topic's dead letter
+// topic (orders.dlq for orders), with the error and original position in
+// the same headers as the shared Kafka consumer's. The publisher must have
+// been created without a topic.
+type TopicDeadLetters struct {
+	publisher Publisher
+}
+
+// NewTopicDeadLetters creates a sink publishing with publisher
+func NewTopicDeadLetters(publisher Publisher) *TopicDeadLetters {
+	return &TopicDeadLetters{publisher: publisher}
+}
+
+// DeadLetter publishes msg to its dead letter topic
+func (d *TopicDeadLetters) DeadLetter(ctx context.Context, msg Message, err error) error {
+	headers := make([]Header, len(msg.Headers), len(msg.Headers)+4)
+	copy(headers, msg.Headers)
+
+	letter := Message{
+		Topic:   msg.Topic + sharedkafka.DeadLetterSuffix,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	}
+	carrier := sharedkafka.HeaderCarrier{Headers: &letter.Headers}
+	carrier.Set(sharedkafka.HeaderDLQError, err.Error())
+	carrier.Set(sharedkafka.HeaderDLQTopic, msg.Topic)
+	carrier.Set(sharedkafka.HeaderDLQPartition, strconv.Itoa(msg.Partition))
+	carrier.Set(sharedkafka.HeaderDLQOffset, strconv.FormatInt(msg.Offset, 10))
+
+	return d.publisher.Publish(ctx, letter)
+}
@@ -0,0 +1,77 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// kafkaBroker wraps the shared Kafka producer and consumer, so Kafka
+// deployments keep their partition ordering, commit tracking and kafka_*
+// metrics
+type kafkaBroker struct {
+	brokers []string
+	logger  *zap.Logger
+}
+
+func newKafkaBroker(brokers []string, logger *zap.Logger) *kafkaBroker {
+	return &kafkaBroker{brokers: brokers, logger: logger}
+}
+
+func (b *kafkaBroker) Type() string {
+	return TypeKafka
+}
+
+func (b *kafkaBroker) NewPublisher(topic string) Publisher {
+	return sharedkafka.NewProducer(sharedkafka.ProducerConfig{
+		Brokers: b.brokers,
+		Topic:   topic,
+	}, b.logger)
+}
+
+// NewConsumer creates a consumer group reading the topics. Groups start at
+// the oldest message unless StartAtLatest is set.
+func (b *kafkaBroker) NewConsumer(cfg ConsumerConfig, handler Handler) Consumer {
+	startOffset := kafkago.FirstOffset
+	if cfg.StartAtLatest {
+		startOffset = kafkago.LastOffset
+	}
+
+	return sharedkafka.NewConsumer(sharedkafka.ConsumerConfig{
+		Brokers:       b.brokers,
+		GroupID:       cfg.Group,
+		Topics:        cfg.Topics,
+		StartOffset:   startOffset,
+		Concurrency:   cfg.Concurrency,
+		QueueSize:     cfg.QueueSize,
+		RatePerSecond: cfg.RatePerSecond,
+		MaxAttempts:   cfg.MaxAttempts,
+		RetryBackoff:  cfg.RetryBackoff,
+		Route:         cfg.Route,
+		Retried:       cfg.Retried,
+		DeadLetters:   cfg.DeadLetters,
+		Tracer:        cfg.Tracer,
+	}, handler, b.logger)
+}
+
+// Ping connects to the first of the brokers that accepts a connection
+func (b *kafkaBroker) Ping(ctx context.Context) error {
+	var errs []error
+	for _, broker := range b.brokers {
+		conn, err := kafkago.DialContext(ctx, "tcp", broker)
+		if err == nil {
+			return conn.Close()
+		}
+		errs = append(errs, err)
+	}
+	return fmt.Errorf("failed to connect to kafka: %w", errors.Join(errs...))
+}
+
+// Close is a no-op: Kafka producers and readers hold their own connections
+func (b *kafkaBroker) Close() error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package broker
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// The RabbitMQ and NATS brokers' metrics. Kafka keeps the shared Kafka
+// package's kafka_* metrics.
+var (
+	// PublishedTotal counts published messages by result: published or
+	// failed
+	PublishedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "broker_producer_messages_total",
+		Help: "Messages published by result",
+	}, []string{"broker", "topic", "result"})
+
+	// ConsumedTotal counts consumed messages by result: processed, retried,
+	// dead_lettered or skipped
+	ConsumedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "broker_consumer_messages_total",
+		Help: "Consumed messages by result",
+	}, []string{"broker", "group", "topic", "result"})
+
+	// ProcessingDuration measures handler time per message, retries included
+	ProcessingDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "broker_consumer_processing_seconds",
+		Help:    "Time to process a message, including retries",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"broker", "group", "topic"})
+
+	// QueueDepth is the number of delivered messages waiting for a worker
+	QueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "broker_consumer_queue_depth",
+		Help: "Delivered messages waiting for a worker",
+	}, []string{"broker", "group"})
+)
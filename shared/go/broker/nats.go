@@ -0,0 +1,301 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// natsKeyHeader carries a message's key, which NATS messages don't have
+const natsKeyHeader = "X-Message-Key"
+
+// natsAckWait is how long a delivered message may go unacknowledged,
+// retries included, before JetStream redelivers it
+const natsAckWait = 5 * time.Minute
+
+// natsPingTimeout bounds Ping when its context has no deadline
+const natsPingTimeout = 5 * time.Second
+
+// natsBroker maps topics to JetStream streams with the topic as their only
+// subject, and consumer groups to a durable pull consumer per group and
+// stream. Streams are created on first use with JetStream's default limits;
+// streams created beforehand keep their own configuration.
+type natsBroker struct {
+	nc     *nats.Conn
+	js     jetstream.JetStream
+	logger *zap.Logger
+
+	mu      sync.Mutex
+	streams map[string]bool
+}
+
+// newNATSBroker connects in the background, reconnecting indefinitely
+func newNATSBroker(url string, logger *zap.Logger) (*natsBroker, error) {
+	nc, err := nats.Connect(url,
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				logger.Warn("Disconnected from NATS", zap.Error(err))
+			}
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("failed to create jetstream context: %w", err)
+	}
+
+	return &natsBroker{
+		nc:      nc,
+		js:      js,
+		logger:  logger,
+		streams: make(map[string]bool),
+	}, nil
+}
+
+func (b *natsBroker) Type() string {
+	return TypeNATS
+}
+
+// Ping round-trips to the server, failing while disconnected
+func (b *natsBroker) Ping(ctx context.Context) error {
+	if !b.nc.IsConnected() {
+		return fmt.Errorf("nats is %s", b.nc.Status())
+	}
+	// Flushing needs a deadline
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, natsPingTimeout)
+		defer cancel()
+	}
+	return b.nc.FlushWithContext(ctx)
+}
+
+func (b *natsBroker) Close() error {
+	b.nc.Close()
+	return nil
+}
+
+// natsName turns a topic or group into a stream or consumer name, which
+// can't contain subject separators or wildcards
+func natsName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', '/', '\\', ' ', '\t':
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// ensureStream creates a topic's stream unless it has been seen already.
+// A stream that exists with another configuration is used as it is.
+func (b *natsBroker) ensureStream(ctx context.Context, topic string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.streams[topic] {
+		return nil
+	}
+	_, err := b.js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     natsName(topic),
+		Subjects: []string{topic},
+		Storage:  jetstream.FileStorage,
+	})
+	if err != nil && !errors.Is(err, jetstream.ErrStreamNameAlreadyInUse) {
+		return fmt.Errorf("failed to create stream for %s: %w", topic, err)
+	}
+	b.streams[topic] = true
+	return nil
+}
+
+func (b *natsBroker) NewPublisher(topic string) Publisher {
+	return &natsPublisher{broker: b, topic: topic}
+}
+
+// natsPublisher publishes to JetStream, one message at a time so messages
+// keep their order, returning once the stream has stored them
+type natsPublisher struct {
+	broker *natsBroker
+	topic  string
+}
+
+func (p *natsPublisher) Publish(ctx context.Context, messages ...Message) error {
+	for i := range messages {
+		sharedkafka.Inject(ctx, &messages[i])
+		msg := messages[i]
+
+		topic := p.topic
+		if topic == "" {
+			topic = msg.Topic
+		}
+
+		err := p.broker.ensureStream(ctx, topic)
+		if err == nil {
+			_, err = p.broker.js.PublishMsg(ctx, toNATSMsg(topic, msg))
+		}
+		if err != nil {
+			PublishedTotal.WithLabelValues(TypeNATS, topic, "failed").Inc()
+			return fmt.Errorf("failed to publish to %s: %w", topic, err)
+		}
+		PublishedTotal.WithLabelValues(TypeNATS, topic, "published").Inc()
+	}
+	return nil
+}
+
+// Close is a no-op: publishers share the broker's connection
+func (p *natsPublisher) Close() error {
+	return nil
+}
+
+func toNATSMsg(subject string, msg Message) *nats.Msg {
+	header := make(nats.Header, len(msg.Headers)+1)
+	for _, h := range msg.Headers {
+		header.Add(h.Key, string(h.Value))
+	}
+	if len(msg.Key) > 0 {
+		header.Set(natsKeyHeader, string(msg.Key))
+	}
+	return &nats.Msg{Subject: subject, Data: msg.Value, Header: header}
+}
+
+func fromNATSMsg(msg jetstream.Msg) Message {
+	message := Message{
+		Topic: msg.Subject(),
+		Value: msg.Data(),
+	}
+	for key, values := range msg.Headers() {
+		if key == natsKeyHeader {
+			message.Key = []byte(values[0])
+			continue
+		}
+		for _, value := range values {
+			message.Headers = append(message.Headers, Header{Key: key, Value: []byte(value)})
+		}
+	}
+	if meta, err := msg.Metadata(); err == nil {
+		message.Offset = int64(meta.Sequence.Stream)
+		message.Time = meta.Timestamp
+	}
+	return message
+}
+
+func (b *natsBroker) NewConsumer(cfg ConsumerConfig, handler Handler) Consumer {
+	return &natsConsumer{
+		broker: b,
+		pool:   newPool(TypeNATS, cfg, handler, b.logger),
+	}
+}
+
+// natsConsumer pulls from its group's consumer on each topic's stream, with
+// up to QueueSize messages unacknowledged per stream
+type natsConsumer struct {
+	broker *natsBroker
+	pool   *pool
+}
+
+// Run consumes until ctx is cancelled, subscribing again after errors.
+// Messages that aren't acknowledged are redelivered once their ack wait
+// expires.
+func (c *natsConsumer) Run(ctx context.Context) error {
+	deliveries := make(chan delivery, c.pool.cfg.QueueSize)
+	poolDone := make(chan struct{})
+	go func() {
+		defer close(poolDone)
+		c.pool.run(ctx, deliveries)
+	}()
+
+	var subscriptions sync.WaitGroup
+	for _, topic := range c.pool.cfg.Topics {
+		subscriptions.Add(1)
+		go func(topic string) {
+			defer subscriptions.Done()
+			for ctx.Err() == nil {
+				err := c.consume(ctx, topic, deliveries)
+				if ctx.Err() != nil {
+					return
+				}
+				c.pool.logger.Warn("NATS consumer stopped, resubscribing", zap.String("topic", topic), zap.Error(err))
+				select {
+				case <-ctx.Done():
+				case <-time.After(reconnectDelay):
+				}
+			}
+		}(topic)
+	}
+
+	subscriptions.Wait()
+	<-poolDone
+	return nil
+}
+
+// consume forwards one topic's messages until ctx is cancelled or the
+// subscription fails
+func (c *natsConsumer) consume(ctx context.Context, topic string, out chan<- delivery) error {
+	if err := c.broker.ensureStream(ctx, topic); err != nil {
+		return err
+	}
+
+	deliverPolicy := jetstream.DeliverAllPolicy
+	if c.pool.cfg.StartAtLatest {
+		deliverPolicy = jetstream.DeliverNewPolicy
+	}
+	consumer, err := c.broker.js.CreateOrUpdateConsumer(ctx, natsName(topic), jetstream.ConsumerConfig{
+		Durable:       natsName(c.pool.cfg.Group),
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		DeliverPolicy: deliverPolicy,
+		AckWait:       natsAckWait,
+		MaxAckPending: c.pool.cfg.QueueSize,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create consumer: %w", err)
+	}
+
+	messages, err := consumer.Messages(jetstream.PullMaxMessages(c.pool.cfg.QueueSize))
+	if err != nil {
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stopped:
+		}
+		messages.Stop()
+	}()
+
+	for {
+		msg, err := messages.Next()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		next := delivery{
+			msg:  fromNATSMsg(msg),
+			ack:  msg.Ack,
+			nack: msg.Nak,
+		}
+		select {
+		case out <- next:
+		case <-ctx.Done():
+			_ = msg.Nak()
+			return nil
+		}
+	}
+}
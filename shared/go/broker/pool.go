@@ -0,0 +1,234 @@
+package broker
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+)
+
+// delivery is a message delivered by RabbitMQ or NATS, with the calls that
+// settle it
+type delivery struct {
+	msg Message
+	// ack removes the message from the broker; nack has it redelivered
+	ack  func() error
+	nack func() error
+}
+
+// pool is the RabbitMQ and NATS consumers' worker pool, working like the
+// shared Kafka consumer's: deliveries are routed to workers by ordering key,
+// retried, and dead-lettered once their attempts are used up. A message is
+// acknowledged once processed or dead-lettered; messages interrupted by
+// shutdown are released for redelivery.
+type pool struct {
+	broker  string
+	cfg     ConsumerConfig
+	handler Handler
+	logger  *zap.Logger
+}
+
+func newPool(broker string, cfg ConsumerConfig, handler Handler, logger *zap.Logger) *pool {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 100
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = time.Second
+	}
+	if cfg.Route == nil {
+		cfg.Route = defaultRoute
+	}
+
+	return &pool{
+		broker:  broker,
+		cfg:     cfg,
+		handler: handler,
+		logger:  logger.With(zap.String("broker", broker), zap.String("group", cfg.Group)),
+	}
+}
+
+// run processes deliveries until ctx is cancelled. Each worker has its own
+// queue, so one key is never processed by two workers at once while
+// different keys run in parallel.
+func (p *pool) run(ctx context.Context, deliveries <-chan delivery) {
+	var throttle <-chan time.Time
+	if p.cfg.RatePerSecond > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(p.cfg.RatePerSecond))
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	workerQueueSize := p.cfg.QueueSize / p.cfg.Concurrency
+	if workerQueueSize < 1 {
+		workerQueueSize = 1
+	}
+
+	var wg sync.WaitGroup
+	queues := make([]chan delivery, p.cfg.Concurrency)
+	for i := range queues {
+		queues[i] = make(chan delivery, workerQueueSize)
+		wg.Add(1)
+		go func(queue <-chan delivery) {
+			defer wg.Done()
+			p.work(ctx, queue, throttle)
+		}(queues[i])
+	}
+
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			done = true
+		case d := <-deliveries:
+			QueueDepth.WithLabelValues(p.broker, p.cfg.Group).Set(float64(len(deliveries)))
+
+			key, process := p.cfg.Route(d.msg)
+			if !process {
+				ConsumedTotal.WithLabelValues(p.broker, p.cfg.Group, d.msg.Topic, "skipped").Inc()
+				p.settle(d.ack, d.msg)
+				continue
+			}
+
+			select {
+			case queues[shard(key, len(queues))] <- d:
+			case <-ctx.Done():
+				p.settle(d.nack, d.msg)
+				done = true
+			}
+		}
+	}
+	wg.Wait()
+
+	// Release what the workers didn't get to
+	for _, queue := range queues {
+		for len(queue) > 0 {
+			d := <-queue
+			p.settle(d.nack, d.msg)
+		}
+	}
+}
+
+// shard picks the worker an ordering key is pinned to
+func shard(key string, workers int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(workers))
+}
+
+func (p *pool) work(ctx context.Context, deliveries <-chan delivery, throttle <-chan time.Time) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-deliveries:
+			if throttle != nil {
+				select {
+				case <-ctx.Done():
+					p.settle(d.nack, d.msg)
+					return
+				case <-throttle:
+				}
+			}
+
+			p.process(ctx, d.msg)
+
+			if ctx.Err() != nil {
+				p.settle(d.nack, d.msg)
+				return
+			}
+			p.settle(d.ack, d.msg)
+		}
+	}
+}
+
+// settle acknowledges or releases a message, logging failures: the broker
+// then redelivers the message, so they cost a duplicate at worst
+func (p *pool) settle(settle func() error, msg Message) {
+	if err := settle(); err != nil {
+		p.logger.Warn("Failed to settle message",
+			zap.String("topic", msg.Topic),
+			zap.Int64("offset", msg.Offset),
+			zap.Error(err),
+		)
+	}
+}
+
+// process runs the handler until it succeeds or its attempts are used up,
+// then dead-letters the message
+func (p *pool) process(ctx context.Context, msg Message) {
+	start := time.Now()
+	defer func() {
+		ProcessingDuration.WithLabelValues(p.broker, p.cfg.Group, msg.Topic).Observe(time.Since(start).Seconds())
+	}()
+
+	backoff := p.cfg.RetryBackoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = p.handle(sharedkafka.WithAttempt(ctx, attempt), msg); err == nil {
+			ConsumedTotal.WithLabelValues(p.broker, p.cfg.Group, msg.Topic, "processed").Inc()
+			return
+		}
+		if ctx.Err() != nil || attempt == p.cfg.MaxAttempts {
+			break
+		}
+
+		ConsumedTotal.WithLabelValues(p.broker, p.cfg.Group, msg.Topic, "retried").Inc()
+		if p.cfg.Retried != nil {
+			p.cfg.Retried(ctx, msg, err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	ConsumedTotal.WithLabelValues(p.broker, p.cfg.Group, msg.Topic, "dead_lettered").Inc()
+	logger := logging.WithContext(sharedkafka.Extract(ctx, msg), p.logger)
+	if p.cfg.DeadLetters == nil {
+		logger.Error("Skipping message that failed processing",
+			zap.String("topic", msg.Topic),
+			zap.Int64("offset", msg.Offset),
+			zap.Error(err),
+		)
+		return
+	}
+	if dlqErr := p.cfg.DeadLetters.DeadLetter(ctx, msg, err); dlqErr != nil {
+		logger.Error("Failed to dead-letter message",
+			zap.String("topic", msg.Topic),
+			zap.Int64("offset", msg.Offset),
+			zap.NamedError("cause", err),
+			zap.Error(dlqErr),
+		)
+	}
+}
+
+// handle runs the handler once, in a consumer span if there is a tracer
+func (p *pool) handle(ctx context.Context, msg Message) error {
+	if p.cfg.Tracer == nil {
+		return p.handler(ctx, msg)
+	}
+
+	ctx, span := sharedkafka.StartSpan(ctx, p.cfg.Tracer, msg)
+	defer span.End()
+
+	err := p.handler(ctx, msg)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,354 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	sharedkafka "github.com/ecommerce-platform/shared/go/kafka"
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+// reconnectDelay is how long RabbitMQ and NATS consumers wait before
+// subscribing again after losing their connection
+const reconnectDelay = 5 * time.Second
+
+// rabbitMQBroker maps topics to durable topic exchanges, routed by message
+// key, and consumer groups to a durable queue per group and topic, named
+// <group>.<topic>. The connection is dialed on first use and again after
+// it is lost.
+type rabbitMQBroker struct {
+	url    string
+	logger *zap.Logger
+
+	mu   sync.Mutex
+	conn *amqp.Connection
+}
+
+func newRabbitMQBroker(url string, logger *zap.Logger) *rabbitMQBroker {
+	return &rabbitMQBroker{url: url, logger: logger}
+}
+
+func (b *rabbitMQBroker) Type() string {
+	return TypeRabbitMQ
+}
+
+// channel opens a channel, dialing if there is no open connection
+func (b *rabbitMQBroker) channel() (*amqp.Channel, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.conn == nil || b.conn.IsClosed() {
+		conn, err := amqp.Dial(b.url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to rabbitmq: %w", err)
+		}
+		b.conn = conn
+	}
+	return b.conn.Channel()
+}
+
+// Ping opens and closes a channel, dialing if there is no open connection
+func (b *rabbitMQBroker) Ping(ctx context.Context) error {
+	ch, err := b.channel()
+	if err != nil {
+		return err
+	}
+	return ch.Close()
+}
+
+func (b *rabbitMQBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.conn == nil || b.conn.IsClosed() {
+		return nil
+	}
+	return b.conn.Close()
+}
+
+// declareExchange declares a topic's exchange. Dead letter topics also get
+// a queue of the same name, since a message published to an exchange
+// without queues is dropped.
+func declareExchange(ch *amqp.Channel, topic string) error {
+	if err := ch.ExchangeDeclare(topic, amqp.ExchangeTopic, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare exchange %s: %w", topic, err)
+	}
+	if strings.HasSuffix(topic, sharedkafka.DeadLetterSuffix) {
+		return bindQueue(ch, topic, topic)
+	}
+	return nil
+}
+
+// bindQueue declares a durable queue receiving every message of a topic
+func bindQueue(ch *amqp.Channel, queue, topic string) error {
+	if _, err := ch.QueueDeclare(queue, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare queue %s: %w", queue, err)
+	}
+	if err := ch.QueueBind(queue, "#", topic, false, nil); err != nil {
+		return fmt.Errorf("failed to bind queue %s: %w", queue, err)
+	}
+	return nil
+}
+
+func (b *rabbitMQBroker) NewPublisher(topic string) Publisher {
+	return &rabbitMQPublisher{broker: b, topic: topic}
+}
+
+// rabbitMQPublisher publishes persistent messages on a channel in confirm
+// mode, so Publish returns once RabbitMQ has taken the messages
+type rabbitMQPublisher struct {
+	broker *rabbitMQBroker
+	topic  string
+
+	mu       sync.Mutex
+	ch       *amqp.Channel
+	declared map[string]bool
+}
+
+func (p *rabbitMQPublisher) Publish(ctx context.Context, messages ...Message) error {
+	confirms, err := p.publish(ctx, messages)
+	if err == nil {
+		for _, confirm := range confirms {
+			ok, waitErr := confirm.WaitContext(ctx)
+			if waitErr != nil {
+				err = waitErr
+				break
+			}
+			if !ok {
+				err = errors.New("rabbitmq rejected message")
+				break
+			}
+		}
+	}
+
+	result := "published"
+	if err != nil {
+		result = "failed"
+	}
+	for _, msg := range messages {
+		PublishedTotal.WithLabelValues(TypeRabbitMQ, p.topicOf(msg), result).Inc()
+	}
+	return err
+}
+
+// publish sends messages without waiting for their confirmations. Messages
+// share a channel, so they are sent one publisher call at a time.
+func (p *rabbitMQPublisher) publish(ctx context.Context, messages []Message) ([]*amqp.DeferredConfirmation, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ch == nil || p.ch.IsClosed() {
+		ch, err := p.broker.channel()
+		if err != nil {
+			return nil, err
+		}
+		if err := ch.Confirm(false); err != nil {
+			ch.Close()
+			return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+		}
+		p.ch = ch
+		p.declared = make(map[string]bool)
+	}
+
+	confirms := make([]*amqp.DeferredConfirmation, 0, len(messages))
+	for i := range messages {
+		sharedkafka.Inject(ctx, &messages[i])
+		// Delivery tags change on redelivery, so messages carry an ID
+		carrier := sharedkafka.HeaderCarrier{Headers: &messages[i].Headers}
+		if carrier.Get(HeaderMessageID) == "" {
+			carrier.Set(HeaderMessageID, uuid.NewString())
+		}
+		msg := messages[i]
+
+		topic := p.topicOf(msg)
+		if !p.declared[topic] {
+			if err := declareExchange(p.ch, topic); err != nil {
+				return nil, err
+			}
+			p.declared[topic] = true
+		}
+
+		timestamp := msg.Time
+		if timestamp.IsZero() {
+			timestamp = time.Now()
+		}
+		confirm, err := p.ch.PublishWithDeferredConfirmWithContext(ctx, topic, string(msg.Key), false, false, amqp.Publishing{
+			Headers:      toTable(msg.Headers),
+			DeliveryMode: amqp.Persistent,
+			Timestamp:    timestamp,
+			Body:         msg.Value,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to publish to %s: %w", topic, err)
+		}
+		confirms = append(confirms, confirm)
+	}
+	return confirms, nil
+}
+
+func (p *rabbitMQPublisher) topicOf(msg Message) string {
+	if p.topic != "" {
+		return p.topic
+	}
+	return msg.Topic
+}
+
+func (p *rabbitMQPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ch == nil || p.ch.IsClosed() {
+		return nil
+	}
+	return p.ch.Close()
+}
+
+func toTable(headers []Header) amqp.Table {
+	table := make(amqp.Table, len(headers))
+	for _, h := range headers {
+		table[h.Key] = string(h.Value)
+	}
+	return table
+}
+
+func fromTable(table amqp.Table) []Header {
+	headers := make([]Header, 0, len(table))
+	for key, value := range table {
+		var v []byte
+		switch value := value.(type) {
+		case string:
+			v = []byte(value)
+		case []byte:
+			v = value
+		default:
+			v = []byte(fmt.Sprint(value))
+		}
+		headers = append(headers, Header{Key: key, Value: v})
+	}
+	return headers
+}
+
+func (b *rabbitMQBroker) NewConsumer(cfg ConsumerConfig, handler Handler) Consumer {
+	return &rabbitMQConsumer{
+		broker: b,
+		pool:   newPool(TypeRabbitMQ, cfg, handler, b.logger),
+	}
+}
+
+// rabbitMQConsumer consumes its group's queues, prefetching up to QueueSize
+// unacknowledged messages per queue
+type rabbitMQConsumer struct {
+	broker *rabbitMQBroker
+	pool   *pool
+}
+
+// Run consumes until ctx is cancelled, subscribing again whenever the
+// connection is lost. Unacknowledged messages are requeued by RabbitMQ
+// when their channel closes.
+func (c *rabbitMQConsumer) Run(ctx context.Context) error {
+	deliveries := make(chan delivery, c.pool.cfg.QueueSize)
+	poolDone := make(chan struct{})
+	go func() {
+		defer close(poolDone)
+		c.pool.run(ctx, deliveries)
+	}()
+
+	for ctx.Err() == nil {
+		err := c.consume(ctx, deliveries, poolDone)
+		if ctx.Err() != nil {
+			break
+		}
+		c.pool.logger.Warn("RabbitMQ consumer disconnected, resubscribing", zap.Error(err))
+		select {
+		case <-ctx.Done():
+		case <-time.After(reconnectDelay):
+		}
+	}
+
+	<-poolDone
+	return nil
+}
+
+// consume subscribes to the group's queues on one channel and forwards
+// their deliveries until ctx is cancelled or the channel closes. On
+// shutdown the channel stays open until the workers have settled their
+// messages.
+func (c *rabbitMQConsumer) consume(ctx context.Context, out chan<- delivery, poolDone <-chan struct{}) error {
+	ch, err := c.broker.channel()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if ctx.Err() != nil {
+			<-poolDone
+		}
+		ch.Close()
+	}()
+
+	if err := ch.Qos(c.pool.cfg.QueueSize, 0, false); err != nil {
+		return fmt.Errorf("failed to set prefetch: %w", err)
+	}
+
+	var forwarders sync.WaitGroup
+	for _, topic := range c.pool.cfg.Topics {
+		if err := declareExchange(ch, topic); err != nil {
+			return err
+		}
+		queue := c.pool.cfg.Group + "." + topic
+		if err := bindQueue(ch, queue, topic); err != nil {
+			return err
+		}
+		messages, err := ch.Consume(queue, "", false, false, false, false, nil)
+		if err != nil {
+			return fmt.Errorf("failed to consume %s: %w", queue, err)
+		}
+
+		forwarders.Add(1)
+		go func() {
+			defer forwarders.Done()
+			c.forward(ctx, messages, out)
+		}()
+	}
+	forwarders.Wait()
+
+	return errors.New("rabbitmq channel closed")
+}
+
+// forward hands a queue's deliveries to the pool
+func (c *rabbitMQConsumer) forward(ctx context.Context, messages <-chan amqp.Delivery, out chan<- delivery) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d, ok := <-messages:
+			if !ok {
+				return
+			}
+			next := delivery{
+				msg: Message{
+					Topic:   d.Exchange,
+					Offset:  int64(d.DeliveryTag),
+					Key:     []byte(d.RoutingKey),
+					Value:   d.Body,
+					Headers: fromTable(d.Headers),
+					Time:    d.Timestamp,
+				},
+				ack:  func() error { return d.Ack(false) },
+				nack: func() error { return d.Nack(false, true) },
+			}
+
+			select {
+			case out <- next:
+			case <-ctx.Done():
+				_ = d.Nack(false, true)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,426 @@
+// Package config populates configuration structs from command-line flags,
+// environment variables, secret files and env files, as described by
+// struct tags
+package config
+
+import (
+	"bufio"
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Struct tags read by Load
+const (
+	// tagEnv names the environment variable; comma-separated names are
+	// tried in order, so a renamed variable can keep its old name
+	tagEnv = "env"
+	// tagDefault is used when no source sets the field
+	tagDefault = "default"
+	// tagRequired ("true") makes an unset or empty field an error
+	tagRequired = "required"
+	// tagSecret ("true") hides the value in Redacted
+	tagSecret = "secret"
+	// tagFlag names a command-line flag that overrides the environment
+	tagFlag = "flag"
+)
+
+// redactedValue replaces set secrets in Redacted
+const redactedValue = "[REDACTED]"
+
+// Resolver resolves secret references, e.g. vault://kv/jwt-secret, to the
+// secrets they name; shared/go/secrets implements it
+type Resolver interface {
+	// Resolve returns the secret value names, or value itself if it isn't
+	// a reference
+	Resolve(value string) (string, error)
+}
+
+// Validator is implemented by configs that check or normalize themselves
+// once loaded
+type Validator interface {
+	Validate() error
+}
+
+// Options chooses where Load reads values besides the environment
+type Options struct {
+	// Args are parsed as flags for fields with a flag tag, e.g. os.Args[1:]
+	Args []string
+	// Files are KEY=VALUE env files, read in order with the environment
+	// taking precedence; missing files are skipped
+	Files []string
+	// Secrets, if set, resolves values that are secret references, from
+	// any source
+	Secrets Resolver
+}
+
+// Load populates target, a pointer to a struct, from the environment. See
+// LoadWith.
+func Load(target interface{}) error {
+	return LoadWith(target, Options{})
+}
+
+// LoadWith populates target, a pointer to a struct, then calls its Validate
+// method if it has one. Each field's value comes from the first of:
+//
+//   - its flag, if it has a flag tag and opts.Args set it
+//   - its environment variable
+//   - the file named by its variable with a _FILE suffix, e.g. a Docker secret
+//     in DB_PASSWORD_FILE
+//   - opts.Files
+//   - its default tag
+//
+// With opts.Secrets set, values that are secret references are replaced by
+// the secrets they name. Nested structs are populated too. Every invalid or
+// missing value is reported, not just the first.
+func LoadWith(target interface{}, opts Options) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		return errors.New("config: target must be a pointer to a struct")
+	}
+
+	fileValues := make(map[string]string)
+	for _, path := range opts.Files {
+		if err := readEnvFile(path, fileValues); err != nil {
+			return err
+		}
+	}
+
+	flagValues, err := parseFlags(value.Elem(), opts.Args)
+	if err != nil {
+		return err
+	}
+
+	l := &loader{flags: flagValues, files: fileValues, secrets: opts.Secrets}
+	l.populate(value.Elem())
+	if len(l.errs) > 0 {
+		return errors.Join(l.errs...)
+	}
+
+	if validator, ok := target.(Validator); ok {
+		return validator.Validate()
+	}
+	return nil
+}
+
+type loader struct {
+	flags   map[string]string
+	files   map[string]string
+	secrets Resolver
+	errs    []error
+}
+
+func (l *loader) populate(value reflect.Value) {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		envTag := field.Tag.Get(tagEnv)
+		if envTag == "" {
+			if field.Type.Kind() == reflect.Struct && !isText(field.Type) {
+				l.populate(value.Field(i))
+			}
+			continue
+		}
+		names := strings.Split(envTag, ",")
+
+		raw, ok, err := l.lookup(names, field.Tag.Get(tagFlag))
+		if err != nil {
+			l.errs = append(l.errs, err)
+			continue
+		}
+		if !ok {
+			raw, ok = field.Tag.Lookup(tagDefault)
+		}
+		if !ok || raw == "" {
+			if field.Tag.Get(tagRequired) == "true" {
+				l.errs = append(l.errs, fmt.Errorf("%s is required", names[0]))
+				continue
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		if l.secrets != nil && raw != "" {
+			if raw, err = l.secrets.Resolve(raw); err != nil {
+				l.errs = append(l.errs, fmt.Errorf("invalid %s: %w", names[0], err))
+				continue
+			}
+		}
+
+		if err := setField(value.Field(i), raw); err != nil {
+			l.errs = append(l.errs, fmt.Errorf("invalid %s: %w", names[0], err))
+		}
+	}
+}
+
+// lookup returns a field's value from the first source that sets it
+func (l *loader) lookup(names []string, flagName string) (string, bool, error) {
+	if flagName != "" {
+		if raw, ok := l.flags[flagName]; ok {
+			return raw, true, nil
+		}
+	}
+	for _, name := range names {
+		if raw := os.Getenv(name); raw != "" {
+			return raw, true, nil
+		}
+	}
+	for _, name := range names {
+		if path := os.Getenv(name + "_FILE"); path != "" {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return "", false, fmt.Errorf("invalid %s_FILE: %w", name, err)
+			}
+			return strings.TrimSpace(string(content)), true, nil
+		}
+	}
+	for _, name := range names {
+		if raw, ok := l.files[name]; ok && raw != "" {
+			return raw, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// parseFlags parses args as the flags named by the struct's flag tags and
+// returns the ones that were set
+func parseFlags(value reflect.Value, args []string) (map[string]string, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	flags := flag.NewFlagSet("config", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	registerFlags(flags, value.Type())
+	if err := flags.Parse(args); err != nil {
+		return nil, fmt.Errorf("invalid flags: %w", err)
+	}
+
+	values := make(map[string]string)
+	flags.Visit(func(f *flag.Flag) {
+		values[f.Name] = f.Value.String()
+	})
+	return values, nil
+}
+
+func registerFlags(flags *flag.FlagSet, valueType reflect.Type) {
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if name := field.Tag.Get(tagFlag); name != "" {
+			flags.String(name, field.Tag.Get(tagDefault), "sets "+field.Tag.Get(tagEnv))
+		} else if field.Type.Kind() == reflect.Struct && field.Tag.Get(tagEnv) == "" && !isText(field.Type) {
+			registerFlags(flags, field.Type)
+		}
+	}
+}
+
+// readEnvFile reads KEY=VALUE lines into values. Blank lines and lines
+// starting with # are skipped, and values may be quoted.
+func readEnvFile(path string, values map[string]string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			return fmt.Errorf("config: %s:%d: expected KEY=VALUE", path, n)
+		}
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
+		}
+		// Earlier files take precedence over later ones
+		if _, seen := values[strings.TrimSpace(key)]; !seen {
+			values[strings.TrimSpace(key)] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	return nil
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isText reports whether a type parses itself with UnmarshalText
+func isText(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// setField parses raw into a field. Lists are comma-separated, with blank
+// items dropped; maps are comma-separated key=value pairs.
+func setField(field reflect.Value, raw string) error {
+	if isText(field.Type()) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+
+	switch field.Kind() {
+	case reflect.Slice:
+		items := splitList(raw)
+		slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setScalar(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+
+	case reflect.Map:
+		pairs, err := splitMap(raw)
+		if err != nil {
+			return err
+		}
+		m := reflect.MakeMapWithSize(field.Type(), len(pairs))
+		for _, pair := range pairs {
+			key := reflect.New(field.Type().Key()).Elem()
+			if err := setScalar(key, pair[0]); err != nil {
+				return err
+			}
+			value := reflect.New(field.Type().Elem()).Elem()
+			if err := setScalar(value, pair[1]); err != nil {
+				return fmt.Errorf("bad value %q for %s: %w", pair[1], pair[0], err)
+			}
+			m.SetMapIndex(key, value)
+		}
+		field.Set(m)
+		return nil
+	}
+
+	return setScalar(field, raw)
+}
+
+func setScalar(field reflect.Value, raw string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// splitMap parses comma-separated key=value pairs
+func splitMap(value string) ([][2]string, error) {
+	var pairs [][2]string
+	for _, pair := range splitList(value) {
+		key, val, ok := strings.Cut(pair, "=")
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		if !ok || key == "" || val == "" {
+			return nil, fmt.Errorf("expected key=value, got %q", pair)
+		}
+		pairs = append(pairs, [2]string{key, val})
+	}
+	return pairs, nil
+}
+
+// Redacted returns a config's tagged fields by variable name, for logging.
+// Secrets that are set show as [REDACTED].
+func Redacted(cfg interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	value := reflect.Indirect(reflect.ValueOf(cfg))
+	if value.Kind() == reflect.Struct {
+		redact(value, fields)
+	}
+	return fields
+}
+
+func redact(value reflect.Value, fields map[string]interface{}) {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		envTag := field.Tag.Get(tagEnv)
+		if envTag == "" {
+			if field.Type.Kind() == reflect.Struct && !isText(field.Type) {
+				redact(value.Field(i), fields)
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(envTag, ",")
+
+		if field.Tag.Get(tagSecret) == "true" {
+			if value.Field(i).IsZero() {
+				fields[name] = ""
+			} else {
+				fields[name] = redactedValue
+			}
+			continue
+		}
+		fields[name] = value.Field(i).Interface()
+	}
+}
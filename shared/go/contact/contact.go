@@ -0,0 +1,31 @@
+// Package contact decides what a valid email address or phone number is,
+// and the canonical form to store and send to, so that registration,
+// notification senders and order flows agree on contacts
+package contact
+
+import "fmt"
+
+// Validation error codes, for metrics and API responses
+const (
+	CodeEmpty          = "empty"
+	CodeInvalidFormat  = "invalid_format"
+	CodeTooLong        = "too_long"
+	CodeUnknownCountry = "unknown_country"
+	CodeInvalidLength  = "invalid_length"
+	CodeInvalidNumber  = "invalid_number"
+	CodeNotMobile      = "not_mobile"
+)
+
+// Error is an invalid email address or phone number, with a
+// machine-readable code
+type Error struct {
+	// Kind is "email" or "phone"
+	Kind    string
+	Value   string
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid %s (%s): %s", e.Kind, e.Code, e.Message)
+}
@@ -0,0 +1,44 @@
+package contact
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// maxEmailLength is the longest address SMTP can deliver to (RFC 5321)
+const maxEmailLength = 254
+
+// NormalizeEmail returns the canonical form of an email address: trimmed
+// and lower case, which is how addresses are stored and compared. It
+// rejects display names ("Jane <jane@example.com>"), addresses without a
+// dotted domain, and addresses longer than 254 characters.
+func NormalizeEmail(email string) (string, error) {
+	address := strings.ToLower(strings.TrimSpace(email))
+	if address == "" {
+		return "", emailError(email, CodeEmpty, "no email address")
+	}
+	if len(address) > maxEmailLength {
+		return "", emailError(email, CodeTooLong, "longer than 254 characters")
+	}
+
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Address != address {
+		return "", emailError(email, CodeInvalidFormat, "not a plain email address")
+	}
+	_, domain, _ := strings.Cut(address, "@")
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return "", emailError(email, CodeInvalidFormat, "domain must be a fully qualified name")
+	}
+
+	return address, nil
+}
+
+// ValidEmail reports whether NormalizeEmail accepts email
+func ValidEmail(email string) bool {
+	_, err := NormalizeEmail(email)
+	return err == nil
+}
+
+func emailError(email, code, message string) *Error {
+	return &Error{Kind: "email", Value: email, Code: code, Message: message}
+}
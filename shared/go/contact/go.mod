@@ -0,0 +1,10 @@
+module github.com/ecommerce-platform/shared/go/contact
+
+go 1.21
+
+require github.com/nyaruka/phonenumbers v1.1.9
+
+require (
+	github.com/golang/protobuf v1.3.2 // indirect
+	golang.org/x/text v0.3.8 // indirect
+)
@@ -0,0 +1,105 @@
+package contact
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+// Phone is a parsed, valid phone number
+type Phone struct {
+	number *phonenumbers.PhoneNumber
+}
+
+// ParsePhone parses a phone number, in any common format, with
+// libphonenumber's metadata. Numbers without a leading + or international
+// prefix are national numbers of defaultRegion (ISO 3166-1 alpha-2, e.g.
+// GB); with an empty defaultRegion they are rejected.
+func ParsePhone(phone, defaultRegion string) (Phone, error) {
+	if strings.TrimSpace(phone) == "" {
+		return Phone{}, phoneError(phone, CodeEmpty, "no phone number")
+	}
+
+	number, err := phonenumbers.Parse(phone, strings.ToUpper(defaultRegion))
+	switch {
+	case errors.Is(err, phonenumbers.ErrInvalidCountryCode):
+		if defaultRegion == "" && !strings.HasPrefix(strings.TrimSpace(phone), "+") {
+			return Phone{}, phoneError(phone, CodeUnknownCountry, "national number without a country code")
+		}
+		return Phone{}, phoneError(phone, CodeUnknownCountry, "unknown country calling code")
+	case errors.Is(err, phonenumbers.ErrTooShortNSN), errors.Is(err, phonenumbers.ErrNumTooLong):
+		return Phone{}, phoneError(phone, CodeInvalidLength, "too short or too long")
+	case err != nil:
+		return Phone{}, phoneError(phone, CodeInvalidFormat, "not a phone number")
+	}
+
+	if !phonenumbers.IsValidNumber(number) {
+		return Phone{}, phoneError(phone, CodeInvalidNumber, "not an assigned number range")
+	}
+	return Phone{number: number}, nil
+}
+
+// NormalizePhone returns a phone number's canonical E.164 form, e.g.
+// +442079460958, which is how numbers are stored and sent to
+func NormalizePhone(phone, defaultRegion string) (string, error) {
+	p, err := ParsePhone(phone, defaultRegion)
+	if err != nil {
+		return "", err
+	}
+	return p.E164(), nil
+}
+
+// NormalizeMobile is NormalizePhone for numbers that must receive SMS: it
+// also rejects numbers in landline and other non-mobile ranges, where the
+// country's mobile ranges are distinct
+func NormalizeMobile(phone, defaultRegion string) (string, error) {
+	p, err := ParsePhone(phone, defaultRegion)
+	if err != nil {
+		return "", err
+	}
+	if !p.IsMobile() {
+		return "", phoneError(phone, CodeNotMobile, "landline or non-mobile number")
+	}
+	return p.E164(), nil
+}
+
+// E164 formats the number as +<country code><number>, e.g. +442079460958
+func (p Phone) E164() string {
+	return phonenumbers.Format(p.number, phonenumbers.E164)
+}
+
+// International formats the number for people abroad, e.g. +44 20 7946 0958
+func (p Phone) International() string {
+	return phonenumbers.Format(p.number, phonenumbers.INTERNATIONAL)
+}
+
+// National formats the number for people in its country, e.g. 020 7946 0958
+func (p Phone) National() string {
+	return phonenumbers.Format(p.number, phonenumbers.NATIONAL)
+}
+
+// Region returns the number's ISO 3166-1 alpha-2 country, e.g. GB
+func (p Phone) Region() string {
+	return phonenumbers.GetRegionCodeForNumber(p.number)
+}
+
+// IsMobile reports whether the number may be a mobile: it is in a mobile
+// range, or in a country whose mobile and landline ranges can't be told
+// apart, e.g. the US
+func (p Phone) IsMobile() bool {
+	switch phonenumbers.GetNumberType(p.number) {
+	case phonenumbers.MOBILE, phonenumbers.FIXED_LINE_OR_MOBILE:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p Phone) String() string {
+	return p.E164()
+}
+
+func phoneError(phone, code, message string) *Error {
+	return &Error{Kind: "phone", Value: phone, Code: code, Message: message}
+}
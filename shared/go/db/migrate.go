@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// migrationLockID is the Postgres advisory lock held while migrating, so
+// replicas starting together apply each migration once
+const migrationLockID = 7_262_071_400
+
+// Migrate applies the .sql files at the root of fsys that haven't been
+// applied yet, in name order, e.g. 001_create_users.sql before
+// 002_add_phone.sql. Each runs in its own transaction and is recorded in
+// the schema_migrations table, so a failed migration leaves nothing behind
+// and is retried on the next start. Statements that can't run in a
+// transaction, such as CREATE INDEX CONCURRENTLY, aren't supported.
+//
+// Services embed their migrations directory:
+//
+//	//go:embed *.sql
+//	var FS embed.FS
+func Migrate(ctx context.Context, db *sql.DB, fsys fs.FS, logger *zap.Logger) error {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return fmt.Errorf("db: failed to list migrations: %w", err)
+	}
+	sort.Strings(files)
+
+	// Advisory locks belong to a session, so everything runs on one
+	// connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("db: failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("db: failed to lock migrations: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version VARCHAR(255) PRIMARY KEY,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`); err != nil {
+		return fmt.Errorf("db: failed to create schema_migrations: %w", err)
+	}
+
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, file := range files {
+		if applied[file] {
+			continue
+		}
+
+		script, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return fmt.Errorf("db: failed to read migration %s: %w", file, err)
+		}
+		if err := applyMigration(ctx, conn, file, string(script)); err != nil {
+			return err
+		}
+
+		logger.Info("Migration applied", zap.String("migration", file))
+		count++
+	}
+
+	logger.Info("Database migrated", zap.Int("applied", count), zap.Int("migrations", len(files)))
+	return nil
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[string]bool, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("db: failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("db: failed to read applied migrations: %w", err)
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+func applyMigration(ctx context.Context, conn *sql.Conn, version, script string) (err error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("db: failed to begin migration %s: %w", version, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// Without arguments, the whole script is sent as one simple query
+	if _, err = tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("db: migration %s failed: %w", version, err)
+	}
+	if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", version); err != nil {
+		return fmt.Errorf("db: failed to record migration %s: %w", version, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("db: failed to commit migration %s: %w", version, err)
+	}
+	return nil
+}
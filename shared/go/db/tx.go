@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Querier is what *sql.DB and *sql.Tx have in common, so repositories can
+// run the same queries in and out of transactions
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// WithTx runs fn in a transaction, committed if fn returns nil and rolled
+// back if it returns an error or panics. opts may be nil.
+func WithTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("db: failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("db: failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RetryPolicy configures WithRetry. Zero values get defaults.
+type RetryPolicy struct {
+	// MaxAttempts, including the first; default 5
+	MaxAttempts int
+	// InitialBackoff doubles after every attempt, with full jitter, up to
+	// MaxBackoff; default 10ms and 1s
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// WithRetry calls fn until it succeeds, fails with an error other than a
+// serialization failure or deadlock, or runs out of attempts. Postgres
+// aborts such transactions expecting them to be retried, so fn must run a
+// whole transaction, e.g. with WithTx.
+func WithRetry(ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) error) error {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 5
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = 10 * time.Millisecond
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = time.Second
+	}
+
+	backoff := policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || !IsSerializationFailure(err) || attempt >= policy.MaxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(rand.Int63n(int64(backoff) + 1))):
+		}
+
+		backoff *= 2
+		if backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// WithSerializableTx runs fn in a SERIALIZABLE transaction, retried with
+// the default RetryPolicy when it conflicts with a concurrent one. fn may
+// run several times, so it must not have effects outside the transaction.
+func WithSerializableTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	return WithRetry(ctx, RetryPolicy{}, func(ctx context.Context) error {
+		return WithTx(ctx, db, &sql.TxOptions{Isolation: sql.LevelSerializable}, fn)
+	})
+}
+
+// IsSerializationFailure reports whether err is a serialization failure
+// (SQLSTATE 40001) or a deadlock (40P01), after which a transaction can be
+// retried
+func IsSerializationFailure(err error) bool {
+	var sqlErr interface{ SQLState() string }
+	if !errors.As(err, &sqlErr) {
+		return false
+	}
+	switch sqlErr.SQLState() {
+	case "40001", "40P01":
+		return true
+	}
+	return false
+}
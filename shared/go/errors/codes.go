@@ -0,0 +1,111 @@
+package errors
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Codes clients can rely on; the constructors in this package use them
+const (
+	CodeBadRequest         = "BAD_REQUEST"
+	CodeValidationFailed   = "VALIDATION_FAILED"
+	CodeUnauthorized       = "UNAUTHORIZED"
+	CodeForbidden          = "FORBIDDEN"
+	CodeNotFound           = "NOT_FOUND"
+	CodeConflict           = "CONFLICT"
+	CodeTooManyRequests    = "TOO_MANY_REQUESTS"
+	CodeInternal           = "INTERNAL_ERROR"
+	CodeServiceUnavailable = "SERVICE_UNAVAILABLE"
+	CodeTimeout            = "GATEWAY_TIMEOUT"
+)
+
+// CodeInfo describes a registered code
+type CodeInfo struct {
+	Code        string `json:"code"`
+	Status      int    `json:"status"`
+	Description string `json:"description"`
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]CodeInfo{
+		CodeBadRequest:         {CodeBadRequest, http.StatusBadRequest, "The request is malformed"},
+		CodeValidationFailed:   {CodeValidationFailed, http.StatusBadRequest, "Fields of the request are invalid, listed in fields"},
+		CodeUnauthorized:       {CodeUnauthorized, http.StatusUnauthorized, "Authentication is missing or invalid"},
+		CodeForbidden:          {CodeForbidden, http.StatusForbidden, "The caller may not make the request"},
+		CodeNotFound:           {CodeNotFound, http.StatusNotFound, "The resource doesn't exist"},
+		CodeConflict:           {CodeConflict, http.StatusConflict, "The request conflicts with the resource's current state"},
+		CodeTooManyRequests:    {CodeTooManyRequests, http.StatusTooManyRequests, "The caller is rate limited; retry after Retry-After"},
+		CodeInternal:           {CodeInternal, http.StatusInternalServerError, "An unexpected server error"},
+		CodeServiceUnavailable: {CodeServiceUnavailable, http.StatusServiceUnavailable, "A dependency is unavailable; retry later"},
+		CodeTimeout:            {CodeTimeout, http.StatusGatewayTimeout, "A dependency didn't respond in time; retry later"},
+	}
+)
+
+// Register adds a code to the registry, e.g. a service's own
+// INSUFFICIENT_STOCK. It panics if the code is already registered, so
+// call it from init or main.
+func Register(code string, status int, description string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[code]; ok {
+		panic(fmt.Sprintf("errors: code %s registered twice", code))
+	}
+	registry[code] = CodeInfo{Code: code, Status: status, Description: description}
+}
+
+// Lookup returns the registered code
+func Lookup(code string) (CodeInfo, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	info, ok := registry[code]
+	return info, ok
+}
+
+// Codes returns every registered code, sorted, e.g. to document them
+func Codes() []CodeInfo {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	codes := make([]CodeInfo, 0, len(registry))
+	for _, info := range registry {
+		codes = append(codes, info)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].Code < codes[j].Code })
+	return codes
+}
+
+// NewCode creates an error with a registered code and its status. Codes
+// that aren't registered are a bug and become internal errors.
+func NewCode(code, message string) *AppError {
+	info, ok := Lookup(code)
+	if !ok {
+		return &AppError{
+			Code:       CodeInternal,
+			Message:    "Internal server error",
+			StatusCode: http.StatusInternalServerError,
+			Internal:   fmt.Errorf("unregistered error code %s", code),
+			stack:      callers(),
+		}
+	}
+	return &AppError{Code: code, Message: message, StatusCode: info.Status}
+}
+
+// Sentinels to match with errors.Is, e.g. errors.Is(err, ErrNotFound), and
+// to derive errors from with WithMessage and WithCause
+var (
+	ErrBadRequest         = NewCode(CodeBadRequest, "Bad request")
+	ErrValidationFailed   = NewCode(CodeValidationFailed, "Validation failed")
+	ErrUnauthorized       = NewCode(CodeUnauthorized, "Unauthorized")
+	ErrForbidden          = NewCode(CodeForbidden, "Forbidden")
+	ErrNotFound           = NewCode(CodeNotFound, "Not found")
+	ErrConflict           = NewCode(CodeConflict, "Conflict")
+	ErrTooManyRequests    = NewCode(CodeTooManyRequests, "Too many requests, please try again later")
+	ErrInternal           = NewCode(CodeInternal, "Internal server error")
+	ErrServiceUnavailable = NewCode(CodeServiceUnavailable, "Service temporarily unavailable")
+	ErrTimeout            = NewCode(CodeTimeout, "Request timed out")
+)
@@ -0,0 +1,145 @@
+package errors
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// The converters below turn errors of the clients services use into
+// AppErrors with the status they deserve, wrapping the original error.
+// They recognise driver errors by their methods, e.g. SQLState, so the
+// module doesn't depend on lib/pq, go-redis or kafka-go.
+
+// FromSQL converts a database/sql error; resource names what was queried,
+// e.g. "Order":
+//   - sql.ErrNoRows: 404 NOT_FOUND
+//   - unique and foreign key violations, serialization failures: 409 CONFLICT
+//   - not null, check and data violations: 400 BAD_REQUEST
+//   - connection failures and an overloaded or shut down server: 503
+//   - canceled queries and deadlines: 504
+//   - anything else: 500
+func FromSQL(err error, resource string) *AppError {
+	if err == nil {
+		return nil
+	}
+	if appErr, ok := asAppError(err); ok {
+		return appErr
+	}
+
+	if stderrors.Is(err, sql.ErrNoRows) {
+		return NewNotFound(resource).WithCause(err)
+	}
+	if stderrors.Is(err, sql.ErrConnDone) || stderrors.Is(err, driver.ErrBadConn) {
+		return ErrServiceUnavailable.WithCause(err)
+	}
+
+	var state interface{ SQLState() string }
+	if stderrors.As(err, &state) {
+		code := state.SQLState()
+		switch {
+		case code == "23505": // unique_violation
+			return NewConflict(fmt.Sprintf("%s already exists", resource)).WithCause(err)
+		case code == "23503": // foreign_key_violation
+			return NewConflict(fmt.Sprintf("%s references or is referenced by other data", resource)).WithCause(err)
+		case code == "40001", code == "40P01": // serialization_failure, deadlock_detected
+			return NewConflict("Concurrent update, please try again").WithCause(err)
+		case code == "23502", code == "23514", strings.HasPrefix(code, "22"): // not_null_violation, check_violation, data_exception
+			return NewBadRequest(fmt.Sprintf("Invalid %s", strings.ToLower(resource))).WithCause(err)
+		case code == "57014": // query_canceled, e.g. by statement_timeout
+			return ErrTimeout.WithCause(err)
+		case strings.HasPrefix(code, "08"), strings.HasPrefix(code, "53"), strings.HasPrefix(code, "57P"):
+			// connection_exception, insufficient_resources, admin_shutdown
+			return ErrServiceUnavailable.WithCause(err)
+		}
+	}
+
+	return fromCommon(err)
+}
+
+// Messages of go-redis errors
+const (
+	redisNil         = "redis: nil"
+	redisClosed      = "redis: client is closed"
+	redisPoolTimeout = "redis: connection pool timeout"
+)
+
+// FromRedis converts a go-redis error; resource names what was read, e.g.
+// "Cart":
+//   - redis.Nil: 404 NOT_FOUND
+//   - timeouts and deadlines: 504
+//   - connection failures, a closed client and an exhausted pool: 503
+//   - anything else, including errors replied by Redis: 500
+func FromRedis(err error, resource string) *AppError {
+	if err == nil {
+		return nil
+	}
+	if appErr, ok := asAppError(err); ok {
+		return appErr
+	}
+
+	for e := err; e != nil; e = stderrors.Unwrap(e) {
+		switch e.Error() {
+		case redisNil:
+			return NewNotFound(resource).WithCause(err)
+		case redisClosed, redisPoolTimeout:
+			return ErrServiceUnavailable.WithCause(err)
+		}
+	}
+
+	return fromCommon(err)
+}
+
+// FromKafka converts a kafka-go error:
+//   - timeouts and deadlines: 504
+//   - errors Kafka deems retriable, e.g. LeaderNotAvailable, and connection
+//     failures: 503
+//   - anything else: 500
+func FromKafka(err error) *AppError {
+	if err == nil {
+		return nil
+	}
+	if appErr, ok := asAppError(err); ok {
+		return appErr
+	}
+
+	var temporary interface{ Temporary() bool }
+	if stderrors.As(err, &temporary) && temporary.Temporary() && !isTimeout(err) {
+		return ErrServiceUnavailable.WithCause(err)
+	}
+
+	return fromCommon(err)
+}
+
+// fromCommon converts the errors every client returns: deadlines, timeouts
+// and network failures
+func fromCommon(err error) *AppError {
+	if isTimeout(err) {
+		return ErrTimeout.WithCause(err)
+	}
+
+	var netErr net.Error
+	if stderrors.As(err, &netErr) {
+		return ErrServiceUnavailable.WithCause(err)
+	}
+
+	return NewInternal(err)
+}
+
+func isTimeout(err error) bool {
+	if stderrors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var timeout interface{ Timeout() bool }
+	return stderrors.As(err, &timeout) && timeout.Timeout()
+}
+
+func asAppError(err error) (*AppError, bool) {
+	var appErr *AppError
+	ok := stderrors.As(err, &appErr)
+	return appErr, ok
+}
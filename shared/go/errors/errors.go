@@ -4,6 +4,8 @@ package errors
 import (
 	"fmt"
 	"net/http"
+	"runtime"
+	"strings"
 )
 
 // AppError represents an application error with HTTP status code
@@ -12,6 +14,11 @@ type AppError struct {
 	Message    string `json:"message"`
 	StatusCode int    `json:"-"`
 	Internal   error  `json:"-"`
+
+	// Fields are extra fields of the response body, e.g. validation details
+	Fields map[string]interface{} `json:"-"`
+
+	stack []uintptr
 }
 
 func (e *AppError) Error() string {
@@ -21,27 +28,115 @@ func (e *AppError) Error() string {
 	return e.Message
 }
 
+// Unwrap returns the internal error
+func (e *AppError) Unwrap() error {
+	return e.Internal
+}
+
+// Is reports whether target is an AppError with the same code, so
+// errors.Is(err, ErrNotFound) matches any not found error
+func (e *AppError) Is(target error) bool {
+	t, ok := target.(*AppError)
+	return ok && t.Code == e.Code
+}
+
+// WithFields returns a copy of the error with fields added to the response
+// body
+func (e *AppError) WithFields(fields map[string]interface{}) *AppError {
+	clone := *e
+	clone.Fields = make(map[string]interface{}, len(e.Fields)+len(fields))
+	for key, value := range e.Fields {
+		clone.Fields[key] = value
+	}
+	for key, value := range fields {
+		clone.Fields[key] = value
+	}
+	return &clone
+}
+
+// WithMessage returns a copy of the error with another message
+func (e *AppError) WithMessage(message string) *AppError {
+	clone := *e
+	clone.Message = message
+	return &clone
+}
+
+// WithCause returns a copy of the error wrapping err, so errors.Is and
+// errors.As see both. Server errors record where it was called.
+func (e *AppError) WithCause(err error) *AppError {
+	clone := *e
+	clone.Internal = err
+	if clone.StatusCode >= http.StatusInternalServerError {
+		clone.stack = callers()
+	}
+	return &clone
+}
+
+// StackTrace returns where an internal error was created, or "" for client
+// errors
+func (e *AppError) StackTrace() string {
+	if len(e.stack) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	frames := runtime.CallersFrames(e.stack)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// callers records the stack of the caller of the function calling it
+func callers() []uintptr {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	return pcs[:n]
+}
+
+// New creates an error with the given status; its code is the status text,
+// e.g. UNPROCESSABLE_ENTITY
+func New(statusCode int, message string) *AppError {
+	code := strings.ToUpper(strings.ReplaceAll(http.StatusText(statusCode), " ", "_"))
+	return &AppError{Code: code, Message: message, StatusCode: statusCode}
+}
+
 // Common error constructors
 func NewBadRequest(message string) *AppError {
-	return &AppError{Code: "BAD_REQUEST", Message: message, StatusCode: http.StatusBadRequest}
+	return &AppError{Code: CodeBadRequest, Message: message, StatusCode: http.StatusBadRequest}
 }
 
 func NewNotFound(resource string) *AppError {
-	return &AppError{Code: "NOT_FOUND", Message: fmt.Sprintf("%s not found", resource), StatusCode: http.StatusNotFound}
+	return &AppError{Code: CodeNotFound, Message: fmt.Sprintf("%s not found", resource), StatusCode: http.StatusNotFound}
 }
 
 func NewUnauthorized(message string) *AppError {
-	return &AppError{Code: "UNAUTHORIZED", Message: message, StatusCode: http.StatusUnauthorized}
+	return &AppError{Code: CodeUnauthorized, Message: message, StatusCode: http.StatusUnauthorized}
 }
 
 func NewForbidden(message string) *AppError {
-	return &AppError{Code: "FORBIDDEN", Message: message, StatusCode: http.StatusForbidden}
+	return &AppError{Code: CodeForbidden, Message: message, StatusCode: http.StatusForbidden}
 }
 
 func NewInternal(err error) *AppError {
-	return &AppError{Code: "INTERNAL_ERROR", Message: "Internal server error", StatusCode: http.StatusInternalServerError, Internal: err}
+	return &AppError{Code: CodeInternal, Message: "Internal server error", StatusCode: http.StatusInternalServerError, Internal: err, stack: callers()}
+}
+
+// Wrap creates an internal error with a message that is safe to show
+// clients, e.g. "Failed to create order"; err itself is only logged
+func Wrap(err error, message string) *AppError {
+	return &AppError{Code: CodeInternal, Message: message, StatusCode: http.StatusInternalServerError, Internal: err, stack: callers()}
+}
+
+// Wrapf is Wrap with a formatted message
+func Wrapf(err error, format string, args ...interface{}) *AppError {
+	return &AppError{Code: CodeInternal, Message: fmt.Sprintf(format, args...), StatusCode: http.StatusInternalServerError, Internal: err, stack: callers()}
 }
 
 func NewConflict(message string) *AppError {
-	return &AppError{Code: "CONFLICT", Message: message, StatusCode: http.StatusConflict}
+	return &AppError{Code: CodeConflict, Message: message, StatusCode: http.StatusConflict}
 }
@@ -0,0 +1,82 @@
+package errors
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Abort stops the request with err; Middleware writes the response. Errors
+// that aren't an AppError become internal errors, and the response doesn't
+// reveal them.
+func Abort(c *gin.Context, err error) {
+	var appErr *AppError
+	if !stderrors.As(err, &appErr) {
+		appErr = &AppError{
+			Code:       CodeInternal,
+			Message:    "Internal server error",
+			StatusCode: http.StatusInternalServerError,
+			Internal:   err,
+			stack:      callers(),
+		}
+	}
+
+	_ = c.Error(appErr)
+	c.Abort()
+}
+
+// BindJSON binds the request body into obj and, if that fails, aborts with
+// the error FromBinding converts it to and returns false
+func BindJSON(c *gin.Context, obj interface{}) bool {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		Abort(c, FromBinding(err, obj))
+		return false
+	}
+	return true
+}
+
+// From returns the AppError in err's chain, or an internal error wrapping err
+func From(err error) *AppError {
+	var appErr *AppError
+	if stderrors.As(err, &appErr) {
+		return appErr
+	}
+	return NewInternal(err)
+}
+
+// Middleware writes the response for the last error a handler aborted with,
+// unless the handler already wrote one. The body is {"error": message,
+// "code": code} plus the error's fields. Server errors are logged with the
+// internal error and its stack trace, which clients never see.
+func Middleware(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		appErr := From(c.Errors.Last().Err)
+		if appErr.StatusCode >= http.StatusInternalServerError {
+			logging.WithContext(c.Request.Context(), logger).Error(appErr.Message,
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.Request.URL.Path),
+				zap.Int("status", appErr.StatusCode),
+				zap.NamedError("error", appErr.Internal),
+				zap.String("stack", appErr.StackTrace()),
+			)
+		}
+
+		body := make(gin.H, len(appErr.Fields)+2)
+		for key, value := range appErr.Fields {
+			body[key] = value
+		}
+		body["error"] = appErr.Message
+		body["code"] = appErr.Code
+
+		c.JSON(appErr.StatusCode, body)
+	}
+}
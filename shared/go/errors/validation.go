@@ -0,0 +1,192 @@
+package errors
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError is a problem with one field of a request
+type FieldError struct {
+	// Field is the JSON path of the field, e.g. items[0].quantity
+	Field string `json:"field"`
+	// Rule is the rule the field broke, e.g. required or min
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors collects the problems with a request's fields
+type ValidationErrors []FieldError
+
+// Add records a problem with field
+func (v *ValidationErrors) Add(field, rule, message string) {
+	*v = append(*v, FieldError{Field: field, Rule: rule, Message: message})
+}
+
+// Err returns a VALIDATION_FAILED error listing the problems, or nil if
+// there are none
+func (v ValidationErrors) Err() *AppError {
+	if len(v) == 0 {
+		return nil
+	}
+	return NewValidation(v...)
+}
+
+// NewValidation creates a VALIDATION_FAILED error whose body lists fields:
+// {"error": "Validation failed", "code": "VALIDATION_FAILED", "fields": [...]}
+func NewValidation(fields ...FieldError) *AppError {
+	return NewCode(CodeValidationFailed, "Validation failed").WithFields(map[string]interface{}{"fields": fields})
+}
+
+// FromBinding converts an error binding a request into obj, e.g. from
+// c.ShouldBindJSON(obj). Failed binding tags become a VALIDATION_FAILED
+// error with a field error each, named by their JSON path; malformed JSON
+// becomes a BAD_REQUEST error.
+func FromBinding(err error, obj interface{}) *AppError {
+	var validationErrs validator.ValidationErrors
+	if stderrors.As(err, &validationErrs) {
+		fields := make(ValidationErrors, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			field := jsonPath(reflect.TypeOf(obj), fe.StructNamespace(), fe.Field())
+			fields.Add(field, fe.Tag(), fmt.Sprintf("%s %s", field, ruleMessage(fe)))
+		}
+		return fields.Err().WithCause(err)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if stderrors.As(err, &typeErr) {
+		var fields ValidationErrors
+		fields.Add(typeErr.Field, "type", fmt.Sprintf("%s must be %s", typeErr.Field, jsonKind(typeErr.Type)))
+		return fields.Err().WithCause(err)
+	}
+
+	if stderrors.Is(err, io.EOF) {
+		return NewBadRequest("Request body is empty").WithCause(err)
+	}
+	return NewBadRequest("Invalid request body").WithCause(err).WithFields(map[string]interface{}{"details": err.Error()})
+}
+
+// jsonPath maps a validator namespace, e.g. CreateOrderRequest.Items[0].Quantity,
+// to the JSON path of the field in t, e.g. items[0].quantity. It returns
+// fallback when the namespace doesn't match t.
+func jsonPath(t reflect.Type, namespace, fallback string) string {
+	segments := strings.Split(namespace, ".")
+	if t == nil || len(segments) < 2 {
+		return fallback
+	}
+
+	path := make([]string, 0, len(segments)-1)
+	for _, segment := range segments[1:] {
+		t = elem(t)
+		if t.Kind() != reflect.Struct {
+			return fallback
+		}
+
+		name, index := segment, ""
+		if i := strings.IndexByte(segment, '['); i >= 0 {
+			name, index = segment[:i], segment[i:]
+		}
+		field, ok := t.FieldByName(name)
+		if !ok {
+			return fallback
+		}
+		t = field.Type
+
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.Anonymous && tag == "" {
+			// Embedded struct fields are flattened into the parent
+			continue
+		}
+		if tag == "" || tag == "-" {
+			tag = field.Name
+		}
+		path = append(path, tag+index)
+	}
+	return strings.Join(path, ".")
+}
+
+// elem returns the struct type behind pointers, slices and maps
+func elem(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+}
+
+func ruleMessage(fe validator.FieldError) string {
+	unit := ""
+	switch fe.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+
+	switch fe.Tag() {
+	case "required", "required_if", "required_unless", "required_with", "required_without":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url", "uri":
+		return "must be a valid URL"
+	case "uuid", "uuid4":
+		return "must be a valid UUID"
+	case "sku":
+		return "must be 6 to 20 upper case letters, digits or hyphens"
+	case "e164":
+		return "must be an E.164 phone number, e.g. +14155552671"
+	case "currency", "iso4217":
+		return "must be an ISO 4217 currency code"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "min":
+		return fmt.Sprintf("must be at least %s%s", fe.Param(), unit)
+	case "max":
+		return fmt.Sprintf("must be at most %s%s", fe.Param(), unit)
+	case "len":
+		return fmt.Sprintf("must be exactly %s%s", fe.Param(), unit)
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be at least " + fe.Param()
+	case "lt":
+		return "must be less than " + fe.Param()
+	case "lte":
+		return "must be at most " + fe.Param()
+	default:
+		return fmt.Sprintf("failed the %s rule", fe.Tag())
+	}
+}
+
+// jsonKind names the JSON type a Go type is decoded from
+func jsonKind(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	default:
+		return "a valid " + t.String()
+	}
+}
@@ -0,0 +1,24 @@
+package events
+
+// ProductViewed is published for the storefront when a shopper opens a
+// product page. Anonymous shoppers are identified by their session.
+type ProductViewed struct {
+	ProductID string `json:"product_id"`
+	UserID    string `json:"user_id,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+func (*ProductViewed) EventType() string  { return "product.viewed" }
+func (*ProductViewed) SchemaVersion() int { return 1 }
+
+// SearchPerformed is published for the storefront when a shopper searches
+// the catalog. Anonymous shoppers are identified by their session.
+type SearchPerformed struct {
+	Query     string `json:"query"`
+	Results   int    `json:"results"`
+	UserID    string `json:"user_id,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+func (*SearchPerformed) EventType() string  { return "search.performed" }
+func (*SearchPerformed) SchemaVersion() int { return 1 }
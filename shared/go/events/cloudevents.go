@@ -0,0 +1,128 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// CloudEventsSpecVersion is the CloudEvents version events are encoded
+	// in
+	CloudEventsSpecVersion = "1.0"
+	// CloudEventsContentType is the content type of structured-mode
+	// CloudEvents, e.g. for a Kafka content-type header
+	CloudEventsContentType = "application/cloudevents+json"
+	// CloudEventsTypePrefix prefixes the event type in a CloudEvent's type,
+	// e.g. "com.ecommerce.inventory.reserved"
+	CloudEventsTypePrefix = "com.ecommerce."
+)
+
+// CloudEvent is an event as a structured-mode CloudEvents 1.0 JSON event.
+// The envelope's version and IDs are extension attributes, which must be
+// lowercase alphanumeric.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	DataSchema      string          `json:"dataschema,omitempty"`
+	SchemaVersion   int             `json:"schemaversion"`
+	TenantID        string          `json:"tenantid,omitempty"`
+	OrderID         string          `json:"orderid,omitempty"`
+	PaymentID       string          `json:"paymentid,omitempty"`
+	ProductID       string          `json:"productid,omitempty"`
+	UserID          string          `json:"userid,omitempty"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// CloudEvents encodes events as CloudEvents instead of envelopes
+type CloudEvents struct {
+	// Source identifies the producer, e.g. "/inventory-service"
+	Source string
+	// SchemaBaseURL, if set, is where the schemas directory is served from;
+	// events' dataschema is the URL of their version's schema under it
+	SchemaBaseURL string
+}
+
+// Marshal encodes payload as a CloudEvent, with env's timestamp, unless
+// zero, and IDs
+func (c CloudEvents) Marshal(env Envelope, payload Payload) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s data: %w", payload.EventType(), err)
+	}
+	id, err := newEventID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate %s event ID: %w", payload.EventType(), err)
+	}
+
+	event := CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              id,
+		Source:          c.Source,
+		Type:            CloudEventsTypePrefix + payload.EventType(),
+		Time:            env.Timestamp,
+		DataContentType: "application/json",
+		SchemaVersion:   payload.SchemaVersion(),
+		TenantID:        env.TenantID,
+		OrderID:         env.OrderID,
+		PaymentID:       env.PaymentID,
+		ProductID:       env.ProductID,
+		UserID:          env.UserID,
+		Data:            data,
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	if c.SchemaBaseURL != "" {
+		event.DataSchema = fmt.Sprintf("%s/v%d/%s.json",
+			strings.TrimSuffix(c.SchemaBaseURL, "/"), event.SchemaVersion, payload.EventType())
+	}
+	return json.Marshal(event)
+}
+
+// Envelope returns the event as an envelope, so consumers handle it like
+// one
+func (e *CloudEvent) Envelope() (*Envelope, error) {
+	if e.SpecVersion != CloudEventsSpecVersion {
+		return nil, fmt.Errorf("failed to decode event: unsupported CloudEvents specversion %q", e.SpecVersion)
+	}
+	if !strings.HasPrefix(e.Type, CloudEventsTypePrefix) || e.Type == CloudEventsTypePrefix {
+		return nil, fmt.Errorf("failed to decode event: CloudEvent type %q is not %s<event_type>", e.Type, CloudEventsTypePrefix)
+	}
+	if e.DataContentType != "" && !strings.Contains(e.DataContentType, "json") {
+		return nil, fmt.Errorf("failed to decode event: unsupported CloudEvent datacontenttype %q", e.DataContentType)
+	}
+
+	return &Envelope{
+		EventType:     strings.TrimPrefix(e.Type, CloudEventsTypePrefix),
+		SchemaVersion: e.SchemaVersion,
+		Timestamp:     e.Time,
+		TenantID:      e.TenantID,
+		OrderID:       e.OrderID,
+		PaymentID:     e.PaymentID,
+		ProductID:     e.ProductID,
+		UserID:        e.UserID,
+		Data:          e.Data,
+	}, nil
+}
+
+// newEventID returns a random UUID (v4), unique per event as CloudEvents
+// requires within a source
+func newEventID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	s := hex.EncodeToString(b[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:], nil
+}
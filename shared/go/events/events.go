@@ -0,0 +1,155 @@
+// Package events defines the typed contracts of the events services
+// exchange over Kafka, so producers and consumers agree on fields at
+// compile time instead of through map[string]interface{} payloads
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultVersion is assumed for events without a schema_version, which
+// predate versioning
+const DefaultVersion = 1
+
+var (
+	// ErrWrongType is returned when decoding an event into the payload of
+	// another event type
+	ErrWrongType = errors.New("events: wrong event type")
+	// ErrUnsupportedVersion is returned for events newer than the payload
+	// type this service was built with
+	ErrUnsupportedVersion = errors.New("events: unsupported schema version")
+)
+
+// Payload is the data of one event type
+type Payload interface {
+	// EventType is the event_type the payload is sent with, e.g.
+	// "order.created"
+	EventType() string
+	// SchemaVersion is the payload's current version. It is bumped, and a
+	// schemas/v<version> schema added, for changes that break consumers:
+	// removing, renaming or retyping a field. Adding optional fields isn't
+	// breaking.
+	SchemaVersion() int
+}
+
+// Envelope is the wire format of every event. The top-level IDs repeat
+// the data's for consumers routing events without decoding them. TenantID
+// is the storefront the event belongs to; events without one belong to the
+// default tenant.
+type Envelope struct {
+	EventType     string          `json:"event_type"`
+	SchemaVersion int             `json:"schema_version"`
+	Timestamp     time.Time       `json:"timestamp"`
+	TenantID      string          `json:"tenant_id,omitempty"`
+	OrderID       string          `json:"order_id,omitempty"`
+	PaymentID     string          `json:"payment_id,omitempty"`
+	ProductID     string          `json:"product_id,omitempty"`
+	UserID        string          `json:"user_id,omitempty"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// Marshal encodes payload in env, setting its type and version, and its
+// timestamp unless set
+func Marshal(env Envelope, payload Payload) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s data: %w", payload.EventType(), err)
+	}
+
+	env.EventType = payload.EventType()
+	env.SchemaVersion = payload.SchemaVersion()
+	if env.Timestamp.IsZero() {
+		env.Timestamp = time.Now().UTC()
+	}
+	env.Data = data
+	return json.Marshal(env)
+}
+
+// Decode decodes an event's envelope, leaving its data to DecodeData, e.g.
+// once a consumer has switched on the event type. Events encoded as
+// CloudEvents decode into the same envelope.
+func Decode(raw []byte) (*Envelope, error) {
+	var probe struct {
+		EventType   string `json:"event_type"`
+		SpecVersion string `json:"specversion"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	var env Envelope
+	if probe.EventType == "" && probe.SpecVersion != "" {
+		var event CloudEvent
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return nil, fmt.Errorf("failed to decode event: %w", err)
+		}
+		decoded, err := event.Envelope()
+		if err != nil {
+			return nil, err
+		}
+		env = *decoded
+	} else if err := json.Unmarshal(raw, &env); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+	if env.EventType == "" {
+		return nil, errors.New("failed to decode event: event_type is required")
+	}
+	if env.SchemaVersion == 0 {
+		env.SchemaVersion = DefaultVersion
+	}
+	return &env, nil
+}
+
+// Upgrader is implemented by payloads whose older versions don't decode
+// into the current one as they are, e.g. after a field was retyped
+type Upgrader interface {
+	// DecodeVersion decodes data of an older schema version into the
+	// payload
+	DecodeVersion(version int, data []byte) error
+}
+
+// DecodeData decodes the event's data into payload. Events of older
+// versions decode, their missing fields left zero, or through the
+// payload's Upgrader; newer versions fail with ErrUnsupportedVersion, as
+// their fields may have changed meaning.
+func (e *Envelope) DecodeData(payload Payload) error {
+	if e.EventType != payload.EventType() {
+		return fmt.Errorf("%w: %s is not %s", ErrWrongType, e.EventType, payload.EventType())
+	}
+	if e.SchemaVersion > payload.SchemaVersion() {
+		return fmt.Errorf("%w: %s v%d, supported up to v%d",
+			ErrUnsupportedVersion, e.EventType, e.SchemaVersion, payload.SchemaVersion())
+	}
+	if upgrader, ok := payload.(Upgrader); ok && e.SchemaVersion < payload.SchemaVersion() {
+		if err := upgrader.DecodeVersion(e.SchemaVersion, e.Data); err != nil {
+			return fmt.Errorf("failed to decode %s v%d data: %w", e.EventType, e.SchemaVersion, err)
+		}
+		return nil
+	}
+	if err := json.Unmarshal(e.Data, payload); err != nil {
+		return fmt.Errorf("failed to decode %s data: %w", e.EventType, err)
+	}
+	return nil
+}
+
+// Unmarshal decodes an event of a known type, e.g.
+//
+//	env, created, err := events.Unmarshal[events.OrderCreated](msg.Value)
+func Unmarshal[T any, P interface {
+	*T
+	Payload
+}](raw []byte) (*Envelope, *T, error) {
+	env, err := Decode(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	payload := P(new(T))
+	if err := env.DecodeData(payload); err != nil {
+		return nil, nil, err
+	}
+	return env, payload, nil
+}
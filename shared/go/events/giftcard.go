@@ -0,0 +1,26 @@
+package events
+
+import (
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/money"
+)
+
+// GiftCardIssued is published by giftcard-service when a card is issued to
+// a recipient, bought or sent by staff, for notification-service to email
+// them its code. The code spends the balance, so the event must only reach
+// trusted consumers.
+type GiftCardIssued struct {
+	Code           string      `json:"code"`
+	Balance        money.Money `json:"balance"`
+	ExpiresAt      *time.Time  `json:"expires_at,omitempty"`
+	RecipientEmail string      `json:"recipient_email"`
+	RecipientName  string      `json:"recipient_name,omitempty"`
+	SenderName     string      `json:"sender_name,omitempty"`
+	Message        string      `json:"message,omitempty"`
+	PurchaserID    string      `json:"purchaser_id,omitempty"`
+	OrderID        string      `json:"order_id,omitempty"`
+}
+
+func (*GiftCardIssued) EventType() string  { return "gift_card.issued" }
+func (*GiftCardIssued) SchemaVersion() int { return 1 }
@@ -0,0 +1,7 @@
+module github.com/ecommerce-platform/shared/go/events
+
+go 1.21
+
+require github.com/ecommerce-platform/shared/go/money v0.0.0
+
+replace github.com/ecommerce-platform/shared/go/money => ../money
@@ -0,0 +1,150 @@
+package events
+
+import "time"
+
+// InventoryCreated is published by inventory-service when a product's
+// stock starts being tracked
+type InventoryCreated struct {
+	ID                string `json:"id"`
+	ProductID         string `json:"product_id"`
+	SKU               string `json:"sku"`
+	Quantity          int    `json:"quantity"`
+	AvailableQuantity int    `json:"available_quantity"`
+	Status            string `json:"status"`
+}
+
+func (*InventoryCreated) EventType() string  { return "inventory.created" }
+func (*InventoryCreated) SchemaVersion() int { return 1 }
+
+// InventoryUpdated is published when an item's stock settings change
+type InventoryUpdated struct {
+	ID                string `json:"id"`
+	ProductID         string `json:"product_id"`
+	Quantity          int    `json:"quantity"`
+	ReservedQuantity  int    `json:"reserved_quantity"`
+	AvailableQuantity int    `json:"available_quantity"`
+	Status            string `json:"status"`
+}
+
+func (*InventoryUpdated) EventType() string  { return "inventory.updated" }
+func (*InventoryUpdated) SchemaVersion() int { return 1 }
+
+// InventoryReserved is published when stock is reserved for an order
+type InventoryReserved struct {
+	ProductID         string    `json:"product_id"`
+	ReservationID     string    `json:"reservation_id"`
+	OrderID           string    `json:"order_id"`
+	Quantity          int       `json:"quantity"`
+	ReservedQuantity  int       `json:"reserved_quantity"`
+	AvailableQuantity int       `json:"available_quantity"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+func (*InventoryReserved) EventType() string  { return "inventory.reserved" }
+func (*InventoryReserved) SchemaVersion() int { return 1 }
+
+// ReservationReleased is published when a reservation is released and its
+// stock is available again
+type ReservationReleased struct {
+	ProductID         string `json:"product_id"`
+	ReservationID     string `json:"reservation_id"`
+	OrderID           string `json:"order_id"`
+	Quantity          int    `json:"quantity"`
+	ReservedQuantity  int    `json:"reserved_quantity"`
+	AvailableQuantity int    `json:"available_quantity"`
+}
+
+func (*ReservationReleased) EventType() string  { return "inventory.reservation_released" }
+func (*ReservationReleased) SchemaVersion() int { return 1 }
+
+// ReservationExpired is published when a pending reservation expires, its
+// order neither confirming nor releasing it in time, and its stock is
+// available again
+type ReservationExpired struct {
+	ProductID         string    `json:"product_id"`
+	ReservationID     string    `json:"reservation_id"`
+	OrderID           string    `json:"order_id"`
+	Quantity          int       `json:"quantity"`
+	ReservedQuantity  int       `json:"reserved_quantity"`
+	AvailableQuantity int       `json:"available_quantity"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+func (*ReservationExpired) EventType() string  { return "inventory.reservation_expired" }
+func (*ReservationExpired) SchemaVersion() int { return 1 }
+
+// InventoryAdjusted is published when stock is adjusted by hand, e.g.
+// after a stock count
+type InventoryAdjusted struct {
+	ProductID         string `json:"product_id"`
+	AdjustmentID      string `json:"adjustment_id"`
+	QuantityChange    int    `json:"quantity_change"`
+	NewQuantity       int    `json:"new_quantity"`
+	AvailableQuantity int    `json:"available_quantity"`
+	Reason            string `json:"reason"`
+	AdjustedBy        string `json:"adjusted_by"`
+}
+
+func (*InventoryAdjusted) EventType() string  { return "inventory.adjusted" }
+func (*InventoryAdjusted) SchemaVersion() int { return 1 }
+
+// InventoryLowStock is published when an item's available stock falls to
+// its reorder level, so purchasing can restock it
+type InventoryLowStock struct {
+	ProductID         string `json:"product_id"`
+	SKU               string `json:"sku"`
+	AvailableQuantity int    `json:"available_quantity"`
+	ReorderLevel      int    `json:"reorder_level"`
+	ReorderQuantity   int    `json:"reorder_quantity"`
+	Warehouse         string `json:"warehouse"`
+}
+
+func (*InventoryLowStock) EventType() string  { return "inventory.low_stock" }
+func (*InventoryLowStock) SchemaVersion() int { return 1 }
+
+// InventoryOutOfStock is published when an item's available stock runs
+// out
+type InventoryOutOfStock struct {
+	ProductID         string `json:"product_id"`
+	SKU               string `json:"sku"`
+	AvailableQuantity int    `json:"available_quantity"`
+	ReorderLevel      int    `json:"reorder_level"`
+	ReorderQuantity   int    `json:"reorder_quantity"`
+	Warehouse         string `json:"warehouse"`
+}
+
+func (*InventoryOutOfStock) EventType() string  { return "inventory.out_of_stock" }
+func (*InventoryOutOfStock) SchemaVersion() int { return 1 }
+
+// InventoryExpiringSoon is published when a lot of perishable stock is
+// about to expire, e.g. to discount it or move it first
+type InventoryExpiringSoon struct {
+	ProductID string    `json:"product_id"`
+	SKU       string    `json:"sku"`
+	LotNumber string    `json:"lot_number"`
+	Quantity  int       `json:"quantity"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Warehouse string    `json:"warehouse"`
+}
+
+func (*InventoryExpiringSoon) EventType() string  { return "inventory.expiring_soon" }
+func (*InventoryExpiringSoon) SchemaVersion() int { return 1 }
+
+// ReturnRestocked is published when an order's returned units are checked
+// back in: sellable ones are put back into stock, damaged ones only
+// recorded
+type ReturnRestocked struct {
+	ProductID    string `json:"product_id"`
+	AdjustmentID string `json:"adjustment_id"`
+	OrderID      string `json:"order_id"`
+	// Quantity is the units returned, and RestockedQuantity those put back
+	// into stock
+	Quantity          int    `json:"quantity"`
+	Condition         string `json:"condition"`
+	RestockedQuantity int    `json:"restocked_quantity"`
+	NewQuantity       int    `json:"new_quantity"`
+	AvailableQuantity int    `json:"available_quantity"`
+}
+
+func (*ReturnRestocked) EventType() string  { return "inventory.return_restocked" }
+func (*ReturnRestocked) SchemaVersion() int { return 1 }
@@ -0,0 +1,58 @@
+package events
+
+import (
+	"encoding/json"
+
+	"github.com/ecommerce-platform/shared/go/money"
+)
+
+// OrderCreated is published by order-service when an order is placed
+type OrderCreated struct {
+	OrderNumber   string      `json:"order_number"`
+	UserID        string      `json:"user_id"`
+	TotalAmount   money.Money `json:"total_amount"`
+	ItemCount     int         `json:"item_count"`
+	Status        string      `json:"status"`
+	CustomerEmail string      `json:"customer_email"`
+	CustomerName  string      `json:"customer_name,omitempty"`
+	CustomerPhone string      `json:"customer_phone,omitempty"`
+	Items         []OrderItem `json:"items"`
+}
+
+// OrderItem is a line of an order
+type OrderItem struct {
+	ProductID string      `json:"product_id"`
+	SKU       string      `json:"sku"`
+	Name      string      `json:"name"`
+	Quantity  int         `json:"quantity"`
+	Price     money.Money `json:"price"`
+}
+
+func (*OrderCreated) EventType() string { return "order.created" }
+
+// SchemaVersion 2 made amounts Money instead of float64 dollars
+func (*OrderCreated) SchemaVersion() int { return 2 }
+
+// DecodeVersion decodes v1 events, whose amounts are float64 dollars
+func (o *OrderCreated) DecodeVersion(version int, data []byte) error {
+	var v1 struct {
+		OrderCreated
+		TotalAmount float64 `json:"total_amount"`
+		Items       []struct {
+			OrderItem
+			Price float64 `json:"price"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &v1); err != nil {
+		return err
+	}
+
+	*o = v1.OrderCreated
+	o.TotalAmount = money.FromMajor(v1.TotalAmount, legacyCurrency)
+	o.Items = make([]OrderItem, len(v1.Items))
+	for i, item := range v1.Items {
+		o.Items[i] = item.OrderItem
+		o.Items[i].Price = money.FromMajor(item.Price, legacyCurrency)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package events
+
+import (
+	"encoding/json"
+
+	"github.com/ecommerce-platform/shared/go/money"
+)
+
+// legacyCurrency is the currency of v1 amounts that don't name one
+const legacyCurrency = "USD"
+
+// PaymentSuccessful is published by payment-service when a payment is
+// captured
+type PaymentSuccessful struct {
+	OrderID         string      `json:"order_id"`
+	Amount          money.Money `json:"amount"`
+	PaymentMethod   string      `json:"payment_method"`
+	TransactionID   string      `json:"transaction_id"`
+	PaymentIntentID string      `json:"payment_intent_id,omitempty"`
+	// Customer details, when the publisher knows them
+	CustomerEmail string `json:"customer_email,omitempty"`
+	CustomerName  string `json:"customer_name,omitempty"`
+	OrderNumber   string `json:"order_number,omitempty"`
+	UserID        string `json:"user_id,omitempty"`
+}
+
+func (*PaymentSuccessful) EventType() string { return "payment.successful" }
+
+// SchemaVersion 2 made the amount Money, replacing float64 amount and
+// currency fields
+func (*PaymentSuccessful) SchemaVersion() int { return 2 }
+
+// DecodeVersion decodes v1 events, whose amount is a float64 in currency
+func (p *PaymentSuccessful) DecodeVersion(version int, data []byte) error {
+	var v1 struct {
+		PaymentSuccessful
+		Amount   float64 `json:"amount"`
+		Currency string  `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &v1); err != nil {
+		return err
+	}
+
+	currency := v1.Currency
+	if currency == "" {
+		currency = legacyCurrency
+	}
+	*p = v1.PaymentSuccessful
+	p.Amount = money.FromMajor(v1.Amount, currency)
+	return nil
+}
@@ -0,0 +1,22 @@
+package events
+
+import (
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/money"
+)
+
+// PriceChanged is published by pricing-service when a product's base
+// price changes, set at once or when a scheduled change takes effect
+type PriceChanged struct {
+	ProductID string `json:"product_id"`
+	// OldPrice is nil for a product's first price
+	OldPrice    *money.Money `json:"old_price,omitempty"`
+	NewPrice    money.Money  `json:"new_price"`
+	EffectiveAt time.Time    `json:"effective_at"`
+	// Scheduled is true for changes scheduled ahead of time
+	Scheduled bool `json:"scheduled"`
+}
+
+func (*PriceChanged) EventType() string  { return "price.changed" }
+func (*PriceChanged) SchemaVersion() int { return 1 }
@@ -0,0 +1,83 @@
+package events
+
+import "github.com/ecommerce-platform/shared/go/money"
+
+// ReturnUpdate is the data every return event carries: the return as it
+// stands after the change
+type ReturnUpdate struct {
+	ReturnID      string       `json:"return_id"`
+	OrderID       string       `json:"order_id"`
+	OrderNumber   string       `json:"order_number,omitempty"`
+	UserID        string       `json:"user_id"`
+	CustomerEmail string       `json:"customer_email"`
+	CustomerName  string       `json:"customer_name,omitempty"`
+	Status        string       `json:"status"`
+	Items         []ReturnItem `json:"items"`
+	RefundAmount  money.Money  `json:"refund_amount"`
+}
+
+// ReturnItem is a product being returned
+type ReturnItem struct {
+	ProductID string      `json:"product_id"`
+	SKU       string      `json:"sku,omitempty"`
+	Name      string      `json:"name,omitempty"`
+	Quantity  int         `json:"quantity"`
+	UnitPrice money.Money `json:"unit_price"`
+}
+
+// ReturnLabel is the shipping label a customer sends a return back with
+type ReturnLabel struct {
+	Carrier        string `json:"carrier,omitempty"`
+	TrackingNumber string `json:"tracking_number,omitempty"`
+	LabelURL       string `json:"label_url"`
+}
+
+// ReturnRequested is published by returns-service when a customer asks to
+// return items of a delivered order
+type ReturnRequested struct {
+	ReturnUpdate
+	Reason string `json:"reason"`
+}
+
+func (*ReturnRequested) EventType() string  { return "return.requested" }
+func (*ReturnRequested) SchemaVersion() int { return 1 }
+
+// ReturnApproved is published by returns-service when staff accept a
+// return, with the label to send it back with if one was generated
+type ReturnApproved struct {
+	ReturnUpdate
+	Label *ReturnLabel `json:"label,omitempty"`
+}
+
+func (*ReturnApproved) EventType() string  { return "return.approved" }
+func (*ReturnApproved) SchemaVersion() int { return 1 }
+
+// ReturnRejected is published by returns-service when staff refuse a
+// return
+type ReturnRejected struct {
+	ReturnUpdate
+	Reason string `json:"reason,omitempty"`
+}
+
+func (*ReturnRejected) EventType() string  { return "return.rejected" }
+func (*ReturnRejected) SchemaVersion() int { return 1 }
+
+// ReturnReceived is published by returns-service when the returned items
+// arrive back at the warehouse
+type ReturnReceived struct {
+	ReturnUpdate
+	Restocked bool `json:"restocked"`
+}
+
+func (*ReturnReceived) EventType() string  { return "return.received" }
+func (*ReturnReceived) SchemaVersion() int { return 1 }
+
+// ReturnRefunded is published by returns-service once payment-service has
+// refunded a received return
+type ReturnRefunded struct {
+	ReturnUpdate
+	RefundID string `json:"refund_id"`
+}
+
+func (*ReturnRefunded) EventType() string  { return "return.refunded" }
+func (*ReturnRefunded) SchemaVersion() int { return 1 }
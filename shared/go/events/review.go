@@ -0,0 +1,36 @@
+package events
+
+// ReviewRequested is published by review-service a while after an order
+// is delivered, asking the customer to review the items they haven't yet
+type ReviewRequested struct {
+	OrderID       string           `json:"order_id"`
+	OrderNumber   string           `json:"order_number,omitempty"`
+	UserID        string           `json:"user_id"`
+	CustomerEmail string           `json:"customer_email"`
+	CustomerName  string           `json:"customer_name,omitempty"`
+	Items         []ReviewableItem `json:"items"`
+}
+
+// ReviewableItem is a delivered product the customer may review
+type ReviewableItem struct {
+	ProductID string `json:"product_id"`
+	Name      string `json:"name,omitempty"`
+}
+
+func (*ReviewRequested) EventType() string  { return "review.requested" }
+func (*ReviewRequested) SchemaVersion() int { return 1 }
+
+// ReviewCreated is published by review-service when a customer reviews a
+// product they bought. The review awaits moderation.
+type ReviewCreated struct {
+	ReviewID  string `json:"review_id"`
+	ProductID string `json:"product_id"`
+	OrderID   string `json:"order_id"`
+	UserID    string `json:"user_id"`
+	Rating    int    `json:"rating"`
+	Title     string `json:"title,omitempty"`
+	Status    string `json:"status"`
+}
+
+func (*ReviewCreated) EventType() string  { return "review.created" }
+func (*ReviewCreated) SchemaVersion() int { return 1 }
@@ -0,0 +1,10 @@
+package events
+
+import "embed"
+
+// Schemas holds the JSON Schema (draft 7) of every event type, at
+// schemas/v<version>/<event_type>.json, for validating events and for
+// producers in other languages
+//
+//go:embed schemas/v*/*.json
+var Schemas embed.FS
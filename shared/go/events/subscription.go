@@ -0,0 +1,112 @@
+package events
+
+import (
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/money"
+)
+
+// SubscriptionUpdate is the data every subscription event carries: the
+// subscription as it stands after the change
+type SubscriptionUpdate struct {
+	SubscriptionID string               `json:"subscription_id"`
+	UserID         string               `json:"user_id"`
+	CustomerEmail  string               `json:"customer_email"`
+	CustomerName   string               `json:"customer_name,omitempty"`
+	Status         string               `json:"status"`
+	Interval       SubscriptionInterval `json:"interval"`
+	// NextOrderAt is when the next order is placed; absent once cancelled
+	NextOrderAt *time.Time         `json:"next_order_at,omitempty"`
+	Items       []SubscriptionItem `json:"items"`
+	Total       money.Money        `json:"total"`
+}
+
+// SubscriptionInterval is how often a subscription orders, e.g. every 2
+// weeks
+type SubscriptionInterval struct {
+	Unit  string `json:"unit"`
+	Count int    `json:"count"`
+}
+
+// SubscriptionItem is a product delivered every cycle
+type SubscriptionItem struct {
+	ProductID string      `json:"product_id"`
+	SKU       string      `json:"sku,omitempty"`
+	Name      string      `json:"name,omitempty"`
+	Quantity  int         `json:"quantity"`
+	Price     money.Money `json:"price"`
+}
+
+// SubscriptionCreated is published by subscription-service when a customer
+// subscribes
+type SubscriptionCreated struct {
+	SubscriptionUpdate
+}
+
+func (*SubscriptionCreated) EventType() string  { return "subscription.created" }
+func (*SubscriptionCreated) SchemaVersion() int { return 1 }
+
+// SubscriptionRenewalUpcoming is published by subscription-service ahead of
+// a subscription's next order, when its stock is reserved, so the customer
+// can still skip or pause it
+type SubscriptionRenewalUpcoming struct {
+	SubscriptionUpdate
+}
+
+func (*SubscriptionRenewalUpcoming) EventType() string  { return "subscription.renewal_upcoming" }
+func (*SubscriptionRenewalUpcoming) SchemaVersion() int { return 1 }
+
+// SubscriptionPaused is published by subscription-service when a customer
+// pauses their subscription, until they resume it or ResumeAt
+type SubscriptionPaused struct {
+	SubscriptionUpdate
+	ResumeAt *time.Time `json:"resume_at,omitempty"`
+}
+
+func (*SubscriptionPaused) EventType() string  { return "subscription.paused" }
+func (*SubscriptionPaused) SchemaVersion() int { return 1 }
+
+// SubscriptionResumed is published by subscription-service when a paused
+// subscription resumes, by the customer or at its resume date
+type SubscriptionResumed struct {
+	SubscriptionUpdate
+}
+
+func (*SubscriptionResumed) EventType() string  { return "subscription.resumed" }
+func (*SubscriptionResumed) SchemaVersion() int { return 1 }
+
+// SubscriptionSkipped is published by subscription-service when a customer
+// skips their next order
+type SubscriptionSkipped struct {
+	SubscriptionUpdate
+	// SkippedOrderAt is when the skipped order would have been placed
+	SkippedOrderAt time.Time `json:"skipped_order_at"`
+}
+
+func (*SubscriptionSkipped) EventType() string  { return "subscription.skipped" }
+func (*SubscriptionSkipped) SchemaVersion() int { return 1 }
+
+// SubscriptionCancelled is published by subscription-service when a
+// customer cancels their subscription, or its order ran out of payment
+// attempts
+type SubscriptionCancelled struct {
+	SubscriptionUpdate
+	Reason string `json:"reason,omitempty"`
+}
+
+func (*SubscriptionCancelled) EventType() string  { return "subscription.cancelled" }
+func (*SubscriptionCancelled) SchemaVersion() int { return 1 }
+
+// SubscriptionPaymentFailed is published by subscription-service when a
+// subscription's order fails to pay. NextAttemptAt is when a new order is
+// tried; absent after the last attempt, which cancels the subscription.
+type SubscriptionPaymentFailed struct {
+	SubscriptionUpdate
+	OrderID       string     `json:"order_id"`
+	Attempt       int        `json:"attempt"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	Error         string     `json:"error,omitempty"`
+}
+
+func (*SubscriptionPaymentFailed) EventType() string  { return "subscription.payment_failed" }
+func (*SubscriptionPaymentFailed) SchemaVersion() int { return 1 }
@@ -0,0 +1,12 @@
+package events
+
+// UserRegistered is published when a customer creates an account
+type UserRegistered struct {
+	UserID          string `json:"user_id"`
+	Email           string `json:"email"`
+	FirstName       string `json:"first_name,omitempty"`
+	VerificationURL string `json:"verification_url,omitempty"`
+}
+
+func (*UserRegistered) EventType() string  { return "user.registered" }
+func (*UserRegistered) SchemaVersion() int { return 1 }
@@ -0,0 +1,41 @@
+package events
+
+import "github.com/ecommerce-platform/shared/go/money"
+
+// BackInStock is published by wishlist-service when a product that was
+// out of stock is available again, with the customers wishing for it
+type BackInStock struct {
+	ProductID         string       `json:"product_id"`
+	ProductName       string       `json:"product_name"`
+	ProductURL        string       `json:"product_url,omitempty"`
+	ImageURL          string       `json:"image_url,omitempty"`
+	Price             *money.Money `json:"price,omitempty"`
+	AvailableQuantity int          `json:"available_quantity"`
+	Subscribers       []Subscriber `json:"subscribers"`
+}
+
+func (*BackInStock) EventType() string  { return "inventory.back_in_stock" }
+func (*BackInStock) SchemaVersion() int { return 1 }
+
+// WishlistPriceDropped is published by wishlist-service when the price of
+// a product on customers' wishlists goes down
+type WishlistPriceDropped struct {
+	ProductID   string       `json:"product_id"`
+	ProductName string       `json:"product_name"`
+	ProductURL  string       `json:"product_url,omitempty"`
+	ImageURL    string       `json:"image_url,omitempty"`
+	OldPrice    money.Money  `json:"old_price"`
+	NewPrice    money.Money  `json:"new_price"`
+	Subscribers []Subscriber `json:"subscribers"`
+}
+
+func (*WishlistPriceDropped) EventType() string  { return "wishlist.price_dropped" }
+func (*WishlistPriceDropped) SchemaVersion() int { return 1 }
+
+// Subscriber is a customer to notify about a product
+type Subscriber struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+	Name   string `json:"name,omitempty"`
+	Phone  string `json:"phone,omitempty"`
+}
@@ -0,0 +1,100 @@
+// Package httpmetrics records RED metrics (rate, errors, duration) for Gin
+// services under the same names and labels, so dashboards and alerts work
+// for every service
+package httpmetrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// unmatchedRoute labels requests no route matched, so scanners probing
+// random paths can't create a series per path
+const unmatchedRoute = "unmatched"
+
+var (
+	// RequestsTotal counts requests; errors are those with a 5xx status
+	RequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by route, method and status",
+	}, []string{"service", "route", "method", "status"})
+
+	// RequestDuration measures time to respond, from when the middleware
+	// runs until the handler chain returns
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time to respond to HTTP requests",
+		Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"service", "route", "method", "status"})
+
+	// RequestsInFlight is the number of requests being handled
+	RequestsInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "HTTP requests being handled",
+	}, []string{"service", "route"})
+)
+
+// Middleware records the metrics of every request to the router. Register
+// it after the tracing middleware, so durations can link to traces, and
+// before apperrors.Middleware, so errors it renders are counted with their
+// status. Routes are labelled by pattern, e.g. /api/v1/inventory/:id, not
+// by path.
+func Middleware(service string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		inFlight := RequestsInFlight.WithLabelValues(service, route)
+		inFlight.Inc()
+		start := time.Now()
+
+		defer func() {
+			inFlight.Dec()
+			status := c.Writer.Status()
+			panicked := recover()
+			if panicked != nil {
+				// The recovery middleware answers with a 500 once the panic
+				// reaches it
+				status = http.StatusInternalServerError
+			}
+
+			labels := []string{service, route, c.Request.Method, strconv.Itoa(status)}
+			RequestsTotal.WithLabelValues(labels...).Inc()
+			observe(RequestDuration.WithLabelValues(labels...), time.Since(start).Seconds(), c)
+
+			if panicked != nil {
+				panic(panicked)
+			}
+		}()
+
+		c.Next()
+	}
+}
+
+// observe records a duration, linked to the request's trace when it is
+// sampled so a slow bucket in Grafana leads to an example trace
+func observe(observer prometheus.Observer, seconds float64, c *gin.Context) {
+	span := trace.SpanContextFromContext(c.Request.Context())
+	if exemplars, ok := observer.(prometheus.ExemplarObserver); ok && span.IsSampled() {
+		exemplars.ObserveWithExemplar(seconds, prometheus.Labels{"trace_id": span.TraceID().String()})
+		return
+	}
+	observer.Observe(seconds)
+}
+
+// Handler serves the metrics of the default registry, including those of
+// the shared Kafka library, for Prometheus to scrape. OpenMetrics is
+// enabled so duration exemplars are exposed.
+func Handler() http.Handler {
+	return promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+}
@@ -0,0 +1,162 @@
+package interceptors
+
+import (
+	"context"
+	"strings"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authorizationKey is the metadata key tokens are sent in, as "Bearer <token>"
+const authorizationKey = "authorization"
+
+// TokenSource returns the token a client calls with, e.g. the caller's
+// token forwarded from an HTTP request, or a service token
+type TokenSource func(ctx context.Context) (string, error)
+
+// StaticToken always returns token
+func StaticToken(token string) TokenSource {
+	return func(context.Context) (string, error) {
+		return token, nil
+	}
+}
+
+// UnaryServerAuth verifies the caller's user-service JWT and stores its
+// claims in the context, for sharedauth.FromContext and RequireRole. Calls
+// without a valid token fail with Unauthenticated, except to public
+// methods, e.g. "/grpc.health.v1.Health/Check", and calls whose x-tenant-id
+// isn't the token's with PermissionDenied.
+func UnaryServerAuth(verifier *sharedauth.Verifier, logger *zap.Logger, public ...string) grpc.UnaryServerInterceptor {
+	isPublic := methodSet(public)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if isPublic[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, verifier, logger)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerAuth is UnaryServerAuth for streams
+func StreamServerAuth(verifier *sharedauth.Verifier, logger *zap.Logger, public ...string) grpc.StreamServerInterceptor {
+	isPublic := methodSet(public)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if isPublic[info.FullMethod] {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticate(ss.Context(), verifier, logger)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authenticate(ctx context.Context, verifier *sharedauth.Verifier, logger *zap.Logger) (context.Context, error) {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(authorizationKey); len(values) > 0 {
+			token, _ = strings.CutPrefix(values[0], "Bearer ")
+		}
+	}
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "Authorization required")
+	}
+
+	claims, err := verifier.Verify(token)
+	if err != nil {
+		logger.Warn("Invalid token", zap.Error(err))
+		return nil, status.Error(codes.Unauthenticated, "Invalid or expired token")
+	}
+	// A token only works on its own tenant
+	if tenant, ok := incomingTenant(ctx); ok && tenant != claims.Tenant() {
+		logger.Warn("Token used on another tenant",
+			zap.String("user_id", claims.UserID),
+			zap.String("token_tenant", claims.Tenant()),
+			zap.String("request_tenant", tenant),
+		)
+		return nil, status.Error(codes.PermissionDenied, "Token belongs to another tenant")
+	}
+	return sharedauth.NewContext(ctx, claims), nil
+}
+
+func methodSet(methods []string) map[string]bool {
+	set := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		set[method] = true
+	}
+	return set
+}
+
+// RequireRole returns a PermissionDenied error unless the authenticated
+// caller has one of roles, for handlers to check:
+//
+//	if err := interceptors.RequireRole(ctx, sharedauth.RoleAdmin); err != nil {
+//	    return nil, err
+//	}
+func RequireRole(ctx context.Context, roles ...string) error {
+	claims, ok := sharedauth.FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "Authorization required")
+	}
+	if !claims.HasRole(roles...) {
+		return status.Error(codes.PermissionDenied, "Insufficient permissions")
+	}
+	return nil
+}
+
+// RequirePermission returns a PermissionDenied error unless the
+// authenticated caller has all permissions
+func RequirePermission(ctx context.Context, permissions ...string) error {
+	claims, ok := sharedauth.FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "Authorization required")
+	}
+	for _, permission := range permissions {
+		if !claims.HasPermission(permission) {
+			return status.Error(codes.PermissionDenied, "Insufficient permissions")
+		}
+	}
+	return nil
+}
+
+// UnaryClientToken sends the token source's token with calls
+func UnaryClientToken(source TokenSource) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, err := withToken(ctx, source)
+		if err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientToken is UnaryClientToken for streams
+func StreamClientToken(source TokenSource) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, err := withToken(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+func withToken(ctx context.Context, source TokenSource) (context.Context, error) {
+	token, err := source(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "getting token: %v", err)
+	}
+	if token == "" {
+		return ctx, nil
+	}
+	return metadata.AppendToOutgoingContext(ctx, authorizationKey, "Bearer "+token), nil
+}
@@ -0,0 +1,68 @@
+package interceptors
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// correlationIDKey is logging.CorrelationIDHeader as gRPC metadata keys are
+// lower case
+var correlationIDKey = strings.ToLower(logging.CorrelationIDHeader)
+
+// UnaryServerCorrelationID stores the caller's correlation ID, or a new
+// one, in the context so logging.WithContext logs it, and returns it in
+// the response header
+func UnaryServerCorrelationID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withCorrelationID(ctx), req)
+	}
+}
+
+// StreamServerCorrelationID is UnaryServerCorrelationID for streams
+func StreamServerCorrelationID() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: withCorrelationID(ss.Context())})
+	}
+}
+
+func withCorrelationID(ctx context.Context) context.Context {
+	var correlationID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(correlationIDKey); len(values) > 0 {
+			correlationID = values[0]
+		}
+	}
+	if correlationID == "" {
+		correlationID = uuid.New().String()
+	}
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs(correlationIDKey, correlationID))
+	return logging.WithCorrelationID(ctx, correlationID)
+}
+
+// UnaryClientCorrelationID sends the correlation ID in the context with
+// calls, so the callee logs the same one
+func UnaryClientCorrelationID() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoingCorrelationID(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientCorrelationID is UnaryClientCorrelationID for streams
+func StreamClientCorrelationID() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoingCorrelationID(ctx), desc, cc, method, opts...)
+	}
+}
+
+func outgoingCorrelationID(ctx context.Context) context.Context {
+	if id := logging.CorrelationID(ctx); id != "" {
+		return metadata.AppendToOutgoingContext(ctx, correlationIDKey, id)
+	}
+	return ctx
+}
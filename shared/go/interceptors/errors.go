@@ -0,0 +1,104 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/ecommerce-platform/shared/go/logging"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// errorCodeKey is the trailer carrying an AppError's code, e.g. NOT_FOUND
+const errorCodeKey = "x-error-code"
+
+// UnaryServerErrors converts the errors handlers return into gRPC statuses,
+// so handlers can return AppErrors as HTTP handlers do:
+//
+//   - statuses are returned as they are
+//   - AppErrors get the code matching their HTTP status and their message,
+//     and their code in the x-error-code trailer
+//   - context errors become Canceled or DeadlineExceeded
+//   - anything else becomes Internal, without revealing the error, as
+//     apperrors.From does
+//
+// Server errors are logged with their cause and stack trace, which callers
+// never see, like apperrors.Middleware does.
+func UnaryServerErrors(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, toStatus(ctx, logger, info.FullMethod, err)
+		}
+		return resp, nil
+	}
+}
+
+// StreamServerErrors is UnaryServerErrors for streams
+func StreamServerErrors(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return toStatus(ss.Context(), logger, info.FullMethod, err)
+		}
+		return nil
+	}
+}
+
+func toStatus(ctx context.Context, logger *zap.Logger, method string, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "Request canceled")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "Request timed out")
+	}
+
+	appErr := apperrors.From(err)
+	if appErr.StatusCode >= http.StatusInternalServerError {
+		logging.WithContext(ctx, logger).Error(appErr.Message,
+			zap.String("grpc_method", method),
+			zap.NamedError("error", appErr.Internal),
+			zap.String("stack", appErr.StackTrace()),
+		)
+	}
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(errorCodeKey, appErr.Code))
+	return status.Error(CodeFromHTTP(appErr.StatusCode), appErr.Message)
+}
+
+// CodeFromHTTP returns the gRPC code matching an HTTP status
+func CodeFromHTTP(statusCode int) codes.Code {
+	switch statusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	if statusCode >= http.StatusInternalServerError {
+		return codes.Internal
+	}
+	return codes.Unknown
+}
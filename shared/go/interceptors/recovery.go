@@ -0,0 +1,46 @@
+package interceptors
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/ecommerce-platform/shared/go/logging"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerRecovery turns a panicking handler into an Internal error,
+// logged with the panic and its stack, instead of crashing the server
+func UnaryServerRecovery(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(ctx, logger, info.FullMethod, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerRecovery is UnaryServerRecovery for streams
+func StreamServerRecovery(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(ss.Context(), logger, info.FullMethod, r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func recovered(ctx context.Context, logger *zap.Logger, method string, r interface{}) error {
+	logging.WithContext(ctx, logger).Error("Panic in gRPC handler",
+		zap.String("grpc_method", method),
+		zap.Any("panic", r),
+		zap.String("stack", string(debug.Stack())),
+	)
+	return status.Error(codes.Internal, "Internal server error")
+}
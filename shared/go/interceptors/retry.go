@@ -0,0 +1,85 @@
+package interceptors
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy configures UnaryClientRetry; zero fields use the defaults
+type RetryPolicy struct {
+	// MaxAttempts includes the first call; default 3, 1 disables retries
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, doubling up to
+	// MaxBackoff, with jitter; defaults 100ms and 2s
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// PerAttemptTimeout bounds each attempt, within the call's deadline;
+	// 0 leaves attempts bounded by the call's deadline only
+	PerAttemptTimeout time.Duration
+	// Codes are retried; default Unavailable, which a call fails with when
+	// it didn't reach a server. Only add others, e.g. DeadlineExceeded or
+	// ResourceExhausted, for idempotent methods.
+	Codes []codes.Code
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = 3
+	}
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = 100 * time.Millisecond
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = 2 * time.Second
+	}
+	if len(p.Codes) == 0 {
+		p.Codes = []codes.Code{codes.Unavailable}
+	}
+	return p
+}
+
+// UnaryClientRetry retries calls failing with one of the policy's codes,
+// with exponential backoff, until the policy's attempts or the call's
+// context run out
+func UnaryClientRetry(policy RetryPolicy) grpc.UnaryClientInterceptor {
+	policy = policy.withDefaults()
+	retryable := make(map[codes.Code]bool, len(policy.Codes))
+	for _, code := range policy.Codes {
+		retryable[code] = true
+	}
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		backoff := policy.InitialBackoff
+		for attempt := 1; ; attempt++ {
+			err := invokeAttempt(ctx, policy.PerAttemptTimeout, method, req, reply, cc, invoker, opts)
+			if err == nil || attempt >= policy.MaxAttempts || !retryable[status.Code(err)] {
+				return err
+			}
+
+			// Full jitter, so clients failing together don't retry together
+			wait := time.Duration(rand.Int63n(int64(backoff) + 1))
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(wait):
+			}
+			if backoff *= 2; backoff > policy.MaxBackoff {
+				backoff = policy.MaxBackoff
+			}
+		}
+	}
+}
+
+func invokeAttempt(ctx context.Context, timeout time.Duration, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts []grpc.CallOption) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
@@ -0,0 +1,84 @@
+package interceptors
+
+import (
+	"context"
+	"strings"
+
+	sharedauth "github.com/ecommerce-platform/shared/go/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// tenantKey is sharedauth.TenantHeader as gRPC metadata keys are lower case
+var tenantKey = strings.ToLower(sharedauth.TenantHeader)
+
+// UnaryServerTenant scopes calls to the tenant in their x-tenant-id
+// metadata, for sharedauth.TenantFromContext, as sharedauth.ResolveTenant
+// does for HTTP requests. Calls without one are scoped to their token's
+// tenant, or else the default tenant; malformed IDs fail with
+// InvalidArgument.
+func UnaryServerTenant() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := withTenant(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerTenant is UnaryServerTenant for streams
+func StreamServerTenant() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := withTenant(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func withTenant(ctx context.Context) (context.Context, error) {
+	tenant, ok := incomingTenant(ctx)
+	if !ok {
+		return ctx, nil
+	}
+	if !sharedauth.ValidTenantID(tenant) {
+		return nil, status.Error(codes.InvalidArgument, "Invalid tenant ID")
+	}
+	return sharedauth.WithTenant(ctx, tenant), nil
+}
+
+// incomingTenant returns the caller's x-tenant-id metadata, if any
+func incomingTenant(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	values := md.Get(tenantKey)
+	if len(values) == 0 || values[0] == "" {
+		return "", false
+	}
+	return values[0], true
+}
+
+// UnaryClientTenant sends the tenant of the context with calls, so the
+// callee acts on the same tenant
+func UnaryClientTenant() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoingTenant(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientTenant is UnaryClientTenant for streams
+func StreamClientTenant() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoingTenant(ctx), desc, cc, method, opts...)
+	}
+}
+
+func outgoingTenant(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, tenantKey, sharedauth.TenantFromContext(ctx))
+}
@@ -0,0 +1,422 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/ecommerce-platform/shared/go/logging"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Handler processes a message. Returning an error retries the message and,
+// once its attempts are used up, dead-letters it.
+type Handler func(ctx context.Context, msg kafkago.Message) error
+
+// ConsumerConfig configures a Consumer. Zero values get defaults.
+type ConsumerConfig struct {
+	Brokers []string
+	GroupID string
+	Topics  []string
+	// StartOffset is where a new consumer group starts: kafka.FirstOffset
+	// (default) or kafka.LastOffset
+	StartOffset int64
+
+	Concurrency   int // workers; default 1
+	QueueSize     int // fetched messages waiting for workers; default 100
+	RatePerSecond int // messages started per second; 0 = unlimited
+
+	// Attempts per message before it is dead-lettered, with RetryBackoff
+	// doubling between them; default 1 (no retries) and 1s
+	MaxAttempts  int
+	RetryBackoff time.Duration
+
+	// Route returns a message's ordering key, and false for messages this
+	// consumer skips. Messages with the same key are processed one at a
+	// time, in the order they were read. By default every message is
+	// processed and keyed by its Kafka key, or else its partition.
+	Route func(msg kafkago.Message) (key string, process bool)
+
+	// Retried, if set, is called with the error each time a message fails
+	// an attempt that will be retried
+	Retried func(ctx context.Context, msg kafkago.Message, err error)
+
+	// DeadLetters receives messages that failed every attempt. Without it
+	// they are logged and skipped.
+	DeadLetters DeadLetterSink
+
+	// Tracer, if set, runs the handler in a consumer span continuing the
+	// producer's trace; see StartSpan
+	Tracer trace.Tracer
+}
+
+// Consumer reads topics with a consumer group and hands messages to a pool
+// of workers. Offsets are committed per partition only up to the oldest
+// message still in flight, so a crash redelivers messages but never skips
+// them.
+type Consumer struct {
+	cfg     ConsumerConfig
+	handler Handler
+	logger  *zap.Logger
+}
+
+// job is a fetched message queued for a worker
+type job struct {
+	msg      kafkago.Message
+	key      string
+	entry    *inflight
+	commits  *commitTracker
+	queuedAt time.Time
+}
+
+// NewConsumer creates a consumer running handler for each message
+func NewConsumer(cfg ConsumerConfig, handler Handler, logger *zap.Logger) *Consumer {
+	if cfg.StartOffset == 0 {
+		cfg.StartOffset = kafkago.FirstOffset
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 100
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = time.Second
+	}
+	if cfg.Route == nil {
+		cfg.Route = defaultRoute
+	}
+
+	return &Consumer{
+		cfg:     cfg,
+		handler: handler,
+		logger:  logger.With(zap.String("group_id", cfg.GroupID)),
+	}
+}
+
+func defaultRoute(msg kafkago.Message) (string, bool) {
+	if len(msg.Key) > 0 {
+		return "key:" + string(msg.Key), true
+	}
+	return fmt.Sprintf("partition:%s/%d", msg.Topic, msg.Partition), true
+}
+
+// Run consumes until ctx is cancelled. Workers finish their current message
+// before the readers flush their last commits and close; messages
+// interrupted by shutdown are left uncommitted so they are redelivered.
+func (c *Consumer) Run(ctx context.Context) error {
+	jobs := make(chan job, c.cfg.QueueSize)
+
+	var workers sync.WaitGroup
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		c.runWorkers(ctx, jobs)
+	}()
+
+	readers := make([]*kafkago.Reader, 0, len(c.cfg.Topics))
+	var fetchers sync.WaitGroup
+	for _, topic := range c.cfg.Topics {
+		reader := kafkago.NewReader(kafkago.ReaderConfig{
+			Brokers:        c.cfg.Brokers,
+			GroupID:        c.cfg.GroupID,
+			Topic:          topic,
+			StartOffset:    c.cfg.StartOffset,
+			CommitInterval: time.Second,
+			MinBytes:       10e3,
+			MaxBytes:       10e6,
+		})
+		readers = append(readers, reader)
+
+		fetchers.Add(1)
+		go func() {
+			defer fetchers.Done()
+			c.fetch(ctx, reader, jobs)
+		}()
+	}
+
+	<-ctx.Done()
+
+	fetchers.Wait()
+	workers.Wait()
+
+	var errs []error
+	for _, reader := range readers {
+		if err := reader.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fetch feeds the workers' queue from one reader. Skipped messages are
+// committed without processing.
+func (c *Consumer) fetch(ctx context.Context, reader *kafkago.Reader, jobs chan<- job) {
+	commits := newCommitTracker(reader, c.logger)
+
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.logger.Error("Failed to fetch message", zap.Error(err))
+			continue
+		}
+
+		ConsumerLag.WithLabelValues(c.cfg.GroupID, msg.Topic).Set(float64(msg.HighWaterMark - msg.Offset - 1))
+
+		entry := commits.track(msg)
+		key, process := c.cfg.Route(msg)
+		if !process {
+			ConsumedTotal.WithLabelValues(c.cfg.GroupID, msg.Topic, "skipped").Inc()
+			commits.done(entry)
+			continue
+		}
+
+		select {
+		case jobs <- job{msg: msg, key: key, entry: entry, commits: commits, queuedAt: time.Now()}:
+			QueueDepth.WithLabelValues(c.cfg.GroupID).Set(float64(len(jobs)))
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runWorkers processes the queue until ctx is cancelled, throttled to the
+// rate limit. Each worker has its own queue and jobs are routed to them by
+// ordering key, so one key is never processed by two workers at once while
+// different keys run in parallel.
+func (c *Consumer) runWorkers(ctx context.Context, jobs <-chan job) {
+	var throttle <-chan time.Time
+	if c.cfg.RatePerSecond > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(c.cfg.RatePerSecond))
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	workerQueueSize := c.cfg.QueueSize / c.cfg.Concurrency
+	if workerQueueSize < 1 {
+		workerQueueSize = 1
+	}
+
+	var wg sync.WaitGroup
+	queues := make([]chan job, c.cfg.Concurrency)
+	for i := range queues {
+		queues[i] = make(chan job, workerQueueSize)
+		wg.Add(1)
+		go func(queue <-chan job) {
+			defer wg.Done()
+			c.work(ctx, queue, throttle)
+		}(queues[i])
+	}
+
+	// A busy key only holds up the keys that share its worker once that
+	// worker's queue is full
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			done = true
+		case j := <-jobs:
+			QueueDepth.WithLabelValues(c.cfg.GroupID).Set(float64(len(jobs)))
+
+			select {
+			case queues[shard(j.key, len(queues))] <- j:
+			case <-ctx.Done():
+				done = true
+			}
+		}
+	}
+	wg.Wait()
+}
+
+// shard picks the worker an ordering key is pinned to
+func shard(key string, workers int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(workers))
+}
+
+func (c *Consumer) work(ctx context.Context, jobs <-chan job, throttle <-chan time.Time) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-jobs:
+			if throttle != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-throttle:
+				}
+			}
+			WaitDuration.WithLabelValues(c.cfg.GroupID).Observe(time.Since(j.queuedAt).Seconds())
+
+			c.process(ctx, j.msg)
+
+			// Leave messages interrupted by shutdown uncommitted so they are
+			// redelivered
+			if ctx.Err() != nil {
+				return
+			}
+			j.commits.done(j.entry)
+		}
+	}
+}
+
+// process runs the handler until it succeeds or its attempts are used up,
+// then dead-letters the message
+func (c *Consumer) process(ctx context.Context, msg kafkago.Message) {
+	start := time.Now()
+	defer func() {
+		ProcessingDuration.WithLabelValues(c.cfg.GroupID, msg.Topic).Observe(time.Since(start).Seconds())
+	}()
+
+	backoff := c.cfg.RetryBackoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = c.handle(WithAttempt(ctx, attempt), msg); err == nil {
+			ConsumedTotal.WithLabelValues(c.cfg.GroupID, msg.Topic, "processed").Inc()
+			return
+		}
+		if ctx.Err() != nil || attempt == c.cfg.MaxAttempts {
+			break
+		}
+
+		ConsumedTotal.WithLabelValues(c.cfg.GroupID, msg.Topic, "retried").Inc()
+		if c.cfg.Retried != nil {
+			c.cfg.Retried(ctx, msg, err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	ConsumedTotal.WithLabelValues(c.cfg.GroupID, msg.Topic, "dead_lettered").Inc()
+	logger := logging.WithContext(Extract(ctx, msg), c.logger)
+	if c.cfg.DeadLetters == nil {
+		logger.Error("Skipping message that failed processing",
+			zap.String("topic", msg.Topic),
+			zap.Int("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset),
+			zap.Error(err),
+		)
+		return
+	}
+	if dlqErr := c.cfg.DeadLetters.DeadLetter(ctx, msg, err); dlqErr != nil {
+		logger.Error("Failed to dead-letter message",
+			zap.String("topic", msg.Topic),
+			zap.Int("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset),
+			zap.NamedError("cause", err),
+			zap.Error(dlqErr),
+		)
+	}
+}
+
+type attemptKey struct{}
+
+// WithAttempt returns ctx carrying which attempt at a message it is for
+func WithAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// Attempt returns which attempt at its message a handler's ctx is for,
+// starting at 1, or 0 outside a consumer. Handlers use it to skip work a
+// failed earlier attempt already did.
+func Attempt(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptKey{}).(int)
+	return attempt
+}
+
+// handle runs the handler once, in a consumer span if there is a tracer
+func (c *Consumer) handle(ctx context.Context, msg kafkago.Message) error {
+	if c.cfg.Tracer == nil {
+		return c.handler(ctx, msg)
+	}
+
+	ctx, span := StartSpan(ctx, c.cfg.Tracer, msg)
+	defer span.End()
+
+	err := c.handler(ctx, msg)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// commitTracker commits a reader's offsets in order. Workers finish
+// messages out of order, so a partition is only committed up to the oldest
+// message still in flight.
+type commitTracker struct {
+	reader *kafkago.Reader
+	logger *zap.Logger
+
+	mu       sync.Mutex
+	inflight map[int][]*inflight
+}
+
+// inflight is a fetched message that hasn't been committed
+type inflight struct {
+	msg  kafkago.Message
+	done bool
+}
+
+func newCommitTracker(reader *kafkago.Reader, logger *zap.Logger) *commitTracker {
+	return &commitTracker{
+		reader:   reader,
+		logger:   logger,
+		inflight: make(map[int][]*inflight),
+	}
+}
+
+// track registers a fetched message, in fetch order
+func (t *commitTracker) track(msg kafkago.Message) *inflight {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry := &inflight{msg: msg}
+	t.inflight[msg.Partition] = append(t.inflight[msg.Partition], entry)
+	return entry
+}
+
+// done marks a message finished and commits every finished message at the
+// head of its partition
+func (t *commitTracker) done(entry *inflight) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry.done = true
+
+	partition := entry.msg.Partition
+	queue := t.inflight[partition]
+	n := 0
+	for n < len(queue) && queue[n].done {
+		n++
+	}
+	if n == 0 {
+		return
+	}
+	last := queue[n-1].msg
+	t.inflight[partition] = queue[n:]
+
+	// Commits are batched by the reader (CommitInterval), so this doesn't block
+	if err := t.reader.CommitMessages(context.Background(), last); err != nil {
+		t.logger.Error("Failed to commit message", zap.Error(err))
+	}
+}
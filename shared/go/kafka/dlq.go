@@ -0,0 +1,66 @@
+package kafka
+
+import (
+	"context"
+	"strconv"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// DeadLetterSuffix is appended to a topic's name to name its dead letter
+// topic
+const DeadLetterSuffix = ".dlq"
+
+// Headers added to dead-lettered messages
+const (
+	HeaderDLQError     = "dlq-error"
+	HeaderDLQTopic     = "dlq-original-topic"
+	HeaderDLQPartition = "dlq-original-partition"
+	HeaderDLQOffset    = "dlq-original-offset"
+)
+
+// DeadLetterSink receives messages that failed processing
+type DeadLetterSink interface {
+	DeadLetter(ctx context.Context, msg kafkago.Message, err error) error
+}
+
+// DeadLetterFunc adapts a function to a DeadLetterSink
+type DeadLetterFunc func(ctx context.Context, msg kafkago.Message, err error) error
+
+// DeadLetter calls f
+func (f DeadLetterFunc) DeadLetter(ctx context.Context, msg kafkago.Message, err error) error {
+	return f(ctx, msg, err)
+}
+
+// TopicDeadLetters publishes failed messages to their topic's dead letter
+// topic (orders.dlq for orders), with the error and original position in
+// the headers. The producer must not have a fixed Topic.
+type TopicDeadLetters struct {
+	producer *Producer
+}
+
+// NewTopicDeadLetters creates a sink publishing with producer
+func NewTopicDeadLetters(producer *Producer) *TopicDeadLetters {
+	return &TopicDeadLetters{producer: producer}
+}
+
+// DeadLetter publishes msg to its dead letter topic
+func (d *TopicDeadLetters) DeadLetter(ctx context.Context, msg kafkago.Message, err error) error {
+	headers := make([]kafkago.Header, len(msg.Headers), len(msg.Headers)+4)
+	copy(headers, msg.Headers)
+
+	letter := kafkago.Message{
+		Topic:   msg.Topic + DeadLetterSuffix,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	}
+	carrier := HeaderCarrier{Headers: &letter.Headers}
+	carrier.Set(HeaderDLQError, err.Error())
+	carrier.Set(HeaderDLQTopic, msg.Topic)
+	carrier.Set(HeaderDLQPartition, strconv.Itoa(msg.Partition))
+	carrier.Set(HeaderDLQOffset, strconv.FormatInt(msg.Offset, 10))
+
+	// The original headers carry the producer's trace and correlation ID
+	return d.producer.write(ctx, []kafkago.Message{letter})
+}
@@ -0,0 +1,96 @@
+// Package kafka provides the Kafka producer and consumer shared by Go
+// services: trace and correlation ID propagation in message headers, writer
+// retries and batching, an optional outbox, and a consumer worker pool that
+// commits only processed messages and dead-letters failures.
+package kafka
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ecommerce-platform/shared/go/logging"
+	"github.com/google/uuid"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HeaderCarrier adapts Kafka message headers to a propagation.TextMapCarrier
+type HeaderCarrier struct {
+	Headers *[]kafkago.Header
+}
+
+// Get returns the value of a header, matching keys case-insensitively
+func (c HeaderCarrier) Get(key string) string {
+	for _, h := range *c.Headers {
+		if strings.EqualFold(h.Key, key) {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set replaces or adds a header
+func (c HeaderCarrier) Set(key, value string) {
+	for i, h := range *c.Headers {
+		if strings.EqualFold(h.Key, key) {
+			(*c.Headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.Headers = append(*c.Headers, kafkago.Header{Key: key, Value: []byte(value)})
+}
+
+// Keys lists the header keys
+func (c HeaderCarrier) Keys() []string {
+	keys := make([]string, len(*c.Headers))
+	for i, h := range *c.Headers {
+		keys[i] = h.Key
+	}
+	return keys
+}
+
+// Header returns the value of one of a message's headers
+func Header(msg kafkago.Message, key string) string {
+	return HeaderCarrier{Headers: &msg.Headers}.Get(key)
+}
+
+// Inject writes the trace and correlation ID in ctx to a message's headers.
+// A correlation ID header the message already has is kept.
+func Inject(ctx context.Context, msg *kafkago.Message) {
+	carrier := HeaderCarrier{Headers: &msg.Headers}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if id := logging.CorrelationID(ctx); id != "" && carrier.Get(logging.CorrelationIDHeader) == "" {
+		carrier.Set(logging.CorrelationIDHeader, id)
+	}
+}
+
+// Extract continues the producer's trace and correlation ID from a
+// message's headers. Messages without a correlation ID get a new one.
+func Extract(ctx context.Context, msg kafkago.Message) context.Context {
+	carrier := HeaderCarrier{Headers: &msg.Headers}
+	ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+
+	correlationID := carrier.Get(logging.CorrelationIDHeader)
+	if correlationID == "" {
+		correlationID = uuid.New().String()
+	}
+	return logging.WithCorrelationID(ctx, correlationID)
+}
+
+// StartSpan extracts a message's trace and correlation ID and starts a
+// consumer span for processing it
+func StartSpan(ctx context.Context, tracer trace.Tracer, msg kafkago.Message) (context.Context, trace.Span) {
+	ctx = Extract(ctx, msg)
+	return tracer.Start(ctx, msg.Topic+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", msg.Topic),
+			attribute.Int("messaging.kafka.destination.partition", msg.Partition),
+			attribute.Int64("messaging.kafka.message.offset", msg.Offset),
+			attribute.String("correlation_id", logging.CorrelationID(ctx)),
+		),
+	)
+}
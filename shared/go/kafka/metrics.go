@@ -0,0 +1,50 @@
+package kafka
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// ProducedTotal counts messages written to Kafka by result: published
+	// or failed. Messages stored in an outbox are counted when relayed.
+	ProducedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_producer_messages_total",
+		Help: "Messages written to Kafka by result",
+	}, []string{"topic", "result"})
+
+	// ConsumedTotal counts consumed messages by result: processed, retried,
+	// dead_lettered or skipped
+	ConsumedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_consumer_messages_total",
+		Help: "Consumed messages by result",
+	}, []string{"group", "topic", "result"})
+
+	// ProcessingDuration measures handler time per message, retries included
+	ProcessingDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_consumer_processing_seconds",
+		Help:    "Time to process a message, including retries",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"group", "topic"})
+
+	// ConsumerLag is how far behind the partition head a consumer group was
+	// at its last fetch
+	ConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_lag",
+		Help: "Messages behind the partition head at the last fetch",
+	}, []string{"group", "topic"})
+
+	// QueueDepth is the number of fetched messages waiting for a worker
+	QueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_queue_depth",
+		Help: "Fetched messages waiting for a worker",
+	}, []string{"group"})
+
+	// WaitDuration measures the time from fetch until a worker starts on a
+	// message, including rate limiting
+	WaitDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_consumer_wait_seconds",
+		Help:    "Time from fetch until a worker starts processing",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"group"})
+)
@@ -0,0 +1,219 @@
+package kafka
+
+import (
+	"context"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// ProducerConfig configures a Producer. Zero values get defaults.
+type ProducerConfig struct {
+	Brokers []string
+	// Topic every message is written to. Leave it empty to set each
+	// message's Topic instead.
+	Topic string
+	// Balancer picks partitions; default hashing the key, so messages with
+	// the same key stay in order
+	Balancer     kafkago.Balancer
+	RequiredAcks kafkago.RequiredAcks // default RequireOne
+	// Async makes Publish queue messages and return at once. Delivery
+	// errors are then only reported to Completion.
+	Async      bool
+	Completion func(messages []kafkago.Message, err error)
+
+	BatchSize    int           // messages per batch; default 100
+	BatchTimeout time.Duration // longest a partial batch waits; default 10ms
+
+	// Attempts per batch before giving up, with exponential backoff
+	// between them; default 10, 100ms to 1s
+	MaxAttempts     int
+	RetryBackoffMin time.Duration
+	RetryBackoffMax time.Duration
+
+	// Outbox, if set, makes Publish store messages instead of writing them;
+	// RelayOutbox writes them. Async is ignored with an outbox, since relayed
+	// messages are only removed once Kafka has acknowledged them.
+	Outbox Outbox
+}
+
+// Outbox stores messages to be published later, typically in the database
+// transaction that produced them, so an event is published if and only if
+// its change was committed
+type Outbox interface {
+	// Add stores messages for publishing
+	Add(ctx context.Context, messages []kafkago.Message) error
+	// Pending returns up to limit stored messages, oldest first
+	Pending(ctx context.Context, limit int) ([]OutboxMessage, error)
+	// MarkPublished removes published messages from the outbox
+	MarkPublished(ctx context.Context, ids []string) error
+}
+
+// OutboxMessage is a message stored in an outbox
+type OutboxMessage struct {
+	ID      string
+	Message kafkago.Message
+}
+
+// Producer writes messages to Kafka with the producer's trace and
+// correlation ID in their headers
+type Producer struct {
+	writer *kafkago.Writer
+	cfg    ProducerConfig
+	logger *zap.Logger
+}
+
+// NewProducer creates a producer. The writer connects on first use.
+func NewProducer(cfg ProducerConfig, logger *zap.Logger) *Producer {
+	if cfg.Balancer == nil {
+		cfg.Balancer = &kafkago.Hash{}
+	}
+	if cfg.RequiredAcks == 0 {
+		cfg.RequiredAcks = kafkago.RequireOne
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.BatchTimeout <= 0 {
+		cfg.BatchTimeout = 10 * time.Millisecond
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 10
+	}
+	if cfg.RetryBackoffMin <= 0 {
+		cfg.RetryBackoffMin = 100 * time.Millisecond
+	}
+	if cfg.RetryBackoffMax <= 0 {
+		cfg.RetryBackoffMax = time.Second
+	}
+	if cfg.Outbox != nil {
+		cfg.Async = false
+	}
+
+	p := &Producer{cfg: cfg, logger: logger}
+	p.writer = &kafkago.Writer{
+		Addr:            kafkago.TCP(cfg.Brokers...),
+		Topic:           cfg.Topic,
+		Balancer:        cfg.Balancer,
+		RequiredAcks:    cfg.RequiredAcks,
+		Async:           cfg.Async,
+		BatchSize:       cfg.BatchSize,
+		BatchTimeout:    cfg.BatchTimeout,
+		MaxAttempts:     cfg.MaxAttempts,
+		WriteBackoffMin: cfg.RetryBackoffMin,
+		WriteBackoffMax: cfg.RetryBackoffMax,
+	}
+	if cfg.Async {
+		p.writer.Completion = p.completed
+	}
+	return p
+}
+
+// Publish adds the trace and correlation ID in ctx to the messages' headers
+// and writes them, or stores them in the outbox if there is one. Async
+// writes outlive ctx.
+func (p *Producer) Publish(ctx context.Context, messages ...kafkago.Message) error {
+	for i := range messages {
+		Inject(ctx, &messages[i])
+	}
+
+	if p.cfg.Outbox != nil {
+		return p.cfg.Outbox.Add(ctx, messages)
+	}
+	return p.write(ctx, messages)
+}
+
+func (p *Producer) write(ctx context.Context, messages []kafkago.Message) error {
+	if p.cfg.Async {
+		// Async writes only fail here on a closed writer; delivery errors are
+		// reported to completed
+		ctx = context.WithoutCancel(ctx)
+	}
+
+	err := p.writer.WriteMessages(ctx, messages...)
+	if err != nil || !p.cfg.Async {
+		p.record(messages, err)
+	}
+	return err
+}
+
+// completed records the outcome of an asynchronous batch write
+func (p *Producer) completed(messages []kafkago.Message, err error) {
+	p.record(messages, err)
+	if p.cfg.Completion != nil {
+		p.cfg.Completion(messages, err)
+	}
+}
+
+func (p *Producer) record(messages []kafkago.Message, err error) {
+	result := "published"
+	if err != nil {
+		result = "failed"
+	}
+	for _, msg := range messages {
+		topic := msg.Topic
+		if topic == "" {
+			topic = p.cfg.Topic
+		}
+		ProducedTotal.WithLabelValues(topic, result).Inc()
+	}
+}
+
+// RelayOutbox writes the outbox's messages to Kafka every interval until
+// ctx is cancelled. Messages are removed once written, so a crash between
+// the two publishes them again: consumers must tolerate duplicates.
+func (p *Producer) RelayOutbox(ctx context.Context, interval time.Duration) {
+	if p.cfg.Outbox == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Drain the backlog before waiting for the next tick
+			for ctx.Err() == nil {
+				n, err := p.relayBatch(ctx)
+				if err != nil {
+					p.logger.Error("Failed to relay outbox", zap.Error(err))
+					break
+				}
+				if n < p.cfg.BatchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// relayBatch writes one batch of outbox messages and returns its size
+func (p *Producer) relayBatch(ctx context.Context) (int, error) {
+	pending, err := p.cfg.Outbox.Pending(ctx, p.cfg.BatchSize)
+	if err != nil || len(pending) == 0 {
+		return 0, err
+	}
+
+	messages := make([]kafkago.Message, len(pending))
+	ids := make([]string, len(pending))
+	for i, m := range pending {
+		messages[i] = m.Message
+		ids[i] = m.ID
+	}
+
+	err = p.writer.WriteMessages(ctx, messages...)
+	p.record(messages, err)
+	if err != nil {
+		return 0, err
+	}
+	return len(pending), p.cfg.Outbox.MarkPublished(ctx, ids)
+}
+
+// Close flushes pending writes and closes the writer
+func (p *Producer) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,13 @@
+module github.com/ecommerce-platform/shared/go/logging
+
+go 1.21
+
+require (
+	go.opentelemetry.io/otel/trace v1.21.0
+	go.uber.org/zap v1.26.0
+)
+
+require (
+	go.opentelemetry.io/otel v1.21.0 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+)
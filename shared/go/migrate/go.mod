@@ -0,0 +1,16 @@
+module github.com/ecommerce-platform/shared/go/migrate
+
+go 1.21
+
+require (
+	github.com/golang-migrate/migrate/v4 v4.15.2
+	github.com/lib/pq v1.10.9
+	go.uber.org/zap v1.26.0
+)
+
+require (
+	github.com/hashicorp/errwrap v1.1.0 // indirect
+	github.com/hashicorp/go-multierror v1.1.1 // indirect
+	go.uber.org/atomic v1.7.0 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+)
@@ -0,0 +1,220 @@
+// Package migrate applies and rolls back a service's embedded migrations
+// with golang-migrate
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	gomigrate "github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// legacyTable is where shareddb.Migrate records applied migrations, by
+// file name
+const legacyTable = "schema_migrations"
+
+// adoptLockID is the Postgres advisory lock held while adopting the legacy
+// table, so replicas starting together adopt it once
+const adoptLockID = 7_262_071_401
+
+// Migrator applies and rolls back the migrations at the root of a
+// service's embedded migrations directory. Each is a numbered pair of
+// files, e.g. 001_create_users.up.sql and 001_create_users.down.sql, which
+// undoes it.
+type Migrator struct {
+	migrate *gomigrate.Migrate
+	conn    *sql.Conn
+	fsys    fs.FS
+	logger  *zap.Logger
+}
+
+// New creates a migrator for the migrations in fsys, recording the version
+// in table, which should be the service's own. On a database migrated by
+// shareddb.Migrate, it first records the last of the migrations applied
+// then as the current version. Close it when done.
+func New(ctx context.Context, db *sql.DB, fsys fs.FS, table string, logger *zap.Logger) (*Migrator, error) {
+	source, err := iofs.New(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("migrate: failed to read migrations: %w", err)
+	}
+
+	// golang-migrate's advisory lock belongs to a session, so everything
+	// runs on one connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: failed to get connection: %w", err)
+	}
+	driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{MigrationsTable: table})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("migrate: failed to prepare migrations: %w", err)
+	}
+	m, err := gomigrate.NewWithInstance("iofs", source, "postgres", driver)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("migrate: failed to prepare migrations: %w", err)
+	}
+
+	migrator := &Migrator{migrate: m, conn: conn, fsys: fsys, logger: logger}
+	if err := migrator.adoptLegacy(ctx); err != nil {
+		migrator.Close()
+		return nil, err
+	}
+	return migrator, nil
+}
+
+// Up applies the pending migrations in order. Each file runs as a single
+// statement batch; if one fails, the version is left dirty until fixed
+// with Force.
+func (m *Migrator) Up() error {
+	from, _, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if err := m.migrate.Up(); err != nil && !errors.Is(err, gomigrate.ErrNoChange) {
+		return fmt.Errorf("migrate: migration failed: %w", err)
+	}
+	to, _, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	m.logger.Info("Database migrated", zap.Uint("from_version", from), zap.Uint("version", to))
+	return nil
+}
+
+// Down rolls back the last steps migrations
+func (m *Migrator) Down(steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("migrate: steps to roll back must be positive")
+	}
+	from, _, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if err := m.migrate.Steps(-steps); err != nil {
+		return fmt.Errorf("migrate: rollback failed: %w", err)
+	}
+	to, _, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	m.logger.Info("Database rolled back", zap.Uint("from_version", from), zap.Uint("version", to))
+	return nil
+}
+
+// Version returns the version of the last migration applied, 0 if none
+// was, and whether it failed part way
+func (m *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := m.migrate.Version()
+	if errors.Is(err, gomigrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("migrate: failed to read migration version: %w", err)
+	}
+	return version, dirty, nil
+}
+
+// Force sets the version without running any migration, clearing the
+// dirty flag, once a failed migration has been fixed by hand. -1 means no
+// migration is applied.
+func (m *Migrator) Force(version int) error {
+	if err := m.migrate.Force(version); err != nil {
+		return fmt.Errorf("migrate: failed to force migration version: %w", err)
+	}
+	m.logger.Info("Migration version forced", zap.Int("version", version))
+	return nil
+}
+
+// Close releases the migrator's connection
+func (m *Migrator) Close() error {
+	_, err := m.migrate.Close()
+	return err
+}
+
+// adoptLegacy sets the version to the last of the migrations listed in the
+// legacy table, so they aren't applied again. The table is left alone, as
+// other services may record theirs in the same database.
+func (m *Migrator) adoptLegacy(ctx context.Context) error {
+	if _, err := m.conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", adoptLockID); err != nil {
+		return fmt.Errorf("migrate: failed to lock migrations: %w", err)
+	}
+	defer m.conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", adoptLockID)
+
+	if current, _, err := m.Version(); err != nil || current > 0 {
+		return err
+	}
+
+	var exists bool
+	if err := m.conn.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", legacyTable).Scan(&exists); err != nil {
+		return fmt.Errorf("migrate: failed to look for legacy migrations: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+
+	latest, err := m.legacyVersion(ctx)
+	if err != nil || latest == 0 {
+		return err
+	}
+	if err := m.migrate.Force(int(latest)); err != nil {
+		return fmt.Errorf("migrate: failed to adopt legacy migrations: %w", err)
+	}
+
+	m.logger.Info("Adopted legacy migrations", zap.Uint("version", latest))
+	return nil
+}
+
+// legacyVersion returns the version of the last of the migrations in the
+// legacy table, which names them without .up, e.g. 001_create_users.sql
+func (m *Migrator) legacyVersion(ctx context.Context) (uint, error) {
+	files, err := fs.Glob(m.fsys, "*.up.sql")
+	if err != nil {
+		return 0, fmt.Errorf("migrate: failed to list migrations: %w", err)
+	}
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = strings.TrimSuffix(file, ".up.sql") + ".sql"
+	}
+
+	rows, err := m.conn.QueryContext(ctx, "SELECT version FROM "+legacyTable+" WHERE version = ANY($1)", pq.Array(names))
+	if err != nil {
+		return 0, fmt.Errorf("migrate: failed to read legacy migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var latest uint
+	for rows.Next() {
+		var file string
+		if err := rows.Scan(&file); err != nil {
+			return 0, fmt.Errorf("migrate: failed to read legacy migrations: %w", err)
+		}
+		var version uint
+		if _, err := fmt.Sscanf(file, "%d_", &version); err != nil {
+			return 0, fmt.Errorf("migrate: legacy migration %s isn't numbered", file)
+		}
+		latest = max(latest, version)
+	}
+	return latest, rows.Err()
+}
+
+// Up applies the pending migrations in fsys, e.g. at startup; see New
+func Up(ctx context.Context, db *sql.DB, fsys fs.FS, table string, logger *zap.Logger) error {
+	migrator, err := New(ctx, db, fsys, table, logger)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	return migrator.Up()
+}
@@ -0,0 +1,93 @@
+package money
+
+import "fmt"
+
+// Add returns m + other. Both must have the same currency, unless one is
+// the zero Money.
+func (m Money) Add(other Money) (Money, error) {
+	currency, err := m.common(other)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{MinorUnits: m.MinorUnits + other.MinorUnits, Currency: currency}, nil
+}
+
+// Sub returns m - other, of the same currency
+func (m Money) Sub(other Money) (Money, error) {
+	return m.Add(other.Neg())
+}
+
+// Neg returns -m
+func (m Money) Neg() Money {
+	return Money{MinorUnits: -m.MinorUnits, Currency: m.Currency}
+}
+
+// Mul returns m times n, e.g. a unit price times a quantity
+func (m Money) Mul(n int64) Money {
+	return Money{MinorUnits: m.MinorUnits * n, Currency: m.Currency}
+}
+
+// Cmp compares m with other, of the same currency: -1 if m is less, 0 if
+// equal and +1 if greater
+func (m Money) Cmp(other Money) (int, error) {
+	if _, err := m.common(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.MinorUnits < other.MinorUnits:
+		return -1, nil
+	case m.MinorUnits > other.MinorUnits:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// Split divides m into n parts that add up to m exactly, the remainder
+// going one minor unit at a time to the first parts, e.g. $10.00 in 3 is
+// $3.34, $3.33 and $3.33
+func (m Money) Split(n int) []Money {
+	if n <= 0 {
+		return nil
+	}
+
+	parts := make([]Money, n)
+	share, remainder := m.MinorUnits/int64(n), m.MinorUnits%int64(n)
+	step := int64(1)
+	if remainder < 0 {
+		step, remainder = -1, -remainder
+	}
+	for i := range parts {
+		parts[i] = Money{MinorUnits: share, Currency: m.Currency}
+		if int64(i) < remainder {
+			parts[i].MinorUnits += step
+		}
+	}
+	return parts
+}
+
+// Sum adds amounts of one currency; the sum of none is the zero Money
+func Sum(amounts ...Money) (Money, error) {
+	var total Money
+	for _, amount := range amounts {
+		var err error
+		if total, err = total.Add(amount); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// common returns the currency of an operation on m and other
+func (m Money) common(other Money) (string, error) {
+	switch {
+	case m.Currency == other.Currency:
+		return m.Currency, nil
+	case m.Currency == "" && m.IsZero():
+		return other.Currency, nil
+	case other.Currency == "" && other.IsZero():
+		return m.Currency, nil
+	default:
+		return "", fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+}
@@ -0,0 +1,76 @@
+package money
+
+import (
+	"strconv"
+	"strings"
+)
+
+// exponents are the currencies whose minor unit isn't a hundredth
+var exponents = map[string]int{
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0,
+	"XOF": 0, "XPF": 0,
+}
+
+// symbols are written before amounts of their currency; other currencies
+// are written after the amount, e.g. 129.97 CHF
+var symbols = map[string]string{
+	"USD": "$", "EUR": "€", "GBP": "£", "JPY": "¥", "INR": "₹", "KRW": "₩",
+	"VND": "₫", "CAD": "CA$", "AUD": "A$",
+}
+
+// Exponent returns the number of decimals of currency's minor unit: 2 for
+// most currencies, 0 for e.g. JPY and 3 for e.g. KWD
+func Exponent(currency string) int {
+	if exponent, ok := exponents[strings.ToUpper(currency)]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// Decimal returns the amount in major units, without grouping, e.g.
+// "1299.50"
+func (m Money) Decimal() string {
+	return m.decimal(false)
+}
+
+// String formats m for people, e.g. "$1,299.50", "¥1,300" or
+// "129.97 CHF". Templates rendering a Money get this form.
+func (m Money) String() string {
+	amount := m.decimal(true)
+	if symbol, ok := symbols[m.Currency]; ok {
+		if strings.HasPrefix(amount, "-") {
+			return "-" + symbol + amount[1:]
+		}
+		return symbol + amount
+	}
+	if m.Currency == "" {
+		return amount
+	}
+	return amount + " " + m.Currency
+}
+
+func (m Money) decimal(grouped bool) string {
+	digits := strconv.FormatInt(m.MinorUnits, 10)
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+
+	exponent := Exponent(m.Currency)
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	whole, fraction := digits[:len(digits)-exponent], digits[len(digits)-exponent:]
+
+	if grouped {
+		for i := len(whole) - 3; i > 0; i -= 3 {
+			whole = whole[:i] + "," + whole[i:]
+		}
+	}
+	if exponent == 0 {
+		return sign + whole
+	}
+	return sign + whole + "." + fraction
+}
@@ -0,0 +1,105 @@
+// Package money represents amounts as integer minor units of an ISO 4217
+// currency, e.g. 12997 USD cents for $129.97, so that adding, splitting and
+// serializing amounts never rounds them
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ErrCurrencyMismatch is returned when combining amounts of different
+// currencies
+var ErrCurrencyMismatch = errors.New("money: currency mismatch")
+
+// Money is an amount in the minor units of a currency. The zero value is
+// zero of no currency, which adds to any currency.
+type Money struct {
+	// MinorUnits is the amount in the currency's smallest unit, e.g. cents
+	MinorUnits int64 `json:"minor_units"`
+	// Currency is the ISO 4217 code, e.g. USD
+	Currency string `json:"currency"`
+}
+
+// New returns minorUnits of currency, e.g. New(12997, "USD") for $129.97
+func New(minorUnits int64, currency string) Money {
+	return Money{MinorUnits: minorUnits, Currency: strings.ToUpper(currency)}
+}
+
+// FromMajor converts an amount in major units, e.g. 129.97 dollars, rounding
+// half away from zero to the currency's minor unit. Use it only at the
+// edges, for amounts that arrive as floats.
+func FromMajor(amount float64, currency string) Money {
+	scale := math.Pow10(Exponent(currency))
+	return New(int64(math.Round(amount*scale)), currency)
+}
+
+// Parse parses a decimal amount in major units, e.g. "129.97", exactly. It
+// fails when the amount has more decimals than the currency's minor unit.
+func Parse(amount, currency string) (Money, error) {
+	s := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	exponent := Exponent(currency)
+	if whole == "" && fraction == "" || len(fraction) > exponent {
+		return Money{}, fmt.Errorf("money: invalid %s amount %q", currency, amount)
+	}
+	fraction += strings.Repeat("0", exponent-len(fraction))
+
+	var minor int64
+	for _, r := range whole + fraction {
+		if r < '0' || r > '9' {
+			return Money{}, fmt.Errorf("money: invalid %s amount %q", currency, amount)
+		}
+		if minor > (math.MaxInt64-9)/10 {
+			return Money{}, fmt.Errorf("money: %s amount %q out of range", currency, amount)
+		}
+		minor = minor*10 + int64(r-'0')
+	}
+	if negative {
+		minor = -minor
+	}
+	return New(minor, currency), nil
+}
+
+// MustParse is Parse for amounts known to be valid, e.g. constants; it
+// panics on errors
+func MustParse(amount, currency string) Money {
+	m, err := Parse(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// UnmarshalJSON decodes {"minor_units": 12997, "currency": "USD"},
+// requiring the currency
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		MinorUnits *int64 `json:"minor_units"`
+		Currency   string `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("money: amounts must be objects with minor_units and currency: %w", err)
+	}
+	if raw.MinorUnits == nil || raw.Currency == "" {
+		return errors.New("money: minor_units and currency are required")
+	}
+	*m = New(*raw.MinorUnits, raw.Currency)
+	return nil
+}
+
+// IsZero reports whether m is zero, in any currency
+func (m Money) IsZero() bool {
+	return m.MinorUnits == 0
+}
+
+// IsNegative reports whether m is below zero
+func (m Money) IsNegative() bool {
+	return m.MinorUnits < 0
+}
@@ -0,0 +1,29 @@
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Value stores m in a JSONB column, as {"minor_units": ..., "currency": ...}.
+// Tables that query amounts store MinorUnits and Currency in columns of
+// their own instead.
+func (m Money) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
+
+// Scan reads a Money stored by Value
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, m)
+	case string:
+		return json.Unmarshal([]byte(v), m)
+	case nil:
+		*m = Money{}
+		return nil
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+}
@@ -0,0 +1,21 @@
+package mtls
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// CertificateExpiry is when the loaded certificate expires, for
+	// alerting on certificates that stop being rotated
+	CertificateExpiry = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "mtls_certificate_expiry_timestamp_seconds",
+		Help: "Unix time the service's mTLS certificate expires",
+	})
+
+	// ReloadFailures counts certificate rotations that couldn't be loaded
+	ReloadFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mtls_certificate_reload_failures_total",
+		Help: "mTLS certificate reloads that failed, keeping the previous certificate",
+	})
+)
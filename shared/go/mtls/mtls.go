@@ -0,0 +1,258 @@
+// Package mtls secures the HTTP and gRPC traffic between services with
+// mutual TLS: servers only accept callers presenting a certificate from the
+// internal CA, optionally with an allowed identity, and clients present
+// their own. Certificates are reloaded when their files change, so they can
+// be rotated, e.g. by cert-manager or a SPIFFE helper writing SVIDs to
+// disk, without restarting.
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Modes, chosen per environment
+const (
+	// ModeDisabled serves and calls plain HTTP and gRPC
+	ModeDisabled = "disabled"
+	// ModePermissive serves TLS and verifies the client certificates
+	// callers present, but also accepts callers without one, e.g. while
+	// clients are being migrated
+	ModePermissive = "permissive"
+	// ModeStrict only accepts callers with a valid client certificate and,
+	// if AllowedPeers is set, an allowed identity
+	ModeStrict = "strict"
+)
+
+// Config configures mutual TLS, loaded by the shared config loader
+type Config struct {
+	Mode string `env:"MTLS_MODE" default:"disabled"`
+	// CertFile and KeyFile are the service's PEM certificate, with any
+	// intermediates, and key
+	CertFile string `env:"MTLS_CERT_FILE"`
+	KeyFile  string `env:"MTLS_KEY_FILE"`
+	// CAFile holds the PEM certificates of the CAs peers' certificates are
+	// verified against, e.g. a SPIFFE trust bundle
+	CAFile string `env:"MTLS_CA_FILE"`
+	// AllowedPeers are the identities that may call the service in strict
+	// mode: SPIFFE IDs, e.g. spiffe://ecommerce.local/returns-service, or
+	// DNS names. Empty allows any certificate the CA issued.
+	AllowedPeers []string `env:"MTLS_ALLOWED_PEERS"`
+}
+
+// Validate checks that the files a mode needs are set
+func (c *Config) Validate() error {
+	switch c.Mode {
+	case "", ModeDisabled:
+		c.Mode = ModeDisabled
+		return nil
+	case ModePermissive, ModeStrict:
+	default:
+		return fmt.Errorf("invalid MTLS_MODE %q: must be %s, %s or %s", c.Mode, ModeDisabled, ModePermissive, ModeStrict)
+	}
+	if c.CertFile == "" || c.KeyFile == "" || c.CAFile == "" {
+		return errors.New("MTLS_CERT_FILE, MTLS_KEY_FILE and MTLS_CA_FILE are required when MTLS_MODE is " + c.Mode)
+	}
+	return nil
+}
+
+// TLS provides the TLS configurations of a service's servers and clients,
+// always using the latest certificates on disk
+type TLS struct {
+	cfg     Config
+	allowed map[string]bool
+	store   *store
+	logger  *zap.Logger
+}
+
+// New loads the certificates cfg names and watches their files for
+// changes. With mode disabled, it returns a TLS whose servers and clients
+// don't use TLS.
+func New(cfg Config, logger *zap.Logger) (*TLS, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	t := &TLS{cfg: cfg, allowed: make(map[string]bool), logger: logger}
+	for _, peer := range cfg.AllowedPeers {
+		if peer = strings.TrimSpace(peer); peer != "" {
+			t.allowed[peer] = true
+		}
+	}
+	if !t.Enabled() {
+		return t, nil
+	}
+
+	store, err := newStore(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	t.store = store
+	return t, nil
+}
+
+// Enabled reports whether servers and clients use TLS
+func (t *TLS) Enabled() bool {
+	return t.cfg.Mode != ModeDisabled
+}
+
+// Mode is the configured mode
+func (t *TLS) Mode() string {
+	return t.cfg.Mode
+}
+
+// Close stops watching the certificate files
+func (t *TLS) Close() error {
+	if t.store == nil {
+		return nil
+	}
+	return t.store.close()
+}
+
+// ServerConfig returns the TLS configuration of the service's HTTP and gRPC
+// servers, or nil when disabled. Each handshake uses the current
+// certificate and CAs.
+func (t *TLS) ServerConfig() *tls.Config {
+	if !t.Enabled() {
+		return nil
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return t.handshakeConfig(), nil
+		},
+	}
+}
+
+func (t *TLS) handshakeConfig() *tls.Config {
+	certs := t.store.current()
+	clientAuth := tls.VerifyClientCertIfGiven
+	if t.cfg.Mode == ModeStrict {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*certs.cert},
+		ClientAuth:   clientAuth,
+		ClientCAs:    certs.roots,
+		// Returned configs don't inherit the server's, so they offer
+		// HTTP/2 themselves, which gRPC requires
+		NextProtos:       []string{"h2", "http/1.1"},
+		VerifyConnection: t.authorizeClient,
+	}
+}
+
+// authorizeClient rejects, in strict mode, verified clients whose identity
+// isn't allowed
+func (t *TLS) authorizeClient(state tls.ConnectionState) error {
+	if t.cfg.Mode != ModeStrict || len(t.allowed) == 0 || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	for _, id := range Identities(state.PeerCertificates[0]) {
+		if t.allowed[id] {
+			return nil
+		}
+	}
+	t.logger.Warn("Rejected mTLS client with unknown identity",
+		zap.Strings("identities", Identities(state.PeerCertificates[0])),
+	)
+	return errors.New("mtls: client identity not allowed")
+}
+
+// ClientConfig returns the TLS configuration of the service's clients of
+// other services, presenting the current certificate, or nil when
+// disabled
+func (t *TLS) ClientConfig() *tls.Config {
+	if !t.Enabled() {
+		return nil
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return t.store.current().cert, nil
+		},
+		// The chain is verified by verifyServer instead, against the
+		// current CAs rather than those loaded when the client was created
+		InsecureSkipVerify: true,
+		VerifyConnection:   t.verifyServer,
+	}
+}
+
+// verifyServer verifies a server's chain against the current CAs. SPIFFE
+// SVIDs have no DNS names, so the host name is only checked for
+// certificates that have some.
+func (t *TLS) verifyServer(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("mtls: server presented no certificate")
+	}
+	leaf := state.PeerCertificates[0]
+	opts := x509.VerifyOptions{
+		Roots:         t.store.current().roots,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, cert := range state.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if len(leaf.DNSNames) > 0 || net.ParseIP(state.ServerName) != nil {
+		opts.DNSName = state.ServerName
+	}
+	if _, err := leaf.Verify(opts); err != nil {
+		return fmt.Errorf("mtls: invalid server certificate: %w", err)
+	}
+	return nil
+}
+
+// Transport returns an HTTP transport for calls to other services,
+// presenting the service's certificate to https URLs. Plain http URLs are
+// called as before, so it can be used before every service serves TLS.
+func (t *TLS) Transport() http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if t.Enabled() {
+		transport.TLSClientConfig = t.ClientConfig()
+	}
+	return transport
+}
+
+// ListenAndServe serves srv with TLS when enabled, else plain HTTP
+func (t *TLS) ListenAndServe(srv *http.Server) error {
+	if !t.Enabled() {
+		return srv.ListenAndServe()
+	}
+	srv.TLSConfig = t.ServerConfig()
+	return srv.ListenAndServeTLS("", "")
+}
+
+// Identities returns a certificate's identities: its SPIFFE ID and other
+// URI SANs, then its DNS names
+func Identities(cert *x509.Certificate) []string {
+	ids := make([]string, 0, len(cert.URIs)+len(cert.DNSNames))
+	for _, uri := range cert.URIs {
+		ids = append(ids, uri.String())
+	}
+	return append(ids, cert.DNSNames...)
+}
+
+// PeerIdentity returns the first identity of the client certificate r was
+// made with, e.g. its SPIFFE ID, or "" for requests without one
+func PeerIdentity(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return ""
+	}
+	if ids := Identities(r.TLS.PeerCertificates[0]); len(ids) > 0 {
+		return ids[0]
+	}
+	return r.TLS.PeerCertificates[0].Subject.CommonName
+}
+
+// expiresIn is how long until a certificate expires, for logs
+func expiresIn(cert *x509.Certificate) time.Duration {
+	return time.Until(cert.NotAfter).Round(time.Second)
+}
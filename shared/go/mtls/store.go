@@ -0,0 +1,143 @@
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// reloadDelay lets a rotation finish writing every file before they are
+// read, as the certificate, key and CA files change one after another
+const reloadDelay = 500 * time.Millisecond
+
+// certificates are the files' contents as of one load
+type certificates struct {
+	cert  *tls.Certificate
+	leaf  *x509.Certificate
+	roots *x509.CertPool
+}
+
+// store holds the latest certificates, reloading them when their files
+// change. A reload that fails, e.g. on a key that doesn't match the
+// certificate yet, keeps the previous certificates.
+type store struct {
+	cfg     Config
+	logger  *zap.Logger
+	certs   atomic.Pointer[certificates]
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newStore(cfg Config, logger *zap.Logger) (*store, error) {
+	s := &store{cfg: cfg, logger: logger, done: make(chan struct{})}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("mtls: failed to watch certificates: %w", err)
+	}
+	// Directories are watched rather than files: Kubernetes and SPIFFE
+	// helpers replace files, or the symlinks to them, instead of writing
+	// them in place
+	dirs := map[string]bool{}
+	for _, file := range []string{cfg.CertFile, cfg.KeyFile, cfg.CAFile} {
+		dirs[filepath.Dir(file)] = true
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("mtls: failed to watch %s: %w", dir, err)
+		}
+	}
+	s.watcher = watcher
+
+	s.wg.Add(1)
+	go s.watch()
+	return s, nil
+}
+
+func (s *store) current() *certificates {
+	return s.certs.Load()
+}
+
+// reload reads the files, replacing the current certificates if they are
+// valid
+func (s *store) reload() error {
+	cert, err := tls.LoadX509KeyPair(s.cfg.CertFile, s.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("mtls: failed to load certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("mtls: failed to parse certificate: %w", err)
+	}
+	cert.Leaf = leaf
+
+	caPEM, err := os.ReadFile(s.cfg.CAFile)
+	if err != nil {
+		return fmt.Errorf("mtls: failed to read CA file: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return errors.New("mtls: no certificates in CA file " + s.cfg.CAFile)
+	}
+
+	s.certs.Store(&certificates{cert: &cert, leaf: leaf, roots: roots})
+	CertificateExpiry.Set(float64(leaf.NotAfter.Unix()))
+	s.logger.Info("Loaded mTLS certificate",
+		zap.Strings("identities", Identities(leaf)),
+		zap.Time("not_after", leaf.NotAfter),
+		zap.Duration("expires_in", expiresIn(leaf)),
+	)
+	return nil
+}
+
+// watch reloads the certificates once their files have stopped changing
+func (s *store) watch() {
+	defer s.wg.Done()
+
+	var pending <-chan time.Time
+	for {
+		select {
+		case <-s.done:
+			return
+		case event, ok := <-s.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 {
+				pending = time.After(reloadDelay)
+			}
+		case err, ok := <-s.watcher.Errors:
+			if !ok {
+				return
+			}
+			s.logger.Warn("mTLS certificate watch error", zap.Error(err))
+		case <-pending:
+			pending = nil
+			if err := s.reload(); err != nil {
+				ReloadFailures.Inc()
+				s.logger.Error("Failed to reload mTLS certificates, keeping the previous ones", zap.Error(err))
+			}
+		}
+	}
+}
+
+func (s *store) close() error {
+	close(s.done)
+	err := s.watcher.Close()
+	s.wg.Wait()
+	return err
+}
@@ -0,0 +1,3 @@
+module github.com/ecommerce-platform/shared/go/pagination
+
+go 1.21
@@ -0,0 +1,136 @@
+// Package pagination provides the cursor pagination and response envelope
+// shared by Go services' list endpoints
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+// Page sizes used when a request asks for none or too many
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// ErrInvalidCursor is returned for cursors that weren't issued by
+// EncodeCursor
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Params are a list request's page size and position
+type Params struct {
+	Limit  int
+	Cursor string // empty for the first page
+	// IncludeTotal asks lists that don't always count their items to count
+	// them
+	IncludeTotal bool
+}
+
+// FromQuery reads the limit, cursor and include_total query parameters.
+// Missing or invalid limits get DefaultLimit and larger ones are clamped to
+// MaxLimit.
+func FromQuery(query url.Values) Params {
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	includeTotal, _ := strconv.ParseBool(query.Get("include_total"))
+	return Params{
+		Limit:        ClampLimit(limit),
+		Cursor:       query.Get("cursor"),
+		IncludeTotal: includeTotal,
+	}
+}
+
+// ClampLimit returns limit within 1 and MaxLimit, or DefaultLimit when it
+// isn't positive
+func ClampLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultLimit
+	}
+	if limit > MaxLimit {
+		return MaxLimit
+	}
+	return limit
+}
+
+// EncodeCursor encodes the position after an item, typically the columns
+// the list is sorted by, as an opaque URL-safe cursor
+func EncodeCursor(position interface{}) (string, error) {
+	data, err := json.Marshal(position)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor decodes a cursor from EncodeCursor into position
+func DecodeCursor(cursor string, position interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, position); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+// Page is the response envelope of list endpoints. NextCursor and Links
+// are omitted on the last page, and TotalCount from uncounted pages.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	TotalCount *int64 `json:"total_count,omitempty"`
+	Links      *Links `json:"links,omitempty"`
+}
+
+// Links are the URLs of a page's neighbours
+type Links struct {
+	Next string `json:"next"`
+}
+
+// NewPage builds a page from up to limit+1 items: fetching one item more
+// than the page holds tells whether there is a next page without counting.
+// position returns the cursor position after an item.
+func NewPage[T any](items []T, limit int, totalCount int64, position func(T) interface{}) (Page[T], error) {
+	page, err := NewUncountedPage(items, limit, position)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	page.TotalCount = &totalCount
+	return page, nil
+}
+
+// NewUncountedPage builds a page as NewPage does without a total count,
+// for lists too large to count on every request
+func NewUncountedPage[T any](items []T, limit int, position func(T) interface{}) (Page[T], error) {
+	page := Page[T]{Items: items}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+
+	if len(items) > limit {
+		page.Items = items[:limit]
+		cursor, err := EncodeCursor(position(page.Items[limit-1]))
+		if err != nil {
+			return Page[T]{}, err
+		}
+		page.NextCursor = cursor
+	}
+	return page, nil
+}
+
+// WithLinks links the page to the next one, requested as requestURL was
+// with the next cursor, e.g. for c.Request.URL. The link keeps the
+// request's path and query, without its scheme and host.
+func (p Page[T]) WithLinks(requestURL *url.URL) Page[T] {
+	if p.NextCursor == "" {
+		return p
+	}
+	query := requestURL.Query()
+	query.Set("cursor", p.NextCursor)
+	next := url.URL{Path: requestURL.Path, RawQuery: query.Encode()}
+	p.Links = &Links{Next: next.String()}
+	return p
+}
@@ -0,0 +1,159 @@
+// Package ratelimit provides Redis-backed rate limiting for Gin services, so
+// limits hold across every replica of a service
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Algorithm is how a rule counts requests
+type Algorithm int
+
+const (
+	// SlidingWindow allows Limit requests in any Window-long period
+	SlidingWindow Algorithm = iota
+	// TokenBucket allows bursts of up to Limit requests, refilled evenly
+	// over Window
+	TokenBucket
+)
+
+// Rule is a limit applied per key
+type Rule struct {
+	// Name separates the counters of rules sharing a key, e.g. "login"
+	Name      string
+	Limit     int
+	Window    time.Duration
+	Algorithm Algorithm
+}
+
+// Result is the outcome of a request against a rule
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the key is back to its full limit
+	Reset time.Duration
+	// RetryAfter is the time until a rejected request would be allowed
+	RetryAfter time.Duration
+}
+
+// Limiter counts requests in Redis
+type Limiter struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewLimiter creates a limiter whose keys start with prefix, typically the
+// service name
+func NewLimiter(client redis.UniversalClient, prefix string) *Limiter {
+	return &Limiter{client: client, prefix: prefix}
+}
+
+// slidingWindowScript keeps a sorted set of request times. It returns
+// whether the request is allowed, the requests in the window, and the time
+// in milliseconds until the oldest and the newest of them leave it.
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call("ZREMRANGEBYSCORE", key, "-inf", now - window)
+local count = redis.call("ZCARD", key)
+local allowed = 0
+if count < limit then
+	redis.call("ZADD", key, now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call("PEXPIRE", key, window)
+
+local oldest = redis.call("ZRANGE", key, 0, 0, "WITHSCORES")
+local newest = redis.call("ZRANGE", key, -1, -1, "WITHSCORES")
+local retry, reset = 0, 0
+if oldest[2] then
+	retry = tonumber(oldest[2]) + window - now
+	reset = tonumber(newest[2]) + window - now
+end
+return {allowed, count, retry, reset}
+`)
+
+// tokenBucketScript stores a bucket's tokens (in thousandths, as Redis
+// truncates script results to integers) and when it was last refilled. It
+// returns whether the request is allowed, the whole tokens left, and the
+// time in milliseconds until the next token and until the bucket is full.
+var tokenBucketScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local capacity = tonumber(ARGV[3]) * 1000
+local rate = capacity / window
+
+local bucket = redis.call("HMGET", key, "tokens", "ts")
+local tokens = tonumber(bucket[1]) or capacity
+local ts = tonumber(bucket[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+if tokens >= 1000 then
+	tokens = tokens - 1000
+	allowed = 1
+end
+redis.call("HSET", key, "tokens", tokens, "ts", now)
+redis.call("PEXPIRE", key, window)
+
+local wait = 0
+if tokens < 1000 then
+	wait = math.ceil((1000 - tokens) / rate)
+end
+return {allowed, math.floor(tokens / 1000), wait, math.ceil((capacity - tokens) / rate)}
+`)
+
+// Allow counts a request by key against rule
+func (l *Limiter) Allow(ctx context.Context, rule Rule, key string) (Result, error) {
+	redisKey := fmt.Sprintf("%s:ratelimit:%s:%s", l.prefix, rule.Name, key)
+	now := time.Now().UnixMilli()
+	window := rule.Window.Milliseconds()
+
+	result := Result{Limit: rule.Limit}
+	switch rule.Algorithm {
+	case TokenBucket:
+		values, err := tokenBucketScript.Run(ctx, l.client, []string{redisKey}, now, window, rule.Limit).Int64Slice()
+		if err != nil {
+			return Result{}, err
+		}
+		result.Allowed = values[0] == 1
+		result.Remaining = int(values[1])
+		result.Reset = time.Duration(values[3]) * time.Millisecond
+		if !result.Allowed {
+			result.RetryAfter = time.Duration(values[2]) * time.Millisecond
+		}
+
+	default:
+		values, err := slidingWindowScript.Run(ctx, l.client, []string{redisKey}, now, window, rule.Limit, requestID(now)).Int64Slice()
+		if err != nil {
+			return Result{}, err
+		}
+		result.Allowed = values[0] == 1
+		result.Remaining = rule.Limit - int(values[1])
+		result.Reset = time.Duration(values[3]) * time.Millisecond
+		if !result.Allowed {
+			result.RetryAfter = time.Duration(values[2]) * time.Millisecond
+		}
+	}
+	return result, nil
+}
+
+// requestID makes a request's sorted set member unique, as several
+// requests can arrive in the same millisecond
+func requestID(now int64) string {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return fmt.Sprintf("%d-%s", now, hex.EncodeToString(suffix))
+}
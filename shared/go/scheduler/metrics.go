@@ -0,0 +1,35 @@
+package scheduler
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// RunsTotal counts scheduled times by result: succeeded or failed, on
+	// the replica that ran them, or skipped, on the others
+	RunsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "scheduler_job_runs_total",
+		Help: "Scheduled job runs by result",
+	}, []string{"service", "job", "status"})
+
+	// RunDuration is how long runs took, including retries
+	RunDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "scheduler_job_duration_seconds",
+		Help:    "Scheduled job run duration, including retries",
+		Buckets: []float64{.1, .5, 1, 5, 15, 30, 60, 300, 900},
+	}, []string{"service", "job"})
+
+	// RetriesTotal counts failed attempts that were retried
+	RetriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "scheduler_job_retries_total",
+		Help: "Scheduled job attempts that failed and were retried",
+	}, []string{"service", "job"})
+
+	// LastSuccess is when a job last succeeded on this replica, for
+	// alerting on jobs that stop succeeding; take the max across replicas
+	LastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "scheduler_job_last_success_timestamp_seconds",
+		Help: "Unix time a scheduled job last succeeded on this replica",
+	}, []string{"service", "job"})
+)
@@ -0,0 +1,192 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Schema creates the scheduled_job_runs table the scheduler records runs
+// in. Services with migration files copy it into a migration; those that
+// create their schema at startup run it with EnsureSchema.
+//
+//go:embed schema.sql
+var Schema string
+
+// EnsureSchema creates the scheduled_job_runs table if it doesn't exist
+func EnsureSchema(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, Schema); err != nil {
+		return fmt.Errorf("failed to create scheduled_job_runs table: %w", err)
+	}
+	return nil
+}
+
+// Run statuses
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Run is a job's run at one scheduled time
+type Run struct {
+	ID          int64      `json:"id"`
+	Job         string     `json:"job"`
+	ScheduledAt time.Time  `json:"scheduled_at"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	Status      string     `json:"status"`
+	Attempts    int        `json:"attempts"`
+	Error       string     `json:"error,omitempty"`
+	Instance    string     `json:"instance"`
+}
+
+// run runs job for its scheduled time unless another replica is running
+// the job or has run that time
+func (s *Scheduler) run(ctx context.Context, job *scheduledJob, scheduledAt time.Time) {
+	logger := s.logger.With(zap.String("job", job.Name), zap.Time("scheduled_at", scheduledAt))
+
+	// Advisory locks belong to a session, so the lock is taken and released
+	// on one connection
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		logger.Error("Failed to get connection for job", zap.Error(err))
+		RunsTotal.WithLabelValues(s.cfg.Service, job.Name, StatusFailed).Inc()
+		return
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, lockKey(job.Name)).Scan(&locked); err != nil {
+		logger.Error("Failed to lock job", zap.Error(err))
+		RunsTotal.WithLabelValues(s.cfg.Service, job.Name, StatusFailed).Inc()
+		return
+	}
+	if !locked {
+		logger.Debug("Job is running on another replica")
+		RunsTotal.WithLabelValues(s.cfg.Service, job.Name, "skipped").Inc()
+		return
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtext($1))`, lockKey(job.Name))
+
+	var id int64
+	err = conn.QueryRowContext(ctx, `
+		INSERT INTO scheduled_job_runs (job, scheduled_at, started_at, status, instance)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (job, scheduled_at) DO NOTHING
+		RETURNING id
+	`, job.Name, scheduledAt.UTC(), time.Now().UTC(), StatusRunning, s.cfg.Instance).Scan(&id)
+	if err == sql.ErrNoRows {
+		logger.Debug("Job already ran for this time on another replica")
+		RunsTotal.WithLabelValues(s.cfg.Service, job.Name, "skipped").Inc()
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to record job run", zap.Error(err))
+		RunsTotal.WithLabelValues(s.cfg.Service, job.Name, StatusFailed).Inc()
+		return
+	}
+
+	start := time.Now()
+	attempts, runErr := s.attempt(ctx, job, logger)
+	RunDuration.WithLabelValues(s.cfg.Service, job.Name).Observe(time.Since(start).Seconds())
+
+	status, message := StatusSucceeded, ""
+	if runErr != nil {
+		status, message = StatusFailed, runErr.Error()
+		logger.Error("Job failed", zap.Int("attempts", attempts), zap.Error(runErr))
+	} else {
+		LastSuccess.WithLabelValues(s.cfg.Service, job.Name).SetToCurrentTime()
+		logger.Info("Job succeeded", zap.Int("attempts", attempts), zap.Duration("duration", time.Since(start)))
+	}
+	RunsTotal.WithLabelValues(s.cfg.Service, job.Name, status).Inc()
+
+	// The run is recorded even when shutting down mid-run
+	recordCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := conn.ExecContext(recordCtx, `
+		UPDATE scheduled_job_runs
+		SET status = $1, finished_at = $2, attempts = $3, error = $4
+		WHERE id = $5
+	`, status, time.Now().UTC(), attempts, message, id); err != nil {
+		logger.Error("Failed to record job result", zap.Error(err))
+	}
+	if _, err := conn.ExecContext(recordCtx, `
+		DELETE FROM scheduled_job_runs WHERE job = $1 AND scheduled_at < $2
+	`, job.Name, time.Now().UTC().Add(-s.cfg.Retention)); err != nil {
+		logger.Warn("Failed to prune job history", zap.Error(err))
+	}
+}
+
+// attempt runs job until it succeeds, it has been attempted MaxAttempts
+// times or ctx is done
+func (s *Scheduler) attempt(ctx context.Context, job *scheduledJob, logger *zap.Logger) (int, error) {
+	backoff := job.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, job.Timeout)
+		err := job.Run(attemptCtx)
+		cancel()
+		if err == nil || attempt >= job.MaxAttempts || ctx.Err() != nil {
+			return attempt, err
+		}
+
+		RetriesTotal.WithLabelValues(s.cfg.Service, job.Name).Inc()
+		logger.Warn("Job attempt failed, retrying",
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		select {
+		case <-ctx.Done():
+			return attempt, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// History returns job's most recent runs, newest first
+func (s *Scheduler) History(ctx context.Context, job string, limit int) ([]Run, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, job, scheduled_at, started_at, finished_at, status, attempts, error, instance
+		FROM scheduled_job_runs
+		WHERE job = $1
+		ORDER BY scheduled_at DESC
+		LIMIT $2
+	`, job, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []Run{}
+	for rows.Next() {
+		var run Run
+		if err := rows.Scan(
+			&run.ID, &run.Job, &run.ScheduledAt, &run.StartedAt, &run.FinishedAt,
+			&run.Status, &run.Attempts, &run.Error, &run.Instance,
+		); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// Jobs returns the names of the registered jobs
+func (s *Scheduler) Jobs() []string {
+	names := make([]string, len(s.jobs))
+	for i, job := range s.jobs {
+		names[i] = job.Name
+	}
+	return names
+}
+
+// lockKey is job's advisory lock key, hashed by Postgres
+func lockKey(job string) string {
+	return "scheduler:" + job
+}
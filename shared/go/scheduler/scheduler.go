@@ -0,0 +1,164 @@
+// Package scheduler runs a service's cron-style background jobs once per
+// scheduled time across all of its replicas. Replicas coordinate through
+// the service's Postgres database: a job runs on the replica holding its
+// advisory lock, and each run is recorded in the scheduled_job_runs table,
+// whose unique (job, scheduled_at) key stops a replica waking late from
+// running a time another replica already ran. Failed runs are retried, and
+// every run is measured per job.
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+// Defaults of Job's optional fields
+const (
+	DefaultTimeout      = 5 * time.Minute
+	DefaultMaxAttempts  = 3
+	DefaultRetryBackoff = 10 * time.Second
+	// DefaultRetention is how long run history is kept
+	DefaultRetention = 30 * 24 * time.Hour
+)
+
+// Job is a function run on a schedule
+type Job struct {
+	// Name identifies the job in its lock, history and metrics, e.g.
+	// reservations.expire. Names are unique per database.
+	Name string
+	// Schedule is a standard five field cron expression, e.g. "*/5 * * * *",
+	// or a descriptor such as "@hourly" or "@every 1m". Times are in
+	// Config.Location unless the expression starts with CRON_TZ=<zone>.
+	Schedule string
+	// Run does the job's work. It must return once ctx is done; it may run
+	// again for the same time after a crash, so it must be idempotent.
+	Run func(ctx context.Context) error
+	// Timeout bounds each attempt (DefaultTimeout)
+	Timeout time.Duration
+	// MaxAttempts includes the first (DefaultMaxAttempts)
+	MaxAttempts int
+	// RetryBackoff is the wait before the second attempt, doubling for each
+	// later one (DefaultRetryBackoff)
+	RetryBackoff time.Duration
+}
+
+// Config configures a scheduler
+type Config struct {
+	// Service labels the metrics, e.g. inventory-service
+	Service string
+	// Instance is recorded with runs; defaults to the host name
+	Instance string
+	// Location is the time zone schedules are in; defaults to UTC
+	Location *time.Location
+	// Retention is how long run history is kept (DefaultRetention)
+	Retention time.Duration
+}
+
+// Scheduler runs registered jobs at their scheduled times
+type Scheduler struct {
+	db     *sql.DB
+	cfg    Config
+	jobs   []*scheduledJob
+	names  map[string]bool
+	logger *zap.Logger
+}
+
+type scheduledJob struct {
+	Job
+	schedule cron.Schedule
+}
+
+// New creates a scheduler coordinating through db, whose
+// scheduled_job_runs table must exist, see Schema
+func New(db *sql.DB, cfg Config, logger *zap.Logger) *Scheduler {
+	if cfg.Instance == "" {
+		cfg.Instance, _ = os.Hostname()
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = DefaultRetention
+	}
+	return &Scheduler{db: db, cfg: cfg, names: make(map[string]bool), logger: logger}
+}
+
+// Register adds a job, rejecting invalid schedules. Jobs must be
+// registered before Start.
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return errors.New("scheduler: jobs need a name and a function")
+	}
+	if s.names[job.Name] {
+		return fmt.Errorf("scheduler: job %s is already registered", job.Name)
+	}
+	schedule, err := cron.ParseStandard(job.Schedule)
+	if err != nil {
+		return fmt.Errorf("scheduler: invalid schedule %q for job %s: %w", job.Schedule, job.Name, err)
+	}
+	if job.Timeout <= 0 {
+		job.Timeout = DefaultTimeout
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = DefaultMaxAttempts
+	}
+	if job.RetryBackoff <= 0 {
+		job.RetryBackoff = DefaultRetryBackoff
+	}
+
+	s.names[job.Name] = true
+	s.jobs = append(s.jobs, &scheduledJob{Job: job, schedule: schedule})
+	return nil
+}
+
+// Start runs the jobs until ctx is cancelled, then waits for running jobs
+// to return
+func (s *Scheduler) Start(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, job := range s.jobs {
+		s.logger.Info("Scheduling job",
+			zap.String("job", job.Name),
+			zap.String("schedule", job.Schedule),
+		)
+		wg.Add(1)
+		go func(job *scheduledJob) {
+			defer wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+	wg.Wait()
+	s.logger.Info("Scheduler stopped")
+}
+
+// loop runs job at each of its scheduled times. Times missed while no
+// replica was running are skipped, not caught up.
+func (s *Scheduler) loop(ctx context.Context, job *scheduledJob) {
+	for {
+		next := s.next(job, time.Now())
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.run(ctx, job, next)
+		}
+	}
+}
+
+// next is job's first scheduled time after now. "@every" intervals are
+// aligned to multiples of the interval, so replicas agree on the times.
+func (s *Scheduler) next(job *scheduledJob, now time.Time) time.Time {
+	if every, ok := job.schedule.(cron.ConstantDelaySchedule); ok {
+		return now.Truncate(every.Delay).Add(every.Delay)
+	}
+	return job.schedule.Next(now.In(s.cfg.Location))
+}
@@ -0,0 +1,65 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+type awsProvider struct {
+	mu     sync.Mutex
+	client *secretsmanager.Client
+}
+
+// NewAWSProvider creates the provider of awssm://<secret-id> references,
+// where the ID is a secret's name or ARN. Secrets stored as JSON objects,
+// as the console stores key/value secrets, have a value per key.
+//
+// The SDK's default credentials and region, e.g. AWS_REGION and an IAM
+// role, are loaded when the first secret is fetched, so services that
+// don't use AWS don't need them.
+func NewAWSProvider() Provider {
+	return &awsProvider{}
+}
+
+func (p *awsProvider) Fetch(ctx context.Context, id string) (map[string]string, error) {
+	client, err := p.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: &id})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("aws: %w", err)
+	}
+
+	if out.SecretString != nil {
+		return parseValues([]byte(*out.SecretString)), nil
+	}
+	return map[string]string{"value": string(out.SecretBinary)}, nil
+}
+
+// getClient creates the client on first use; failures are retried on the
+// next fetch
+func (p *awsProvider) getClient(ctx context.Context) (*secretsmanager.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.client == nil {
+		cfg, err := awsconfig.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("aws: loading config: %w", err)
+		}
+		p.client = secretsmanager.NewFromConfig(cfg)
+	}
+	return p.client, nil
+}
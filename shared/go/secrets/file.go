@@ -0,0 +1,30 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+type fileProvider struct{}
+
+// NewFileProvider creates the provider of file://<path> references, e.g.
+// file:///run/secrets/jwt_secret or, relative to the working directory,
+// file://secrets/dev.json. Files holding a JSON object have a value per
+// key; others are a single value, trimmed. It suits local development and
+// secrets mounted by Docker or Kubernetes.
+func NewFileProvider() Provider {
+	return fileProvider{}
+}
+
+func (fileProvider) Fetch(_ context.Context, path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("file: %w", err)
+	}
+	return parseValues(content), nil
+}
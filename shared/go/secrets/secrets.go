@@ -0,0 +1,278 @@
+// Package secrets resolves references like vault://kv/jwt-secret to the
+// secrets they name, so credentials live in a secrets manager instead of
+// plain environment variables
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Reference schemes of the built-in providers
+const (
+	SchemeVault = "vault"
+	SchemeAWS   = "awssm"
+	SchemeFile  = "file"
+)
+
+// DefaultTimeout bounds fetching one secret
+const DefaultTimeout = 10 * time.Second
+
+// ErrNotFound is returned for secrets, or keys of secrets, that don't exist
+var ErrNotFound = errors.New("secret not found")
+
+// Provider fetches secrets from one store
+type Provider interface {
+	// Fetch returns the values of the secret at path, e.g. kv/jwt-secret
+	// for vault://kv/jwt-secret. Secrets that aren't key/value pairs have
+	// a single value keyed "value".
+	Fetch(ctx context.Context, path string) (map[string]string, error)
+}
+
+// Manager resolves references with the provider registered for their
+// scheme, and keeps the values it resolved so Refresh can update them
+type Manager struct {
+	timeout time.Duration
+
+	mu        sync.RWMutex
+	providers map[string]Provider
+	values    map[string]string
+	watchers  map[string][]func(string)
+}
+
+// NewManager creates a manager without providers; timeout bounds fetching
+// one secret and defaults to DefaultTimeout
+func NewManager(timeout time.Duration) *Manager {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Manager{
+		timeout:   timeout,
+		providers: make(map[string]Provider),
+		values:    make(map[string]string),
+		watchers:  make(map[string][]func(string)),
+	}
+}
+
+// FromEnv creates a manager with the providers the environment configures:
+//
+//   - file:// always
+//   - awssm:// always, with the AWS SDK's default credentials and region,
+//     loaded when the first reference is resolved
+//   - vault:// when VAULT_ADDR is set, authenticated with VAULT_TOKEN or
+//     the file named by VAULT_TOKEN_FILE, e.g. a Vault Agent sink
+func FromEnv() (*Manager, error) {
+	m := NewManager(DefaultTimeout)
+	m.Register(SchemeFile, NewFileProvider())
+	m.Register(SchemeAWS, NewAWSProvider())
+
+	if addr := os.Getenv("VAULT_ADDR"); addr != "" {
+		vault, err := NewVaultProvider(VaultConfig{
+			Address:   addr,
+			Token:     os.Getenv("VAULT_TOKEN"),
+			TokenFile: os.Getenv("VAULT_TOKEN_FILE"),
+			Namespace: os.Getenv("VAULT_NAMESPACE"),
+			KVVersion: os.Getenv("VAULT_KV_VERSION"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		m.Register(SchemeVault, vault)
+	}
+	return m, nil
+}
+
+// Register sets the provider of a scheme
+func (m *Manager) Register(scheme string, provider Provider) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.providers[scheme] = provider
+}
+
+// IsReference reports whether value names a secret of a registered scheme.
+// Other URLs, e.g. postgres://..., aren't references.
+func (m *Manager) IsReference(value string) bool {
+	scheme, _, ok := strings.Cut(value, "://")
+	if !ok {
+		return false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok = m.providers[scheme]
+	return ok
+}
+
+// Resolve returns the secret value names, or value itself if it isn't a
+// reference. It implements shared/go/config's Resolver.
+func (m *Manager) Resolve(value string) (string, error) {
+	if !m.IsReference(value) {
+		return value, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	return m.Get(ctx, value)
+}
+
+// Get fetches the secret ref names. A reference is <scheme>://<path>, with
+// #<key> appended to pick one value of a secret with several, e.g.
+// awssm://prod/db#password.
+func (m *Manager) Get(ctx context.Context, ref string) (string, error) {
+	value, err := m.fetch(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	m.values[ref] = value
+	m.mu.Unlock()
+	return value, nil
+}
+
+func (m *Manager) fetch(ctx context.Context, ref string) (string, error) {
+	scheme, rest, ok := strings.Cut(ref, "://")
+	if !ok {
+		return "", fmt.Errorf("secrets: %q is not a reference", ref)
+	}
+	path, key, _ := strings.Cut(rest, "#")
+
+	m.mu.RLock()
+	provider, ok := m.providers[scheme]
+	m.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("secrets: no provider for %s://", scheme)
+	}
+
+	values, err := provider.Fetch(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("secrets: %s: %w", ref, err)
+	}
+	value, err := pick(values, key)
+	if err != nil {
+		return "", fmt.Errorf("secrets: %s: %w", ref, err)
+	}
+	return value, nil
+}
+
+// pick returns the value of key, or the only value when key is empty
+func pick(values map[string]string, key string) (string, error) {
+	if key != "" {
+		value, ok := values[key]
+		if !ok {
+			return "", fmt.Errorf("key %s: %w", key, ErrNotFound)
+		}
+		return value, nil
+	}
+
+	if len(values) == 1 {
+		for _, value := range values {
+			return value, nil
+		}
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return "", fmt.Errorf("secret has keys %s; name one with #<key>", strings.Join(keys, ", "))
+}
+
+// Watch calls fn with the new value whenever Refresh finds that the secret
+// ref names changed, e.g. to rotate a database password
+func (m *Manager) Watch(ref string, fn func(value string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.watchers[ref] = append(m.watchers[ref], fn)
+}
+
+// Refresh fetches every secret resolved so far, and watched ones, again and
+// notifies the watchers of those that changed. Secrets that can't be
+// fetched keep their value and are reported.
+func (m *Manager) Refresh(ctx context.Context) error {
+	m.mu.RLock()
+	refs := make(map[string]bool, len(m.values)+len(m.watchers))
+	for ref := range m.values {
+		refs[ref] = true
+	}
+	for ref := range m.watchers {
+		refs[ref] = true
+	}
+	m.mu.RUnlock()
+
+	var errs []error
+	for ref := range refs {
+		fetchCtx, cancel := context.WithTimeout(ctx, m.timeout)
+		value, err := m.fetch(fetchCtx, ref)
+		cancel()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		m.mu.Lock()
+		previous, seen := m.values[ref]
+		m.values[ref] = value
+		watchers := m.watchers[ref]
+		m.mu.Unlock()
+
+		if seen && previous == value {
+			continue
+		}
+		for _, fn := range watchers {
+			fn(value)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run refreshes the secrets every interval until ctx is done
+func (m *Manager) Run(ctx context.Context, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Refresh(ctx); err != nil {
+				logger.Warn("Failed to refresh secrets", zap.Error(err))
+			}
+		}
+	}
+}
+
+// parseValues reads a JSON object of strings, or else treats data as a
+// single value
+func parseValues(data []byte) map[string]string {
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err == nil {
+		return stringify(object)
+	}
+	return map[string]string{"value": strings.TrimSpace(string(data))}
+}
+
+// stringify converts the values of a JSON object to strings
+func stringify(object map[string]interface{}) map[string]string {
+	values := make(map[string]string, len(object))
+	for key, value := range object {
+		switch v := value.(type) {
+		case string:
+			values[key] = v
+		default:
+			encoded, _ := json.Marshal(v)
+			values[key] = string(encoded)
+		}
+	}
+	return values
+}
@@ -0,0 +1,145 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// VaultConfig configures the Vault provider, which reads KV secrets over
+// Vault's HTTP API
+type VaultConfig struct {
+	// Address is Vault's URL, e.g. http://vault:8200
+	Address string
+	// Token authenticates requests, unless TokenFile is set
+	Token string
+	// TokenFile is read before every request, so a token renewed by Vault
+	// Agent is picked up
+	TokenFile string
+	// Namespace is the Vault Enterprise namespace, if any
+	Namespace string
+	// KVVersion is the KV secrets engine version, "1" or "2" (default)
+	KVVersion string
+	// HTTPClient defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+type vaultProvider struct {
+	cfg VaultConfig
+}
+
+// NewVaultProvider creates the provider of vault://<mount>/<path>
+// references, e.g. vault://kv/jwt-secret for the secret jwt-secret of the
+// KV engine mounted at kv
+func NewVaultProvider(cfg VaultConfig) (Provider, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("secrets: Vault address is required")
+	}
+	if cfg.Token == "" && cfg.TokenFile == "" {
+		return nil, errors.New("secrets: VAULT_TOKEN or VAULT_TOKEN_FILE is required")
+	}
+	switch cfg.KVVersion {
+	case "":
+		cfg.KVVersion = "2"
+	case "1", "2":
+	default:
+		return nil, fmt.Errorf("secrets: invalid Vault KV version %q", cfg.KVVersion)
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	cfg.Address = strings.TrimSuffix(cfg.Address, "/")
+	return &vaultProvider{cfg: cfg}, nil
+}
+
+func (p *vaultProvider) Fetch(ctx context.Context, path string) (map[string]string, error) {
+	mount, secret, ok := strings.Cut(strings.Trim(path, "/"), "/")
+	if !ok || secret == "" {
+		return nil, fmt.Errorf("expected vault://<mount>/<path>, got vault://%s", path)
+	}
+	apiPath := mount + "/" + secret
+	if p.cfg.KVVersion == "2" {
+		apiPath = mount + "/data/" + secret
+	}
+
+	token, err := p.token()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.Address+"/v1/"+apiPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if p.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.cfg.Namespace)
+	}
+
+	resp, err := p.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		var failure struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.Unmarshal(body, &failure)
+		return nil, fmt.Errorf("vault: status %d: %s", resp.StatusCode, strings.Join(failure.Errors, "; "))
+	}
+
+	// KV v2 nests the secret's data in data.data
+	var result struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("vault: invalid response: %w", err)
+	}
+	data := result.Data
+	if p.cfg.KVVersion == "2" {
+		var v2 struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(data, &v2); err != nil {
+			return nil, fmt.Errorf("vault: invalid response: %w", err)
+		}
+		data = v2.Data
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, fmt.Errorf("vault: invalid response: %w", err)
+	}
+	if object == nil {
+		// KV v2 returns null data for deleted versions
+		return nil, ErrNotFound
+	}
+	return stringify(object), nil
+}
+
+func (p *vaultProvider) token() (string, error) {
+	if p.cfg.TokenFile == "" {
+		return p.cfg.Token, nil
+	}
+	content, err := os.ReadFile(p.cfg.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("vault token: %w", err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
@@ -0,0 +1,99 @@
+package testsupport
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// HTTPClient sends requests straight to a service's handler, e.g. its Gin
+// router, without listening on a port
+type HTTPClient struct {
+	Handler http.Handler
+	// Header is sent with every request, e.g. an Authorization header
+	Header http.Header
+}
+
+// NewHTTPClient creates a client for handler
+func NewHTTPClient(handler http.Handler) *HTTPClient {
+	return &HTTPClient{Handler: handler, Header: http.Header{}}
+}
+
+// WithBearer returns a copy of the client that sends token as a bearer
+// token
+func (c *HTTPClient) WithBearer(token string) *HTTPClient {
+	header := c.Header.Clone()
+	header.Set("Authorization", "Bearer "+token)
+	return &HTTPClient{Handler: c.Handler, Header: header}
+}
+
+// Do sends a request. Bodies other than nil, []byte or io.Reader are
+// encoded as JSON.
+func (c *HTTPClient) Do(t testing.TB, method, path string, body interface{}) *Response {
+	t.Helper()
+
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		reader = bytes.NewReader(b)
+	case io.Reader:
+		reader = b
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatalf("failed to encode request body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	for key, values := range c.Header {
+		req.Header[key] = values
+	}
+	if body != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	recorder := httptest.NewRecorder()
+	c.Handler.ServeHTTP(recorder, req)
+	return &Response{ResponseRecorder: recorder}
+}
+
+// Get sends a GET request
+func (c *HTTPClient) Get(t testing.TB, path string) *Response {
+	t.Helper()
+	return c.Do(t, http.MethodGet, path, nil)
+}
+
+// Post sends a POST request with a JSON body
+func (c *HTTPClient) Post(t testing.TB, path string, body interface{}) *Response {
+	t.Helper()
+	return c.Do(t, http.MethodPost, path, body)
+}
+
+// Response is a recorded response
+type Response struct {
+	*httptest.ResponseRecorder
+}
+
+// ExpectStatus fails the test, showing the body, unless the response has
+// status
+func (r *Response) ExpectStatus(t testing.TB, status int) *Response {
+	t.Helper()
+	if r.Code != status {
+		t.Fatalf("expected status %d, got %d: %s", status, r.Code, r.Body.String())
+	}
+	return r
+}
+
+// JSON decodes the body into v
+func (r *Response) JSON(t testing.TB, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(r.Body.Bytes(), v); err != nil {
+		t.Fatalf("failed to decode response body %q: %v", r.Body.String(), err)
+	}
+}
@@ -0,0 +1,149 @@
+package testsupport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/testcontainers/testcontainers-go/modules/kafka"
+)
+
+// Kafka is a running single-broker Kafka container
+type Kafka struct {
+	Brokers []string
+}
+
+// StartKafka starts Kafka in KRaft mode, so no ZooKeeper container is
+// needed
+func StartKafka(t testing.TB) *Kafka {
+	t.Helper()
+	RequireDocker(t)
+
+	ctx, cancel := startContext()
+	defer cancel()
+
+	container, err := kafka.RunContainer(ctx, kafka.WithClusterID("testsupport"))
+	if err != nil {
+		t.Fatalf("failed to start kafka: %v", err)
+	}
+	terminateOnCleanup(t, container)
+
+	brokers, err := container.Brokers(ctx)
+	if err != nil {
+		t.Fatalf("failed to get kafka brokers: %v", err)
+	}
+	return &Kafka{Brokers: brokers}
+}
+
+// CreateTopics creates single-partition topics, so consumers see their
+// messages in the order they were produced
+func (k *Kafka) CreateTopics(t testing.TB, topics ...string) {
+	t.Helper()
+
+	conn, err := kafkago.Dial("tcp", k.Brokers[0])
+	if err != nil {
+		t.Fatalf("failed to connect to kafka: %v", err)
+	}
+	defer conn.Close()
+
+	controller, err := conn.Controller()
+	if err != nil {
+		t.Fatalf("failed to find kafka controller: %v", err)
+	}
+	controllerConn, err := kafkago.Dial("tcp", net.JoinHostPort(controller.Host, strconv.Itoa(controller.Port)))
+	if err != nil {
+		t.Fatalf("failed to connect to kafka controller: %v", err)
+	}
+	defer controllerConn.Close()
+
+	configs := make([]kafkago.TopicConfig, len(topics))
+	for i, topic := range topics {
+		configs[i] = kafkago.TopicConfig{Topic: topic, NumPartitions: 1, ReplicationFactor: 1}
+	}
+	if err := controllerConn.CreateTopics(configs...); err != nil {
+		t.Fatalf("failed to create topics: %v", err)
+	}
+}
+
+// Produce publishes an event to topic. Values other than []byte are
+// encoded as JSON, like the services' events.
+func (k *Kafka) Produce(t testing.TB, topic, key string, value interface{}, headers ...kafkago.Header) {
+	t.Helper()
+
+	data, ok := value.([]byte)
+	if !ok {
+		var err error
+		if data, err = json.Marshal(value); err != nil {
+			t.Fatalf("failed to encode event: %v", err)
+		}
+	}
+
+	writer := &kafkago.Writer{
+		Addr:                   kafkago.TCP(k.Brokers...),
+		Topic:                  topic,
+		AllowAutoTopicCreation: true,
+	}
+	defer writer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	msg := kafkago.Message{Key: []byte(key), Value: data, Headers: headers}
+	if err := writer.WriteMessages(ctx, msg); err != nil {
+		t.Fatalf("failed to produce to %s: %v", topic, err)
+	}
+}
+
+// Expect reads topic from the beginning until a message satisfies match,
+// failing the test if none does within timeout. A nil match accepts the
+// first message.
+func (k *Kafka) Expect(t testing.TB, topic string, timeout time.Duration, match func(kafkago.Message) bool) kafkago.Message {
+	t.Helper()
+
+	// A group of its own reads every partition without disturbing the
+	// service's consumer group
+	reader := kafkago.NewReader(kafkago.ReaderConfig{
+		Brokers:     k.Brokers,
+		Topic:       topic,
+		GroupID:     "testsupport-" + randomSuffix(),
+		StartOffset: kafkago.FirstOffset,
+	})
+	defer reader.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for {
+		msg, err := reader.ReadMessage(ctx)
+		if errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("no matching message on %s within %s", topic, timeout)
+		}
+		if err != nil {
+			t.Fatalf("failed to consume from %s: %v", topic, err)
+		}
+		if match == nil || match(msg) {
+			return msg
+		}
+	}
+}
+
+// ExpectJSON is Expect for JSON events, decoding the matching one into v
+func (k *Kafka) ExpectJSON(t testing.TB, topic string, timeout time.Duration, match func(kafkago.Message) bool, v interface{}) kafkago.Message {
+	t.Helper()
+	msg := k.Expect(t, topic, timeout, match)
+	if err := json.Unmarshal(msg.Value, v); err != nil {
+		t.Fatalf("failed to decode event from %s: %v", topic, err)
+	}
+	return msg
+}
+
+func randomSuffix() string {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return hex.EncodeToString(suffix)
+}
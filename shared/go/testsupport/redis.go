@@ -0,0 +1,56 @@
+package testsupport
+
+import (
+	"context"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/testcontainers/testcontainers-go"
+	redismodule "github.com/testcontainers/testcontainers-go/modules/redis"
+)
+
+// Redis is a running Redis container
+type Redis struct {
+	Client *redis.Client
+	Addr   string
+}
+
+// StartRedis starts Redis, from the image docker-compose runs, and
+// connects to it
+func StartRedis(t testing.TB) *Redis {
+	t.Helper()
+	RequireDocker(t)
+
+	ctx, cancel := startContext()
+	defer cancel()
+
+	container, err := redismodule.RunContainer(ctx, testcontainers.WithImage("redis:7-alpine"))
+	if err != nil {
+		t.Fatalf("failed to start redis: %v", err)
+	}
+	terminateOnCleanup(t, container)
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("failed to get redis connection string: %v", err)
+	}
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		t.Fatalf("failed to parse redis connection string: %v", err)
+	}
+
+	client := redis.NewClient(options)
+	t.Cleanup(func() { client.Close() })
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Fatalf("failed to ping redis: %v", err)
+	}
+	return &Redis{Client: client, Addr: options.Addr}
+}
+
+// Flush empties the database, e.g. between subtests sharing a container
+func (r *Redis) Flush(t testing.TB) {
+	t.Helper()
+	if err := r.Client.FlushDB(context.Background()).Err(); err != nil {
+		t.Fatalf("failed to flush redis: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+// Package testsupport starts the Postgres, Redis and Kafka containers Go
+// services' integration tests run against, and provides helpers for
+// driving services through Kafka and HTTP. Containers are removed when the
+// test finishes.
+package testsupport
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// StartupTimeout bounds how long a container may take to start
+const StartupTimeout = 2 * time.Minute
+
+// RequireDocker skips the test in -short mode or when Docker isn't
+// available, so `go test -short ./...` keeps running unit tests only
+func RequireDocker(t testing.TB) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping integration test in -short mode")
+	}
+
+	provider, err := testcontainers.ProviderDocker.GetProvider()
+	if err != nil {
+		t.Skipf("skipping integration test, Docker is unavailable: %v", err)
+	}
+	defer provider.Close()
+	if err := provider.Health(context.Background()); err != nil {
+		t.Skipf("skipping integration test, Docker is unavailable: %v", err)
+	}
+}
+
+// terminateOnCleanup removes a container when the test finishes
+func terminateOnCleanup(t testing.TB, container testcontainers.Container) {
+	t.Cleanup(func() {
+		if err := container.Terminate(context.Background()); err != nil {
+			t.Logf("failed to terminate container: %v", err)
+		}
+	})
+}
+
+// startContext bounds a container's startup by StartupTimeout
+func startContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), StartupTimeout)
+}
@@ -0,0 +1,145 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+)
+
+// DefaultVersion is assumed for events without a schema_version
+const DefaultVersion = 1
+
+// ValidationError lists every way an event failed its schema
+type ValidationError struct {
+	EventType string
+	Version   int
+	Fields    apperrors.ValidationErrors
+}
+
+func (e *ValidationError) Error() string {
+	eventType := e.EventType
+	if eventType == "" {
+		eventType = "event"
+	}
+	return fmt.Sprintf("%s failed schema v%d validation: %s", eventType, e.Version, messages(e.Fields))
+}
+
+// AppError returns the VALIDATION_FAILED error listing the problems, for
+// events received over HTTP
+func (e *ValidationError) AppError() *apperrors.AppError {
+	return apperrors.NewValidation(e.Fields...).WithCause(e)
+}
+
+// Registry holds versioned per-event-type schemas, at
+// schemas/v<version>/<event_type>.json
+type Registry struct {
+	schemas map[string]map[int]*Schema
+}
+
+// NewRegistry loads the schemas in fsys, e.g. a service's embedded schemas
+// or sharedevents.Schemas
+func NewRegistry(fsys fs.FS) (*Registry, error) {
+	r := &Registry{schemas: make(map[string]map[int]*Schema)}
+
+	files, err := fs.Glob(fsys, "schemas/v*/*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		version, err := strconv.Atoi(strings.TrimPrefix(path.Base(path.Dir(file)), "v"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema version directory for %s", file)
+		}
+		eventType := strings.TrimSuffix(path.Base(file), ".json")
+
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		s, err := ParseSchema(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load schema %s: %w", file, err)
+		}
+
+		if r.schemas[eventType] == nil {
+			r.schemas[eventType] = make(map[int]*Schema)
+		}
+		r.schemas[eventType][version] = s
+	}
+
+	return r, nil
+}
+
+// Len returns the number of event types with at least one schema
+func (r *Registry) Len() int {
+	return len(r.schemas)
+}
+
+// Validate checks a raw event against the schema for its event_type and
+// schema_version, returning a *ValidationError listing the problems. Event
+// types without any schema are not validated.
+func (r *Registry) Validate(payload []byte) error {
+	var event map[string]interface{}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	eventType, _ := event["event_type"].(string)
+	if eventType == "" {
+		return invalid("", DefaultVersion, "event_type", "required", "event_type is required")
+	}
+
+	versions, ok := r.schemas[eventType]
+	if !ok {
+		return nil
+	}
+
+	version, err := schemaVersion(event["schema_version"])
+	if err != nil {
+		return invalid(eventType, DefaultVersion, "schema_version", "type", err.Error())
+	}
+
+	s, ok := versions[version]
+	if !ok {
+		return invalid(eventType, version, "schema_version", "enum", fmt.Sprintf("schema_version %d is not supported", version))
+	}
+
+	var errs apperrors.ValidationErrors
+	s.validate("", event, &errs)
+	if len(errs) > 0 {
+		return &ValidationError{EventType: eventType, Version: version, Fields: errs}
+	}
+
+	return nil
+}
+
+// invalid returns a ValidationError with a single problem
+func invalid(eventType string, version int, field, rule, message string) *ValidationError {
+	var errs apperrors.ValidationErrors
+	errs.Add(field, rule, message)
+	return &ValidationError{EventType: eventType, Version: version, Fields: errs}
+}
+
+// schemaVersion accepts 2, "2" and "v2"
+func schemaVersion(raw interface{}) (int, error) {
+	switch v := raw.(type) {
+	case nil:
+		return DefaultVersion, nil
+	case float64:
+		if v >= 1 && v == float64(int(v)) {
+			return int(v), nil
+		}
+	case string:
+		if n, err := strconv.Atoi(strings.TrimPrefix(v, "v")); err == nil && n >= 1 {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("schema_version must be a positive integer, got %v", raw)
+}
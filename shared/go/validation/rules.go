@@ -0,0 +1,71 @@
+// Package validation checks requests and events the same way in every Go
+// service: custom go-playground/validator rules for HTTP binding, and JSON
+// Schema for event payloads, both reporting problems as the shared errors
+// package's field errors
+package validation
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// skuPattern is the catalog's SKU format: 6 to 20 upper case letters,
+	// digits and hyphens, e.g. LAPTOP-001
+	skuPattern = regexp.MustCompile(`^[A-Z0-9-]{6,20}$`)
+	// e164Pattern is an international phone number: +, the country code and
+	// subscriber number, 15 digits at most
+	e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+	// uuidPattern is a UUID in its canonical, hyphenated form
+	uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// IsSKU reports whether s is a valid SKU, e.g. LAPTOP-001
+func IsSKU(s string) bool {
+	return skuPattern.MatchString(s)
+}
+
+// IsE164 reports whether s is an E.164 phone number, e.g. +14155552671
+func IsE164(s string) bool {
+	return e164Pattern.MatchString(s)
+}
+
+// IsCurrency reports whether s is an ISO 4217 currency code, e.g. USD
+func IsCurrency(s string) bool {
+	return currencies[s]
+}
+
+// IsUUID reports whether s is a UUID, e.g.
+// 123e4567-e89b-12d3-a456-426614174000
+func IsUUID(s string) bool {
+	return uuidPattern.MatchString(s)
+}
+
+// rules are the checks added to both layers: the tag of the binding rule
+// and the JSON Schema format
+var rules = map[string]func(string) bool{
+	"sku":      IsSKU,
+	"e164":     IsE164,
+	"currency": IsCurrency,
+	"uuid":     IsUUID,
+}
+
+// currencies are the active ISO 4217 currency codes
+var currencies = func() map[string]bool {
+	codes := strings.Fields(`
+		AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND
+		BOB BRL BSD BTN BWP BYN BZD CAD CDF CHF CLP CNY COP CRC CUP CVE CZK DJF
+		DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GNF GTQ GYD HKD
+		HNL HTG HUF IDR ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF KPW KRW
+		KWD KYD KZT LAK LBP LKR LRD LSL LYD MAD MDL MGA MKD MMK MNT MOP MRU MUR
+		MVR MWK MXN MYR MZN NAD NGN NIO NOK NPR NZD OMR PAB PEN PGK PHP PKR PLN
+		PYG QAR RON RSD RUB RWF SAR SBD SCR SDG SEK SGD SHP SLE SOS SRD SSP STN
+		SVC SYP SZL THB TJS TMT TND TOP TRY TTD TWD TZS UAH UGX USD UYU UZS VES
+		VND VUV WST XAF XCD XOF XPF YER ZAR ZMW ZWL
+	`)
+	set := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		set[code] = true
+	}
+	return set
+}()
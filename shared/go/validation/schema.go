@@ -0,0 +1,292 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+)
+
+// Schema is the subset of JSON Schema (draft 7) used by the event schemas:
+// type, required, properties, additionalProperties, items, enum, anyOf,
+// string length/pattern/format and numeric bounds. Besides email, uri,
+// date-time and uuid, formats include the custom rules sku, e164 and
+// currency.
+type Schema struct {
+	Type                 typeList           `json:"type"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+	AnyOf                []*Schema          `json:"anyOf"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+	Format               string             `json:"format"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+
+	pattern *regexp.Regexp
+}
+
+// typeList accepts both "type": "string" and "type": ["string", "null"]
+type typeList []string
+
+func (t *typeList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = typeList{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return fmt.Errorf("type must be a string or array of strings")
+	}
+	*t = multiple
+	return nil
+}
+
+// ParseSchema parses and compiles a JSON Schema document
+func ParseSchema(content []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(content, &s); err != nil {
+		return nil, err
+	}
+	if err := s.compile(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Validate checks a decoded JSON value against s, returning a
+// VALIDATION_FAILED AppError listing every violation, or nil
+func (s *Schema) Validate(value interface{}) *apperrors.AppError {
+	var errs apperrors.ValidationErrors
+	s.validate("", value, &errs)
+	return errs.Err()
+}
+
+// compile prepares patterns for the schema and its subschemas
+func (s *Schema) compile() error {
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+
+	for _, sub := range s.Properties {
+		if err := sub.compile(); err != nil {
+			return err
+		}
+	}
+	for _, sub := range s.AnyOf {
+		if err := sub.compile(); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile()
+	}
+
+	return nil
+}
+
+// validate adds a field error for every violation of s by value at path.
+// Rules are named after the JSON Schema keyword, e.g. minLength.
+func (s *Schema) validate(path string, value interface{}, errs *apperrors.ValidationErrors) {
+	fail := func(rule, format string, args ...interface{}) {
+		label := path
+		if label == "" {
+			label = "event"
+		}
+		errs.Add(label, rule, fmt.Sprintf("%s %s", label, fmt.Sprintf(format, args...)))
+	}
+
+	if len(s.Type) > 0 && !s.matchesType(value) {
+		fail("type", "must be %s, got %s", strings.Join(s.Type, " or "), typeOf(value))
+		return
+	}
+
+	if len(s.Enum) > 0 && !containsValue(s.Enum, value) {
+		fail("enum", "must be one of %v", s.Enum)
+	}
+
+	if len(s.AnyOf) > 0 {
+		matched := false
+		var first apperrors.ValidationErrors
+		for i, sub := range s.AnyOf {
+			var subErrs apperrors.ValidationErrors
+			sub.validate(path, value, &subErrs)
+			if len(subErrs) == 0 {
+				matched = true
+				break
+			}
+			if i == 0 {
+				first = subErrs
+			}
+		}
+		if !matched {
+			fail("anyOf", "must match at least one allowed shape (first: %s)", messages(first))
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		s.validateString(v, fail)
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			fail("minimum", "must be >= %v", *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			fail("maximum", "must be <= %v", *s.Maximum)
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+	case map[string]interface{}:
+		s.validateObject(path, v, errs, fail)
+	}
+}
+
+func (s *Schema) validateString(v string, fail func(string, string, ...interface{})) {
+	length := utf8.RuneCountInString(v)
+	if s.MinLength != nil && length < *s.MinLength {
+		if *s.MinLength == 1 {
+			fail("minLength", "must not be empty")
+		} else {
+			fail("minLength", "must be at least %d characters", *s.MinLength)
+		}
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		fail("maxLength", "must be at most %d characters", *s.MaxLength)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(v) {
+		fail("pattern", "must match pattern %s", s.Pattern)
+	}
+	if s.Format != "" && v != "" && !validFormat(s.Format, v) {
+		fail("format", "must be a valid %s", s.Format)
+	}
+}
+
+func (s *Schema) validateObject(path string, v map[string]interface{}, errs *apperrors.ValidationErrors, fail func(string, string, ...interface{})) {
+	for _, name := range s.Required {
+		if _, ok := v[name]; !ok {
+			field := joinPath(path, name)
+			errs.Add(field, "required", field+" is required")
+		}
+	}
+
+	names := make([]string, 0, len(v))
+	for name := range v {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		sub, ok := s.Properties[name]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				fail("additionalProperties", "has unexpected property %q", name)
+			}
+			continue
+		}
+		sub.validate(joinPath(path, name), v[name], errs)
+	}
+}
+
+func (s *Schema) matchesType(value interface{}) bool {
+	actual := typeOf(value)
+	for _, t := range s.Type {
+		if t == actual {
+			return true
+		}
+		if t == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON Schema type of a value decoded by encoding/json
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func validFormat(format, v string) bool {
+	if rule, ok := rules[format]; ok {
+		return rule(v)
+	}
+
+	switch format {
+	case "email":
+		addr, err := mail.ParseAddress(v)
+		return err == nil && addr.Address == v
+	case "uri":
+		u, err := url.Parse(v)
+		return err == nil && u.Scheme != "" && u.Host != ""
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, v)
+		return err == nil
+	default:
+		// Unknown formats are annotations only
+		return true
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// messages joins the messages of field errors
+func messages(errs apperrors.ValidationErrors) string {
+	parts := make([]string, len(errs))
+	for i, fe := range errs {
+		parts[i] = fe.Message
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,62 @@
+package validation
+
+import (
+	"sync"
+
+	apperrors "github.com/ecommerce-platform/shared/go/errors"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Register adds the custom rules to v, as binding tags:
+//
+//   - sku: a SKU, e.g. LAPTOP-001
+//   - e164: an E.164 phone number, e.g. +14155552671
+//   - currency: an ISO 4217 currency code, e.g. USD
+//   - uuid: a canonical UUID
+//
+// Optional fields combine them with omitempty, e.g. binding:"omitempty,e164".
+func Register(v *validator.Validate) error {
+	for tag, rule := range rules {
+		rule := rule
+		err := v.RegisterValidation(tag, func(fl validator.FieldLevel) bool {
+			return rule(fl.Field().String())
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RegisterGin adds the custom rules to Gin's binding validator, so
+// apperrors.BindJSON checks them. Call it once at startup, before the
+// routes are served.
+func RegisterGin() error {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		return Register(v)
+	}
+	return nil
+}
+
+var (
+	structValidator     *validator.Validate
+	structValidatorOnce sync.Once
+)
+
+// Struct checks obj's binding tags outside HTTP requests, e.g. the payload
+// of a consumed event. Failures are a VALIDATION_FAILED AppError listing
+// the fields by their JSON path, as apperrors.BindJSON reports them.
+func Struct(obj interface{}) error {
+	structValidatorOnce.Do(func() {
+		structValidator = validator.New()
+		structValidator.SetTagName("binding")
+		// The rules are valid; registering them can't fail
+		_ = Register(structValidator)
+	})
+
+	if err := structValidator.Struct(obj); err != nil {
+		return apperrors.FromBinding(err, obj)
+	}
+	return nil
+}